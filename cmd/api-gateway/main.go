@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,20 +16,44 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/pprof"
 	"github.com/gofiber/fiber/v2/middleware/recover"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/onichange/pos-system/internal/domain/store"
+	"github.com/onichange/pos-system/internal/infrastructure/inventoryclient"
+	"github.com/onichange/pos-system/internal/infrastructure/orderclient"
+	"github.com/onichange/pos-system/internal/infrastructure/paymentclient"
+	"github.com/onichange/pos-system/internal/infrastructure/storeclient"
+	"github.com/onichange/pos-system/internal/infrastructure/userclient"
+	"github.com/onichange/pos-system/internal/interfaces/http/gateway"
+	"github.com/onichange/pos-system/migrations"
 	"github.com/onichange/pos-system/pkg/api"
+	"github.com/onichange/pos-system/pkg/audit"
+	"github.com/onichange/pos-system/pkg/audit/security"
 	"github.com/onichange/pos-system/pkg/auth"
 	"github.com/onichange/pos-system/pkg/cache"
 	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/database"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/featureflags"
+	appgrpc "github.com/onichange/pos-system/pkg/grpc"
+	"github.com/onichange/pos-system/pkg/i18n"
 	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
 	"github.com/onichange/pos-system/pkg/middleware"
+	"github.com/onichange/pos-system/pkg/performance"
 	"github.com/onichange/pos-system/pkg/proxy"
+	"github.com/onichange/pos-system/pkg/secrets"
+	"github.com/onichange/pos-system/pkg/slo"
+	"github.com/onichange/pos-system/pkg/tenant"
+	"github.com/onichange/pos-system/pkg/tracing"
 )
 
 func main() {
+	validateConfig := flag.Bool("validate-config", false, "Validate configuration, print it with secrets masked, and exit")
+	flag.Parse()
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.LoadService("api-gateway")
 	if err != nil {
 		fmt.Printf("Failed to load config: %v\n", err)
 		os.Exit(1)
@@ -36,6 +63,43 @@ func main() {
 	log := logger.New(cfg.Server.Environment)
 	log.Info("Starting API Gateway...")
 
+	// Resolve secrets from the configured backend (Vault, AWS Secrets Manager)
+	if err := secrets.Resolve(context.Background(), cfg, log); err != nil {
+		log.Fatalf("Failed to resolve secrets: %v", err)
+	}
+
+	// Print the effective configuration and stop when only validating
+	if *validateConfig {
+		effective, err := cfg.EffectiveYAML()
+		if err != nil {
+			log.Fatalf("Failed to render configuration: %v", err)
+		}
+		fmt.Print(string(effective))
+		return
+	}
+
+	// Initialize tracing; spans are exported only when an exporter is configured
+	tracerProvider, err := tracing.NewTracerProvider(cfg.ServiceName, cfg.Server.Environment, cfg.Tracing, log)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// Report panics and server errors when a DSN is configured
+	if err := apperrors.Init(cfg.ServiceName, cfg.Server.Environment, cfg.ErrorReporting); err != nil {
+		log.Warnf("Error reporting disabled: %v", err)
+	}
+
+	// Initialize the security audit log, kept apart from application logs
+	if err := security.Init(cfg.ServiceName, cfg.Audit.Security); err != nil {
+		log.Fatalf("Failed to open security audit log: %v", err)
+	}
+
+	// Cap concurrent calls to each dependency so a slow one fails fast
+	bulkheads := performance.NewBulkheads(cfg.Bulkheads)
+
+	// Share one retry budget across the process so an outage is not amplified
+	retryBudget := performance.NewRetryBudget(cfg.Retry)
+
 	// Initialize Redis for rate limiting
 	redisCache, err := cache.NewRedisCache(
 		cfg.Redis.Host,
@@ -49,6 +113,7 @@ func main() {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
 	defer redisCache.Close()
+	redisCache.UseBulkhead(bulkheads.Redis)
 
 	// Get Redis client for rate limiter
 	redisClient := redisCache.GetClient()
@@ -59,6 +124,77 @@ func main() {
 		cfg.Security.RateLimitRequestsPerMinute,
 		time.Minute,
 	)
+	rateLimiter.SetFallback(cfg.Security.RateLimitFallback, log)
+
+	// Watch configuration for changes (file updates, SIGHUP)
+	configWatcher := config.NewWatcher(cfg)
+	configWatcher.OnError(func(err error) {
+		log.Errorf("Failed to reload configuration: %v", err)
+	})
+	configWatcher.Subscribe(func(old, new *config.Config) {
+		if old.Security.RateLimitRequestsPerMinute != new.Security.RateLimitRequestsPerMinute {
+			rateLimiter.SetLimit(new.Security.RateLimitRequestsPerMinute)
+		}
+		if old.Security.RateLimitFallback != new.Security.RateLimitFallback {
+			rateLimiter.SetFallback(new.Security.RateLimitFallback, log)
+		}
+		log.Info("Configuration reloaded")
+	})
+	go configWatcher.Run(context.Background())
+
+	// The gateway database holds the audit log and the tenant registry
+	var db *database.PostgresDB
+	migrate := flag.Arg(0) == "migrate"
+	if cfg.Audit.Enabled || cfg.Tenant.Registry || migrate {
+		db, err = database.NewPostgresDB(cfg.Database, log)
+		if err != nil {
+			log.Fatalf("Failed to connect to gateway database: %v", err)
+		}
+		defer db.Close()
+	}
+
+	// "api-gateway migrate ..." migrates the gateway database and exits;
+	// with migrate_on_start, pending migrations are applied before serving
+	if migrate {
+		if err := migrations.Run(context.Background(), db.Pool, cfg.ServiceName, flag.Args()[1:]); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		return
+	}
+	if db != nil && cfg.Database.MigrateOnStart {
+		if err := migrations.Up(context.Background(), db.Pool, cfg.ServiceName, log); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+
+	// Initialize audit log (append-only, written in the background)
+	var auditStore audit.Store
+	var auditRecorder *audit.Recorder
+	if cfg.Audit.Enabled {
+		auditStore = audit.NewPostgresStore(db.Pool)
+		auditRecorder = audit.NewRecorder(auditStore, cfg.Audit.BufferSize, log)
+	}
+
+	// Initialize the tenant registry, which admits provisioned tenants and
+	// carries their rate limits
+	var tenantStore tenant.Store
+	var tenantRegistry *tenant.Registry
+	if cfg.Tenant.Registry {
+		tenantStore = tenant.NewPostgresStore(db.Pool)
+		tenantRegistry = tenant.NewRegistry(tenantStore, cfg.Tenant.CacheTTL, log)
+		rateLimiter.SetTenantLimits(tenantRegistry.RateLimit)
+	}
+	auditCtx, stopAudit := context.WithCancel(context.Background())
+	auditDone := make(chan struct{})
+	if auditRecorder != nil {
+		go func() {
+			auditRecorder.Run(auditCtx)
+			close(auditDone)
+		}()
+		go audit.RunRetention(auditCtx, auditStore, cfg.Audit.Retention, cfg.Audit.RetentionInterval, log)
+	} else {
+		close(auditDone)
+	}
 
 	// Initialize JWT manager
 	jwtManager := auth.NewJWTManager(
@@ -67,7 +203,7 @@ func main() {
 		cfg.JWT.AccessTokenExpiry,
 		cfg.JWT.RefreshTokenExpiry,
 		cfg.JWT.Issuer,
-	)
+	).WithPreviousSecrets(cfg.JWT.PreviousAccessTokenSecrets, cfg.JWT.PreviousRefreshTokenSecrets)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -78,34 +214,54 @@ func main() {
 	})
 
 	// Global middleware
-	app.Use(recover.New())
+	app.Use(recover.New(recover.Config{
+		EnableStackTrace:  true,
+		StackTraceHandler: apperrors.PanicHandler,
+	}))
+	app.Use(middleware.RequestID())
+	app.Use(middleware.TracingMiddleware())
+	app.Use(apperrors.Middleware())
+	if auditRecorder != nil {
+		app.Use(audit.Middleware(auditRecorder))
+	}
 	app.Use(compress.New(compress.Config{
 		Level: compress.LevelBestCompression,
 	}))
+	app.Use(middleware.BodyLogger(log, cfg.Logging)) // Inside compress so bodies are logged uncompressed
+	app.Use(i18n.Middleware())
 	app.Use(middleware.SecurityHeaders())
 	app.Use(middleware.RequestSizeLimit(cfg.Security.MaxRequestSize))
-	app.Use(middleware.PrometheusMetrics()) // Prometheus metrics
+	app.Use(middleware.PrometheusMetrics(metrics.NewRED(cfg.ServiceName, cfg.Metrics.DurationBuckets, cfg.Metrics.Exemplars)))
+
+	// Count requests against the service level objectives
+	sloTracker := slo.NewTracker(cfg.ServiceName, cfg.SLO)
+	prometheus.MustRegister(sloTracker)
+	app.Use(middleware.SLOTracking(sloTracker))
 
 	if cfg.Security.EnableCORS {
-		app.Use(middleware.CORSMiddleware(cfg.Security.CORSOrigins))
+		app.Use(middleware.DynamicCORSMiddleware(func() []string {
+			return configWatcher.Current().Security.CORSOrigins
+		}))
 	}
 
-	// Rate limiting middleware
-	app.Use(rateLimiter.RateLimitMiddleware())
+	// Rate limiting middleware. Users and their tenants are read from verified
+	// bearer tokens, so requests without one are limited by IP address;
+	// JWTAuth still rejects the unauthenticated ones on protected routes.
+	app.Use(middleware.OptionalJWTAuth(jwtManager), rateLimiter.RateLimitMiddleware())
 
 	// Start Prometheus metrics server on separate port
 	go func() {
 		metricsMux := http.NewServeMux()
-		metricsMux.Handle("/metrics", promhttp.Handler())
+		metricsMux.Handle("/metrics", metrics.Handler())
 		metricsServer := &http.Server{
-			Addr:    ":9090",
+			Addr:    ":" + cfg.Service.MetricsPort,
 			Handler: metricsMux,
 		}
 		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Errorf("Metrics server error: %v", err)
 		}
 	}()
-	log.Info("Prometheus metrics server started on :9090/metrics")
+	log.Infof("Prometheus metrics server started on :%s/metrics", cfg.Service.MetricsPort)
 
 	// pprof endpoints (only in development)
 	if cfg.Server.Environment == "development" {
@@ -115,62 +271,375 @@ func main() {
 	// Swagger/OpenAPI documentation
 	api.SetupSwagger(app)
 
+	// Effective configuration for debugging (admins only)
+	api.SetupDebugConfig(app, jwtManager, configWatcher.Current)
+
 	// Health check endpoint
 	app.Get("/health", healthCheck)
 	app.Get("/ready", readinessCheck)
+	app.Get("/slo", sloTracker.Handler())
 
 	// API routes
 	api := app.Group("/api/v1")
 
-	// Authentication routes
+	// Authentication routes, served by the user service
+	userProxy := proxy.NewServiceProxy("user-service", cfg.Services.UserServiceURL, cfg.Proxy)
+	defer userProxy.Close()
+	userProxy.UseRetryBudget(retryBudget)
 	authGroup := api.Group("/auth")
-	authGroup.Post("/login", handleLogin(jwtManager))
-	authGroup.Post("/refresh", handleRefresh(jwtManager))
-	authGroup.Post("/logout", handleLogout)
+	authGroup.Post("/login", userProxy.Proxy)
+	authGroup.Post("/refresh", userProxy.Proxy)
+	authGroup.Post("/logout", userProxy.Proxy)
+
+	// Receipt service routes. Print agents sign their requests, which the
+	// receipt service verifies, so their routes sit outside JWT auth.
+	receiptProxy := proxy.NewServiceProxy("receipt-service", cfg.Services.ReceiptServiceURL, cfg.Proxy)
+	defer receiptProxy.Close()
+	receiptProxy.UseRetryBudget(retryBudget)
+	api.Get("/print-agent/jobs", receiptProxy.Proxy)
+	api.Post("/print-agent/jobs/:id/ack", receiptProxy.Proxy)
+	api.Get("/print-agent/ws", receiptProxy.Proxy)
+
+	// Store service routes. Devices pair with their one-time code and send
+	// heartbeats with their API key, which the store service checks, so
+	// those routes sit outside JWT auth.
+	storeProxy := proxy.NewServiceProxy("store-service", cfg.Services.StoreServiceURL, cfg.Proxy)
+	defer storeProxy.Close()
+	storeProxy.UseRetryBudget(retryBudget)
+	api.Post("/devices/pair", tenant.Middleware(cfg.Tenant), storeProxy.Proxy)
+	api.Post("/devices/heartbeat", tenant.Middleware(cfg.Tenant), storeProxy.Proxy)
+
+	// Webhook service routes. Partners sign their requests, which the webhook
+	// service verifies, so their routes sit outside JWT auth.
+	webhookProxy := proxy.NewServiceProxy("webhook-service", cfg.Services.WebhookServiceURL, cfg.Proxy)
+	defer webhookProxy.Close()
+	webhookProxy.UseRetryBudget(retryBudget)
+	api.Get("/partner/webhooks/subscriptions", webhookProxy.Proxy)
+	api.Get("/partner/webhooks/subscriptions/:id", webhookProxy.Proxy)
+	api.Post("/partner/webhooks/subscriptions", webhookProxy.Proxy)
+	api.Put("/partner/webhooks/subscriptions/:id", webhookProxy.Proxy)
+	api.Delete("/partner/webhooks/subscriptions/:id", webhookProxy.Proxy)
+	api.Post("/partner/webhooks/subscriptions/:id/pause", webhookProxy.Proxy)
+	api.Post("/partner/webhooks/subscriptions/:id/resume", webhookProxy.Proxy)
+	api.Post("/partner/webhooks/subscriptions/:id/replay", webhookProxy.Proxy)
+	api.Get("/partner/webhooks/deliveries", webhookProxy.Proxy)
+	api.Get("/partner/webhooks/deliveries/:id", webhookProxy.Proxy)
+	api.Post("/partner/webhooks/deliveries/:id/replay", webhookProxy.Proxy)
+
+	// Payment service routes. The payment provider signs its webhooks, which
+	// the payment service verifies, so that route sits outside JWT auth.
+	paymentProxy := proxy.NewServiceProxy("payment-service", cfg.Services.PaymentServiceURL, cfg.Proxy)
+	defer paymentProxy.Close()
+	paymentProxy.UseRetryBudget(retryBudget)
+	api.Post("/payments/webhook", paymentProxy.Proxy)
+
+	// Protected routes with JWT authentication, scoped to the caller's tenant
+	protected := api.Group("/", middleware.JWTAuth(jwtManager), tenant.Middleware(cfg.Tenant))
+	if tenantRegistry != nil {
+		protected.Use(tenantRegistry.Require())
+	}
+
+	// Requests from paired devices carry their API key; a deactivated
+	// device is refused once its cached check expires
+	stores := storeclient.NewClient(cfg.Services.StoreServiceURL, cfg.Proxy)
+	protected.Use(middleware.DeviceAuth(verifyDevice(stores), redisCache, cfg.Devices.VerifyCacheTTL))
+
+	// Answer cacheable lookups from Redis, dropping them when a mutation
+	// passes through
+	if cfg.ResponseCache.Enabled {
+		protected.Use(middleware.NewResponseCache(redisClient, cfg.ResponseCache).Middleware())
+	}
+
+	// Feature flag admin API
+	flagStore := featureflags.NewRedisStore(redisClient)
+	flagClient := featureflags.NewClient(flagStore, cfg.FeatureFlags.CacheTTL, log)
+	admin := protected.Group("/admin", middleware.RequireRole("admin"))
+	featureflags.NewHandler(flagStore, flagClient).RegisterRoutes(admin.Group("/feature-flags"))
+
+	// Audit query API
+	if auditStore != nil {
+		audit.NewHandler(auditStore).RegisterRoutes(admin.Group("/audit"))
+	}
 
-	// Protected routes with JWT authentication
-	protected := api.Group("/", middleware.JWTAuth(jwtManager))
+	// Tenant onboarding API, for admins of the default tenant who operate the
+	// platform; every caller can read their own tenant
+	if tenantStore != nil {
+		tenantHandler := tenant.NewHandler(tenantStore, tenantRegistry)
+		tenantHandler.RegisterRoutes(admin.Group("/tenants", tenant.RequireID(cfg.Tenant.Default)))
+		protected.Get("/tenant", tenantHandler.GetCurrentTenant)
+	}
+
+	// Lookups answered over the services' internal gRPC APIs; the rest of
+	// their routes are proxied to their HTTP APIs
+	orderConn, err := appgrpc.Dial(cfg.Services.OrderGRPCTarget, cfg.GRPC, jwtManager)
+	if err != nil {
+		log.Fatalf("Failed to create order client: %v", err)
+	}
+	defer orderConn.Close()
+	paymentConn, err := appgrpc.Dial(cfg.Services.PaymentGRPCTarget, cfg.GRPC, jwtManager)
+	if err != nil {
+		log.Fatalf("Failed to create payment client: %v", err)
+	}
+	defer paymentConn.Close()
+	inventoryConn, err := appgrpc.Dial(cfg.Services.InventoryGRPCTarget, cfg.GRPC, jwtManager)
+	if err != nil {
+		log.Fatalf("Failed to create inventory client: %v", err)
+	}
+	defer inventoryConn.Close()
+	userConn, err := appgrpc.Dial(cfg.Services.UserGRPCTarget, cfg.GRPC, jwtManager)
+	if err != nil {
+		log.Fatalf("Failed to create user client: %v", err)
+	}
+	defer userConn.Close()
+	lookups := gateway.NewHandler(
+		orderclient.NewGRPCClient(orderConn),
+		paymentclient.NewGRPCClient(paymentConn),
+		inventoryclient.NewClient(inventoryConn),
+		userclient.NewClient(userConn),
+	)
 
 	// Order service routes
-	orderProxy := proxy.NewServiceProxy(cfg.Services.OrderServiceURL)
-	protected.Get("/orders", orderProxy.Proxy)
+	orderProxy := proxy.NewServiceProxy("order-service", cfg.Services.OrderServiceURL, cfg.Proxy)
+	defer orderProxy.Close()
+	orderProxy.UseRetryBudget(retryBudget)
+	accounting := middleware.RequireRole("admin") // Exports hold every order, payment and stock value of the tenant
+	protected.Get("/orders/export", accounting, orderProxy.Stream)
+	protected.Get("/orders", lookups.GetOrders)
 	protected.Post("/orders", orderProxy.Proxy)
-	protected.Get("/orders/:id", orderProxy.Proxy)
+	protected.Get("/orders/:id", lookups.GetOrderByID)
 	protected.Put("/orders/:id", orderProxy.Proxy)
 	protected.Delete("/orders/:id", orderProxy.Proxy)
+	protected.Patch("/orders/:id/status", middleware.RequireRole("admin"), orderProxy.Proxy)
+	protected.Put("/orders/:id/status", middleware.RequireRole("admin"), orderProxy.Proxy)
+	protected.Get("/orders/:id/status/history", middleware.RequireRole("admin"), orderProxy.Proxy)
+	protected.Get("/orders/:id/history", middleware.RequireRole("admin"), orderProxy.Proxy)
+	protected.Post("/orders/:id/checkout", orderProxy.Proxy)
+	protected.Post("/carts", orderProxy.Proxy)
+	protected.Get("/carts/:id", orderProxy.Proxy)
+	protected.Delete("/carts/:id", orderProxy.Proxy)
+	protected.Post("/carts/:id/items", orderProxy.Proxy)
+	protected.Put("/carts/:id/items/:productId", orderProxy.Proxy)
+	protected.Delete("/carts/:id/items/:productId", orderProxy.Proxy)
+	protected.Put("/carts/:id/discount", orderProxy.Proxy)
+	protected.Delete("/carts/:id/discount", orderProxy.Proxy)
+	protected.Post("/carts/:id/checkout", orderProxy.Proxy)
 
 	// User service routes
-	userProxy := proxy.NewServiceProxy(cfg.Services.UserServiceURL)
-	protected.Get("/users/me", userProxy.Proxy)
+	protected.Get("/users/me", lookups.GetUserProfile)
 	protected.Put("/users/me", userProxy.Proxy)
+	protected.Put("/users/me/password", userProxy.Proxy)
 
 	// Store service routes
-	storeProxy := proxy.NewServiceProxy(cfg.Services.StoreServiceURL)
 	protected.Get("/stores", storeProxy.Proxy)
 	protected.Get("/stores/:id", storeProxy.Proxy)
+	deviceAdmin := middleware.RequireRole("admin") // Only admins pair and lock out devices
+	protected.Get("/stores/:id/devices", deviceAdmin, storeProxy.Proxy)
+	protected.Post("/stores/:id/devices", deviceAdmin, storeProxy.Proxy)
+	protected.Get("/stores/:id/devices/:deviceId", deviceAdmin, storeProxy.Proxy)
+	protected.Post("/stores/:id/devices/:deviceId/pairing-code", deviceAdmin, storeProxy.Proxy)
+	protected.Post("/stores/:id/devices/:deviceId/deactivate", deviceAdmin, storeProxy.Proxy)
+	exportAdmin := middleware.RequireRole("admin") // Exports hold every order and customer of the store
+	protected.Get("/stores/:id/exports", exportAdmin, storeProxy.Proxy)
+	protected.Post("/stores/:id/exports", exportAdmin, storeProxy.Proxy)
+	protected.Get("/stores/:id/exports/:exportId", exportAdmin, storeProxy.Proxy)
+	protected.Get("/stores/:id/exports/:exportId/download", exportAdmin, storeProxy.Proxy)
 
 	// Payment service routes
-	paymentProxy := proxy.NewServiceProxy(cfg.Services.PaymentServiceURL)
 	protected.Post("/payments", paymentProxy.Proxy)
-	protected.Get("/payments/:id", paymentProxy.Proxy)
+	protected.Get("/payments/export", accounting, paymentProxy.Stream)
+	protected.Get("/payments/:id", lookups.GetPayment)
 
 	// Inventory service routes
-	inventoryProxy := proxy.NewServiceProxy(cfg.Services.InventoryServiceURL)
+	inventoryProxy := proxy.NewServiceProxy("inventory-service", cfg.Services.InventoryServiceURL, cfg.Proxy)
+	defer inventoryProxy.Close()
+	inventoryProxy.UseRetryBudget(retryBudget)
 	protected.Get("/inventory", inventoryProxy.Proxy)
-	protected.Get("/inventory/:id", inventoryProxy.Proxy)
+	stockStaff := middleware.RequireRole("admin", "staff") // Store staff adjust, count and move stock between stores
+	protected.Get("/inventory/transfers", stockStaff, inventoryProxy.Proxy)
+	protected.Post("/inventory/transfers", stockStaff, inventoryProxy.Proxy)
+	protected.Get("/inventory/transfers/:id", stockStaff, inventoryProxy.Proxy)
+	protected.Post("/inventory/transfers/:id/dispatch", stockStaff, inventoryProxy.Proxy)
+	protected.Post("/inventory/transfers/:id/receive", stockStaff, inventoryProxy.Proxy)
+	protected.Post("/inventory/transfers/:id/cancel", stockStaff, inventoryProxy.Proxy)
+	protected.Get("/inventory/counts", stockStaff, inventoryProxy.Proxy)
+	protected.Post("/inventory/counts", stockStaff, inventoryProxy.Proxy)
+	protected.Get("/inventory/counts/:id", stockStaff, inventoryProxy.Proxy)
+	protected.Post("/inventory/counts/:id/lines", stockStaff, inventoryProxy.Proxy)
+	protected.Get("/inventory/counts/:id/variances", stockStaff, inventoryProxy.Proxy)
+	protected.Post("/inventory/counts/:id/reconcile", stockStaff, inventoryProxy.Proxy)
+	protected.Post("/inventory/counts/:id/cancel", stockStaff, inventoryProxy.Proxy)
+	protected.Get("/inventory/export", accounting, inventoryProxy.Stream)
+	protected.Post("/inventory/import", middleware.RequireRole("admin"), inventoryProxy.Proxy) // Imports create stock records in bulk
+	protected.Get("/inventory/:id", lookups.GetInventory)
 	protected.Put("/inventory/:id", inventoryProxy.Proxy)
+	protected.Get("/inventory/:id/cost-layers", inventoryProxy.Proxy)
+	protected.Post("/inventory/:id/adjust", stockStaff, inventoryProxy.Proxy)
+
+	// Catalog service routes
+	catalogProxy := proxy.NewServiceProxy("catalog-service", cfg.Services.CatalogServiceURL, cfg.Proxy)
+	defer catalogProxy.Close()
+	catalogProxy.UseRetryBudget(retryBudget)
+	catalogAdmin := middleware.RequireRole("admin") // Only admins change the catalog and prices
+	protected.Get("/categories", catalogProxy.Proxy)
+	protected.Post("/categories", catalogAdmin, catalogProxy.Proxy)
+	protected.Put("/categories/:id", catalogAdmin, catalogProxy.Proxy)
+	protected.Delete("/categories/:id", catalogAdmin, catalogProxy.Proxy)
+	protected.Get("/products", catalogProxy.Proxy)
+	protected.Get("/products/:id", catalogProxy.Proxy)
+	protected.Post("/products", catalogAdmin, catalogProxy.Proxy)
+	protected.Put("/products/:id", catalogAdmin, catalogProxy.Proxy)
+	protected.Delete("/products/:id", catalogAdmin, catalogProxy.Proxy)
+	protected.Post("/products/:id/variants", catalogAdmin, catalogProxy.Proxy)
+	protected.Get("/variants/lookup", catalogProxy.Proxy)
+	protected.Put("/variants/:id", catalogAdmin, catalogProxy.Proxy)
+	protected.Delete("/variants/:id", catalogAdmin, catalogProxy.Proxy)
+	protected.Get("/stores/:storeId/prices", catalogProxy.Proxy)
+	protected.Put("/stores/:storeId/prices/:variantId", catalogAdmin, catalogProxy.Proxy)
+	protected.Delete("/stores/:storeId/prices/:variantId", catalogAdmin, catalogProxy.Proxy)
+
+	// Loyalty service routes
+	loyaltyProxy := proxy.NewServiceProxy("loyalty-service", cfg.Services.LoyaltyServiceURL, cfg.Proxy)
+	defer loyaltyProxy.Close()
+	loyaltyProxy.UseRetryBudget(retryBudget)
+	protected.Get("/loyalty/program", loyaltyProxy.Proxy)
+	protected.Get("/loyalty/account", loyaltyProxy.Proxy)
+	protected.Get("/loyalty/transactions", loyaltyProxy.Proxy)
+
+	// Promotion service routes; only admins manage campaigns
+	promotionProxy := proxy.NewServiceProxy("promotion-service", cfg.Services.PromotionServiceURL, cfg.Proxy)
+	defer promotionProxy.Close()
+	promotionProxy.UseRetryBudget(retryBudget)
+	promotionAdmin := middleware.RequireRole("admin")
+	protected.Get("/promotions", promotionAdmin, promotionProxy.Proxy)
+	protected.Get("/promotions/:id", promotionAdmin, promotionProxy.Proxy)
+	protected.Post("/promotions", promotionAdmin, promotionProxy.Proxy)
+	protected.Put("/promotions/:id", promotionAdmin, promotionProxy.Proxy)
+	protected.Delete("/promotions/:id", promotionAdmin, promotionProxy.Proxy)
+	protected.Get("/promotions/:id/coupons", promotionAdmin, promotionProxy.Proxy)
+	protected.Post("/promotions/:id/coupons", promotionAdmin, promotionProxy.Proxy)
+	protected.Put("/promotions/:id/coupons/:couponId", promotionAdmin, promotionProxy.Proxy)
+	protected.Delete("/promotions/:id/coupons/:couponId", promotionAdmin, promotionProxy.Proxy)
+	protected.Post("/coupons/validate", promotionProxy.Proxy)
+
+	// Analytics service routes; dashboards are for admins only
+	analyticsProxy := proxy.NewServiceProxy("analytics-service", cfg.Services.AnalyticsServiceURL, cfg.Proxy)
+	defer analyticsProxy.Close()
+	analyticsProxy.UseRetryBudget(retryBudget)
+	analyticsAdmin := middleware.RequireRole("admin")
+	protected.Get("/analytics/revenue", analyticsAdmin, analyticsProxy.Proxy)
+	protected.Get("/analytics/top-products", analyticsAdmin, analyticsProxy.Proxy)
+	protected.Get("/analytics/conversion", analyticsAdmin, analyticsProxy.Proxy)
+	protected.Get("/analytics/stock", analyticsAdmin, analyticsProxy.Proxy)
+	protected.Get("/analytics/timesheets", analyticsAdmin, analyticsProxy.Proxy)
+
+	// Search service routes; orders of every customer are for store staff
+	// and admins, the catalog for anyone signed in
+	searchProxy := proxy.NewServiceProxy("search-service", cfg.Services.SearchServiceURL, cfg.Proxy)
+	defer searchProxy.Close()
+	searchProxy.UseRetryBudget(retryBudget)
+	protected.Get("/search/orders", middleware.RequireRole("admin", "staff"), searchProxy.Proxy)
+	protected.Get("/search/products", searchProxy.Proxy)
+
+	// Sync service routes for POS terminals, run by store staff
+	syncProxy := proxy.NewServiceProxy("sync-service", cfg.Services.SyncServiceURL, cfg.Proxy)
+	defer syncProxy.Close()
+	syncProxy.UseRetryBudget(retryBudget)
+	syncStaff := middleware.RequireRole("admin", "staff")
+	protected.Get("/sync/stores/:storeId/changes", syncStaff, syncProxy.Proxy)
+	protected.Post("/sync/stores/:storeId/transactions", syncStaff, syncProxy.Proxy)
+
+	// Receipts for the caller's orders; templates, the print queue and the
+	// cash drawer are for admins only
+	receiptAdmin := middleware.RequireRole("admin")
+	protected.Post("/receipts", receiptProxy.Proxy)
+	protected.Get("/receipts/:orderId", receiptProxy.Proxy)
+	protected.Get("/stores/:storeId/receipt-template", receiptAdmin, receiptProxy.Proxy)
+	protected.Put("/stores/:storeId/receipt-template", receiptAdmin, receiptProxy.Proxy)
+	protected.Post("/print-jobs/drawer", receiptAdmin, receiptProxy.Proxy)
+	protected.Get("/print-jobs", receiptAdmin, receiptProxy.Proxy)
+	protected.Get("/print-jobs/:id", receiptAdmin, receiptProxy.Proxy)
+
+	// Shift service routes; staff work their own shifts, admins any
+	shiftProxy := proxy.NewServiceProxy("shift-service", cfg.Services.ShiftServiceURL, cfg.Proxy)
+	defer shiftProxy.Close()
+	shiftProxy.UseRetryBudget(retryBudget)
+	protected.Post("/shifts", shiftProxy.Proxy)
+	protected.Get("/shifts", shiftProxy.Proxy)
+	protected.Get("/shifts/z-reports", shiftProxy.Proxy)
+	protected.Get("/shifts/:id", shiftProxy.Proxy)
+	protected.Post("/shifts/:id/close", shiftProxy.Proxy)
+	protected.Post("/shifts/:id/cash-movements", shiftProxy.Proxy)
+	protected.Get("/shifts/:id/cash-movements", shiftProxy.Proxy)
+	protected.Get("/shifts/:id/report", shiftProxy.Proxy)
+	protected.Post("/timeclock/clock-in", shiftProxy.Proxy)
+	protected.Post("/timeclock/clock-out", shiftProxy.Proxy)
+	protected.Post("/timeclock/breaks/start", shiftProxy.Proxy)
+	protected.Post("/timeclock/breaks/end", shiftProxy.Proxy)
+	protected.Get("/timeclock/current", shiftProxy.Proxy)
+	protected.Get("/timeclock/entries", shiftProxy.Proxy)
+	protected.Get("/timeclock/entries/:id", shiftProxy.Proxy)
+	protected.Put("/timeclock/entries/:id", middleware.RequireRole("admin"), shiftProxy.Proxy)
+	protected.Get("/timeclock/entries/:id/adjustments", shiftProxy.Proxy)
+
+	// Tax service routes
+	taxProxy := proxy.NewServiceProxy("tax-service", cfg.Services.TaxServiceURL, cfg.Proxy)
+	defer taxProxy.Close()
+	taxProxy.UseRetryBudget(retryBudget)
+	registerTaxRoutes(protected, taxProxy.Proxy, cfg.Tenant.Default)
+
+	// Procurement service routes; only admins manage suppliers and buy stock
+	procurementProxy := proxy.NewServiceProxy("procurement-service", cfg.Services.ProcurementServiceURL, cfg.Proxy)
+	defer procurementProxy.Close()
+	procurementProxy.UseRetryBudget(retryBudget)
+	procurementAdmin := middleware.RequireRole("admin")
+	protected.Get("/suppliers", procurementAdmin, procurementProxy.Proxy)
+	protected.Get("/suppliers/:id", procurementAdmin, procurementProxy.Proxy)
+	protected.Post("/suppliers", procurementAdmin, procurementProxy.Proxy)
+	protected.Put("/suppliers/:id", procurementAdmin, procurementProxy.Proxy)
+	protected.Delete("/suppliers/:id", procurementAdmin, procurementProxy.Proxy)
+	protected.Get("/suppliers/:id/performance", procurementAdmin, procurementProxy.Proxy)
+	protected.Get("/suppliers/:id/products", procurementAdmin, procurementProxy.Proxy)
+	protected.Put("/suppliers/:id/products/:productId", procurementAdmin, procurementProxy.Proxy)
+	protected.Delete("/suppliers/:id/products/:productId", procurementAdmin, procurementProxy.Proxy)
+	protected.Get("/purchase-orders", procurementAdmin, procurementProxy.Proxy)
+	protected.Get("/purchase-orders/:id", procurementAdmin, procurementProxy.Proxy)
+	protected.Post("/purchase-orders", procurementAdmin, procurementProxy.Proxy)
+	protected.Put("/purchase-orders/:id", procurementAdmin, procurementProxy.Proxy)
+	protected.Post("/purchase-orders/:id/submit", procurementAdmin, procurementProxy.Proxy)
+	protected.Post("/purchase-orders/:id/cancel", procurementAdmin, procurementProxy.Proxy)
+	protected.Post("/purchase-orders/:id/close", procurementAdmin, procurementProxy.Proxy)
+	protected.Get("/purchase-orders/:id/receipts", procurementAdmin, procurementProxy.Proxy)
+	protected.Post("/purchase-orders/:id/receipts", procurementAdmin, procurementProxy.Proxy)
+
+	// Webhook subscriptions and deliveries of every partner are for admins only
+	webhookAdmin := middleware.RequireRole("admin")
+	protected.Get("/webhooks/subscriptions", webhookAdmin, webhookProxy.Proxy)
+	protected.Get("/webhooks/subscriptions/:id", webhookAdmin, webhookProxy.Proxy)
+	protected.Post("/webhooks/subscriptions", webhookAdmin, webhookProxy.Proxy)
+	protected.Put("/webhooks/subscriptions/:id", webhookAdmin, webhookProxy.Proxy)
+	protected.Delete("/webhooks/subscriptions/:id", webhookAdmin, webhookProxy.Proxy)
+	protected.Post("/webhooks/subscriptions/:id/pause", webhookAdmin, webhookProxy.Proxy)
+	protected.Post("/webhooks/subscriptions/:id/resume", webhookAdmin, webhookProxy.Proxy)
+	protected.Post("/webhooks/subscriptions/:id/replay", webhookAdmin, webhookProxy.Proxy)
+	protected.Get("/webhooks/deliveries", webhookAdmin, webhookProxy.Proxy)
+	protected.Get("/webhooks/deliveries/:id", webhookAdmin, webhookProxy.Proxy)
+	protected.Post("/webhooks/deliveries/:id/replay", webhookAdmin, webhookProxy.Proxy)
 
 	// Start server
-	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
+	// Bind before serving so a port of "0" resolves to the actual port in the log
+	listener, err := net.Listen("tcp", cfg.Server.Address())
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", cfg.Server.Address(), err)
+	}
 
 	// Graceful shutdown
 	go func() {
-		if err := app.Listen(addr); err != nil {
+		if err := app.Listener(listener); err != nil {
 			log.Fatalf("Server failed to start: %v", err)
 		}
 	}()
 
-	log.Infof("API Gateway listening on %s", addr)
+	log.Infof("API Gateway listening on %s", listener.Addr())
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -186,9 +655,47 @@ func main() {
 		log.Errorf("Error during shutdown: %v", err)
 	}
 
+	// Flush audit entries recorded by the last requests
+	stopAudit()
+	<-auditDone
+
+	// Flush spans recorded by the last requests
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		log.Errorf("Error flushing traces: %v", err)
+	}
+	apperrors.Flush(5 * time.Second)
+	if err := security.Close(); err != nil {
+		log.Errorf("Error closing security audit log: %v", err)
+	}
+
 	log.Info("API Gateway stopped")
 }
 
+// registerTaxRoutes forwards the tax service routes. Only admins manage tax
+// tables and export filings, and since jurisdictions, categories and holidays
+// are shared by every tenant, only admins of the operator's tenant change them.
+func registerTaxRoutes(protected fiber.Router, forward fiber.Handler, operator string) {
+	taxAdmin := middleware.RequireRole("admin")
+	shared := tenant.RequireID(operator)
+	protected.Get("/tax/jurisdictions", taxAdmin, forward)
+	protected.Get("/tax/jurisdictions/:id", taxAdmin, forward)
+	protected.Post("/tax/jurisdictions", taxAdmin, shared, forward)
+	protected.Put("/tax/jurisdictions/:id", taxAdmin, shared, forward)
+	protected.Get("/tax/categories", taxAdmin, forward)
+	protected.Post("/tax/categories", taxAdmin, shared, forward)
+	protected.Get("/tax/assignments", taxAdmin, forward)
+	protected.Post("/tax/assignments", taxAdmin, forward)
+	protected.Delete("/tax/assignments/:kind/:subjectId", taxAdmin, forward)
+	protected.Get("/tax/holidays", taxAdmin, forward)
+	protected.Post("/tax/holidays", taxAdmin, shared, forward)
+	protected.Delete("/tax/holidays/:id", taxAdmin, shared, forward)
+	protected.Get("/tax/stores/:storeId/rates", taxAdmin, forward)
+	protected.Put("/tax/stores/:storeId/rates/:jurisdictionId", taxAdmin, forward)
+	protected.Delete("/tax/stores/:storeId/rates/:jurisdictionId", taxAdmin, forward)
+	protected.Post("/tax/quote", taxAdmin, forward)
+	protected.Get("/tax/reports", taxAdmin, forward)
+}
+
 func healthCheck(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"status":    "healthy",
@@ -215,23 +722,25 @@ func errorHandler(c *fiber.Ctx, err error) error {
 
 	// Never expose stack traces to clients
 	return c.Status(code).JSON(fiber.Map{
-		"error": message,
+		"error":      message,
+		"request_id": middleware.GetRequestID(c),
 	})
 }
 
-// Placeholder handlers - to be implemented
-func handleLogin(jwtManager *auth.JWTManager) fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{"message": "Login endpoint - to be implemented"})
-	}
-}
-
-func handleRefresh(jwtManager *auth.JWTManager) fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{"message": "Refresh endpoint - to be implemented"})
+// verifyDevice checks device API keys with the store service
+func verifyDevice(stores *storeclient.Client) middleware.DeviceVerifier {
+	return func(ctx context.Context, key string) (string, error) {
+		d, err := stores.VerifyDevice(ctx, key)
+		if errors.Is(err, store.ErrDeviceNotFound) {
+			return "", middleware.ErrUnknownDevice
+		}
+		if err != nil {
+			return "", err
+		}
+		if d.Status != store.DeviceActive {
+			return "", middleware.ErrDeviceDeactivated
+		}
+		return d.ID.String(), nil
 	}
 }
 
-func handleLogout(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"message": "Logout endpoint - to be implemented"})
-}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/middleware"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+func TestTaxRoutesReserveSharedTablesToOperator(t *testing.T) {
+	jwtManager := auth.NewJWTManager("access", "refresh", time.Hour, time.Hour, "test")
+	app := fiber.New()
+	protected := app.Group("/", middleware.JWTAuth(jwtManager), tenant.Middleware(config.TenantConfig{Default: "default"}))
+	registerTaxRoutes(protected, func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }, "default")
+
+	token := func(tenantID string) string {
+		pair, err := jwtManager.GenerateTenantTokenPair(tenantID, "user-1", "admin@example.com", []string{"admin"}, "")
+		require.NoError(t, err)
+		return pair.AccessToken
+	}
+	send := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	shared := [][2]string{
+		{fiber.MethodPost, "/tax/jurisdictions"},
+		{fiber.MethodPut, "/tax/jurisdictions/1"},
+		{fiber.MethodPost, "/tax/categories"},
+		{fiber.MethodPost, "/tax/holidays"},
+		{fiber.MethodDelete, "/tax/holidays/1"},
+	}
+	for _, route := range shared {
+		assert.Equal(t, fiber.StatusForbidden, send(route[0], route[1], token("acme")), route)
+		assert.Equal(t, fiber.StatusOK, send(route[0], route[1], token("default")), route)
+	}
+
+	// A tenant's admins still read the shared tables and manage their own rates
+	assert.Equal(t, fiber.StatusOK, send(fiber.MethodGet, "/tax/jurisdictions", token("acme")))
+	assert.Equal(t, fiber.StatusOK, send(fiber.MethodPut, "/tax/stores/1/rates/1", token("acme")))
+}
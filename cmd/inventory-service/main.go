@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,19 +13,42 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/onichange/pos-system/internal/infrastructure/outbox"
 	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	"github.com/onichange/pos-system/internal/infrastructure/unitofwork"
+	inventorygrpc "github.com/onichange/pos-system/internal/interfaces/grpc/inventory"
 	"github.com/onichange/pos-system/internal/interfaces/http/inventory"
+	"github.com/onichange/pos-system/migrations"
+	"github.com/onichange/pos-system/pkg/archive"
+	"github.com/onichange/pos-system/pkg/audit/security"
+	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/cache"
 	"github.com/onichange/pos-system/pkg/config"
 	"github.com/onichange/pos-system/pkg/database"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	appgrpc "github.com/onichange/pos-system/pkg/grpc"
+	"github.com/onichange/pos-system/pkg/i18n"
 	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messaging"
 	"github.com/onichange/pos-system/pkg/metrics"
 	"github.com/onichange/pos-system/pkg/middleware"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/secrets"
+	"github.com/onichange/pos-system/pkg/slo"
+	"github.com/onichange/pos-system/pkg/tenant"
+	"github.com/onichange/pos-system/pkg/tracing"
+	inventorypb "github.com/onichange/pos-system/proto/inventory"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
+	validateConfig := flag.Bool("validate-config", false, "Validate configuration, print it with secrets masked, and exit")
+	flag.Parse()
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.LoadService("inventory-service")
 	if err != nil {
 		fmt.Printf("Failed to load config: %v\n", err)
 		os.Exit(1)
@@ -33,6 +58,43 @@ func main() {
 	log := logger.New(cfg.Server.Environment)
 	log.Info("Starting Inventory Service...")
 
+	// Resolve secrets from the configured backend (Vault, AWS Secrets Manager)
+	if err := secrets.Resolve(context.Background(), cfg, log); err != nil {
+		log.Fatalf("Failed to resolve secrets: %v", err)
+	}
+
+	// Print the effective configuration and stop when only validating
+	if *validateConfig {
+		effective, err := cfg.EffectiveYAML()
+		if err != nil {
+			log.Fatalf("Failed to render configuration: %v", err)
+		}
+		fmt.Print(string(effective))
+		return
+	}
+
+	// Initialize tracing; spans are exported only when an exporter is configured
+	tracerProvider, err := tracing.NewTracerProvider(cfg.ServiceName, cfg.Server.Environment, cfg.Tracing, log)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// Report panics and server errors when a DSN is configured
+	if err := apperrors.Init(cfg.ServiceName, cfg.Server.Environment, cfg.ErrorReporting); err != nil {
+		log.Warnf("Error reporting disabled: %v", err)
+	}
+
+	// Initialize the security audit log, kept apart from application logs
+	if err := security.Init(cfg.ServiceName, cfg.Audit.Security); err != nil {
+		log.Fatalf("Failed to open security audit log: %v", err)
+	}
+
+	// Cap concurrent calls to each dependency so a slow one fails fast
+	bulkheads := performance.NewBulkheads(cfg.Bulkheads)
+
+	// Share one retry budget across the process so an outage is not amplified
+	retryBudget := performance.NewRetryBudget(cfg.Retry)
+
 	// Initialize database
 	db, err := database.NewPostgresDB(cfg.Database, log)
 	if err != nil {
@@ -40,11 +102,73 @@ func main() {
 	}
 	defer db.Close()
 
-	// Initialize repositories
-	inventoryRepo := repository.NewInventoryRepository(db.Pool)
+	// "<service> migrate ..." migrates the service's database and exits;
+	// with migrate_on_start, pending migrations are applied before serving
+	if flag.Arg(0) == "migrate" {
+		if err := migrations.Run(context.Background(), db.Pool, cfg.ServiceName, flag.Args()[1:]); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		return
+	}
+	if cfg.Database.MigrateOnStart {
+		if err := migrations.Up(context.Background(), db.Pool, cfg.ServiceName, log); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+
+	// Initialize Redis cache (idempotency keys)
+	var redisClient *redis.Client
+	redisCache, err := cache.NewRedisCache(cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.PoolSize, cfg.Redis.MinIdleConns)
+	if err != nil {
+		log.Warnf("Failed to connect to Redis: %v (continuing without cache)", err)
+	} else {
+		defer redisCache.Close()
+		redisCache.UseBulkhead(bulkheads.Redis)
+		redisClient = redisCache.GetClient()
+	}
+
+	// Declare messaging topology (exchanges, queues, topics) from config
+	if _, err := messaging.SetupTopology(cfg.Messaging, log); err != nil {
+		log.Warnf("Failed to apply messaging topology: %v", err)
+	}
+
+	// Initialize repositories. Each query attempt holds a bulkhead slot, and
+	// transient failures are retried.
+	queries := database.WithRetry(
+		database.WithBulkhead(db.Pool, bulkheads.Database),
+		performance.NewRetryPolicy("database", cfg.Retry, retryBudget),
+	)
+	inventoryRepo := repository.NewInventoryRepository(queries)
+	transferRepo := repository.NewStockTransferRepository(queries, db.Pool)
+	reservationRepo := repository.NewStockReservationRepository(queries, db.Pool)
+	countRepo := repository.NewStockCountRepository(queries, db.Pool)
+	importRepo := repository.NewStockImportRepository(queries, database.NewQueryExecutor(db.Pool))
+
+	// Move stock movements past their retention to the archive schema in the background
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go archive.NewArchiver(db.Pool, cfg.Archive, inventoryRepo.ArchiveTables()...).Run(jobsCtx, log)
+
+	// Publish inventory events for other services, such as analytics, through
+	// the outbox, recording each in the transaction of the stock change it
+	// reports, so they wait in the database while RabbitMQ is unreachable
+	uow := unitofwork.New(db.Pool, nil)
+	inTx := func(ctx context.Context, fn func(tx *inventory.Tx) error) error {
+		return uow.Do(ctx, func(tx *unitofwork.Tx) error {
+			return fn(&inventory.Tx{Inventory: tx.Inventory(), Reservations: tx.Reservations(), Counts: tx.Counts(), Events: tx.Events()})
+		})
+	}
+	go outbox.NewRelay(db.Pool, outbox.DialRabbitMQ(cfg.Messaging.RabbitMQURL, log), cfg.Outbox).Run(jobsCtx, log)
 
 	// Initialize handlers
-	inventoryHandler := inventory.NewHandler(inventoryRepo)
+	inventoryHandler := inventory.NewHandler(inventoryRepo, reservationRepo, cfg.Inventory.ReservationTTL, inTx)
+	transferHandler := inventory.NewTransferHandler(transferRepo)
+	countHandler := inventory.NewCountHandler(countRepo, inventoryHandler)
+	importHandler := inventory.NewImportHandler(importRepo)
+
+	// Release stock reservations neither committed nor released before they
+	// expire, such as those of abandoned orders
+	go inventoryHandler.RunSweeper(jobsCtx, cfg.Inventory.SweepInterval, log)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -55,13 +179,26 @@ func main() {
 	})
 
 	// Global middleware
-	app.Use(recover.New())
+	app.Use(recover.New(recover.Config{
+		EnableStackTrace:  true,
+		StackTraceHandler: apperrors.PanicHandler,
+	}))
+	app.Use(middleware.RequestID())
+	app.Use(middleware.TracingMiddleware())
+	app.Use(apperrors.Middleware())
 	app.Use(compress.New(compress.Config{
 		Level: compress.LevelBestCompression,
 	}))
+	app.Use(middleware.BodyLogger(log, cfg.Logging)) // Inside compress so bodies are logged uncompressed
+	app.Use(i18n.Middleware())
 	app.Use(middleware.SecurityHeaders())
 	app.Use(middleware.RequestSizeLimit(cfg.Security.MaxRequestSize))
-	app.Use(middleware.PrometheusMetrics())
+	app.Use(middleware.PrometheusMetrics(metrics.NewRED(cfg.ServiceName, cfg.Metrics.DurationBuckets, cfg.Metrics.Exemplars)))
+
+	// Count requests against the service level objectives
+	sloTracker := slo.NewTracker(cfg.ServiceName, cfg.SLO)
+	prometheus.MustRegister(sloTracker)
+	app.Use(middleware.SLOTracking(sloTracker))
 
 	if cfg.Security.EnableCORS {
 		app.Use(middleware.CORSMiddleware(cfg.Security.CORSOrigins))
@@ -70,34 +207,99 @@ func main() {
 	// Health check endpoints
 	app.Get("/health", healthCheck)
 	app.Get("/ready", readinessCheck)
+	app.Get("/slo", sloTracker.Handler())
 
 	// Prometheus metrics endpoint
 	app.Get("/metrics", metrics.FiberMetricsHandler())
 
 	// API routes
-	api := app.Group("/api/v1")
+	api := app.Group("/api/v1", tenant.Middleware(cfg.Tenant))
+
+	// Stock transfers between stores, ahead of /inventory/:id
+	api.Get("/inventory/transfers", transferHandler.ListTransfers)
+	api.Post("/inventory/transfers", transferHandler.CreateTransfer)
+	api.Get("/inventory/transfers/:id", transferHandler.GetTransfer)
+	api.Post("/inventory/transfers/:id/dispatch", transferHandler.DispatchTransfer)
+	api.Post("/inventory/transfers/:id/receive", transferHandler.ReceiveTransfer)
+	api.Post("/inventory/transfers/:id/cancel", transferHandler.CancelTransfer)
+
+	// Stock counts of a store, ahead of /inventory/:id
+	api.Get("/inventory/counts", countHandler.ListCounts)
+	api.Post("/inventory/counts", countHandler.CreateCount)
+	api.Get("/inventory/counts/:id", countHandler.GetCount)
+	api.Post("/inventory/counts/:id/lines", countHandler.RecordCounts)
+	api.Get("/inventory/counts/:id/variances", countHandler.GetVariances)
+	api.Post("/inventory/counts/:id/reconcile", countHandler.ReconcileCount)
+	api.Post("/inventory/counts/:id/cancel", countHandler.CancelCount)
+
+	// Stock reservations, held until committed, released or expired
+	api.Get("/inventory/reservations/:id", inventoryHandler.GetReservation)
+	api.Post("/inventory/reservations/:id/commit", inventoryHandler.CommitReservation)
+	api.Post("/inventory/reservations/:id/release", inventoryHandler.ReleaseReservation)
 
 	// Inventory routes
+	api.Get("/inventory/export", inventoryHandler.Export)
+	api.Post("/inventory/import", importHandler.Import)
 	api.Get("/inventory/:id", inventoryHandler.GetInventory)
 	api.Get("/inventory/product/:product_id", inventoryHandler.GetInventoryByProduct)
 	api.Get("/inventory/store/:store_id", inventoryHandler.GetInventoryByStore)
 	api.Get("/inventory/low-stock", inventoryHandler.GetLowStockItems)
 	api.Post("/inventory", inventoryHandler.CreateInventory)
 	api.Put("/inventory/:id", inventoryHandler.UpdateInventory)
-	api.Post("/inventory/reserve", inventoryHandler.ReserveStock)
+	api.Get("/inventory/:id/cost-layers", inventoryHandler.GetCostLayers)
+	api.Post("/inventory/:id/adjust", inventoryHandler.AdjustInventory)
+	api.Post("/inventory/reserve", middleware.Idempotency(redisClient, middleware.DefaultIdempotencyConfig()), inventoryHandler.ReserveStock)
+	api.Post("/inventory/reserve-batch", middleware.Idempotency(redisClient, middleware.DefaultIdempotencyConfig()), inventoryHandler.ReserveBatch)
 	api.Post("/inventory/release", inventoryHandler.ReleaseStock)
 
+	// Internal routes for other services; the gateway does not proxy them
+	internal := app.Group("/internal/v1", tenant.Middleware(cfg.Tenant))
+	internal.Get("/stores/:storeId/export", inventoryHandler.ExportStore)
+	internal.Get("/inventory/low-stock", inventoryHandler.ListLowStock)
+	internal.Post("/inventory/reservations", inventoryHandler.ReserveStock)
+	internal.Post("/inventory/reservations/batch", inventoryHandler.ReserveBatch)
+	internal.Post("/inventory/reservations/:id/commit", inventoryHandler.CommitReservation)
+	internal.Post("/inventory/reservations/:id/release", inventoryHandler.ReleaseReservation)
+
+	// Service tokens authenticate calls over gRPC
+	jwtManager := auth.NewJWTManager(
+		cfg.JWT.AccessTokenSecret,
+		cfg.JWT.RefreshTokenSecret,
+		cfg.JWT.AccessTokenExpiry,
+		cfg.JWT.RefreshTokenExpiry,
+		cfg.JWT.Issuer,
+	).WithPreviousSecrets(cfg.JWT.PreviousAccessTokenSecrets, cfg.JWT.PreviousRefreshTokenSecrets)
+
+	// Internal gRPC API for other services, such as procurement receiving stock
+	var grpcServer *appgrpc.Server
+	if cfg.Service.GRPCPort != "" {
+		grpcServer, err = appgrpc.NewServer(net.JoinHostPort(cfg.Server.Host, cfg.Service.GRPCPort), cfg.GRPC, cfg.Tenant, jwtManager, log)
+		if err != nil {
+			log.Fatalf("Failed to create gRPC server: %v", err)
+		}
+		inventorypb.RegisterInventoryServiceServer(grpcServer.GetServer(), inventorygrpc.NewServer(inventoryHandler, inventoryRepo))
+		go func() {
+			if err := grpcServer.Start(); err != nil {
+				log.Fatalf("gRPC server failed: %v", err)
+			}
+		}()
+	}
+
 	// Start server
-	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, "8085") // Inventory service port
+	// Bind before serving so a port of "0" resolves to the actual port in the log
+	listener, err := net.Listen("tcp", cfg.Server.Address())
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", cfg.Server.Address(), err)
+	}
 
 	// Graceful shutdown
 	go func() {
-		if err := app.Listen(addr); err != nil {
+		if err := app.Listener(listener); err != nil {
 			log.Fatalf("Server failed to start: %v", err)
 		}
 	}()
 
-	log.Infof("Inventory Service listening on %s", addr)
+	log.Infof("Inventory Service listening on %s", listener.Addr())
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -105,6 +307,7 @@ func main() {
 	<-quit
 
 	log.Info("Shutting down Inventory Service...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -112,6 +315,18 @@ func main() {
 	if err := app.ShutdownWithContext(ctx); err != nil {
 		log.Errorf("Error during shutdown: %v", err)
 	}
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+
+	// Flush spans recorded by the last requests
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		log.Errorf("Error flushing traces: %v", err)
+	}
+	apperrors.Flush(5 * time.Second)
+	if err := security.Close(); err != nil {
+		log.Errorf("Error closing security audit log: %v", err)
+	}
 
 	log.Info("Inventory Service stopped")
 }
@@ -142,6 +357,7 @@ func errorHandler(c *fiber.Ctx, err error) error {
 	}
 
 	return c.Status(code).JSON(fiber.Map{
-		"error": message,
+		"error":      message,
+		"request_id": middleware.GetRequestID(c),
 	})
 }
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,21 +13,45 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	"github.com/onichange/pos-system/internal/infrastructure/userclient"
 	"github.com/onichange/pos-system/internal/interfaces/http/notification"
+	"github.com/onichange/pos-system/migrations"
+	debugapi "github.com/onichange/pos-system/pkg/api"
+	"github.com/onichange/pos-system/pkg/archive"
+	"github.com/onichange/pos-system/pkg/audit/security"
 	"github.com/onichange/pos-system/pkg/auth"
 	"github.com/onichange/pos-system/pkg/cache"
 	"github.com/onichange/pos-system/pkg/config"
 	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/email"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	appgrpc "github.com/onichange/pos-system/pkg/grpc"
+	"github.com/onichange/pos-system/pkg/i18n"
 	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+	"github.com/onichange/pos-system/pkg/messaging"
 	"github.com/onichange/pos-system/pkg/metrics"
 	"github.com/onichange/pos-system/pkg/middleware"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/push"
+	"github.com/onichange/pos-system/pkg/secrets"
+	"github.com/onichange/pos-system/pkg/slo"
+	"github.com/onichange/pos-system/pkg/sms"
+	"github.com/onichange/pos-system/pkg/tenant"
+	"github.com/onichange/pos-system/pkg/tracing"
+	"github.com/onichange/pos-system/pkg/websocket"
 )
 
 func main() {
+	validateConfig := flag.Bool("validate-config", false, "Validate configuration, print it with secrets masked, and exit")
+	flag.Parse()
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.LoadService("notification-service")
 	if err != nil {
 		fmt.Printf("Failed to load config: %v\n", err)
 		os.Exit(1)
@@ -35,6 +61,43 @@ func main() {
 	log := logger.New(cfg.Server.Environment)
 	log.Info("Starting Notification Service...")
 
+	// Resolve secrets from the configured backend (Vault, AWS Secrets Manager)
+	if err := secrets.Resolve(context.Background(), cfg, log); err != nil {
+		log.Fatalf("Failed to resolve secrets: %v", err)
+	}
+
+	// Print the effective configuration and stop when only validating
+	if *validateConfig {
+		effective, err := cfg.EffectiveYAML()
+		if err != nil {
+			log.Fatalf("Failed to render configuration: %v", err)
+		}
+		fmt.Print(string(effective))
+		return
+	}
+
+	// Initialize tracing; spans are exported only when an exporter is configured
+	tracerProvider, err := tracing.NewTracerProvider(cfg.ServiceName, cfg.Server.Environment, cfg.Tracing, log)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// Report panics and server errors when a DSN is configured
+	if err := apperrors.Init(cfg.ServiceName, cfg.Server.Environment, cfg.ErrorReporting); err != nil {
+		log.Warnf("Error reporting disabled: %v", err)
+	}
+
+	// Initialize the security audit log, kept apart from application logs
+	if err := security.Init(cfg.ServiceName, cfg.Audit.Security); err != nil {
+		log.Fatalf("Failed to open security audit log: %v", err)
+	}
+
+	// Cap concurrent calls to each dependency so a slow one fails fast
+	bulkheads := performance.NewBulkheads(cfg.Bulkheads)
+
+	// Share one retry budget across the process so an outage is not amplified
+	retryBudget := performance.NewRetryBudget(cfg.Retry)
+
 	// Initialize database
 	db, err := database.NewPostgresDB(cfg.Database, log)
 	if err != nil {
@@ -42,10 +105,32 @@ func main() {
 	}
 	defer db.Close()
 
-	// Initialize Redis cache (for async processing)
-	_, err = cache.NewRedisCache(cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.PoolSize, cfg.Redis.MinIdleConns)
+	// "<service> migrate ..." migrates the service's database and exits;
+	// with migrate_on_start, pending migrations are applied before serving
+	if flag.Arg(0) == "migrate" {
+		if err := migrations.Run(context.Background(), db.Pool, cfg.ServiceName, flag.Args()[1:]); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		return
+	}
+	if cfg.Database.MigrateOnStart {
+		if err := migrations.Up(context.Background(), db.Pool, cfg.ServiceName, log); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+
+	// Initialize Redis cache (for async processing and WebSocket fan-out)
+	var redisClient *redis.Client
+	redisCache, err := cache.NewRedisCache(cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.PoolSize, cfg.Redis.MinIdleConns)
 	if err != nil {
 		log.Warnf("Failed to connect to Redis: %v (continuing without cache)", err)
+	} else {
+		redisClient = redisCache.GetClient()
+	}
+
+	// Declare messaging topology (exchanges, queues, topics) from config
+	if _, err := messaging.SetupTopology(cfg.Messaging, log); err != nil {
+		log.Warnf("Failed to apply messaging topology: %v", err)
 	}
 
 	// Initialize JWT manager
@@ -55,13 +140,93 @@ func main() {
 		cfg.JWT.AccessTokenExpiry,
 		cfg.JWT.RefreshTokenExpiry,
 		cfg.JWT.Issuer,
+	).WithPreviousSecrets(cfg.JWT.PreviousAccessTokenSecrets, cfg.JWT.PreviousRefreshTokenSecrets)
+
+	// Initialize repositories. Each query attempt holds a bulkhead slot, and
+	// transient failures are retried.
+	queries := database.WithRetry(
+		database.WithBulkhead(db.Pool, bulkheads.Database),
+		performance.NewRetryPolicy("database", cfg.Retry, retryBudget),
 	)
+	notificationRepo := repository.NewNotificationRepository(queries)
+	deviceRepo := repository.NewDeviceTokenRepository(queries)
 
-	// Initialize repositories
-	notificationRepo := repository.NewNotificationRepository(db.Pool)
+	// Move notifications past their retention to the archive schema in the background
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go archive.NewArchiver(db.Pool, cfg.Archive, notificationRepo.ArchiveTables()...).Run(jobsCtx, log)
 
 	// Initialize handlers
-	notificationHandler := notification.NewHandler(notificationRepo)
+	notificationHandler := notification.NewHandler(notificationRepo, deviceRepo)
+
+	// In-app notifications are pushed to the WebSocket clients of their
+	// recipient; through Redis when available, so any instance can reach them
+	hub := websocket.NewHub(redisClient, log)
+	go hub.Run()
+	notificationHandler.EnableRealtime(hub)
+
+	// The user service has the addresses and phone numbers of recipients;
+	// the connection is made on first use
+	userConn, err := appgrpc.Dial(cfg.Services.UserGRPCTarget, cfg.GRPC, jwtManager)
+	if err != nil {
+		log.Fatalf("Failed to create user client: %v", err)
+	}
+	defer userConn.Close()
+	users := userclient.NewClient(userConn)
+
+	// Send email notifications in the background, to the addresses the user
+	// service has for their recipients
+	sender, err := email.NewSender(cfg.Email)
+	if err != nil {
+		log.Fatalf("Failed to create email sender: %v", err)
+	}
+	if sender != nil {
+		templates, err := email.LoadTemplates(cfg.Email.TemplatesDir)
+		if err != nil {
+			log.Fatalf("Failed to load email templates: %v", err)
+		}
+
+		notificationHandler.EnableEmail(sender, templates, users, cfg.Email)
+		go notificationHandler.RunEmailDispatcher(jobsCtx, log)
+	}
+
+	// Send push notifications in the background to the devices registered
+	// with a configured provider
+	pushProviders, err := push.NewProviders(cfg.Push)
+	if err != nil {
+		log.Fatalf("Failed to create push providers: %v", err)
+	}
+	if len(pushProviders) > 0 {
+		notificationHandler.EnablePush(pushProviders, cfg.Push)
+		go notificationHandler.RunPushDispatcher(jobsCtx, log)
+	}
+
+	// Send SMS notifications in the background, to the phone numbers the user
+	// service has for their recipients, within the outbound SMS rate limit
+	smsProvider, err := sms.NewProvider(cfg.SMS)
+	if err != nil {
+		log.Fatalf("Failed to create SMS provider: %v", err)
+	}
+	if smsProvider != nil {
+		smsLimit := performance.NewDependencyLimiter(performance.LimitSMS, cfg.RateLimits.SMS)
+		notificationHandler.EnableSMS(smsProvider, smsLimit, users, cfg.SMS)
+		go notificationHandler.RunSMSDispatcher(jobsCtx, log)
+	}
+
+	// Create and send the notifications other services request through the
+	// message broker, and relay order events to store displays. Events that fail are retried after the queue's retry
+	// delay, and dead-lettered once out of attempts.
+	broker, err := messagequeue.NewRabbitMQ(cfg.Messaging.RabbitMQURL, log)
+	if err != nil {
+		log.Fatalf("Failed to connect to RabbitMQ: %v", err)
+	}
+	defer broker.Close()
+	broker.UseDefaultTenant(cfg.Tenant.Default)
+	for _, queue := range cfg.Service.Queues {
+		if err := broker.ConsumeWithRetry(queue, cfg.ServiceName, cfg.Messaging.ConsumeAttempts, notificationHandler.HandleEvent); err != nil {
+			log.Fatalf("Failed to consume %s: %v", queue, err)
+		}
+	}
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -72,13 +237,26 @@ func main() {
 	})
 
 	// Global middleware
-	app.Use(recover.New())
+	app.Use(recover.New(recover.Config{
+		EnableStackTrace:  true,
+		StackTraceHandler: apperrors.PanicHandler,
+	}))
+	app.Use(middleware.RequestID())
+	app.Use(middleware.TracingMiddleware())
+	app.Use(apperrors.Middleware())
 	app.Use(compress.New(compress.Config{
 		Level: compress.LevelBestCompression,
 	}))
+	app.Use(middleware.BodyLogger(log, cfg.Logging)) // Inside compress so bodies are logged uncompressed
+	app.Use(i18n.Middleware())
 	app.Use(middleware.SecurityHeaders())
 	app.Use(middleware.RequestSizeLimit(cfg.Security.MaxRequestSize))
-	app.Use(middleware.PrometheusMetrics())
+	app.Use(middleware.PrometheusMetrics(metrics.NewRED(cfg.ServiceName, cfg.Metrics.DurationBuckets, cfg.Metrics.Exemplars)))
+
+	// Count requests against the service level objectives
+	sloTracker := slo.NewTracker(cfg.ServiceName, cfg.SLO)
+	prometheus.MustRegister(sloTracker)
+	app.Use(middleware.SLOTracking(sloTracker))
 
 	if cfg.Security.EnableCORS {
 		app.Use(middleware.CORSMiddleware(cfg.Security.CORSOrigins))
@@ -87,36 +265,57 @@ func main() {
 	// Health check endpoints
 	app.Get("/health", healthCheck)
 	app.Get("/ready", readinessCheck)
+	app.Get("/slo", sloTracker.Handler())
 
 	// Prometheus metrics endpoint
 	app.Get("/metrics", metrics.FiberMetricsHandler())
 
+	// Effective configuration for debugging (admins only)
+	debugapi.SetupDebugConfig(app, jwtManager, func() *config.Config { return cfg })
+
 	// API routes
 	api := app.Group("/api/v1")
 
+	// Delivery receipts of text messages, signed by the SMS provider
+	api.Post("/sms/status", notificationHandler.HandleSMSStatus)
+
+	// Live in-app notifications. Browsers cannot set headers on an upgrade,
+	// so the access token may come in the query string.
+	api.Get("/ws", websocket.QueryToken(), middleware.JWTAuth(jwtManager), tenant.Middleware(cfg.Tenant), websocket.HandleWebSocket(hub, log))
+
 	// Protected routes with JWT authentication
-	protected := api.Group("/", middleware.JWTAuth(jwtManager))
+	protected := api.Group("/", middleware.JWTAuth(jwtManager), tenant.Middleware(cfg.Tenant))
 
 	// Notification routes (async processing ready)
 	protected.Get("/notifications", notificationHandler.GetNotifications)
 	protected.Get("/notifications/unread/count", notificationHandler.GetUnreadCount)
 	protected.Get("/notifications/:id", notificationHandler.GetNotification)
+	protected.Get("/notifications/:id/deliveries", notificationHandler.GetDeliveries)
 	protected.Post("/notifications", notificationHandler.CreateNotification)
 	protected.Put("/notifications/:id/read", notificationHandler.MarkAsRead)
 	protected.Put("/notifications/read-all", notificationHandler.MarkAllAsRead)
 	protected.Delete("/notifications/:id", notificationHandler.DeleteNotification)
 
+	// Device registration for push notifications
+	protected.Post("/devices", notificationHandler.RegisterDevice)
+	protected.Get("/devices", notificationHandler.GetDevices)
+	protected.Delete("/devices/:id", notificationHandler.DeleteDevice)
+
 	// Start server
-	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, "8086") // Notification service port
+	// Bind before serving so a port of "0" resolves to the actual port in the log
+	listener, err := net.Listen("tcp", cfg.Server.Address())
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", cfg.Server.Address(), err)
+	}
 
 	// Graceful shutdown
 	go func() {
-		if err := app.Listen(addr); err != nil {
+		if err := app.Listener(listener); err != nil {
 			log.Fatalf("Server failed to start: %v", err)
 		}
 	}()
 
-	log.Infof("Notification Service listening on %s", addr)
+	log.Infof("Notification Service listening on %s", listener.Addr())
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -124,6 +323,7 @@ func main() {
 	<-quit
 
 	log.Info("Shutting down Notification Service...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -132,6 +332,15 @@ func main() {
 		log.Errorf("Error during shutdown: %v", err)
 	}
 
+	// Flush spans recorded by the last requests
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		log.Errorf("Error flushing traces: %v", err)
+	}
+	apperrors.Flush(5 * time.Second)
+	if err := security.Close(); err != nil {
+		log.Errorf("Error closing security audit log: %v", err)
+	}
+
 	log.Info("Notification Service stopped")
 }
 
@@ -161,6 +370,7 @@ func errorHandler(c *fiber.Ctx, err error) error {
 	}
 
 	return c.Status(code).JSON(fiber.Map{
-		"error": message,
+		"error":      message,
+		"request_id": middleware.GetRequestID(c),
 	})
 }
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	"github.com/onichange/pos-system/pkg/archive"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// archiveOwners returns, per service, the tables its database archives
+var archiveOwners = map[string]func(db database.Querier) []archive.Table{
+	"inventory-service": func(db database.Querier) []archive.Table {
+		return repository.NewInventoryRepository(db).ArchiveTables()
+	},
+	"notification-service": func(db database.Querier) []archive.Table {
+		return repository.NewNotificationRepository(db).ArchiveTables()
+	},
+	"order-service": func(db database.Querier) []archive.Table {
+		return repository.NewOrderRepository(db, nil).ArchiveTables()
+	},
+}
+
+func runArchive(ctx context.Context, args []string) error {
+	sub, args, err := subcommand("archive", args, "status", "run", "restore")
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("archive "+sub, flag.ExitOnError)
+	from := fs.String("from", "", "First day of the rows to restore, as YYYY-MM-DD (restore)")
+	to := fs.String("to", "", "Day after the last of the rows to restore, as YYYY-MM-DD (restore)")
+	tenantID := fs.String("tenant", "", "Restore only this tenant's rows (restore)")
+	fs.Parse(args)
+
+	if sub == "restore" {
+		if fs.NArg() != 1 {
+			return fmt.Errorf("archive restore takes the table whose rows to restore")
+		}
+		start, err := time.Parse("2006-01-02", *from)
+		if err != nil {
+			return fmt.Errorf("-from: %w", err)
+		}
+		end, err := time.Parse("2006-01-02", *to)
+		if err != nil {
+			return fmt.Errorf("-to: %w", err)
+		}
+		if !end.After(start) {
+			return fmt.Errorf("-to must be after -from")
+		}
+		if *tenantID != "" && !tenant.ValidID(*tenantID) {
+			return fmt.Errorf("invalid tenant ID %q", *tenantID)
+		}
+
+		return withArchiver(ctx, fs.Args(), func(a *archive.Archiver, table string) error {
+			restored, err := a.Restore(ctx, table, start, end, *tenantID)
+			fmt.Printf("%s: restored %d rows\n", table, restored)
+			return err
+		})
+	}
+
+	return withArchiver(ctx, fs.Args(), func(a *archive.Archiver, table string) error {
+		if sub == "run" {
+			moved, err := a.Archive(ctx, table)
+			fmt.Printf("%s: archived %d rows\n", table, moved)
+			return err
+		}
+
+		months, err := a.Status(ctx, table)
+		if err != nil {
+			return err
+		}
+		if len(months) == 0 {
+			fmt.Printf("%s: nothing archived\n", table)
+		}
+		for _, m := range months {
+			fmt.Printf("%-20s %s %10d rows\n", table, m.Month.Format("2006-01"), m.Rows)
+		}
+		return nil
+	})
+}
+
+// withArchiver calls fn with each named table, or every archived table when
+// none is named, and an archiver on its owning service's database
+func withArchiver(ctx context.Context, tables []string, fn func(a *archive.Archiver, table string) error) error {
+	// The tables are known without a database, so only their owners are connected to
+	owners := map[string]string{}
+	for service, archived := range archiveOwners {
+		for _, t := range archived(nil) {
+			owners[t.Name] = service
+		}
+	}
+	if len(tables) == 0 {
+		for table := range owners {
+			tables = append(tables, table)
+		}
+	}
+	sort.Strings(tables)
+
+	byService := map[string][]string{}
+	var services []string
+	for _, table := range tables {
+		service, ok := owners[table]
+		if !ok {
+			return fmt.Errorf("%w: %s", archive.ErrUnknownTable, table)
+		}
+		if byService[service] == nil {
+			services = append(services, service)
+		}
+		byService[service] = append(byService[service], table)
+	}
+
+	for _, service := range services {
+		if err := archiveService(ctx, service, byService[service], fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveService calls fn with tables of a service and an archiver on its
+// database
+func archiveService(ctx context.Context, service string, tables []string, fn func(a *archive.Archiver, table string) error) error {
+	db, cfg, err := connect(ctx, service)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	a := archive.NewArchiver(db.Pool, cfg.Archive, archiveOwners[service](db.Pool)...)
+	for _, table := range tables {
+		if err := fn(a, table); err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/onichange/pos-system/pkg/cache"
+)
+
+func runCache(ctx context.Context, args []string) error {
+	sub, patterns, err := subcommand("cache", args, "invalidate")
+	if err != nil {
+		return err
+	}
+	if len(patterns) == 0 {
+		return fmt.Errorf("cache %s takes one or more key patterns, e.g. 'session:*'", sub)
+	}
+
+	cfg, err := loadConfig(ctx, "api-gateway")
+	if err != nil {
+		return err
+	}
+
+	redisCache, err := cache.NewRedisCache(cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.PoolSize, cfg.Redis.MinIdleConns)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	defer redisCache.Close()
+
+	for _, pattern := range patterns {
+		deleted, err := redisCache.DeletePattern(ctx, pattern)
+		fmt.Printf("%s: deleted %d keys\n", pattern, deleted)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/onichange/pos-system/pkg/messaging"
+)
+
+func runDLQ(ctx context.Context, args []string) error {
+	sub, args, err := subcommand("dlq", args, "list", "redrive")
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("dlq "+sub, flag.ExitOnError)
+	topologyFile := fs.String("topology", "", "Messaging topology file (default messaging.topology_file)")
+	limit := fs.Int("limit", 0, "Messages to move back (redrive); 0 moves every waiting message")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(ctx, "api-gateway")
+	if err != nil {
+		return err
+	}
+
+	client, err := messaging.NewRabbitMQClient(cfg.Messaging.RabbitMQURL, log)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if sub == "redrive" {
+		if fs.NArg() != 1 {
+			return fmt.Errorf("dlq redrive takes the queue whose dead letters to move back")
+		}
+		moved, err := client.RedriveDeadLetters(fs.Arg(0), *limit)
+		fmt.Printf("Moved %d messages back to %s\n", moved, fs.Arg(0))
+		return err
+	}
+
+	path := *topologyFile
+	if path == "" {
+		path = cfg.Messaging.TopologyFile
+	}
+	if path == "" {
+		return fmt.Errorf("no topology file; set -topology or MESSAGING_TOPOLOGY_FILE")
+	}
+	topology, err := messaging.LoadTopology(path)
+	if err != nil {
+		return err
+	}
+
+	queues, err := client.DeadLetterQueues(topology)
+	if err != nil {
+		return err
+	}
+	for _, q := range queues {
+		fmt.Printf("%-40s %8d messages  %d consumers\n", q.DLQ, q.Messages, q.Consumers)
+	}
+	return nil
+}
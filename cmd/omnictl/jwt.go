@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// jwtSecretBytes is the size of generated JWT secrets, twice the HS256 key size
+const jwtSecretBytes = 64
+
+func runJWT(ctx context.Context, args []string) error {
+	_, args, err := subcommand("jwt", args, "rotate")
+	if err != nil {
+		return err
+	}
+	return rotateJWT(ctx, args)
+}
+
+// rotateJWT generates new access and refresh secrets and prints them with
+// the current ones moved to the previous lists, which every service keeps
+// accepting until the tokens they signed expire. Nothing is written; the
+// output goes into the secrets backend or environment of every service.
+func rotateJWT(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("jwt rotate", flag.ExitOnError)
+	format := fs.String("format", "env", "Output format: env (variables) or secret (keys of secrets.jwt_secret_path)")
+	fs.Parse(args)
+
+	if *format != "env" && *format != "secret" {
+		return fmt.Errorf("unknown format %q; want env or secret", *format)
+	}
+
+	cfg, err := loadConfig(ctx, "api-gateway")
+	if err != nil {
+		return err
+	}
+
+	access, err := newJWTSecret()
+	if err != nil {
+		return err
+	}
+	refresh, err := newJWTSecret()
+	if err != nil {
+		return err
+	}
+	previousAccess := append([]string{cfg.JWT.AccessTokenSecret}, cfg.JWT.PreviousAccessTokenSecrets...)
+	previousRefresh := append([]string{cfg.JWT.RefreshTokenSecret}, cfg.JWT.PreviousRefreshTokenSecrets...)
+
+	if *format == "env" {
+		fmt.Printf("JWT_ACCESS_SECRET=%s\n", access)
+		fmt.Printf("JWT_REFRESH_SECRET=%s\n", refresh)
+		fmt.Printf("JWT_PREVIOUS_ACCESS_SECRETS=%s\n", strings.Join(previousAccess, ","))
+		fmt.Printf("JWT_PREVIOUS_REFRESH_SECRETS=%s\n", strings.Join(previousRefresh, ","))
+	} else {
+		fmt.Printf("access_secret=%s\n", access)
+		fmt.Printf("refresh_secret=%s\n", refresh)
+		fmt.Printf("previous_access_secrets=%s\n", strings.Join(previousAccess, ","))
+		fmt.Printf("previous_refresh_secrets=%s\n", strings.Join(previousRefresh, ","))
+	}
+
+	fmt.Fprintf(os.Stderr, "Roll these out to every service; the previous secrets can be dropped after %s, the refresh token expiry.\n",
+		cfg.JWT.RefreshTokenExpiry)
+	return nil
+}
+
+// newJWTSecret generates a random JWT secret
+func newJWTSecret() (string, error) {
+	b := make([]byte, jwtSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
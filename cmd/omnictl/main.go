@@ -0,0 +1,136 @@
+// Command omnictl runs operations tasks against a deployment: migrations,
+// demo data, admin users, JWT secret rotation, dead letter queues, caches
+// and archived rows. It reads the same configuration files and environment variables
+// as the services, resolving each service's own settings where it acts on
+// that service's database.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/secrets"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// command is an omnictl subcommand
+type command struct {
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{
+	"migrate": {"migrate up|down|status|force [flags] [directory...]  Apply, roll back or inspect schema migrations", runMigrate},
+	"seed":    {"seed [flags]                                          Load demo stores, catalog, prices and stock", runSeed},
+	"user":    {"user create-admin [flags]                             Create an admin user, or grant an existing user admin", runUser},
+	"jwt":     {"jwt rotate [flags]                                    Generate new JWT secrets, keeping the current ones as previous", runJWT},
+	"dlq":     {"dlq list | dlq redrive [flags] <queue>                Inspect dead letter queues or move their messages back", runDLQ},
+	"cache":   {"cache invalidate <pattern>...                         Delete Redis keys matching glob patterns", runCache},
+	"archive": {"archive status|run|restore [flags] [table...]         Inspect, run or undo archiving of old rows", runArchive},
+}
+
+// log writes to stderr, leaving stdout to command output
+var log = logger.New("development")
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "omnictl: unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := cmd.run(ctx, flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "omnictl %s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: omnictl <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run omnictl <command> -h for a command's flags.")
+}
+
+// loadConfig loads a service's configuration with its secrets resolved
+func loadConfig(ctx context.Context, service string) (*config.Config, error) {
+	cfg, err := config.LoadService(service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s config: %w", service, err)
+	}
+	if err := secrets.Resolve(ctx, cfg, log); err != nil {
+		return nil, fmt.Errorf("failed to resolve %s secrets: %w", service, err)
+	}
+	return cfg, nil
+}
+
+// connect opens a service's database
+func connect(ctx context.Context, service string) (*database.PostgresDB, *config.Config, error) {
+	cfg, err := loadConfig(ctx, service)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := database.NewPostgresDB(cfg.Database, log)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to %s database: %w", service, err)
+	}
+	return db, cfg, nil
+}
+
+// withTenant returns ctx scoped to the tenant id, or to the configured
+// default tenant when id is empty, for repositories to act in
+func withTenant(ctx context.Context, id string, cfg *config.Config) (context.Context, error) {
+	source := tenant.SourceHeader
+	if id == "" {
+		id, source = cfg.Tenant.Default, tenant.SourceDefault
+	}
+	if id == "" {
+		return nil, errors.New("-tenant is required when no default tenant is configured")
+	}
+	if !tenant.ValidID(id) {
+		return nil, fmt.Errorf("invalid tenant ID %q", id)
+	}
+	return tenant.NewContext(ctx, &tenant.Tenant{ID: id, Source: source}), nil
+}
+
+// subcommand splits args into a subcommand of cmd and its arguments
+func subcommand(cmd string, args []string, names ...string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("missing subcommand; want one of %v", names)
+	}
+	for _, name := range names {
+		if args[0] == name {
+			return name, args[1:], nil
+		}
+	}
+	return "", nil, fmt.Errorf("unknown %s subcommand %q; want one of %v", cmd, args[0], names)
+}
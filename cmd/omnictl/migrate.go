@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/onichange/pos-system/migrations"
+	"github.com/onichange/pos-system/pkg/database"
+)
+
+func runMigrate(ctx context.Context, args []string) error {
+	sub, args, err := subcommand("migrate", args, migrations.Subcommands...)
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("migrate "+sub, flag.ExitOnError)
+	root := fs.String("dir", "migrations", "Directory holding a migrations directory per service")
+	steps := fs.Int("steps", 1, "Migrations to roll back (down)")
+	version := fs.Int64("version", -1, "Last version to record as applied (force); 0 records none")
+	fs.Parse(args)
+
+	dirs := fs.Args()
+	switch sub {
+	case "down", "force":
+		if len(dirs) != 1 {
+			return fmt.Errorf("migrate %s takes exactly one directory", sub)
+		}
+	}
+	if sub == "force" && *version < 0 {
+		return errors.New("migrate force needs -version")
+	}
+	if len(dirs) == 0 {
+		entries, err := os.ReadDir(*root)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				dirs = append(dirs, entry.Name())
+			}
+		}
+		sort.Strings(dirs)
+	}
+
+	source := os.DirFS(*root)
+	for _, dir := range dirs {
+		loaded, err := database.LoadMigrations(source, dir)
+		if err != nil {
+			return err
+		}
+		for _, owner := range migrations.Owners(dir) {
+			if err := migrateDir(ctx, sub, dir, owner, loaded, *steps, *version); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateDir runs a migrate subcommand on one migrations directory, against
+// the database of one of its owning services
+func migrateDir(ctx context.Context, sub, dir, owner string, loaded []database.Migration, steps int, version int64) error {
+	db, _, err := connect(ctx, owner)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Directories migrating several databases say which each line is about
+	label := dir
+	if len(migrations.Owners(dir)) > 1 {
+		label = dir + "@" + owner
+	}
+	return migrations.Exec(ctx, sub, database.NewMigrator(db.Pool, dir), label, loaded, steps, version)
+}
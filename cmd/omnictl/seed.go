@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/onichange/pos-system/internal/domain/catalog"
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/domain/store"
+	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// seedNamespace derives the IDs of demo data, so seeding again finds what an
+// earlier run created instead of adding copies
+var seedNamespace = uuid.MustParse("6f1c2b0e-3d4a-4f5e-9a8b-7c6d5e4f3a2b")
+
+// seedID returns the ID of a piece of demo data in the tenant of ctx. IDs
+// are unique across tenants, so each tenant's demo data gets its own.
+func seedID(ctx context.Context, kind, key string) uuid.UUID {
+	return uuid.NewSHA1(seedNamespace, []byte(tenant.IDFromContext(ctx)+":"+kind+":"+key))
+}
+
+// demoVariant is a demo product variant with its stock
+type demoVariant struct {
+	sku      string
+	barcode  string
+	name     string
+	price    float64
+	cost     float64
+	quantity int
+}
+
+// demoProduct is a demo product in a demo category
+type demoProduct struct {
+	category string
+	name     string
+	brand    string
+	variants []demoVariant
+}
+
+var demoCategories = []struct{ slug, name string }{
+	{"beverages", "Beverages"},
+	{"snacks", "Snacks"},
+	{"household", "Household"},
+}
+
+var demoProducts = []demoProduct{
+	{"beverages", "Cold Brew Coffee", "Omni Roasters", []demoVariant{
+		{"DEMO-CBC-250", "8930000000011", "250 ml", 2.50, 1.10, 120},
+		{"DEMO-CBC-500", "8930000000028", "500 ml", 4.20, 1.90, 80},
+	}},
+	{"beverages", "Sparkling Water", "Clearwell", []demoVariant{
+		{"DEMO-SPW-330", "8930000000035", "330 ml", 1.20, 0.40, 200},
+	}},
+	{"snacks", "Sea Salt Crisps", "Crunchline", []demoVariant{
+		{"DEMO-SSC-150", "8930000000042", "150 g", 1.80, 0.70, 150},
+	}},
+	{"snacks", "Dark Chocolate Bar", "Cacao & Co", []demoVariant{
+		{"DEMO-DCB-100", "8930000000059", "100 g", 3.10, 1.30, 90},
+	}},
+	{"household", "Dish Soap", "Brightly", []demoVariant{
+		{"DEMO-DSP-500", "8930000000066", "500 ml", 2.90, 1.20, 60},
+	}},
+}
+
+// runSeed loads a demo store with a catalog, store prices and stock into a
+// tenant. It can be run again; what exists is left as it is.
+func runSeed(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	storeCode := fs.String("store", "DEMO-001", "Code of the demo store")
+	currency := fs.String("currency", "USD", "Currency of the demo prices")
+	tenantID := fs.String("tenant", "", "Tenant to seed (defaults to the configured default tenant)")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(ctx, "store-service")
+	if err != nil {
+		return err
+	}
+	ctx, err = withTenant(ctx, *tenantID, cfg)
+	if err != nil {
+		return err
+	}
+
+	storeID, err := seedStore(ctx, *storeCode)
+	if err != nil {
+		return err
+	}
+	if err := seedCatalog(ctx, storeID, *currency); err != nil {
+		return err
+	}
+	return seedStock(ctx, storeID)
+}
+
+// seedStore creates the demo store unless one with its code exists, and
+// returns its ID
+func seedStore(ctx context.Context, code string) (uuid.UUID, error) {
+	db, _, err := connect(ctx, "store-service")
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer db.Close()
+	storeRepo := repository.NewStoreRepository(db.Pool)
+
+	existing, err := storeRepo.GetByCode(ctx, code)
+	if err == nil {
+		fmt.Printf("Store %s exists (%s)\n", code, existing.ID)
+		return existing.ID, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, err
+	}
+
+	s := &store.Store{
+		ID:         seedID(ctx, "store", code),
+		Name:       "Demo Store",
+		Code:       code,
+		Latitude:   10.7769,
+		Longitude:  106.7009,
+		Address:    "1 Demo Street",
+		City:       "Ho Chi Minh City",
+		State:      "HCM",
+		PostalCode: "700000",
+		Country:    "VN",
+		Email:      "demo-store@example.com",
+		Status:     store.StatusActive,
+	}
+	if err := storeRepo.Create(ctx, s); err != nil {
+		return uuid.Nil, err
+	}
+	fmt.Printf("Created store %s (%s)\n", code, s.ID)
+	return s.ID, nil
+}
+
+// seedCatalog creates the demo categories and products, and prices their
+// variants in the demo store
+func seedCatalog(ctx context.Context, storeID uuid.UUID, currency string) error {
+	db, _, err := connect(ctx, "catalog-service")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	catalogRepo := repository.NewCatalogRepository(db.Pool)
+
+	for _, c := range demoCategories {
+		err := catalogRepo.CreateCategory(ctx, &catalog.Category{
+			ID:   seedID(ctx, "category", c.slug),
+			Name: c.name,
+			Slug: c.slug,
+		})
+		if err != nil && !errors.Is(err, catalog.ErrDuplicate) {
+			return err
+		}
+	}
+
+	created := 0
+	for _, dp := range demoProducts {
+		categoryID := seedID(ctx, "category", dp.category)
+		p := &catalog.Product{
+			ID:         seedID(ctx, "product", dp.name),
+			CategoryID: &categoryID,
+			Name:       dp.name,
+			Brand:      dp.brand,
+			Status:     catalog.StatusActive,
+		}
+		for _, dv := range dp.variants {
+			p.Variants = append(p.Variants, &catalog.Variant{
+				ID:        seedID(ctx, "variant", dv.sku),
+				SKU:       dv.sku,
+				Barcode:   dv.barcode,
+				Name:      dv.name,
+				BasePrice: dv.price,
+				Currency:  currency,
+			})
+		}
+		err := catalogRepo.CreateProduct(ctx, p)
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, catalog.ErrDuplicate):
+			return err
+		}
+
+		for _, dv := range dp.variants {
+			err := catalogRepo.SetPrice(ctx, &catalog.Price{
+				StoreID:   storeID,
+				VariantID: seedID(ctx, "variant", dv.sku),
+				Price:     dv.price,
+				Currency:  currency,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Printf("Created %d of %d demo products; prices set in the demo store\n", created, len(demoProducts))
+	return nil
+}
+
+// seedStock receives the demo stock into the demo store. Each receipt has a
+// fixed source, so it is posted once however often seeding runs.
+func seedStock(ctx context.Context, storeID uuid.UUID) error {
+	db, _, err := connect(ctx, "inventory-service")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	inventoryRepo := repository.NewInventoryRepository(db.Pool)
+
+	received := 0
+	for _, dp := range demoProducts {
+		for _, dv := range dp.variants {
+			_, err := inventoryRepo.ReceiveStock(ctx, &inventory.StockReceipt{
+				ProductID:  seedID(ctx, "variant", dv.sku),
+				StoreID:    storeID,
+				Quantity:   dv.quantity,
+				UnitCost:   dv.cost,
+				SourceType: "seed",
+				SourceID:   seedID(ctx, "stock", storeID.String()+":"+dv.sku),
+				Reason:     "Demo stock",
+			})
+			switch {
+			case err == nil:
+				received++
+			case !errors.Is(err, inventory.ErrAlreadyReceived):
+				return err
+			}
+		}
+	}
+
+	fmt.Printf("Received stock for %d variants\n", received)
+	return nil
+}
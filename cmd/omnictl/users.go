@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/user"
+	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	"github.com/onichange/pos-system/pkg/encryption"
+	"github.com/onichange/pos-system/pkg/secrets"
+)
+
+// minPasswordLength matches the registration rule of user-service
+const minPasswordLength = 8
+
+func runUser(ctx context.Context, args []string) error {
+	_, args, err := subcommand("user", args, "create-admin")
+	if err != nil {
+		return err
+	}
+	return createAdmin(ctx, args)
+}
+
+// createAdmin creates a user with the admin role, or grants it to the user
+// already registered with the email. Passwords are read from stdin so they
+// stay out of shell history and process lists.
+func createAdmin(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("user create-admin", flag.ExitOnError)
+	email := fs.String("email", "", "Email of the admin (required)")
+	firstName := fs.String("first-name", "", "First name")
+	lastName := fs.String("last-name", "", "Last name")
+	tenantID := fs.String("tenant", "", "Tenant of the admin (defaults to the configured default tenant)")
+	fs.Parse(args)
+
+	if *email == "" {
+		return errors.New("-email is required")
+	}
+
+	db, cfg, err := connect(ctx, "user-service")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, err = withTenant(ctx, *tenantID, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Users are stored as user-service stores them, PII encrypted
+	keyring, err := secrets.NewKeyring(cfg.Encryption, cfg.Secrets)
+	if err != nil {
+		return fmt.Errorf("failed to load encryption keys: %w", err)
+	}
+	blindIndex, err := encryption.ParseBlindIndexKey(cfg.Encryption.BlindIndexKey)
+	if err != nil {
+		return fmt.Errorf("failed to load blind index key: %w", err)
+	}
+	envelope := encryption.NewKeyringEnvelope(keyring)
+	if envelope != nil && blindIndex == nil {
+		return errors.New("encrypted emails need a blind index key to be looked up; set encryption.blind_index_key")
+	}
+	userRepo := repository.NewUserRepository(db.Pool, envelope, blindIndex)
+
+	exists, err := userRepo.ExistsByEmail(ctx, *email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		u, err := userRepo.GetByEmail(ctx, *email)
+		if err != nil {
+			return err
+		}
+		if hasRole(u.Roles, "admin") {
+			fmt.Printf("User %s (%s) is already an admin\n", u.ID, *email)
+			return nil
+		}
+		if err := userRepo.SetRoles(ctx, u.ID, append(u.Roles, "admin")); err != nil {
+			return err
+		}
+		fmt.Printf("Granted admin to user %s (%s); it applies from their next login\n", u.ID, *email)
+		return nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+
+	passwordHash, err := encryption.NewPasswordHasher(cfg.Security.PasswordHashing).Hash(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	u := &user.User{
+		ID:           uuid.New(),
+		Email:        *email,
+		PasswordHash: passwordHash,
+		FirstName:    *firstName,
+		LastName:     *lastName,
+		Roles:        []string{"user", "admin"},
+	}
+	if err := userRepo.Create(ctx, u); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created admin user %s (%s)\n", u.ID, *email)
+	return nil
+}
+
+// hasRole reports whether roles include role
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
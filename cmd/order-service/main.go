@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,21 +13,54 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/onichange/pos-system/internal/infrastructure/catalogclient"
+	"github.com/onichange/pos-system/internal/infrastructure/inventoryclient"
+	"github.com/onichange/pos-system/internal/infrastructure/loyaltyclient"
+	"github.com/onichange/pos-system/internal/infrastructure/outbox"
+	"github.com/onichange/pos-system/internal/infrastructure/paymentclient"
+	"github.com/onichange/pos-system/internal/infrastructure/promotionclient"
 	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	"github.com/onichange/pos-system/internal/infrastructure/shiftclient"
+	"github.com/onichange/pos-system/internal/infrastructure/taxclient"
+	"github.com/onichange/pos-system/internal/infrastructure/unitofwork"
+	ordergrpc "github.com/onichange/pos-system/internal/interfaces/grpc/order"
 	"github.com/onichange/pos-system/internal/interfaces/http/order"
+	"github.com/onichange/pos-system/migrations"
+	debugapi "github.com/onichange/pos-system/pkg/api"
+	"github.com/onichange/pos-system/pkg/archive"
+	"github.com/onichange/pos-system/pkg/audit/security"
 	"github.com/onichange/pos-system/pkg/auth"
 	"github.com/onichange/pos-system/pkg/cache"
 	"github.com/onichange/pos-system/pkg/config"
 	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/encryption"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	appgrpc "github.com/onichange/pos-system/pkg/grpc"
+	"github.com/onichange/pos-system/pkg/i18n"
 	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+	"github.com/onichange/pos-system/pkg/messaging"
 	"github.com/onichange/pos-system/pkg/metrics"
 	"github.com/onichange/pos-system/pkg/middleware"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/saga"
+	"github.com/onichange/pos-system/pkg/secrets"
+	"github.com/onichange/pos-system/pkg/slo"
+	"github.com/onichange/pos-system/pkg/tenant"
+	"github.com/onichange/pos-system/pkg/tracing"
+	"github.com/onichange/pos-system/pkg/webhook"
+	orderpb "github.com/onichange/pos-system/proto/order"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
+	validateConfig := flag.Bool("validate-config", false, "Validate configuration, print it with secrets masked, and exit")
+	flag.Parse()
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.LoadService("order-service")
 	if err != nil {
 		fmt.Printf("Failed to load config: %v\n", err)
 		os.Exit(1)
@@ -35,6 +70,43 @@ func main() {
 	log := logger.New(cfg.Server.Environment)
 	log.Info("Starting Order Service...")
 
+	// Resolve secrets from the configured backend (Vault, AWS Secrets Manager)
+	if err := secrets.Resolve(context.Background(), cfg, log); err != nil {
+		log.Fatalf("Failed to resolve secrets: %v", err)
+	}
+
+	// Print the effective configuration and stop when only validating
+	if *validateConfig {
+		effective, err := cfg.EffectiveYAML()
+		if err != nil {
+			log.Fatalf("Failed to render configuration: %v", err)
+		}
+		fmt.Print(string(effective))
+		return
+	}
+
+	// Initialize tracing; spans are exported only when an exporter is configured
+	tracerProvider, err := tracing.NewTracerProvider(cfg.ServiceName, cfg.Server.Environment, cfg.Tracing, log)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// Report panics and server errors when a DSN is configured
+	if err := apperrors.Init(cfg.ServiceName, cfg.Server.Environment, cfg.ErrorReporting); err != nil {
+		log.Warnf("Error reporting disabled: %v", err)
+	}
+
+	// Initialize the security audit log, kept apart from application logs
+	if err := security.Init(cfg.ServiceName, cfg.Audit.Security); err != nil {
+		log.Fatalf("Failed to open security audit log: %v", err)
+	}
+
+	// Cap concurrent calls to each dependency so a slow one fails fast
+	bulkheads := performance.NewBulkheads(cfg.Bulkheads)
+
+	// Share one retry budget across the process so an outage is not amplified
+	retryBudget := performance.NewRetryBudget(cfg.Retry)
+
 	// Initialize database
 	db, err := database.NewPostgresDB(cfg.Database, log)
 	if err != nil {
@@ -42,10 +114,34 @@ func main() {
 	}
 	defer db.Close()
 
-	// Initialize Redis cache (for future caching)
-	_, err = cache.NewRedisCache(cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.PoolSize, cfg.Redis.MinIdleConns)
+	// "<service> migrate ..." migrates the service's database and exits;
+	// with migrate_on_start, pending migrations are applied before serving
+	if flag.Arg(0) == "migrate" {
+		if err := migrations.Run(context.Background(), db.Pool, cfg.ServiceName, flag.Args()[1:]); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		return
+	}
+	if cfg.Database.MigrateOnStart {
+		if err := migrations.Up(context.Background(), db.Pool, cfg.ServiceName, log); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+
+	// Initialize Redis cache (idempotency keys, carts)
+	var redisClient *redis.Client
+	redisCache, err := cache.NewRedisCache(cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.PoolSize, cfg.Redis.MinIdleConns)
 	if err != nil {
 		log.Warnf("Failed to connect to Redis: %v (continuing without cache)", err)
+	} else {
+		defer redisCache.Close()
+		redisCache.UseBulkhead(bulkheads.Redis)
+		redisClient = redisCache.GetClient()
+	}
+
+	// Declare messaging topology (exchanges, queues, topics) from config
+	if _, err := messaging.SetupTopology(cfg.Messaging, log); err != nil {
+		log.Warnf("Failed to apply messaging topology: %v", err)
 	}
 
 	// Initialize JWT manager
@@ -55,13 +151,107 @@ func main() {
 		cfg.JWT.AccessTokenExpiry,
 		cfg.JWT.RefreshTokenExpiry,
 		cfg.JWT.Issuer,
+	).WithPreviousSecrets(cfg.JWT.PreviousAccessTokenSecrets, cfg.JWT.PreviousRefreshTokenSecrets)
+
+	// Encrypt PII fields under the configured master key versions
+	keyring, err := secrets.NewKeyring(cfg.Encryption, cfg.Secrets)
+	if err != nil {
+		log.Fatalf("Failed to load encryption keys: %v", err)
+	}
+	if keyring == nil {
+		log.Warn("No encryption key configured; PII fields are stored unencrypted")
+	}
+	envelope := encryption.NewKeyringEnvelope(keyring)
+
+	// Initialize repositories. Each query attempt holds a bulkhead slot, and
+	// transient failures are retried.
+	queries := database.WithRetry(
+		database.WithBulkhead(db.Pool, bulkheads.Database),
+		performance.NewRetryPolicy("database", cfg.Retry, retryBudget),
 	)
+	var orderRepo repository.OrderStore = repository.NewOrderRepository(queries, envelope)
+	if cfg.Orders.Store == "events" {
+		// Record every change of an order, writing in transactions on the pool
+		orderRepo = repository.NewEventSourcedOrderRepository(queries, db.Pool, envelope, cfg.Orders.SnapshotEvery)
+	}
+
+	// Move stored PII onto the current key version in the background
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go encryption.RunReencryption(jobsCtx, envelope, orderRepo.EncryptedColumns(),
+		cfg.Encryption.ReencryptBatchSize, cfg.Encryption.ReencryptInterval, log)
+
+	// Move orders past their retention to the archive schema
+	go archive.NewArchiver(db.Pool, cfg.Archive, orderRepo.ArchiveTables()...).Run(jobsCtx, log)
+
+	// Background jobs run on a bounded pool that drains on shutdown
+	jobs := performance.NewNamedWorkerPool("order-jobs", cfg.Workers)
+	jobs.Start()
 
-	// Initialize repositories
-	orderRepo := repository.NewOrderRepository(db.Pool)
+	// Price order items from the product catalog, discount them by running
+	// promotions, redeem loyalty points, and check register shifts are open
+	catalogConn, err := appgrpc.Dial(cfg.Services.CatalogGRPCTarget, cfg.GRPC, jwtManager)
+	if err != nil {
+		log.Fatalf("Failed to create catalog client: %v", err)
+	}
+	defer catalogConn.Close()
+	catalog := catalogclient.NewClient(catalogConn)
+	promotions := promotionclient.NewClient(cfg.Services.PromotionServiceURL, cfg.Proxy)
+	loyalty := loyaltyclient.NewClient(cfg.Services.LoyaltyServiceURL, cfg.Proxy)
+	shifts := shiftclient.NewClient(cfg.Services.ShiftServiceURL, cfg.Proxy)
+	taxes := taxclient.NewClient(cfg.Services.TaxServiceURL, cfg.Proxy)
+
+	// Publish order events for other services, such as loyalty accrual,
+	// through the outbox, recording each in the transaction of the change it
+	// reports, so they wait in the database while RabbitMQ is unreachable
+	uow := unitofwork.New(db.Pool, envelope)
+	if cfg.Orders.Store == "events" {
+		uow.WithEventSourcedOrders(cfg.Orders.SnapshotEvery)
+	}
+	inTx := func(ctx context.Context, fn func(tx *order.Tx) error) error {
+		return uow.Do(ctx, func(tx *unitofwork.Tx) error {
+			return fn(&order.Tx{Orders: tx.Orders(), Events: tx.Events()})
+		})
+	}
+	go outbox.NewRelay(db.Pool, outbox.DialRabbitMQ(cfg.Messaging.RabbitMQURL, log), cfg.Outbox).Run(jobsCtx, log)
+
+	// Consume payment events for checkouts
+	broker, err := messagequeue.NewRabbitMQ(cfg.Messaging.RabbitMQURL, log)
+	if err != nil {
+		log.Warnf("Failed to connect to RabbitMQ: %v (checkouts wait for payments by polling)", err)
+	} else {
+		defer broker.Close()
+		broker.UseDefaultTenant(cfg.Tenant.Default)
+	}
 
-	// Initialize handlers
-	orderHandler := order.NewHandler(orderRepo)
+	// Initialize handlers. Order events also go to the configured webhooks,
+	// signed with every webhook secret.
+	orderHandler := order.NewHandler(orderRepo, catalog, promotions, taxes, loyalty, shifts, inTx, webhook.NewSender(cfg.Webhooks), jobs)
+
+	// Check out orders as a saga reserving stock in inventory and charging
+	// the payment service, resumed by any instance should this one stop
+	orchestrator := saga.NewOrchestrator(saga.NewPostgresStore(queries), cfg.Saga)
+	stock := inventoryclient.NewReservationClient(cfg.Services.InventoryServiceURL, cfg.Proxy)
+	payments := paymentclient.NewClient(cfg.Services.PaymentServiceURL, cfg.Proxy, jwtManager)
+	if err := orderHandler.EnableCheckout(orchestrator, stock, payments, cfg.Orders); err != nil {
+		log.Fatalf("Failed to register checkout saga: %v", err)
+	}
+	go orchestrator.Run(jobsCtx)
+
+	// Keep carts in Redis; without it cart routes answer 501
+	if redisClient != nil {
+		orderHandler.EnableCarts(repository.NewCartRepository(redisClient, cfg.Orders.CartTTL))
+	}
+
+	// Wake checkouts waiting on payments once they settle; without a broker
+	// they find out by polling the payment service
+	if broker != nil {
+		for _, queue := range cfg.Service.Queues {
+			if err := broker.ConsumeContext(queue, cfg.ServiceName, orderHandler.HandlePaymentEvent); err != nil {
+				log.Fatalf("Failed to consume %s: %v", queue, err)
+			}
+		}
+	}
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -72,12 +262,26 @@ func main() {
 	})
 
 	// Global middleware
-	app.Use(recover.New())
+	app.Use(recover.New(recover.Config{
+		EnableStackTrace:  true,
+		StackTraceHandler: apperrors.PanicHandler,
+	}))
+	app.Use(middleware.RequestID())
+	app.Use(middleware.TracingMiddleware())
+	app.Use(apperrors.Middleware())
 	app.Use(compress.New(compress.Config{
 		Level: compress.LevelBestCompression,
 	}))
+	app.Use(middleware.BodyLogger(log, cfg.Logging)) // Inside compress so bodies are logged uncompressed
+	app.Use(i18n.Middleware())
 	app.Use(middleware.SecurityHeaders())
 	app.Use(middleware.RequestSizeLimit(cfg.Security.MaxRequestSize))
+	app.Use(middleware.PrometheusMetrics(metrics.NewRED(cfg.ServiceName, cfg.Metrics.DurationBuckets, cfg.Metrics.Exemplars)))
+
+	// Count requests against the service level objectives
+	sloTracker := slo.NewTracker(cfg.ServiceName, cfg.SLO)
+	prometheus.MustRegister(sloTracker)
+	app.Use(middleware.SLOTracking(sloTracker))
 
 	if cfg.Security.EnableCORS {
 		app.Use(middleware.CORSMiddleware(cfg.Security.CORSOrigins))
@@ -86,34 +290,81 @@ func main() {
 	// Health check endpoints
 	app.Get("/health", healthCheck)
 	app.Get("/ready", readinessCheck)
+	app.Get("/slo", sloTracker.Handler())
 
 	// Prometheus metrics endpoint
 	app.Get("/metrics", metrics.FiberMetricsHandler())
 
+	// Effective configuration for debugging (admins only)
+	debugapi.SetupDebugConfig(app, jwtManager, func() *config.Config { return cfg })
+
 	// API routes
 	api := app.Group("/api/v1")
 
-	// Protected routes with JWT authentication
-	protected := api.Group("/", middleware.JWTAuth(jwtManager))
+	// Protected routes with JWT authentication, scoped to the caller's tenant
+	protected := api.Group("/", middleware.JWTAuth(jwtManager), tenant.Middleware(cfg.Tenant))
 
 	// Order routes
 	protected.Get("/orders", orderHandler.GetOrders)
+	protected.Get("/orders/export", middleware.RequireRole("admin"), orderHandler.Export) // Accounting exports of every order
 	protected.Get("/orders/:id", orderHandler.GetOrderByID)
-	protected.Post("/orders", orderHandler.CreateOrder)
+	protected.Post("/orders", middleware.Idempotency(redisClient, middleware.DefaultIdempotencyConfig()), orderHandler.CreateOrder)
 	protected.Put("/orders/:id", orderHandler.UpdateOrder)
 	protected.Delete("/orders/:id", orderHandler.DeleteOrder)
+	protected.Patch("/orders/:id/status", middleware.RequireRole("admin"), orderHandler.UpdateOrderStatus)
+	protected.Put("/orders/:id/status", middleware.RequireRole("admin"), orderHandler.UpdateOrderStatus)
+	protected.Get("/orders/:id/status/history", middleware.RequireRole("admin"), orderHandler.GetOrderStatusHistory)
+	protected.Get("/orders/:id/history", middleware.RequireRole("admin"), orderHandler.GetOrderHistory)
+	protected.Post("/orders/:id/checkout", middleware.Idempotency(redisClient, middleware.DefaultIdempotencyConfig()), orderHandler.CheckoutOrder)
+
+	// Cart routes
+	protected.Post("/carts", orderHandler.CreateCart)
+	protected.Get("/carts/:id", orderHandler.GetCart)
+	protected.Delete("/carts/:id", orderHandler.DeleteCart)
+	protected.Post("/carts/:id/items", orderHandler.AddCartItem)
+	protected.Put("/carts/:id/items/:productId", orderHandler.UpdateCartItem)
+	protected.Delete("/carts/:id/items/:productId", orderHandler.RemoveCartItem)
+	protected.Put("/carts/:id/discount", orderHandler.ApplyCartDiscount)
+	protected.Delete("/carts/:id/discount", orderHandler.RemoveCartDiscount)
+	protected.Post("/carts/:id/checkout", middleware.Idempotency(redisClient, middleware.DefaultIdempotencyConfig()), orderHandler.CheckoutCart)
+
+	// Internal routes for other services, scoped to the tenant they forward;
+	// the gateway does not proxy them
+	internal := app.Group("/internal/v1", tenant.Middleware(cfg.Tenant))
+	internal.Get("/orders/:id", orderHandler.GetOrderInternal)
+	internal.Post("/orders/offline", orderHandler.ImportOfflineOrder)
+	internal.Get("/stores/:storeId/export", orderHandler.ExportStore)
+
+	// Internal gRPC API for the gateway and other services
+	var grpcServer *appgrpc.Server
+	if cfg.Service.GRPCPort != "" {
+		grpcServer, err = appgrpc.NewServer(net.JoinHostPort(cfg.Server.Host, cfg.Service.GRPCPort), cfg.GRPC, cfg.Tenant, jwtManager, log)
+		if err != nil {
+			log.Fatalf("Failed to create gRPC server: %v", err)
+		}
+		orderpb.RegisterOrderServiceServer(grpcServer.GetServer(), ordergrpc.NewServer(orderRepo))
+		go func() {
+			if err := grpcServer.Start(); err != nil {
+				log.Fatalf("gRPC server failed: %v", err)
+			}
+		}()
+	}
 
 	// Start server
-	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, "8081") // Order service port
+	// Bind before serving so a port of "0" resolves to the actual port in the log
+	listener, err := net.Listen("tcp", cfg.Server.Address())
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", cfg.Server.Address(), err)
+	}
 
 	// Graceful shutdown
 	go func() {
-		if err := app.Listen(addr); err != nil {
+		if err := app.Listener(listener); err != nil {
 			log.Fatalf("Server failed to start: %v", err)
 		}
 	}()
 
-	log.Infof("Order Service listening on %s", addr)
+	log.Infof("Order Service listening on %s", listener.Addr())
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -121,6 +372,7 @@ func main() {
 	<-quit
 
 	log.Info("Shutting down Order Service...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -128,6 +380,25 @@ func main() {
 	if err := app.ShutdownWithContext(ctx); err != nil {
 		log.Errorf("Error during shutdown: %v", err)
 	}
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+
+	// Let webhooks queued by the last requests go out
+	drainCtx, drainCancel := context.WithTimeout(ctx, cfg.Workers.DrainTimeout)
+	if err := jobs.Shutdown(drainCtx); err != nil {
+		log.Errorf("Background jobs cut short: %v", err)
+	}
+	drainCancel()
+
+	// Flush spans recorded by the last requests
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		log.Errorf("Error flushing traces: %v", err)
+	}
+	apperrors.Flush(5 * time.Second)
+	if err := security.Close(); err != nil {
+		log.Errorf("Error closing security audit log: %v", err)
+	}
 
 	log.Info("Order Service stopped")
 }
@@ -158,6 +429,7 @@ func errorHandler(c *fiber.Ctx, err error) error {
 	}
 
 	return c.Status(code).JSON(fiber.Map{
-		"error": message,
+		"error":      message,
+		"request_id": middleware.GetRequestID(c),
 	})
 }
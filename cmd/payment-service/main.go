@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,21 +13,43 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/onichange/pos-system/internal/infrastructure/outbox"
 	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	"github.com/onichange/pos-system/internal/infrastructure/unitofwork"
+	paymentgrpc "github.com/onichange/pos-system/internal/interfaces/grpc/payment"
 	"github.com/onichange/pos-system/internal/interfaces/http/payment"
+	"github.com/onichange/pos-system/migrations"
+	debugapi "github.com/onichange/pos-system/pkg/api"
+	"github.com/onichange/pos-system/pkg/audit/security"
 	"github.com/onichange/pos-system/pkg/auth"
 	"github.com/onichange/pos-system/pkg/cache"
 	"github.com/onichange/pos-system/pkg/config"
 	"github.com/onichange/pos-system/pkg/database"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	appgrpc "github.com/onichange/pos-system/pkg/grpc"
+	"github.com/onichange/pos-system/pkg/i18n"
 	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messaging"
 	"github.com/onichange/pos-system/pkg/metrics"
 	"github.com/onichange/pos-system/pkg/middleware"
+	"github.com/onichange/pos-system/pkg/payments/provider"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/secrets"
+	"github.com/onichange/pos-system/pkg/slo"
+	"github.com/onichange/pos-system/pkg/tenant"
+	"github.com/onichange/pos-system/pkg/tracing"
+	paymentpb "github.com/onichange/pos-system/proto/payment"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
+	validateConfig := flag.Bool("validate-config", false, "Validate configuration, print it with secrets masked, and exit")
+	flag.Parse()
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.LoadService("payment-service")
 	if err != nil {
 		fmt.Printf("Failed to load config: %v\n", err)
 		os.Exit(1)
@@ -35,6 +59,46 @@ func main() {
 	log := logger.New(cfg.Server.Environment)
 	log.Info("Starting Payment Service...")
 
+	// Resolve secrets from the configured backend (Vault, AWS Secrets Manager)
+	if err := secrets.Resolve(context.Background(), cfg, log); err != nil {
+		log.Fatalf("Failed to resolve secrets: %v", err)
+	}
+
+	// Print the effective configuration and stop when only validating
+	if *validateConfig {
+		effective, err := cfg.EffectiveYAML()
+		if err != nil {
+			log.Fatalf("Failed to render configuration: %v", err)
+		}
+		fmt.Print(string(effective))
+		return
+	}
+
+	// Initialize tracing; spans are exported only when an exporter is configured
+	tracerProvider, err := tracing.NewTracerProvider(cfg.ServiceName, cfg.Server.Environment, cfg.Tracing, log)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// Report panics and server errors when a DSN is configured
+	if err := apperrors.Init(cfg.ServiceName, cfg.Server.Environment, cfg.ErrorReporting); err != nil {
+		log.Warnf("Error reporting disabled: %v", err)
+	}
+
+	// Initialize the security audit log, kept apart from application logs
+	if err := security.Init(cfg.ServiceName, cfg.Audit.Security); err != nil {
+		log.Fatalf("Failed to open security audit log: %v", err)
+	}
+
+	// Cap concurrent calls to each dependency so a slow one fails fast
+	bulkheads := performance.NewBulkheads(cfg.Bulkheads)
+
+	// Share one retry budget across the process so an outage is not amplified
+	retryBudget := performance.NewRetryBudget(cfg.Retry)
+
+	// Keep outbound provider calls within the provider's rate limit
+	providerLimit := performance.NewDependencyLimiter(performance.LimitProvider, cfg.RateLimits.Provider)
+
 	// Initialize database
 	db, err := database.NewPostgresDB(cfg.Database, log)
 	if err != nil {
@@ -42,10 +106,34 @@ func main() {
 	}
 	defer db.Close()
 
-	// Initialize Redis cache
-	_, err = cache.NewRedisCache(cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.PoolSize, cfg.Redis.MinIdleConns)
+	// "<service> migrate ..." migrates the service's database and exits;
+	// with migrate_on_start, pending migrations are applied before serving
+	if flag.Arg(0) == "migrate" {
+		if err := migrations.Run(context.Background(), db.Pool, cfg.ServiceName, flag.Args()[1:]); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		return
+	}
+	if cfg.Database.MigrateOnStart {
+		if err := migrations.Up(context.Background(), db.Pool, cfg.ServiceName, log); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+
+	// Initialize Redis cache (idempotency keys)
+	var redisClient *redis.Client
+	redisCache, err := cache.NewRedisCache(cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.PoolSize, cfg.Redis.MinIdleConns)
 	if err != nil {
 		log.Warnf("Failed to connect to Redis: %v (continuing without cache)", err)
+	} else {
+		defer redisCache.Close()
+		redisCache.UseBulkhead(bulkheads.Redis)
+		redisClient = redisCache.GetClient()
+	}
+
+	// Declare messaging topology (exchanges, queues, topics) from config
+	if _, err := messaging.SetupTopology(cfg.Messaging, log); err != nil {
+		log.Warnf("Failed to apply messaging topology: %v", err)
 	}
 
 	// Initialize JWT manager
@@ -55,13 +143,51 @@ func main() {
 		cfg.JWT.AccessTokenExpiry,
 		cfg.JWT.RefreshTokenExpiry,
 		cfg.JWT.Issuer,
+	).WithPreviousSecrets(cfg.JWT.PreviousAccessTokenSecrets, cfg.JWT.PreviousRefreshTokenSecrets)
+
+	// Initialize repositories. Each query attempt holds a bulkhead slot, and
+	// transient failures are retried.
+	queries := database.WithRetry(
+		database.WithBulkhead(db.Pool, bulkheads.Database),
+		performance.NewRetryPolicy("database", cfg.Retry, retryBudget),
 	)
+	paymentRepo := repository.NewPaymentRepository(queries)
 
-	// Initialize repositories
-	paymentRepo := repository.NewPaymentRepository(db.Pool)
+	// Charge through the configured payment provider
+	var payments provider.Provider
+	switch cfg.Payments.Provider {
+	case "stripe":
+		if cfg.Payments.Stripe.SecretKey == "" {
+			log.Fatalf("STRIPE_SECRET_KEY is required for the stripe payment provider")
+		}
+		if len(cfg.Payments.Stripe.WebhookSecrets) == 0 {
+			log.Warnf("No Stripe webhook secrets configured (payments needing 3D Secure are not settled)")
+		}
+		payments = provider.NewStripe(cfg.Payments.Stripe)
+	default:
+		log.Warnf("Using the simulated payment provider (charges move no money)")
+		payments = provider.NewSimulated()
+	}
+
+	// Publish payment events for other services, such as analytics and
+	// checkouts, through the outbox, recording each in the transaction of the
+	// change it reports, so they wait in the database while RabbitMQ is
+	// unreachable
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	uow := unitofwork.New(db.Pool, nil)
+	inTx := func(ctx context.Context, fn func(tx *payment.Tx) error) error {
+		return uow.Do(ctx, func(tx *unitofwork.Tx) error {
+			return fn(&payment.Tx{Payments: tx.Payments(), Events: tx.Events()})
+		})
+	}
+	go outbox.NewRelay(db.Pool, outbox.DialRabbitMQ(cfg.Messaging.RabbitMQURL, log), cfg.Outbox).Run(jobsCtx, log)
 
 	// Initialize handlers
-	paymentHandler := payment.NewHandler(paymentRepo)
+	paymentHandler := payment.NewHandler(paymentRepo, payments, bulkheads.Provider, providerLimit, inTx)
+
+	// Settle payments whose provider webhook never arrived
+	go paymentHandler.RunReconciler(jobsCtx, cfg.Payments, log)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -72,13 +198,26 @@ func main() {
 	})
 
 	// Global middleware
-	app.Use(recover.New())
+	app.Use(recover.New(recover.Config{
+		EnableStackTrace:  true,
+		StackTraceHandler: apperrors.PanicHandler,
+	}))
+	app.Use(middleware.RequestID())
+	app.Use(middleware.TracingMiddleware())
+	app.Use(apperrors.Middleware())
 	app.Use(compress.New(compress.Config{
 		Level: compress.LevelBestCompression,
 	}))
+	app.Use(middleware.BodyLogger(log, cfg.Logging)) // Inside compress so bodies are logged uncompressed
+	app.Use(i18n.Middleware())
 	app.Use(middleware.SecurityHeaders())
 	app.Use(middleware.RequestSizeLimit(cfg.Security.MaxRequestSize))
-	app.Use(middleware.PrometheusMetrics())
+	app.Use(middleware.PrometheusMetrics(metrics.NewRED(cfg.ServiceName, cfg.Metrics.DurationBuckets, cfg.Metrics.Exemplars)))
+
+	// Count requests against the service level objectives
+	sloTracker := slo.NewTracker(cfg.ServiceName, cfg.SLO)
+	prometheus.MustRegister(sloTracker)
+	app.Use(middleware.SLOTracking(sloTracker))
 
 	if cfg.Security.EnableCORS {
 		app.Use(middleware.CORSMiddleware(cfg.Security.CORSOrigins))
@@ -87,33 +226,69 @@ func main() {
 	// Health check endpoints
 	app.Get("/health", healthCheck)
 	app.Get("/ready", readinessCheck)
+	app.Get("/slo", sloTracker.Handler())
 
 	// Prometheus metrics endpoint
 	app.Get("/metrics", metrics.FiberMetricsHandler())
 
+	// Effective configuration for debugging (admins only)
+	debugapi.SetupDebugConfig(app, jwtManager, func() *config.Config { return cfg })
+
 	// API routes
 	api := app.Group("/api/v1")
 
+	// Payment provider webhooks are signed by the provider, which the
+	// handler verifies, so they sit outside JWT auth
+	api.Post("/payments/webhook", paymentHandler.HandleProviderWebhook)
+
 	// Protected routes with JWT authentication
-	protected := api.Group("/", middleware.JWTAuth(jwtManager))
+	protected := api.Group("/", middleware.JWTAuth(jwtManager), tenant.Middleware(cfg.Tenant))
 
 	// Payment routes (PCI-DSS compliant)
 	protected.Get("/payments", paymentHandler.GetUserPayments)
+	protected.Get("/payments/export", middleware.RequireRole("admin"), paymentHandler.Export)
 	protected.Get("/payments/:id", paymentHandler.GetPayment)
 	protected.Get("/payments/order/:order_id", paymentHandler.GetPaymentsByOrder)
-	protected.Post("/payments", paymentHandler.ProcessPayment)
+	protected.Post("/payments", middleware.Idempotency(redisClient, middleware.DefaultIdempotencyConfig()), paymentHandler.ProcessPayment)
+
+	// Internal routes for other services, such as the order service's
+	// checkout, scoped to the tenant of the caller's service token; not
+	// exposed by the gateway
+	internal := app.Group("/internal/v1", middleware.ServiceAuth(jwtManager), tenant.Middleware(cfg.Tenant))
+	internal.Post("/payments", paymentHandler.Charge)
+	internal.Get("/payments/:id", paymentHandler.GetPaymentInternal)
+	internal.Post("/payments/:id/refund", paymentHandler.RefundPayment)
+
+	// Internal gRPC API for the gateway and other services
+	var grpcServer *appgrpc.Server
+	if cfg.Service.GRPCPort != "" {
+		grpcServer, err = appgrpc.NewServer(net.JoinHostPort(cfg.Server.Host, cfg.Service.GRPCPort), cfg.GRPC, cfg.Tenant, jwtManager, log)
+		if err != nil {
+			log.Fatalf("Failed to create gRPC server: %v", err)
+		}
+		paymentpb.RegisterPaymentServiceServer(grpcServer.GetServer(), paymentgrpc.NewServer(paymentRepo))
+		go func() {
+			if err := grpcServer.Start(); err != nil {
+				log.Fatalf("gRPC server failed: %v", err)
+			}
+		}()
+	}
 
 	// Start server
-	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, "8084") // Payment service port
+	// Bind before serving so a port of "0" resolves to the actual port in the log
+	listener, err := net.Listen("tcp", cfg.Server.Address())
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", cfg.Server.Address(), err)
+	}
 
 	// Graceful shutdown
 	go func() {
-		if err := app.Listen(addr); err != nil {
+		if err := app.Listener(listener); err != nil {
 			log.Fatalf("Server failed to start: %v", err)
 		}
 	}()
 
-	log.Infof("Payment Service listening on %s", addr)
+	log.Infof("Payment Service listening on %s", listener.Addr())
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -128,6 +303,18 @@ func main() {
 	if err := app.ShutdownWithContext(ctx); err != nil {
 		log.Errorf("Error during shutdown: %v", err)
 	}
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+
+	// Flush spans recorded by the last requests
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		log.Errorf("Error flushing traces: %v", err)
+	}
+	apperrors.Flush(5 * time.Second)
+	if err := security.Close(); err != nil {
+		log.Errorf("Error closing security audit log: %v", err)
+	}
 
 	log.Info("Payment Service stopped")
 }
@@ -158,6 +345,7 @@ func errorHandler(c *fiber.Ctx, err error) error {
 	}
 
 	return c.Status(code).JSON(fiber.Map{
-		"error": message,
+		"error":      message,
+		"request_id": middleware.GetRequestID(c),
 	})
 }
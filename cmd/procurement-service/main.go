@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/onichange/pos-system/internal/infrastructure/inventoryclient"
+	"github.com/onichange/pos-system/internal/infrastructure/outbox"
+	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	"github.com/onichange/pos-system/internal/infrastructure/storeclient"
+	"github.com/onichange/pos-system/internal/infrastructure/unitofwork"
+	"github.com/onichange/pos-system/internal/interfaces/http/procurement"
+	"github.com/onichange/pos-system/migrations"
+	"github.com/onichange/pos-system/pkg/audit/security"
+	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/database"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	appgrpc "github.com/onichange/pos-system/pkg/grpc"
+	"github.com/onichange/pos-system/pkg/i18n"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/middleware"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/secrets"
+	"github.com/onichange/pos-system/pkg/slo"
+	"github.com/onichange/pos-system/pkg/tenant"
+	"github.com/onichange/pos-system/pkg/tracing"
+)
+
+func main() {
+	validateConfig := flag.Bool("validate-config", false, "Validate configuration, print it with secrets masked, and exit")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.LoadService("procurement-service")
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize logger
+	log := logger.New(cfg.Server.Environment)
+	log.Info("Starting Procurement Service...")
+
+	// Resolve secrets from the configured backend (Vault, AWS Secrets Manager)
+	if err := secrets.Resolve(context.Background(), cfg, log); err != nil {
+		log.Fatalf("Failed to resolve secrets: %v", err)
+	}
+
+	// Print the effective configuration and stop when only validating
+	if *validateConfig {
+		effective, err := cfg.EffectiveYAML()
+		if err != nil {
+			log.Fatalf("Failed to render configuration: %v", err)
+		}
+		fmt.Print(string(effective))
+		return
+	}
+
+	// Initialize tracing; spans are exported only when an exporter is configured
+	tracerProvider, err := tracing.NewTracerProvider(cfg.ServiceName, cfg.Server.Environment, cfg.Tracing, log)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// Report panics and server errors when a DSN is configured
+	if err := apperrors.Init(cfg.ServiceName, cfg.Server.Environment, cfg.ErrorReporting); err != nil {
+		log.Warnf("Error reporting disabled: %v", err)
+	}
+
+	// Initialize the security audit log, kept apart from application logs
+	if err := security.Init(cfg.ServiceName, cfg.Audit.Security); err != nil {
+		log.Fatalf("Failed to open security audit log: %v", err)
+	}
+
+	// Cap concurrent calls to each dependency so a slow one fails fast
+	bulkheads := performance.NewBulkheads(cfg.Bulkheads)
+
+	// Share one retry budget across the process so an outage is not amplified
+	retryBudget := performance.NewRetryBudget(cfg.Retry)
+
+	// Initialize database
+	db, err := database.NewPostgresDB(cfg.Database, log)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// "<service> migrate ..." migrates the service's database and exits;
+	// with migrate_on_start, pending migrations are applied before serving
+	if flag.Arg(0) == "migrate" {
+		if err := migrations.Run(context.Background(), db.Pool, cfg.ServiceName, flag.Args()[1:]); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		return
+	}
+	if cfg.Database.MigrateOnStart {
+		if err := migrations.Up(context.Background(), db.Pool, cfg.ServiceName, log); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+
+	// Initialize repositories. Each query attempt holds a bulkhead slot, and
+	// transient failures are retried.
+	queries := database.WithRetry(
+		database.WithBulkhead(db.Pool, bulkheads.Database),
+		performance.NewRetryPolicy("database", cfg.Retry, retryBudget),
+	)
+	procurementRepo := repository.NewProcurementRepository(queries)
+
+	// Initialize JWT manager
+	jwtManager := auth.NewJWTManager(
+		cfg.JWT.AccessTokenSecret,
+		cfg.JWT.RefreshTokenSecret,
+		cfg.JWT.AccessTokenExpiry,
+		cfg.JWT.RefreshTokenExpiry,
+		cfg.JWT.Issuer,
+	).WithPreviousSecrets(cfg.JWT.PreviousAccessTokenSecrets, cfg.JWT.PreviousRefreshTokenSecrets)
+
+	// Post received stock to inventory over its internal gRPC API
+	inventoryConn, err := appgrpc.Dial(cfg.Services.InventoryGRPCTarget, cfg.GRPC, jwtManager)
+	if err != nil {
+		log.Fatalf("Failed to create inventory client: %v", err)
+	}
+	defer inventoryConn.Close()
+
+	// Initialize handlers
+	stores := storeclient.NewClient(cfg.Services.StoreServiceURL, cfg.Proxy)
+	procurementHandler := procurement.NewHandler(
+		procurementRepo,
+		stores,
+		inventoryclient.NewClient(inventoryConn),
+	)
+
+	// Post goods receipts inventory could not take when they were recorded
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go procurementHandler.RunPoster(jobsCtx, cfg.Procurement.PostInterval, log)
+
+	// Raise purchase orders for low stock, and ask the notification service
+	// to tell store managers through the outbox, recording each request in
+	// the transaction of its order, so requests wait in the database while
+	// RabbitMQ is unreachable
+	uow := unitofwork.New(db.Pool, nil)
+	inTx := func(ctx context.Context, fn func(tx *procurement.Tx) error) error {
+		return uow.Do(ctx, func(tx *unitofwork.Tx) error {
+			return fn(&procurement.Tx{Procurement: tx.Procurement(), Events: tx.Events()})
+		})
+	}
+	go outbox.NewRelay(db.Pool, outbox.DialRabbitMQ(cfg.Messaging.RabbitMQURL, log), cfg.Outbox).Run(jobsCtx, log)
+	reorderer := procurement.NewReorderer(
+		procurementRepo,
+		inventoryclient.NewLowStockClient(cfg.Services.InventoryServiceURL, cfg.Proxy),
+		stores,
+		inTx,
+	)
+	go reorderer.Run(jobsCtx, cfg.Procurement.ReorderInterval, log)
+
+	// Create Fiber app
+	app := fiber.New(fiber.Config{
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		ErrorHandler: errorHandler,
+	})
+
+	// Global middleware
+	app.Use(recover.New(recover.Config{
+		EnableStackTrace:  true,
+		StackTraceHandler: apperrors.PanicHandler,
+	}))
+	app.Use(middleware.RequestID())
+	app.Use(middleware.TracingMiddleware())
+	app.Use(apperrors.Middleware())
+	app.Use(compress.New(compress.Config{
+		Level: compress.LevelBestCompression,
+	}))
+	app.Use(middleware.BodyLogger(log, cfg.Logging)) // Inside compress so bodies are logged uncompressed
+	app.Use(i18n.Middleware())
+	app.Use(middleware.SecurityHeaders())
+	app.Use(middleware.RequestSizeLimit(cfg.Security.MaxRequestSize))
+	app.Use(middleware.PrometheusMetrics(metrics.NewRED(cfg.ServiceName, cfg.Metrics.DurationBuckets, cfg.Metrics.Exemplars)))
+
+	// Count requests against the service level objectives
+	sloTracker := slo.NewTracker(cfg.ServiceName, cfg.SLO)
+	prometheus.MustRegister(sloTracker)
+	app.Use(middleware.SLOTracking(sloTracker))
+
+	if cfg.Security.EnableCORS {
+		app.Use(middleware.CORSMiddleware(cfg.Security.CORSOrigins))
+	}
+
+	// Health check endpoints
+	app.Get("/health", healthCheck)
+	app.Get("/ready", readinessCheck)
+	app.Get("/slo", sloTracker.Handler())
+
+	// Prometheus metrics endpoint
+	app.Get("/metrics", metrics.FiberMetricsHandler())
+
+	// API routes
+	api := app.Group("/api/v1")
+
+	// Procurement routes with JWT authentication; only admins buy stock
+	protected := api.Group("/", middleware.JWTAuth(jwtManager), tenant.Middleware(cfg.Tenant), middleware.RequireRole("admin"))
+	protected.Get("/suppliers", procurementHandler.GetSuppliers)
+	protected.Get("/suppliers/:id", procurementHandler.GetSupplier)
+	protected.Post("/suppliers", procurementHandler.CreateSupplier)
+	protected.Put("/suppliers/:id", procurementHandler.UpdateSupplier)
+	protected.Delete("/suppliers/:id", procurementHandler.DeleteSupplier)
+	protected.Get("/suppliers/:id/performance", procurementHandler.GetPerformance)
+	protected.Get("/suppliers/:id/products", procurementHandler.GetSupplierProducts)
+	protected.Put("/suppliers/:id/products/:productId", procurementHandler.SetSupplierProduct)
+	protected.Delete("/suppliers/:id/products/:productId", procurementHandler.DeleteSupplierProduct)
+	protected.Get("/purchase-orders", procurementHandler.GetOrders)
+	protected.Get("/purchase-orders/:id", procurementHandler.GetOrder)
+	protected.Post("/purchase-orders", procurementHandler.CreateOrder)
+	protected.Put("/purchase-orders/:id", procurementHandler.UpdateOrder)
+	protected.Post("/purchase-orders/:id/submit", procurementHandler.SubmitOrder)
+	protected.Post("/purchase-orders/:id/cancel", procurementHandler.CancelOrder)
+	protected.Post("/purchase-orders/:id/close", procurementHandler.CloseOrder)
+	protected.Get("/purchase-orders/:id/receipts", procurementHandler.GetReceipts)
+	protected.Post("/purchase-orders/:id/receipts", procurementHandler.CreateReceipt)
+
+	// Start server
+	// Bind before serving so a port of "0" resolves to the actual port in the log
+	listener, err := net.Listen("tcp", cfg.Server.Address())
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", cfg.Server.Address(), err)
+	}
+
+	// Graceful shutdown
+	go func() {
+		if err := app.Listener(listener); err != nil {
+			log.Fatalf("Server failed to start: %v", err)
+		}
+	}()
+
+	log.Infof("Procurement Service listening on %s", listener.Addr())
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+
+	log.Info("Shutting down Procurement Service...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := app.ShutdownWithContext(ctx); err != nil {
+		log.Errorf("Error during shutdown: %v", err)
+	}
+
+	// Flush spans recorded by the last requests
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		log.Errorf("Error flushing traces: %v", err)
+	}
+	apperrors.Flush(5 * time.Second)
+	if err := security.Close(); err != nil {
+		log.Errorf("Error closing security audit log: %v", err)
+	}
+
+	log.Info("Procurement Service stopped")
+}
+
+func healthCheck(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"status":    "healthy",
+		"service":   "procurement-service",
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+func readinessCheck(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"status":    "ready",
+		"service":   "procurement-service",
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+func errorHandler(c *fiber.Ctx, err error) error {
+	code := fiber.StatusInternalServerError
+	message := "Internal server error"
+
+	if e, ok := err.(*fiber.Error); ok {
+		code = e.Code
+		message = e.Message
+	}
+
+	return c.Status(code).JSON(fiber.Map{
+		"error":      message,
+		"request_id": middleware.GetRequestID(c),
+	})
+}
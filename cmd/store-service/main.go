@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,19 +13,36 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/onichange/pos-system/internal/infrastructure/exportclient"
 	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	"github.com/onichange/pos-system/internal/infrastructure/userclient"
 	"github.com/onichange/pos-system/internal/interfaces/http/store"
+	"github.com/onichange/pos-system/migrations"
+	"github.com/onichange/pos-system/pkg/audit/security"
+	"github.com/onichange/pos-system/pkg/auth"
 	"github.com/onichange/pos-system/pkg/config"
 	"github.com/onichange/pos-system/pkg/database"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	appgrpc "github.com/onichange/pos-system/pkg/grpc"
+	"github.com/onichange/pos-system/pkg/i18n"
 	"github.com/onichange/pos-system/pkg/logger"
 	"github.com/onichange/pos-system/pkg/metrics"
 	"github.com/onichange/pos-system/pkg/middleware"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/secrets"
+	"github.com/onichange/pos-system/pkg/slo"
+	"github.com/onichange/pos-system/pkg/tenant"
+	"github.com/onichange/pos-system/pkg/tracing"
 )
 
 func main() {
+	validateConfig := flag.Bool("validate-config", false, "Validate configuration, print it with secrets masked, and exit")
+	flag.Parse()
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.LoadService("store-service")
 	if err != nil {
 		fmt.Printf("Failed to load config: %v\n", err)
 		os.Exit(1)
@@ -33,6 +52,43 @@ func main() {
 	log := logger.New(cfg.Server.Environment)
 	log.Info("Starting Store Service...")
 
+	// Resolve secrets from the configured backend (Vault, AWS Secrets Manager)
+	if err := secrets.Resolve(context.Background(), cfg, log); err != nil {
+		log.Fatalf("Failed to resolve secrets: %v", err)
+	}
+
+	// Print the effective configuration and stop when only validating
+	if *validateConfig {
+		effective, err := cfg.EffectiveYAML()
+		if err != nil {
+			log.Fatalf("Failed to render configuration: %v", err)
+		}
+		fmt.Print(string(effective))
+		return
+	}
+
+	// Initialize tracing; spans are exported only when an exporter is configured
+	tracerProvider, err := tracing.NewTracerProvider(cfg.ServiceName, cfg.Server.Environment, cfg.Tracing, log)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// Report panics and server errors when a DSN is configured
+	if err := apperrors.Init(cfg.ServiceName, cfg.Server.Environment, cfg.ErrorReporting); err != nil {
+		log.Warnf("Error reporting disabled: %v", err)
+	}
+
+	// Initialize the security audit log, kept apart from application logs
+	if err := security.Init(cfg.ServiceName, cfg.Audit.Security); err != nil {
+		log.Fatalf("Failed to open security audit log: %v", err)
+	}
+
+	// Cap concurrent calls to each dependency so a slow one fails fast
+	bulkheads := performance.NewBulkheads(cfg.Bulkheads)
+
+	// Share one retry budget across the process so an outage is not amplified
+	retryBudget := performance.NewRetryBudget(cfg.Retry)
+
 	// Initialize database
 	db, err := database.NewPostgresDB(cfg.Database, log)
 	if err != nil {
@@ -40,11 +96,66 @@ func main() {
 	}
 	defer db.Close()
 
-	// Initialize repositories
-	storeRepo := repository.NewStoreRepository(db.Pool)
+	// "<service> migrate ..." migrates the service's database and exits;
+	// with migrate_on_start, pending migrations are applied before serving
+	if flag.Arg(0) == "migrate" {
+		if err := migrations.Run(context.Background(), db.Pool, cfg.ServiceName, flag.Args()[1:]); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		return
+	}
+	if cfg.Database.MigrateOnStart {
+		if err := migrations.Up(context.Background(), db.Pool, cfg.ServiceName, log); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+
+	// Initialize repositories. Each query attempt holds a bulkhead slot, and
+	// transient failures are retried.
+	queries := database.WithRetry(
+		database.WithBulkhead(db.Pool, bulkheads.Database),
+		performance.NewRetryPolicy("database", cfg.Retry, retryBudget),
+	)
+	// Stores change rarely and are read far more than written, so they are
+	// read from the replicas when there are any
+	routed := database.WithRetry(
+		database.WithBulkhead(db.Routed(), bulkheads.Database),
+		performance.NewRetryPolicy("database", cfg.Retry, retryBudget),
+	)
+	storeRepo := repository.NewStoreRepository(routed)
+	deviceRepo := repository.NewDeviceRepository(queries)
+	exportRepo := repository.NewExportRepository(queries)
+
+	// Service tokens authenticate calls over gRPC
+	jwtManager := auth.NewJWTManager(
+		cfg.JWT.AccessTokenSecret,
+		cfg.JWT.RefreshTokenSecret,
+		cfg.JWT.AccessTokenExpiry,
+		cfg.JWT.RefreshTokenExpiry,
+		cfg.JWT.Issuer,
+	).WithPreviousSecrets(cfg.JWT.PreviousAccessTokenSecrets, cfg.JWT.PreviousRefreshTokenSecrets)
+
+	// Look up the customers of exported orders over the user service's gRPC API
+	userConn, err := appgrpc.Dial(cfg.Services.UserGRPCTarget, cfg.GRPC, jwtManager)
+	if err != nil {
+		log.Fatalf("Failed to create user client: %v", err)
+	}
+	defer userConn.Close()
 
 	// Initialize handlers
 	storeHandler := store.NewHandler(storeRepo)
+	deviceHandler := store.NewDeviceHandler(storeRepo, deviceRepo, cfg.Devices)
+	exportHandler := store.NewExportHandler(storeRepo, exportRepo, store.ExportSources{
+		Catalog:   exportclient.NewClient("catalog-service", cfg.Services.CatalogServiceURL, cfg.Proxy),
+		Inventory: exportclient.NewClient("inventory-service", cfg.Services.InventoryServiceURL, cfg.Proxy),
+		Orders:    exportclient.NewClient("order-service", cfg.Services.OrderServiceURL, cfg.Proxy),
+		Customers: userclient.NewClient(userConn),
+	}, cfg.Exports)
+
+	// Build requested store exports in the background
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go exportHandler.RunExporter(jobsCtx, log)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -55,13 +166,26 @@ func main() {
 	})
 
 	// Global middleware
-	app.Use(recover.New())
+	app.Use(recover.New(recover.Config{
+		EnableStackTrace:  true,
+		StackTraceHandler: apperrors.PanicHandler,
+	}))
+	app.Use(middleware.RequestID())
+	app.Use(middleware.TracingMiddleware())
+	app.Use(apperrors.Middleware())
 	app.Use(compress.New(compress.Config{
 		Level: compress.LevelBestCompression,
 	}))
+	app.Use(middleware.BodyLogger(log, cfg.Logging)) // Inside compress so bodies are logged uncompressed
+	app.Use(i18n.Middleware())
 	app.Use(middleware.SecurityHeaders())
 	app.Use(middleware.RequestSizeLimit(cfg.Security.MaxRequestSize))
-	app.Use(middleware.PrometheusMetrics())
+	app.Use(middleware.PrometheusMetrics(metrics.NewRED(cfg.ServiceName, cfg.Metrics.DurationBuckets, cfg.Metrics.Exemplars)))
+
+	// Count requests against the service level objectives
+	sloTracker := slo.NewTracker(cfg.ServiceName, cfg.SLO)
+	prometheus.MustRegister(sloTracker)
+	app.Use(middleware.SLOTracking(sloTracker))
 
 	if cfg.Security.EnableCORS {
 		app.Use(middleware.CORSMiddleware(cfg.Security.CORSOrigins))
@@ -70,12 +194,13 @@ func main() {
 	// Health check endpoints
 	app.Get("/health", healthCheck)
 	app.Get("/ready", readinessCheck)
+	app.Get("/slo", sloTracker.Handler())
 
 	// Prometheus metrics endpoint
 	app.Get("/metrics", metrics.FiberMetricsHandler())
 
 	// API routes
-	api := app.Group("/api/v1")
+	api := app.Group("/api/v1", tenant.Middleware(cfg.Tenant))
 
 	// Store routes
 	api.Get("/stores", storeHandler.GetStores)
@@ -85,17 +210,43 @@ func main() {
 	api.Put("/stores/:id", storeHandler.UpdateStore)
 	api.Delete("/stores/:id", storeHandler.DeleteStore)
 
+	// Device routes. Devices pair with their one-time code and send
+	// heartbeats with their API key.
+	api.Get("/stores/:id/devices", deviceHandler.ListDevices)
+	api.Post("/stores/:id/devices", deviceHandler.RegisterDevice)
+	api.Get("/stores/:id/devices/:deviceId", deviceHandler.GetDevice)
+	api.Post("/stores/:id/devices/:deviceId/pairing-code", deviceHandler.ResetPairing)
+	api.Post("/stores/:id/devices/:deviceId/deactivate", deviceHandler.DeactivateDevice)
+	api.Post("/devices/pair", deviceHandler.PairDevice)
+	api.Post("/devices/heartbeat", deviceHandler.Heartbeat)
+
+	// Export routes. Exports are built in the background and downloaded
+	// once completed.
+	api.Get("/stores/:id/exports", exportHandler.ListExports)
+	api.Post("/stores/:id/exports", exportHandler.CreateExport)
+	api.Get("/stores/:id/exports/:exportId", exportHandler.GetExport)
+	api.Get("/stores/:id/exports/:exportId/download", exportHandler.DownloadExport)
+
+	// Internal routes for other services; the gateway does not proxy them
+	internal := app.Group("/internal/v1", tenant.Middleware(cfg.Tenant))
+	internal.Get("/stores/:id", storeHandler.GetStoreByID)
+	internal.Post("/devices/verify", deviceHandler.VerifyDevice)
+
 	// Start server
-	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, "8083") // Store service port
+	// Bind before serving so a port of "0" resolves to the actual port in the log
+	listener, err := net.Listen("tcp", cfg.Server.Address())
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", cfg.Server.Address(), err)
+	}
 
 	// Graceful shutdown
 	go func() {
-		if err := app.Listen(addr); err != nil {
+		if err := app.Listener(listener); err != nil {
 			log.Fatalf("Server failed to start: %v", err)
 		}
 	}()
 
-	log.Infof("Store Service listening on %s", addr)
+	log.Infof("Store Service listening on %s", listener.Addr())
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -103,6 +254,7 @@ func main() {
 	<-quit
 
 	log.Info("Shutting down Store Service...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -111,6 +263,15 @@ func main() {
 		log.Errorf("Error during shutdown: %v", err)
 	}
 
+	// Flush spans recorded by the last requests
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		log.Errorf("Error flushing traces: %v", err)
+	}
+	apperrors.Flush(5 * time.Second)
+	if err := security.Close(); err != nil {
+		log.Errorf("Error closing security audit log: %v", err)
+	}
+
 	log.Info("Store Service stopped")
 }
 
@@ -140,6 +301,7 @@ func errorHandler(c *fiber.Ctx, err error) error {
 	}
 
 	return c.Status(code).JSON(fiber.Map{
-		"error": message,
+		"error":      message,
+		"request_id": middleware.GetRequestID(c),
 	})
 }
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,21 +13,41 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	usergrpc "github.com/onichange/pos-system/internal/interfaces/grpc/user"
 	"github.com/onichange/pos-system/internal/interfaces/http/user"
+	"github.com/onichange/pos-system/migrations"
+	debugapi "github.com/onichange/pos-system/pkg/api"
+	"github.com/onichange/pos-system/pkg/audit/security"
 	"github.com/onichange/pos-system/pkg/auth"
 	"github.com/onichange/pos-system/pkg/cache"
 	"github.com/onichange/pos-system/pkg/config"
 	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/encryption"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	appgrpc "github.com/onichange/pos-system/pkg/grpc"
+	"github.com/onichange/pos-system/pkg/i18n"
 	"github.com/onichange/pos-system/pkg/logger"
 	"github.com/onichange/pos-system/pkg/metrics"
 	"github.com/onichange/pos-system/pkg/middleware"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/secrets"
+	"github.com/onichange/pos-system/pkg/slo"
+	"github.com/onichange/pos-system/pkg/tenant"
+	"github.com/onichange/pos-system/pkg/tracing"
+	userpb "github.com/onichange/pos-system/proto/user"
 )
 
 func main() {
+	validateConfig := flag.Bool("validate-config", false, "Validate configuration, print it with secrets masked, and exit")
+	backfillBlindIndex := flag.Bool("backfill-blind-index", false, "Fill in missing email blind indexes, and exit")
+	rebuildBlindIndex := flag.Bool("rebuild", false, "With -backfill-blind-index, recompute every index, as after changing the key")
+	flag.Parse()
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.LoadService("user-service")
 	if err != nil {
 		fmt.Printf("Failed to load config: %v\n", err)
 		os.Exit(1)
@@ -35,6 +57,43 @@ func main() {
 	log := logger.New(cfg.Server.Environment)
 	log.Info("Starting User Service...")
 
+	// Resolve secrets from the configured backend (Vault, AWS Secrets Manager)
+	if err := secrets.Resolve(context.Background(), cfg, log); err != nil {
+		log.Fatalf("Failed to resolve secrets: %v", err)
+	}
+
+	// Print the effective configuration and stop when only validating
+	if *validateConfig {
+		effective, err := cfg.EffectiveYAML()
+		if err != nil {
+			log.Fatalf("Failed to render configuration: %v", err)
+		}
+		fmt.Print(string(effective))
+		return
+	}
+
+	// Initialize tracing; spans are exported only when an exporter is configured
+	tracerProvider, err := tracing.NewTracerProvider(cfg.ServiceName, cfg.Server.Environment, cfg.Tracing, log)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// Report panics and server errors when a DSN is configured
+	if err := apperrors.Init(cfg.ServiceName, cfg.Server.Environment, cfg.ErrorReporting); err != nil {
+		log.Warnf("Error reporting disabled: %v", err)
+	}
+
+	// Initialize the security audit log, kept apart from application logs
+	if err := security.Init(cfg.ServiceName, cfg.Audit.Security); err != nil {
+		log.Fatalf("Failed to open security audit log: %v", err)
+	}
+
+	// Cap concurrent calls to each dependency so a slow one fails fast
+	bulkheads := performance.NewBulkheads(cfg.Bulkheads)
+
+	// Share one retry budget across the process so an outage is not amplified
+	retryBudget := performance.NewRetryBudget(cfg.Retry)
+
 	// Initialize database
 	db, err := database.NewPostgresDB(cfg.Database, log)
 	if err != nil {
@@ -42,10 +101,18 @@ func main() {
 	}
 	defer db.Close()
 
-	// Initialize Redis cache
-	_, err = cache.NewRedisCache(cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.PoolSize, cfg.Redis.MinIdleConns)
-	if err != nil {
-		log.Warnf("Failed to connect to Redis: %v (continuing without cache)", err)
+	// "<service> migrate ..." migrates the service's database and exits;
+	// with migrate_on_start, pending migrations are applied before serving
+	if flag.Arg(0) == "migrate" {
+		if err := migrations.Run(context.Background(), db.Pool, cfg.ServiceName, flag.Args()[1:]); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		return
+	}
+	if cfg.Database.MigrateOnStart {
+		if err := migrations.Up(context.Background(), db.Pool, cfg.ServiceName, log); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
 	}
 
 	// Initialize JWT manager
@@ -55,13 +122,62 @@ func main() {
 		cfg.JWT.AccessTokenExpiry,
 		cfg.JWT.RefreshTokenExpiry,
 		cfg.JWT.Issuer,
+	).WithPreviousSecrets(cfg.JWT.PreviousAccessTokenSecrets, cfg.JWT.PreviousRefreshTokenSecrets)
+
+	// Initialize Redis cache, which holds revoked refresh tokens
+	var revocations *auth.RevocationList
+	redisCache, err := cache.NewRedisCache(cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.PoolSize, cfg.Redis.MinIdleConns)
+	if err != nil {
+		log.Warnf("Failed to connect to Redis: %v (continuing without cache; refresh tokens cannot be revoked)", err)
+	} else {
+		defer redisCache.Close()
+		revocations = auth.NewRevocationList(redisCache, cfg.JWT.RefreshTokenExpiry)
+		jwtManager.WithRevocationList(revocations)
+	}
+
+	// Encrypt PII fields under the configured master key versions
+	keyring, err := secrets.NewKeyring(cfg.Encryption, cfg.Secrets)
+	if err != nil {
+		log.Fatalf("Failed to load encryption keys: %v", err)
+	}
+	if keyring == nil {
+		log.Warn("No encryption key configured; PII fields are stored unencrypted")
+	}
+	envelope := encryption.NewKeyringEnvelope(keyring)
+	blindIndex, err := encryption.ParseBlindIndexKey(cfg.Encryption.BlindIndexKey)
+	if err != nil {
+		log.Fatalf("Failed to load blind index key: %v", err)
+	}
+	if envelope != nil && blindIndex == nil {
+		log.Fatal("Encrypted emails need a blind index key to be looked up; set encryption.blind_index_key")
+	}
+
+	// Initialize repositories. Each query attempt holds a bulkhead slot, and
+	// transient failures are retried.
+	queries := database.WithRetry(
+		database.WithBulkhead(db.Pool, bulkheads.Database),
+		performance.NewRetryPolicy("database", cfg.Retry, retryBudget),
 	)
+	userRepo := repository.NewUserRepository(queries, envelope, blindIndex)
 
-	// Initialize repositories
-	userRepo := repository.NewUserRepository(db.Pool)
+	// Index the emails of existing users and stop when only backfilling
+	if *backfillBlindIndex {
+		indexed, err := userRepo.BackfillBlindIndexes(context.Background(), cfg.Encryption.ReencryptBatchSize, *rebuildBlindIndex)
+		if err != nil {
+			log.Fatalf("Blind index backfill failed after %d users: %v", indexed, err)
+		}
+		log.Infof("Blind index backfill indexed %d users", indexed)
+		return
+	}
+
+	// Move stored PII onto the current key version in the background
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go encryption.RunReencryption(jobsCtx, envelope, userRepo.EncryptedColumns(),
+		cfg.Encryption.ReencryptBatchSize, cfg.Encryption.ReencryptInterval, log)
 
 	// Initialize handlers
-	userHandler := user.NewHandler(userRepo, jwtManager)
+	userHandler := user.NewHandler(userRepo, jwtManager, encryption.NewPasswordHasher(cfg.Security.PasswordHashing), revocations)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -72,12 +188,26 @@ func main() {
 	})
 
 	// Global middleware
-	app.Use(recover.New())
+	app.Use(recover.New(recover.Config{
+		EnableStackTrace:  true,
+		StackTraceHandler: apperrors.PanicHandler,
+	}))
+	app.Use(middleware.RequestID())
+	app.Use(middleware.TracingMiddleware())
+	app.Use(apperrors.Middleware())
 	app.Use(compress.New(compress.Config{
 		Level: compress.LevelBestCompression,
 	}))
+	app.Use(middleware.BodyLogger(log, cfg.Logging)) // Inside compress so bodies are logged uncompressed
+	app.Use(i18n.Middleware())
 	app.Use(middleware.SecurityHeaders())
 	app.Use(middleware.RequestSizeLimit(cfg.Security.MaxRequestSize))
+	app.Use(middleware.PrometheusMetrics(metrics.NewRED(cfg.ServiceName, cfg.Metrics.DurationBuckets, cfg.Metrics.Exemplars)))
+
+	// Count requests against the service level objectives
+	sloTracker := slo.NewTracker(cfg.ServiceName, cfg.SLO)
+	prometheus.MustRegister(sloTracker)
+	app.Use(middleware.SLOTracking(sloTracker))
 
 	if cfg.Security.EnableCORS {
 		app.Use(middleware.CORSMiddleware(cfg.Security.CORSOrigins))
@@ -86,34 +216,60 @@ func main() {
 	// Health check endpoints
 	app.Get("/health", healthCheck)
 	app.Get("/ready", readinessCheck)
+	app.Get("/slo", sloTracker.Handler())
 
 	// Prometheus metrics endpoint
 	app.Get("/metrics", metrics.FiberMetricsHandler())
 
+	// Effective configuration for debugging (admins only)
+	debugapi.SetupDebugConfig(app, jwtManager, func() *config.Config { return cfg })
+
 	// API routes
 	api := app.Group("/api/v1")
 
 	// Public routes
-	api.Post("/users", userHandler.CreateUser)
-	api.Post("/auth/login", userHandler.Login)
+	api.Post("/users", tenant.Middleware(cfg.Tenant), userHandler.CreateUser)
+	api.Post("/auth/login", tenant.Middleware(cfg.Tenant), userHandler.Login)
+	api.Post("/auth/refresh", tenant.Middleware(cfg.Tenant), userHandler.RefreshToken)
+	api.Post("/auth/logout", tenant.Middleware(cfg.Tenant), userHandler.Logout)
 
 	// Protected routes with JWT authentication
-	protected := api.Group("/", middleware.JWTAuth(jwtManager))
+	protected := api.Group("/", middleware.JWTAuth(jwtManager), tenant.Middleware(cfg.Tenant))
 	protected.Get("/users/me", userHandler.GetUserProfile)
 	protected.Put("/users/me", userHandler.UpdateUserProfile)
+	protected.Put("/users/me/password", userHandler.ChangePassword)
 	protected.Get("/users/:id", userHandler.GetUserByID)
 
+	// Internal gRPC API for other services to look users up
+	var grpcServer *appgrpc.Server
+	if cfg.Service.GRPCPort != "" {
+		grpcServer, err = appgrpc.NewServer(net.JoinHostPort(cfg.Server.Host, cfg.Service.GRPCPort), cfg.GRPC, cfg.Tenant, jwtManager, log)
+		if err != nil {
+			log.Fatalf("Failed to create gRPC server: %v", err)
+		}
+		userpb.RegisterUserServiceServer(grpcServer.GetServer(), usergrpc.NewServer(userRepo))
+		go func() {
+			if err := grpcServer.Start(); err != nil {
+				log.Fatalf("gRPC server failed: %v", err)
+			}
+		}()
+	}
+
 	// Start server
-	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, "8082") // User service port
+	// Bind before serving so a port of "0" resolves to the actual port in the log
+	listener, err := net.Listen("tcp", cfg.Server.Address())
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", cfg.Server.Address(), err)
+	}
 
 	// Graceful shutdown
 	go func() {
-		if err := app.Listen(addr); err != nil {
+		if err := app.Listener(listener); err != nil {
 			log.Fatalf("Server failed to start: %v", err)
 		}
 	}()
 
-	log.Infof("User Service listening on %s", addr)
+	log.Infof("User Service listening on %s", listener.Addr())
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -121,6 +277,7 @@ func main() {
 	<-quit
 
 	log.Info("Shutting down User Service...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -128,6 +285,18 @@ func main() {
 	if err := app.ShutdownWithContext(ctx); err != nil {
 		log.Errorf("Error during shutdown: %v", err)
 	}
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+
+	// Flush spans recorded by the last requests
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		log.Errorf("Error flushing traces: %v", err)
+	}
+	apperrors.Flush(5 * time.Second)
+	if err := security.Close(); err != nil {
+		log.Errorf("Error closing security audit log: %v", err)
+	}
 
 	log.Info("User Service stopped")
 }
@@ -159,6 +328,7 @@ func errorHandler(c *fiber.Ctx, err error) error {
 	}
 
 	return c.Status(code).JSON(fiber.Map{
-		"error": message,
+		"error":      message,
+		"request_id": middleware.GetRequestID(c),
 	})
 }
@@ -0,0 +1,142 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Granularity is the bucket size of a rollup
+type Granularity string
+
+const (
+	Hourly Granularity = "hour"
+	Daily  Granularity = "day"
+)
+
+// Query selects rollup buckets from From (inclusive) to To (exclusive).
+// Buckets are in UTC.
+type Query struct {
+	From     time.Time
+	To       time.Time
+	StoreID  *uuid.UUID // Nil covers every store
+	Currency string     // Empty covers every currency
+}
+
+// Granularity returns the coarsest rollup that answers the query exactly:
+// daily when both ends fall on midnight UTC, hourly otherwise
+func (q Query) Granularity() Granularity {
+	if q.From.Equal(q.From.Truncate(24*time.Hour)) && q.To.Equal(q.To.Truncate(24*time.Hour)) {
+		return Daily
+	}
+	return Hourly
+}
+
+// OrderFact is what one order event adds to the sales rollups
+type OrderFact struct {
+	EventID    string
+	At         time.Time
+	StoreID    uuid.UUID
+	Currency   string
+	Placed     int
+	Completed  int
+	Cancelled  int
+	Refunded   int
+	GrossSales float64       // Value of orders placed
+	Revenue    float64       // Value of orders completed
+	Refunds    float64       // Value of orders refunded
+	Lines      []ProductLine // Items completed or refunded
+}
+
+// ProductLine is what one order line adds to the product rollups
+type ProductLine struct {
+	ProductID string  `json:"product_id"`
+	Name      string  `json:"name"`
+	Units     int     `json:"units"`
+	Amount    float64 `json:"amount"`
+}
+
+// PaymentFact is what one payment event adds to the payment rollups
+type PaymentFact struct {
+	EventID   string
+	At        time.Time
+	Currency  string
+	Method    string
+	Started   int
+	Completed int
+	Amount    float64 // Value of payments completed
+}
+
+// StockFact is what one inventory event adds to the stock rollups
+type StockFact struct {
+	EventID   string
+	At        time.Time
+	StoreID   uuid.UUID // uuid.Nil for stock held outside stores
+	ProductID uuid.UUID
+	Reserved  int
+	Released  int
+	LowStock  int
+}
+
+// SalesPoint is a store's sales in one bucket, or every store's when the
+// query covers them all
+type SalesPoint struct {
+	Bucket          time.Time `json:"bucket"`
+	Currency        string    `json:"currency"`
+	OrdersPlaced    int64     `json:"orders_placed"`
+	OrdersCompleted int64     `json:"orders_completed"`
+	OrdersCancelled int64     `json:"orders_cancelled"`
+	OrdersRefunded  int64     `json:"orders_refunded"`
+	GrossSales      float64   `json:"gross_sales"`
+	Revenue         float64   `json:"revenue"`
+	Refunds         float64   `json:"refunds"`
+	NetRevenue      float64   `json:"net_revenue"` // Revenue less refunds
+}
+
+// ProductSales is a product's sales over a query's range. Revenue is after
+// item discounts and before order-level ones such as loyalty redemptions.
+type ProductSales struct {
+	ProductID     string  `json:"product_id"` // Catalog variant ID
+	Name          string  `json:"name"`
+	Currency      string  `json:"currency"`
+	UnitsSold     int64   `json:"units_sold"`
+	Revenue       float64 `json:"revenue"`
+	UnitsRefunded int64   `json:"units_refunded"`
+	Refunds       float64 `json:"refunds"`
+}
+
+// Conversion is how far orders and payments got over a query's range
+type Conversion struct {
+	OrdersPlaced       int64   `json:"orders_placed"`
+	OrdersCompleted    int64   `json:"orders_completed"`
+	OrdersCancelled    int64   `json:"orders_cancelled"`
+	CompletionRate     float64 `json:"completion_rate"` // Completed per order placed
+	CancellationRate   float64 `json:"cancellation_rate"`
+	PaymentsStarted    int64   `json:"payments_started"`
+	PaymentsCompleted  int64   `json:"payments_completed"`
+	PaymentSuccessRate float64 `json:"payment_success_rate"`
+}
+
+// Rates fills in the rates from the counts
+func (c *Conversion) Rates() {
+	c.CompletionRate = ratio(c.OrdersCompleted, c.OrdersPlaced)
+	c.CancellationRate = ratio(c.OrdersCancelled, c.OrdersPlaced)
+	c.PaymentSuccessRate = ratio(c.PaymentsCompleted, c.PaymentsStarted)
+}
+
+// StockActivity is a product's stock movements over a query's range
+type StockActivity struct {
+	ProductID      uuid.UUID  `json:"product_id"`
+	StoreID        *uuid.UUID `json:"store_id,omitempty"`
+	UnitsReserved  int64      `json:"units_reserved"`
+	UnitsReleased  int64      `json:"units_released"`
+	LowStockEvents int64      `json:"low_stock_events"`
+}
+
+// ratio returns part/whole, or 0 for an empty whole
+func ratio(part, whole int64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole)
+}
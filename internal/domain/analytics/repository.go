@@ -0,0 +1,36 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository defines the analytics repository interface. Each Apply method
+// adds a fact to its hourly and daily rollups once per event ID and reports
+// whether it did.
+type Repository interface {
+	ApplyOrder(ctx context.Context, f *OrderFact) (bool, error)
+	ApplyPayment(ctx context.Context, f *PaymentFact) (bool, error)
+	ApplyStock(ctx context.Context, f *StockFact) (bool, error)
+	// ApplyTimeEntry keeps a time entry fact unless a later version of the
+	// entry is already kept, and reports whether it did
+	ApplyTimeEntry(ctx context.Context, f *TimeEntryFact) (bool, error)
+
+	GetSales(ctx context.Context, g Granularity, q Query) ([]*SalesPoint, error)
+	// GetTopProducts returns the products that sold the most, by revenue or,
+	// with byUnits, by units
+	GetTopProducts(ctx context.Context, q Query, byUnits bool, limit int) ([]*ProductSales, error)
+	GetConversion(ctx context.Context, q Query) (*Conversion, error)
+	// GetStockActivity returns the products with the most units reserved
+	GetStockActivity(ctx context.Context, q Query, limit int) ([]*StockActivity, error)
+	// GetStaffDays returns the closed time entries clocked in during the week
+	// starting at weekStart per staff member and day, at one store or, with
+	// a nil storeID, every store
+	GetStaffDays(ctx context.Context, weekStart time.Time, storeID *uuid.UUID) ([]*StaffDay, error)
+
+	// Prune deletes hourly rollups older than hourlyBefore and the record of
+	// events applied before eventsBefore, returning how many rows it deleted
+	Prune(ctx context.Context, hourlyBefore, eventsBefore time.Time) (int64, error)
+}
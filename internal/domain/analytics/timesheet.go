@@ -0,0 +1,128 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OvertimeThreshold is the time worked in a week beyond which timesheets
+// count overtime. Payroll applies any stricter local rules itself.
+const OvertimeThreshold = 40 * time.Hour
+
+// TimeEntryFact is a staff time entry as of one version. A later version
+// replaces an earlier one, whatever order their events arrive in.
+type TimeEntryFact struct {
+	EntryID      uuid.UUID
+	Version      int
+	StoreID      uuid.UUID
+	StaffID      uuid.UUID
+	Closed       bool
+	ClockIn      time.Time
+	ClockOut     *time.Time
+	Worked       time.Duration // Includes paid breaks
+	UnpaidBreaks time.Duration
+}
+
+// StaffDay is a staff member's closed time entries clocked in on one day,
+// in UTC
+type StaffDay struct {
+	StaffID      uuid.UUID
+	Day          time.Time
+	Entries      int
+	Worked       time.Duration
+	UnpaidBreaks time.Duration
+}
+
+// Timesheet is a staff member's time worked in a week, Monday to Sunday
+// in UTC, for payroll. Entries count toward the day they were clocked in
+// on, and only once clocked out.
+type Timesheet struct {
+	StaffID          uuid.UUID      `json:"staff_id"`
+	WeekStart        string         `json:"week_start"` // YYYY-MM-DD, a Monday
+	Entries          int            `json:"entries"`
+	WorkedHours      float64        `json:"worked_hours"`
+	RegularHours     float64        `json:"regular_hours"`
+	OvertimeHours    float64        `json:"overtime_hours"` // Worked beyond OvertimeThreshold
+	UnpaidBreakHours float64        `json:"unpaid_break_hours"`
+	Days             []TimesheetDay `json:"days"`
+}
+
+// TimesheetDay is a staff member's time worked on one day of a timesheet
+type TimesheetDay struct {
+	Date             string  `json:"date"` // YYYY-MM-DD
+	Entries          int     `json:"entries"`
+	WorkedHours      float64 `json:"worked_hours"`
+	UnpaidBreakHours float64 `json:"unpaid_break_hours"`
+}
+
+// WeekStart returns the Monday of the week t falls in, at midnight UTC
+func WeekStart(t time.Time) time.Time {
+	day := t.UTC().Truncate(24 * time.Hour)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// BuildTimesheets returns the timesheets of the week starting at weekStart
+// from its staff days, one per staff member and day, giving a timesheet per
+// staff member in staff ID order, each with all seven days
+func BuildTimesheets(weekStart time.Time, days []*StaffDay) []*Timesheet {
+	type totals struct {
+		sheet        *Timesheet
+		worked       time.Duration
+		unpaidBreaks time.Duration
+	}
+
+	byStaff := map[uuid.UUID]*totals{}
+	for _, d := range days {
+		t, ok := byStaff[d.StaffID]
+		if !ok {
+			t = &totals{sheet: &Timesheet{
+				StaffID:   d.StaffID,
+				WeekStart: weekStart.Format("2006-01-02"),
+				Days:      make([]TimesheetDay, 7),
+			}}
+			for i := range t.sheet.Days {
+				t.sheet.Days[i].Date = weekStart.AddDate(0, 0, i).Format("2006-01-02")
+			}
+			byStaff[d.StaffID] = t
+		}
+
+		i := int(d.Day.Sub(weekStart) / (24 * time.Hour))
+		if i < 0 || i >= len(t.sheet.Days) {
+			continue
+		}
+		day := &t.sheet.Days[i]
+		day.Entries = d.Entries
+		day.WorkedHours = hours(d.Worked)
+		day.UnpaidBreakHours = hours(d.UnpaidBreaks)
+
+		t.sheet.Entries += d.Entries
+		t.worked += d.Worked
+		t.unpaidBreaks += d.UnpaidBreaks
+	}
+
+	sheets := make([]*Timesheet, 0, len(byStaff))
+	for _, t := range byStaff {
+		regular, overtime := t.worked, time.Duration(0)
+		if regular > OvertimeThreshold {
+			regular, overtime = OvertimeThreshold, t.worked-OvertimeThreshold
+		}
+		t.sheet.WorkedHours = hours(t.worked)
+		t.sheet.RegularHours = hours(regular)
+		t.sheet.OvertimeHours = hours(overtime)
+		t.sheet.UnpaidBreakHours = hours(t.unpaidBreaks)
+		sheets = append(sheets, t.sheet)
+	}
+	sort.Slice(sheets, func(i, j int) bool {
+		return sheets[i].StaffID.String() < sheets[j].StaffID.String()
+	})
+	return sheets
+}
+
+// hours returns d in hours, rounded to the hundredth
+func hours(d time.Duration) float64 {
+	return math.Round(d.Hours()*100) / 100
+}
@@ -0,0 +1,117 @@
+package cart
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCartNotFound = errors.New("cart not found")
+	ErrItemNotFound = errors.New("item not in cart")
+	// ErrCheckingOut is returned when changing a cart being checked out
+	ErrCheckingOut = errors.New("cart is being checked out")
+	// ErrConflict is returned when a cart kept changing while being updated
+	ErrConflict = errors.New("cart changed concurrently")
+)
+
+// CheckoutTimeout is how long a checkout holds a cart. A checkout that did
+// not finish by then, such as one whose instance stopped, no longer keeps
+// the cart from being checked out again.
+const CheckoutTimeout = time.Minute
+
+// DiscountType is how a cart discount is worked out
+type DiscountType string
+
+const (
+	DiscountPercent DiscountType = "percent" // A percentage of the discounted subtotal
+	DiscountAmount  DiscountType = "amount"  // A fixed amount off, at most the subtotal
+)
+
+// Item is a product in a cart. Its price comes from the catalog when the
+// cart is priced.
+type Item struct {
+	ProductID string  `json:"product_id"` // Catalog variant ID
+	Quantity  int     `json:"quantity"`
+	Discount  float64 `json:"discount,omitempty"` // Amount taken off the line by the cashier
+}
+
+// Discount is taken off a whole cart, spread over its lines by value
+type Discount struct {
+	Type   DiscountType `json:"type"`
+	Value  float64      `json:"value"`
+	Reason string       `json:"reason,omitempty"`
+}
+
+// Cart is an order being put together at the register. It lives until
+// checked out into an order, deleted, or left alone for its time to live.
+type Cart struct {
+	ID        uuid.UUID `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	StoreID   uuid.UUID `json:"store_id"`
+	Items     []Item    `json:"items"`
+	Discount  *Discount `json:"discount,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
+	Version   int       `json:"version"` // Incremented by every change
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// CheckoutOrderID is the ID of the order a checkout in progress creates
+	CheckoutOrderID   *uuid.UUID `json:"checkout_order_id,omitempty"`
+	CheckoutStartedAt *time.Time `json:"checkout_started_at,omitempty"`
+}
+
+// CheckingOut reports whether a checkout holds the cart at now
+func (c *Cart) CheckingOut(now time.Time) bool {
+	return c.CheckoutStartedAt != nil && now.Sub(*c.CheckoutStartedAt) < CheckoutTimeout
+}
+
+// AddItem adds quantity of a product, to its line if the cart has one
+func (c *Cart) AddItem(productID string, quantity int) {
+	for i := range c.Items {
+		if c.Items[i].ProductID == productID {
+			c.Items[i].Quantity += quantity
+			return
+		}
+	}
+	c.Items = append(c.Items, Item{ProductID: productID, Quantity: quantity})
+}
+
+// UpdateItem sets the quantity and line discount of a product, removing its
+// line when quantity is not positive
+func (c *Cart) UpdateItem(productID string, quantity int, discount float64) error {
+	for i := range c.Items {
+		if c.Items[i].ProductID != productID {
+			continue
+		}
+		if quantity <= 0 {
+			c.Items = append(c.Items[:i], c.Items[i+1:]...)
+			return nil
+		}
+		c.Items[i].Quantity = quantity
+		c.Items[i].Discount = math.Max(discount, 0)
+		return nil
+	}
+	return ErrItemNotFound
+}
+
+// RemoveItem removes the line of a product
+func (c *Cart) RemoveItem(productID string) error {
+	return c.UpdateItem(productID, 0, 0)
+}
+
+// Amount is the discount on a subtotal, at most the subtotal
+func (d *Discount) Amount(subtotal float64) float64 {
+	if d == nil || subtotal <= 0 {
+		return 0
+	}
+	amount := d.Value
+	if d.Type == DiscountPercent {
+		amount = subtotal * d.Value / 100
+	}
+	return math.Min(math.Max(amount, 0), subtotal)
+}
@@ -0,0 +1,19 @@
+package cart
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Repository defines the cart repository interface. Carts expire once left
+// unchanged for the repository's time to live.
+type Repository interface {
+	Create(ctx context.Context, cart *Cart) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Cart, error)
+	// Update applies fn to a cart and saves it, unless fn fails. Should the
+	// cart change in the meantime, fn is applied again to the new state, and
+	// ErrConflict returned if it keeps changing.
+	Update(ctx context.Context, id uuid.UUID, fn func(*Cart) error) (*Cart, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
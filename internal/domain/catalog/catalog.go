@@ -0,0 +1,103 @@
+package catalog
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrDuplicate is returned when a SKU, barcode or category slug is already in use
+var ErrDuplicate = errors.New("SKU, barcode or slug already in use")
+
+// ProductStatus represents product status
+type ProductStatus string
+
+const (
+	StatusDraft    ProductStatus = "draft"
+	StatusActive   ProductStatus = "active"
+	StatusArchived ProductStatus = "archived"
+)
+
+// Category groups products; categories nest through ParentID
+type Category struct {
+	ID        uuid.UUID  `json:"id"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty"`
+	Name      string     `json:"name"`
+	Slug      string     `json:"slug"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Image is a product image, shown in Position order
+type Image struct {
+	URL      string `json:"url"`
+	AltText  string `json:"alt_text,omitempty"`
+	Position int    `json:"position"`
+}
+
+// Product is a sellable item; what is stocked and priced are its variants
+type Product struct {
+	ID          uuid.UUID     `json:"id"`
+	CategoryID  *uuid.UUID    `json:"category_id,omitempty"`
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Brand       string        `json:"brand,omitempty"`
+	Status      ProductStatus `json:"status"`
+	Images      []Image       `json:"images"`
+	Variants    []*Variant    `json:"variants"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// Variant is one version of a product, e.g. a size or colour, identified by
+// its SKU and scanned by its barcode
+type Variant struct {
+	ID         uuid.UUID         `json:"id"`
+	ProductID  uuid.UUID         `json:"product_id"`
+	SKU        string            `json:"sku"`
+	Barcode    string            `json:"barcode,omitempty"`
+	Name       string            `json:"name"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	BasePrice  float64           `json:"base_price"` // Charged in stores without a price of their own
+	Currency   string            `json:"currency"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// Price is a variant's price in one store. A store's prices make up its
+// price list.
+type Price struct {
+	StoreID   uuid.UUID `json:"store_id"`
+	VariantID uuid.UUID `json:"variant_id"`
+	Price     float64   `json:"price"`
+	Currency  string    `json:"currency"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PricedVariant is a variant with the price a store charges for it
+type PricedVariant struct {
+	VariantID  uuid.UUID     `json:"variant_id"`
+	ProductID  uuid.UUID     `json:"product_id"`
+	CategoryID *uuid.UUID    `json:"category_id,omitempty"`
+	SKU        string        `json:"sku"`
+	Name       string        `json:"name"` // Product name, with the variant name when it has one
+	Status     ProductStatus `json:"status"`
+	Price      float64       `json:"price"`
+	Currency   string        `json:"currency"`
+	StorePrice bool          `json:"store_price"` // Whether the price comes from the store's price list
+}
+
+// Sellable reports whether the variant can be ordered
+func (p *PricedVariant) Sellable() bool {
+	return p.Status == StatusActive
+}
+
+// DisplayName returns the name of a variant of the named product as shown
+// on orders and receipts
+func DisplayName(productName, variantName string) string {
+	if variantName == "" || variantName == productName {
+		return productName
+	}
+	return productName + " - " + variantName
+}
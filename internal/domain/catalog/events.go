@@ -0,0 +1,105 @@
+package catalog
+
+import "github.com/google/uuid"
+
+// Product event types, published to the message broker with the event type
+// as routing key
+const (
+	EventProductCreated = "product.created"
+	EventProductUpdated = "product.updated" // Also sent when one of its variants changes
+	EventProductDeleted = "product.deleted"
+)
+
+// Store price event types. Deleting a store's price reverts the variant to
+// its base price in that store.
+const (
+	EventPriceSet     = "price.set"
+	EventPriceDeleted = "price.deleted"
+)
+
+// EventData is the payload of a product event on the message broker. Deleted
+// products carry only their ID.
+type EventData struct {
+	ProductID   uuid.UUID      `json:"product_id"`
+	CategoryID  *uuid.UUID     `json:"category_id,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Brand       string         `json:"brand,omitempty"`
+	Status      ProductStatus  `json:"status,omitempty"`
+	Variants    []EventVariant `json:"variants,omitempty"`
+}
+
+// EventVariant is a product variant in a product event
+type EventVariant struct {
+	ID        uuid.UUID `json:"id"`
+	SKU       string    `json:"sku"`
+	Barcode   string    `json:"barcode,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	BasePrice float64   `json:"base_price"`
+	Currency  string    `json:"currency,omitempty"`
+}
+
+// NewEventData returns the event payload of p
+func NewEventData(p *Product) EventData {
+	variants := make([]EventVariant, len(p.Variants))
+	for i, v := range p.Variants {
+		variants[i] = EventVariant{
+			ID:        v.ID,
+			SKU:       v.SKU,
+			Barcode:   v.Barcode,
+			Name:      v.Name,
+			BasePrice: v.BasePrice,
+			Currency:  v.Currency,
+		}
+	}
+	return EventData{
+		ProductID:   p.ID,
+		CategoryID:  p.CategoryID,
+		Name:        p.Name,
+		Description: p.Description,
+		Brand:       p.Brand,
+		Status:      p.Status,
+		Variants:    variants,
+	}
+}
+
+// Map returns the payload as a generic map, for publishers that take one
+func (d EventData) Map() map[string]interface{} {
+	data := map[string]interface{}{
+		"product_id": d.ProductID,
+	}
+	if d.Status == "" {
+		return data
+	}
+	data["name"] = d.Name
+	data["description"] = d.Description
+	data["brand"] = d.Brand
+	data["status"] = d.Status
+	data["variants"] = d.Variants
+	if d.CategoryID != nil {
+		data["category_id"] = *d.CategoryID
+	}
+	return data
+}
+
+// PriceEventData is the payload of a store price event. Deleted prices carry
+// no price or currency.
+type PriceEventData struct {
+	StoreID   uuid.UUID `json:"store_id"`
+	VariantID uuid.UUID `json:"variant_id"`
+	Price     float64   `json:"price,omitempty"`
+	Currency  string    `json:"currency,omitempty"`
+}
+
+// Map returns the payload as a generic map, for publishers that take one
+func (d PriceEventData) Map() map[string]interface{} {
+	data := map[string]interface{}{
+		"store_id":   d.StoreID,
+		"variant_id": d.VariantID,
+	}
+	if d.Currency != "" {
+		data["price"] = d.Price
+		data["currency"] = d.Currency
+	}
+	return data
+}
@@ -0,0 +1,35 @@
+package catalog
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Repository defines the catalog repository interface
+type Repository interface {
+	CreateCategory(ctx context.Context, category *Category) error
+	GetCategoryByID(ctx context.Context, id uuid.UUID) (*Category, error)
+	GetCategories(ctx context.Context) ([]*Category, error)
+	UpdateCategory(ctx context.Context, category *Category) error
+	DeleteCategory(ctx context.Context, id uuid.UUID) error
+
+	CreateProduct(ctx context.Context, product *Product) error
+	GetProductByID(ctx context.Context, id uuid.UUID) (*Product, error)
+	GetProducts(ctx context.Context, categoryID *uuid.UUID, limit, offset int) ([]*Product, error)
+	UpdateProduct(ctx context.Context, product *Product) error
+	DeleteProduct(ctx context.Context, id uuid.UUID) error
+
+	CreateVariant(ctx context.Context, variant *Variant) error
+	GetVariantByID(ctx context.Context, id uuid.UUID) (*Variant, error)
+	GetVariantBySKU(ctx context.Context, sku string) (*Variant, error)
+	GetVariantByBarcode(ctx context.Context, barcode string) (*Variant, error)
+	UpdateVariant(ctx context.Context, variant *Variant) error
+	DeleteVariant(ctx context.Context, id uuid.UUID) error
+
+	SetPrice(ctx context.Context, price *Price) error
+	DeletePrice(ctx context.Context, storeID, variantID uuid.UUID) error
+	GetPriceList(ctx context.Context, storeID uuid.UUID) ([]*Price, error)
+	ResolvePrices(ctx context.Context, storeID uuid.UUID, variantIDs []uuid.UUID) ([]*PricedVariant, error)
+	ExportStore(ctx context.Context, storeID uuid.UUID, fn func(*PricedVariant) error) error // Every variant at the store's prices, as of one moment
+}
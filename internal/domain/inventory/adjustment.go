@@ -0,0 +1,41 @@
+package inventory
+
+import "github.com/google/uuid"
+
+// AdjustmentReason explains a manual change to stock on hand
+type AdjustmentReason string
+
+const (
+	ReasonDamage          AdjustmentReason = "damage"
+	ReasonTheft           AdjustmentReason = "theft"
+	ReasonCountCorrection AdjustmentReason = "count_correction" // A count found more or less than on record
+)
+
+// Valid reports whether r is a known reason code
+func (r AdjustmentReason) Valid() bool {
+	switch r {
+	case ReasonDamage, ReasonTheft, ReasonCountCorrection:
+		return true
+	}
+	return false
+}
+
+// Adjustment changes the stock on hand of an inventory record by Change
+// units, provided it is still at Version. Stock cannot drop below what is
+// reserved.
+type Adjustment struct {
+	InventoryID uuid.UUID
+	Change      int // Units added; negative when removed
+	Reason      AdjustmentReason
+	Note        string
+	Version     int
+	UserID      *uuid.UUID
+}
+
+// MovementReason is the reason recorded on the adjustment's stock movement
+func (a *Adjustment) MovementReason() string {
+	if a.Note == "" {
+		return string(a.Reason)
+	}
+	return string(a.Reason) + ": " + a.Note
+}
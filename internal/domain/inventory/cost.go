@@ -0,0 +1,74 @@
+package inventory
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrAlreadyReceived is returned when the stock of a receipt line was posted
+// before
+var ErrAlreadyReceived = errors.New("stock already received")
+
+// CostLayer is stock received at one unit cost. Stock on hand is valued
+// first in, first out: what was sold or written off came from the oldest
+// layers, so what is left is in the newest ones.
+type CostLayer struct {
+	ID          uuid.UUID `json:"id"`
+	InventoryID uuid.UUID `json:"inventory_id"`
+	Quantity    int       `json:"quantity"`  // Units received
+	Remaining   int       `json:"remaining"` // Units still on hand, by FIFO
+	UnitCost    float64   `json:"unit_cost"`
+	SourceType  string    `json:"source_type"` // e.g. goods_receipt
+	SourceID    uuid.UUID `json:"source_id"`   // e.g. the goods receipt line
+	ReceivedAt  time.Time `json:"received_at"`
+}
+
+// StockReceipt adds received stock to a store's inventory at a unit cost.
+// The source identifies the receipt so that posting it again adds nothing.
+type StockReceipt struct {
+	ProductID  uuid.UUID
+	StoreID    uuid.UUID
+	Quantity   int
+	UnitCost   float64
+	SourceType string
+	SourceID   uuid.UUID
+	Reason     string
+	UserID     *uuid.UUID
+	ReceivedAt time.Time
+}
+
+// Valuation is the cost of the stock on hand
+type Valuation struct {
+	Quantity int          `json:"quantity"`
+	Value    float64      `json:"value"`
+	Layers   []*CostLayer `json:"layers"`   // Oldest first
+	Uncosted int          `json:"uncosted"` // Units on hand beyond all layers, with no known cost
+}
+
+// ValueFIFO values onHand units against layers, oldest first, by filling
+// them from the newest layer back and setting each layer's Remaining
+func ValueFIFO(onHand int, layers []*CostLayer) *Valuation {
+	v := &Valuation{Quantity: onHand, Layers: layers}
+	left := onHand
+	for i := len(layers) - 1; i >= 0; i-- {
+		layer := layers[i]
+		layer.Remaining = 0
+		if left <= 0 {
+			continue
+		}
+		layer.Remaining = layer.Quantity
+		if layer.Remaining > left {
+			layer.Remaining = left
+		}
+		left -= layer.Remaining
+		v.Value += float64(layer.Remaining) * layer.UnitCost
+	}
+	if left > 0 {
+		v.Uncosted = left
+	}
+	v.Value = math.Round(v.Value*100) / 100
+	return v
+}
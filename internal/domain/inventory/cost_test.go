@@ -0,0 +1,101 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueFIFO(t *testing.T) {
+	tests := []struct {
+		name          string
+		onHand        int
+		layers        [][2]float64 // Quantity and unit cost, oldest first
+		wantRemaining []int
+		wantValue     float64
+		wantUncosted  int
+	}{
+		{
+			name:          "nothing sold",
+			onHand:        23,
+			layers:        [][2]float64{{10, 1}, {5, 1.2}, {8, 1.5}},
+			wantRemaining: []int{10, 5, 8},
+			wantValue:     28,
+		},
+		{
+			name:          "oldest layer partly sold",
+			onHand:        18,
+			layers:        [][2]float64{{10, 1}, {5, 1.2}, {8, 1.5}},
+			wantRemaining: []int{5, 5, 8},
+			wantValue:     23,
+		},
+		{
+			name:          "sales span layers",
+			onHand:        10,
+			layers:        [][2]float64{{10, 1}, {5, 1.2}, {8, 1.5}},
+			wantRemaining: []int{0, 2, 8},
+			wantValue:     14.4,
+		},
+		{
+			name:          "only the newest layer partly left",
+			onHand:        3,
+			layers:        [][2]float64{{10, 1}, {5, 1.2}, {8, 1.5}},
+			wantRemaining: []int{0, 0, 3},
+			wantValue:     4.5,
+		},
+		{
+			name:          "sold out",
+			onHand:        0,
+			layers:        [][2]float64{{10, 1}, {5, 1.2}},
+			wantRemaining: []int{0, 0},
+		},
+		{
+			name:          "oversold",
+			onHand:        -2,
+			layers:        [][2]float64{{10, 1}},
+			wantRemaining: []int{0},
+		},
+		{
+			// Stock counted or adjusted in without a receipt has no cost
+			name:          "more on hand than the layers hold",
+			onHand:        30,
+			layers:        [][2]float64{{10, 1}, {5, 1.2}, {8, 1.5}},
+			wantRemaining: []int{10, 5, 8},
+			wantValue:     28,
+			wantUncosted:  7,
+		},
+		{
+			name:          "no layers",
+			onHand:        4,
+			wantRemaining: []int{},
+			wantUncosted:  4,
+		},
+		{
+			name:          "value rounded to cents",
+			onHand:        3,
+			layers:        [][2]float64{{3, 0.333}},
+			wantRemaining: []int{3},
+			wantValue:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layers := make([]*CostLayer, len(tt.layers))
+			for i, l := range tt.layers {
+				// Remaining is recomputed, whatever it was
+				layers[i] = &CostLayer{Quantity: int(l[0]), UnitCost: l[1], Remaining: 99}
+			}
+
+			v := ValueFIFO(tt.onHand, layers)
+			assert.Equal(t, tt.onHand, v.Quantity)
+			assert.Equal(t, tt.wantValue, v.Value)
+			assert.Equal(t, tt.wantUncosted, v.Uncosted)
+			remaining := []int{}
+			for _, layer := range v.Layers {
+				remaining = append(remaining, layer.Remaining)
+			}
+			assert.Equal(t, tt.wantRemaining, remaining)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCountNotFound   = errors.New("stock count not found")
+	ErrCountClosed     = errors.New("stock count is no longer open")
+	ErrCountInProgress = errors.New("store already has an open stock count")
+)
+
+// CountStatus is the stage of a stock count
+type CountStatus string
+
+const (
+	CountOpen       CountStatus = "open"       // Products are being counted
+	CountReconciled CountStatus = "reconciled" // Stock on hand was corrected to the counts
+	CountCancelled  CountStatus = "cancelled"  // Closed without changing stock
+)
+
+// MovementReferenceCount is the reference type of the adjustment movements
+// of a reconciled count, which reference it by ID
+const MovementReferenceCount = "stock_count"
+
+// Count is a stock take of a store. While open, staff record how many of
+// each product they counted; reconciling it sets each counted product's
+// stock on hand to its count. Products not counted are left as they are.
+type Count struct {
+	ID        uuid.UUID   `json:"id"`
+	StoreID   uuid.UUID   `json:"store_id"`
+	Status    CountStatus `json:"status"`
+	Note      string      `json:"note,omitempty"`
+	OpenedBy  *uuid.UUID  `json:"opened_by,omitempty"`
+	ClosedBy  *uuid.UUID  `json:"closed_by,omitempty"`
+	ClosedAt  *time.Time  `json:"closed_at,omitempty"` // When reconciled or cancelled
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// CountLine is the quantity of a product counted, against the stock on
+// record: the current stock while the count is open, and the stock it
+// replaced once reconciled
+type CountLine struct {
+	ProductID       uuid.UUID  `json:"product_id"`
+	InventoryID     uuid.UUID  `json:"inventory_id"`
+	CountedQuantity int        `json:"counted_quantity"`
+	SystemQuantity  int        `json:"system_quantity"`
+	Variance        int        `json:"variance"`       // Counted less on record; negative when stock is missing
+	VarianceValue   float64    `json:"variance_value"` // Variance at the record's cost price
+	CountedBy       *uuid.UUID `json:"counted_by,omitempty"`
+	CountedAt       time.Time  `json:"counted_at"`
+}
+
+// CountEntry is a product counted, recorded on a count
+type CountEntry struct {
+	ProductID uuid.UUID
+	Quantity  int
+}
+
+// CountCorrection is the change a reconciled count made to a stock record
+type CountCorrection struct {
+	Inventory *Inventory // After the correction
+	Change    int        // Units added; negative when removed
+}
+
+// CountFilter narrows a listing of counts
+type CountFilter struct {
+	StoreID *uuid.UUID
+	Status  CountStatus
+}
+
+// CountRepository persists stock counts and reconciles them with stock
+type CountRepository interface {
+	// CreateCount opens a count; ErrCountInProgress when its store already
+	// has one open
+	CreateCount(ctx context.Context, c *Count) error
+	GetCount(ctx context.Context, id uuid.UUID) (*Count, error)
+	ListCounts(ctx context.Context, filter CountFilter, limit, offset int) ([]*Count, error)
+	// RecordCounts records the quantities counted of products on an open
+	// count, replacing earlier counts of them. ErrInventoryNotFound when a
+	// product has no stock record at the count's store, recording none.
+	RecordCounts(ctx context.Context, id uuid.UUID, entries []CountEntry, userID *uuid.UUID) error
+	// ListCountLines returns the products counted with their variances, by
+	// product
+	ListCountLines(ctx context.Context, id uuid.UUID) ([]*CountLine, error)
+	// ReconcileCount closes an open count and sets the stock on hand of each
+	// product counted to its count, recording an adjustment movement per
+	// change. ErrInsufficientStock when a count is below the stock reserved,
+	// leaving everything unchanged.
+	ReconcileCount(ctx context.Context, id uuid.UUID, userID *uuid.UUID) (*Count, []CountCorrection, error)
+	// CancelCount closes an open count without changing stock
+	CancelCount(ctx context.Context, id uuid.UUID, userID *uuid.UUID) (*Count, error)
+}
@@ -0,0 +1,47 @@
+package inventory
+
+import "github.com/google/uuid"
+
+// Inventory event types, published to the message broker with the event type
+// as routing key
+const (
+	EventReserved = "inventory.reserved"
+	EventReleased = "inventory.released"
+	EventLowStock = "inventory.low_stock" // Available stock fell to the reorder point
+	EventReceived = "inventory.received"  // Stock was received from a supplier
+	EventAdjusted = "inventory.adjusted"  // Stock on hand was corrected, with a reason
+)
+
+// EventData is the payload of an inventory event on the message broker
+type EventData struct {
+	ProductID    uuid.UUID  `json:"product_id"`
+	StoreID      *uuid.UUID `json:"store_id,omitempty"`
+	Quantity     int        `json:"quantity"`            // Units reserved or released
+	Available    *int       `json:"available,omitempty"` // Available stock after the change, when known
+	ReorderPoint int        `json:"reorder_point,omitempty"`
+	Reason       string     `json:"reason,omitempty"` // Of an adjustment, or why a reservation was released
+
+	ReservationID *uuid.UUID `json:"reservation_id,omitempty"` // Of stock reserved or released through a reservation
+}
+
+// Map returns the payload as a generic map, for publishers that take one
+func (d EventData) Map() map[string]interface{} {
+	data := map[string]interface{}{
+		"product_id": d.ProductID,
+		"quantity":   d.Quantity,
+	}
+	if d.StoreID != nil {
+		data["store_id"] = *d.StoreID
+	}
+	if d.Available != nil {
+		data["available"] = *d.Available
+		data["reorder_point"] = d.ReorderPoint
+	}
+	if d.Reason != "" {
+		data["reason"] = d.Reason
+	}
+	if d.ReservationID != nil {
+		data["reservation_id"] = *d.ReservationID
+	}
+	return data
+}
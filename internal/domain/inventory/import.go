@@ -0,0 +1,38 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrAlreadyStocked is returned when an imported product already has a stock
+// record where it is imported
+var ErrAlreadyStocked = errors.New("product is already stocked")
+
+// StockKey identifies a stock record: a product at a store, or held
+// centrally when StoreID is uuid.Nil
+type StockKey struct {
+	ProductID uuid.UUID
+	StoreID   uuid.UUID
+}
+
+// KeyOf returns the key of a stock record
+func KeyOf(inv *Inventory) StockKey {
+	key := StockKey{ProductID: inv.ProductID}
+	if inv.StoreID != nil {
+		key.StoreID = *inv.StoreID
+	}
+	return key
+}
+
+// ImportRepository creates stock records in bulk, such as when a store's
+// stock is loaded from a spreadsheet
+type ImportRepository interface {
+	// Stocked returns which of keys already have a stock record
+	Stocked(ctx context.Context, keys []StockKey) (map[StockKey]bool, error)
+	// Import creates every record at once, or none of them:
+	// ErrAlreadyStocked when one already exists
+	Import(ctx context.Context, records []*Inventory) error
+}
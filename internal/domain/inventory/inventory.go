@@ -8,6 +8,7 @@ import (
 )
 
 var (
+	ErrInventoryNotFound = errors.New("inventory not found")
 	ErrInsufficientStock = errors.New("insufficient stock")
 	ErrVersionConflict   = errors.New("version conflict - optimistic locking failed")
 )
@@ -40,13 +41,16 @@ type Inventory struct {
 	UpdatedAt        time.Time `json:"updated_at"`
 }
 
+// Stock changes keep AvailableQuantity in step with the generated column. The
+// version is left alone; UpdateWithVersion matches on it and then increments it.
+
 // Reserve reserves quantity from available stock
 func (i *Inventory) Reserve(quantity int) error {
 	if i.AvailableQuantity < quantity {
 		return ErrInsufficientStock
 	}
 	i.ReservedQuantity += quantity
-	i.Version++
+	i.AvailableQuantity -= quantity
 	return nil
 }
 
@@ -54,14 +58,14 @@ func (i *Inventory) Reserve(quantity int) error {
 func (i *Inventory) Release(quantity int) {
 	if i.ReservedQuantity >= quantity {
 		i.ReservedQuantity -= quantity
-		i.Version++
+		i.AvailableQuantity += quantity
 	}
 }
 
 // Add adds quantity to inventory
 func (i *Inventory) Add(quantity int) {
 	i.Quantity += quantity
-	i.Version++
+	i.AvailableQuantity += quantity
 }
 
 // Subtract subtracts quantity from inventory
@@ -70,10 +74,16 @@ func (i *Inventory) Subtract(quantity int) error {
 		return ErrInsufficientStock
 	}
 	i.Quantity -= quantity
-	i.Version++
+	i.AvailableQuantity -= quantity
 	return nil
 }
 
+// SetQuantity replaces the on-hand quantity, as after a stock count
+func (i *Inventory) SetQuantity(quantity int) {
+	i.Quantity = quantity
+	i.AvailableQuantity = quantity - i.ReservedQuantity
+}
+
 // NeedsReorder checks if inventory needs reorder
 func (i *Inventory) NeedsReorder() bool {
 	return i.AvailableQuantity <= i.ReorderPoint
@@ -15,10 +15,22 @@ type Repository interface {
 	GetByStoreID(ctx context.Context, storeID uuid.UUID, limit, offset int) ([]*Inventory, error)
 	Update(ctx context.Context, inventory *Inventory) error
 	UpdateWithVersion(ctx context.Context, inventory *Inventory) error // Optimistic locking
-	ReserveStock(ctx context.Context, productID uuid.UUID, storeID *uuid.UUID, quantity int) error
+	ReserveStock(ctx context.Context, productID uuid.UUID, storeID *uuid.UUID, quantity int) (*Inventory, error)
 	ReleaseStock(ctx context.Context, productID uuid.UUID, storeID *uuid.UUID, quantity int) error
 	RecordMovement(ctx context.Context, movement *StockMovement) error
+	// RecordMovements records movements in one round trip, all of them or,
+	// when one fails, none
+	RecordMovements(ctx context.Context, movements []*StockMovement) error
 	GetLowStockItems(ctx context.Context, storeID *uuid.UUID) ([]*Inventory, error)
+	// ListLowStock returns the stock of every store at or below its reorder
+	// point, for reordering
+	ListLowStock(ctx context.Context) ([]*Inventory, error)
+	ReceiveStock(ctx context.Context, receipt *StockReceipt) (*Inventory, error) // ErrAlreadyReceived when the source was posted before
+	// AdjustStock fails with ErrVersionConflict when the record is no longer at
+	// the adjustment's version, and ErrInsufficientStock below what is reserved
+	AdjustStock(ctx context.Context, adjustment *Adjustment) (*Inventory, *StockMovement, error)
+	GetCostLayers(ctx context.Context, inventoryID uuid.UUID) ([]*CostLayer, error)
+	ExportStore(ctx context.Context, storeID uuid.UUID, fn func(*Inventory) error) error // Every stock level of the store, as of one moment
 }
 
 // StockMovement represents a stock movement record
@@ -0,0 +1,96 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrReservationNotFound = errors.New("stock reservation not found")
+	ErrReservationExists   = errors.New("stock reservation already exists")
+	ErrReservationClosed   = errors.New("stock reservation is no longer held")
+)
+
+// ReservationStatus is the stage of a stock reservation
+type ReservationStatus string
+
+const (
+	ReservationHeld      ReservationStatus = "held"      // Reserved until it expires
+	ReservationCommitted ReservationStatus = "committed" // Kept for its order; it no longer expires
+	ReservationReleased  ReservationStatus = "released"
+	ReservationExpired   ReservationStatus = "expired" // Released by the sweeper, never committed
+)
+
+// ReasonReservationExpired is the reason of the released events of
+// reservations the sweeper released
+const ReasonReservationExpired = "reservation expired"
+
+// Reservation holds stock of a product for an order until it is committed
+// or released. A reservation neither committed nor released by ExpiresAt is
+// released by the sweeper, so that an abandoned order gives its stock back.
+type Reservation struct {
+	ID            uuid.UUID         `json:"id"`
+	InventoryID   uuid.UUID         `json:"inventory_id"`
+	ProductID     uuid.UUID         `json:"product_id"`
+	StoreID       *uuid.UUID        `json:"store_id,omitempty"`
+	Quantity      int               `json:"quantity"`
+	Status        ReservationStatus `json:"status"`
+	ReferenceID   *uuid.UUID        `json:"reference_id,omitempty"` // Order the stock is held for
+	ReferenceType string            `json:"reference_type,omitempty"`
+	ExpiresAt     time.Time         `json:"expires_at"`
+	CommittedAt   *time.Time        `json:"committed_at,omitempty"`
+	ClosedAt      *time.Time        `json:"closed_at,omitempty"` // When released or expired
+	CreatedAt     time.Time         `json:"created_at"`
+	TenantID      string            `json:"-"` // Set on reservations listed across tenants
+}
+
+// ReservationErrors holds why items of a batch could not be reserved, by
+// their index in the batch
+type ReservationErrors map[int]error
+
+func (e ReservationErrors) Error() string {
+	indexes := make([]int, 0, len(e))
+	for i := range e {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	msgs := make([]string, len(indexes))
+	for n, i := range indexes {
+		msgs[n] = fmt.Sprintf("item %d: %v", i, e[i])
+	}
+	return "failed to reserve batch: " + strings.Join(msgs, "; ")
+}
+
+// ReservationRepository persists stock reservations along with the stock
+// they hold
+type ReservationRepository interface {
+	// CreateReservation reserves a reservation's quantity of its product and
+	// records it as held; ErrInsufficientStock when too little is available,
+	// ErrReservationExists when its ID was taken, both leaving stock unchanged
+	CreateReservation(ctx context.Context, r *Reservation) (*Inventory, error)
+	// CreateReservations reserves every reservation of a batch, or none:
+	// ReservationErrors tells which could not be reserved and why. It returns
+	// each reservation's stock after the batch.
+	CreateReservations(ctx context.Context, rs []*Reservation) ([]*Inventory, error)
+	GetReservation(ctx context.Context, id uuid.UUID) (*Reservation, error)
+	// CommitReservation keeps a held reservation's stock reserved past its
+	// expiry; committing it again is a no-op. ErrReservationClosed once
+	// released or expired.
+	CommitReservation(ctx context.Context, id uuid.UUID, at time.Time) (*Reservation, error)
+	// ReleaseReservation makes a held or committed reservation's stock
+	// available again; ErrReservationClosed once released or expired
+	ReleaseReservation(ctx context.Context, id uuid.UUID, at time.Time) (*Reservation, *Inventory, error)
+	// ListExpiredReservations returns held reservations past their expiry at
+	// at, soonest expired first, across tenants
+	ListExpiredReservations(ctx context.Context, at time.Time, limit int) ([]*Reservation, error)
+	// ExpireReservation releases a held reservation past its expiry at at;
+	// ErrReservationClosed when it was committed or released first
+	ExpireReservation(ctx context.Context, id uuid.UUID, at time.Time) (*Reservation, *Inventory, error)
+}
@@ -0,0 +1,76 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTransferNotFound          = errors.New("stock transfer not found")
+	ErrInvalidTransferTransition = errors.New("stock transfer cannot make this change in its current status")
+)
+
+// TransferStatus is the stage of a stock transfer
+type TransferStatus string
+
+const (
+	TransferRequested TransferStatus = "requested"
+	TransferInTransit TransferStatus = "in_transit" // Dispatched; the stock is held at the source
+	TransferReceived  TransferStatus = "received"   // The stock moved to the destination
+	TransferCancelled TransferStatus = "cancelled"
+)
+
+// MovementReferenceTransfer is the reference type of the stock movements of
+// a transfer, which reference it by ID
+const MovementReferenceTransfer = "stock_transfer"
+
+// Transfer moves stock of some products from one store to another.
+// Dispatching it reserves the stock at the source; receiving it takes the
+// stock off the source and adds it to the destination.
+type Transfer struct {
+	ID                 uuid.UUID      `json:"id"`
+	SourceStoreID      uuid.UUID      `json:"source_store_id"`
+	DestinationStoreID uuid.UUID      `json:"destination_store_id"`
+	Status             TransferStatus `json:"status"`
+	Items              []TransferItem `json:"items"`
+	Note               string         `json:"note,omitempty"`
+	RequestedBy        *uuid.UUID     `json:"requested_by,omitempty"`
+	DispatchedBy       *uuid.UUID     `json:"dispatched_by,omitempty"`
+	ReceivedBy         *uuid.UUID     `json:"received_by,omitempty"`
+	DispatchedAt       *time.Time     `json:"dispatched_at,omitempty"`
+	ReceivedAt         *time.Time     `json:"received_at,omitempty"`
+	CancelledAt        *time.Time     `json:"cancelled_at,omitempty"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+}
+
+// TransferItem is the quantity of a product moved by a transfer
+type TransferItem struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+}
+
+// TransferFilter narrows a listing of transfers
+type TransferFilter struct {
+	StoreID *uuid.UUID // Transfers from or to the store
+	Status  TransferStatus
+}
+
+// TransferRepository persists stock transfers and moves their stock
+type TransferRepository interface {
+	CreateTransfer(ctx context.Context, t *Transfer) error
+	GetTransfer(ctx context.Context, id uuid.UUID) (*Transfer, error)
+	ListTransfers(ctx context.Context, filter TransferFilter, limit, offset int) ([]*Transfer, error)
+	// DispatchTransfer reserves a requested transfer's stock at the source
+	// and moves it in transit; ErrInsufficientStock when the source has too
+	// little available, leaving everything unchanged
+	DispatchTransfer(ctx context.Context, id uuid.UUID, userID *uuid.UUID) (*Transfer, error)
+	// ReceiveTransfer takes an in-transit transfer's stock off the source and
+	// adds it to the destination, recording a movement on each side
+	ReceiveTransfer(ctx context.Context, id uuid.UUID, userID *uuid.UUID) (*Transfer, error)
+	// CancelTransfer cancels a transfer not yet dispatched
+	CancelTransfer(ctx context.Context, id uuid.UUID) (*Transfer, error)
+}
@@ -0,0 +1,149 @@
+package loyalty
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInsufficientPoints is returned when a redemption exceeds the balance
+	ErrInsufficientPoints = errors.New("insufficient loyalty points")
+	// ErrAlreadyRedeemed is returned when points were already redeemed for an order
+	ErrAlreadyRedeemed = errors.New("loyalty points already redeemed for order")
+	// ErrBelowMinimum is returned when a redemption is smaller than the program allows
+	ErrBelowMinimum = errors.New("loyalty redemption below minimum")
+	// ErrExceedsLimit is returned when a redemption is worth more than the program allows for an order
+	ErrExceedsLimit = errors.New("loyalty redemption exceeds order limit")
+	// ErrOtherTenant is returned when points are earned by a member whose account belongs to another tenant
+	ErrOtherTenant = errors.New("loyalty account belongs to another tenant")
+)
+
+// TransactionType represents a kind of ledger entry
+type TransactionType string
+
+const (
+	TypeEarn    TransactionType = "earn"    // Points for a completed order
+	TypeRedeem  TransactionType = "redeem"  // Points spent as an order discount
+	TypeRelease TransactionType = "release" // A redemption returned when its order was cancelled
+	TypeReverse TransactionType = "reverse" // Earned points taken back when their order was refunded
+	TypeExpire  TransactionType = "expire"  // Points not spent before they expired
+)
+
+// Account represents a member's points balance
+type Account struct {
+	UserID           uuid.UUID  `json:"user_id"`
+	Balance          int64      `json:"balance"`
+	LifetimePoints   int64      `json:"lifetime_points"`
+	Tier             string     `json:"tier"`
+	NextTier         string     `json:"next_tier,omitempty"`
+	PointsToNextTier int64      `json:"points_to_next_tier,omitempty"`
+	ExpiringPoints   int64      `json:"expiring_points"`          // Points expiring within the program's notice period
+	NextExpiryAt     *time.Time `json:"next_expiry_at,omitempty"` // When the next unspent points expire
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// Transaction represents a ledger entry. Points are positive for credits and
+// negative for debits.
+type Transaction struct {
+	ID          uuid.UUID       `json:"id"`
+	UserID      uuid.UUID       `json:"user_id"`
+	Type        TransactionType `json:"type"`
+	Points      int64           `json:"points"`
+	OrderID     *uuid.UUID      `json:"order_id,omitempty"`
+	Amount      float64         `json:"amount,omitempty"` // Order total earned on, or discount redeemed for
+	Currency    string          `json:"currency,omitempty"`
+	Description string          `json:"description,omitempty"`
+	ExpiresAt   *time.Time      `json:"expires_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Redemption represents points spent as a discount on an order
+type Redemption struct {
+	OrderID  uuid.UUID `json:"order_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Points   int64     `json:"points"`
+	Amount   float64   `json:"amount"`
+	Currency string    `json:"currency"`
+}
+
+// Tier represents a membership level reached at a number of lifetime points
+type Tier struct {
+	Name       string  `json:"name"`
+	MinPoints  int64   `json:"min_points"`
+	Multiplier float64 `json:"multiplier"` // Scales the points earned by members of the tier
+}
+
+// Program holds the accrual, redemption and expiry rules
+type Program struct {
+	PointsPerUnit      float64       // Points earned per currency unit spent
+	PointValue         float64       // Currency value of one point when redeemed
+	MinRedemption      int64         // Fewest points redeemable at once
+	MaxRedemptionShare float64       // Largest share of an order total payable with points
+	PointsLifetime     time.Duration // How long earned points last; 0 never expires them
+	ExpiryNotice       time.Duration // How far ahead balances report expiring points
+	Tiers              []Tier        // Ascending by MinPoints
+}
+
+// TierFor returns the tier of a member with lifetime points, and the next
+// tier up, if any
+func (p *Program) TierFor(lifetime int64) (current Tier, next *Tier) {
+	current = Tier{Name: "member", Multiplier: 1}
+	for i, t := range p.Tiers {
+		if lifetime < t.MinPoints {
+			return current, &p.Tiers[i]
+		}
+		current = t
+	}
+	return current, nil
+}
+
+// PointsFor returns the points earned on an order total by a member with
+// lifetime points
+func (p *Program) PointsFor(amount float64, lifetime int64) int64 {
+	tier, _ := p.TierFor(lifetime)
+	multiplier := tier.Multiplier
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+	return int64(math.Floor(amount * p.PointsPerUnit * multiplier))
+}
+
+// Value returns the discount points are worth, in currency units
+func (p *Program) Value(points int64) float64 {
+	return math.Round(float64(points)*p.PointValue*100) / 100
+}
+
+// CheckRedemption checks that points may be redeemed against an order total
+func (p *Program) CheckRedemption(points int64, orderTotal float64) error {
+	if points < p.MinRedemption || points <= 0 {
+		return ErrBelowMinimum
+	}
+	if p.Value(points) > orderTotal*p.MaxRedemptionShare {
+		return ErrExceedsLimit
+	}
+	return nil
+}
+
+// ExpiresAt returns when points earned at earnedAt expire, or nil if they don't
+func (p *Program) ExpiresAt(earnedAt time.Time) *time.Time {
+	if p.PointsLifetime <= 0 {
+		return nil
+	}
+	expiresAt := earnedAt.Add(p.PointsLifetime)
+	return &expiresAt
+}
+
+// Describe sets the tier fields of an account from its lifetime points
+func (p *Program) Describe(a *Account) {
+	tier, next := p.TierFor(a.LifetimePoints)
+	a.Tier = tier.Name
+	a.NextTier, a.PointsToNextTier = "", 0
+	if next != nil {
+		a.NextTier = next.Name
+		a.PointsToNextTier = next.MinPoints - a.LifetimePoints
+	}
+}
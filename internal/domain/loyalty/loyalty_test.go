@@ -0,0 +1,113 @@
+package loyalty
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testProgram earns a point per unit, worth a cent, with silver from 1000
+// lifetime points and gold from 5000
+var testProgram = Program{
+	PointsPerUnit:      1,
+	PointValue:         0.01,
+	MinRedemption:      100,
+	MaxRedemptionShare: 0.5,
+	Tiers: []Tier{
+		{Name: "silver", MinPoints: 1000, Multiplier: 1.25},
+		{Name: "gold", MinPoints: 5000, Multiplier: 1.5},
+	},
+}
+
+func TestPointsForRoundsDown(t *testing.T) {
+	tests := []struct {
+		name     string
+		program  Program
+		amount   float64
+		lifetime int64
+		want     int64
+	}{
+		{name: "whole units", program: testProgram, amount: 25, want: 25},
+		{name: "cents are not earned on", program: testProgram, amount: 25.99, want: 25},
+		{name: "under a unit", program: testProgram, amount: 0.99, want: 0},
+		{name: "silver multiplier", program: testProgram, amount: 25.99, lifetime: 1000, want: 32}, // 32.4875
+		{name: "gold multiplier", program: testProgram, amount: 9.99, lifetime: 5000, want: 14},    // 14.985
+		{name: "fractional rate", program: Program{PointsPerUnit: 0.1}, amount: 19.99, want: 1},
+		{name: "tier without multiplier", program: Program{PointsPerUnit: 2, Tiers: []Tier{{Name: "staff"}}}, amount: 3.5, want: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.program.PointsFor(tt.amount, tt.lifetime))
+		})
+	}
+}
+
+func TestTierPromotionThresholds(t *testing.T) {
+	tests := []struct {
+		lifetime int64
+		tier     string
+		next     string
+		toNext   int64
+	}{
+		{lifetime: 0, tier: "member", next: "silver", toNext: 1000},
+		{lifetime: 999, tier: "member", next: "silver", toNext: 1},
+		{lifetime: 1000, tier: "silver", next: "gold", toNext: 4000},
+		{lifetime: 4999, tier: "silver", next: "gold", toNext: 1},
+		{lifetime: 5000, tier: "gold"},
+		{lifetime: 1_000_000, tier: "gold"},
+	}
+
+	for _, tt := range tests {
+		account := &Account{LifetimePoints: tt.lifetime, NextTier: "stale", PointsToNextTier: 42}
+		testProgram.Describe(account)
+		assert.Equal(t, tt.tier, account.Tier, "lifetime %d", tt.lifetime)
+		assert.Equal(t, tt.next, account.NextTier, "lifetime %d", tt.lifetime)
+		assert.Equal(t, tt.toNext, account.PointsToNextTier, "lifetime %d", tt.lifetime)
+	}
+
+	// Without tiers everyone is a member
+	account := &Account{LifetimePoints: 10_000}
+	(&Program{}).Describe(account)
+	assert.Equal(t, "member", account.Tier)
+	assert.Empty(t, account.NextTier)
+}
+
+func TestCheckRedemption(t *testing.T) {
+	tests := []struct {
+		name       string
+		points     int64
+		orderTotal float64
+		want       error
+	}{
+		{name: "at the minimum", points: 100, orderTotal: 10},
+		{name: "below the minimum", points: 99, orderTotal: 10, want: ErrBelowMinimum},
+		{name: "nothing", points: 0, orderTotal: 10, want: ErrBelowMinimum},
+		{name: "negative", points: -500, orderTotal: 10, want: ErrBelowMinimum},
+		{name: "up to the order share", points: 500, orderTotal: 10},
+		{name: "beyond the order share", points: 501, orderTotal: 10, want: ErrExceedsLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, testProgram.CheckRedemption(tt.points, tt.orderTotal))
+		})
+	}
+}
+
+func TestValueRoundsToCents(t *testing.T) {
+	assert.Equal(t, 1.23, testProgram.Value(123))
+	assert.Equal(t, 0.33, (&Program{PointValue: 1.0 / 30}).Value(10))
+}
+
+func TestExpiresAt(t *testing.T) {
+	earnedAt := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+	assert.Nil(t, testProgram.ExpiresAt(earnedAt))
+
+	program := Program{PointsLifetime: 365 * 24 * time.Hour}
+	expiresAt := program.ExpiresAt(earnedAt)
+	require.NotNil(t, expiresAt)
+	assert.Equal(t, time.Date(2027, 1, 31, 12, 0, 0, 0, time.UTC), *expiresAt)
+}
@@ -0,0 +1,30 @@
+package loyalty
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository defines the loyalty repository interface
+type Repository interface {
+	// GetAccount returns a member's account, with the points expiring before
+	// expiringBefore. Members without one get an empty account.
+	GetAccount(ctx context.Context, userID uuid.UUID, expiringBefore time.Time) (*Account, error)
+	GetTransactions(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Transaction, error)
+	// Earn credits an earn transaction, once per order. It reports whether
+	// the points were credited.
+	Earn(ctx context.Context, tx *Transaction) (bool, error)
+	// Redeem debits a redemption, once per order
+	Redeem(ctx context.Context, r *Redemption) error
+	// ReleaseRedemption returns the points redeemed for an order. It reports
+	// whether there were any to return.
+	ReleaseRedemption(ctx context.Context, orderID uuid.UUID, expiresAt *time.Time) (bool, error)
+	// ReverseEarn takes back the points earned on an order, as far as the
+	// balance allows. It reports whether there were any to take back.
+	ReverseEarn(ctx context.Context, orderID uuid.UUID) (bool, error)
+	// ExpirePoints expires up to limit credits due by now and returns how
+	// many credits and points it expired
+	ExpirePoints(ctx context.Context, now time.Time, limit int) (credits int, points int64, err error)
+}
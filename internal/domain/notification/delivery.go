@@ -0,0 +1,59 @@
+package notification
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeliveryStatus is how far a delivery got
+type DeliveryStatus string
+
+const (
+	DeliveryPending DeliveryStatus = "pending" // Awaiting its next attempt
+	DeliverySent    DeliveryStatus = "sent"
+	DeliveryFailed  DeliveryStatus = "failed" // Out of attempts, or refused for good
+)
+
+// ErrDeliveryNotFound is returned for a delivery receipt matching no delivery
+var ErrDeliveryNotFound = errors.New("delivery not found")
+
+// Delivery is a notification sent on one channel outside the app, attempted
+// until sent or out of attempts
+type Delivery struct {
+	ID                uuid.UUID      `json:"id"`
+	NotificationID    uuid.UUID      `json:"notification_id"`
+	Channel           Channel        `json:"channel"`
+	Status            DeliveryStatus `json:"status"`
+	Recipient         string         `json:"recipient,omitempty"` // Where the last attempt went, such as an email address
+	Attempts          int            `json:"attempts"`
+	NextAttemptAt     *time.Time     `json:"next_attempt_at,omitempty"`
+	LastAttemptAt     *time.Time     `json:"last_attempt_at,omitempty"`
+	LastError         string         `json:"last_error,omitempty"`
+	SentAt            *time.Time     `json:"sent_at,omitempty"`
+	ProviderMessageID string         `json:"provider_message_id,omitempty"` // On channels reporting receipts, such as SMS
+	Receipt           string         `json:"receipt,omitempty"`             // The provider's last report, such as delivered or undelivered
+	DeliveredAt       *time.Time     `json:"delivered_at,omitempty"`        // When the provider handed it to the device
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
+
+// DueDelivery is a delivery claimed for an attempt, with the notification it
+// sends and the tenant both belong to
+type DueDelivery struct {
+	Delivery     *Delivery
+	Notification *Notification
+	TenantID     string
+}
+
+// Receipt is a provider's final report on a sent delivery: whether it reached
+// the recipient's device, or why not
+type Receipt struct {
+	DeliveryID        uuid.UUID
+	ProviderMessageID string
+	Status            string // The provider's, such as delivered
+	Delivered         bool
+	Error             string // Why an undelivered message was not
+	At                time.Time
+}
@@ -0,0 +1,25 @@
+package notification
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrDeviceTokenNotFound is returned for a device token not registered to
+// the user
+var ErrDeviceTokenNotFound = errors.New("device token not found")
+
+// DeviceToken registers an install of a mobile app with a push provider, so
+// that its user's notifications on the push channel reach it
+type DeviceToken struct {
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Provider   string    `json:"provider"` // fcm or apns; see pkg/push
+	Token      string    `json:"token"`    // Issued to the app by the provider
+	DeviceName string    `json:"device_name,omitempty"`
+	AppVersion string    `json:"app_version,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"` // When last registered
+}
@@ -0,0 +1,50 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventRequested is published to the message broker, with the event type as
+// routing key, by services asking the notification service to notify a user
+const EventRequested = "notification.requested"
+
+// RequestedData is the payload of a notification.requested event. Its ID,
+// or without one the event's, makes a redelivered event create the
+// notification once.
+type RequestedData struct {
+	ID        uuid.UUID              `json:"id,omitempty"`
+	UserID    uuid.UUID              `json:"user_id"`
+	Type      NotificationType       `json:"type"`
+	Title     string                 `json:"title"`
+	Message   string                 `json:"message"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Channels  []Channel              `json:"channels"`
+	Priority  Priority               `json:"priority,omitempty"` // Normal when empty
+	ExpiresAt *time.Time             `json:"expires_at,omitempty"`
+}
+
+// Map returns the payload as the data of a notification.requested event
+func (d RequestedData) Map() map[string]interface{} {
+	data := map[string]interface{}{
+		"user_id":  d.UserID,
+		"type":     d.Type,
+		"title":    d.Title,
+		"message":  d.Message,
+		"channels": d.Channels,
+	}
+	if d.ID != uuid.Nil {
+		data["id"] = d.ID
+	}
+	if d.Data != nil {
+		data["data"] = d.Data
+	}
+	if d.Priority != "" {
+		data["priority"] = d.Priority
+	}
+	if d.ExpiresAt != nil {
+		data["expires_at"] = *d.ExpiresAt
+	}
+	return data
+}
@@ -2,18 +2,58 @@ package notification
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 // Repository defines the notification repository interface
 type Repository interface {
-	Create(ctx context.Context, notification *Notification) error
+	// Create creates a notification and queues a pending delivery of it on
+	// each of deliver, due at once. Creating a notification with the ID of
+	// one created before does nothing.
+	Create(ctx context.Context, notification *Notification, deliver []Channel) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Notification, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int, unreadOnly bool) ([]*Notification, error)
 	MarkAsRead(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
 	MarkAllAsRead(ctx context.Context, userID uuid.UUID) error
 	Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
 	CountUnread(ctx context.Context, userID uuid.UUID) (int, error)
+
+	// ClaimDue hands up to limit pending deliveries on channel that are due by
+	// now to the caller, holding them off other claims for lease. Rows are
+	// locked with SKIP LOCKED, so instances claiming at once get different
+	// deliveries.
+	ClaimDue(ctx context.Context, channel Channel, now time.Time, lease time.Duration, limit int) ([]*DueDelivery, error)
+	// ClaimPending claims the pending deliveries of a notification that are
+	// due by now, as ClaimDue does
+	ClaimPending(ctx context.Context, notificationID uuid.UUID, now time.Time, lease time.Duration) ([]*DueDelivery, error)
+	// RecordAttempt saves a delivery as its last attempt left it; a sent
+	// delivery marks its notification sent
+	RecordAttempt(ctx context.Context, d *Delivery) error
+	// ListDeliveries returns the deliveries of a notification
+	ListDeliveries(ctx context.Context, notificationID uuid.UUID) ([]*Delivery, error)
+	// CountSent counts the deliveries on channel sent to a user since since
+	CountSent(ctx context.Context, channel Channel, userID uuid.UUID, since time.Time) (int, error)
+	// RecordReceipt saves a delivery receipt onto the delivery it reports on;
+	// an undelivered one fails the delivery. It fails with
+	// ErrDeliveryNotFound when no delivery sent as the receipt's message
+	// matches.
+	RecordReceipt(ctx context.Context, r *Receipt) error
+}
+
+// DeviceTokenRepository defines the device token repository interface
+type DeviceTokenRepository interface {
+	// RegisterDeviceToken saves a device token. A token registered before,
+	// such as by another user of a shared register, moves to t's user and
+	// keeps its ID.
+	RegisterDeviceToken(ctx context.Context, t *DeviceToken) error
+	// ListDeviceTokens returns a user's device tokens, newest first
+	ListDeviceTokens(ctx context.Context, userID uuid.UUID) ([]*DeviceToken, error)
+	// DeleteDeviceToken removes a user's device token, or fails with
+	// ErrDeviceTokenNotFound
+	DeleteDeviceToken(ctx context.Context, id uuid.UUID, userID uuid.UUID) error
+	// DeleteDeviceTokens removes device tokens the provider no longer accepts
+	DeleteDeviceTokens(ctx context.Context, ids []uuid.UUID) error
 }
 
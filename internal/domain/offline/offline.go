@@ -0,0 +1,62 @@
+package offline
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrCursorExpired is returned when a terminal pulls changes from before its
+// feed's horizon, past deletions since pruned; it must pull a full snapshot
+var ErrCursorExpired = errors.New("sync cursor is older than the change feed's horizon")
+
+// Entity is the kind of data a change carries
+type Entity string
+
+const (
+	EntityProduct Entity = "product" // A product and its variants, in every store's feed
+	EntityPrice   Entity = "price"   // A store's price of a variant; deleted when the base price applies again
+	EntityStock   Entity = "stock"   // A variant's available stock in a store
+)
+
+// Op is what a change does to its entity
+type Op string
+
+const (
+	OpUpsert Op = "upsert"
+	OpDelete Op = "delete" // A tombstone, kept until pruned
+)
+
+// Change is the latest change of one entity in a tenant's change feed.
+// Changes are numbered in the order they were recorded; recording a newer
+// change of the same entity replaces it under a new number.
+type Change struct {
+	Seq       int64           `json:"seq"`
+	Entity    Entity          `json:"entity"`
+	EntityID  uuid.UUID       `json:"entity_id"`
+	StoreID   *uuid.UUID      `json:"store_id,omitempty"` // Nil for entities every store shares
+	Op        Op              `json:"op"`
+	Data      json.RawMessage `json:"data,omitempty"` // The entity's state; none for tombstones
+	Version   int64           `json:"-"`              // Time of the event recorded, so older events never replace newer ones
+	ChangedAt time.Time       `json:"changed_at"`
+}
+
+// Page is a batch of a store's changes in sequence order
+type Page struct {
+	Changes []*Change `json:"changes"`
+	Cursor  int64     `json:"cursor"`   // Where the next pull starts from
+	HasMore bool      `json:"has_more"` // Whether changes past the cursor are waiting
+}
+
+// Price is the data of a price change
+type Price struct {
+	Price    float64 `json:"price"`
+	Currency string  `json:"currency"`
+}
+
+// Stock is the data of a stock change
+type Stock struct {
+	Available int `json:"available"`
+}
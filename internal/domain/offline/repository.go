@@ -0,0 +1,25 @@
+package offline
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository defines the change feed repository interface
+type Repository interface {
+	// Record adds a change to the feed, setting its sequence number. A change
+	// older than the entity's latest is dropped and reports false.
+	Record(ctx context.Context, change *Change) (bool, error)
+
+	// Changes returns up to limit changes of a store's feed after since,
+	// those of shared entities included. Since 0 pulls a full snapshot,
+	// without tombstones; a since below the feed's horizon fails with
+	// ErrCursorExpired.
+	Changes(ctx context.Context, storeID uuid.UUID, since int64, limit int) (*Page, error)
+
+	// PruneTombstones deletes tombstones recorded before before, across
+	// tenants, raising their feeds' horizons, and returns how many it deleted
+	PruneTombstones(ctx context.Context, before time.Time) (int64, error)
+}
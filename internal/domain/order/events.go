@@ -0,0 +1,97 @@
+package order
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Order event types, sent to webhooks and published to the message broker
+// with the event type as routing key
+const (
+	EventCreated   = "order.created"
+	EventUpdated   = "order.updated"
+	EventCancelled = "order.cancelled"
+	EventCompleted = "order.completed" // The order was delivered
+	EventRefunded  = "order.refunded"
+)
+
+// EventData is the payload of an order event on the message broker
+type EventData struct {
+	OrderID       uuid.UUID   `json:"order_id"`
+	TenantID      string      `json:"tenant_id"`
+	UserID        uuid.UUID   `json:"user_id"`
+	StoreID       uuid.UUID   `json:"store_id"`
+	Status        OrderStatus `json:"status"`
+	TotalAmount   float64     `json:"total_amount"`
+	Currency      string      `json:"currency"`
+	LoyaltyPoints int64       `json:"loyalty_points"`
+	ShiftID       *uuid.UUID  `json:"shift_id,omitempty"`
+	Tender        string      `json:"tender,omitempty"`
+	TaxAmount     float64     `json:"tax_amount"`
+	Taxes         []TaxLine   `json:"taxes,omitempty"`
+	Items         []EventItem `json:"items"`
+	CreatedAt     time.Time   `json:"created_at"` // When the order was placed
+}
+
+// EventItem is an order line in an order event
+type EventItem struct {
+	ProductID string  `json:"product_id"`
+	Name      string  `json:"name"`
+	Quantity  int     `json:"quantity"`
+	Subtotal  float64 `json:"subtotal"` // After item discounts
+}
+
+// NewEventData returns the event payload of o
+func NewEventData(o *Order) EventData {
+	items := make([]EventItem, len(o.Items))
+	for i, item := range o.Items {
+		items[i] = EventItem{
+			ProductID: item.ProductID,
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			Subtotal:  item.Subtotal,
+		}
+	}
+	return EventData{
+		OrderID:       o.ID,
+		TenantID:      o.TenantID,
+		UserID:        o.UserID,
+		StoreID:       o.StoreID,
+		Status:        o.Status,
+		TotalAmount:   o.TotalAmount,
+		Currency:      o.Currency,
+		LoyaltyPoints: o.LoyaltyPoints,
+		ShiftID:       o.ShiftID,
+		Tender:        o.Tender,
+		TaxAmount:     o.TaxAmount,
+		Taxes:         o.Taxes,
+		Items:         items,
+		CreatedAt:     o.CreatedAt,
+	}
+}
+
+// Map returns the payload as a generic map, for publishers that take one
+func (d EventData) Map() map[string]interface{} {
+	m := map[string]interface{}{
+		"order_id":       d.OrderID,
+		"tenant_id":      d.TenantID,
+		"user_id":        d.UserID,
+		"store_id":       d.StoreID,
+		"status":         d.Status,
+		"total_amount":   d.TotalAmount,
+		"currency":       d.Currency,
+		"loyalty_points": d.LoyaltyPoints,
+		"tax_amount":     d.TaxAmount,
+		"items":          d.Items,
+		"created_at":     d.CreatedAt,
+	}
+	if len(d.Taxes) > 0 {
+		m["taxes"] = d.Taxes
+	}
+	if d.ShiftID != nil {
+		m["shift_id"] = d.ShiftID
+		m["tender"] = d.Tender
+	}
+	return m
+}
@@ -0,0 +1,80 @@
+package order
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChangeType is the kind of change recorded in an order's history
+type ChangeType string
+
+const (
+	ChangeCreated       ChangeType = "created"        // Carries the new order
+	ChangeUpdated       ChangeType = "updated"        // Carries the order as updated
+	ChangeStatusChanged ChangeType = "status_changed" // Carries the new status
+	ChangeDeleted       ChangeType = "deleted"        // The order was cancelled by deleting it
+)
+
+// Change is one entry of an order's history. Versions count from 1 with no
+// gaps, so replaying the changes in version order rebuilds the order.
+type Change struct {
+	Version    int         `json:"version"`
+	Type       ChangeType  `json:"type"`
+	Order      *Order      `json:"order,omitempty"`  // Created and updated
+	Status     OrderStatus `json:"status,omitempty"` // Status changed
+	OccurredAt time.Time   `json:"occurred_at"`
+}
+
+// Apply returns the state of an order after c, given its state before;
+// before is nil for a created change. before is not modified.
+func (c *Change) Apply(before *Order) *Order {
+	var after Order
+	switch {
+	case c.Order != nil:
+		after = *c.Order
+		if before != nil {
+			after.CreatedAt = before.CreatedAt
+		}
+	case before != nil:
+		after = *before
+	}
+
+	after.UpdatedAt = c.OccurredAt
+	switch c.Type {
+	case ChangeStatusChanged:
+		after.Status = c.Status
+		at := c.OccurredAt
+		switch c.Status {
+		case StatusDelivered:
+			after.CompletedAt = &at
+		case StatusCancelled:
+			after.CancelledAt = &at
+		}
+	case ChangeDeleted:
+		at := c.OccurredAt
+		after.Status = StatusCancelled
+		after.CancelledAt = &at
+	}
+	return &after
+}
+
+// Replay applies changes in order to an order's state, nil before it was
+// created, returning nil if there are none
+func Replay(state *Order, changes []*Change) *Order {
+	for _, c := range changes {
+		state = c.Apply(state)
+	}
+	return state
+}
+
+// History is implemented by order repositories that record every change of
+// an order rather than only its current state
+type History interface {
+	// Changes returns an order's history, oldest first
+	Changes(ctx context.Context, id uuid.UUID) ([]*Change, error)
+	// GetAsOf returns an order as it was at a time, failing with
+	// ErrOrderNotFound if it did not exist yet
+	GetAsOf(ctx context.Context, id uuid.UUID, at time.Time) (*Order, error)
+}
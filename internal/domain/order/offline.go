@@ -0,0 +1,82 @@
+package order
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConflictPolicy decides what happens when an order rung up offline no
+// longer matches the catalog it is uploaded against
+type ConflictPolicy string
+
+const (
+	// PolicyClientWins keeps the prices the customer paid and accepts items
+	// no longer sold
+	PolicyClientWins ConflictPolicy = "client_wins"
+	// PolicyServerWins reprices items from the catalog and rejects the order
+	// when an item is no longer sold
+	PolicyServerWins ConflictPolicy = "server_wins"
+	// PolicyReject rejects the order on any difference, for staff to review
+	PolicyReject ConflictPolicy = "reject"
+)
+
+// Valid reports whether p is a known policy
+func (p ConflictPolicy) Valid() bool {
+	return p == PolicyClientWins || p == PolicyServerWins || p == PolicyReject
+}
+
+// OfflineOrder is an order a terminal rang up while it could not reach the
+// order service. Its ID is generated by the terminal, so uploading it again
+// never creates a second order.
+type OfflineOrder struct {
+	ID         uuid.UUID      `json:"id" validate:"required"`
+	UserID     uuid.UUID      `json:"user_id" validate:"required"` // Cashier who uploaded it
+	StoreID    uuid.UUID      `json:"store_id" validate:"required"`
+	TerminalID string         `json:"terminal_id,omitempty"`
+	Items      []OrderItem    `json:"items" validate:"required,min=1"` // With the names and prices the terminal charged
+	Currency   string         `json:"currency,omitempty"`
+	ShiftID    *uuid.UUID     `json:"shift_id,omitempty"`
+	Tender     string         `json:"tender,omitempty" validate:"required_with=ShiftID,omitempty,oneof=cash card digital_wallet bank_transfer"`
+	Notes      string         `json:"notes,omitempty"`
+	Policy     ConflictPolicy `json:"conflict_policy,omitempty"`
+	CreatedAt  time.Time      `json:"created_at" validate:"required"` // When it was rung up
+}
+
+// Conflict kinds
+const (
+	ConflictPrice       = "price_changed" // The catalog price differs from the one charged
+	ConflictUnavailable = "unavailable"   // The item is no longer sold
+)
+
+// Conflict resolutions
+const (
+	ResolutionKeptClient = "kept_client"
+	ResolutionUsedServer = "used_server"
+	ResolutionRejected   = "rejected"
+)
+
+// Conflict is an item of an offline order that differs from the catalog,
+// and how its policy resolved it
+type Conflict struct {
+	ProductID   string  `json:"product_id"`
+	Kind        string  `json:"kind"`
+	ClientPrice float64 `json:"client_price"`
+	ServerPrice float64 `json:"server_price,omitempty"`
+	Resolution  string  `json:"resolution"`
+}
+
+// Import statuses of an offline order
+const (
+	ImportAccepted  = "accepted"
+	ImportDuplicate = "duplicate" // Uploaded before; the order is the one first imported
+	ImportRejected  = "rejected"
+)
+
+// ImportResult is the outcome of uploading an offline order
+type ImportResult struct {
+	Status    string     `json:"status"`
+	Order     *Order     `json:"order,omitempty"`
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+	Error     string     `json:"error,omitempty"` // Why it was rejected
+}
@@ -1,11 +1,20 @@
 package order
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrOrderNotFound = errors.New("order not found")
+	ErrOrderExists   = errors.New("order already exists")
+	// ErrInvalidTransition is matched by a TransitionError
+	ErrInvalidTransition = errors.New("invalid order status transition")
+)
+
 // OrderStatus represents order status
 type OrderStatus string
 
@@ -27,37 +36,102 @@ type OrderItem struct {
 	UnitPrice float64 `json:"unit_price"`
 	Subtotal  float64 `json:"subtotal"`
 	Discount  float64 `json:"discount,omitempty"`
+	// CategoryID is the item's catalog category, which promotions and tax
+	// categories apply by
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	Tax        float64    `json:"tax,omitempty"` // Sales tax on the subtotal
 }
 
-// Address represents shipping/billing address
+// AppliedPromotion is a promotion that discounted an order. Its discount is
+// spread over the order's item discounts.
+type AppliedPromotion struct {
+	PromotionID uuid.UUID `json:"promotion_id"`
+	Name        string    `json:"name"`
+	Amount      float64   `json:"amount"`
+	Coupon      string    `json:"coupon,omitempty"` // Code that unlocked the promotion
+}
+
+// TaxLine is the sales tax one jurisdiction levies on an order
+type TaxLine struct {
+	JurisdictionID uuid.UUID `json:"jurisdiction_id"`
+	Name           string    `json:"name"`
+	Level          string    `json:"level"` // country, state or city
+	Taxable        float64   `json:"taxable"`
+	Exempt         float64   `json:"exempt"`
+	Tax            float64   `json:"tax"`
+	Inclusive      bool      `json:"inclusive,omitempty"` // Included in the item prices, not added to them
+}
+
+// Address represents shipping/billing address. The street and postal code
+// are stored encrypted; the rest stays readable for shipping and tax reports.
 type Address struct {
-	Street     string `json:"street"`
+	Street     string `json:"street" encrypt:"pii"`
 	City       string `json:"city"`
 	State      string `json:"state"`
-	PostalCode string `json:"postal_code"`
+	PostalCode string `json:"postal_code" encrypt:"pii"`
 	Country    string `json:"country"`
 }
 
 // Order represents an order entity
 type Order struct {
-	ID              uuid.UUID   `json:"id"`
-	UserID          uuid.UUID   `json:"user_id"`
-	StoreID         uuid.UUID   `json:"store_id"`
-	Status          OrderStatus `json:"status"`
-	TotalAmount     float64     `json:"total_amount"`
-	Currency        string      `json:"currency"`
-	Items           []OrderItem `json:"items"`
-	ShippingAddress *Address    `json:"shipping_address,omitempty"`
-	BillingAddress  *Address    `json:"billing_address,omitempty"`
-	Notes           string      `json:"notes,omitempty"`
-	CreatedAt       time.Time   `json:"created_at"`
-	UpdatedAt       time.Time   `json:"updated_at"`
-	CompletedAt     *time.Time  `json:"completed_at,omitempty"`
-	CancelledAt     *time.Time  `json:"cancelled_at,omitempty"`
-}
-
-// CalculateTotal calculates total amount from items
+	ID              uuid.UUID          `json:"id"`
+	TenantID        string             `json:"tenant_id"`
+	UserID          uuid.UUID          `json:"user_id"`
+	StoreID         uuid.UUID          `json:"store_id"`
+	Status          OrderStatus        `json:"status"`
+	TotalAmount     float64            `json:"total_amount"`
+	Currency        string             `json:"currency"`
+	Items           []OrderItem        `json:"items"`
+	ShippingAddress *Address           `json:"shipping_address,omitempty"`
+	BillingAddress  *Address           `json:"billing_address,omitempty"`
+	Notes           string             `json:"notes,omitempty"`
+	LoyaltyPoints   int64              `json:"loyalty_points,omitempty"`   // Points redeemed against the order
+	LoyaltyDiscount float64            `json:"loyalty_discount,omitempty"` // Discount the redeemed points gave
+	Promotions      []AppliedPromotion `json:"promotions,omitempty"`
+	ShiftID         *uuid.UUID         `json:"shift_id,omitempty"` // Register shift the order was rung up on
+	Tender          string             `json:"tender,omitempty"`   // How it was paid at the register
+	TaxAmount       float64            `json:"tax_amount"`         // Sales tax, added to the total unless included in prices
+	Taxes           []TaxLine          `json:"taxes,omitempty"`    // Sales tax by jurisdiction
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+	CompletedAt     *time.Time         `json:"completed_at,omitempty"`
+	CancelledAt     *time.Time         `json:"cancelled_at,omitempty"`
+}
+
+// CalculateTotal calculates total amount from items, less the loyalty
+// discount, plus the sales tax not already included in their prices
 func (o *Order) CalculateTotal() float64 {
+	total := o.Subtotal() - o.LoyaltyDiscount
+	if total < 0 {
+		total = 0
+	}
+	return total + o.TaxAmount - o.IncludedTax()
+}
+
+// Coupons returns the coupon codes that unlocked the order's promotions
+func (o *Order) Coupons() []string {
+	var codes []string
+	for _, p := range o.Promotions {
+		if p.Coupon != "" {
+			codes = append(codes, p.Coupon)
+		}
+	}
+	return codes
+}
+
+// IncludedTax sums the sales tax included in the item prices
+func (o *Order) IncludedTax() float64 {
+	included := 0.0
+	for _, t := range o.Taxes {
+		if t.Inclusive {
+			included += t.Tax
+		}
+	}
+	return included
+}
+
+// Subtotal sums the item subtotals
+func (o *Order) Subtotal() float64 {
 	total := 0.0
 	for _, item := range o.Items {
 		total += item.Subtotal
@@ -70,6 +144,65 @@ func (o *Order) CanCancel() bool {
 	return o.Status == StatusPending || o.Status == StatusConfirmed
 }
 
+// statusTransitions lists the statuses each status can move to
+var statusTransitions = map[OrderStatus][]OrderStatus{
+	StatusPending:    {StatusConfirmed, StatusProcessing, StatusCancelled},
+	StatusConfirmed:  {StatusProcessing, StatusShipped, StatusCancelled},
+	StatusProcessing: {StatusShipped, StatusDelivered},
+	StatusShipped:    {StatusDelivered},
+	StatusDelivered:  {StatusRefunded},
+}
+
+// CanTransitionTo checks if the order can move to status
+func (o *Order) CanTransitionTo(status OrderStatus) bool {
+	for _, next := range statusTransitions[o.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStatuses lists the statuses the order can move to, none once it is
+// cancelled or refunded
+func (o *Order) NextStatuses() []OrderStatus {
+	return append([]OrderStatus{}, statusTransitions[o.Status]...)
+}
+
+// TransitionTo moves the order to status, stamping when it was completed
+// or cancelled. A move the transition table does not allow fails with a
+// TransitionError and leaves the order unchanged.
+func (o *Order) TransitionTo(status OrderStatus, at time.Time) error {
+	if !o.CanTransitionTo(status) {
+		return &TransitionError{From: o.Status, To: status}
+	}
+	o.Status = status
+	o.UpdatedAt = at
+	switch status {
+	case StatusDelivered:
+		o.CompletedAt = &at
+	case StatusCancelled:
+		o.CancelledAt = &at
+	}
+	return nil
+}
+
+// TransitionError is returned when an order cannot move from its status to
+// another
+type TransitionError struct {
+	From OrderStatus
+	To   OrderStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("order cannot move from %s to %s", e.From, e.To)
+}
+
+// Is matches ErrInvalidTransition
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
 // CanUpdate checks if order can be updated
 func (o *Order) CanUpdate() bool {
 	return o.Status == StatusPending || o.Status == StatusConfirmed
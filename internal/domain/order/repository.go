@@ -2,18 +2,39 @@ package order
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// ExportFilter narrows an export of orders to those placed in a period,
+// optionally at one store
+type ExportFilter struct {
+	From    time.Time // Inclusive
+	To      time.Time // Exclusive
+	StoreID *uuid.UUID
+}
+
 // Repository defines the order repository interface
 type Repository interface {
 	Create(ctx context.Context, order *Order) error
+	// CreateBatch creates orders in one round trip: all of them or, with
+	// ErrOrderExists when one already exists, none
+	CreateBatch(ctx context.Context, orders []*Order) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Order, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Order, error)
 	GetByStoreID(ctx context.Context, storeID uuid.UUID, limit, offset int) ([]*Order, error)
 	Update(ctx context.Context, order *Order) error
-	UpdateStatus(ctx context.Context, id uuid.UUID, status OrderStatus) error
+	// UpdateStatus moves an order from t.From to t.To and records t in its
+	// status history. It fails with a TransitionError if the order is no
+	// longer in t.From, such as after a concurrent change.
+	UpdateStatus(ctx context.Context, id uuid.UUID, t *StatusTransition) error
+	// StatusHistory returns the status transitions of an order, oldest first
+	StatusHistory(ctx context.Context, id uuid.UUID) ([]*StatusTransition, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 	CountByUserID(ctx context.Context, userID uuid.UUID) (int, error)
+	ExportStore(ctx context.Context, storeID uuid.UUID, fn func(*Order) error) error // Every order of the store, as of one moment
+	// Export calls fn with each order the filter matches, oldest first, as
+	// it is read rather than after reading them all
+	Export(ctx context.Context, filter ExportFilter, fn func(*Order) error) error
 }
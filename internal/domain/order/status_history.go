@@ -0,0 +1,33 @@
+package order
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StatusTransition is one move of an order from a status to another, as
+// recorded in its status history
+type StatusTransition struct {
+	ID        uuid.UUID   `json:"id"`
+	OrderID   uuid.UUID   `json:"order_id"`
+	From      OrderStatus `json:"from"`
+	To        OrderStatus `json:"to"`
+	ChangedBy *uuid.UUID  `json:"changed_by,omitempty"` // The user who moved it, if any
+	Reason    string      `json:"reason,omitempty"`
+	ChangedAt time.Time   `json:"changed_at"`
+}
+
+// NewStatusTransition records an order moving from its status to status
+// now, by a user when changedBy is set
+func NewStatusTransition(o *Order, status OrderStatus, changedBy *uuid.UUID, reason string) *StatusTransition {
+	return &StatusTransition{
+		ID:        uuid.New(),
+		OrderID:   o.ID,
+		From:      o.Status,
+		To:        status,
+		ChangedBy: changedBy,
+		Reason:    reason,
+		ChangedAt: time.Now(),
+	}
+}
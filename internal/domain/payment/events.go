@@ -0,0 +1,49 @@
+package payment
+
+import "github.com/google/uuid"
+
+// Payment event types, published to the message broker with the event type
+// as routing key
+const (
+	EventCreated   = "payment.created" // The payment was sent to the provider
+	EventCompleted = "payment.completed"
+	EventFailed    = "payment.failed" // The provider declined or gave up on the payment
+	EventRefunded  = "payment.refunded"
+)
+
+// EventData is the payload of a payment event on the message broker
+type EventData struct {
+	PaymentID uuid.UUID         `json:"payment_id"`
+	OrderID   uuid.UUID         `json:"order_id"`
+	UserID    uuid.UUID         `json:"user_id"`
+	Method    PaymentMethodType `json:"method"`
+	Status    PaymentStatus     `json:"status"`
+	Amount    float64           `json:"amount"`
+	Currency  string            `json:"currency"`
+}
+
+// NewEventData returns the event payload of p
+func NewEventData(p *Payment) EventData {
+	return EventData{
+		PaymentID: p.ID,
+		OrderID:   p.OrderID,
+		UserID:    p.UserID,
+		Method:    p.PaymentMethodType,
+		Status:    p.Status,
+		Amount:    p.Amount,
+		Currency:  p.Currency,
+	}
+}
+
+// Map returns the payload as a generic map, for publishers that take one
+func (d EventData) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"payment_id": d.PaymentID,
+		"order_id":   d.OrderID,
+		"user_id":    d.UserID,
+		"method":     d.Method,
+		"status":     d.Status,
+		"amount":     d.Amount,
+		"currency":   d.Currency,
+	}
+}
@@ -1,11 +1,22 @@
 package payment
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrDeclined is returned when the provider declines or refuses a
+	// payment method
+	ErrDeclined = errors.New("payment declined")
+	// ErrNotSettled is returned for a payment the provider has yet to
+	// settle, such as when refunding it
+	ErrNotSettled = errors.New("payment not settled")
+)
+
 // PaymentStatus represents payment status
 type PaymentStatus string
 
@@ -46,6 +57,23 @@ type Payment struct {
 	UpdatedAt             time.Time         `json:"updated_at"`
 	ProcessedAt           *time.Time        `json:"processed_at,omitempty"`
 	CompletedAt           *time.Time        `json:"completed_at,omitempty"`
+	TenantID              string            `json:"-"` // Set only on payments listed across tenants
+}
+
+// Actions recorded in a payment's audit log
+const (
+	ActionCharge    = "charge"    // The provider answered the charge
+	ActionWebhook   = "webhook"   // The provider reported on the charge later
+	ActionReconcile = "reconcile" // The reconciler asked the provider, or gave up on a payment never charged
+	ActionRefund    = "refund"    // The payment was refunded, such as when its order's checkout was undone
+)
+
+// StatusChange describes what moved a payment to a new status, for its audit
+// log
+type StatusChange struct {
+	Action  string
+	EventID string // Provider event applied; recorded so a redelivery changes nothing
+	Reason  string
 }
 
 // CanRefund checks if payment can be refunded
@@ -53,6 +81,12 @@ func (p *Payment) CanRefund() bool {
 	return p.Status == StatusCompleted
 }
 
+// Settled reports whether the payment reached a status the provider no
+// longer changes
+func (p *Payment) Settled() bool {
+	return p.Status == StatusCompleted || p.Status == StatusFailed || p.Status == StatusRefunded
+}
+
 // CanCancel checks if payment can be cancelled
 func (p *Payment) CanCancel() bool {
 	return p.Status == StatusPending || p.Status == StatusProcessing
@@ -2,10 +2,17 @@ package payment
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// ExportFilter narrows an export of payments to those created in a period
+type ExportFilter struct {
+	From time.Time // Inclusive
+	To   time.Time // Exclusive
+}
+
 // Repository defines the payment repository interface
 type Repository interface {
 	Create(ctx context.Context, payment *Payment) error
@@ -14,4 +21,20 @@ type Repository interface {
 	GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Payment, error)
 	Update(ctx context.Context, payment *Payment) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status PaymentStatus) error
+	// Export calls fn with each payment the filter matches, oldest first, as
+	// it is read rather than after reading them all
+	Export(ctx context.Context, filter ExportFilter, fn func(*Payment) error) error
+
+	// Settle saves p's status and provider fields if the payment is still in
+	// one of from, and records the change in its audit log, all at once. It
+	// returns false when the payment had moved on, or change.EventID was
+	// applied before.
+	Settle(ctx context.Context, p *Payment, from []PaymentStatus, change StatusChange) (bool, error)
+	// ListUnsettled retrieves pending and processing payments last updated
+	// before the given time, across tenants, in pages ordered by ID: the next
+	// page starts after the last ID of the previous one
+	ListUnsettled(ctx context.Context, before time.Time, afterID uuid.UUID, limit int) ([]*Payment, error)
+	// PruneProviderEvents forgets provider events received before the given
+	// time, across tenants
+	PruneProviderEvents(ctx context.Context, before time.Time) (int64, error)
 }
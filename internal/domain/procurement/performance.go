@@ -0,0 +1,99 @@
+package procurement
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrderSample is what one submitted purchase order tells of its supplier
+type OrderSample struct {
+	OrderID          uuid.UUID
+	Status           Status
+	SubmittedAt      time.Time
+	ExpectedOn       *time.Time
+	OrderedQuantity  int
+	ReceivedQuantity int
+	LastReceivedAt   *time.Time // Latest goods receipt, if any
+}
+
+// Performance rates a supplier on the orders submitted to it in a period.
+// Rates are nil when no order tells them yet.
+type Performance struct {
+	SupplierID          uuid.UUID `json:"supplier_id"`
+	From                time.Time `json:"from"`
+	To                  time.Time `json:"to"`
+	Orders              int       `json:"orders"`
+	OpenOrders          int       `json:"open_orders"`
+	OverdueOrders       int       `json:"overdue_orders"`    // Open past their expected day
+	OrderedQuantity     int       `json:"ordered_quantity"`  // On settled orders
+	ReceivedQuantity    int       `json:"received_quantity"` // On settled orders
+	FillRate            *float64  `json:"fill_rate"`         // Share of the quantity ordered on settled orders that arrived
+	OnTimeRate          *float64  `json:"on_time_rate"`      // Share of orders completed by their expected day, counting overdue ones as late
+	AverageLeadTimeDays *float64  `json:"average_lead_time_days"`
+	QuotedLeadTimeDays  int       `json:"quoted_lead_time_days"`
+}
+
+// BuildPerformance rates supplier s from its orders' samples. Received and
+// closed orders are settled: their fill rate and lead time, from submission
+// to the last goods receipt, are final. An order is on time when it was
+// received in full by the end of its expected day.
+func BuildPerformance(s *Supplier, from, to time.Time, samples []*OrderSample, now time.Time) *Performance {
+	p := &Performance{
+		SupplierID:         s.ID,
+		From:               from,
+		To:                 to,
+		Orders:             len(samples),
+		QuotedLeadTimeDays: s.LeadTimeDays,
+	}
+
+	var leadTime time.Duration
+	leadTimes, timed, onTime := 0, 0, 0
+	for _, sample := range samples {
+		var due time.Time
+		if sample.ExpectedOn != nil {
+			due = sample.ExpectedOn.AddDate(0, 0, 1) // End of the expected day
+		}
+
+		switch sample.Status {
+		case StatusReceived, StatusClosed:
+			p.OrderedQuantity += sample.OrderedQuantity
+			p.ReceivedQuantity += sample.ReceivedQuantity
+			if sample.LastReceivedAt != nil {
+				leadTime += sample.LastReceivedAt.Sub(sample.SubmittedAt)
+				leadTimes++
+			}
+			if !due.IsZero() {
+				timed++
+				if sample.Status == StatusReceived && sample.LastReceivedAt != nil && sample.LastReceivedAt.Before(due) {
+					onTime++
+				}
+			}
+		default:
+			p.OpenOrders++
+			if !due.IsZero() && now.After(due) {
+				p.OverdueOrders++
+				timed++
+			}
+		}
+	}
+
+	if p.OrderedQuantity > 0 {
+		p.FillRate = ratio(float64(p.ReceivedQuantity), float64(p.OrderedQuantity))
+	}
+	if timed > 0 {
+		p.OnTimeRate = ratio(float64(onTime), float64(timed))
+	}
+	if leadTimes > 0 {
+		days := math.Round(leadTime.Hours()/24/float64(leadTimes)*10) / 10
+		p.AverageLeadTimeDays = &days
+	}
+	return p
+}
+
+// ratio returns part/whole rounded to four decimals
+func ratio(part, whole float64) *float64 {
+	r := math.Round(part/whole*10000) / 10000
+	return &r
+}
@@ -0,0 +1,155 @@
+package procurement
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(month time.Month, day, hour int) time.Time {
+	return time.Date(2026, month, day, hour, 0, 0, 0, time.UTC)
+}
+
+func rate(r float64) *float64 {
+	return &r
+}
+
+// sample is an order submitted on March 1 expecting delivery on expectedDay
+// of March, or with no expected day when it is 0
+func sample(status Status, expectedDay, ordered, received int, lastReceivedAt *time.Time) *OrderSample {
+	s := &OrderSample{
+		OrderID:          uuid.New(),
+		Status:           status,
+		SubmittedAt:      date(3, 1, 0),
+		OrderedQuantity:  ordered,
+		ReceivedQuantity: received,
+		LastReceivedAt:   lastReceivedAt,
+	}
+	if expectedDay > 0 {
+		expectedOn := date(3, expectedDay, 0)
+		s.ExpectedOn = &expectedOn
+	}
+	return s
+}
+
+func at(month time.Month, day, hour int) *time.Time {
+	t := date(month, day, hour)
+	return &t
+}
+
+func TestBuildPerformance(t *testing.T) {
+	now := date(3, 31, 12)
+
+	tests := []struct {
+		name         string
+		samples      []*OrderSample
+		wantOpen     int
+		wantOverdue  int
+		wantOrdered  int
+		wantReceived int
+		wantFill     *float64
+		wantOnTime   *float64
+		wantLeadTime *float64
+	}{
+		{
+			name: "supplier without orders",
+		},
+		{
+			name:     "open orders not yet due",
+			samples:  []*OrderSample{sample(StatusSubmitted, 0, 10, 0, nil), {OrderID: uuid.New(), Status: StatusSubmitted, ExpectedOn: at(4, 5, 0)}},
+			wantOpen: 2,
+		},
+		{
+			name:        "overdue orders count as late",
+			samples:     []*OrderSample{sample(StatusPartiallyReceived, 20, 10, 4, at(3, 18, 0))},
+			wantOpen:    1,
+			wantOverdue: 1,
+			wantOnTime:  rate(0),
+		},
+		{
+			name:         "received by the end of the expected day",
+			samples:      []*OrderSample{sample(StatusReceived, 10, 10, 10, at(3, 10, 23))},
+			wantOrdered:  10,
+			wantReceived: 10,
+			wantFill:     rate(1),
+			wantOnTime:   rate(1),
+			wantLeadTime: rate(10), // 9 days and 23 hours, to a tenth of a day
+		},
+		{
+			name:         "received the day after",
+			samples:      []*OrderSample{sample(StatusReceived, 10, 10, 10, at(3, 11, 0))},
+			wantOrdered:  10,
+			wantReceived: 10,
+			wantFill:     rate(1),
+			wantOnTime:   rate(0),
+			wantLeadTime: rate(10),
+		},
+		{
+			name:         "closed short is never on time",
+			samples:      []*OrderSample{sample(StatusClosed, 10, 10, 6, at(3, 5, 0))},
+			wantOrdered:  10,
+			wantReceived: 6,
+			wantFill:     rate(0.6),
+			wantOnTime:   rate(0),
+			wantLeadTime: rate(4),
+		},
+		{
+			name:        "closed without any receipt",
+			samples:     []*OrderSample{sample(StatusClosed, 0, 5, 0, nil)},
+			wantOrdered: 5,
+			wantFill:    rate(0),
+		},
+		{
+			name: "only settled orders are filled",
+			samples: []*OrderSample{
+				sample(StatusReceived, 0, 10, 10, at(3, 4, 12)),
+				sample(StatusClosed, 0, 20, 10, at(3, 3, 0)),
+				sample(StatusPartiallyReceived, 0, 10, 5, at(3, 2, 0)),
+			},
+			wantOpen:     1,
+			wantOrdered:  30,
+			wantReceived: 20,
+			wantFill:     rate(0.6667),
+			wantLeadTime: rate(2.8), // 3.5 and 2 days
+		},
+		{
+			name: "on time rate over every timed order",
+			samples: []*OrderSample{
+				sample(StatusReceived, 10, 1, 1, at(3, 9, 0)),
+				sample(StatusReceived, 10, 1, 1, at(3, 12, 0)),
+				sample(StatusSubmitted, 20, 1, 0, nil),
+				sample(StatusReceived, 0, 1, 1, at(3, 2, 0)), // Not timed
+			},
+			wantOpen:     1,
+			wantOverdue:  1,
+			wantOrdered:  3,
+			wantReceived: 3,
+			wantFill:     rate(1),
+			wantOnTime:   rate(0.3333),
+			wantLeadTime: rate(6.7), // 8, 11 and 1 days
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			supplier := &Supplier{ID: uuid.New(), LeadTimeDays: 7}
+			from, to := date(3, 1, 0), date(4, 1, 0)
+
+			p := BuildPerformance(supplier, from, to, tt.samples, now)
+			assert.Equal(t, supplier.ID, p.SupplierID)
+			assert.Equal(t, from, p.From)
+			assert.Equal(t, to, p.To)
+			assert.Equal(t, 7, p.QuotedLeadTimeDays)
+			assert.Equal(t, len(tt.samples), p.Orders)
+			assert.Equal(t, tt.wantOpen, p.OpenOrders, "open orders")
+			assert.Equal(t, tt.wantOverdue, p.OverdueOrders, "overdue orders")
+			assert.Equal(t, tt.wantOrdered, p.OrderedQuantity, "ordered quantity")
+			assert.Equal(t, tt.wantReceived, p.ReceivedQuantity, "received quantity")
+			assert.Equal(t, tt.wantFill, p.FillRate, "fill rate")
+			assert.Equal(t, tt.wantOnTime, p.OnTimeRate, "on time rate")
+			assert.Equal(t, tt.wantLeadTime, p.AverageLeadTimeDays, "average lead time")
+		})
+	}
+}
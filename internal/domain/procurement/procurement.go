@@ -0,0 +1,179 @@
+package procurement
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSupplierNotFound  = errors.New("supplier not found")
+	ErrSupplierExists    = errors.New("supplier code already in use")
+	ErrSupplierInactive  = errors.New("supplier is inactive")
+	ErrSupplierInUse     = errors.New("supplier has purchase orders")
+	ErrOrderNotFound     = errors.New("purchase order not found")
+	ErrInvalidTransition = errors.New("purchase order cannot make this change in its current status")
+	ErrOverReceipt       = errors.New("received quantity exceeds the quantity ordered")
+	ErrReceiptNotFound   = errors.New("goods receipt not found")
+)
+
+// Supplier is a vendor stock is bought from
+type Supplier struct {
+	ID           uuid.UUID `json:"id"`
+	Code         string    `json:"code"` // Short unique code, e.g. ACME
+	Name         string    `json:"name"`
+	ContactName  string    `json:"contact_name,omitempty"`
+	Email        string    `json:"email,omitempty"`
+	Phone        string    `json:"phone,omitempty"`
+	Address      string    `json:"address,omitempty"`
+	Currency     string    `json:"currency"`
+	PaymentTerms string    `json:"payment_terms,omitempty"` // e.g. net 30
+	LeadTimeDays int       `json:"lead_time_days"`          // Quoted days from order to delivery
+	Active       bool      `json:"active"`
+	Notes        string    `json:"notes,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Status is the stage of a purchase order
+type Status string
+
+const (
+	StatusDraft             Status = "draft"
+	StatusSubmitted         Status = "submitted" // Sent to the supplier
+	StatusPartiallyReceived Status = "partially_received"
+	StatusReceived          Status = "received"
+	StatusClosed            Status = "closed" // Closed short; what is outstanding will not come
+	StatusCancelled         Status = "cancelled"
+)
+
+// transitions lists the statuses each status can be moved to by hand.
+// Receiving goods moves an order to partially received or received.
+var transitions = map[Status][]Status{
+	StatusDraft:             {StatusSubmitted, StatusCancelled},
+	StatusSubmitted:         {StatusCancelled, StatusClosed},
+	StatusPartiallyReceived: {StatusClosed},
+}
+
+// CanTransition reports whether an order in status from can be moved to to
+func CanTransition(from, to Status) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Sources returns the statuses from which an order can be moved to to
+func Sources(to Status) []Status {
+	var from []Status
+	for s, targets := range transitions {
+		for _, t := range targets {
+			if t == to {
+				from = append(from, s)
+			}
+		}
+	}
+	return from
+}
+
+// Receivable reports whether goods can be received against an order in s
+func (s Status) Receivable() bool {
+	return s == StatusSubmitted || s == StatusPartiallyReceived
+}
+
+// Line is a product ordered on a purchase order
+type Line struct {
+	ID               uuid.UUID `json:"id"`
+	ProductID        uuid.UUID `json:"product_id"` // Catalog variant ID, as held in inventory
+	SupplierSKU      string    `json:"supplier_sku,omitempty"`
+	Description      string    `json:"description,omitempty"`
+	Quantity         int       `json:"quantity"`
+	UnitCost         float64   `json:"unit_cost"`
+	ReceivedQuantity int       `json:"received_quantity"`
+}
+
+// Outstanding is the quantity still to be received
+func (l *Line) Outstanding() int {
+	if l.ReceivedQuantity >= l.Quantity {
+		return 0
+	}
+	return l.Quantity - l.ReceivedQuantity
+}
+
+// PurchaseOrder orders stock from a supplier for one store
+type PurchaseOrder struct {
+	ID          uuid.UUID  `json:"id"`
+	Number      int64      `json:"number"` // Sequential, for quoting to the supplier
+	SupplierID  uuid.UUID  `json:"supplier_id"`
+	StoreID     uuid.UUID  `json:"store_id"` // Where the goods are delivered
+	Status      Status     `json:"status"`
+	Currency    string     `json:"currency"`
+	Lines       []Line     `json:"lines"`
+	Total       float64    `json:"total"`
+	ExpectedOn  *time.Time `json:"expected_on,omitempty"` // Day the supplier is to deliver
+	Notes       string     `json:"notes,omitempty"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	SubmittedAt *time.Time `json:"submitted_at,omitempty"`
+	ReceivedAt  *time.Time `json:"received_at,omitempty"` // When the last outstanding goods arrived
+	ClosedAt    *time.Time `json:"closed_at,omitempty"`   // When it was closed short or cancelled
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// CalculateTotal sums the ordered lines at their unit costs
+func (o *PurchaseOrder) CalculateTotal() float64 {
+	total := 0.0
+	for _, l := range o.Lines {
+		total += float64(l.Quantity) * l.UnitCost
+	}
+	return roundCents(total)
+}
+
+// Line returns the line with the given ID, or nil
+func (o *PurchaseOrder) Line(id uuid.UUID) *Line {
+	for i := range o.Lines {
+		if o.Lines[i].ID == id {
+			return &o.Lines[i]
+		}
+	}
+	return nil
+}
+
+// OrderFilter narrows a purchase order listing; zero values match all
+type OrderFilter struct {
+	SupplierID *uuid.UUID
+	StoreID    *uuid.UUID
+	Status     Status
+}
+
+// GoodsReceipt records goods delivered against a purchase order. Its stock
+// is posted to inventory once PostedAt is set.
+type GoodsReceipt struct {
+	ID              uuid.UUID     `json:"id"`
+	TenantID        string        `json:"tenant_id"`
+	PurchaseOrderID uuid.UUID     `json:"purchase_order_id"`
+	StoreID         uuid.UUID     `json:"store_id"`
+	Lines           []ReceiptLine `json:"lines"`
+	Note            string        `json:"note,omitempty"`
+	ReceivedBy      *uuid.UUID    `json:"received_by,omitempty"`
+	ReceivedAt      time.Time     `json:"received_at"`
+	PostedAt        *time.Time    `json:"posted_at,omitempty"` // Nil until inventory took the stock
+}
+
+// ReceiptLine is the quantity of one order line received
+type ReceiptLine struct {
+	ID        uuid.UUID `json:"id"`
+	LineID    uuid.UUID `json:"line_id"`
+	ProductID uuid.UUID `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+	UnitCost  float64   `json:"unit_cost"` // Cost of the stock layer, the order line's unless invoiced otherwise
+}
+
+// roundCents rounds an amount to cents
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,39 @@
+package procurement
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrSupplierProductNotFound is returned when a supplier does not sell a product
+var ErrSupplierProductNotFound = errors.New("supplier does not sell this product")
+
+// ReorderNote is the note of purchase orders raised automatically for low
+// stock
+const ReorderNote = "Raised automatically for low stock"
+
+// SupplierProduct is a product a supplier sells, at the cost it quotes.
+// Stock of the product running low is reordered from the cheapest active
+// supplier selling it.
+type SupplierProduct struct {
+	SupplierID       uuid.UUID `json:"supplier_id"`
+	ProductID        uuid.UUID `json:"product_id"` // Catalog variant ID, as held in inventory
+	SupplierSKU      string    `json:"supplier_sku,omitempty"`
+	UnitCost         float64   `json:"unit_cost"`
+	MinOrderQuantity int       `json:"min_order_quantity"` // Fewest units the supplier takes an order for
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// ReorderQuantity is the quantity to order of stock whose available quantity
+// fell to its reorder point: its reorder quantity, or without one enough to
+// lift it above the point, and no fewer than the supplier's minimum
+func ReorderQuantity(available, reorderPoint, reorderQuantity, minOrderQuantity int) int {
+	quantity := reorderQuantity
+	if quantity <= 0 {
+		quantity = reorderPoint - available + 1
+	}
+	return max(quantity, minOrderQuantity, 1)
+}
@@ -0,0 +1,58 @@
+package procurement
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository defines the procurement repository interface
+type Repository interface {
+	CreateSupplier(ctx context.Context, s *Supplier) error // ErrSupplierExists when the code is taken
+	GetSupplier(ctx context.Context, id uuid.UUID) (*Supplier, error)
+	ListSuppliers(ctx context.Context, activeOnly bool, limit, offset int) ([]*Supplier, error)
+	UpdateSupplier(ctx context.Context, s *Supplier) error
+	DeleteSupplier(ctx context.Context, id uuid.UUID) error // ErrSupplierInUse when it has purchase orders
+
+	CreateOrder(ctx context.Context, o *PurchaseOrder) error
+	GetOrder(ctx context.Context, id uuid.UUID) (*PurchaseOrder, error)
+	ListOrders(ctx context.Context, filter OrderFilter, limit, offset int) ([]*PurchaseOrder, error)
+	// UpdateDraft replaces a draft order's lines, expected day and notes;
+	// ErrInvalidTransition when it is no longer a draft
+	UpdateDraft(ctx context.Context, o *PurchaseOrder) error
+	// Transition moves an order to status to, from a status it can be moved
+	// from; ErrInvalidTransition otherwise
+	Transition(ctx context.Context, id uuid.UUID, to Status, expectedOn *time.Time, at time.Time) (*PurchaseOrder, error)
+
+	// CreateReceipt records goods received against a receivable order and
+	// adds them to its lines' received quantities, moving it to partially
+	// received or received; ErrOverReceipt when a line would exceed its
+	// quantity
+	CreateReceipt(ctx context.Context, r *GoodsReceipt) error
+	ListReceipts(ctx context.Context, orderID uuid.UUID) ([]*GoodsReceipt, error)
+	ListUnpostedReceipts(ctx context.Context, limit int) ([]*GoodsReceipt, error)
+	MarkReceiptPosted(ctx context.Context, id uuid.UUID, at time.Time) error
+
+	// SetSupplierProduct adds a product to those a supplier sells, or updates
+	// it; ErrSupplierNotFound when the supplier does not exist
+	SetSupplierProduct(ctx context.Context, p *SupplierProduct) error
+	ListSupplierProducts(ctx context.Context, supplierID uuid.UUID) ([]*SupplierProduct, error)
+	DeleteSupplierProduct(ctx context.Context, supplierID, productID uuid.UUID) error
+	// ReorderSources returns, for each of productIDs an active supplier
+	// sells, the cheapest supplier's product
+	ReorderSources(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]*SupplierProduct, error)
+	// OnOrder returns which of productIDs are still to be delivered to a store
+	// on open orders, drafts included
+	OnOrder(ctx context.Context, storeID uuid.UUID, productIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+	// ReorderTenants returns the tenants whose suppliers sell any product,
+	// across tenants; only their low stock can be reordered
+	ReorderTenants(ctx context.Context) ([]string, error)
+	// ClaimReorder claims a tenant's reorder run at at, unless one was
+	// claimed less than every before, across instances
+	ClaimReorder(ctx context.Context, tenantID string, at time.Time, every time.Duration) (bool, error)
+
+	// OrderSamples returns the orders submitted to a supplier in [from, to),
+	// other than cancelled ones
+	OrderSamples(ctx context.Context, supplierID uuid.UUID, from, to time.Time) ([]*OrderSample, error)
+}
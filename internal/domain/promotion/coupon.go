@@ -0,0 +1,73 @@
+package promotion
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrCouponNotFound is returned when no coupon has a code or ID
+	ErrCouponNotFound = errors.New("coupon not found")
+	// ErrCouponExists is returned when creating a coupon with a code in use
+	ErrCouponExists = errors.New("coupon code already exists")
+	// ErrCouponInactive is returned when presenting a disabled coupon
+	ErrCouponInactive = errors.New("coupon is not active")
+	// ErrCouponExpired is returned when presenting a coupon past its expiry
+	ErrCouponExpired = errors.New("coupon has expired")
+	// ErrCouponExhausted is returned when a coupon reached its usage limit,
+	// overall or for the customer
+	ErrCouponExhausted = errors.New("coupon usage limit reached")
+	// ErrCouponNotApplicable is returned when a coupon's promotion does not
+	// run in the order's store at the time
+	ErrCouponNotApplicable = errors.New("coupon does not apply to this order")
+	// ErrCouponRedeemed is returned when an order redeemed a coupon already
+	ErrCouponRedeemed = errors.New("coupon already redeemed for this order")
+)
+
+// Coupon is a code that unlocks a promotion for the orders presenting it.
+// Promotions marked coupon_only apply only through one of their coupons.
+type Coupon struct {
+	ID             uuid.UUID  `json:"id"`
+	Code           string     `json:"code"` // Upper case; matched regardless of case
+	PromotionID    uuid.UUID  `json:"promotion_id"`
+	MaxUses        int        `json:"max_uses,omitempty"`          // 0 is unlimited
+	MaxUsesPerUser int        `json:"max_uses_per_user,omitempty"` // 0 is unlimited
+	Uses           int        `json:"uses"`                        // Redemptions by orders not cancelled
+	Active         bool       `json:"active"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// CouponRedemption is a coupon used by an order
+type CouponRedemption struct {
+	CouponID   uuid.UUID `json:"coupon_id"`
+	Code       string    `json:"code"`
+	OrderID    uuid.UUID `json:"order_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	RedeemedAt time.Time `json:"redeemed_at"`
+}
+
+// NormalizeCode returns the form coupon codes are stored and matched in
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Check reports why the coupon cannot be presented at now, given how often
+// the customer redeemed it already; nil when it can
+func (c *Coupon) Check(now time.Time, userUses int) error {
+	switch {
+	case !c.Active:
+		return ErrCouponInactive
+	case c.ExpiresAt != nil && !now.Before(*c.ExpiresAt):
+		return ErrCouponExpired
+	case c.MaxUses > 0 && c.Uses >= c.MaxUses:
+		return ErrCouponExhausted
+	case c.MaxUsesPerUser > 0 && userUses >= c.MaxUsesPerUser:
+		return ErrCouponExhausted
+	}
+	return nil
+}
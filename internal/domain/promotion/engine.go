@@ -0,0 +1,291 @@
+package promotion
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// clockLayout is the layout of happy hour window times
+const clockLayout = "15:04"
+
+// Line is an order line being priced
+type Line struct {
+	ProductID  string     `json:"product_id"` // Catalog variant ID
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	Quantity   int        `json:"quantity"`
+	UnitPrice  float64    `json:"unit_price"`
+}
+
+// Cart is an order being priced
+type Cart struct {
+	StoreID  uuid.UUID  `json:"store_id"`
+	UserID   *uuid.UUID `json:"user_id,omitempty"`  // Customer, for per-customer coupon limits
+	OrderID  *uuid.UUID `json:"order_id,omitempty"` // Order repriced, whose redeemed coupons keep applying
+	Currency string     `json:"currency"`
+	Lines    []Line     `json:"lines"`
+	Coupons  []string   `json:"coupons,omitempty"` // Coupon codes presented
+}
+
+// Applied is a promotion that discounted an order, and by how much
+type Applied struct {
+	PromotionID uuid.UUID `json:"promotion_id"`
+	Name        string    `json:"name"`
+	Type        RuleType  `json:"type"`
+	Amount      float64   `json:"amount"`
+	Coupon      string    `json:"coupon,omitempty"` // Code that unlocked the promotion
+}
+
+// Result is what promotions take off an order
+type Result struct {
+	LineDiscounts []float64 `json:"line_discounts"` // Aligned with the cart's lines
+	Applied       []Applied `json:"applied"`
+	Total         float64   `json:"total"`
+}
+
+// Evaluate applies the promotions running in the cart's store at now, those
+// only coupons unlock when one of coupons is for them. Promotions apply by
+// descending priority, older first among equals, each to what earlier ones
+// left of the line values. An exclusive promotion applies only if none has
+// before it, and none apply after it. Basket discounts are spread over the
+// lines in scope by value, so every discount lands on a line.
+func Evaluate(promotions []*Promotion, coupons []*Coupon, cart *Cart, now time.Time) *Result {
+	codes := make(map[uuid.UUID]string, len(coupons))
+	for _, c := range coupons {
+		codes[c.PromotionID] = c.Code
+	}
+
+	running := make([]*Promotion, 0, len(promotions))
+	for _, p := range promotions {
+		if _, unlocked := codes[p.ID]; p.CouponOnly && !unlocked {
+			continue
+		}
+		if p.RunsAt(cart.StoreID, now) {
+			running = append(running, p)
+		}
+	}
+	sort.SliceStable(running, func(i, j int) bool {
+		if running[i].Priority != running[j].Priority {
+			return running[i].Priority > running[j].Priority
+		}
+		return running[i].CreatedAt.Before(running[j].CreatedAt)
+	})
+
+	remaining := make([]float64, len(cart.Lines))
+	for i, l := range cart.Lines {
+		remaining[i] = roundCents(l.UnitPrice * float64(l.Quantity))
+	}
+	result := &Result{
+		LineDiscounts: make([]float64, len(cart.Lines)),
+		Applied:       []Applied{},
+	}
+
+	for _, p := range running {
+		if p.Exclusive && len(result.Applied) > 0 {
+			continue
+		}
+
+		amount := 0.0
+		for i, d := range p.discounts(cart, remaining, now) {
+			d = math.Min(roundCents(d), remaining[i])
+			if d <= 0 {
+				continue
+			}
+			remaining[i] = roundCents(remaining[i] - d)
+			result.LineDiscounts[i] = roundCents(result.LineDiscounts[i] + d)
+			amount += d
+		}
+		if amount <= 0 {
+			continue
+		}
+
+		result.Applied = append(result.Applied, Applied{
+			PromotionID: p.ID,
+			Name:        p.Name,
+			Type:        p.Type,
+			Amount:      roundCents(amount),
+			Coupon:      codes[p.ID],
+		})
+		result.Total = roundCents(result.Total + amount)
+		if p.Exclusive {
+			break
+		}
+	}
+	return result
+}
+
+// discounts returns what the promotion takes off each line, given what is
+// left of the line values
+func (p *Promotion) discounts(cart *Cart, remaining []float64, now time.Time) []float64 {
+	switch p.Type {
+	case TypeBOGO:
+		return p.bogo(cart, remaining)
+	case TypeBasketThreshold:
+		return p.basket(cart, remaining)
+	case TypeCategoryPercent, TypePercentOff:
+		return p.percentOff(cart, remaining)
+	case TypeAmountOff:
+		return p.basket(cart, remaining)
+	case TypeHappyHour:
+		if !p.Rule.inWindow(now) {
+			return nil
+		}
+		return p.percentOff(cart, remaining)
+	}
+	return nil
+}
+
+// percentOff takes the rule's percentage off the lines in scope
+func (p *Promotion) percentOff(cart *Cart, remaining []float64) []float64 {
+	discounts := make([]float64, len(cart.Lines))
+	for i, l := range cart.Lines {
+		if p.Rule.covers(l) {
+			discounts[i] = remaining[i] * p.Rule.Percent / 100
+		}
+	}
+	return discounts
+}
+
+// bogo discounts GetQuantity units after every BuyQuantity, taking units in
+// scope from the dearest down so the cheapest ones are discounted
+func (p *Promotion) bogo(cart *Cart, remaining []float64) []float64 {
+	type unit struct {
+		line  int
+		price float64
+	}
+	var units []unit
+	for i, l := range cart.Lines {
+		if l.Quantity <= 0 || !p.Rule.covers(l) {
+			continue
+		}
+		price := remaining[i] / float64(l.Quantity)
+		for range l.Quantity {
+			units = append(units, unit{line: i, price: price})
+		}
+	}
+	sort.SliceStable(units, func(i, j int) bool { return units[i].price > units[j].price })
+
+	group := p.Rule.BuyQuantity + p.Rule.GetQuantity
+	discounts := make([]float64, len(cart.Lines))
+	for n, u := range units {
+		if n%group >= p.Rule.BuyQuantity {
+			discounts[u.line] += u.price * p.Rule.Percent / 100
+		}
+	}
+	return discounts
+}
+
+// basket takes the rule's amount or percentage off orders whose lines in
+// scope reach the minimum subtotal, if any, spread over those lines by value
+func (p *Promotion) basket(cart *Cart, remaining []float64) []float64 {
+	if p.Currency != cart.Currency {
+		return nil
+	}
+
+	base := 0.0
+	largest := -1
+	for i, l := range cart.Lines {
+		if !p.Rule.covers(l) || remaining[i] <= 0 {
+			continue
+		}
+		base += remaining[i]
+		if largest < 0 || remaining[i] > remaining[largest] {
+			largest = i
+		}
+	}
+	if largest < 0 || base < p.Rule.MinSubtotal {
+		return nil
+	}
+
+	off := p.Rule.Amount
+	if p.Rule.Percent > 0 {
+		off = base * p.Rule.Percent / 100
+	}
+	off = roundCents(math.Min(off, base))
+
+	// Round each share to cents and give the rounding difference to the
+	// largest line, so the shares add up to the discount
+	discounts := make([]float64, len(cart.Lines))
+	allocated := 0.0
+	for i, l := range cart.Lines {
+		if i == largest || !p.Rule.covers(l) || remaining[i] <= 0 {
+			continue
+		}
+		discounts[i] = roundCents(off * remaining[i] / base)
+		allocated += discounts[i]
+	}
+	discounts[largest] = roundCents(off - allocated)
+	return discounts
+}
+
+// covers reports whether a line is in the rule's scope
+func (r *Rule) covers(l Line) bool {
+	if len(r.ProductIDs) == 0 && len(r.CategoryIDs) == 0 {
+		return true
+	}
+	for _, id := range r.ProductIDs {
+		if id == l.ProductID {
+			return true
+		}
+	}
+	if l.CategoryID != nil {
+		for _, id := range r.CategoryIDs {
+			if id == *l.CategoryID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inWindow reports whether now falls in the rule's daily window. The part of
+// a window past midnight belongs to the day it started.
+func (r *Rule) inWindow(now time.Time) bool {
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return false
+	}
+	start, err := time.Parse(clockLayout, r.StartTime)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(clockLayout, r.EndTime)
+	if err != nil {
+		return false
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+
+	switch {
+	case from < to:
+		return minute >= from && minute < to && r.onDay(local.Weekday())
+	case minute >= from:
+		return r.onDay(local.Weekday())
+	case minute < to:
+		return r.onDay((local.Weekday() + 6) % 7)
+	}
+	return false
+}
+
+// onDay reports whether the rule's window opens on day
+func (r *Rule) onDay(day time.Weekday) bool {
+	if len(r.Days) == 0 {
+		return true
+	}
+	for _, d := range r.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// roundCents rounds an amount to cents
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,334 @@
+package promotion
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testStore = uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	testNow   = time.Date(2026, 3, 6, 18, 0, 0, 0, time.UTC) // A Friday
+)
+
+// newPromotion returns an active USD promotion of every store, created in
+// the order of its name
+func newPromotion(name string, typ RuleType, rule Rule) *Promotion {
+	return &Promotion{
+		ID:        uuid.NewSHA1(uuid.Nil, []byte(name)),
+		Name:      name,
+		Type:      typ,
+		Rule:      rule,
+		Active:    true,
+		Currency:  "USD",
+		CreatedAt: testNow.Add(-time.Hour),
+	}
+}
+
+// with returns p changed by change
+func with(p *Promotion, change func(p *Promotion)) *Promotion {
+	change(p)
+	return p
+}
+
+func at(t time.Time) *time.Time { return &t }
+
+func TestEvaluate(t *testing.T) {
+	drinks := uuid.New()
+	drink := func(quantity int, price float64) Line {
+		return Line{ProductID: "drink", CategoryID: &drinks, Quantity: quantity, UnitPrice: price}
+	}
+	item := func(product string, quantity int, price float64) Line {
+		return Line{ProductID: product, Quantity: quantity, UnitPrice: price}
+	}
+
+	tests := []struct {
+		name       string
+		promotions []*Promotion
+		coupons    []*Coupon
+		lines      []Line
+		now        time.Time
+		want       []float64 // Line discounts
+		applied    []string
+	}{
+		{
+			name:       "bogo discounts the cheapest units",
+			promotions: []*Promotion{newPromotion("3 for 2", TypeBOGO, Rule{BuyQuantity: 2, GetQuantity: 1, Percent: 100})},
+			lines:      []Line{item("shirt", 2, 20), item("sock", 1, 4)},
+			want:       []float64{0, 4},
+			applied:    []string{"3 for 2"},
+		},
+		{
+			name:       "bogo discounts a unit per group",
+			promotions: []*Promotion{newPromotion("half price", TypeBOGO, Rule{BuyQuantity: 1, GetQuantity: 1, Percent: 50})},
+			lines:      []Line{item("shirt", 1, 20), item("sock", 3, 4)},
+			want:       []float64{0, 4}, // Units 20, 4, 4, 4: the second and the fourth are half price
+			applied:    []string{"half price"},
+		},
+		{
+			name:       "bogo skips lines out of scope",
+			promotions: []*Promotion{newPromotion("socks", TypeBOGO, Rule{ProductIDs: []string{"sock"}, BuyQuantity: 1, GetQuantity: 1, Percent: 100})},
+			lines:      []Line{item("shirt", 1, 20), item("sock", 1, 4)},
+			want:       []float64{0, 0},
+		},
+		{
+			name:       "basket below its minimum subtotal",
+			promotions: []*Promotion{newPromotion("5 off 50", TypeBasketThreshold, Rule{MinSubtotal: 50, Amount: 5})},
+			lines:      []Line{item("shirt", 2, 20)},
+			want:       []float64{0},
+		},
+		{
+			name:       "basket at its minimum subtotal",
+			promotions: []*Promotion{newPromotion("5 off 50", TypeBasketThreshold, Rule{MinSubtotal: 50, Amount: 5})},
+			lines:      []Line{item("shirt", 2, 20), item("sock", 1, 10)},
+			want:       []float64{4, 1},
+			applied:    []string{"5 off 50"},
+		},
+		{
+			name:       "basket minimum counts only lines in scope",
+			promotions: []*Promotion{newPromotion("drinks", TypeBasketThreshold, Rule{CategoryIDs: []uuid.UUID{drinks}, MinSubtotal: 20, Percent: 10})},
+			lines:      []Line{item("shirt", 1, 50), drink(2, 5)},
+			want:       []float64{0, 0},
+		},
+		{
+			name:       "basket in another currency",
+			promotions: []*Promotion{with(newPromotion("eur", TypeBasketThreshold, Rule{MinSubtotal: 10, Amount: 5}), func(p *Promotion) { p.Currency = "EUR" })},
+			lines:      []Line{item("shirt", 1, 50)},
+			want:       []float64{0},
+		},
+		{
+			name:       "percent off the category in scope",
+			promotions: []*Promotion{newPromotion("drinks", TypeCategoryPercent, Rule{CategoryIDs: []uuid.UUID{drinks}, Percent: 25})},
+			lines:      []Line{item("shirt", 1, 50), drink(2, 5)},
+			want:       []float64{0, 2.5},
+			applied:    []string{"drinks"},
+		},
+		{
+			name: "promotions stack on what earlier ones left",
+			promotions: []*Promotion{
+				with(newPromotion("20 off", TypePercentOff, Rule{Percent: 20}), func(p *Promotion) { p.Priority = 1 }),
+				with(newPromotion("10 off", TypePercentOff, Rule{Percent: 10}), func(p *Promotion) { p.Priority = 5 }),
+			},
+			lines:   []Line{item("shirt", 1, 100)},
+			want:    []float64{28},
+			applied: []string{"10 off", "20 off"},
+		},
+		{
+			name: "exclusive blocks later promotions",
+			promotions: []*Promotion{
+				with(newPromotion("exclusive", TypePercentOff, Rule{Percent: 10}), func(p *Promotion) { p.Priority, p.Exclusive = 5, true }),
+				newPromotion("later", TypePercentOff, Rule{Percent: 20}),
+			},
+			lines:   []Line{item("shirt", 1, 100)},
+			want:    []float64{10},
+			applied: []string{"exclusive"},
+		},
+		{
+			name: "exclusive after another applies not",
+			promotions: []*Promotion{
+				with(newPromotion("first", TypePercentOff, Rule{Percent: 10}), func(p *Promotion) { p.Priority = 5 }),
+				with(newPromotion("exclusive", TypePercentOff, Rule{Percent: 50}), func(p *Promotion) { p.Exclusive = true }),
+			},
+			lines:   []Line{item("shirt", 1, 100)},
+			want:    []float64{10},
+			applied: []string{"first"},
+		},
+		{
+			name: "exclusive applies when earlier ones discount nothing",
+			promotions: []*Promotion{
+				with(newPromotion("socks", TypePercentOff, Rule{ProductIDs: []string{"sock"}, Percent: 10}), func(p *Promotion) { p.Priority = 5 }),
+				with(newPromotion("exclusive", TypePercentOff, Rule{Percent: 50}), func(p *Promotion) { p.Exclusive = true }),
+			},
+			lines:   []Line{item("shirt", 1, 100)},
+			want:    []float64{50},
+			applied: []string{"exclusive"},
+		},
+		{
+			name:       "before the promotion starts",
+			promotions: []*Promotion{with(newPromotion("spring", TypePercentOff, Rule{Percent: 10}), func(p *Promotion) { p.StartsAt = at(testNow.Add(time.Minute)) })},
+			lines:      []Line{item("shirt", 1, 100)},
+			want:       []float64{0},
+		},
+		{
+			name:       "when the promotion ends",
+			promotions: []*Promotion{with(newPromotion("spring", TypePercentOff, Rule{Percent: 10}), func(p *Promotion) { p.EndsAt = at(testNow) })},
+			lines:      []Line{item("shirt", 1, 100)},
+			want:       []float64{0},
+		},
+		{
+			name: "while the promotion runs",
+			promotions: []*Promotion{with(newPromotion("spring", TypePercentOff, Rule{Percent: 10}), func(p *Promotion) {
+				p.StartsAt, p.EndsAt = at(testNow), at(testNow.Add(time.Minute))
+			})},
+			lines:   []Line{item("shirt", 1, 100)},
+			want:    []float64{10},
+			applied: []string{"spring"},
+		},
+		{
+			name:       "paused promotion",
+			promotions: []*Promotion{with(newPromotion("paused", TypePercentOff, Rule{Percent: 10}), func(p *Promotion) { p.Active = false })},
+			lines:      []Line{item("shirt", 1, 100)},
+			want:       []float64{0},
+		},
+		{
+			name:       "promotion of another store",
+			promotions: []*Promotion{with(newPromotion("elsewhere", TypePercentOff, Rule{Percent: 10}), func(p *Promotion) { p.StoreIDs = []uuid.UUID{uuid.New()} })},
+			lines:      []Line{item("shirt", 1, 100)},
+			want:       []float64{0},
+		},
+		{
+			name:       "inside the happy hour",
+			promotions: []*Promotion{newPromotion("happy hour", TypeHappyHour, Rule{Percent: 50, StartTime: "17:00", EndTime: "19:00", Timezone: "UTC"})},
+			lines:      []Line{drink(1, 8)},
+			want:       []float64{4},
+			applied:    []string{"happy hour"},
+		},
+		{
+			name:       "when the happy hour ends",
+			promotions: []*Promotion{newPromotion("happy hour", TypeHappyHour, Rule{Percent: 50, StartTime: "17:00", EndTime: "19:00", Timezone: "UTC"})},
+			lines:      []Line{drink(1, 8)},
+			now:        testNow.Add(time.Hour),
+			want:       []float64{0},
+		},
+		{
+			name:       "happy hour in its own timezone",
+			promotions: []*Promotion{newPromotion("happy hour", TypeHappyHour, Rule{Percent: 50, StartTime: "17:00", EndTime: "19:00", Timezone: "America/New_York"})},
+			lines:      []Line{drink(1, 8)},
+			want:       []float64{0}, // 13:00 in New York
+		},
+		{
+			name:       "happy hour past midnight belongs to the day it started",
+			promotions: []*Promotion{newPromotion("late", TypeHappyHour, Rule{Percent: 50, StartTime: "22:00", EndTime: "02:00", Days: []time.Weekday{time.Friday}, Timezone: "UTC"})},
+			lines:      []Line{drink(1, 8)},
+			now:        testNow.Add(7 * time.Hour), // Saturday 01:00
+			want:       []float64{4},
+			applied:    []string{"late"},
+		},
+		{
+			name:       "happy hour on another day",
+			promotions: []*Promotion{newPromotion("late", TypeHappyHour, Rule{Percent: 50, StartTime: "22:00", EndTime: "02:00", Days: []time.Weekday{time.Saturday}, Timezone: "UTC"})},
+			lines:      []Line{drink(1, 8)},
+			now:        testNow.Add(7 * time.Hour), // Saturday 01:00, in Friday's window
+			want:       []float64{0},
+		},
+		{
+			name:       "coupon-only promotion without its coupon",
+			promotions: []*Promotion{with(newPromotion("welcome", TypePercentOff, Rule{Percent: 10}), func(p *Promotion) { p.CouponOnly = true })},
+			lines:      []Line{item("shirt", 1, 100)},
+			want:       []float64{0},
+		},
+		{
+			name:       "coupon-only promotion with its coupon",
+			promotions: []*Promotion{with(newPromotion("welcome", TypePercentOff, Rule{Percent: 10}), func(p *Promotion) { p.CouponOnly = true })},
+			coupons:    []*Coupon{{Code: "WELCOME", PromotionID: uuid.NewSHA1(uuid.Nil, []byte("welcome"))}},
+			lines:      []Line{item("shirt", 1, 100)},
+			want:       []float64{10},
+			applied:    []string{"welcome"},
+		},
+		{
+			name:       "discounts never exceed the line",
+			promotions: []*Promotion{newPromotion("100 off", TypeAmountOff, Rule{Amount: 100})},
+			lines:      []Line{item("sock", 2, 4)},
+			want:       []float64{8},
+			applied:    []string{"100 off"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := tt.now
+			if now.IsZero() {
+				now = testNow
+			}
+			cart := &Cart{StoreID: testStore, Currency: "USD", Lines: tt.lines}
+			result := Evaluate(tt.promotions, tt.coupons, cart, now)
+
+			assert.Equal(t, tt.want, result.LineDiscounts)
+			names := []string{}
+			total := 0.0
+			for _, a := range result.Applied {
+				names = append(names, a.Name)
+				total += a.Amount
+			}
+			if tt.applied == nil {
+				tt.applied = []string{}
+			}
+			assert.Equal(t, tt.applied, names)
+			assert.InDelta(t, total, result.Total, 0.001)
+		})
+	}
+}
+
+func TestEvaluateRecordsUnlockingCoupon(t *testing.T) {
+	p := with(newPromotion("welcome", TypePercentOff, Rule{Percent: 10}), func(p *Promotion) { p.CouponOnly = true })
+	cart := &Cart{StoreID: testStore, Currency: "USD", Lines: []Line{{ProductID: "shirt", Quantity: 1, UnitPrice: 100}}}
+
+	result := Evaluate([]*Promotion{p}, []*Coupon{{Code: "WELCOME", PromotionID: p.ID}}, cart, testNow)
+	require.Len(t, result.Applied, 1)
+	assert.Equal(t, "WELCOME", result.Applied[0].Coupon)
+	assert.Equal(t, 10.0, result.Applied[0].Amount)
+}
+
+func TestBasketAllocatesEveryCent(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   Rule
+		prices []float64
+		off    float64
+	}{
+		{name: "thirds", rule: Rule{Amount: 10}, prices: []float64{10, 10, 10}, off: 10},
+		{name: "uneven lines", rule: Rule{Amount: 7.01}, prices: []float64{3.33, 19.99, 0.07, 5.55}, off: 7.01},
+		{name: "percentage", rule: Rule{Percent: 15}, prices: []float64{1.11, 2.22, 3.33, 4.44, 5.55, 6.66}, off: 3.50},
+		{name: "more than the basket", rule: Rule{Amount: 50}, prices: []float64{0.1, 0.2}, off: 0.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPromotion(tt.name, TypeBasketThreshold, tt.rule)
+			cart := &Cart{Currency: "USD"}
+			for _, price := range tt.prices {
+				cart.Lines = append(cart.Lines, Line{ProductID: "item", Quantity: 1, UnitPrice: price})
+			}
+
+			discounts := p.basket(cart, tt.prices)
+			sum := 0.0
+			for i, d := range discounts {
+				assert.Equal(t, roundCents(d), d, "line %d is not discounted in cents", i)
+				assert.LessOrEqual(t, d, tt.prices[i])
+				sum += d
+			}
+			assert.Equal(t, tt.off, roundCents(sum))
+
+			// Evaluate hands out the same shares, adding up to the total
+			result := Evaluate([]*Promotion{p}, nil, cart, testNow)
+			assert.Equal(t, discounts, result.LineDiscounts)
+			assert.Equal(t, tt.off, result.Total)
+		})
+	}
+}
+
+func TestCouponCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		coupon   Coupon
+		userUses int
+		want     error
+	}{
+		{name: "unlimited", coupon: Coupon{Active: true, Uses: 1000}, userUses: 1000},
+		{name: "under its limits", coupon: Coupon{Active: true, MaxUses: 10, MaxUsesPerUser: 2, Uses: 9}, userUses: 1},
+		{name: "at its usage limit", coupon: Coupon{Active: true, MaxUses: 10, Uses: 10}, want: ErrCouponExhausted},
+		{name: "at the customer's limit", coupon: Coupon{Active: true, MaxUsesPerUser: 2}, userUses: 2, want: ErrCouponExhausted},
+		{name: "inactive", coupon: Coupon{}, want: ErrCouponInactive},
+		{name: "expired", coupon: Coupon{Active: true, ExpiresAt: at(testNow)}, want: ErrCouponExpired},
+		{name: "before expiry", coupon: Coupon{Active: true, ExpiresAt: at(testNow.Add(time.Second))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.coupon.Check(testNow, tt.userUses))
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package promotion
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound is returned when a promotion does not exist
+	ErrNotFound = errors.New("promotion not found")
+	// ErrInvalidRule is returned when a promotion's rule is incomplete for its type
+	ErrInvalidRule = errors.New("invalid promotion rule")
+)
+
+// RuleType represents how a promotion discounts an order
+type RuleType string
+
+const (
+	TypeBOGO            RuleType = "bogo"             // Buy some units, get more at a discount
+	TypeBasketThreshold RuleType = "basket_threshold" // An amount or percentage off orders above a subtotal
+	TypeCategoryPercent RuleType = "category_percent" // A percentage off items of some categories
+	TypeHappyHour       RuleType = "happy_hour"       // A percentage off during a daily time window
+	TypePercentOff      RuleType = "percent_off"      // A percentage off the items in scope
+	TypeAmountOff       RuleType = "amount_off"       // A fixed amount off the items in scope
+)
+
+// Rule holds the parameters of a promotion. Which fields apply depends on
+// the promotion's type.
+type Rule struct {
+	// Scope: the variants and categories a promotion applies to. Empty
+	// scopes apply to every item; category_percent requires categories.
+	ProductIDs  []string    `json:"product_ids,omitempty"` // Catalog variant IDs
+	CategoryIDs []uuid.UUID `json:"category_ids,omitempty"`
+
+	// Percent off the discounted units (bogo, where 100 makes them free), the
+	// items in scope (category_percent, happy_hour, percent_off) or the
+	// basket (basket_threshold)
+	Percent float64 `json:"percent,omitempty"`
+
+	// bogo: every BuyQuantity units bought make the next GetQuantity cheapest
+	// units discounted
+	BuyQuantity int `json:"buy_quantity,omitempty"`
+	GetQuantity int `json:"get_quantity,omitempty"`
+
+	// basket_threshold: the subtotal in scope the order must reach, and the
+	// amount off when no percentage is given; both in the promotion's
+	// currency. amount_off takes Amount off the items in scope, once they
+	// reach MinSubtotal if given.
+	MinSubtotal float64 `json:"min_subtotal,omitempty"`
+	Amount      float64 `json:"amount,omitempty"`
+
+	// happy_hour: the daily window, as "15:04" in Timezone, on Days (0 is
+	// Sunday; empty is every day). A window ending before it starts runs past
+	// midnight.
+	StartTime string         `json:"start_time,omitempty"`
+	EndTime   string         `json:"end_time,omitempty"`
+	Days      []time.Weekday `json:"days,omitempty"`
+	Timezone  string         `json:"timezone,omitempty"`
+}
+
+// Promotion represents a campaign that discounts orders while it runs
+type Promotion struct {
+	ID          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Type        RuleType    `json:"type"`
+	Rule        Rule        `json:"rule"`
+	Priority    int         `json:"priority"`            // Higher priorities apply first
+	Exclusive   bool        `json:"exclusive"`           // Applies only alone; see Evaluate
+	Active      bool        `json:"active"`              // Paused campaigns are kept but not applied
+	CouponOnly  bool        `json:"coupon_only"`         // Applies only to orders presenting one of its coupons
+	StoreIDs    []uuid.UUID `json:"store_ids,omitempty"` // Empty runs in every store
+	Currency    string      `json:"currency,omitempty"`  // Of the rule's amounts; required by basket_threshold
+	StartsAt    *time.Time  `json:"starts_at,omitempty"`
+	EndsAt      *time.Time  `json:"ends_at,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// Validate checks that the promotion's rule is complete for its type
+func (p *Promotion) Validate() error {
+	r := &p.Rule
+	if r.Percent < 0 || r.Percent > 100 {
+		return fmt.Errorf("%w: percent must be between 0 and 100", ErrInvalidRule)
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidRule)
+	}
+
+	switch p.Type {
+	case TypeBOGO:
+		if r.BuyQuantity < 1 || r.GetQuantity < 1 {
+			return fmt.Errorf("%w: bogo needs buy_quantity and get_quantity", ErrInvalidRule)
+		}
+		if r.Percent == 0 {
+			return fmt.Errorf("%w: bogo needs a percent off the units it discounts", ErrInvalidRule)
+		}
+	case TypeBasketThreshold:
+		if p.Currency == "" {
+			return fmt.Errorf("%w: basket_threshold needs a currency", ErrInvalidRule)
+		}
+		if r.MinSubtotal <= 0 {
+			return fmt.Errorf("%w: basket_threshold needs min_subtotal", ErrInvalidRule)
+		}
+		if (r.Percent == 0) == (r.Amount <= 0) {
+			return fmt.Errorf("%w: basket_threshold needs either percent or amount", ErrInvalidRule)
+		}
+	case TypeCategoryPercent:
+		if len(r.CategoryIDs) == 0 || r.Percent == 0 {
+			return fmt.Errorf("%w: category_percent needs category_ids and percent", ErrInvalidRule)
+		}
+	case TypePercentOff:
+		if r.Percent == 0 {
+			return fmt.Errorf("%w: percent_off needs percent", ErrInvalidRule)
+		}
+	case TypeAmountOff:
+		if p.Currency == "" {
+			return fmt.Errorf("%w: amount_off needs a currency", ErrInvalidRule)
+		}
+		if r.Amount <= 0 || r.Percent != 0 {
+			return fmt.Errorf("%w: amount_off needs an amount and no percent", ErrInvalidRule)
+		}
+		if r.MinSubtotal < 0 {
+			return fmt.Errorf("%w: min_subtotal cannot be negative", ErrInvalidRule)
+		}
+	case TypeHappyHour:
+		if r.Percent == 0 {
+			return fmt.Errorf("%w: happy_hour needs percent", ErrInvalidRule)
+		}
+		if _, err := time.Parse(clockLayout, r.StartTime); err != nil {
+			return fmt.Errorf("%w: happy_hour start_time must be HH:MM", ErrInvalidRule)
+		}
+		if _, err := time.Parse(clockLayout, r.EndTime); err != nil {
+			return fmt.Errorf("%w: happy_hour end_time must be HH:MM", ErrInvalidRule)
+		}
+		if r.StartTime == r.EndTime {
+			return fmt.Errorf("%w: happy_hour window is empty", ErrInvalidRule)
+		}
+		if _, err := time.LoadLocation(r.Timezone); err != nil {
+			return fmt.Errorf("%w: unknown timezone %q", ErrInvalidRule, r.Timezone)
+		}
+		for _, d := range r.Days {
+			if d < time.Sunday || d > time.Saturday {
+				return fmt.Errorf("%w: days must be 0 (Sunday) to 6", ErrInvalidRule)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidRule, p.Type)
+	}
+	return nil
+}
+
+// RunsAt reports whether the promotion applies to orders in storeID at now
+func (p *Promotion) RunsAt(storeID uuid.UUID, now time.Time) bool {
+	if !p.Active {
+		return false
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return false
+	}
+	if len(p.StoreIDs) == 0 {
+		return true
+	}
+	for _, id := range p.StoreIDs {
+		if id == storeID {
+			return true
+		}
+	}
+	return false
+}
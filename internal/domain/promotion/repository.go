@@ -0,0 +1,44 @@
+package promotion
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository defines the promotion repository interface
+type Repository interface {
+	Create(ctx context.Context, p *Promotion) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Promotion, error)
+	// List returns campaigns by descending priority; activeOnly leaves out
+	// paused ones
+	List(ctx context.Context, activeOnly bool, limit, offset int) ([]*Promotion, error)
+	Update(ctx context.Context, p *Promotion) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// GetRunning returns the active promotions of storeID whose schedule
+	// includes now
+	GetRunning(ctx context.Context, storeID uuid.UUID, now time.Time) ([]*Promotion, error)
+}
+
+// CouponRepository defines the coupon repository interface
+type CouponRepository interface {
+	// CreateCoupon fails with ErrCouponExists when the code is in use
+	CreateCoupon(ctx context.Context, c *Coupon) error
+	GetCoupon(ctx context.Context, id uuid.UUID) (*Coupon, error)
+	GetCouponByCode(ctx context.Context, code string) (*Coupon, error)
+	ListCoupons(ctx context.Context, promotionID uuid.UUID, limit, offset int) ([]*Coupon, error)
+	UpdateCoupon(ctx context.Context, c *Coupon) error
+	DeleteCoupon(ctx context.Context, id uuid.UUID) error
+	// UserRedemptions counts the redemptions of a coupon by a customer
+	UserRedemptions(ctx context.Context, couponID, userID uuid.UUID) (int, error)
+	// Redeemed reports whether an order redeemed a coupon
+	Redeemed(ctx context.Context, couponID, orderID uuid.UUID) (bool, error)
+	// Redeem records an order's use of a coupon, failing with
+	// ErrCouponExhausted once either usage limit is reached and with
+	// ErrCouponRedeemed when the order used it already
+	Redeem(ctx context.Context, r *CouponRedemption) error
+	// Release forgets the coupons an order redeemed, so they can be used
+	// again. Orders without redemptions are ignored.
+	Release(ctx context.Context, orderID uuid.UUID) error
+}
@@ -0,0 +1,73 @@
+package receipt
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrJobNotFound = errors.New("print job not found")
+	ErrJobClosed   = errors.New("print job is not awaiting acknowledgement")
+)
+
+// Template is how a store's receipts look and print
+type Template struct {
+	StoreID     uuid.UUID `json:"store_id"`
+	Header      []string  `json:"header"` // Store name first, then address and contact lines
+	Footer      []string  `json:"footer"`
+	Width       int       `json:"width"`        // Characters per line of the store's printers: 32 for 58mm paper, 42 or 48 for 80mm
+	ShowBarcode bool      `json:"show_barcode"` // Print the order number as a barcode
+	OpenDrawer  bool      `json:"open_drawer"`  // Kick the cash drawer when a receipt prints
+	DrawerPin   int       `json:"drawer_pin"`   // Drawer connector pin, 2 or 5
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DefaultTemplate is the template of a store that has not saved one
+func DefaultTemplate(storeID uuid.UUID) *Template {
+	return &Template{
+		StoreID:     storeID,
+		Header:      []string{},
+		Footer:      []string{"Thank you for your purchase"},
+		Width:       48,
+		ShowBarcode: true,
+		DrawerPin:   2,
+	}
+}
+
+// JobKind is what a print job does
+type JobKind string
+
+const (
+	KindReceipt JobKind = "receipt"
+	KindDrawer  JobKind = "drawer" // Opens the cash drawer without printing
+)
+
+// JobStatus is how far a print job got
+type JobStatus string
+
+const (
+	JobQueued   JobStatus = "queued"
+	JobPrinting JobStatus = "printing" // Handed to an agent, awaiting its acknowledgement
+	JobPrinted  JobStatus = "printed"
+	JobFailed   JobStatus = "failed"
+)
+
+// PrintJob is an ESC/POS payload queued for a store's print agents. A job
+// handed to an agent that does not acknowledge it within the lease is handed
+// out again, up to a number of attempts.
+type PrintJob struct {
+	ID          uuid.UUID  `json:"id"`
+	StoreID     uuid.UUID  `json:"store_id"`
+	PrinterID   string     `json:"printer_id,omitempty"` // Empty lets any of the store's agents print it
+	OrderID     *uuid.UUID `json:"order_id,omitempty"`
+	Kind        JobKind    `json:"kind"`
+	Status      JobStatus  `json:"status"`
+	Payload     []byte     `json:"payload,omitempty"` // ESC/POS bytes, base64 in JSON
+	Attempts    int        `json:"attempts"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ClaimedAt   *time.Time `json:"claimed_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,30 @@
+package receipt
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository defines the receipt repository interface
+type Repository interface {
+	// GetTemplate returns a store's template, or its default when it has
+	// not saved one
+	GetTemplate(ctx context.Context, storeID uuid.UUID) (*Template, error)
+	SaveTemplate(ctx context.Context, t *Template) error
+
+	CreateJob(ctx context.Context, job *PrintJob) error
+	GetJob(ctx context.Context, id uuid.UUID) (*PrintJob, error)
+	// ListJobs returns a store's jobs, newest first and without payloads. An
+	// empty status lists every job.
+	ListJobs(ctx context.Context, storeID uuid.UUID, status JobStatus, limit, offset int) ([]*PrintJob, error)
+
+	// ClaimJobs hands up to limit of a store's queued jobs, oldest first, to
+	// the agent of printerID, along with jobs whose lease has run out. Jobs
+	// out of lease after maxAttempts fail instead.
+	ClaimJobs(ctx context.Context, storeID uuid.UUID, printerID string, limit int, now time.Time, lease time.Duration, maxAttempts int) ([]*PrintJob, error)
+	// CompleteJob records an agent's acknowledgement of a job it was handed,
+	// failing with ErrJobClosed when the job is not awaiting one
+	CompleteJob(ctx context.Context, id, storeID uuid.UUID, printed bool, message string, now time.Time) (*PrintJob, error)
+}
@@ -0,0 +1,22 @@
+package search
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Repository defines the search index interface. Writes carry a version,
+// the time of the event they come from, so events delivered out of order
+// never replace a newer document with an older one.
+type Repository interface {
+	// EnsureIndexes creates the indexes and their mappings if missing
+	EnsureIndexes(ctx context.Context) error
+
+	IndexOrder(ctx context.Context, doc *OrderDocument, version int64) error
+	IndexProduct(ctx context.Context, doc *ProductDocument, version int64) error
+	DeleteProduct(ctx context.Context, id uuid.UUID, version int64) error
+
+	SearchOrders(ctx context.Context, q OrderQuery) (*OrderResults, error)
+	SearchProducts(ctx context.Context, q ProductQuery) (*ProductResults, error)
+}
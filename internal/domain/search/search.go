@@ -0,0 +1,95 @@
+package search
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrderDocument is an order as indexed for search, with its customer's name
+// and email denormalized from the user service
+type OrderDocument struct {
+	ID            uuid.UUID `json:"id"`
+	TenantID      string    `json:"tenant_id"`
+	StoreID       uuid.UUID `json:"store_id"`
+	UserID        uuid.UUID `json:"user_id"`
+	CustomerName  string    `json:"customer_name,omitempty"`
+	CustomerEmail string    `json:"customer_email,omitempty"`
+	Status        string    `json:"status"`
+	TotalAmount   float64   `json:"total_amount"`
+	Currency      string    `json:"currency"`
+	ItemNames     []string  `json:"item_names"`
+	ProductIDs    []string  `json:"product_ids"` // Catalog variant IDs
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"` // Time of the event last indexed
+}
+
+// ProductDocument is a product and its variants as indexed for search
+type ProductDocument struct {
+	ID           uuid.UUID  `json:"id"`
+	TenantID     string     `json:"tenant_id"`
+	CategoryID   *uuid.UUID `json:"category_id,omitempty"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description,omitempty"`
+	Brand        string     `json:"brand,omitempty"`
+	Status       string     `json:"status"`
+	SKUs         []string   `json:"skus"`
+	Barcodes     []string   `json:"barcodes"`
+	VariantNames []string   `json:"variant_names"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Interval is the bucket size of the date facet of an order search
+type Interval string
+
+const (
+	Day   Interval = "day"
+	Week  Interval = "week"
+	Month Interval = "month"
+)
+
+// OrderQuery selects orders. Text matches customer names and emails and item
+// names, tolerating typos; the other fields filter exactly.
+type OrderQuery struct {
+	Text     string
+	Status   string
+	StoreID  *uuid.UUID
+	From     *time.Time // Inclusive
+	To       *time.Time // Exclusive
+	Interval Interval   // Of the created_at facet
+	Limit    int
+	Offset   int
+}
+
+// ProductQuery selects products. Text matches names, brands, descriptions
+// and variant names, tolerating typos, and SKUs and barcodes exactly.
+type ProductQuery struct {
+	Text       string
+	Status     string
+	Brand      string
+	CategoryID *uuid.UUID
+	Limit      int
+	Offset     int
+}
+
+// Bucket is one value of a facet and how many results have it
+type Bucket struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// OrderResults is a page of matching orders. Facets count every match, not
+// just the page: by "status", "store_id" and "created_at".
+type OrderResults struct {
+	Total  int64               `json:"total"`
+	Orders []*OrderDocument    `json:"data"`
+	Facets map[string][]Bucket `json:"facets"`
+}
+
+// ProductResults is a page of matching products, with facets by "status",
+// "brand" and "category_id"
+type ProductResults struct {
+	Total    int64               `json:"total"`
+	Products []*ProductDocument  `json:"data"`
+	Facets   map[string][]Bucket `json:"facets"`
+}
@@ -0,0 +1,68 @@
+package shift
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Time clock event types, published to the message broker with the event
+// type as routing key
+const (
+	EventTimeEntryClosed   = "timeclock.entry.closed"   // The staff member clocked out
+	EventTimeEntryAdjusted = "timeclock.entry.adjusted" // A manager changed the entry
+)
+
+// TimeEntryEventData is the payload of a time clock event on the message
+// broker: the entry as it stands, with its durations worked out
+type TimeEntryEventData struct {
+	EntryID            uuid.UUID   `json:"entry_id"`
+	StoreID            uuid.UUID   `json:"store_id"`
+	StaffID            uuid.UUID   `json:"staff_id"`
+	ShiftID            *uuid.UUID  `json:"shift_id,omitempty"`
+	Status             EntryStatus `json:"status"`
+	ClockIn            time.Time   `json:"clock_in"`
+	ClockOut           *time.Time  `json:"clock_out,omitempty"`
+	WorkedSeconds      int64       `json:"worked_seconds"`
+	UnpaidBreakSeconds int64       `json:"unpaid_break_seconds"`
+	Version            int         `json:"version"`
+}
+
+// NewTimeEntryEventData returns the event payload of an entry, with an open
+// entry's durations counted up to now
+func NewTimeEntryEventData(e *TimeEntry, now time.Time) TimeEntryEventData {
+	worked, breaks := e.Durations(now)
+	return TimeEntryEventData{
+		EntryID:            e.ID,
+		StoreID:            e.StoreID,
+		StaffID:            e.StaffID,
+		ShiftID:            e.ShiftID,
+		Status:             e.Status,
+		ClockIn:            e.ClockIn,
+		ClockOut:           e.ClockOut,
+		WorkedSeconds:      int64(worked / time.Second),
+		UnpaidBreakSeconds: int64(breaks / time.Second),
+		Version:            e.Version,
+	}
+}
+
+// Map returns the payload as a generic map, for publishers that take one
+func (d TimeEntryEventData) Map() map[string]interface{} {
+	data := map[string]interface{}{
+		"entry_id":             d.EntryID,
+		"store_id":             d.StoreID,
+		"staff_id":             d.StaffID,
+		"status":               d.Status,
+		"clock_in":             d.ClockIn,
+		"worked_seconds":       d.WorkedSeconds,
+		"unpaid_break_seconds": d.UnpaidBreakSeconds,
+		"version":              d.Version,
+	}
+	if d.ShiftID != nil {
+		data["shift_id"] = *d.ShiftID
+	}
+	if d.ClockOut != nil {
+		data["clock_out"] = *d.ClockOut
+	}
+	return data
+}
@@ -0,0 +1,303 @@
+package shift
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReportKind tells a mid-shift X report from the final Z report
+type ReportKind string
+
+const (
+	ReportX ReportKind = "X" // Read while the shift is open; nothing is reset
+	ReportZ ReportKind = "Z" // Taken once at close and kept unchanged
+)
+
+// Report totals a shift's sales, tenders, tax and cash
+type Report struct {
+	Kind        ReportKind     `json:"kind"`
+	ShiftID     uuid.UUID      `json:"shift_id"`
+	StoreID     uuid.UUID      `json:"store_id"`
+	RegisterID  string         `json:"register_id"`
+	StaffID     uuid.UUID      `json:"staff_id"`
+	Currency    string         `json:"currency"`
+	OpenedAt    time.Time      `json:"opened_at"`
+	ClosedAt    *time.Time     `json:"closed_at,omitempty"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Sales       SalesSummary   `json:"sales"`
+	Tenders     []TenderTotal  `json:"tenders"`
+	Tax         TaxSummary     `json:"tax"`
+	Cash        CashSummary    `json:"cash"`
+	Movements   []MovementLine `json:"movements"`
+}
+
+// SalesSummary counts the sales rung up on a shift. Net sales are gross
+// sales less voids and refunds.
+type SalesSummary struct {
+	Orders        int     `json:"orders"` // Every sale rung up, including those voided or refunded since
+	Gross         float64 `json:"gross"`
+	Voids         int     `json:"voids"`
+	VoidAmount    float64 `json:"void_amount"`
+	Refunds       int     `json:"refunds"`
+	RefundAmount  float64 `json:"refund_amount"`
+	Net           float64 `json:"net"`
+	AverageTicket float64 `json:"average_ticket"` // Net sales per sale kept
+}
+
+// TenderTotal is the net takings of one tender
+type TenderTotal struct {
+	Tender Tender  `json:"tender"`
+	Orders int     `json:"orders"` // Sales kept
+	Amount float64 `json:"amount"` // Net of voids and refunds
+}
+
+// TaxSummary is the tax charged on the sales kept, as quoted for each order
+// by the tax service
+type TaxSummary struct {
+	Taxable float64 `json:"taxable"` // Net sales before tax
+	Amount  float64 `json:"amount"`
+}
+
+// CashSummary reconciles the drawer: the cash expected is the opening float
+// plus net cash sales and pay ins, less pay outs and drops. Over/short is
+// the cash counted less the cash expected, known once the shift is closed.
+type CashSummary struct {
+	OpeningFloat float64  `json:"opening_float"`
+	Sales        float64  `json:"sales"`
+	PayIns       float64  `json:"pay_ins"`
+	PayOuts      float64  `json:"pay_outs"`
+	Drops        float64  `json:"drops"`
+	Expected     float64  `json:"expected"`
+	Counted      *float64 `json:"counted,omitempty"`
+	OverShort    *float64 `json:"over_short,omitempty"`
+}
+
+// MovementLine totals one type of cash movement
+type MovementLine struct {
+	Type   MovementType `json:"type"`
+	Count  int          `json:"count"`
+	Amount float64      `json:"amount"`
+}
+
+// BuildReport totals a shift's sales and cash movements. Sales in other
+// currencies than the shift's are left out, since they were not taken in its
+// drawer.
+func BuildReport(kind ReportKind, s *Shift, sales []*Sale, movements []*CashMovement, now time.Time) *Report {
+	r := &Report{
+		Kind:        kind,
+		ShiftID:     s.ID,
+		StoreID:     s.StoreID,
+		RegisterID:  s.RegisterID,
+		StaffID:     s.StaffID,
+		Currency:    s.Currency,
+		OpenedAt:    s.OpenedAt,
+		ClosedAt:    s.ClosedAt,
+		GeneratedAt: now,
+		Tenders:     []TenderTotal{},
+		Movements:   []MovementLine{},
+	}
+
+	tenders := make(map[Tender]*TenderTotal)
+	for _, sale := range sales {
+		if sale.Currency != s.Currency {
+			continue
+		}
+		t := tenders[sale.Tender]
+		if t == nil {
+			t = &TenderTotal{Tender: sale.Tender}
+			tenders[sale.Tender] = t
+		}
+
+		r.Sales.Orders++
+		r.Sales.Gross += sale.Amount
+		switch sale.Status {
+		case SaleVoided:
+			r.Sales.Voids++
+			r.Sales.VoidAmount += sale.Amount
+		case SaleRefunded:
+			r.Sales.Refunds++
+			r.Sales.RefundAmount += sale.Amount
+		default:
+			t.Orders++
+			t.Amount += sale.Amount
+			r.Tax.Amount += sale.Tax
+		}
+	}
+	r.Sales.Net = r.Sales.Gross - r.Sales.VoidAmount - r.Sales.RefundAmount
+	if kept := r.Sales.Orders - r.Sales.Voids - r.Sales.Refunds; kept > 0 {
+		r.Sales.AverageTicket = r.Sales.Net / float64(kept)
+	}
+
+	for _, t := range tenders {
+		t.Amount = round(t.Amount)
+		r.Tenders = append(r.Tenders, *t)
+	}
+	sort.Slice(r.Tenders, func(i, j int) bool { return r.Tenders[i].Tender < r.Tenders[j].Tender })
+
+	r.Tax.Amount = round(r.Tax.Amount)
+	r.Tax.Taxable = round(r.Sales.Net - r.Tax.Amount)
+
+	lines := make(map[MovementType]*MovementLine)
+	for _, m := range movements {
+		line := lines[m.Type]
+		if line == nil {
+			line = &MovementLine{Type: m.Type}
+			lines[m.Type] = line
+		}
+		line.Count++
+		line.Amount += m.Amount
+
+		switch m.Type {
+		case MovementPayIn:
+			r.Cash.PayIns += m.Amount
+		case MovementPayOut:
+			r.Cash.PayOuts += m.Amount
+		case MovementDrop:
+			r.Cash.Drops += m.Amount
+		}
+	}
+	for _, line := range lines {
+		line.Amount = round(line.Amount)
+		r.Movements = append(r.Movements, *line)
+	}
+	sort.Slice(r.Movements, func(i, j int) bool { return r.Movements[i].Type < r.Movements[j].Type })
+
+	if cash := tenders[TenderCash]; cash != nil {
+		r.Cash.Sales = cash.Amount
+	}
+	r.Cash.OpeningFloat = s.OpeningFloat
+	r.Cash.Expected = round(s.OpeningFloat + r.Cash.Sales + r.Cash.PayIns - r.Cash.PayOuts - r.Cash.Drops)
+	if s.CountedCash != nil {
+		counted := *s.CountedCash
+		overShort := round(counted - r.Cash.Expected)
+		r.Cash.Counted = &counted
+		r.Cash.OverShort = &overShort
+	}
+
+	r.Sales.Gross = round(r.Sales.Gross)
+	r.Sales.VoidAmount = round(r.Sales.VoidAmount)
+	r.Sales.RefundAmount = round(r.Sales.RefundAmount)
+	r.Sales.Net = round(r.Sales.Net)
+	r.Sales.AverageTicket = round(r.Sales.AverageTicket)
+	r.Cash.PayIns = round(r.Cash.PayIns)
+	r.Cash.PayOuts = round(r.Cash.PayOuts)
+	r.Cash.Drops = round(r.Cash.Drops)
+	return r
+}
+
+// round rounds an amount to cents
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// DayReport reconciles the cash of the shifts closed on a day from their Z
+// reports, in total and per cashier. Shifts in different currencies are
+// totalled apart.
+type DayReport struct {
+	Date     string         `json:"date"` // YYYY-MM-DD, in UTC
+	StoreID  *uuid.UUID     `json:"store_id,omitempty"`
+	Totals   []DayTotal     `json:"totals"`
+	Cashiers []CashierTotal `json:"cashiers"`
+	Reports  []*Report      `json:"reports"`
+}
+
+// DayTotal totals the Z reports of a day in one currency
+type DayTotal struct {
+	Currency  string  `json:"currency"`
+	Shifts    int     `json:"shifts"`
+	NetSales  float64 `json:"net_sales"`
+	Expected  float64 `json:"expected"` // Cash expected in the drawers at close
+	Counted   float64 `json:"counted"`
+	OverShort float64 `json:"over_short"` // Counted less expected
+}
+
+// CashierTotal totals the Z reports of one cashier's shifts of a day in one
+// currency
+type CashierTotal struct {
+	StaffID   uuid.UUID `json:"staff_id"`
+	Currency  string    `json:"currency"`
+	Shifts    int       `json:"shifts"`
+	Expected  float64   `json:"expected"`
+	Counted   float64   `json:"counted"`
+	OverShort float64   `json:"over_short"`
+}
+
+// BuildDayReport totals the Z reports of the shifts closed on day, at
+// storeID when set, ordered by close
+func BuildDayReport(day time.Time, storeID *uuid.UUID, reports []*Report) *DayReport {
+	sort.SliceStable(reports, func(i, j int) bool {
+		return closedAt(reports[i]).Before(closedAt(reports[j]))
+	})
+
+	d := &DayReport{
+		Date:     day.Format("2006-01-02"),
+		StoreID:  storeID,
+		Totals:   []DayTotal{},
+		Cashiers: []CashierTotal{},
+		Reports:  reports,
+	}
+
+	type cashierKey struct {
+		staffID  uuid.UUID
+		currency string
+	}
+	totals := make(map[string]*DayTotal)
+	cashiers := make(map[cashierKey]*CashierTotal)
+	for _, r := range reports {
+		var counted, overShort float64
+		if r.Cash.Counted != nil {
+			counted, overShort = *r.Cash.Counted, *r.Cash.OverShort
+		}
+
+		t := totals[r.Currency]
+		if t == nil {
+			t = &DayTotal{Currency: r.Currency}
+			totals[r.Currency] = t
+		}
+		t.Shifts++
+		t.NetSales += r.Sales.Net
+		t.Expected += r.Cash.Expected
+		t.Counted += counted
+		t.OverShort += overShort
+
+		key := cashierKey{r.StaffID, r.Currency}
+		c := cashiers[key]
+		if c == nil {
+			c = &CashierTotal{StaffID: r.StaffID, Currency: r.Currency}
+			cashiers[key] = c
+		}
+		c.Shifts++
+		c.Expected += r.Cash.Expected
+		c.Counted += counted
+		c.OverShort += overShort
+	}
+
+	for _, t := range totals {
+		t.NetSales, t.Expected, t.Counted, t.OverShort = round(t.NetSales), round(t.Expected), round(t.Counted), round(t.OverShort)
+		d.Totals = append(d.Totals, *t)
+	}
+	sort.Slice(d.Totals, func(i, j int) bool { return d.Totals[i].Currency < d.Totals[j].Currency })
+
+	for _, c := range cashiers {
+		c.Expected, c.Counted, c.OverShort = round(c.Expected), round(c.Counted), round(c.OverShort)
+		d.Cashiers = append(d.Cashiers, *c)
+	}
+	sort.Slice(d.Cashiers, func(i, j int) bool {
+		if d.Cashiers[i].StaffID != d.Cashiers[j].StaffID {
+			return d.Cashiers[i].StaffID.String() < d.Cashiers[j].StaffID.String()
+		}
+		return d.Cashiers[i].Currency < d.Cashiers[j].Currency
+	})
+	return d
+}
+
+// closedAt returns when a report's shift closed, or the zero time
+func closedAt(r *Report) time.Time {
+	if r.ClosedAt == nil {
+		return time.Time{}
+	}
+	return *r.ClosedAt
+}
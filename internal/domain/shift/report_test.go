@@ -0,0 +1,291 @@
+package shift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	staffA = uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	staffB = uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+)
+
+func sale(tender Tender, amount, tax float64, status SaleStatus) *Sale {
+	return &Sale{OrderID: uuid.New(), Tender: tender, Amount: amount, Tax: tax, Currency: "USD", Status: status}
+}
+
+func movement(t MovementType, amount float64) *CashMovement {
+	return &CashMovement{ID: uuid.New(), Type: t, Amount: amount}
+}
+
+func amount(a float64) *float64 {
+	return &a
+}
+
+func TestBuildReport(t *testing.T) {
+	tests := []struct {
+		name         string
+		openingFloat float64
+		counted      *float64
+		sales        []*Sale
+		movements    []*CashMovement
+		wantSales    SalesSummary
+		wantTenders  []TenderTotal
+		wantTax      TaxSummary
+		wantCash     CashSummary
+		wantMoves    []MovementLine
+	}{
+		{
+			name:         "open shift without sales",
+			openingFloat: 100,
+			wantTenders:  []TenderTotal{},
+			wantCash:     CashSummary{OpeningFloat: 100, Expected: 100},
+			wantMoves:    []MovementLine{},
+		},
+		{
+			name:         "closed shift without sales",
+			openingFloat: 100,
+			counted:      amount(100),
+			wantTenders:  []TenderTotal{},
+			wantCash:     CashSummary{OpeningFloat: 100, Expected: 100, Counted: amount(100), OverShort: amount(0)},
+			wantMoves:    []MovementLine{},
+		},
+		{
+			name:         "multiple tenders",
+			openingFloat: 50,
+			sales: []*Sale{
+				sale(TenderCash, 10.5, 0.5, SaleCompleted),
+				sale(TenderCard, 20, 1, SaleCompleted),
+				sale(TenderCard, 5.25, 0.25, SaleCompleted),
+				sale(TenderDigitalWallet, 7, 0, SaleCompleted),
+				sale(TenderCard, 3, 0.15, SaleVoided),
+				sale(TenderCash, 4, 0.2, SaleRefunded),
+				{OrderID: uuid.New(), Tender: TenderCash, Amount: 99, Currency: "EUR", Status: SaleCompleted},
+			},
+			wantSales: SalesSummary{
+				Orders: 6, Gross: 49.75, Voids: 1, VoidAmount: 3, Refunds: 1, RefundAmount: 4,
+				Net: 42.75, AverageTicket: 10.69,
+			},
+			wantTenders: []TenderTotal{
+				{Tender: TenderCard, Orders: 2, Amount: 25.25},
+				{Tender: TenderCash, Orders: 1, Amount: 10.5},
+				{Tender: TenderDigitalWallet, Orders: 1, Amount: 7},
+			},
+			wantTax:   TaxSummary{Taxable: 41, Amount: 1.75},
+			wantCash:  CashSummary{OpeningFloat: 50, Sales: 10.5, Expected: 60.5},
+			wantMoves: []MovementLine{},
+		},
+		{
+			name:         "cash over",
+			openingFloat: 100,
+			counted:      amount(55),
+			sales:        []*Sale{sale(TenderCash, 20, 0, SaleCompleted)},
+			movements: []*CashMovement{
+				movement(MovementPayIn, 10), movement(MovementPayOut, 5.5),
+				movement(MovementDrop, 50), movement(MovementDrop, 20),
+			},
+			wantSales:   SalesSummary{Orders: 1, Gross: 20, Net: 20, AverageTicket: 20},
+			wantTenders: []TenderTotal{{Tender: TenderCash, Orders: 1, Amount: 20}},
+			wantTax:     TaxSummary{Taxable: 20},
+			wantCash: CashSummary{
+				OpeningFloat: 100, Sales: 20, PayIns: 10, PayOuts: 5.5, Drops: 70,
+				Expected: 54.5, Counted: amount(55), OverShort: amount(0.5),
+			},
+			wantMoves: []MovementLine{
+				{Type: MovementDrop, Count: 2, Amount: 70},
+				{Type: MovementPayIn, Count: 1, Amount: 10},
+				{Type: MovementPayOut, Count: 1, Amount: 5.5},
+			},
+		},
+		{
+			name:         "cash short",
+			openingFloat: 100,
+			counted:      amount(54),
+			sales:        []*Sale{sale(TenderCash, 20, 0, SaleCompleted), sale(TenderCard, 15, 0, SaleCompleted)},
+			movements:    []*CashMovement{movement(MovementDrop, 65.5)},
+			wantSales:    SalesSummary{Orders: 2, Gross: 35, Net: 35, AverageTicket: 17.5},
+			wantTenders: []TenderTotal{
+				{Tender: TenderCard, Orders: 1, Amount: 15},
+				{Tender: TenderCash, Orders: 1, Amount: 20},
+			},
+			wantTax: TaxSummary{Taxable: 35},
+			wantCash: CashSummary{
+				OpeningFloat: 100, Sales: 20, Drops: 65.5,
+				Expected: 54.5, Counted: amount(54), OverShort: amount(-0.5),
+			},
+			wantMoves: []MovementLine{{Type: MovementDrop, Count: 1, Amount: 65.5}},
+		},
+		{
+			// 0.1 + 0.2 is not 0.3 in floating point, but the drawer is even
+			name:        "cash counted to the cent",
+			counted:     amount(0.3),
+			sales:       []*Sale{sale(TenderCash, 0.1, 0, SaleCompleted), sale(TenderCash, 0.2, 0, SaleCompleted)},
+			wantSales:   SalesSummary{Orders: 2, Gross: 0.3, Net: 0.3, AverageTicket: 0.15},
+			wantTenders: []TenderTotal{{Tender: TenderCash, Orders: 2, Amount: 0.3}},
+			wantTax:     TaxSummary{Taxable: 0.3},
+			wantCash:    CashSummary{Sales: 0.3, Expected: 0.3, Counted: amount(0.3), OverShort: amount(0)},
+			wantMoves:   []MovementLine{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Shift{
+				ID:           uuid.New(),
+				StaffID:      staffA,
+				Currency:     "USD",
+				OpeningFloat: tt.openingFloat,
+				CountedCash:  tt.counted,
+				OpenedAt:     time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+			}
+			kind := ReportX
+			if tt.counted != nil {
+				kind = ReportZ
+			}
+
+			r := BuildReport(kind, s, tt.sales, tt.movements, time.Now())
+			assert.Equal(t, kind, r.Kind)
+			assert.Equal(t, s.ID, r.ShiftID)
+			assert.Equal(t, tt.wantSales, r.Sales)
+			assert.Equal(t, tt.wantTenders, r.Tenders)
+			assert.Equal(t, tt.wantTax, r.Tax)
+			assert.Equal(t, tt.wantCash, r.Cash)
+			assert.Equal(t, tt.wantMoves, r.Movements)
+		})
+	}
+}
+
+func TestBuildReportOfShiftCrossingMidnight(t *testing.T) {
+	openedAt := time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)
+	closedAt := time.Date(2026, 3, 2, 2, 0, 0, 0, time.UTC)
+	s := &Shift{
+		ID: uuid.New(), Currency: "USD", OpeningFloat: 20, CountedCash: amount(45),
+		OpenedAt: openedAt, ClosedAt: &closedAt,
+	}
+	before := sale(TenderCash, 10, 0, SaleCompleted)
+	before.RecordedAt = time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC)
+	after := sale(TenderCash, 15, 0, SaleCompleted)
+	after.RecordedAt = time.Date(2026, 3, 2, 0, 30, 0, 0, time.UTC)
+
+	// Sales on either side of midnight belong to the one shift
+	r := BuildReport(ReportZ, s, []*Sale{before, after}, nil, closedAt)
+	assert.Equal(t, openedAt, r.OpenedAt)
+	assert.Equal(t, &closedAt, r.ClosedAt)
+	assert.Equal(t, 2, r.Sales.Orders)
+	assert.Equal(t, 45.0, r.Cash.Expected)
+	assert.Equal(t, amount(0), r.Cash.OverShort)
+}
+
+// zReport is the Z report of a closed shift, with counted nil for a drawer
+// never counted
+func zReport(staffID uuid.UUID, currency string, net, expected float64, counted *float64, closedAt time.Time) *Report {
+	r := &Report{
+		Kind:     ReportZ,
+		ShiftID:  uuid.New(),
+		StaffID:  staffID,
+		Currency: currency,
+		OpenedAt: closedAt.Add(-8 * time.Hour),
+		ClosedAt: &closedAt,
+		Sales:    SalesSummary{Net: net},
+		Cash:     CashSummary{Expected: expected, Counted: counted},
+	}
+	if counted != nil {
+		r.Cash.OverShort = amount(round(*counted - expected))
+	}
+	return r
+}
+
+func TestBuildDayReport(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time { return day.Add(time.Duration(hour) * time.Hour) }
+
+	tests := []struct {
+		name         string
+		reports      []*Report
+		wantTotals   []DayTotal
+		wantCashiers []CashierTotal
+	}{
+		{
+			name:         "no shifts",
+			wantTotals:   []DayTotal{},
+			wantCashiers: []CashierTotal{},
+		},
+		{
+			name: "cashiers and currencies",
+			reports: []*Report{
+				zReport(staffB, "EUR", 20, 70, amount(69.5), at(18)),
+				zReport(staffA, "USD", 100, 150, amount(151), at(12)),
+				zReport(staffB, "USD", 30, 30, amount(30), at(17)),
+				zReport(staffA, "USD", 50, 80, amount(79), at(20)),
+			},
+			wantTotals: []DayTotal{
+				{Currency: "EUR", Shifts: 1, NetSales: 20, Expected: 70, Counted: 69.5, OverShort: -0.5},
+				{Currency: "USD", Shifts: 3, NetSales: 180, Expected: 260, Counted: 260},
+			},
+			wantCashiers: []CashierTotal{
+				{StaffID: staffA, Currency: "USD", Shifts: 2, Expected: 230, Counted: 230},
+				{StaffID: staffB, Currency: "EUR", Shifts: 1, Expected: 70, Counted: 69.5, OverShort: -0.5},
+				{StaffID: staffB, Currency: "USD", Shifts: 1, Expected: 30, Counted: 30},
+			},
+		},
+		{
+			name: "cents add up without drift",
+			reports: []*Report{
+				zReport(staffA, "USD", 0.1, 0.1, amount(0.2), at(10)),
+				zReport(staffA, "USD", 0.2, 0.2, amount(0.2), at(11)),
+			},
+			wantTotals: []DayTotal{
+				{Currency: "USD", Shifts: 2, NetSales: 0.3, Expected: 0.3, Counted: 0.4, OverShort: 0.1},
+			},
+			wantCashiers: []CashierTotal{
+				{StaffID: staffA, Currency: "USD", Shifts: 2, Expected: 0.3, Counted: 0.4, OverShort: 0.1},
+			},
+		},
+		{
+			name: "drawer never counted",
+			reports: []*Report{
+				zReport(staffA, "USD", 40, 60, nil, at(9)),
+			},
+			wantTotals: []DayTotal{
+				{Currency: "USD", Shifts: 1, NetSales: 40, Expected: 60},
+			},
+			wantCashiers: []CashierTotal{
+				{StaffID: staffA, Currency: "USD", Shifts: 1, Expected: 60},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := BuildDayReport(day, nil, tt.reports)
+			assert.Equal(t, "2026-03-02", d.Date)
+			assert.Equal(t, tt.wantTotals, d.Totals)
+			assert.Equal(t, tt.wantCashiers, d.Cashiers)
+			require.Len(t, d.Reports, len(tt.reports))
+			for i := 1; i < len(d.Reports); i++ {
+				assert.False(t, d.Reports[i].ClosedAt.Before(*d.Reports[i-1].ClosedAt), "reports are ordered by close")
+			}
+		})
+	}
+}
+
+func TestBuildDayReportOfShiftCrossingMidnight(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	storeID := uuid.New()
+
+	// The night shift opened the day before and closed at 1am counts on the
+	// day it closed, first of the day's shifts
+	night := zReport(staffA, "USD", 80, 100, amount(100), day.Add(time.Hour))
+	night.OpenedAt = day.Add(-3 * time.Hour)
+	evening := zReport(staffB, "USD", 50, 70, amount(70), day.Add(23*time.Hour))
+
+	d := BuildDayReport(day, &storeID, []*Report{evening, night})
+	assert.Equal(t, "2026-03-02", d.Date)
+	assert.Equal(t, &storeID, d.StoreID)
+	assert.Equal(t, []*Report{night, evening}, d.Reports)
+	assert.Equal(t, []DayTotal{{Currency: "USD", Shifts: 2, NetSales: 130, Expected: 170, Counted: 170}}, d.Totals)
+}
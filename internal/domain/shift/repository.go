@@ -0,0 +1,60 @@
+package shift
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository defines the shift repository interface
+type Repository interface {
+	// Open starts a shift, failing with ErrShiftOpen when its register
+	// already has one open
+	Open(ctx context.Context, s *Shift) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Shift, error)
+	// List returns the shifts matching filter, newest first
+	List(ctx context.Context, filter Filter, limit, offset int) ([]*Shift, error)
+	// Close closes an open shift with the cash counted in its drawer,
+	// failing with ErrShiftClosed when it is already closed
+	Close(ctx context.Context, id, closedBy uuid.UUID, countedCash float64, notes string, now time.Time) (*Shift, error)
+
+	// SaveReport stores the Z report of a closed shift; a stored report is
+	// never replaced
+	SaveReport(ctx context.Context, id uuid.UUID, report *Report) error
+	// GetReport returns a shift's stored Z report, or nil when none is
+	GetReport(ctx context.Context, id uuid.UUID) (*Report, error)
+
+	// AddMovement records a cash movement on an open shift
+	AddMovement(ctx context.Context, m *CashMovement) error
+	GetMovements(ctx context.Context, shiftID uuid.UUID) ([]*CashMovement, error)
+
+	// RecordSale records a sale or what became of it. A voided or refunded
+	// sale is never reverted by a late event.
+	RecordSale(ctx context.Context, sale *Sale) error
+	GetSales(ctx context.Context, shiftID uuid.UUID) ([]*Sale, error)
+}
+
+// TimeClockRepository defines the time clock repository interface. Changes
+// are saved against the version read, so concurrent ones cannot overwrite
+// each other.
+type TimeClockRepository interface {
+	// ClockIn starts a time entry, failing with ErrClockedIn when the staff
+	// member already has one open
+	ClockIn(ctx context.Context, e *TimeEntry) error
+	GetEntry(ctx context.Context, id uuid.UUID) (*TimeEntry, error)
+	// GetOpenEntry returns a staff member's open entry, or fails with
+	// ErrNotClockedIn
+	GetOpenEntry(ctx context.Context, staffID uuid.UUID) (*TimeEntry, error)
+	// ListEntries returns the entries matching filter, latest clock-in first
+	ListEntries(ctx context.Context, filter EntryFilter, limit, offset int) ([]*TimeEntry, error)
+	// Update saves an entry changed from its version as read and moves it to
+	// the next version, failing with ErrEntryChanged when another change
+	// was saved first
+	Update(ctx context.Context, e *TimeEntry) error
+	// Adjust saves a manager's change to an entry as Update does, together
+	// with its audit record
+	Adjust(ctx context.Context, e *TimeEntry, adj *TimeAdjustment) error
+	// GetAdjustments returns an entry's adjustments, oldest first
+	GetAdjustments(ctx context.Context, entryID uuid.UUID) ([]*TimeAdjustment, error)
+}
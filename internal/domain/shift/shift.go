@@ -0,0 +1,114 @@
+package shift
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrShiftNotFound = errors.New("shift not found")
+	ErrShiftClosed   = errors.New("shift is closed")
+	ErrShiftOpen     = errors.New("register already has an open shift")
+)
+
+// Status is whether a shift is still taking sales
+type Status string
+
+const (
+	StatusOpen   Status = "open"
+	StatusClosed Status = "closed"
+)
+
+// Tender is how a sale was paid at the register
+type Tender string
+
+const (
+	TenderCash          Tender = "cash"
+	TenderCard          Tender = "card"
+	TenderDigitalWallet Tender = "digital_wallet"
+	TenderBankTransfer  Tender = "bank_transfer"
+)
+
+// Shift is a staff member's session on a store's register, from opening the
+// cash drawer with a float to counting it at close. A register has at most
+// one open shift.
+type Shift struct {
+	ID           uuid.UUID  `json:"id"`
+	StoreID      uuid.UUID  `json:"store_id"`
+	RegisterID   string     `json:"register_id"`
+	StaffID      uuid.UUID  `json:"staff_id"`
+	Status       Status     `json:"status"`
+	Currency     string     `json:"currency"`
+	OpeningFloat float64    `json:"opening_float"`          // Cash in the drawer at open
+	CountedCash  *float64   `json:"counted_cash,omitempty"` // Cash in the drawer at close
+	Notes        string     `json:"notes,omitempty"`
+	OpenedAt     time.Time  `json:"opened_at"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+	ClosedBy     *uuid.UUID `json:"closed_by,omitempty"`
+}
+
+// IsOpen reports whether the shift still takes sales and cash movements
+func (s *Shift) IsOpen() bool {
+	return s.Status == StatusOpen
+}
+
+// Filter selects shifts; unset fields match every shift
+type Filter struct {
+	StoreID    *uuid.UUID
+	RegisterID string
+	StaffID    *uuid.UUID
+	Status     Status
+	ClosedFrom *time.Time // Closed at or after
+	ClosedTo   *time.Time // Closed before
+}
+
+// MovementType is why cash entered or left the drawer outside a sale
+type MovementType string
+
+const (
+	MovementPayIn  MovementType = "pay_in"  // Cash added, such as change from the bank
+	MovementPayOut MovementType = "pay_out" // Cash paid out, such as for supplies
+	MovementDrop   MovementType = "drop"    // Cash moved to the safe
+)
+
+// CashMovement is cash put in or taken out of a shift's drawer
+type CashMovement struct {
+	ID        uuid.UUID    `json:"id"`
+	ShiftID   uuid.UUID    `json:"shift_id"`
+	Type      MovementType `json:"type"`
+	Amount    float64      `json:"amount"` // Always positive; the type gives the direction
+	Reason    string       `json:"reason,omitempty"`
+	StaffID   uuid.UUID    `json:"staff_id"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Effect returns the change the movement makes to the cash in the drawer
+func (m *CashMovement) Effect() float64 {
+	if m.Type == MovementPayIn {
+		return m.Amount
+	}
+	return -m.Amount
+}
+
+// SaleStatus is what became of a sale rung up on a shift
+type SaleStatus string
+
+const (
+	SaleCompleted SaleStatus = "completed"
+	SaleVoided    SaleStatus = "voided"   // The order was cancelled
+	SaleRefunded  SaleStatus = "refunded" // The order was refunded
+)
+
+// Sale is an order rung up on a shift, recorded from the order's events
+type Sale struct {
+	OrderID    uuid.UUID  `json:"order_id"`
+	ShiftID    uuid.UUID  `json:"shift_id"`
+	Tender     Tender     `json:"tender"`
+	Amount     float64    `json:"amount"`
+	Tax        float64    `json:"tax"` // Tax charged on the order, part of its amount
+	Currency   string     `json:"currency"`
+	Status     SaleStatus `json:"status"`
+	RecordedAt time.Time  `json:"recorded_at"`
+}
@@ -0,0 +1,180 @@
+package shift
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTimeEntryNotFound = errors.New("time entry not found")
+	ErrClockedIn         = errors.New("staff member is already clocked in")
+	ErrNotClockedIn      = errors.New("staff member is not clocked in")
+	ErrOnBreak           = errors.New("staff member is already on a break")
+	ErrNotOnBreak        = errors.New("staff member is not on a break")
+	ErrEntryChanged      = errors.New("time entry was changed by another request")
+	ErrInvalidTimes      = errors.New("breaks must fall between clock-in and clock-out without overlapping")
+)
+
+// EntryStatus is whether a staff member is still on the clock
+type EntryStatus string
+
+const (
+	EntryOpen   EntryStatus = "open"
+	EntryClosed EntryStatus = "closed"
+)
+
+// TimeEntry is a staff member's time on the clock at a store, from clock-in
+// to clock-out, optionally worked on a register shift. A staff member has at
+// most one open entry. Version goes up with every change, so readers of its
+// events keep the latest.
+type TimeEntry struct {
+	ID        uuid.UUID   `json:"id"`
+	StoreID   uuid.UUID   `json:"store_id"`
+	StaffID   uuid.UUID   `json:"staff_id"`
+	ShiftID   *uuid.UUID  `json:"shift_id,omitempty"`
+	Status    EntryStatus `json:"status"`
+	ClockIn   time.Time   `json:"clock_in"`
+	ClockOut  *time.Time  `json:"clock_out,omitempty"`
+	Breaks    []Break     `json:"breaks"`
+	Notes     string      `json:"notes,omitempty"`
+	Version   int         `json:"version"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// Break is a break taken during a time entry. Unpaid breaks do not count as
+// time worked.
+type Break struct {
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Paid      bool       `json:"paid"`
+}
+
+// IsOpen reports whether the staff member is still on the clock
+func (e *TimeEntry) IsOpen() bool {
+	return e.Status == EntryOpen
+}
+
+// OnBreak reports whether the entry has a break still running
+func (e *TimeEntry) OnBreak() bool {
+	return len(e.Breaks) > 0 && e.Breaks[len(e.Breaks)-1].EndedAt == nil
+}
+
+// StartBreak starts a break at now
+func (e *TimeEntry) StartBreak(paid bool, now time.Time) error {
+	if !e.IsOpen() {
+		return ErrNotClockedIn
+	}
+	if e.OnBreak() {
+		return ErrOnBreak
+	}
+	e.Breaks = append(e.Breaks, Break{StartedAt: now, Paid: paid})
+	return nil
+}
+
+// EndBreak ends the running break at now
+func (e *TimeEntry) EndBreak(now time.Time) error {
+	if !e.IsOpen() {
+		return ErrNotClockedIn
+	}
+	if !e.OnBreak() {
+		return ErrNotOnBreak
+	}
+	e.Breaks[len(e.Breaks)-1].EndedAt = &now
+	return nil
+}
+
+// Close clocks the staff member out at now, ending a running break with it
+func (e *TimeEntry) Close(now time.Time) error {
+	if !e.IsOpen() {
+		return ErrNotClockedIn
+	}
+	if e.OnBreak() {
+		e.Breaks[len(e.Breaks)-1].EndedAt = &now
+	}
+	e.Status = EntryClosed
+	e.ClockOut = &now
+	return nil
+}
+
+// Validate checks that the breaks are in order within the entry and, but
+// for the last break of an open entry, ended
+func (e *TimeEntry) Validate() error {
+	if e.ClockOut != nil && e.ClockOut.Before(e.ClockIn) {
+		return ErrInvalidTimes
+	}
+	sort.SliceStable(e.Breaks, func(i, j int) bool {
+		return e.Breaks[i].StartedAt.Before(e.Breaks[j].StartedAt)
+	})
+
+	previousEnd := e.ClockIn
+	for i, b := range e.Breaks {
+		if b.StartedAt.Before(previousEnd) {
+			return ErrInvalidTimes
+		}
+		if b.EndedAt == nil {
+			if e.ClockOut != nil || i != len(e.Breaks)-1 {
+				return ErrInvalidTimes
+			}
+			continue
+		}
+		if b.EndedAt.Before(b.StartedAt) || (e.ClockOut != nil && b.EndedAt.After(*e.ClockOut)) {
+			return ErrInvalidTimes
+		}
+		previousEnd = *b.EndedAt
+	}
+	return nil
+}
+
+// Durations returns the time worked, which includes paid breaks, and the
+// time on unpaid breaks, counting an open entry and a running break up to
+// now
+func (e *TimeEntry) Durations(now time.Time) (worked, unpaidBreaks time.Duration) {
+	end := now
+	if e.ClockOut != nil {
+		end = *e.ClockOut
+	}
+	for _, b := range e.Breaks {
+		if b.Paid {
+			continue
+		}
+		breakEnd := end
+		if b.EndedAt != nil && b.EndedAt.Before(end) {
+			breakEnd = *b.EndedAt
+		}
+		if breakEnd.After(b.StartedAt) {
+			unpaidBreaks += breakEnd.Sub(b.StartedAt)
+		}
+	}
+	if end.After(e.ClockIn) {
+		worked = end.Sub(e.ClockIn) - unpaidBreaks
+	}
+	if worked < 0 {
+		worked = 0
+	}
+	return worked, unpaidBreaks
+}
+
+// EntryFilter selects time entries by when they were clocked in, From
+// inclusive and To exclusive; unset fields match every entry
+type EntryFilter struct {
+	StoreID *uuid.UUID
+	StaffID *uuid.UUID
+	Status  EntryStatus
+	From    *time.Time
+	To      *time.Time
+}
+
+// TimeAdjustment is a manager's change to a time entry, kept for audit with
+// the entry as it was before and after
+type TimeAdjustment struct {
+	ID         uuid.UUID  `json:"id"`
+	EntryID    uuid.UUID  `json:"entry_id"`
+	AdjustedBy uuid.UUID  `json:"adjusted_by"`
+	Reason     string     `json:"reason"`
+	Before     *TimeEntry `json:"before"`
+	After      *TimeEntry `json:"after"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
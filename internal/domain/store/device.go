@@ -0,0 +1,60 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Errors of device pairing and lookup
+var (
+	ErrDeviceNotFound     = errors.New("device not found")
+	ErrInvalidPairingCode = errors.New("invalid or expired pairing code")
+)
+
+// DeviceType is the kind of in-store hardware
+type DeviceType string
+
+const (
+	DeviceTerminal DeviceType = "terminal"
+	DevicePrinter  DeviceType = "printer"
+	DeviceScanner  DeviceType = "scanner"
+)
+
+// DeviceStatus is where a device is in its lifecycle
+type DeviceStatus string
+
+const (
+	DevicePending     DeviceStatus = "pending"     // Registered, waiting to be paired with its code
+	DeviceActive      DeviceStatus = "active"      // Paired; authenticates with its API key
+	DeviceDeactivated DeviceStatus = "deactivated" // Locked out, e.g. stolen or retired
+)
+
+// Device is a terminal, printer or scanner of a store. It is registered by
+// an admin with a one-time pairing code, which the device exchanges for an
+// API key it authenticates with from then on. Only hashes of the code and
+// the key are stored.
+type Device struct {
+	ID                 uuid.UUID    `json:"id"`
+	StoreID            uuid.UUID    `json:"store_id"`
+	Name               string       `json:"name"`
+	Type               DeviceType   `json:"type"`
+	Status             DeviceStatus `json:"status"`
+	SerialNumber       string       `json:"serial_number,omitempty"`
+	PairingExpiresAt   *time.Time   `json:"pairing_expires_at,omitempty"` // While pending
+	KeyPrefix          string       `json:"key_prefix,omitempty"`         // Start of the API key, to tell keys apart
+	AppVersion         string       `json:"app_version,omitempty"`
+	LastIP             string       `json:"last_ip,omitempty"`
+	LastSeenAt         *time.Time   `json:"last_seen_at,omitempty"` // Last heartbeat
+	PairedAt           *time.Time   `json:"paired_at,omitempty"`
+	DeactivatedAt      *time.Time   `json:"deactivated_at,omitempty"`
+	DeactivationReason string       `json:"deactivation_reason,omitempty"`
+	CreatedAt          time.Time    `json:"created_at"`
+	UpdatedAt          time.Time    `json:"updated_at"`
+}
+
+// Online reports whether an active device sent a heartbeat within offlineAfter
+func (d *Device) Online(now time.Time, offlineAfter time.Duration) bool {
+	return d.Status == DeviceActive && d.LastSeenAt != nil && now.Sub(*d.LastSeenAt) < offlineAfter
+}
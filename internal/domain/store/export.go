@@ -0,0 +1,41 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrExportNotFound is returned when an export does not exist, or was
+// claimed by another instance since it was read
+var ErrExportNotFound = errors.New("export not found")
+
+// ExportStatus is where an export is in being built
+type ExportStatus string
+
+const (
+	ExportPending   ExportStatus = "pending"   // Requested, waiting to be built
+	ExportRunning   ExportStatus = "running"   // Being built by an instance
+	ExportCompleted ExportStatus = "completed" // Built; downloadable until it expires
+	ExportFailed    ExportStatus = "failed"
+)
+
+// Export is a zipped copy of a store's data: the store itself with its
+// catalog at the store's prices, its stock levels, its orders and the
+// customers who placed them. It is built in the background after it is
+// requested, and deleted once it expires.
+type Export struct {
+	ID          uuid.UUID      `json:"id"`
+	TenantID    string         `json:"-"`
+	StoreID     uuid.UUID      `json:"store_id"`
+	Status      ExportStatus   `json:"status"`
+	Records     map[string]int `json:"records,omitempty"`  // Records per dataset, once completed
+	Size        int64          `json:"size,omitempty"`     // Of the archive, in bytes
+	Checksum    string         `json:"checksum,omitempty"` // Hex SHA-256 of the archive
+	Error       string         `json:"error,omitempty"`    // Why it failed
+	CreatedAt   time.Time      `json:"created_at"`
+	StartedAt   *time.Time     `json:"started_at,omitempty"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time     `json:"expires_at,omitempty"`
+}
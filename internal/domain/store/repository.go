@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -16,3 +17,37 @@ type Repository interface {
 	Delete(ctx context.Context, id uuid.UUID) error
 	SearchByLocation(ctx context.Context, lat, lng float64, radiusKm float64) ([]*Store, error)
 }
+
+// DeviceRepository defines the device repository interface. Pairing codes
+// and API keys are passed and looked up by their hashes.
+type DeviceRepository interface {
+	Create(ctx context.Context, device *Device, codeHash string, codeTTL time.Duration) error
+	GetByID(ctx context.Context, storeID, id uuid.UUID) (*Device, error)
+	GetByKey(ctx context.Context, keyHash string) (*Device, error)
+	ListByStore(ctx context.Context, storeID uuid.UUID, status DeviceStatus) ([]*Device, error)
+	// Pair activates the pending device with the unexpired pairing code, or
+	// fails with ErrInvalidPairingCode
+	Pair(ctx context.Context, codeHash, keyHash, keyPrefix, serialNumber, appVersion string) (*Device, error)
+	// ResetPairing revokes a device's API key and returns it to pending with a new code
+	ResetPairing(ctx context.Context, storeID, id uuid.UUID, codeHash string, codeTTL time.Duration) (*Device, error)
+	Deactivate(ctx context.Context, storeID, id uuid.UUID, reason string) (*Device, error)
+	Heartbeat(ctx context.Context, id uuid.UUID, appVersion, ip string) error
+}
+
+// ExportRepository defines the export repository interface. Claim and
+// DeleteExpired work across tenants, for the background builder.
+type ExportRepository interface {
+	Create(ctx context.Context, export *Export) error
+	GetByID(ctx context.Context, storeID, id uuid.UUID) (*Export, error)
+	ListByStore(ctx context.Context, storeID uuid.UUID, limit int) ([]*Export, error)
+	// Claim marks the oldest pending export, or one running for longer than
+	// timeout, running; nil when there is none
+	Claim(ctx context.Context, timeout time.Duration) (*Export, error)
+	// Complete and Fail finish an export as claimed, or fail with
+	// ErrExportNotFound when it was claimed again since; either way it
+	// expires after ttl
+	Complete(ctx context.Context, export *Export, ttl time.Duration) error
+	Fail(ctx context.Context, export *Export, reason string, ttl time.Duration) error
+	// DeleteExpired deletes and returns the exports past their expiry
+	DeleteExpired(ctx context.Context) ([]*Export, error)
+}
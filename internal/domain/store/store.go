@@ -1,11 +1,15 @@
 package store
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// ErrNotFound is returned when a store does not exist
+var ErrNotFound = errors.New("store not found")
+
 // StoreStatus represents store status
 type StoreStatus string
 
@@ -29,6 +33,7 @@ type Store struct {
 	Country    string      `json:"country"`
 	Phone      string      `json:"phone,omitempty"`
 	Email      string      `json:"email,omitempty"`
+	ManagerID  *uuid.UUID  `json:"manager_id,omitempty"` // User told of the store's automatic reorders
 	Status     StoreStatus `json:"status"`
 	CreatedAt  time.Time   `json:"created_at"`
 	UpdatedAt  time.Time   `json:"updated_at"`
@@ -0,0 +1,147 @@
+package tax
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Line is an order line to quote tax on
+type Line struct {
+	ProductID  string     `json:"product_id"` // Catalog variant ID, as on orders
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	Quantity   int        `json:"quantity"`
+	Amount     float64    `json:"amount"` // After discounts, with any tax the store's prices include
+}
+
+// UnitPrice is what one item of l sells for after discounts
+func (l Line) UnitPrice() float64 {
+	if l.Quantity <= 0 {
+		return l.Amount
+	}
+	return l.Amount / float64(l.Quantity)
+}
+
+// QuoteRequest asks for the tax due on a sale in a store. Sales shipped to
+// an address are taxed there; the rest at the store.
+type QuoteRequest struct {
+	StoreID  uuid.UUID `json:"store_id"`
+	Address  *Location `json:"address,omitempty"`
+	Currency string    `json:"currency"`
+	Lines    []Line    `json:"lines"`
+}
+
+// LineTax is the tax on one line of a quote
+type LineTax struct {
+	Category string  `json:"category"`
+	Rate     float64 `json:"rate"` // Sum of the rates levied on the line
+	Tax      float64 `json:"tax"`
+	Included float64 `json:"included,omitempty"` // Part of the tax already in the line's amount
+}
+
+// JurisdictionTax is what one jurisdiction levies on a sale
+type JurisdictionTax struct {
+	JurisdictionID uuid.UUID `json:"jurisdiction_id"`
+	Name           string    `json:"name"`
+	Level          Level     `json:"level"`
+	Taxable        float64   `json:"taxable"` // Sales the jurisdiction taxed
+	Exempt         float64   `json:"exempt"`  // Sales it exempted, by category or holiday
+	Tax            float64   `json:"tax"`
+	Inclusive      bool      `json:"inclusive,omitempty"` // The tax is included in the store's prices
+}
+
+// Quote is the tax due on a sale
+type Quote struct {
+	Location      Location          `json:"location"`
+	Date          time.Time         `json:"date"`
+	Currency      string            `json:"currency"`
+	Lines         []LineTax         `json:"lines"` // Aligned with the request's lines
+	Jurisdictions []JurisdictionTax `json:"jurisdictions"`
+	TotalTax      float64           `json:"total_tax"`
+	IncludedTax   float64           `json:"included_tax"` // Part of the total tax already in the line amounts
+}
+
+// Calculate quotes the tax jurisdictions levy at loc on lines sold on day.
+// categories holds each line's tax category, holidays those of the
+// jurisdictions, and rates how the selling store levies some of them. Taxes
+// a store's prices include are taken out of the line amounts first, leaving
+// the net amount every jurisdiction taxes. Tax is rounded to cents per line
+// and jurisdiction.
+func Calculate(loc Location, currency string, lines []Line, categories []*Category, jurisdictions []*Jurisdiction, rates []*StoreRate, holidays []*Holiday, day time.Time) *Quote {
+	q := &Quote{
+		Location:      loc,
+		Date:          truncateDay(day),
+		Currency:      currency,
+		Lines:         make([]LineTax, len(lines)),
+		Jurisdictions: make([]JurisdictionTax, len(jurisdictions)),
+	}
+
+	byJurisdiction := make(map[uuid.UUID][]*Holiday)
+	for _, h := range holidays {
+		byJurisdiction[h.JurisdictionID] = append(byJurisdiction[h.JurisdictionID], h)
+	}
+	storeRates := make(map[uuid.UUID]*StoreRate)
+	for _, r := range rates {
+		storeRates[r.JurisdictionID] = r
+	}
+	for i, j := range jurisdictions {
+		q.Jurisdictions[i] = JurisdictionTax{JurisdictionID: j.ID, Name: j.Name, Level: j.Level()}
+		if r, ok := storeRates[j.ID]; ok {
+			q.Jurisdictions[i].Inclusive = r.Inclusive
+		}
+	}
+
+	levied := make([]float64, len(jurisdictions))
+	for i, l := range lines {
+		category := categories[i]
+		q.Lines[i].Category = category.Code
+
+		// The rate of each jurisdiction, and the net amount they all tax
+		included := 0.0
+		for k, j := range jurisdictions {
+			levied[k] = 0
+			if category.Exempt {
+				continue
+			}
+			rate := j.RateFor(category.Code)
+			if r, ok := storeRates[j.ID]; ok {
+				rate = r.RateFor(j, category.Code)
+			}
+			for _, h := range byJurisdiction[j.ID] {
+				if h.Covers(day, category.Code, l.UnitPrice()) && h.Rate < rate {
+					rate = h.Rate
+				}
+			}
+			levied[k] = rate
+			if q.Jurisdictions[k].Inclusive {
+				included += rate
+			}
+		}
+		net := l.Amount / (1 + included)
+
+		for k, rate := range levied {
+			jt := &q.Jurisdictions[k]
+			if rate <= 0 {
+				jt.Exempt = roundCents(jt.Exempt + net)
+				continue
+			}
+			tax := roundCents(net * rate)
+			jt.Taxable = roundCents(jt.Taxable + net)
+			jt.Tax = roundCents(jt.Tax + tax)
+			q.Lines[i].Rate = math.Round((q.Lines[i].Rate+rate)*1e6) / 1e6
+			q.Lines[i].Tax = roundCents(q.Lines[i].Tax + tax)
+			if jt.Inclusive {
+				q.Lines[i].Included = roundCents(q.Lines[i].Included + tax)
+			}
+		}
+		q.TotalTax = roundCents(q.TotalTax + q.Lines[i].Tax)
+		q.IncludedTax = roundCents(q.IncludedTax + q.Lines[i].Included)
+	}
+	return q
+}
+
+// roundCents rounds an amount to cents
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,264 @@
+package tax
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	country = &Jurisdiction{ID: uuid.New(), Country: "US", Name: "Country", Rate: 0.05}
+	state   = &Jurisdiction{ID: uuid.New(), Country: "US", State: "NY", Name: "State", Rate: 0.04,
+		CategoryRates: map[string]float64{"clothing": 0}}
+	city = &Jurisdiction{ID: uuid.New(), Country: "US", State: "NY", City: "New York", Name: "City", Rate: 0.0125}
+
+	testCategories = map[string]*Category{
+		StandardCategory: {Code: StandardCategory},
+		"clothing":       {Code: "clothing"},
+		"groceries":      {Code: "groceries", Exempt: true},
+		"supplies":       {Code: "supplies"},
+	}
+
+	// schoolWeek takes the state's tax off school supplies up to 100 each,
+	// from July 1 to 3
+	schoolWeek = &Holiday{
+		JurisdictionID: state.ID,
+		Name:           "School week",
+		Category:       "supplies",
+		StartsOn:       day(7, 1),
+		EndsOn:         day(7, 3),
+		MaxUnitPrice:   100,
+	}
+)
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+// sold is a line of quantity items of category, for amount
+type sold struct {
+	category string
+	quantity int
+	amount   float64
+}
+
+func TestCalculate(t *testing.T) {
+	stateRate := 0.03
+
+	tests := []struct {
+		name          string
+		jurisdictions []*Jurisdiction
+		rates         []*StoreRate
+		holidays      []*Holiday
+		day           time.Time
+		lines         []sold
+		lineTax       []float64
+		lineRate      []float64
+		jurisdiction  []float64 // Tax of each jurisdiction
+		exempt        []float64 // Sales each jurisdiction exempted
+		included      float64
+	}{
+		{
+			name:          "rates of every jurisdiction stack",
+			jurisdictions: []*Jurisdiction{country, state, city},
+			lines:         []sold{{StandardCategory, 1, 100}},
+			lineTax:       []float64{10.25},
+			lineRate:      []float64{0.1025},
+			jurisdiction:  []float64{5, 4, 1.25},
+			exempt:        []float64{0, 0, 0},
+		},
+		{
+			name:          "a category rate replaces the standard one",
+			jurisdictions: []*Jurisdiction{country, state, city},
+			lines:         []sold{{"clothing", 2, 100}, {StandardCategory, 1, 10}},
+			lineTax:       []float64{6.25, 1.03},
+			lineRate:      []float64{0.0625, 0.1025},
+			jurisdiction:  []float64{5.5, 0.4, 1.38},
+			exempt:        []float64{0, 100, 0},
+		},
+		{
+			name:          "exempt categories owe no tax anywhere",
+			jurisdictions: []*Jurisdiction{country, state, city},
+			lines:         []sold{{"groceries", 3, 50}, {StandardCategory, 1, 100}},
+			lineTax:       []float64{0, 10.25},
+			lineRate:      []float64{0, 0.1025},
+			jurisdiction:  []float64{5, 4, 1.25},
+			exempt:        []float64{50, 50, 50},
+		},
+		{
+			name:          "a store exempts more categories",
+			jurisdictions: []*Jurisdiction{country, state},
+			rates:         []*StoreRate{{JurisdictionID: country.ID, ExemptCategories: []string{StandardCategory}}},
+			lines:         []sold{{StandardCategory, 1, 100}},
+			lineTax:       []float64{4},
+			lineRate:      []float64{0.04},
+			jurisdiction:  []float64{0, 4},
+			exempt:        []float64{100, 0},
+		},
+		{
+			name:          "a store rate replaces the standard rate only",
+			jurisdictions: []*Jurisdiction{state},
+			rates:         []*StoreRate{{JurisdictionID: state.ID, Rate: &stateRate}},
+			lines:         []sold{{StandardCategory, 1, 100}, {"clothing", 1, 100}},
+			lineTax:       []float64{3, 0},
+			lineRate:      []float64{0.03, 0},
+			jurisdiction:  []float64{3},
+			exempt:        []float64{100},
+		},
+		{
+			name:          "tax included in the store's prices is taken out first",
+			jurisdictions: []*Jurisdiction{country, state},
+			rates:         []*StoreRate{{JurisdictionID: state.ID, Inclusive: true}},
+			lines:         []sold{{StandardCategory, 1, 104}},
+			lineTax:       []float64{9},
+			lineRate:      []float64{0.09},
+			jurisdiction:  []float64{5, 4},
+			exempt:        []float64{0, 0},
+			included:      4,
+		},
+		{
+			name:          "holiday on its first day",
+			jurisdictions: []*Jurisdiction{country, state},
+			holidays:      []*Holiday{schoolWeek},
+			day:           day(7, 1),
+			lines:         []sold{{"supplies", 2, 100}},
+			lineTax:       []float64{5},
+			lineRate:      []float64{0.05},
+			jurisdiction:  []float64{5, 0},
+			exempt:        []float64{0, 100},
+		},
+		{
+			name:          "holiday until the end of its last day",
+			jurisdictions: []*Jurisdiction{country, state},
+			holidays:      []*Holiday{schoolWeek},
+			day:           day(7, 3).Add(23 * time.Hour),
+			lines:         []sold{{"supplies", 1, 100}},
+			lineTax:       []float64{5},
+			lineRate:      []float64{0.05},
+			jurisdiction:  []float64{5, 0},
+			exempt:        []float64{0, 100},
+		},
+		{
+			name:          "before the holiday",
+			jurisdictions: []*Jurisdiction{country, state},
+			holidays:      []*Holiday{schoolWeek},
+			day:           day(6, 30),
+			lines:         []sold{{"supplies", 1, 100}},
+			lineTax:       []float64{9},
+			lineRate:      []float64{0.09},
+			jurisdiction:  []float64{5, 4},
+			exempt:        []float64{0, 0},
+		},
+		{
+			name:          "after the holiday",
+			jurisdictions: []*Jurisdiction{country, state},
+			holidays:      []*Holiday{schoolWeek},
+			day:           day(7, 4),
+			lines:         []sold{{"supplies", 1, 100}},
+			lineTax:       []float64{9},
+			lineRate:      []float64{0.09},
+			jurisdiction:  []float64{5, 4},
+			exempt:        []float64{0, 0},
+		},
+		{
+			name:          "holiday of another category",
+			jurisdictions: []*Jurisdiction{country, state},
+			holidays:      []*Holiday{schoolWeek},
+			day:           day(7, 2),
+			lines:         []sold{{StandardCategory, 1, 100}, {"supplies", 1, 100}},
+			lineTax:       []float64{9, 5},
+			lineRate:      []float64{0.09, 0.05},
+			jurisdiction:  []float64{10, 4},
+			exempt:        []float64{0, 100},
+		},
+		{
+			name:          "holiday up to its unit price",
+			jurisdictions: []*Jurisdiction{country, state},
+			holidays:      []*Holiday{schoolWeek},
+			day:           day(7, 2),
+			lines:         []sold{{"supplies", 2, 250}},
+			lineTax:       []float64{22.5},
+			lineRate:      []float64{0.09},
+			jurisdiction:  []float64{12.5, 10},
+			exempt:        []float64{0, 0},
+		},
+		{
+			name:          "holiday of another jurisdiction",
+			jurisdictions: []*Jurisdiction{country, city},
+			holidays:      []*Holiday{schoolWeek},
+			day:           day(7, 2),
+			lines:         []sold{{"supplies", 1, 100}},
+			lineTax:       []float64{6.25},
+			lineRate:      []float64{0.0625},
+			jurisdiction:  []float64{5, 1.25},
+			exempt:        []float64{0, 0},
+		},
+		{
+			// 0.10 at 7.25% owes 0.00725 a line, rounded to a cent each,
+			// where the order's 0.30 would owe 0.02
+			name:          "tax is rounded per line",
+			jurisdictions: []*Jurisdiction{{ID: uuid.New(), Country: "US", Name: "Country", Rate: 0.0725}},
+			lines:         []sold{{StandardCategory, 1, 0.10}, {StandardCategory, 1, 0.10}, {StandardCategory, 1, 0.10}},
+			lineTax:       []float64{0.01, 0.01, 0.01},
+			lineRate:      []float64{0.0725, 0.0725, 0.0725},
+			jurisdiction:  []float64{0.03},
+			exempt:        []float64{0},
+		},
+		{
+			// 1.50 owes 0.075 to each jurisdiction, rounded to 0.08 apiece
+			// where the line's 10% of 1.50 would be 0.15
+			name:          "tax is rounded per jurisdiction",
+			jurisdictions: []*Jurisdiction{country, {ID: uuid.New(), Country: "US", State: "CA", Name: "Other", Rate: 0.05}},
+			lines:         []sold{{StandardCategory, 1, 1.50}},
+			lineTax:       []float64{0.16},
+			lineRate:      []float64{0.1},
+			jurisdiction:  []float64{0.08, 0.08},
+			exempt:        []float64{0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			on := tt.day
+			if on.IsZero() {
+				on = day(3, 1)
+			}
+			var lines []Line
+			var categories []*Category
+			for _, s := range tt.lines {
+				lines = append(lines, Line{ProductID: uuid.NewString(), Quantity: s.quantity, Amount: s.amount})
+				categories = append(categories, testCategories[s.category])
+			}
+
+			q := Calculate(Location{Country: "US", State: "NY", City: "New York"}, "USD",
+				lines, categories, tt.jurisdictions, tt.rates, tt.holidays, on)
+
+			require.Len(t, q.Lines, len(tt.lines))
+			total := 0.0
+			for i, l := range q.Lines {
+				assert.Equal(t, tt.lines[i].category, l.Category, "line %d", i)
+				assert.Equal(t, tt.lineTax[i], l.Tax, "tax of line %d", i)
+				assert.Equal(t, tt.lineRate[i], l.Rate, "rate of line %d", i)
+				total += l.Tax
+			}
+			assert.Equal(t, roundCents(total), q.TotalTax)
+			assert.Equal(t, tt.included, q.IncludedTax)
+
+			require.Len(t, q.Jurisdictions, len(tt.jurisdictions))
+			for k, jt := range q.Jurisdictions {
+				assert.Equal(t, tt.jurisdictions[k].ID, jt.JurisdictionID)
+				assert.Equal(t, tt.jurisdiction[k], jt.Tax, "tax of %s", jt.Name)
+				assert.Equal(t, tt.exempt[k], jt.Exempt, "exempt sales of %s", jt.Name)
+			}
+		})
+	}
+}
+
+func TestJurisdictionLevel(t *testing.T) {
+	assert.Equal(t, LevelCountry, country.Level())
+	assert.Equal(t, LevelState, state.Level())
+	assert.Equal(t, LevelCity, city.Level())
+}
@@ -0,0 +1,52 @@
+package tax
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sale is the tax collected on one order, by jurisdiction, as recorded for
+// filing
+type Sale struct {
+	OrderID    uuid.UUID         `json:"order_id"`
+	StoreID    uuid.UUID         `json:"store_id"`
+	Currency   string            `json:"currency"`
+	Taxes      []JurisdictionTax `json:"taxes"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+// ReportFilter narrows a filing report to a period and place
+type ReportFilter struct {
+	From    time.Time // First day, inclusive
+	To      time.Time // Last day, inclusive
+	Country string
+	State   string
+}
+
+// ReportRow is what one jurisdiction is owed for a period, in one currency.
+// Sales cancelled or refunded in the period are taken off.
+type ReportRow struct {
+	JurisdictionID uuid.UUID `json:"jurisdiction_id"`
+	Name           string    `json:"name"`
+	Level          Level     `json:"level"`
+	Country        string    `json:"country"`
+	State          string    `json:"state,omitempty"`
+	City           string    `json:"city,omitempty"`
+	Currency       string    `json:"currency"`
+	Orders         int       `json:"orders"`
+	Taxable        float64   `json:"taxable"`
+	Exempt         float64   `json:"exempt"`
+	Tax            float64   `json:"tax"`
+}
+
+// Report is the tax collected for each jurisdiction over a period, for
+// filing returns
+type Report struct {
+	From        time.Time    `json:"from"`
+	To          time.Time    `json:"to"`
+	Country     string       `json:"country,omitempty"`
+	State       string       `json:"state,omitempty"`
+	Rows        []*ReportRow `json:"rows"`
+	GeneratedAt time.Time    `json:"generated_at"`
+}
@@ -0,0 +1,57 @@
+package tax
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository defines the tax repository interface
+type Repository interface {
+	CreateJurisdiction(ctx context.Context, j *Jurisdiction) error
+	GetJurisdiction(ctx context.Context, id uuid.UUID) (*Jurisdiction, error)
+	// ListJurisdictions returns jurisdictions by location; an empty country
+	// lists every country
+	ListJurisdictions(ctx context.Context, country string, limit, offset int) ([]*Jurisdiction, error)
+	UpdateJurisdiction(ctx context.Context, j *Jurisdiction) error
+	// MatchJurisdictions returns the active jurisdictions covering loc,
+	// country first
+	MatchJurisdictions(ctx context.Context, loc Location) ([]*Jurisdiction, error)
+
+	// SaveCategory creates or replaces a tax category
+	SaveCategory(ctx context.Context, c *Category) error
+	ListCategories(ctx context.Context) ([]*Category, error)
+	// SaveAssignment creates or replaces an assignment; ErrCategoryNotFound
+	// when its category does not exist
+	SaveAssignment(ctx context.Context, a *Assignment) error
+	DeleteAssignment(ctx context.Context, kind AssignmentKind, subjectID uuid.UUID) error
+	ListAssignments(ctx context.Context, kind AssignmentKind, limit, offset int) ([]*Assignment, error)
+	// ResolveCategories returns the tax category of each line: the product's
+	// own, its catalog category's, or the standard category
+	ResolveCategories(ctx context.Context, lines []Line) ([]*Category, error)
+
+	// SaveStoreRate creates or replaces how a store levies a jurisdiction;
+	// ErrJurisdictionNotFound when the jurisdiction does not exist
+	SaveStoreRate(ctx context.Context, rate *StoreRate) error
+	// StoreRates returns how a store levies the jurisdictions it has rates of
+	StoreRates(ctx context.Context, storeID uuid.UUID) ([]*StoreRate, error)
+	DeleteStoreRate(ctx context.Context, storeID, jurisdictionID uuid.UUID) error
+
+	CreateHoliday(ctx context.Context, h *Holiday) error
+	// ListHolidays returns holidays by start date; a nil jurisdiction lists
+	// every jurisdiction's
+	ListHolidays(ctx context.Context, jurisdictionID *uuid.UUID, limit, offset int) ([]*Holiday, error)
+	DeleteHoliday(ctx context.Context, id uuid.UUID) error
+	// GetHolidays returns the holidays of jurisdictionIDs running on day
+	GetHolidays(ctx context.Context, jurisdictionIDs []uuid.UUID, day time.Time) ([]*Holiday, error)
+
+	// RecordSale records or replaces the tax collected on an order, unless
+	// the order was already reversed
+	RecordSale(ctx context.Context, sale *Sale) error
+	// ReverseSale takes the tax collected on an order off the period it was
+	// cancelled or refunded in. Reversing twice is a no-op.
+	ReverseSale(ctx context.Context, orderID uuid.UUID, at time.Time) error
+	// Report totals the tax collected for each jurisdiction matching filter
+	Report(ctx context.Context, filter ReportFilter) ([]*ReportRow, error)
+}
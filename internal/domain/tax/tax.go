@@ -0,0 +1,182 @@
+package tax
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrJurisdictionNotFound is returned when a jurisdiction does not exist
+	ErrJurisdictionNotFound = errors.New("tax jurisdiction not found")
+	// ErrJurisdictionExists is returned when a jurisdiction already covers a location
+	ErrJurisdictionExists = errors.New("tax jurisdiction already exists")
+	// ErrCategoryNotFound is returned when a tax category does not exist
+	ErrCategoryNotFound = errors.New("tax category not found")
+	// ErrAssignmentNotFound is returned when a product or catalog category has no tax category assigned
+	ErrAssignmentNotFound = errors.New("tax category assignment not found")
+	// ErrHolidayNotFound is returned when a tax holiday does not exist
+	ErrHolidayNotFound = errors.New("tax holiday not found")
+	// ErrStoreNotFound is returned when a quote is for an unknown store
+	ErrStoreNotFound = errors.New("store not found")
+	// ErrStoreRateNotFound is returned when a store has no rate of a jurisdiction
+	ErrStoreRateNotFound = errors.New("store tax rate not found")
+)
+
+// StandardCategory is the tax category of products with no other assigned
+const StandardCategory = "standard"
+
+// Level is how much of a location a jurisdiction covers
+type Level string
+
+const (
+	LevelCountry Level = "country"
+	LevelState   Level = "state"
+	LevelCity    Level = "city"
+)
+
+// Location is where a sale is taxed: the ship-to address, or the store for
+// sales over the counter
+type Location struct {
+	Country string `json:"country"`
+	State   string `json:"state,omitempty"`
+	City    string `json:"city,omitempty"`
+}
+
+// Normalize upper-cases the country and state codes and trims the city
+func (l Location) Normalize() Location {
+	return Location{
+		Country: strings.ToUpper(strings.TrimSpace(l.Country)),
+		State:   strings.ToUpper(strings.TrimSpace(l.State)),
+		City:    strings.TrimSpace(l.City),
+	}
+}
+
+// Jurisdiction is a country, state or city levying sales tax. A sale owes
+// the tax of every jurisdiction covering its location, so a city sale pays
+// city, state and country rates.
+type Jurisdiction struct {
+	ID            uuid.UUID          `json:"id"`
+	Country       string             `json:"country"`
+	State         string             `json:"state,omitempty"`
+	City          string             `json:"city,omitempty"`
+	Name          string             `json:"name"`
+	Rate          float64            `json:"rate"`                     // Standard rate; 0.07 is 7%
+	CategoryRates map[string]float64 `json:"category_rates,omitempty"` // Rates by tax category, replacing the standard one
+	Active        bool               `json:"active"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+// Level returns how much of a location j covers
+func (j *Jurisdiction) Level() Level {
+	switch {
+	case j.City != "":
+		return LevelCity
+	case j.State != "":
+		return LevelState
+	}
+	return LevelCountry
+}
+
+// RateFor returns the rate j levies on products of category
+func (j *Jurisdiction) RateFor(category string) float64 {
+	if rate, ok := j.CategoryRates[category]; ok {
+		return rate
+	}
+	return j.Rate
+}
+
+// StoreRate is how a store levies a jurisdiction's tax when it differs from
+// the jurisdiction's own rates, such as in a special district: at other
+// rates, exempting more categories, or included in the store's prices
+// rather than added to them.
+type StoreRate struct {
+	StoreID          uuid.UUID          `json:"store_id"`
+	JurisdictionID   uuid.UUID          `json:"jurisdiction_id"`
+	Rate             *float64           `json:"rate,omitempty"`           // Replaces the standard rate; nil keeps the jurisdiction's
+	CategoryRates    map[string]float64 `json:"category_rates,omitempty"` // Replace the jurisdiction's rates of these categories
+	ExemptCategories []string           `json:"exempt_categories,omitempty"`
+	Inclusive        bool               `json:"inclusive"` // The store's prices include the tax
+	UpdatedAt        time.Time          `json:"updated_at"`
+}
+
+// RateFor returns the rate the store levies for j on products of category
+func (r *StoreRate) RateFor(j *Jurisdiction, category string) float64 {
+	for _, exempt := range r.ExemptCategories {
+		if exempt == category {
+			return 0
+		}
+	}
+	if rate, ok := r.CategoryRates[category]; ok {
+		return rate
+	}
+	if _, ok := j.CategoryRates[category]; !ok && r.Rate != nil {
+		return *r.Rate
+	}
+	return j.RateFor(category)
+}
+
+// Category groups products taxed alike, such as groceries or clothing.
+// Exempt categories owe no tax anywhere.
+type Category struct {
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Exempt      bool      `json:"exempt"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AssignmentKind is what a tax category is assigned to
+type AssignmentKind string
+
+const (
+	// AssignProduct assigns a category to a catalog variant, as sold on orders
+	AssignProduct AssignmentKind = "product"
+	// AssignCatalogCategory assigns a category to every product of a catalog category
+	AssignCatalogCategory AssignmentKind = "catalog_category"
+)
+
+// Assignment gives products a tax category. A product's own assignment wins
+// over its catalog category's.
+type Assignment struct {
+	SubjectID uuid.UUID      `json:"subject_id"`
+	Kind      AssignmentKind `json:"kind"`
+	Category  string         `json:"category"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// Holiday lowers a jurisdiction's rate between two dates, for one category
+// or all of them, on items up to a unit price
+type Holiday struct {
+	ID             uuid.UUID `json:"id"`
+	JurisdictionID uuid.UUID `json:"jurisdiction_id"`
+	Name           string    `json:"name"`
+	Category       string    `json:"category,omitempty"`       // Empty covers every category
+	StartsOn       time.Time `json:"starts_on"`                // First day, inclusive
+	EndsOn         time.Time `json:"ends_on"`                  // Last day, inclusive
+	Rate           float64   `json:"rate"`                     // Usually 0
+	MaxUnitPrice   float64   `json:"max_unit_price,omitempty"` // 0 covers items of any price
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Covers reports whether h applies on day to an item of category sold at
+// unitPrice
+func (h *Holiday) Covers(day time.Time, category string, unitPrice float64) bool {
+	day = truncateDay(day)
+	if day.Before(truncateDay(h.StartsOn)) || day.After(truncateDay(h.EndsOn)) {
+		return false
+	}
+	if h.Category != "" && h.Category != category {
+		return false
+	}
+	return h.MaxUnitPrice == 0 || unitPrice <= h.MaxUnitPrice
+}
+
+// truncateDay returns the UTC midnight starting t's day
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -13,6 +13,7 @@ type Repository interface {
 	GetByEmail(ctx context.Context, email string) (*User, error)
 	Update(ctx context.Context, user *User) error
 	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	SetRoles(ctx context.Context, id uuid.UUID, roles []string) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	ExistsByEmail(ctx context.Context, email string) (bool, error)
 }
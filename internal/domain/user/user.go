@@ -1,21 +1,27 @@
 package user
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// ErrNotFound is returned when a user does not exist
+var ErrNotFound = errors.New("user not found")
+
 // User represents a user entity
 type User struct {
 	ID                uuid.UUID  `json:"id"`
-	Email             string     `json:"email"`
+	TenantID          string     `json:"tenant_id"`
+	Email             string     `json:"email" encrypt:"pii"` // Looked up by blind index
 	PasswordHash      string     `json:"-"` // Never expose in JSON
 	FirstName         string     `json:"first_name,omitempty"`
 	LastName          string     `json:"last_name,omitempty"`
-	Phone             string     `json:"phone,omitempty"`
+	Phone             string     `json:"phone,omitempty" encrypt:"pii"`
+	Roles             []string   `json:"roles"` // Carried in issued tokens; "user" unless granted more
 	MFAEnabled        bool       `json:"mfa_enabled"`
-	MFASecret         string     `json:"-"` // Never expose
+	MFASecret         string     `json:"-" encrypt:"pii"` // Never expose
 	FailedLoginAttempts int      `json:"-"`
 	AccountLockedUntil *time.Time `json:"-"`
 	LastLoginAt       *time.Time `json:"last_login_at,omitempty"`
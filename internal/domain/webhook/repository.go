@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository defines the interface for webhook data access
+type Repository interface {
+	CreateSubscription(ctx context.Context, s *Subscription) error
+	GetSubscription(ctx context.Context, id uuid.UUID) (*Subscription, error)
+	// ListSubscriptions returns subscriptions, oldest first, of partnerID or
+	// of every partner when it is empty
+	ListSubscriptions(ctx context.Context, partnerID string, limit, offset int) ([]*Subscription, error)
+	UpdateSubscription(ctx context.Context, s *Subscription) error
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+
+	// Enqueue queues a delivery of event to every subscription matching its
+	// type, paused ones included, and returns how many were queued. An event
+	// queued before is not queued again.
+	Enqueue(ctx context.Context, event *Event, now time.Time) (int64, error)
+	// ClaimDue hands up to limit pending deliveries of active subscriptions
+	// that are due by now to the caller, holding them off other claims for
+	// lease. Rows are locked with SKIP LOCKED, so instances claiming at once
+	// get different deliveries.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*DueDelivery, error)
+	// RecordAttempt records an attempt at a delivery along with the status it
+	// left the delivery in; next is when a pending delivery is tried again
+	RecordAttempt(ctx context.Context, a *Attempt, status DeliveryStatus, next *time.Time) error
+	GetDelivery(ctx context.Context, id uuid.UUID) (*Delivery, error)
+	// ListDeliveries returns deliveries, newest first, without payloads
+	ListDeliveries(ctx context.Context, filter DeliveryFilter, limit, offset int) ([]*Delivery, error)
+	ListAttempts(ctx context.Context, deliveryID uuid.UUID) ([]*Attempt, error)
+	// Replay queues a finished delivery again for now, with fresh attempts.
+	// A delivery still pending fails with ErrDeliveryPending.
+	Replay(ctx context.Context, id uuid.UUID, now time.Time) (*Delivery, error)
+	// ReplayFailed queues again a subscription's failed deliveries of events
+	// since since, and returns how many were queued
+	ReplayFailed(ctx context.Context, subscriptionID uuid.UUID, since, now time.Time) (int64, error)
+	// PruneDeliveries deletes finished deliveries created before before, with
+	// their attempts
+	PruneDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
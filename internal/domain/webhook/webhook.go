@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrDeliveryPending      = errors.New("webhook delivery is still being attempted")
+)
+
+// SubscriptionStatus is whether a subscription's deliveries go out
+type SubscriptionStatus string
+
+const (
+	SubscriptionActive SubscriptionStatus = "active"
+	SubscriptionPaused SubscriptionStatus = "paused" // Deliveries are queued and go out once resumed
+)
+
+// Subscription sends the events of the types it lists to a partner's
+// endpoint. Event types are exact, such as order.created, a prefix wildcard,
+// such as order.*, or * for every event.
+type Subscription struct {
+	ID          uuid.UUID          `json:"id"`
+	TenantID    string             `json:"tenant_id"`
+	PartnerID   string             `json:"partner_id"`
+	URL         string             `json:"url"`
+	EventTypes  []string           `json:"event_types"`
+	Description string             `json:"description,omitempty"`
+	Secret      string             `json:"-"` // Signs deliveries; shown once, when created
+	Status      SubscriptionStatus `json:"status"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// Matches reports whether s subscribes to events of eventType
+func (s *Subscription) Matches(eventType string) bool {
+	for _, t := range s.EventTypes {
+		if t == "*" || t == eventType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(t, "*"); ok && strings.HasPrefix(eventType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is a broker event to be delivered to the subscriptions matching its
+// type
+type Event struct {
+	ID        string
+	Type      string
+	Data      json.RawMessage
+	CreatedAt time.Time
+}
+
+// DeliveryStatus is how far a delivery got
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending" // Awaiting its next attempt
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed" // Out of attempts; can be replayed
+)
+
+// Delivery is one event sent to one subscription, attempted until the
+// endpoint answers 2xx or attempts run out
+type Delivery struct {
+	ID             uuid.UUID       `json:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload,omitempty"` // The event's data
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"` // Since it was queued or last replayed
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseCode   *int            `json:"response_code,omitempty"` // Of the last attempt
+	LastError      string          `json:"last_error,omitempty"`
+	EventAt        time.Time       `json:"event_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// Attempt records one try at a delivery
+type Attempt struct {
+	ID           uuid.UUID `json:"id"`
+	DeliveryID   uuid.UUID `json:"delivery_id"`
+	Number       int       `json:"number"`
+	ResponseCode *int      `json:"response_code,omitempty"` // Nil when no response arrived
+	ResponseBody string    `json:"response_body,omitempty"` // Truncated
+	Error        string    `json:"error,omitempty"`
+	DurationMS   int64     `json:"duration_ms"`
+	AttemptedAt  time.Time `json:"attempted_at"`
+}
+
+// Succeeded reports whether the endpoint accepted the delivery
+func (a *Attempt) Succeeded() bool {
+	return a.ResponseCode != nil && *a.ResponseCode >= 200 && *a.ResponseCode <= 299
+}
+
+// DueDelivery is a delivery claimed for an attempt, with where it goes
+type DueDelivery struct {
+	Delivery     *Delivery
+	Subscription *Subscription
+}
+
+// DeliveryFilter narrows a delivery listing; zero values match all
+type DeliveryFilter struct {
+	PartnerID      string
+	SubscriptionID *uuid.UUID
+	Status         DeliveryStatus
+	EventType      string
+}
+
+// Backoff returns how long to wait after failed attempt number attempt,
+// doubling from base up to max
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	wait := base
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if wait >= max {
+			return max
+		}
+	}
+	if wait > max {
+		return max
+	}
+	return wait
+}
@@ -0,0 +1,77 @@
+package catalogclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+
+	"github.com/onichange/pos-system/internal/domain/catalog"
+	catalogpb "github.com/onichange/pos-system/proto/catalog"
+)
+
+// Client calls the catalog service's internal gRPC API
+type Client struct {
+	pricing catalogpb.PricingServiceClient
+}
+
+// NewClient creates a client of the catalog service reached over conn, as
+// dialed by pkg/grpc.Dial
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{pricing: catalogpb.NewPricingServiceClient(conn)}
+}
+
+// ResolvePrices returns the prices storeID charges for variants, by variant
+// ID. Unknown variants are missing from the result.
+func (c *Client) ResolvePrices(ctx context.Context, storeID uuid.UUID, variantIDs []uuid.UUID) (map[uuid.UUID]*catalog.PricedVariant, error) {
+	req := &catalogpb.ResolvePricesRequest{StoreId: storeID.String(), VariantIds: make([]string, len(variantIDs))}
+	for i, id := range variantIDs {
+		req.VariantIds[i] = id.String()
+	}
+	resp, err := c.pricing.ResolvePrices(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("catalog-service: %w", err)
+	}
+
+	priced := make(map[uuid.UUID]*catalog.PricedVariant, len(resp.GetVariants()))
+	for _, v := range resp.GetVariants() {
+		pv, err := pricedVariant(v)
+		if err != nil {
+			return nil, fmt.Errorf("catalog-service: %w", err)
+		}
+		priced[pv.VariantID] = pv
+	}
+	return priced, nil
+}
+
+// pricedVariant converts a priced variant of the API
+func pricedVariant(v *catalogpb.PricedVariant) (*catalog.PricedVariant, error) {
+	variantID, err := uuid.Parse(v.GetVariantId())
+	if err != nil {
+		return nil, fmt.Errorf("invalid variant ID %q", v.GetVariantId())
+	}
+	productID, err := uuid.Parse(v.GetProductId())
+	if err != nil {
+		return nil, fmt.Errorf("invalid product ID %q", v.GetProductId())
+	}
+
+	pv := &catalog.PricedVariant{
+		VariantID:  variantID,
+		ProductID:  productID,
+		SKU:        v.GetSku(),
+		Name:       v.GetName(),
+		Status:     catalog.ProductStatus(v.GetStatus()),
+		Price:      v.GetPrice(),
+		Currency:   v.GetCurrency(),
+		StorePrice: v.GetStorePrice(),
+	}
+	if v.GetCategoryId() != "" {
+		categoryID, err := uuid.Parse(v.GetCategoryId())
+		if err != nil {
+			return nil, fmt.Errorf("invalid category ID %q", v.GetCategoryId())
+		}
+		pv.CategoryID = &categoryID
+	}
+	return pv, nil
+}
@@ -0,0 +1,31 @@
+package exportclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/infrastructure/serviceclient"
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// Client streams a store's records from the internal export API of the
+// service owning them, such as the catalog, inventory or order service
+type Client struct {
+	client *serviceclient.Client
+}
+
+// NewClient creates a client of the service instances listed in baseURLs,
+// separated by commas, balanced and ejected as by the gateway's proxy
+// settings
+func NewClient(service, baseURLs string, cfg config.ProxyConfig) *Client {
+	return &Client{client: serviceclient.New(service, baseURLs, cfg)}
+}
+
+// ExportStore opens the stream of a store's records, as written by
+// pkg/dataexport, for the caller to read and close
+func (c *Client) ExportStore(ctx context.Context, storeID uuid.UUID) (io.ReadCloser, error) {
+	return c.client.Open(ctx, http.MethodGet, "/internal/v1/stores/"+storeID.String()+"/export", nil)
+}
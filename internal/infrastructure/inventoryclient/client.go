@@ -0,0 +1,142 @@
+package inventoryclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	inventorypb "github.com/onichange/pos-system/proto/inventory"
+)
+
+// Client calls the inventory service's internal gRPC API
+type Client struct {
+	inventory inventorypb.InventoryServiceClient
+}
+
+// NewClient creates a client of the inventory service reached over conn, as
+// dialed by pkg/grpc.Dial
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{inventory: inventorypb.NewInventoryServiceClient(conn)}
+}
+
+// GetInventory returns an inventory record of the tenant in ctx, or
+// inventory.ErrInventoryNotFound
+func (c *Client) GetInventory(ctx context.Context, id uuid.UUID) (*inventory.Inventory, error) {
+	resp, err := c.inventory.GetInventory(ctx, &inventorypb.GetInventoryRequest{InventoryId: id.String()})
+	if status.Code(err) == codes.NotFound {
+		return nil, inventory.ErrInventoryNotFound
+	}
+	if err != nil {
+		return nil, errorOf(err)
+	}
+
+	msg := resp.GetInventory()
+	inv := &inventory.Inventory{
+		Quantity:          int(msg.GetQuantity()),
+		ReservedQuantity:  int(msg.GetReservedQuantity()),
+		AvailableQuantity: int(msg.GetAvailableQuantity()),
+		ReorderPoint:      int(msg.GetReorderPoint()),
+		ReorderQuantity:   int(msg.GetReorderQuantity()),
+		Version:           int(msg.GetVersion()),
+		CreatedAt:         msg.GetCreatedAt().AsTime(),
+		UpdatedAt:         msg.GetUpdatedAt().AsTime(),
+	}
+	if inv.ID, err = uuid.Parse(msg.GetId()); err != nil {
+		return nil, fmt.Errorf("inventory-service: invalid inventory ID %q", msg.GetId())
+	}
+	if inv.ProductID, err = uuid.Parse(msg.GetProductId()); err != nil {
+		return nil, fmt.Errorf("inventory-service: invalid product ID %q", msg.GetProductId())
+	}
+	if msg.GetStoreId() != "" {
+		storeID, err := uuid.Parse(msg.GetStoreId())
+		if err != nil {
+			return nil, fmt.Errorf("inventory-service: invalid store ID %q", msg.GetStoreId())
+		}
+		inv.StoreID = &storeID
+	}
+	if msg.GetCostPrice() != nil {
+		costPrice := msg.GetCostPrice().GetValue()
+		inv.CostPrice = &costPrice
+	}
+	if msg.GetSellingPrice() != nil {
+		sellingPrice := msg.GetSellingPrice().GetValue()
+		inv.SellingPrice = &sellingPrice
+	}
+	return inv, nil
+}
+
+// ReserveStock reserves quantity of a product in storeID, or in any store
+// when storeID is nil, and returns the quantity left available. It returns
+// inventory.ErrInsufficientStock when too little is available.
+func (c *Client) ReserveStock(ctx context.Context, productID uuid.UUID, storeID *uuid.UUID, quantity int) (int, error) {
+	resp, err := c.inventory.ReserveStock(ctx, &inventorypb.ReserveStockRequest{
+		ProductId: productID.String(),
+		StoreId:   optionalID(storeID),
+		Quantity:  int32(quantity),
+	})
+	if err != nil {
+		return 0, errorOf(err)
+	}
+	return int(resp.GetAvailableQuantity()), nil
+}
+
+// ReleaseStock makes quantity of a product reserved in storeID, or in any
+// store when storeID is nil, available again
+func (c *Client) ReleaseStock(ctx context.Context, productID uuid.UUID, storeID *uuid.UUID, quantity int) error {
+	_, err := c.inventory.ReleaseStock(ctx, &inventorypb.ReleaseStockRequest{
+		ProductId: productID.String(),
+		StoreId:   optionalID(storeID),
+		Quantity:  int32(quantity),
+	})
+	return errorOf(err)
+}
+
+// ReceiveStock adds received stock to a store's inventory, or returns
+// inventory.ErrAlreadyReceived when its source was posted before
+func (c *Client) ReceiveStock(ctx context.Context, r *inventory.StockReceipt) error {
+	req := &inventorypb.ReceiveStockRequest{
+		ProductId:  r.ProductID.String(),
+		StoreId:    r.StoreID.String(),
+		Quantity:   int32(r.Quantity),
+		UnitCost:   r.UnitCost,
+		SourceType: r.SourceType,
+		SourceId:   r.SourceID.String(),
+		Reason:     r.Reason,
+		UserId:     optionalID(r.UserID),
+	}
+	if !r.ReceivedAt.IsZero() {
+		req.ReceivedAt = timestamppb.New(r.ReceivedAt)
+	}
+
+	_, err := c.inventory.ReceiveStock(ctx, req)
+	return errorOf(err)
+}
+
+// errorOf returns the inventory error a status stands for, if any
+func errorOf(err error) error {
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.FailedPrecondition:
+		return inventory.ErrInsufficientStock
+	case codes.Aborted:
+		return inventory.ErrVersionConflict
+	case codes.AlreadyExists:
+		return inventory.ErrAlreadyReceived
+	}
+	return fmt.Errorf("inventory-service: %w", err)
+}
+
+// optionalID formats an optional ID, empty when absent
+func optionalID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
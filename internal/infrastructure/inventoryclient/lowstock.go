@@ -0,0 +1,35 @@
+package inventoryclient
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/infrastructure/serviceclient"
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// LowStockClient lists low stock over the inventory service's internal HTTP
+// API, for the listings the gRPC API does not carry
+type LowStockClient struct {
+	client *serviceclient.Client
+}
+
+// NewLowStockClient creates a client of the inventory service instances
+// listed in baseURLs, separated by commas, balanced and ejected as by the
+// gateway's proxy settings
+func NewLowStockClient(baseURLs string, cfg config.ProxyConfig) *LowStockClient {
+	return &LowStockClient{client: serviceclient.New("inventory-service", baseURLs, cfg)}
+}
+
+// ListLowStock returns the stock of every store of the tenant in ctx at or
+// below its reorder point
+func (c *LowStockClient) ListLowStock(ctx context.Context) ([]*inventory.Inventory, error) {
+	var resp struct {
+		Data []*inventory.Inventory `json:"data"`
+	}
+	if err := c.client.Do(ctx, http.MethodGet, "/internal/v1/inventory/low-stock", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
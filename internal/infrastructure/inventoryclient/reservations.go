@@ -0,0 +1,128 @@
+package inventoryclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/infrastructure/serviceclient"
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// ReservationClient reserves stock over the inventory service's internal
+// HTTP API, through reservations held until committed, released or expired
+type ReservationClient struct {
+	client *serviceclient.Client
+}
+
+// NewReservationClient creates a client of the inventory service instances
+// listed in baseURLs, separated by commas, balanced and ejected as by the
+// gateway's proxy settings
+func NewReservationClient(baseURLs string, cfg config.ProxyConfig) *ReservationClient {
+	return &ReservationClient{client: serviceclient.New("inventory-service", baseURLs, cfg)}
+}
+
+// ReserveBatch reserves the stock of every reservation of rs for an order,
+// under their IDs, or none of it. The items that could not be reserved are
+// returned as inventory.ReservationErrors, of inventory.ErrInsufficientStock
+// or inventory.ErrInventoryNotFound. Reserving IDs again reserves nothing
+// more; it fails with inventory.ErrReservationClosed once any was released
+// or expired.
+func (c *ReservationClient) ReserveBatch(ctx context.Context, orderID uuid.UUID, rs []*inventory.Reservation) error {
+	items := make([]map[string]any, len(rs))
+	for i, res := range rs {
+		items[i] = map[string]any{
+			"reservation_id": res.ID,
+			"product_id":     res.ProductID,
+			"store_id":       res.StoreID,
+			"quantity":       res.Quantity,
+		}
+	}
+	req := map[string]any{
+		"items":          items,
+		"reference_id":   orderID,
+		"reference_type": "order",
+	}
+
+	var resp struct {
+		Reservations []struct {
+			Status inventory.ReservationStatus `json:"status"`
+		} `json:"reservations"`
+	}
+	err := c.client.Do(ctx, http.MethodPost, "/internal/v1/inventory/reservations/batch", req, &resp)
+	var statusErr *serviceclient.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusConflict {
+		return batchErrors(statusErr)
+	}
+	if err != nil {
+		return err
+	}
+	for _, res := range resp.Reservations {
+		if res.Status != inventory.ReservationHeld && res.Status != inventory.ReservationCommitted {
+			return inventory.ErrReservationClosed
+		}
+	}
+	return nil
+}
+
+// Commit keeps a reservation's stock reserved past its expiry, or returns
+// inventory.ErrReservationClosed once it was released or expired
+func (c *ReservationClient) Commit(ctx context.Context, id uuid.UUID) error {
+	return reservationError(c.client.Do(ctx, http.MethodPost, "/internal/v1/inventory/reservations/"+id.String()+"/commit", nil, nil))
+}
+
+// Release makes a reservation's stock available again, or returns
+// inventory.ErrReservationClosed once it was released or expired
+func (c *ReservationClient) Release(ctx context.Context, id uuid.UUID) error {
+	return reservationError(c.client.Do(ctx, http.MethodPost, "/internal/v1/inventory/reservations/"+id.String()+"/release", nil, nil))
+}
+
+// batchErrors returns the errors of the items of a batch that could not be
+// reserved, as answered in a 409
+func batchErrors(statusErr *serviceclient.StatusError) error {
+	var payload struct {
+		Items []struct {
+			Index int    `json:"index"`
+			Code  string `json:"code"`
+			Error string `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(statusErr.Body, &payload); err != nil || len(payload.Items) == 0 {
+		return statusErr
+	}
+
+	failed := inventory.ReservationErrors{}
+	for _, item := range payload.Items {
+		switch item.Code {
+		case "insufficient_stock":
+			failed[item.Index] = inventory.ErrInsufficientStock
+		case "inventory_not_found":
+			failed[item.Index] = inventory.ErrInventoryNotFound
+		case "reservation_exists":
+			failed[item.Index] = inventory.ErrReservationExists
+		default:
+			failed[item.Index] = fmt.Errorf("inventory-service: %s", item.Error)
+		}
+	}
+	return failed
+}
+
+// reservationError returns the inventory error a response to a change of a
+// reservation stands for, if any
+func reservationError(err error) error {
+	var statusErr *serviceclient.StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.Code {
+		case http.StatusConflict:
+			return inventory.ErrReservationClosed
+		case http.StatusNotFound:
+			return inventory.ErrReservationNotFound
+		}
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package loyaltyclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/loyalty"
+	"github.com/onichange/pos-system/internal/infrastructure/serviceclient"
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// rejections are the loyalty errors the loyalty service reports by message
+var rejections = []error{
+	loyalty.ErrInsufficientPoints,
+	loyalty.ErrAlreadyRedeemed,
+	loyalty.ErrBelowMinimum,
+	loyalty.ErrExceedsLimit,
+}
+
+// Client calls the loyalty service's internal API
+type Client struct {
+	client *serviceclient.Client
+}
+
+// NewClient creates a client of the loyalty service instances listed in
+// baseURLs, separated by commas, balanced and ejected as by the gateway's
+// proxy settings
+func NewClient(baseURLs string, cfg config.ProxyConfig) *Client {
+	return &Client{client: serviceclient.New("loyalty-service", baseURLs, cfg)}
+}
+
+// Redeem spends a member's points as a discount on an order totalling
+// orderTotal. A redemption the program refuses fails with its loyalty error.
+func (c *Client) Redeem(ctx context.Context, userID, orderID uuid.UUID, points int64, orderTotal float64, currency string) (*loyalty.Redemption, error) {
+	req := map[string]any{
+		"user_id":     userID,
+		"order_id":    orderID,
+		"points":      points,
+		"order_total": orderTotal,
+		"currency":    currency,
+	}
+	var redemption loyalty.Redemption
+	if err := c.client.Do(ctx, http.MethodPost, "/internal/v1/redemptions", req, &redemption); err != nil {
+		return nil, rejection(err)
+	}
+	return &redemption, nil
+}
+
+// Release returns the points redeemed for an order. Orders without a
+// redemption are ignored.
+func (c *Client) Release(ctx context.Context, orderID uuid.UUID) error {
+	return c.client.Do(ctx, http.MethodDelete, "/internal/v1/redemptions/"+orderID.String(), nil, nil)
+}
+
+// rejection maps a refused request to the loyalty error it reports
+func rejection(err error) error {
+	var statusErr *serviceclient.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code >= http.StatusInternalServerError {
+		return err
+	}
+	for _, rejected := range rejections {
+		if statusErr.Message == rejected.Error() {
+			return rejected
+		}
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+package orderclient
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/infrastructure/serviceclient"
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// Client calls the order service's internal API
+type Client struct {
+	client *serviceclient.Client
+}
+
+// NewClient creates a client of the order service instances listed in
+// baseURLs, separated by commas, balanced and ejected as by the gateway's
+// proxy settings
+func NewClient(baseURLs string, cfg config.ProxyConfig) *Client {
+	return &Client{client: serviceclient.New("order-service", baseURLs, cfg)}
+}
+
+// GetOrder returns an order of the tenant in ctx, whoever owns it
+func (c *Client) GetOrder(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	var o order.Order
+	if err := c.client.Do(ctx, http.MethodGet, "/internal/v1/orders/"+id.String(), nil, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// ImportOfflineOrder uploads an order a terminal rang up offline. Uploading
+// the same order again returns it as a duplicate; an order its conflict
+// policy rejects comes back rejected with its conflicts, not as an error.
+func (c *Client) ImportOfflineOrder(ctx context.Context, o *order.OfflineOrder) (*order.ImportResult, error) {
+	var result order.ImportResult
+	if err := c.client.Do(ctx, http.MethodPost, "/internal/v1/orders/offline", o, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
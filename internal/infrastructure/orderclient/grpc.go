@@ -0,0 +1,184 @@
+package orderclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	orderpb "github.com/onichange/pos-system/proto/order"
+)
+
+// GRPCClient calls the order service's internal gRPC API
+type GRPCClient struct {
+	orders orderpb.OrderServiceClient
+}
+
+// NewGRPCClient creates a client of the order service reached over conn, as
+// dialed by pkg/grpc.Dial
+func NewGRPCClient(conn grpc.ClientConnInterface) *GRPCClient {
+	return &GRPCClient{orders: orderpb.NewOrderServiceClient(conn)}
+}
+
+// GetOrder returns an order of the tenant in ctx, whoever owns it, or
+// order.ErrOrderNotFound
+func (c *GRPCClient) GetOrder(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	resp, err := c.orders.GetOrder(ctx, &orderpb.GetOrderRequest{OrderId: id.String()})
+	if status.Code(err) == codes.NotFound {
+		return nil, order.ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("order-service: %w", err)
+	}
+	return fromProto(resp.GetOrder())
+}
+
+// ListOrders returns up to limit of a user's orders, newest first, skipping
+// the first offset
+func (c *GRPCClient) ListOrders(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*order.Order, error) {
+	resp, err := c.orders.ListOrders(ctx, &orderpb.ListOrdersRequest{
+		UserId: userID.String(),
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("order-service: %w", err)
+	}
+
+	orders := make([]*order.Order, len(resp.GetOrders()))
+	for i, msg := range resp.GetOrders() {
+		if orders[i], err = fromProto(msg); err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+// fromProto converts an order message to the domain order
+func fromProto(msg *orderpb.Order) (*order.Order, error) {
+	o := &order.Order{
+		Status:          order.OrderStatus(strings.ToLower(strings.TrimPrefix(msg.GetStatus().String(), "ORDER_STATUS_"))),
+		TotalAmount:     msg.GetTotalAmount(),
+		Currency:        msg.GetCurrency(),
+		Items:           make([]order.OrderItem, len(msg.GetItems())),
+		ShippingAddress: addressFromProto(msg.GetShippingAddress()),
+		BillingAddress:  addressFromProto(msg.GetBillingAddress()),
+		Notes:           msg.GetNotes(),
+		LoyaltyPoints:   msg.GetLoyaltyPoints(),
+		LoyaltyDiscount: msg.GetLoyaltyDiscount(),
+		Tender:          msg.GetTender(),
+		TaxAmount:       msg.GetTaxAmount(),
+		CreatedAt:       msg.GetCreatedAt().AsTime(),
+		UpdatedAt:       msg.GetUpdatedAt().AsTime(),
+		CompletedAt:     optionalTime(msg.GetCompletedAt()),
+		CancelledAt:     optionalTime(msg.GetCancelledAt()),
+	}
+
+	var err error
+	if o.ID, err = parseID("order", msg.GetId()); err != nil {
+		return nil, err
+	}
+	if o.UserID, err = parseID("user", msg.GetUserId()); err != nil {
+		return nil, err
+	}
+	if o.StoreID, err = parseID("store", msg.GetStoreId()); err != nil {
+		return nil, err
+	}
+	if o.ShiftID, err = parseOptionalID("shift", msg.GetShiftId()); err != nil {
+		return nil, err
+	}
+
+	for i, item := range msg.GetItems() {
+		o.Items[i] = order.OrderItem{
+			ProductID: item.GetProductId(),
+			Name:      item.GetName(),
+			Quantity:  int(item.GetQuantity()),
+			UnitPrice: item.GetUnitPrice(),
+			Subtotal:  item.GetSubtotal(),
+			Discount:  item.GetDiscount(),
+			Tax:       item.GetTax(),
+		}
+		if o.Items[i].CategoryID, err = parseOptionalID("category", item.GetCategoryId()); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range msg.GetPromotions() {
+		promotionID, err := parseID("promotion", p.GetPromotionId())
+		if err != nil {
+			return nil, err
+		}
+		o.Promotions = append(o.Promotions, order.AppliedPromotion{
+			PromotionID: promotionID,
+			Name:        p.GetName(),
+			Amount:      p.GetAmount(),
+			Coupon:      p.GetCoupon(),
+		})
+	}
+	for _, t := range msg.GetTaxes() {
+		jurisdictionID, err := parseID("jurisdiction", t.GetJurisdictionId())
+		if err != nil {
+			return nil, err
+		}
+		o.Taxes = append(o.Taxes, order.TaxLine{
+			JurisdictionID: jurisdictionID,
+			Name:           t.GetName(),
+			Level:          t.GetLevel(),
+			Taxable:        t.GetTaxable(),
+			Exempt:         t.GetExempt(),
+			Tax:            t.GetTax(),
+			Inclusive:      t.GetInclusive(),
+		})
+	}
+	return o, nil
+}
+
+// addressFromProto converts an optional address message, nil when unset
+func addressFromProto(msg *orderpb.Address) *order.Address {
+	if msg == nil {
+		return nil
+	}
+	return &order.Address{
+		Street:     msg.GetStreet(),
+		City:       msg.GetCity(),
+		State:      msg.GetState(),
+		PostalCode: msg.GetPostalCode(),
+		Country:    msg.GetCountry(),
+	}
+}
+
+// parseID parses the ID of a kind of entity in an order message
+func parseID(kind, id string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("order-service: invalid %s ID %q", kind, id)
+	}
+	return parsed, nil
+}
+
+// parseOptionalID parses an optional ID, nil when empty
+func parseOptionalID(kind, id string) (*uuid.UUID, error) {
+	if id == "" {
+		return nil, nil
+	}
+	parsed, err := parseID(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// optionalTime converts an optional timestamp, nil when unset
+func optionalTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
@@ -0,0 +1,75 @@
+// Package outbox publishes service events at least once. Instead of
+// publishing to the broker while handling a request, a service records each
+// event in the outbox table of its own database, and a Relay publishes the
+// recorded events to the broker, deleting each once the broker confirms it.
+// Events recorded while the broker is unreachable wait for it to come back.
+//
+// Events are recorded in the transaction saving the change they report, by
+// a Publisher on that transaction, so an event is recorded if and only if
+// its change is saved. A relay crashing after publishing an event but before
+// deleting it publishes it again, so consumers must tolerate redeliveries,
+// as they already do for broker redeliveries.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+	"github.com/onichange/pos-system/pkg/tenant"
+	"github.com/onichange/pos-system/pkg/tracing"
+)
+
+// eventsExchange is the exchange domain events are published to
+const eventsExchange = "events"
+
+// Publisher records events in the outbox table for the relay to publish.
+// It stands in for the broker as the event publisher of handlers.
+type Publisher struct {
+	db database.Querier
+}
+
+// NewPublisher creates a publisher recording events in db's outbox table
+func NewPublisher(db database.Querier) *Publisher {
+	return &Publisher{db: db}
+}
+
+// PublishEventContext records a domain event for the events exchange, with
+// the trace, request ID and tenant of ctx it is to be published with
+func (p *Publisher) PublishEventContext(ctx context.Context, eventType, routingKey string, data map[string]interface{}) error {
+	id := uuid.New()
+	now := time.Now()
+	body, err := json.Marshal(messagequeue.Event{
+		ID:        "evt_" + id.String(),
+		Type:      eventType,
+		Source:    "onichange-pos",
+		Timestamp: now,
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", eventType, err)
+	}
+
+	headers := amqp.Table{}
+	tracing.InjectAMQP(ctx, headers)
+	headersJSON, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO outbox (id, tenant_id, exchange, routing_key, body, headers, created_at, available_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+	`
+	_, err = p.db.Exec(ctx, query, id, tenant.IDFromContext(ctx), eventsExchange, routingKey, body, headersJSON, now)
+	if err != nil {
+		return fmt.Errorf("failed to record %s event in the outbox: %w", eventType, err)
+	}
+	return nil
+}
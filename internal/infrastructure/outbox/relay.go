@@ -0,0 +1,215 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/streadway/amqp"
+
+	"github.com/onichange/pos-system/pkg/config"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+	"github.com/onichange/pos-system/pkg/tracing"
+)
+
+// DB is the database holding the outbox table, as *pgxpool.Pool
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Broker publishes relayed events, as *messagequeue.RabbitMQ in confirm mode
+type Broker interface {
+	PublishContext(ctx context.Context, exchange, routingKey string, message interface{}) error
+	Close() error
+}
+
+// Dialer connects to the broker
+type Dialer func() (Broker, error)
+
+// DialRabbitMQ connects to RabbitMQ at url in confirm mode, so events are
+// deleted from the outbox only once RabbitMQ has taken them
+func DialRabbitMQ(url string, log *logger.Logger) Dialer {
+	return func() (Broker, error) {
+		broker, err := messagequeue.NewRabbitMQ(url, log)
+		if err != nil {
+			return nil, err
+		}
+		if err := broker.UseConfirms(); err != nil {
+			broker.Close()
+			return nil, err
+		}
+		return broker, nil
+	}
+}
+
+// Relay publishes the events recorded in the outbox table, oldest first.
+// Instances of a service share the table, each publishing the events the
+// others have not locked.
+type Relay struct {
+	db     DB
+	dial   Dialer
+	cfg    config.OutboxConfig
+	broker Broker // Nil until connected, and after the broker failed
+}
+
+// NewRelay creates a relay of db's outbox table, connecting to the broker
+// with dial when it has events to publish
+func NewRelay(db DB, dial Dialer, cfg config.OutboxConfig) *Relay {
+	return &Relay{db: db, dial: dial, cfg: cfg}
+}
+
+// message is an event recorded in the outbox
+type message struct {
+	id         uuid.UUID
+	exchange   string
+	routingKey string
+	body       json.RawMessage
+	headers    amqp.Table
+	attempts   int
+}
+
+// RelayBatch publishes a batch of the events due, and returns how many it
+// published. It stops at the first event the broker refuses, which is
+// retried after a backoff, and disconnects from the broker.
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	messages, err := r.lockDue(ctx, tx)
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+
+	if r.broker == nil {
+		broker, err := r.dial()
+		if err != nil {
+			return 0, err
+		}
+		r.broker = broker
+	}
+
+	var published []uuid.UUID
+	var failure error
+	for _, m := range messages {
+		if err := r.publish(ctx, m); err != nil {
+			failure = err
+			r.disconnect()
+			if ctx.Err() == nil {
+				if err := r.retryLater(ctx, tx, m, err); err != nil {
+					return 0, err
+				}
+			}
+			break
+		}
+		published = append(published, m.id)
+	}
+
+	if len(published) > 0 {
+		if _, err := tx.Exec(ctx, `DELETE FROM outbox WHERE id = ANY($1)`, published); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(published), failure
+}
+
+// lockDue locks a batch of the events due that no other relay has locked
+func (r *Relay) lockDue(ctx context.Context, tx pgx.Tx) ([]*message, error) {
+	query := `
+		SELECT id, exchange, routing_key, body, headers, attempts
+		FROM outbox
+		WHERE available_at <= NOW()
+		ORDER BY created_at, id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.Query(ctx, query, r.cfg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*message
+	for rows.Next() {
+		m := &message{}
+		var body, headersJSON []byte
+		if err := rows.Scan(&m.id, &m.exchange, &m.routingKey, &body, &headersJSON, &m.attempts); err != nil {
+			return nil, err
+		}
+		m.body = body
+		if err := json.Unmarshal(headersJSON, &m.headers); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// publish publishes an event with the headers it was recorded with
+func (r *Relay) publish(ctx context.Context, m *message) error {
+	ctx = tracing.ExtractAMQP(ctx, m.headers)
+	return r.broker.PublishContext(ctx, m.exchange, m.routingKey, m.body)
+}
+
+// retryLater records why the broker refused an event and holds it back for
+// a backoff doubling with each refusal
+func (r *Relay) retryLater(ctx context.Context, tx pgx.Tx, m *message, cause error) error {
+	backoff := r.cfg.MaxBackoff
+	if m.attempts < 30 {
+		if d := r.cfg.PollInterval << m.attempts; d > 0 && d < backoff {
+			backoff = d
+		}
+	}
+	query := `
+		UPDATE outbox
+		SET attempts = attempts + 1, last_error = $2, available_at = $3
+		WHERE id = $1
+	`
+	_, err := tx.Exec(ctx, query, m.id, cause.Error(), time.Now().Add(backoff))
+	return err
+}
+
+// disconnect drops the broker connection, to dial it again for the next batch
+func (r *Relay) disconnect() {
+	if r.broker != nil {
+		r.broker.Close()
+		r.broker = nil
+	}
+}
+
+// Run relays events every cfg.PollInterval until ctx ends, publishing
+// batches back to back while there are events due
+func (r *Relay) Run(ctx context.Context, log *logger.Logger) {
+	defer apperrors.Recover(ctx, "outbox")
+	defer r.disconnect()
+
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			published, err := r.RelayBatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Warnf("Failed to relay outbox events: %v", err)
+			}
+			if err != nil || published < r.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+var testConfig = config.OutboxConfig{PollInterval: time.Second, BatchSize: 10, MaxBackoff: time.Minute}
+
+// fakeRow is a row of the outbox table
+type fakeRow struct {
+	id          uuid.UUID
+	routingKey  string
+	attempts    int
+	lastError   string
+	availableAt time.Time
+}
+
+// fakeDB is an outbox table whose transactions apply their writes on commit
+type fakeDB struct {
+	rows []*fakeRow
+}
+
+func (db *fakeDB) add(routingKeys ...string) {
+	for _, key := range routingKeys {
+		db.rows = append(db.rows, &fakeRow{id: uuid.New(), routingKey: key})
+	}
+}
+
+func (db *fakeDB) routingKeys() []string {
+	var keys []string
+	for _, row := range db.rows {
+		keys = append(keys, row.routingKey)
+	}
+	return keys
+}
+
+func (db *fakeDB) Begin(context.Context) (pgx.Tx, error) {
+	return &fakeTx{db: db}, nil
+}
+
+// fakeTx is a transaction of a fakeDB, supporting the relay's queries only
+type fakeTx struct {
+	pgx.Tx
+	db      *fakeDB
+	deleted []uuid.UUID
+	retried map[uuid.UUID]fakeRow // The attempt's error and availability
+}
+
+func (tx *fakeTx) Query(_ context.Context, _ string, args ...interface{}) (pgx.Rows, error) {
+	var due []*fakeRow
+	for _, row := range tx.db.rows {
+		if !row.availableAt.After(time.Now()) && len(due) < args[0].(int) {
+			due = append(due, row)
+		}
+	}
+	return &fakeRows{rows: due, next: -1}, nil
+}
+
+func (tx *fakeTx) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	switch {
+	case len(args) == 1:
+		tx.deleted = append(tx.deleted, args[0].([]uuid.UUID)...)
+	case len(args) == 3:
+		if tx.retried == nil {
+			tx.retried = map[uuid.UUID]fakeRow{}
+		}
+		tx.retried[args[0].(uuid.UUID)] = fakeRow{lastError: args[1].(string), availableAt: args[2].(time.Time)}
+	default:
+		return pgconn.CommandTag{}, errors.New("unexpected query: " + sql)
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	var kept []*fakeRow
+	for _, row := range tx.db.rows {
+		if retry, ok := tx.retried[row.id]; ok {
+			row.attempts++
+			row.lastError, row.availableAt = retry.lastError, retry.availableAt
+		}
+		deleted := false
+		for _, id := range tx.deleted {
+			deleted = deleted || id == row.id
+		}
+		if !deleted {
+			kept = append(kept, row)
+		}
+	}
+	tx.db.rows = kept
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error { return nil }
+
+// fakeRows are the rows locked by the relay
+type fakeRows struct {
+	pgx.Rows
+	rows []*fakeRow
+	next int
+}
+
+func (r *fakeRows) Next() bool { r.next++; return r.next < len(r.rows) }
+func (r *fakeRows) Close()     {}
+func (r *fakeRows) Err() error { return nil }
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	row := r.rows[r.next]
+	*dest[0].(*uuid.UUID) = row.id
+	*dest[1].(*string) = eventsExchange
+	*dest[2].(*string) = row.routingKey
+	*dest[3].(*[]byte) = []byte(`{}`)
+	*dest[4].(*[]byte) = []byte(`{}`)
+	*dest[5].(*int) = row.attempts
+	return nil
+}
+
+// fakeBroker records the routing keys it takes, refusing those in refuse
+type fakeBroker struct {
+	published []string
+	refuse    map[string]bool
+	closed    bool
+}
+
+func (b *fakeBroker) PublishContext(_ context.Context, _, routingKey string, _ interface{}) error {
+	if b.refuse[routingKey] {
+		return errors.New("broker refused " + routingKey)
+	}
+	b.published = append(b.published, routingKey)
+	return nil
+}
+
+func (b *fakeBroker) Close() error {
+	b.closed = true
+	return nil
+}
+
+// dialer returns a dialer handing out brokers refusing refuse, and the
+// brokers it dialed
+func dialer(refuse ...string) (Dialer, *[]*fakeBroker) {
+	var dialed []*fakeBroker
+	return func() (Broker, error) {
+		broker := &fakeBroker{refuse: map[string]bool{}}
+		for _, key := range refuse {
+			broker.refuse[key] = true
+		}
+		dialed = append(dialed, broker)
+		return broker, nil
+	}, &dialed
+}
+
+func TestRelayBatchDeletesPublishedEvents(t *testing.T) {
+	db := &fakeDB{}
+	db.add("order.created", "order.paid")
+	dial, dialed := dialer()
+	relay := NewRelay(db, dial, testConfig)
+
+	published, err := relay.RelayBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Empty(t, db.rows)
+	require.Len(t, *dialed, 1)
+	assert.Equal(t, []string{"order.created", "order.paid"}, (*dialed)[0].published)
+}
+
+func TestRelayBatchDoesNotDialWithoutEvents(t *testing.T) {
+	dial, dialed := dialer()
+	published, err := NewRelay(&fakeDB{}, dial, testConfig).RelayBatch(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, published)
+	assert.Empty(t, *dialed)
+}
+
+func TestRelayBatchStopsAtRefusedEvent(t *testing.T) {
+	db := &fakeDB{}
+	db.add("order.created", "order.paid", "order.shipped")
+	dial, dialed := dialer("order.paid")
+	relay := NewRelay(db, dial, testConfig)
+
+	before := time.Now()
+	published, err := relay.RelayBatch(context.Background())
+	require.EqualError(t, err, "broker refused order.paid")
+	assert.Equal(t, 1, published)
+
+	// Only the published event is deleted, and the refused one is held back
+	// while the events after it wait their turn
+	assert.Equal(t, []string{"order.paid", "order.shipped"}, db.routingKeys())
+	refused := db.rows[0]
+	assert.Equal(t, 1, refused.attempts)
+	assert.Equal(t, "broker refused order.paid", refused.lastError)
+	assert.WithinDuration(t, before.Add(testConfig.PollInterval), refused.availableAt, time.Second)
+	assert.Zero(t, db.rows[1].attempts)
+	assert.Equal(t, []string{"order.created"}, (*dialed)[0].published)
+
+	// The backoff doubles with each refusal, up to MaxBackoff
+	refused.availableAt = time.Time{}
+	_, err = relay.RelayBatch(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, 2, refused.attempts)
+	assert.WithinDuration(t, time.Now().Add(2*testConfig.PollInterval), refused.availableAt, time.Second)
+
+	refused.attempts, refused.availableAt = 40, time.Time{}
+	_, err = relay.RelayBatch(context.Background())
+	require.Error(t, err)
+	assert.WithinDuration(t, time.Now().Add(testConfig.MaxBackoff), refused.availableAt, time.Second)
+}
+
+func TestRelayBatchRedialsAfterFailure(t *testing.T) {
+	db := &fakeDB{}
+	db.add("order.created")
+	dial, dialed := dialer("order.created")
+	relay := NewRelay(db, dial, testConfig)
+
+	_, err := relay.RelayBatch(context.Background())
+	require.Error(t, err)
+	require.Len(t, *dialed, 1)
+	assert.True(t, (*dialed)[0].closed, "the failed broker is disconnected")
+
+	// The next batch dials a new broker, which takes the event
+	delete((*dialed)[0].refuse, "order.created")
+	dial2, dialed2 := dialer()
+	relay.dial = dial2
+	db.rows[0].availableAt = time.Time{}
+
+	published, err := relay.RelayBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	require.Len(t, *dialed2, 1)
+	assert.Equal(t, []string{"order.created"}, (*dialed2)[0].published)
+	assert.Empty(t, (*dialed)[0].published, "the failed broker is not reused")
+	assert.Empty(t, db.rows)
+}
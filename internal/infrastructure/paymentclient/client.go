@@ -0,0 +1,80 @@
+package paymentclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/internal/infrastructure/serviceclient"
+	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// Client calls the payment service's internal API
+type Client struct {
+	client *serviceclient.Client
+}
+
+// NewClient creates a client of the payment service instances listed in
+// baseURLs, separated by commas, balanced and ejected as by the gateway's
+// proxy settings. Calls carry service tokens issued by jwtManager.
+func NewClient(baseURLs string, cfg config.ProxyConfig, jwtManager *auth.JWTManager) *Client {
+	return &Client{client: serviceclient.New("payment-service", baseURLs, cfg).WithServiceTokens(jwtManager)}
+}
+
+// Charge charges p, identified by its ID, and returns it as it stands after
+// the charge: completed, failed, or processing until the provider settles
+// it. Charging an ID again returns its payment rather than charging twice.
+// A payment method the provider declines fails with payment.ErrDeclined.
+func (c *Client) Charge(ctx context.Context, p *payment.Payment) (*payment.Payment, error) {
+	req := map[string]any{
+		"id":                   p.ID,
+		"order_id":             p.OrderID,
+		"user_id":              p.UserID,
+		"payment_method_token": p.PaymentMethodToken,
+		"payment_method_type":  p.PaymentMethodType,
+		"amount":               p.Amount,
+		"currency":             p.Currency,
+		"three_d_secure":       p.ThreeDSecureEnabled,
+	}
+	var charged payment.Payment
+	err := c.client.Do(ctx, http.MethodPost, "/internal/v1/payments", req, &charged)
+	var statusErr *serviceclient.StatusError
+	if errors.As(err, &statusErr) && (statusErr.Code == http.StatusPaymentRequired || statusErr.Code == http.StatusUnprocessableEntity) {
+		return nil, fmt.Errorf("%w: %s", payment.ErrDeclined, statusErr.Message)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &charged, nil
+}
+
+// GetPayment returns a payment, or payment.ErrPaymentNotFound
+func (c *Client) GetPayment(ctx context.Context, id uuid.UUID) (*payment.Payment, error) {
+	var p payment.Payment
+	err := c.client.Do(ctx, http.MethodGet, "/internal/v1/payments/"+id.String(), nil, &p)
+	var statusErr *serviceclient.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound {
+		return nil, payment.ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Refund refunds all of a completed payment. Payments that failed, were
+// refunded before or were never made are left as they are. A payment the
+// provider has yet to settle fails with payment.ErrNotSettled.
+func (c *Client) Refund(ctx context.Context, id uuid.UUID, reason string) error {
+	err := c.client.Do(ctx, http.MethodPost, "/internal/v1/payments/"+id.String()+"/refund", map[string]any{"reason": reason}, nil)
+	var statusErr *serviceclient.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusConflict {
+		return payment.ErrNotSettled
+	}
+	return err
+}
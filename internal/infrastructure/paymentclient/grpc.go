@@ -0,0 +1,75 @@
+package paymentclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/onichange/pos-system/internal/domain/payment"
+	paymentpb "github.com/onichange/pos-system/proto/payment"
+)
+
+// GRPCClient calls the payment service's internal gRPC API
+type GRPCClient struct {
+	payments paymentpb.PaymentServiceClient
+}
+
+// NewGRPCClient creates a client of the payment service reached over conn,
+// as dialed by pkg/grpc.Dial
+func NewGRPCClient(conn grpc.ClientConnInterface) *GRPCClient {
+	return &GRPCClient{payments: paymentpb.NewPaymentServiceClient(conn)}
+}
+
+// GetPayment returns a payment of the tenant in ctx, whoever made it, or
+// payment.ErrPaymentNotFound. The payment method token is left empty.
+func (c *GRPCClient) GetPayment(ctx context.Context, id uuid.UUID) (*payment.Payment, error) {
+	resp, err := c.payments.GetPayment(ctx, &paymentpb.GetPaymentRequest{PaymentId: id.String()})
+	if status.Code(err) == codes.NotFound {
+		return nil, payment.ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("payment-service: %w", err)
+	}
+
+	msg := resp.GetPayment()
+	p := &payment.Payment{
+		PaymentMethodType:     payment.PaymentMethodType(msg.GetPaymentMethodType()),
+		Amount:                msg.GetAmount(),
+		Currency:              msg.GetCurrency(),
+		Status:                payment.PaymentStatus(strings.ToLower(strings.TrimPrefix(msg.GetStatus().String(), "PAYMENT_STATUS_"))),
+		Provider:              msg.GetProvider(),
+		ProviderTransactionID: msg.GetProviderTransactionId(),
+		ThreeDSecureEnabled:   msg.GetThreeDSecureEnabled(),
+		ThreeDSecureStatus:    msg.GetThreeDSecureStatus(),
+		CreatedAt:             msg.GetCreatedAt().AsTime(),
+		UpdatedAt:             msg.GetUpdatedAt().AsTime(),
+		ProcessedAt:           optionalTime(msg.GetProcessedAt()),
+		CompletedAt:           optionalTime(msg.GetCompletedAt()),
+	}
+	if p.ID, err = uuid.Parse(msg.GetId()); err != nil {
+		return nil, fmt.Errorf("payment-service: invalid payment ID %q", msg.GetId())
+	}
+	if p.OrderID, err = uuid.Parse(msg.GetOrderId()); err != nil {
+		return nil, fmt.Errorf("payment-service: invalid order ID %q", msg.GetOrderId())
+	}
+	if p.UserID, err = uuid.Parse(msg.GetUserId()); err != nil {
+		return nil, fmt.Errorf("payment-service: invalid user ID %q", msg.GetUserId())
+	}
+	return p, nil
+}
+
+// optionalTime converts an optional timestamp, nil when unset
+func optionalTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
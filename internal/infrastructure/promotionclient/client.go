@@ -0,0 +1,81 @@
+package promotionclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/promotion"
+	"github.com/onichange/pos-system/internal/infrastructure/serviceclient"
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// rejections are the coupon errors the promotion service reports by message
+var rejections = []error{
+	promotion.ErrCouponNotFound,
+	promotion.ErrCouponInactive,
+	promotion.ErrCouponExpired,
+	promotion.ErrCouponExhausted,
+	promotion.ErrCouponNotApplicable,
+	promotion.ErrCouponRedeemed,
+}
+
+// Client calls the promotion service's internal API
+type Client struct {
+	client *serviceclient.Client
+}
+
+// NewClient creates a client of the promotion service instances listed in
+// baseURLs, separated by commas, balanced and ejected as by the gateway's
+// proxy settings
+func NewClient(baseURLs string, cfg config.ProxyConfig) *Client {
+	return &Client{client: serviceclient.New("promotion-service", baseURLs, cfg)}
+}
+
+// Evaluate returns what the promotions running in the cart's store, and
+// those its coupons unlock, take off its lines. A coupon that cannot be used
+// fails with its promotion error.
+func (c *Client) Evaluate(ctx context.Context, cart *promotion.Cart) (*promotion.Result, error) {
+	var result promotion.Result
+	if err := c.client.Do(ctx, http.MethodPost, "/internal/v1/promotions/evaluate", cart, &result); err != nil {
+		return nil, rejection(err)
+	}
+	return &result, nil
+}
+
+// RedeemCoupon records an order's use of a coupon. Redeeming it again for
+// the same order succeeds.
+func (c *Client) RedeemCoupon(ctx context.Context, code string, orderID, userID uuid.UUID) error {
+	req := map[string]any{
+		"code":     code,
+		"order_id": orderID,
+		"user_id":  userID,
+	}
+	err := rejection(c.client.Do(ctx, http.MethodPost, "/internal/v1/coupons/redemptions", req, nil))
+	if errors.Is(err, promotion.ErrCouponRedeemed) {
+		return nil
+	}
+	return err
+}
+
+// ReleaseCoupons gives back the coupons an order redeemed. Orders without
+// redemptions are ignored.
+func (c *Client) ReleaseCoupons(ctx context.Context, orderID uuid.UUID) error {
+	return c.client.Do(ctx, http.MethodDelete, "/internal/v1/coupons/redemptions/"+orderID.String(), nil, nil)
+}
+
+// rejection maps a refused request to the promotion error it reports
+func rejection(err error) error {
+	var statusErr *serviceclient.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code >= http.StatusInternalServerError {
+		return err
+	}
+	for _, rejected := range rejections {
+		if statusErr.Message == rejected.Error() {
+			return rejected
+		}
+	}
+	return err
+}
@@ -0,0 +1,461 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/analytics"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// AnalyticsRepository implements analytics.Repository. Each fact is applied
+// in a single statement that records its event, so a fact lands in every
+// rollup or in none; time entries are kept by version instead. Rollups are
+// kept per tenant; every query but pruning is
+// scoped to the tenant in ctx and fails with tenant.ErrNoTenant when there is
+// none.
+type AnalyticsRepository struct {
+	db database.Querier
+}
+
+// NewAnalyticsRepository creates a new analytics repository
+func NewAnalyticsRepository(db database.Querier) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// appliedBuckets records an event ($1) and yields its hourly and daily
+// buckets for the time in $2, or no rows if the event was already applied
+const appliedBuckets = `
+	applied AS (
+		INSERT INTO analytics_applied_events (event_id) VALUES ($1)
+		ON CONFLICT (event_id) DO NOTHING
+		RETURNING event_id
+	), buckets AS (
+		SELECT g.granularity, date_trunc(g.granularity, $2::timestamp) AS bucket
+		FROM (VALUES ('hour'), ('day')) AS g(granularity), applied
+	)
+`
+
+// ApplyOrder adds an order fact to the sales and product rollups
+func (r *AnalyticsRepository) ApplyOrder(ctx context.Context, f *analytics.OrderFact) (bool, error) {
+	ctx, span := startSpan(ctx, "AnalyticsRepository.ApplyOrder")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	lines := f.Lines
+	if lines == nil {
+		lines = []analytics.ProductLine{}
+	}
+	linesJSON, err := json.Marshal(lines)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		WITH ` + appliedBuckets + `, sales AS (
+			INSERT INTO analytics_sales_rollups (
+				tenant_id, granularity, bucket, store_id, currency, orders_placed, orders_completed,
+				orders_cancelled, orders_refunded, gross_sales, revenue, refunds
+			)
+			SELECT $14::varchar, granularity, bucket, $3::uuid, $4::varchar, $5::bigint, $6::bigint,
+				$7::bigint, $8::bigint, $9::numeric, $10::numeric, $11::numeric
+			FROM buckets
+			ON CONFLICT (tenant_id, granularity, bucket, store_id, currency) DO UPDATE SET
+				orders_placed = analytics_sales_rollups.orders_placed + EXCLUDED.orders_placed,
+				orders_completed = analytics_sales_rollups.orders_completed + EXCLUDED.orders_completed,
+				orders_cancelled = analytics_sales_rollups.orders_cancelled + EXCLUDED.orders_cancelled,
+				orders_refunded = analytics_sales_rollups.orders_refunded + EXCLUDED.orders_refunded,
+				gross_sales = analytics_sales_rollups.gross_sales + EXCLUDED.gross_sales,
+				revenue = analytics_sales_rollups.revenue + EXCLUDED.revenue,
+				refunds = analytics_sales_rollups.refunds + EXCLUDED.refunds
+		), lines AS (
+			SELECT product_id, MAX(name) AS name, SUM(units) AS units, SUM(amount) AS amount
+			FROM jsonb_to_recordset($12::jsonb) AS l(product_id VARCHAR, name VARCHAR, units BIGINT, amount NUMERIC)
+			GROUP BY product_id
+		), products AS (
+			INSERT INTO analytics_product_rollups (
+				tenant_id, granularity, bucket, store_id, product_id, currency, name,
+				units_sold, revenue, units_refunded, refunds
+			)
+			SELECT $14::varchar, b.granularity, b.bucket, $3::uuid, l.product_id, $4::varchar, COALESCE(l.name, ''),
+				CASE WHEN $13::boolean THEN 0 ELSE l.units END,
+				CASE WHEN $13::boolean THEN 0 ELSE l.amount END,
+				CASE WHEN $13::boolean THEN l.units ELSE 0 END,
+				CASE WHEN $13::boolean THEN l.amount ELSE 0 END
+			FROM buckets b, lines l
+			ON CONFLICT (tenant_id, granularity, bucket, store_id, product_id, currency) DO UPDATE SET
+				name = EXCLUDED.name,
+				units_sold = analytics_product_rollups.units_sold + EXCLUDED.units_sold,
+				revenue = analytics_product_rollups.revenue + EXCLUDED.revenue,
+				units_refunded = analytics_product_rollups.units_refunded + EXCLUDED.units_refunded,
+				refunds = analytics_product_rollups.refunds + EXCLUDED.refunds
+		)
+		SELECT COUNT(*) FROM applied
+	`
+
+	var applied int
+	err = r.db.QueryRow(ctx, query,
+		f.EventID, f.At.UTC(), f.StoreID, f.Currency, f.Placed, f.Completed,
+		f.Cancelled, f.Refunded, f.GrossSales, f.Revenue, f.Refunds,
+		linesJSON, f.Refunded > 0, tenantID,
+	).Scan(&applied)
+	return applied > 0, err
+}
+
+// ApplyPayment adds a payment fact to the payment rollups
+func (r *AnalyticsRepository) ApplyPayment(ctx context.Context, f *analytics.PaymentFact) (bool, error) {
+	ctx, span := startSpan(ctx, "AnalyticsRepository.ApplyPayment")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		WITH ` + appliedBuckets + `, payments AS (
+			INSERT INTO analytics_payment_rollups (
+				tenant_id, granularity, bucket, currency, method, payments_started, payments_completed, amount_completed
+			)
+			SELECT $8::varchar, granularity, bucket, $3::varchar, $4::varchar, $5::bigint, $6::bigint, $7::numeric
+			FROM buckets
+			ON CONFLICT (tenant_id, granularity, bucket, currency, method) DO UPDATE SET
+				payments_started = analytics_payment_rollups.payments_started + EXCLUDED.payments_started,
+				payments_completed = analytics_payment_rollups.payments_completed + EXCLUDED.payments_completed,
+				amount_completed = analytics_payment_rollups.amount_completed + EXCLUDED.amount_completed
+		)
+		SELECT COUNT(*) FROM applied
+	`
+
+	var applied int
+	err = r.db.QueryRow(ctx, query,
+		f.EventID, f.At.UTC(), f.Currency, f.Method, f.Started, f.Completed, f.Amount, tenantID,
+	).Scan(&applied)
+	return applied > 0, err
+}
+
+// ApplyStock adds a stock fact to the stock rollups
+func (r *AnalyticsRepository) ApplyStock(ctx context.Context, f *analytics.StockFact) (bool, error) {
+	ctx, span := startSpan(ctx, "AnalyticsRepository.ApplyStock")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		WITH ` + appliedBuckets + `, stock AS (
+			INSERT INTO analytics_stock_rollups (
+				tenant_id, granularity, bucket, store_id, product_id, units_reserved, units_released, low_stock_events
+			)
+			SELECT $8::varchar, granularity, bucket, $3::uuid, $4::uuid, $5::bigint, $6::bigint, $7::bigint
+			FROM buckets
+			ON CONFLICT (tenant_id, granularity, bucket, store_id, product_id) DO UPDATE SET
+				units_reserved = analytics_stock_rollups.units_reserved + EXCLUDED.units_reserved,
+				units_released = analytics_stock_rollups.units_released + EXCLUDED.units_released,
+				low_stock_events = analytics_stock_rollups.low_stock_events + EXCLUDED.low_stock_events
+		)
+		SELECT COUNT(*) FROM applied
+	`
+
+	var applied int
+	err = r.db.QueryRow(ctx, query,
+		f.EventID, f.At.UTC(), f.StoreID, f.ProductID, f.Reserved, f.Released, f.LowStock, tenantID,
+	).Scan(&applied)
+	return applied > 0, err
+}
+
+// ApplyTimeEntry keeps a time entry fact, replacing an earlier version of
+// the entry
+func (r *AnalyticsRepository) ApplyTimeEntry(ctx context.Context, f *analytics.TimeEntryFact) (bool, error) {
+	ctx, span := startSpan(ctx, "AnalyticsRepository.ApplyTimeEntry")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO analytics_time_entries (
+			tenant_id, entry_id, version, store_id, staff_id, closed, clock_in, clock_out, worked_seconds, unpaid_break_seconds
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (tenant_id, entry_id) DO UPDATE SET
+			version = EXCLUDED.version,
+			store_id = EXCLUDED.store_id,
+			staff_id = EXCLUDED.staff_id,
+			closed = EXCLUDED.closed,
+			clock_in = EXCLUDED.clock_in,
+			clock_out = EXCLUDED.clock_out,
+			worked_seconds = EXCLUDED.worked_seconds,
+			unpaid_break_seconds = EXCLUDED.unpaid_break_seconds
+		WHERE analytics_time_entries.version < EXCLUDED.version
+	`
+
+	var clockOut *time.Time
+	if f.ClockOut != nil {
+		t := f.ClockOut.UTC()
+		clockOut = &t
+	}
+	tag, err := r.db.Exec(ctx, query,
+		tenantID, f.EntryID, f.Version, f.StoreID, f.StaffID, f.Closed, f.ClockIn.UTC(), clockOut,
+		int64(f.Worked/time.Second), int64(f.UnpaidBreaks/time.Second),
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetSales retrieves sales per bucket and currency
+func (r *AnalyticsRepository) GetSales(ctx context.Context, g analytics.Granularity, q analytics.Query) ([]*analytics.SalesPoint, error) {
+	ctx, span := startSpan(ctx, "AnalyticsRepository.GetSales")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT bucket, currency, SUM(orders_placed), SUM(orders_completed), SUM(orders_cancelled),
+			SUM(orders_refunded), SUM(gross_sales), SUM(revenue), SUM(refunds)
+		FROM analytics_sales_rollups
+		WHERE tenant_id = $6 AND granularity = $1 AND bucket >= $2 AND bucket < $3
+			AND ($4::uuid IS NULL OR store_id = $4) AND ($5 = '' OR currency = $5)
+		GROUP BY bucket, currency
+		ORDER BY bucket, currency
+	`
+
+	rows, err := r.db.Query(ctx, query, string(g), q.From.UTC(), q.To.UTC(), q.StoreID, q.Currency, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []*analytics.SalesPoint{}
+	for rows.Next() {
+		var p analytics.SalesPoint
+		err := rows.Scan(
+			&p.Bucket, &p.Currency, &p.OrdersPlaced, &p.OrdersCompleted, &p.OrdersCancelled,
+			&p.OrdersRefunded, &p.GrossSales, &p.Revenue, &p.Refunds,
+		)
+		if err != nil {
+			return nil, err
+		}
+		p.NetRevenue = p.Revenue - p.Refunds
+		points = append(points, &p)
+	}
+	return points, rows.Err()
+}
+
+// GetTopProducts retrieves the best selling products
+func (r *AnalyticsRepository) GetTopProducts(ctx context.Context, q analytics.Query, byUnits bool, limit int) ([]*analytics.ProductSales, error) {
+	ctx, span := startSpan(ctx, "AnalyticsRepository.GetTopProducts")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	order := "SUM(revenue)"
+	if byUnits {
+		order = "SUM(units_sold)"
+	}
+	query := `
+		SELECT product_id, MAX(name), currency, SUM(units_sold), SUM(revenue),
+			SUM(units_refunded), SUM(refunds)
+		FROM analytics_product_rollups
+		WHERE tenant_id = $7 AND granularity = $1 AND bucket >= $2 AND bucket < $3
+			AND ($4::uuid IS NULL OR store_id = $4) AND ($5 = '' OR currency = $5)
+		GROUP BY product_id, currency
+		ORDER BY ` + order + ` DESC, product_id
+		LIMIT $6
+	`
+
+	rows, err := r.db.Query(ctx, query, string(q.Granularity()), q.From.UTC(), q.To.UTC(), q.StoreID, q.Currency, limit, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []*analytics.ProductSales{}
+	for rows.Next() {
+		var p analytics.ProductSales
+		err := rows.Scan(
+			&p.ProductID, &p.Name, &p.Currency, &p.UnitsSold, &p.Revenue,
+			&p.UnitsRefunded, &p.Refunds,
+		)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, &p)
+	}
+	return products, rows.Err()
+}
+
+// GetConversion retrieves order and payment counts. Payments are not tied to
+// a store, so their counts cover every store.
+func (r *AnalyticsRepository) GetConversion(ctx context.Context, q analytics.Query) (*analytics.Conversion, error) {
+	ctx, span := startSpan(ctx, "AnalyticsRepository.GetConversion")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Conversion reads two rollups; a CTE per rollup keeps the filters apart
+	query := `
+		WITH sales AS (
+			SELECT orders_placed, orders_completed, orders_cancelled
+			FROM analytics_sales_rollups
+			WHERE tenant_id = $6 AND granularity = $1 AND bucket >= $2 AND bucket < $3
+				AND ($4::uuid IS NULL OR store_id = $4) AND ($5 = '' OR currency = $5)
+		), payments AS (
+			SELECT payments_started, payments_completed
+			FROM analytics_payment_rollups
+			WHERE tenant_id = $6 AND granularity = $1 AND bucket >= $2 AND bucket < $3
+				AND ($5 = '' OR currency = $5)
+		)
+		SELECT
+			COALESCE((SELECT SUM(orders_placed) FROM sales), 0),
+			COALESCE((SELECT SUM(orders_completed) FROM sales), 0),
+			COALESCE((SELECT SUM(orders_cancelled) FROM sales), 0),
+			COALESCE((SELECT SUM(payments_started) FROM payments), 0),
+			COALESCE((SELECT SUM(payments_completed) FROM payments), 0)
+	`
+
+	var c analytics.Conversion
+	err = r.db.QueryRow(ctx, query, string(q.Granularity()), q.From.UTC(), q.To.UTC(), q.StoreID, q.Currency, tenantID).Scan(
+		&c.OrdersPlaced, &c.OrdersCompleted, &c.OrdersCancelled, &c.PaymentsStarted, &c.PaymentsCompleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	c.Rates()
+	return &c, nil
+}
+
+// GetStockActivity retrieves the products with the most units reserved
+func (r *AnalyticsRepository) GetStockActivity(ctx context.Context, q analytics.Query, limit int) ([]*analytics.StockActivity, error) {
+	ctx, span := startSpan(ctx, "AnalyticsRepository.GetStockActivity")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT product_id, store_id, SUM(units_reserved), SUM(units_released), SUM(low_stock_events)
+		FROM analytics_stock_rollups
+		WHERE tenant_id = $6 AND granularity = $1 AND bucket >= $2 AND bucket < $3
+			AND ($4::uuid IS NULL OR store_id = $4)
+		GROUP BY product_id, store_id
+		ORDER BY SUM(units_reserved) DESC, product_id
+		LIMIT $5
+	`
+
+	rows, err := r.db.Query(ctx, query, string(q.Granularity()), q.From.UTC(), q.To.UTC(), q.StoreID, limit, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activity := []*analytics.StockActivity{}
+	for rows.Next() {
+		var a analytics.StockActivity
+		var storeID uuid.UUID
+		if err := rows.Scan(&a.ProductID, &storeID, &a.UnitsReserved, &a.UnitsReleased, &a.LowStockEvents); err != nil {
+			return nil, err
+		}
+		if storeID != uuid.Nil {
+			a.StoreID = &storeID
+		}
+		activity = append(activity, &a)
+	}
+	return activity, rows.Err()
+}
+
+// GetStaffDays retrieves a week's closed time entries per staff member and
+// day
+func (r *AnalyticsRepository) GetStaffDays(ctx context.Context, weekStart time.Time, storeID *uuid.UUID) ([]*analytics.StaffDay, error) {
+	ctx, span := startSpan(ctx, "AnalyticsRepository.GetStaffDays")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT staff_id, date_trunc('day', clock_in) AS day, COUNT(*),
+			SUM(worked_seconds), SUM(unpaid_break_seconds)
+		FROM analytics_time_entries
+		WHERE tenant_id = $4 AND closed
+			AND clock_in >= $1 AND clock_in < $2
+			AND ($3::uuid IS NULL OR store_id = $3)
+		GROUP BY staff_id, day
+		ORDER BY staff_id, day
+	`
+
+	weekStart = weekStart.UTC()
+	rows, err := r.db.Query(ctx, query, weekStart, weekStart.AddDate(0, 0, 7), storeID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []*analytics.StaffDay{}
+	for rows.Next() {
+		var d analytics.StaffDay
+		var worked, unpaidBreaks int64
+		if err := rows.Scan(&d.StaffID, &d.Day, &d.Entries, &worked, &unpaidBreaks); err != nil {
+			return nil, err
+		}
+		d.Worked = time.Duration(worked) * time.Second
+		d.UnpaidBreaks = time.Duration(unpaidBreaks) * time.Second
+		days = append(days, &d)
+	}
+	return days, rows.Err()
+}
+
+// Prune deletes expired hourly rollups and applied event records of every
+// tenant
+func (r *AnalyticsRepository) Prune(ctx context.Context, hourlyBefore, eventsBefore time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "AnalyticsRepository.Prune")
+	defer span.End()
+
+	query := `
+		WITH sales AS (
+			DELETE FROM analytics_sales_rollups WHERE granularity = 'hour' AND bucket < $1 RETURNING 1
+		), products AS (
+			DELETE FROM analytics_product_rollups WHERE granularity = 'hour' AND bucket < $1 RETURNING 1
+		), payments AS (
+			DELETE FROM analytics_payment_rollups WHERE granularity = 'hour' AND bucket < $1 RETURNING 1
+		), stock AS (
+			DELETE FROM analytics_stock_rollups WHERE granularity = 'hour' AND bucket < $1 RETURNING 1
+		), events AS (
+			DELETE FROM analytics_applied_events WHERE applied_at < $2 RETURNING 1
+		)
+		SELECT (SELECT COUNT(*) FROM sales) + (SELECT COUNT(*) FROM products)
+			+ (SELECT COUNT(*) FROM payments) + (SELECT COUNT(*) FROM stock)
+			+ (SELECT COUNT(*) FROM events)
+	`
+
+	var deleted int64
+	err := r.db.QueryRow(ctx, query, hourlyBefore.UTC(), eventsBefore.UTC()).Scan(&deleted)
+	return deleted, err
+}
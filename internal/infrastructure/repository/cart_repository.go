@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/onichange/pos-system/internal/domain/cart"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// cartUpdateAttempts bounds how often an update is retried against a cart
+// changing under it
+const cartUpdateAttempts = 5
+
+// CartRepository implements cart.Repository in Redis, one JSON value per
+// cart expiring ttl after its last change. Every key is scoped to the tenant
+// in ctx, and fails with tenant.ErrNoTenant when there is none.
+type CartRepository struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewCartRepository creates a new cart repository
+func NewCartRepository(client *redis.Client, ttl time.Duration) *CartRepository {
+	return &CartRepository{client: client, ttl: ttl}
+}
+
+// Create saves a new cart
+func (r *CartRepository) Create(ctx context.Context, c *cart.Cart) error {
+	ctx, span := startSpan(ctx, "CartRepository.Create")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+	c.TenantID = tenantID
+
+	now := time.Now()
+	c.CreatedAt = now
+	c.Version = 1
+	data, err := r.encode(c, now)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, cartKey(tenantID, c.ID), data, r.ttl).Err()
+}
+
+// GetByID returns a cart by ID
+func (r *CartRepository) GetByID(ctx context.Context, id uuid.UUID) (*cart.Cart, error) {
+	ctx, span := startSpan(ctx, "CartRepository.GetByID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return decodeCart(r.client.Get(ctx, cartKey(tenantID, id)).Bytes())
+}
+
+// Update applies fn to a cart and saves it, restarting its time to live.
+// The cart is watched from read to write, so a change in between fails the
+// write and fn is applied again.
+func (r *CartRepository) Update(ctx context.Context, id uuid.UUID, fn func(*cart.Cart) error) (*cart.Cart, error) {
+	ctx, span := startSpan(ctx, "CartRepository.Update")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := cartKey(tenantID, id)
+
+	for attempt := 0; attempt < cartUpdateAttempts; attempt++ {
+		var updated *cart.Cart
+		err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+			c, err := decodeCart(tx.Get(ctx, key).Bytes())
+			if err != nil {
+				return err
+			}
+			if err := fn(c); err != nil {
+				return err
+			}
+
+			c.Version++
+			data, err := r.encode(c, time.Now())
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, data, r.ttl)
+				return nil
+			})
+			updated = c
+			return err
+		}, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return updated, nil
+	}
+	return nil, cart.ErrConflict
+}
+
+// Delete removes a cart
+func (r *CartRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startSpan(ctx, "CartRepository.Delete")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+	removed, err := r.client.Del(ctx, cartKey(tenantID, id)).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return cart.ErrCartNotFound
+	}
+	return nil
+}
+
+// encode stamps a cart as changed at now and encodes it
+func (r *CartRepository) encode(c *cart.Cart, now time.Time) ([]byte, error) {
+	c.UpdatedAt = now
+	c.ExpiresAt = now.Add(r.ttl)
+	return json.Marshal(c)
+}
+
+// decodeCart decodes a cart read from Redis
+func decodeCart(data []byte, err error) (*cart.Cart, error) {
+	if errors.Is(err, redis.Nil) {
+		return nil, cart.ErrCartNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var c cart.Cart
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to decode cart: %w", err)
+	}
+	return &c, nil
+}
+
+// cartKey is the Redis key of a cart in a tenant
+func cartKey(tenantID string, id uuid.UUID) string {
+	return "cart:" + tenantID + ":" + id.String()
+}
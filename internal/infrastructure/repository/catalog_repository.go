@@ -0,0 +1,754 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/onichange/pos-system/internal/domain/catalog"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// CatalogRepository implements catalog.Repository. Every query is scoped to
+// the tenant in ctx and fails with tenant.ErrNoTenant when there is none;
+// slugs, SKUs and barcodes are unique within a tenant.
+type CatalogRepository struct {
+	db database.Querier
+}
+
+// NewCatalogRepository creates a new catalog repository
+func NewCatalogRepository(db database.Querier) *CatalogRepository {
+	return &CatalogRepository{db: db}
+}
+
+// CreateCategory creates a new category
+func (r *CatalogRepository) CreateCategory(ctx context.Context, c *catalog.Category) error {
+	ctx, span := startSpan(ctx, "CatalogRepository.CreateCategory")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO categories (id, parent_id, name, slug, created_at, updated_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $5, $6)
+	`
+
+	now := time.Now()
+	_, err = r.db.Exec(ctx, query, c.ID, c.ParentID, c.Name, c.Slug, now, tenantID)
+	if err != nil {
+		return catalogError(err)
+	}
+	c.CreatedAt, c.UpdatedAt = now, now
+	return nil
+}
+
+// GetCategoryByID retrieves a category by ID
+func (r *CatalogRepository) GetCategoryByID(ctx context.Context, id uuid.UUID) (*catalog.Category, error) {
+	ctx, span := startSpan(ctx, "CatalogRepository.GetCategoryByID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, parent_id, name, slug, created_at, updated_at
+		FROM categories
+		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+	`
+	return scanCategory(r.db.QueryRow(ctx, query, id, tenantID))
+}
+
+// GetCategories retrieves all categories
+func (r *CatalogRepository) GetCategories(ctx context.Context) ([]*catalog.Category, error) {
+	ctx, span := startSpan(ctx, "CatalogRepository.GetCategories")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, parent_id, name, slug, created_at, updated_at
+		FROM categories
+		WHERE tenant_id = $1 AND deleted_at IS NULL
+		ORDER BY name
+	`
+
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*catalog.Category
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// UpdateCategory updates a category
+func (r *CatalogRepository) UpdateCategory(ctx context.Context, c *catalog.Category) error {
+	ctx, span := startSpan(ctx, "CatalogRepository.UpdateCategory")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE categories SET parent_id = $2, name = $3, slug = $4, updated_at = $5
+		WHERE id = $1 AND tenant_id = $6 AND deleted_at IS NULL
+	`
+	_, err = r.db.Exec(ctx, query, c.ID, c.ParentID, c.Name, c.Slug, time.Now(), tenantID)
+	return catalogError(err)
+}
+
+// DeleteCategory soft deletes a category. Its products and subcategories are
+// left without one.
+func (r *CatalogRepository) DeleteCategory(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startSpan(ctx, "CatalogRepository.DeleteCategory")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH detached_products AS (
+			UPDATE products SET category_id = NULL WHERE category_id = $1 AND tenant_id = $3
+		), detached_categories AS (
+			UPDATE categories SET parent_id = NULL WHERE parent_id = $1 AND tenant_id = $3
+		)
+		UPDATE categories SET deleted_at = $2, updated_at = $2 WHERE id = $1 AND tenant_id = $3
+	`
+	_, err = r.db.Exec(ctx, query, id, time.Now(), tenantID)
+	return err
+}
+
+// CreateProduct creates a product together with its variants
+func (r *CatalogRepository) CreateProduct(ctx context.Context, p *catalog.Product) error {
+	ctx, span := startSpan(ctx, "CatalogRepository.CreateProduct")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	imagesJSON, err := json.Marshal(imagesOrEmpty(p.Images))
+	if err != nil {
+		return err
+	}
+	variants := p.Variants
+	if variants == nil {
+		variants = []*catalog.Variant{}
+	}
+	variantsJSON, err := json.Marshal(variants)
+	if err != nil {
+		return err
+	}
+
+	// One statement, so a duplicate SKU leaves no product behind
+	query := `
+		WITH product AS (
+			INSERT INTO products (
+				id, category_id, name, description, brand, status, images, created_at, updated_at, tenant_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $10)
+		)
+		INSERT INTO product_variants (
+			id, product_id, sku, barcode, name, attributes, base_price, currency, created_at, updated_at, tenant_id
+		)
+		SELECT v.id, $1, v.sku, NULLIF(v.barcode, ''), v.name, COALESCE(v.attributes, '{}'),
+			v.base_price, v.currency, $8, $8, $10
+		FROM jsonb_to_recordset($9::jsonb) AS v(
+			id UUID, sku TEXT, barcode TEXT, name TEXT, attributes JSONB, base_price NUMERIC, currency TEXT
+		)
+	`
+
+	now := time.Now()
+	_, err = r.db.Exec(ctx, query,
+		p.ID, p.CategoryID, p.Name, p.Description, p.Brand, string(p.Status), imagesJSON, now,
+		variantsJSON, tenantID,
+	)
+	if err != nil {
+		return catalogError(err)
+	}
+
+	p.CreatedAt, p.UpdatedAt = now, now
+	for _, v := range p.Variants {
+		v.ProductID = p.ID
+		v.CreatedAt, v.UpdatedAt = now, now
+	}
+	return nil
+}
+
+// GetProductByID retrieves a product and its variants by ID
+func (r *CatalogRepository) GetProductByID(ctx context.Context, id uuid.UUID) (*catalog.Product, error) {
+	ctx, span := startSpan(ctx, "CatalogRepository.GetProductByID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, category_id, name, description, brand, status, images, created_at, updated_at
+		FROM products
+		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+	`
+
+	p, err := scanProduct(r.db.QueryRow(ctx, query, id, tenantID))
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadVariants(ctx, tenantID, []*catalog.Product{p}); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetProducts retrieves products with their variants, optionally of one
+// category, with pagination
+func (r *CatalogRepository) GetProducts(ctx context.Context, categoryID *uuid.UUID, limit, offset int) ([]*catalog.Product, error) {
+	ctx, span := startSpan(ctx, "CatalogRepository.GetProducts")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, category_id, name, description, brand, status, images, created_at, updated_at
+		FROM products
+		WHERE tenant_id = $4 AND deleted_at IS NULL AND ($1::uuid IS NULL OR category_id = $1)
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, categoryID, limit, offset, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*catalog.Product
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadVariants(ctx, tenantID, products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// loadVariants fills in the variants of products of a tenant
+func (r *CatalogRepository) loadVariants(ctx context.Context, tenantID string, products []*catalog.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*catalog.Product, len(products))
+	ids := make([]uuid.UUID, len(products))
+	for i, p := range products {
+		p.Variants = []*catalog.Variant{}
+		byID[p.ID] = p
+		ids[i] = p.ID
+	}
+
+	query := `
+		SELECT id, product_id, sku, barcode, name, attributes, base_price, currency, created_at, updated_at
+		FROM product_variants
+		WHERE product_id = ANY($1) AND tenant_id = $2 AND deleted_at IS NULL
+		ORDER BY sku
+	`
+
+	rows, err := r.db.Query(ctx, query, ids, tenantID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		v, err := scanVariant(rows)
+		if err != nil {
+			return err
+		}
+		byID[v.ProductID].Variants = append(byID[v.ProductID].Variants, v)
+	}
+	return rows.Err()
+}
+
+// UpdateProduct updates a product. Its variants are updated separately.
+func (r *CatalogRepository) UpdateProduct(ctx context.Context, p *catalog.Product) error {
+	ctx, span := startSpan(ctx, "CatalogRepository.UpdateProduct")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	imagesJSON, err := json.Marshal(imagesOrEmpty(p.Images))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE products SET
+			category_id = $2, name = $3, description = $4, brand = $5,
+			status = $6, images = $7, updated_at = $8
+		WHERE id = $1 AND tenant_id = $9 AND deleted_at IS NULL
+	`
+	_, err = r.db.Exec(ctx, query,
+		p.ID, p.CategoryID, p.Name, p.Description, p.Brand,
+		string(p.Status), imagesJSON, time.Now(), tenantID,
+	)
+	return err
+}
+
+// DeleteProduct soft deletes a product and its variants
+func (r *CatalogRepository) DeleteProduct(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startSpan(ctx, "CatalogRepository.DeleteProduct")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH deleted_variants AS (
+			UPDATE product_variants SET deleted_at = $2, updated_at = $2
+			WHERE product_id = $1 AND tenant_id = $3 AND deleted_at IS NULL
+		)
+		UPDATE products SET deleted_at = $2, updated_at = $2 WHERE id = $1 AND tenant_id = $3
+	`
+	_, err = r.db.Exec(ctx, query, id, time.Now(), tenantID)
+	return err
+}
+
+// CreateVariant adds a variant to an existing product
+func (r *CatalogRepository) CreateVariant(ctx context.Context, v *catalog.Variant) error {
+	ctx, span := startSpan(ctx, "CatalogRepository.CreateVariant")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	attributesJSON, err := json.Marshal(attributesOrEmpty(v.Attributes))
+	if err != nil {
+		return err
+	}
+
+	// Only products of the tenant take variants
+	query := `
+		INSERT INTO product_variants (
+			id, product_id, sku, barcode, name, attributes, base_price, currency, created_at, updated_at, tenant_id
+		)
+		SELECT $1, p.id, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $9, p.tenant_id
+		FROM products p
+		WHERE p.id = $2 AND p.tenant_id = $10
+	`
+
+	now := time.Now()
+	tag, err := r.db.Exec(ctx, query,
+		v.ID, v.ProductID, v.SKU, v.Barcode, v.Name, attributesJSON, v.BasePrice, v.Currency, now, tenantID,
+	)
+	if err != nil {
+		return catalogError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	v.CreatedAt, v.UpdatedAt = now, now
+	return nil
+}
+
+// GetVariantByID retrieves a variant by ID
+func (r *CatalogRepository) GetVariantByID(ctx context.Context, id uuid.UUID) (*catalog.Variant, error) {
+	ctx, span := startSpan(ctx, "CatalogRepository.GetVariantByID")
+	defer span.End()
+
+	return r.getVariant(ctx, "id", id)
+}
+
+// GetVariantBySKU retrieves a variant by SKU
+func (r *CatalogRepository) GetVariantBySKU(ctx context.Context, sku string) (*catalog.Variant, error) {
+	ctx, span := startSpan(ctx, "CatalogRepository.GetVariantBySKU")
+	defer span.End()
+
+	return r.getVariant(ctx, "sku", sku)
+}
+
+// GetVariantByBarcode retrieves a variant by barcode
+func (r *CatalogRepository) GetVariantByBarcode(ctx context.Context, barcode string) (*catalog.Variant, error) {
+	ctx, span := startSpan(ctx, "CatalogRepository.GetVariantByBarcode")
+	defer span.End()
+
+	return r.getVariant(ctx, "barcode", barcode)
+}
+
+// getVariant retrieves the variant of the tenant whose column equals value
+func (r *CatalogRepository) getVariant(ctx context.Context, column string, value any) (*catalog.Variant, error) {
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, product_id, sku, barcode, name, attributes, base_price, currency, created_at, updated_at
+		FROM product_variants
+		WHERE ` + column + ` = $1 AND tenant_id = $2 AND deleted_at IS NULL
+	`
+	return scanVariant(r.db.QueryRow(ctx, query, value, tenantID))
+}
+
+// UpdateVariant updates a variant
+func (r *CatalogRepository) UpdateVariant(ctx context.Context, v *catalog.Variant) error {
+	ctx, span := startSpan(ctx, "CatalogRepository.UpdateVariant")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	attributesJSON, err := json.Marshal(attributesOrEmpty(v.Attributes))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE product_variants SET
+			sku = $2, barcode = NULLIF($3, ''), name = $4, attributes = $5,
+			base_price = $6, currency = $7, updated_at = $8
+		WHERE id = $1 AND tenant_id = $9 AND deleted_at IS NULL
+	`
+	_, err = r.db.Exec(ctx, query,
+		v.ID, v.SKU, v.Barcode, v.Name, attributesJSON,
+		v.BasePrice, v.Currency, time.Now(), tenantID,
+	)
+	return catalogError(err)
+}
+
+// DeleteVariant soft deletes a variant and drops its store prices
+func (r *CatalogRepository) DeleteVariant(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startSpan(ctx, "CatalogRepository.DeleteVariant")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH deleted_prices AS (
+			DELETE FROM store_prices WHERE variant_id = $1 AND tenant_id = $3
+		)
+		UPDATE product_variants SET deleted_at = $2, updated_at = $2 WHERE id = $1 AND tenant_id = $3
+	`
+	_, err = r.db.Exec(ctx, query, id, time.Now(), tenantID)
+	return err
+}
+
+// SetPrice sets a variant's price in a store's price list
+func (r *CatalogRepository) SetPrice(ctx context.Context, p *catalog.Price) error {
+	ctx, span := startSpan(ctx, "CatalogRepository.SetPrice")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Only variants of the tenant are priced, and only its prices replaced
+	query := `
+		INSERT INTO store_prices (store_id, variant_id, price, currency, updated_at, tenant_id)
+		SELECT $1, v.id, $3, $4, $5, v.tenant_id
+		FROM product_variants v
+		WHERE v.id = $2 AND v.tenant_id = $6 AND v.deleted_at IS NULL
+		ON CONFLICT (store_id, variant_id) DO UPDATE SET
+			price = EXCLUDED.price,
+			currency = EXCLUDED.currency,
+			updated_at = EXCLUDED.updated_at
+		WHERE store_prices.tenant_id = EXCLUDED.tenant_id
+	`
+
+	now := time.Now()
+	tag, err := r.db.Exec(ctx, query, p.StoreID, p.VariantID, p.Price, p.Currency, now, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	p.UpdatedAt = now
+	return nil
+}
+
+// DeletePrice removes a variant from a store's price list, so it sells at
+// its base price there
+func (r *CatalogRepository) DeletePrice(ctx context.Context, storeID, variantID uuid.UUID) error {
+	ctx, span := startSpan(ctx, "CatalogRepository.DeletePrice")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM store_prices WHERE store_id = $1 AND variant_id = $2 AND tenant_id = $3`
+	_, err = r.db.Exec(ctx, query, storeID, variantID, tenantID)
+	return err
+}
+
+// GetPriceList retrieves a store's prices
+func (r *CatalogRepository) GetPriceList(ctx context.Context, storeID uuid.UUID) ([]*catalog.Price, error) {
+	ctx, span := startSpan(ctx, "CatalogRepository.GetPriceList")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT sp.store_id, sp.variant_id, sp.price, sp.currency, sp.updated_at
+		FROM store_prices sp
+		JOIN product_variants v ON v.id = sp.variant_id AND v.deleted_at IS NULL
+		WHERE sp.store_id = $1 AND sp.tenant_id = $2
+		ORDER BY v.sku
+	`
+
+	rows, err := r.db.Query(ctx, query, storeID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []*catalog.Price
+	for rows.Next() {
+		var p catalog.Price
+		if err := rows.Scan(&p.StoreID, &p.VariantID, &p.Price, &p.Currency, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		prices = append(prices, &p)
+	}
+	return prices, rows.Err()
+}
+
+// ResolvePrices prices variants for a store: at the store's price where it
+// has one, otherwise at the base price. Unknown variants are left out.
+func (r *CatalogRepository) ResolvePrices(ctx context.Context, storeID uuid.UUID, variantIDs []uuid.UUID) ([]*catalog.PricedVariant, error) {
+	ctx, span := startSpan(ctx, "CatalogRepository.ResolvePrices")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT v.id, v.product_id, p.category_id, v.sku, p.name, COALESCE(v.name, ''), p.status,
+			COALESCE(sp.price, v.base_price), COALESCE(sp.currency, v.currency), sp.price IS NOT NULL
+		FROM product_variants v
+		JOIN products p ON p.id = v.product_id AND p.deleted_at IS NULL
+		LEFT JOIN store_prices sp ON sp.variant_id = v.id AND sp.store_id = $1
+		WHERE v.id = ANY($2) AND v.tenant_id = $3 AND v.deleted_at IS NULL
+	`
+
+	rows, err := r.db.Query(ctx, query, storeID, variantIDs, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var priced []*catalog.PricedVariant
+	for rows.Next() {
+		pv, err := scanPricedVariant(rows)
+		if err != nil {
+			return nil, err
+		}
+		priced = append(priced, pv)
+	}
+	return priced, rows.Err()
+}
+
+// ExportStore calls fn with every variant at the store's prices. The
+// variants are read by one query, so they are as of one moment.
+func (r *CatalogRepository) ExportStore(ctx context.Context, storeID uuid.UUID, fn func(*catalog.PricedVariant) error) error {
+	ctx, span := startSpan(ctx, "CatalogRepository.ExportStore")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		SELECT v.id, v.product_id, p.category_id, v.sku, p.name, COALESCE(v.name, ''), p.status,
+			COALESCE(sp.price, v.base_price), COALESCE(sp.currency, v.currency), sp.price IS NOT NULL
+		FROM product_variants v
+		JOIN products p ON p.id = v.product_id AND p.deleted_at IS NULL
+		LEFT JOIN store_prices sp ON sp.variant_id = v.id AND sp.store_id = $1
+		WHERE v.tenant_id = $2 AND v.deleted_at IS NULL
+		ORDER BY v.sku
+	`
+
+	rows, err := r.db.Query(ctx, query, storeID, tenantID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		pv, err := scanPricedVariant(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(pv); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// uniqueViolation is the Postgres error code of a unique index violation
+const uniqueViolation = "23505"
+
+// catalogError reports unique index violations as catalog.ErrDuplicate
+func catalogError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return fmt.Errorf("%w: %s", catalog.ErrDuplicate, pgErr.ConstraintName)
+	}
+	return err
+}
+
+// scanPricedVariant scans a row into a PricedVariant
+func scanPricedVariant(rows interface {
+	Scan(dest ...interface{}) error
+}) (*catalog.PricedVariant, error) {
+	var pv catalog.PricedVariant
+	var productName, variantName, status string
+	err := rows.Scan(
+		&pv.VariantID, &pv.ProductID, &pv.CategoryID, &pv.SKU, &productName, &variantName, &status,
+		&pv.Price, &pv.Currency, &pv.StorePrice,
+	)
+	if err != nil {
+		return nil, err
+	}
+	pv.Name = catalog.DisplayName(productName, variantName)
+	pv.Status = catalog.ProductStatus(status)
+	return &pv, nil
+}
+
+// scanCategory scans a row into a Category
+func scanCategory(rows interface {
+	Scan(dest ...interface{}) error
+}) (*catalog.Category, error) {
+	var c catalog.Category
+	if err := rows.Scan(&c.ID, &c.ParentID, &c.Name, &c.Slug, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// scanProduct scans a row into a Product, without its variants
+func scanProduct(rows interface {
+	Scan(dest ...interface{}) error
+}) (*catalog.Product, error) {
+	var p catalog.Product
+	var description, brand sql.NullString
+	var status string
+	var imagesJSON []byte
+
+	err := rows.Scan(
+		&p.ID, &p.CategoryID, &p.Name, &description, &brand, &status, &imagesJSON,
+		&p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Description = description.String
+	p.Brand = brand.String
+	p.Status = catalog.ProductStatus(status)
+	if err := json.Unmarshal(imagesJSON, &p.Images); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// scanVariant scans a row into a Variant
+func scanVariant(rows interface {
+	Scan(dest ...interface{}) error
+}) (*catalog.Variant, error) {
+	var v catalog.Variant
+	var barcode, name sql.NullString
+	var attributesJSON []byte
+
+	err := rows.Scan(
+		&v.ID, &v.ProductID, &v.SKU, &barcode, &name, &attributesJSON,
+		&v.BasePrice, &v.Currency, &v.CreatedAt, &v.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	v.Barcode = barcode.String
+	v.Name = name.String
+	if err := json.Unmarshal(attributesJSON, &v.Attributes); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// imagesOrEmpty stores a product without images as an empty array
+func imagesOrEmpty(images []catalog.Image) []catalog.Image {
+	if images == nil {
+		return []catalog.Image{}
+	}
+	return images
+}
+
+// attributesOrEmpty stores a variant without attributes as an empty object
+func attributesOrEmpty(attributes map[string]string) map[string]string {
+	if attributes == nil {
+		return map[string]string{}
+	}
+	return attributes
+}
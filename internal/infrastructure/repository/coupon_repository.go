@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/onichange/pos-system/internal/domain/promotion"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// couponColumns are the columns scanCoupon reads
+const couponColumns = `
+	id, code, promotion_id, max_uses, max_uses_per_user, uses, active,
+	expires_at, created_at, updated_at
+`
+
+// CouponRepository implements promotion.CouponRepository. Every query is
+// scoped to the tenant in ctx and fails with tenant.ErrNoTenant when there is
+// none.
+type CouponRepository struct {
+	db database.Querier
+	tx TxBeginner
+}
+
+// NewCouponRepository creates a coupon repository reading through db and
+// redeeming coupons in transactions begun on tx
+func NewCouponRepository(db database.Querier, tx TxBeginner) *CouponRepository {
+	return &CouponRepository{db: db, tx: tx}
+}
+
+// CreateCoupon creates a new coupon
+func (r *CouponRepository) CreateCoupon(ctx context.Context, c *promotion.Coupon) error {
+	ctx, span := startSpan(ctx, "CouponRepository.CreateCoupon")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO coupons (
+			id, code, promotion_id, max_uses, max_uses_per_user, active,
+			expires_at, created_at, updated_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9)
+	`
+
+	now := time.Now()
+	_, err = r.db.Exec(ctx, query,
+		c.ID, c.Code, c.PromotionID, c.MaxUses, c.MaxUsesPerUser, c.Active,
+		c.ExpiresAt, now, tenantID,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return promotion.ErrCouponExists
+		}
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return promotion.ErrNotFound
+		}
+		return err
+	}
+	c.CreatedAt, c.UpdatedAt = now, now
+	return nil
+}
+
+// GetCoupon retrieves a coupon by ID
+func (r *CouponRepository) GetCoupon(ctx context.Context, id uuid.UUID) (*promotion.Coupon, error) {
+	ctx, span := startSpan(ctx, "CouponRepository.GetCoupon")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + couponColumns + ` FROM coupons WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL`
+	return scanCoupon(r.db.QueryRow(ctx, query, id, tenantID))
+}
+
+// GetCouponByCode retrieves a coupon by its code, in any case
+func (r *CouponRepository) GetCouponByCode(ctx context.Context, code string) (*promotion.Coupon, error) {
+	ctx, span := startSpan(ctx, "CouponRepository.GetCouponByCode")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + couponColumns + ` FROM coupons WHERE code = $1 AND tenant_id = $2 AND deleted_at IS NULL`
+	return scanCoupon(r.db.QueryRow(ctx, query, promotion.NormalizeCode(code), tenantID))
+}
+
+// ListCoupons retrieves the coupons of a promotion, newest first
+func (r *CouponRepository) ListCoupons(ctx context.Context, promotionID uuid.UUID, limit, offset int) ([]*promotion.Coupon, error) {
+	ctx, span := startSpan(ctx, "CouponRepository.ListCoupons")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + couponColumns + `
+		FROM coupons
+		WHERE promotion_id = $1 AND tenant_id = $4 AND deleted_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(ctx, query, promotionID, limit, offset, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var coupons []*promotion.Coupon
+	for rows.Next() {
+		c, err := scanCoupon(rows)
+		if err != nil {
+			return nil, err
+		}
+		coupons = append(coupons, c)
+	}
+	return coupons, rows.Err()
+}
+
+// UpdateCoupon updates the limits, expiry and state of a coupon
+func (r *CouponRepository) UpdateCoupon(ctx context.Context, c *promotion.Coupon) error {
+	ctx, span := startSpan(ctx, "CouponRepository.UpdateCoupon")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE coupons SET
+			max_uses = $2, max_uses_per_user = $3, active = $4, expires_at = $5, updated_at = $6
+		WHERE id = $1 AND tenant_id = $7 AND deleted_at IS NULL
+	`
+
+	now := time.Now()
+	tag, err := r.db.Exec(ctx, query,
+		c.ID, c.MaxUses, c.MaxUsesPerUser, c.Active, c.ExpiresAt, now, tenantID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return promotion.ErrCouponNotFound
+	}
+	c.UpdatedAt = now
+	return nil
+}
+
+// DeleteCoupon soft deletes a coupon, freeing its code
+func (r *CouponRepository) DeleteCoupon(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startSpan(ctx, "CouponRepository.DeleteCoupon")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE coupons SET deleted_at = $2, updated_at = $2 WHERE id = $1 AND tenant_id = $3 AND deleted_at IS NULL`
+	tag, err := r.db.Exec(ctx, query, id, time.Now(), tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return promotion.ErrCouponNotFound
+	}
+	return nil
+}
+
+// UserRedemptions counts the redemptions of a coupon by a customer
+func (r *CouponRepository) UserRedemptions(ctx context.Context, couponID, userID uuid.UUID) (int, error) {
+	ctx, span := startSpan(ctx, "CouponRepository.UserRedemptions")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `SELECT COUNT(*) FROM coupon_redemptions WHERE coupon_id = $1 AND user_id = $2 AND tenant_id = $3`
+	var count int
+	err = r.db.QueryRow(ctx, query, couponID, userID, tenantID).Scan(&count)
+	return count, err
+}
+
+// Redeemed reports whether an order redeemed a coupon
+func (r *CouponRepository) Redeemed(ctx context.Context, couponID, orderID uuid.UUID) (bool, error) {
+	ctx, span := startSpan(ctx, "CouponRepository.Redeemed")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	query := `SELECT EXISTS (SELECT 1 FROM coupon_redemptions WHERE coupon_id = $1 AND order_id = $2 AND tenant_id = $3)`
+	var redeemed bool
+	err = r.db.QueryRow(ctx, query, couponID, orderID, tenantID).Scan(&redeemed)
+	return redeemed, err
+}
+
+// Redeem counts a use of the coupon and records the order that used it.
+// Redemptions of a coupon by one user are serialized by an advisory lock
+// taken first, so each counts the user's redemptions committed before it;
+// the lock on the coupon's row taken by the update keeps all users together
+// within its total uses.
+func (r *CouponRepository) Redeem(ctx context.Context, rd *promotion.CouponRedemption) error {
+	ctx, span := startSpan(ctx, "CouponRepository.Redeem")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH claimed AS (
+			UPDATE coupons SET uses = uses + 1
+			WHERE id = $1 AND tenant_id = $5 AND deleted_at IS NULL
+				AND (max_uses = 0 OR uses < max_uses)
+				AND (max_uses_per_user = 0 OR max_uses_per_user > (
+					SELECT COUNT(*) FROM coupon_redemptions
+					WHERE coupon_id = $1 AND user_id = $3 AND tenant_id = $5
+				))
+			RETURNING id
+		)
+		INSERT INTO coupon_redemptions (coupon_id, order_id, user_id, redeemed_at, tenant_id)
+		SELECT id, $2, $3, $4, $5 FROM claimed
+	`
+
+	rd.RedeemedAt = time.Now()
+	err = r.inTx(ctx, func(tx pgx.Tx) error {
+		// The count of the user's redemptions is read by the statement after
+		// the lock, so it sees those committed while it waited
+		lock := `SELECT pg_advisory_xact_lock(hashtext('coupon_redemptions:' || $1 || ':' || $2))`
+		if _, err := tx.Exec(ctx, lock, rd.CouponID.String(), rd.UserID.String()); err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, query, rd.CouponID, rd.OrderID, rd.UserID, rd.RedeemedAt, tenantID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return promotion.ErrCouponExhausted
+		}
+		return nil
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return promotion.ErrCouponRedeemed
+	}
+	return err
+}
+
+// Release deletes the redemptions of an order and gives their uses back
+func (r *CouponRepository) Release(ctx context.Context, orderID uuid.UUID) error {
+	ctx, span := startSpan(ctx, "CouponRepository.Release")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH released AS (
+			DELETE FROM coupon_redemptions
+			WHERE order_id = $1 AND tenant_id = $2
+			RETURNING coupon_id
+		)
+		UPDATE coupons c SET uses = GREATEST(c.uses - 1, 0)
+		FROM released
+		WHERE c.id = released.coupon_id
+	`
+	_, err = r.db.Exec(ctx, query, orderID, tenantID)
+	return err
+}
+
+// scanCoupon scans a row into a Coupon
+func scanCoupon(row interface {
+	Scan(dest ...interface{}) error
+}) (*promotion.Coupon, error) {
+	var c promotion.Coupon
+	var expiresAt sql.NullTime
+
+	err := row.Scan(
+		&c.ID, &c.Code, &c.PromotionID, &c.MaxUses, &c.MaxUsesPerUser, &c.Uses, &c.Active,
+		&expiresAt, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, promotion.ErrCouponNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		c.ExpiresAt = &expiresAt.Time
+	}
+	return &c, nil
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds
+func (r *CouponRepository) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := r.tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/onichange/pos-system/internal/domain/store"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// deviceColumns are the columns scanDevice reads
+const deviceColumns = `
+	id, store_id, name, type, status, serial_number, pairing_expires_at, key_prefix,
+	app_version, last_ip, last_seen_at, paired_at, deactivated_at, deactivation_reason,
+	created_at, updated_at
+`
+
+// DeviceRepository implements store.DeviceRepository. Every query is scoped
+// to the tenant in ctx and fails with tenant.ErrNoTenant when there is none.
+// Pairing code expiry is computed by the database, so it agrees with NOW()
+// whatever the database's time zone.
+type DeviceRepository struct {
+	db database.Querier
+}
+
+// NewDeviceRepository creates a new device repository
+func NewDeviceRepository(db database.Querier) *DeviceRepository {
+	return &DeviceRepository{db: db}
+}
+
+// Create registers a pending device with the hash of its pairing code
+func (r *DeviceRepository) Create(ctx context.Context, d *store.Device, codeHash string, codeTTL time.Duration) error {
+	ctx, span := startSpan(ctx, "DeviceRepository.Create")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO devices (
+			id, store_id, name, type, status, serial_number,
+			pairing_code_hash, pairing_expires_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NOW() + make_interval(secs => $8), $9)
+		RETURNING ` + deviceColumns
+
+	created, err := scanDevice(r.db.QueryRow(ctx, query,
+		d.ID, d.StoreID, d.Name, string(d.Type), string(store.DevicePending), d.SerialNumber,
+		codeHash, codeTTL.Seconds(), tenantID,
+	))
+	if err != nil {
+		return err
+	}
+	*d = *created
+	return nil
+}
+
+// GetByID retrieves a device of a store
+func (r *DeviceRepository) GetByID(ctx context.Context, storeID, id uuid.UUID) (*store.Device, error) {
+	ctx, span := startSpan(ctx, "DeviceRepository.GetByID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + deviceColumns + ` FROM devices WHERE id = $1 AND store_id = $2 AND tenant_id = $3`
+	return deviceOrNotFound(scanDevice(r.db.QueryRow(ctx, query, id, storeID, tenantID)))
+}
+
+// GetByKey retrieves the device holding an API key, active or deactivated
+func (r *DeviceRepository) GetByKey(ctx context.Context, keyHash string) (*store.Device, error) {
+	ctx, span := startSpan(ctx, "DeviceRepository.GetByKey")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + deviceColumns + ` FROM devices WHERE key_hash = $1 AND tenant_id = $2`
+	return deviceOrNotFound(scanDevice(r.db.QueryRow(ctx, query, keyHash, tenantID)))
+}
+
+// ListByStore retrieves a store's devices, optionally only those with status
+func (r *DeviceRepository) ListByStore(ctx context.Context, storeID uuid.UUID, status store.DeviceStatus) ([]*store.Device, error) {
+	ctx, span := startSpan(ctx, "DeviceRepository.ListByStore")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + deviceColumns + `
+		FROM devices
+		WHERE store_id = $1 AND tenant_id = $2 AND ($3 = '' OR status = $3)
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, storeID, tenantID, string(status))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []*store.Device{}
+	for rows.Next() {
+		d, err := scanDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+// Pair exchanges an unexpired pairing code for an API key. The code is
+// cleared, so it pairs one device once.
+func (r *DeviceRepository) Pair(ctx context.Context, codeHash, keyHash, keyPrefix, serialNumber, appVersion string) (*store.Device, error) {
+	ctx, span := startSpan(ctx, "DeviceRepository.Pair")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE devices SET
+			status = $1,
+			key_hash = $2,
+			key_prefix = $3,
+			serial_number = CASE WHEN $4 = '' THEN serial_number ELSE $4 END,
+			app_version = $5,
+			pairing_code_hash = NULL,
+			pairing_expires_at = NULL,
+			paired_at = NOW()
+		WHERE pairing_code_hash = $6 AND tenant_id = $7
+			AND status = $8 AND pairing_expires_at > NOW()
+		RETURNING ` + deviceColumns
+
+	d, err := scanDevice(r.db.QueryRow(ctx, query,
+		string(store.DeviceActive), keyHash, keyPrefix, serialNumber, appVersion,
+		codeHash, tenantID, string(store.DevicePending),
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, store.ErrInvalidPairingCode
+	}
+	return d, err
+}
+
+// ResetPairing revokes a device's API key and issues it a new pairing code,
+// for re-pairing a replaced or reactivated device
+func (r *DeviceRepository) ResetPairing(ctx context.Context, storeID, id uuid.UUID, codeHash string, codeTTL time.Duration) (*store.Device, error) {
+	ctx, span := startSpan(ctx, "DeviceRepository.ResetPairing")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE devices SET
+			status = $1,
+			key_hash = NULL,
+			key_prefix = '',
+			pairing_code_hash = $2,
+			pairing_expires_at = NOW() + make_interval(secs => $3),
+			paired_at = NULL,
+			deactivated_at = NULL,
+			deactivation_reason = ''
+		WHERE id = $4 AND store_id = $5 AND tenant_id = $6
+		RETURNING ` + deviceColumns
+
+	return deviceOrNotFound(scanDevice(r.db.QueryRow(ctx, query,
+		string(store.DevicePending), codeHash, codeTTL.Seconds(), id, storeID, tenantID,
+	)))
+}
+
+// Deactivate locks a device out. Its key is kept, so the device is told it
+// was deactivated rather than that its key is unknown. Deactivating a
+// deactivated device keeps the original time and reason.
+func (r *DeviceRepository) Deactivate(ctx context.Context, storeID, id uuid.UUID, reason string) (*store.Device, error) {
+	ctx, span := startSpan(ctx, "DeviceRepository.Deactivate")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE devices SET
+			status = $1,
+			pairing_code_hash = NULL,
+			pairing_expires_at = NULL,
+			deactivated_at = COALESCE(deactivated_at, NOW()),
+			deactivation_reason = CASE WHEN status = $1 THEN deactivation_reason ELSE $2 END
+		WHERE id = $3 AND store_id = $4 AND tenant_id = $5
+		RETURNING ` + deviceColumns
+
+	return deviceOrNotFound(scanDevice(r.db.QueryRow(ctx, query,
+		string(store.DeviceDeactivated), reason, id, storeID, tenantID,
+	)))
+}
+
+// Heartbeat records that an active device checked in
+func (r *DeviceRepository) Heartbeat(ctx context.Context, id uuid.UUID, appVersion, ip string) error {
+	ctx, span := startSpan(ctx, "DeviceRepository.Heartbeat")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE devices SET
+			last_seen_at = NOW(),
+			last_ip = $1,
+			app_version = CASE WHEN $2 = '' THEN app_version ELSE $2 END
+		WHERE id = $3 AND tenant_id = $4 AND status = $5
+	`
+
+	tag, err := r.db.Exec(ctx, query, ip, appVersion, id, tenantID, string(store.DeviceActive))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return store.ErrDeviceNotFound
+	}
+	return nil
+}
+
+// deviceOrNotFound maps a missing row to store.ErrDeviceNotFound
+func deviceOrNotFound(d *store.Device, err error) (*store.Device, error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, store.ErrDeviceNotFound
+	}
+	return d, err
+}
+
+// scanDevice scans deviceColumns
+func scanDevice(row interface{ Scan(dest ...interface{}) error }) (*store.Device, error) {
+	var d store.Device
+	var deviceType, status string
+
+	err := row.Scan(
+		&d.ID, &d.StoreID, &d.Name, &deviceType, &status, &d.SerialNumber, &d.PairingExpiresAt, &d.KeyPrefix,
+		&d.AppVersion, &d.LastIP, &d.LastSeenAt, &d.PairedAt, &d.DeactivatedAt, &d.DeactivationReason,
+		&d.CreatedAt, &d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	d.Type = store.DeviceType(deviceType)
+	d.Status = store.DeviceStatus(status)
+	return &d, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/notification"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// DeviceTokenRepository implements notification.DeviceTokenRepository. Every
+// query is scoped to the tenant in ctx and fails with tenant.ErrNoTenant when
+// there is none.
+type DeviceTokenRepository struct {
+	db database.Querier
+}
+
+// NewDeviceTokenRepository creates a new device token repository
+func NewDeviceTokenRepository(db database.Querier) *DeviceTokenRepository {
+	return &DeviceTokenRepository{db: db}
+}
+
+// RegisterDeviceToken inserts a device token, or updates the one with the
+// same provider and token
+func (r *DeviceTokenRepository) RegisterDeviceToken(ctx context.Context, t *notification.DeviceToken) error {
+	ctx, span := startSpan(ctx, "DeviceTokenRepository.RegisterDeviceToken")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO device_tokens (
+			id, user_id, provider, token, device_name, app_version, tenant_id, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		ON CONFLICT (tenant_id, provider, token) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			device_name = EXCLUDED.device_name,
+			app_version = EXCLUDED.app_version,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at, updated_at
+	`
+
+	return r.db.QueryRow(ctx, query,
+		t.ID, t.UserID, t.Provider, t.Token, t.DeviceName, t.AppVersion, tenantID, time.Now().UTC(),
+	).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+}
+
+// ListDeviceTokens retrieves a user's device tokens
+func (r *DeviceTokenRepository) ListDeviceTokens(ctx context.Context, userID uuid.UUID) ([]*notification.DeviceToken, error) {
+	ctx, span := startSpan(ctx, "DeviceTokenRepository.ListDeviceTokens")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, user_id, provider, token, device_name, app_version, created_at, updated_at
+		FROM device_tokens
+		WHERE user_id = $1 AND tenant_id = $2
+		ORDER BY updated_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*notification.DeviceToken{}
+	for rows.Next() {
+		var t notification.DeviceToken
+		if err := rows.Scan(
+			&t.ID, &t.UserID, &t.Provider, &t.Token, &t.DeviceName, &t.AppVersion, &t.CreatedAt, &t.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &t)
+	}
+	return tokens, rows.Err()
+}
+
+// DeleteDeviceToken deletes a user's device token
+func (r *DeviceTokenRepository) DeleteDeviceToken(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
+	ctx, span := startSpan(ctx, "DeviceTokenRepository.DeleteDeviceToken")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM device_tokens WHERE id = $1 AND user_id = $2 AND tenant_id = $3`
+	tag, err := r.db.Exec(ctx, query, id, userID, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notification.ErrDeviceTokenNotFound
+	}
+	return nil
+}
+
+// DeleteDeviceTokens deletes device tokens by ID
+func (r *DeviceTokenRepository) DeleteDeviceTokens(ctx context.Context, ids []uuid.UUID) error {
+	ctx, span := startSpan(ctx, "DeviceTokenRepository.DeleteDeviceTokens")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM device_tokens WHERE id = ANY($1) AND tenant_id = $2`
+	_, err = r.db.Exec(ctx, query, ids, tenantID)
+	return err
+}
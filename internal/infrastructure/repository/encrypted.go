@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/encryption"
+)
+
+// sealedColumn is a text column encrypted with encryption.EncryptString, as
+// an encryption.SealedStore. Plaintext left from before the column was
+// encrypted is encrypted by the first re-encryption pass.
+type sealedColumn struct {
+	db     database.Querier
+	table  string
+	column string
+	aad    func(id string) []byte // As the repository encrypts with
+	where  string                 // Optional condition limiting the rows encrypted
+}
+
+// Name labels the store
+func (c sealedColumn) Name() string {
+	return c.table + "." + c.column
+}
+
+// ScanSealed returns a page of non-empty values in row ID order
+func (c sealedColumn) ScanSealed(ctx context.Context, after string, limit int) ([]encryption.SealedValue, error) {
+	where := "TRUE"
+	if c.where != "" {
+		where = c.where
+	}
+	query := fmt.Sprintf(`
+		SELECT id::text, %[2]s
+		FROM %[1]s
+		WHERE %[2]s IS NOT NULL AND %[2]s <> '' AND id::text > $1 AND (%[3]s)
+		ORDER BY id::text
+		LIMIT $2
+	`, c.table, c.column, where)
+
+	rows, err := c.db.Query(ctx, query, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []encryption.SealedValue
+	for rows.Next() {
+		var v encryption.SealedValue
+		if err := rows.Scan(&v.ID, &v.Value); err != nil {
+			return nil, err
+		}
+		v.AAD = c.aad(v.ID)
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// SwapSealed replaces a value unless it changed since it was read
+func (c sealedColumn) SwapSealed(ctx context.Context, id, old, new string) (bool, error) {
+	query := fmt.Sprintf(`UPDATE %s SET %[2]s = $3 WHERE id = $1 AND %[2]s = $2`, c.table, c.column)
+	tag, err := c.db.Exec(ctx, query, id, old, new)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// sealedJSONFields is the encrypted fields of JSON object columns, as an
+// encryption.SealedStore. Each field is a value with the ID
+// row ID/column/key.
+type sealedJSONFields struct {
+	db      database.Querier
+	table   string
+	columns []string
+	keys    []string // JSON keys of the fields tagged encrypt:"pii"
+	aad     func(id string) []byte
+}
+
+// Name labels the store
+func (f sealedJSONFields) Name() string {
+	return f.table + "." + strings.Join(f.columns, ",")
+}
+
+// ScanSealed returns a page of non-empty fields in ID order
+func (f sealedJSONFields) ScanSealed(ctx context.Context, after string, limit int) ([]encryption.SealedValue, error) {
+	documents := make([]string, len(f.columns))
+	for i, column := range f.columns {
+		documents[i] = fmt.Sprintf("('%[1]s', t.%[1]s)", column)
+	}
+	query := fmt.Sprintf(`
+		SELECT field_id, value FROM (
+			SELECT t.id::text || '/' || d.name || '/' || j.key AS field_id, j.value
+			FROM %s t
+			CROSS JOIN LATERAL (VALUES %s) AS d(name, doc)
+			CROSS JOIN LATERAL jsonb_each_text(CASE WHEN jsonb_typeof(d.doc) = 'object' THEN d.doc ELSE '{}' END) AS j
+			WHERE j.key = ANY($3) AND j.value <> ''
+		) fields
+		WHERE field_id > $1
+		ORDER BY field_id
+		LIMIT $2
+	`, f.table, strings.Join(documents, ", "))
+
+	rows, err := f.db.Query(ctx, query, after, limit, f.keys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []encryption.SealedValue
+	for rows.Next() {
+		var v encryption.SealedValue
+		if err := rows.Scan(&v.ID, &v.Value); err != nil {
+			return nil, err
+		}
+		id, _, _ := strings.Cut(v.ID, "/")
+		v.AAD = f.aad(id)
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// SwapSealed replaces a field unless it changed since it was read
+func (f sealedJSONFields) SwapSealed(ctx context.Context, fieldID, old, new string) (bool, error) {
+	parts := strings.SplitN(fieldID, "/", 3)
+	if len(parts) != 3 {
+		return false, fmt.Errorf("invalid field ID %q", fieldID)
+	}
+	id, column, key := parts[0], parts[1], parts[2]
+	if !slices.Contains(f.columns, column) {
+		return false, fmt.Errorf("unknown column %q", column)
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET %[2]s = jsonb_set(%[2]s, ARRAY[$2::text], to_jsonb($4::text))
+		WHERE id = $1 AND %[2]s->>$2 = $3
+	`, f.table, column)
+	tag, err := f.db.Exec(ctx, query, id, key, old, new)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
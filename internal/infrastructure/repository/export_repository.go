@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/onichange/pos-system/internal/domain/store"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// exportColumns are the columns scanExport reads
+const exportColumns = `
+	id, tenant_id, store_id, status, records, size, checksum, error,
+	created_at, started_at, completed_at, expires_at
+`
+
+// exportReturning is exportColumns qualified for Claim's UPDATE ... FROM
+const exportReturning = `
+	e.id, e.tenant_id, e.store_id, e.status, e.records, e.size, e.checksum, e.error,
+	e.created_at, e.started_at, e.completed_at, e.expires_at
+`
+
+// ExportRepository implements store.ExportRepository. Queries made for a
+// request are scoped to the tenant in ctx and fail with tenant.ErrNoTenant
+// when there is none; Claim and DeleteExpired serve every tenant.
+type ExportRepository struct {
+	db database.Querier
+}
+
+// NewExportRepository creates a new export repository
+func NewExportRepository(db database.Querier) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+// Create records a pending export
+func (r *ExportRepository) Create(ctx context.Context, e *store.Export) error {
+	ctx, span := startSpan(ctx, "ExportRepository.Create")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO store_exports (id, store_id, status, tenant_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + exportColumns
+
+	created, err := scanExport(r.db.QueryRow(ctx, query, e.ID, e.StoreID, string(store.ExportPending), tenantID))
+	if err != nil {
+		return err
+	}
+	*e = *created
+	return nil
+}
+
+// GetByID retrieves an export of a store
+func (r *ExportRepository) GetByID(ctx context.Context, storeID, id uuid.UUID) (*store.Export, error) {
+	ctx, span := startSpan(ctx, "ExportRepository.GetByID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + exportColumns + ` FROM store_exports WHERE id = $1 AND store_id = $2 AND tenant_id = $3`
+	return exportOrNotFound(scanExport(r.db.QueryRow(ctx, query, id, storeID, tenantID)))
+}
+
+// ListByStore retrieves a store's most recent exports, newest first
+func (r *ExportRepository) ListByStore(ctx context.Context, storeID uuid.UUID, limit int) ([]*store.Export, error) {
+	ctx, span := startSpan(ctx, "ExportRepository.ListByStore")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + exportColumns + `
+		FROM store_exports
+		WHERE store_id = $1 AND tenant_id = $2
+		ORDER BY created_at DESC
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(ctx, query, storeID, tenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := []*store.Export{}
+	for rows.Next() {
+		e, err := scanExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+	return exports, rows.Err()
+}
+
+// Claim marks the oldest pending export of any tenant, or one whose build
+// has run for longer than timeout, running. Instances claiming at once get
+// different exports.
+func (r *ExportRepository) Claim(ctx context.Context, timeout time.Duration) (*store.Export, error) {
+	ctx, span := startSpan(ctx, "ExportRepository.Claim")
+	defer span.End()
+
+	query := `
+		WITH next AS (
+			SELECT id FROM store_exports
+			WHERE status = $1 OR (status = $2 AND started_at < NOW() - make_interval(secs => $3))
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE store_exports e SET status = $2, started_at = NOW()
+		FROM next
+		WHERE e.id = next.id
+		RETURNING ` + exportReturning
+
+	e, err := scanExport(r.db.QueryRow(ctx, query,
+		string(store.ExportPending), string(store.ExportRunning), timeout.Seconds(),
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return e, err
+}
+
+// Complete records that a claimed export was built, with its records,
+// size and checksum
+func (r *ExportRepository) Complete(ctx context.Context, e *store.Export, ttl time.Duration) error {
+	ctx, span := startSpan(ctx, "ExportRepository.Complete")
+	defer span.End()
+
+	records, err := json.Marshal(e.Records)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE store_exports SET
+			status = $1,
+			records = $2,
+			size = $3,
+			checksum = $4,
+			completed_at = NOW(),
+			expires_at = NOW() + make_interval(secs => $5)
+		WHERE id = $6 AND status = $7 AND started_at = $8
+		RETURNING ` + exportColumns
+
+	return r.finish(ctx, e, query,
+		string(store.ExportCompleted), records, e.Size, e.Checksum, ttl.Seconds(),
+		e.ID, string(store.ExportRunning), e.StartedAt,
+	)
+}
+
+// Fail records that building a claimed export failed
+func (r *ExportRepository) Fail(ctx context.Context, e *store.Export, reason string, ttl time.Duration) error {
+	ctx, span := startSpan(ctx, "ExportRepository.Fail")
+	defer span.End()
+
+	query := `
+		UPDATE store_exports SET
+			status = $1,
+			error = $2,
+			completed_at = NOW(),
+			expires_at = NOW() + make_interval(secs => $3)
+		WHERE id = $4 AND status = $5 AND started_at = $6
+		RETURNING ` + exportColumns
+
+	return r.finish(ctx, e, query,
+		string(store.ExportFailed), reason, ttl.Seconds(),
+		e.ID, string(store.ExportRunning), e.StartedAt,
+	)
+}
+
+// DeleteExpired deletes the exports of every tenant past their expiry
+func (r *ExportRepository) DeleteExpired(ctx context.Context) ([]*store.Export, error) {
+	ctx, span := startSpan(ctx, "ExportRepository.DeleteExpired")
+	defer span.End()
+
+	rows, err := r.db.Query(ctx, `DELETE FROM store_exports WHERE expires_at < NOW() RETURNING `+exportColumns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expired []*store.Export
+	for rows.Next() {
+		e, err := scanExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		expired = append(expired, e)
+	}
+	return expired, rows.Err()
+}
+
+// finish runs an update finishing a claimed export and reads it back into e
+func (r *ExportRepository) finish(ctx context.Context, e *store.Export, query string, args ...any) error {
+	finished, err := exportOrNotFound(scanExport(r.db.QueryRow(ctx, query, args...)))
+	if err != nil {
+		return err
+	}
+	*e = *finished
+	return nil
+}
+
+// exportOrNotFound maps a missing row to store.ErrExportNotFound
+func exportOrNotFound(e *store.Export, err error) (*store.Export, error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, store.ErrExportNotFound
+	}
+	return e, err
+}
+
+// scanExport scans exportColumns
+func scanExport(row interface{ Scan(dest ...interface{}) error }) (*store.Export, error) {
+	var e store.Export
+	var status string
+	var records []byte
+
+	err := row.Scan(
+		&e.ID, &e.TenantID, &e.StoreID, &status, &records, &e.Size, &e.Checksum, &e.Error,
+		&e.CreatedAt, &e.StartedAt, &e.CompletedAt, &e.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	e.Status = store.ExportStatus(status)
+	if err := json.Unmarshal(records, &e.Records); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
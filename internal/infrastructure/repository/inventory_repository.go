@@ -3,39 +3,54 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/pkg/archive"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/tenant"
 )
 
-// InventoryRepository implements inventory.Repository
+// InventoryRepository implements inventory.Repository. Every query is scoped
+// to the tenant in ctx and fails with tenant.ErrNoTenant when there is none.
 type InventoryRepository struct {
-	db *pgxpool.Pool
+	db database.Querier
 }
 
 // NewInventoryRepository creates a new inventory repository
-func NewInventoryRepository(db *pgxpool.Pool) *InventoryRepository {
+func NewInventoryRepository(db database.Querier) *InventoryRepository {
 	return &InventoryRepository{db: db}
 }
 
 // Create creates a new inventory record
 func (r *InventoryRepository) Create(ctx context.Context, inv *inventory.Inventory) error {
+	ctx, span := startSpan(ctx, "InventoryRepository.Create")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO inventory (
 			id, product_id, store_id, quantity, reserved_quantity,
 			reorder_point, reorder_quantity, cost_price, selling_price,
-			version, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			version, created_at, updated_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
 	now := time.Now()
-	_, err := r.db.Exec(ctx, query,
+	_, err = r.db.Exec(ctx, query,
 		inv.ID, inv.ProductID, inv.StoreID, inv.Quantity, inv.ReservedQuantity,
 		inv.ReorderPoint, inv.ReorderQuantity, inv.CostPrice, inv.SellingPrice,
-		inv.Version, now, now,
+		inv.Version, now, now, tenantID,
 	)
 
 	return err
@@ -43,18 +58,26 @@ func (r *InventoryRepository) Create(ctx context.Context, inv *inventory.Invento
 
 // GetByID retrieves inventory by ID
 func (r *InventoryRepository) GetByID(ctx context.Context, id uuid.UUID) (*inventory.Inventory, error) {
+	ctx, span := startSpan(ctx, "InventoryRepository.GetByID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, product_id, store_id, quantity, reserved_quantity,
 			available_quantity, reorder_point, reorder_quantity,
 			cost_price, selling_price, version, created_at, updated_at
 		FROM inventory
-		WHERE id = $1
+		WHERE id = $1 AND tenant_id = $2
 	`
 
 	var inv inventory.Inventory
 	var storeID sql.NullString
 
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err = r.db.QueryRow(ctx, query, id, tenantID).Scan(
 		&inv.ID, &inv.ProductID, &storeID, &inv.Quantity, &inv.ReservedQuantity,
 		&inv.AvailableQuantity, &inv.ReorderPoint, &inv.ReorderQuantity,
 		&inv.CostPrice, &inv.SellingPrice, &inv.Version, &inv.CreatedAt, &inv.UpdatedAt,
@@ -73,6 +96,14 @@ func (r *InventoryRepository) GetByID(ctx context.Context, id uuid.UUID) (*inven
 
 // GetByProductID retrieves inventory by product ID and optional store ID
 func (r *InventoryRepository) GetByProductID(ctx context.Context, productID uuid.UUID, storeID *uuid.UUID) (*inventory.Inventory, error) {
+	ctx, span := startSpan(ctx, "InventoryRepository.GetByProductID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var query string
 	var args []interface{}
 
@@ -82,24 +113,24 @@ func (r *InventoryRepository) GetByProductID(ctx context.Context, productID uuid
 				available_quantity, reorder_point, reorder_quantity,
 				cost_price, selling_price, version, created_at, updated_at
 			FROM inventory
-			WHERE product_id = $1 AND store_id = $2
+			WHERE product_id = $1 AND store_id = $2 AND tenant_id = $3
 		`
-		args = []interface{}{productID, storeID}
+		args = []interface{}{productID, storeID, tenantID}
 	} else {
 		query = `
 			SELECT id, product_id, store_id, quantity, reserved_quantity,
 				available_quantity, reorder_point, reorder_quantity,
 				cost_price, selling_price, version, created_at, updated_at
 			FROM inventory
-			WHERE product_id = $1 AND store_id IS NULL
+			WHERE product_id = $1 AND store_id IS NULL AND tenant_id = $2
 		`
-		args = []interface{}{productID}
+		args = []interface{}{productID, tenantID}
 	}
 
 	var inv inventory.Inventory
 	var storeIDVal sql.NullString
 
-	err := r.db.QueryRow(ctx, query, args...).Scan(
+	err = r.db.QueryRow(ctx, query, args...).Scan(
 		&inv.ID, &inv.ProductID, &storeIDVal, &inv.Quantity, &inv.ReservedQuantity,
 		&inv.AvailableQuantity, &inv.ReorderPoint, &inv.ReorderQuantity,
 		&inv.CostPrice, &inv.SellingPrice, &inv.Version, &inv.CreatedAt, &inv.UpdatedAt,
@@ -118,17 +149,25 @@ func (r *InventoryRepository) GetByProductID(ctx context.Context, productID uuid
 
 // GetByStoreID retrieves inventory by store ID
 func (r *InventoryRepository) GetByStoreID(ctx context.Context, storeID uuid.UUID, limit, offset int) ([]*inventory.Inventory, error) {
+	ctx, span := startSpan(ctx, "InventoryRepository.GetByStoreID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, product_id, store_id, quantity, reserved_quantity,
 			available_quantity, reorder_point, reorder_quantity,
 			cost_price, selling_price, version, created_at, updated_at
 		FROM inventory
-		WHERE store_id = $1
+		WHERE store_id = $1 AND tenant_id = $4
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := r.db.Query(ctx, query, storeID, limit, offset)
+	rows, err := r.db.Query(ctx, query, storeID, limit, offset, tenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -146,22 +185,68 @@ func (r *InventoryRepository) GetByStoreID(ctx context.Context, storeID uuid.UUI
 	return inventories, rows.Err()
 }
 
+// ExportStore calls fn with every stock level of the store. The levels are
+// read by one query, so they are as of one moment.
+func (r *InventoryRepository) ExportStore(ctx context.Context, storeID uuid.UUID, fn func(*inventory.Inventory) error) error {
+	ctx, span := startSpan(ctx, "InventoryRepository.ExportStore")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		SELECT id, product_id, store_id, quantity, reserved_quantity,
+			available_quantity, reorder_point, reorder_quantity,
+			cost_price, selling_price, version, created_at, updated_at
+		FROM inventory
+		WHERE store_id = $1 AND tenant_id = $2
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, storeID, tenantID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		inv, err := scanInventory(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(inv); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 // Update updates inventory
 func (r *InventoryRepository) Update(ctx context.Context, inv *inventory.Inventory) error {
+	ctx, span := startSpan(ctx, "InventoryRepository.Update")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE inventory SET
 			quantity = $2, reserved_quantity = $3,
 			reorder_point = $4, reorder_quantity = $5,
 			cost_price = $6, selling_price = $7,
 			version = $8, updated_at = $9
-		WHERE id = $1
+		WHERE id = $1 AND tenant_id = $10
 	`
 
-	_, err := r.db.Exec(ctx, query,
+	_, err = r.db.Exec(ctx, query,
 		inv.ID, inv.Quantity, inv.ReservedQuantity,
 		inv.ReorderPoint, inv.ReorderQuantity,
 		inv.CostPrice, inv.SellingPrice,
-		inv.Version, time.Now(),
+		inv.Version, time.Now(), tenantID,
 	)
 
 	return err
@@ -169,20 +254,28 @@ func (r *InventoryRepository) Update(ctx context.Context, inv *inventory.Invento
 
 // UpdateWithVersion updates inventory with optimistic locking
 func (r *InventoryRepository) UpdateWithVersion(ctx context.Context, inv *inventory.Inventory) error {
+	ctx, span := startSpan(ctx, "InventoryRepository.UpdateWithVersion")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE inventory SET
 			quantity = $2, reserved_quantity = $3,
 			reorder_point = $4, reorder_quantity = $5,
 			cost_price = $6, selling_price = $7,
 			version = version + 1, updated_at = $8
-		WHERE id = $1 AND version = $9
+		WHERE id = $1 AND version = $9 AND tenant_id = $10
 	`
 
 	result, err := r.db.Exec(ctx, query,
 		inv.ID, inv.Quantity, inv.ReservedQuantity,
 		inv.ReorderPoint, inv.ReorderQuantity,
 		inv.CostPrice, inv.SellingPrice,
-		time.Now(), inv.Version,
+		time.Now(), inv.Version, tenantID,
 	)
 
 	if err != nil {
@@ -190,6 +283,7 @@ func (r *InventoryRepository) UpdateWithVersion(ctx context.Context, inv *invent
 	}
 
 	if result.RowsAffected() == 0 {
+		logger.FromContext(ctx).Debugf("Inventory %s changed since version %d was read", inv.ID, inv.Version)
 		return inventory.ErrVersionConflict
 	}
 
@@ -198,21 +292,30 @@ func (r *InventoryRepository) UpdateWithVersion(ctx context.Context, inv *invent
 }
 
 // ReserveStock reserves stock with optimistic locking
-func (r *InventoryRepository) ReserveStock(ctx context.Context, productID uuid.UUID, storeID *uuid.UUID, quantity int) error {
+func (r *InventoryRepository) ReserveStock(ctx context.Context, productID uuid.UUID, storeID *uuid.UUID, quantity int) (*inventory.Inventory, error) {
+	ctx, span := startSpan(ctx, "InventoryRepository.ReserveStock")
+	defer span.End()
+
 	inv, err := r.GetByProductID(ctx, productID, storeID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := inv.Reserve(quantity); err != nil {
-		return err
+		return nil, err
 	}
 
-	return r.UpdateWithVersion(ctx, inv)
+	if err := r.UpdateWithVersion(ctx, inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
 }
 
 // ReleaseStock releases reserved stock
 func (r *InventoryRepository) ReleaseStock(ctx context.Context, productID uuid.UUID, storeID *uuid.UUID, quantity int) error {
+	ctx, span := startSpan(ctx, "InventoryRepository.ReleaseStock")
+	defer span.End()
+
 	inv, err := r.GetByProductID(ctx, productID, storeID)
 	if err != nil {
 		return err
@@ -222,27 +325,71 @@ func (r *InventoryRepository) ReleaseStock(ctx context.Context, productID uuid.U
 	return r.UpdateWithVersion(ctx, inv)
 }
 
+// insertMovementQuery records a stock movement
+const insertMovementQuery = `
+	INSERT INTO stock_movements (
+		id, inventory_id, movement_type, quantity,
+		previous_quantity, new_quantity, reason,
+		reference_id, reference_type, user_id, created_at, tenant_id
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+`
+
 // RecordMovement records a stock movement
 func (r *InventoryRepository) RecordMovement(ctx context.Context, movement *inventory.StockMovement) error {
-	query := `
-		INSERT INTO stock_movements (
-			id, inventory_id, movement_type, quantity,
-			previous_quantity, new_quantity, reason,
-			reference_id, reference_type, user_id, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-	`
+	ctx, span := startSpan(ctx, "InventoryRepository.RecordMovement")
+	defer span.End()
 
-	_, err := r.db.Exec(ctx, query,
-		movement.ID, movement.InventoryID, string(movement.MovementType), movement.Quantity,
-		movement.PreviousQuantity, movement.NewQuantity, movement.Reason,
-		movement.ReferenceID, movement.ReferenceType, movement.UserID, time.Now(),
-	)
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
 
+	_, err = r.db.Exec(ctx, insertMovementQuery, movementArgs(movement, tenantID, time.Now())...)
 	return err
 }
 
+// RecordMovements records stock movements in one round trip, all of them
+// or, when one fails, none
+func (r *InventoryRepository) RecordMovements(ctx context.Context, movements []*inventory.StockMovement) error {
+	ctx, span := startSpan(ctx, "InventoryRepository.RecordMovements")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+	if len(movements) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	batch := &pgx.Batch{}
+	for _, movement := range movements {
+		batch.Queue(insertMovementQuery, movementArgs(movement, tenantID, now)...)
+	}
+	return database.ExecBatch(ctx, r.db, batch)
+}
+
+// movementArgs returns the arguments of insertMovementQuery recording
+// movement at now
+func movementArgs(movement *inventory.StockMovement, tenantID string, now time.Time) []interface{} {
+	return []interface{}{
+		movement.ID, movement.InventoryID, string(movement.MovementType), movement.Quantity,
+		movement.PreviousQuantity, movement.NewQuantity, movement.Reason,
+		movement.ReferenceID, movement.ReferenceType, movement.UserID, now, tenantID,
+	}
+}
+
 // GetLowStockItems retrieves items below reorder point
 func (r *InventoryRepository) GetLowStockItems(ctx context.Context, storeID *uuid.UUID) ([]*inventory.Inventory, error) {
+	ctx, span := startSpan(ctx, "InventoryRepository.GetLowStockItems")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var query string
 	var args []interface{}
 
@@ -252,20 +399,20 @@ func (r *InventoryRepository) GetLowStockItems(ctx context.Context, storeID *uui
 				available_quantity, reorder_point, reorder_quantity,
 				cost_price, selling_price, version, created_at, updated_at
 			FROM inventory
-			WHERE store_id = $1 AND available_quantity <= reorder_point
+			WHERE store_id = $1 AND tenant_id = $2 AND available_quantity <= reorder_point
 			ORDER BY available_quantity ASC
 		`
-		args = []interface{}{storeID}
+		args = []interface{}{storeID, tenantID}
 	} else {
 		query = `
 			SELECT id, product_id, store_id, quantity, reserved_quantity,
 				available_quantity, reorder_point, reorder_quantity,
 				cost_price, selling_price, version, created_at, updated_at
 			FROM inventory
-			WHERE store_id IS NULL AND available_quantity <= reorder_point
+			WHERE store_id IS NULL AND tenant_id = $1 AND available_quantity <= reorder_point
 			ORDER BY available_quantity ASC
 		`
-		args = []interface{}{}
+		args = []interface{}{tenantID}
 	}
 
 	rows, err := r.db.Query(ctx, query, args...)
@@ -286,6 +433,43 @@ func (r *InventoryRepository) GetLowStockItems(ctx context.Context, storeID *uui
 	return inventories, rows.Err()
 }
 
+// ListLowStock retrieves the stock of every store at or below its reorder
+// point, by store
+func (r *InventoryRepository) ListLowStock(ctx context.Context) ([]*inventory.Inventory, error) {
+	ctx, span := startSpan(ctx, "InventoryRepository.ListLowStock")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, product_id, store_id, quantity, reserved_quantity,
+			available_quantity, reorder_point, reorder_quantity,
+			cost_price, selling_price, version, created_at, updated_at
+		FROM inventory
+		WHERE store_id IS NOT NULL AND tenant_id = $1 AND available_quantity <= reorder_point
+		ORDER BY store_id, available_quantity ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inventories := []*inventory.Inventory{}
+	for rows.Next() {
+		inv, err := scanInventory(rows)
+		if err != nil {
+			return nil, err
+		}
+		inventories = append(inventories, inv)
+	}
+	return inventories, rows.Err()
+}
+
 // scanInventory scans a row into an Inventory
 func scanInventory(rows interface {
 	Scan(dest ...interface{}) error
@@ -310,3 +494,193 @@ func scanInventory(rows interface {
 	return &inv, nil
 }
 
+
+// ReceiveStock adds received stock to a store's inventory, creating its
+// record if needed, in one statement: the on-hand quantity goes up, the cost
+// price becomes the average cost of the stock on hand, and a cost layer and
+// an "in" movement are recorded against the receipt's source
+func (r *InventoryRepository) ReceiveStock(ctx context.Context, receipt *inventory.StockReceipt) (*inventory.Inventory, error) {
+	ctx, span := startSpan(ctx, "InventoryRepository.ReceiveStock")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH fresh AS (
+			SELECT 1
+			WHERE NOT EXISTS (
+				SELECT 1 FROM inventory_cost_layers
+				WHERE tenant_id = $13 AND source_type = $5 AND source_id = $6
+			)
+		), stock AS (
+			INSERT INTO inventory (id, product_id, store_id, quantity, cost_price, created_at, updated_at, tenant_id)
+			SELECT $1, $2, $3, $4, ROUND($7::numeric, 2), $8, $8, $13 FROM fresh
+			ON CONFLICT (tenant_id, product_id, store_id) WHERE store_id IS NOT NULL DO UPDATE SET
+				quantity = inventory.quantity + EXCLUDED.quantity,
+				cost_price = ROUND(
+					(COALESCE(inventory.cost_price, $7) * GREATEST(inventory.quantity, 0) + $7 * EXCLUDED.quantity)
+					/ (GREATEST(inventory.quantity, 0) + EXCLUDED.quantity), 2),
+				updated_at = EXCLUDED.updated_at
+			RETURNING id, product_id, store_id, quantity, reserved_quantity,
+				available_quantity, reorder_point, reorder_quantity,
+				cost_price, selling_price, version, created_at, updated_at
+		), layer AS (
+			INSERT INTO inventory_cost_layers (inventory_id, quantity, unit_cost, source_type, source_id, received_at, tenant_id)
+			SELECT id, $4, $7, $5, $6, $8, $13 FROM stock
+		), movement AS (
+			INSERT INTO stock_movements (
+				id, inventory_id, movement_type, quantity,
+				previous_quantity, new_quantity, reason,
+				reference_id, reference_type, user_id, created_at, tenant_id
+			)
+			SELECT $9, id, $10, $4, quantity - $4, quantity, $11, $6, $5, $12, $8, $13 FROM stock
+		)
+		SELECT id, product_id, store_id, quantity, reserved_quantity,
+			available_quantity, reorder_point, reorder_quantity,
+			cost_price, selling_price, version, created_at, updated_at
+		FROM stock
+	`
+
+	receivedAt := receipt.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+	row := r.db.QueryRow(ctx, query,
+		uuid.New(), receipt.ProductID, receipt.StoreID, receipt.Quantity,
+		receipt.SourceType, receipt.SourceID, receipt.UnitCost, receivedAt.UTC(),
+		uuid.New(), string(inventory.MovementIn), receipt.Reason, receipt.UserID, tenantID,
+	)
+	inv, err := scanInventory(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, inventory.ErrAlreadyReceived
+	}
+	// The same source posted concurrently
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return nil, inventory.ErrAlreadyReceived
+	}
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// AdjustStock changes the stock on hand of an inventory record and records
+// the adjustment movement in one statement, provided the record is still at
+// the adjustment's version and keeps enough stock for its reservations
+func (r *InventoryRepository) AdjustStock(ctx context.Context, adj *inventory.Adjustment) (*inventory.Inventory, *inventory.StockMovement, error) {
+	ctx, span := startSpan(ctx, "InventoryRepository.AdjustStock")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := `
+		WITH stock AS (
+			UPDATE inventory SET
+				quantity = quantity + $2,
+				version = version + 1, updated_at = $3
+			WHERE id = $1 AND tenant_id = $4 AND version = $5
+				AND quantity + $2 >= reserved_quantity
+			RETURNING id, product_id, store_id, quantity, reserved_quantity,
+				available_quantity, reorder_point, reorder_quantity,
+				cost_price, selling_price, version, created_at, updated_at
+		), movement AS (
+			INSERT INTO stock_movements (
+				id, inventory_id, movement_type, quantity,
+				previous_quantity, new_quantity, reason,
+				user_id, created_at, tenant_id
+			)
+			SELECT $6, id, $7, $2, quantity - $2, quantity, $8, $9, $3, $4 FROM stock
+		)
+		SELECT id, product_id, store_id, quantity, reserved_quantity,
+			available_quantity, reorder_point, reorder_quantity,
+			cost_price, selling_price, version, created_at, updated_at
+		FROM stock
+	`
+
+	movement := &inventory.StockMovement{
+		ID:           uuid.New(),
+		InventoryID:  adj.InventoryID,
+		MovementType: inventory.MovementAdjustment,
+		Quantity:     adj.Change,
+		Reason:       adj.MovementReason(),
+		UserID:       adj.UserID,
+		CreatedAt:    time.Now().UTC(),
+	}
+	row := r.db.QueryRow(ctx, query,
+		adj.InventoryID, adj.Change, movement.CreatedAt, tenantID, adj.Version,
+		movement.ID, string(movement.MovementType), movement.Reason, movement.UserID,
+	)
+	inv, err := scanInventory(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Nothing was changed; find out why
+		current, err := r.GetByID(ctx, adj.InventoryID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, inventory.ErrInventoryNotFound
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if current.Version != adj.Version {
+			logger.FromContext(ctx).Debugf("Inventory %s changed since version %d was read", adj.InventoryID, adj.Version)
+			return nil, nil, inventory.ErrVersionConflict
+		}
+		return nil, nil, inventory.ErrInsufficientStock
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	movement.PreviousQuantity = inv.Quantity - adj.Change
+	movement.NewQuantity = inv.Quantity
+	return inv, movement, nil
+}
+
+// GetCostLayers retrieves the cost layers of an inventory record, oldest
+// first
+func (r *InventoryRepository) GetCostLayers(ctx context.Context, inventoryID uuid.UUID) ([]*inventory.CostLayer, error) {
+	ctx, span := startSpan(ctx, "InventoryRepository.GetCostLayers")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, inventory_id, quantity, unit_cost, source_type, source_id, received_at
+		FROM inventory_cost_layers
+		WHERE inventory_id = $1 AND tenant_id = $2
+		ORDER BY received_at, id
+	`
+
+	rows, err := r.db.Query(ctx, query, inventoryID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	layers := []*inventory.CostLayer{}
+	for rows.Next() {
+		var layer inventory.CostLayer
+		if err := rows.Scan(
+			&layer.ID, &layer.InventoryID, &layer.Quantity, &layer.UnitCost,
+			&layer.SourceType, &layer.SourceID, &layer.ReceivedAt,
+		); err != nil {
+			return nil, err
+		}
+		layers = append(layers, &layer)
+	}
+	return layers, rows.Err()
+}
+
+// ArchiveTables returns the tables whose old rows are archived: stock movements, by when they were recorded
+func (r *InventoryRepository) ArchiveTables() []archive.Table {
+	return []archive.Table{{Name: "stock_movements", TimeColumn: "created_at"}}
+}
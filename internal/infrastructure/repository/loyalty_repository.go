@@ -0,0 +1,386 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/onichange/pos-system/internal/domain/loyalty"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// LoyaltyRepository implements loyalty.Repository. Each change to a balance
+// is made in one transaction, so the ledger and the balance move together.
+// Every query but the expiry sweep is scoped to the tenant in ctx and fails
+// with tenant.ErrNoTenant when there is none.
+type LoyaltyRepository struct {
+	db database.Querier
+	tx TxBeginner
+}
+
+// NewLoyaltyRepository creates a loyalty repository reading through db and
+// earning and redeeming points in transactions begun on tx
+func NewLoyaltyRepository(db database.Querier, tx TxBeginner) *LoyaltyRepository {
+	return &LoyaltyRepository{db: db, tx: tx}
+}
+
+// GetAccount retrieves a member's account
+func (r *LoyaltyRepository) GetAccount(ctx context.Context, userID uuid.UUID, expiringBefore time.Time) (*loyalty.Account, error) {
+	ctx, span := startSpan(ctx, "LoyaltyRepository.GetAccount")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT a.user_id, a.balance, a.lifetime_points, a.created_at, a.updated_at,
+			COALESCE((
+				SELECT SUM(remaining) FROM loyalty_transactions
+				WHERE user_id = a.user_id AND tenant_id = a.tenant_id AND remaining > 0 AND expires_at <= $2
+			), 0),
+			(
+				SELECT MIN(expires_at) FROM loyalty_transactions
+				WHERE user_id = a.user_id AND tenant_id = a.tenant_id AND remaining > 0
+			)
+		FROM loyalty_accounts a
+		WHERE a.user_id = $1 AND a.tenant_id = $3
+	`
+
+	var a loyalty.Account
+	var nextExpiry sql.NullTime
+	err = r.db.QueryRow(ctx, query, userID, expiringBefore, tenantID).Scan(
+		&a.UserID, &a.Balance, &a.LifetimePoints, &a.CreatedAt, &a.UpdatedAt,
+		&a.ExpiringPoints, &nextExpiry,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &loyalty.Account{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Points taken back from a partly spent credit can leave more unspent
+	// credit than balance; the balance is what can expire
+	a.ExpiringPoints = min(a.ExpiringPoints, a.Balance)
+	if nextExpiry.Valid && a.Balance > 0 {
+		a.NextExpiryAt = &nextExpiry.Time
+	}
+	return &a, nil
+}
+
+// GetTransactions retrieves a member's ledger, newest first
+func (r *LoyaltyRepository) GetTransactions(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*loyalty.Transaction, error) {
+	ctx, span := startSpan(ctx, "LoyaltyRepository.GetTransactions")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, user_id, type, points, order_id, amount, COALESCE(currency, ''),
+			COALESCE(description, ''), expires_at, created_at
+		FROM loyalty_transactions
+		WHERE user_id = $1 AND tenant_id = $4
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, limit, offset, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*loyalty.Transaction
+	for rows.Next() {
+		tx, err := scanLoyaltyTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, tx)
+	}
+
+	return transactions, rows.Err()
+}
+
+// Earn credits the points of an earn transaction and opens the member's
+// account on their first points. It fails with loyalty.ErrOtherTenant,
+// crediting nothing, when the member's account belongs to another tenant.
+func (r *LoyaltyRepository) Earn(ctx context.Context, tx *loyalty.Transaction) (bool, error) {
+	ctx, span := startSpan(ctx, "LoyaltyRepository.Earn")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		WITH credit AS (
+			INSERT INTO loyalty_transactions (
+				id, user_id, type, points, remaining, order_id, amount, currency,
+				description, expires_at, created_at, tenant_id
+			) VALUES ($1, $2, 'earn', $3, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (tenant_id, order_id, type) WHERE order_id IS NOT NULL DO NOTHING
+			RETURNING user_id, points
+		), account AS (
+			INSERT INTO loyalty_accounts (user_id, balance, lifetime_points, created_at, updated_at, tenant_id)
+			SELECT user_id, points, points, $9, $9, $10 FROM credit
+			ON CONFLICT (user_id) DO UPDATE SET
+				balance = loyalty_accounts.balance + EXCLUDED.balance,
+				lifetime_points = loyalty_accounts.lifetime_points + EXCLUDED.lifetime_points
+			WHERE loyalty_accounts.tenant_id = EXCLUDED.tenant_id
+			RETURNING user_id
+		)
+		SELECT (SELECT COUNT(*) FROM credit), (SELECT COUNT(*) FROM account)
+	`
+
+	var credited bool
+	err = r.inTx(ctx, func(dbTx pgx.Tx) error {
+		var credits, accounts int
+		err := dbTx.QueryRow(ctx, query,
+			tx.ID, tx.UserID, tx.Points, tx.OrderID, tx.Amount, tx.Currency,
+			tx.Description, tx.ExpiresAt, tx.CreatedAt, tenantID,
+		).Scan(&credits, &accounts)
+		if err != nil {
+			return err
+		}
+		// The account is keyed by member alone, so a member of another
+		// tenant conflicts without being updated
+		if credits > accounts {
+			return loyalty.ErrOtherTenant
+		}
+		credited = credits > 0
+		return nil
+	})
+	return credited, err
+}
+
+// Redeem debits the points of a redemption, spending the credits that expire
+// first. The member's account is locked before its balance and credits are
+// read, so concurrent redemptions are debited one after the other.
+func (r *LoyaltyRepository) Redeem(ctx context.Context, rd *loyalty.Redemption) error {
+	ctx, span := startSpan(ctx, "LoyaltyRepository.Redeem")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH account AS (
+			UPDATE loyalty_accounts SET balance = balance - $3
+			WHERE user_id = $2 AND tenant_id = $8
+			RETURNING user_id
+		), credits AS (
+			SELECT id, SUM(remaining) OVER (ORDER BY expires_at NULLS LAST, created_at, id) - remaining AS spent_before
+			FROM loyalty_transactions
+			WHERE user_id = $2 AND tenant_id = $8 AND remaining > 0
+		), spent AS (
+			UPDATE loyalty_transactions t
+			SET remaining = t.remaining - LEAST(t.remaining, $3 - c.spent_before)
+			FROM credits c, account
+			WHERE t.id = c.id AND c.spent_before < $3
+		)
+		INSERT INTO loyalty_transactions (
+			id, user_id, type, points, order_id, amount, currency, description, created_at, tenant_id
+		)
+		SELECT $1::uuid, user_id, 'redeem', -$3, $4::uuid, $5::numeric, $6::varchar, 'Redeemed for order discount', $7::timestamp, $8
+		FROM account
+	`
+
+	err = r.inTx(ctx, func(tx pgx.Tx) error {
+		var balance int64
+		err := tx.QueryRow(ctx,
+			`SELECT balance FROM loyalty_accounts WHERE user_id = $1 AND tenant_id = $2 FOR UPDATE`,
+			rd.UserID, tenantID,
+		).Scan(&balance)
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && balance < rd.Points) {
+			return loyalty.ErrInsufficientPoints
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, query,
+			uuid.New(), rd.UserID, rd.Points, rd.OrderID, rd.Amount, rd.Currency, time.Now(), tenantID,
+		)
+		return err
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return loyalty.ErrAlreadyRedeemed
+	}
+	return err
+}
+
+// ReleaseRedemption credits back the points redeemed for an order. The
+// returned points expire at expiresAt.
+func (r *LoyaltyRepository) ReleaseRedemption(ctx context.Context, orderID uuid.UUID, expiresAt *time.Time) (bool, error) {
+	ctx, span := startSpan(ctx, "LoyaltyRepository.ReleaseRedemption")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		WITH credit AS (
+			INSERT INTO loyalty_transactions (
+				id, user_id, type, points, remaining, order_id, amount, currency,
+				description, expires_at, created_at, tenant_id
+			)
+			SELECT $1::uuid, user_id, 'release', -points, -points, order_id, amount, currency,
+				'Redemption returned', $3::timestamp, $4::timestamp, tenant_id
+			FROM loyalty_transactions
+			WHERE order_id = $2 AND tenant_id = $5 AND type = 'redeem'
+			ON CONFLICT (tenant_id, order_id, type) WHERE order_id IS NOT NULL DO NOTHING
+			RETURNING user_id, points
+		)
+		UPDATE loyalty_accounts a SET balance = a.balance + credit.points
+		FROM credit
+		WHERE a.user_id = credit.user_id AND a.tenant_id = $5
+	`
+
+	tag, err := r.db.Exec(ctx, query, uuid.New(), orderID, expiresAt, time.Now(), tenantID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReverseEarn debits the points earned on an order and removes them from the
+// member's lifetime points. Points already spent are not taken back.
+func (r *LoyaltyRepository) ReverseEarn(ctx context.Context, orderID uuid.UUID) (bool, error) {
+	ctx, span := startSpan(ctx, "LoyaltyRepository.ReverseEarn")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		WITH earned AS (
+			SELECT e.user_id, e.points, LEAST(e.points, a.balance) AS taken
+			FROM loyalty_transactions e
+			JOIN loyalty_accounts a ON a.user_id = e.user_id AND a.tenant_id = e.tenant_id
+			WHERE e.order_id = $2 AND e.tenant_id = $4 AND e.type = 'earn'
+		), debit AS (
+			INSERT INTO loyalty_transactions (id, user_id, type, points, order_id, description, created_at, tenant_id)
+			SELECT $1::uuid, user_id, 'reverse', -taken, $2, 'Order refunded', $3::timestamp, $4
+			FROM earned
+			ON CONFLICT (tenant_id, order_id, type) WHERE order_id IS NOT NULL DO NOTHING
+			RETURNING user_id
+		), credit AS (
+			UPDATE loyalty_transactions t SET remaining = 0
+			FROM debit
+			WHERE t.order_id = $2 AND t.tenant_id = $4 AND t.type = 'earn'
+		)
+		UPDATE loyalty_accounts a SET
+			balance = a.balance - earned.taken,
+			lifetime_points = GREATEST(a.lifetime_points - earned.points, 0)
+		FROM debit, earned
+		WHERE a.user_id = debit.user_id AND a.tenant_id = $4
+	`
+
+	tag, err := r.db.Exec(ctx, query, uuid.New(), orderID, time.Now(), tenantID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ExpirePoints zeroes the unspent points of credits due by now and debits
+// them from their members' balances, one expire transaction per member. It
+// sweeps every tenant; each debit belongs to its member's tenant.
+func (r *LoyaltyRepository) ExpirePoints(ctx context.Context, now time.Time, limit int) (int, int64, error) {
+	ctx, span := startSpan(ctx, "LoyaltyRepository.ExpirePoints")
+	defer span.End()
+
+	query := `
+		WITH due AS (
+			SELECT id, user_id, tenant_id, remaining
+			FROM loyalty_transactions
+			WHERE remaining > 0 AND expires_at <= $1
+			ORDER BY expires_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), credits AS (
+			UPDATE loyalty_transactions t SET remaining = 0
+			FROM due
+			WHERE t.id = due.id
+		), totals AS (
+			SELECT due.user_id, due.tenant_id, LEAST(SUM(due.remaining), MIN(a.balance)) AS points
+			FROM due
+			JOIN loyalty_accounts a ON a.user_id = due.user_id AND a.tenant_id = due.tenant_id
+			GROUP BY due.user_id, due.tenant_id
+		), debits AS (
+			INSERT INTO loyalty_transactions (id, user_id, type, points, description, created_at, tenant_id)
+			SELECT gen_random_uuid(), user_id, 'expire', -points, 'Points expired', $1, tenant_id
+			FROM totals
+			WHERE points > 0
+		), accounts AS (
+			UPDATE loyalty_accounts a SET balance = a.balance - totals.points
+			FROM totals
+			WHERE a.user_id = totals.user_id AND a.tenant_id = totals.tenant_id AND totals.points > 0
+		)
+		SELECT (SELECT COUNT(*) FROM due), COALESCE((SELECT SUM(points) FROM totals), 0)
+	`
+
+	var credits int
+	var points int64
+	if err := r.db.QueryRow(ctx, query, now, limit).Scan(&credits, &points); err != nil {
+		return 0, 0, err
+	}
+	return credits, points, nil
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds
+func (r *LoyaltyRepository) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := r.tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// scanLoyaltyTransaction scans a row into a Transaction
+func scanLoyaltyTransaction(rows interface {
+	Scan(dest ...interface{}) error
+}) (*loyalty.Transaction, error) {
+	var tx loyalty.Transaction
+	var txType string
+	var expiresAt sql.NullTime
+
+	err := rows.Scan(
+		&tx.ID, &tx.UserID, &txType, &tx.Points, &tx.OrderID, &tx.Amount, &tx.Currency,
+		&tx.Description, &expiresAt, &tx.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tx.Type = loyalty.TransactionType(txType)
+	if expiresAt.Valid {
+		tx.ExpiresAt = &expiresAt.Time
+	}
+	return &tx, nil
+}
@@ -7,28 +7,50 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/onichange/pos-system/internal/domain/notification"
+	"github.com/onichange/pos-system/pkg/archive"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
 )
 
-// NotificationRepository implements notification.Repository
+// NotificationRepository implements notification.Repository. Every query is
+// scoped to the tenant in ctx and fails with tenant.ErrNoTenant when there is
+// none.
 type NotificationRepository struct {
-	db *pgxpool.Pool
+	db database.Querier
 }
 
 // NewNotificationRepository creates a new notification repository
-func NewNotificationRepository(db *pgxpool.Pool) *NotificationRepository {
+func NewNotificationRepository(db database.Querier) *NotificationRepository {
 	return &NotificationRepository{db: db}
 }
 
-// Create creates a new notification
-func (r *NotificationRepository) Create(ctx context.Context, n *notification.Notification) error {
+// Create creates a new notification and queues its deliveries in one
+// statement. A notification created before is left as it is.
+func (r *NotificationRepository) Create(ctx context.Context, n *notification.Notification, deliver []notification.Channel) error {
+	ctx, span := startSpan(ctx, "NotificationRepository.Create")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO notifications (
-			id, user_id, type, title, message, data,
-			channels, priority, expires_at, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		WITH created AS (
+			INSERT INTO notifications (
+				id, user_id, type, title, message, data,
+				channels, priority, expires_at, created_at, tenant_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			ON CONFLICT (id) DO NOTHING
+			RETURNING id
+		)
+		INSERT INTO notification_deliveries (
+			notification_id, channel, status, next_attempt_at, tenant_id, created_at, updated_at
+		)
+		SELECT created.id, channel, 'pending', $13, $11, $13, $13
+		FROM created, unnest($12::text[]) AS channel
 	`
 
 	dataJSON, _ := json.Marshal(n.Data)
@@ -36,10 +58,17 @@ func (r *NotificationRepository) Create(ctx context.Context, n *notification.Not
 	for i, ch := range n.Channels {
 		channels[i] = string(ch)
 	}
+	delivered := make([]string, len(deliver))
+	for i, ch := range deliver {
+		delivered[i] = string(ch)
+	}
 
-	_, err := r.db.Exec(ctx, query,
+	now := time.Now()
+	n.CreatedAt = now
+	_, err = r.db.Exec(ctx, query,
 		n.ID, n.UserID, string(n.Type), n.Title, n.Message, dataJSON,
-		channels, string(n.Priority), n.ExpiresAt, time.Now(),
+		channels, string(n.Priority), n.ExpiresAt, now, tenantID,
+		delivered, now.UTC(),
 	)
 
 	return err
@@ -47,12 +76,20 @@ func (r *NotificationRepository) Create(ctx context.Context, n *notification.Not
 
 // GetByID retrieves a notification by ID
 func (r *NotificationRepository) GetByID(ctx context.Context, id uuid.UUID) (*notification.Notification, error) {
+	ctx, span := startSpan(ctx, "NotificationRepository.GetByID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, user_id, type, title, message, data,
 			is_read, read_at, channels, sent_at, priority,
 			expires_at, created_at
 		FROM notifications
-		WHERE id = $1
+		WHERE id = $1 AND tenant_id = $2
 	`
 
 	var n notification.Notification
@@ -61,7 +98,7 @@ func (r *NotificationRepository) GetByID(ctx context.Context, id uuid.UUID) (*no
 	var channels []string
 	var readAt, sentAt, expiresAt sql.NullTime
 
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err = r.db.QueryRow(ctx, query, id, tenantID).Scan(
 		&n.ID, &n.UserID, &typeStr, &n.Title, &n.Message, &dataJSON,
 		&n.IsRead, &readAt, &channels, &sentAt, &priorityStr,
 		&expiresAt, &n.CreatedAt,
@@ -98,6 +135,14 @@ func (r *NotificationRepository) GetByID(ctx context.Context, id uuid.UUID) (*no
 
 // GetByUserID retrieves notifications by user ID
 func (r *NotificationRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int, unreadOnly bool) ([]*notification.Notification, error) {
+	ctx, span := startSpan(ctx, "NotificationRepository.GetByUserID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var query string
 	if unreadOnly {
 		query = `
@@ -105,7 +150,7 @@ func (r *NotificationRepository) GetByUserID(ctx context.Context, userID uuid.UU
 				is_read, read_at, channels, sent_at, priority,
 				expires_at, created_at
 			FROM notifications
-			WHERE user_id = $1 AND is_read = FALSE
+			WHERE user_id = $1 AND tenant_id = $4 AND is_read = FALSE
 			ORDER BY created_at DESC
 			LIMIT $2 OFFSET $3
 		`
@@ -115,13 +160,13 @@ func (r *NotificationRepository) GetByUserID(ctx context.Context, userID uuid.UU
 				is_read, read_at, channels, sent_at, priority,
 				expires_at, created_at
 			FROM notifications
-			WHERE user_id = $1
+			WHERE user_id = $1 AND tenant_id = $4
 			ORDER BY created_at DESC
 			LIMIT $2 OFFSET $3
 		`
 	}
 
-	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	rows, err := r.db.Query(ctx, query, userID, limit, offset, tenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -141,60 +186,318 @@ func (r *NotificationRepository) GetByUserID(ctx context.Context, userID uuid.UU
 
 // MarkAsRead marks a notification as read
 func (r *NotificationRepository) MarkAsRead(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
+	ctx, span := startSpan(ctx, "NotificationRepository.MarkAsRead")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE notifications SET
 			is_read = TRUE,
 			read_at = $3
-		WHERE id = $1 AND user_id = $2
+		WHERE id = $1 AND user_id = $2 AND tenant_id = $4
 	`
 
-	_, err := r.db.Exec(ctx, query, id, userID, time.Now())
+	_, err = r.db.Exec(ctx, query, id, userID, time.Now(), tenantID)
 	return err
 }
 
 // MarkAllAsRead marks all user notifications as read
 func (r *NotificationRepository) MarkAllAsRead(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := startSpan(ctx, "NotificationRepository.MarkAllAsRead")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE notifications SET
 			is_read = TRUE,
 			read_at = $2
-		WHERE user_id = $1 AND is_read = FALSE
+		WHERE user_id = $1 AND tenant_id = $3 AND is_read = FALSE
 	`
 
-	_, err := r.db.Exec(ctx, query, userID, time.Now())
+	_, err = r.db.Exec(ctx, query, userID, time.Now(), tenantID)
 	return err
 }
 
 // Delete deletes a notification
 func (r *NotificationRepository) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
-	query := `DELETE FROM notifications WHERE id = $1 AND user_id = $2`
-	_, err := r.db.Exec(ctx, query, id, userID)
+	ctx, span := startSpan(ctx, "NotificationRepository.Delete")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM notifications WHERE id = $1 AND user_id = $2 AND tenant_id = $3`
+	_, err = r.db.Exec(ctx, query, id, userID, tenantID)
 	return err
 }
 
 // CountUnread counts unread notifications for a user
 func (r *NotificationRepository) CountUnread(ctx context.Context, userID uuid.UUID) (int, error) {
-	query := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND is_read = FALSE`
+	ctx, span := startSpan(ctx, "NotificationRepository.CountUnread")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND tenant_id = $2 AND is_read = FALSE`
+	var count int
+	err = r.db.QueryRow(ctx, query, userID, tenantID).Scan(&count)
+	return count, err
+}
+
+// ClaimDue hands due deliveries on channel of every tenant to the caller by
+// moving their next attempt past the lease. Each comes with its
+// notification, and the tenant to attempt it in.
+func (r *NotificationRepository) ClaimDue(ctx context.Context, channel notification.Channel, now time.Time, lease time.Duration, limit int) ([]*notification.DueDelivery, error) {
+	ctx, span := startSpan(ctx, "NotificationRepository.ClaimDue")
+	defer span.End()
+
+	query := `
+		WITH due AS (
+			SELECT id FROM notification_deliveries
+			WHERE channel = $1 AND status = 'pending' AND next_attempt_at <= $2
+			ORDER BY next_attempt_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE notification_deliveries d
+		SET next_attempt_at = $3
+		FROM due, notifications n
+		WHERE d.id = due.id AND n.id = d.notification_id
+		RETURNING ` + notificationDeliveryColumns + `, d.tenant_id,
+			n.id, n.user_id, n.type, n.title, n.message, n.data,
+			n.is_read, n.read_at, n.channels, n.sent_at, n.priority,
+			n.expires_at, n.created_at
+	`
+
+	now = now.UTC()
+	return r.claim(ctx, query, string(channel), now, now.Add(lease), limit)
+}
+
+// ClaimPending claims the pending deliveries of a notification due by now
+// like ClaimDue
+func (r *NotificationRepository) ClaimPending(ctx context.Context, notificationID uuid.UUID, now time.Time, lease time.Duration) ([]*notification.DueDelivery, error) {
+	ctx, span := startSpan(ctx, "NotificationRepository.ClaimPending")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH due AS (
+			SELECT id FROM notification_deliveries
+			WHERE notification_id = $1 AND status = 'pending' AND next_attempt_at <= $2 AND tenant_id = $4
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE notification_deliveries d
+		SET next_attempt_at = $3
+		FROM due, notifications n
+		WHERE d.id = due.id AND n.id = d.notification_id
+		RETURNING ` + notificationDeliveryColumns + `, d.tenant_id,
+			n.id, n.user_id, n.type, n.title, n.message, n.data,
+			n.is_read, n.read_at, n.channels, n.sent_at, n.priority,
+			n.expires_at, n.created_at
+	`
+
+	now = now.UTC()
+	return r.claim(ctx, query, notificationID, now, now.Add(lease), tenantID)
+}
+
+// claim runs a query claiming deliveries and scans them with their
+// notifications
+func (r *NotificationRepository) claim(ctx context.Context, query string, args ...interface{}) ([]*notification.DueDelivery, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := []*notification.DueDelivery{}
+	for rows.Next() {
+		var d notification.Delivery
+		var channelStr, statusStr, tenantID string
+		n, err := scanNotification(rows,
+			&d.ID, &d.NotificationID, &channelStr, &statusStr, &d.Recipient, &d.Attempts, &d.NextAttemptAt,
+			&d.LastAttemptAt, &d.LastError, &d.SentAt, &d.ProviderMessageID, &d.Receipt, &d.DeliveredAt,
+			&d.CreatedAt, &d.UpdatedAt, &tenantID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		d.Channel = notification.Channel(channelStr)
+		d.Status = notification.DeliveryStatus(statusStr)
+		due = append(due, &notification.DueDelivery{Delivery: &d, Notification: n, TenantID: tenantID})
+	}
+	return due, rows.Err()
+}
+
+// RecordAttempt saves a delivery's outcome, and marks its notification sent
+// with it, in one statement
+func (r *NotificationRepository) RecordAttempt(ctx context.Context, d *notification.Delivery) error {
+	ctx, span := startSpan(ctx, "NotificationRepository.RecordAttempt")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH delivery AS (
+			UPDATE notification_deliveries
+			SET status = $2, recipient = $3, attempts = $4, next_attempt_at = $5,
+				last_attempt_at = $6, last_error = $7, sent_at = $8, updated_at = $9,
+				provider_message_id = $11
+			WHERE id = $1 AND tenant_id = $10
+			RETURNING notification_id, sent_at
+		)
+		UPDATE notifications n SET sent_at = delivery.sent_at
+		FROM delivery
+		WHERE n.id = delivery.notification_id AND delivery.sent_at IS NOT NULL AND n.sent_at IS NULL
+	`
+
+	d.UpdatedAt = time.Now().UTC()
+	_, err = r.db.Exec(ctx, query,
+		d.ID, string(d.Status), d.Recipient, d.Attempts, d.NextAttemptAt,
+		d.LastAttemptAt, d.LastError, d.SentAt, d.UpdatedAt, tenantID,
+		d.ProviderMessageID,
+	)
+	return err
+}
+
+// ListDeliveries returns the deliveries of a notification, by channel
+func (r *NotificationRepository) ListDeliveries(ctx context.Context, notificationID uuid.UUID) ([]*notification.Delivery, error) {
+	ctx, span := startSpan(ctx, "NotificationRepository.ListDeliveries")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + notificationDeliveryColumns + `
+		FROM notification_deliveries d
+		WHERE d.notification_id = $1 AND d.tenant_id = $2
+		ORDER BY d.channel
+	`
+
+	rows, err := r.db.Query(ctx, query, notificationID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*notification.Delivery{}
+	for rows.Next() {
+		var d notification.Delivery
+		var channelStr, statusStr string
+		if err := rows.Scan(
+			&d.ID, &d.NotificationID, &channelStr, &statusStr, &d.Recipient, &d.Attempts, &d.NextAttemptAt,
+			&d.LastAttemptAt, &d.LastError, &d.SentAt, &d.ProviderMessageID, &d.Receipt, &d.DeliveredAt,
+			&d.CreatedAt, &d.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		d.Channel = notification.Channel(channelStr)
+		d.Status = notification.DeliveryStatus(statusStr)
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
+
+// CountSent counts the deliveries on channel sent to a user since since
+func (r *NotificationRepository) CountSent(ctx context.Context, channel notification.Channel, userID uuid.UUID, since time.Time) (int, error) {
+	ctx, span := startSpan(ctx, "NotificationRepository.CountSent")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		SELECT COUNT(*)
+		FROM notification_deliveries d
+		JOIN notifications n ON n.id = d.notification_id
+		WHERE d.channel = $1 AND d.sent_at >= $2 AND n.user_id = $3 AND d.tenant_id = $4
+	`
+
 	var count int
-	err := r.db.QueryRow(ctx, query, userID).Scan(&count)
+	err = r.db.QueryRow(ctx, query, string(channel), since.UTC(), userID, tenantID).Scan(&count)
 	return count, err
 }
 
-// scanNotification scans a row into a Notification
+// RecordReceipt saves a receipt onto the delivery sent as its message. A
+// receipt of a message not delivered fails the delivery with its error.
+func (r *NotificationRepository) RecordReceipt(ctx context.Context, receipt *notification.Receipt) error {
+	ctx, span := startSpan(ctx, "NotificationRepository.RecordReceipt")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE notification_deliveries
+		SET receipt = $3,
+			delivered_at = CASE WHEN $4 THEN $6 ELSE delivered_at END,
+			status = CASE WHEN $4 THEN status ELSE 'failed' END,
+			last_error = CASE WHEN $4 THEN last_error ELSE $5 END,
+			updated_at = $6
+		WHERE id = $1 AND provider_message_id = $2 AND tenant_id = $7
+	`
+
+	tag, err := r.db.Exec(ctx, query,
+		receipt.DeliveryID, receipt.ProviderMessageID, receipt.Status, receipt.Delivered, receipt.Error,
+		receipt.At.UTC(), tenantID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notification.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// notificationDeliveryColumns lists a delivery's columns, as qualified in
+// queries joining notifications
+const notificationDeliveryColumns = `d.id, d.notification_id, d.channel, d.status, d.recipient, d.attempts, d.next_attempt_at,
+	d.last_attempt_at, d.last_error, d.sent_at, d.provider_message_id, d.receipt, d.delivered_at,
+	d.created_at, d.updated_at`
+
+// scanNotification scans a row into a Notification, after scanning any
+// columns preceding the notification's into leading
 func scanNotification(rows interface {
 	Scan(dest ...interface{}) error
-}) (*notification.Notification, error) {
+}, leading ...interface{}) (*notification.Notification, error) {
 	var n notification.Notification
 	var typeStr, priorityStr string
 	var dataJSON []byte
 	var channels []string
 	var readAt, sentAt, expiresAt sql.NullTime
 
-	err := rows.Scan(
+	err := rows.Scan(append(leading,
 		&n.ID, &n.UserID, &typeStr, &n.Title, &n.Message, &dataJSON,
 		&n.IsRead, &readAt, &channels, &sentAt, &priorityStr,
 		&expiresAt, &n.CreatedAt,
-	)
+	)...)
 	if err != nil {
 		return nil, err
 	}
@@ -225,3 +528,7 @@ func scanNotification(rows interface {
 	return &n, nil
 }
 
+// ArchiveTables returns the tables whose old rows are archived: notifications, by when they were created
+func (r *NotificationRepository) ArchiveTables() []archive.Table {
+	return []archive.Table{{Name: "notifications", TimeColumn: "created_at"}}
+}
@@ -0,0 +1,496 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/pkg/archive"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/encryption"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// OrderStore is an order repository whose encrypted columns can be
+// re-encrypted and whose old orders can be archived, as both
+// OrderRepository and EventSourcedOrderRepository are
+type OrderStore interface {
+	order.Repository
+	EncryptedColumns() []encryption.SealedStore
+	ArchiveTables() []archive.Table
+}
+
+// TxBeginner starts database transactions, as *pgxpool.Pool does
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// EventSourcedOrderRepository implements order.Repository and order.History.
+// Every change of an order is appended to order_events and projected into the
+// orders table in the same transaction, so reads are served by the
+// projection exactly as by OrderRepository, while the events give the
+// order's full history and its state at any earlier time. Every
+// snapshotEvery events the order's state is snapshotted, bounding how many
+// events are replayed to rebuild it.
+//
+// Orders created before the store was switched to events get a created
+// event holding their state when they are first changed.
+type EventSourcedOrderRepository struct {
+	*OrderRepository // The projection, which serves reads
+	tx               TxBeginner
+	snapshotEvery    int
+}
+
+// NewEventSourcedOrderRepository creates an event-sourced order repository
+// reading through db and writing in transactions begun on tx
+func NewEventSourcedOrderRepository(db database.Querier, tx TxBeginner, envelope *encryption.Envelope, snapshotEvery int) *EventSourcedOrderRepository {
+	return &EventSourcedOrderRepository{
+		OrderRepository: NewOrderRepository(db, envelope),
+		tx:              tx,
+		snapshotEvery:   snapshotEvery,
+	}
+}
+
+// Create records an order's creation and projects it
+func (r *EventSourcedOrderRepository) Create(ctx context.Context, o *order.Order) error {
+	ctx, span := startSpan(ctx, "EventSourcedOrderRepository.Create")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.inTx(ctx, func(tx pgx.Tx) error {
+		if err := NewOrderRepository(tx, r.envelope).Create(ctx, o); err != nil {
+			return err
+		}
+		return r.append(ctx, tx, tenantID, o.ID, &order.Change{
+			Version:    1,
+			Type:       order.ChangeCreated,
+			Order:      o,
+			OccurredAt: o.UpdatedAt,
+		})
+	})
+}
+
+// CreateBatch records the creation of orders and projects them in one
+// transaction, the projections in one round trip
+func (r *EventSourcedOrderRepository) CreateBatch(ctx context.Context, orders []*order.Order) error {
+	ctx, span := startSpan(ctx, "EventSourcedOrderRepository.CreateBatch")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.inTx(ctx, func(tx pgx.Tx) error {
+		if err := NewOrderRepository(tx, r.envelope).CreateBatch(ctx, orders); err != nil {
+			return err
+		}
+		for _, o := range orders {
+			err := r.append(ctx, tx, tenantID, o.ID, &order.Change{
+				Version:    1,
+				Type:       order.ChangeCreated,
+				Order:      o,
+				OccurredAt: o.UpdatedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Update records an order's new state and projects it. Cancelled orders are
+// left unchanged.
+func (r *EventSourcedOrderRepository) Update(ctx context.Context, o *order.Order) error {
+	ctx, span := startSpan(ctx, "EventSourcedOrderRepository.Update")
+	defer span.End()
+
+	return r.change(ctx, o.ID, func(projection *OrderRepository) (*order.Change, error) {
+		if err := projection.Update(ctx, o); err != nil {
+			return nil, err
+		}
+		return &order.Change{Type: order.ChangeUpdated, Order: o}, nil
+	})
+}
+
+// UpdateStatus records an order's new status and projects it, with the
+// transition in its status history. Cancelled orders are left unchanged.
+func (r *EventSourcedOrderRepository) UpdateStatus(ctx context.Context, id uuid.UUID, t *order.StatusTransition) error {
+	ctx, span := startSpan(ctx, "EventSourcedOrderRepository.UpdateStatus")
+	defer span.End()
+
+	return r.change(ctx, id, func(projection *OrderRepository) (*order.Change, error) {
+		if err := projection.UpdateStatus(ctx, id, t); err != nil {
+			return nil, err
+		}
+		return &order.Change{Type: order.ChangeStatusChanged, Status: t.To}, nil
+	})
+}
+
+// Delete records an order's cancellation and projects it
+func (r *EventSourcedOrderRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startSpan(ctx, "EventSourcedOrderRepository.Delete")
+	defer span.End()
+
+	return r.change(ctx, id, func(projection *OrderRepository) (*order.Change, error) {
+		if err := projection.Delete(ctx, id); err != nil {
+			return nil, err
+		}
+		return &order.Change{Type: order.ChangeDeleted}, nil
+	})
+}
+
+// Changes returns an order's history, oldest first
+func (r *EventSourcedOrderRepository) Changes(ctx context.Context, id uuid.UUID) ([]*order.Change, error) {
+	ctx, span := startSpan(ctx, "EventSourcedOrderRepository.Changes")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := r.changes(ctx, r.db, tenantID, id, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, order.ErrOrderNotFound
+	}
+	return changes, nil
+}
+
+// GetAsOf returns an order as it was at a time, cancelled or not
+func (r *EventSourcedOrderRepository) GetAsOf(ctx context.Context, id uuid.UUID, at time.Time) (*order.Order, error) {
+	ctx, span := startSpan(ctx, "EventSourcedOrderRepository.GetAsOf")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	o, _, _, err := r.replay(ctx, r.db, tenantID, id, &at)
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		return nil, order.ErrOrderNotFound
+	}
+	return o, nil
+}
+
+// EncryptedColumns returns the stored address fields of the projection,
+// the events and the snapshots, for re-encryption under a rotated key
+func (r *EventSourcedOrderRepository) EncryptedColumns() []encryption.SealedStore {
+	columns := []string{"shipping_address", "billing_address"}
+	keys := []string{"street", "postal_code"}
+	return append(r.OrderRepository.EncryptedColumns(),
+		sealedJSONFields{db: r.db, table: "order_events", columns: columns, keys: keys, aad: orderEventAAD},
+		sealedJSONFields{db: r.db, table: "order_snapshots", columns: columns, keys: keys, aad: orderSnapshotAAD},
+	)
+}
+
+// change applies a change to a live order's projection with project and
+// appends the change it returns, in one transaction. The order's row is
+// locked, so concurrent changes get consecutive versions.
+func (r *EventSourcedOrderRepository) change(ctx context.Context, id uuid.UUID, project func(projection *OrderRepository) (*order.Change, error)) error {
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.inTx(ctx, func(tx pgx.Tx) error {
+		var live bool
+		err := tx.QueryRow(ctx,
+			`SELECT cancelled_at IS NULL FROM orders WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+			id, tenantID,
+		).Scan(&live)
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && !live) {
+			return nil // As with OrderRepository, missing and cancelled orders are not changed
+		}
+		if err != nil {
+			return err
+		}
+
+		var version int
+		err = tx.QueryRow(ctx,
+			`SELECT COALESCE(MAX(version), 0) FROM order_events WHERE order_id = $1 AND tenant_id = $2`,
+			id, tenantID,
+		).Scan(&version)
+		if err != nil {
+			return err
+		}
+
+		projection := NewOrderRepository(tx, r.envelope)
+
+		// An order stored before its history was recorded starts it with its state
+		if version == 0 {
+			current, err := projection.GetByID(ctx, id)
+			if err != nil {
+				return err
+			}
+			version = 1
+			err = r.append(ctx, tx, tenantID, id, &order.Change{
+				Version:    version,
+				Type:       order.ChangeCreated,
+				Order:      current,
+				OccurredAt: current.UpdatedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		c, err := project(projection)
+		if err != nil {
+			return err
+		}
+		c.Version = version + 1
+		c.OccurredAt = time.Now()
+		return r.append(ctx, tx, tenantID, id, c)
+	})
+}
+
+// statusData is the data of a status change
+type statusData struct {
+	Status order.OrderStatus `json:"status"`
+}
+
+// append inserts a change into order_events, and snapshots the order when
+// its version is a multiple of snapshotEvery
+func (r *EventSourcedOrderRepository) append(ctx context.Context, tx pgx.Tx, tenantID string, orderID uuid.UUID, c *order.Change) error {
+	eventID := uuid.New()
+
+	var data, shipping, billing []byte
+	var err error
+	switch {
+	case c.Order != nil:
+		data, shipping, billing, err = r.sealState(ctx, c.Order, orderEventAAD(eventID.String()))
+	case c.Type == order.ChangeStatusChanged:
+		data, err = json.Marshal(statusData{Status: c.Status})
+	default:
+		data = []byte(`{}`)
+	}
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO order_events (
+			id, order_id, tenant_id, version, type, data, shipping_address, billing_address, occurred_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err = tx.Exec(ctx, query,
+		eventID, orderID, tenantID, c.Version, string(c.Type), data, shipping, billing, c.OccurredAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if r.snapshotEvery > 0 && c.Version%r.snapshotEvery == 0 {
+		return r.snapshot(ctx, tx, tenantID, orderID)
+	}
+	return nil
+}
+
+// snapshot saves an order's current state in order_snapshots
+func (r *EventSourcedOrderRepository) snapshot(ctx context.Context, tx pgx.Tx, tenantID string, orderID uuid.UUID) error {
+	state, version, asOf, err := r.replay(ctx, tx, tenantID, orderID, nil)
+	if err != nil || state == nil {
+		return err
+	}
+
+	data, shipping, billing, err := r.sealState(ctx, state, orderSnapshotAAD(orderID.String()))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO order_snapshots (id, tenant_id, version, data, shipping_address, billing_address, as_of, taken_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (id) DO UPDATE SET
+			version = EXCLUDED.version,
+			data = EXCLUDED.data,
+			shipping_address = EXCLUDED.shipping_address,
+			billing_address = EXCLUDED.billing_address,
+			as_of = EXCLUDED.as_of,
+			taken_at = EXCLUDED.taken_at
+	`
+	_, err = tx.Exec(ctx, query, orderID, tenantID, version, data, shipping, billing, asOf)
+	return err
+}
+
+// replay rebuilds an order's state, at a time or else now, from its latest
+// usable snapshot and the events after it. It returns the state, nil if the
+// order did not exist, with the version and time of its last event.
+func (r *EventSourcedOrderRepository) replay(ctx context.Context, q database.Querier, tenantID string, orderID uuid.UUID, at *time.Time) (*order.Order, int, time.Time, error) {
+	var state *order.Order
+	var version int
+	var asOf time.Time
+
+	var data, shipping, billing []byte
+	err := q.QueryRow(ctx,
+		`SELECT version, data, shipping_address, billing_address, as_of FROM order_snapshots WHERE id = $1 AND tenant_id = $2`,
+		orderID, tenantID,
+	).Scan(&version, &data, &shipping, &billing, &asOf)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		version = 0
+	case err != nil:
+		return nil, 0, time.Time{}, err
+	case at != nil && asOf.After(*at):
+		version = 0 // Taken after the time asked for
+	default:
+		if state, err = r.openState(ctx, data, shipping, billing, orderSnapshotAAD(orderID.String())); err != nil {
+			return nil, 0, time.Time{}, err
+		}
+	}
+
+	changes, err := r.changes(ctx, q, tenantID, orderID, version, at)
+	if err != nil {
+		return nil, 0, time.Time{}, err
+	}
+	if len(changes) > 0 {
+		last := changes[len(changes)-1]
+		version, asOf = last.Version, last.OccurredAt
+	}
+	return order.Replay(state, changes), version, asOf, nil
+}
+
+// changes reads an order's events after a version, up to a time if at is set
+func (r *EventSourcedOrderRepository) changes(ctx context.Context, q database.Querier, tenantID string, orderID uuid.UUID, after int, at *time.Time) ([]*order.Change, error) {
+	query := `
+		SELECT id, version, type, data, shipping_address, billing_address, occurred_at
+		FROM order_events
+		WHERE order_id = $1 AND tenant_id = $2 AND version > $3
+			AND ($4::timestamptz IS NULL OR occurred_at <= $4)
+		ORDER BY version
+	`
+
+	rows, err := q.Query(ctx, query, orderID, tenantID, after, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []*order.Change
+	for rows.Next() {
+		var eventID uuid.UUID
+		var changeType string
+		var data, shipping, billing []byte
+		c := &order.Change{}
+		if err := rows.Scan(&eventID, &c.Version, &changeType, &data, &shipping, &billing, &c.OccurredAt); err != nil {
+			return nil, err
+		}
+		c.Type = order.ChangeType(changeType)
+
+		switch c.Type {
+		case order.ChangeCreated, order.ChangeUpdated:
+			if c.Order, err = r.openState(ctx, data, shipping, billing, orderEventAAD(eventID.String())); err != nil {
+				return nil, err
+			}
+		case order.ChangeStatusChanged:
+			var status statusData
+			if err := json.Unmarshal(data, &status); err != nil {
+				return nil, err
+			}
+			c.Status = status.Status
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, c := range changes {
+		if c.Version != after+i+1 {
+			return nil, fmt.Errorf("order %s history is missing version %d", orderID, after+i+1)
+		}
+	}
+	return changes, nil
+}
+
+// sealState splits an order into its JSON without addresses and its
+// addresses, with their PII fields encrypted under aad
+func (r *EventSourcedOrderRepository) sealState(ctx context.Context, o *order.Order, aad []byte) (data, shipping, billing []byte, err error) {
+	sealed := *o
+	if err := r.envelope.EncryptFields(ctx, &sealed, aad); err != nil {
+		return nil, nil, nil, err
+	}
+	if sealed.ShippingAddress != nil {
+		if shipping, err = json.Marshal(sealed.ShippingAddress); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if sealed.BillingAddress != nil {
+		if billing, err = json.Marshal(sealed.BillingAddress); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	sealed.ShippingAddress, sealed.BillingAddress = nil, nil
+	if data, err = json.Marshal(sealed); err != nil {
+		return nil, nil, nil, err
+	}
+	return data, shipping, billing, nil
+}
+
+// openState reverses sealState
+func (r *EventSourcedOrderRepository) openState(ctx context.Context, data, shipping, billing []byte, aad []byte) (*order.Order, error) {
+	var o order.Order
+	if err := json.Unmarshal(data, &o); err != nil {
+		return nil, err
+	}
+	if len(shipping) > 0 {
+		o.ShippingAddress = &order.Address{}
+		if err := json.Unmarshal(shipping, o.ShippingAddress); err != nil {
+			return nil, err
+		}
+	}
+	if len(billing) > 0 {
+		o.BillingAddress = &order.Address{}
+		if err := json.Unmarshal(billing, o.BillingAddress); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.envelope.DecryptFields(ctx, &o, aad); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds
+func (r *EventSourcedOrderRepository) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := r.tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// orderEventAAD binds an event's encrypted fields to its row
+func orderEventAAD(id string) []byte {
+	return []byte("order_events:" + id)
+}
+
+// orderSnapshotAAD binds a snapshot's encrypted fields to its row
+func orderSnapshotAAD(id string) []byte {
+	return []byte("order_snapshots:" + id)
+}
@@ -4,83 +4,187 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/pkg/archive"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/encryption"
+	"github.com/onichange/pos-system/pkg/tenant"
 )
 
-// OrderRepository implements order.Repository
+// OrderRepository implements order.Repository. Every query is scoped to the
+// tenant in ctx and fails with tenant.ErrNoTenant when there is none.
 type OrderRepository struct {
-	db *pgxpool.Pool
+	db       database.Querier
+	envelope *encryption.Envelope // Encrypts address fields tagged encrypt:"pii"; nil stores them as is
 }
 
 // NewOrderRepository creates a new order repository
-func NewOrderRepository(db *pgxpool.Pool) *OrderRepository {
-	return &OrderRepository{db: db}
+func NewOrderRepository(db database.Querier, envelope *encryption.Envelope) *OrderRepository {
+	return &OrderRepository{db: db, envelope: envelope}
 }
 
+// insertOrderQuery creates an order
+const insertOrderQuery = `
+	INSERT INTO orders (
+		id, user_id, store_id, status, total_amount, currency,
+		items, shipping_address, billing_address, notes,
+		created_at, updated_at, tenant_id, loyalty_points, loyalty_discount,
+		promotions, shift_id, tender, tax_amount, taxes
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+`
+
 // Create creates a new order
 func (r *OrderRepository) Create(ctx context.Context, o *order.Order) error {
-	itemsJSON, err := json.Marshal(o.Items)
+	ctx, span := startSpan(ctx, "OrderRepository.Create")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
 	if err != nil {
 		return err
 	}
+	o.TenantID = tenantID
 
-	var shippingAddrJSON, billingAddrJSON []byte
-	if o.ShippingAddress != nil {
-		shippingAddrJSON, err = json.Marshal(o.ShippingAddress)
-		if err != nil {
-			return err
-		}
+	now := time.Now()
+	args, createdAt, err := r.insertArgs(ctx, o, tenantID, now)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(ctx, insertOrderQuery, args...)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return order.ErrOrderExists
+	}
+	if err != nil {
+		return err
 	}
-	if o.BillingAddress != nil {
-		billingAddrJSON, err = json.Marshal(o.BillingAddress)
+
+	o.CreatedAt = createdAt
+	o.UpdatedAt = now
+	return nil
+}
+
+// CreateBatch creates orders in one round trip, failing with
+// ErrOrderExists, and creating none, when one of them already exists
+func (r *OrderRepository) CreateBatch(ctx context.Context, orders []*order.Order) error {
+	ctx, span := startSpan(ctx, "OrderRepository.CreateBatch")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+	if len(orders) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	batch := &pgx.Batch{}
+	createdAt := make([]time.Time, len(orders))
+	for i, o := range orders {
+		o.TenantID = tenantID
+		var args []interface{}
+		args, createdAt[i], err = r.insertArgs(ctx, o, tenantID, now)
 		if err != nil {
 			return err
 		}
+		batch.Queue(insertOrderQuery, args...)
 	}
 
-	query := `
-		INSERT INTO orders (
-			id, user_id, store_id, status, total_amount, currency,
-			items, shipping_address, billing_address, notes,
-			created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-	`
+	err = database.ExecBatch(ctx, r.db, batch)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return order.ErrOrderExists
+	}
+	if err != nil {
+		return err
+	}
 
-	now := time.Now()
-	_, err = r.db.Exec(ctx, query,
+	for i, o := range orders {
+		o.CreatedAt = createdAt[i]
+		o.UpdatedAt = now
+	}
+	return nil
+}
+
+// insertArgs returns the arguments of insertOrderQuery creating o at now,
+// and the time it is recorded as created
+func (r *OrderRepository) insertArgs(ctx context.Context, o *order.Order, tenantID string, now time.Time) ([]interface{}, time.Time, error) {
+	itemsJSON, err := json.Marshal(o.Items)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	promotionsJSON, err := json.Marshal(promotionsOrEmpty(o.Promotions))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	taxesJSON, err := json.Marshal(taxesOrEmpty(o.Taxes))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	shippingAddrJSON, billingAddrJSON, err := r.sealAddresses(ctx, o)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	// Orders rung up offline keep the time they were rung up
+	createdAt := o.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = now
+	}
+	return []interface{}{
 		o.ID, o.UserID, o.StoreID, string(o.Status), o.TotalAmount, o.Currency,
 		itemsJSON, shippingAddrJSON, billingAddrJSON, o.Notes,
-		now, now,
-	)
-
-	return err
+		createdAt, now, tenantID, o.LoyaltyPoints, o.LoyaltyDiscount,
+		promotionsJSON, o.ShiftID, o.Tender, o.TaxAmount, taxesJSON,
+	}, createdAt, nil
 }
 
 // GetByID retrieves an order by ID
 func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	ctx, span := startSpan(ctx, "OrderRepository.GetByID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, user_id, store_id, status, total_amount, currency,
 			items, shipping_address, billing_address, notes,
-			created_at, updated_at, completed_at, cancelled_at
+			created_at, updated_at, completed_at, cancelled_at, tenant_id,
+			loyalty_points, loyalty_discount, promotions, shift_id, tender,
+			tax_amount, taxes
 		FROM orders
-		WHERE id = $1 AND cancelled_at IS NULL
+		WHERE id = $1 AND tenant_id = $2 AND cancelled_at IS NULL
 	`
 
 	var o order.Order
 	var statusStr string
-	var itemsJSON, shippingAddrJSON, billingAddrJSON []byte
+	var itemsJSON, shippingAddrJSON, billingAddrJSON, promotionsJSON, taxesJSON []byte
 	var completedAt, cancelledAt sql.NullTime
 
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err = r.db.QueryRow(ctx, query, id, tenantID).Scan(
 		&o.ID, &o.UserID, &o.StoreID, &statusStr, &o.TotalAmount, &o.Currency,
 		&itemsJSON, &shippingAddrJSON, &billingAddrJSON, &o.Notes,
-		&o.CreatedAt, &o.UpdatedAt, &completedAt, &cancelledAt,
+		&o.CreatedAt, &o.UpdatedAt, &completedAt, &cancelledAt, &o.TenantID,
+		&o.LoyaltyPoints, &o.LoyaltyDiscount, &promotionsJSON, &o.ShiftID, &o.Tender,
+		&o.TaxAmount, &taxesJSON,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, order.ErrOrderNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -97,6 +201,12 @@ func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*order.Ord
 	if err := json.Unmarshal(itemsJSON, &o.Items); err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal(promotionsJSON, &o.Promotions); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(taxesJSON, &o.Taxes); err != nil {
+		return nil, err
+	}
 	if len(shippingAddrJSON) > 0 {
 		var addr order.Address
 		if err := json.Unmarshal(shippingAddrJSON, &addr); err == nil {
@@ -109,23 +219,36 @@ func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*order.Ord
 			o.BillingAddress = &addr
 		}
 	}
+	if err := r.envelope.DecryptFields(ctx, &o, orderAAD(o.ID.String())); err != nil {
+		return nil, err
+	}
 
 	return &o, nil
 }
 
 // GetByUserID retrieves orders by user ID
 func (r *OrderRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*order.Order, error) {
+	ctx, span := startSpan(ctx, "OrderRepository.GetByUserID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, user_id, store_id, status, total_amount, currency,
 			items, shipping_address, billing_address, notes,
-			created_at, updated_at, completed_at, cancelled_at
+			created_at, updated_at, completed_at, cancelled_at, tenant_id,
+			loyalty_points, loyalty_discount, promotions, shift_id, tender,
+			tax_amount, taxes
 		FROM orders
-		WHERE user_id = $1 AND cancelled_at IS NULL
+		WHERE user_id = $1 AND tenant_id = $4 AND cancelled_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	rows, err := r.db.Query(ctx, query, userID, limit, offset, tenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -137,6 +260,9 @@ func (r *OrderRepository) GetByUserID(ctx context.Context, userID uuid.UUID, lim
 		if err != nil {
 			return nil, err
 		}
+		if err := r.envelope.DecryptFields(ctx, o, orderAAD(o.ID.String())); err != nil {
+			return nil, err
+		}
 		orders = append(orders, o)
 	}
 
@@ -145,17 +271,27 @@ func (r *OrderRepository) GetByUserID(ctx context.Context, userID uuid.UUID, lim
 
 // GetByStoreID retrieves orders by store ID
 func (r *OrderRepository) GetByStoreID(ctx context.Context, storeID uuid.UUID, limit, offset int) ([]*order.Order, error) {
+	ctx, span := startSpan(ctx, "OrderRepository.GetByStoreID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, user_id, store_id, status, total_amount, currency,
 			items, shipping_address, billing_address, notes,
-			created_at, updated_at, completed_at, cancelled_at
+			created_at, updated_at, completed_at, cancelled_at, tenant_id,
+			loyalty_points, loyalty_discount, promotions, shift_id, tender,
+			tax_amount, taxes
 		FROM orders
-		WHERE store_id = $1 AND cancelled_at IS NULL
+		WHERE store_id = $1 AND tenant_id = $4 AND cancelled_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := r.db.Query(ctx, query, storeID, limit, offset)
+	rows, err := r.db.Query(ctx, query, storeID, limit, offset, tenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -167,31 +303,130 @@ func (r *OrderRepository) GetByStoreID(ctx context.Context, storeID uuid.UUID, l
 		if err != nil {
 			return nil, err
 		}
+		if err := r.envelope.DecryptFields(ctx, o, orderAAD(o.ID.String())); err != nil {
+			return nil, err
+		}
 		orders = append(orders, o)
 	}
 
 	return orders, rows.Err()
 }
 
-// Update updates an order
-func (r *OrderRepository) Update(ctx context.Context, o *order.Order) error {
-	itemsJSON, err := json.Marshal(o.Items)
+// ExportStore calls fn with every order of the store, cancelled ones
+// included. The orders are read by one query, so they are as of one moment.
+func (r *OrderRepository) ExportStore(ctx context.Context, storeID uuid.UUID, fn func(*order.Order) error) error {
+	ctx, span := startSpan(ctx, "OrderRepository.ExportStore")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
 	if err != nil {
 		return err
 	}
 
-	var shippingAddrJSON, billingAddrJSON []byte
-	if o.ShippingAddress != nil {
-		shippingAddrJSON, err = json.Marshal(o.ShippingAddress)
+	query := `
+		SELECT id, user_id, store_id, status, total_amount, currency,
+			items, shipping_address, billing_address, notes,
+			created_at, updated_at, completed_at, cancelled_at, tenant_id,
+			loyalty_points, loyalty_discount, promotions, shift_id, tender,
+			tax_amount, taxes
+		FROM orders
+		WHERE store_id = $1 AND tenant_id = $2
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, storeID, tenantID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		o, err := scanOrder(rows)
 		if err != nil {
 			return err
 		}
+		if err := r.envelope.DecryptFields(ctx, o, orderAAD(o.ID.String())); err != nil {
+			return err
+		}
+		if err := fn(o); err != nil {
+			return err
+		}
 	}
-	if o.BillingAddress != nil {
-		billingAddrJSON, err = json.Marshal(o.BillingAddress)
+	return rows.Err()
+}
+
+// Export calls fn with each order placed in the filter's period, oldest
+// first, streaming them from one query
+func (r *OrderRepository) Export(ctx context.Context, filter order.ExportFilter, fn func(*order.Order) error) error {
+	ctx, span := startSpan(ctx, "OrderRepository.Export")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		SELECT id, user_id, store_id, status, total_amount, currency,
+			items, shipping_address, billing_address, notes,
+			created_at, updated_at, completed_at, cancelled_at, tenant_id,
+			loyalty_points, loyalty_discount, promotions, shift_id, tender,
+			tax_amount, taxes
+		FROM orders
+		WHERE tenant_id = $1 AND created_at >= $2 AND created_at < $3
+			AND ($4::uuid IS NULL OR store_id = $4)
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Query(ctx, query, tenantID, filter.From, filter.To, filter.StoreID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		o, err := scanOrder(rows)
 		if err != nil {
 			return err
 		}
+		if err := r.envelope.DecryptFields(ctx, o, orderAAD(o.ID.String())); err != nil {
+			return err
+		}
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Update updates an order
+func (r *OrderRepository) Update(ctx context.Context, o *order.Order) error {
+	ctx, span := startSpan(ctx, "OrderRepository.Update")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	itemsJSON, err := json.Marshal(o.Items)
+	if err != nil {
+		return err
+	}
+
+	promotionsJSON, err := json.Marshal(promotionsOrEmpty(o.Promotions))
+	if err != nil {
+		return err
+	}
+
+	taxesJSON, err := json.Marshal(taxesOrEmpty(o.Taxes))
+	if err != nil {
+		return err
+	}
+
+	shippingAddrJSON, billingAddrJSON, err := r.sealAddresses(ctx, o)
+	if err != nil {
+		return err
 	}
 
 	query := `
@@ -199,70 +434,219 @@ func (r *OrderRepository) Update(ctx context.Context, o *order.Order) error {
 			status = $2, total_amount = $3, currency = $4,
 			items = $5, shipping_address = $6, billing_address = $7,
 			notes = $8, updated_at = $9,
-			completed_at = $10, cancelled_at = $11
-		WHERE id = $1 AND cancelled_at IS NULL
+			completed_at = $10, cancelled_at = $11, promotions = $13,
+			tax_amount = $14, taxes = $15
+		WHERE id = $1 AND tenant_id = $12 AND cancelled_at IS NULL
 	`
 
 	_, err = r.db.Exec(ctx, query,
 		o.ID, string(o.Status), o.TotalAmount, o.Currency,
 		itemsJSON, shippingAddrJSON, billingAddrJSON, o.Notes,
-		time.Now(), o.CompletedAt, o.CancelledAt,
+		time.Now(), o.CompletedAt, o.CancelledAt, tenantID, promotionsJSON,
+		o.TaxAmount, taxesJSON,
 	)
 
 	return err
 }
 
-// UpdateStatus updates order status
-func (r *OrderRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status order.OrderStatus) error {
+// UpdateStatus moves an order from t.From to t.To and appends t to
+// order_status_history in one statement, so the history holds every move
+// made. An order no longer in t.From, or cancelled, is left unchanged.
+func (r *OrderRepository) UpdateStatus(ctx context.Context, id uuid.UUID, t *order.StatusTransition) error {
+	ctx, span := startSpan(ctx, "OrderRepository.UpdateStatus")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		UPDATE orders SET
-			status = $2,
-			updated_at = $3,
-			completed_at = CASE WHEN $2 = 'delivered' THEN $3 ELSE completed_at END,
-			cancelled_at = CASE WHEN $2 = 'cancelled' THEN $3 ELSE cancelled_at END
-		WHERE id = $1 AND cancelled_at IS NULL
+		WITH updated AS (
+			UPDATE orders SET
+				status = $2,
+				updated_at = $3,
+				completed_at = CASE WHEN $2 = 'delivered' THEN $3 ELSE completed_at END,
+				cancelled_at = CASE WHEN $2 = 'cancelled' THEN $3 ELSE cancelled_at END
+			WHERE id = $1 AND tenant_id = $4 AND status = $5 AND cancelled_at IS NULL
+			RETURNING id
+		)
+		INSERT INTO order_status_history (id, order_id, tenant_id, from_status, to_status, changed_by, reason, changed_at)
+		SELECT $6, id, $4, $5, $2, $7, $8, $3 FROM updated
+		RETURNING id
 	`
 
-	now := time.Now()
-	_, err := r.db.Exec(ctx, query, id, string(status), now)
+	var recorded uuid.UUID
+	err = r.db.QueryRow(ctx, query,
+		id, string(t.To), t.ChangedAt, tenantID, string(t.From), t.ID, t.ChangedBy, t.Reason,
+	).Scan(&recorded)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &order.TransitionError{From: t.From, To: t.To}
+	}
 	return err
 }
 
+// StatusHistory returns the status transitions of an order, oldest first,
+// failing with ErrOrderNotFound for an order that is not stored
+func (r *OrderRepository) StatusHistory(ctx context.Context, id uuid.UUID) ([]*order.StatusTransition, error) {
+	ctx, span := startSpan(ctx, "OrderRepository.StatusHistory")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	err = r.db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1 AND tenant_id = $2)`, id, tenantID,
+	).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, order.ErrOrderNotFound
+	}
+
+	query := `
+		SELECT id, order_id, from_status, to_status, changed_by, reason, changed_at
+		FROM order_status_history
+		WHERE order_id = $1 AND tenant_id = $2
+		ORDER BY changed_at, id
+	`
+
+	rows, err := r.db.Query(ctx, query, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []*order.StatusTransition{}
+	for rows.Next() {
+		var t order.StatusTransition
+		var from, to string
+		if err := rows.Scan(&t.ID, &t.OrderID, &from, &to, &t.ChangedBy, &t.Reason, &t.ChangedAt); err != nil {
+			return nil, err
+		}
+		t.From = order.OrderStatus(from)
+		t.To = order.OrderStatus(to)
+		history = append(history, &t)
+	}
+	return history, rows.Err()
+}
+
 // Delete soft deletes an order
 func (r *OrderRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startSpan(ctx, "OrderRepository.Delete")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE orders SET
 			status = 'cancelled',
 			cancelled_at = $2,
 			updated_at = $2
-		WHERE id = $1
+		WHERE id = $1 AND tenant_id = $3
 	`
 
-	_, err := r.db.Exec(ctx, query, id, time.Now())
+	_, err = r.db.Exec(ctx, query, id, time.Now(), tenantID)
 	return err
 }
 
 // CountByUserID counts orders by user ID
 func (r *OrderRepository) CountByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
-	query := `SELECT COUNT(*) FROM orders WHERE user_id = $1 AND cancelled_at IS NULL`
+	ctx, span := startSpan(ctx, "OrderRepository.CountByUserID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `SELECT COUNT(*) FROM orders WHERE user_id = $1 AND tenant_id = $2 AND cancelled_at IS NULL`
 	var count int
-	err := r.db.QueryRow(ctx, query, userID).Scan(&count)
+	err = r.db.QueryRow(ctx, query, userID, tenantID).Scan(&count)
 	return count, err
 }
 
+// orderAAD binds an order's encrypted fields to its row
+func orderAAD(id string) []byte {
+	return []byte("orders:" + id)
+}
+
+// sealAddresses returns o's addresses as JSON with their PII fields encrypted
+func (r *OrderRepository) sealAddresses(ctx context.Context, o *order.Order) (shipping, billing []byte, err error) {
+	sealed := *o
+	if err := r.envelope.EncryptFields(ctx, &sealed, orderAAD(o.ID.String())); err != nil {
+		return nil, nil, err
+	}
+	if sealed.ShippingAddress != nil {
+		if shipping, err = json.Marshal(sealed.ShippingAddress); err != nil {
+			return nil, nil, err
+		}
+	}
+	if sealed.BillingAddress != nil {
+		if billing, err = json.Marshal(sealed.BillingAddress); err != nil {
+			return nil, nil, err
+		}
+	}
+	return shipping, billing, nil
+}
+
+// EncryptedColumns returns the stored address fields, for re-encryption
+// under a rotated key
+func (r *OrderRepository) EncryptedColumns() []encryption.SealedStore {
+	return []encryption.SealedStore{
+		sealedJSONFields{
+			db:      r.db,
+			table:   "orders",
+			columns: []string{"shipping_address", "billing_address"},
+			keys:    []string{"street", "postal_code"},
+			aad:     orderAAD,
+		},
+		sealedJSONFields{
+			db:      r.db,
+			table:   archive.Schema + ".orders",
+			columns: []string{"shipping_address", "billing_address"},
+			keys:    []string{"street", "postal_code"},
+			aad:     orderAAD,
+		},
+	}
+}
+
+// ArchiveTables returns the tables whose old rows are archived: orders in
+// a final status, by when they last changed, with their audit log
+func (r *OrderRepository) ArchiveTables() []archive.Table {
+	return []archive.Table{{
+		Name:       "orders",
+		TimeColumn: "updated_at",
+		Where:      "status IN ('delivered', 'cancelled', 'refunded')",
+		Dependents: []archive.Dependent{
+			{Name: "order_audit_log", ForeignKey: "order_id"},
+			{Name: "order_status_history", ForeignKey: "order_id"},
+		},
+	}}
+}
+
 // scanOrder scans a row into an Order
 func scanOrder(rows interface {
 	Scan(dest ...interface{}) error
 }) (*order.Order, error) {
 	var o order.Order
 	var statusStr string
-	var itemsJSON, shippingAddrJSON, billingAddrJSON []byte
+	var itemsJSON, shippingAddrJSON, billingAddrJSON, promotionsJSON, taxesJSON []byte
 	var completedAt, cancelledAt sql.NullTime
 
 	err := rows.Scan(
 		&o.ID, &o.UserID, &o.StoreID, &statusStr, &o.TotalAmount, &o.Currency,
 		&itemsJSON, &shippingAddrJSON, &billingAddrJSON, &o.Notes,
-		&o.CreatedAt, &o.UpdatedAt, &completedAt, &cancelledAt,
+		&o.CreatedAt, &o.UpdatedAt, &completedAt, &cancelledAt, &o.TenantID,
+		&o.LoyaltyPoints, &o.LoyaltyDiscount, &promotionsJSON, &o.ShiftID, &o.Tender,
+		&o.TaxAmount, &taxesJSON,
 	)
 	if err != nil {
 		return nil, err
@@ -280,6 +664,12 @@ func scanOrder(rows interface {
 	if err := json.Unmarshal(itemsJSON, &o.Items); err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal(promotionsJSON, &o.Promotions); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(taxesJSON, &o.Taxes); err != nil {
+		return nil, err
+	}
 	if len(shippingAddrJSON) > 0 {
 		var addr order.Address
 		if err := json.Unmarshal(shippingAddrJSON, &addr); err == nil {
@@ -296,3 +686,18 @@ func scanOrder(rows interface {
 	return &o, nil
 }
 
+// promotionsOrEmpty stores an order without promotions as an empty array
+func promotionsOrEmpty(promotions []order.AppliedPromotion) []order.AppliedPromotion {
+	if promotions == nil {
+		return []order.AppliedPromotion{}
+	}
+	return promotions
+}
+
+// taxesOrEmpty stores an order without sales tax as an empty array
+func taxesOrEmpty(taxes []order.TaxLine) []order.TaxLine {
+	if taxes == nil {
+		return []order.TaxLine{}
+	}
+	return taxes
+}
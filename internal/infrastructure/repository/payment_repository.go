@@ -3,41 +3,54 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 
 	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
 )
 
-// PaymentRepository implements payment.Repository
+// PaymentRepository implements payment.Repository. Every query is scoped to
+// the tenant in ctx and fails with tenant.ErrNoTenant when there is none.
 type PaymentRepository struct {
-	db *pgxpool.Pool
+	db database.Querier
 }
 
 // NewPaymentRepository creates a new payment repository
-func NewPaymentRepository(db *pgxpool.Pool) *PaymentRepository {
+func NewPaymentRepository(db database.Querier) *PaymentRepository {
 	return &PaymentRepository{db: db}
 }
 
 // Create creates a new payment
 func (r *PaymentRepository) Create(ctx context.Context, p *payment.Payment) error {
+	ctx, span := startSpan(ctx, "PaymentRepository.Create")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO payments (
 			id, order_id, user_id, payment_method_token, payment_method_type,
 			amount, currency, status, provider, provider_transaction_id,
 			three_d_secure_enabled, three_d_secure_status, fraud_score, fraud_flagged,
-			created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+			created_at, updated_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
 
 	now := time.Now()
-	_, err := r.db.Exec(ctx, query,
+	_, err = r.db.Exec(ctx, query,
 		p.ID, p.OrderID, p.UserID, p.PaymentMethodToken, string(p.PaymentMethodType),
 		p.Amount, p.Currency, string(p.Status), p.Provider, p.ProviderTransactionID,
 		p.ThreeDSecureEnabled, p.ThreeDSecureStatus, p.FraudScore, p.FraudFlagged,
-		now, now,
+		now, now, tenantID,
 	)
 
 	return err
@@ -45,25 +58,36 @@ func (r *PaymentRepository) Create(ctx context.Context, p *payment.Payment) erro
 
 // GetByID retrieves a payment by ID
 func (r *PaymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*payment.Payment, error) {
+	ctx, span := startSpan(ctx, "PaymentRepository.GetByID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, order_id, user_id, payment_method_token, payment_method_type,
 			amount, currency, status, provider, provider_transaction_id,
 			three_d_secure_enabled, three_d_secure_status, fraud_score, fraud_flagged,
 			created_at, updated_at, processed_at, completed_at
 		FROM payments
-		WHERE id = $1
+		WHERE id = $1 AND tenant_id = $2
 	`
 
 	var p payment.Payment
 	var methodTypeStr, statusStr string
 	var processedAt, completedAt sql.NullTime
 
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err = r.db.QueryRow(ctx, query, id, tenantID).Scan(
 		&p.ID, &p.OrderID, &p.UserID, &p.PaymentMethodToken, &methodTypeStr,
 		&p.Amount, &p.Currency, &statusStr, &p.Provider, &p.ProviderTransactionID,
 		&p.ThreeDSecureEnabled, &p.ThreeDSecureStatus, &p.FraudScore, &p.FraudFlagged,
 		&p.CreatedAt, &p.UpdatedAt, &processedAt, &completedAt,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, payment.ErrPaymentNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -82,17 +106,25 @@ func (r *PaymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*payment
 
 // GetByOrderID retrieves payments by order ID
 func (r *PaymentRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]*payment.Payment, error) {
+	ctx, span := startSpan(ctx, "PaymentRepository.GetByOrderID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, order_id, user_id, payment_method_token, payment_method_type,
 			amount, currency, status, provider, provider_transaction_id,
 			three_d_secure_enabled, three_d_secure_status, fraud_score, fraud_flagged,
 			created_at, updated_at, processed_at, completed_at
 		FROM payments
-		WHERE order_id = $1
+		WHERE order_id = $1 AND tenant_id = $2
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Query(ctx, query, orderID)
+	rows, err := r.db.Query(ctx, query, orderID, tenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -112,18 +144,26 @@ func (r *PaymentRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID)
 
 // GetByUserID retrieves payments by user ID
 func (r *PaymentRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*payment.Payment, error) {
+	ctx, span := startSpan(ctx, "PaymentRepository.GetByUserID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, order_id, user_id, payment_method_token, payment_method_type,
 			amount, currency, status, provider, provider_transaction_id,
 			three_d_secure_enabled, three_d_secure_status, fraud_score, fraud_flagged,
 			created_at, updated_at, processed_at, completed_at
 		FROM payments
-		WHERE user_id = $1
+		WHERE user_id = $1 AND tenant_id = $4
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	rows, err := r.db.Query(ctx, query, userID, limit, offset, tenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -141,20 +181,67 @@ func (r *PaymentRepository) GetByUserID(ctx context.Context, userID uuid.UUID, l
 	return payments, rows.Err()
 }
 
+// Export calls fn with each payment created in the filter's period, oldest
+// first, streaming them from one query
+func (r *PaymentRepository) Export(ctx context.Context, filter payment.ExportFilter, fn func(*payment.Payment) error) error {
+	ctx, span := startSpan(ctx, "PaymentRepository.Export")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		SELECT id, order_id, user_id, payment_method_token, payment_method_type,
+			amount, currency, status, provider, provider_transaction_id,
+			three_d_secure_enabled, three_d_secure_status, fraud_score, fraud_flagged,
+			created_at, updated_at, processed_at, completed_at
+		FROM payments
+		WHERE tenant_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Query(ctx, query, tenantID, filter.From, filter.To)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 // Update updates a payment
 func (r *PaymentRepository) Update(ctx context.Context, p *payment.Payment) error {
+	ctx, span := startSpan(ctx, "PaymentRepository.Update")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE payments SET
 			status = $2, provider = $3, provider_transaction_id = $4,
 			three_d_secure_status = $5, fraud_score = $6, fraud_flagged = $7,
 			processed_at = $8, completed_at = $9, updated_at = $10
-		WHERE id = $1
+		WHERE id = $1 AND tenant_id = $11
 	`
 
-	_, err := r.db.Exec(ctx, query,
+	_, err = r.db.Exec(ctx, query,
 		p.ID, string(p.Status), p.Provider, p.ProviderTransactionID,
 		p.ThreeDSecureStatus, p.FraudScore, p.FraudFlagged,
-		p.ProcessedAt, p.CompletedAt, time.Now(),
+		p.ProcessedAt, p.CompletedAt, time.Now(), tenantID,
 	)
 
 	return err
@@ -162,20 +249,151 @@ func (r *PaymentRepository) Update(ctx context.Context, p *payment.Payment) erro
 
 // UpdateStatus updates payment status
 func (r *PaymentRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status payment.PaymentStatus) error {
+	ctx, span := startSpan(ctx, "PaymentRepository.UpdateStatus")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE payments SET
 			status = $2,
 			updated_at = $3,
 			processed_at = CASE WHEN $2 = 'processing' THEN $3 ELSE processed_at END,
 			completed_at = CASE WHEN $2 = 'completed' THEN $3 ELSE completed_at END
-		WHERE id = $1
+		WHERE id = $1 AND tenant_id = $4
 	`
 
 	now := time.Now()
-	_, err := r.db.Exec(ctx, query, id, string(status), now)
+	_, err = r.db.Exec(ctx, query, id, string(status), now, tenantID)
 	return err
 }
 
+// Settle saves a payment's status and provider fields if it is still in one
+// of from. The provider event, status change and audit record are written in
+// one statement, so none lands without the others.
+func (r *PaymentRepository) Settle(ctx context.Context, p *payment.Payment, from []payment.PaymentStatus, change payment.StatusChange) (bool, error) {
+	ctx, span := startSpan(ctx, "PaymentRepository.Settle")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	statuses := make([]string, len(from))
+	for i, status := range from {
+		statuses[i] = string(status)
+	}
+	metadata, err := json.Marshal(map[string]string{
+		"event_id":                change.EventID,
+		"reason":                  change.Reason,
+		"provider_transaction_id": p.ProviderTransactionID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		WITH event AS (
+			INSERT INTO payment_provider_events (provider, event_id, payment_id, tenant_id, received_at)
+			SELECT $9, $10, $1, $8, $7 WHERE $10 <> ''
+			ON CONFLICT DO NOTHING
+			RETURNING event_id
+		), previous AS (
+			SELECT status FROM payments WHERE id = $1 AND tenant_id = $8
+		), updated AS (
+			UPDATE payments
+			SET status = $2, provider_transaction_id = $3, three_d_secure_status = $4,
+				processed_at = $5, completed_at = $6, updated_at = $7
+			WHERE id = $1 AND tenant_id = $8 AND status = ANY($11)
+				AND ($10 = '' OR EXISTS (SELECT 1 FROM event))
+			RETURNING id
+		)
+		INSERT INTO payment_audit_log (payment_id, action, old_status, new_status, metadata, created_at)
+		SELECT updated.id, $12, previous.status, $2, $13, $7 FROM updated, previous
+	`
+
+	now := time.Now()
+	tag, err := r.db.Exec(ctx, query,
+		p.ID, string(p.Status), p.ProviderTransactionID, p.ThreeDSecureStatus, p.ProcessedAt, p.CompletedAt, now, tenantID,
+		p.Provider, change.EventID, statuses, change.Action, metadata,
+	)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	p.UpdatedAt = now
+	return true, nil
+}
+
+// ListUnsettled retrieves pending and processing payments last updated
+// before the given time, after afterID in ID order, across tenants. Each
+// payment carries its tenant to settle it in.
+func (r *PaymentRepository) ListUnsettled(ctx context.Context, before time.Time, afterID uuid.UUID, limit int) ([]*payment.Payment, error) {
+	ctx, span := startSpan(ctx, "PaymentRepository.ListUnsettled")
+	defer span.End()
+
+	query := `
+		SELECT id, order_id, user_id, payment_method_token, payment_method_type,
+			amount, currency, status, provider, provider_transaction_id,
+			three_d_secure_enabled, three_d_secure_status, fraud_score, fraud_flagged,
+			created_at, updated_at, processed_at, completed_at, tenant_id
+		FROM payments
+		WHERE status IN ('pending', 'processing') AND updated_at < $1 AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(ctx, query, before, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*payment.Payment
+	for rows.Next() {
+		var tenantID string
+		p, err := scanPayment(withTenant{row: rows, tenantID: &tenantID})
+		if err != nil {
+			return nil, err
+		}
+		p.TenantID = tenantID
+		payments = append(payments, p)
+	}
+
+	return payments, rows.Err()
+}
+
+// PruneProviderEvents forgets provider events received before the given
+// time, across tenants. Providers stop redelivering an event long before.
+func (r *PaymentRepository) PruneProviderEvents(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "PaymentRepository.PruneProviderEvents")
+	defer span.End()
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM payment_provider_events WHERE received_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// withTenant scans a payment row followed by its tenant ID
+type withTenant struct {
+	row interface {
+		Scan(dest ...interface{}) error
+	}
+	tenantID *string
+}
+
+func (w withTenant) Scan(dest ...interface{}) error {
+	return w.row.Scan(append(dest, w.tenantID)...)
+}
+
 // scanPayment scans a row into a Payment
 func scanPayment(rows interface {
 	Scan(dest ...interface{}) error
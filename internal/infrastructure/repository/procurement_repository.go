@@ -0,0 +1,951 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/onichange/pos-system/internal/domain/procurement"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// checkViolation is the Postgres error code of a check constraint violation
+const checkViolation = "23514"
+
+// ProcurementRepository implements procurement.Repository. Every query but
+// the unposted receipts sweep is scoped to the tenant in ctx and fails with
+// tenant.ErrNoTenant when there is none; order and receipt lines are reached
+// through their scoped order or receipt.
+type ProcurementRepository struct {
+	db database.Querier
+}
+
+// NewProcurementRepository creates a new procurement repository
+func NewProcurementRepository(db database.Querier) *ProcurementRepository {
+	return &ProcurementRepository{db: db}
+}
+
+const supplierColumns = `id, code, name, contact_name, email, phone, address, currency,
+	payment_terms, lead_time_days, active, notes, created_at, updated_at`
+
+// CreateSupplier creates a supplier
+func (r *ProcurementRepository) CreateSupplier(ctx context.Context, s *procurement.Supplier) error {
+	ctx, span := startSpan(ctx, "ProcurementRepository.CreateSupplier")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO suppliers (
+			id, code, name, contact_name, email, phone, address, currency,
+			payment_terms, lead_time_days, active, notes, created_at, updated_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $13, $14)
+	`
+
+	now := time.Now().UTC()
+	_, err = r.db.Exec(ctx, query,
+		s.ID, s.Code, s.Name, s.ContactName, s.Email, s.Phone, s.Address, s.Currency,
+		s.PaymentTerms, s.LeadTimeDays, s.Active, s.Notes, now, tenantID,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return procurement.ErrSupplierExists
+	}
+	if err != nil {
+		return err
+	}
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	return nil
+}
+
+// GetSupplier retrieves a supplier by ID
+func (r *ProcurementRepository) GetSupplier(ctx context.Context, id uuid.UUID) (*procurement.Supplier, error) {
+	ctx, span := startSpan(ctx, "ProcurementRepository.GetSupplier")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + supplierColumns + ` FROM suppliers WHERE id = $1 AND tenant_id = $2`
+
+	s, err := scanSupplier(r.db.QueryRow(ctx, query, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, procurement.ErrSupplierNotFound
+	}
+	return s, err
+}
+
+// ListSuppliers retrieves suppliers by name
+func (r *ProcurementRepository) ListSuppliers(ctx context.Context, activeOnly bool, limit, offset int) ([]*procurement.Supplier, error) {
+	ctx, span := startSpan(ctx, "ProcurementRepository.ListSuppliers")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + supplierColumns + `
+		FROM suppliers
+		WHERE tenant_id = $4 AND (active OR NOT $1)
+		ORDER BY name, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, activeOnly, limit, offset, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppliers := []*procurement.Supplier{}
+	for rows.Next() {
+		s, err := scanSupplier(rows)
+		if err != nil {
+			return nil, err
+		}
+		suppliers = append(suppliers, s)
+	}
+	return suppliers, rows.Err()
+}
+
+// UpdateSupplier updates a supplier's details
+func (r *ProcurementRepository) UpdateSupplier(ctx context.Context, s *procurement.Supplier) error {
+	ctx, span := startSpan(ctx, "ProcurementRepository.UpdateSupplier")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE suppliers SET
+			name = $2, contact_name = $3, email = $4, phone = $5, address = $6, currency = $7,
+			payment_terms = $8, lead_time_days = $9, active = $10, notes = $11, updated_at = $12
+		WHERE id = $1 AND tenant_id = $13
+	`
+
+	now := time.Now().UTC()
+	tag, err := r.db.Exec(ctx, query,
+		s.ID, s.Name, s.ContactName, s.Email, s.Phone, s.Address, s.Currency,
+		s.PaymentTerms, s.LeadTimeDays, s.Active, s.Notes, now, tenantID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return procurement.ErrSupplierNotFound
+	}
+	s.UpdatedAt = now
+	return nil
+}
+
+// DeleteSupplier deletes a supplier no purchase order was raised with
+func (r *ProcurementRepository) DeleteSupplier(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startSpan(ctx, "ProcurementRepository.DeleteSupplier")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM suppliers WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return procurement.ErrSupplierInUse
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return procurement.ErrSupplierNotFound
+	}
+	return nil
+}
+
+const orderColumns = `id, number, supplier_id, store_id, status, currency, total, expected_on, notes,
+	created_by, submitted_at, received_at, closed_at, created_at, updated_at`
+
+// CreateOrder creates a purchase order with its lines in one statement
+func (r *ProcurementRepository) CreateOrder(ctx context.Context, o *procurement.PurchaseOrder) error {
+	ctx, span := startSpan(ctx, "ProcurementRepository.CreateOrder")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH po AS (
+			INSERT INTO purchase_orders (
+				id, supplier_id, store_id, status, currency, total, expected_on, notes,
+				created_by, created_at, updated_at, tenant_id
+			)
+			SELECT $1, s.id, $3, $4, $5, $6, $7, $8, $9, $10, $10, s.tenant_id
+			FROM suppliers s
+			WHERE s.id = $2 AND s.tenant_id = $17
+			RETURNING id, number
+		), lines AS (
+			INSERT INTO purchase_order_lines (
+				id, purchase_order_id, position, product_id, supplier_sku, description, quantity, unit_cost
+			)
+			SELECT t.id, po.id, t.position, t.product_id, t.supplier_sku, t.description, t.quantity, t.unit_cost
+			FROM po, unnest($11::uuid[], $12::uuid[], $13::text[], $14::text[], $15::int[], $16::numeric[])
+				WITH ORDINALITY AS t(id, product_id, supplier_sku, description, quantity, unit_cost, position)
+		)
+		SELECT number FROM po
+	`
+
+	now := time.Now().UTC()
+	lines := newLineArrays(o.Lines)
+	err = r.db.QueryRow(ctx, query,
+		o.ID, o.SupplierID, o.StoreID, string(o.Status), o.Currency, o.Total, o.ExpectedOn, o.Notes,
+		o.CreatedBy, now,
+		lines.ids, lines.productIDs, lines.skus, lines.descriptions, lines.quantities, lines.unitCosts,
+		tenantID,
+	).Scan(&o.Number)
+	// Suppliers of other tenants are as missing as deleted ones
+	var pgErr *pgconn.PgError
+	if errors.Is(err, pgx.ErrNoRows) || errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return procurement.ErrSupplierNotFound
+	}
+	if err != nil {
+		return err
+	}
+	o.CreatedAt = now
+	o.UpdatedAt = now
+	return nil
+}
+
+// GetOrder retrieves a purchase order with its lines
+func (r *ProcurementRepository) GetOrder(ctx context.Context, id uuid.UUID) (*procurement.PurchaseOrder, error) {
+	ctx, span := startSpan(ctx, "ProcurementRepository.GetOrder")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + orderColumns + ` FROM purchase_orders WHERE id = $1 AND tenant_id = $2`
+
+	o, err := scanPurchaseOrder(r.db.QueryRow(ctx, query, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, procurement.ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadLines(ctx, []*procurement.PurchaseOrder{o}); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// ListOrders retrieves purchase orders, newest first, with their lines
+func (r *ProcurementRepository) ListOrders(ctx context.Context, filter procurement.OrderFilter, limit, offset int) ([]*procurement.PurchaseOrder, error) {
+	ctx, span := startSpan(ctx, "ProcurementRepository.ListOrders")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + orderColumns + `
+		FROM purchase_orders
+		WHERE tenant_id = $6
+			AND ($1::uuid IS NULL OR supplier_id = $1)
+			AND ($2::uuid IS NULL OR store_id = $2)
+			AND ($3 = '' OR status = $3)
+		ORDER BY created_at DESC, id
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := r.db.Query(ctx, query, filter.SupplierID, filter.StoreID, string(filter.Status), limit, offset, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []*procurement.PurchaseOrder{}
+	for rows.Next() {
+		o, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadLines(ctx, orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// UpdateDraft replaces a draft order's lines, total, expected day and notes
+// in one statement
+func (r *ProcurementRepository) UpdateDraft(ctx context.Context, o *procurement.PurchaseOrder) error {
+	ctx, span := startSpan(ctx, "ProcurementRepository.UpdateDraft")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH po AS (
+			UPDATE purchase_orders SET total = $2, expected_on = $3, notes = $4, updated_at = $5
+			WHERE id = $1 AND tenant_id = $12 AND status = 'draft'
+			RETURNING id
+		), dropped AS (
+			DELETE FROM purchase_order_lines WHERE purchase_order_id IN (SELECT id FROM po)
+		), lines AS (
+			INSERT INTO purchase_order_lines (
+				id, purchase_order_id, position, product_id, supplier_sku, description, quantity, unit_cost
+			)
+			SELECT t.id, po.id, t.position, t.product_id, t.supplier_sku, t.description, t.quantity, t.unit_cost
+			FROM po, unnest($6::uuid[], $7::uuid[], $8::text[], $9::text[], $10::int[], $11::numeric[])
+				WITH ORDINALITY AS t(id, product_id, supplier_sku, description, quantity, unit_cost, position)
+		)
+		SELECT count(*) FROM po
+	`
+
+	now := time.Now().UTC()
+	lines := newLineArrays(o.Lines)
+	var updated int
+	err = r.db.QueryRow(ctx, query,
+		o.ID, o.Total, o.ExpectedOn, o.Notes, now,
+		lines.ids, lines.productIDs, lines.skus, lines.descriptions, lines.quantities, lines.unitCosts,
+		tenantID,
+	).Scan(&updated)
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return r.missingOr(ctx, tenantID, o.ID, procurement.ErrInvalidTransition)
+	}
+	o.UpdatedAt = now
+	return nil
+}
+
+// Transition moves an order to status to from any status it can be moved
+// from, stamping when it was submitted or closed. Submitting sets the
+// expected day when the order has none.
+func (r *ProcurementRepository) Transition(ctx context.Context, id uuid.UUID, to procurement.Status, expectedOn *time.Time, at time.Time) (*procurement.PurchaseOrder, error) {
+	ctx, span := startSpan(ctx, "ProcurementRepository.Transition")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := procurement.Sources(to)
+	from := make([]string, len(sources))
+	for i, s := range sources {
+		from[i] = string(s)
+	}
+
+	query := `
+		UPDATE purchase_orders SET
+			status = $2,
+			expected_on = COALESCE(expected_on, $3),
+			submitted_at = CASE WHEN $2 = 'submitted' THEN $4 ELSE submitted_at END,
+			closed_at = CASE WHEN $2 IN ('closed', 'cancelled') THEN $4 ELSE closed_at END,
+			updated_at = $4
+		WHERE id = $1 AND tenant_id = $6 AND status = ANY($5)
+	`
+
+	tag, err := r.db.Exec(ctx, query, id, string(to), expectedOn, at.UTC(), from, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, r.missingOr(ctx, tenantID, id, procurement.ErrInvalidTransition)
+	}
+	return r.GetOrder(ctx, id)
+}
+
+// CreateReceipt records a goods receipt, adds its quantities to the order
+// lines and marks the order partially received in one statement, then marks
+// it received once nothing is outstanding. The lines' check constraint
+// refuses quantities beyond those ordered, concurrent receipts included.
+func (r *ProcurementRepository) CreateReceipt(ctx context.Context, rc *procurement.GoodsReceipt) error {
+	ctx, span := startSpan(ctx, "ProcurementRepository.CreateReceipt")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH po AS (
+			SELECT id, store_id, tenant_id FROM purchase_orders
+			WHERE id = $2 AND tenant_id = $12 AND status IN ('submitted', 'partially_received')
+			FOR UPDATE
+		), receipt AS (
+			INSERT INTO goods_receipts (id, purchase_order_id, store_id, note, received_by, received_at, tenant_id)
+			SELECT $1, id, store_id, $3, $4, $5, tenant_id FROM po
+			RETURNING store_id
+		), received AS (
+			INSERT INTO goods_receipt_lines (id, receipt_id, line_id, product_id, quantity, unit_cost)
+			SELECT t.id, $1, t.line_id, t.product_id, t.quantity, t.unit_cost
+			FROM receipt, unnest($6::uuid[], $7::uuid[], $8::uuid[], $9::int[], $10::numeric[])
+				AS t(id, line_id, product_id, quantity, unit_cost)
+		), bumped AS (
+			UPDATE purchase_order_lines l SET received_quantity = l.received_quantity + t.quantity
+			FROM po, unnest($7::uuid[], $9::int[]) AS t(line_id, quantity)
+			WHERE l.id = t.line_id AND l.purchase_order_id = po.id
+		), ordered AS (
+			UPDATE purchase_orders o SET status = 'partially_received', updated_at = $11
+			FROM po
+			WHERE o.id = po.id
+		)
+		SELECT store_id FROM receipt
+	`
+
+	n := len(rc.Lines)
+	ids := make([]uuid.UUID, n)
+	lineIDs := make([]uuid.UUID, n)
+	productIDs := make([]uuid.UUID, n)
+	quantities := make([]int, n)
+	unitCosts := make([]float64, n)
+	for i, l := range rc.Lines {
+		ids[i], lineIDs[i], productIDs[i], quantities[i], unitCosts[i] = l.ID, l.LineID, l.ProductID, l.Quantity, l.UnitCost
+	}
+
+	now := time.Now().UTC()
+	err = r.db.QueryRow(ctx, query,
+		rc.ID, rc.PurchaseOrderID, rc.Note, rc.ReceivedBy, rc.ReceivedAt.UTC(),
+		ids, lineIDs, productIDs, quantities, unitCosts, now, tenantID,
+	).Scan(&rc.StoreID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.missingOr(ctx, tenantID, rc.PurchaseOrderID, procurement.ErrInvalidTransition)
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == checkViolation {
+		return procurement.ErrOverReceipt
+	}
+	if err != nil {
+		return err
+	}
+	rc.TenantID = tenantID
+
+	// Apart from the receipt, so that the order is settled on the lines as
+	// committed by concurrent receipts too
+	settle := `
+		UPDATE purchase_orders o SET status = 'received', received_at = $2, updated_at = $3
+		WHERE o.id = $1 AND o.tenant_id = $4 AND o.status = 'partially_received'
+			AND NOT EXISTS (
+				SELECT 1 FROM purchase_order_lines l
+				WHERE l.purchase_order_id = o.id AND l.received_quantity < l.quantity
+			)
+	`
+	_, err = r.db.Exec(ctx, settle, rc.PurchaseOrderID, rc.ReceivedAt.UTC(), now, tenantID)
+	return err
+}
+
+const receiptColumns = `id, tenant_id, purchase_order_id, store_id, note, received_by, received_at, posted_at`
+
+// ListReceipts retrieves the goods receipts of an order, oldest first
+func (r *ProcurementRepository) ListReceipts(ctx context.Context, orderID uuid.UUID) ([]*procurement.GoodsReceipt, error) {
+	ctx, span := startSpan(ctx, "ProcurementRepository.ListReceipts")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + receiptColumns + `
+		FROM goods_receipts
+		WHERE purchase_order_id = $1 AND tenant_id = $2
+		ORDER BY received_at, id
+	`
+	return r.queryReceipts(ctx, query, orderID, tenantID)
+}
+
+// ListUnpostedReceipts retrieves receipts whose stock is not yet posted to
+// inventory, oldest first, across tenants. Each receipt carries its tenant
+// to post it in.
+func (r *ProcurementRepository) ListUnpostedReceipts(ctx context.Context, limit int) ([]*procurement.GoodsReceipt, error) {
+	ctx, span := startSpan(ctx, "ProcurementRepository.ListUnpostedReceipts")
+	defer span.End()
+
+	query := `
+		SELECT ` + receiptColumns + `
+		FROM goods_receipts
+		WHERE posted_at IS NULL
+		ORDER BY received_at, id
+		LIMIT $1
+	`
+	return r.queryReceipts(ctx, query, limit)
+}
+
+// MarkReceiptPosted records that inventory took a receipt's stock
+func (r *ProcurementRepository) MarkReceiptPosted(ctx context.Context, id uuid.UUID, at time.Time) error {
+	ctx, span := startSpan(ctx, "ProcurementRepository.MarkReceiptPosted")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, `UPDATE goods_receipts SET posted_at = $2 WHERE id = $1 AND tenant_id = $3 AND posted_at IS NULL`, id, at.UTC(), tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		var exists bool
+		if err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM goods_receipts WHERE id = $1 AND tenant_id = $2)`, id, tenantID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return procurement.ErrReceiptNotFound
+		}
+	}
+	return nil
+}
+
+const supplierProductColumns = `supplier_id, product_id, supplier_sku, unit_cost, min_order_quantity, created_at, updated_at`
+
+// SetSupplierProduct adds a product to a supplier's of the tenant, or
+// updates its SKU, cost and minimum
+func (r *ProcurementRepository) SetSupplierProduct(ctx context.Context, p *procurement.SupplierProduct) error {
+	ctx, span := startSpan(ctx, "ProcurementRepository.SetSupplierProduct")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO supplier_products (
+			supplier_id, product_id, supplier_sku, unit_cost, min_order_quantity, created_at, updated_at, tenant_id
+		)
+		SELECT s.id, $2, $3, $4, $5, $6, $6, s.tenant_id
+		FROM suppliers s
+		WHERE s.id = $1 AND s.tenant_id = $7
+		ON CONFLICT (supplier_id, product_id) DO UPDATE SET
+			supplier_sku = EXCLUDED.supplier_sku,
+			unit_cost = EXCLUDED.unit_cost,
+			min_order_quantity = EXCLUDED.min_order_quantity,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at, updated_at
+	`
+
+	now := time.Now().UTC()
+	err = r.db.QueryRow(ctx, query,
+		p.SupplierID, p.ProductID, p.SupplierSKU, p.UnitCost, p.MinOrderQuantity, now, tenantID,
+	).Scan(&p.CreatedAt, &p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return procurement.ErrSupplierNotFound
+	}
+	return err
+}
+
+// ListSupplierProducts retrieves the products a supplier of the tenant sells
+func (r *ProcurementRepository) ListSupplierProducts(ctx context.Context, supplierID uuid.UUID) ([]*procurement.SupplierProduct, error) {
+	ctx, span := startSpan(ctx, "ProcurementRepository.ListSupplierProducts")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + supplierProductColumns + `
+		FROM supplier_products
+		WHERE supplier_id = $1 AND tenant_id = $2
+		ORDER BY created_at, product_id
+	`
+	return r.querySupplierProducts(ctx, query, supplierID, tenantID)
+}
+
+// DeleteSupplierProduct stops reordering a product from a supplier
+func (r *ProcurementRepository) DeleteSupplierProduct(ctx context.Context, supplierID, productID uuid.UUID) error {
+	ctx, span := startSpan(ctx, "ProcurementRepository.DeleteSupplierProduct")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx,
+		`DELETE FROM supplier_products WHERE supplier_id = $1 AND product_id = $2 AND tenant_id = $3`,
+		supplierID, productID, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return procurement.ErrSupplierProductNotFound
+	}
+	return nil
+}
+
+// ReorderSources retrieves the cheapest active supplier's product for each
+// of productIDs, the earliest added on a tie
+func (r *ProcurementRepository) ReorderSources(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]*procurement.SupplierProduct, error) {
+	ctx, span := startSpan(ctx, "ProcurementRepository.ReorderSources")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT DISTINCT ON (p.product_id) p.supplier_id, p.product_id, p.supplier_sku, p.unit_cost,
+			p.min_order_quantity, p.created_at, p.updated_at
+		FROM supplier_products p
+		JOIN suppliers s ON s.id = p.supplier_id
+		WHERE p.tenant_id = $2 AND p.product_id = ANY($1) AND s.active
+		ORDER BY p.product_id, p.unit_cost, p.created_at
+	`
+
+	products, err := r.querySupplierProducts(ctx, query, productIDs, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	sources := make(map[uuid.UUID]*procurement.SupplierProduct, len(products))
+	for _, p := range products {
+		sources[p.ProductID] = p
+	}
+	return sources, nil
+}
+
+// OnOrder retrieves which of productIDs have quantities outstanding on a
+// store's draft, submitted or partially received orders
+func (r *ProcurementRepository) OnOrder(ctx context.Context, storeID uuid.UUID, productIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	ctx, span := startSpan(ctx, "ProcurementRepository.OnOrder")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT DISTINCT l.product_id
+		FROM purchase_orders o
+		JOIN purchase_order_lines l ON l.purchase_order_id = o.id
+		WHERE o.store_id = $1 AND o.tenant_id = $3
+			AND o.status IN ('draft', 'submitted', 'partially_received')
+			AND l.product_id = ANY($2) AND l.received_quantity < l.quantity
+	`
+
+	rows, err := r.db.Query(ctx, query, storeID, productIDs, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	onOrder := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var productID uuid.UUID
+		if err := rows.Scan(&productID); err != nil {
+			return nil, err
+		}
+		onOrder[productID] = true
+	}
+	return onOrder, rows.Err()
+}
+
+// ReorderTenants retrieves the tenants with supplier products, across
+// tenants
+func (r *ProcurementRepository) ReorderTenants(ctx context.Context) ([]string, error) {
+	ctx, span := startSpan(ctx, "ProcurementRepository.ReorderTenants")
+	defer span.End()
+
+	rows, err := r.db.Query(ctx, `SELECT DISTINCT tenant_id FROM supplier_products ORDER BY tenant_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tenants := []string{}
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenantID)
+	}
+	return tenants, rows.Err()
+}
+
+// ClaimReorder records a tenant's reorder run at at, unless another was
+// recorded less than every before
+func (r *ProcurementRepository) ClaimReorder(ctx context.Context, tenantID string, at time.Time, every time.Duration) (bool, error) {
+	ctx, span := startSpan(ctx, "ProcurementRepository.ClaimReorder")
+	defer span.End()
+
+	query := `
+		INSERT INTO reorder_runs (tenant_id, ran_at) VALUES ($1, $2)
+		ON CONFLICT (tenant_id) DO UPDATE SET ran_at = EXCLUDED.ran_at
+		WHERE reorder_runs.ran_at <= $3
+	`
+
+	at = at.UTC()
+	tag, err := r.db.Exec(ctx, query, tenantID, at, at.Add(-every))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// OrderSamples retrieves the quantities and last receipt of the orders
+// submitted to a supplier in [from, to), other than cancelled ones
+func (r *ProcurementRepository) OrderSamples(ctx context.Context, supplierID uuid.UUID, from, to time.Time) ([]*procurement.OrderSample, error) {
+	ctx, span := startSpan(ctx, "ProcurementRepository.OrderSamples")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT o.id, o.status, o.submitted_at, o.expected_on,
+			COALESCE(SUM(l.quantity), 0), COALESCE(SUM(l.received_quantity), 0),
+			(SELECT MAX(g.received_at) FROM goods_receipts g WHERE g.purchase_order_id = o.id)
+		FROM purchase_orders o
+		LEFT JOIN purchase_order_lines l ON l.purchase_order_id = o.id
+		WHERE o.supplier_id = $1 AND o.tenant_id = $4 AND o.submitted_at >= $2 AND o.submitted_at < $3
+			AND o.status <> 'cancelled'
+		GROUP BY o.id
+		ORDER BY o.submitted_at
+	`
+
+	rows, err := r.db.Query(ctx, query, supplierID, from.UTC(), to.UTC(), tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := []*procurement.OrderSample{}
+	for rows.Next() {
+		var s procurement.OrderSample
+		var status string
+		if err := rows.Scan(
+			&s.OrderID, &status, &s.SubmittedAt, &s.ExpectedOn,
+			&s.OrderedQuantity, &s.ReceivedQuantity, &s.LastReceivedAt,
+		); err != nil {
+			return nil, err
+		}
+		s.Status = procurement.Status(status)
+		samples = append(samples, &s)
+	}
+	return samples, rows.Err()
+}
+
+// missingOr returns procurement.ErrOrderNotFound when the order does not
+// exist in the tenant, and err otherwise
+func (r *ProcurementRepository) missingOr(ctx context.Context, tenantID string, id uuid.UUID, err error) error {
+	var exists bool
+	if qerr := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM purchase_orders WHERE id = $1 AND tenant_id = $2)`, id, tenantID).Scan(&exists); qerr != nil {
+		return qerr
+	}
+	if !exists {
+		return procurement.ErrOrderNotFound
+	}
+	return err
+}
+
+// loadLines fills in the lines of orders, in their order
+func (r *ProcurementRepository) loadLines(ctx context.Context, orders []*procurement.PurchaseOrder) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	byID := make(map[uuid.UUID]*procurement.PurchaseOrder, len(orders))
+	ids := make([]uuid.UUID, len(orders))
+	for i, o := range orders {
+		o.Lines = []procurement.Line{}
+		byID[o.ID] = o
+		ids[i] = o.ID
+	}
+
+	query := `
+		SELECT purchase_order_id, id, product_id, supplier_sku, description, quantity, unit_cost, received_quantity
+		FROM purchase_order_lines
+		WHERE purchase_order_id = ANY($1)
+		ORDER BY purchase_order_id, position
+	`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID uuid.UUID
+		var l procurement.Line
+		if err := rows.Scan(
+			&orderID, &l.ID, &l.ProductID, &l.SupplierSKU, &l.Description, &l.Quantity, &l.UnitCost, &l.ReceivedQuantity,
+		); err != nil {
+			return err
+		}
+		o := byID[orderID]
+		o.Lines = append(o.Lines, l)
+	}
+	return rows.Err()
+}
+
+// queryReceipts runs a query for receipts and fills in their lines
+func (r *ProcurementRepository) queryReceipts(ctx context.Context, query string, args ...interface{}) ([]*procurement.GoodsReceipt, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	receipts := []*procurement.GoodsReceipt{}
+	byID := make(map[uuid.UUID]*procurement.GoodsReceipt)
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var rc procurement.GoodsReceipt
+		if err := rows.Scan(
+			&rc.ID, &rc.TenantID, &rc.PurchaseOrderID, &rc.StoreID, &rc.Note, &rc.ReceivedBy, &rc.ReceivedAt, &rc.PostedAt,
+		); err != nil {
+			return nil, err
+		}
+		rc.Lines = []procurement.ReceiptLine{}
+		receipts = append(receipts, &rc)
+		byID[rc.ID] = &rc
+		ids = append(ids, rc.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return receipts, nil
+	}
+
+	lineRows, err := r.db.Query(ctx, `
+		SELECT receipt_id, id, line_id, product_id, quantity, unit_cost
+		FROM goods_receipt_lines
+		WHERE receipt_id = ANY($1)
+		ORDER BY receipt_id, id
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer lineRows.Close()
+
+	for lineRows.Next() {
+		var receiptID uuid.UUID
+		var l procurement.ReceiptLine
+		if err := lineRows.Scan(&receiptID, &l.ID, &l.LineID, &l.ProductID, &l.Quantity, &l.UnitCost); err != nil {
+			return nil, err
+		}
+		rc := byID[receiptID]
+		rc.Lines = append(rc.Lines, l)
+	}
+	return receipts, lineRows.Err()
+}
+
+// querySupplierProducts runs a query for supplier products
+func (r *ProcurementRepository) querySupplierProducts(ctx context.Context, query string, args ...interface{}) ([]*procurement.SupplierProduct, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []*procurement.SupplierProduct{}
+	for rows.Next() {
+		var p procurement.SupplierProduct
+		if err := rows.Scan(
+			&p.SupplierID, &p.ProductID, &p.SupplierSKU, &p.UnitCost, &p.MinOrderQuantity, &p.CreatedAt, &p.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		products = append(products, &p)
+	}
+	return products, rows.Err()
+}
+
+// lineArrays holds order lines column by column, for unnest
+type lineArrays struct {
+	ids          []uuid.UUID
+	productIDs   []uuid.UUID
+	skus         []string
+	descriptions []string
+	quantities   []int
+	unitCosts    []float64
+}
+
+func newLineArrays(lines []procurement.Line) lineArrays {
+	a := lineArrays{
+		ids:          make([]uuid.UUID, len(lines)),
+		productIDs:   make([]uuid.UUID, len(lines)),
+		skus:         make([]string, len(lines)),
+		descriptions: make([]string, len(lines)),
+		quantities:   make([]int, len(lines)),
+		unitCosts:    make([]float64, len(lines)),
+	}
+	for i, l := range lines {
+		a.ids[i] = l.ID
+		a.productIDs[i] = l.ProductID
+		a.skus[i] = l.SupplierSKU
+		a.descriptions[i] = l.Description
+		a.quantities[i] = l.Quantity
+		a.unitCosts[i] = l.UnitCost
+	}
+	return a
+}
+
+// scanSupplier scans a row into a Supplier
+func scanSupplier(row interface{ Scan(dest ...interface{}) error }) (*procurement.Supplier, error) {
+	var s procurement.Supplier
+	err := row.Scan(
+		&s.ID, &s.Code, &s.Name, &s.ContactName, &s.Email, &s.Phone, &s.Address, &s.Currency,
+		&s.PaymentTerms, &s.LeadTimeDays, &s.Active, &s.Notes, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// scanPurchaseOrder scans a row into a PurchaseOrder, without its lines
+func scanPurchaseOrder(row interface{ Scan(dest ...interface{}) error }) (*procurement.PurchaseOrder, error) {
+	var o procurement.PurchaseOrder
+	var status string
+	err := row.Scan(
+		&o.ID, &o.Number, &o.SupplierID, &o.StoreID, &status, &o.Currency, &o.Total, &o.ExpectedOn, &o.Notes,
+		&o.CreatedBy, &o.SubmittedAt, &o.ReceivedAt, &o.ClosedAt, &o.CreatedAt, &o.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	o.Status = procurement.Status(status)
+	return &o, nil
+}
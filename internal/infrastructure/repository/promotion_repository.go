@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/onichange/pos-system/internal/domain/promotion"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// promotionColumns are the columns scanPromotion reads
+const promotionColumns = `
+	id, name, description, type, rule, priority, exclusive, active,
+	coupon_only, store_ids, currency, starts_at, ends_at, created_at, updated_at
+`
+
+// PromotionRepository implements promotion.Repository. Every query is scoped
+// to the tenant in ctx and fails with tenant.ErrNoTenant when there is none.
+type PromotionRepository struct {
+	db database.Querier
+}
+
+// NewPromotionRepository creates a new promotion repository
+func NewPromotionRepository(db database.Querier) *PromotionRepository {
+	return &PromotionRepository{db: db}
+}
+
+// Create creates a new promotion
+func (r *PromotionRepository) Create(ctx context.Context, p *promotion.Promotion) error {
+	ctx, span := startSpan(ctx, "PromotionRepository.Create")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	ruleJSON, err := json.Marshal(p.Rule)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO promotions (
+			id, name, description, type, rule, priority, exclusive, active,
+			store_ids, currency, starts_at, ends_at, created_at, updated_at, tenant_id, coupon_only
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $13, $14, $15)
+	`
+
+	now := time.Now()
+	_, err = r.db.Exec(ctx, query,
+		p.ID, p.Name, p.Description, string(p.Type), ruleJSON, p.Priority, p.Exclusive, p.Active,
+		storeIDsOrEmpty(p.StoreIDs), p.Currency, p.StartsAt, p.EndsAt, now, tenantID, p.CouponOnly,
+	)
+	if err != nil {
+		return err
+	}
+	p.CreatedAt, p.UpdatedAt = now, now
+	return nil
+}
+
+// GetByID retrieves a promotion by ID
+func (r *PromotionRepository) GetByID(ctx context.Context, id uuid.UUID) (*promotion.Promotion, error) {
+	ctx, span := startSpan(ctx, "PromotionRepository.GetByID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + promotionColumns + ` FROM promotions WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL`
+
+	p, err := scanPromotion(r.db.QueryRow(ctx, query, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, promotion.ErrNotFound
+	}
+	return p, err
+}
+
+// List retrieves promotions by descending priority
+func (r *PromotionRepository) List(ctx context.Context, activeOnly bool, limit, offset int) ([]*promotion.Promotion, error) {
+	ctx, span := startSpan(ctx, "PromotionRepository.List")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + promotionColumns + `
+		FROM promotions
+		WHERE tenant_id = $4 AND deleted_at IS NULL AND (active OR NOT $1)
+		ORDER BY priority DESC, created_at
+		LIMIT $2 OFFSET $3
+	`
+	return r.queryPromotions(ctx, query, activeOnly, limit, offset, tenantID)
+}
+
+// Update updates a promotion
+func (r *PromotionRepository) Update(ctx context.Context, p *promotion.Promotion) error {
+	ctx, span := startSpan(ctx, "PromotionRepository.Update")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	ruleJSON, err := json.Marshal(p.Rule)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE promotions SET
+			name = $2, description = $3, type = $4, rule = $5, priority = $6,
+			exclusive = $7, active = $8, store_ids = $9, currency = $10,
+			starts_at = $11, ends_at = $12, updated_at = $13, coupon_only = $15
+		WHERE id = $1 AND tenant_id = $14 AND deleted_at IS NULL
+	`
+
+	now := time.Now()
+	tag, err := r.db.Exec(ctx, query,
+		p.ID, p.Name, p.Description, string(p.Type), ruleJSON, p.Priority,
+		p.Exclusive, p.Active, storeIDsOrEmpty(p.StoreIDs), p.Currency,
+		p.StartsAt, p.EndsAt, now, tenantID, p.CouponOnly,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return promotion.ErrNotFound
+	}
+	p.UpdatedAt = now
+	return nil
+}
+
+// Delete soft deletes a promotion
+func (r *PromotionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startSpan(ctx, "PromotionRepository.Delete")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE promotions SET deleted_at = $2, updated_at = $2 WHERE id = $1 AND tenant_id = $3 AND deleted_at IS NULL`
+	tag, err := r.db.Exec(ctx, query, id, time.Now(), tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return promotion.ErrNotFound
+	}
+	return nil
+}
+
+// GetRunning retrieves the active promotions of a store scheduled for now
+func (r *PromotionRepository) GetRunning(ctx context.Context, storeID uuid.UUID, now time.Time) ([]*promotion.Promotion, error) {
+	ctx, span := startSpan(ctx, "PromotionRepository.GetRunning")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + promotionColumns + `
+		FROM promotions
+		WHERE tenant_id = $3 AND deleted_at IS NULL AND active
+			AND (starts_at IS NULL OR starts_at <= $2)
+			AND (ends_at IS NULL OR ends_at > $2)
+			AND (cardinality(store_ids) = 0 OR store_ids @> ARRAY[$1::uuid])
+		ORDER BY priority DESC, created_at
+	`
+	return r.queryPromotions(ctx, query, storeID, now, tenantID)
+}
+
+// queryPromotions runs a query selecting promotionColumns
+func (r *PromotionRepository) queryPromotions(ctx context.Context, query string, args ...interface{}) ([]*promotion.Promotion, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var promotions []*promotion.Promotion
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, p)
+	}
+	return promotions, rows.Err()
+}
+
+// scanPromotion scans a row into a Promotion
+func scanPromotion(rows interface {
+	Scan(dest ...interface{}) error
+}) (*promotion.Promotion, error) {
+	var p promotion.Promotion
+	var description, currency sql.NullString
+	var promotionType string
+	var ruleJSON []byte
+	var startsAt, endsAt sql.NullTime
+
+	err := rows.Scan(
+		&p.ID, &p.Name, &description, &promotionType, &ruleJSON, &p.Priority, &p.Exclusive, &p.Active,
+		&p.CouponOnly, &p.StoreIDs, &currency, &startsAt, &endsAt, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Description = description.String
+	p.Type = promotion.RuleType(promotionType)
+	p.Currency = currency.String
+	if startsAt.Valid {
+		p.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		p.EndsAt = &endsAt.Time
+	}
+	if err := json.Unmarshal(ruleJSON, &p.Rule); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// storeIDsOrEmpty stores a promotion for every store as an empty array
+func storeIDsOrEmpty(storeIDs []uuid.UUID) []uuid.UUID {
+	if storeIDs == nil {
+		return []uuid.UUID{}
+	}
+	return storeIDs
+}
@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/onichange/pos-system/internal/domain/receipt"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// printJobColumns are the columns scanPrintJob reads after the payload
+const printJobColumns = `
+	id, store_id, printer_id, order_id, kind, status, attempts, error,
+	created_at, claimed_at, completed_at
+`
+
+// ReceiptRepository implements receipt.Repository. Every query is scoped to
+// the tenant in ctx and fails with tenant.ErrNoTenant when there is none.
+type ReceiptRepository struct {
+	db database.Querier
+}
+
+// NewReceiptRepository creates a new receipt repository
+func NewReceiptRepository(db database.Querier) *ReceiptRepository {
+	return &ReceiptRepository{db: db}
+}
+
+// GetTemplate retrieves a store's receipt template
+func (r *ReceiptRepository) GetTemplate(ctx context.Context, storeID uuid.UUID) (*receipt.Template, error) {
+	ctx, span := startSpan(ctx, "ReceiptRepository.GetTemplate")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT store_id, header, footer, width, show_barcode, open_drawer, drawer_pin, updated_at
+		FROM receipt_templates
+		WHERE store_id = $1 AND tenant_id = $2
+	`
+
+	var t receipt.Template
+	err = r.db.QueryRow(ctx, query, storeID, tenantID).Scan(
+		&t.StoreID, &t.Header, &t.Footer, &t.Width, &t.ShowBarcode, &t.OpenDrawer, &t.DrawerPin, &t.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return receipt.DefaultTemplate(storeID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SaveTemplate creates or replaces a store's receipt template
+func (r *ReceiptRepository) SaveTemplate(ctx context.Context, t *receipt.Template) error {
+	ctx, span := startSpan(ctx, "ReceiptRepository.SaveTemplate")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO receipt_templates (
+			store_id, header, footer, width, show_barcode, open_drawer, drawer_pin, updated_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (store_id) DO UPDATE SET
+			header = EXCLUDED.header,
+			footer = EXCLUDED.footer,
+			width = EXCLUDED.width,
+			show_barcode = EXCLUDED.show_barcode,
+			open_drawer = EXCLUDED.open_drawer,
+			drawer_pin = EXCLUDED.drawer_pin,
+			updated_at = EXCLUDED.updated_at
+		WHERE receipt_templates.tenant_id = EXCLUDED.tenant_id
+	`
+
+	now := time.Now()
+	_, err = r.db.Exec(ctx, query,
+		t.StoreID, linesOrEmpty(t.Header), linesOrEmpty(t.Footer), t.Width, t.ShowBarcode, t.OpenDrawer, t.DrawerPin, now, tenantID,
+	)
+	if err != nil {
+		return err
+	}
+	t.UpdatedAt = now
+	return nil
+}
+
+// CreateJob queues a print job
+func (r *ReceiptRepository) CreateJob(ctx context.Context, job *receipt.PrintJob) error {
+	ctx, span := startSpan(ctx, "ReceiptRepository.CreateJob")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO print_jobs (id, store_id, printer_id, order_id, kind, status, payload, created_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	now := time.Now().UTC()
+	_, err = r.db.Exec(ctx, query,
+		job.ID, job.StoreID, job.PrinterID, job.OrderID, string(job.Kind), string(job.Status), job.Payload, now, tenantID,
+	)
+	if err != nil {
+		return err
+	}
+	job.CreatedAt = now
+	return nil
+}
+
+// GetJob retrieves a print job by ID
+func (r *ReceiptRepository) GetJob(ctx context.Context, id uuid.UUID) (*receipt.PrintJob, error) {
+	ctx, span := startSpan(ctx, "ReceiptRepository.GetJob")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT payload, ` + printJobColumns + ` FROM print_jobs WHERE id = $1 AND tenant_id = $2`
+
+	job, err := scanPrintJob(r.db.QueryRow(ctx, query, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, receipt.ErrJobNotFound
+	}
+	return job, err
+}
+
+// ListJobs retrieves a store's print jobs, newest first
+func (r *ReceiptRepository) ListJobs(ctx context.Context, storeID uuid.UUID, status receipt.JobStatus, limit, offset int) ([]*receipt.PrintJob, error) {
+	ctx, span := startSpan(ctx, "ReceiptRepository.ListJobs")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT NULL::bytea, ` + printJobColumns + `
+		FROM print_jobs
+		WHERE store_id = $1 AND tenant_id = $5 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.Query(ctx, query, storeID, string(status), limit, offset, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*receipt.PrintJob{}
+	for rows.Next() {
+		job, err := scanPrintJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// ClaimJobs hands queued and out of lease jobs to a store's agent. Rows are
+// locked with SKIP LOCKED, so agents claiming at once get different jobs.
+func (r *ReceiptRepository) ClaimJobs(ctx context.Context, storeID uuid.UUID, printerID string, limit int, now time.Time, lease time.Duration, maxAttempts int) ([]*receipt.PrintJob, error) {
+	ctx, span := startSpan(ctx, "ReceiptRepository.ClaimJobs")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH abandoned AS (
+			UPDATE print_jobs
+			SET status = 'failed', error = 'Not acknowledged by a print agent', completed_at = $4
+			WHERE store_id = $1 AND tenant_id = $7 AND status = 'printing' AND claimed_at < $5 AND attempts >= $6
+		), claimable AS (
+			SELECT id FROM print_jobs
+			WHERE store_id = $1 AND tenant_id = $7 AND (printer_id = '' OR printer_id = $2)
+				AND (status = 'queued' OR (status = 'printing' AND claimed_at < $5 AND attempts < $6))
+			ORDER BY created_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE print_jobs j
+		SET status = 'printing', claimed_at = $4, attempts = j.attempts + 1
+		FROM claimable
+		WHERE j.id = claimable.id
+		RETURNING j.payload, j.id, j.store_id, j.printer_id, j.order_id, j.kind, j.status, j.attempts, j.error,
+			j.created_at, j.claimed_at, j.completed_at
+	`
+
+	now = now.UTC()
+	rows, err := r.db.Query(ctx, query, storeID, printerID, limit, now, now.Add(-lease), maxAttempts, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*receipt.PrintJob{}
+	for rows.Next() {
+		job, err := scanPrintJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// RETURNING follows no order; agents print oldest first
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return jobs, nil
+}
+
+// CompleteJob records an agent's acknowledgement of a job
+func (r *ReceiptRepository) CompleteJob(ctx context.Context, id, storeID uuid.UUID, printed bool, message string, now time.Time) (*receipt.PrintJob, error) {
+	ctx, span := startSpan(ctx, "ReceiptRepository.CompleteJob")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := receipt.JobFailed
+	if printed {
+		status = receipt.JobPrinted
+	}
+	query := `
+		UPDATE print_jobs
+		SET status = $3, error = NULLIF($4, ''), completed_at = $5
+		WHERE id = $1 AND store_id = $2 AND tenant_id = $6 AND status = 'printing'
+		RETURNING NULL::bytea, ` + printJobColumns
+
+	job, err := scanPrintJob(r.db.QueryRow(ctx, query, id, storeID, string(status), message, now.UTC(), tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Tell a job acknowledged twice or out of lease from one never handed out
+		var exists bool
+		if err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM print_jobs WHERE id = $1 AND store_id = $2 AND tenant_id = $3)`, id, storeID, tenantID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, receipt.ErrJobClosed
+		}
+		return nil, receipt.ErrJobNotFound
+	}
+	return job, err
+}
+
+// scanPrintJob scans a payload followed by printJobColumns
+func scanPrintJob(row interface{ Scan(dest ...interface{}) error }) (*receipt.PrintJob, error) {
+	var job receipt.PrintJob
+	var kind, status string
+	var message sql.NullString
+
+	err := row.Scan(
+		&job.Payload, &job.ID, &job.StoreID, &job.PrinterID, &job.OrderID, &kind, &status, &job.Attempts, &message,
+		&job.CreatedAt, &job.ClaimedAt, &job.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	job.Kind = receipt.JobKind(kind)
+	job.Status = receipt.JobStatus(status)
+	job.Error = message.String
+	return &job, nil
+}
+
+// linesOrEmpty stores a nil list of template lines as an empty array
+func linesOrEmpty(lines []string) []string {
+	if lines == nil {
+		return []string{}
+	}
+	return lines
+}
@@ -0,0 +1,336 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/search"
+	searchclient "github.com/onichange/pos-system/pkg/search"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// Names of the search indexes, before the configured prefix
+const (
+	ordersIndex   = "orders"
+	productsIndex = "products"
+)
+
+// Sizes of the terms facets
+const (
+	statusFacetSize = 20
+	valueFacetSize  = 50
+)
+
+// ordersMapping is the mapping of the orders index. Customer and item names
+// are analyzed for full-text search; IDs and statuses are exact keywords.
+var ordersMapping = map[string]any{
+	"mappings": map[string]any{
+		"dynamic": "strict",
+		"properties": map[string]any{
+			"id":             map[string]any{"type": "keyword"},
+			"tenant_id":      map[string]any{"type": "keyword"},
+			"store_id":       map[string]any{"type": "keyword"},
+			"user_id":        map[string]any{"type": "keyword"},
+			"customer_name":  map[string]any{"type": "text"},
+			"customer_email": map[string]any{"type": "text", "fields": map[string]any{"keyword": map[string]any{"type": "keyword"}}},
+			"status":         map[string]any{"type": "keyword"},
+			"total_amount":   map[string]any{"type": "double"},
+			"currency":       map[string]any{"type": "keyword"},
+			"item_names":     map[string]any{"type": "text"},
+			"product_ids":    map[string]any{"type": "keyword"},
+			"created_at":     map[string]any{"type": "date"},
+			"updated_at":     map[string]any{"type": "date"},
+		},
+	},
+}
+
+// productsMapping is the mapping of the products index. SKUs and barcodes
+// are lowercased keywords so scanners and staff typing them both match.
+var productsMapping = map[string]any{
+	"settings": map[string]any{
+		"analysis": map[string]any{
+			"normalizer": map[string]any{
+				"lowercase": map[string]any{"type": "custom", "filter": []string{"lowercase"}},
+			},
+		},
+	},
+	"mappings": map[string]any{
+		"dynamic": "strict",
+		"properties": map[string]any{
+			"id":            map[string]any{"type": "keyword"},
+			"tenant_id":     map[string]any{"type": "keyword"},
+			"category_id":   map[string]any{"type": "keyword"},
+			"name":          map[string]any{"type": "text", "fields": map[string]any{"keyword": map[string]any{"type": "keyword"}}},
+			"description":   map[string]any{"type": "text"},
+			"brand":         map[string]any{"type": "text", "fields": map[string]any{"keyword": map[string]any{"type": "keyword"}}},
+			"status":        map[string]any{"type": "keyword"},
+			"skus":          map[string]any{"type": "keyword", "normalizer": "lowercase"},
+			"barcodes":      map[string]any{"type": "keyword", "normalizer": "lowercase"},
+			"variant_names": map[string]any{"type": "text"},
+			"updated_at":    map[string]any{"type": "date"},
+		},
+	},
+}
+
+// SearchRepository implements search.Repository on OpenSearch. Tenants
+// share one index per document kind; documents carry their tenant and every
+// search is filtered to the tenant in ctx, failing with tenant.ErrNoTenant
+// when there is none.
+type SearchRepository struct {
+	client *searchclient.Client
+}
+
+// NewSearchRepository creates a new search repository
+func NewSearchRepository(client *searchclient.Client) *SearchRepository {
+	return &SearchRepository{client: client}
+}
+
+// EnsureIndexes creates the orders and products indexes if missing
+func (r *SearchRepository) EnsureIndexes(ctx context.Context) error {
+	if err := r.client.EnsureIndex(ctx, r.client.Index(ordersIndex), ordersMapping); err != nil {
+		return err
+	}
+	return r.client.EnsureIndex(ctx, r.client.Index(productsIndex), productsMapping)
+}
+
+// IndexOrder indexes an order under the tenant in ctx
+func (r *SearchRepository) IndexOrder(ctx context.Context, doc *search.OrderDocument, version int64) error {
+	ctx, span := startSpan(ctx, "SearchRepository.IndexOrder")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+	doc.TenantID = tenantID
+
+	return r.client.Put(ctx, r.client.Index(ordersIndex), doc.ID.String(), version, doc)
+}
+
+// IndexProduct indexes a product under the tenant in ctx
+func (r *SearchRepository) IndexProduct(ctx context.Context, doc *search.ProductDocument, version int64) error {
+	ctx, span := startSpan(ctx, "SearchRepository.IndexProduct")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+	doc.TenantID = tenantID
+
+	return r.client.Put(ctx, r.client.Index(productsIndex), doc.ID.String(), version, doc)
+}
+
+// DeleteProduct removes a product from the index
+func (r *SearchRepository) DeleteProduct(ctx context.Context, id uuid.UUID, version int64) error {
+	ctx, span := startSpan(ctx, "SearchRepository.DeleteProduct")
+	defer span.End()
+
+	if _, err := tenant.Scope(ctx); err != nil {
+		return err
+	}
+
+	return r.client.Delete(ctx, r.client.Index(productsIndex), id.String(), version)
+}
+
+// SearchOrders returns the orders matching q, best matches first when q has
+// text and newest first otherwise. An order ID as text finds that order.
+func (r *SearchRepository) SearchOrders(ctx context.Context, q search.OrderQuery) (*search.OrderResults, error) {
+	ctx, span := startSpan(ctx, "SearchRepository.SearchOrders")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []any{term("tenant_id", tenantID)}
+	if q.Status != "" {
+		filters = append(filters, term("status", q.Status))
+	}
+	if q.StoreID != nil {
+		filters = append(filters, term("store_id", q.StoreID.String()))
+	}
+	if q.From != nil || q.To != nil {
+		bounds := map[string]any{}
+		if q.From != nil {
+			bounds["gte"] = q.From
+		}
+		if q.To != nil {
+			bounds["lt"] = q.To
+		}
+		filters = append(filters, map[string]any{"range": map[string]any{"created_at": bounds}})
+	}
+
+	boolQuery := map[string]any{"filter": filters}
+	text := strings.TrimSpace(q.Text)
+	if id, err := uuid.Parse(text); err == nil {
+		filters = append(filters, term("id", id.String()))
+		boolQuery["filter"] = filters
+	} else if text != "" {
+		boolQuery["must"] = []any{fuzzyMatch(text, "customer_name^3", "customer_email^2", "item_names")}
+	}
+
+	interval := q.Interval
+	if interval == "" {
+		interval = search.Day
+	}
+	body := map[string]any{
+		"query":            map[string]any{"bool": boolQuery},
+		"from":             q.Offset,
+		"size":             q.Limit,
+		"track_total_hits": true,
+		"sort":             sortBy(text, "created_at", "desc"),
+		"aggs": map[string]any{
+			"status":   termsAgg("status", statusFacetSize),
+			"store_id": termsAgg("store_id", valueFacetSize),
+			"created_at": map[string]any{
+				"date_histogram": map[string]any{
+					"field":             "created_at",
+					"calendar_interval": string(interval),
+					"min_doc_count":     1,
+				},
+			},
+		},
+	}
+
+	resp, err := r.client.Search(ctx, r.client.Index(ordersIndex), body)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &search.OrderResults{
+		Total:  resp.Total,
+		Orders: make([]*search.OrderDocument, 0, len(resp.Hits)),
+		Facets: facets(resp),
+	}
+	for _, hit := range resp.Hits {
+		var doc search.OrderDocument
+		if err := json.Unmarshal(hit, &doc); err != nil {
+			return nil, err
+		}
+		results.Orders = append(results.Orders, &doc)
+	}
+	return results, nil
+}
+
+// SearchProducts returns the products matching q, best matches first when
+// q has text and by name otherwise
+func (r *SearchRepository) SearchProducts(ctx context.Context, q search.ProductQuery) (*search.ProductResults, error) {
+	ctx, span := startSpan(ctx, "SearchRepository.SearchProducts")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []any{term("tenant_id", tenantID)}
+	if q.Status != "" {
+		filters = append(filters, term("status", q.Status))
+	}
+	if q.Brand != "" {
+		filters = append(filters, term("brand.keyword", q.Brand))
+	}
+	if q.CategoryID != nil {
+		filters = append(filters, term("category_id", q.CategoryID.String()))
+	}
+
+	boolQuery := map[string]any{"filter": filters}
+	text := strings.TrimSpace(q.Text)
+	if text != "" {
+		// A scanned SKU or barcode ranks its product first
+		boolQuery["must"] = []any{map[string]any{
+			"bool": map[string]any{
+				"should": []any{
+					fuzzyMatch(text, "name^3", "brand^2", "variant_names^2", "description"),
+					map[string]any{"term": map[string]any{"skus": map[string]any{"value": text, "boost": 10}}},
+					map[string]any{"term": map[string]any{"barcodes": map[string]any{"value": text, "boost": 10}}},
+				},
+				"minimum_should_match": 1,
+			},
+		}}
+	}
+
+	body := map[string]any{
+		"query":            map[string]any{"bool": boolQuery},
+		"from":             q.Offset,
+		"size":             q.Limit,
+		"track_total_hits": true,
+		"sort":             sortBy(text, "name.keyword", "asc"),
+		"aggs": map[string]any{
+			"status":      termsAgg("status", statusFacetSize),
+			"brand":       termsAgg("brand.keyword", valueFacetSize),
+			"category_id": termsAgg("category_id", valueFacetSize),
+		},
+	}
+
+	resp, err := r.client.Search(ctx, r.client.Index(productsIndex), body)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &search.ProductResults{
+		Total:    resp.Total,
+		Products: make([]*search.ProductDocument, 0, len(resp.Hits)),
+		Facets:   facets(resp),
+	}
+	for _, hit := range resp.Hits {
+		var doc search.ProductDocument
+		if err := json.Unmarshal(hit, &doc); err != nil {
+			return nil, err
+		}
+		results.Products = append(results.Products, &doc)
+	}
+	return results, nil
+}
+
+// term returns an exact match query on a keyword field
+func term(field, value string) map[string]any {
+	return map[string]any{"term": map[string]any{field: value}}
+}
+
+// fuzzyMatch returns a full-text query over fields that tolerates typos,
+// allowing more edits the longer each word is. Every word must match one of
+// the fields.
+func fuzzyMatch(text string, fields ...string) map[string]any {
+	return map[string]any{
+		"multi_match": map[string]any{
+			"query":         text,
+			"fields":        fields,
+			"fuzziness":     "AUTO",
+			"prefix_length": 1,
+			"operator":      "and",
+		},
+	}
+}
+
+// termsAgg returns a facet counting the most frequent values of a field
+func termsAgg(field string, size int) map[string]any {
+	return map[string]any{"terms": map[string]any{"field": field, "size": size}}
+}
+
+// sortBy sorts by relevance when there is text to rank by, then by field
+func sortBy(text, field, order string) []any {
+	var sort []any
+	if text != "" {
+		sort = append(sort, "_score")
+	}
+	return append(sort, map[string]any{field: order})
+}
+
+// facets converts the aggregations of a search response
+func facets(resp *searchclient.Response) map[string][]search.Bucket {
+	out := make(map[string][]search.Bucket, len(resp.Aggregations))
+	for name, buckets := range resp.Aggregations {
+		values := make([]search.Bucket, len(buckets))
+		for i, b := range buckets {
+			values[i] = search.Bucket{Key: b.Key, Count: b.Count}
+		}
+		out[name] = values
+	}
+	return out
+}
@@ -0,0 +1,353 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/onichange/pos-system/internal/domain/shift"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// shiftColumns are the columns scanShift reads
+const shiftColumns = `
+	id, store_id, register_id, staff_id, status, currency, opening_float, counted_cash,
+	notes, opened_at, closed_at, closed_by
+`
+
+// ShiftRepository implements shift.Repository. Every query is scoped to the
+// tenant in ctx and fails with tenant.ErrNoTenant when there is none.
+type ShiftRepository struct {
+	db database.Querier
+}
+
+// NewShiftRepository creates a new shift repository
+func NewShiftRepository(db database.Querier) *ShiftRepository {
+	return &ShiftRepository{db: db}
+}
+
+// Open starts a shift
+func (r *ShiftRepository) Open(ctx context.Context, s *shift.Shift) error {
+	ctx, span := startSpan(ctx, "ShiftRepository.Open")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO shifts (id, store_id, register_id, staff_id, status, currency, opening_float, notes, opened_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	now := time.Now().UTC()
+	_, err = r.db.Exec(ctx, query,
+		s.ID, s.StoreID, s.RegisterID, s.StaffID, string(shift.StatusOpen), s.Currency, s.OpeningFloat, s.Notes, now, tenantID,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return shift.ErrShiftOpen
+	}
+	if err != nil {
+		return err
+	}
+	s.Status = shift.StatusOpen
+	s.OpenedAt = now
+	return nil
+}
+
+// GetByID retrieves a shift by ID
+func (r *ShiftRepository) GetByID(ctx context.Context, id uuid.UUID) (*shift.Shift, error) {
+	ctx, span := startSpan(ctx, "ShiftRepository.GetByID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + shiftColumns + ` FROM shifts WHERE id = $1 AND tenant_id = $2`
+
+	s, err := scanShift(r.db.QueryRow(ctx, query, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, shift.ErrShiftNotFound
+	}
+	return s, err
+}
+
+// List retrieves the shifts matching filter, newest first
+func (r *ShiftRepository) List(ctx context.Context, filter shift.Filter, limit, offset int) ([]*shift.Shift, error) {
+	ctx, span := startSpan(ctx, "ShiftRepository.List")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + shiftColumns + `
+		FROM shifts
+		WHERE tenant_id = $7
+			AND ($1::uuid IS NULL OR store_id = $1)
+			AND ($2 = '' OR register_id = $2)
+			AND ($3::uuid IS NULL OR staff_id = $3)
+			AND ($4 = '' OR status = $4)
+			AND ($8::timestamp IS NULL OR closed_at >= $8)
+			AND ($9::timestamp IS NULL OR closed_at < $9)
+		ORDER BY opened_at DESC
+		LIMIT $5 OFFSET $6
+	`
+
+	rows, err := r.db.Query(ctx, query,
+		filter.StoreID, filter.RegisterID, filter.StaffID, string(filter.Status), limit, offset, tenantID,
+		filter.ClosedFrom, filter.ClosedTo,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shifts := []*shift.Shift{}
+	for rows.Next() {
+		s, err := scanShift(rows)
+		if err != nil {
+			return nil, err
+		}
+		shifts = append(shifts, s)
+	}
+	return shifts, rows.Err()
+}
+
+// Close closes an open shift. Closing first, and reporting after, keeps
+// cash movements from slipping in behind the Z report.
+func (r *ShiftRepository) Close(ctx context.Context, id, closedBy uuid.UUID, countedCash float64, notes string, now time.Time) (*shift.Shift, error) {
+	ctx, span := startSpan(ctx, "ShiftRepository.Close")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE shifts
+		SET status = 'closed', counted_cash = $3, closed_at = $4, closed_by = $2,
+			notes = CASE WHEN $5 = '' THEN notes ELSE $5 END
+		WHERE id = $1 AND tenant_id = $6 AND status = 'open'
+		RETURNING ` + shiftColumns
+
+	s, err := scanShift(r.db.QueryRow(ctx, query, id, closedBy, countedCash, now.UTC(), notes, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, r.closedOrMissing(ctx, tenantID, id)
+	}
+	return s, err
+}
+
+// SaveReport stores the Z report of a closed shift
+func (r *ShiftRepository) SaveReport(ctx context.Context, id uuid.UUID, report *shift.Report) error {
+	ctx, span := startSpan(ctx, "ShiftRepository.SaveReport")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE shifts SET z_report = $2 WHERE id = $1 AND tenant_id = $3 AND status = 'closed' AND z_report IS NULL`
+	_, err = r.db.Exec(ctx, query, id, reportJSON, tenantID)
+	return err
+}
+
+// GetReport retrieves a shift's stored Z report
+func (r *ShiftRepository) GetReport(ctx context.Context, id uuid.UUID) (*shift.Report, error) {
+	ctx, span := startSpan(ctx, "ShiftRepository.GetReport")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reportJSON []byte
+	err = r.db.QueryRow(ctx, `SELECT z_report FROM shifts WHERE id = $1 AND tenant_id = $2`, id, tenantID).Scan(&reportJSON)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, shift.ErrShiftNotFound
+	}
+	if err != nil || reportJSON == nil {
+		return nil, err
+	}
+
+	var report shift.Report
+	if err := json.Unmarshal(reportJSON, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// AddMovement records a cash movement, in the same statement that checks
+// the shift is open
+func (r *ShiftRepository) AddMovement(ctx context.Context, m *shift.CashMovement) error {
+	ctx, span := startSpan(ctx, "ShiftRepository.AddMovement")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO cash_movements (id, shift_id, type, amount, reason, staff_id, created_at, tenant_id)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8
+		WHERE EXISTS (SELECT 1 FROM shifts WHERE id = $2 AND tenant_id = $8 AND status = 'open')
+	`
+
+	now := time.Now().UTC()
+	tag, err := r.db.Exec(ctx, query, m.ID, m.ShiftID, string(m.Type), m.Amount, m.Reason, m.StaffID, now, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return r.closedOrMissing(ctx, tenantID, m.ShiftID)
+	}
+	m.CreatedAt = now
+	return nil
+}
+
+// GetMovements retrieves a shift's cash movements, oldest first
+func (r *ShiftRepository) GetMovements(ctx context.Context, shiftID uuid.UUID) ([]*shift.CashMovement, error) {
+	ctx, span := startSpan(ctx, "ShiftRepository.GetMovements")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, shift_id, type, amount, reason, staff_id, created_at
+		FROM cash_movements
+		WHERE shift_id = $1 AND tenant_id = $2
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, shiftID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := []*shift.CashMovement{}
+	for rows.Next() {
+		var m shift.CashMovement
+		var movementType string
+		if err := rows.Scan(&m.ID, &m.ShiftID, &movementType, &m.Amount, &m.Reason, &m.StaffID, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		m.Type = shift.MovementType(movementType)
+		movements = append(movements, &m)
+	}
+	return movements, rows.Err()
+}
+
+// RecordSale records a sale, or moves a completed one to voided or refunded
+func (r *ShiftRepository) RecordSale(ctx context.Context, sale *shift.Sale) error {
+	ctx, span := startSpan(ctx, "ShiftRepository.RecordSale")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO shift_sales (order_id, shift_id, tender, amount, tax, currency, status, recorded_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (order_id) DO UPDATE SET status = EXCLUDED.status
+		WHERE shift_sales.status = 'completed' AND EXCLUDED.status <> 'completed'
+			AND shift_sales.tenant_id = EXCLUDED.tenant_id
+	`
+
+	_, err = r.db.Exec(ctx, query,
+		sale.OrderID, sale.ShiftID, string(sale.Tender), sale.Amount, sale.Tax, sale.Currency, string(sale.Status), time.Now().UTC(), tenantID,
+	)
+	return err
+}
+
+// GetSales retrieves the sales rung up on a shift
+func (r *ShiftRepository) GetSales(ctx context.Context, shiftID uuid.UUID) ([]*shift.Sale, error) {
+	ctx, span := startSpan(ctx, "ShiftRepository.GetSales")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT order_id, shift_id, tender, amount, tax, currency, status, recorded_at
+		FROM shift_sales
+		WHERE shift_id = $1 AND tenant_id = $2
+		ORDER BY recorded_at
+	`
+
+	rows, err := r.db.Query(ctx, query, shiftID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sales := []*shift.Sale{}
+	for rows.Next() {
+		var sale shift.Sale
+		var tender, status string
+		if err := rows.Scan(&sale.OrderID, &sale.ShiftID, &tender, &sale.Amount, &sale.Tax, &sale.Currency, &status, &sale.RecordedAt); err != nil {
+			return nil, err
+		}
+		sale.Tender = shift.Tender(tender)
+		sale.Status = shift.SaleStatus(status)
+		sales = append(sales, &sale)
+	}
+	return sales, rows.Err()
+}
+
+// closedOrMissing tells a closed shift of a tenant from one that does not
+// exist
+func (r *ShiftRepository) closedOrMissing(ctx context.Context, tenantID string, id uuid.UUID) error {
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM shifts WHERE id = $1 AND tenant_id = $2)`, id, tenantID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return shift.ErrShiftClosed
+	}
+	return shift.ErrShiftNotFound
+}
+
+// scanShift scans shiftColumns into a Shift
+func scanShift(row interface{ Scan(dest ...interface{}) error }) (*shift.Shift, error) {
+	var s shift.Shift
+	var status string
+
+	err := row.Scan(
+		&s.ID, &s.StoreID, &s.RegisterID, &s.StaffID, &status, &s.Currency, &s.OpeningFloat, &s.CountedCash,
+		&s.Notes, &s.OpenedAt, &s.ClosedAt, &s.ClosedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.Status = shift.Status(status)
+	return &s, nil
+}
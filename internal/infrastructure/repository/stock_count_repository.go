@@ -0,0 +1,413 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// StockCountRepository implements inventory.CountRepository. Recording and
+// reconciling counts take several statements, run in transactions begun on
+// tx. Every query is scoped to the tenant in ctx.
+type StockCountRepository struct {
+	db database.Querier
+	tx TxBeginner
+}
+
+// NewStockCountRepository creates a stock count repository reading through
+// db and changing counts and stock in transactions begun on tx
+func NewStockCountRepository(db database.Querier, tx TxBeginner) *StockCountRepository {
+	return &StockCountRepository{db: db, tx: tx}
+}
+
+const countColumns = `id, store_id, status, COALESCE(note, ''), opened_by, closed_by, closed_at, created_at, updated_at`
+
+// CreateCount opens a count of a store
+func (r *StockCountRepository) CreateCount(ctx context.Context, c *inventory.Count) error {
+	ctx, span := startSpan(ctx, "StockCountRepository.CreateCount")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO stock_counts (id, store_id, status, note, opened_by, created_at, updated_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $7)
+	`
+
+	now := time.Now().UTC()
+	_, err = r.db.Exec(ctx, query, c.ID, c.StoreID, string(inventory.CountOpen), c.Note, c.OpenedBy, now, tenantID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return inventory.ErrCountInProgress
+	}
+	if err != nil {
+		return err
+	}
+	c.Status = inventory.CountOpen
+	c.CreatedAt = now
+	c.UpdatedAt = now
+	return nil
+}
+
+// GetCount retrieves a count
+func (r *StockCountRepository) GetCount(ctx context.Context, id uuid.UUID) (*inventory.Count, error) {
+	ctx, span := startSpan(ctx, "StockCountRepository.GetCount")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + countColumns + ` FROM stock_counts WHERE id = $1 AND tenant_id = $2`
+
+	c, err := scanCount(r.db.QueryRow(ctx, query, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, inventory.ErrCountNotFound
+	}
+	return c, err
+}
+
+// ListCounts retrieves counts, newest first
+func (r *StockCountRepository) ListCounts(ctx context.Context, filter inventory.CountFilter, limit, offset int) ([]*inventory.Count, error) {
+	ctx, span := startSpan(ctx, "StockCountRepository.ListCounts")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + countColumns + `
+		FROM stock_counts
+		WHERE tenant_id = $5
+			AND ($1::uuid IS NULL OR store_id = $1)
+			AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.Query(ctx, query, filter.StoreID, string(filter.Status), limit, offset, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []*inventory.Count{}
+	for rows.Next() {
+		c, err := scanCount(rows)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// RecordCounts records counted quantities against the stock records of the
+// count's store, holding the count open until they are in
+func (r *StockCountRepository) RecordCounts(ctx context.Context, id uuid.UUID, entries []inventory.CountEntry, userID *uuid.UUID) error {
+	ctx, span := startSpan(ctx, "StockCountRepository.RecordCounts")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	productIDs := make([]uuid.UUID, len(entries))
+	quantities := make([]int, len(entries))
+	for i, e := range entries {
+		productIDs[i], quantities[i] = e.ProductID, e.Quantity
+	}
+
+	return r.inTx(ctx, func(tx pgx.Tx) error {
+		// Locking the count keeps it from being reconciled meanwhile
+		var storeID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			SELECT store_id FROM stock_counts
+			WHERE id = $1 AND tenant_id = $2 AND status = 'open'
+			FOR UPDATE
+		`, id, tenantID).Scan(&storeID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return r.missingOr(ctx, tenantID, id, inventory.ErrCountClosed)
+		}
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, `
+			INSERT INTO stock_count_lines (count_id, product_id, inventory_id, counted_quantity, counted_by, counted_at)
+			SELECT $1, e.product_id, i.id, e.quantity, $6, $7
+			FROM unnest($2::uuid[], $3::int[]) AS e(product_id, quantity)
+			JOIN inventory i ON i.tenant_id = $4 AND i.store_id = $5 AND i.product_id = e.product_id
+			ON CONFLICT (count_id, product_id) DO UPDATE SET
+				inventory_id = EXCLUDED.inventory_id,
+				counted_quantity = EXCLUDED.counted_quantity,
+				counted_by = EXCLUDED.counted_by,
+				counted_at = EXCLUDED.counted_at
+			RETURNING product_id
+		`, id, productIDs, quantities, tenantID, storeID, userID, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		recorded, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+		if err != nil {
+			return err
+		}
+		if len(recorded) == len(entries) {
+			return nil
+		}
+
+		found := make(map[uuid.UUID]bool, len(recorded))
+		for _, productID := range recorded {
+			found[productID] = true
+		}
+		var missing []uuid.UUID
+		for _, productID := range productIDs {
+			if !found[productID] {
+				missing = append(missing, productID)
+			}
+		}
+		return fmt.Errorf("%w at store %s for products %v", inventory.ErrInventoryNotFound, storeID, missing)
+	})
+}
+
+// ListCountLines retrieves the lines of a count, against the stock they
+// replaced once reconciled and the current stock otherwise
+func (r *StockCountRepository) ListCountLines(ctx context.Context, id uuid.UUID) ([]*inventory.CountLine, error) {
+	ctx, span := startSpan(ctx, "StockCountRepository.ListCountLines")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT l.product_id, l.inventory_id, l.counted_quantity, COALESCE(l.system_quantity, i.quantity),
+			COALESCE(i.cost_price, 0)::float8, l.counted_by, l.counted_at
+		FROM stock_count_lines l
+		JOIN stock_counts c ON c.id = l.count_id
+		JOIN inventory i ON i.id = l.inventory_id
+		WHERE l.count_id = $1 AND c.tenant_id = $2
+		ORDER BY l.product_id
+	`
+
+	rows, err := r.db.Query(ctx, query, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []*inventory.CountLine{}
+	for rows.Next() {
+		var l inventory.CountLine
+		var costPrice float64
+		if err := rows.Scan(
+			&l.ProductID, &l.InventoryID, &l.CountedQuantity, &l.SystemQuantity,
+			&costPrice, &l.CountedBy, &l.CountedAt,
+		); err != nil {
+			return nil, err
+		}
+		l.Variance = l.CountedQuantity - l.SystemQuantity
+		l.VarianceValue = math.Round(float64(l.Variance)*costPrice*100) / 100
+		lines = append(lines, &l)
+	}
+	return lines, rows.Err()
+}
+
+// ReconcileCount closes an open count, freezes the stock each line is
+// measured against and corrects the stock records counted differently,
+// each with an adjustment movement referencing the count
+func (r *StockCountRepository) ReconcileCount(ctx context.Context, id uuid.UUID, userID *uuid.UUID) (*inventory.Count, []inventory.CountCorrection, error) {
+	ctx, span := startSpan(ctx, "StockCountRepository.ReconcileCount")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	var corrections []inventory.CountCorrection
+	err = r.inTx(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE stock_counts SET status = 'reconciled', closed_by = $2, closed_at = $3, updated_at = $3
+			WHERE id = $1 AND tenant_id = $4 AND status = 'open'
+		`, id, userID, now, tenantID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return r.missingOr(ctx, tenantID, id, inventory.ErrCountClosed)
+		}
+
+		// The records counted are locked so that the stock the counts are
+		// measured against cannot change before it is corrected
+		rows, err := tx.Query(ctx, `
+			SELECT l.product_id, l.counted_quantity, i.quantity, i.reserved_quantity
+			FROM stock_count_lines l
+			JOIN inventory i ON i.id = l.inventory_id
+			WHERE l.count_id = $1
+			ORDER BY i.id
+			FOR UPDATE OF i
+		`, id)
+		if err != nil {
+			return err
+		}
+		changes := make(map[uuid.UUID]int)
+		var short []uuid.UUID
+		for rows.Next() {
+			var productID uuid.UUID
+			var counted, quantity, reserved int
+			if err := rows.Scan(&productID, &counted, &quantity, &reserved); err != nil {
+				rows.Close()
+				return err
+			}
+			if counted < reserved {
+				short = append(short, productID)
+			}
+			changes[productID] = counted - quantity
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(short) > 0 {
+			return fmt.Errorf("%w: products %v were counted below their reserved stock", inventory.ErrInsufficientStock, short)
+		}
+
+		if _, err := tx.Exec(ctx, `
+			UPDATE stock_count_lines l SET system_quantity = i.quantity
+			FROM inventory i
+			WHERE l.count_id = $1 AND i.id = l.inventory_id
+		`, id); err != nil {
+			return err
+		}
+
+		rows, err = tx.Query(ctx, `
+			WITH stock AS (
+				UPDATE inventory i SET
+					quantity = l.counted_quantity,
+					version = i.version + 1, updated_at = $2
+				FROM stock_count_lines l
+				WHERE l.count_id = $1 AND i.id = l.inventory_id AND i.quantity <> l.counted_quantity
+				RETURNING `+stockReturning+`, l.system_quantity
+			), movement AS (
+				INSERT INTO stock_movements (
+					id, inventory_id, movement_type, quantity,
+					previous_quantity, new_quantity, reason,
+					reference_id, reference_type, user_id, created_at, tenant_id
+				)
+				SELECT gen_random_uuid(), id, $3, quantity - system_quantity, system_quantity, quantity, $4, $1, $5, $6, $2, $7
+				FROM stock
+			)
+			SELECT id, product_id, store_id, quantity, reserved_quantity,
+				available_quantity, reorder_point, reorder_quantity,
+				cost_price, selling_price, version, created_at, updated_at
+			FROM stock
+			ORDER BY product_id
+		`, id, now, string(inventory.MovementAdjustment), string(inventory.ReasonCountCorrection),
+			inventory.MovementReferenceCount, userID, tenantID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			inv, err := scanInventory(rows)
+			if err != nil {
+				return err
+			}
+			corrections = append(corrections, inventory.CountCorrection{Inventory: inv, Change: changes[inv.ProductID]})
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c, err := r.GetCount(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, corrections, nil
+}
+
+// CancelCount cancels an open count, leaving stock as it is
+func (r *StockCountRepository) CancelCount(ctx context.Context, id uuid.UUID, userID *uuid.UUID) (*inventory.Count, error) {
+	ctx, span := startSpan(ctx, "StockCountRepository.CancelCount")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := r.db.Exec(ctx, `
+		UPDATE stock_counts SET status = 'cancelled', closed_by = $2, closed_at = $3, updated_at = $3
+		WHERE id = $1 AND tenant_id = $4 AND status = 'open'
+	`, id, userID, time.Now().UTC(), tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, r.missingOr(ctx, tenantID, id, inventory.ErrCountClosed)
+	}
+	return r.GetCount(ctx, id)
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds
+func (r *StockCountRepository) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := r.tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// missingOr returns ErrCountNotFound when the count does not exist, and err
+// otherwise
+func (r *StockCountRepository) missingOr(ctx context.Context, tenantID string, id uuid.UUID, err error) error {
+	var exists bool
+	if qerr := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM stock_counts WHERE id = $1 AND tenant_id = $2)`, id, tenantID).Scan(&exists); qerr != nil {
+		return qerr
+	}
+	if !exists {
+		return inventory.ErrCountNotFound
+	}
+	return err
+}
+
+// scanCount scans a row of countColumns
+func scanCount(row pgx.Row) (*inventory.Count, error) {
+	var c inventory.Count
+	var status string
+	if err := row.Scan(
+		&c.ID, &c.StoreID, &status, &c.Note, &c.OpenedBy, &c.ClosedBy, &c.ClosedAt, &c.CreatedAt, &c.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	c.Status = inventory.CountStatus(status)
+	return &c, nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// importColumns are the columns an import fills; the rest take their defaults
+var importColumns = []string{
+	"id", "tenant_id", "product_id", "store_id", "quantity",
+	"reorder_point", "reorder_quantity", "cost_price", "selling_price",
+	"created_at", "updated_at",
+}
+
+// StockImportRepository implements inventory.ImportRepository, creating the
+// records of an import with a batch insert. Every query is scoped to the
+// tenant in ctx.
+type StockImportRepository struct {
+	db    database.Querier
+	batch *database.QueryExecutor
+}
+
+// NewStockImportRepository creates a stock import repository reading through
+// db and inserting through batch
+func NewStockImportRepository(db database.Querier, batch *database.QueryExecutor) *StockImportRepository {
+	return &StockImportRepository{db: db, batch: batch}
+}
+
+// Stocked returns which of keys already have a stock record
+func (r *StockImportRepository) Stocked(ctx context.Context, keys []inventory.StockKey) (map[inventory.StockKey]bool, error) {
+	ctx, span := startSpan(ctx, "StockImportRepository.Stocked")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]uuid.UUID, len(keys))
+	storeIDs := make([]uuid.UUID, len(keys))
+	for i, key := range keys {
+		productIDs[i], storeIDs[i] = key.ProductID, key.StoreID
+	}
+
+	// Central stock has no store; it is keyed by the nil UUID
+	query := `
+		SELECT i.product_id, COALESCE(i.store_id, '00000000-0000-0000-0000-000000000000')
+		FROM inventory i
+		JOIN unnest($2::uuid[], $3::uuid[]) AS k(product_id, store_id)
+			ON i.product_id = k.product_id
+			AND COALESCE(i.store_id, '00000000-0000-0000-0000-000000000000') = k.store_id
+		WHERE i.tenant_id = $1
+	`
+
+	rows, err := r.db.Query(ctx, query, tenantID, productIDs, storeIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stocked := make(map[inventory.StockKey]bool)
+	for rows.Next() {
+		var key inventory.StockKey
+		if err := rows.Scan(&key.ProductID, &key.StoreID); err != nil {
+			return nil, err
+		}
+		stocked[key] = true
+	}
+	return stocked, rows.Err()
+}
+
+// Import creates every record in one batch insert, so either all of them
+// are created or, when one is already stocked, none
+func (r *StockImportRepository) Import(ctx context.Context, records []*inventory.Inventory) error {
+	ctx, span := startSpan(ctx, "StockImportRepository.Import")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	values := make([][]interface{}, len(records))
+	for i, inv := range records {
+		inv.Version, inv.CreatedAt, inv.UpdatedAt = 1, now, now
+		inv.AvailableQuantity = inv.Quantity - inv.ReservedQuantity
+		values[i] = []interface{}{
+			inv.ID, tenantID, inv.ProductID, inv.StoreID, inv.Quantity,
+			inv.ReorderPoint, inv.ReorderQuantity, inv.CostPrice, inv.SellingPrice,
+			now, now,
+		}
+	}
+
+	err = r.batch.BatchInsert(ctx, "inventory", importColumns, values)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return inventory.ErrAlreadyStocked
+	}
+	return err
+}
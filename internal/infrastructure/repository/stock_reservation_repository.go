@@ -0,0 +1,430 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// StockReservationRepository implements inventory.ReservationRepository.
+// Each change of a reservation and of the stock it holds is made in one
+// statement, except for batches, reserved in transactions begun on tx.
+// Every query is scoped to the tenant in ctx, except listing expired
+// reservations.
+type StockReservationRepository struct {
+	db database.Querier
+	tx TxBeginner
+}
+
+// NewStockReservationRepository creates a stock reservation repository
+// reading through db and reserving batches in transactions begun on tx
+func NewStockReservationRepository(db database.Querier, tx TxBeginner) *StockReservationRepository {
+	return &StockReservationRepository{db: db, tx: tx}
+}
+
+const reservationColumns = `r.id, r.inventory_id, i.product_id, i.store_id, r.quantity, r.status,
+	r.reference_id, COALESCE(r.reference_type, ''), r.expires_at, r.committed_at, r.closed_at, r.created_at, r.tenant_id`
+
+// stockReturning is the inventory columns returned by a change of stock, as
+// scanned by scanInventory
+const stockReturning = `i.id, i.product_id, i.store_id, i.quantity, i.reserved_quantity,
+	i.available_quantity, i.reorder_point, i.reorder_quantity,
+	i.cost_price, i.selling_price, i.version, i.created_at, i.updated_at`
+
+// CreateReservation reserves stock of the reservation's product and store,
+// or of its stock held by no store when StoreID is nil, and records the
+// reservation as held
+func (r *StockReservationRepository) CreateReservation(ctx context.Context, res *inventory.Reservation) (*inventory.Inventory, error) {
+	ctx, span := startSpan(ctx, "StockReservationRepository.CreateReservation")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH stock AS (
+			UPDATE inventory i SET reserved_quantity = i.reserved_quantity + $4, updated_at = $8
+			WHERE i.tenant_id = $2 AND i.product_id = $3 AND i.store_id IS NOT DISTINCT FROM $9::uuid
+				AND i.quantity - i.reserved_quantity >= $4
+				AND NOT EXISTS (SELECT 1 FROM stock_reservations WHERE id = $1)
+			RETURNING ` + stockReturning + `
+		), reservation AS (
+			INSERT INTO stock_reservations (
+				id, tenant_id, inventory_id, quantity, status,
+				reference_id, reference_type, expires_at, created_at
+			)
+			SELECT $1, $2, id, $4, 'held', $5::uuid, NULLIF($6::text, ''), $7::timestamp, $8 FROM stock
+		)
+		SELECT id, product_id, store_id, quantity, reserved_quantity,
+			available_quantity, reorder_point, reorder_quantity,
+			cost_price, selling_price, version, created_at, updated_at
+		FROM stock
+	`
+
+	now := time.Now().UTC()
+	inv, err := scanInventory(r.db.QueryRow(ctx, query,
+		res.ID, tenantID, res.ProductID, res.Quantity,
+		res.ReferenceID, res.ReferenceType, res.ExpiresAt.UTC(), now, res.StoreID,
+	))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return nil, inventory.ErrReservationExists // Taken by a concurrent request
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Nothing was reserved; find out why
+		var taken, stocked bool
+		if err := r.db.QueryRow(ctx, `
+			SELECT
+				EXISTS (SELECT 1 FROM stock_reservations WHERE id = $1),
+				EXISTS (SELECT 1 FROM inventory WHERE tenant_id = $2 AND product_id = $3 AND store_id IS NOT DISTINCT FROM $4::uuid)
+		`, res.ID, tenantID, res.ProductID, res.StoreID).Scan(&taken, &stocked); err != nil {
+			return nil, err
+		}
+		switch {
+		case taken:
+			return nil, inventory.ErrReservationExists
+		case !stocked:
+			return nil, inventory.ErrInventoryNotFound
+		}
+		return nil, inventory.ErrInsufficientStock
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res.InventoryID = inv.ID
+	res.StoreID = inv.StoreID
+	res.Status = inventory.ReservationHeld
+	res.ExpiresAt = res.ExpiresAt.UTC()
+	res.CreatedAt = now
+	res.TenantID = tenantID
+	return inv, nil
+}
+
+// CreateReservations locks the stock of a batch's reservations, in the order
+// of its records so that batches sharing stock wait for each other rather
+// than deadlock, checks there is enough of all of it, then reserves it and
+// records the reservations as held. Reservations of one record add up.
+func (r *StockReservationRepository) CreateReservations(ctx context.Context, rs []*inventory.Reservation) ([]*inventory.Inventory, error) {
+	ctx, span := startSpan(ctx, "StockReservationRepository.CreateReservations")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(rs))
+	productIDs := make([]uuid.UUID, len(rs))
+	storeIDs := make([]*uuid.UUID, len(rs))
+	for i, res := range rs {
+		ids[i], productIDs[i], storeIDs[i] = res.ID, res.ProductID, res.StoreID
+	}
+
+	type stockKey struct {
+		productID uuid.UUID
+		storeID   uuid.UUID // uuid.Nil for stock held by no store
+	}
+	keyOf := func(productID uuid.UUID, storeID *uuid.UUID) stockKey {
+		if storeID == nil {
+			return stockKey{productID: productID}
+		}
+		return stockKey{productID: productID, storeID: *storeID}
+	}
+
+	now := time.Now().UTC()
+	stock := make([]*inventory.Inventory, len(rs))
+	err = r.inTx(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT id FROM stock_reservations WHERE id = ANY($1)`, ids)
+		if err != nil {
+			return err
+		}
+		taken, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+		if err != nil {
+			return err
+		}
+
+		rows, err = tx.Query(ctx, `
+			SELECT `+stockReturning+`
+			FROM inventory i
+			JOIN unnest($2::uuid[], $3::uuid[]) AS t(product_id, store_id)
+				ON i.product_id = t.product_id AND i.store_id IS NOT DISTINCT FROM t.store_id
+			WHERE i.tenant_id = $1
+			ORDER BY i.id
+			FOR UPDATE OF i
+		`, tenantID, productIDs, storeIDs)
+		if err != nil {
+			return err
+		}
+		locked := make(map[stockKey]*inventory.Inventory)
+		for rows.Next() {
+			inv, err := scanInventory(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			locked[keyOf(inv.ProductID, inv.StoreID)] = inv
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		isTaken := make(map[uuid.UUID]bool, len(taken))
+		for _, id := range taken {
+			isTaken[id] = true
+		}
+		failed := inventory.ReservationErrors{}
+		for i, res := range rs {
+			inv, ok := locked[keyOf(res.ProductID, res.StoreID)]
+			switch {
+			case isTaken[res.ID]:
+				failed[i] = inventory.ErrReservationExists
+			case !ok:
+				failed[i] = inventory.ErrInventoryNotFound
+			default:
+				if err := inv.Reserve(res.Quantity); err != nil {
+					failed[i] = err
+				}
+				stock[i] = inv
+			}
+			isTaken[res.ID] = true // An ID given twice is taken by its first item
+		}
+		if len(failed) > 0 {
+			return failed
+		}
+
+		inventoryIDs := make([]uuid.UUID, len(rs))
+		quantities := make([]int, len(rs))
+		referenceIDs := make([]*uuid.UUID, len(rs))
+		referenceTypes := make([]string, len(rs))
+		expiries := make([]time.Time, len(rs))
+		for i, res := range rs {
+			inventoryIDs[i], quantities[i] = stock[i].ID, res.Quantity
+			referenceIDs[i], referenceTypes[i], expiries[i] = res.ReferenceID, res.ReferenceType, res.ExpiresAt.UTC()
+		}
+
+		if _, err := tx.Exec(ctx, `
+			UPDATE inventory i SET reserved_quantity = i.reserved_quantity + t.quantity, updated_at = $3
+			FROM (
+				SELECT inventory_id, SUM(quantity) AS quantity
+				FROM unnest($1::uuid[], $2::int[]) AS r(inventory_id, quantity)
+				GROUP BY inventory_id
+			) t
+			WHERE i.id = t.inventory_id
+		`, inventoryIDs, quantities, now); err != nil {
+			return err
+		}
+		for _, inv := range locked {
+			inv.Version++ // By the trigger
+			inv.UpdatedAt = now
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO stock_reservations (
+				id, tenant_id, inventory_id, quantity, status,
+				reference_id, reference_type, expires_at, created_at
+			)
+			SELECT t.id, $2, t.inventory_id, t.quantity, 'held', t.reference_id, NULLIF(t.reference_type, ''), t.expires_at, $8
+			FROM unnest($1::uuid[], $3::uuid[], $4::int[], $5::uuid[], $6::text[], $7::timestamp[])
+				AS t(id, inventory_id, quantity, reference_id, reference_type, expires_at)
+		`, ids, tenantID, inventoryIDs, quantities, referenceIDs, referenceTypes, expiries, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, res := range rs {
+		res.InventoryID = stock[i].ID
+		res.StoreID = stock[i].StoreID
+		res.Status = inventory.ReservationHeld
+		res.ExpiresAt = res.ExpiresAt.UTC()
+		res.CreatedAt = now
+		res.TenantID = tenantID
+	}
+	return stock, nil
+}
+
+// GetReservation retrieves a reservation
+func (r *StockReservationRepository) GetReservation(ctx context.Context, id uuid.UUID) (*inventory.Reservation, error) {
+	ctx, span := startSpan(ctx, "StockReservationRepository.GetReservation")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + reservationColumns + `
+		FROM stock_reservations r
+		JOIN inventory i ON i.id = r.inventory_id
+		WHERE r.id = $1 AND r.tenant_id = $2
+	`
+
+	res, err := scanReservation(r.db.QueryRow(ctx, query, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, inventory.ErrReservationNotFound
+	}
+	return res, err
+}
+
+// CommitReservation commits a held reservation not yet past its expiry
+func (r *StockReservationRepository) CommitReservation(ctx context.Context, id uuid.UUID, at time.Time) (*inventory.Reservation, error) {
+	ctx, span := startSpan(ctx, "StockReservationRepository.CommitReservation")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.db.Exec(ctx, `
+		UPDATE stock_reservations SET status = 'committed', committed_at = $3
+		WHERE id = $1 AND tenant_id = $2 AND status = 'held' AND expires_at > $3
+	`, id, tenantID, at.UTC()); err != nil {
+		return nil, err
+	}
+
+	// A reservation past its expiry is left to the sweeper
+	res, err := r.GetReservation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if res.Status != inventory.ReservationCommitted {
+		return nil, inventory.ErrReservationClosed
+	}
+	return res, nil
+}
+
+// ReleaseReservation releases a held or committed reservation
+func (r *StockReservationRepository) ReleaseReservation(ctx context.Context, id uuid.UUID, at time.Time) (*inventory.Reservation, *inventory.Inventory, error) {
+	ctx, span := startSpan(ctx, "StockReservationRepository.ReleaseReservation")
+	defer span.End()
+
+	return r.close(ctx, id, inventory.ReservationReleased, at, `r.status IN ('held', 'committed')`)
+}
+
+// ListExpiredReservations retrieves held reservations past their expiry,
+// across tenants. Each reservation carries its tenant to expire it in.
+func (r *StockReservationRepository) ListExpiredReservations(ctx context.Context, at time.Time, limit int) ([]*inventory.Reservation, error) {
+	ctx, span := startSpan(ctx, "StockReservationRepository.ListExpiredReservations")
+	defer span.End()
+
+	query := `
+		SELECT ` + reservationColumns + `
+		FROM stock_reservations r
+		JOIN inventory i ON i.id = r.inventory_id
+		WHERE r.status = 'held' AND r.expires_at <= $1
+		ORDER BY r.expires_at, r.id
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, at.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reservations := []*inventory.Reservation{}
+	for rows.Next() {
+		res, err := scanReservation(rows)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, res)
+	}
+	return reservations, rows.Err()
+}
+
+// ExpireReservation releases a held reservation past its expiry
+func (r *StockReservationRepository) ExpireReservation(ctx context.Context, id uuid.UUID, at time.Time) (*inventory.Reservation, *inventory.Inventory, error) {
+	ctx, span := startSpan(ctx, "StockReservationRepository.ExpireReservation")
+	defer span.End()
+
+	return r.close(ctx, id, inventory.ReservationExpired, at, `r.status = 'held' AND r.expires_at <= $4`)
+}
+
+// close moves a reservation matching condition to status to and makes its
+// stock available again, in one statement. condition may refer to at as $4.
+func (r *StockReservationRepository) close(ctx context.Context, id uuid.UUID, to inventory.ReservationStatus, at time.Time, condition string) (*inventory.Reservation, *inventory.Inventory, error) {
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := `
+		WITH reservation AS (
+			UPDATE stock_reservations r SET status = $3, closed_at = $4
+			WHERE r.id = $1 AND r.tenant_id = $2 AND ` + condition + `
+			RETURNING r.inventory_id, r.quantity
+		), stock AS (
+			UPDATE inventory i SET
+				reserved_quantity = GREATEST(i.reserved_quantity - reservation.quantity, 0),
+				updated_at = $4
+			FROM reservation
+			WHERE i.id = reservation.inventory_id
+			RETURNING ` + stockReturning + `
+		)
+		SELECT id, product_id, store_id, quantity, reserved_quantity,
+			available_quantity, reorder_point, reorder_quantity,
+			cost_price, selling_price, version, created_at, updated_at
+		FROM stock
+	`
+
+	inv, err := scanInventory(r.db.QueryRow(ctx, query, id, tenantID, string(to), at.UTC()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := r.GetReservation(ctx, id); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, inventory.ErrReservationClosed
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := r.GetReservation(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, inv, nil
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds
+func (r *StockReservationRepository) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := r.tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// scanReservation scans a row of reservationColumns
+func scanReservation(row pgx.Row) (*inventory.Reservation, error) {
+	var res inventory.Reservation
+	var status string
+	if err := row.Scan(
+		&res.ID, &res.InventoryID, &res.ProductID, &res.StoreID, &res.Quantity, &status,
+		&res.ReferenceID, &res.ReferenceType, &res.ExpiresAt, &res.CommittedAt, &res.ClosedAt, &res.CreatedAt, &res.TenantID,
+	); err != nil {
+		return nil, err
+	}
+	res.Status = inventory.ReservationStatus(status)
+	return &res, nil
+}
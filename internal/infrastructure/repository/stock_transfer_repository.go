@@ -0,0 +1,392 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// StockTransferRepository implements inventory.TransferRepository. Moving a
+// transfer's stock takes several statements, run in transactions begun on
+// tx. Every query is scoped to the tenant in ctx.
+type StockTransferRepository struct {
+	db database.Querier
+	tx TxBeginner
+}
+
+// NewStockTransferRepository creates a stock transfer repository reading
+// through db and moving stock in transactions begun on tx
+func NewStockTransferRepository(db database.Querier, tx TxBeginner) *StockTransferRepository {
+	return &StockTransferRepository{db: db, tx: tx}
+}
+
+const transferColumns = `id, source_store_id, destination_store_id, status, COALESCE(note, ''),
+	requested_by, dispatched_by, received_by, dispatched_at, received_at, cancelled_at, created_at, updated_at`
+
+// CreateTransfer creates a requested transfer with its items in one statement
+func (r *StockTransferRepository) CreateTransfer(ctx context.Context, t *inventory.Transfer) error {
+	ctx, span := startSpan(ctx, "StockTransferRepository.CreateTransfer")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH transfer AS (
+			INSERT INTO stock_transfers (
+				id, source_store_id, destination_store_id, status, note,
+				requested_by, created_at, updated_at, tenant_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $7, $8)
+			RETURNING id
+		)
+		INSERT INTO stock_transfer_items (transfer_id, product_id, quantity)
+		SELECT transfer.id, t.product_id, t.quantity
+		FROM transfer, unnest($9::uuid[], $10::int[]) AS t(product_id, quantity)
+	`
+
+	productIDs := make([]uuid.UUID, len(t.Items))
+	quantities := make([]int, len(t.Items))
+	for i, item := range t.Items {
+		productIDs[i], quantities[i] = item.ProductID, item.Quantity
+	}
+
+	now := time.Now().UTC()
+	if _, err := r.db.Exec(ctx, query,
+		t.ID, t.SourceStoreID, t.DestinationStoreID, string(inventory.TransferRequested), t.Note,
+		t.RequestedBy, now, tenantID, productIDs, quantities,
+	); err != nil {
+		return err
+	}
+	t.Status = inventory.TransferRequested
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	return nil
+}
+
+// GetTransfer retrieves a transfer with its items
+func (r *StockTransferRepository) GetTransfer(ctx context.Context, id uuid.UUID) (*inventory.Transfer, error) {
+	ctx, span := startSpan(ctx, "StockTransferRepository.GetTransfer")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + transferColumns + ` FROM stock_transfers WHERE id = $1 AND tenant_id = $2`
+
+	t, err := scanTransfer(r.db.QueryRow(ctx, query, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, inventory.ErrTransferNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadItems(ctx, []*inventory.Transfer{t}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ListTransfers retrieves transfers, newest first, with their items
+func (r *StockTransferRepository) ListTransfers(ctx context.Context, filter inventory.TransferFilter, limit, offset int) ([]*inventory.Transfer, error) {
+	ctx, span := startSpan(ctx, "StockTransferRepository.ListTransfers")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + transferColumns + `
+		FROM stock_transfers
+		WHERE tenant_id = $5
+			AND ($1::uuid IS NULL OR source_store_id = $1 OR destination_store_id = $1)
+			AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.Query(ctx, query, filter.StoreID, string(filter.Status), limit, offset, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []*inventory.Transfer{}
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadItems(ctx, transfers); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// DispatchTransfer moves a requested transfer in transit and reserves its
+// items at the source, as long as the source has every one available
+func (r *StockTransferRepository) DispatchTransfer(ctx context.Context, id uuid.UUID, userID *uuid.UUID) (*inventory.Transfer, error) {
+	ctx, span := startSpan(ctx, "StockTransferRepository.DispatchTransfer")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	err = r.inTx(ctx, func(tx pgx.Tx) error {
+		var sourceID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			UPDATE stock_transfers SET status = 'in_transit', dispatched_by = $2, dispatched_at = $3, updated_at = $3
+			WHERE id = $1 AND tenant_id = $4 AND status = 'requested'
+			RETURNING source_store_id
+		`, id, userID, now, tenantID).Scan(&sourceID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return r.missingOr(ctx, tenantID, id, inventory.ErrInvalidTransferTransition)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Each item is reserved only if enough of it is available, checked
+		// again against concurrent changes as its row is locked
+		rows, err := tx.Query(ctx, `
+			WITH reserved AS (
+				UPDATE inventory i SET reserved_quantity = i.reserved_quantity + t.quantity, updated_at = $4
+				FROM stock_transfer_items t
+				WHERE t.transfer_id = $1 AND i.tenant_id = $2 AND i.store_id = $3
+					AND i.product_id = t.product_id AND i.available_quantity >= t.quantity
+				RETURNING i.product_id
+			)
+			SELECT product_id FROM stock_transfer_items
+			WHERE transfer_id = $1 AND product_id NOT IN (SELECT product_id FROM reserved)
+			ORDER BY product_id
+		`, id, tenantID, sourceID, now)
+		if err != nil {
+			return err
+		}
+		short, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+		if err != nil {
+			return err
+		}
+		if len(short) > 0 {
+			return fmt.Errorf("%w at the source for products %v", inventory.ErrInsufficientStock, short)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetTransfer(ctx, id)
+}
+
+// ReceiveTransfer marks an in-transit transfer received, takes its items off
+// the source's stock and reservations and adds them to the destination's
+// stock, creating its records where needed at the source's prices. Each side
+// gets a movement referencing the transfer.
+func (r *StockTransferRepository) ReceiveTransfer(ctx context.Context, id uuid.UUID, userID *uuid.UUID) (*inventory.Transfer, error) {
+	ctx, span := startSpan(ctx, "StockTransferRepository.ReceiveTransfer")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	err = r.inTx(ctx, func(tx pgx.Tx) error {
+		var sourceID, destinationID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			UPDATE stock_transfers SET status = 'received', received_by = $2, received_at = $3, updated_at = $3
+			WHERE id = $1 AND tenant_id = $4 AND status = 'in_transit'
+			RETURNING source_store_id, destination_store_id
+		`, id, userID, now, tenantID).Scan(&sourceID, &destinationID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return r.missingOr(ctx, tenantID, id, inventory.ErrInvalidTransferTransition)
+		}
+		if err != nil {
+			return err
+		}
+
+		out, err := tx.Exec(ctx, `
+			WITH moved AS (
+				UPDATE inventory i SET
+					quantity = i.quantity - t.quantity,
+					reserved_quantity = GREATEST(i.reserved_quantity - t.quantity, 0),
+					updated_at = $5
+				FROM stock_transfer_items t
+				WHERE t.transfer_id = $1 AND i.tenant_id = $2 AND i.store_id = $3 AND i.product_id = t.product_id
+				RETURNING i.id, i.quantity, t.quantity AS moved
+			)
+			INSERT INTO stock_movements (
+				id, inventory_id, movement_type, quantity,
+				previous_quantity, new_quantity, reason,
+				reference_id, reference_type, user_id, created_at, tenant_id
+			)
+			SELECT gen_random_uuid(), id, $6, moved, quantity + moved, quantity, 'transfer', $1, $7, $4, $5, $2
+			FROM moved
+		`, id, tenantID, sourceID, userID, now, string(inventory.MovementOut), inventory.MovementReferenceTransfer)
+		if err != nil {
+			return err
+		}
+
+		in, err := tx.Exec(ctx, `
+			WITH items AS (
+				SELECT t.product_id, t.quantity, s.cost_price, s.selling_price
+				FROM stock_transfer_items t
+				LEFT JOIN inventory s ON s.tenant_id = $2 AND s.store_id = $3 AND s.product_id = t.product_id
+				WHERE t.transfer_id = $1
+			), stock AS (
+				INSERT INTO inventory (product_id, store_id, quantity, cost_price, selling_price, created_at, updated_at, tenant_id)
+				SELECT product_id, $4, quantity, cost_price, selling_price, $6, $6, $2 FROM items
+				ON CONFLICT (tenant_id, product_id, store_id) WHERE store_id IS NOT NULL DO UPDATE SET
+					quantity = inventory.quantity + EXCLUDED.quantity,
+					updated_at = EXCLUDED.updated_at
+				RETURNING id, product_id, quantity
+			)
+			INSERT INTO stock_movements (
+				id, inventory_id, movement_type, quantity,
+				previous_quantity, new_quantity, reason,
+				reference_id, reference_type, user_id, created_at, tenant_id
+			)
+			SELECT gen_random_uuid(), s.id, $7, i.quantity, s.quantity - i.quantity, s.quantity, 'transfer', $1, $8, $5, $6, $2
+			FROM stock s JOIN items i USING (product_id)
+		`, id, tenantID, sourceID, destinationID, userID, now, string(inventory.MovementIn), inventory.MovementReferenceTransfer)
+		if err != nil {
+			return err
+		}
+
+		// The source's records were reserved against at dispatch; one gone
+		// since would leave stock arriving from nowhere
+		if out.RowsAffected() != in.RowsAffected() {
+			return fmt.Errorf("%w: stock of transfer %s is missing at the source", inventory.ErrInventoryNotFound, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetTransfer(ctx, id)
+}
+
+// CancelTransfer cancels a requested transfer, which holds no stock
+func (r *StockTransferRepository) CancelTransfer(ctx context.Context, id uuid.UUID) (*inventory.Transfer, error) {
+	ctx, span := startSpan(ctx, "StockTransferRepository.CancelTransfer")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := r.db.Exec(ctx, `
+		UPDATE stock_transfers SET status = 'cancelled', cancelled_at = $2, updated_at = $2
+		WHERE id = $1 AND tenant_id = $3 AND status = 'requested'
+	`, id, time.Now().UTC(), tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, r.missingOr(ctx, tenantID, id, inventory.ErrInvalidTransferTransition)
+	}
+	return r.GetTransfer(ctx, id)
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds
+func (r *StockTransferRepository) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := r.tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// missingOr returns ErrTransferNotFound when the transfer does not exist,
+// and err otherwise
+func (r *StockTransferRepository) missingOr(ctx context.Context, tenantID string, id uuid.UUID, err error) error {
+	var exists bool
+	if qerr := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM stock_transfers WHERE id = $1 AND tenant_id = $2)`, id, tenantID).Scan(&exists); qerr != nil {
+		return qerr
+	}
+	if !exists {
+		return inventory.ErrTransferNotFound
+	}
+	return err
+}
+
+// loadItems fills in the items of transfers
+func (r *StockTransferRepository) loadItems(ctx context.Context, transfers []*inventory.Transfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+	byID := make(map[uuid.UUID]*inventory.Transfer, len(transfers))
+	ids := make([]uuid.UUID, len(transfers))
+	for i, t := range transfers {
+		t.Items = []inventory.TransferItem{}
+		byID[t.ID] = t
+		ids[i] = t.ID
+	}
+
+	query := `
+		SELECT transfer_id, product_id, quantity
+		FROM stock_transfer_items
+		WHERE transfer_id = ANY($1)
+		ORDER BY transfer_id, product_id
+	`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transferID uuid.UUID
+		var item inventory.TransferItem
+		if err := rows.Scan(&transferID, &item.ProductID, &item.Quantity); err != nil {
+			return err
+		}
+		t := byID[transferID]
+		t.Items = append(t.Items, item)
+	}
+	return rows.Err()
+}
+
+// scanTransfer scans a row of transferColumns
+func scanTransfer(row pgx.Row) (*inventory.Transfer, error) {
+	var t inventory.Transfer
+	var status string
+	if err := row.Scan(
+		&t.ID, &t.SourceStoreID, &t.DestinationStoreID, &status, &t.Note,
+		&t.RequestedBy, &t.DispatchedBy, &t.ReceivedBy, &t.DispatchedAt, &t.ReceivedAt, &t.CancelledAt,
+		&t.CreatedAt, &t.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	t.Status = inventory.TransferStatus(status)
+	return &t, nil
+}
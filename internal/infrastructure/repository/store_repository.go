@@ -5,35 +5,46 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/onichange/pos-system/internal/domain/store"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
 )
 
-// StoreRepository implements store.Repository
+// StoreRepository implements store.Repository. Every query is scoped to the
+// tenant in ctx and fails with tenant.ErrNoTenant when there is none; store
+// codes are unique within a tenant.
 type StoreRepository struct {
-	db *pgxpool.Pool
+	db database.Querier
 }
 
 // NewStoreRepository creates a new store repository
-func NewStoreRepository(db *pgxpool.Pool) *StoreRepository {
+func NewStoreRepository(db database.Querier) *StoreRepository {
 	return &StoreRepository{db: db}
 }
 
 // Create creates a new store
 func (r *StoreRepository) Create(ctx context.Context, s *store.Store) error {
+	ctx, span := startSpan(ctx, "StoreRepository.Create")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO stores (
 			id, name, code, latitude, longitude, address, city, state,
-			postal_code, country, phone, email, is_active, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			postal_code, country, phone, email, manager_id, is_active, created_at, updated_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
 
 	now := time.Now()
 	isActive := s.Status == store.StatusActive
-	_, err := r.db.Exec(ctx, query,
+	_, err = r.db.Exec(ctx, query,
 		s.ID, s.Name, s.Code, s.Latitude, s.Longitude, s.Address, s.City, s.State,
-		s.PostalCode, s.Country, s.Phone, s.Email, isActive, now, now,
+		s.PostalCode, s.Country, s.Phone, s.Email, s.ManagerID, isActive, now, now, tenantID,
 	)
 
 	return err
@@ -41,19 +52,27 @@ func (r *StoreRepository) Create(ctx context.Context, s *store.Store) error {
 
 // GetByID retrieves a store by ID
 func (r *StoreRepository) GetByID(ctx context.Context, id uuid.UUID) (*store.Store, error) {
+	ctx, span := startSpan(ctx, "StoreRepository.GetByID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, name, code, latitude, longitude, address, city, state,
-			postal_code, country, phone, email, is_active, created_at, updated_at
+			postal_code, country, phone, email, manager_id, is_active, created_at, updated_at
 		FROM stores
-		WHERE id = $1 AND deleted_at IS NULL
+		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
 	`
 
 	var s store.Store
 	var isActive bool
 
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err = r.db.QueryRow(ctx, query, id, tenantID).Scan(
 		&s.ID, &s.Name, &s.Code, &s.Latitude, &s.Longitude, &s.Address, &s.City, &s.State,
-		&s.PostalCode, &s.Country, &s.Phone, &s.Email, &isActive, &s.CreatedAt, &s.UpdatedAt,
+		&s.PostalCode, &s.Country, &s.Phone, &s.Email, &s.ManagerID, &isActive, &s.CreatedAt, &s.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -69,19 +88,27 @@ func (r *StoreRepository) GetByID(ctx context.Context, id uuid.UUID) (*store.Sto
 
 // GetByCode retrieves a store by code
 func (r *StoreRepository) GetByCode(ctx context.Context, code string) (*store.Store, error) {
+	ctx, span := startSpan(ctx, "StoreRepository.GetByCode")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, name, code, latitude, longitude, address, city, state,
-			postal_code, country, phone, email, is_active, created_at, updated_at
+			postal_code, country, phone, email, manager_id, is_active, created_at, updated_at
 		FROM stores
-		WHERE code = $1 AND deleted_at IS NULL
+		WHERE code = $1 AND tenant_id = $2 AND deleted_at IS NULL
 	`
 
 	var s store.Store
 	var isActive bool
 
-	err := r.db.QueryRow(ctx, query, code).Scan(
+	err = r.db.QueryRow(ctx, query, code, tenantID).Scan(
 		&s.ID, &s.Name, &s.Code, &s.Latitude, &s.Longitude, &s.Address, &s.City, &s.State,
-		&s.PostalCode, &s.Country, &s.Phone, &s.Email, &isActive, &s.CreatedAt, &s.UpdatedAt,
+		&s.PostalCode, &s.Country, &s.Phone, &s.Email, &s.ManagerID, &isActive, &s.CreatedAt, &s.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -97,16 +124,24 @@ func (r *StoreRepository) GetByCode(ctx context.Context, code string) (*store.St
 
 // GetAll retrieves all stores with pagination
 func (r *StoreRepository) GetAll(ctx context.Context, limit, offset int) ([]*store.Store, error) {
+	ctx, span := startSpan(ctx, "StoreRepository.GetAll")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, name, code, latitude, longitude, address, city, state,
-			postal_code, country, phone, email, is_active, created_at, updated_at
+			postal_code, country, phone, email, manager_id, is_active, created_at, updated_at
 		FROM stores
-		WHERE deleted_at IS NULL
+		WHERE tenant_id = $3 AND deleted_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
 	`
 
-	rows, err := r.db.Query(ctx, query, limit, offset)
+	rows, err := r.db.Query(ctx, query, limit, offset, tenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -119,7 +154,7 @@ func (r *StoreRepository) GetAll(ctx context.Context, limit, offset int) ([]*sto
 
 		err := rows.Scan(
 			&s.ID, &s.Name, &s.Code, &s.Latitude, &s.Longitude, &s.Address, &s.City, &s.State,
-			&s.PostalCode, &s.Country, &s.Phone, &s.Email, &isActive, &s.CreatedAt, &s.UpdatedAt,
+			&s.PostalCode, &s.Country, &s.Phone, &s.Email, &s.ManagerID, &isActive, &s.CreatedAt, &s.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -138,21 +173,29 @@ func (r *StoreRepository) GetAll(ctx context.Context, limit, offset int) ([]*sto
 
 // Update updates a store
 func (r *StoreRepository) Update(ctx context.Context, s *store.Store) error {
+	ctx, span := startSpan(ctx, "StoreRepository.Update")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE stores SET
 			name = $2, code = $3, latitude = $4, longitude = $5,
 			address = $6, city = $7, state = $8, postal_code = $9,
 			country = $10, phone = $11, email = $12, is_active = $13,
-			updated_at = $14
-		WHERE id = $1 AND deleted_at IS NULL
+			manager_id = $16, updated_at = $14
+		WHERE id = $1 AND tenant_id = $15 AND deleted_at IS NULL
 	`
 
 	isActive := s.Status == store.StatusActive
-	_, err := r.db.Exec(ctx, query,
+	_, err = r.db.Exec(ctx, query,
 		s.ID, s.Name, s.Code, s.Latitude, s.Longitude,
 		s.Address, s.City, s.State, s.PostalCode,
 		s.Country, s.Phone, s.Email, isActive,
-		time.Now(),
+		time.Now(), tenantID, s.ManagerID,
 	)
 
 	return err
@@ -160,18 +203,34 @@ func (r *StoreRepository) Update(ctx context.Context, s *store.Store) error {
 
 // Delete soft deletes a store
 func (r *StoreRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startSpan(ctx, "StoreRepository.Delete")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE stores SET
 			deleted_at = $2,
 			updated_at = $2
-		WHERE id = $1
+		WHERE id = $1 AND tenant_id = $3
 	`
-	_, err := r.db.Exec(ctx, query, id, time.Now())
+	_, err = r.db.Exec(ctx, query, id, time.Now(), tenantID)
 	return err
 }
 
 // SearchByLocation searches stores by location (simplified - using bounding box)
 func (r *StoreRepository) SearchByLocation(ctx context.Context, lat, lng float64, radiusKm float64) ([]*store.Store, error) {
+	ctx, span := startSpan(ctx, "StoreRepository.SearchByLocation")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Simple bounding box search (for production, use PostGIS for accurate distance)
 	// Approximate: 1 degree latitude ≈ 111 km
 	latDelta := radiusKm / 111.0
@@ -179,11 +238,12 @@ func (r *StoreRepository) SearchByLocation(ctx context.Context, lat, lng float64
 
 	query := `
 		SELECT id, name, code, latitude, longitude, address, city, state,
-			postal_code, country, phone, email, is_active, created_at, updated_at
+			postal_code, country, phone, email, manager_id, is_active, created_at, updated_at
 		FROM stores
 		WHERE latitude BETWEEN $1 AND $2
 			AND longitude BETWEEN $3 AND $4
 			AND is_active = TRUE
+			AND tenant_id = $7
 			AND deleted_at IS NULL
 		ORDER BY 
 			SQRT(POWER(latitude - $5, 2) + POWER(longitude - $6, 2))
@@ -193,7 +253,7 @@ func (r *StoreRepository) SearchByLocation(ctx context.Context, lat, lng float64
 	rows, err := r.db.Query(ctx, query,
 		lat-latDelta, lat+latDelta,
 		lng-lngDelta, lng+lngDelta,
-		lat, lng,
+		lat, lng, tenantID,
 	)
 	if err != nil {
 		return nil, err
@@ -207,7 +267,7 @@ func (r *StoreRepository) SearchByLocation(ctx context.Context, lat, lng float64
 
 		err := rows.Scan(
 			&s.ID, &s.Name, &s.Code, &s.Latitude, &s.Longitude, &s.Address, &s.City, &s.State,
-			&s.PostalCode, &s.Country, &s.Phone, &s.Email, &isActive, &s.CreatedAt, &s.UpdatedAt,
+			&s.PostalCode, &s.Country, &s.Phone, &s.Email, &s.ManagerID, &isActive, &s.CreatedAt, &s.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
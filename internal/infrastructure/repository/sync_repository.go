@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/onichange/pos-system/internal/domain/offline"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// SyncRepository implements offline.Repository. Every query but pruning is
+// scoped to the tenant in ctx and fails with tenant.ErrNoTenant when there
+// is none.
+type SyncRepository struct {
+	db database.Querier
+}
+
+// NewSyncRepository creates a new sync repository
+func NewSyncRepository(db database.Querier) *SyncRepository {
+	return &SyncRepository{db: db}
+}
+
+// Record upserts the entity's change under the next sequence number of the
+// tenant's feed. Allocating the number locks the feed's row until the change
+// commits, so changes commit in sequence order and a pull never skips one
+// that commits later with a lower number.
+func (r *SyncRepository) Record(ctx context.Context, change *offline.Change) (bool, error) {
+	ctx, span := startSpan(ctx, "SyncRepository.Record")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	storeKey := uuid.Nil
+	if change.StoreID != nil {
+		storeKey = *change.StoreID
+	}
+	var data []byte
+	if len(change.Data) > 0 {
+		data = change.Data
+	}
+
+	query := `
+		WITH feed AS (
+			INSERT INTO sync_feeds (tenant_id, last_seq) VALUES ($1, 1)
+			ON CONFLICT (tenant_id) DO UPDATE SET last_seq = sync_feeds.last_seq + 1
+			RETURNING last_seq
+		)
+		INSERT INTO sync_changes (tenant_id, entity, entity_id, store_key, seq, op, data, version, changed_at)
+		SELECT $1, $2, $3, $4, feed.last_seq, $5, $6, $7, NOW() FROM feed
+		ON CONFLICT (tenant_id, entity, entity_id, store_key) DO UPDATE
+		SET seq = EXCLUDED.seq, op = EXCLUDED.op, data = EXCLUDED.data,
+			version = EXCLUDED.version, changed_at = EXCLUDED.changed_at
+		WHERE sync_changes.version <= EXCLUDED.version
+		RETURNING seq, changed_at
+	`
+
+	err = r.db.QueryRow(ctx, query,
+		tenantID, string(change.Entity), change.EntityID, storeKey,
+		string(change.Op), data, change.Version,
+	).Scan(&change.Seq, &change.ChangedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Changes returns a page of a store's feed. The feed's last sequence number
+// is read first: every change numbered up to it has committed, so a page
+// that reaches it moves the cursor there, past changes of other stores.
+func (r *SyncRepository) Changes(ctx context.Context, storeID uuid.UUID, since int64, limit int) (*offline.Page, error) {
+	ctx, span := startSpan(ctx, "SyncRepository.Changes")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastSeq, horizon int64
+	err = r.db.QueryRow(ctx, `SELECT last_seq, horizon FROM sync_feeds WHERE tenant_id = $1`, tenantID).
+		Scan(&lastSeq, &horizon)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &offline.Page{Changes: []*offline.Change{}, Cursor: since}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if since > 0 && since < horizon {
+		return nil, offline.ErrCursorExpired
+	}
+
+	query := `
+		SELECT seq, entity, entity_id, store_key, op, data, version, changed_at
+		FROM sync_changes
+		WHERE tenant_id = $1 AND store_key IN ($2, $3) AND seq > $4 AND seq <= $5
+			AND (op = 'upsert' OR $4 > 0)
+		ORDER BY seq
+		LIMIT $6
+	`
+
+	rows, err := r.db.Query(ctx, query, tenantID, storeID, uuid.Nil, since, lastSeq, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &offline.Page{Changes: []*offline.Change{}, Cursor: lastSeq}
+	for rows.Next() {
+		var c offline.Change
+		var entity, op string
+		var storeKey uuid.UUID
+		var data []byte
+		if err := rows.Scan(&c.Seq, &entity, &c.EntityID, &storeKey, &op, &data, &c.Version, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		c.Entity = offline.Entity(entity)
+		c.Op = offline.Op(op)
+		c.Data = data
+		if storeKey != uuid.Nil {
+			c.StoreID = &storeKey
+		}
+		page.Changes = append(page.Changes, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Changes) > limit {
+		page.Changes = page.Changes[:limit]
+		page.Cursor = page.Changes[limit-1].Seq
+		page.HasMore = true
+	}
+	if page.Cursor < since {
+		page.Cursor = since
+	}
+	return page, nil
+}
+
+// PruneTombstones deletes old tombstones of every tenant, raising each
+// feed's horizon to the highest sequence number it pruned
+func (r *SyncRepository) PruneTombstones(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "SyncRepository.PruneTombstones")
+	defer span.End()
+
+	// The horizons update runs though nothing reads it, as every data
+	// modifying statement in a WITH does
+	query := `
+		WITH pruned AS (
+			DELETE FROM sync_changes WHERE op = 'delete' AND changed_at < $1
+			RETURNING tenant_id, seq
+		), horizons AS (
+			UPDATE sync_feeds f SET horizon = GREATEST(f.horizon, p.max_seq)
+			FROM (SELECT tenant_id, MAX(seq) AS max_seq FROM pruned GROUP BY tenant_id) p
+			WHERE f.tenant_id = p.tenant_id
+		)
+		SELECT COUNT(*) FROM pruned
+	`
+
+	var deleted int64
+	err := r.db.QueryRow(ctx, query, before.UTC()).Scan(&deleted)
+	return deleted, err
+}
@@ -0,0 +1,730 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/onichange/pos-system/internal/domain/tax"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// foreignKeyViolation is the Postgres error code of a foreign key violation
+const foreignKeyViolation = "23503"
+
+// jurisdictionColumns are the columns scanJurisdiction reads
+const jurisdictionColumns = `
+	id, country, state, city, name, rate, category_rates, active, created_at, updated_at
+`
+
+// holidayColumns are the columns queryHolidays reads
+const holidayColumns = `
+	id, jurisdiction_id, name, category, starts_on, ends_on, rate, max_unit_price, created_at
+`
+
+// TaxRepository implements tax.Repository. Jurisdictions, holidays and tax
+// categories are set by tax authorities and shared by every tenant;
+// assignments, store rates and the ledger are scoped to the tenant in ctx
+// and fail with tenant.ErrNoTenant when there is none.
+type TaxRepository struct {
+	db database.Querier
+}
+
+// NewTaxRepository creates a new tax repository
+func NewTaxRepository(db database.Querier) *TaxRepository {
+	return &TaxRepository{db: db}
+}
+
+// CreateJurisdiction creates a jurisdiction
+func (r *TaxRepository) CreateJurisdiction(ctx context.Context, j *tax.Jurisdiction) error {
+	ctx, span := startSpan(ctx, "TaxRepository.CreateJurisdiction")
+	defer span.End()
+
+	ratesJSON, err := json.Marshal(categoryRatesOrEmpty(j.CategoryRates))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO tax_jurisdictions (id, country, state, city, name, rate, category_rates, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+	`
+
+	now := time.Now().UTC()
+	_, err = r.db.Exec(ctx, query, j.ID, j.Country, j.State, j.City, j.Name, j.Rate, ratesJSON, j.Active, now)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return tax.ErrJurisdictionExists
+	}
+	if err != nil {
+		return err
+	}
+	j.CreatedAt = now
+	j.UpdatedAt = now
+	return nil
+}
+
+// GetJurisdiction retrieves a jurisdiction by ID
+func (r *TaxRepository) GetJurisdiction(ctx context.Context, id uuid.UUID) (*tax.Jurisdiction, error) {
+	ctx, span := startSpan(ctx, "TaxRepository.GetJurisdiction")
+	defer span.End()
+
+	query := `SELECT ` + jurisdictionColumns + ` FROM tax_jurisdictions WHERE id = $1`
+
+	j, err := scanJurisdiction(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, tax.ErrJurisdictionNotFound
+	}
+	return j, err
+}
+
+// ListJurisdictions retrieves jurisdictions by location
+func (r *TaxRepository) ListJurisdictions(ctx context.Context, country string, limit, offset int) ([]*tax.Jurisdiction, error) {
+	ctx, span := startSpan(ctx, "TaxRepository.ListJurisdictions")
+	defer span.End()
+
+	query := `
+		SELECT ` + jurisdictionColumns + `
+		FROM tax_jurisdictions
+		WHERE $1 = '' OR country = $1
+		ORDER BY country, state, city
+		LIMIT $2 OFFSET $3
+	`
+
+	return r.queryJurisdictions(ctx, query, country, limit, offset)
+}
+
+// UpdateJurisdiction updates a jurisdiction's name, rates and status. Its
+// location never changes, so filed reports keep their meaning.
+func (r *TaxRepository) UpdateJurisdiction(ctx context.Context, j *tax.Jurisdiction) error {
+	ctx, span := startSpan(ctx, "TaxRepository.UpdateJurisdiction")
+	defer span.End()
+
+	ratesJSON, err := json.Marshal(categoryRatesOrEmpty(j.CategoryRates))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE tax_jurisdictions
+		SET name = $2, rate = $3, category_rates = $4, active = $5, updated_at = $6
+		WHERE id = $1
+	`
+
+	now := time.Now().UTC()
+	tag, err := r.db.Exec(ctx, query, j.ID, j.Name, j.Rate, ratesJSON, j.Active, now)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return tax.ErrJurisdictionNotFound
+	}
+	j.UpdatedAt = now
+	return nil
+}
+
+// MatchJurisdictions retrieves the active jurisdictions covering loc
+func (r *TaxRepository) MatchJurisdictions(ctx context.Context, loc tax.Location) ([]*tax.Jurisdiction, error) {
+	ctx, span := startSpan(ctx, "TaxRepository.MatchJurisdictions")
+	defer span.End()
+
+	query := `
+		SELECT ` + jurisdictionColumns + `
+		FROM tax_jurisdictions
+		WHERE active AND country = $1
+			AND (state = '' OR state = $2)
+			AND (city = '' OR (state = $2 AND LOWER(city) = LOWER($3)))
+		ORDER BY state <> '', city <> ''
+	`
+
+	return r.queryJurisdictions(ctx, query, loc.Country, loc.State, loc.City)
+}
+
+// SaveCategory creates or replaces a tax category
+func (r *TaxRepository) SaveCategory(ctx context.Context, c *tax.Category) error {
+	ctx, span := startSpan(ctx, "TaxRepository.SaveCategory")
+	defer span.End()
+
+	query := `
+		INSERT INTO tax_categories (code, name, description, exempt, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (code) DO UPDATE SET
+			name = EXCLUDED.name,
+			description = EXCLUDED.description,
+			exempt = EXCLUDED.exempt
+		RETURNING created_at
+	`
+
+	return r.db.QueryRow(ctx, query, c.Code, c.Name, c.Description, c.Exempt, time.Now().UTC()).Scan(&c.CreatedAt)
+}
+
+// ListCategories retrieves every tax category by code
+func (r *TaxRepository) ListCategories(ctx context.Context) ([]*tax.Category, error) {
+	ctx, span := startSpan(ctx, "TaxRepository.ListCategories")
+	defer span.End()
+
+	query := `SELECT code, name, description, exempt, created_at FROM tax_categories ORDER BY code`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*tax.Category{}
+	for rows.Next() {
+		var c tax.Category
+		if err := rows.Scan(&c.Code, &c.Name, &c.Description, &c.Exempt, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		categories = append(categories, &c)
+	}
+	return categories, rows.Err()
+}
+
+// SaveAssignment creates or replaces a tax category assignment
+func (r *TaxRepository) SaveAssignment(ctx context.Context, a *tax.Assignment) error {
+	ctx, span := startSpan(ctx, "TaxRepository.SaveAssignment")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO tax_assignments (subject_id, kind, category, updated_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (kind, subject_id) DO UPDATE SET
+			category = EXCLUDED.category,
+			updated_at = EXCLUDED.updated_at
+		WHERE tax_assignments.tenant_id = EXCLUDED.tenant_id
+	`
+
+	now := time.Now().UTC()
+	_, err = r.db.Exec(ctx, query, a.SubjectID, string(a.Kind), a.Category, now, tenantID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return tax.ErrCategoryNotFound
+	}
+	if err != nil {
+		return err
+	}
+	a.UpdatedAt = now
+	return nil
+}
+
+// DeleteAssignment removes a tax category assignment
+func (r *TaxRepository) DeleteAssignment(ctx context.Context, kind tax.AssignmentKind, subjectID uuid.UUID) error {
+	ctx, span := startSpan(ctx, "TaxRepository.DeleteAssignment")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM tax_assignments WHERE kind = $1 AND subject_id = $2 AND tenant_id = $3`, string(kind), subjectID, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return tax.ErrAssignmentNotFound
+	}
+	return nil
+}
+
+// ListAssignments retrieves assignments, latest first. An empty kind lists
+// both kinds.
+func (r *TaxRepository) ListAssignments(ctx context.Context, kind tax.AssignmentKind, limit, offset int) ([]*tax.Assignment, error) {
+	ctx, span := startSpan(ctx, "TaxRepository.ListAssignments")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT subject_id, kind, category, updated_at
+		FROM tax_assignments
+		WHERE tenant_id = $4 AND ($1 = '' OR kind = $1)
+		ORDER BY updated_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, string(kind), limit, offset, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []*tax.Assignment{}
+	for rows.Next() {
+		var a tax.Assignment
+		var kind string
+		if err := rows.Scan(&a.SubjectID, &kind, &a.Category, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		a.Kind = tax.AssignmentKind(kind)
+		assignments = append(assignments, &a)
+	}
+	return assignments, rows.Err()
+}
+
+// ResolveCategories retrieves the tax category of each line in one query,
+// along with the standard category for lines without an assignment
+func (r *TaxRepository) ResolveCategories(ctx context.Context, lines []tax.Line) ([]*tax.Category, error) {
+	ctx, span := startSpan(ctx, "TaxRepository.ResolveCategories")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var productIDs, catalogCategoryIDs []uuid.UUID
+	for _, l := range lines {
+		if id, err := uuid.Parse(l.ProductID); err == nil {
+			productIDs = append(productIDs, id)
+		}
+		if l.CategoryID != nil {
+			catalogCategoryIDs = append(catalogCategoryIDs, *l.CategoryID)
+		}
+	}
+
+	query := `
+		SELECT a.kind, a.subject_id, c.code, c.name, c.description, c.exempt, c.created_at
+		FROM tax_assignments a
+		JOIN tax_categories c ON c.code = a.category
+		WHERE a.tenant_id = $4 AND (
+			(a.kind = 'product' AND a.subject_id = ANY($1))
+			OR (a.kind = 'catalog_category' AND a.subject_id = ANY($2)))
+		UNION ALL
+		SELECT '', NULL, code, name, description, exempt, created_at
+		FROM tax_categories
+		WHERE code = $3
+	`
+
+	rows, err := r.db.Query(ctx, query, productIDs, catalogCategoryIDs, tax.StandardCategory, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make(map[uuid.UUID]*tax.Category)
+	catalogCategories := make(map[uuid.UUID]*tax.Category)
+	standard := &tax.Category{Code: tax.StandardCategory, Name: "Standard"}
+	for rows.Next() {
+		var c tax.Category
+		var kind string
+		var subjectID *uuid.UUID
+		if err := rows.Scan(&kind, &subjectID, &c.Code, &c.Name, &c.Description, &c.Exempt, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		switch tax.AssignmentKind(kind) {
+		case tax.AssignProduct:
+			products[*subjectID] = &c
+		case tax.AssignCatalogCategory:
+			catalogCategories[*subjectID] = &c
+		default:
+			standard = &c
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	categories := make([]*tax.Category, len(lines))
+	for i, l := range lines {
+		categories[i] = standard
+		if l.CategoryID != nil {
+			if c, ok := catalogCategories[*l.CategoryID]; ok {
+				categories[i] = c
+			}
+		}
+		if id, err := uuid.Parse(l.ProductID); err == nil {
+			if c, ok := products[id]; ok {
+				categories[i] = c
+			}
+		}
+	}
+	return categories, nil
+}
+
+// SaveStoreRate creates or replaces how a store levies a jurisdiction
+func (r *TaxRepository) SaveStoreRate(ctx context.Context, rate *tax.StoreRate) error {
+	ctx, span := startSpan(ctx, "TaxRepository.SaveStoreRate")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	ratesJSON, err := json.Marshal(categoryRatesOrEmpty(rate.CategoryRates))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO tax_rates (store_id, jurisdiction_id, rate, category_rates, exempt_categories, inclusive, updated_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (tenant_id, store_id, jurisdiction_id) DO UPDATE SET
+			rate = EXCLUDED.rate,
+			category_rates = EXCLUDED.category_rates,
+			exempt_categories = EXCLUDED.exempt_categories,
+			inclusive = EXCLUDED.inclusive,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	now := time.Now().UTC()
+	_, err = r.db.Exec(ctx, query,
+		rate.StoreID, rate.JurisdictionID, rate.Rate, ratesJSON, exemptCategoriesOrEmpty(rate.ExemptCategories), rate.Inclusive, now, tenantID,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return tax.ErrJurisdictionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	rate.UpdatedAt = now
+	return nil
+}
+
+// StoreRates retrieves the rates of a store
+func (r *TaxRepository) StoreRates(ctx context.Context, storeID uuid.UUID) ([]*tax.StoreRate, error) {
+	ctx, span := startSpan(ctx, "TaxRepository.StoreRates")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT store_id, jurisdiction_id, rate, category_rates, exempt_categories, inclusive, updated_at
+		FROM tax_rates
+		WHERE tenant_id = $1 AND store_id = $2
+		ORDER BY jurisdiction_id
+	`
+
+	rows, err := r.db.Query(ctx, query, tenantID, storeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []*tax.StoreRate{}
+	for rows.Next() {
+		var rate tax.StoreRate
+		var ratesJSON []byte
+		err := rows.Scan(
+			&rate.StoreID, &rate.JurisdictionID, &rate.Rate, &ratesJSON, &rate.ExemptCategories, &rate.Inclusive, &rate.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(ratesJSON, &rate.CategoryRates); err != nil {
+			return nil, err
+		}
+		rates = append(rates, &rate)
+	}
+	return rates, rows.Err()
+}
+
+// DeleteStoreRate removes a store's rate of a jurisdiction, leaving the
+// jurisdiction's own rates to apply
+func (r *TaxRepository) DeleteStoreRate(ctx context.Context, storeID, jurisdictionID uuid.UUID) error {
+	ctx, span := startSpan(ctx, "TaxRepository.DeleteStoreRate")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM tax_rates WHERE tenant_id = $1 AND store_id = $2 AND jurisdiction_id = $3`, tenantID, storeID, jurisdictionID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return tax.ErrStoreRateNotFound
+	}
+	return nil
+}
+
+// CreateHoliday creates a tax holiday
+func (r *TaxRepository) CreateHoliday(ctx context.Context, h *tax.Holiday) error {
+	ctx, span := startSpan(ctx, "TaxRepository.CreateHoliday")
+	defer span.End()
+
+	query := `
+		INSERT INTO tax_holidays (id, jurisdiction_id, name, category, starts_on, ends_on, rate, max_unit_price, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	now := time.Now().UTC()
+	_, err := r.db.Exec(ctx, query,
+		h.ID, h.JurisdictionID, h.Name, h.Category, h.StartsOn, h.EndsOn, h.Rate, h.MaxUnitPrice, now,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return tax.ErrJurisdictionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	h.CreatedAt = now
+	return nil
+}
+
+// ListHolidays retrieves holidays, latest start first
+func (r *TaxRepository) ListHolidays(ctx context.Context, jurisdictionID *uuid.UUID, limit, offset int) ([]*tax.Holiday, error) {
+	ctx, span := startSpan(ctx, "TaxRepository.ListHolidays")
+	defer span.End()
+
+	query := `
+		SELECT ` + holidayColumns + `
+		FROM tax_holidays
+		WHERE $1::uuid IS NULL OR jurisdiction_id = $1
+		ORDER BY starts_on DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	return r.queryHolidays(ctx, query, jurisdictionID, limit, offset)
+}
+
+// DeleteHoliday removes a tax holiday
+func (r *TaxRepository) DeleteHoliday(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startSpan(ctx, "TaxRepository.DeleteHoliday")
+	defer span.End()
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM tax_holidays WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return tax.ErrHolidayNotFound
+	}
+	return nil
+}
+
+// GetHolidays retrieves the holidays of jurisdictionIDs running on day
+func (r *TaxRepository) GetHolidays(ctx context.Context, jurisdictionIDs []uuid.UUID, day time.Time) ([]*tax.Holiday, error) {
+	ctx, span := startSpan(ctx, "TaxRepository.GetHolidays")
+	defer span.End()
+
+	if len(jurisdictionIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT ` + holidayColumns + `
+		FROM tax_holidays
+		WHERE jurisdiction_id = ANY($1) AND starts_on <= $2::date AND ends_on >= $2::date
+	`
+
+	return r.queryHolidays(ctx, query, jurisdictionIDs, day.UTC())
+}
+
+// RecordSale records the tax collected on an order in one statement. A
+// replaced sale keeps the time it was first recorded, and drops the
+// jurisdictions no longer taxing it.
+func (r *TaxRepository) RecordSale(ctx context.Context, sale *tax.Sale) error {
+	ctx, span := startSpan(ctx, "TaxRepository.RecordSale")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	jurisdictionIDs := make([]uuid.UUID, len(sale.Taxes))
+	taxable := make([]float64, len(sale.Taxes))
+	exempt := make([]float64, len(sale.Taxes))
+	amounts := make([]float64, len(sale.Taxes))
+	for i, t := range sale.Taxes {
+		jurisdictionIDs[i] = t.JurisdictionID
+		taxable[i] = t.Taxable
+		exempt[i] = t.Exempt
+		amounts[i] = t.Tax
+	}
+
+	query := `
+		WITH reversed AS (
+			SELECT 1 FROM tax_ledger WHERE order_id = $1 AND tenant_id = $9 AND kind = 'reversal' LIMIT 1
+		), dropped AS (
+			DELETE FROM tax_ledger
+			WHERE order_id = $1 AND tenant_id = $9 AND kind = 'sale' AND jurisdiction_id <> ALL($5)
+				AND NOT EXISTS (SELECT 1 FROM reversed)
+		)
+		INSERT INTO tax_ledger (order_id, jurisdiction_id, kind, store_id, currency, taxable, exempt, tax, occurred_at, tenant_id)
+		SELECT $1, t.jurisdiction_id, 'sale', $2, $3, t.taxable, t.exempt, t.tax, $4, $9
+		FROM unnest($5::uuid[], $6::numeric[], $7::numeric[], $8::numeric[]) AS t(jurisdiction_id, taxable, exempt, tax)
+		WHERE NOT EXISTS (SELECT 1 FROM reversed)
+		ON CONFLICT (order_id, jurisdiction_id, kind) DO UPDATE SET
+			currency = EXCLUDED.currency,
+			taxable = EXCLUDED.taxable,
+			exempt = EXCLUDED.exempt,
+			tax = EXCLUDED.tax
+		WHERE tax_ledger.tenant_id = EXCLUDED.tenant_id
+	`
+
+	_, err = r.db.Exec(ctx, query,
+		sale.OrderID, sale.StoreID, sale.Currency, sale.OccurredAt.UTC(), jurisdictionIDs, taxable, exempt, amounts, tenantID,
+	)
+	return err
+}
+
+// ReverseSale copies an order's sale entries as reversals dated at
+func (r *TaxRepository) ReverseSale(ctx context.Context, orderID uuid.UUID, at time.Time) error {
+	ctx, span := startSpan(ctx, "TaxRepository.ReverseSale")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO tax_ledger (order_id, jurisdiction_id, kind, store_id, currency, taxable, exempt, tax, occurred_at, tenant_id)
+		SELECT order_id, jurisdiction_id, 'reversal', store_id, currency, taxable, exempt, tax, $2, tenant_id
+		FROM tax_ledger
+		WHERE order_id = $1 AND tenant_id = $3 AND kind = 'sale'
+		ON CONFLICT (order_id, jurisdiction_id, kind) DO NOTHING
+	`
+
+	_, err = r.db.Exec(ctx, query, orderID, at.UTC(), tenantID)
+	return err
+}
+
+// Report totals the ledger by jurisdiction and currency, reversals taken off
+func (r *TaxRepository) Report(ctx context.Context, filter tax.ReportFilter) ([]*tax.ReportRow, error) {
+	ctx, span := startSpan(ctx, "TaxRepository.Report")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT j.id, j.name, j.country, j.state, j.city, l.currency,
+			COUNT(*) FILTER (WHERE l.kind = 'sale') - COUNT(*) FILTER (WHERE l.kind = 'reversal'),
+			COALESCE(SUM(CASE WHEN l.kind = 'sale' THEN l.taxable ELSE -l.taxable END), 0),
+			COALESCE(SUM(CASE WHEN l.kind = 'sale' THEN l.exempt ELSE -l.exempt END), 0),
+			COALESCE(SUM(CASE WHEN l.kind = 'sale' THEN l.tax ELSE -l.tax END), 0)
+		FROM tax_ledger l
+		JOIN tax_jurisdictions j ON j.id = l.jurisdiction_id
+		WHERE l.tenant_id = $5 AND l.occurred_at >= $1 AND l.occurred_at < $2
+			AND ($3 = '' OR j.country = $3)
+			AND ($4 = '' OR j.state = $4)
+		GROUP BY j.id, j.name, j.country, j.state, j.city, l.currency
+		ORDER BY j.country, j.state, j.city, l.currency
+	`
+
+	from := filter.From.UTC()
+	to := filter.To.UTC().AddDate(0, 0, 1)
+	rows, err := r.db.Query(ctx, query, from, to, filter.Country, filter.State, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := []*tax.ReportRow{}
+	for rows.Next() {
+		var row tax.ReportRow
+		err := rows.Scan(
+			&row.JurisdictionID, &row.Name, &row.Country, &row.State, &row.City, &row.Currency,
+			&row.Orders, &row.Taxable, &row.Exempt, &row.Tax,
+		)
+		if err != nil {
+			return nil, err
+		}
+		row.Level = (&tax.Jurisdiction{State: row.State, City: row.City}).Level()
+		report = append(report, &row)
+	}
+	return report, rows.Err()
+}
+
+// queryJurisdictions runs a query selecting jurisdictionColumns
+func (r *TaxRepository) queryJurisdictions(ctx context.Context, query string, args ...interface{}) ([]*tax.Jurisdiction, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jurisdictions := []*tax.Jurisdiction{}
+	for rows.Next() {
+		j, err := scanJurisdiction(rows)
+		if err != nil {
+			return nil, err
+		}
+		jurisdictions = append(jurisdictions, j)
+	}
+	return jurisdictions, rows.Err()
+}
+
+// queryHolidays runs a query selecting holidayColumns
+func (r *TaxRepository) queryHolidays(ctx context.Context, query string, args ...interface{}) ([]*tax.Holiday, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holidays := []*tax.Holiday{}
+	for rows.Next() {
+		var h tax.Holiday
+		err := rows.Scan(
+			&h.ID, &h.JurisdictionID, &h.Name, &h.Category, &h.StartsOn, &h.EndsOn, &h.Rate, &h.MaxUnitPrice, &h.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, &h)
+	}
+	return holidays, rows.Err()
+}
+
+// scanJurisdiction scans jurisdictionColumns into a Jurisdiction
+func scanJurisdiction(row interface{ Scan(dest ...interface{}) error }) (*tax.Jurisdiction, error) {
+	var j tax.Jurisdiction
+	var ratesJSON []byte
+
+	err := row.Scan(
+		&j.ID, &j.Country, &j.State, &j.City, &j.Name, &j.Rate, &ratesJSON, &j.Active, &j.CreatedAt, &j.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(ratesJSON, &j.CategoryRates); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// categoryRatesOrEmpty stores a nil rate map as an empty object
+func categoryRatesOrEmpty(rates map[string]float64) map[string]float64 {
+	if rates == nil {
+		return map[string]float64{}
+	}
+	return rates
+}
+
+// exemptCategoriesOrEmpty stores a nil list of exempt categories as an empty array
+func exemptCategoriesOrEmpty(categories []string) []string {
+	if categories == nil {
+		return []string{}
+	}
+	return categories
+}
@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/onichange/pos-system/internal/domain/shift"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// timeEntryColumns are the columns scanTimeEntry reads
+const timeEntryColumns = `
+	id, store_id, staff_id, shift_id, status, clock_in, clock_out, breaks, notes, version, updated_at
+`
+
+// TimeClockRepository implements shift.TimeClockRepository. Every query is
+// scoped to the tenant in ctx and fails with tenant.ErrNoTenant when there
+// is none.
+type TimeClockRepository struct {
+	db database.Querier
+}
+
+// NewTimeClockRepository creates a new time clock repository
+func NewTimeClockRepository(db database.Querier) *TimeClockRepository {
+	return &TimeClockRepository{db: db}
+}
+
+// ClockIn starts a time entry
+func (r *TimeClockRepository) ClockIn(ctx context.Context, e *shift.TimeEntry) error {
+	ctx, span := startSpan(ctx, "TimeClockRepository.ClockIn")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO time_entries (id, store_id, staff_id, shift_id, status, clock_in, notes, version, updated_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, 1, $6, $8)
+	`
+
+	now := time.Now().UTC()
+	_, err = r.db.Exec(ctx, query, e.ID, e.StoreID, e.StaffID, e.ShiftID, string(shift.EntryOpen), now, e.Notes, tenantID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return shift.ErrClockedIn
+	}
+	if err != nil {
+		return err
+	}
+	e.Status = shift.EntryOpen
+	e.ClockIn = now
+	e.Breaks = []shift.Break{}
+	e.Version = 1
+	e.UpdatedAt = now
+	return nil
+}
+
+// GetEntry retrieves a time entry by ID
+func (r *TimeClockRepository) GetEntry(ctx context.Context, id uuid.UUID) (*shift.TimeEntry, error) {
+	ctx, span := startSpan(ctx, "TimeClockRepository.GetEntry")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + timeEntryColumns + ` FROM time_entries WHERE id = $1 AND tenant_id = $2`
+
+	e, err := scanTimeEntry(r.db.QueryRow(ctx, query, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, shift.ErrTimeEntryNotFound
+	}
+	return e, err
+}
+
+// GetOpenEntry retrieves a staff member's open time entry
+func (r *TimeClockRepository) GetOpenEntry(ctx context.Context, staffID uuid.UUID) (*shift.TimeEntry, error) {
+	ctx, span := startSpan(ctx, "TimeClockRepository.GetOpenEntry")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + timeEntryColumns + ` FROM time_entries WHERE staff_id = $1 AND tenant_id = $2 AND status = 'open'`
+
+	e, err := scanTimeEntry(r.db.QueryRow(ctx, query, staffID, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, shift.ErrNotClockedIn
+	}
+	return e, err
+}
+
+// ListEntries retrieves the time entries matching filter, latest clock-in
+// first
+func (r *TimeClockRepository) ListEntries(ctx context.Context, filter shift.EntryFilter, limit, offset int) ([]*shift.TimeEntry, error) {
+	ctx, span := startSpan(ctx, "TimeClockRepository.ListEntries")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + timeEntryColumns + `
+		FROM time_entries
+		WHERE tenant_id = $8
+			AND ($1::uuid IS NULL OR store_id = $1)
+			AND ($2::uuid IS NULL OR staff_id = $2)
+			AND ($3 = '' OR status = $3)
+			AND ($4::timestamp IS NULL OR clock_in >= $4)
+			AND ($5::timestamp IS NULL OR clock_in < $5)
+		ORDER BY clock_in DESC
+		LIMIT $6 OFFSET $7
+	`
+
+	rows, err := r.db.Query(ctx, query,
+		filter.StoreID, filter.StaffID, string(filter.Status), utcOrNil(filter.From), utcOrNil(filter.To), limit, offset, tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*shift.TimeEntry{}
+	for rows.Next() {
+		e, err := scanTimeEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Update saves a changed time entry against the version it was read at
+func (r *TimeClockRepository) Update(ctx context.Context, e *shift.TimeEntry) error {
+	ctx, span := startSpan(ctx, "TimeClockRepository.Update")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	breaksJSON, err := json.Marshal(e.Breaks)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE time_entries
+		SET status = $3, clock_in = $4, clock_out = $5, breaks = $6, notes = $7,
+			version = version + 1, updated_at = $8
+		WHERE id = $1 AND tenant_id = $9 AND version = $2
+		RETURNING version
+	`
+
+	now := time.Now().UTC()
+	err = r.db.QueryRow(ctx, query,
+		e.ID, e.Version, string(e.Status), e.ClockIn.UTC(), utcOrNil(e.ClockOut), breaksJSON, e.Notes, now, tenantID,
+	).Scan(&e.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.changedOrMissing(ctx, tenantID, e.ID)
+	}
+	if err != nil {
+		return err
+	}
+	e.UpdatedAt = now
+	return nil
+}
+
+// Adjust saves a manager's change to a time entry and its audit record in
+// one statement, so neither lands without the other
+func (r *TimeClockRepository) Adjust(ctx context.Context, e *shift.TimeEntry, adj *shift.TimeAdjustment) error {
+	ctx, span := startSpan(ctx, "TimeClockRepository.Adjust")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	after := *e
+	after.Version++
+	after.UpdatedAt = now
+
+	breaksJSON, err := json.Marshal(e.Breaks)
+	if err != nil {
+		return err
+	}
+	beforeJSON, err := json.Marshal(adj.Before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := json.Marshal(&after)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH updated AS (
+			UPDATE time_entries
+			SET status = $3, clock_in = $4, clock_out = $5, breaks = $6, notes = $7,
+				version = version + 1, updated_at = $8
+			WHERE id = $1 AND tenant_id = $9 AND version = $2
+			RETURNING id
+		)
+		INSERT INTO time_entry_adjustments (id, tenant_id, entry_id, adjusted_by, reason, before, after, created_at)
+		SELECT $10, $9, id, $11, $12, $13, $14, $8 FROM updated
+	`
+
+	tag, err := r.db.Exec(ctx, query,
+		e.ID, e.Version, string(e.Status), e.ClockIn.UTC(), utcOrNil(e.ClockOut), breaksJSON, e.Notes, now, tenantID,
+		adj.ID, adj.AdjustedBy, adj.Reason, beforeJSON, afterJSON,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return r.changedOrMissing(ctx, tenantID, e.ID)
+	}
+	*e = after
+	adj.EntryID = e.ID
+	adj.After = &after
+	adj.CreatedAt = now
+	return nil
+}
+
+// GetAdjustments retrieves a time entry's adjustments, oldest first
+func (r *TimeClockRepository) GetAdjustments(ctx context.Context, entryID uuid.UUID) ([]*shift.TimeAdjustment, error) {
+	ctx, span := startSpan(ctx, "TimeClockRepository.GetAdjustments")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, entry_id, adjusted_by, reason, before, after, created_at
+		FROM time_entry_adjustments
+		WHERE entry_id = $1 AND tenant_id = $2
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, entryID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	adjustments := []*shift.TimeAdjustment{}
+	for rows.Next() {
+		var adj shift.TimeAdjustment
+		var beforeJSON, afterJSON []byte
+		if err := rows.Scan(&adj.ID, &adj.EntryID, &adj.AdjustedBy, &adj.Reason, &beforeJSON, &afterJSON, &adj.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(beforeJSON, &adj.Before); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(afterJSON, &adj.After); err != nil {
+			return nil, err
+		}
+		adjustments = append(adjustments, &adj)
+	}
+	return adjustments, rows.Err()
+}
+
+// changedOrMissing tells a time entry of a tenant changed since it was read
+// from one that does not exist
+func (r *TimeClockRepository) changedOrMissing(ctx context.Context, tenantID string, id uuid.UUID) error {
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM time_entries WHERE id = $1 AND tenant_id = $2)`, id, tenantID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return shift.ErrEntryChanged
+	}
+	return shift.ErrTimeEntryNotFound
+}
+
+// utcOrNil returns t in UTC, or nil when it is unset
+func utcOrNil(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// scanTimeEntry scans timeEntryColumns into a TimeEntry
+func scanTimeEntry(row interface {
+	Scan(dest ...interface{}) error
+}) (*shift.TimeEntry, error) {
+	var e shift.TimeEntry
+	var status string
+	var breaksJSON []byte
+
+	err := row.Scan(
+		&e.ID, &e.StoreID, &e.StaffID, &e.ShiftID, &status, &e.ClockIn, &e.ClockOut, &breaksJSON, &e.Notes, &e.Version, &e.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	e.Status = shift.EntryStatus(status)
+	if err := json.Unmarshal(breaksJSON, &e.Breaks); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name used for repository spans
+const tracerName = "repository"
+
+// startSpan starts a span for a repository method. The queries it runs nest
+// beneath it as database spans carrying their statements.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name)
+}
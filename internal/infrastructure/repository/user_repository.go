@@ -3,37 +3,63 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 
 	"github.com/onichange/pos-system/internal/domain/user"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/encryption"
+	"github.com/onichange/pos-system/pkg/tenant"
 )
 
-// UserRepository implements user.Repository
+// UserRepository implements user.Repository. Every query is scoped to the
+// tenant in ctx and fails with tenant.ErrNoTenant when there is none; emails
+// are unique within a tenant.
 type UserRepository struct {
-	db *pgxpool.Pool
+	db       database.Querier
+	envelope *encryption.Envelope   // Encrypts fields tagged encrypt:"pii"; nil stores them as is
+	index    *encryption.BlindIndex // Indexes encrypted emails for lookup; needed with an envelope
 }
 
+// emailIndexDomain separates email indexes from those of other columns
+const emailIndexDomain = "users.email"
+
 // NewUserRepository creates a new user repository
-func NewUserRepository(db *pgxpool.Pool) *UserRepository {
-	return &UserRepository{db: db}
+func NewUserRepository(db database.Querier, envelope *encryption.Envelope, index *encryption.BlindIndex) *UserRepository {
+	return &UserRepository{db: db, envelope: envelope, index: index}
 }
 
 // Create creates a new user
 func (r *UserRepository) Create(ctx context.Context, u *user.User) error {
+	ctx, span := startSpan(ctx, "UserRepository.Create")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+	u.TenantID = tenantID
+
 	query := `
 		INSERT INTO users (
-			id, email, password_hash, first_name, last_name, phone,
-			mfa_enabled, mfa_secret, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			id, email, email_index, password_hash, first_name, last_name, phone,
+			roles, mfa_enabled, mfa_secret, created_at, updated_at, tenant_id
+		) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
+	sealed, err := r.seal(ctx, u)
+	if err != nil {
+		return err
+	}
+
 	now := time.Now()
-	_, err := r.db.Exec(ctx, query,
-		u.ID, u.Email, u.PasswordHash, u.FirstName, u.LastName, u.Phone,
-		u.MFAEnabled, u.MFASecret, now, now,
+	_, err = r.db.Exec(ctx, query,
+		u.ID, sealed.Email, r.emailIndex(u.Email), u.PasswordHash, u.FirstName, u.LastName, sealed.Phone,
+		userRoles(u.Roles), u.MFAEnabled, sealed.MFASecret, now, now, tenantID,
 	)
 
 	return err
@@ -41,21 +67,29 @@ func (r *UserRepository) Create(ctx context.Context, u *user.User) error {
 
 // GetByID retrieves a user by ID
 func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	ctx, span := startSpan(ctx, "UserRepository.GetByID")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-		SELECT id, email, password_hash, first_name, last_name, phone,
+		SELECT id, email, password_hash, first_name, last_name, phone, roles,
 			mfa_enabled, mfa_secret, failed_login_attempts, account_locked_until,
-			last_login_at, created_at, updated_at, deleted_at
+			last_login_at, created_at, updated_at, deleted_at, tenant_id
 		FROM users
-		WHERE id = $1 AND deleted_at IS NULL
+		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
 	`
 
 	var u user.User
 	var accountLockedUntil, lastLoginAt, deletedAt sql.NullTime
 
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&u.ID, &u.Email, &u.PasswordHash, &u.FirstName, &u.LastName, &u.Phone,
+	err = r.db.QueryRow(ctx, query, id, tenantID).Scan(
+		&u.ID, &u.Email, &u.PasswordHash, &u.FirstName, &u.LastName, &u.Phone, &u.Roles,
 		&u.MFAEnabled, &u.MFASecret, &u.FailedLoginAttempts, &accountLockedUntil,
-		&lastLoginAt, &u.CreatedAt, &u.UpdatedAt, &deletedAt,
+		&lastLoginAt, &u.CreatedAt, &u.UpdatedAt, &deletedAt, &u.TenantID,
 	)
 	if err != nil {
 		return nil, err
@@ -70,27 +104,39 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*user.User,
 	if deletedAt.Valid {
 		u.DeletedAt = &deletedAt.Time
 	}
+	if err := r.envelope.DecryptFields(ctx, &u, userAAD(u.ID.String())); err != nil {
+		return nil, err
+	}
 
 	return &u, nil
 }
 
-// GetByEmail retrieves a user by email
+// GetByEmail retrieves a user by email. Rows written before the blind index
+// was filled in are matched by their plaintext email.
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*user.User, error) {
+	ctx, span := startSpan(ctx, "UserRepository.GetByEmail")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-		SELECT id, email, password_hash, first_name, last_name, phone,
+		SELECT id, email, password_hash, first_name, last_name, phone, roles,
 			mfa_enabled, mfa_secret, failed_login_attempts, account_locked_until,
-			last_login_at, created_at, updated_at, deleted_at
+			last_login_at, created_at, updated_at, deleted_at, tenant_id
 		FROM users
-		WHERE email = $1 AND deleted_at IS NULL
+		WHERE (email_index = $1 OR (email_index IS NULL AND email = $2)) AND tenant_id = $3 AND deleted_at IS NULL
 	`
 
 	var u user.User
 	var accountLockedUntil, lastLoginAt, deletedAt sql.NullTime
 
-	err := r.db.QueryRow(ctx, query, email).Scan(
-		&u.ID, &u.Email, &u.PasswordHash, &u.FirstName, &u.LastName, &u.Phone,
+	err = r.db.QueryRow(ctx, query, r.emailIndex(email), email, tenantID).Scan(
+		&u.ID, &u.Email, &u.PasswordHash, &u.FirstName, &u.LastName, &u.Phone, &u.Roles,
 		&u.MFAEnabled, &u.MFASecret, &u.FailedLoginAttempts, &accountLockedUntil,
-		&lastLoginAt, &u.CreatedAt, &u.UpdatedAt, &deletedAt,
+		&lastLoginAt, &u.CreatedAt, &u.UpdatedAt, &deletedAt, &u.TenantID,
 	)
 	if err != nil {
 		return nil, err
@@ -105,26 +151,42 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*user.Us
 	if deletedAt.Valid {
 		u.DeletedAt = &deletedAt.Time
 	}
+	if err := r.envelope.DecryptFields(ctx, &u, userAAD(u.ID.String())); err != nil {
+		return nil, err
+	}
 
 	return &u, nil
 }
 
 // Update updates a user
 func (r *UserRepository) Update(ctx context.Context, u *user.User) error {
+	ctx, span := startSpan(ctx, "UserRepository.Update")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE users SET
 			first_name = $2, last_name = $3, phone = $4,
 			mfa_enabled = $5, mfa_secret = $6,
 			failed_login_attempts = $7, account_locked_until = $8,
 			last_login_at = $9, updated_at = $10
-		WHERE id = $1 AND deleted_at IS NULL
+		WHERE id = $1 AND tenant_id = $11 AND deleted_at IS NULL
 	`
 
-	_, err := r.db.Exec(ctx, query,
-		u.ID, u.FirstName, u.LastName, u.Phone,
-		u.MFAEnabled, u.MFASecret,
+	sealed, err := r.seal(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(ctx, query,
+		u.ID, u.FirstName, u.LastName, sealed.Phone,
+		u.MFAEnabled, sealed.MFASecret,
 		u.FailedLoginAttempts, u.AccountLockedUntil,
-		u.LastLoginAt, time.Now(),
+		u.LastLoginAt, time.Now(), tenantID,
 	)
 
 	return err
@@ -132,34 +194,190 @@ func (r *UserRepository) Update(ctx context.Context, u *user.User) error {
 
 // UpdatePassword updates user password
 func (r *UserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	ctx, span := startSpan(ctx, "UserRepository.UpdatePassword")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE users SET
 			password_hash = $2,
 			updated_at = $3
-		WHERE id = $1 AND deleted_at IS NULL
+		WHERE id = $1 AND tenant_id = $4 AND deleted_at IS NULL
 	`
 
-	_, err := r.db.Exec(ctx, query, id, passwordHash, time.Now())
+	_, err = r.db.Exec(ctx, query, id, passwordHash, time.Now(), tenantID)
 	return err
 }
 
+// SetRoles replaces the roles of a user
+func (r *UserRepository) SetRoles(ctx context.Context, id uuid.UUID, roles []string) error {
+	ctx, span := startSpan(ctx, "UserRepository.SetRoles")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE users SET
+			roles = $2,
+			updated_at = $3
+		WHERE id = $1 AND tenant_id = $4 AND deleted_at IS NULL
+	`
+
+	tag, err := r.db.Exec(ctx, query, id, userRoles(roles), time.Now(), tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
 // Delete soft deletes a user
 func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startSpan(ctx, "UserRepository.Delete")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE users SET
 			deleted_at = $2,
 			updated_at = $2
-		WHERE id = $1
+		WHERE id = $1 AND tenant_id = $3
 	`
 
-	_, err := r.db.Exec(ctx, query, id, time.Now())
+	_, err = r.db.Exec(ctx, query, id, time.Now(), tenantID)
 	return err
 }
 
 // ExistsByEmail checks if user exists by email
 func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1 AND deleted_at IS NULL)`
+	ctx, span := startSpan(ctx, "UserRepository.ExistsByEmail")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM users
+			WHERE (email_index = $1 OR (email_index IS NULL AND email = $2)) AND tenant_id = $3 AND deleted_at IS NULL
+		)
+	`
 	var exists bool
-	err := r.db.QueryRow(ctx, query, email).Scan(&exists)
+	err = r.db.QueryRow(ctx, query, r.emailIndex(email), email, tenantID).Scan(&exists)
 	return exists, err
 }
+
+// userRoles defaults the roles of a user to "user"
+func userRoles(roles []string) []string {
+	if len(roles) == 0 {
+		return []string{"user"}
+	}
+	return roles
+}
+
+// userAAD binds a user's encrypted fields to their row, so they cannot be
+// copied to another user
+func userAAD(id string) []byte {
+	return []byte("users:" + id)
+}
+
+// seal returns a copy of u with its PII fields encrypted for storage
+func (r *UserRepository) seal(ctx context.Context, u *user.User) (*user.User, error) {
+	sealed := *u
+	if err := r.envelope.EncryptFields(ctx, &sealed, userAAD(u.ID.String())); err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+// emailIndex returns the blind index of an email, or an empty string, which
+// matches no row, without a blind index
+func (r *UserRepository) emailIndex(email string) string {
+	return r.index.Compute(emailIndexDomain, encryption.NormalizeEmail(email))
+}
+
+// BackfillBlindIndexes fills in the email index of users stored before it
+// existed, or with rebuild, of every user, as after a change of index key.
+// It works through the users of every tenant in ID order, batchSize at a
+// time, and returns how many were indexed.
+func (r *UserRepository) BackfillBlindIndexes(ctx context.Context, batchSize int, rebuild bool) (int, error) {
+	if r.index == nil {
+		return 0, errors.New("no blind index key configured")
+	}
+
+	query := `
+		SELECT id, email FROM users
+		WHERE id > $1 AND ($3 OR email_index IS NULL)
+		ORDER BY id
+		LIMIT $2
+	`
+	indexed := 0
+	after := uuid.Nil
+	for {
+		rows, err := r.db.Query(ctx, query, after, batchSize, rebuild)
+		if err != nil {
+			return indexed, err
+		}
+		type pending struct {
+			id    uuid.UUID
+			email string
+		}
+		var batch []pending
+		for rows.Next() {
+			var p pending
+			if err := rows.Scan(&p.id, &p.email); err != nil {
+				rows.Close()
+				return indexed, err
+			}
+			batch = append(batch, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return indexed, err
+		}
+
+		for _, p := range batch {
+			email, err := r.envelope.DecryptString(ctx, p.email, userAAD(p.id.String()))
+			if err != nil {
+				return indexed, fmt.Errorf("user %s: %w", p.id, err)
+			}
+			_, err = r.db.Exec(ctx, `UPDATE users SET email_index = $2 WHERE id = $1 AND email = $3`,
+				p.id, r.emailIndex(email), p.email)
+			if err != nil {
+				return indexed, fmt.Errorf("user %s: %w", p.id, err)
+			}
+			indexed++
+		}
+
+		if len(batch) < batchSize {
+			return indexed, nil
+		}
+		after = batch[len(batch)-1].id
+	}
+}
+
+// EncryptedColumns returns the stored PII columns, for re-encryption under a
+// rotated key, across tenants. Emails are encrypted once they are indexed,
+// so they stay findable.
+func (r *UserRepository) EncryptedColumns() []encryption.SealedStore {
+	return []encryption.SealedStore{
+		sealedColumn{db: r.db, table: "users", column: "email", aad: userAAD, where: "email_index IS NOT NULL"},
+		sealedColumn{db: r.db, table: "users", column: "phone", aad: userAAD},
+		sealedColumn{db: r.db, table: "users", column: "mfa_secret", aad: userAAD},
+	}
+}
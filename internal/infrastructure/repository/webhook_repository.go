@@ -0,0 +1,489 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/onichange/pos-system/internal/domain/webhook"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// WebhookRepository implements webhook.Repository. Every query but claiming
+// and pruning deliveries is scoped to the tenant in ctx and fails with
+// tenant.ErrNoTenant when there is none; attempts are reached through their
+// scoped delivery.
+type WebhookRepository struct {
+	db database.Querier
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db database.Querier) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const subscriptionColumns = `id, tenant_id, partner_id, url, event_types, description, secret, status, created_at, updated_at`
+
+// CreateSubscription creates a webhook subscription
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, s *webhook.Subscription) error {
+	ctx, span := startSpan(ctx, "WebhookRepository.CreateSubscription")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO webhook_subscriptions (
+			id, partner_id, url, event_types, description, secret, status, created_at, updated_at, tenant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9)
+	`
+
+	now := time.Now().UTC()
+	_, err = r.db.Exec(ctx, query,
+		s.ID, s.PartnerID, s.URL, s.EventTypes, s.Description, s.Secret, string(s.Status), now, tenantID,
+	)
+	if err != nil {
+		return err
+	}
+	s.TenantID = tenantID
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	return nil
+}
+
+// GetSubscription retrieves a webhook subscription by ID
+func (r *WebhookRepository) GetSubscription(ctx context.Context, id uuid.UUID) (*webhook.Subscription, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.GetSubscription")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1 AND tenant_id = $2`
+
+	s, err := scanSubscription(r.db.QueryRow(ctx, query, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, webhook.ErrSubscriptionNotFound
+	}
+	return s, err
+}
+
+// ListSubscriptions retrieves a partner's webhook subscriptions, or every
+// partner's when partnerID is empty
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context, partnerID string, limit, offset int) ([]*webhook.Subscription, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.ListSubscriptions")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM webhook_subscriptions
+		WHERE tenant_id = $4 AND ($1 = '' OR partner_id = $1)
+		ORDER BY created_at, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, partnerID, limit, offset, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []*webhook.Subscription{}
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, rows.Err()
+}
+
+// UpdateSubscription updates a subscription's endpoint, event types,
+// description and status
+func (r *WebhookRepository) UpdateSubscription(ctx context.Context, s *webhook.Subscription) error {
+	ctx, span := startSpan(ctx, "WebhookRepository.UpdateSubscription")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE webhook_subscriptions
+		SET url = $2, event_types = $3, description = $4, status = $5, updated_at = $6
+		WHERE id = $1 AND tenant_id = $7
+	`
+
+	now := time.Now().UTC()
+	tag, err := r.db.Exec(ctx, query, s.ID, s.URL, s.EventTypes, s.Description, string(s.Status), now, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return webhook.ErrSubscriptionNotFound
+	}
+	s.UpdatedAt = now
+	return nil
+}
+
+// DeleteSubscription deletes a subscription with its deliveries
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startSpan(ctx, "WebhookRepository.DeleteSubscription")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return webhook.ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// Enqueue queues a delivery of event to every subscription of the tenant
+// listing its type, a wildcard prefix of it or *. Redelivered events hit the
+// unique index on the event and are skipped.
+func (r *WebhookRepository) Enqueue(ctx context.Context, event *webhook.Event, now time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.Enqueue")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (
+			id, subscription_id, event_id, event_type, payload, status, next_attempt_at, event_at, created_at, tenant_id
+		)
+		SELECT gen_random_uuid(), s.id, $1, $2, $3, 'pending', $5, $4, $5, s.tenant_id
+		FROM webhook_subscriptions s
+		WHERE s.tenant_id = $6 AND EXISTS (
+			SELECT 1 FROM unnest(s.event_types) AS t(type)
+			WHERE t.type = $2 OR (t.type LIKE '%*' AND starts_with($2, rtrim(t.type, '*')))
+		)
+		ON CONFLICT (subscription_id, event_type, event_id) DO NOTHING
+	`
+
+	now = now.UTC()
+	tag, err := r.db.Exec(ctx, query, event.ID, event.Type, []byte(event.Data), event.CreatedAt.UTC(), now, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ClaimDue hands due deliveries of active subscriptions of every tenant to
+// the caller by moving their next attempt past the lease. Each subscription
+// carries its tenant to attempt the delivery in.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*webhook.DueDelivery, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.ClaimDue")
+	defer span.End()
+
+	query := `
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= $1 AND s.status = 'active'
+			ORDER BY d.next_attempt_at
+			LIMIT $3
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = $2
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING ` + deliveryReturning + `, d.payload,
+			s.id, s.tenant_id, s.partner_id, s.url, s.event_types, s.description, s.secret, s.status, s.created_at, s.updated_at
+	`
+
+	now = now.UTC()
+	rows, err := r.db.Query(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := []*webhook.DueDelivery{}
+	for rows.Next() {
+		var d webhook.Delivery
+		var s webhook.Subscription
+		var status, subscriptionStatus string
+		var payload []byte
+		if err := rows.Scan(
+			&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastAttemptAt, &d.ResponseCode, &d.LastError, &d.EventAt, &d.CreatedAt, &d.DeliveredAt, &payload,
+			&s.ID, &s.TenantID, &s.PartnerID, &s.URL, &s.EventTypes, &s.Description, &s.Secret, &subscriptionStatus, &s.CreatedAt, &s.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		d.Status = webhook.DeliveryStatus(status)
+		d.Payload = payload
+		s.Status = webhook.SubscriptionStatus(subscriptionStatus)
+		due = append(due, &webhook.DueDelivery{Delivery: &d, Subscription: &s})
+	}
+	return due, rows.Err()
+}
+
+// RecordAttempt records an attempt and the delivery's outcome in one
+// statement
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, a *webhook.Attempt, status webhook.DeliveryStatus, next *time.Time) error {
+	ctx, span := startSpan(ctx, "WebhookRepository.RecordAttempt")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH delivery AS (
+			UPDATE webhook_deliveries
+			SET attempts = $3, status = $9, next_attempt_at = $10, last_attempt_at = $8,
+				response_code = $4, last_error = $6,
+				delivered_at = CASE WHEN $9 = 'succeeded' THEN $8 ELSE delivered_at END
+			WHERE id = $2 AND tenant_id = $11
+			RETURNING id
+		)
+		INSERT INTO webhook_delivery_attempts (
+			id, delivery_id, number, response_code, response_body, error, duration_ms, attempted_at
+		)
+		SELECT $1, id, $3, $4, $5, $6, $7, $8 FROM delivery
+	`
+
+	_, err = r.db.Exec(ctx, query,
+		a.ID, a.DeliveryID, a.Number, a.ResponseCode, a.ResponseBody, a.Error, a.DurationMS, a.AttemptedAt.UTC(),
+		string(status), next, tenantID,
+	)
+	return err
+}
+
+// deliveryReturning lists a delivery's columns, bar its payload, as
+// qualified in queries joining subscriptions
+const deliveryReturning = `d.id, d.subscription_id, d.event_id, d.event_type, d.status, d.attempts, d.next_attempt_at,
+	d.last_attempt_at, d.response_code, d.last_error, d.event_at, d.created_at, d.delivered_at`
+
+// GetDelivery retrieves a delivery with its payload
+func (r *WebhookRepository) GetDelivery(ctx context.Context, id uuid.UUID) (*webhook.Delivery, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.GetDelivery")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + deliveryReturning + `, d.payload FROM webhook_deliveries d WHERE d.id = $1 AND d.tenant_id = $2`
+
+	d, err := scanDelivery(r.db.QueryRow(ctx, query, id, tenantID), true)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, webhook.ErrDeliveryNotFound
+	}
+	return d, err
+}
+
+// ListDeliveries retrieves deliveries, newest first, without payloads
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, filter webhook.DeliveryFilter, limit, offset int) ([]*webhook.Delivery, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.ListDeliveries")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + deliveryReturning + `
+		FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		WHERE d.tenant_id = $7
+			AND ($1 = '' OR s.partner_id = $1)
+			AND ($2::uuid IS NULL OR d.subscription_id = $2)
+			AND ($3 = '' OR d.status = $3)
+			AND ($4 = '' OR d.event_type = $4)
+		ORDER BY d.created_at DESC, d.id
+		LIMIT $5 OFFSET $6
+	`
+
+	rows, err := r.db.Query(ctx, query,
+		filter.PartnerID, filter.SubscriptionID, string(filter.Status), filter.EventType, limit, offset, tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*webhook.Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows, false)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// ListAttempts retrieves a delivery's attempts, oldest first
+func (r *WebhookRepository) ListAttempts(ctx context.Context, deliveryID uuid.UUID) ([]*webhook.Attempt, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.ListAttempts")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT a.id, a.delivery_id, a.number, a.response_code, a.response_body, a.error, a.duration_ms, a.attempted_at
+		FROM webhook_delivery_attempts a
+		JOIN webhook_deliveries d ON d.id = a.delivery_id
+		WHERE a.delivery_id = $1 AND d.tenant_id = $2
+		ORDER BY a.attempted_at, a.id
+	`
+
+	rows, err := r.db.Query(ctx, query, deliveryID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []*webhook.Attempt{}
+	for rows.Next() {
+		var a webhook.Attempt
+		if err := rows.Scan(
+			&a.ID, &a.DeliveryID, &a.Number, &a.ResponseCode, &a.ResponseBody, &a.Error, &a.DurationMS, &a.AttemptedAt,
+		); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, &a)
+	}
+	return attempts, rows.Err()
+}
+
+// Replay queues a succeeded or failed delivery again
+func (r *WebhookRepository) Replay(ctx context.Context, id uuid.UUID, now time.Time) (*webhook.Delivery, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.Replay")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE webhook_deliveries d
+		SET status = 'pending', attempts = 0, next_attempt_at = $2, delivered_at = NULL
+		WHERE d.id = $1 AND d.tenant_id = $3 AND d.status <> 'pending'
+		RETURNING ` + deliveryReturning + `, d.payload
+	`
+
+	d, err := scanDelivery(r.db.QueryRow(ctx, query, id, now.UTC(), tenantID), true)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM webhook_deliveries WHERE id = $1 AND tenant_id = $2)`, id, tenantID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, webhook.ErrDeliveryNotFound
+		}
+		return nil, webhook.ErrDeliveryPending
+	}
+	return d, err
+}
+
+// ReplayFailed queues a subscription's failed deliveries of events since
+// since again
+func (r *WebhookRepository) ReplayFailed(ctx context.Context, subscriptionID uuid.UUID, since, now time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.ReplayFailed")
+	defer span.End()
+
+	tenantID, err := tenant.Scope(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = $3
+		WHERE subscription_id = $1 AND tenant_id = $4 AND status = 'failed' AND event_at >= $2
+	`
+
+	tag, err := r.db.Exec(ctx, query, subscriptionID, since.UTC(), now.UTC(), tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// PruneDeliveries deletes finished deliveries of every tenant created before
+// before; their attempts go with them
+func (r *WebhookRepository) PruneDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "WebhookRepository.PruneDeliveries")
+	defer span.End()
+
+	tag, err := r.db.Exec(ctx,
+		`DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < $1`,
+		before.UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// scanSubscription scans a row into a Subscription
+func scanSubscription(row interface{ Scan(dest ...interface{}) error }) (*webhook.Subscription, error) {
+	var s webhook.Subscription
+	var status string
+	err := row.Scan(
+		&s.ID, &s.TenantID, &s.PartnerID, &s.URL, &s.EventTypes, &s.Description, &s.Secret, &status, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.Status = webhook.SubscriptionStatus(status)
+	return &s, nil
+}
+
+// scanDelivery scans a row of deliveryReturning into a Delivery, followed by
+// its payload when withPayload is set
+func scanDelivery(row interface{ Scan(dest ...interface{}) error }, withPayload bool) (*webhook.Delivery, error) {
+	var d webhook.Delivery
+	var status string
+	var payload []byte
+	dest := []interface{}{
+		&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastAttemptAt, &d.ResponseCode, &d.LastError, &d.EventAt, &d.CreatedAt, &d.DeliveredAt,
+	}
+	if withPayload {
+		dest = append(dest, &payload)
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	d.Status = webhook.DeliveryStatus(status)
+	d.Payload = payload
+	return &d, nil
+}
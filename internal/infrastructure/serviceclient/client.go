@@ -0,0 +1,173 @@
+package serviceclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/proxy"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// requestTimeout bounds each call to a service
+const requestTimeout = 5 * time.Second
+
+// StatusError is a response with a status other than 2xx
+type StatusError struct {
+	Service string
+	Path    string
+	Code    int
+	Message string // The response's "error" field, if any
+	Body    []byte // The response, up to 64 KiB, for callers decoding more of it
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s %s returned %d: %s", e.Service, e.Path, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s %s returned %d", e.Service, e.Path, e.Code)
+}
+
+// Client calls the internal API of one service
+type Client struct {
+	service  string
+	client   *http.Client
+	streams  *http.Client // Without a timeout, for responses read as they arrive
+	balancer *proxy.Balancer
+	tokens   *auth.JWTManager // Nil sends no service token
+}
+
+// New creates a client of the service instances listed in baseURLs,
+// separated by commas, balanced and ejected as by the gateway's proxy
+// settings
+func New(service, baseURLs string, cfg config.ProxyConfig) *Client {
+	return &Client{
+		service:  service,
+		client:   &http.Client{Timeout: requestTimeout},
+		streams:  &http.Client{},
+		balancer: proxy.NewBalancer(service, config.SplitURLs(baseURLs), cfg),
+	}
+}
+
+// WithServiceTokens authenticates every call with a service token issued by
+// jwtManager for the tenant of its context, for services whose internal API
+// sits behind middleware.ServiceAuth
+func (c *Client) WithServiceTokens(jwtManager *auth.JWTManager) *Client {
+	c.tokens = jwtManager
+	return c
+}
+
+// Do sends in as JSON to one instance, with the trace and tenant of ctx, and
+// decodes a 2xx response into out. A nil in sends no body and a nil out
+// discards the response.
+func (c *Client) Do(ctx context.Context, method, path string, in, out any) error {
+	resp, release, err := c.send(ctx, c.client, method, path, in)
+	if err != nil {
+		return err
+	}
+	defer release()
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Open sends a request as Do does and returns the body of a 2xx response,
+// for the caller to read as it arrives and close. Unlike Do, reading the
+// body is not limited by the request timeout, only by ctx.
+func (c *Client) Open(ctx context.Context, method, path string, in any) (io.ReadCloser, error) {
+	resp, release, err := c.send(ctx, c.streams, method, path, in)
+	if err != nil {
+		return nil, err
+	}
+	return &stream{ReadCloser: resp.Body, release: release}, nil
+}
+
+// send sends a request to one instance, returning a 2xx response and a
+// function releasing the instance once the response has been read
+func (c *Client) send(ctx context.Context, client *http.Client, method, path string, in any) (*http.Response, func(), error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	target := c.balancer.Pick()
+	if target == nil {
+		return nil, nil, fmt.Errorf("no %s instances configured", c.service)
+	}
+	release := c.balancer.Acquire(target)
+
+	req, err := http.NewRequestWithContext(ctx, method, target.URL+path, body)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	tenantID := tenant.IDFromContext(ctx)
+	if tenantID != "" {
+		req.Header.Set(tenant.Header, tenantID)
+	}
+	if c.tokens != nil {
+		token, err := c.tokens.GenerateServiceToken(tenantID)
+		if err != nil {
+			release()
+			return nil, nil, fmt.Errorf("failed to issue %s service token: %w", c.service, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		c.balancer.Report(target, err, 0, time.Since(start))
+		release()
+		return nil, nil, fmt.Errorf("%s %s failed: %w", c.service, path, err)
+	}
+	c.balancer.Report(target, nil, resp.StatusCode, time.Since(start))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer release()
+		defer resp.Body.Close()
+		statusErr := &StatusError{Service: c.service, Path: path, Code: resp.StatusCode}
+		statusErr.Body, _ = io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		var payload struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(statusErr.Body, &payload) == nil {
+			statusErr.Message = payload.Error
+		}
+		return nil, nil, statusErr
+	}
+	return resp, release, nil
+}
+
+// stream is a response body that releases its instance when closed
+type stream struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (s *stream) Close() error {
+	defer s.once.Do(s.release)
+	return s.ReadCloser.Close()
+}
@@ -0,0 +1,39 @@
+package shiftclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/shift"
+	"github.com/onichange/pos-system/internal/infrastructure/serviceclient"
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// Client calls the shift service's internal API
+type Client struct {
+	client *serviceclient.Client
+}
+
+// NewClient creates a client of the shift service instances listed in
+// baseURLs, separated by commas, balanced and ejected as by the gateway's
+// proxy settings
+func NewClient(baseURLs string, cfg config.ProxyConfig) *Client {
+	return &Client{client: serviceclient.New("shift-service", baseURLs, cfg)}
+}
+
+// GetShift returns a register shift, or shift.ErrShiftNotFound
+func (c *Client) GetShift(ctx context.Context, id uuid.UUID) (*shift.Shift, error) {
+	var s shift.Shift
+	err := c.client.Do(ctx, http.MethodGet, "/internal/v1/shifts/"+id.String(), nil, &s)
+	var statusErr *serviceclient.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound {
+		return nil, shift.ErrShiftNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,54 @@
+package storeclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/store"
+	"github.com/onichange/pos-system/internal/infrastructure/serviceclient"
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// Client calls the store service's internal API
+type Client struct {
+	client *serviceclient.Client
+}
+
+// NewClient creates a client of the store service instances listed in
+// baseURLs, separated by commas, balanced and ejected as by the gateway's
+// proxy settings
+func NewClient(baseURLs string, cfg config.ProxyConfig) *Client {
+	return &Client{client: serviceclient.New("store-service", baseURLs, cfg)}
+}
+
+// GetStore returns a store, or store.ErrNotFound
+func (c *Client) GetStore(ctx context.Context, id uuid.UUID) (*store.Store, error) {
+	var s store.Store
+	err := c.client.Do(ctx, http.MethodGet, "/internal/v1/stores/"+id.String(), nil, &s)
+	var statusErr *serviceclient.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// VerifyDevice returns the device holding an API key, active or
+// deactivated, or store.ErrDeviceNotFound
+func (c *Client) VerifyDevice(ctx context.Context, key string) (*store.Device, error) {
+	var d store.Device
+	err := c.client.Do(ctx, http.MethodPost, "/internal/v1/devices/verify", map[string]string{"key": key}, &d)
+	var statusErr *serviceclient.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound {
+		return nil, store.ErrDeviceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
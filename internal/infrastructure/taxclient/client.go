@@ -0,0 +1,37 @@
+package taxclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/onichange/pos-system/internal/domain/tax"
+	"github.com/onichange/pos-system/internal/infrastructure/serviceclient"
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// Client calls the tax service's internal API
+type Client struct {
+	client *serviceclient.Client
+}
+
+// NewClient creates a client of the tax service instances listed in
+// baseURLs, separated by commas, balanced and ejected as by the gateway's
+// proxy settings
+func NewClient(baseURLs string, cfg config.ProxyConfig) *Client {
+	return &Client{client: serviceclient.New("tax-service", baseURLs, cfg)}
+}
+
+// Quote returns the tax due on a sale, or tax.ErrStoreNotFound
+func (c *Client) Quote(ctx context.Context, req *tax.QuoteRequest) (*tax.Quote, error) {
+	var quote tax.Quote
+	err := c.client.Do(ctx, http.MethodPost, "/internal/v1/tax/quote", req, &quote)
+	var statusErr *serviceclient.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound {
+		return nil, tax.ErrStoreNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
@@ -0,0 +1,121 @@
+// Package unitofwork groups the writes of several repositories in one
+// database transaction, so that they are saved together or not at all:
+//
+//	err := uow.Do(ctx, func(tx *unitofwork.Tx) error {
+//		if err := tx.Orders().Create(ctx, o); err != nil {
+//			return err
+//		}
+//		return tx.Payments().Create(ctx, intent)
+//	})
+//
+// Repositories handed out by a Tx read and write in its transaction, so
+// they see its uncommitted writes, and so do events recorded through
+// Tx.Events, which are relayed only once the transaction commits.
+package unitofwork
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/internal/domain/procurement"
+	"github.com/onichange/pos-system/internal/infrastructure/outbox"
+	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/encryption"
+)
+
+// UnitOfWork runs functions in database transactions begun on a pool
+type UnitOfWork struct {
+	tx            repository.TxBeginner
+	envelope      *encryption.Envelope // Encrypts the PII of orders; nil stores it as is
+	snapshotEvery int                  // Event-sourced orders are snapshotted every snapshotEvery events; 0 stores orders as rows only
+}
+
+// New creates a unit of work beginning its transactions on tx, as
+// *pgxpool.Pool does, and encrypting order PII with envelope
+func New(tx repository.TxBeginner, envelope *encryption.Envelope) *UnitOfWork {
+	return &UnitOfWork{tx: tx, envelope: envelope}
+}
+
+// WithEventSourcedOrders makes the orders of the unit of work event-sourced,
+// as they are with the events order store, snapshotted every snapshotEvery
+// events
+func (u *UnitOfWork) WithEventSourcedOrders(snapshotEvery int) *UnitOfWork {
+	u.snapshotEvery = snapshotEvery
+	return u
+}
+
+// Do begins a transaction and calls fn with it. The transaction commits
+// when fn returns nil, and rolls back when fn fails or panics, whose error
+// Do returns or whose panic it lets through. Failed transactions, such as
+// on a serialization failure, are not retried.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(tx *Tx) error) error {
+	tx, err := u.tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // After Commit, a no-op
+
+	if err := fn(&Tx{tx: tx, uow: u}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Tx hands out repositories reading and writing in a transaction. It is
+// only valid within the function given to Do.
+type Tx struct {
+	tx  pgx.Tx
+	uow *UnitOfWork
+}
+
+// Orders returns the order repository of the transaction
+func (t *Tx) Orders() order.Repository {
+	if t.uow.snapshotEvery > 0 {
+		// Its own transactions are savepoints in this one
+		return repository.NewEventSourcedOrderRepository(t.tx, t.tx, t.uow.envelope, t.uow.snapshotEvery)
+	}
+	return repository.NewOrderRepository(t.tx, t.uow.envelope)
+}
+
+// Payments returns the payment repository of the transaction
+func (t *Tx) Payments() payment.Repository {
+	return repository.NewPaymentRepository(t.tx)
+}
+
+// Inventory returns the inventory repository of the transaction
+func (t *Tx) Inventory() inventory.Repository {
+	return repository.NewInventoryRepository(t.tx)
+}
+
+// Reservations returns the stock reservation repository of the transaction
+func (t *Tx) Reservations() inventory.ReservationRepository {
+	// Its own transactions are savepoints in this one
+	return repository.NewStockReservationRepository(t.tx, t.tx)
+}
+
+// Counts returns the stock count repository of the transaction
+func (t *Tx) Counts() inventory.CountRepository {
+	return repository.NewStockCountRepository(t.tx, t.tx)
+}
+
+// Procurement returns the procurement repository of the transaction
+func (t *Tx) Procurement() procurement.Repository {
+	return repository.NewProcurementRepository(t.tx)
+}
+
+// Events returns a publisher recording events in the outbox in the
+// transaction
+func (t *Tx) Events() *outbox.Publisher {
+	return outbox.NewPublisher(t.tx)
+}
+
+// Querier returns the transaction, for repositories the Tx does not hand
+// out, e.g. repository.NewShiftRepository(tx.Querier())
+func (t *Tx) Querier() database.Querier {
+	return t.tx
+}
@@ -0,0 +1,58 @@
+package userclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/onichange/pos-system/internal/domain/user"
+	userpb "github.com/onichange/pos-system/proto/user"
+)
+
+// Client calls the user service's internal gRPC API
+type Client struct {
+	users userpb.UserServiceClient
+}
+
+// NewClient creates a client of the user service reached over conn, as
+// dialed by pkg/grpc.Dial
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{users: userpb.NewUserServiceClient(conn)}
+}
+
+// GetUser returns a user's profile, or user.ErrNotFound. Credentials and
+// roles are not part of the API and are left empty.
+func (c *Client) GetUser(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	resp, err := c.users.GetUser(ctx, &userpb.GetUserRequest{UserId: id.String()})
+	if status.Code(err) == codes.NotFound {
+		return nil, user.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("user-service: %w", err)
+	}
+
+	u := resp.GetUser()
+	userID, err := uuid.Parse(u.GetId())
+	if err != nil {
+		return nil, fmt.Errorf("user-service: invalid user ID %q", u.GetId())
+	}
+	profile := &user.User{
+		ID:         userID,
+		Email:      u.GetEmail(),
+		FirstName:  u.GetFirstName(),
+		LastName:   u.GetLastName(),
+		Phone:      u.GetPhone(),
+		MFAEnabled: u.GetMfaEnabled(),
+		CreatedAt:  u.GetCreatedAt().AsTime(),
+		UpdatedAt:  u.GetUpdatedAt().AsTime(),
+	}
+	if u.GetLastLoginAt() != nil {
+		lastLoginAt := u.GetLastLoginAt().AsTime()
+		profile.LastLoginAt = &lastLoginAt
+	}
+	return profile, nil
+}
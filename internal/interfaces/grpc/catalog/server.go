@@ -0,0 +1,75 @@
+// Package catalog serves the catalog service's internal gRPC API
+package catalog
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/onichange/pos-system/internal/domain/catalog"
+	"github.com/onichange/pos-system/pkg/logger"
+	catalogpb "github.com/onichange/pos-system/proto/catalog"
+)
+
+// maxVariants bounds the variants priced per call, as over HTTP
+const maxVariants = 500
+
+// Prices resolves the prices stores charge, as catalog.Repository does
+type Prices interface {
+	ResolvePrices(ctx context.Context, storeID uuid.UUID, variantIDs []uuid.UUID) ([]*catalog.PricedVariant, error)
+}
+
+// Server serves PricingService
+type Server struct {
+	catalogpb.UnimplementedPricingServiceServer
+	prices Prices
+}
+
+// NewServer creates a new pricing gRPC server
+func NewServer(prices Prices) *Server {
+	return &Server{prices: prices}
+}
+
+// ResolvePrices returns the prices a store charges for variants, called by
+// the order service to price order lines
+func (s *Server) ResolvePrices(ctx context.Context, req *catalogpb.ResolvePricesRequest) (*catalogpb.ResolvePricesResponse, error) {
+	storeID, err := uuid.Parse(req.GetStoreId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid store ID")
+	}
+	if len(req.GetVariantIds()) == 0 || len(req.GetVariantIds()) > maxVariants {
+		return nil, status.Errorf(codes.InvalidArgument, "between 1 and %d variant IDs are required", maxVariants)
+	}
+	variantIDs := make([]uuid.UUID, len(req.GetVariantIds()))
+	for i, id := range req.GetVariantIds() {
+		if variantIDs[i], err = uuid.Parse(id); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid variant ID")
+		}
+	}
+
+	priced, err := s.prices.ResolvePrices(ctx, storeID, variantIDs)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to resolve prices: %v", err)
+		return nil, status.Error(codes.Internal, "failed to resolve prices")
+	}
+
+	resp := &catalogpb.ResolvePricesResponse{Variants: make([]*catalogpb.PricedVariant, len(priced))}
+	for i, pv := range priced {
+		resp.Variants[i] = &catalogpb.PricedVariant{
+			VariantId:  pv.VariantID.String(),
+			ProductId:  pv.ProductID.String(),
+			Sku:        pv.SKU,
+			Name:       pv.Name,
+			Status:     string(pv.Status),
+			Price:      pv.Price,
+			Currency:   pv.Currency,
+			StorePrice: pv.StorePrice,
+		}
+		if pv.CategoryID != nil {
+			resp.Variants[i].CategoryId = pv.CategoryID.String()
+		}
+	}
+	return resp, nil
+}
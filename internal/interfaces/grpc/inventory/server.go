@@ -0,0 +1,196 @@
+// Package inventory serves the inventory service's internal gRPC API
+package inventory
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/pkg/logger"
+	inventorypb "github.com/onichange/pos-system/proto/inventory"
+)
+
+// Stock moves stock, recording each change and publishing its events as the
+// HTTP API does
+type Stock interface {
+	Reserve(ctx context.Context, productID uuid.UUID, storeID *uuid.UUID, quantity int) (*inventory.Inventory, error)
+	Release(ctx context.Context, productID uuid.UUID, storeID *uuid.UUID, quantity int) error
+	Receive(ctx context.Context, receipt *inventory.StockReceipt) (*inventory.Inventory, error)
+}
+
+// Records looks inventory records up, as inventory.Repository does
+type Records interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*inventory.Inventory, error)
+}
+
+// Server serves InventoryService
+type Server struct {
+	inventorypb.UnimplementedInventoryServiceServer
+	stock   Stock
+	records Records
+}
+
+// NewServer creates a new inventory gRPC server
+func NewServer(stock Stock, records Records) *Server {
+	return &Server{stock: stock, records: records}
+}
+
+// GetInventory returns an inventory record by ID
+func (s *Server) GetInventory(ctx context.Context, req *inventorypb.GetInventoryRequest) (*inventorypb.GetInventoryResponse, error) {
+	inventoryID, err := uuid.Parse(req.GetInventoryId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid inventory ID")
+	}
+
+	inv, err := s.records.GetByID(ctx, inventoryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "inventory not found")
+	}
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to get inventory: %v", err)
+		return nil, status.Error(codes.Internal, "failed to get inventory")
+	}
+
+	msg := &inventorypb.Inventory{
+		Id:                inv.ID.String(),
+		ProductId:         inv.ProductID.String(),
+		Quantity:          int32(inv.Quantity),
+		ReservedQuantity:  int32(inv.ReservedQuantity),
+		AvailableQuantity: int32(inv.AvailableQuantity),
+		ReorderPoint:      int32(inv.ReorderPoint),
+		ReorderQuantity:   int32(inv.ReorderQuantity),
+		Version:           int32(inv.Version),
+		CreatedAt:         timestamppb.New(inv.CreatedAt),
+		UpdatedAt:         timestamppb.New(inv.UpdatedAt),
+	}
+	if inv.StoreID != nil {
+		msg.StoreId = inv.StoreID.String()
+	}
+	if inv.CostPrice != nil {
+		msg.CostPrice = wrapperspb.Double(*inv.CostPrice)
+	}
+	if inv.SellingPrice != nil {
+		msg.SellingPrice = wrapperspb.Double(*inv.SellingPrice)
+	}
+	return &inventorypb.GetInventoryResponse{Inventory: msg}, nil
+}
+
+// ReserveStock reserves stock for an order
+func (s *Server) ReserveStock(ctx context.Context, req *inventorypb.ReserveStockRequest) (*inventorypb.ReserveStockResponse, error) {
+	productID, storeID, err := parseStock(req.GetProductId(), req.GetStoreId(), req.GetQuantity())
+	if err != nil {
+		return nil, err
+	}
+
+	inv, err := s.stock.Reserve(ctx, productID, storeID, int(req.GetQuantity()))
+	if err != nil {
+		return nil, statusOf(ctx, err, "Failed to reserve stock")
+	}
+	return &inventorypb.ReserveStockResponse{AvailableQuantity: int32(inv.AvailableQuantity)}, nil
+}
+
+// ReleaseStock makes reserved stock available again
+func (s *Server) ReleaseStock(ctx context.Context, req *inventorypb.ReleaseStockRequest) (*inventorypb.ReleaseStockResponse, error) {
+	productID, storeID, err := parseStock(req.GetProductId(), req.GetStoreId(), req.GetQuantity())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.stock.Release(ctx, productID, storeID, int(req.GetQuantity())); err != nil {
+		return nil, statusOf(ctx, err, "Failed to release stock")
+	}
+	return &inventorypb.ReleaseStockResponse{}, nil
+}
+
+// ReceiveStock adds stock received from a supplier
+func (s *Server) ReceiveStock(ctx context.Context, req *inventorypb.ReceiveStockRequest) (*inventorypb.ReceiveStockResponse, error) {
+	productID, err := uuid.Parse(req.GetProductId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid product ID")
+	}
+	storeID, err := uuid.Parse(req.GetStoreId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid store ID")
+	}
+	sourceID, err := uuid.Parse(req.GetSourceId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid source ID")
+	}
+	if req.GetQuantity() < 1 {
+		return nil, status.Error(codes.InvalidArgument, "quantity must be at least 1")
+	}
+	if req.GetUnitCost() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "unit cost must not be negative")
+	}
+	if req.GetSourceType() == "" || len(req.GetSourceType()) > 50 {
+		return nil, status.Error(codes.InvalidArgument, "source type must have 1 to 50 characters")
+	}
+
+	receipt := &inventory.StockReceipt{
+		ProductID:  productID,
+		StoreID:    storeID,
+		Quantity:   int(req.GetQuantity()),
+		UnitCost:   req.GetUnitCost(),
+		SourceType: req.GetSourceType(),
+		SourceID:   sourceID,
+		Reason:     req.GetReason(),
+	}
+	if req.GetUserId() != "" {
+		userID, err := uuid.Parse(req.GetUserId())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+		}
+		receipt.UserID = &userID
+	}
+	if req.GetReceivedAt() != nil {
+		receipt.ReceivedAt = req.GetReceivedAt().AsTime()
+	}
+
+	inv, err := s.stock.Receive(ctx, receipt)
+	if err != nil {
+		return nil, statusOf(ctx, err, "Failed to receive stock")
+	}
+	return &inventorypb.ReceiveStockResponse{AvailableQuantity: int32(inv.AvailableQuantity)}, nil
+}
+
+// parseStock validates the product, optional store and quantity of a
+// reservation or release
+func parseStock(productID, storeID string, quantity int32) (uuid.UUID, *uuid.UUID, error) {
+	product, err := uuid.Parse(productID)
+	if err != nil {
+		return uuid.Nil, nil, status.Error(codes.InvalidArgument, "invalid product ID")
+	}
+	if quantity < 1 {
+		return uuid.Nil, nil, status.Error(codes.InvalidArgument, "quantity must be at least 1")
+	}
+	if storeID == "" {
+		return product, nil, nil
+	}
+	store, err := uuid.Parse(storeID)
+	if err != nil {
+		return uuid.Nil, nil, status.Error(codes.InvalidArgument, "invalid store ID")
+	}
+	return product, &store, nil
+}
+
+// statusOf returns the status answering a failed stock change, logging
+// unexpected failures
+func statusOf(ctx context.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, inventory.ErrInsufficientStock):
+		return status.Error(codes.FailedPrecondition, "insufficient stock")
+	case errors.Is(err, inventory.ErrVersionConflict):
+		return status.Error(codes.Aborted, "inventory was modified by another request")
+	case errors.Is(err, inventory.ErrAlreadyReceived):
+		return status.Error(codes.AlreadyExists, "stock already received")
+	}
+	logger.FromContext(ctx).Errorf("%s: %v", message, err)
+	return status.Error(codes.Internal, message)
+}
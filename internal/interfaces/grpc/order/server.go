@@ -0,0 +1,164 @@
+// Package order serves the order service's internal gRPC API
+package order
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/pkg/logger"
+	orderpb "github.com/onichange/pos-system/proto/order"
+)
+
+// maxOrders bounds the orders listed per call, as over HTTP
+const maxOrders = 100
+
+// Orders looks orders up, as repository.OrderStore does
+type Orders interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*order.Order, error)
+	GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*order.Order, error)
+}
+
+// Server serves the lookups of OrderService. Orders are created and changed
+// through the HTTP API only.
+type Server struct {
+	orderpb.UnimplementedOrderServiceServer
+	orders Orders
+}
+
+// NewServer creates a new order gRPC server
+func NewServer(orders Orders) *Server {
+	return &Server{orders: orders}
+}
+
+// GetOrder returns an order by ID. Callers check ownership themselves.
+func (s *Server) GetOrder(ctx context.Context, req *orderpb.GetOrderRequest) (*orderpb.GetOrderResponse, error) {
+	orderID, err := uuid.Parse(req.GetOrderId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid order ID")
+	}
+
+	o, err := s.orders.GetByID(ctx, orderID)
+	if errors.Is(err, order.ErrOrderNotFound) {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to get order: %v", err)
+		return nil, status.Error(codes.Internal, "failed to get order")
+	}
+	return &orderpb.GetOrderResponse{Order: toProto(o)}, nil
+}
+
+// ListOrders returns a page of a user's orders
+func (s *Server) ListOrders(ctx context.Context, req *orderpb.ListOrdersRequest) (*orderpb.ListOrdersResponse, error) {
+	userID, err := uuid.Parse(req.GetUserId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+	if req.GetLimit() < 1 || req.GetLimit() > maxOrders {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be 1 to %d", maxOrders)
+	}
+	if req.GetOffset() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+
+	orders, err := s.orders.GetByUserID(ctx, userID, int(req.GetLimit()), int(req.GetOffset()))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch orders: %v", err)
+		return nil, status.Error(codes.Internal, "failed to fetch orders")
+	}
+
+	resp := &orderpb.ListOrdersResponse{Orders: make([]*orderpb.Order, len(orders))}
+	for i, o := range orders {
+		resp.Orders[i] = toProto(o)
+	}
+	return resp, nil
+}
+
+// toProto converts a domain order to its message
+func toProto(o *order.Order) *orderpb.Order {
+	msg := &orderpb.Order{
+		Id:              o.ID.String(),
+		UserId:          o.UserID.String(),
+		StoreId:         o.StoreID.String(),
+		Status:          orderpb.OrderStatus(orderpb.OrderStatus_value["ORDER_STATUS_"+strings.ToUpper(string(o.Status))]),
+		TotalAmount:     o.TotalAmount,
+		Currency:        o.Currency,
+		CreatedAt:       timestamppb.New(o.CreatedAt),
+		UpdatedAt:       timestamppb.New(o.UpdatedAt),
+		ShippingAddress: addressToProto(o.ShippingAddress),
+		BillingAddress:  addressToProto(o.BillingAddress),
+		Notes:           o.Notes,
+		LoyaltyPoints:   o.LoyaltyPoints,
+		LoyaltyDiscount: o.LoyaltyDiscount,
+		Tender:          o.Tender,
+		TaxAmount:       o.TaxAmount,
+	}
+	for _, item := range o.Items {
+		msg.Items = append(msg.Items, &orderpb.OrderItem{
+			ProductId:  item.ProductID,
+			Name:       item.Name,
+			Quantity:   int32(item.Quantity),
+			UnitPrice:  item.UnitPrice,
+			Subtotal:   item.Subtotal,
+			Discount:   item.Discount,
+			CategoryId: optionalID(item.CategoryID),
+			Tax:        item.Tax,
+		})
+	}
+	for _, p := range o.Promotions {
+		msg.Promotions = append(msg.Promotions, &orderpb.AppliedPromotion{
+			PromotionId: p.PromotionID.String(),
+			Name:        p.Name,
+			Amount:      p.Amount,
+			Coupon:      p.Coupon,
+		})
+	}
+	for _, t := range o.Taxes {
+		msg.Taxes = append(msg.Taxes, &orderpb.TaxLine{
+			JurisdictionId: t.JurisdictionID.String(),
+			Name:           t.Name,
+			Level:          t.Level,
+			Taxable:        t.Taxable,
+			Exempt:         t.Exempt,
+			Tax:            t.Tax,
+			Inclusive:      t.Inclusive,
+		})
+	}
+	msg.ShiftId = optionalID(o.ShiftID)
+	if o.CompletedAt != nil {
+		msg.CompletedAt = timestamppb.New(*o.CompletedAt)
+	}
+	if o.CancelledAt != nil {
+		msg.CancelledAt = timestamppb.New(*o.CancelledAt)
+	}
+	return msg
+}
+
+// addressToProto converts an optional address to its message
+func addressToProto(a *order.Address) *orderpb.Address {
+	if a == nil {
+		return nil
+	}
+	return &orderpb.Address{
+		Street:     a.Street,
+		City:       a.City,
+		State:      a.State,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+	}
+}
+
+// optionalID formats an optional ID, empty when absent
+func optionalID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
@@ -0,0 +1,73 @@
+// Package payment serves the payment service's internal gRPC API
+package payment
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/pkg/logger"
+	paymentpb "github.com/onichange/pos-system/proto/payment"
+)
+
+// Payments looks payments up, as payment.Repository does
+type Payments interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*payment.Payment, error)
+}
+
+// Server serves PaymentService
+type Server struct {
+	paymentpb.UnimplementedPaymentServiceServer
+	payments Payments
+}
+
+// NewServer creates a new payment gRPC server
+func NewServer(payments Payments) *Server {
+	return &Server{payments: payments}
+}
+
+// GetPayment returns a payment by ID. Callers check ownership themselves.
+func (s *Server) GetPayment(ctx context.Context, req *paymentpb.GetPaymentRequest) (*paymentpb.GetPaymentResponse, error) {
+	paymentID, err := uuid.Parse(req.GetPaymentId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid payment ID")
+	}
+
+	p, err := s.payments.GetByID(ctx, paymentID)
+	if errors.Is(err, payment.ErrPaymentNotFound) {
+		return nil, status.Error(codes.NotFound, "payment not found")
+	}
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to get payment: %v", err)
+		return nil, status.Error(codes.Internal, "failed to get payment")
+	}
+
+	msg := &paymentpb.Payment{
+		Id:                    p.ID.String(),
+		OrderId:               p.OrderID.String(),
+		UserId:                p.UserID.String(),
+		PaymentMethodType:     string(p.PaymentMethodType),
+		Amount:                p.Amount,
+		Currency:              p.Currency,
+		Status:                paymentpb.PaymentStatus(paymentpb.PaymentStatus_value["PAYMENT_STATUS_"+strings.ToUpper(string(p.Status))]),
+		Provider:              p.Provider,
+		ProviderTransactionId: p.ProviderTransactionID,
+		ThreeDSecureEnabled:   p.ThreeDSecureEnabled,
+		ThreeDSecureStatus:    p.ThreeDSecureStatus,
+		CreatedAt:             timestamppb.New(p.CreatedAt),
+		UpdatedAt:             timestamppb.New(p.UpdatedAt),
+	}
+	if p.ProcessedAt != nil {
+		msg.ProcessedAt = timestamppb.New(*p.ProcessedAt)
+	}
+	if p.CompletedAt != nil {
+		msg.CompletedAt = timestamppb.New(*p.CompletedAt)
+	}
+	return &paymentpb.GetPaymentResponse{Payment: msg}, nil
+}
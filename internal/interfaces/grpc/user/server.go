@@ -0,0 +1,66 @@
+// Package user serves the user service's internal gRPC API
+package user
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/onichange/pos-system/internal/domain/user"
+	"github.com/onichange/pos-system/pkg/logger"
+	userpb "github.com/onichange/pos-system/proto/user"
+)
+
+// Users looks users up, as user.Repository does
+type Users interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*user.User, error)
+}
+
+// Server serves the lookups of UserService. Accounts are created, changed
+// and authenticated through the HTTP API only.
+type Server struct {
+	userpb.UnimplementedUserServiceServer
+	users Users
+}
+
+// NewServer creates a new user gRPC server
+func NewServer(users Users) *Server {
+	return &Server{users: users}
+}
+
+// GetUser returns a user by ID
+func (s *Server) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.GetUserResponse, error) {
+	userID, err := uuid.Parse(req.GetUserId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+
+	u, err := s.users.GetByID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to get user: %v", err)
+		return nil, status.Error(codes.Internal, "failed to get user")
+	}
+
+	msg := &userpb.User{
+		Id:         u.ID.String(),
+		Email:      u.Email,
+		FirstName:  u.FirstName,
+		LastName:   u.LastName,
+		Phone:      u.Phone,
+		MfaEnabled: u.MFAEnabled,
+		CreatedAt:  timestamppb.New(u.CreatedAt),
+		UpdatedAt:  timestamppb.New(u.UpdatedAt),
+	}
+	if u.LastLoginAt != nil {
+		msg.LastLoginAt = timestamppb.New(*u.LastLoginAt)
+	}
+	return &userpb.GetUserResponse{User: msg}, nil
+}
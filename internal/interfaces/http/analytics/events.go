@@ -0,0 +1,226 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+
+	"github.com/onichange/pos-system/internal/domain/analytics"
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/internal/domain/shift"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+	"github.com/onichange/pos-system/pkg/metrics"
+)
+
+// HandleEvent adds an order, payment or inventory event from the message
+// broker to the rollups, and keeps the time entries of time clock events.
+// Events are applied at most once per event ID, and time entries only when
+// newer than the one kept, so redeliveries are harmless. Malformed messages
+// and event types the rollups do not count are dropped.
+func (h *Handler) HandleEvent(ctx context.Context, msg amqp.Delivery) error {
+	log := logger.FromContext(ctx)
+
+	var event messagequeue.Event
+	if err := json.Unmarshal(msg.Body, &event); err != nil || event.ID == "" {
+		log.Errorf("Dropping malformed event: %v", err)
+		return nil
+	}
+	raw, _ := json.Marshal(event.Data)
+
+	var (
+		applied bool
+		err     error
+	)
+	switch event.Type {
+	case order.EventCreated, order.EventCompleted, order.EventCancelled, order.EventRefunded:
+		var data order.EventData
+		if err := json.Unmarshal(raw, &data); err != nil || data.OrderID == uuid.Nil {
+			log.Errorf("Dropping %s event %s without an order", event.Type, event.ID)
+			return nil
+		}
+		applied, err = h.analyticsRepo.ApplyOrder(ctx, orderFact(event, data))
+	case payment.EventCreated, payment.EventCompleted:
+		var data payment.EventData
+		if err := json.Unmarshal(raw, &data); err != nil || data.PaymentID == uuid.Nil {
+			log.Errorf("Dropping %s event %s without a payment", event.Type, event.ID)
+			return nil
+		}
+		applied, err = h.analyticsRepo.ApplyPayment(ctx, paymentFact(event, data))
+	case inventory.EventReserved, inventory.EventReleased, inventory.EventLowStock:
+		var data inventory.EventData
+		if err := json.Unmarshal(raw, &data); err != nil || data.ProductID == uuid.Nil {
+			log.Errorf("Dropping %s event %s without a product", event.Type, event.ID)
+			return nil
+		}
+		applied, err = h.analyticsRepo.ApplyStock(ctx, stockFact(event, data))
+	case shift.EventTimeEntryClosed, shift.EventTimeEntryAdjusted:
+		var data shift.TimeEntryEventData
+		if err := json.Unmarshal(raw, &data); err != nil || data.EntryID == uuid.Nil {
+			log.Errorf("Dropping %s event %s without a time entry", event.Type, event.ID)
+			return nil
+		}
+		applied, err = h.analyticsRepo.ApplyTimeEntry(ctx, timeEntryFact(data))
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply %s event %s: %w", event.Type, event.ID, err)
+	}
+
+	result := "applied"
+	if !applied {
+		result = "duplicate"
+	}
+	metrics.AnalyticsEvents.WithLabelValues(event.Type, result).Inc()
+	return nil
+}
+
+// factID scopes an event ID by its type, since each publisher numbers its
+// events independently
+func factID(event messagequeue.Event) string {
+	return event.Type + "/" + event.ID
+}
+
+// orderFact returns what an order event adds to the rollups: placed orders
+// count toward gross sales, completed ones toward revenue and product sales,
+// and refunded ones toward refunds
+func orderFact(event messagequeue.Event, data order.EventData) *analytics.OrderFact {
+	f := &analytics.OrderFact{
+		EventID:  factID(event),
+		At:       event.Timestamp.UTC(),
+		StoreID:  data.StoreID,
+		Currency: data.Currency,
+	}
+	switch event.Type {
+	case order.EventCreated:
+		f.Placed = 1
+		f.GrossSales = data.TotalAmount
+	case order.EventCompleted:
+		f.Completed = 1
+		f.Revenue = data.TotalAmount
+		f.Lines = productLines(data.Items)
+	case order.EventCancelled:
+		f.Cancelled = 1
+	case order.EventRefunded:
+		f.Refunded = 1
+		f.Refunds = data.TotalAmount
+		f.Lines = productLines(data.Items)
+	}
+	return f
+}
+
+// productLines returns the product rollup lines of an order's items
+func productLines(items []order.EventItem) []analytics.ProductLine {
+	lines := make([]analytics.ProductLine, 0, len(items))
+	for _, item := range items {
+		if item.ProductID == "" {
+			continue
+		}
+		lines = append(lines, analytics.ProductLine{
+			ProductID: item.ProductID,
+			Name:      item.Name,
+			Units:     item.Quantity,
+			Amount:    item.Subtotal,
+		})
+	}
+	return lines
+}
+
+// paymentFact returns what a payment event adds to the rollups
+func paymentFact(event messagequeue.Event, data payment.EventData) *analytics.PaymentFact {
+	f := &analytics.PaymentFact{
+		EventID:  factID(event),
+		At:       event.Timestamp.UTC(),
+		Currency: data.Currency,
+		Method:   string(data.Method),
+	}
+	switch event.Type {
+	case payment.EventCreated:
+		f.Started = 1
+	case payment.EventCompleted:
+		f.Completed = 1
+		f.Amount = data.Amount
+	}
+	return f
+}
+
+// stockFact returns what an inventory event adds to the rollups
+func stockFact(event messagequeue.Event, data inventory.EventData) *analytics.StockFact {
+	f := &analytics.StockFact{
+		EventID:   factID(event),
+		At:        event.Timestamp.UTC(),
+		ProductID: data.ProductID,
+	}
+	if data.StoreID != nil {
+		f.StoreID = *data.StoreID
+	}
+	switch event.Type {
+	case inventory.EventReserved:
+		f.Reserved = data.Quantity
+	case inventory.EventReleased:
+		f.Released = data.Quantity
+	case inventory.EventLowStock:
+		f.LowStock = 1
+	}
+	return f
+}
+
+// timeEntryFact returns the time entry a time clock event carries
+func timeEntryFact(data shift.TimeEntryEventData) *analytics.TimeEntryFact {
+	return &analytics.TimeEntryFact{
+		EntryID:      data.EntryID,
+		Version:      data.Version,
+		StoreID:      data.StoreID,
+		StaffID:      data.StaffID,
+		Closed:       data.Status == shift.EntryClosed,
+		ClockIn:      data.ClockIn.UTC(),
+		ClockOut:     data.ClockOut,
+		Worked:       time.Duration(data.WorkedSeconds) * time.Second,
+		UnpaidBreaks: time.Duration(data.UnpaidBreakSeconds) * time.Second,
+	}
+}
+
+// RunRetention deletes hourly rollups older than hourlyRetention and the
+// record of events applied longer ago than eventRetention, every interval
+// until ctx is cancelled. An interval of zero disables it.
+func (h *Handler) RunRetention(ctx context.Context, interval, hourlyRetention, eventRetention time.Duration, log *logger.Logger) {
+	defer apperrors.Recover(ctx, "analytics-retention")
+
+	if interval <= 0 {
+		return
+	}
+
+	pass := func() {
+		now := time.Now()
+		deleted, err := h.analyticsRepo.Prune(ctx, now.Add(-hourlyRetention), now.Add(-eventRetention))
+		if err != nil {
+			log.Errorf("Failed to prune analytics rollups: %v", err)
+			return
+		}
+		if deleted > 0 {
+			log.Infof("Pruned %d analytics rows", deleted)
+		}
+	}
+
+	pass()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pass()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/analytics"
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+)
+
+// memoryRepository keeps the facts applied to it, once per event ID, as the
+// database's record of applied events does
+type memoryRepository struct {
+	analytics.Repository // Queries are not used by HandleEvent
+	orders               map[string]*analytics.OrderFact
+	payments             map[string]*analytics.PaymentFact
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		orders:   make(map[string]*analytics.OrderFact),
+		payments: make(map[string]*analytics.PaymentFact),
+	}
+}
+
+func (r *memoryRepository) ApplyOrder(_ context.Context, f *analytics.OrderFact) (bool, error) {
+	if _, ok := r.orders[f.EventID]; ok {
+		return false, nil
+	}
+	r.orders[f.EventID] = f
+	return true, nil
+}
+
+func (r *memoryRepository) ApplyPayment(_ context.Context, f *analytics.PaymentFact) (bool, error) {
+	if _, ok := r.payments[f.EventID]; ok {
+		return false, nil
+	}
+	r.payments[f.EventID] = f
+	return true, nil
+}
+
+// delivery returns a broker delivery of an event
+func delivery(t *testing.T, id, eventType string, data any) amqp.Delivery {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	require.NoError(t, err)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &fields))
+
+	body, err := json.Marshal(messagequeue.Event{
+		ID:        id,
+		Type:      eventType,
+		Source:    "order-service",
+		Timestamp: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Data:      fields,
+	})
+	require.NoError(t, err)
+	return amqp.Delivery{Body: body}
+}
+
+func TestHandleEventRecordsEventOnce(t *testing.T) {
+	repo := newMemoryRepository()
+	h := NewHandler(repo)
+	ctx := context.Background()
+
+	data := order.EventData{OrderID: uuid.New(), StoreID: uuid.New(), TotalAmount: 42.5, Currency: "USD"}
+	msg := delivery(t, "evt-1", order.EventCreated, data)
+	require.NoError(t, h.HandleEvent(ctx, msg))
+
+	require.Len(t, repo.orders, 1)
+	fact := repo.orders[order.EventCreated+"/evt-1"]
+	require.NotNil(t, fact)
+	assert.Equal(t, 1, fact.Placed)
+	assert.Equal(t, 42.5, fact.GrossSales)
+	assert.Equal(t, data.StoreID, fact.StoreID)
+
+	// A redelivery, even with other data, changes nothing
+	data.TotalAmount = 100
+	require.NoError(t, h.HandleEvent(ctx, delivery(t, "evt-1", order.EventCreated, data)))
+	require.Len(t, repo.orders, 1)
+	assert.Equal(t, 42.5, repo.orders[order.EventCreated+"/evt-1"].GrossSales)
+
+	// Event IDs are numbered by each publisher, so another type's event of
+	// the same ID is not a duplicate
+	require.NoError(t, h.HandleEvent(ctx, delivery(t, "evt-1", order.EventCompleted, data)))
+	assert.Len(t, repo.orders, 2)
+}
+
+func TestHandleEventDropsUnusableMessages(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  func(t *testing.T) amqp.Delivery
+	}{
+		{
+			name: "malformed JSON",
+			msg:  func(*testing.T) amqp.Delivery { return amqp.Delivery{Body: []byte(`{"id": "evt-1", "type":`)} },
+		},
+		{
+			name: "without an event ID",
+			msg: func(t *testing.T) amqp.Delivery {
+				return delivery(t, "", order.EventCreated, order.EventData{OrderID: uuid.New()})
+			},
+		},
+		{
+			name: "unknown type",
+			msg: func(t *testing.T) amqp.Delivery {
+				return delivery(t, "evt-1", "order.shipped", order.EventData{OrderID: uuid.New()})
+			},
+		},
+		{
+			name: "order event without an order",
+			msg: func(t *testing.T) amqp.Delivery {
+				return delivery(t, "evt-1", order.EventCreated, map[string]any{"total_amount": 10})
+			},
+		},
+		{
+			name: "payment event of the wrong shape",
+			msg: func(t *testing.T) amqp.Delivery {
+				return delivery(t, "evt-1", payment.EventCompleted, map[string]any{"payment_id": 42})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemoryRepository()
+			h := NewHandler(repo)
+
+			// Acknowledged without applying anything, so the broker neither
+			// redelivers nor dead-letters it
+			assert.NoError(t, h.HandleEvent(context.Background(), tt.msg(t)))
+			assert.Empty(t, repo.orders)
+			assert.Empty(t, repo.payments)
+		})
+	}
+}
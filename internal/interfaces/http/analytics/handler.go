@@ -0,0 +1,212 @@
+package analytics
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/analytics"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// Default ranges of dashboard queries that give no start
+const (
+	defaultDailyRange  = 30 * 24 * time.Hour
+	defaultHourlyRange = 48 * time.Hour
+)
+
+// Handler handles analytics HTTP requests. Every query reads the rollup
+// tables, never the services' own databases.
+type Handler struct {
+	analyticsRepo analytics.Repository
+}
+
+// NewHandler creates a new analytics handler
+func NewHandler(analyticsRepo analytics.Repository) *Handler {
+	return &Handler{
+		analyticsRepo: analyticsRepo,
+	}
+}
+
+// GetRevenue handles GET /analytics/revenue?granularity=&from=&to=&store_id=&currency=
+func (h *Handler) GetRevenue(c *fiber.Ctx) error {
+	granularity := analytics.Granularity(c.Query("granularity", string(analytics.Daily)))
+	if granularity != analytics.Hourly && granularity != analytics.Daily {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "granularity must be hour or day",
+		})
+	}
+
+	q, err := parseQuery(c, granularity)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	points, err := h.analyticsRepo.GetSales(c.UserContext(), granularity, q)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch revenue: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch revenue",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":        points,
+		"granularity": granularity,
+		"from":        q.From,
+		"to":          q.To,
+	})
+}
+
+// GetTopProducts handles GET /analytics/top-products?by=&limit=&from=&to=&store_id=&currency=
+func (h *Handler) GetTopProducts(c *fiber.Ctx) error {
+	by := c.Query("by", "revenue")
+	if by != "revenue" && by != "units" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "by must be revenue or units",
+		})
+	}
+
+	q, err := parseQuery(c, analytics.Daily)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	limit := parseLimit(c)
+
+	products, err := h.analyticsRepo.GetTopProducts(c.UserContext(), q, by == "units", limit)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch top products: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch top products",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  products,
+		"by":    by,
+		"from":  q.From,
+		"to":    q.To,
+		"limit": limit,
+	})
+}
+
+// GetConversion handles GET /analytics/conversion?from=&to=&store_id=&currency=
+func (h *Handler) GetConversion(c *fiber.Ctx) error {
+	q, err := parseQuery(c, analytics.Daily)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	conversion, err := h.analyticsRepo.GetConversion(c.UserContext(), q)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch conversion: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch conversion",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": conversion,
+		"from": q.From,
+		"to":   q.To,
+	})
+}
+
+// GetStockActivity handles GET /analytics/stock?limit=&from=&to=&store_id=
+func (h *Handler) GetStockActivity(c *fiber.Ctx) error {
+	q, err := parseQuery(c, analytics.Daily)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	limit := parseLimit(c)
+
+	activity, err := h.analyticsRepo.GetStockActivity(c.UserContext(), q, limit)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch stock activity: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch stock activity",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  activity,
+		"from":  q.From,
+		"to":    q.To,
+		"limit": limit,
+	})
+}
+
+// parseQuery reads the range and filters of a dashboard query. from and to
+// are RFC 3339 times or YYYY-MM-DD dates in UTC; to defaults to the end of
+// the current bucket and from to a range suited to the granularity.
+func parseQuery(c *fiber.Ctx, granularity analytics.Granularity) (analytics.Query, error) {
+	var q analytics.Query
+
+	bucket := time.Hour
+	span := defaultHourlyRange
+	if granularity == analytics.Daily {
+		bucket = 24 * time.Hour
+		span = defaultDailyRange
+	}
+
+	q.To = time.Now().UTC().Truncate(bucket).Add(bucket)
+	if value := c.Query("to"); value != "" {
+		to, err := parseTime(value)
+		if err != nil {
+			return q, fiber.NewError(fiber.StatusBadRequest, "to must be an RFC 3339 time or a YYYY-MM-DD date")
+		}
+		q.To = to
+	}
+	q.From = q.To.Add(-span)
+	if value := c.Query("from"); value != "" {
+		from, err := parseTime(value)
+		if err != nil {
+			return q, fiber.NewError(fiber.StatusBadRequest, "from must be an RFC 3339 time or a YYYY-MM-DD date")
+		}
+		q.From = from
+	}
+	if !q.From.Before(q.To) {
+		return q, fiber.NewError(fiber.StatusBadRequest, "from must be before to")
+	}
+
+	if value := c.Query("store_id"); value != "" {
+		storeID, err := uuid.Parse(value)
+		if err != nil {
+			return q, fiber.NewError(fiber.StatusBadRequest, "Invalid store ID")
+		}
+		q.StoreID = &storeID
+	}
+	q.Currency = strings.ToUpper(c.Query("currency"))
+
+	return q, nil
+}
+
+// parseTime parses an RFC 3339 time or a YYYY-MM-DD date, in UTC
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// parseLimit reads the limit of a ranking, 20 by default and at most 100
+func parseLimit(c *fiber.Ctx) int {
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	return limit
+}
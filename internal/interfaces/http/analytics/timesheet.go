@@ -0,0 +1,114 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/analytics"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// timesheetColumns are the header of a CSV timesheet export, before one
+// column of hours worked per day of the week
+var timesheetColumns = []string{
+	"staff_id", "week_start", "entries", "worked_hours", "regular_hours", "overtime_hours", "unpaid_break_hours",
+}
+
+// GetTimesheets handles GET /analytics/timesheets?week=&store_id=&format=json|csv:
+// each staff member's hours worked in a week, for payroll. week is any
+// YYYY-MM-DD date in the week, the current week by default; weeks run
+// Monday to Sunday in UTC.
+func (h *Handler) GetTimesheets(c *fiber.Ctx) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "format must be json or csv",
+		})
+	}
+
+	day := time.Now().UTC()
+	if value := c.Query("week"); value != "" {
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "week must be a YYYY-MM-DD date",
+			})
+		}
+		day = t
+	}
+	weekStart := analytics.WeekStart(day)
+
+	var storeID *uuid.UUID
+	if value := c.Query("store_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid store ID",
+			})
+		}
+		storeID = &id
+	}
+
+	days, err := h.analyticsRepo.GetStaffDays(c.UserContext(), weekStart, storeID)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch timesheets: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch timesheets",
+		})
+	}
+	sheets := analytics.BuildTimesheets(weekStart, days)
+
+	if format == "csv" {
+		return writeTimesheetsCSV(c, weekStart, sheets)
+	}
+	return c.JSON(fiber.Map{
+		"data":       sheets,
+		"week_start": weekStart.Format("2006-01-02"),
+	})
+}
+
+// writeTimesheetsCSV answers with timesheets as a CSV attachment, one row
+// per staff member
+func writeTimesheetsCSV(c *fiber.Ctx, weekStart time.Time, sheets []*analytics.Timesheet) error {
+	header := append([]string{}, timesheetColumns...)
+	for i := 0; i < 7; i++ {
+		header = append(header, weekStart.AddDate(0, 0, i).Format("2006-01-02"))
+	}
+
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, sheet := range sheets {
+		record := []string{
+			sheet.StaffID.String(), sheet.WeekStart, strconv.Itoa(sheet.Entries), hours(sheet.WorkedHours),
+			hours(sheet.RegularHours), hours(sheet.OvertimeHours), hours(sheet.UnpaidBreakHours),
+		}
+		for _, day := range sheet.Days {
+			record = append(record, hours(day.WorkedHours))
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	filename := "timesheets-" + weekStart.Format("2006-01-02") + ".csv"
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	return c.SendString(b.String())
+}
+
+// hours formats hours with two decimals
+func hours(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
@@ -0,0 +1,86 @@
+package catalog
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/catalog"
+)
+
+// CreateCategoryRequest represents create category request
+type CreateCategoryRequest struct {
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+	Name     string     `json:"name" validate:"required"`
+	Slug     string     `json:"slug" validate:"required"`
+}
+
+// UpdateCategoryRequest represents update category request
+type UpdateCategoryRequest struct {
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+	Name     string     `json:"name,omitempty"`
+	Slug     string     `json:"slug,omitempty"`
+}
+
+// VariantRequest represents a variant to create
+type VariantRequest struct {
+	SKU        string            `json:"sku" validate:"required,max=64"`
+	Barcode    string            `json:"barcode,omitempty" validate:"omitempty,max=64"`
+	Name       string            `json:"name,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	BasePrice  float64           `json:"base_price" validate:"gte=0"`
+	Currency   string            `json:"currency,omitempty" validate:"omitempty,len=3"`
+}
+
+// UpdateVariantRequest represents update variant request
+type UpdateVariantRequest struct {
+	SKU        string            `json:"sku,omitempty" validate:"omitempty,max=64"`
+	Barcode    *string           `json:"barcode,omitempty" validate:"omitempty,max=64"`
+	Name       *string           `json:"name,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	BasePrice  *float64          `json:"base_price,omitempty" validate:"omitempty,gte=0"`
+	Currency   string            `json:"currency,omitempty" validate:"omitempty,len=3"`
+}
+
+// CreateProductRequest represents create product request
+type CreateProductRequest struct {
+	CategoryID  *uuid.UUID       `json:"category_id,omitempty"`
+	Name        string           `json:"name" validate:"required"`
+	Description string           `json:"description,omitempty"`
+	Brand       string           `json:"brand,omitempty"`
+	Status      string           `json:"status,omitempty" validate:"omitempty,oneof=draft active archived"`
+	Images      []catalog.Image  `json:"images,omitempty"`
+	Variants    []VariantRequest `json:"variants" validate:"required,min=1,dive"`
+}
+
+// UpdateProductRequest represents update product request
+type UpdateProductRequest struct {
+	CategoryID  *uuid.UUID      `json:"category_id,omitempty"`
+	Name        string          `json:"name,omitempty"`
+	Description *string         `json:"description,omitempty"`
+	Brand       *string         `json:"brand,omitempty"`
+	Status      string          `json:"status,omitempty" validate:"omitempty,oneof=draft active archived"`
+	Images      []catalog.Image `json:"images,omitempty"` // Replaces the product's images
+}
+
+// SetPriceRequest represents a store price
+type SetPriceRequest struct {
+	Price    float64 `json:"price" validate:"gte=0"`
+	Currency string  `json:"currency,omitempty" validate:"omitempty,len=3"`
+}
+
+// newVariant converts a variant request to a domain Variant
+func newVariant(productID uuid.UUID, req VariantRequest) *catalog.Variant {
+	currency := req.Currency
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	return &catalog.Variant{
+		ID:         uuid.New(),
+		ProductID:  productID,
+		SKU:        req.SKU,
+		Barcode:    req.Barcode,
+		Name:       req.Name,
+		Attributes: req.Attributes,
+		BasePrice:  req.BasePrice,
+		Currency:   currency,
+	}
+}
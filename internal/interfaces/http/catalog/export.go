@@ -0,0 +1,29 @@
+package catalog
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/catalog"
+	"github.com/onichange/pos-system/pkg/dataexport"
+)
+
+// ExportStore handles GET /internal/v1/stores/:storeId/export, streaming
+// every variant at a store's prices to the store service building an export
+// of the store
+func (h *Handler) ExportStore(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("storeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+
+	return dataexport.Stream(c, func(ctx context.Context, emit func(v any) error) error {
+		return h.catalogRepo.ExportStore(ctx, storeID, func(v *catalog.PricedVariant) error {
+			return emit(v)
+		})
+	})
+}
@@ -0,0 +1,636 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/catalog"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// defaultCurrency is used for prices given without a currency
+const defaultCurrency = "USD"
+
+// EventPublisher publishes product events to the message broker
+type EventPublisher interface {
+	PublishEventContext(ctx context.Context, eventType, routingKey string, data map[string]interface{}) error
+}
+
+// Handler handles catalog HTTP requests
+type Handler struct {
+	catalogRepo catalog.Repository
+	events      EventPublisher // Nil when no broker is reachable
+}
+
+// NewHandler creates a new catalog handler; events may be nil
+func NewHandler(catalogRepo catalog.Repository, events EventPublisher) *Handler {
+	return &Handler{
+		catalogRepo: catalogRepo,
+		events:      events,
+	}
+}
+
+// GetCategories handles GET /categories
+func (h *Handler) GetCategories(c *fiber.Ctx) error {
+	categories, err := h.catalogRepo.GetCategories(c.UserContext())
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch categories: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch categories",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": categories,
+	})
+}
+
+// CreateCategory handles POST /categories
+func (h *Handler) CreateCategory(c *fiber.Ctx) error {
+	var req CreateCategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	category := &catalog.Category{
+		ID:       uuid.New(),
+		ParentID: req.ParentID,
+		Name:     req.Name,
+		Slug:     req.Slug,
+	}
+
+	if err := h.catalogRepo.CreateCategory(c.UserContext(), category); err != nil {
+		return h.writeError(c, err, "Failed to create category")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(category)
+}
+
+// UpdateCategory handles PUT /categories/:id
+func (h *Handler) UpdateCategory(c *fiber.Ctx) error {
+	categoryID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid category ID",
+		})
+	}
+
+	category, err := h.catalogRepo.GetCategoryByID(c.UserContext(), categoryID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Category not found",
+		})
+	}
+
+	var req UpdateCategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.ParentID != nil {
+		if *req.ParentID == category.ID {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "A category cannot be its own parent",
+			})
+		}
+		category.ParentID = req.ParentID
+	}
+	if req.Name != "" {
+		category.Name = req.Name
+	}
+	if req.Slug != "" {
+		category.Slug = req.Slug
+	}
+
+	if err := h.catalogRepo.UpdateCategory(c.UserContext(), category); err != nil {
+		return h.writeError(c, err, "Failed to update category")
+	}
+
+	return c.JSON(category)
+}
+
+// DeleteCategory handles DELETE /categories/:id
+func (h *Handler) DeleteCategory(c *fiber.Ctx) error {
+	categoryID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid category ID",
+		})
+	}
+
+	if err := h.catalogRepo.DeleteCategory(c.UserContext(), categoryID); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to delete category: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete category",
+		})
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// GetProducts handles GET /products?category_id=
+func (h *Handler) GetProducts(c *fiber.Ctx) error {
+	limit := 20
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	var categoryID *uuid.UUID
+	if categoryStr := c.Query("category_id"); categoryStr != "" {
+		id, err := uuid.Parse(categoryStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid category ID",
+			})
+		}
+		categoryID = &id
+	}
+
+	products, err := h.catalogRepo.GetProducts(c.UserContext(), categoryID, limit, offset)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch products: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch products",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   products,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetProductByID handles GET /products/:id
+func (h *Handler) GetProductByID(c *fiber.Ctx) error {
+	productID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	p, err := h.catalogRepo.GetProductByID(c.UserContext(), productID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+
+	return c.JSON(p)
+}
+
+// CreateProduct handles POST /products
+func (h *Handler) CreateProduct(c *fiber.Ctx) error {
+	var req CreateProductRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	status := catalog.StatusDraft
+	if req.Status != "" {
+		status = catalog.ProductStatus(req.Status)
+	}
+
+	p := &catalog.Product{
+		ID:          uuid.New(),
+		CategoryID:  req.CategoryID,
+		Name:        req.Name,
+		Description: req.Description,
+		Brand:       req.Brand,
+		Status:      status,
+		Images:      req.Images,
+	}
+	for _, variantReq := range req.Variants {
+		p.Variants = append(p.Variants, newVariant(p.ID, variantReq))
+	}
+
+	if err := h.catalogRepo.CreateProduct(c.UserContext(), p); err != nil {
+		return h.writeError(c, err, "Failed to create product")
+	}
+	h.publish(c.UserContext(), catalog.EventProductCreated, catalog.NewEventData(p))
+
+	return c.Status(fiber.StatusCreated).JSON(p)
+}
+
+// UpdateProduct handles PUT /products/:id
+func (h *Handler) UpdateProduct(c *fiber.Ctx) error {
+	productID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	p, err := h.catalogRepo.GetProductByID(c.UserContext(), productID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+
+	var req UpdateProductRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	// Update fields
+	if req.CategoryID != nil {
+		p.CategoryID = req.CategoryID
+	}
+	if req.Name != "" {
+		p.Name = req.Name
+	}
+	if req.Description != nil {
+		p.Description = *req.Description
+	}
+	if req.Brand != nil {
+		p.Brand = *req.Brand
+	}
+	if req.Status != "" {
+		p.Status = catalog.ProductStatus(req.Status)
+	}
+	if req.Images != nil {
+		p.Images = req.Images
+	}
+
+	if err := h.catalogRepo.UpdateProduct(c.UserContext(), p); err != nil {
+		return h.writeError(c, err, "Failed to update product")
+	}
+	h.publish(c.UserContext(), catalog.EventProductUpdated, catalog.NewEventData(p))
+
+	return c.JSON(p)
+}
+
+// DeleteProduct handles DELETE /products/:id
+func (h *Handler) DeleteProduct(c *fiber.Ctx) error {
+	productID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	if err := h.catalogRepo.DeleteProduct(c.UserContext(), productID); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to delete product: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete product",
+		})
+	}
+	h.publish(c.UserContext(), catalog.EventProductDeleted, catalog.EventData{ProductID: productID})
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// CreateVariant handles POST /products/:id/variants
+func (h *Handler) CreateVariant(c *fiber.Ctx) error {
+	productID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	if _, err := h.catalogRepo.GetProductByID(c.UserContext(), productID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+
+	var req VariantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	v := newVariant(productID, req)
+	if err := h.catalogRepo.CreateVariant(c.UserContext(), v); err != nil {
+		return h.writeError(c, err, "Failed to create variant")
+	}
+	h.publishProduct(c.UserContext(), productID)
+
+	return c.Status(fiber.StatusCreated).JSON(v)
+}
+
+// UpdateVariant handles PUT /variants/:id
+func (h *Handler) UpdateVariant(c *fiber.Ctx) error {
+	variantID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid variant ID",
+		})
+	}
+
+	v, err := h.catalogRepo.GetVariantByID(c.UserContext(), variantID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Variant not found",
+		})
+	}
+
+	var req UpdateVariantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	// Update fields
+	if req.SKU != "" {
+		v.SKU = req.SKU
+	}
+	if req.Barcode != nil {
+		v.Barcode = *req.Barcode
+	}
+	if req.Name != nil {
+		v.Name = *req.Name
+	}
+	if req.Attributes != nil {
+		v.Attributes = req.Attributes
+	}
+	if req.BasePrice != nil {
+		v.BasePrice = *req.BasePrice
+	}
+	if req.Currency != "" {
+		v.Currency = req.Currency
+	}
+
+	if err := h.catalogRepo.UpdateVariant(c.UserContext(), v); err != nil {
+		return h.writeError(c, err, "Failed to update variant")
+	}
+	h.publishProduct(c.UserContext(), v.ProductID)
+
+	return c.JSON(v)
+}
+
+// DeleteVariant handles DELETE /variants/:id
+func (h *Handler) DeleteVariant(c *fiber.Ctx) error {
+	variantID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid variant ID",
+		})
+	}
+
+	// Looked up first to tell search which product lost the variant
+	v, err := h.catalogRepo.GetVariantByID(c.UserContext(), variantID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Variant not found",
+		})
+	}
+
+	if err := h.catalogRepo.DeleteVariant(c.UserContext(), variantID); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to delete variant: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete variant",
+		})
+	}
+	h.publishProduct(c.UserContext(), v.ProductID)
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// LookupVariant handles GET /variants/lookup?sku= or ?barcode=, as when a
+// terminal scans an item or inventory checks a SKU
+func (h *Handler) LookupVariant(c *fiber.Ctx) error {
+	var v *catalog.Variant
+	var err error
+	switch {
+	case c.Query("sku") != "":
+		v, err = h.catalogRepo.GetVariantBySKU(c.UserContext(), c.Query("sku"))
+	case c.Query("barcode") != "":
+		v, err = h.catalogRepo.GetVariantByBarcode(c.UserContext(), c.Query("barcode"))
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "sku or barcode is required",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Variant not found",
+		})
+	}
+
+	return c.JSON(v)
+}
+
+// GetPriceList handles GET /stores/:storeId/prices
+func (h *Handler) GetPriceList(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("storeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+
+	prices, err := h.catalogRepo.GetPriceList(c.UserContext(), storeID)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch price list: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch price list",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": prices,
+	})
+}
+
+// SetPrice handles PUT /stores/:storeId/prices/:variantId
+func (h *Handler) SetPrice(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("storeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+	variantID, err := uuid.Parse(c.Params("variantId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid variant ID",
+		})
+	}
+
+	v, err := h.catalogRepo.GetVariantByID(c.UserContext(), variantID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Variant not found",
+		})
+	}
+
+	var req SetPriceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	price := &catalog.Price{
+		StoreID:   storeID,
+		VariantID: variantID,
+		Price:     req.Price,
+		Currency:  req.Currency,
+	}
+	if price.Currency == "" {
+		price.Currency = v.Currency
+	}
+
+	if err := h.catalogRepo.SetPrice(c.UserContext(), price); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to set price: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to set price",
+		})
+	}
+	h.publishPrice(c.UserContext(), catalog.EventPriceSet, catalog.PriceEventData{
+		StoreID:   storeID,
+		VariantID: variantID,
+		Price:     price.Price,
+		Currency:  price.Currency,
+	})
+
+	return c.JSON(price)
+}
+
+// DeletePrice handles DELETE /stores/:storeId/prices/:variantId
+func (h *Handler) DeletePrice(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("storeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+	variantID, err := uuid.Parse(c.Params("variantId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid variant ID",
+		})
+	}
+
+	if err := h.catalogRepo.DeletePrice(c.UserContext(), storeID, variantID); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to delete price: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete price",
+		})
+	}
+	h.publishPrice(c.UserContext(), catalog.EventPriceDeleted, catalog.PriceEventData{
+		StoreID:   storeID,
+		VariantID: variantID,
+	})
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// publishProduct publishes an updated event with the current state of a
+// product whose variants changed
+func (h *Handler) publishProduct(ctx context.Context, productID uuid.UUID) {
+	if h.events == nil {
+		return
+	}
+	p, err := h.catalogRepo.GetProductByID(ctx, productID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to load product %s for its %s event: %v", productID, catalog.EventProductUpdated, err)
+		return
+	}
+	h.publish(ctx, catalog.EventProductUpdated, catalog.NewEventData(p))
+}
+
+// publish publishes a product event to the message broker. Failures are
+// logged; the change stands.
+func (h *Handler) publish(ctx context.Context, eventType string, data catalog.EventData) {
+	if h.events == nil {
+		return
+	}
+	if err := h.events.PublishEventContext(ctx, eventType, eventType, data.Map()); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to publish %s event for product %s: %v", eventType, data.ProductID, err)
+	}
+}
+
+// publishPrice publishes a store price event to the message broker.
+// Failures are logged; the change stands.
+func (h *Handler) publishPrice(ctx context.Context, eventType string, data catalog.PriceEventData) {
+	if h.events == nil {
+		return
+	}
+	if err := h.events.PublishEventContext(ctx, eventType, eventType, data.Map()); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to publish %s event for variant %s in store %s: %v", eventType, data.VariantID, data.StoreID, err)
+	}
+}
+
+// writeError answers a failed write, with 409 for a duplicate SKU, barcode
+// or slug
+func (h *Handler) writeError(c *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, catalog.ErrDuplicate) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "SKU, barcode or slug already in use",
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("%s: %v", message, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,239 @@
+// Package gateway serves the gateway routes answered over the services'
+// internal gRPC APIs rather than proxied to their HTTP APIs. Responses are
+// rendered as the services render them, so clients cannot tell the two apart.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/internal/domain/user"
+	inventoryhttp "github.com/onichange/pos-system/internal/interfaces/http/inventory"
+	orderhttp "github.com/onichange/pos-system/internal/interfaces/http/order"
+	paymenthttp "github.com/onichange/pos-system/internal/interfaces/http/payment"
+	userhttp "github.com/onichange/pos-system/internal/interfaces/http/user"
+	"github.com/onichange/pos-system/pkg/i18n"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// Orders looks orders up, as orderclient.GRPCClient does
+type Orders interface {
+	GetOrder(ctx context.Context, id uuid.UUID) (*order.Order, error)
+	ListOrders(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*order.Order, error)
+}
+
+// Payments looks payments up, as paymentclient.GRPCClient does
+type Payments interface {
+	GetPayment(ctx context.Context, id uuid.UUID) (*payment.Payment, error)
+}
+
+// Inventory looks inventory records up, as inventoryclient.Client does
+type Inventory interface {
+	GetInventory(ctx context.Context, id uuid.UUID) (*inventory.Inventory, error)
+}
+
+// Users looks user profiles up, as userclient.Client does
+type Users interface {
+	GetUser(ctx context.Context, id uuid.UUID) (*user.User, error)
+}
+
+// Handler answers gateway routes over gRPC
+type Handler struct {
+	orders    Orders
+	payments  Payments
+	inventory Inventory
+	users     Users
+}
+
+// NewHandler creates a new gateway handler
+func NewHandler(orders Orders, payments Payments, inventory Inventory, users Users) *Handler {
+	return &Handler{
+		orders:    orders,
+		payments:  payments,
+		inventory: inventory,
+		users:     users,
+	}
+}
+
+// GetOrders handles GET /orders
+func (h *Handler) GetOrders(c *fiber.Ctx) error {
+	userID, err := callerID(c)
+	if err != nil {
+		return err
+	}
+
+	limit := 20
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	orders, err := h.orders.ListOrders(c.UserContext(), userID, limit, offset)
+	if err != nil {
+		return upstreamError(c, "order-service", err)
+	}
+
+	responses := make([]*orderhttp.OrderResponse, len(orders))
+	for i, o := range orders {
+		responses[i] = orderhttp.ToResponse(o).Localize(i18n.Locale(c))
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   responses,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetOrderByID handles GET /orders/:id
+func (h *Handler) GetOrderByID(c *fiber.Ctx) error {
+	userID, err := callerID(c)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	o, err := h.orders.GetOrder(c.UserContext(), orderID)
+	if errors.Is(err, order.ErrOrderNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Order not found",
+		})
+	}
+	if err != nil {
+		return upstreamError(c, "order-service", err)
+	}
+
+	// Check ownership
+	if o.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	return c.JSON(orderhttp.ToResponse(o).Localize(i18n.Locale(c)))
+}
+
+// GetPayment handles GET /payments/:id
+func (h *Handler) GetPayment(c *fiber.Ctx) error {
+	userID, err := callerID(c)
+	if err != nil {
+		return err
+	}
+
+	paymentID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid payment ID",
+		})
+	}
+
+	p, err := h.payments.GetPayment(c.UserContext(), paymentID)
+	if errors.Is(err, payment.ErrPaymentNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Payment not found",
+		})
+	}
+	if err != nil {
+		return upstreamError(c, "payment-service", err)
+	}
+
+	// Check ownership
+	if p.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	return c.JSON(paymenthttp.ToResponse(p).Localize(i18n.Locale(c)))
+}
+
+// GetInventory handles GET /inventory/:id
+func (h *Handler) GetInventory(c *fiber.Ctx) error {
+	inventoryID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid inventory ID",
+		})
+	}
+
+	inv, err := h.inventory.GetInventory(c.UserContext(), inventoryID)
+	if errors.Is(err, inventory.ErrInventoryNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Inventory not found",
+		})
+	}
+	if err != nil {
+		return upstreamError(c, "inventory-service", err)
+	}
+
+	return c.JSON(inventoryhttp.ToResponse(inv))
+}
+
+// GetUserProfile handles GET /users/me
+func (h *Handler) GetUserProfile(c *fiber.Ctx) error {
+	userID, err := callerID(c)
+	if err != nil {
+		return err
+	}
+
+	u, err := h.users.GetUser(c.UserContext(), userID)
+	if errors.Is(err, user.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+	if err != nil {
+		return upstreamError(c, "user-service", err)
+	}
+
+	return c.JSON(userhttp.ToResponse(u))
+}
+
+// callerID returns the ID of the authenticated user
+func callerID(c *fiber.Ctx) (uuid.UUID, error) {
+	userIDStr, _ := c.Locals("user_id").(string)
+	if userIDStr == "" {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+	return userID, nil
+}
+
+// upstreamError answers a failed call to a service as the proxy answers a
+// failed upstream request
+func upstreamError(c *fiber.Ctx, service string, err error) error {
+	logger.FromContext(c.UserContext()).Errorf("Failed to call %s: %v", service, err)
+	switch status.Code(err) {
+	case codes.DeadlineExceeded:
+		return fiber.NewError(fiber.StatusGatewayTimeout, "Service timed out")
+	case codes.Unavailable, codes.ResourceExhausted:
+		return fiber.NewError(fiber.StatusServiceUnavailable, "Service unavailable, try again")
+	}
+	return fiber.NewError(fiber.StatusBadGateway, "Failed to reach "+service)
+}
@@ -0,0 +1,106 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// AdjustInventory handles POST /inventory/:id/adjust. The stock on hand
+// changes by the requested amount only if the record is still at the
+// requested version, and the change is recorded as an adjustment movement
+// with its reason.
+func (h *Handler) AdjustInventory(c *fiber.Ctx) error {
+	inventoryID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid inventory ID",
+		})
+	}
+
+	var req AdjustStockRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	adj := &inventory.Adjustment{
+		InventoryID: inventoryID,
+		Change:      req.QuantityChange,
+		Reason:      inventory.AdjustmentReason(req.Reason),
+		Note:        req.Note,
+		Version:     req.Version,
+		UserID:      requestUser(c),
+	}
+
+	ctx := c.UserContext()
+	var inv *inventory.Inventory
+	var movement *inventory.StockMovement
+	err = h.inTx(ctx, func(tx *Tx) error {
+		var err error
+		inv, movement, err = tx.Inventory.AdjustStock(ctx, adj)
+		if err != nil {
+			return err
+		}
+		return tx.publishAdjusted(ctx, inv, adj.Change, adj.Reason)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, inventory.ErrInventoryNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Inventory not found",
+			})
+		case errors.Is(err, inventory.ErrVersionConflict):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Inventory was modified by another request. Please retry.",
+			})
+		case errors.Is(err, inventory.ErrInsufficientStock):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Stock on hand cannot drop below the reserved quantity",
+			})
+		}
+		logger.FromContext(ctx).Errorf("Failed to adjust inventory: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to adjust inventory",
+		})
+	}
+
+	metrics.RecordStockChange(storeLabel(inv.StoreID), inv.AvailableQuantity-adj.Change, inv.AvailableQuantity, inv.ReorderPoint)
+
+	return c.JSON(&AdjustStockResponse{
+		Inventory: ToResponse(inv),
+		Movement:  movement,
+	})
+}
+
+// publishAdjusted records the events of a change of change units to inv's
+// stock on hand
+func (tx *Tx) publishAdjusted(ctx context.Context, inv *inventory.Inventory, change int, reason inventory.AdjustmentReason) error {
+	available := inv.AvailableQuantity
+	if err := tx.publish(ctx, inventory.EventAdjusted, inventory.EventData{
+		ProductID:    inv.ProductID,
+		StoreID:      inv.StoreID,
+		Quantity:     change,
+		Available:    &available,
+		ReorderPoint: inv.ReorderPoint,
+		Reason:       string(reason),
+	}); err != nil {
+		return err
+	}
+	return tx.publishLowStock(ctx, inv, inv.AvailableQuantity-change)
+}
@@ -0,0 +1,342 @@
+package inventory
+
+import (
+	"encoding/csv"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// varianceColumns are the header of a CSV variance report
+var varianceColumns = []string{
+	"product_id", "inventory_id", "system_quantity", "counted_quantity", "variance", "variance_value", "counted_by", "counted_at",
+}
+
+// CountHandler handles stock count HTTP requests
+type CountHandler struct {
+	countRepo inventory.CountRepository
+	stock     *Handler // Publishes the adjustments of reconciled counts
+}
+
+// NewCountHandler creates a new stock count handler, publishing the
+// adjustments of reconciled counts through stock
+func NewCountHandler(countRepo inventory.CountRepository, stock *Handler) *CountHandler {
+	return &CountHandler{countRepo: countRepo, stock: stock}
+}
+
+// CreateCount handles POST /inventory/counts, opening a count of a store
+func (h *CountHandler) CreateCount(c *fiber.Ctx) error {
+	var req CreateCountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	count := &inventory.Count{
+		ID:       uuid.New(),
+		StoreID:  req.StoreID,
+		Note:     req.Note,
+		OpenedBy: requestUser(c),
+	}
+	if err := h.countRepo.CreateCount(c.UserContext(), count); err != nil {
+		return countError(c, err, "open")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(count)
+}
+
+// GetCount handles GET /inventory/counts/:id
+func (h *CountHandler) GetCount(c *fiber.Ctx) error {
+	countID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid count ID",
+		})
+	}
+
+	count, err := h.countRepo.GetCount(c.UserContext(), countID)
+	if err != nil {
+		return countError(c, err, "fetch")
+	}
+	return c.JSON(count)
+}
+
+// ListCounts handles GET /inventory/counts, optionally narrowed to a store's
+// counts and to a status
+func (h *CountHandler) ListCounts(c *fiber.Ctx) error {
+	var filter inventory.CountFilter
+	if storeIDStr := c.Query("store_id"); storeIDStr != "" {
+		id, err := uuid.Parse(storeIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid store ID",
+			})
+		}
+		filter.StoreID = &id
+	}
+	filter.Status = inventory.CountStatus(c.Query("status"))
+
+	limit := 20
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	counts, err := h.countRepo.ListCounts(c.UserContext(), filter, limit, offset)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch stock counts: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch stock counts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   counts,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// RecordCounts handles POST /inventory/counts/:id/lines, recording how many
+// of some products were counted. Counting a product again replaces its
+// earlier count.
+func (h *CountHandler) RecordCounts(c *fiber.Ctx) error {
+	countID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid count ID",
+		})
+	}
+
+	var req RecordCountsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	entries := make([]inventory.CountEntry, len(req.Items))
+	seen := make(map[uuid.UUID]bool, len(req.Items))
+	for i, item := range req.Items {
+		if seen[item.ProductID] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Each product may appear in a request once",
+			})
+		}
+		seen[item.ProductID] = true
+		entries[i] = inventory.CountEntry{ProductID: item.ProductID, Quantity: item.CountedQuantity}
+	}
+
+	ctx := c.UserContext()
+	if err := h.countRepo.RecordCounts(ctx, countID, entries, requestUser(c)); err != nil {
+		return countError(c, err, "record")
+	}
+
+	lines, err := h.countRepo.ListCountLines(ctx, countID)
+	if err != nil {
+		return countError(c, err, "fetch")
+	}
+	return c.JSON(fiber.Map{
+		"data": lines,
+	})
+}
+
+// GetVariances handles GET /inventory/counts/:id/variances?format=json|csv:
+// each product counted against the stock on record, with their totals
+func (h *CountHandler) GetVariances(c *fiber.Ctx) error {
+	countID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid count ID",
+		})
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "format must be json or csv",
+		})
+	}
+
+	ctx := c.UserContext()
+	count, err := h.countRepo.GetCount(ctx, countID)
+	if err != nil {
+		return countError(c, err, "fetch")
+	}
+	lines, err := h.countRepo.ListCountLines(ctx, countID)
+	if err != nil {
+		return countError(c, err, "fetch")
+	}
+
+	if format == "csv" {
+		return writeVariancesCSV(c, count, lines)
+	}
+
+	report := VarianceReport{Count: count, Lines: lines}
+	for _, l := range lines {
+		report.Summary.LinesCounted++
+		switch {
+		case l.Variance > 0:
+			report.Summary.UnitsOver += l.Variance
+		case l.Variance < 0:
+			report.Summary.UnitsShort -= l.Variance
+		}
+		if l.Variance != 0 {
+			report.Summary.LinesVarying++
+		}
+		report.Summary.VarianceValue += l.VarianceValue
+	}
+	report.Summary.VarianceValue = math.Round(report.Summary.VarianceValue*100) / 100
+	return c.JSON(report)
+}
+
+// ReconcileCount handles POST /inventory/counts/:id/reconcile, closing the
+// count and setting the stock on hand of each product counted to its count
+func (h *CountHandler) ReconcileCount(c *fiber.Ctx) error {
+	countID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid count ID",
+		})
+	}
+
+	ctx := c.UserContext()
+	var count *inventory.Count
+	var corrections []inventory.CountCorrection
+	err = h.stock.inTx(ctx, func(tx *Tx) error {
+		var err error
+		count, corrections, err = tx.Counts.ReconcileCount(ctx, countID, requestUser(c))
+		if err != nil {
+			return err
+		}
+		for _, correction := range corrections {
+			if err := tx.publishAdjusted(ctx, correction.Inventory, correction.Change, inventory.ReasonCountCorrection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return countError(c, err, "reconcile")
+	}
+
+	resp := CountReconciliationResponse{Count: count, Adjustments: make([]CountAdjustmentResponse, len(corrections))}
+	for i, correction := range corrections {
+		inv := correction.Inventory
+		metrics.RecordStockChange(storeLabel(inv.StoreID), inv.AvailableQuantity-correction.Change, inv.AvailableQuantity, inv.ReorderPoint)
+		resp.Adjustments[i] = CountAdjustmentResponse{
+			Inventory:      ToResponse(correction.Inventory),
+			QuantityChange: correction.Change,
+		}
+	}
+	return c.JSON(resp)
+}
+
+// CancelCount handles POST /inventory/counts/:id/cancel, closing the count
+// without changing stock
+func (h *CountHandler) CancelCount(c *fiber.Ctx) error {
+	countID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid count ID",
+		})
+	}
+
+	count, err := h.countRepo.CancelCount(c.UserContext(), countID, requestUser(c))
+	if err != nil {
+		return countError(c, err, "cancel")
+	}
+	return c.JSON(count)
+}
+
+// writeVariancesCSV answers with the lines of a count as a CSV attachment
+func writeVariancesCSV(c *fiber.Ctx, count *inventory.Count, lines []*inventory.CountLine) error {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.Write(varianceColumns); err != nil {
+		return err
+	}
+	for _, l := range lines {
+		countedBy := ""
+		if l.CountedBy != nil {
+			countedBy = l.CountedBy.String()
+		}
+		if err := w.Write([]string{
+			l.ProductID.String(), l.InventoryID.String(), strconv.Itoa(l.SystemQuantity), strconv.Itoa(l.CountedQuantity),
+			strconv.Itoa(l.Variance), strconv.FormatFloat(l.VarianceValue, 'f', 2, 64), countedBy, l.CountedAt.Format(time.RFC3339),
+		}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	filename := "stock-count-" + count.ID.String() + ".csv"
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	return c.SendString(b.String())
+}
+
+// countError responds to a failure to act on a count
+func countError(c *fiber.Ctx, err error, action string) error {
+	switch {
+	case errors.Is(err, inventory.ErrCountNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Stock count not found",
+		})
+	case errors.Is(err, inventory.ErrCountClosed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Stock count is no longer open",
+		})
+	case errors.Is(err, inventory.ErrCountInProgress):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Store already has an open stock count",
+		})
+	case errors.Is(err, inventory.ErrInventoryNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "A product counted has no inventory at the store",
+		})
+	case errors.Is(err, inventory.ErrInsufficientStock):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A product was counted below its reserved stock",
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("Failed to %s stock count: %v", action, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to " + action + " stock count",
+	})
+}
@@ -1,6 +1,8 @@
 package inventory
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/onichange/pos-system/internal/domain/inventory"
 )
@@ -31,6 +33,51 @@ type ReserveStockRequest struct {
 	StoreID   *uuid.UUID `json:"store_id,omitempty"`
 	Quantity  int        `json:"quantity" validate:"required,min=1"`
 	Reason    string     `json:"reason,omitempty"`
+	// ReservationID makes the request safe to retry; one is assigned when
+	// absent
+	ReservationID *uuid.UUID `json:"reservation_id,omitempty"`
+	ReferenceID   *uuid.UUID `json:"reference_id,omitempty"` // Order the stock is reserved for
+	ReferenceType string     `json:"reference_type,omitempty" validate:"max=50"`
+}
+
+// ReserveStockResponse represents a reservation made, to commit or release
+// by its ID before it expires
+type ReserveStockResponse struct {
+	Message       string                      `json:"message"`
+	ReservationID uuid.UUID                   `json:"reservation_id"`
+	Status        inventory.ReservationStatus `json:"status"`
+	ExpiresAt     time.Time                   `json:"expires_at"`
+}
+
+// ReserveBatchRequest represents a request reserving the stock of several
+// items at once, all or none
+type ReserveBatchRequest struct {
+	Items         []ReserveBatchItem `json:"items" validate:"required,min=1,max=100,dive"`
+	ReferenceID   *uuid.UUID         `json:"reference_id,omitempty"` // Order the stock is reserved for
+	ReferenceType string             `json:"reference_type,omitempty" validate:"max=50"`
+}
+
+// ReserveBatchItem represents one item of a batch reservation
+type ReserveBatchItem struct {
+	ReservationID *uuid.UUID `json:"reservation_id,omitempty"` // Assigned when absent
+	ProductID     uuid.UUID  `json:"product_id" validate:"required"`
+	StoreID       *uuid.UUID `json:"store_id,omitempty"`
+	Quantity      int        `json:"quantity" validate:"required,min=1"`
+}
+
+// ReserveBatchResponse represents the reservations of a batch, in the order
+// of its items
+type ReserveBatchResponse struct {
+	Reservations []ReserveStockResponse `json:"reservations"`
+}
+
+// ReserveBatchItemError represents why an item of a batch could not be
+// reserved
+type ReserveBatchItemError struct {
+	Index     int       `json:"index"`
+	ProductID uuid.UUID `json:"product_id"`
+	Code      string    `json:"code"` // insufficient_stock, inventory_not_found or reservation_exists
+	Error     string    `json:"error"`
 }
 
 // ReleaseStockRequest represents release stock request
@@ -76,3 +123,97 @@ func ToResponse(inv *inventory.Inventory) *InventoryResponse {
 	}
 }
 
+
+// AdjustStockRequest represents an adjustment of the stock on hand. Version
+// is that of the inventory record the change was worked out from.
+type AdjustStockRequest struct {
+	QuantityChange int    `json:"quantity_change" validate:"required"` // Negative to remove stock
+	Reason         string `json:"reason" validate:"required,oneof=damage theft count_correction"`
+	Note           string `json:"note,omitempty" validate:"max=500"`
+	Version        int    `json:"version" validate:"required,min=1"`
+}
+
+// AdjustStockResponse is an adjusted inventory record with the movement
+// recording the adjustment
+type AdjustStockResponse struct {
+	Inventory *InventoryResponse       `json:"inventory"`
+	Movement  *inventory.StockMovement `json:"movement"`
+}
+
+// CreateTransferRequest represents a request to move stock between stores
+type CreateTransferRequest struct {
+	SourceStoreID      uuid.UUID             `json:"source_store_id" validate:"required"`
+	DestinationStoreID uuid.UUID             `json:"destination_store_id" validate:"required,nefield=SourceStoreID"`
+	Items              []TransferItemRequest `json:"items" validate:"required,min=1,dive"`
+	Note               string                `json:"note,omitempty" validate:"max=500"`
+}
+
+// TransferItemRequest is a product to move and how many of it
+type TransferItemRequest struct {
+	ProductID uuid.UUID `json:"product_id" validate:"required"`
+	Quantity  int       `json:"quantity" validate:"required,min=1"`
+}
+
+// CreateCountRequest represents a request to open a stock count of a store
+type CreateCountRequest struct {
+	StoreID uuid.UUID `json:"store_id" validate:"required"`
+	Note    string    `json:"note,omitempty" validate:"max=500"`
+}
+
+// RecordCountsRequest represents the quantities counted of some products
+type RecordCountsRequest struct {
+	Items []CountItemRequest `json:"items" validate:"required,min=1,max=500,dive"`
+}
+
+// CountItemRequest is a product counted and how many of it were found
+type CountItemRequest struct {
+	ProductID       uuid.UUID `json:"product_id" validate:"required"`
+	CountedQuantity int       `json:"counted_quantity" validate:"min=0"`
+}
+
+// CountReconciliationResponse is a reconciled count with the adjustment of
+// each stock record it corrected
+type CountReconciliationResponse struct {
+	Count       *inventory.Count          `json:"count"`
+	Adjustments []CountAdjustmentResponse `json:"adjustments"`
+}
+
+// CountAdjustmentResponse is a stock record corrected by a count
+type CountAdjustmentResponse struct {
+	Inventory      *InventoryResponse `json:"inventory"`
+	QuantityChange int                `json:"quantity_change"`
+}
+
+// VarianceReport is the variance of each product counted on a count, with
+// their totals
+type VarianceReport struct {
+	Count   *inventory.Count       `json:"count"`
+	Lines   []*inventory.CountLine `json:"lines"`
+	Summary VarianceSummary        `json:"summary"`
+}
+
+// VarianceSummary totals the variances of a count
+type VarianceSummary struct {
+	LinesCounted  int     `json:"lines_counted"`
+	LinesVarying  int     `json:"lines_varying"` // Counted differently than on record
+	UnitsOver     int     `json:"units_over"`    // Found beyond the stock on record
+	UnitsShort    int     `json:"units_short"`   // Missing from the stock on record
+	VarianceValue float64 `json:"variance_value"`
+}
+
+// ImportReport is the outcome of a stock import: the rows read, how many
+// records were created, and what is wrong with the rows that are invalid.
+// An import with invalid rows creates nothing.
+type ImportReport struct {
+	DryRun   bool             `json:"dry_run"`
+	Rows     int              `json:"rows"`
+	Imported int              `json:"imported"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// ImportRowError is what is wrong with a row of an import
+type ImportRowError struct {
+	Line   int    `json:"line"` // Of the file, the header being line 1
+	Column string `json:"column,omitempty"`
+	Error  string `json:"error"`
+}
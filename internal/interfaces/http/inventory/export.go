@@ -0,0 +1,82 @@
+package inventory
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/pkg/csvexport"
+	"github.com/onichange/pos-system/pkg/dataexport"
+)
+
+// ExportStore handles GET /internal/v1/stores/:storeId/export, streaming
+// every stock level of a store to the store service building an export of
+// the store
+func (h *Handler) ExportStore(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("storeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+
+	return dataexport.Stream(c, func(ctx context.Context, emit func(v any) error) error {
+		return h.inventoryRepo.ExportStore(ctx, storeID, func(v *inventory.Inventory) error {
+			return emit(v)
+		})
+	})
+}
+
+// stockColumns are the columns of a stock export, in their default order
+var stockColumns = []csvexport.Column[*inventory.Inventory]{
+	{Name: "product_id", Value: func(i *inventory.Inventory) string { return i.ProductID.String() }},
+	{Name: "inventory_id", Value: func(i *inventory.Inventory) string { return i.ID.String() }},
+	{Name: "quantity", Value: func(i *inventory.Inventory) string { return strconv.Itoa(i.Quantity) }},
+	{Name: "reserved_quantity", Value: func(i *inventory.Inventory) string { return strconv.Itoa(i.ReservedQuantity) }},
+	{Name: "available_quantity", Value: func(i *inventory.Inventory) string { return strconv.Itoa(i.AvailableQuantity) }},
+	{Name: "reorder_point", Value: func(i *inventory.Inventory) string { return strconv.Itoa(i.ReorderPoint) }},
+	{Name: "cost_price", Value: func(i *inventory.Inventory) string { return optionalMoney(i.CostPrice) }},
+	{Name: "selling_price", Value: func(i *inventory.Inventory) string { return optionalMoney(i.SellingPrice) }},
+	{Name: "stock_value", Value: func(i *inventory.Inventory) string {
+		if i.CostPrice == nil {
+			return ""
+		}
+		return csvexport.Money(float64(i.Quantity) * *i.CostPrice)
+	}},
+	{Name: "updated_at", Value: func(i *inventory.Inventory) string { return csvexport.Time(&i.UpdatedAt) }},
+}
+
+// Export handles GET /inventory/export?store_id=&format=csv|excel&columns=&gzip=,
+// streaming the stock on hand at a store as a CSV file for accounting
+func (h *Handler) Export(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Query("store_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "store_id is required",
+		})
+	}
+
+	export, err := csvexport.FromQuery(c, stockColumns)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	name := "stock-" + storeID.String() + "-" + time.Now().UTC().Format(csvexport.DateLayout)
+	return export.Stream(c, name, func(ctx context.Context, emit func(*inventory.Inventory) error) error {
+		return h.inventoryRepo.ExportStore(ctx, storeID, emit)
+	})
+}
+
+// optionalMoney formats an amount for a cell, empty when there is none
+func optionalMoney(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return csvexport.Money(*v)
+}
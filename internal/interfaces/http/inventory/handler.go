@@ -1,24 +1,55 @@
 package inventory
 
 import (
+	"context"
+	"errors"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
 	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
 	"github.com/onichange/pos-system/pkg/validator"
 )
 
+// EventPublisher publishes inventory events to the message broker
+type EventPublisher interface {
+	PublishEventContext(ctx context.Context, eventType, routingKey string, data map[string]interface{}) error
+}
+
+// Tx holds the repositories and event publisher of one database
+// transaction, so a stock change and the events reporting it are saved
+// together or not at all
+type Tx struct {
+	Inventory    inventory.Repository
+	Reservations inventory.ReservationRepository
+	Counts       inventory.CountRepository
+	Events       EventPublisher
+}
+
+// Transactor runs fn in a database transaction, committing it when fn
+// returns nil and rolling it back otherwise
+type Transactor func(ctx context.Context, fn func(tx *Tx) error) error
+
 // Handler handles inventory HTTP requests
 type Handler struct {
-	inventoryRepo inventory.Repository
+	inventoryRepo   inventory.Repository
+	reservationRepo inventory.ReservationRepository
+	reservationTTL  time.Duration // How long reservations are held unless committed
+	inTx            Transactor    // Saves stock changes with their events
 }
 
-// NewHandler creates a new inventory handler
-func NewHandler(inventoryRepo inventory.Repository) *Handler {
+// NewHandler creates a new inventory handler, holding stock reserved through
+// the HTTP API for reservationTTL
+func NewHandler(inventoryRepo inventory.Repository, reservationRepo inventory.ReservationRepository, reservationTTL time.Duration, inTx Transactor) *Handler {
 	return &Handler{
-		inventoryRepo: inventoryRepo,
+		inventoryRepo:   inventoryRepo,
+		reservationRepo: reservationRepo,
+		reservationTTL:  reservationTTL,
+		inTx:            inTx,
 	}
 }
 
@@ -31,7 +62,7 @@ func (h *Handler) GetInventory(c *fiber.Ctx) error {
 		})
 	}
 
-	inv, err := h.inventoryRepo.GetByID(c.Context(), inventoryID)
+	inv, err := h.inventoryRepo.GetByID(c.UserContext(), inventoryID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Inventory not found",
@@ -57,7 +88,7 @@ func (h *Handler) GetInventoryByProduct(c *fiber.Ctx) error {
 		}
 	}
 
-	inv, err := h.inventoryRepo.GetByProductID(c.Context(), productID, storeID)
+	inv, err := h.inventoryRepo.GetByProductID(c.UserContext(), productID, storeID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Inventory not found",
@@ -96,7 +127,8 @@ func (h *Handler) CreateInventory(c *fiber.Ctx) error {
 		Version:          1,
 	}
 
-	if err := h.inventoryRepo.Create(c.Context(), inv); err != nil {
+	if err := h.inventoryRepo.Create(c.UserContext(), inv); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to create inventory: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create inventory",
 		})
@@ -114,7 +146,7 @@ func (h *Handler) UpdateInventory(c *fiber.Ctx) error {
 		})
 	}
 
-	inv, err := h.inventoryRepo.GetByID(c.Context(), inventoryID)
+	inv, err := h.inventoryRepo.GetByID(c.UserContext(), inventoryID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Inventory not found",
@@ -128,9 +160,11 @@ func (h *Handler) UpdateInventory(c *fiber.Ctx) error {
 		})
 	}
 
+	previousAvailable := inv.AvailableQuantity
+
 	// Update fields
 	if req.Quantity != nil {
-		inv.Quantity = *req.Quantity
+		inv.SetQuantity(*req.Quantity)
 	}
 	if req.ReorderPoint != nil {
 		inv.ReorderPoint = *req.ReorderPoint
@@ -145,21 +179,33 @@ func (h *Handler) UpdateInventory(c *fiber.Ctx) error {
 		inv.SellingPrice = req.SellingPrice
 	}
 
-	if err := h.inventoryRepo.UpdateWithVersion(c.Context(), inv); err != nil {
+	err = h.inTx(c.UserContext(), func(tx *Tx) error {
+		if err := tx.Inventory.UpdateWithVersion(c.UserContext(), inv); err != nil {
+			return err
+		}
+		return tx.publishLowStock(c.UserContext(), inv, previousAvailable)
+	})
+	if err != nil {
 		if err == inventory.ErrVersionConflict {
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 				"error": "Inventory was modified by another request. Please retry.",
 			})
 		}
+		logger.FromContext(c.UserContext()).Errorf("Failed to update inventory: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update inventory",
 		})
 	}
 
+	metrics.RecordStockChange(storeLabel(inv.StoreID), previousAvailable, inv.AvailableQuantity, inv.ReorderPoint)
+
 	return c.JSON(ToResponse(inv))
 }
 
-// ReserveStock handles POST /inventory/reserve
+// ReserveStock handles POST /inventory/reserve, reserving stock for as long
+// as the reservation TTL unless the reservation it answers with is committed
+// or released first. A reservation ID sent again answers with the
+// reservation made for it, rather than reserving twice.
 func (h *Handler) ReserveStock(c *fiber.Ctx) error {
 	var req ReserveStockRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -175,19 +221,51 @@ func (h *Handler) ReserveStock(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.inventoryRepo.ReserveStock(c.Context(), req.ProductID, req.StoreID, req.Quantity); err != nil {
-		if err == inventory.ErrInsufficientStock {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+	res := &inventory.Reservation{
+		ID:            uuid.New(),
+		ProductID:     req.ProductID,
+		StoreID:       req.StoreID,
+		Quantity:      req.Quantity,
+		ReferenceID:   req.ReferenceID,
+		ReferenceType: req.ReferenceType,
+		ExpiresAt:     time.Now().Add(h.reservationTTL),
+	}
+	if req.ReservationID != nil {
+		res.ID = *req.ReservationID
+	}
+
+	err := h.CreateReservation(c.UserContext(), res)
+	if errors.Is(err, inventory.ErrReservationExists) {
+		existing, gerr := h.reservationRepo.GetReservation(c.UserContext(), res.ID)
+		if errors.Is(gerr, inventory.ErrReservationNotFound) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Reservation ID is taken",
+			})
+		}
+		res, err = existing, gerr
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, inventory.ErrInsufficientStock):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 				"error": "Insufficient stock",
 			})
+		case errors.Is(err, inventory.ErrInventoryNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Inventory not found",
+			})
 		}
+		logger.FromContext(c.UserContext()).Errorf("Failed to reserve stock: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to reserve stock",
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Stock reserved successfully",
+	return c.Status(fiber.StatusOK).JSON(ReserveStockResponse{
+		Message:       "Stock reserved successfully",
+		ReservationID: res.ID,
+		Status:        res.Status,
+		ExpiresAt:     res.ExpiresAt,
 	})
 }
 
@@ -207,7 +285,8 @@ func (h *Handler) ReleaseStock(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.inventoryRepo.ReleaseStock(c.Context(), req.ProductID, req.StoreID, req.Quantity); err != nil {
+	if err := h.Release(c.UserContext(), req.ProductID, req.StoreID, req.Quantity); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to release stock: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to release stock",
 		})
@@ -227,8 +306,29 @@ func (h *Handler) GetLowStockItems(c *fiber.Ctx) error {
 		}
 	}
 
-	items, err := h.inventoryRepo.GetLowStockItems(c.Context(), storeID)
+	items, err := h.inventoryRepo.GetLowStockItems(c.UserContext(), storeID)
 	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch low stock items: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch low stock items",
+		})
+	}
+
+	responses := make([]*InventoryResponse, len(items))
+	for i, inv := range items {
+		responses[i] = ToResponse(inv)
+	}
+
+	return c.JSON(fiber.Map{"data": responses})
+}
+
+// ListLowStock handles GET /internal/v1/inventory/low-stock: the stock of
+// every store at or below its reorder point, for the procurement service to
+// reorder
+func (h *Handler) ListLowStock(c *fiber.Ctx) error {
+	items, err := h.inventoryRepo.ListLowStock(c.UserContext())
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch low stock items: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch low stock items",
 		})
@@ -264,8 +364,9 @@ func (h *Handler) GetInventoryByStore(c *fiber.Ctx) error {
 		}
 	}
 
-	items, err := h.inventoryRepo.GetByStoreID(c.Context(), storeID, limit, offset)
+	items, err := h.inventoryRepo.GetByStoreID(c.UserContext(), storeID, limit, offset)
 	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch inventory: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch inventory",
 		})
@@ -282,3 +383,92 @@ func (h *Handler) GetInventoryByStore(c *fiber.Ctx) error {
 		"offset": offset,
 	})
 }
+
+// storeLabel returns the store ID used as a metric label, empty for inventory
+// not held by a store
+func storeLabel(storeID *uuid.UUID) string {
+	if storeID == nil {
+		return ""
+	}
+	return storeID.String()
+}
+
+// Reserve reserves stock of a product until released by product, recording
+// the change and publishing its events. It serves the gRPC API; the HTTP API
+// reserves through reservations, which expire.
+func (h *Handler) Reserve(ctx context.Context, productID uuid.UUID, storeID *uuid.UUID, quantity int) (*inventory.Inventory, error) {
+	var inv *inventory.Inventory
+	err := h.inTx(ctx, func(tx *Tx) error {
+		var err error
+		inv, err = tx.Inventory.ReserveStock(ctx, productID, storeID, quantity)
+		if err != nil {
+			return err
+		}
+		available := inv.AvailableQuantity
+		if err := tx.publish(ctx, inventory.EventReserved, inventory.EventData{
+			ProductID:    productID,
+			StoreID:      storeID,
+			Quantity:     quantity,
+			Available:    &available,
+			ReorderPoint: inv.ReorderPoint,
+		}); err != nil {
+			return err
+		}
+		return tx.publishLowStock(ctx, inv, inv.AvailableQuantity+quantity)
+	})
+	if err != nil {
+		if errors.Is(err, inventory.ErrInsufficientStock) {
+			metrics.RecordReservationConflict(metrics.ConflictInsufficientStock)
+		}
+		if errors.Is(err, inventory.ErrVersionConflict) {
+			metrics.RecordReservationConflict(metrics.ConflictVersion)
+		}
+		return nil, err
+	}
+
+	metrics.RecordStockChange(storeLabel(inv.StoreID), inv.AvailableQuantity+quantity, inv.AvailableQuantity, inv.ReorderPoint)
+	return inv, nil
+}
+
+// Release makes reserved stock of a product available again, publishing its
+// event. It is shared by the HTTP and gRPC APIs.
+func (h *Handler) Release(ctx context.Context, productID uuid.UUID, storeID *uuid.UUID, quantity int) error {
+	return h.inTx(ctx, func(tx *Tx) error {
+		if err := tx.Inventory.ReleaseStock(ctx, productID, storeID, quantity); err != nil {
+			return err
+		}
+		data := inventory.EventData{
+			ProductID: productID,
+			StoreID:   storeID,
+			Quantity:  quantity,
+		}
+		// The event goes out without the stock left when it cannot be read back
+		if inv, err := tx.Inventory.GetByProductID(ctx, productID, storeID); err == nil {
+			available := inv.AvailableQuantity
+			data.Available = &available
+			data.ReorderPoint = inv.ReorderPoint
+		}
+		return tx.publish(ctx, inventory.EventReleased, data)
+	})
+}
+
+// publishLowStock records a low stock event when a change took inv's
+// available stock from above its reorder point to at or below it
+func (tx *Tx) publishLowStock(ctx context.Context, inv *inventory.Inventory, previousAvailable int) error {
+	if previousAvailable <= inv.ReorderPoint || inv.AvailableQuantity > inv.ReorderPoint {
+		return nil
+	}
+	available := inv.AvailableQuantity
+	return tx.publish(ctx, inventory.EventLowStock, inventory.EventData{
+		ProductID:    inv.ProductID,
+		StoreID:      inv.StoreID,
+		Available:    &available,
+		ReorderPoint: inv.ReorderPoint,
+	})
+}
+
+// publish records an inventory event in the transaction, for the message
+// broker
+func (tx *Tx) publish(ctx context.Context, eventType string, data inventory.EventData) error {
+	return tx.Events.PublishEventContext(ctx, eventType, eventType, data.Map())
+}
@@ -0,0 +1,270 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// maxImportRows bounds the rows of an import, which is validated and
+// inserted whole
+const maxImportRows = 5000
+
+// importColumns are the columns an import file may have, by header; only
+// product_id is required
+var importColumns = map[string]bool{
+	"product_id": true, "store_id": true, "quantity": true, "reorder_point": true,
+	"reorder_quantity": true, "cost_price": true, "selling_price": true,
+}
+
+// ImportHandler handles bulk imports of stock records
+type ImportHandler struct {
+	importRepo inventory.ImportRepository
+}
+
+// NewImportHandler creates a new stock import handler
+func NewImportHandler(importRepo inventory.ImportRepository) *ImportHandler {
+	return &ImportHandler{importRepo: importRepo}
+}
+
+// Import handles POST /inventory/import?dry_run=true|false, creating a stock
+// record per row of a CSV file, sent as the body or as the file field of a
+// multipart form. The header names the columns: product_id, and optionally
+// store_id (empty for central stock), quantity, reorder_point,
+// reorder_quantity, cost_price and selling_price. Every row is validated
+// first; when any is invalid, or with dry_run, nothing is created.
+func (h *ImportHandler) Import(c *fiber.Ctx) error {
+	file, err := importFile(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Send a CSV file as the body or the file field of a form",
+		})
+	}
+
+	records, report, err := parseImport(file)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	report.DryRun = c.QueryBool("dry_run")
+
+	// Products already stocked where they are imported are invalid too
+	ctx := c.UserContext()
+	keys := make([]inventory.StockKey, len(records))
+	for i, rec := range records {
+		keys[i] = inventory.KeyOf(rec.inv)
+	}
+	stocked, err := h.importRepo.Stocked(ctx, keys)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to check imported stock: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to import stock",
+		})
+	}
+	for _, rec := range records {
+		if stocked[inventory.KeyOf(rec.inv)] {
+			report.Errors = append(report.Errors, ImportRowError{
+				Line: rec.line, Column: "product_id", Error: "product is already stocked there",
+			})
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(report)
+	}
+	if report.DryRun {
+		return c.JSON(report)
+	}
+
+	inventories := make([]*inventory.Inventory, len(records))
+	for i, rec := range records {
+		inventories[i] = rec.inv
+	}
+	if err := h.importRepo.Import(ctx, inventories); err != nil {
+		if errors.Is(err, inventory.ErrAlreadyStocked) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "A product was stocked while importing; nothing was imported",
+			})
+		}
+		logger.FromContext(ctx).Errorf("Failed to import stock: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to import stock",
+		})
+	}
+
+	report.Imported = len(inventories)
+	return c.Status(fiber.StatusCreated).JSON(report)
+}
+
+// importFile returns the CSV file of an import request
+func importFile(c *fiber.Ctx) (io.Reader, error) {
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		return bytes.NewReader(c.Body()), nil
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	return header.Open()
+}
+
+// importRecord is a valid row of an import, with the line it was read from
+type importRecord struct {
+	inv  *inventory.Inventory
+	line int
+}
+
+// parseImport reads the rows of an import file, reporting what is wrong
+// with each invalid one. It fails when the file itself is unusable: not
+// CSV, without a usable header or rows, or too long.
+func parseImport(file io.Reader) ([]importRecord, ImportReport, error) {
+	report := ImportReport{Errors: []ImportRowError{}}
+
+	r := csv.NewReader(file)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, report, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, report, fmt.Errorf("the file is not valid CSV: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // Written by Excel
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !importColumns[name] {
+			return nil, report, fmt.Errorf("unknown column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, report, fmt.Errorf("column %q appears twice", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["product_id"]; !ok {
+		return nil, report, errors.New("the product_id column is required")
+	}
+
+	var records []importRecord
+	seen := make(map[inventory.StockKey]int)
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, report, fmt.Errorf("the file is not valid CSV: %w", err)
+		}
+		report.Rows++
+		if report.Rows > maxImportRows {
+			return nil, report, fmt.Errorf("imports hold at most %d rows", maxImportRows)
+		}
+
+		line, _ := r.FieldPos(0)
+		rowErr := func(column, msg string) {
+			report.Errors = append(report.Errors, ImportRowError{Line: line, Column: column, Error: msg})
+		}
+		field := func(column string) string {
+			if i, ok := columns[column]; ok {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		inv, valid := parseImportRow(field, rowErr)
+		if !valid {
+			continue
+		}
+		key := inventory.KeyOf(inv)
+		if first, ok := seen[key]; ok {
+			rowErr("product_id", fmt.Sprintf("product is imported there on line %d too", first))
+			continue
+		}
+		seen[key] = line
+		records = append(records, importRecord{inv: inv, line: line})
+	}
+	if report.Rows == 0 {
+		return nil, report, errors.New("the file has no rows")
+	}
+	return records, report, nil
+}
+
+// parseImportRow builds the stock record of a row from its fields, reporting
+// each invalid one through rowErr
+func parseImportRow(field func(column string) string, rowErr func(column, msg string)) (*inventory.Inventory, bool) {
+	valid := true
+	inv := &inventory.Inventory{ID: uuid.New()}
+
+	productID, err := uuid.Parse(field("product_id"))
+	if err != nil {
+		rowErr("product_id", "must be a UUID")
+		valid = false
+	}
+	inv.ProductID = productID
+
+	if value := field("store_id"); value != "" {
+		storeID, err := uuid.Parse(value)
+		if err != nil {
+			rowErr("store_id", "must be a UUID, or empty for central stock")
+			valid = false
+		}
+		inv.StoreID = &storeID
+	}
+
+	for _, f := range []struct {
+		column string
+		dst    *int
+	}{
+		{"quantity", &inv.Quantity},
+		{"reorder_point", &inv.ReorderPoint},
+		{"reorder_quantity", &inv.ReorderQuantity},
+	} {
+		value := field(f.column)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			rowErr(f.column, "must be a whole number of at least 0")
+			valid = false
+		}
+		*f.dst = n
+	}
+	inv.AvailableQuantity = inv.Quantity
+
+	for _, f := range []struct {
+		column string
+		dst    **float64
+	}{
+		{"cost_price", &inv.CostPrice},
+		{"selling_price", &inv.SellingPrice},
+	} {
+		value := field(f.column)
+		if value == "" {
+			continue
+		}
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || price < 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+			rowErr(f.column, "must be an amount of at least 0")
+			valid = false
+		}
+		*f.dst = &price
+	}
+
+	return inv, valid
+}
@@ -0,0 +1,68 @@
+package inventory
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+)
+
+// Receive adds received stock, recording the change and publishing its
+// event. It serves the gRPC API; a source posted before returns
+// inventory.ErrAlreadyReceived and leaves stock unchanged.
+func (h *Handler) Receive(ctx context.Context, receipt *inventory.StockReceipt) (*inventory.Inventory, error) {
+	var inv *inventory.Inventory
+	err := h.inTx(ctx, func(tx *Tx) error {
+		var err error
+		inv, err = tx.Inventory.ReceiveStock(ctx, receipt)
+		if err != nil {
+			return err
+		}
+		available := inv.AvailableQuantity
+		return tx.publish(ctx, inventory.EventReceived, inventory.EventData{
+			ProductID:    inv.ProductID,
+			StoreID:      inv.StoreID,
+			Quantity:     receipt.Quantity,
+			Available:    &available,
+			ReorderPoint: inv.ReorderPoint,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.RecordStockChange(storeLabel(inv.StoreID), inv.AvailableQuantity-receipt.Quantity, inv.AvailableQuantity, inv.ReorderPoint)
+	return inv, nil
+}
+
+// GetCostLayers handles GET /inventory/:id/cost-layers, valuing the stock on
+// hand first in, first out
+func (h *Handler) GetCostLayers(c *fiber.Ctx) error {
+	inventoryID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid inventory ID",
+		})
+	}
+
+	inv, err := h.inventoryRepo.GetByID(c.UserContext(), inventoryID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Inventory not found",
+		})
+	}
+
+	layers, err := h.inventoryRepo.GetCostLayers(c.UserContext(), inv.ID)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch cost layers: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch cost layers",
+		})
+	}
+
+	return c.JSON(inventory.ValueFIFO(inv.Quantity, layers))
+}
@@ -0,0 +1,406 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/tenant"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// sweepBatchSize is how many expired reservations are released per query
+const sweepBatchSize = 100
+
+// GetReservation handles GET /inventory/reservations/:id
+func (h *Handler) GetReservation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid reservation ID",
+		})
+	}
+
+	res, err := h.reservationRepo.GetReservation(c.UserContext(), id)
+	if err != nil {
+		return h.writeReservationError(c, err, "Failed to fetch reservation")
+	}
+
+	return c.JSON(res)
+}
+
+// CommitReservation handles POST /inventory/reservations/:id/commit,
+// keeping the reservation's stock reserved for its order past its expiry.
+// Committing a reservation again answers with it as it stands.
+func (h *Handler) CommitReservation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid reservation ID",
+		})
+	}
+
+	res, err := h.reservationRepo.CommitReservation(c.UserContext(), id, time.Now())
+	if err != nil {
+		return h.writeReservationError(c, err, "Failed to commit reservation")
+	}
+
+	return c.JSON(res)
+}
+
+// ReleaseReservation handles POST /inventory/reservations/:id/release,
+// making the reservation's stock available again, whether or not it was
+// committed
+func (h *Handler) ReleaseReservation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid reservation ID",
+		})
+	}
+
+	var res *inventory.Reservation
+	err = h.inTx(c.UserContext(), func(tx *Tx) error {
+		var inv *inventory.Inventory
+		var err error
+		res, inv, err = tx.Reservations.ReleaseReservation(c.UserContext(), id, time.Now())
+		if err != nil {
+			return err
+		}
+		return tx.publishReleased(c.UserContext(), res, inv, "")
+	})
+	if err != nil {
+		return h.writeReservationError(c, err, "Failed to release reservation")
+	}
+
+	return c.JSON(res)
+}
+
+// CreateReservation reserves stock through a reservation held until its
+// expiry, recording the change and publishing its events
+func (h *Handler) CreateReservation(ctx context.Context, res *inventory.Reservation) error {
+	var inv *inventory.Inventory
+	err := h.inTx(ctx, func(tx *Tx) error {
+		var err error
+		inv, err = tx.Reservations.CreateReservation(ctx, res)
+		if err != nil {
+			return err
+		}
+		return tx.publishReserved(ctx, []*inventory.Reservation{res}, []*inventory.Inventory{inv})
+	})
+	if err != nil {
+		if errors.Is(err, inventory.ErrInsufficientStock) {
+			metrics.RecordReservationConflict(metrics.ConflictInsufficientStock)
+		}
+		return err
+	}
+
+	recordReserved([]*inventory.Reservation{res}, []*inventory.Inventory{inv})
+	return nil
+}
+
+// ReserveBatch handles POST /inventory/reserve-batch, reserving the stock of
+// every item for as long as the reservation TTL, or none of it. Items that
+// could not be reserved are answered with 409, each with its error. A batch
+// sent again with the same reservation IDs answers with the reservations
+// made for them, rather than reserving twice.
+func (h *Handler) ReserveBatch(c *fiber.Ctx) error {
+	var req ReserveBatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	expiresAt := time.Now().Add(h.reservationTTL)
+	rs := make([]*inventory.Reservation, len(req.Items))
+	for i, item := range req.Items {
+		rs[i] = &inventory.Reservation{
+			ID:            uuid.New(),
+			ProductID:     item.ProductID,
+			StoreID:       item.StoreID,
+			Quantity:      item.Quantity,
+			ReferenceID:   req.ReferenceID,
+			ReferenceType: req.ReferenceType,
+			ExpiresAt:     expiresAt,
+		}
+		if item.ReservationID != nil {
+			rs[i].ID = *item.ReservationID
+		}
+	}
+
+	err := h.CreateReservations(c.UserContext(), rs)
+	var failed inventory.ReservationErrors
+	if errors.As(err, &failed) && h.reservedBefore(failed, len(rs)) {
+		rs, err = h.getReservations(c.UserContext(), rs)
+	}
+	if errors.As(err, &failed) {
+		items := make([]ReserveBatchItemError, 0, len(failed))
+		for i, item := range req.Items {
+			if ferr, ok := failed[i]; ok {
+				code, message := reservationFailure(ferr)
+				items = append(items, ReserveBatchItemError{Index: i, ProductID: item.ProductID, Code: code, Error: message})
+			}
+		}
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Stock could not be reserved",
+			"items": items,
+		})
+	}
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to reserve stock: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reserve stock",
+		})
+	}
+
+	resp := ReserveBatchResponse{Reservations: make([]ReserveStockResponse, len(rs))}
+	for i, res := range rs {
+		resp.Reservations[i] = ReserveStockResponse{
+			Message:       "Stock reserved successfully",
+			ReservationID: res.ID,
+			Status:        res.Status,
+			ExpiresAt:     res.ExpiresAt,
+		}
+	}
+	return c.JSON(resp)
+}
+
+// CreateReservations reserves the stock of a batch of reservations, all or
+// none, publishing the events of each
+func (h *Handler) CreateReservations(ctx context.Context, rs []*inventory.Reservation) error {
+	var stock []*inventory.Inventory
+	err := h.inTx(ctx, func(tx *Tx) error {
+		var err error
+		stock, err = tx.Reservations.CreateReservations(ctx, rs)
+		if err != nil {
+			return err
+		}
+		return tx.publishReserved(ctx, rs, stock)
+	})
+	if err != nil {
+		var failed inventory.ReservationErrors
+		if errors.As(err, &failed) {
+			for _, ferr := range failed {
+				if errors.Is(ferr, inventory.ErrInsufficientStock) {
+					metrics.RecordReservationConflict(metrics.ConflictInsufficientStock)
+				}
+			}
+		}
+		return err
+	}
+
+	recordReserved(rs, stock)
+	return nil
+}
+
+// reservedBefore reports whether every one of n items failed only because
+// its reservation ID was taken, as when a batch is sent again
+func (h *Handler) reservedBefore(failed inventory.ReservationErrors, n int) bool {
+	if len(failed) != n {
+		return false
+	}
+	for _, err := range failed {
+		if !errors.Is(err, inventory.ErrReservationExists) {
+			return false
+		}
+	}
+	return true
+}
+
+// getReservations fetches the reservations with the IDs of rs; IDs taken by
+// another tenant are returned as ReservationErrors
+func (h *Handler) getReservations(ctx context.Context, rs []*inventory.Reservation) ([]*inventory.Reservation, error) {
+	found := make([]*inventory.Reservation, len(rs))
+	failed := inventory.ReservationErrors{}
+	for i, res := range rs {
+		existing, err := h.reservationRepo.GetReservation(ctx, res.ID)
+		if errors.Is(err, inventory.ErrReservationNotFound) {
+			failed[i] = inventory.ErrReservationExists
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found[i] = existing
+	}
+	if len(failed) > 0 {
+		return rs, failed
+	}
+	return found, nil
+}
+
+// publishReserved records the events of reservations made, each with its
+// stock after them. Records reserved by several reservations report low
+// stock once.
+func (tx *Tx) publishReserved(ctx context.Context, rs []*inventory.Reservation, stock []*inventory.Inventory) error {
+	reserved := reservedByRecord(rs, stock)
+	for i, res := range rs {
+		inv := stock[i]
+		available := inv.AvailableQuantity
+		if err := tx.publish(ctx, inventory.EventReserved, inventory.EventData{
+			ProductID:     res.ProductID,
+			StoreID:       res.StoreID,
+			Quantity:      res.Quantity,
+			Available:     &available,
+			ReorderPoint:  inv.ReorderPoint,
+			ReservationID: &res.ID,
+		}); err != nil {
+			return err
+		}
+
+		quantity, ok := reserved[inv.ID]
+		if !ok {
+			continue
+		}
+		delete(reserved, inv.ID)
+		if err := tx.publishLowStock(ctx, inv, inv.AvailableQuantity+quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordReserved records the stock changes of reservations made, once per
+// stock record
+func recordReserved(rs []*inventory.Reservation, stock []*inventory.Inventory) {
+	reserved := reservedByRecord(rs, stock)
+	for _, inv := range stock {
+		quantity, ok := reserved[inv.ID]
+		if !ok {
+			continue
+		}
+		delete(reserved, inv.ID)
+		metrics.RecordStockChange(storeLabel(inv.StoreID), inv.AvailableQuantity+quantity, inv.AvailableQuantity, inv.ReorderPoint)
+	}
+}
+
+// reservedByRecord returns how much reservations made reserved of each
+// stock record
+func reservedByRecord(rs []*inventory.Reservation, stock []*inventory.Inventory) map[uuid.UUID]int {
+	reserved := make(map[uuid.UUID]int)
+	for i, res := range rs {
+		reserved[stock[i].ID] += res.Quantity
+	}
+	return reserved
+}
+
+// RunSweeper releases reservations past their expiry that were neither
+// committed nor released, each in its own tenant, every interval until ctx
+// is cancelled. Instances sweeping at once release each reservation once.
+func (h *Handler) RunSweeper(ctx context.Context, interval time.Duration, log *logger.Logger) {
+	defer apperrors.Recover(ctx, "inventory-reservation-sweeper")
+
+	if interval <= 0 {
+		return
+	}
+
+	pass := func() {
+		for {
+			expired, err := h.reservationRepo.ListExpiredReservations(ctx, time.Now(), sweepBatchSize)
+			if err != nil {
+				log.Errorf("Failed to fetch expired stock reservations: %v", err)
+				return
+			}
+			for _, res := range expired {
+				ctx := tenant.NewContext(ctx, &tenant.Tenant{ID: res.TenantID, Source: tenant.SourceJob})
+				var released *inventory.Reservation
+				err := h.inTx(ctx, func(tx *Tx) error {
+					var inv *inventory.Inventory
+					var err error
+					released, inv, err = tx.Reservations.ExpireReservation(ctx, res.ID, time.Now())
+					if err != nil {
+						return err
+					}
+					return tx.publishReleased(ctx, released, inv, inventory.ReasonReservationExpired)
+				})
+				if errors.Is(err, inventory.ErrReservationClosed) {
+					continue // Committed, released or swept by another instance since listed
+				}
+				if err != nil {
+					log.Warnf("Failed to release expired stock reservation %s: %v", res.ID, err)
+					return
+				}
+				log.Infof("Released %d of product %s held by expired reservation %s", released.Quantity, released.ProductID, released.ID)
+			}
+			if len(expired) < sweepBatchSize {
+				return
+			}
+		}
+	}
+
+	pass()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pass()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// publishReleased records the released event of a reservation's stock
+func (tx *Tx) publishReleased(ctx context.Context, res *inventory.Reservation, inv *inventory.Inventory, reason string) error {
+	available := inv.AvailableQuantity
+	return tx.publish(ctx, inventory.EventReleased, inventory.EventData{
+		ProductID:     res.ProductID,
+		StoreID:       res.StoreID,
+		Quantity:      res.Quantity,
+		Available:     &available,
+		ReorderPoint:  inv.ReorderPoint,
+		Reason:        reason,
+		ReservationID: &res.ID,
+	})
+}
+
+// reservationFailure returns the code and message answering why an item
+// could not be reserved
+func reservationFailure(err error) (string, string) {
+	switch {
+	case errors.Is(err, inventory.ErrInsufficientStock):
+		return "insufficient_stock", "Insufficient stock"
+	case errors.Is(err, inventory.ErrInventoryNotFound):
+		return "inventory_not_found", "Inventory not found"
+	case errors.Is(err, inventory.ErrReservationExists):
+		return "reservation_exists", "Reservation ID is taken"
+	}
+	return "", err.Error()
+}
+
+// writeReservationError answers with the status of a reservation error, or
+// logs it and answers 500 with message
+func (h *Handler) writeReservationError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, inventory.ErrReservationNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Reservation not found",
+		})
+	case errors.Is(err, inventory.ErrReservationClosed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Reservation is no longer held",
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("%s: %v", message, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
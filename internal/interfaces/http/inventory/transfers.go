@@ -0,0 +1,208 @@
+package inventory
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// TransferHandler handles stock transfer HTTP requests
+type TransferHandler struct {
+	transferRepo inventory.TransferRepository
+}
+
+// NewTransferHandler creates a new stock transfer handler
+func NewTransferHandler(transferRepo inventory.TransferRepository) *TransferHandler {
+	return &TransferHandler{transferRepo: transferRepo}
+}
+
+// CreateTransfer handles POST /inventory/transfers
+func (h *TransferHandler) CreateTransfer(c *fiber.Ctx) error {
+	var req CreateTransferRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	t := &inventory.Transfer{
+		ID:                 uuid.New(),
+		SourceStoreID:      req.SourceStoreID,
+		DestinationStoreID: req.DestinationStoreID,
+		Note:               req.Note,
+		RequestedBy:        requestUser(c),
+		Items:              make([]inventory.TransferItem, len(req.Items)),
+	}
+	seen := make(map[uuid.UUID]bool, len(req.Items))
+	for i, item := range req.Items {
+		if seen[item.ProductID] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Each product may appear on a transfer once",
+			})
+		}
+		seen[item.ProductID] = true
+		t.Items[i] = inventory.TransferItem{ProductID: item.ProductID, Quantity: item.Quantity}
+	}
+
+	if err := h.transferRepo.CreateTransfer(c.UserContext(), t); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to create stock transfer: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create stock transfer",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(t)
+}
+
+// GetTransfer handles GET /inventory/transfers/:id
+func (h *TransferHandler) GetTransfer(c *fiber.Ctx) error {
+	transferID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid transfer ID",
+		})
+	}
+
+	t, err := h.transferRepo.GetTransfer(c.UserContext(), transferID)
+	if err != nil {
+		return transferError(c, err, "fetch")
+	}
+	return c.JSON(t)
+}
+
+// ListTransfers handles GET /inventory/transfers, optionally narrowed to the
+// transfers from or to a store and to a status
+func (h *TransferHandler) ListTransfers(c *fiber.Ctx) error {
+	var filter inventory.TransferFilter
+	if storeIDStr := c.Query("store_id"); storeIDStr != "" {
+		id, err := uuid.Parse(storeIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid store ID",
+			})
+		}
+		filter.StoreID = &id
+	}
+	filter.Status = inventory.TransferStatus(c.Query("status"))
+
+	limit := 20
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	transfers, err := h.transferRepo.ListTransfers(c.UserContext(), filter, limit, offset)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch stock transfers: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch stock transfers",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   transfers,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// DispatchTransfer handles POST /inventory/transfers/:id/dispatch, holding
+// the transfer's stock at the source until it is received
+func (h *TransferHandler) DispatchTransfer(c *fiber.Ctx) error {
+	transferID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid transfer ID",
+		})
+	}
+
+	t, err := h.transferRepo.DispatchTransfer(c.UserContext(), transferID, requestUser(c))
+	if err != nil {
+		return transferError(c, err, "dispatch")
+	}
+	return c.JSON(t)
+}
+
+// ReceiveTransfer handles POST /inventory/transfers/:id/receive, moving the
+// transfer's stock from the source to the destination
+func (h *TransferHandler) ReceiveTransfer(c *fiber.Ctx) error {
+	transferID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid transfer ID",
+		})
+	}
+
+	t, err := h.transferRepo.ReceiveTransfer(c.UserContext(), transferID, requestUser(c))
+	if err != nil {
+		return transferError(c, err, "receive")
+	}
+	return c.JSON(t)
+}
+
+// CancelTransfer handles POST /inventory/transfers/:id/cancel
+func (h *TransferHandler) CancelTransfer(c *fiber.Ctx) error {
+	transferID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid transfer ID",
+		})
+	}
+
+	t, err := h.transferRepo.CancelTransfer(c.UserContext(), transferID)
+	if err != nil {
+		return transferError(c, err, "cancel")
+	}
+	return c.JSON(t)
+}
+
+// transferError responds to a failure to act on a transfer
+func transferError(c *fiber.Ctx, err error, action string) error {
+	switch {
+	case errors.Is(err, inventory.ErrTransferNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Stock transfer not found",
+		})
+	case errors.Is(err, inventory.ErrInvalidTransferTransition):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Stock transfer cannot make this change in its current status",
+		})
+	case errors.Is(err, inventory.ErrInsufficientStock):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Insufficient stock at the source store",
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("Failed to %s stock transfer: %v", action, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to " + action + " stock transfer",
+	})
+}
+
+// requestUser returns the user the gateway passed the request on for, if any
+func requestUser(c *fiber.Ctx) *uuid.UUID {
+	userID, err := uuid.Parse(c.Get("X-User-ID"))
+	if err != nil {
+		return nil
+	}
+	return &userID
+}
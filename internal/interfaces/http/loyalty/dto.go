@@ -0,0 +1,38 @@
+package loyalty
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/loyalty"
+)
+
+// RedeemRequest asks to spend a member's points on an order
+type RedeemRequest struct {
+	UserID     uuid.UUID `json:"user_id" validate:"required"`
+	OrderID    uuid.UUID `json:"order_id" validate:"required"`
+	Points     int64     `json:"points" validate:"gt=0"`
+	OrderTotal float64   `json:"order_total" validate:"gte=0"` // Before the discount
+	Currency   string    `json:"currency" validate:"required,len=3"`
+}
+
+// ProgramResponse describes the loyalty program to members
+type ProgramResponse struct {
+	PointsPerUnit      float64        `json:"points_per_unit"`
+	PointValue         float64        `json:"point_value"`
+	MinRedemption      int64          `json:"min_redemption"`
+	MaxRedemptionShare float64        `json:"max_redemption_share"`
+	PointsLifetimeDays int            `json:"points_lifetime_days,omitempty"` // Omitted when points never expire
+	Tiers              []loyalty.Tier `json:"tiers"`
+}
+
+// toProgramResponse describes a program
+func toProgramResponse(p *loyalty.Program) *ProgramResponse {
+	return &ProgramResponse{
+		PointsPerUnit:      p.PointsPerUnit,
+		PointValue:         p.PointValue,
+		MinRedemption:      p.MinRedemption,
+		MaxRedemptionShare: p.MaxRedemptionShare,
+		PointsLifetimeDays: int(p.PointsLifetime.Hours() / 24),
+		Tiers:              p.Tiers,
+	}
+}
@@ -0,0 +1,156 @@
+package loyalty
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+
+	"github.com/onichange/pos-system/internal/domain/loyalty"
+	"github.com/onichange/pos-system/internal/domain/order"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+	"github.com/onichange/pos-system/pkg/metrics"
+)
+
+// HandleOrderEvent applies an order event from the message broker: completed
+// orders earn points, cancelled orders return their redeemed points, and
+// refunded orders return both. Events are applied at most once per order, so
+// redeliveries are harmless. Malformed messages are dropped.
+func (h *Handler) HandleOrderEvent(ctx context.Context, msg amqp.Delivery) error {
+	log := logger.FromContext(ctx)
+
+	var event messagequeue.Event
+	if err := json.Unmarshal(msg.Body, &event); err != nil {
+		log.Errorf("Dropping malformed order event: %v", err)
+		return nil
+	}
+	var data order.EventData
+	raw, _ := json.Marshal(event.Data)
+	if err := json.Unmarshal(raw, &data); err != nil || data.OrderID == uuid.Nil {
+		log.Errorf("Dropping %s event %s without an order", event.Type, event.ID)
+		return nil
+	}
+
+	switch event.Type {
+	case order.EventCompleted:
+		return h.earn(ctx, data)
+	case order.EventCancelled:
+		return h.release(ctx, data)
+	case order.EventRefunded:
+		if err := h.release(ctx, data); err != nil {
+			return err
+		}
+		return h.reverse(ctx, data)
+	}
+	return nil
+}
+
+// earn credits the points a completed order earns at its customer's tier
+func (h *Handler) earn(ctx context.Context, data order.EventData) error {
+	account, err := h.loyaltyRepo.GetAccount(ctx, data.UserID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to fetch loyalty account of %s: %w", data.UserID, err)
+	}
+	points := h.program.PointsFor(data.TotalAmount, account.LifetimePoints)
+	if points <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	tx := &loyalty.Transaction{
+		ID:          uuid.New(),
+		UserID:      data.UserID,
+		Type:        loyalty.TypeEarn,
+		Points:      points,
+		OrderID:     &data.OrderID,
+		Amount:      data.TotalAmount,
+		Currency:    data.Currency,
+		Description: "Order completed",
+		ExpiresAt:   h.program.ExpiresAt(now),
+		CreatedAt:   now,
+	}
+	earned, err := h.loyaltyRepo.Earn(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("failed to credit points of order %s: %w", data.OrderID, err)
+	}
+	if earned {
+		metrics.LoyaltyPoints.WithLabelValues(string(loyalty.TypeEarn)).Add(float64(points))
+		logger.FromContext(ctx).Infof("Credited %d loyalty points to %s for order %s", points, data.UserID, data.OrderID)
+	}
+	return nil
+}
+
+// release returns the points redeemed for an order
+func (h *Handler) release(ctx context.Context, data order.EventData) error {
+	if data.LoyaltyPoints == 0 {
+		return nil
+	}
+	released, err := h.loyaltyRepo.ReleaseRedemption(ctx, data.OrderID, h.program.ExpiresAt(time.Now()))
+	if err != nil {
+		return fmt.Errorf("failed to release redemption of order %s: %w", data.OrderID, err)
+	}
+	if released {
+		metrics.LoyaltyPoints.WithLabelValues(string(loyalty.TypeRelease)).Add(float64(data.LoyaltyPoints))
+	}
+	return nil
+}
+
+// reverse takes back the points a refunded order earned
+func (h *Handler) reverse(ctx context.Context, data order.EventData) error {
+	reversed, err := h.loyaltyRepo.ReverseEarn(ctx, data.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to reverse points of order %s: %w", data.OrderID, err)
+	}
+	if reversed {
+		logger.FromContext(ctx).Infof("Reversed loyalty points of refunded order %s", data.OrderID)
+	}
+	return nil
+}
+
+// RunExpiry expires points past their lifetime every interval until ctx is
+// cancelled. An interval of zero disables it.
+func (h *Handler) RunExpiry(ctx context.Context, interval time.Duration, batchSize int, log *logger.Logger) {
+	defer apperrors.Recover(ctx, "loyalty-expiry")
+
+	if interval <= 0 {
+		return
+	}
+
+	pass := func() {
+		var total int64
+		for {
+			credits, points, err := h.loyaltyRepo.ExpirePoints(ctx, time.Now(), batchSize)
+			if err != nil {
+				log.Errorf("Failed to expire loyalty points: %v", err)
+				break
+			}
+			total += points
+			if credits < batchSize || ctx.Err() != nil {
+				break
+			}
+		}
+		if total > 0 {
+			metrics.LoyaltyPoints.WithLabelValues(string(loyalty.TypeExpire)).Add(float64(total))
+			log.Infof("Expired %d loyalty points", total)
+		}
+	}
+
+	pass()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pass()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package loyalty
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/loyalty"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// Handler handles loyalty HTTP requests and order events
+type Handler struct {
+	loyaltyRepo loyalty.Repository
+	program     *loyalty.Program
+}
+
+// NewHandler creates a new loyalty handler running the program in cfg
+func NewHandler(loyaltyRepo loyalty.Repository, cfg config.LoyaltyConfig) *Handler {
+	return &Handler{
+		loyaltyRepo: loyaltyRepo,
+		program:     newProgram(cfg),
+	}
+}
+
+// newProgram returns the loyalty program configured by cfg
+func newProgram(cfg config.LoyaltyConfig) *loyalty.Program {
+	tiers := make([]loyalty.Tier, len(cfg.Tiers))
+	for i, t := range cfg.Tiers {
+		tiers[i] = loyalty.Tier{Name: t.Name, MinPoints: t.MinPoints, Multiplier: t.Multiplier}
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinPoints < tiers[j].MinPoints })
+
+	return &loyalty.Program{
+		PointsPerUnit:      cfg.PointsPerUnit,
+		PointValue:         cfg.PointValue,
+		MinRedemption:      cfg.MinRedemption,
+		MaxRedemptionShare: cfg.MaxRedemptionShare,
+		PointsLifetime:     cfg.PointsLifetime,
+		ExpiryNotice:       cfg.ExpiryNotice,
+		Tiers:              tiers,
+	}
+}
+
+// GetProgram handles GET /loyalty/program
+func (h *Handler) GetProgram(c *fiber.Ctx) error {
+	return c.JSON(toProgramResponse(h.program))
+}
+
+// GetAccount handles GET /loyalty/account
+func (h *Handler) GetAccount(c *fiber.Ctx) error {
+	userID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	account, err := h.loyaltyRepo.GetAccount(c.UserContext(), userID, time.Now().Add(h.program.ExpiryNotice))
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch loyalty account: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch loyalty account",
+		})
+	}
+	h.program.Describe(account)
+
+	return c.JSON(account)
+}
+
+// GetTransactions handles GET /loyalty/transactions
+func (h *Handler) GetTransactions(c *fiber.Ctx) error {
+	userID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse pagination
+	limit := 20
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	transactions, err := h.loyaltyRepo.GetTransactions(c.UserContext(), userID, limit, offset)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch loyalty transactions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch loyalty transactions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   transactions,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// Redeem handles POST /redemptions, called by the order service while it
+// creates an order
+func (h *Handler) Redeem(c *fiber.Ctx) error {
+	var req RedeemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	if err := h.program.CheckRedemption(req.Points, req.OrderTotal); err != nil {
+		return redemptionError(c, err)
+	}
+
+	redemption := &loyalty.Redemption{
+		OrderID:  req.OrderID,
+		UserID:   req.UserID,
+		Points:   req.Points,
+		Amount:   h.program.Value(req.Points),
+		Currency: req.Currency,
+	}
+	if err := h.loyaltyRepo.Redeem(c.UserContext(), redemption); err != nil {
+		return redemptionError(c, err)
+	}
+	metrics.LoyaltyPoints.WithLabelValues(string(loyalty.TypeRedeem)).Add(float64(redemption.Points))
+
+	return c.Status(fiber.StatusCreated).JSON(redemption)
+}
+
+// ReleaseRedemption handles DELETE /redemptions/:orderId, returning the
+// points redeemed for an order that was not placed or was cancelled
+func (h *Handler) ReleaseRedemption(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("orderId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	if _, err := h.loyaltyRepo.ReleaseRedemption(c.UserContext(), orderID, h.program.ExpiresAt(time.Now())); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to release redemption of order %s: %v", orderID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to release redemption",
+		})
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// redemptionError answers a redemption that failed. Refusals carry the
+// loyalty error's message, which the order service maps back to the error.
+func redemptionError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, loyalty.ErrAlreadyRedeemed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, loyalty.ErrInsufficientPoints),
+		errors.Is(err, loyalty.ErrBelowMinimum),
+		errors.Is(err, loyalty.ErrExceedsLimit):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("Failed to redeem loyalty points: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to redeem loyalty points",
+	})
+}
+
+// currentUser returns the ID of the authenticated member. The error is a
+// *fiber.Error for the app's error handler to answer with.
+func currentUser(c *fiber.Ctx) (uuid.UUID, error) {
+	userIDStr, _ := c.Locals("user_id").(string)
+	if userIDStr == "" {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+	return userID, nil
+}
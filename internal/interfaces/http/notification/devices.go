@@ -0,0 +1,115 @@
+package notification
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/notification"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// RegisterDevice handles POST /devices, registering the caller's app install
+// to receive push notifications. Registering a token again refreshes it.
+func (h *Handler) RegisterDevice(c *fiber.Ctx) error {
+	userID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	var req RegisterDeviceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	t := &notification.DeviceToken{
+		ID:         uuid.New(),
+		UserID:     userID,
+		Provider:   req.Provider,
+		Token:      req.Token,
+		DeviceName: req.DeviceName,
+		AppVersion: req.AppVersion,
+	}
+	if err := h.deviceRepo.RegisterDeviceToken(c.UserContext(), t); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to register device: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to register device",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(t)
+}
+
+// GetDevices handles GET /devices, listing the caller's registered devices
+func (h *Handler) GetDevices(c *fiber.Ctx) error {
+	userID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := h.deviceRepo.ListDeviceTokens(c.UserContext(), userID)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch devices: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch devices",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": tokens,
+	})
+}
+
+// DeleteDevice handles DELETE /devices/:id, such as when signing out of the
+// app
+func (h *Handler) DeleteDevice(c *fiber.Ctx) error {
+	userID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid device ID",
+		})
+	}
+
+	if err := h.deviceRepo.DeleteDeviceToken(c.UserContext(), deviceID, userID); err != nil {
+		if errors.Is(err, notification.ErrDeviceTokenNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Device not found",
+			})
+		}
+		logger.FromContext(c.UserContext()).Errorf("Failed to delete device: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete device",
+		})
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// currentUser returns the authenticated user's ID
+func currentUser(c *fiber.Ctx) (uuid.UUID, error) {
+	userIDStr, _ := c.Locals("user_id").(string)
+	if userIDStr == "" {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+	return userID, nil
+}
@@ -0,0 +1,148 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/onichange/pos-system/internal/domain/notification"
+	"github.com/onichange/pos-system/internal/domain/webhook"
+	"github.com/onichange/pos-system/pkg/config"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+var (
+	// errPermanent marks failures that no retry can fix
+	errPermanent = errors.New("permanent failure")
+	// errDeferred marks sends held back, such as by a rate limit, which are
+	// tried again after the base backoff without using up an attempt
+	errDeferred = errors.New("deferred")
+)
+
+// deliverFunc sends a notification on one channel, noting on the delivery
+// where it went. Failures wrapping errPermanent are not retried; those
+// wrapping errDeferred are retried without counting as an attempt.
+type deliverFunc func(ctx context.Context, d *notification.Delivery, n *notification.Notification) error
+
+// deliveredChannels returns the channels of n sent outside the app
+func (h *Handler) deliveredChannels(n *notification.Notification) []notification.Channel {
+	deliver := []notification.Channel{}
+	for _, ch := range n.Channels {
+		if _, _, _, ok := h.channelDispatch(ch); ok {
+			deliver = append(deliver, ch)
+		}
+	}
+	return deliver
+}
+
+// channelDispatch returns how deliveries on ch are dispatched and sent, or
+// false when ch is not sent outside the app
+func (h *Handler) channelDispatch(ch notification.Channel) (config.DispatchConfig, time.Duration, deliverFunc, bool) {
+	switch {
+	case ch == notification.ChannelEmail && h.sender != nil:
+		return h.emailCfg.DispatchConfig, h.emailCfg.Timeout, h.deliverEmail, true
+	case ch == notification.ChannelPush && len(h.pushProviders) > 0:
+		return h.pushCfg.DispatchConfig, h.pushCfg.Timeout, h.deliverPush, true
+	case ch == notification.ChannelSMS && h.smsProvider != nil:
+		return h.smsCfg.DispatchConfig, h.smsCfg.Timeout, h.deliverSMS, true
+	}
+	return config.DispatchConfig{}, 0, nil, false
+}
+
+// runDispatcher sends due deliveries on channel every dispatch interval until
+// ctx is cancelled. Each pass claims a batch across tenants, sends it
+// concurrently and records every attempt in the delivery's tenant; a failed
+// send is retried after an exponential backoff until it runs out of
+// attempts, unless it failed for good. A send taking up to timeout keeps its
+// delivery from other instances.
+func (h *Handler) runDispatcher(ctx context.Context, log *logger.Logger, channel notification.Channel, cfg config.DispatchConfig, timeout time.Duration, deliver deliverFunc) {
+	defer apperrors.Recover(ctx, string(channel)+"-dispatcher")
+
+	// Claimed deliveries are held off other instances for longer than a send
+	// can take
+	lease := timeout + time.Minute
+
+	pass := func() {
+		due, err := h.notificationRepo.ClaimDue(ctx, channel, time.Now(), lease, cfg.BatchSize)
+		if err != nil {
+			log.Errorf("Failed to claim %s deliveries: %v", channel, err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, d := range due {
+			wg.Add(1)
+			go func(d *notification.DueDelivery) {
+				defer wg.Done()
+				ctx := tenant.NewContext(ctx, &tenant.Tenant{ID: d.TenantID, Source: tenant.SourceJob})
+				if err := h.attempt(ctx, d, cfg, deliver); err != nil {
+					log.Errorf("Failed to record %s delivery %s: %v", channel, d.Delivery.ID, err)
+				}
+			}(d)
+		}
+		wg.Wait()
+	}
+
+	pass()
+
+	ticker := time.NewTicker(cfg.DispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pass()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// attempt sends a claimed delivery and records the outcome
+func (h *Handler) attempt(ctx context.Context, due *notification.DueDelivery, cfg config.DispatchConfig, deliver deliverFunc) error {
+	d, n := due.Delivery, due.Notification
+
+	now := time.Now().UTC()
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.NextAttemptAt = nil
+
+	var err error
+	if n.IsExpired() {
+		err = fmt.Errorf("%w: notification expired", errPermanent)
+	} else {
+		err = deliver(ctx, d, n)
+	}
+	result := "failure"
+	switch {
+	case err == nil:
+		result = "success"
+		d.Status = notification.DeliverySent
+		d.SentAt = &now
+		d.LastError = ""
+	case errors.Is(err, errDeferred):
+		result = "deferred"
+		d.Attempts--
+		d.Status = notification.DeliveryPending
+		d.LastError = err.Error()
+		retryAt := now.Add(cfg.BackoffBase)
+		d.NextAttemptAt = &retryAt
+	case errors.Is(err, errPermanent), d.Attempts >= cfg.MaxAttempts:
+		d.Status = notification.DeliveryFailed
+		d.LastError = err.Error()
+	default:
+		d.Status = notification.DeliveryPending
+		d.LastError = err.Error()
+		retryAt := now.Add(webhook.Backoff(d.Attempts, cfg.BackoffBase, cfg.BackoffMax))
+		d.NextAttemptAt = &retryAt
+	}
+
+	metrics.NotificationDeliveries.WithLabelValues(string(d.Channel), result).Inc()
+
+	return h.notificationRepo.RecordAttempt(ctx, d)
+}
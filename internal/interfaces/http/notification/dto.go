@@ -18,6 +18,14 @@ type CreateNotificationRequest struct {
 	ExpiresAt *string                `json:"expires_at,omitempty"`
 }
 
+// RegisterDeviceRequest represents register device request
+type RegisterDeviceRequest struct {
+	Provider   string `json:"provider" validate:"required,oneof=fcm apns"`
+	Token      string `json:"token" validate:"required,max=4096"`
+	DeviceName string `json:"device_name,omitempty" validate:"max=255"`
+	AppVersion string `json:"app_version,omitempty" validate:"max=50"`
+}
+
 // NotificationResponse represents notification response
 type NotificationResponse struct {
 	ID        uuid.UUID              `json:"id"`
@@ -0,0 +1,90 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/notification"
+	"github.com/onichange/pos-system/internal/domain/user"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/email"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// UserDirectory looks up the recipients of notifications, as userclient does
+type UserDirectory interface {
+	GetUser(ctx context.Context, id uuid.UUID) (*user.User, error)
+}
+
+// EnableEmail sends notifications on the email channel with sender, rendered
+// by templates, to the address users has for their recipient. Without it,
+// email notifications are kept but never sent.
+//
+// A notification's data picks the template with its "template" key, and may
+// send to another address with its "email" key; otherwise the notification's
+// type names the template.
+func (h *Handler) EnableEmail(sender email.Sender, templates *email.Templates, users UserDirectory, cfg config.EmailConfig) {
+	h.sender = sender
+	h.templates = templates
+	h.users = users
+	h.emailCfg = cfg
+}
+
+// RunEmailDispatcher sends due email deliveries until ctx is cancelled; see
+// runDispatcher
+func (h *Handler) RunEmailDispatcher(ctx context.Context, log *logger.Logger) {
+	h.runDispatcher(ctx, log, notification.ChannelEmail, h.emailCfg.DispatchConfig, h.emailCfg.Timeout, h.deliverEmail)
+}
+
+// deliverEmail sends n to its recipient's address
+func (h *Handler) deliverEmail(ctx context.Context, d *notification.Delivery, n *notification.Notification) error {
+	data := &email.TemplateData{
+		Title:    n.Title,
+		Message:  n.Message,
+		Type:     string(n.Type),
+		Priority: string(n.Priority),
+		Data:     n.Data,
+	}
+	msg := &email.Message{ID: d.ID.String()}
+	if address, ok := n.Data["email"].(string); ok && address != "" {
+		msg.To = address
+	} else {
+		u, err := h.users.GetUser(ctx, n.UserID)
+		if errors.Is(err, user.ErrNotFound) {
+			return fmt.Errorf("%w: recipient not found", errPermanent)
+		}
+		if err != nil {
+			return err
+		}
+		if u.Email == "" {
+			return fmt.Errorf("%w: recipient has no email address", errPermanent)
+		}
+		msg.To = u.Email
+		msg.ToName = strings.TrimSpace(u.FirstName + " " + u.LastName)
+		data.RecipientName = u.FirstName
+	}
+	d.Recipient = msg.To
+
+	name := string(n.Type)
+	if template, ok := n.Data["template"].(string); ok && template != "" {
+		name = template
+	}
+	subject, html, err := h.templates.Render(name, data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	msg.Subject = subject
+	msg.HTML = html
+
+	if err := h.sender.Send(ctx, msg); err != nil {
+		if errors.Is(err, email.ErrRejected) {
+			return fmt.Errorf("%w: %v", errPermanent, err)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+
+	"github.com/onichange/pos-system/internal/domain/notification"
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+)
+
+// HandleEvent handles an event from the message broker: it creates the
+// notification a notification.requested event asks for, and relays order
+// events to the displays of the order's store. Malformed events and other
+// event types are dropped.
+func (h *Handler) HandleEvent(ctx context.Context, msg amqp.Delivery) error {
+	log := logger.FromContext(ctx)
+
+	var event messagequeue.Event
+	if err := json.Unmarshal(msg.Body, &event); err != nil {
+		log.Errorf("Dropping malformed event: %v", err)
+		return nil
+	}
+	switch event.Type {
+	case notification.EventRequested:
+		return h.handleRequested(ctx, &event)
+	case order.EventCreated, order.EventUpdated, order.EventCancelled, order.EventCompleted, order.EventRefunded:
+		return h.relayOrder(ctx, &event)
+	default:
+		return nil
+	}
+}
+
+// handleRequested creates the notification a notification.requested event
+// asks for, and sends it on each of its channels outside the app at once and
+// in parallel. Sends that fail are recorded on their delivery and retried by
+// the channel's dispatcher; a failure to create or record them fails the
+// event, which the broker retries.
+func (h *Handler) handleRequested(ctx context.Context, event *messagequeue.Event) error {
+	var data notification.RequestedData
+	raw, _ := json.Marshal(event.Data)
+	if err := json.Unmarshal(raw, &data); err != nil || data.UserID == uuid.Nil || data.Title == "" || data.Message == "" {
+		logger.FromContext(ctx).Errorf("Dropping %s event %s without a recipient, title and message", event.Type, event.ID)
+		return nil
+	}
+
+	n := &notification.Notification{
+		ID:        data.ID,
+		UserID:    data.UserID,
+		Type:      data.Type,
+		Title:     data.Title,
+		Message:   data.Message,
+		Data:      data.Data,
+		Channels:  data.Channels,
+		Priority:  data.Priority,
+		ExpiresAt: data.ExpiresAt,
+	}
+	if n.ID == uuid.Nil {
+		n.ID = uuid.NewSHA1(uuid.NameSpaceOID, []byte(event.ID))
+	}
+	if n.Type == "" {
+		n.Type = notification.TypeSystem
+	}
+	if len(n.Channels) == 0 {
+		n.Channels = []notification.Channel{notification.ChannelInApp}
+	}
+	if n.Priority == "" {
+		n.Priority = notification.PriorityNormal
+	}
+
+	if err := h.notificationRepo.Create(ctx, n, h.deliveredChannels(n)); err != nil {
+		return fmt.Errorf("failed to create notification %s: %w", n.ID, err)
+	}
+	h.pushInApp(ctx, n)
+	return h.sendNow(ctx, n)
+}
+
+// sendNow attempts the pending deliveries of n in parallel
+func (h *Handler) sendNow(ctx context.Context, n *notification.Notification) error {
+	// Claimed deliveries are held off the dispatchers for longer than the
+	// slowest channel's send can take
+	lease := time.Duration(0)
+	for _, ch := range n.Channels {
+		if _, timeout, _, ok := h.channelDispatch(ch); ok {
+			lease = max(lease, timeout+time.Minute)
+		}
+	}
+	if lease == 0 {
+		return nil
+	}
+
+	due, err := h.notificationRepo.ClaimPending(ctx, n.ID, time.Now(), lease)
+	if err != nil {
+		return fmt.Errorf("failed to claim deliveries of notification %s: %w", n.ID, err)
+	}
+
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for _, d := range due {
+		cfg, _, deliver, ok := h.channelDispatch(d.Delivery.Channel)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(d *notification.DueDelivery) {
+			defer wg.Done()
+			if err := h.attempt(ctx, d, cfg, deliver); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("failed to record %s delivery %s: %w", d.Delivery.Channel, d.Delivery.ID, err))
+				mu.Unlock()
+			}
+		}(d)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
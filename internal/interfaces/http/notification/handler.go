@@ -8,18 +8,38 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/onichange/pos-system/internal/domain/notification"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/email"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/push"
+	"github.com/onichange/pos-system/pkg/sms"
 	"github.com/onichange/pos-system/pkg/validator"
+	"github.com/onichange/pos-system/pkg/websocket"
 )
 
 // Handler handles notification HTTP requests
 type Handler struct {
 	notificationRepo notification.Repository
+	deviceRepo       notification.DeviceTokenRepository
+	sender           email.Sender
+	templates        *email.Templates
+	users            UserDirectory
+	emailCfg         config.EmailConfig
+	pushProviders    map[string]push.Provider
+	pushCfg          config.PushConfig
+	smsProvider      sms.Provider
+	smsReceipts      sms.ReceiptParser
+	smsLimiter       *performance.TokenBucket
+	smsCfg           config.SMSConfig
+	hub              *websocket.Hub
 }
 
 // NewHandler creates a new notification handler
-func NewHandler(notificationRepo notification.Repository) *Handler {
+func NewHandler(notificationRepo notification.Repository, deviceRepo notification.DeviceTokenRepository) *Handler {
 	return &Handler{
 		notificationRepo: notificationRepo,
+		deviceRepo:       deviceRepo,
 	}
 }
 
@@ -57,8 +77,9 @@ func (h *Handler) GetNotifications(c *fiber.Ctx) error {
 		unreadOnly = true
 	}
 
-	notifications, err := h.notificationRepo.GetByUserID(c.Context(), userID, limit, offset, unreadOnly)
+	notifications, err := h.notificationRepo.GetByUserID(c.UserContext(), userID, limit, offset, unreadOnly)
 	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch notifications: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch notifications",
 		})
@@ -99,7 +120,7 @@ func (h *Handler) GetNotification(c *fiber.Ctx) error {
 		})
 	}
 
-	n, err := h.notificationRepo.GetByID(c.Context(), notificationID)
+	n, err := h.notificationRepo.GetByID(c.UserContext(), notificationID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Notification not found",
@@ -116,6 +137,55 @@ func (h *Handler) GetNotification(c *fiber.Ctx) error {
 	return c.JSON(ToResponse(n))
 }
 
+// GetDeliveries handles GET /notifications/:id/deliveries, answering how far
+// the notification got on each channel sent outside the app
+func (h *Handler) GetDeliveries(c *fiber.Ctx) error {
+	userIDStr := c.Locals("user_id")
+	if userIDStr == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	notificationID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid notification ID",
+		})
+	}
+
+	n, err := h.notificationRepo.GetByID(c.UserContext(), notificationID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Notification not found",
+		})
+	}
+	if n.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	deliveries, err := h.notificationRepo.ListDeliveries(c.UserContext(), notificationID)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch notification deliveries: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch notification deliveries",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": deliveries,
+	})
+}
+
 // CreateNotification handles POST /notifications
 func (h *Handler) CreateNotification(c *fiber.Ctx) error {
 	var req CreateNotificationRequest
@@ -169,11 +239,13 @@ func (h *Handler) CreateNotification(c *fiber.Ctx) error {
 		IsRead:    false,
 	}
 
-	if err := h.notificationRepo.Create(c.Context(), n); err != nil {
+	if err := h.notificationRepo.Create(c.UserContext(), n, h.deliveredChannels(n)); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to create notification: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create notification",
 		})
 	}
+	h.pushInApp(c.UserContext(), n)
 
 	return c.Status(fiber.StatusCreated).JSON(ToResponse(n))
 }
@@ -201,7 +273,8 @@ func (h *Handler) MarkAsRead(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.notificationRepo.MarkAsRead(c.Context(), notificationID, userID); err != nil {
+	if err := h.notificationRepo.MarkAsRead(c.UserContext(), notificationID, userID); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to mark notification as read: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to mark notification as read",
 		})
@@ -228,7 +301,8 @@ func (h *Handler) MarkAllAsRead(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.notificationRepo.MarkAllAsRead(c.Context(), userID); err != nil {
+	if err := h.notificationRepo.MarkAllAsRead(c.UserContext(), userID); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to mark all notifications as read: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to mark all notifications as read",
 		})
@@ -262,7 +336,8 @@ func (h *Handler) DeleteNotification(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.notificationRepo.Delete(c.Context(), notificationID, userID); err != nil {
+	if err := h.notificationRepo.Delete(c.UserContext(), notificationID, userID); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to delete notification: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete notification",
 		})
@@ -287,8 +362,9 @@ func (h *Handler) GetUnreadCount(c *fiber.Ctx) error {
 		})
 	}
 
-	count, err := h.notificationRepo.CountUnread(c.Context(), userID)
+	count, err := h.notificationRepo.CountUnread(c.UserContext(), userID)
 	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to count unread notifications: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to count unread notifications",
 		})
@@ -0,0 +1,84 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+	"github.com/onichange/pos-system/pkg/tenant"
+	"github.com/onichange/pos-system/pkg/websocket"
+)
+
+// MessageOrder is the type of WebSocket messages carrying an order event to
+// the displays of the order's store
+const MessageOrder = "order"
+
+// storeDisplayRoles may subscribe to the orders of a store
+var storeDisplayRoles = []string{"admin", "staff"}
+
+// OrderUpdate is the data of an order message
+type OrderUpdate struct {
+	Event string          `json:"event"` // order.created, order.updated, ...
+	Order order.EventData `json:"order"`
+}
+
+// StoreOrdersChannel returns the WebSocket channel of the orders of a store
+func StoreOrdersChannel(storeID uuid.UUID) string {
+	return "store:" + storeID.String() + ":orders"
+}
+
+// canSubscribe lets staff subscribe to the orders of their tenant's stores;
+// the hub keeps each tenant's channels apart
+func canSubscribe(c *websocket.Client, channel string) bool {
+	parts := strings.Split(channel, ":")
+	if len(parts) != 3 || parts[0] != "store" || parts[2] != "orders" {
+		return false
+	}
+	if _, err := uuid.Parse(parts[1]); err != nil {
+		return false
+	}
+	for _, role := range c.Roles {
+		for _, allowed := range storeDisplayRoles {
+			if role == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// relayOrder sends an order event to the subscribers of the order's store
+func (h *Handler) relayOrder(ctx context.Context, event *messagequeue.Event) error {
+	if h.hub == nil {
+		return nil
+	}
+
+	var data order.EventData
+	raw, _ := json.Marshal(event.Data)
+	if err := json.Unmarshal(raw, &data); err != nil || data.OrderID == uuid.Nil || data.StoreID == uuid.Nil {
+		logger.FromContext(ctx).Errorf("Dropping %s event %s without an order and store", event.Type, event.ID)
+		return nil
+	}
+	tenantID := data.TenantID
+	if tenantID == "" {
+		tenantID = tenant.IDFromContext(ctx)
+	}
+
+	err := h.hub.PublishToChannel(&websocket.Message{
+		Type:      MessageOrder,
+		TenantID:  tenantID,
+		Channel:   StoreOrdersChannel(data.StoreID),
+		Data:      OrderUpdate{Event: event.Type, Order: data},
+		Timestamp: event.Timestamp.Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to relay %s event %s: %w", event.Type, event.ID, err)
+	}
+	return nil
+}
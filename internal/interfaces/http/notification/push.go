@@ -0,0 +1,106 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/notification"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/push"
+)
+
+// EnablePush sends notifications on the push channel through providers, by
+// name, to every device token their recipient registered with one of them.
+// Without it, push notifications are kept but never sent.
+func (h *Handler) EnablePush(providers map[string]push.Provider, cfg config.PushConfig) {
+	h.pushProviders = providers
+	h.pushCfg = cfg
+}
+
+// RunPushDispatcher sends due push deliveries until ctx is cancelled; see
+// runDispatcher
+func (h *Handler) RunPushDispatcher(ctx context.Context, log *logger.Logger) {
+	h.runDispatcher(ctx, log, notification.ChannelPush, h.pushCfg.DispatchConfig, h.pushCfg.Timeout, h.deliverPush)
+}
+
+// deliverPush sends n to every device of its recipient. It succeeds once
+// any device accepts it, so a retry never reaches a device twice. Tokens the
+// provider no longer accepts are deleted.
+func (h *Handler) deliverPush(ctx context.Context, d *notification.Delivery, n *notification.Notification) error {
+	tokens, err := h.deviceRepo.ListDeviceTokens(ctx, n.UserID)
+	if err != nil {
+		return err
+	}
+
+	msg := &push.Message{
+		ID:     n.ID.String(),
+		Title:  n.Title,
+		Body:   n.Message,
+		Data:   pushData(n),
+		Urgent: n.Priority == notification.PriorityHigh || n.Priority == notification.PriorityUrgent,
+	}
+
+	sent := 0
+	var invalid []uuid.UUID
+	var failures, transient []error
+	for _, t := range tokens {
+		provider, ok := h.pushProviders[t.Provider]
+		if !ok {
+			continue
+		}
+		err := provider.Send(ctx, t.Token, msg)
+		switch {
+		case err == nil:
+			sent++
+		case errors.Is(err, push.ErrInvalidToken):
+			invalid = append(invalid, t.ID)
+		case errors.Is(err, push.ErrRejected):
+			failures = append(failures, err)
+		default:
+			transient = append(transient, err)
+		}
+	}
+	d.Recipient = fmt.Sprintf("%d of %d devices", sent, len(tokens))
+
+	if len(invalid) > 0 {
+		if err := h.deviceRepo.DeleteDeviceTokens(ctx, invalid); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to delete %d invalid device tokens: %v", len(invalid), err)
+		}
+	}
+
+	switch {
+	case sent > 0:
+		return nil
+	case len(transient) > 0:
+		return errors.Join(transient...)
+	case len(failures) > 0:
+		return fmt.Errorf("%w: %v", errPermanent, errors.Join(failures...))
+	}
+	return fmt.Errorf("%w: recipient has no registered devices", errPermanent)
+}
+
+// pushData returns the data a push message hands the app: the
+// notification's, strings kept and other values as JSON, with its ID and
+// type
+func pushData(n *notification.Notification) map[string]string {
+	data := make(map[string]string, len(n.Data)+2)
+	for key, value := range n.Data {
+		if s, ok := value.(string); ok {
+			data[key] = s
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		data[key] = string(encoded)
+	}
+	data["notification_id"] = n.ID.String()
+	data["type"] = string(n.Type)
+	return data
+}
@@ -0,0 +1,48 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/onichange/pos-system/internal/domain/notification"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/websocket"
+)
+
+// MessageNotification is the type of WebSocket messages carrying a new
+// in-app notification
+const MessageNotification = "notification"
+
+// EnableRealtime pushes in-app notifications to their recipient's WebSocket
+// connections on hub as they are created, and order events to the staff
+// subscribed to their store's channel. Without it, apps see them when they
+// next fetch notifications or orders.
+func (h *Handler) EnableRealtime(hub *websocket.Hub) {
+	hub.Authorize(canSubscribe)
+	h.hub = hub
+}
+
+// pushInApp sends n to its recipient's open connections when it is shown in
+// the app. A failed push is only logged; the notification stays unread.
+func (h *Handler) pushInApp(ctx context.Context, n *notification.Notification) {
+	if h.hub == nil || !hasChannel(n, notification.ChannelInApp) {
+		return
+	}
+	err := h.hub.PublishToUser(&websocket.Message{
+		Type:   MessageNotification,
+		UserID: n.UserID.String(),
+		Data:   ToResponse(n),
+	})
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Failed to push notification %s: %v", n.ID, err)
+	}
+}
+
+// hasChannel reports whether n is shown on ch
+func hasChannel(n *notification.Notification, ch notification.Channel) bool {
+	for _, c := range n.Channels {
+		if c == ch {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/notification"
+	"github.com/onichange/pos-system/internal/domain/user"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/sms"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// EnableSMS sends notifications on the sms channel through provider to the
+// phone number users has for their recipient, and records the receipts it
+// reports back when it can. Sends to a user beyond cfg.PerUserLimit per
+// window, or beyond limiter across users, wait their turn. Without it, SMS
+// notifications are kept but never sent.
+//
+// A notification's data may send to another number with its "phone" key.
+func (h *Handler) EnableSMS(provider sms.Provider, limiter *performance.TokenBucket, users UserDirectory, cfg config.SMSConfig) {
+	h.smsProvider = provider
+	h.smsReceipts, _ = provider.(sms.ReceiptParser)
+	h.smsLimiter = limiter
+	h.users = users
+	h.smsCfg = cfg
+}
+
+// RunSMSDispatcher sends due SMS deliveries until ctx is cancelled; see
+// runDispatcher
+func (h *Handler) RunSMSDispatcher(ctx context.Context, log *logger.Logger) {
+	h.runDispatcher(ctx, log, notification.ChannelSMS, h.smsCfg.DispatchConfig, h.smsCfg.Timeout, h.deliverSMS)
+}
+
+// deliverSMS texts n to its recipient's phone number, unless they were sent
+// their share of text messages already
+func (h *Handler) deliverSMS(ctx context.Context, d *notification.Delivery, n *notification.Notification) error {
+	if h.smsCfg.PerUserLimit > 0 {
+		sent, err := h.notificationRepo.CountSent(ctx, notification.ChannelSMS, n.UserID, time.Now().Add(-h.smsCfg.PerUserWindow))
+		if err != nil {
+			return err
+		}
+		if sent >= h.smsCfg.PerUserLimit {
+			return fmt.Errorf("%w: recipient was sent %d text messages in the last %s", errDeferred, sent, h.smsCfg.PerUserWindow)
+		}
+	}
+
+	msg := &sms.Message{Body: n.Message}
+	if n.Title != "" {
+		msg.Body = n.Title + "\n" + n.Message
+	}
+	if phone, ok := n.Data["phone"].(string); ok && phone != "" {
+		msg.To = phone
+	} else {
+		u, err := h.users.GetUser(ctx, n.UserID)
+		if errors.Is(err, user.ErrNotFound) {
+			return fmt.Errorf("%w: recipient not found", errPermanent)
+		}
+		if err != nil {
+			return err
+		}
+		if u.Phone == "" {
+			return fmt.Errorf("%w: recipient has no phone number", errPermanent)
+		}
+		msg.To = u.Phone
+	}
+	d.Recipient = msg.To
+
+	if h.smsReceipts != nil && h.smsCfg.StatusCallbackURL != "" {
+		tenantID, err := tenant.Scope(ctx)
+		if err != nil {
+			return err
+		}
+		msg.StatusCallback = statusCallbackURL(h.smsCfg.StatusCallbackURL, tenantID, d.ID)
+	}
+
+	err := h.smsLimiter.Do(ctx, func() error {
+		messageID, err := h.smsProvider.Send(ctx, msg)
+		d.ProviderMessageID = messageID
+		return err
+	})
+	switch {
+	case errors.Is(err, performance.ErrRateLimited):
+		return fmt.Errorf("%w: %v", errDeferred, err)
+	case errors.Is(err, sms.ErrRejected):
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	return err
+}
+
+// statusCallbackURL returns where the receipts of a delivery are posted: the
+// configured URL naming the delivery and its tenant
+func statusCallbackURL(base string, tenantID string, deliveryID uuid.UUID) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base
+	}
+	query := u.Query()
+	query.Set("tenant_id", tenantID)
+	query.Set("delivery_id", deliveryID.String())
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// HandleSMSStatus handles POST /sms/status, where the SMS provider reports
+// how far each message got. Final receipts are recorded on their delivery;
+// an undelivered message fails it.
+func (h *Handler) HandleSMSStatus(c *fiber.Ctx) error {
+	log := logger.FromContext(c.UserContext())
+
+	if h.smsReceipts == nil || h.smsCfg.StatusCallbackURL == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "SMS provider sends no receipts",
+		})
+	}
+
+	tenantID := c.Query("tenant_id")
+	deliveryID, err := uuid.Parse(c.Query("delivery_id"))
+	if err != nil || !tenant.ValidID(tenantID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid status callback",
+		})
+	}
+
+	form := url.Values{}
+	c.Request().PostArgs().VisitAll(func(key, value []byte) {
+		form.Add(string(key), string(value))
+	})
+	callbackURL := statusCallbackURL(h.smsCfg.StatusCallbackURL, tenantID, deliveryID)
+	receipt, err := h.smsReceipts.ParseReceipt(callbackURL, form, c.Get(sms.SignatureHeader))
+	if err != nil {
+		log.Warnf("Refused SMS status callback for delivery %s: %v", deliveryID, err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid status callback",
+		})
+	}
+	if !receipt.Final() {
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	ctx := tenant.NewContext(c.UserContext(), &tenant.Tenant{ID: tenantID, Source: tenant.SourceWebhook})
+	r := &notification.Receipt{
+		DeliveryID:        deliveryID,
+		ProviderMessageID: receipt.MessageID,
+		Status:            receipt.Status,
+		Delivered:         receipt.Status == sms.ReceiptDelivered,
+		At:                time.Now(),
+	}
+	if !r.Delivered {
+		r.Error = fmt.Sprintf("%s with error code %s", receipt.Status, receipt.ErrorCode)
+	}
+	if err := h.notificationRepo.RecordReceipt(ctx, r); err != nil {
+		if errors.Is(err, notification.ErrDeliveryNotFound) {
+			log.Infof("Ignoring %s receipt of message %s matching no delivery", receipt.Status, receipt.MessageID)
+			return c.SendStatus(fiber.StatusOK)
+		}
+		log.Errorf("Failed to record %s receipt of delivery %s: %v", receipt.Status, deliveryID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record receipt",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
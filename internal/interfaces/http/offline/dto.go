@@ -0,0 +1,38 @@
+package offline
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+)
+
+// PushRequest is a batch of transactions a terminal rang up offline
+type PushRequest struct {
+	TerminalID     string               `json:"terminal_id" validate:"required,max=64"`
+	ConflictPolicy order.ConflictPolicy `json:"conflict_policy,omitempty" validate:"omitempty,oneof=client_wins server_wins reject"` // For transactions that name none
+	Transactions   []Transaction        `json:"transactions" validate:"required,min=1,dive"`
+}
+
+// Transaction is a sale rung up offline, identified by an ID the terminal
+// generated so uploading it twice creates one order
+type Transaction struct {
+	ID             uuid.UUID            `json:"id" validate:"required"`
+	Items          []order.OrderItem    `json:"items" validate:"required,min=1"` // With the names and prices charged
+	Currency       string               `json:"currency,omitempty" validate:"omitempty,len=3"`
+	ShiftID        *uuid.UUID           `json:"shift_id,omitempty"`
+	Tender         string               `json:"tender,omitempty" validate:"required_with=ShiftID,omitempty,oneof=cash card digital_wallet bank_transfer"`
+	Notes          string               `json:"notes,omitempty"`
+	ConflictPolicy order.ConflictPolicy `json:"conflict_policy,omitempty" validate:"omitempty,oneof=client_wins server_wins reject"`
+	CreatedAt      time.Time            `json:"created_at" validate:"required"` // When it was rung up
+}
+
+// TransactionResult is the outcome of uploading one transaction
+type TransactionResult struct {
+	ID          uuid.UUID        `json:"id"`     // Also the ID of the order it created
+	Status      string           `json:"status"` // accepted, duplicate, rejected or failed
+	TotalAmount *float64         `json:"total_amount,omitempty"`
+	Conflicts   []order.Conflict `json:"conflicts,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
@@ -0,0 +1,120 @@
+package offline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+
+	"github.com/onichange/pos-system/internal/domain/catalog"
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/domain/offline"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+)
+
+// HandleEvent records a product, price or inventory event from the message
+// broker in the change feeds. Changes are versioned by the event's time, so
+// redeliveries and events arriving out of order never replace a newer
+// change. Malformed messages, inventory events without the stock left and
+// other event types are dropped.
+func (h *Handler) HandleEvent(ctx context.Context, msg amqp.Delivery) error {
+	log := logger.FromContext(ctx)
+
+	var event messagequeue.Event
+	if err := json.Unmarshal(msg.Body, &event); err != nil || event.ID == "" {
+		log.Errorf("Dropping malformed event: %v", err)
+		return nil
+	}
+	raw, _ := json.Marshal(event.Data)
+
+	change := &offline.Change{Op: offline.OpUpsert, Version: event.Timestamp.UnixMicro()}
+	switch event.Type {
+	case catalog.EventProductCreated, catalog.EventProductUpdated, catalog.EventProductDeleted:
+		var data catalog.EventData
+		if err := json.Unmarshal(raw, &data); err != nil || data.ProductID == uuid.Nil {
+			log.Errorf("Dropping %s event %s without a product", event.Type, event.ID)
+			return nil
+		}
+		change.Entity = offline.EntityProduct
+		change.EntityID = data.ProductID
+		if event.Type == catalog.EventProductDeleted {
+			change.Op = offline.OpDelete
+		} else {
+			change.Data = raw
+		}
+	case catalog.EventPriceSet, catalog.EventPriceDeleted:
+		var data catalog.PriceEventData
+		if err := json.Unmarshal(raw, &data); err != nil || data.VariantID == uuid.Nil || data.StoreID == uuid.Nil {
+			log.Errorf("Dropping %s event %s without a store price", event.Type, event.ID)
+			return nil
+		}
+		change.Entity = offline.EntityPrice
+		change.EntityID = data.VariantID
+		change.StoreID = &data.StoreID
+		if event.Type == catalog.EventPriceDeleted {
+			change.Op = offline.OpDelete
+		} else {
+			change.Data, _ = json.Marshal(offline.Price{Price: data.Price, Currency: data.Currency})
+		}
+	case inventory.EventReserved, inventory.EventReleased, inventory.EventReceived, inventory.EventLowStock:
+		var data inventory.EventData
+		if err := json.Unmarshal(raw, &data); err != nil || data.ProductID == uuid.Nil {
+			log.Errorf("Dropping %s event %s without a product", event.Type, event.ID)
+			return nil
+		}
+		if data.StoreID == nil || data.Available == nil {
+			return nil
+		}
+		change.Entity = offline.EntityStock
+		change.EntityID = data.ProductID
+		change.StoreID = data.StoreID
+		change.Data, _ = json.Marshal(offline.Stock{Available: *data.Available})
+	default:
+		return nil
+	}
+
+	if _, err := h.syncRepo.Record(ctx, change); err != nil {
+		return fmt.Errorf("failed to record %s event %s: %w", event.Type, event.ID, err)
+	}
+	return nil
+}
+
+// RunPruning deletes tombstones older than retention every interval until
+// ctx is cancelled. An interval of zero disables it.
+func (h *Handler) RunPruning(ctx context.Context, interval, retention time.Duration, log *logger.Logger) {
+	defer apperrors.Recover(ctx, "sync-pruning")
+
+	if interval <= 0 {
+		return
+	}
+
+	pass := func() {
+		deleted, err := h.syncRepo.PruneTombstones(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Errorf("Failed to prune sync tombstones: %v", err)
+			return
+		}
+		if deleted > 0 {
+			log.Infof("Pruned %d sync tombstones", deleted)
+		}
+	}
+
+	pass()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pass()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
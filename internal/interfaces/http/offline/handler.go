@@ -0,0 +1,187 @@
+package offline
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/offline"
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/infrastructure/serviceclient"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// Upload statuses of a transaction besides the order service's own
+const (
+	// uploadFailed means the order service could not be reached; the terminal
+	// uploads the transaction again later
+	uploadFailed = "failed"
+)
+
+// OrderImporter imports the orders terminals rang up offline
+type OrderImporter interface {
+	ImportOfflineOrder(ctx context.Context, o *order.OfflineOrder) (*order.ImportResult, error)
+}
+
+// Handler serves the change feeds terminals pull and the transactions they
+// push after working offline, and records changes from the message broker
+type Handler struct {
+	syncRepo offline.Repository
+	orders   OrderImporter
+	cfg      config.SyncConfig
+}
+
+// NewHandler creates a new sync handler
+func NewHandler(syncRepo offline.Repository, orders OrderImporter, cfg config.SyncConfig) *Handler {
+	return &Handler{
+		syncRepo: syncRepo,
+		orders:   orders,
+		cfg:      cfg,
+	}
+}
+
+// GetChanges handles GET /sync/stores/:storeId/changes?since=&limit=. A
+// terminal passes the cursor of its last pull as since, or 0 for a full
+// snapshot, and pulls again while has_more is set. Answers 410 with reset
+// set when the terminal was offline longer than tombstones are kept.
+func (h *Handler) GetChanges(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("storeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+
+	var since int64
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		since, err = strconv.ParseInt(sinceStr, 10, 64)
+		if err != nil || since < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "since must be a cursor returned by an earlier pull, or 0",
+			})
+		}
+	}
+	limit := h.cfg.MaxBatch
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= h.cfg.MaxBatch {
+			limit = l
+		}
+	}
+
+	page, err := h.syncRepo.Changes(c.UserContext(), storeID, since, limit)
+	if errors.Is(err, offline.ErrCursorExpired) {
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{
+			"error": "Changes since the cursor were pruned; pull a full snapshot with since=0",
+			"reset": true,
+		})
+	}
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch changes of store %s: %v", storeID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch changes",
+		})
+	}
+
+	return c.JSON(page)
+}
+
+// PushTransactions handles POST /sync/stores/:storeId/transactions, the
+// sales a terminal rang up while offline, in the order it rang them up.
+// Each comes back accepted, duplicate (uploaded before), rejected with its
+// conflicts or reason, or failed when it should be uploaded again later.
+func (h *Handler) PushTransactions(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userIDStr, _ := c.Locals("user_id").(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	storeID, err := uuid.Parse(c.Params("storeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+
+	var req PushRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+	if len(req.Transactions) > h.cfg.MaxBatch {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Push at most " + strconv.Itoa(h.cfg.MaxBatch) + " transactions at a time",
+		})
+	}
+
+	results := make([]TransactionResult, len(req.Transactions))
+	unreachable := false
+	for i, tx := range req.Transactions {
+		results[i] = TransactionResult{ID: tx.ID}
+		if unreachable {
+			// Calling again would only wait out another timeout
+			results[i].Status = uploadFailed
+			results[i].Error = "Order service unavailable"
+			continue
+		}
+
+		policy := tx.ConflictPolicy
+		if policy == "" {
+			policy = req.ConflictPolicy
+		}
+		if policy == "" {
+			policy = order.ConflictPolicy(h.cfg.ConflictPolicy)
+		}
+
+		result, err := h.orders.ImportOfflineOrder(ctx, &order.OfflineOrder{
+			ID:         tx.ID,
+			UserID:     userID,
+			StoreID:    storeID,
+			TerminalID: req.TerminalID,
+			Items:      tx.Items,
+			Currency:   tx.Currency,
+			ShiftID:    tx.ShiftID,
+			Tender:     tx.Tender,
+			Notes:      tx.Notes,
+			Policy:     policy,
+			CreatedAt:  tx.CreatedAt,
+		})
+		var statusErr *serviceclient.StatusError
+		switch {
+		case errors.As(err, &statusErr) && statusErr.Code < fiber.StatusInternalServerError:
+			results[i].Status = order.ImportRejected
+			results[i].Error = statusErr.Message
+		case err != nil:
+			logger.FromContext(ctx).Errorf("Failed to upload offline transaction %s: %v", tx.ID, err)
+			results[i].Status = uploadFailed
+			results[i].Error = "Order service unavailable"
+			unreachable = statusErr == nil
+		default:
+			results[i].Status = result.Status
+			results[i].Conflicts = result.Conflicts
+			results[i].Error = result.Error
+			if result.Order != nil {
+				results[i].TotalAmount = &result.Order.TotalAmount
+			}
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"data": results,
+	})
+}
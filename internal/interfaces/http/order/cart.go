@@ -0,0 +1,488 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/cart"
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/pkg/i18n"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// pricingError is a failure pricing a cart, with the answer to it
+type pricingError struct {
+	err    error
+	answer func(*fiber.Ctx, error) error
+}
+
+func (e *pricingError) Error() string {
+	return e.err.Error()
+}
+
+func (e *pricingError) Unwrap() error {
+	return e.err
+}
+
+// EnableCarts keeps carts in carts. Without it, cart routes answer 501.
+//
+// A cart is an order put together item by item at the register. Every read
+// of a cart prices it as its order would be: items from the catalog,
+// discounted by the running promotions and then by the cashier's line and
+// cart discounts, and taxed. Checking a cart out creates that order.
+func (h *Handler) EnableCarts(carts cart.Repository) {
+	h.carts = carts
+}
+
+// CreateCart handles POST /carts
+func (h *Handler) CreateCart(c *fiber.Ctx) error {
+	if h.carts == nil {
+		return fiber.NewError(fiber.StatusNotImplemented, "Carts are not enabled")
+	}
+
+	userID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	var req CreateCartRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	ct := &cart.Cart{
+		ID:      uuid.New(),
+		UserID:  userID,
+		StoreID: req.StoreID,
+		Items:   []cart.Item{},
+		Notes:   req.Notes,
+	}
+	for _, item := range req.Items {
+		ct.AddItem(item.ProductID, item.Quantity)
+	}
+
+	// Refuse items the catalog cannot price before keeping the cart
+	o, err := h.priceCart(c.UserContext(), ct, nil)
+	if err != nil {
+		return cartPricingFailed(c, err)
+	}
+
+	if err := h.carts.Create(c.UserContext(), ct); err != nil {
+		return cartError(c, err, "Failed to create cart")
+	}
+	return c.Status(fiber.StatusCreated).JSON(ToCartResponse(ct, o).Localize(i18n.Locale(c)))
+}
+
+// GetCart handles GET /carts/:id, answering the cart priced as of now
+func (h *Handler) GetCart(c *fiber.Ctx) error {
+	ct, err := h.ownCart(c)
+	if err != nil {
+		return err
+	}
+	return h.respondCart(c, ct)
+}
+
+// DeleteCart handles DELETE /carts/:id
+func (h *Handler) DeleteCart(c *fiber.Ctx) error {
+	ct, err := h.ownCart(c)
+	if err != nil {
+		return err
+	}
+	if ct.CheckingOut(time.Now()) {
+		return cartError(c, cart.ErrCheckingOut, "Failed to delete cart")
+	}
+	if err := h.carts.Delete(c.UserContext(), ct.ID); err != nil {
+		return cartError(c, err, "Failed to delete cart")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// AddCartItem handles POST /carts/:id/items, adding to the product's line
+// when the cart has one
+func (h *Handler) AddCartItem(c *fiber.Ctx) error {
+	var req CartItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	return h.changeCart(c, func(ct *cart.Cart) error {
+		ct.AddItem(req.ProductID, req.Quantity)
+		return nil
+	})
+}
+
+// UpdateCartItem handles PUT /carts/:id/items/:productId, setting the
+// quantity and discount of a line; a quantity of 0 removes it
+func (h *Handler) UpdateCartItem(c *fiber.Ctx) error {
+	var req UpdateCartItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	productID := c.Params("productId")
+	return h.changeCart(c, func(ct *cart.Cart) error {
+		return ct.UpdateItem(productID, req.Quantity, req.Discount)
+	})
+}
+
+// RemoveCartItem handles DELETE /carts/:id/items/:productId
+func (h *Handler) RemoveCartItem(c *fiber.Ctx) error {
+	productID := c.Params("productId")
+	return h.changeCart(c, func(ct *cart.Cart) error {
+		return ct.RemoveItem(productID)
+	})
+}
+
+// ApplyCartDiscount handles PUT /carts/:id/discount, replacing the discount
+// taken off the whole cart
+func (h *Handler) ApplyCartDiscount(c *fiber.Ctx) error {
+	var req CartDiscountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+	if req.Type == cart.DiscountPercent && req.Value > 100 {
+		return fiber.NewError(fiber.StatusBadRequest, "A percent discount cannot exceed 100")
+	}
+
+	return h.changeCart(c, func(ct *cart.Cart) error {
+		ct.Discount = &cart.Discount{Type: req.Type, Value: req.Value, Reason: req.Reason}
+		return nil
+	})
+}
+
+// RemoveCartDiscount handles DELETE /carts/:id/discount
+func (h *Handler) RemoveCartDiscount(c *fiber.Ctx) error {
+	return h.changeCart(c, func(ct *cart.Cart) error {
+		ct.Discount = nil
+		return nil
+	})
+}
+
+// CheckoutCart handles POST /carts/:id/checkout, creating the order the
+// cart prices to and deleting the cart.
+//
+// The checkout first claims the cart, naming the order it creates, so that
+// changes and other checkouts are refused until it ends. A cart whose
+// checkout stopped before deleting it keeps the order's ID: checking it out
+// again answers the order already created, or creates it under that ID, so a
+// cart never yields two orders.
+func (h *Handler) CheckoutCart(c *fiber.Ctx) error {
+	ct, err := h.ownCart(c)
+	if err != nil {
+		return err
+	}
+
+	var req CartCheckoutRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	ctx := c.UserContext()
+
+	// Claim the cart for this checkout
+	ct, err = h.carts.Update(ctx, ct.ID, func(ct *cart.Cart) error {
+		now := time.Now().UTC()
+		if ct.CheckingOut(now) {
+			return cart.ErrCheckingOut
+		}
+		if len(ct.Items) == 0 {
+			return errEmptyCart
+		}
+		if ct.CheckoutOrderID == nil {
+			orderID := uuid.New()
+			ct.CheckoutOrderID = &orderID
+		}
+		ct.CheckoutStartedAt = &now
+		return nil
+	})
+	if err != nil {
+		return cartError(c, err, "Failed to check out cart")
+	}
+
+	// A checkout that stopped after creating the order only has to delete
+	// the cart
+	if existing, err := h.orderRepo.GetByID(ctx, *ct.CheckoutOrderID); err == nil {
+		h.finishCheckout(ctx, ct)
+		return c.JSON(ToResponse(existing).Localize(i18n.Locale(c)))
+	}
+
+	o := &order.Order{
+		ID:              *ct.CheckoutOrderID,
+		ShippingAddress: req.ShippingAddress,
+		BillingAddress:  req.BillingAddress,
+	}
+	created := false
+	defer func() {
+		if !created {
+			h.releaseCart(ctx, ct)
+		}
+	}()
+
+	// Price the cart as its order
+	o, err = h.priceCart(ctx, ct, o)
+	if err != nil {
+		return cartPricingFailed(c, err)
+	}
+
+	// Link the order to the register shift it is rung up on
+	if err := h.assignShift(ctx, o, req.ShiftID, req.Tender); err != nil {
+		return shiftFailed(c, err)
+	}
+
+	// Pay for part of the order with loyalty points
+	if err := h.redeemPoints(ctx, o, req.RedeemPoints); err != nil {
+		return redemptionFailed(c, err)
+	}
+
+	// Save order
+	if err := h.save(ctx, order.EventCreated, o, func(orders order.Repository) error {
+		return orders.Create(ctx, o)
+	}); err != nil {
+		h.releasePoints(ctx, o)
+		if errors.Is(err, order.ErrOrderExists) {
+			return fiber.NewError(fiber.StatusConflict, "Cart is already being checked out")
+		}
+		logger.FromContext(ctx).Errorf("Failed to create order from cart %s: %v", ct.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create order",
+		})
+	}
+	created = true
+	h.finishCheckout(ctx, ct)
+
+	metrics.RecordOrderCreated(o.StoreID.String(), o.Currency, o.TotalAmount)
+
+	return c.Status(fiber.StatusCreated).JSON(ToResponse(o).Localize(i18n.Locale(c)))
+}
+
+// errEmptyCart is returned when checking out a cart without items
+var errEmptyCart = errors.New("cart is empty")
+
+// ownCart returns the caller's cart named in the path
+func (h *Handler) ownCart(c *fiber.Ctx) (*cart.Cart, error) {
+	if h.carts == nil {
+		return nil, fiber.NewError(fiber.StatusNotImplemented, "Carts are not enabled")
+	}
+
+	userID, err := currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	cartID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid cart ID")
+	}
+
+	ct, err := h.carts.GetByID(c.UserContext(), cartID)
+	if err != nil {
+		return nil, cartError(c, err, "Failed to fetch cart")
+	}
+	if ct.UserID != userID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+	return ct, nil
+}
+
+// changeCart applies fn to the caller's cart named in the path, unless a
+// checkout holds it, and answers the changed cart
+func (h *Handler) changeCart(c *fiber.Ctx, fn func(*cart.Cart) error) error {
+	ct, err := h.ownCart(c)
+	if err != nil {
+		return err
+	}
+
+	ct, err = h.carts.Update(c.UserContext(), ct.ID, func(ct *cart.Cart) error {
+		if ct.CheckingOut(time.Now()) {
+			return cart.ErrCheckingOut
+		}
+		return fn(ct)
+	})
+	if err != nil {
+		return cartError(c, err, "Failed to update cart")
+	}
+	return h.respondCart(c, ct)
+}
+
+// respondCart answers a cart with its prices as of now
+func (h *Handler) respondCart(c *fiber.Ctx, ct *cart.Cart) error {
+	o, err := h.priceCart(c.UserContext(), ct, nil)
+	if err != nil {
+		return cartPricingFailed(c, err)
+	}
+	return c.JSON(ToCartResponse(ct, o).Localize(i18n.Locale(c)))
+}
+
+// priceCart prices ct as the order o, or a new order when o is nil. Items
+// are priced from the catalog and discounted by the running promotions, then
+// by the cart's own discounts, and the result is taxed.
+func (h *Handler) priceCart(ctx context.Context, ct *cart.Cart, o *order.Order) (*order.Order, error) {
+	if o == nil {
+		o = &order.Order{ID: uuid.New()}
+	}
+	o.UserID = ct.UserID
+	o.StoreID = ct.StoreID
+	o.Status = order.StatusPending
+	o.Notes = ct.Notes
+	o.Currency = defaultCurrency
+	o.Items = make([]order.OrderItem, len(ct.Items))
+	for i, item := range ct.Items {
+		o.Items[i] = order.OrderItem{ProductID: item.ProductID, Quantity: item.Quantity}
+	}
+	if len(o.Items) == 0 {
+		return o, nil
+	}
+
+	// Price the items from the catalog
+	currency, err := h.priceItems(ctx, ct.StoreID, o.Items)
+	if err != nil {
+		return nil, &pricingError{err: err, answer: pricingFailed}
+	}
+	o.Currency = currency
+
+	// Discount the items by the promotions running in the store
+	if err := h.applyPromotions(ctx, o, nil); err != nil {
+		return nil, &pricingError{err: err, answer: promotionsFailed}
+	}
+
+	// Then by the cashier's discounts
+	applyCartDiscounts(o, ct)
+
+	// Charge the sales tax of the store or shipping address
+	if err := h.applyTax(ctx, o); err != nil {
+		return nil, &pricingError{err: err, answer: taxFailed}
+	}
+
+	o.TotalAmount = o.CalculateTotal()
+	return o, nil
+}
+
+// applyCartDiscounts adds the discounts of ct to those of o's items: each
+// line's own, then the cart's, spread over the lines by what is left of
+// their value
+func applyCartDiscounts(o *order.Order, ct *cart.Cart) {
+	for i := range o.Items {
+		item := &o.Items[i]
+		gross := item.UnitPrice * float64(item.Quantity)
+		item.Discount = math.Min(item.Discount+ct.Items[i].Discount, gross)
+		item.Subtotal = gross - item.Discount
+	}
+
+	subtotal := o.Subtotal()
+	amount := ct.Discount.Amount(subtotal)
+	if amount == 0 {
+		return
+	}
+	remaining := amount
+	for i := range o.Items {
+		item := &o.Items[i]
+		share := amount * item.Subtotal / subtotal
+		if i == len(o.Items)-1 {
+			share = math.Min(remaining, item.Subtotal) // What rounding left over
+		}
+		item.Discount += share
+		item.Subtotal -= share
+		remaining -= share
+	}
+}
+
+// finishCheckout deletes a checked out cart. Failures are logged; checking
+// the cart out again answers the order created.
+func (h *Handler) finishCheckout(ctx context.Context, ct *cart.Cart) {
+	if err := h.carts.Delete(ctx, ct.ID); err != nil && !errors.Is(err, cart.ErrCartNotFound) {
+		logger.FromContext(ctx).Errorf("Failed to delete checked out cart %s: %v", ct.ID, err)
+	}
+}
+
+// releaseCart ends a checkout that created no order, so the cart can be
+// changed and checked out again. Failures are logged; the claim lapses after
+// cart.CheckoutTimeout.
+func (h *Handler) releaseCart(ctx context.Context, ct *cart.Cart) {
+	_, err := h.carts.Update(ctx, ct.ID, func(ct *cart.Cart) error {
+		ct.CheckoutStartedAt = nil
+		return nil
+	})
+	if err != nil && !errors.Is(err, cart.ErrCartNotFound) {
+		logger.FromContext(ctx).Errorf("Failed to release cart %s: %v", ct.ID, err)
+	}
+}
+
+// cartPricingFailed answers a request whose cart could not be priced
+func cartPricingFailed(c *fiber.Ctx, err error) error {
+	var failed *pricingError
+	if errors.As(err, &failed) {
+		return failed.answer(c, failed.err)
+	}
+	logger.FromContext(c.UserContext()).Errorf("Failed to price cart: %v", err)
+	return fiber.NewError(fiber.StatusInternalServerError, "Failed to price cart")
+}
+
+// cartError maps a failure to the error answered, with 404 for an unknown
+// cart or item and 409 for a cart being checked out or changing too fast
+func cartError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, cart.ErrCartNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Cart not found")
+	case errors.Is(err, cart.ErrItemNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Item not in cart")
+	case errors.Is(err, cart.ErrCheckingOut):
+		return fiber.NewError(fiber.StatusConflict, "Cart is being checked out")
+	case errors.Is(err, cart.ErrConflict):
+		return fiber.NewError(fiber.StatusConflict, "Cart changed concurrently, try again")
+	case errors.Is(err, errEmptyCart):
+		return fiber.NewError(fiber.StatusBadRequest, "Cart is empty")
+	}
+	logger.FromContext(c.UserContext()).Errorf("%s: %v", message, err)
+	return fiber.NewError(fiber.StatusInternalServerError, message)
+}
+
+// currentUser returns the authenticated user's ID
+func currentUser(c *fiber.Ctx) (uuid.UUID, error) {
+	userIDStr, _ := c.Locals("user_id").(string)
+	if userIDStr == "" {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+	return userID, nil
+}
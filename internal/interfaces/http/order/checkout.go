@@ -0,0 +1,464 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/i18n"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/saga"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// checkoutSaga is the name of the saga checking out orders
+const checkoutSaga = "checkout"
+
+// StockReserver reserves the stock of orders in the inventory service,
+// through reservations that are released when they expire uncommitted
+type StockReserver interface {
+	ReserveBatch(ctx context.Context, orderID uuid.UUID, rs []*inventory.Reservation) error
+	Commit(ctx context.Context, id uuid.UUID) error
+	Release(ctx context.Context, id uuid.UUID) error
+}
+
+// PaymentGateway charges and refunds orders through the payment service
+type PaymentGateway interface {
+	Charge(ctx context.Context, p *payment.Payment) (*payment.Payment, error)
+	Refund(ctx context.Context, id uuid.UUID, reason string) error
+}
+
+// errOrderNotPending is returned when checking out an order past pending
+var errOrderNotPending = errors.New("order is not pending")
+
+// checkout holds what the checkout saga needs
+type checkout struct {
+	orchestrator *saga.Orchestrator
+	stock        StockReserver
+	payments     PaymentGateway
+	pollInterval time.Duration
+}
+
+// checkoutPayment is how the customer pays for a checkout, as recorded in
+// the saga's data
+type checkoutPayment struct {
+	Token        string                    `json:"token"`
+	Type         payment.PaymentMethodType `json:"type"`
+	ThreeDSecure bool                      `json:"three_d_secure,omitempty"`
+}
+
+// EnableCheckout registers the checkout saga with orchestrator, which must
+// be done before the orchestrator runs. Without it, checkouts answer 501.
+//
+// A checkout takes an order from pending to confirmed: it reserves the
+// order's stock, charges its total through the payment service under the
+// saga execution's ID, then commits the reservations and confirms the
+// order. A payment still in progress, such as one awaiting 3D Secure, leaves
+// the saga waiting until HandlePaymentEvent wakes it, or until the poll
+// interval passes. When a step fails, or the checkout outlasts its timeout,
+// the payment is refunded, the stock released and the order cancelled. Two
+// checkouts of one order both reserve and charge, but at most one confirms
+// it; the others are undone.
+func (h *Handler) EnableCheckout(orchestrator *saga.Orchestrator, stock StockReserver, payments PaymentGateway, cfg config.OrdersConfig) error {
+	h.checkout = &checkout{
+		orchestrator: orchestrator,
+		stock:        stock,
+		payments:     payments,
+		pollInterval: cfg.PaymentPollInterval,
+	}
+	return orchestrator.Register(saga.Definition{
+		Name:    checkoutSaga,
+		Timeout: cfg.CheckoutTimeout,
+		Steps: []saga.Step{
+			{Name: "hold_order", Action: h.holdOrder, Compensate: h.cancelCheckedOutOrder},
+			{Name: "reserve_stock", Action: h.reserveStock, Compensate: h.releaseStock},
+			{Name: "charge_payment", Action: h.chargePayment, Compensate: h.refundPayment},
+			{Name: "confirm_order", Action: h.confirmOrder},
+		},
+	})
+}
+
+// CheckoutOrder handles POST /orders/:id/checkout, reserving the stock of
+// a pending order of the caller and charging it. The checkout is answered
+// with the confirmed order once paid, or with 202 while the payment is still
+// in progress; the order is then confirmed or cancelled once it settles.
+func (h *Handler) CheckoutOrder(c *fiber.Ctx) error {
+	if h.checkout == nil {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
+			"error": "Checkout is not enabled",
+		})
+	}
+
+	// Get user ID from JWT
+	userIDStr := c.Locals("user_id")
+	if userIDStr == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	// Parse order ID
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	// Parse request
+	var req CheckoutRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Validate request
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	// Get order
+	o, err := h.orderRepo.GetByID(c.UserContext(), orderID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Order not found",
+		})
+	}
+
+	// Check ownership
+	if o.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	if o.Status != order.StatusPending {
+		return transitionConflict(c, o, order.StatusConfirmed)
+	}
+
+	e, err := h.checkout.orchestrator.Start(c.UserContext(), checkoutSaga, map[string]interface{}{
+		"order_id": o.ID,
+		"user_id":  userID,
+		"payment": checkoutPayment{
+			Token:        req.PaymentMethodToken,
+			Type:         payment.PaymentMethodType(req.PaymentMethodType),
+			ThreeDSecure: req.ThreeDSecure,
+		},
+	})
+	if errors.Is(err, saga.ErrAwaiting) {
+		return c.Status(fiber.StatusAccepted).JSON(CheckoutResponse{
+			CheckoutID: e.ID,
+			OrderID:    o.ID,
+			Status:     "awaiting_payment",
+		})
+	}
+	if err != nil {
+		return checkoutFailed(c, o.ID, err)
+	}
+
+	o, err = h.orderRepo.GetByID(c.UserContext(), orderID)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch checked out order %s: %v", orderID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch order",
+		})
+	}
+	return c.JSON(CheckoutResponse{
+		CheckoutID: e.ID,
+		OrderID:    o.ID,
+		Status:     "completed",
+		Order:      ToResponse(o).Localize(i18n.Locale(c)),
+	})
+}
+
+// checkoutFailed answers a checkout the saga undid
+func checkoutFailed(c *fiber.Ctx, orderID uuid.UUID, err error) error {
+	switch {
+	case errors.Is(err, inventory.ErrInsufficientStock):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Insufficient stock",
+		})
+	case errors.Is(err, payment.ErrDeclined):
+		return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
+			"error": "Payment declined",
+		})
+	case errors.Is(err, errOrderNotPending), errors.Is(err, order.ErrInvalidTransition):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Order changed during checkout",
+		})
+	case errors.Is(err, saga.ErrDeadline):
+		return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{
+			"error": "Checkout timed out",
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("Checkout of order %s failed: %v", orderID, err)
+	return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+		"error": "Checkout failed",
+	})
+}
+
+// checkoutOrder returns the order a checkout execution is for
+func (h *Handler) checkoutOrder(ctx context.Context, e *saga.Execution) (*order.Order, error) {
+	var orderID uuid.UUID
+	if _, err := e.Get("order_id", &orderID); err != nil {
+		return nil, performance.Permanent(err)
+	}
+	o, err := h.orderRepo.GetByID(ctx, orderID)
+	if errors.Is(err, order.ErrOrderNotFound) {
+		return nil, performance.Permanent(err)
+	}
+	return o, err
+}
+
+// holdOrder checks the order is still pending
+func (h *Handler) holdOrder(ctx context.Context, e *saga.Execution) error {
+	o, err := h.checkoutOrder(ctx, e)
+	if err != nil {
+		return err
+	}
+	if o.Status != order.StatusPending {
+		return performance.Permanent(fmt.Errorf("%w: %s", errOrderNotPending, o.Status))
+	}
+	return nil
+}
+
+// cancelCheckedOutOrder cancels the order of an undone checkout, returning
+// its loyalty points. Orders no longer pending, such as those another
+// checkout confirmed, are left as they are.
+func (h *Handler) cancelCheckedOutOrder(ctx context.Context, e *saga.Execution) error {
+	o, err := h.checkoutOrder(ctx, e)
+	if errors.Is(err, order.ErrOrderNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if o.Status != order.StatusPending {
+		return nil
+	}
+
+	transition := order.NewStatusTransition(o, order.StatusCancelled, nil, "checkout failed: "+e.Error)
+	if err := o.TransitionTo(order.StatusCancelled, transition.ChangedAt); err != nil {
+		return nil
+	}
+	err = h.save(ctx, order.EventCancelled, o, func(orders order.Repository) error {
+		return orders.UpdateStatus(ctx, o.ID, transition)
+	})
+	if errors.Is(err, order.ErrInvalidTransition) {
+		return nil // Moved on since it was read
+	}
+	if err != nil {
+		return err
+	}
+
+	h.releasePoints(ctx, o)
+	h.releaseCoupons(ctx, o)
+	return nil
+}
+
+// reserveStock reserves every item of the order in its store in one batch,
+// all or none, each under a reservation ID derived from the execution's, so
+// that retrying the step reserves nothing twice. Reservations made by an
+// attempt whose answer was lost are answered to the retry, or expire.
+func (h *Handler) reserveStock(ctx context.Context, e *saga.Execution) error {
+	var reserved bool
+	if _, err := e.Get("reserved", &reserved); err != nil || reserved {
+		return err
+	}
+
+	o, err := h.checkoutOrder(ctx, e)
+	if err != nil {
+		return err
+	}
+
+	rs := make([]*inventory.Reservation, len(o.Items))
+	for i, item := range o.Items {
+		productID, err := uuid.Parse(item.ProductID)
+		if err != nil {
+			return performance.Permanent(fmt.Errorf("product %s: %w", item.ProductID, inventory.ErrInventoryNotFound))
+		}
+		rs[i] = &inventory.Reservation{ID: reservationID(e, i), ProductID: productID, StoreID: &o.StoreID, Quantity: item.Quantity}
+	}
+
+	err = h.checkout.stock.ReserveBatch(ctx, o.ID, rs)
+	var failed inventory.ReservationErrors
+	if errors.As(err, &failed) {
+		for i, item := range o.Items {
+			if ferr, ok := failed[i]; ok && !errors.Is(ferr, inventory.ErrReservationExists) {
+				return performance.Permanent(fmt.Errorf("product %s: %w", item.ProductID, inventory.ErrInsufficientStock))
+			}
+		}
+		return performance.Permanent(err)
+	}
+	if errors.Is(err, inventory.ErrReservationClosed) {
+		return performance.Permanent(err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reserve stock: %w", err)
+	}
+	return e.Set("reserved", true)
+}
+
+// releaseStock releases the stock reserved for the order
+func (h *Handler) releaseStock(ctx context.Context, e *saga.Execution) error {
+	var reserved bool
+	if _, err := e.Get("reserved", &reserved); err != nil || !reserved {
+		return err
+	}
+
+	o, err := h.checkoutOrder(ctx, e)
+	if err != nil {
+		return err
+	}
+	for i, item := range o.Items {
+		err := h.checkout.stock.Release(ctx, reservationID(e, i))
+		if errors.Is(err, inventory.ErrReservationClosed) || errors.Is(err, inventory.ErrReservationNotFound) {
+			continue // Released before, or expired
+		}
+		if err != nil {
+			return fmt.Errorf("failed to release product %s: %w", item.ProductID, err)
+		}
+	}
+	// Released items are not released twice should the compensation be retried
+	return e.Set("reserved", false)
+}
+
+// commitStock commits the order's reservations, so that they no longer
+// expire; a reservation that expired fails the checkout
+func (h *Handler) commitStock(ctx context.Context, e *saga.Execution, o *order.Order) error {
+	for i, item := range o.Items {
+		err := h.checkout.stock.Commit(ctx, reservationID(e, i))
+		if errors.Is(err, inventory.ErrReservationClosed) || errors.Is(err, inventory.ErrReservationNotFound) {
+			return performance.Permanent(fmt.Errorf("stock of product %s is no longer reserved: %w", item.ProductID, err))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to commit stock of product %s: %w", item.ProductID, err)
+		}
+	}
+	return nil
+}
+
+// reservationID is the ID of the reservation of the order's item i in
+// checkout e
+func reservationID(e *saga.Execution, i int) uuid.UUID {
+	return uuid.NewSHA1(e.ID, []byte(strconv.Itoa(i)))
+}
+
+// chargePayment charges the order's total under the execution's ID. The
+// payment service answers a charge made before as it stands, so the step
+// also looks up the outcome of a payment in progress once woken.
+func (h *Handler) chargePayment(ctx context.Context, e *saga.Execution) error {
+	o, err := h.checkoutOrder(ctx, e)
+	if err != nil {
+		return err
+	}
+	if o.TotalAmount <= 0 {
+		return nil // Paid for in full with loyalty points
+	}
+
+	var method checkoutPayment
+	if _, err := e.Get("payment", &method); err != nil {
+		return performance.Permanent(err)
+	}
+
+	p, err := h.checkout.payments.Charge(ctx, &payment.Payment{
+		ID:                  e.ID,
+		OrderID:             o.ID,
+		UserID:              o.UserID,
+		PaymentMethodToken:  method.Token,
+		PaymentMethodType:   method.Type,
+		Amount:              o.TotalAmount,
+		Currency:            o.Currency,
+		ThreeDSecureEnabled: method.ThreeDSecure,
+	})
+	if errors.Is(err, payment.ErrDeclined) {
+		return performance.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch p.Status {
+	case payment.StatusCompleted:
+		return nil
+	case payment.StatusPending, payment.StatusProcessing:
+		return saga.Await(h.checkout.pollInterval)
+	default:
+		return performance.Permanent(fmt.Errorf("%w: payment %s", payment.ErrDeclined, p.Status))
+	}
+}
+
+// refundPayment refunds the payment of an undone checkout. Payments that
+// took no money need no refund; a payment still in progress fails the
+// compensation, leaving the checkout for manual repair.
+func (h *Handler) refundPayment(ctx context.Context, e *saga.Execution) error {
+	err := h.checkout.payments.Refund(ctx, e.ID, "checkout undone")
+	if errors.Is(err, payment.ErrNotSettled) {
+		return performance.Permanent(err)
+	}
+	return err
+}
+
+// confirmOrder commits the paid order's stock reservations and confirms it,
+// as the customer checking it out.
+// The transition names the execution, so an attempt that confirmed the order
+// before failing to answer is told apart from another checkout confirming it.
+func (h *Handler) confirmOrder(ctx context.Context, e *saga.Execution) error {
+	o, err := h.checkoutOrder(ctx, e)
+	if err != nil {
+		return err
+	}
+
+	var changedBy *uuid.UUID
+	var userID uuid.UUID
+	if ok, _ := e.Get("user_id", &userID); ok {
+		changedBy = &userID
+	}
+	reason := "checkout " + e.ID.String()
+	transition := order.NewStatusTransition(o, order.StatusConfirmed, changedBy, reason)
+
+	if err := h.commitStock(ctx, e, o); err != nil {
+		return err
+	}
+
+	err = o.TransitionTo(order.StatusConfirmed, transition.ChangedAt)
+	if err == nil {
+		err = h.save(ctx, order.EventUpdated, o, func(orders order.Repository) error {
+			return orders.UpdateStatus(ctx, o.ID, transition)
+		})
+	}
+	if errors.Is(err, order.ErrInvalidTransition) {
+		history, herr := h.orderRepo.StatusHistory(ctx, o.ID)
+		if herr != nil {
+			return herr
+		}
+		for _, t := range history {
+			if t.To == order.StatusConfirmed && t.Reason == reason {
+				return nil
+			}
+		}
+		return performance.Permanent(err)
+	}
+	return err
+}
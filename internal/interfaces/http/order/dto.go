@@ -3,7 +3,9 @@ package order
 import (
 	"github.com/google/uuid"
 
+	"github.com/onichange/pos-system/internal/domain/cart"
 	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/pkg/i18n"
 )
 
 // CreateOrderRequest represents create order request
@@ -13,6 +15,10 @@ type CreateOrderRequest struct {
 	ShippingAddress *order.Address    `json:"shipping_address,omitempty"`
 	BillingAddress  *order.Address    `json:"billing_address,omitempty"`
 	Notes           string            `json:"notes,omitempty"`
+	RedeemPoints    int64             `json:"redeem_points,omitempty" validate:"gte=0"` // Loyalty points to pay with
+	ShiftID         *uuid.UUID        `json:"shift_id,omitempty"`                       // Open register shift the order is rung up on
+	Tender          string            `json:"tender,omitempty" validate:"required_with=ShiftID,omitempty,oneof=cash card digital_wallet bank_transfer"`
+	CouponCodes     []string          `json:"coupon_codes,omitempty" validate:"omitempty,max=5,dive,min=1,max=64"` // Coupons unlocking promotions
 }
 
 // UpdateOrderRequest represents update order request
@@ -25,40 +31,75 @@ type UpdateOrderRequest struct {
 
 // UpdateOrderStatusRequest represents update order status request
 type UpdateOrderStatusRequest struct {
-	Status order.OrderStatus `json:"status" validate:"required"`
+	Status order.OrderStatus `json:"status" validate:"required,oneof=pending confirmed processing shipped delivered cancelled refunded"`
+	Reason string            `json:"reason,omitempty" validate:"omitempty,max=500"` // Recorded in the status history
+}
+
+// CheckoutRequest is how the customer pays for an order checked out
+type CheckoutRequest struct {
+	PaymentMethodToken string `json:"payment_method_token" validate:"required"`
+	PaymentMethodType  string `json:"payment_method_type" validate:"required,oneof=card bank_transfer digital_wallet"`
+	ThreeDSecure       bool   `json:"three_d_secure,omitempty"`
+}
+
+// CheckoutResponse answers a checkout, completed or still waiting for its
+// payment
+type CheckoutResponse struct {
+	CheckoutID uuid.UUID      `json:"checkout_id"` // Also the ID of its payment
+	OrderID    uuid.UUID      `json:"order_id"`
+	Status     string         `json:"status"`          // completed or awaiting_payment
+	Order      *OrderResponse `json:"order,omitempty"` // Set once completed
 }
 
 // OrderResponse represents order response
 type OrderResponse struct {
-	ID              uuid.UUID         `json:"id"`
-	UserID          uuid.UUID         `json:"user_id"`
-	StoreID         uuid.UUID         `json:"store_id"`
-	Status          string            `json:"status"`
-	TotalAmount     float64           `json:"total_amount"`
-	Currency        string            `json:"currency"`
-	Items           []order.OrderItem `json:"items"`
-	ShippingAddress *order.Address    `json:"shipping_address,omitempty"`
-	BillingAddress  *order.Address    `json:"billing_address,omitempty"`
-	Notes           string            `json:"notes,omitempty"`
-	CreatedAt       string            `json:"created_at"`
-	UpdatedAt       string            `json:"updated_at"`
-	CompletedAt     *string           `json:"completed_at,omitempty"`
-	CancelledAt     *string           `json:"cancelled_at,omitempty"`
+	ID              uuid.UUID                `json:"id"`
+	UserID          uuid.UUID                `json:"user_id"`
+	StoreID         uuid.UUID                `json:"store_id"`
+	Status          string                   `json:"status"`
+	TotalAmount     float64                  `json:"total_amount"`
+	TaxAmount       float64                  `json:"tax_amount"`
+	TaxIncluded     float64                  `json:"tax_included,omitempty"`    // Part of the tax already in the item prices
+	FormattedTotal  string                   `json:"formatted_total,omitempty"` // Set by Localize
+	FormattedTax    string                   `json:"formatted_tax,omitempty"`
+	Taxes           []order.TaxLine          `json:"taxes,omitempty"`
+	Currency        string                   `json:"currency"`
+	LoyaltyPoints   int64                    `json:"loyalty_points,omitempty"`
+	LoyaltyDiscount float64                  `json:"loyalty_discount,omitempty"`
+	Promotions      []order.AppliedPromotion `json:"promotions,omitempty"`
+	ShiftID         *uuid.UUID               `json:"shift_id,omitempty"`
+	Tender          string                   `json:"tender,omitempty"`
+	Items           []order.OrderItem        `json:"items"`
+	ShippingAddress *order.Address           `json:"shipping_address,omitempty"`
+	BillingAddress  *order.Address           `json:"billing_address,omitempty"`
+	Notes           string                   `json:"notes,omitempty"`
+	CreatedAt       string                   `json:"created_at"`
+	UpdatedAt       string                   `json:"updated_at"`
+	CompletedAt     *string                  `json:"completed_at,omitempty"`
+	CancelledAt     *string                  `json:"cancelled_at,omitempty"`
 }
 
 // ToResponse converts domain Order to OrderResponse
 func ToResponse(o *order.Order) *OrderResponse {
 	resp := &OrderResponse{
-		ID:          o.ID,
-		UserID:      o.UserID,
-		StoreID:     o.StoreID,
-		Status:      string(o.Status),
-		TotalAmount: o.TotalAmount,
-		Currency:    o.Currency,
-		Items:       o.Items,
-		Notes:       o.Notes,
-		CreatedAt:   o.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:   o.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ID:              o.ID,
+		UserID:          o.UserID,
+		StoreID:         o.StoreID,
+		Status:          string(o.Status),
+		TotalAmount:     o.TotalAmount,
+		TaxAmount:       o.TaxAmount,
+		TaxIncluded:     o.IncludedTax(),
+		Taxes:           o.Taxes,
+		Currency:        o.Currency,
+		Items:           o.Items,
+		LoyaltyPoints:   o.LoyaltyPoints,
+		LoyaltyDiscount: o.LoyaltyDiscount,
+		Promotions:      o.Promotions,
+		ShiftID:         o.ShiftID,
+		Tender:          o.Tender,
+		Notes:           o.Notes,
+		CreatedAt:       o.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:       o.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
 
 	if o.ShippingAddress != nil {
@@ -78,3 +119,154 @@ func ToResponse(o *order.Order) *OrderResponse {
 
 	return resp
 }
+
+// Localize sets the formatted amounts for display in locale
+func (r *OrderResponse) Localize(locale string) *OrderResponse {
+	r.FormattedTotal = i18n.FormatMoney(locale, r.TotalAmount, r.Currency)
+	r.FormattedTax = i18n.FormatMoney(locale, r.TaxAmount, r.Currency)
+	return r
+}
+
+// CreateCartRequest starts a cart, optionally with items
+type CreateCartRequest struct {
+	StoreID uuid.UUID         `json:"store_id" validate:"required"`
+	Items   []CartItemRequest `json:"items,omitempty" validate:"dive"`
+	Notes   string            `json:"notes,omitempty" validate:"omitempty,max=500"`
+}
+
+// CartItemRequest adds a product to a cart
+type CartItemRequest struct {
+	ProductID string `json:"product_id" validate:"required,uuid"` // Catalog variant ID
+	Quantity  int    `json:"quantity" validate:"required,gte=1"`
+}
+
+// UpdateCartItemRequest sets the quantity and discount of a cart line
+type UpdateCartItemRequest struct {
+	Quantity int     `json:"quantity" validate:"gte=0"`           // 0 removes the line
+	Discount float64 `json:"discount,omitempty" validate:"gte=0"` // Amount off the line
+}
+
+// CartDiscountRequest discounts a whole cart
+type CartDiscountRequest struct {
+	Type   cart.DiscountType `json:"type" validate:"required,oneof=percent amount"`
+	Value  float64           `json:"value" validate:"gt=0"`
+	Reason string            `json:"reason,omitempty" validate:"omitempty,max=200"`
+}
+
+// CartCheckoutRequest is what the order of a cart needs beyond its items
+type CartCheckoutRequest struct {
+	ShippingAddress *order.Address `json:"shipping_address,omitempty"`
+	BillingAddress  *order.Address `json:"billing_address,omitempty"`
+	RedeemPoints    int64          `json:"redeem_points,omitempty" validate:"gte=0"` // Loyalty points to pay with
+	ShiftID         *uuid.UUID     `json:"shift_id,omitempty"`                       // Open register shift the order is rung up on
+	Tender          string         `json:"tender,omitempty" validate:"required_with=ShiftID,omitempty,oneof=cash card digital_wallet bank_transfer"`
+}
+
+// CartResponse is a cart priced as its order would be
+type CartResponse struct {
+	ID                uuid.UUID                `json:"id"`
+	UserID            uuid.UUID                `json:"user_id"`
+	StoreID           uuid.UUID                `json:"store_id"`
+	Items             []order.OrderItem        `json:"items"`
+	Discount          *cart.Discount           `json:"discount,omitempty"`
+	Promotions        []order.AppliedPromotion `json:"promotions,omitempty"`
+	Subtotal          float64                  `json:"subtotal"` // After every discount, before tax not included in prices
+	TaxAmount         float64                  `json:"tax_amount"`
+	TaxIncluded       float64                  `json:"tax_included,omitempty"` // Part of the tax already in the subtotal
+	Taxes             []order.TaxLine          `json:"taxes,omitempty"`
+	TotalAmount       float64                  `json:"total_amount"`
+	Currency          string                   `json:"currency"`
+	FormattedSubtotal string                   `json:"formatted_subtotal,omitempty"` // Set by Localize
+	FormattedTax      string                   `json:"formatted_tax,omitempty"`
+	FormattedTotal    string                   `json:"formatted_total,omitempty"`
+	Notes             string                   `json:"notes,omitempty"`
+	Version           int                      `json:"version"`
+	CreatedAt         string                   `json:"created_at"`
+	UpdatedAt         string                   `json:"updated_at"`
+	ExpiresAt         string                   `json:"expires_at"`
+}
+
+// ToCartResponse converts a cart and the order it prices to
+func ToCartResponse(ct *cart.Cart, o *order.Order) *CartResponse {
+	return &CartResponse{
+		ID:          ct.ID,
+		UserID:      ct.UserID,
+		StoreID:     ct.StoreID,
+		Items:       o.Items,
+		Discount:    ct.Discount,
+		Promotions:  o.Promotions,
+		Subtotal:    o.Subtotal(),
+		TaxAmount:   o.TaxAmount,
+		TaxIncluded: o.IncludedTax(),
+		Taxes:       o.Taxes,
+		TotalAmount: o.TotalAmount,
+		Currency:    o.Currency,
+		Notes:       ct.Notes,
+		Version:     ct.Version,
+		CreatedAt:   ct.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:   ct.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ExpiresAt:   ct.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// Localize sets the formatted amounts for display in locale
+func (r *CartResponse) Localize(locale string) *CartResponse {
+	r.FormattedSubtotal = i18n.FormatMoney(locale, r.Subtotal, r.Currency)
+	r.FormattedTax = i18n.FormatMoney(locale, r.TaxAmount, r.Currency)
+	r.FormattedTotal = i18n.FormatMoney(locale, r.TotalAmount, r.Currency)
+	return r
+}
+
+// OrderChangeResponse is one recorded change of an order
+type OrderChangeResponse struct {
+	Version    int            `json:"version"`
+	Type       string         `json:"type"`
+	Order      *OrderResponse `json:"order,omitempty"`  // The order as created or updated
+	Status     string         `json:"status,omitempty"` // The new status
+	OccurredAt string         `json:"occurred_at"`
+}
+
+// StatusTransitionResponse is one recorded status transition of an order
+type StatusTransitionResponse struct {
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	ChangedBy *uuid.UUID `json:"changed_by,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	ChangedAt string     `json:"changed_at"`
+}
+
+// StatusHistoryResponse is the status transitions of an order, oldest first
+type StatusHistoryResponse struct {
+	Transitions []StatusTransitionResponse `json:"transitions"`
+}
+
+// ToStatusTransitionResponse converts a recorded status transition
+func ToStatusTransitionResponse(t *order.StatusTransition) StatusTransitionResponse {
+	return StatusTransitionResponse{
+		From:      string(t.From),
+		To:        string(t.To),
+		ChangedBy: t.ChangedBy,
+		Reason:    t.Reason,
+		ChangedAt: t.ChangedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// OrderHistoryResponse is an order's recorded changes and its state after them
+type OrderHistoryResponse struct {
+	Order   *OrderResponse        `json:"order"`
+	Changes []OrderChangeResponse `json:"changes"`
+}
+
+// ToChangeResponse converts a recorded order change, with amounts formatted for locale
+func ToChangeResponse(c *order.Change, locale string) OrderChangeResponse {
+	resp := OrderChangeResponse{
+		Version:    c.Version,
+		Type:       string(c.Type),
+		Status:     string(c.Status),
+		OccurredAt: c.OccurredAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if c.Order != nil {
+		resp.Order = ToResponse(c.Order).Localize(locale)
+	}
+	return resp
+}
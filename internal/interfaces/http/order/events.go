@@ -0,0 +1,57 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+
+	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+	"github.com/onichange/pos-system/pkg/saga"
+)
+
+// HandlePaymentEvent wakes the checkout waiting on a payment once the
+// payment service reports it completed or failed. Checkouts charge their
+// payment under their own ID, so payments of other checkouts, or made
+// outside one, find none waiting and are ignored. Malformed messages are
+// dropped.
+func (h *Handler) HandlePaymentEvent(ctx context.Context, msg amqp.Delivery) error {
+	log := logger.FromContext(ctx)
+
+	var event messagequeue.Event
+	if err := json.Unmarshal(msg.Body, &event); err != nil {
+		log.Errorf("Dropping malformed payment event: %v", err)
+		return nil
+	}
+	if event.Type != payment.EventCompleted && event.Type != payment.EventFailed {
+		return nil
+	}
+	if h.checkout == nil {
+		return nil
+	}
+	var data payment.EventData
+	raw, _ := json.Marshal(event.Data)
+	if err := json.Unmarshal(raw, &data); err != nil || data.PaymentID == uuid.Nil {
+		log.Errorf("Dropping %s event %s without a payment", event.Type, event.ID)
+		return nil
+	}
+
+	e, err := h.checkout.orchestrator.Wake(ctx, data.PaymentID)
+	switch {
+	case errors.Is(err, saga.ErrNotFound), errors.Is(err, saga.ErrAwaiting):
+		return nil
+	case err != nil && e == nil:
+		return fmt.Errorf("failed to wake checkout %s: %w", data.PaymentID, err)
+	case err != nil:
+		// Undone, or left for the orchestrator to resume
+		log.Warnf("Checkout %s of order %s not completed after %s: %v", e.ID, data.OrderID, event.Type, err)
+		return nil
+	}
+	log.Infof("Checkout %s of order %s completed after %s", e.ID, data.OrderID, event.Type)
+	return nil
+}
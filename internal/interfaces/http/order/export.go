@@ -0,0 +1,91 @@
+package order
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/pkg/csvexport"
+	"github.com/onichange/pos-system/pkg/dataexport"
+)
+
+// ExportStore handles GET /internal/v1/stores/:storeId/export, streaming
+// every order of a store to the store service building an export of it
+func (h *Handler) ExportStore(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("storeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+
+	return dataexport.Stream(c, func(ctx context.Context, emit func(v any) error) error {
+		return h.orderRepo.ExportStore(ctx, storeID, func(v *order.Order) error {
+			return emit(v)
+		})
+	})
+}
+
+// orderColumns are the columns of an order export, in their default order
+var orderColumns = []csvexport.Column[*order.Order]{
+	{Name: "id", Value: func(o *order.Order) string { return o.ID.String() }},
+	{Name: "created_at", Value: func(o *order.Order) string { return csvexport.Time(&o.CreatedAt) }},
+	{Name: "completed_at", Value: func(o *order.Order) string { return csvexport.Time(o.CompletedAt) }},
+	{Name: "cancelled_at", Value: func(o *order.Order) string { return csvexport.Time(o.CancelledAt) }},
+	{Name: "store_id", Value: func(o *order.Order) string { return o.StoreID.String() }},
+	{Name: "user_id", Value: func(o *order.Order) string { return o.UserID.String() }},
+	{Name: "shift_id", Value: func(o *order.Order) string {
+		if o.ShiftID == nil {
+			return ""
+		}
+		return o.ShiftID.String()
+	}},
+	{Name: "status", Value: func(o *order.Order) string { return string(o.Status) }},
+	{Name: "currency", Value: func(o *order.Order) string { return o.Currency }},
+	{Name: "items", Value: func(o *order.Order) string { return strconv.Itoa(len(o.Items)) }},
+	{Name: "subtotal", Value: func(o *order.Order) string { return csvexport.Money(o.Subtotal()) }},
+	{Name: "loyalty_discount", Value: func(o *order.Order) string { return csvexport.Money(o.LoyaltyDiscount) }},
+	{Name: "tax_amount", Value: func(o *order.Order) string { return csvexport.Money(o.TaxAmount) }},
+	{Name: "total_amount", Value: func(o *order.Order) string { return csvexport.Money(o.TotalAmount) }},
+	{Name: "tender", Value: func(o *order.Order) string { return o.Tender }},
+	{Name: "coupons", Value: func(o *order.Order) string { return strings.Join(o.Coupons(), ";") }},
+}
+
+// Export handles GET /orders/export?from=&to=&store_id=&format=csv|excel&columns=&gzip=,
+// streaming the orders placed over a period as a CSV file for accounting.
+// from and to are YYYY-MM-DD dates, both included.
+func (h *Handler) Export(c *fiber.Ctx) error {
+	from, to, err := csvexport.Period(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	filter := order.ExportFilter{From: from, To: to}
+	if storeIDStr := c.Query("store_id"); storeIDStr != "" {
+		id, err := uuid.Parse(storeIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid store ID",
+			})
+		}
+		filter.StoreID = &id
+	}
+
+	export, err := csvexport.FromQuery(c, orderColumns)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	name := "orders-" + c.Query("from") + "-" + c.Query("to")
+	return export.Stream(c, name, func(ctx context.Context, emit func(*order.Order) error) error {
+		return h.orderRepo.Export(ctx, filter, emit)
+	})
+}
@@ -1,24 +1,68 @@
 package order
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
+	"github.com/onichange/pos-system/internal/domain/cart"
 	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/pkg/i18n"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/performance"
 	"github.com/onichange/pos-system/pkg/validator"
+	"github.com/onichange/pos-system/pkg/webhook"
 )
 
+// EventPublisher publishes order events to the message broker
+type EventPublisher interface {
+	PublishEventContext(ctx context.Context, eventType, routingKey string, data map[string]interface{}) error
+}
+
+// Tx holds the order repository and event publisher of one database
+// transaction, so an order change and the event reporting it are saved
+// together or not at all
+type Tx struct {
+	Orders order.Repository
+	Events EventPublisher
+}
+
+// Transactor runs fn in a database transaction, committing it when fn
+// returns nil and rolling it back otherwise
+type Transactor func(ctx context.Context, fn func(tx *Tx) error) error
+
 // Handler handles order HTTP requests
 type Handler struct {
-	orderRepo order.Repository
+	orderRepo  order.Repository
+	prices     PriceResolver           // Nil keeps item prices as sent
+	promotions PromotionEvaluator      // Nil keeps item discounts as sent
+	taxes      TaxQuoter               // Nil charges no sales tax
+	loyalty    LoyaltyRedeemer         // Nil refuses point redemptions
+	shifts     ShiftReader             // Nil refuses orders rung up on a shift
+	inTx       Transactor              // Saves order changes with their events
+	webhooks   *webhook.Sender         // Nil when no endpoints are configured
+	jobs       *performance.WorkerPool // Delivers webhooks after the response
+	checkout   *checkout               // Nil until EnableCheckout
+	carts      cart.Repository         // Nil until EnableCarts
 }
 
 // NewHandler creates a new order handler
-func NewHandler(orderRepo order.Repository) *Handler {
+func NewHandler(orderRepo order.Repository, prices PriceResolver, promotions PromotionEvaluator, taxes TaxQuoter, loyalty LoyaltyRedeemer, shifts ShiftReader, inTx Transactor, webhooks *webhook.Sender, jobs *performance.WorkerPool) *Handler {
 	return &Handler{
-		orderRepo: orderRepo,
+		orderRepo:  orderRepo,
+		prices:     prices,
+		promotions: promotions,
+		taxes:      taxes,
+		loyalty:    loyalty,
+		shifts:     shifts,
+		inTx:       inTx,
+		webhooks:   webhooks,
+		jobs:       jobs,
 	}
 }
 
@@ -54,8 +98,9 @@ func (h *Handler) GetOrders(c *fiber.Ctx) error {
 	}
 
 	// Get orders
-	orders, err := h.orderRepo.GetByUserID(c.Context(), userID, limit, offset)
+	orders, err := h.orderRepo.GetByUserID(c.UserContext(), userID, limit, offset)
 	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch orders: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch orders",
 		})
@@ -64,7 +109,7 @@ func (h *Handler) GetOrders(c *fiber.Ctx) error {
 	// Convert to response
 	responses := make([]*OrderResponse, len(orders))
 	for i, o := range orders {
-		responses[i] = ToResponse(o)
+		responses[i] = ToResponse(o).Localize(i18n.Locale(c))
 	}
 
 	return c.JSON(fiber.Map{
@@ -100,7 +145,7 @@ func (h *Handler) GetOrderByID(c *fiber.Ctx) error {
 	}
 
 	// Get order
-	o, err := h.orderRepo.GetByID(c.Context(), orderID)
+	o, err := h.orderRepo.GetByID(c.UserContext(), orderID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Order not found",
@@ -114,7 +159,28 @@ func (h *Handler) GetOrderByID(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(ToResponse(o))
+	return c.JSON(ToResponse(o).Localize(i18n.Locale(c)))
+}
+
+// GetOrderInternal handles GET /internal/v1/orders/:id, called by services
+// that act on an order for its owner, such as the receipt service. Callers
+// check ownership themselves.
+func (h *Handler) GetOrderInternal(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	o, err := h.orderRepo.GetByID(c.UserContext(), orderID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Order not found",
+		})
+	}
+
+	return c.JSON(o)
 }
 
 // CreateOrder handles POST /orders
@@ -150,6 +216,12 @@ func (h *Handler) CreateOrder(c *fiber.Ctx) error {
 		})
 	}
 
+	// Price the items from the catalog
+	currency, err := h.priceItems(c.UserContext(), req.StoreID, req.Items)
+	if err != nil {
+		return pricingFailed(c, err)
+	}
+
 	// Create order
 	o := &order.Order{
 		ID:              uuid.New(),
@@ -160,20 +232,54 @@ func (h *Handler) CreateOrder(c *fiber.Ctx) error {
 		ShippingAddress: req.ShippingAddress,
 		BillingAddress:  req.BillingAddress,
 		Notes:           req.Notes,
-		Currency:        "USD",
+		Currency:        currency,
+	}
+
+	// Link the order to the register shift it is rung up on
+	if err := h.assignShift(c.UserContext(), o, req.ShiftID, req.Tender); err != nil {
+		return shiftFailed(c, err)
+	}
+
+	// Discount the items by the promotions running in the store, and those
+	// the coupons unlock
+	if err := h.applyPromotions(c.UserContext(), o, req.CouponCodes); err != nil {
+		return promotionsFailed(c, err)
+	}
+
+	// Charge the sales tax of the store or shipping address
+	if err := h.applyTax(c.UserContext(), o); err != nil {
+		return taxFailed(c, err)
 	}
 
 	// Calculate total
 	o.TotalAmount = o.CalculateTotal()
 
+	// Count the coupons used
+	if err := h.redeemCoupons(c.UserContext(), o); err != nil {
+		return promotionsFailed(c, err)
+	}
+
+	// Pay for part of the order with loyalty points
+	if err := h.redeemPoints(c.UserContext(), o, req.RedeemPoints); err != nil {
+		h.releaseCoupons(c.UserContext(), o)
+		return redemptionFailed(c, err)
+	}
+
 	// Save order
-	if err := h.orderRepo.Create(c.Context(), o); err != nil {
+	if err := h.save(c.UserContext(), order.EventCreated, o, func(orders order.Repository) error {
+		return orders.Create(c.UserContext(), o)
+	}); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to create order: %v", err)
+		h.releasePoints(c.UserContext(), o)
+		h.releaseCoupons(c.UserContext(), o)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create order",
 		})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(ToResponse(o))
+	metrics.RecordOrderCreated(o.StoreID.String(), o.Currency, o.TotalAmount)
+
+	return c.Status(fiber.StatusCreated).JSON(ToResponse(o).Localize(i18n.Locale(c)))
 }
 
 // UpdateOrder handles PUT /orders/:id
@@ -202,7 +308,7 @@ func (h *Handler) UpdateOrder(c *fiber.Ctx) error {
 	}
 
 	// Get existing order
-	o, err := h.orderRepo.GetByID(c.Context(), orderID)
+	o, err := h.orderRepo.GetByID(c.UserContext(), orderID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Order not found",
@@ -233,12 +339,26 @@ func (h *Handler) UpdateOrder(c *fiber.Ctx) error {
 
 	// Update fields
 	if len(req.Items) > 0 {
+		currency, err := h.priceItems(c.UserContext(), o.StoreID, req.Items)
+		if err != nil {
+			return pricingFailed(c, err)
+		}
 		o.Items = req.Items
-		o.TotalAmount = o.CalculateTotal()
+		o.Currency = currency
+		if err := h.applyPromotions(c.UserContext(), o, o.Coupons()); err != nil {
+			return promotionsFailed(c, err)
+		}
 	}
 	if req.ShippingAddress != nil {
 		o.ShippingAddress = req.ShippingAddress
 	}
+	// New items or a new address change the sales tax
+	if len(req.Items) > 0 || req.ShippingAddress != nil {
+		if err := h.applyTax(c.UserContext(), o); err != nil {
+			return taxFailed(c, err)
+		}
+		o.TotalAmount = o.CalculateTotal()
+	}
 	if req.BillingAddress != nil {
 		o.BillingAddress = req.BillingAddress
 	}
@@ -247,13 +367,16 @@ func (h *Handler) UpdateOrder(c *fiber.Ctx) error {
 	}
 
 	// Save order
-	if err := h.orderRepo.Update(c.Context(), o); err != nil {
+	if err := h.save(c.UserContext(), order.EventUpdated, o, func(orders order.Repository) error {
+		return orders.Update(c.UserContext(), o)
+	}); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to update order: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update order",
 		})
 	}
 
-	return c.JSON(ToResponse(o))
+	return c.JSON(ToResponse(o).Localize(i18n.Locale(c)))
 }
 
 // DeleteOrder handles DELETE /orders/:id
@@ -282,7 +405,7 @@ func (h *Handler) DeleteOrder(c *fiber.Ctx) error {
 	}
 
 	// Get existing order
-	o, err := h.orderRepo.GetByID(c.Context(), orderID)
+	o, err := h.orderRepo.GetByID(c.UserContext(), orderID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Order not found",
@@ -304,11 +427,147 @@ func (h *Handler) DeleteOrder(c *fiber.Ctx) error {
 	}
 
 	// Delete (soft delete)
-	if err := h.orderRepo.Delete(c.Context(), orderID); err != nil {
+	o.Status = order.StatusCancelled
+	if err := h.save(c.UserContext(), order.EventCancelled, o, func(orders order.Repository) error {
+		return orders.Delete(c.UserContext(), orderID)
+	}); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to delete order: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete order",
 		})
 	}
+	h.releasePoints(c.UserContext(), o)
+	h.releaseCoupons(c.UserContext(), o)
 
 	return c.Status(fiber.StatusNoContent).Send(nil)
 }
+
+// UpdateOrderStatus handles PATCH (and PUT) /orders/:id/status, moving an
+// order along the transitions the order domain allows and recording the
+// move in its status history. Moves it does not allow answer 409 with the
+// statuses the order can move to.
+func (h *Handler) UpdateOrderStatus(c *fiber.Ctx) error {
+	// Parse order ID
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	// Parse request
+	var req UpdateOrderStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Validate request
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	// Get existing order
+	o, err := h.orderRepo.GetByID(c.UserContext(), orderID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Order not found",
+		})
+	}
+
+	var changedBy *uuid.UUID
+	if userID, err := uuid.Parse(fmt.Sprint(c.Locals("user_id"))); err == nil {
+		changedBy = &userID
+	}
+	transition := order.NewStatusTransition(o, req.Status, changedBy, req.Reason)
+
+	// Check the transition
+	if err := o.TransitionTo(req.Status, transition.ChangedAt); err != nil {
+		return transitionConflict(c, o, req.Status)
+	}
+
+	eventType := order.EventUpdated
+	switch req.Status {
+	case order.StatusDelivered:
+		eventType = order.EventCompleted
+	case order.StatusCancelled:
+		eventType = order.EventCancelled
+	case order.StatusRefunded:
+		eventType = order.EventRefunded
+	}
+
+	// Save it, unless the order moved since it was read
+	err = h.save(c.UserContext(), eventType, o, func(orders order.Repository) error {
+		return orders.UpdateStatus(c.UserContext(), orderID, transition)
+	})
+	if errors.Is(err, order.ErrInvalidTransition) {
+		current, err := h.orderRepo.GetByID(c.UserContext(), orderID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Order not found",
+			})
+		}
+		return transitionConflict(c, current, req.Status)
+	}
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to update order status: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update order status",
+		})
+	}
+
+	// Cancelled and refunded orders give back their points and coupons
+	if req.Status == order.StatusCancelled || req.Status == order.StatusRefunded {
+		h.releasePoints(c.UserContext(), o)
+		h.releaseCoupons(c.UserContext(), o)
+	}
+
+	return c.JSON(ToResponse(o).Localize(i18n.Locale(c)))
+}
+
+// transitionConflict answers 409 for an order that cannot move to status,
+// with the statuses it can move to
+func transitionConflict(c *fiber.Ctx, o *order.Order, status order.OrderStatus) error {
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error":   fmt.Sprintf("Order cannot move from %s to %s", o.Status, status),
+		"status":  o.Status,
+		"allowed": o.NextStatuses(),
+	})
+}
+
+// save runs write with the order repository of a transaction recording the
+// eventType event of o for the message broker, so the change and its event
+// are saved together or not at all. Once saved, the event also goes to the
+// webhooks.
+func (h *Handler) save(ctx context.Context, eventType string, o *order.Order, write func(orders order.Repository) error) error {
+	err := h.inTx(ctx, func(tx *Tx) error {
+		if err := write(tx.Orders); err != nil {
+			return err
+		}
+		return tx.Events.PublishEventContext(ctx, eventType, eventType, order.NewEventData(o).Map())
+	})
+	if err != nil {
+		return err
+	}
+	h.notify(ctx, eventType, o)
+	return nil
+}
+
+// notify sends an order event to the webhooks once the response is written.
+// Delivery failures are logged; the order change stands.
+func (h *Handler) notify(ctx context.Context, eventType string, o *order.Order) {
+	if h.webhooks == nil {
+		return
+	}
+	data := ToResponse(o)
+	err := h.jobs.Go(ctx, func(ctx context.Context) error {
+		return h.webhooks.Send(ctx, eventType, data)
+	})
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to schedule %s webhook for order %s: %v", eventType, o.ID, err)
+	}
+}
@@ -0,0 +1,107 @@
+package order
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/pkg/i18n"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// GetOrderHistory handles GET /orders/:id/history, listing every recorded
+// change of an order with the order's current state. With as_of, only the
+// changes up to that time are listed, with the order as it was then.
+// Orders only have a history with the event-sourced order store.
+func (h *Handler) GetOrderHistory(c *fiber.Ctx) error {
+	history, ok := h.orderRepo.(order.History)
+	if !ok {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
+			"error": "Order history is not recorded",
+		})
+	}
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	at := time.Now()
+	if asOf := c.Query("as_of"); asOf != "" {
+		if at, err = time.Parse(time.RFC3339, asOf); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "as_of must be an RFC 3339 time",
+			})
+		}
+	}
+
+	o, err := history.GetAsOf(c.UserContext(), orderID, at)
+	if errors.Is(err, order.ErrOrderNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Order not found",
+		})
+	}
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to replay order %s: %v", orderID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch order history",
+		})
+	}
+
+	changes, err := history.Changes(c.UserContext(), orderID)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch order %s history: %v", orderID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch order history",
+		})
+	}
+
+	locale := i18n.Locale(c)
+	resp := OrderHistoryResponse{
+		Order:   ToResponse(o).Localize(locale),
+		Changes: []OrderChangeResponse{},
+	}
+	for _, change := range changes {
+		if change.OccurredAt.After(at) {
+			break
+		}
+		resp.Changes = append(resp.Changes, ToChangeResponse(change, locale))
+	}
+	return c.JSON(resp)
+}
+
+// GetOrderStatusHistory handles GET /orders/:id/status/history, listing the
+// status transitions of an order, oldest first. Unlike GetOrderHistory it
+// works with either order store.
+func (h *Handler) GetOrderStatusHistory(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	history, err := h.orderRepo.StatusHistory(c.UserContext(), orderID)
+	if errors.Is(err, order.ErrOrderNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Order not found",
+		})
+	}
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch order %s status history: %v", orderID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch order status history",
+		})
+	}
+
+	resp := StatusHistoryResponse{Transitions: make([]StatusTransitionResponse, 0, len(history))}
+	for _, t := range history {
+		resp.Transitions = append(resp.Transitions, ToStatusTransitionResponse(t))
+	}
+	return c.JSON(resp)
+}
@@ -0,0 +1,76 @@
+package order
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/loyalty"
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// LoyaltyRedeemer spends loyalty points as order discounts
+type LoyaltyRedeemer interface {
+	Redeem(ctx context.Context, userID, orderID uuid.UUID, points int64, orderTotal float64, currency string) (*loyalty.Redemption, error)
+	Release(ctx context.Context, orderID uuid.UUID) error
+}
+
+// errLoyaltyDisabled is returned when points are redeemed without a loyalty service
+var errLoyaltyDisabled = errors.New("loyalty redemption disabled")
+
+// redeemPoints spends points of the order's customer as a discount on o and
+// recalculates its total
+func (h *Handler) redeemPoints(ctx context.Context, o *order.Order, points int64) error {
+	if points <= 0 {
+		return nil
+	}
+	if h.loyalty == nil {
+		return errLoyaltyDisabled
+	}
+
+	redemption, err := h.loyalty.Redeem(ctx, o.UserID, o.ID, points, o.Subtotal(), o.Currency)
+	if err != nil {
+		return err
+	}
+	o.LoyaltyPoints = redemption.Points
+	o.LoyaltyDiscount = redemption.Amount
+	o.TotalAmount = o.CalculateTotal()
+	return nil
+}
+
+// releasePoints returns the points redeemed for o. Failures are logged; the
+// loyalty service also releases them when it sees the order cancelled.
+func (h *Handler) releasePoints(ctx context.Context, o *order.Order) {
+	if o.LoyaltyPoints == 0 || h.loyalty == nil {
+		return
+	}
+	if err := h.loyalty.Release(ctx, o.ID); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to release %d loyalty points of order %s: %v", o.LoyaltyPoints, o.ID, err)
+	}
+}
+
+// redemptionFailed answers a request whose points could not be redeemed
+func redemptionFailed(c *fiber.Ctx, err error) error {
+	var message string
+	switch {
+	case errors.Is(err, errLoyaltyDisabled):
+		message = "Loyalty points cannot be redeemed"
+	case errors.Is(err, loyalty.ErrInsufficientPoints):
+		message = "Insufficient loyalty points"
+	case errors.Is(err, loyalty.ErrBelowMinimum):
+		message = "Too few loyalty points to redeem"
+	case errors.Is(err, loyalty.ErrExceedsLimit):
+		message = "Loyalty points cannot pay for this much of the order"
+	default:
+		logger.FromContext(c.UserContext()).Errorf("Failed to redeem loyalty points: %v", err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Loyalty service unavailable",
+		})
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": message,
+	})
+}
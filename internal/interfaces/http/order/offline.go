@@ -0,0 +1,247 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/shift"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// priceTolerance is how far apart two prices may be and still match
+const priceTolerance = 0.005
+
+// ImportOfflineOrder handles POST /internal/v1/orders/offline, called by the
+// sync service with an order a terminal rang up while offline. Answers 201
+// with an accepted order, and 200 with the order a repeated upload created
+// first or with the conflicts for which the order's policy rejects it.
+func (h *Handler) ImportOfflineOrder(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	var req order.OfflineOrder
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+	if req.Policy == "" {
+		req.Policy = order.PolicyServerWins
+	}
+	if !req.Policy.Valid() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "conflict_policy must be client_wins, server_wins or reject",
+		})
+	}
+
+	if existing, err := h.orderRepo.GetByID(ctx, req.ID); err == nil {
+		return c.JSON(order.ImportResult{Status: order.ImportDuplicate, Order: existing})
+	} else if !errors.Is(err, order.ErrOrderNotFound) {
+		logger.FromContext(ctx).Errorf("Failed to look up offline order %s: %v", req.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to import order",
+		})
+	}
+
+	currency, conflicts, err := h.reconcileItems(ctx, &req)
+	if err != nil {
+		return pricingFailed(c, err)
+	}
+	for _, conflict := range conflicts {
+		if conflict.Resolution == order.ResolutionRejected {
+			return c.JSON(order.ImportResult{
+				Status:    order.ImportRejected,
+				Conflicts: conflicts,
+				Error:     "Order conflicts with the catalog",
+			})
+		}
+	}
+
+	o := &order.Order{
+		ID:        req.ID,
+		UserID:    req.UserID,
+		StoreID:   req.StoreID,
+		Status:    order.StatusPending,
+		Items:     req.Items,
+		Notes:     req.Notes,
+		Currency:  currency,
+		CreatedAt: req.CreatedAt.UTC(),
+	}
+
+	if err := h.assignOfflineShift(ctx, o, req.ShiftID, req.Tender); err != nil {
+		return shiftFailed(c, err)
+	}
+	// Terminals apply the promotions they knew of; only a repriced order is
+	// discounted afresh
+	if req.Policy == order.PolicyServerWins {
+		if err := h.applyPromotions(ctx, o, nil); err != nil {
+			return promotionsFailed(c, err)
+		}
+	}
+	if err := h.applyTax(ctx, o); err != nil {
+		return taxFailed(c, err)
+	}
+	o.TotalAmount = o.CalculateTotal()
+
+	if err := h.save(ctx, order.EventCreated, o, func(orders order.Repository) error {
+		return orders.Create(ctx, o)
+	}); err != nil {
+		// A concurrent upload of the same order got there first
+		if errors.Is(err, order.ErrOrderExists) {
+			if existing, err := h.orderRepo.GetByID(ctx, req.ID); err == nil {
+				return c.JSON(order.ImportResult{Status: order.ImportDuplicate, Order: existing})
+			}
+		}
+		logger.FromContext(ctx).Errorf("Failed to create offline order %s: %v", req.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to import order",
+		})
+	}
+
+	metrics.RecordOrderCreated(o.StoreID.String(), o.Currency, o.TotalAmount)
+
+	return c.Status(fiber.StatusCreated).JSON(order.ImportResult{
+		Status:    order.ImportAccepted,
+		Order:     o,
+		Conflicts: conflicts,
+	})
+}
+
+// reconcileItems checks the items of an offline order against the catalog
+// at its store's current prices and resolves each difference by the order's
+// policy. It sets the items' names and categories from the catalog, and
+// their prices as the policy resolved them, and returns the order currency
+// and the conflicts. Without a resolver the items are kept as sent.
+func (h *Handler) reconcileItems(ctx context.Context, req *order.OfflineOrder) (string, []order.Conflict, error) {
+	variantIDs := make([]uuid.UUID, len(req.Items))
+	for i, item := range req.Items {
+		id, err := uuid.Parse(item.ProductID)
+		if err != nil {
+			return "", nil, &itemError{message: fmt.Sprintf("Invalid product ID %q", item.ProductID)}
+		}
+		if item.Quantity <= 0 {
+			return "", nil, &itemError{message: fmt.Sprintf("Invalid quantity for product %s", item.ProductID)}
+		}
+		if item.UnitPrice < 0 {
+			return "", nil, &itemError{message: fmt.Sprintf("Invalid unit price for product %s", item.ProductID)}
+		}
+		variantIDs[i] = id
+	}
+
+	if h.prices == nil {
+		for i := range req.Items {
+			setSubtotal(&req.Items[i])
+		}
+		return orDefaultCurrency(req.Currency), nil, nil
+	}
+
+	priced, err := h.prices.ResolvePrices(ctx, req.StoreID, variantIDs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	currency := req.Currency
+	var conflicts []order.Conflict
+	for i := range req.Items {
+		item := &req.Items[i]
+		pv, ok := priced[variantIDs[i]]
+		if !ok || !pv.Sellable() {
+			resolution := order.ResolutionRejected
+			if req.Policy == order.PolicyClientWins {
+				resolution = order.ResolutionKeptClient
+			}
+			conflicts = append(conflicts, order.Conflict{
+				ProductID:   item.ProductID,
+				Kind:        order.ConflictUnavailable,
+				ClientPrice: item.UnitPrice,
+				Resolution:  resolution,
+			})
+			setSubtotal(item)
+			continue
+		}
+		if currency == "" {
+			currency = pv.Currency
+		}
+		if pv.Currency != currency {
+			return "", nil, &itemError{message: "Products priced in different currencies cannot be ordered together"}
+		}
+
+		item.Name = pv.Name
+		item.CategoryID = pv.CategoryID
+		if math.Abs(pv.Price-item.UnitPrice) > priceTolerance {
+			conflict := order.Conflict{
+				ProductID:   item.ProductID,
+				Kind:        order.ConflictPrice,
+				ClientPrice: item.UnitPrice,
+				ServerPrice: pv.Price,
+			}
+			switch req.Policy {
+			case order.PolicyClientWins:
+				conflict.Resolution = order.ResolutionKeptClient
+			case order.PolicyServerWins:
+				conflict.Resolution = order.ResolutionUsedServer
+				item.UnitPrice = pv.Price
+			default:
+				conflict.Resolution = order.ResolutionRejected
+			}
+			conflicts = append(conflicts, conflict)
+		}
+		setSubtotal(item)
+	}
+	return orDefaultCurrency(currency), conflicts, nil
+}
+
+// orDefaultCurrency returns currency, or the default currency when empty
+func orDefaultCurrency(currency string) string {
+	if currency == "" {
+		return defaultCurrency
+	}
+	return currency
+}
+
+// setSubtotal sets an item's subtotal from its unit price, quantity and
+// discount, keeping the discount within the item's price
+func setSubtotal(item *order.OrderItem) {
+	gross := item.UnitPrice * float64(item.Quantity)
+	item.Discount = math.Min(math.Max(item.Discount, 0), gross)
+	item.Subtotal = gross - item.Discount
+}
+
+// assignOfflineShift links an offline order to the register shift it was
+// rung up on. Unlike assignShift it accepts a shift closed since, as long as
+// the order was rung up before the shift closed.
+func (h *Handler) assignOfflineShift(ctx context.Context, o *order.Order, shiftID *uuid.UUID, tender string) error {
+	if shiftID == nil {
+		return nil
+	}
+	if h.shifts == nil {
+		return errShiftsDisabled
+	}
+
+	s, err := h.shifts.GetShift(ctx, *shiftID)
+	if err != nil {
+		return err
+	}
+	if o.CreatedAt.Before(s.OpenedAt) || (!s.IsOpen() && s.ClosedAt != nil && o.CreatedAt.After(*s.ClosedAt)) {
+		return shift.ErrShiftClosed
+	}
+	if s.StoreID != o.StoreID || s.Currency != o.Currency {
+		return errShiftMismatch
+	}
+	o.ShiftID = &s.ID
+	o.Tender = tender
+	return nil
+}
@@ -0,0 +1,101 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/catalog"
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// defaultCurrency is the currency of orders priced without a catalog
+const defaultCurrency = "USD"
+
+// PriceResolver prices order items from the product catalog
+type PriceResolver interface {
+	ResolvePrices(ctx context.Context, storeID uuid.UUID, variantIDs []uuid.UUID) (map[uuid.UUID]*catalog.PricedVariant, error)
+}
+
+// itemError is a problem with the items of an order, reported to the client
+type itemError struct {
+	message string
+}
+
+func (e *itemError) Error() string {
+	return e.message
+}
+
+// priceItems sets the name, category, unit price and subtotal of each item
+// from the catalog at storeID's prices, and returns the order currency.
+// Subtotals and discounts are rounded to cents. Item product IDs are catalog
+// variant IDs; the names and prices sent are ignored. Without a resolver the
+// items are kept as sent.
+func (h *Handler) priceItems(ctx context.Context, storeID uuid.UUID, items []order.OrderItem) (string, error) {
+	if h.prices == nil {
+		return defaultCurrency, nil
+	}
+
+	variantIDs := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		id, err := uuid.Parse(item.ProductID)
+		if err != nil {
+			return "", &itemError{message: fmt.Sprintf("Invalid product ID %q", item.ProductID)}
+		}
+		if item.Quantity <= 0 {
+			return "", &itemError{message: fmt.Sprintf("Invalid quantity for product %s", item.ProductID)}
+		}
+		variantIDs[i] = id
+	}
+
+	priced, err := h.prices.ResolvePrices(ctx, storeID, variantIDs)
+	if err != nil {
+		return "", err
+	}
+
+	currency := ""
+	for i := range items {
+		pv, ok := priced[variantIDs[i]]
+		if !ok || !pv.Sellable() {
+			return "", &itemError{message: fmt.Sprintf("Product %s is not available", items[i].ProductID)}
+		}
+		if currency != "" && pv.Currency != currency {
+			return "", &itemError{message: "Products priced in different currencies cannot be ordered together"}
+		}
+		currency = pv.Currency
+
+		item := &items[i]
+		item.Name = pv.Name
+		item.CategoryID = pv.CategoryID
+		item.UnitPrice = pv.Price
+		gross := roundCents(pv.Price * float64(item.Quantity))
+		item.Discount = math.Min(math.Max(roundCents(item.Discount), 0), gross)
+		item.Subtotal = roundCents(gross - item.Discount)
+	}
+	return currency, nil
+}
+
+// roundCents rounds an amount to cents, so that 3 items at 0.10 come to
+// 0.30 rather than 0.30000000000000004
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// pricingFailed answers a request whose items could not be priced
+func pricingFailed(c *fiber.Ctx, err error) error {
+	var invalid *itemError
+	if errors.As(err, &invalid) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": invalid.message,
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("Failed to price order items: %v", err)
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+		"error": "Product catalog unavailable",
+	})
+}
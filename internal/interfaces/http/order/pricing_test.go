@@ -0,0 +1,163 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/catalog"
+	"github.com/onichange/pos-system/internal/domain/order"
+)
+
+// priceList resolves the variants it holds, as the catalog priced them for
+// any store
+type priceList map[uuid.UUID]*catalog.PricedVariant
+
+func (l priceList) ResolvePrices(_ context.Context, _ uuid.UUID, variantIDs []uuid.UUID) (map[uuid.UUID]*catalog.PricedVariant, error) {
+	priced := make(map[uuid.UUID]*catalog.PricedVariant)
+	for _, id := range variantIDs {
+		if pv, ok := l[id]; ok {
+			priced[id] = pv
+		}
+	}
+	return priced, nil
+}
+
+// failingPrices is a catalog that cannot be reached
+type failingPrices struct{}
+
+func (failingPrices) ResolvePrices(context.Context, uuid.UUID, []uuid.UUID) (map[uuid.UUID]*catalog.PricedVariant, error) {
+	return nil, errors.New("connection refused")
+}
+
+func variant(price float64, currency string, storePrice bool) *catalog.PricedVariant {
+	return &catalog.PricedVariant{
+		VariantID:  uuid.New(),
+		Name:       "Coffee - Large",
+		Status:     catalog.StatusActive,
+		Price:      price,
+		Currency:   currency,
+		StorePrice: storePrice,
+	}
+}
+
+func TestPriceItems(t *testing.T) {
+	storePriced := variant(3.5, "EUR", true)
+	basePriced := variant(4, "EUR", false)
+	dime := variant(0.1, "USD", false)
+	third := variant(1.0/3, "USD", false)
+	dollars := variant(2, "USD", true)
+	archived := variant(1, "EUR", false)
+	archived.Status = catalog.StatusArchived
+	prices := priceList{}
+	for _, pv := range []*catalog.PricedVariant{storePriced, basePriced, dime, third, dollars, archived} {
+		prices[pv.VariantID] = pv
+	}
+
+	item := func(pv *catalog.PricedVariant, quantity int, discount float64) order.OrderItem {
+		return order.OrderItem{ProductID: pv.VariantID.String(), Name: "Sent name", UnitPrice: 99, Quantity: quantity, Discount: discount}
+	}
+
+	tests := []struct {
+		name         string
+		items        []order.OrderItem
+		wantCurrency string
+		wantPrices   []float64
+		wantDiscount []float64
+		wantSubtotal []float64
+		wantErr      string
+	}{
+		{
+			name:         "store and base prices replace the prices sent",
+			items:        []order.OrderItem{item(storePriced, 2, 0), item(basePriced, 1, 0)},
+			wantCurrency: "EUR",
+			wantPrices:   []float64{3.5, 4},
+			wantDiscount: []float64{0, 0},
+			wantSubtotal: []float64{7, 4},
+		},
+		{
+			name:         "subtotals are rounded to cents",
+			items:        []order.OrderItem{item(dime, 3, 0), item(third, 2, 0)},
+			wantCurrency: "USD",
+			wantPrices:   []float64{0.1, 1.0 / 3},
+			wantDiscount: []float64{0, 0},
+			wantSubtotal: []float64{0.3, 0.67},
+		},
+		{
+			name:         "discounts are rounded and kept within the item",
+			items:        []order.OrderItem{item(dollars, 3, 0.125), item(dollars, 1, 5), item(dime, 1, -1)},
+			wantCurrency: "USD",
+			wantPrices:   []float64{2, 2, 0.1},
+			wantDiscount: []float64{0.13, 2, 0},
+			wantSubtotal: []float64{5.87, 0, 0.1},
+		},
+		{
+			name:    "currencies cannot be mixed",
+			items:   []order.OrderItem{item(storePriced, 1, 0), item(dime, 1, 0)},
+			wantErr: "Products priced in different currencies cannot be ordered together",
+		},
+		{
+			name:    "variants not sellable",
+			items:   []order.OrderItem{item(archived, 1, 0)},
+			wantErr: "Product " + archived.VariantID.String() + " is not available",
+		},
+		{
+			name:    "variants not in the catalog",
+			items:   []order.OrderItem{{ProductID: uuid.Nil.String(), Quantity: 1}},
+			wantErr: "Product " + uuid.Nil.String() + " is not available",
+		},
+		{
+			name:    "product IDs that are not variant IDs",
+			items:   []order.OrderItem{{ProductID: "coffee", Quantity: 1}},
+			wantErr: `Invalid product ID "coffee"`,
+		},
+		{
+			name:    "quantities below one",
+			items:   []order.OrderItem{item(dime, 0, 0)},
+			wantErr: "Invalid quantity for product " + dime.VariantID.String(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{prices: prices}
+			currency, err := h.priceItems(context.Background(), uuid.New(), tt.items)
+			if tt.wantErr != "" {
+				var invalid *itemError
+				require.ErrorAs(t, err, &invalid)
+				assert.Equal(t, tt.wantErr, invalid.message)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantCurrency, currency)
+			for i, item := range tt.items {
+				assert.Equal(t, "Coffee - Large", item.Name, "name of item %d", i)
+				assert.Equal(t, tt.wantPrices[i], item.UnitPrice, "unit price of item %d", i)
+				assert.Equal(t, tt.wantDiscount[i], item.Discount, "discount of item %d", i)
+				assert.Equal(t, tt.wantSubtotal[i], item.Subtotal, "subtotal of item %d", i)
+			}
+		})
+	}
+}
+
+func TestPriceItemsWithoutCatalog(t *testing.T) {
+	items := []order.OrderItem{{ProductID: "coffee", Name: "Coffee", UnitPrice: 2.5, Quantity: 2, Subtotal: 5}}
+
+	// Without a catalog the items are kept as sent, in the default currency
+	currency, err := (&Handler{}).priceItems(context.Background(), uuid.New(), items)
+	require.NoError(t, err)
+	assert.Equal(t, defaultCurrency, currency)
+	assert.Equal(t, order.OrderItem{ProductID: "coffee", Name: "Coffee", UnitPrice: 2.5, Quantity: 2, Subtotal: 5}, items[0])
+
+	// An unreachable catalog is not a problem with the items
+	_, err = (&Handler{prices: failingPrices{}}).priceItems(context.Background(), uuid.New(),
+		[]order.OrderItem{{ProductID: uuid.NewString(), Quantity: 1}})
+	require.Error(t, err)
+	var invalid *itemError
+	assert.False(t, errors.As(err, &invalid))
+}
@@ -0,0 +1,135 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/promotion"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// PromotionEvaluator discounts order lines by the promotions running in
+// their store and keeps count of the coupons orders use
+type PromotionEvaluator interface {
+	Evaluate(ctx context.Context, cart *promotion.Cart) (*promotion.Result, error)
+	RedeemCoupon(ctx context.Context, code string, orderID, userID uuid.UUID) error
+	ReleaseCoupons(ctx context.Context, orderID uuid.UUID) error
+}
+
+// errCouponsDisabled is returned when coupons are presented without a
+// promotion service
+var errCouponsDisabled = errors.New("coupons disabled")
+
+// applyPromotions sets the item discounts of o from the promotions running
+// in its store, and those the coupons unlock, and records the promotions
+// that applied. Without an evaluator the discounts are kept as priced.
+func (h *Handler) applyPromotions(ctx context.Context, o *order.Order, coupons []string) error {
+	if h.promotions == nil {
+		if len(coupons) > 0 {
+			return errCouponsDisabled
+		}
+		return nil
+	}
+
+	cart := &promotion.Cart{
+		StoreID:  o.StoreID,
+		UserID:   &o.UserID,
+		Currency: o.Currency,
+		Lines:    make([]promotion.Line, len(o.Items)),
+		Coupons:  coupons,
+	}
+	for i, item := range o.Items {
+		cart.Lines[i] = promotion.Line{
+			ProductID:  item.ProductID,
+			CategoryID: item.CategoryID,
+			Quantity:   item.Quantity,
+			UnitPrice:  item.UnitPrice,
+		}
+	}
+	if len(o.Promotions) > 0 {
+		cart.OrderID = &o.ID // Repriced; its coupons stay redeemed
+	}
+
+	result, err := h.promotions.Evaluate(ctx, cart)
+	if err != nil {
+		return err
+	}
+	if len(result.LineDiscounts) != len(o.Items) {
+		return fmt.Errorf("promotion service discounted %d lines of %d", len(result.LineDiscounts), len(o.Items))
+	}
+
+	for i := range o.Items {
+		item := &o.Items[i]
+		gross := item.UnitPrice * float64(item.Quantity)
+		item.Discount = math.Min(math.Max(result.LineDiscounts[i], 0), gross)
+		item.Subtotal = gross - item.Discount
+	}
+	o.Promotions = make([]order.AppliedPromotion, len(result.Applied))
+	for i, applied := range result.Applied {
+		o.Promotions[i] = order.AppliedPromotion{
+			PromotionID: applied.PromotionID,
+			Name:        applied.Name,
+			Amount:      applied.Amount,
+			Coupon:      applied.Coupon,
+		}
+	}
+	return nil
+}
+
+// redeemCoupons counts the use of the coupons that discounted o. A coupon
+// used up meanwhile fails the order, releasing those redeemed before it.
+func (h *Handler) redeemCoupons(ctx context.Context, o *order.Order) error {
+	codes := o.Coupons()
+	if len(codes) == 0 {
+		return nil
+	}
+	for _, code := range codes {
+		if err := h.promotions.RedeemCoupon(ctx, code, o.ID, o.UserID); err != nil {
+			h.releaseCoupons(ctx, o)
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseCoupons gives back the coupons o redeemed. Failures are logged; the
+// uses stay counted.
+func (h *Handler) releaseCoupons(ctx context.Context, o *order.Order) {
+	if len(o.Coupons()) == 0 || h.promotions == nil {
+		return
+	}
+	if err := h.promotions.ReleaseCoupons(ctx, o.ID); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to release coupons of order %s: %v", o.ID, err)
+	}
+}
+
+// promotionsFailed answers a request whose promotions could not be
+// evaluated, with 400 and the reason for a coupon that cannot be used
+func promotionsFailed(c *fiber.Ctx, err error) error {
+	var message string
+	switch {
+	case errors.Is(err, errCouponsDisabled):
+		message = "Coupons cannot be used"
+	case errors.Is(err, promotion.ErrCouponNotFound):
+		message = "Coupon not found"
+	case errors.Is(err, promotion.ErrCouponInactive),
+		errors.Is(err, promotion.ErrCouponExpired),
+		errors.Is(err, promotion.ErrCouponExhausted),
+		errors.Is(err, promotion.ErrCouponNotApplicable):
+		message = "Coupon cannot be used: " + err.Error()
+	default:
+		logger.FromContext(c.UserContext()).Errorf("Failed to evaluate promotions: %v", err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Promotions unavailable",
+		})
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": message,
+	})
+}
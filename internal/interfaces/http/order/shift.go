@@ -0,0 +1,76 @@
+package order
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/shift"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// ShiftReader fetches register shifts from the shift service
+type ShiftReader interface {
+	GetShift(ctx context.Context, id uuid.UUID) (*shift.Shift, error)
+}
+
+var (
+	// errShiftsDisabled is returned when an order names a shift without a shift service
+	errShiftsDisabled = errors.New("register shifts disabled")
+	// errShiftMismatch is returned when an order's store or currency differs from its shift's
+	errShiftMismatch = errors.New("shift belongs to another store or currency")
+)
+
+// assignShift links o to the open register shift it is rung up on, paid by
+// tender. Orders without a shift are left alone.
+func (h *Handler) assignShift(ctx context.Context, o *order.Order, shiftID *uuid.UUID, tender string) error {
+	if shiftID == nil {
+		return nil
+	}
+	if h.shifts == nil {
+		return errShiftsDisabled
+	}
+
+	s, err := h.shifts.GetShift(ctx, *shiftID)
+	if err != nil {
+		return err
+	}
+	if !s.IsOpen() {
+		return shift.ErrShiftClosed
+	}
+	if s.StoreID != o.StoreID || s.Currency != o.Currency {
+		return errShiftMismatch
+	}
+	o.ShiftID = &s.ID
+	o.Tender = tender
+	return nil
+}
+
+// shiftFailed answers a request whose shift could not be assigned
+func shiftFailed(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errShiftsDisabled):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Register shifts are not enabled",
+		})
+	case errors.Is(err, shift.ErrShiftNotFound):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shift not found",
+		})
+	case errors.Is(err, shift.ErrShiftClosed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Shift is closed",
+		})
+	case errors.Is(err, errShiftMismatch):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shift belongs to another store or currency",
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("Failed to fetch shift: %v", err)
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+		"error": "Shift service unavailable",
+	})
+}
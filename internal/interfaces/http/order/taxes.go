@@ -0,0 +1,86 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/tax"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// TaxQuoter quotes the sales tax due on orders
+type TaxQuoter interface {
+	Quote(ctx context.Context, req *tax.QuoteRequest) (*tax.Quote, error)
+}
+
+// applyTax sets the sales tax of o and of each of its items, from the
+// jurisdictions covering its shipping address or, without one, its store.
+// Without a quoter orders carry no tax.
+func (h *Handler) applyTax(ctx context.Context, o *order.Order) error {
+	if h.taxes == nil {
+		for i := range o.Items {
+			o.Items[i].Tax = 0
+		}
+		return nil
+	}
+
+	req := &tax.QuoteRequest{
+		StoreID:  o.StoreID,
+		Currency: o.Currency,
+		Lines:    make([]tax.Line, len(o.Items)),
+	}
+	if a := o.ShippingAddress; a != nil && a.Country != "" {
+		req.Address = &tax.Location{Country: a.Country, State: a.State, City: a.City}
+	}
+	for i, item := range o.Items {
+		req.Lines[i] = tax.Line{
+			ProductID:  item.ProductID,
+			CategoryID: item.CategoryID,
+			Quantity:   item.Quantity,
+			Amount:     item.Subtotal,
+		}
+	}
+
+	quote, err := h.taxes.Quote(ctx, req)
+	if err != nil {
+		return err
+	}
+	if len(quote.Lines) != len(o.Items) {
+		return fmt.Errorf("tax service quoted %d lines of %d", len(quote.Lines), len(o.Items))
+	}
+
+	for i := range o.Items {
+		o.Items[i].Tax = quote.Lines[i].Tax
+	}
+	o.TaxAmount = quote.TotalTax
+	o.Taxes = make([]order.TaxLine, len(quote.Jurisdictions))
+	for i, j := range quote.Jurisdictions {
+		o.Taxes[i] = order.TaxLine{
+			JurisdictionID: j.JurisdictionID,
+			Name:           j.Name,
+			Level:          string(j.Level),
+			Taxable:        j.Taxable,
+			Exempt:         j.Exempt,
+			Tax:            j.Tax,
+			Inclusive:      j.Inclusive,
+		}
+	}
+	return nil
+}
+
+// taxFailed answers a request whose tax could not be quoted
+func taxFailed(c *fiber.Ctx, err error) error {
+	if errors.Is(err, tax.ErrStoreNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Store not found",
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("Failed to quote sales tax: %v", err)
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+		"error": "Tax service unavailable",
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/pkg/i18n"
 )
 
 // ProcessPaymentRequest represents process payment request
@@ -11,9 +12,30 @@ type ProcessPaymentRequest struct {
 	OrderID            uuid.UUID `json:"order_id" validate:"required"`
 	PaymentMethodToken string    `json:"payment_method_token" validate:"required"`
 	PaymentMethodType  string    `json:"payment_method_type" validate:"required"`
+	Amount             float64   `json:"amount" validate:"required,gt=0"`
+	Currency           string    `json:"currency,omitempty" validate:"omitempty,len=3,alpha"` // USD when empty
 	ThreeDSecure       bool      `json:"three_d_secure,omitempty"`
 }
 
+// ChargeRequest is a payment charged on behalf of another service, such as
+// the order service checking out an order. The caller picks the payment's
+// ID, so a retried request takes up the payment of the first.
+type ChargeRequest struct {
+	ID                 uuid.UUID `json:"id" validate:"required"`
+	OrderID            uuid.UUID `json:"order_id" validate:"required"`
+	UserID             uuid.UUID `json:"user_id" validate:"required"`
+	PaymentMethodToken string    `json:"payment_method_token" validate:"required"`
+	PaymentMethodType  string    `json:"payment_method_type" validate:"required"`
+	Amount             float64   `json:"amount" validate:"required,gt=0"`
+	Currency           string    `json:"currency,omitempty" validate:"omitempty,len=3,alpha"` // USD when empty
+	ThreeDSecure       bool      `json:"three_d_secure,omitempty"`
+}
+
+// RefundRequest is a refund of a whole payment
+type RefundRequest struct {
+	Reason string `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
+
 // PaymentResponse represents payment response
 type PaymentResponse struct {
 	ID                    uuid.UUID `json:"id"`
@@ -21,11 +43,13 @@ type PaymentResponse struct {
 	UserID                uuid.UUID `json:"user_id"`
 	PaymentMethodType     string    `json:"payment_method_type"`
 	Amount                float64   `json:"amount"`
+	FormattedAmount       string    `json:"formatted_amount,omitempty"` // Set by Localize
 	Currency              string    `json:"currency"`
 	Status                string    `json:"status"`
 	Provider              string    `json:"provider,omitempty"`
 	ProviderTransactionID string    `json:"provider_transaction_id,omitempty"`
 	ThreeDSecureEnabled   bool      `json:"three_d_secure_enabled"`
+	ThreeDSecureStatus    string    `json:"three_d_secure_status,omitempty"`
 	CreatedAt             string    `json:"created_at"`
 	UpdatedAt             string    `json:"updated_at"`
 	ProcessedAt           *string   `json:"processed_at,omitempty"`
@@ -45,6 +69,7 @@ func ToResponse(p *payment.Payment) *PaymentResponse {
 		Provider:              p.Provider,
 		ProviderTransactionID: p.ProviderTransactionID,
 		ThreeDSecureEnabled:   p.ThreeDSecureEnabled,
+		ThreeDSecureStatus:    p.ThreeDSecureStatus,
 		CreatedAt:             p.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:             p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
@@ -60,3 +85,9 @@ func ToResponse(p *payment.Payment) *PaymentResponse {
 
 	return resp
 }
+
+// Localize sets the formatted amount for display in locale
+func (r *PaymentResponse) Localize(locale string) *PaymentResponse {
+	r.FormattedAmount = i18n.FormatMoney(locale, r.Amount, r.Currency)
+	return r
+}
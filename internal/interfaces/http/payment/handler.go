@@ -1,29 +1,72 @@
 package payment
 
 import (
+	"context"
+	"errors"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
 	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/pkg/i18n"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/middleware"
+	"github.com/onichange/pos-system/pkg/payments/provider"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/tenant"
 	"github.com/onichange/pos-system/pkg/validator"
 )
 
+// EventPublisher publishes payment events to the message broker
+type EventPublisher interface {
+	PublishEventContext(ctx context.Context, eventType, routingKey string, data map[string]interface{}) error
+}
+
+// Tx holds the payment repository and event publisher of one database
+// transaction, so a payment change and the events reporting it are saved
+// together or not at all
+type Tx struct {
+	Payments payment.Repository
+	Events   EventPublisher
+}
+
+// Transactor runs fn in a database transaction, committing it when fn
+// returns nil and rolling it back otherwise
+type Transactor func(ctx context.Context, fn func(tx *Tx) error) error
+
+// paymentIDSpace derives payment IDs from idempotency keys, so a retried
+// request finds the payment of the first attempt
+var paymentIDSpace = uuid.MustParse("6f1c2a4e-8d3b-4f5a-9c7e-2b1d0e4f6a8c")
+
 // Handler handles payment HTTP requests
 type Handler struct {
 	paymentRepo payment.Repository
+	payments    provider.Provider
+	providers   *performance.Bulkhead    // Limits concurrent provider calls; nil leaves them unlimited
+	providerAPI *performance.TokenBucket // Limits the rate of provider calls; nil leaves it unlimited
+	inTx        Transactor               // Saves payment changes with their events
 }
 
 // NewHandler creates a new payment handler
-func NewHandler(paymentRepo payment.Repository) *Handler {
+func NewHandler(paymentRepo payment.Repository, payments provider.Provider, providers *performance.Bulkhead, providerAPI *performance.TokenBucket, inTx Transactor) *Handler {
 	return &Handler{
 		paymentRepo: paymentRepo,
+		payments:    payments,
+		providers:   providers,
+		providerAPI: providerAPI,
+		inTx:        inTx,
 	}
 }
 
-// ProcessPayment handles POST /payments
+// ProcessPayment handles POST /payments, charging the payment method
+// through the provider. Requests with an Idempotency-Key can be retried
+// after a failure: the retry continues the payment of the first attempt,
+// and the provider is sent the same idempotency key so the customer is
+// charged once.
 func (h *Handler) ProcessPayment(c *fiber.Ctx) error {
 	userIDStr := c.Locals("user_id")
 	if userIDStr == nil {
@@ -53,6 +96,11 @@ func (h *Handler) ProcessPayment(c *fiber.Ctx) error {
 		})
 	}
 
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = "USD"
+	}
+
 	// Create payment (PCI-DSS: only tokenized data, never raw card data)
 	p := &payment.Payment{
 		ID:                  uuid.New(),
@@ -60,35 +108,122 @@ func (h *Handler) ProcessPayment(c *fiber.Ctx) error {
 		UserID:              userID,
 		PaymentMethodToken:  req.PaymentMethodToken, // Tokenized
 		PaymentMethodType:   payment.PaymentMethodType(req.PaymentMethodType),
+		Amount:              req.Amount,
+		Currency:            currency,
 		Status:              payment.StatusPending,
-		Currency:            "USD",
 		ThreeDSecureEnabled: req.ThreeDSecure,
-		Provider:            "stripe", // Default provider
+		Provider:            h.payments.Name(),
 	}
 
-	// TODO: Integrate with payment provider (Stripe, PayPal, etc.)
-	// For now, simulate processing
-	p.Status = payment.StatusProcessing
-	now := time.Now()
-	p.ProcessedAt = &now
+	ctx := c.UserContext()
+	retried := false
+	if key := c.Get(middleware.IdempotencyKeyHeader); key != "" {
+		p.ID = uuid.NewSHA1(paymentIDSpace, []byte(tenant.IDFromContext(ctx)+"|"+userID.String()+"|"+key))
+		if existing, err := h.paymentRepo.GetByID(ctx, p.ID); err == nil {
+			if existing.Status != payment.StatusPending {
+				return c.Status(fiber.StatusCreated).JSON(ToResponse(existing).Localize(i18n.Locale(c)))
+			}
+			p, retried = existing, true
+		}
+	}
+
+	return h.charge(c, p, retried)
+}
+
+// charge records a new payment, or takes up a retried one still pending,
+// and charges it through the provider, answering with the payment or why it
+// was not charged
+func (h *Handler) charge(c *fiber.Ctx, p *payment.Payment, retried bool) error {
+	ctx := c.UserContext()
+
+	// Record the payment before charging, so no charge is left without one
+	if !retried {
+		err := h.inTx(ctx, func(tx *Tx) error {
+			if err := tx.Payments.Create(ctx, p); err != nil {
+				return err
+			}
+			return tx.publish(ctx, payment.EventCreated, p)
+		})
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to process payment: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to process payment",
+			})
+		}
+	}
+
+	var charge *provider.Charge
+	err := h.callProvider(ctx, func() error {
+		var err error
+		charge, err = h.payments.Charge(ctx, provider.ChargeRequest{
+			Amount:         provider.MinorUnits(p.Amount, p.Currency),
+			Currency:       p.Currency,
+			PaymentMethod:  p.PaymentMethodToken,
+			Capture:        true,
+			ThreeDSecure:   p.ThreeDSecureEnabled,
+			Description:    "Order " + p.OrderID.String(),
+			Metadata:       chargeMetadata(ctx, p),
+			IdempotencyKey: p.ID.String(),
+		})
+		return err
+	})
+
+	// The payment stays pending while the outcome is unknown; retrying with
+	// the same Idempotency-Key settles it
+	if providerBusy(err) {
+		logger.FromContext(ctx).Warnf("Payment %s left pending: %v", p.ID, err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":      "Payment provider busy, try again",
+			"payment_id": p.ID,
+		})
+	}
 
-	// Save payment
-	if err := h.paymentRepo.Create(c.Context(), p); err != nil {
+	var decline *provider.DeclineError
+	var apiErr *provider.APIError
+	switch {
+	case errors.As(err, &decline):
+		change := payment.StatusChange{Action: payment.ActionCharge, Reason: decline.Code}
+		if _, err := h.applyCharge(ctx, p, decline.ChargeID, provider.StatusFailed, change); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to record declined payment %s: %v", p.ID, err)
+		}
+		return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
+			"error":        "Payment declined",
+			"decline_code": decline.Code,
+			"payment_id":   p.ID,
+		})
+	case errors.As(err, &apiErr):
+		logger.FromContext(ctx).Warnf("Provider refused payment %s: %v", p.ID, err)
+		change := payment.StatusChange{Action: payment.ActionCharge, Reason: apiErr.Code}
+		if _, err := h.applyCharge(ctx, p, "", provider.StatusFailed, change); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to record refused payment %s: %v", p.ID, err)
+		}
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":      "Payment method refused by the provider",
+			"payment_id": p.ID,
+		})
+	case err != nil:
+		logger.FromContext(ctx).Errorf("Failed to process payment %s: %v", p.ID, err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to process payment",
 		})
 	}
 
-	// Simulate completion (in production, this would be async via webhook)
-	go func() {
-		time.Sleep(2 * time.Second)
-		p.Status = payment.StatusCompleted
-		completedAt := time.Now()
-		p.CompletedAt = &completedAt
-		h.paymentRepo.Update(c.Context(), p)
-	}()
+	applied, err := h.applyCharge(ctx, p, charge.ID, charge.Status, payment.StatusChange{Action: payment.ActionCharge})
+	if err != nil {
+		// The webhook for the charge, a retry or the reconciler records it
+		logger.FromContext(ctx).Errorf("Failed to record charge %s of payment %s: %v", charge.ID, p.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to process payment",
+		})
+	}
+	if !applied {
+		// A webhook settled the payment first
+		if current, err := h.paymentRepo.GetByID(ctx, p.ID); err == nil {
+			p = current
+		}
+	}
 
-	return c.Status(fiber.StatusCreated).JSON(ToResponse(p))
+	return c.Status(fiber.StatusCreated).JSON(ToResponse(p).Localize(i18n.Locale(c)))
 }
 
 // GetPayment handles GET /payments/:id
@@ -114,7 +249,7 @@ func (h *Handler) GetPayment(c *fiber.Ctx) error {
 		})
 	}
 
-	p, err := h.paymentRepo.GetByID(c.Context(), paymentID)
+	p, err := h.paymentRepo.GetByID(c.UserContext(), paymentID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Payment not found",
@@ -128,7 +263,7 @@ func (h *Handler) GetPayment(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(ToResponse(p))
+	return c.JSON(ToResponse(p).Localize(i18n.Locale(c)))
 }
 
 // GetPaymentsByOrder handles GET /payments/order/:order_id
@@ -140,8 +275,9 @@ func (h *Handler) GetPaymentsByOrder(c *fiber.Ctx) error {
 		})
 	}
 
-	payments, err := h.paymentRepo.GetByOrderID(c.Context(), orderID)
+	payments, err := h.paymentRepo.GetByOrderID(c.UserContext(), orderID)
 	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch payments: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch payments",
 		})
@@ -149,7 +285,7 @@ func (h *Handler) GetPaymentsByOrder(c *fiber.Ctx) error {
 
 	responses := make([]*PaymentResponse, len(payments))
 	for i, p := range payments {
-		responses[i] = ToResponse(p)
+		responses[i] = ToResponse(p).Localize(i18n.Locale(c))
 	}
 
 	return c.JSON(fiber.Map{"data": responses})
@@ -184,8 +320,9 @@ func (h *Handler) GetUserPayments(c *fiber.Ctx) error {
 		}
 	}
 
-	payments, err := h.paymentRepo.GetByUserID(c.Context(), userID, limit, offset)
+	payments, err := h.paymentRepo.GetByUserID(c.UserContext(), userID, limit, offset)
 	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch payments: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch payments",
 		})
@@ -193,7 +330,7 @@ func (h *Handler) GetUserPayments(c *fiber.Ctx) error {
 
 	responses := make([]*PaymentResponse, len(payments))
 	for i, p := range payments {
-		responses[i] = ToResponse(p)
+		responses[i] = ToResponse(p).Localize(i18n.Locale(c))
 	}
 
 	return c.JSON(fiber.Map{
@@ -202,3 +339,95 @@ func (h *Handler) GetUserPayments(c *fiber.Ctx) error {
 		"offset": offset,
 	})
 }
+
+// unsettled are the statuses the provider's answers move a payment from
+var unsettled = []payment.PaymentStatus{payment.StatusPending, payment.StatusProcessing}
+
+// callProvider calls the payment provider through the bulkhead, so a slow
+// provider turns payments away instead of holding every request, and
+// through the rate limit, so bursts stay within the provider's API quota
+func (h *Handler) callProvider(ctx context.Context, call func() error) error {
+	return h.providers.Do(ctx, func() error {
+		return h.providerAPI.Do(ctx, call)
+	})
+}
+
+// providerBusy reports whether a provider call failed without an answer,
+// leaving it to be tried again
+func providerBusy(err error) bool {
+	return errors.Is(err, performance.ErrBulkheadFull) || errors.Is(err, performance.ErrRateLimited) || errors.Is(err, provider.ErrUnavailable)
+}
+
+// applyCharge moves an unsettled payment to where its charge stands at the
+// provider, and publishes its completion or failure. It returns false when
+// there was nothing new, or the payment was settled meanwhile; charges still
+// in progress are settled by a later webhook or the reconciler.
+func (h *Handler) applyCharge(ctx context.Context, p *payment.Payment, chargeID string, status provider.ChargeStatus, change payment.StatusChange) (bool, error) {
+	next := *p
+	if chargeID != "" {
+		next.ProviderTransactionID = chargeID
+	}
+
+	now := time.Now()
+	switch status {
+	case provider.StatusFailed, provider.StatusCanceled:
+		next.Status = payment.StatusFailed
+	case provider.StatusSucceeded:
+		next.Status = payment.StatusCompleted
+		if next.ProcessedAt == nil {
+			next.ProcessedAt = &now
+		}
+		next.CompletedAt = &now
+	default:
+		next.Status = payment.StatusProcessing
+		if status == provider.StatusRequiresAction {
+			next.ThreeDSecureStatus = "action_required"
+		}
+		if next.ProcessedAt == nil {
+			next.ProcessedAt = &now
+		}
+	}
+	if next.Status == p.Status && next.ProviderTransactionID == p.ProviderTransactionID && next.ThreeDSecureStatus == p.ThreeDSecureStatus {
+		return false, nil
+	}
+
+	var applied bool
+	err := h.inTx(ctx, func(tx *Tx) error {
+		var err error
+		applied, err = tx.Payments.Settle(ctx, &next, unsettled, change)
+		if err != nil || !applied {
+			return err
+		}
+		switch next.Status {
+		case payment.StatusCompleted:
+			return tx.publish(ctx, payment.EventCompleted, &next)
+		case payment.StatusFailed:
+			return tx.publish(ctx, payment.EventFailed, &next)
+		}
+		return nil
+	})
+	if err != nil || !applied {
+		return false, err
+	}
+	*p = next
+
+	if p.Status == payment.StatusCompleted || p.Status == payment.StatusFailed {
+		metrics.RecordPayment(p.Provider, string(p.Status))
+	}
+	return true, nil
+}
+
+// chargeMetadata is sent with a payment's charge and comes back with the
+// provider's webhooks, naming the payment and its tenant
+func chargeMetadata(ctx context.Context, p *payment.Payment) map[string]string {
+	return map[string]string{
+		"payment_id": p.ID.String(),
+		"order_id":   p.OrderID.String(),
+		"tenant_id":  tenant.IDFromContext(ctx),
+	}
+}
+
+// publish records a payment event in the transaction, for the message broker
+func (tx *Tx) publish(ctx context.Context, eventType string, p *payment.Payment) error {
+	return tx.Events.PublishEventContext(ctx, eventType, eventType, payment.NewEventData(p).Map())
+}
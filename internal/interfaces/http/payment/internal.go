@@ -0,0 +1,199 @@
+package payment
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/pkg/i18n"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/payments/provider"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// Charge handles POST /payments on the internal API, charging a payment for
+// another service as ProcessPayment does for a customer. A payment whose ID
+// was charged before is answered as it stands, or charged again while still
+// pending.
+func (h *Handler) Charge(c *fiber.Ctx) error {
+	var req ChargeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	existing, err := h.paymentRepo.GetByID(c.UserContext(), req.ID)
+	switch {
+	case err == nil && existing.Status != payment.StatusPending:
+		return c.Status(fiber.StatusCreated).JSON(ToResponse(existing).Localize(i18n.Locale(c)))
+	case err == nil:
+		return h.charge(c, existing, true)
+	case !errors.Is(err, payment.ErrPaymentNotFound):
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch payment %s: %v", req.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to process payment",
+		})
+	}
+
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = "USD"
+	}
+	return h.charge(c, &payment.Payment{
+		ID:                  req.ID,
+		OrderID:             req.OrderID,
+		UserID:              req.UserID,
+		PaymentMethodToken:  req.PaymentMethodToken,
+		PaymentMethodType:   payment.PaymentMethodType(req.PaymentMethodType),
+		Amount:              req.Amount,
+		Currency:            currency,
+		Status:              payment.StatusPending,
+		ThreeDSecureEnabled: req.ThreeDSecure,
+		Provider:            h.payments.Name(),
+	}, false)
+}
+
+// GetPaymentInternal handles GET /payments/:id on the internal API, for
+// services waiting on a payment they charged
+func (h *Handler) GetPaymentInternal(c *fiber.Ctx) error {
+	paymentID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid payment ID",
+		})
+	}
+
+	p, err := h.paymentRepo.GetByID(c.UserContext(), paymentID)
+	if errors.Is(err, payment.ErrPaymentNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Payment not found",
+		})
+	}
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch payment %s: %v", paymentID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch payment",
+		})
+	}
+	return c.JSON(ToResponse(p))
+}
+
+// RefundPayment handles POST /payments/:id/refund on the internal API,
+// refunding all of a completed payment, such as when the checkout of its
+// order is undone. Payments that took no money, or were never made, need no
+// refund and are answered as they stand; payments still in progress cannot
+// be refunded until the provider settles them.
+func (h *Handler) RefundPayment(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	log := logger.FromContext(ctx)
+
+	paymentID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid payment ID",
+		})
+	}
+
+	var req RefundRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	p, err := h.paymentRepo.GetByID(ctx, paymentID)
+	if errors.Is(err, payment.ErrPaymentNotFound) {
+		return c.Status(fiber.StatusNoContent).Send(nil)
+	}
+	if err != nil {
+		log.Errorf("Failed to fetch payment %s: %v", paymentID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refund payment",
+		})
+	}
+
+	switch {
+	case p.Status == payment.StatusFailed || p.Status == payment.StatusRefunded:
+		return c.JSON(ToResponse(p))
+	case !p.CanRefund():
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":  "Payment is not settled yet",
+			"status": p.Status,
+		})
+	}
+
+	var refund *provider.Refund
+	err = h.callProvider(ctx, func() error {
+		var err error
+		refund, err = h.payments.Refund(ctx, provider.RefundRequest{
+			ChargeID:       p.ProviderTransactionID,
+			Reason:         req.Reason,
+			IdempotencyKey: "refund-" + p.ID.String(),
+		})
+		return err
+	})
+	if providerBusy(err) {
+		log.Warnf("Refund of payment %s not made: %v", p.ID, err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Payment provider busy, try again",
+		})
+	}
+	if err == nil && refund.Status == "failed" {
+		err = errors.New("provider failed refund " + refund.ID)
+	}
+	if err != nil {
+		log.Errorf("Failed to refund payment %s: %v", p.ID, err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Payment provider refused the refund",
+		})
+	}
+
+	refunded := *p
+	refunded.Status = payment.StatusRefunded
+	change := payment.StatusChange{Action: payment.ActionRefund, Reason: req.Reason}
+	var applied bool
+	err = h.inTx(ctx, func(tx *Tx) error {
+		var err error
+		applied, err = tx.Payments.Settle(ctx, &refunded, []payment.PaymentStatus{payment.StatusCompleted}, change)
+		if err != nil || !applied {
+			return err
+		}
+		return tx.publish(ctx, payment.EventRefunded, &refunded)
+	})
+	if err != nil {
+		// Retrying finds the refund made under the same idempotency key
+		log.Errorf("Failed to record refund %s of payment %s: %v", refund.ID, p.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refund payment",
+		})
+	}
+	if !applied {
+		// A concurrent refund recorded it first
+		if current, err := h.paymentRepo.GetByID(ctx, p.ID); err == nil {
+			p = current
+		}
+		return c.JSON(ToResponse(p))
+	}
+
+	log.Infof("Refunded payment %s (%s)", p.ID, refund.ID)
+	return c.JSON(ToResponse(&refunded))
+}
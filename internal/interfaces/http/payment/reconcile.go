@@ -0,0 +1,121 @@
+package payment
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/pkg/config"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/payments/provider"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// reconcileBatchSize is how many unsettled payments are read at a time
+const reconcileBatchSize = 100
+
+// RunReconciler settles payments whose webhook never arrived, such as while
+// the service was down, by asking the provider where their charges stand.
+// Payments never charged are left for the client to retry until
+// cfg.AbandonAfter, then failed. It runs every cfg.ReconcileInterval until
+// ctx is cancelled.
+func (h *Handler) RunReconciler(ctx context.Context, cfg config.PaymentsConfig, log *logger.Logger) {
+	defer apperrors.Recover(ctx, "payment-reconciler")
+
+	if cfg.ReconcileInterval <= 0 {
+		return
+	}
+
+	pass := func() {
+		now := time.Now()
+		settled, err := h.reconcile(ctx, now.Add(-cfg.ReconcileAfter), now.Add(-cfg.AbandonAfter), log)
+		if err != nil {
+			log.Errorf("Failed to reconcile payments: %v", err)
+		}
+		if settled > 0 {
+			log.Infof("Reconciled %d payments with the payment provider", settled)
+		}
+
+		if pruned, err := h.paymentRepo.PruneProviderEvents(ctx, now.Add(-cfg.EventRetention)); err != nil {
+			log.Errorf("Failed to prune payment provider events: %v", err)
+		} else if pruned > 0 {
+			log.Infof("Pruned %d payment provider events", pruned)
+		}
+	}
+
+	pass()
+
+	ticker := time.NewTicker(cfg.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pass()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reconcile settles the payments unsettled since before, each in its own
+// tenant, failing those never charged since abandonBefore. It stops early
+// when the provider is unavailable, and returns how many payments changed.
+func (h *Handler) reconcile(ctx context.Context, before, abandonBefore time.Time, log *logger.Logger) (int, error) {
+	settled := 0
+	afterID := uuid.Nil
+	for {
+		payments, err := h.paymentRepo.ListUnsettled(ctx, before, afterID, reconcileBatchSize)
+		if err != nil {
+			return settled, err
+		}
+
+		for _, p := range payments {
+			ctx := tenant.NewContext(ctx, &tenant.Tenant{ID: p.TenantID, Source: tenant.SourceJob})
+			applied, err := h.reconcilePayment(ctx, p, abandonBefore)
+			if providerBusy(err) || ctx.Err() != nil {
+				return settled, err
+			}
+			if err != nil {
+				log.Warnf("Failed to reconcile payment %s: %v", p.ID, err)
+				continue
+			}
+			if applied {
+				settled++
+			}
+		}
+
+		if len(payments) < reconcileBatchSize {
+			return settled, nil
+		}
+		afterID = payments[len(payments)-1].ID
+	}
+}
+
+// reconcilePayment settles one payment from its charge at the provider
+func (h *Handler) reconcilePayment(ctx context.Context, p *payment.Payment, abandonBefore time.Time) (bool, error) {
+	if p.ProviderTransactionID == "" {
+		// The charge never got an answer. A charge that did go through
+		// reports itself through the webhook, which finds the payment by
+		// the metadata sent with it.
+		if p.CreatedAt.After(abandonBefore) {
+			return false, nil
+		}
+		change := payment.StatusChange{Action: payment.ActionReconcile, Reason: "abandoned"}
+		return h.applyCharge(ctx, p, "", provider.StatusFailed, change)
+	}
+
+	var charge *provider.Charge
+	err := h.callProvider(ctx, func() error {
+		var err error
+		charge, err = h.payments.GetCharge(ctx, p.ProviderTransactionID)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return h.applyCharge(ctx, p, charge.ID, charge.Status, payment.StatusChange{Action: payment.ActionReconcile})
+}
@@ -0,0 +1,80 @@
+package payment
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/payments/provider"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// HandleProviderWebhook handles POST /payments/webhook, settling
+// payments the provider finished after answering the charge, such as after
+// 3D Secure. The payment and its tenant come from the metadata sent with the
+// charge. Each event is applied once: the payment's change is saved with
+// the event's ID, so a redelivery changes nothing.
+func (h *Handler) HandleProviderWebhook(c *fiber.Ctx) error {
+	log := logger.FromContext(c.UserContext())
+
+	header := http.Header{}
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+
+	event, err := h.payments.ParseWebhook(c.Body(), header)
+	if errors.Is(err, provider.ErrWebhooksUnsupported) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Payment provider sends no webhooks",
+		})
+	}
+	if err != nil {
+		log.Warnf("Refused payment provider webhook: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid webhook",
+		})
+	}
+
+	if event.Type == provider.EventOther {
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	paymentID, err := uuid.Parse(event.Metadata["payment_id"])
+	tenantID := event.Metadata["tenant_id"]
+	if err != nil || !tenant.ValidID(tenantID) {
+		// Charges made outside this service, such as from the dashboard
+		log.Infof("Ignoring %s event %s for charge %s without a payment", event.RawType, event.ID, event.ChargeID)
+		return c.SendStatus(fiber.StatusOK)
+	}
+	ctx := tenant.NewContext(c.UserContext(), &tenant.Tenant{ID: tenantID, Source: tenant.SourceWebhook})
+
+	p, err := h.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		// Answering with an error has the provider redeliver the event
+		log.Errorf("Failed to fetch payment %s for %s event %s: %v", paymentID, event.RawType, event.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch payment",
+		})
+	}
+	if p.Settled() {
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	change := payment.StatusChange{Action: payment.ActionWebhook, EventID: event.ID}
+	applied, err := h.applyCharge(ctx, p, event.ChargeID, event.Status, change)
+	if err != nil {
+		log.Errorf("Failed to apply %s event %s to payment %s: %v", event.RawType, event.ID, p.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update payment",
+		})
+	}
+	if applied {
+		log.Infof("Payment %s is %s after %s event %s", p.ID, p.Status, event.RawType, event.ID)
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,96 @@
+package procurement
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateSupplierRequest represents create supplier request. Currency is that
+// of the supplier's price lists and invoices.
+type CreateSupplierRequest struct {
+	Code         string `json:"code" validate:"required,max=32"`
+	Name         string `json:"name" validate:"required,max=255"`
+	ContactName  string `json:"contact_name,omitempty" validate:"max=255"`
+	Email        string `json:"email,omitempty" validate:"omitempty,email,max=255"`
+	Phone        string `json:"phone,omitempty" validate:"max=50"`
+	Address      string `json:"address,omitempty"`
+	Currency     string `json:"currency" validate:"required,len=3"`
+	PaymentTerms string `json:"payment_terms,omitempty" validate:"max=100"`
+	LeadTimeDays int    `json:"lead_time_days" validate:"gte=0,lte=365"`
+	Active       *bool  `json:"active,omitempty"` // Defaults to true
+	Notes        string `json:"notes,omitempty"`
+}
+
+// UpdateSupplierRequest represents update supplier request. Fields left out
+// are kept; the code is fixed.
+type UpdateSupplierRequest struct {
+	Name         string  `json:"name,omitempty" validate:"omitempty,max=255"`
+	ContactName  *string `json:"contact_name,omitempty" validate:"omitempty,max=255"`
+	Email        *string `json:"email,omitempty" validate:"omitempty,max=255"`
+	Phone        *string `json:"phone,omitempty" validate:"omitempty,max=50"`
+	Address      *string `json:"address,omitempty"`
+	Currency     string  `json:"currency,omitempty" validate:"omitempty,len=3"`
+	PaymentTerms *string `json:"payment_terms,omitempty" validate:"omitempty,max=100"`
+	LeadTimeDays *int    `json:"lead_time_days,omitempty" validate:"omitempty,gte=0,lte=365"`
+	Active       *bool   `json:"active,omitempty"`
+	Notes        *string `json:"notes,omitempty"`
+}
+
+// OrderLineRequest represents a product ordered on a purchase order
+type OrderLineRequest struct {
+	ProductID   uuid.UUID `json:"product_id" validate:"required"`
+	SupplierSKU string    `json:"supplier_sku,omitempty" validate:"max=100"`
+	Description string    `json:"description,omitempty" validate:"max=255"`
+	Quantity    int       `json:"quantity" validate:"required,min=1"`
+	UnitCost    float64   `json:"unit_cost" validate:"gte=0"`
+}
+
+// CreateOrderRequest represents create purchase order request. Currency
+// defaults to the supplier's.
+type CreateOrderRequest struct {
+	SupplierID uuid.UUID          `json:"supplier_id" validate:"required"`
+	StoreID    uuid.UUID          `json:"store_id" validate:"required"`
+	Currency   string             `json:"currency,omitempty" validate:"omitempty,len=3"`
+	ExpectedOn string             `json:"expected_on,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Notes      string             `json:"notes,omitempty"`
+	Lines      []OrderLineRequest `json:"lines" validate:"required,min=1,max=500,dive"`
+}
+
+// UpdateOrderRequest represents update draft purchase order request. The
+// lines given replace the order's.
+type UpdateOrderRequest struct {
+	ExpectedOn string             `json:"expected_on,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Notes      string             `json:"notes,omitempty"`
+	Lines      []OrderLineRequest `json:"lines" validate:"required,min=1,max=500,dive"`
+}
+
+// SubmitOrderRequest represents submit purchase order request. An order
+// with no expected day is expected after the supplier's lead time.
+type SubmitOrderRequest struct {
+	ExpectedOn string `json:"expected_on,omitempty" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// ReceiptLineRequest represents the quantity of one order line received.
+// Unit cost defaults to the order line's.
+type ReceiptLineRequest struct {
+	LineID   uuid.UUID `json:"line_id" validate:"required"`
+	Quantity int       `json:"quantity" validate:"required,min=1"`
+	UnitCost *float64  `json:"unit_cost,omitempty" validate:"omitempty,gte=0"`
+}
+
+// CreateReceiptRequest represents receive goods request. ReceivedAt defaults
+// to now.
+type CreateReceiptRequest struct {
+	Lines      []ReceiptLineRequest `json:"lines" validate:"required,min=1,max=500,dive"`
+	Note       string               `json:"note,omitempty" validate:"max=1000"`
+	ReceivedAt *time.Time           `json:"received_at,omitempty"`
+}
+
+// SetSupplierProductRequest represents set supplier product request: the
+// SKU, cost and minimum order quantity a supplier sells a product at
+type SetSupplierProductRequest struct {
+	SupplierSKU      string  `json:"supplier_sku,omitempty" validate:"max=100"`
+	UnitCost         float64 `json:"unit_cost" validate:"gte=0"`
+	MinOrderQuantity int     `json:"min_order_quantity,omitempty" validate:"gte=0"` // Defaults to 1
+}
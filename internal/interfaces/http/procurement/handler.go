@@ -0,0 +1,570 @@
+package procurement
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/domain/procurement"
+	"github.com/onichange/pos-system/internal/domain/store"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// dateLayout is the layout of dates in requests
+const dateLayout = "2006-01-02"
+
+// StoreLocator fetches stores from the store service, to deliver orders to
+type StoreLocator interface {
+	GetStore(ctx context.Context, id uuid.UUID) (*store.Store, error)
+}
+
+// StockPoster adds received goods to the inventory service's stock
+type StockPoster interface {
+	ReceiveStock(ctx context.Context, r *inventory.StockReceipt) error
+}
+
+// Handler handles procurement HTTP requests
+type Handler struct {
+	procurementRepo procurement.Repository
+	stores          StoreLocator
+	stock           StockPoster
+}
+
+// NewHandler creates a new procurement handler
+func NewHandler(procurementRepo procurement.Repository, stores StoreLocator, stock StockPoster) *Handler {
+	return &Handler{
+		procurementRepo: procurementRepo,
+		stores:          stores,
+		stock:           stock,
+	}
+}
+
+// GetSuppliers handles GET /suppliers?active=true
+func (h *Handler) GetSuppliers(c *fiber.Ctx) error {
+	limit, offset := pagination(c)
+
+	suppliers, err := h.procurementRepo.ListSuppliers(c.UserContext(), c.QueryBool("active"), limit, offset)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch suppliers")
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   suppliers,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetSupplier handles GET /suppliers/:id
+func (h *Handler) GetSupplier(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid supplier ID",
+		})
+	}
+
+	s, err := h.procurementRepo.GetSupplier(c.UserContext(), id)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch supplier")
+	}
+
+	return c.JSON(s)
+}
+
+// CreateSupplier handles POST /suppliers
+func (h *Handler) CreateSupplier(c *fiber.Ctx) error {
+	var req CreateSupplierRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	s := &procurement.Supplier{
+		ID:           uuid.New(),
+		Code:         strings.ToUpper(strings.TrimSpace(req.Code)),
+		Name:         req.Name,
+		ContactName:  req.ContactName,
+		Email:        req.Email,
+		Phone:        req.Phone,
+		Address:      req.Address,
+		Currency:     strings.ToUpper(req.Currency),
+		PaymentTerms: req.PaymentTerms,
+		LeadTimeDays: req.LeadTimeDays,
+		Active:       req.Active == nil || *req.Active,
+		Notes:        req.Notes,
+	}
+
+	if err := h.procurementRepo.CreateSupplier(c.UserContext(), s); err != nil {
+		return h.writeError(c, err, "Failed to create supplier")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(s)
+}
+
+// UpdateSupplier handles PUT /suppliers/:id
+func (h *Handler) UpdateSupplier(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid supplier ID",
+		})
+	}
+
+	var req UpdateSupplierRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	s, err := h.procurementRepo.GetSupplier(c.UserContext(), id)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch supplier")
+	}
+
+	if req.Name != "" {
+		s.Name = req.Name
+	}
+	if req.ContactName != nil {
+		s.ContactName = *req.ContactName
+	}
+	if req.Email != nil {
+		s.Email = *req.Email
+	}
+	if req.Phone != nil {
+		s.Phone = *req.Phone
+	}
+	if req.Address != nil {
+		s.Address = *req.Address
+	}
+	if req.Currency != "" {
+		s.Currency = strings.ToUpper(req.Currency)
+	}
+	if req.PaymentTerms != nil {
+		s.PaymentTerms = *req.PaymentTerms
+	}
+	if req.LeadTimeDays != nil {
+		s.LeadTimeDays = *req.LeadTimeDays
+	}
+	if req.Active != nil {
+		s.Active = *req.Active
+	}
+	if req.Notes != nil {
+		s.Notes = *req.Notes
+	}
+
+	if err := h.procurementRepo.UpdateSupplier(c.UserContext(), s); err != nil {
+		return h.writeError(c, err, "Failed to update supplier")
+	}
+
+	return c.JSON(s)
+}
+
+// DeleteSupplier handles DELETE /suppliers/:id. A supplier purchase orders
+// were raised with is kept for their history; deactivate it instead.
+func (h *Handler) DeleteSupplier(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid supplier ID",
+		})
+	}
+
+	if err := h.procurementRepo.DeleteSupplier(c.UserContext(), id); err != nil {
+		return h.writeError(c, err, "Failed to delete supplier")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetPerformance handles GET /suppliers/:id/performance?from=&to=: the fill
+// rate, on-time rate and lead time of the orders submitted to a supplier.
+// from and to are YYYY-MM-DD dates, both included, defaulting to the last 90
+// days.
+func (h *Handler) GetPerformance(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid supplier ID",
+		})
+	}
+
+	now := time.Now().UTC()
+	to := now.Truncate(24 * time.Hour)
+	if c.Query("to") != "" {
+		if to, err = time.Parse(dateLayout, c.Query("to")); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "to must be a YYYY-MM-DD date",
+			})
+		}
+	}
+	from := to.AddDate(0, 0, -89)
+	if c.Query("from") != "" {
+		if from, err = time.Parse(dateLayout, c.Query("from")); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "from must be a YYYY-MM-DD date",
+			})
+		}
+	}
+	if to.Before(from) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "to must not be before from",
+		})
+	}
+
+	s, err := h.procurementRepo.GetSupplier(c.UserContext(), id)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch supplier")
+	}
+
+	samples, err := h.procurementRepo.OrderSamples(c.UserContext(), s.ID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch supplier orders")
+	}
+
+	return c.JSON(procurement.BuildPerformance(s, from, to, samples, now))
+}
+
+// GetOrders handles GET /purchase-orders?supplier_id=&store_id=&status=
+func (h *Handler) GetOrders(c *fiber.Ctx) error {
+	limit, offset := pagination(c)
+
+	filter := procurement.OrderFilter{Status: procurement.Status(c.Query("status"))}
+	if raw := c.Query("supplier_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid supplier ID",
+			})
+		}
+		filter.SupplierID = &id
+	}
+	if raw := c.Query("store_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid store ID",
+			})
+		}
+		filter.StoreID = &id
+	}
+
+	orders, err := h.procurementRepo.ListOrders(c.UserContext(), filter, limit, offset)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch purchase orders")
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   orders,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetOrder handles GET /purchase-orders/:id
+func (h *Handler) GetOrder(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid purchase order ID",
+		})
+	}
+
+	o, err := h.procurementRepo.GetOrder(c.UserContext(), id)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch purchase order")
+	}
+
+	return c.JSON(o)
+}
+
+// CreateOrder handles POST /purchase-orders, raising a draft order with an
+// active supplier for delivery to a store
+func (h *Handler) CreateOrder(c *fiber.Ctx) error {
+	var req CreateOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	supplier, err := h.procurementRepo.GetSupplier(c.UserContext(), req.SupplierID)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch supplier")
+	}
+	if !supplier.Active {
+		return h.writeError(c, procurement.ErrSupplierInactive, "")
+	}
+	if _, err := h.stores.GetStore(c.UserContext(), req.StoreID); err != nil {
+		return h.writeError(c, err, "Failed to fetch store")
+	}
+
+	userID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	o := &procurement.PurchaseOrder{
+		ID:         uuid.New(),
+		SupplierID: supplier.ID,
+		StoreID:    req.StoreID,
+		Status:     procurement.StatusDraft,
+		Currency:   supplier.Currency,
+		Lines:      orderLines(req.Lines),
+		ExpectedOn: parseDate(req.ExpectedOn),
+		Notes:      req.Notes,
+		CreatedBy:  &userID,
+	}
+	if req.Currency != "" {
+		o.Currency = strings.ToUpper(req.Currency)
+	}
+	o.Total = o.CalculateTotal()
+
+	if err := h.procurementRepo.CreateOrder(c.UserContext(), o); err != nil {
+		return h.writeError(c, err, "Failed to create purchase order")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(o)
+}
+
+// UpdateOrder handles PUT /purchase-orders/:id, replacing the lines of a
+// draft order
+func (h *Handler) UpdateOrder(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid purchase order ID",
+		})
+	}
+
+	var req UpdateOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	o, err := h.procurementRepo.GetOrder(c.UserContext(), id)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch purchase order")
+	}
+
+	o.Lines = orderLines(req.Lines)
+	o.ExpectedOn = parseDate(req.ExpectedOn)
+	o.Notes = req.Notes
+	o.Total = o.CalculateTotal()
+
+	if err := h.procurementRepo.UpdateDraft(c.UserContext(), o); err != nil {
+		return h.writeError(c, err, "Failed to update purchase order")
+	}
+
+	return c.JSON(o)
+}
+
+// SubmitOrder handles POST /purchase-orders/:id/submit, sending a draft order
+// to its supplier
+func (h *Handler) SubmitOrder(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid purchase order ID",
+		})
+	}
+
+	var req SubmitOrderRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	o, err := h.procurementRepo.GetOrder(c.UserContext(), id)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch purchase order")
+	}
+	supplier, err := h.procurementRepo.GetSupplier(c.UserContext(), o.SupplierID)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch supplier")
+	}
+	if !supplier.Active {
+		return h.writeError(c, procurement.ErrSupplierInactive, "")
+	}
+
+	now := time.Now().UTC()
+	expectedOn := parseDate(req.ExpectedOn)
+	if expectedOn == nil {
+		day := now.Truncate(24*time.Hour).AddDate(0, 0, supplier.LeadTimeDays)
+		expectedOn = &day
+	}
+
+	o, err = h.procurementRepo.Transition(c.UserContext(), id, procurement.StatusSubmitted, expectedOn, now)
+	if err != nil {
+		return h.writeError(c, err, "Failed to submit purchase order")
+	}
+
+	return c.JSON(o)
+}
+
+// CancelOrder handles POST /purchase-orders/:id/cancel, for an order nothing
+// was received against
+func (h *Handler) CancelOrder(c *fiber.Ctx) error {
+	return h.transition(c, procurement.StatusCancelled, "Failed to cancel purchase order")
+}
+
+// CloseOrder handles POST /purchase-orders/:id/close, closing an order short
+// when what is outstanding will not be delivered
+func (h *Handler) CloseOrder(c *fiber.Ctx) error {
+	return h.transition(c, procurement.StatusClosed, "Failed to close purchase order")
+}
+
+// transition moves the order in the path to status to
+func (h *Handler) transition(c *fiber.Ctx, to procurement.Status, message string) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid purchase order ID",
+		})
+	}
+
+	o, err := h.procurementRepo.Transition(c.UserContext(), id, to, nil, time.Now().UTC())
+	if err != nil {
+		return h.writeError(c, err, message)
+	}
+
+	return c.JSON(o)
+}
+
+// writeError answers a failed request, logging unexpected errors
+func (h *Handler) writeError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, procurement.ErrSupplierNotFound), errors.Is(err, procurement.ErrOrderNotFound),
+		errors.Is(err, procurement.ErrReceiptNotFound), errors.Is(err, procurement.ErrSupplierProductNotFound),
+		errors.Is(err, store.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": capitalize(err.Error()),
+		})
+	case errors.Is(err, procurement.ErrSupplierExists), errors.Is(err, procurement.ErrSupplierInactive),
+		errors.Is(err, procurement.ErrSupplierInUse), errors.Is(err, procurement.ErrInvalidTransition),
+		errors.Is(err, procurement.ErrOverReceipt):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": capitalize(err.Error()),
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("%s: %v", message, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+// orderLines turns requested lines into order lines
+func orderLines(reqs []OrderLineRequest) []procurement.Line {
+	lines := make([]procurement.Line, len(reqs))
+	for i, req := range reqs {
+		lines[i] = procurement.Line{
+			ID:          uuid.New(),
+			ProductID:   req.ProductID,
+			SupplierSKU: req.SupplierSKU,
+			Description: req.Description,
+			Quantity:    req.Quantity,
+			UnitCost:    req.UnitCost,
+		}
+	}
+	return lines
+}
+
+// parseDate parses a validated YYYY-MM-DD date, or returns nil for none
+func parseDate(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	day, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil
+	}
+	return &day
+}
+
+// currentUser returns the authenticated user's ID
+func currentUser(c *fiber.Ctx) (uuid.UUID, error) {
+	userIDStr, _ := c.Locals("user_id").(string)
+	if userIDStr == "" {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+	return userID, nil
+}
+
+// capitalize turns an error message into a response message
+func capitalize(message string) string {
+	if message == "" {
+		return message
+	}
+	return strings.ToUpper(message[:1]) + message[1:]
+}
+
+// pagination parses the limit and offset of a list request
+func pagination(c *fiber.Ctx) (int, int) {
+	limit := 50
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+	return limit, offset
+}
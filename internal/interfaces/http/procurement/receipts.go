@@ -0,0 +1,211 @@
+package procurement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/domain/procurement"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/tenant"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// receiptSourceType identifies goods receipt lines as the source of the
+// inventory cost layers they post
+const receiptSourceType = "goods_receipt"
+
+// postBatchSize caps the receipts retried per poster pass
+const postBatchSize = 100
+
+// GetReceipts handles GET /purchase-orders/:id/receipts
+func (h *Handler) GetReceipts(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid purchase order ID",
+		})
+	}
+
+	if _, err := h.procurementRepo.GetOrder(c.UserContext(), id); err != nil {
+		return h.writeError(c, err, "Failed to fetch purchase order")
+	}
+
+	receipts, err := h.procurementRepo.ListReceipts(c.UserContext(), id)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch goods receipts")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": receipts,
+	})
+}
+
+// CreateReceipt handles POST /purchase-orders/:id/receipts: goods delivered
+// against a submitted order. The stock is posted to inventory at once; when
+// inventory cannot take it, the receipt is still recorded, with no
+// posted_at, and posting is retried in the background.
+func (h *Handler) CreateReceipt(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid purchase order ID",
+		})
+	}
+
+	var req CreateReceiptRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	o, err := h.procurementRepo.GetOrder(c.UserContext(), id)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch purchase order")
+	}
+	if !o.Status.Receivable() {
+		return h.writeError(c, procurement.ErrInvalidTransition, "")
+	}
+
+	userID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	receipt := &procurement.GoodsReceipt{
+		ID:              uuid.New(),
+		PurchaseOrderID: o.ID,
+		StoreID:         o.StoreID,
+		Lines:           make([]procurement.ReceiptLine, len(req.Lines)),
+		Note:            req.Note,
+		ReceivedBy:      &userID,
+		ReceivedAt:      time.Now().UTC(),
+	}
+	if req.ReceivedAt != nil {
+		receipt.ReceivedAt = req.ReceivedAt.UTC()
+	}
+
+	seen := make(map[uuid.UUID]bool, len(req.Lines))
+	for i, lr := range req.Lines {
+		line := o.Line(lr.LineID)
+		if line == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Line %s is not on this purchase order", lr.LineID),
+			})
+		}
+		if seen[lr.LineID] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Line %s is received twice", lr.LineID),
+			})
+		}
+		seen[lr.LineID] = true
+		if lr.Quantity > line.Outstanding() {
+			return h.writeError(c, procurement.ErrOverReceipt, "")
+		}
+
+		unitCost := line.UnitCost
+		if lr.UnitCost != nil {
+			unitCost = *lr.UnitCost
+		}
+		receipt.Lines[i] = procurement.ReceiptLine{
+			ID:        uuid.New(),
+			LineID:    line.ID,
+			ProductID: line.ProductID,
+			Quantity:  lr.Quantity,
+			UnitCost:  unitCost,
+		}
+	}
+
+	if err := h.procurementRepo.CreateReceipt(c.UserContext(), receipt); err != nil {
+		return h.writeError(c, err, "Failed to receive goods")
+	}
+
+	if err := h.postReceipt(c.UserContext(), receipt); err != nil {
+		logger.FromContext(c.UserContext()).Warnf("Goods receipt %s not yet posted to inventory: %v", receipt.ID, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(receipt)
+}
+
+// postReceipt adds a receipt's stock to inventory, a cost layer per line,
+// and marks it posted. Lines inventory already took are skipped, so a
+// receipt that failed part way is posted again whole.
+func (h *Handler) postReceipt(ctx context.Context, receipt *procurement.GoodsReceipt) error {
+	for _, line := range receipt.Lines {
+		err := h.stock.ReceiveStock(ctx, &inventory.StockReceipt{
+			ProductID:  line.ProductID,
+			StoreID:    receipt.StoreID,
+			Quantity:   line.Quantity,
+			UnitCost:   line.UnitCost,
+			SourceType: receiptSourceType,
+			SourceID:   line.ID,
+			Reason:     "Goods receipt " + receipt.ID.String(),
+			UserID:     receipt.ReceivedBy,
+			ReceivedAt: receipt.ReceivedAt,
+		})
+		if err != nil && !errors.Is(err, inventory.ErrAlreadyReceived) {
+			return err
+		}
+	}
+
+	now := time.Now().UTC()
+	if err := h.procurementRepo.MarkReceiptPosted(ctx, receipt.ID, now); err != nil {
+		return err
+	}
+	receipt.PostedAt = &now
+	return nil
+}
+
+// RunPoster posts goods receipts inventory could not take when they were
+// recorded, each in its own tenant, every interval until ctx is cancelled
+func (h *Handler) RunPoster(ctx context.Context, interval time.Duration, log *logger.Logger) {
+	defer apperrors.Recover(ctx, "procurement-poster")
+
+	if interval <= 0 {
+		return
+	}
+
+	pass := func() {
+		receipts, err := h.procurementRepo.ListUnpostedReceipts(ctx, postBatchSize)
+		if err != nil {
+			log.Errorf("Failed to fetch unposted goods receipts: %v", err)
+			return
+		}
+		for _, receipt := range receipts {
+			ctx := tenant.NewContext(ctx, &tenant.Tenant{ID: receipt.TenantID, Source: tenant.SourceJob})
+			if err := h.postReceipt(ctx, receipt); err != nil {
+				log.Warnf("Failed to post goods receipt %s to inventory: %v", receipt.ID, err)
+				return
+			}
+			log.Infof("Posted goods receipt %s to inventory", receipt.ID)
+		}
+	}
+
+	pass()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pass()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
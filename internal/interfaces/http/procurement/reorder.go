@@ -0,0 +1,355 @@
+package procurement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/domain/notification"
+	"github.com/onichange/pos-system/internal/domain/procurement"
+	"github.com/onichange/pos-system/internal/domain/store"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/tenant"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// GetSupplierProducts handles GET /suppliers/:id/products
+func (h *Handler) GetSupplierProducts(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid supplier ID",
+		})
+	}
+
+	if _, err := h.procurementRepo.GetSupplier(c.UserContext(), id); err != nil {
+		return h.writeError(c, err, "Failed to fetch supplier")
+	}
+
+	products, err := h.procurementRepo.ListSupplierProducts(c.UserContext(), id)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch supplier products")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": products,
+	})
+}
+
+// SetSupplierProduct handles PUT /suppliers/:id/products/:productId, adding
+// a product to those the supplier sells, or updating its cost. Low stock of
+// the product is reordered from the cheapest active supplier selling it.
+func (h *Handler) SetSupplierProduct(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid supplier ID",
+		})
+	}
+	productID, err := uuid.Parse(c.Params("productId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	var req SetSupplierProductRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	p := &procurement.SupplierProduct{
+		SupplierID:       id,
+		ProductID:        productID,
+		SupplierSKU:      req.SupplierSKU,
+		UnitCost:         req.UnitCost,
+		MinOrderQuantity: max(req.MinOrderQuantity, 1),
+	}
+	if err := h.procurementRepo.SetSupplierProduct(c.UserContext(), p); err != nil {
+		return h.writeError(c, err, "Failed to set supplier product")
+	}
+
+	return c.JSON(p)
+}
+
+// DeleteSupplierProduct handles DELETE /suppliers/:id/products/:productId
+func (h *Handler) DeleteSupplierProduct(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid supplier ID",
+		})
+	}
+	productID, err := uuid.Parse(c.Params("productId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	if err := h.procurementRepo.DeleteSupplierProduct(c.UserContext(), id, productID); err != nil {
+		return h.writeError(c, err, "Failed to delete supplier product")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// LowStockLister lists the inventory service's stock at or below its
+// reorder point
+type LowStockLister interface {
+	ListLowStock(ctx context.Context) ([]*inventory.Inventory, error)
+}
+
+// EventPublisher publishes events to the message broker
+type EventPublisher interface {
+	PublishEventContext(ctx context.Context, eventType, routingKey string, data map[string]interface{}) error
+}
+
+// Tx holds the procurement repository and event publisher of one database
+// transaction, so a purchase order and the notification requested of it are
+// saved together or not at all
+type Tx struct {
+	Procurement procurement.Repository
+	Events      EventPublisher
+}
+
+// Transactor runs fn in a database transaction, committing it when fn
+// returns nil and rolling it back otherwise
+type Transactor func(ctx context.Context, fn func(tx *Tx) error) error
+
+// Reorderer raises purchase orders for low stock and tells the managers of
+// the stores they deliver to, for them to review and submit
+type Reorderer struct {
+	procurementRepo procurement.Repository
+	stock           LowStockLister
+	stores          StoreLocator
+	inTx            Transactor // Saves purchase orders with their notifications
+}
+
+// NewReorderer creates a reorderer
+func NewReorderer(procurementRepo procurement.Repository, stock LowStockLister, stores StoreLocator, inTx Transactor) *Reorderer {
+	return &Reorderer{
+		procurementRepo: procurementRepo,
+		stock:           stock,
+		stores:          stores,
+		inTx:            inTx,
+	}
+}
+
+// Run reorders the low stock of each tenant whose suppliers sell anything
+// every interval until ctx is cancelled. Each tenant is reordered by one
+// instance per interval.
+func (r *Reorderer) Run(ctx context.Context, interval time.Duration, log *logger.Logger) {
+	defer apperrors.Recover(ctx, "procurement-reorderer")
+
+	if interval <= 0 {
+		return
+	}
+
+	pass := func() {
+		tenants, err := r.procurementRepo.ReorderTenants(ctx)
+		if err != nil {
+			log.Errorf("Failed to fetch tenants to reorder for: %v", err)
+			return
+		}
+		for _, tenantID := range tenants {
+			// Claimed for half the interval, so that a tick arriving a little
+			// early still claims the run
+			claimed, err := r.procurementRepo.ClaimReorder(ctx, tenantID, time.Now(), interval/2)
+			if err != nil {
+				log.Errorf("Failed to claim reorder run of tenant %s: %v", tenantID, err)
+				return
+			}
+			if !claimed {
+				continue
+			}
+
+			ctx := tenant.NewContext(ctx, &tenant.Tenant{ID: tenantID, Source: tenant.SourceJob})
+			orders, err := r.Reorder(ctx)
+			if err != nil {
+				log.Warnf("Failed to reorder low stock of tenant %s: %v", tenantID, err)
+				continue
+			}
+			for _, o := range orders {
+				log.Infof("Raised purchase order %d for low stock of store %s", o.Number, o.StoreID)
+			}
+		}
+	}
+
+	pass()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pass()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Reorder raises draft purchase orders for the low stock of the tenant in
+// ctx that is not already on order, one per store and supplier, each
+// product from its cheapest active supplier. Stock no supplier sells is
+// left alone, as are stores no longer active. The manager of each store is
+// notified of its orders.
+func (r *Reorderer) Reorder(ctx context.Context) ([]*procurement.PurchaseOrder, error) {
+	items, err := r.stock.ListLowStock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch low stock: %w", err)
+	}
+
+	byStore := make(map[uuid.UUID][]*inventory.Inventory)
+	storeIDs := []uuid.UUID{}
+	productIDs := []uuid.UUID{}
+	for _, inv := range items {
+		if inv.StoreID == nil {
+			continue
+		}
+		if _, ok := byStore[*inv.StoreID]; !ok {
+			storeIDs = append(storeIDs, *inv.StoreID)
+		}
+		byStore[*inv.StoreID] = append(byStore[*inv.StoreID], inv)
+		productIDs = append(productIDs, inv.ProductID)
+	}
+	if len(productIDs) == 0 {
+		return nil, nil
+	}
+
+	sources, err := r.procurementRepo.ReorderSources(ctx, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch supplier products: %w", err)
+	}
+
+	suppliers := make(map[uuid.UUID]*procurement.Supplier)
+	orders := []*procurement.PurchaseOrder{}
+	for _, storeID := range storeIDs {
+		raised, err := r.reorderStore(ctx, storeID, byStore[storeID], sources, suppliers)
+		if err != nil {
+			return orders, err
+		}
+		orders = append(orders, raised...)
+	}
+	return orders, nil
+}
+
+// reorderStore raises the orders for one store's low stock, fetching
+// suppliers not yet in suppliers
+func (r *Reorderer) reorderStore(ctx context.Context, storeID uuid.UUID, items []*inventory.Inventory,
+	sources map[uuid.UUID]*procurement.SupplierProduct, suppliers map[uuid.UUID]*procurement.Supplier) ([]*procurement.PurchaseOrder, error) {
+	st, err := r.stores.GetStore(ctx, storeID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch store %s: %w", storeID, err)
+	}
+	if st.Status != store.StatusActive {
+		return nil, nil
+	}
+
+	productIDs := make([]uuid.UUID, len(items))
+	for i, inv := range items {
+		productIDs[i] = inv.ProductID
+	}
+	onOrder, err := r.procurementRepo.OnOrder(ctx, storeID, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stock on order for store %s: %w", storeID, err)
+	}
+
+	bySupplier := make(map[uuid.UUID][]procurement.Line)
+	supplierIDs := []uuid.UUID{}
+	for _, inv := range items {
+		source, ok := sources[inv.ProductID]
+		if !ok || onOrder[inv.ProductID] {
+			continue
+		}
+		if _, ok := bySupplier[source.SupplierID]; !ok {
+			supplierIDs = append(supplierIDs, source.SupplierID)
+		}
+		bySupplier[source.SupplierID] = append(bySupplier[source.SupplierID], procurement.Line{
+			ID:          uuid.New(),
+			ProductID:   inv.ProductID,
+			SupplierSKU: source.SupplierSKU,
+			Quantity: procurement.ReorderQuantity(
+				inv.AvailableQuantity, inv.ReorderPoint, inv.ReorderQuantity, source.MinOrderQuantity,
+			),
+			UnitCost: source.UnitCost,
+		})
+	}
+
+	orders := []*procurement.PurchaseOrder{}
+	for _, supplierID := range supplierIDs {
+		supplier, ok := suppliers[supplierID]
+		if !ok {
+			if supplier, err = r.procurementRepo.GetSupplier(ctx, supplierID); err != nil {
+				return orders, fmt.Errorf("failed to fetch supplier %s: %w", supplierID, err)
+			}
+			suppliers[supplierID] = supplier
+		}
+
+		o := &procurement.PurchaseOrder{
+			ID:         uuid.New(),
+			SupplierID: supplier.ID,
+			StoreID:    storeID,
+			Status:     procurement.StatusDraft,
+			Currency:   supplier.Currency,
+			Lines:      bySupplier[supplierID],
+			Notes:      procurement.ReorderNote,
+		}
+		o.Total = o.CalculateTotal()
+		err := r.inTx(ctx, func(tx *Tx) error {
+			if err := tx.Procurement.CreateOrder(ctx, o); err != nil {
+				return err
+			}
+			return tx.notifyManager(ctx, st, supplier, o)
+		})
+		if err != nil {
+			return orders, fmt.Errorf("failed to create purchase order for store %s: %w", storeID, err)
+		}
+		orders = append(orders, o)
+	}
+	return orders, nil
+}
+
+// notifyManager records a request for the notification service to tell a
+// store's manager of an order raised for it, when the store has one
+func (tx *Tx) notifyManager(ctx context.Context, st *store.Store, supplier *procurement.Supplier, o *procurement.PurchaseOrder) error {
+	if st.ManagerID == nil {
+		return nil
+	}
+
+	data := notification.RequestedData{
+		ID:     o.ID, // Once per order
+		UserID: *st.ManagerID,
+		Type:   notification.TypeInventory,
+		Title:  "Purchase order raised for low stock",
+		Message: fmt.Sprintf("Draft purchase order %d to %s reorders %d low-stock products for %s. Review and submit it.",
+			o.Number, supplier.Name, len(o.Lines), st.Name),
+		Data: map[string]interface{}{
+			"purchase_order_id": o.ID,
+			"store_id":          st.ID,
+			"supplier_id":       supplier.ID,
+		},
+		Channels: []notification.Channel{notification.ChannelInApp, notification.ChannelEmail},
+	}
+	return tx.Events.PublishEventContext(ctx, notification.EventRequested, notification.EventRequested, data.Map())
+}
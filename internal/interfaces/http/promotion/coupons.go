@@ -0,0 +1,392 @@
+package promotion
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/promotion"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// GetCoupons handles GET /promotions/:id/coupons
+func (h *Handler) GetCoupons(c *fiber.Ctx) error {
+	p, err := h.pathPromotion(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse pagination
+	limit := 20
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	coupons, err := h.couponRepo.ListCoupons(c.UserContext(), p.ID, limit, offset)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch coupons")
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   coupons,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// CreateCoupon handles POST /promotions/:id/coupons
+func (h *Handler) CreateCoupon(c *fiber.Ctx) error {
+	p, err := h.pathPromotion(c)
+	if err != nil {
+		return err
+	}
+
+	var req CreateCouponRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	coupon := &promotion.Coupon{
+		ID:             uuid.New(),
+		Code:           promotion.NormalizeCode(req.Code),
+		PromotionID:    p.ID,
+		MaxUses:        req.MaxUses,
+		MaxUsesPerUser: req.MaxUsesPerUser,
+		Active:         req.Active == nil || *req.Active,
+		ExpiresAt:      req.ExpiresAt,
+	}
+	if err := h.couponRepo.CreateCoupon(c.UserContext(), coupon); err != nil {
+		return h.writeError(c, err, "Failed to create coupon")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(coupon)
+}
+
+// UpdateCoupon handles PUT /promotions/:id/coupons/:couponId
+func (h *Handler) UpdateCoupon(c *fiber.Ctx) error {
+	coupon, err := h.pathCoupon(c)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateCouponRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	// Update fields
+	if req.MaxUses != nil {
+		coupon.MaxUses = *req.MaxUses
+	}
+	if req.MaxUsesPerUser != nil {
+		coupon.MaxUsesPerUser = *req.MaxUsesPerUser
+	}
+	if req.Active != nil {
+		coupon.Active = *req.Active
+	}
+	if req.ExpiresAt != nil {
+		coupon.ExpiresAt = req.ExpiresAt
+	}
+
+	if err := h.couponRepo.UpdateCoupon(c.UserContext(), coupon); err != nil {
+		return h.writeError(c, err, "Failed to update coupon")
+	}
+
+	return c.JSON(coupon)
+}
+
+// DeleteCoupon handles DELETE /promotions/:id/coupons/:couponId. Orders that
+// redeemed the coupon keep their discount.
+func (h *Handler) DeleteCoupon(c *fiber.Ctx) error {
+	coupon, err := h.pathCoupon(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.couponRepo.DeleteCoupon(c.UserContext(), coupon.ID); err != nil {
+		return h.writeError(c, err, "Failed to delete coupon")
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// ValidateCoupon handles POST /coupons/validate, telling a customer or
+// cashier whether a coupon can be used in a store now and, given the order
+// lines, what it takes off them. A coupon that cannot be used answers 200
+// with the reason.
+func (h *Handler) ValidateCoupon(c *fiber.Ctx) error {
+	var req ValidateCouponRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	ctx := c.UserContext()
+	now := time.Now()
+	running, err := h.promotionRepo.GetRunning(ctx, req.StoreID, now)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch running promotions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to validate coupon",
+		})
+	}
+
+	// The gateway names the caller, for the per-customer limit
+	cart := &promotion.Cart{
+		StoreID:  req.StoreID,
+		Currency: req.Currency,
+		Lines:    req.Lines,
+		Coupons:  []string{req.Code},
+	}
+	if userID, err := uuid.Parse(c.Get("X-User-ID")); err == nil {
+		cart.UserID = &userID
+	}
+
+	coupons, err := h.presentedCoupons(ctx, cart, running, now)
+	if err != nil {
+		if !isCouponRejection(err) {
+			logger.FromContext(ctx).Errorf("Failed to validate coupon: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to validate coupon",
+			})
+		}
+		return c.JSON(CouponValidation{Reason: err.Error()})
+	}
+
+	validation := CouponValidation{Valid: true, Coupon: coupons[0]}
+	for _, p := range running {
+		if p.ID == coupons[0].PromotionID {
+			validation.Promotion = p
+		}
+	}
+	if len(req.Lines) > 0 {
+		validation.Discount = promotion.Evaluate(running, coupons, cart, now)
+	}
+	return c.JSON(validation)
+}
+
+// RedeemCoupon handles POST /internal/v1/coupons/redemptions, called by the
+// order service as it saves an order that used a coupon
+func (h *Handler) RedeemCoupon(c *fiber.Ctx) error {
+	var req RedeemCouponRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	ctx := c.UserContext()
+	coupon, err := h.couponRepo.GetCouponByCode(ctx, req.Code)
+	if err != nil {
+		return couponError(c, err, "Failed to redeem coupon")
+	}
+	// Usage limits are checked as the redemption is recorded
+	if err := coupon.Check(time.Now(), 0); err != nil {
+		return couponError(c, err, "Failed to redeem coupon")
+	}
+
+	redemption := &promotion.CouponRedemption{
+		CouponID: coupon.ID,
+		Code:     coupon.Code,
+		OrderID:  req.OrderID,
+		UserID:   req.UserID,
+	}
+	if err := h.couponRepo.Redeem(ctx, redemption); err != nil {
+		return couponError(c, err, "Failed to redeem coupon")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(redemption)
+}
+
+// ReleaseCoupons handles DELETE /internal/v1/coupons/redemptions/:orderId,
+// called by the order service when an order that used coupons is cancelled
+// or not saved
+func (h *Handler) ReleaseCoupons(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("orderId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	if err := h.couponRepo.Release(c.UserContext(), orderID); err != nil {
+		return h.writeError(c, err, "Failed to release coupons")
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// presentedCoupons returns the coupons whose codes the cart presents, failing
+// with the reason one of them cannot be used on it: unknown, disabled,
+// expired, used up, or for a promotion not running among running. Coupons
+// the order repriced redeemed already are kept as they are.
+func (h *Handler) presentedCoupons(ctx context.Context, cart *promotion.Cart, running []*promotion.Promotion, now time.Time) ([]*promotion.Coupon, error) {
+	var coupons []*promotion.Coupon
+	seen := make(map[string]bool, len(cart.Coupons))
+	for _, code := range cart.Coupons {
+		code = promotion.NormalizeCode(code)
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		coupon, err := h.couponRepo.GetCouponByCode(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		if cart.OrderID != nil {
+			redeemed, err := h.couponRepo.Redeemed(ctx, coupon.ID, *cart.OrderID)
+			if err != nil {
+				return nil, err
+			}
+			if redeemed {
+				coupons = append(coupons, coupon)
+				continue
+			}
+		}
+		userUses := 0
+		if cart.UserID != nil && coupon.MaxUsesPerUser > 0 {
+			if userUses, err = h.couponRepo.UserRedemptions(ctx, coupon.ID, *cart.UserID); err != nil {
+				return nil, err
+			}
+		}
+		if err := coupon.Check(now, userUses); err != nil {
+			return nil, err
+		}
+		if !runsPromotion(running, coupon.PromotionID) {
+			return nil, promotion.ErrCouponNotApplicable
+		}
+		coupons = append(coupons, coupon)
+	}
+	return coupons, nil
+}
+
+// pathPromotion returns the promotion named in the path
+func (h *Handler) pathPromotion(c *fiber.Ctx) (*promotion.Promotion, error) {
+	promotionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid promotion ID")
+	}
+
+	p, err := h.promotionRepo.GetByID(c.UserContext(), promotionID)
+	if err != nil {
+		return nil, h.writeError(c, err, "Failed to fetch promotion")
+	}
+	return p, nil
+}
+
+// pathCoupon returns the coupon named in the path, when it belongs to the
+// promotion named there
+func (h *Handler) pathCoupon(c *fiber.Ctx) (*promotion.Coupon, error) {
+	promotionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid promotion ID")
+	}
+	couponID, err := uuid.Parse(c.Params("couponId"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid coupon ID")
+	}
+
+	coupon, err := h.couponRepo.GetCoupon(c.UserContext(), couponID)
+	if err != nil {
+		return nil, h.writeError(c, err, "Failed to fetch coupon")
+	}
+	if coupon.PromotionID != promotionID {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Coupon not found")
+	}
+	return coupon, nil
+}
+
+// runsPromotion reports whether the promotion with id is among running
+func runsPromotion(running []*promotion.Promotion, id uuid.UUID) bool {
+	for _, p := range running {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// couponRejections are the reasons a coupon presented cannot be used
+var couponRejections = []error{
+	promotion.ErrCouponNotFound,
+	promotion.ErrCouponInactive,
+	promotion.ErrCouponExpired,
+	promotion.ErrCouponExhausted,
+	promotion.ErrCouponNotApplicable,
+}
+
+// isCouponRejection reports whether err is a reason a coupon cannot be used
+func isCouponRejection(err error) bool {
+	for _, rejected := range couponRejections {
+		if errors.Is(err, rejected) {
+			return true
+		}
+	}
+	return false
+}
+
+// couponError answers a request presenting a coupon, with 422 and the reason
+// for a coupon that cannot be used and 409 for one the order redeemed already
+func couponError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, promotion.ErrCouponRedeemed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case isCouponRejection(err):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("%s: %v", message, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,96 @@
+package promotion
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/promotion"
+)
+
+// CreatePromotionRequest represents create promotion request
+type CreatePromotionRequest struct {
+	Name        string         `json:"name" validate:"required,max=255"`
+	Description string         `json:"description,omitempty"`
+	Type        string         `json:"type" validate:"required,oneof=bogo basket_threshold category_percent happy_hour percent_off amount_off"`
+	Rule        promotion.Rule `json:"rule"`
+	Priority    int            `json:"priority"`
+	Exclusive   bool           `json:"exclusive"`
+	Active      *bool          `json:"active,omitempty"` // Defaults to true
+	CouponOnly  bool           `json:"coupon_only"`
+	StoreIDs    []uuid.UUID    `json:"store_ids,omitempty"`
+	Currency    string         `json:"currency,omitempty" validate:"omitempty,len=3"`
+	StartsAt    *time.Time     `json:"starts_at,omitempty"`
+	EndsAt      *time.Time     `json:"ends_at,omitempty"`
+}
+
+// UpdatePromotionRequest represents update promotion request. The rule and
+// store list given replace the promotion's.
+type UpdatePromotionRequest struct {
+	Name        string          `json:"name,omitempty" validate:"omitempty,max=255"`
+	Description *string         `json:"description,omitempty"`
+	Type        string          `json:"type,omitempty" validate:"omitempty,oneof=bogo basket_threshold category_percent happy_hour percent_off amount_off"`
+	Rule        *promotion.Rule `json:"rule,omitempty"`
+	Priority    *int            `json:"priority,omitempty"`
+	Exclusive   *bool           `json:"exclusive,omitempty"`
+	Active      *bool           `json:"active,omitempty"`
+	CouponOnly  *bool           `json:"coupon_only,omitempty"`
+	StoreIDs    []uuid.UUID     `json:"store_ids,omitempty"`
+	Currency    string          `json:"currency,omitempty" validate:"omitempty,len=3"`
+	StartsAt    *time.Time      `json:"starts_at,omitempty"`
+	EndsAt      *time.Time      `json:"ends_at,omitempty"`
+}
+
+// EvaluateRequest asks what the promotions running in a store take off an
+// order, with those the coupons presented unlock
+type EvaluateRequest struct {
+	StoreID  uuid.UUID        `json:"store_id" validate:"required"`
+	UserID   *uuid.UUID       `json:"user_id,omitempty"`
+	OrderID  *uuid.UUID       `json:"order_id,omitempty"` // Order repriced
+	Currency string           `json:"currency" validate:"required,len=3"`
+	Lines    []promotion.Line `json:"lines" validate:"required,min=1,max=500"`
+	Coupons  []string         `json:"coupons,omitempty" validate:"omitempty,max=5,dive,min=1,max=64"`
+}
+
+// CreateCouponRequest represents create coupon request
+type CreateCouponRequest struct {
+	Code           string     `json:"code" validate:"required,min=3,max=64,printascii"`
+	MaxUses        int        `json:"max_uses,omitempty" validate:"gte=0"` // 0 is unlimited
+	MaxUsesPerUser int        `json:"max_uses_per_user,omitempty" validate:"gte=0"`
+	Active         *bool      `json:"active,omitempty"` // Defaults to true
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+// UpdateCouponRequest represents update coupon request. The code of a
+// coupon does not change.
+type UpdateCouponRequest struct {
+	MaxUses        *int       `json:"max_uses,omitempty" validate:"omitempty,gte=0"`
+	MaxUsesPerUser *int       `json:"max_uses_per_user,omitempty" validate:"omitempty,gte=0"`
+	Active         *bool      `json:"active,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+// ValidateCouponRequest asks whether a coupon can be used in a store and,
+// given lines, what it would take off them
+type ValidateCouponRequest struct {
+	Code     string           `json:"code" validate:"required,max=64"`
+	StoreID  uuid.UUID        `json:"store_id" validate:"required"`
+	Currency string           `json:"currency,omitempty" validate:"required_with=Lines,omitempty,len=3"`
+	Lines    []promotion.Line `json:"lines,omitempty" validate:"omitempty,max=500"`
+}
+
+// CouponValidation is whether a coupon can be used, and why not
+type CouponValidation struct {
+	Valid     bool                 `json:"valid"`
+	Reason    string               `json:"reason,omitempty"` // Why the coupon cannot be used
+	Coupon    *promotion.Coupon    `json:"coupon,omitempty"`
+	Promotion *promotion.Promotion `json:"promotion,omitempty"` // What the coupon unlocks
+	Discount  *promotion.Result    `json:"discount,omitempty"`  // What it takes off the lines given, with the running promotions
+}
+
+// RedeemCouponRequest records an order's use of a coupon
+type RedeemCouponRequest struct {
+	Code    string    `json:"code" validate:"required,max=64"`
+	OrderID uuid.UUID `json:"order_id" validate:"required"`
+	UserID  uuid.UUID `json:"user_id" validate:"required"`
+}
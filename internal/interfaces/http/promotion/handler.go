@@ -0,0 +1,286 @@
+package promotion
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/promotion"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// Handler handles promotion HTTP requests
+type Handler struct {
+	promotionRepo promotion.Repository
+	couponRepo    promotion.CouponRepository
+}
+
+// NewHandler creates a new promotion handler
+func NewHandler(promotionRepo promotion.Repository, couponRepo promotion.CouponRepository) *Handler {
+	return &Handler{
+		promotionRepo: promotionRepo,
+		couponRepo:    couponRepo,
+	}
+}
+
+// GetPromotions handles GET /promotions?active=
+func (h *Handler) GetPromotions(c *fiber.Ctx) error {
+	// Parse pagination
+	limit := 20
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+	activeOnly := c.QueryBool("active")
+
+	promotions, err := h.promotionRepo.List(c.UserContext(), activeOnly, limit, offset)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch promotions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch promotions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   promotions,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetPromotionByID handles GET /promotions/:id
+func (h *Handler) GetPromotionByID(c *fiber.Ctx) error {
+	promotionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid promotion ID",
+		})
+	}
+
+	p, err := h.promotionRepo.GetByID(c.UserContext(), promotionID)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch promotion")
+	}
+
+	return c.JSON(p)
+}
+
+// CreatePromotion handles POST /promotions
+func (h *Handler) CreatePromotion(c *fiber.Ctx) error {
+	var req CreatePromotionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	p := &promotion.Promotion{
+		ID:          uuid.New(),
+		Name:        req.Name,
+		Description: req.Description,
+		Type:        promotion.RuleType(req.Type),
+		Rule:        req.Rule,
+		Priority:    req.Priority,
+		Exclusive:   req.Exclusive,
+		Active:      req.Active == nil || *req.Active,
+		CouponOnly:  req.CouponOnly,
+		StoreIDs:    req.StoreIDs,
+		Currency:    req.Currency,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+	}
+
+	if err := p.Validate(); err != nil {
+		return h.writeError(c, err, "Failed to create promotion")
+	}
+	if err := h.promotionRepo.Create(c.UserContext(), p); err != nil {
+		return h.writeError(c, err, "Failed to create promotion")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(p)
+}
+
+// UpdatePromotion handles PUT /promotions/:id
+func (h *Handler) UpdatePromotion(c *fiber.Ctx) error {
+	promotionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid promotion ID",
+		})
+	}
+
+	p, err := h.promotionRepo.GetByID(c.UserContext(), promotionID)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch promotion")
+	}
+
+	var req UpdatePromotionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	// Update fields
+	if req.Name != "" {
+		p.Name = req.Name
+	}
+	if req.Description != nil {
+		p.Description = *req.Description
+	}
+	if req.Type != "" {
+		p.Type = promotion.RuleType(req.Type)
+	}
+	if req.Rule != nil {
+		p.Rule = *req.Rule
+	}
+	if req.Priority != nil {
+		p.Priority = *req.Priority
+	}
+	if req.Exclusive != nil {
+		p.Exclusive = *req.Exclusive
+	}
+	if req.Active != nil {
+		p.Active = *req.Active
+	}
+	if req.CouponOnly != nil {
+		p.CouponOnly = *req.CouponOnly
+	}
+	if req.StoreIDs != nil {
+		p.StoreIDs = req.StoreIDs
+	}
+	if req.Currency != "" {
+		p.Currency = req.Currency
+	}
+	if req.StartsAt != nil {
+		p.StartsAt = req.StartsAt
+	}
+	if req.EndsAt != nil {
+		p.EndsAt = req.EndsAt
+	}
+
+	if err := p.Validate(); err != nil {
+		return h.writeError(c, err, "Failed to update promotion")
+	}
+	if err := h.promotionRepo.Update(c.UserContext(), p); err != nil {
+		return h.writeError(c, err, "Failed to update promotion")
+	}
+
+	return c.JSON(p)
+}
+
+// DeletePromotion handles DELETE /promotions/:id
+func (h *Handler) DeletePromotion(c *fiber.Ctx) error {
+	promotionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid promotion ID",
+		})
+	}
+
+	if err := h.promotionRepo.Delete(c.UserContext(), promotionID); err != nil {
+		return h.writeError(c, err, "Failed to delete promotion")
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// Evaluate handles POST /internal/v1/promotions/evaluate, called by the
+// order service to discount order lines as it prices them. Coupons presented
+// that cannot be used on the order answer 422 with the reason.
+func (h *Handler) Evaluate(c *fiber.Ctx) error {
+	var req EvaluateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	now := time.Now()
+	running, err := h.promotionRepo.GetRunning(c.UserContext(), req.StoreID, now)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch running promotions: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to evaluate promotions",
+		})
+	}
+
+	cart := &promotion.Cart{
+		StoreID:  req.StoreID,
+		UserID:   req.UserID,
+		OrderID:  req.OrderID,
+		Currency: req.Currency,
+		Lines:    req.Lines,
+		Coupons:  req.Coupons,
+	}
+	coupons, err := h.presentedCoupons(c.UserContext(), cart, running, now)
+	if err != nil {
+		return couponError(c, err, "Failed to evaluate promotions")
+	}
+	result := promotion.Evaluate(running, coupons, cart, now)
+	for _, applied := range result.Applied {
+		metrics.PromotionsApplied.WithLabelValues(string(applied.Type)).Inc()
+	}
+
+	return c.JSON(result)
+}
+
+// writeError answers a failed request, with 404 for an unknown promotion or
+// coupon, 409 for a coupon code in use and 400 for an invalid rule
+func (h *Handler) writeError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, promotion.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Promotion not found",
+		})
+	case errors.Is(err, promotion.ErrCouponNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Coupon not found",
+		})
+	case errors.Is(err, promotion.ErrCouponExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, promotion.ErrInvalidRule):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("%s: %v", message, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,201 @@
+package receipt
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/receipt"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// maxClaim caps the jobs handed to an agent at once
+const maxClaim = 50
+
+// PollJobs handles GET /print-agent/jobs?printer_id=&limit=, claiming the
+// agent's store's pending jobs. Claimed jobs not acknowledged within the
+// lease are handed out again.
+func (h *Handler) PollJobs(c *fiber.Ctx) error {
+	storeID, err := h.agentStore(c)
+	if err != nil {
+		return err
+	}
+
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= maxClaim {
+			limit = l
+		}
+	}
+
+	jobs, err := h.claim(c.UserContext(), storeID, c.Query("printer_id"), limit)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to claim print jobs: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch print jobs",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": jobs,
+	})
+}
+
+// AckJob handles POST /print-agent/jobs/:id/ack
+func (h *Handler) AckJob(c *fiber.Ctx) error {
+	storeID, err := h.agentStore(c)
+	if err != nil {
+		return err
+	}
+
+	jobID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid print job ID",
+		})
+	}
+
+	var req AckRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	job, err := h.complete(c.UserContext(), jobID, storeID, req)
+	if err != nil {
+		return h.writeError(c, err, "Failed to acknowledge print job")
+	}
+
+	return c.JSON(job)
+}
+
+// StreamJobs handles GET /print-agent/ws?printer_id=, pushing the agent's
+// store's jobs over a WebSocket as they are queued. Agents acknowledge jobs
+// with "ack" messages on the same connection.
+func (h *Handler) StreamJobs(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	storeID, err := h.agentStore(c)
+	if err != nil {
+		return err
+	}
+	c.Locals("store_id", storeID)
+	c.Locals("printer_id", c.Query("printer_id"))
+	c.Locals("log", logger.FromContext(c.UserContext()))
+	return h.upgrade(c)
+}
+
+// newStream returns the WebSocket handler behind StreamJobs
+func newStream(h *Handler) fiber.Handler {
+	return websocket.New(h.stream)
+}
+
+// stream claims jobs for a connected agent whenever one is queued on this
+// instance, and at every poll interval for jobs queued elsewhere or out of
+// lease
+func (h *Handler) stream(conn *websocket.Conn) {
+	storeID, _ := conn.Locals("store_id").(uuid.UUID)
+	printerID, _ := conn.Locals("printer_id").(string)
+	log, _ := conn.Locals("log").(*logger.Logger)
+	ctx := context.Background()
+
+	queued, unsubscribe := h.queued.subscribe(storeID)
+	defer unsubscribe()
+
+	// Agents that stop answering pings are dropped
+	deadline := 3 * h.cfg.PollInterval
+	_ = conn.SetReadDeadline(time.Now().Add(deadline))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(deadline))
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var msg AgentMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if msg.Type != "ack" {
+				continue
+			}
+
+			ack := AckRequest{Status: msg.Status, Error: msg.Error}
+			if validationErrors := validator.ValidateStruct(&ack); len(validationErrors) > 0 {
+				log.Warnf("Invalid acknowledgement of print job %s from store %s", msg.JobID, storeID)
+				continue
+			}
+			if _, err := h.complete(ctx, msg.JobID, storeID, ack); err != nil {
+				log.Warnf("Failed to acknowledge print job %s: %v", msg.JobID, err)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(h.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		jobs, err := h.claim(ctx, storeID, printerID, maxClaim)
+		if err != nil {
+			log.Errorf("Failed to claim print jobs for store %s: %v", storeID, err)
+		}
+		for _, job := range jobs {
+			if err := conn.WriteJSON(AgentMessage{Type: "job", Job: job}); err != nil {
+				// Unsent jobs are handed out again once their lease runs out
+				return
+			}
+		}
+
+		select {
+		case <-queued:
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second*10)); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// agentStore returns the store of the signed request's print agent
+func (h *Handler) agentStore(c *fiber.Ctx) (uuid.UUID, error) {
+	agentID, _ := c.Locals("partner_id").(string)
+	storeID, err := uuid.Parse(h.cfg.Agents[agentID])
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusForbidden, "Not a print agent")
+	}
+	return storeID, nil
+}
+
+// claim hands a store's pending jobs to one of its agents
+func (h *Handler) claim(ctx context.Context, storeID uuid.UUID, printerID string, limit int) ([]*receipt.PrintJob, error) {
+	return h.receiptRepo.ClaimJobs(ctx, storeID, printerID, limit, time.Now(), h.cfg.JobLease, h.cfg.MaxAttempts)
+}
+
+// complete records an agent's acknowledgement of a job
+func (h *Handler) complete(ctx context.Context, jobID, storeID uuid.UUID, ack AckRequest) (*receipt.PrintJob, error) {
+	job, err := h.receiptRepo.CompleteJob(ctx, jobID, storeID, ack.Status == string(receipt.JobPrinted), ack.Error, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	metrics.PrintJobs.WithLabelValues(string(job.Kind), string(job.Status)).Inc()
+	return job, nil
+}
@@ -0,0 +1,48 @@
+package receipt
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/receipt"
+)
+
+// UpdateTemplateRequest represents update receipt template request. Header
+// and footer lists given replace the template's.
+type UpdateTemplateRequest struct {
+	Header      []string `json:"header,omitempty" validate:"omitempty,max=10,dive,max=64"`
+	Footer      []string `json:"footer,omitempty" validate:"omitempty,max=10,dive,max=128"`
+	Width       int      `json:"width,omitempty" validate:"omitempty,min=24,max=64"`
+	ShowBarcode *bool    `json:"show_barcode,omitempty"`
+	OpenDrawer  *bool    `json:"open_drawer,omitempty"`
+	DrawerPin   int      `json:"drawer_pin,omitempty" validate:"omitempty,oneof=2 5"`
+}
+
+// PrintReceiptRequest represents print receipt request
+type PrintReceiptRequest struct {
+	OrderID    uuid.UUID `json:"order_id" validate:"required"`
+	PrinterID  string    `json:"printer_id,omitempty" validate:"max=64"` // Empty prints on any of the store's printers
+	Copies     int       `json:"copies,omitempty" validate:"omitempty,min=1,max=5"`
+	OpenDrawer *bool     `json:"open_drawer,omitempty"` // Overrides the store's template
+}
+
+// OpenDrawerRequest represents open cash drawer request
+type OpenDrawerRequest struct {
+	StoreID   uuid.UUID `json:"store_id" validate:"required"`
+	PrinterID string    `json:"printer_id,omitempty" validate:"max=64"`
+}
+
+// AckRequest represents a print agent's acknowledgement of a job
+type AckRequest struct {
+	Status string `json:"status" validate:"required,oneof=printed failed"`
+	Error  string `json:"error,omitempty" validate:"max=500"`
+}
+
+// AgentMessage is a message on a print agent's WebSocket: jobs from the
+// service, and acknowledgements from the agent
+type AgentMessage struct {
+	Type   string            `json:"type"` // "job" or "ack"
+	Job    *receipt.PrintJob `json:"job,omitempty"`
+	JobID  uuid.UUID         `json:"job_id,omitempty"`
+	Status string            `json:"status,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
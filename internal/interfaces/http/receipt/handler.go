@@ -0,0 +1,393 @@
+package receipt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/receipt"
+	"github.com/onichange/pos-system/internal/infrastructure/serviceclient"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	printout "github.com/onichange/pos-system/pkg/receipt"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// OrderReader fetches orders from the order service
+type OrderReader interface {
+	GetOrder(ctx context.Context, id uuid.UUID) (*order.Order, error)
+}
+
+// Handler handles receipt and print job HTTP requests
+type Handler struct {
+	receiptRepo receipt.Repository
+	orders      OrderReader
+	cfg         config.ReceiptConfig
+	queued      *notifier
+	upgrade     fiber.Handler
+}
+
+// NewHandler creates a new receipt handler
+func NewHandler(receiptRepo receipt.Repository, orders OrderReader, cfg config.ReceiptConfig) *Handler {
+	h := &Handler{
+		receiptRepo: receiptRepo,
+		orders:      orders,
+		cfg:         cfg,
+		queued:      newNotifier(),
+	}
+	h.upgrade = newStream(h)
+	return h
+}
+
+// GetTemplate handles GET /stores/:storeId/receipt-template
+func (h *Handler) GetTemplate(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("storeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+
+	t, err := h.receiptRepo.GetTemplate(c.UserContext(), storeID)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch receipt template: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch receipt template",
+		})
+	}
+
+	return c.JSON(t)
+}
+
+// UpdateTemplate handles PUT /stores/:storeId/receipt-template
+func (h *Handler) UpdateTemplate(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("storeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+
+	var req UpdateTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	t, err := h.receiptRepo.GetTemplate(c.UserContext(), storeID)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch receipt template: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update receipt template",
+		})
+	}
+
+	// Update fields
+	if req.Header != nil {
+		t.Header = req.Header
+	}
+	if req.Footer != nil {
+		t.Footer = req.Footer
+	}
+	if req.Width != 0 {
+		t.Width = req.Width
+	}
+	if req.ShowBarcode != nil {
+		t.ShowBarcode = *req.ShowBarcode
+	}
+	if req.OpenDrawer != nil {
+		t.OpenDrawer = *req.OpenDrawer
+	}
+	if req.DrawerPin != 0 {
+		t.DrawerPin = req.DrawerPin
+	}
+
+	if err := h.receiptRepo.SaveTemplate(c.UserContext(), t); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to save receipt template: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update receipt template",
+		})
+	}
+
+	return c.JSON(t)
+}
+
+// PrintReceipt handles POST /receipts, queuing an order's receipt for its
+// store's print agents
+func (h *Handler) PrintReceipt(c *fiber.Ctx) error {
+	var req PrintReceiptRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	o, t, err := h.orderReceipt(c, req.OrderID)
+	if err != nil {
+		return err
+	}
+	if req.OpenDrawer != nil {
+		t.OpenDrawer = *req.OpenDrawer
+	}
+
+	// Only the first copy opens the drawer
+	copies := req.Copies
+	if copies == 0 {
+		copies = 1
+	}
+	r := buildReceipt(o, t)
+	var payload bytes.Buffer
+	for i := 0; i < copies; i++ {
+		payload.Write(printout.ESCPOS(r))
+		r.OpenDrawer = false
+	}
+
+	job := &receipt.PrintJob{
+		ID:        uuid.New(),
+		StoreID:   o.StoreID,
+		PrinterID: req.PrinterID,
+		OrderID:   &o.ID,
+		Kind:      receipt.KindReceipt,
+		Status:    receipt.JobQueued,
+		Payload:   payload.Bytes(),
+	}
+	return h.queue(c, job)
+}
+
+// GetReceipt handles GET /receipts/:orderId?format=pdf|escpos, rendering an
+// order's receipt for download or reprinting elsewhere
+func (h *Handler) GetReceipt(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("orderId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	format := c.Query("format", "pdf")
+	if format != "pdf" && format != "escpos" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "format must be pdf or escpos",
+		})
+	}
+
+	o, t, err := h.orderReceipt(c, orderID)
+	if err != nil {
+		return err
+	}
+	r := buildReceipt(o, t)
+	r.OpenDrawer = false
+
+	name := "receipt-" + orderNumber(o)
+	if format == "escpos" {
+		c.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+name+`.bin"`)
+		return c.Send(printout.ESCPOS(r))
+	}
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="`+name+`.pdf"`)
+	return c.Send(printout.PDF(r))
+}
+
+// OpenDrawer handles POST /print-jobs/drawer, queuing a job that opens a
+// store's cash drawer without a sale
+func (h *Handler) OpenDrawer(c *fiber.Ctx) error {
+	var req OpenDrawerRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	t, err := h.receiptRepo.GetTemplate(c.UserContext(), req.StoreID)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch receipt template: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to open cash drawer",
+		})
+	}
+
+	job := &receipt.PrintJob{
+		ID:        uuid.New(),
+		StoreID:   req.StoreID,
+		PrinterID: req.PrinterID,
+		Kind:      receipt.KindDrawer,
+		Status:    receipt.JobQueued,
+		Payload:   printout.DrawerKick(printout.DrawerPin(t.DrawerPin)),
+	}
+	return h.queue(c, job)
+}
+
+// GetPrintJobs handles GET /print-jobs?store_id=&status=
+func (h *Handler) GetPrintJobs(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Query("store_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "store_id is required",
+		})
+	}
+
+	// Parse pagination
+	limit := 20
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	jobs, err := h.receiptRepo.ListJobs(c.UserContext(), storeID, receipt.JobStatus(c.Query("status")), limit, offset)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch print jobs: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch print jobs",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   jobs,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetPrintJob handles GET /print-jobs/:id
+func (h *Handler) GetPrintJob(c *fiber.Ctx) error {
+	jobID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid print job ID",
+		})
+	}
+
+	job, err := h.receiptRepo.GetJob(c.UserContext(), jobID)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch print job")
+	}
+
+	return c.JSON(job)
+}
+
+// orderReceipt fetches an order the caller may print, owned by them unless
+// they are an admin, and its store's template
+func (h *Handler) orderReceipt(c *fiber.Ctx, orderID uuid.UUID) (*order.Order, *receipt.Template, error) {
+	userID, err := currentUser(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	o, err := h.orders.GetOrder(c.UserContext(), orderID)
+	if err != nil {
+		var statusErr *serviceclient.StatusError
+		if errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound {
+			return nil, nil, fiber.NewError(fiber.StatusNotFound, "Order not found")
+		}
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch order %s: %v", orderID, err)
+		return nil, nil, fiber.NewError(fiber.StatusServiceUnavailable, "Orders unavailable")
+	}
+	if o.UserID != userID && !isAdmin(c) {
+		return nil, nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	t, err := h.receiptRepo.GetTemplate(c.UserContext(), o.StoreID)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch receipt template: %v", err)
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to render receipt")
+	}
+	return o, t, nil
+}
+
+// queue stores a print job and wakes the store's agents connected to this
+// instance
+func (h *Handler) queue(c *fiber.Ctx, job *receipt.PrintJob) error {
+	if err := h.receiptRepo.CreateJob(c.UserContext(), job); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to queue print job: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to queue print job",
+		})
+	}
+	metrics.PrintJobs.WithLabelValues(string(job.Kind), string(job.Status)).Inc()
+	h.queued.notify(job.StoreID)
+
+	accepted := *job
+	accepted.Payload = nil
+	return c.Status(fiber.StatusAccepted).JSON(accepted)
+}
+
+// writeError answers a failed request, with 404 for an unknown job and 409
+// for a job that is not awaiting acknowledgement
+func (h *Handler) writeError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, receipt.ErrJobNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Print job not found",
+		})
+	case errors.Is(err, receipt.ErrJobClosed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("%s: %v", message, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+// currentUser returns the authenticated user's ID
+func currentUser(c *fiber.Ctx) (uuid.UUID, error) {
+	userIDStr, _ := c.Locals("user_id").(string)
+	if userIDStr == "" {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+	return userID, nil
+}
+
+// isAdmin reports whether the authenticated user is an admin
+func isAdmin(c *fiber.Ctx) bool {
+	roles, _ := c.Locals("roles").([]string)
+	for _, role := range roles {
+		if role == "admin" {
+			return true
+		}
+	}
+	return false
+}
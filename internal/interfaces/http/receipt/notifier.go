@@ -0,0 +1,54 @@
+package receipt
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// notifier wakes the print agent connections of a store on this instance
+// when a job is queued for it. Agents on other instances pick the job up on
+// their next poll.
+type notifier struct {
+	mu   sync.Mutex
+	subs map[uuid.UUID]map[chan struct{}]struct{}
+}
+
+func newNotifier() *notifier {
+	return &notifier{subs: make(map[uuid.UUID]map[chan struct{}]struct{})}
+}
+
+// subscribe returns a channel signalled when storeID has new jobs, and a
+// function that unsubscribes it
+func (n *notifier) subscribe(storeID uuid.UUID) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	n.mu.Lock()
+	if n.subs[storeID] == nil {
+		n.subs[storeID] = make(map[chan struct{}]struct{})
+	}
+	n.subs[storeID][ch] = struct{}{}
+	n.mu.Unlock()
+
+	return ch, func() {
+		n.mu.Lock()
+		delete(n.subs[storeID], ch)
+		if len(n.subs[storeID]) == 0 {
+			delete(n.subs, storeID)
+		}
+		n.mu.Unlock()
+	}
+}
+
+// notify signals storeID's subscribers without blocking; a subscriber
+// already signalled claims every pending job when it wakes
+func (n *notifier) notify(storeID uuid.UUID) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for ch := range n.subs[storeID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package receipt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/receipt"
+	printout "github.com/onichange/pos-system/pkg/receipt"
+)
+
+// orderNumber is the short order reference printed on receipts
+func orderNumber(o *order.Order) string {
+	return strings.ToUpper(strings.ReplaceAll(o.ID.String(), "-", "")[:8])
+}
+
+// buildReceipt lays o out with its store's template
+func buildReceipt(o *order.Order, t *receipt.Template) *printout.Receipt {
+	r := &printout.Receipt{
+		Width:      t.Width,
+		Header:     t.Header,
+		Footer:     t.Footer,
+		Currency:   o.Currency,
+		OpenDrawer: t.OpenDrawer,
+		DrawerPin:  printout.DrawerPin(t.DrawerPin),
+	}
+
+	r.Meta = []string{
+		"Order #" + orderNumber(o),
+		o.CreatedAt.Format("2006-01-02 15:04"),
+	}
+	if o.Tender != "" {
+		r.Meta = append(r.Meta, "Paid by "+strings.ReplaceAll(o.Tender, "_", " "))
+	}
+	if o.Status == order.StatusCancelled || o.Status == order.StatusRefunded {
+		r.Meta = append(r.Meta, strings.ToUpper(string(o.Status)))
+	}
+	if t.ShowBarcode {
+		r.Barcode = orderNumber(o)
+	}
+
+	// Promotion discounts are already spread over the item discounts
+	var saved float64
+	for _, item := range o.Items {
+		r.Items = append(r.Items, printout.Item{
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+			Discount:  item.Discount,
+			Amount:    item.Subtotal,
+		})
+		saved += item.Discount
+	}
+
+	r.Totals = append(r.Totals, printout.Total{Label: "Subtotal", Amount: o.Subtotal()})
+	if o.LoyaltyDiscount > 0 {
+		r.Totals = append(r.Totals, printout.Total{
+			Label:  fmt.Sprintf("Loyalty (%d pts)", o.LoyaltyPoints),
+			Amount: -o.LoyaltyDiscount,
+		})
+		saved += o.LoyaltyDiscount
+	}
+	for _, t := range o.Taxes {
+		if t.Tax > 0 {
+			r.Totals = append(r.Totals, printout.Total{Label: t.Name, Amount: t.Tax})
+		}
+	}
+	if len(o.Taxes) == 0 && o.TaxAmount > 0 {
+		r.Totals = append(r.Totals, printout.Total{Label: "Tax", Amount: o.TaxAmount})
+	}
+	r.Totals = append(r.Totals, printout.Total{Label: "TOTAL", Amount: o.TotalAmount, Grand: true})
+	if saved > 0 {
+		r.Totals = append(r.Totals, printout.Total{Label: "You saved", Amount: saved})
+	}
+	for _, p := range o.Promotions {
+		r.Meta = append(r.Meta, "Promotion: "+p.Name)
+	}
+
+	return r
+}
@@ -0,0 +1,137 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+
+	"github.com/onichange/pos-system/internal/domain/catalog"
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/search"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+)
+
+// HandleEvent indexes an order or product event from the message broker.
+// Every event carries the whole document, versioned by the event's time, so
+// redeliveries and events arriving out of order are harmless. Malformed
+// messages and other event types are dropped.
+func (h *Handler) HandleEvent(ctx context.Context, msg amqp.Delivery) error {
+	log := logger.FromContext(ctx)
+
+	var event messagequeue.Event
+	if err := json.Unmarshal(msg.Body, &event); err != nil || event.ID == "" {
+		log.Errorf("Dropping malformed event: %v", err)
+		return nil
+	}
+	raw, _ := json.Marshal(event.Data)
+	version := event.Timestamp.UnixMicro()
+
+	var err error
+	switch event.Type {
+	case order.EventCreated, order.EventUpdated, order.EventCancelled, order.EventCompleted, order.EventRefunded:
+		var data order.EventData
+		if err := json.Unmarshal(raw, &data); err != nil || data.OrderID == uuid.Nil {
+			log.Errorf("Dropping %s event %s without an order", event.Type, event.ID)
+			return nil
+		}
+		doc := orderDocument(data)
+		doc.UpdatedAt = event.Timestamp.UTC()
+		if doc.CreatedAt.IsZero() {
+			// Published before events carried the order's creation time
+			doc.CreatedAt = doc.UpdatedAt
+		}
+		h.addCustomer(ctx, doc)
+		err = h.searchRepo.IndexOrder(ctx, doc, version)
+	case catalog.EventProductDeleted:
+		var data catalog.EventData
+		if err := json.Unmarshal(raw, &data); err != nil || data.ProductID == uuid.Nil {
+			log.Errorf("Dropping %s event %s without a product", event.Type, event.ID)
+			return nil
+		}
+		err = h.searchRepo.DeleteProduct(ctx, data.ProductID, version)
+	case catalog.EventProductCreated, catalog.EventProductUpdated:
+		var data catalog.EventData
+		if err := json.Unmarshal(raw, &data); err != nil || data.ProductID == uuid.Nil {
+			log.Errorf("Dropping %s event %s without a product", event.Type, event.ID)
+			return nil
+		}
+		doc := productDocument(data)
+		doc.UpdatedAt = event.Timestamp.UTC()
+		err = h.searchRepo.IndexProduct(ctx, doc, version)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to index %s event %s: %w", event.Type, event.ID, err)
+	}
+	return nil
+}
+
+// addCustomer fills in the name and email of an order's customer. Orders
+// are indexed without them when the user service cannot be reached, rather
+// than holding up the queue; the order's next event fills them in.
+func (h *Handler) addCustomer(ctx context.Context, doc *search.OrderDocument) {
+	if h.users == nil || doc.UserID == uuid.Nil {
+		return
+	}
+
+	u, err := h.users.GetUser(ctx, doc.UserID)
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Indexing order %s without its customer: %v", doc.ID, err)
+		return
+	}
+	doc.CustomerName = strings.TrimSpace(u.FirstName + " " + u.LastName)
+	doc.CustomerEmail = u.Email
+}
+
+// orderDocument returns the search document of an order event
+func orderDocument(data order.EventData) *search.OrderDocument {
+	doc := &search.OrderDocument{
+		ID:          data.OrderID,
+		StoreID:     data.StoreID,
+		UserID:      data.UserID,
+		Status:      string(data.Status),
+		TotalAmount: data.TotalAmount,
+		Currency:    data.Currency,
+		ItemNames:   make([]string, 0, len(data.Items)),
+		ProductIDs:  make([]string, 0, len(data.Items)),
+		CreatedAt:   data.CreatedAt.UTC(),
+	}
+	for _, item := range data.Items {
+		doc.ItemNames = append(doc.ItemNames, item.Name)
+		if item.ProductID != "" {
+			doc.ProductIDs = append(doc.ProductIDs, item.ProductID)
+		}
+	}
+	return doc
+}
+
+// productDocument returns the search document of a product event
+func productDocument(data catalog.EventData) *search.ProductDocument {
+	doc := &search.ProductDocument{
+		ID:           data.ProductID,
+		CategoryID:   data.CategoryID,
+		Name:         data.Name,
+		Description:  data.Description,
+		Brand:        data.Brand,
+		Status:       string(data.Status),
+		SKUs:         make([]string, 0, len(data.Variants)),
+		Barcodes:     make([]string, 0, len(data.Variants)),
+		VariantNames: make([]string, 0, len(data.Variants)),
+	}
+	for _, v := range data.Variants {
+		doc.SKUs = append(doc.SKUs, v.SKU)
+		if v.Barcode != "" {
+			doc.Barcodes = append(doc.Barcodes, v.Barcode)
+		}
+		if v.Name != "" {
+			doc.VariantNames = append(doc.VariantNames, v.Name)
+		}
+	}
+	return doc
+}
@@ -0,0 +1,178 @@
+package search
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/search"
+	"github.com/onichange/pos-system/internal/domain/user"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// maxWindow is how deep into results a search may page; OpenSearch refuses
+// to go past its max_result_window
+const maxWindow = 10000
+
+// UserDirectory looks up the customers of indexed orders
+type UserDirectory interface {
+	GetUser(ctx context.Context, id uuid.UUID) (*user.User, error)
+}
+
+// Handler handles search HTTP requests and indexes the events they search
+type Handler struct {
+	searchRepo search.Repository
+	users      UserDirectory
+}
+
+// NewHandler creates a new search handler. users may be nil, in which case
+// orders are indexed without their customer's name and email.
+func NewHandler(searchRepo search.Repository, users UserDirectory) *Handler {
+	return &Handler{
+		searchRepo: searchRepo,
+		users:      users,
+	}
+}
+
+// SearchOrders handles GET /search/orders?q=&status=&store_id=&from=&to=&interval=&limit=&offset=
+func (h *Handler) SearchOrders(c *fiber.Ctx) error {
+	q := search.OrderQuery{
+		Text:     c.Query("q"),
+		Status:   c.Query("status"),
+		Interval: search.Interval(c.Query("interval", string(search.Day))),
+	}
+	if q.Interval != search.Day && q.Interval != search.Week && q.Interval != search.Month {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "interval must be day, week or month",
+		})
+	}
+
+	if value := c.Query("store_id"); value != "" {
+		storeID, err := uuid.Parse(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid store ID",
+			})
+		}
+		q.StoreID = &storeID
+	}
+	var err error
+	if q.From, err = parseTime(c, "from"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if q.To, err = parseTime(c, "to"); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "from must be before to",
+		})
+	}
+	if q.Limit, q.Offset, err = pagination(c); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	results, err := h.searchRepo.SearchOrders(c.UserContext(), q)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to search orders: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search orders",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   results.Orders,
+		"total":  results.Total,
+		"facets": results.Facets,
+		"limit":  q.Limit,
+		"offset": q.Offset,
+	})
+}
+
+// SearchProducts handles GET /search/products?q=&status=&brand=&category_id=&limit=&offset=
+func (h *Handler) SearchProducts(c *fiber.Ctx) error {
+	q := search.ProductQuery{
+		Text:   c.Query("q"),
+		Status: c.Query("status"),
+		Brand:  c.Query("brand"),
+	}
+
+	if value := c.Query("category_id"); value != "" {
+		categoryID, err := uuid.Parse(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid category ID",
+			})
+		}
+		q.CategoryID = &categoryID
+	}
+	var err error
+	if q.Limit, q.Offset, err = pagination(c); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	results, err := h.searchRepo.SearchProducts(c.UserContext(), q)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to search products: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search products",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   results.Products,
+		"total":  results.Total,
+		"facets": results.Facets,
+		"limit":  q.Limit,
+		"offset": q.Offset,
+	})
+}
+
+// parseTime reads an optional RFC 3339 time or YYYY-MM-DD date, in UTC
+func parseTime(c *fiber.Ctx, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, key+" must be an RFC 3339 time or a YYYY-MM-DD date")
+	}
+	return &t, nil
+}
+
+// pagination parses the limit and offset of a search, 20 results by default
+// and at most 100
+func pagination(c *fiber.Ctx) (int, int, error) {
+	limit := 20
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+	if offset+limit > maxWindow {
+		return 0, 0, fiber.NewError(fiber.StatusBadRequest, "Narrow the search to page past "+strconv.Itoa(maxWindow)+" results")
+	}
+	return limit, offset, nil
+}
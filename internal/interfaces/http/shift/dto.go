@@ -0,0 +1,71 @@
+package shift
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/shift"
+)
+
+// OpenShiftRequest represents open shift request
+type OpenShiftRequest struct {
+	StoreID      uuid.UUID `json:"store_id" validate:"required"`
+	RegisterID   string    `json:"register_id" validate:"required,max=64"`
+	Currency     string    `json:"currency,omitempty" validate:"omitempty,len=3"` // USD when empty
+	OpeningFloat float64   `json:"opening_float" validate:"gte=0"`
+	Notes        string    `json:"notes,omitempty" validate:"max=500"`
+}
+
+// CloseShiftRequest represents close shift request
+type CloseShiftRequest struct {
+	CountedCash *float64 `json:"counted_cash" validate:"required,gte=0"` // Cash counted in the drawer
+	Notes       string   `json:"notes,omitempty" validate:"max=500"`
+}
+
+// CashMovementRequest represents record cash movement request
+type CashMovementRequest struct {
+	Type   shift.MovementType `json:"type" validate:"required,oneof=pay_in pay_out drop"`
+	Amount float64            `json:"amount" validate:"gt=0"`
+	Reason string             `json:"reason,omitempty" validate:"max=200"`
+}
+
+// CloseShiftResponse is a closed shift with its Z report
+type CloseShiftResponse struct {
+	Shift  *shift.Shift  `json:"shift"`
+	Report *shift.Report `json:"report"`
+}
+
+// ClockInRequest represents clock in request
+type ClockInRequest struct {
+	StoreID uuid.UUID  `json:"store_id" validate:"required"`
+	ShiftID *uuid.UUID `json:"shift_id,omitempty"` // The register shift worked, if any
+	Notes   string     `json:"notes,omitempty" validate:"max=500"`
+}
+
+// ClockOutRequest represents clock out request
+type ClockOutRequest struct {
+	Notes string `json:"notes,omitempty" validate:"max=500"`
+}
+
+// StartBreakRequest represents start break request
+type StartBreakRequest struct {
+	Paid bool `json:"paid"` // Paid breaks count as time worked
+}
+
+// AdjustTimeEntryRequest represents a manager's adjustment of a time entry.
+// The times and breaks given replace the entry's.
+type AdjustTimeEntryRequest struct {
+	ClockIn  *time.Time     `json:"clock_in" validate:"required"`
+	ClockOut *time.Time     `json:"clock_out,omitempty"` // Clocks an open entry out
+	Breaks   []BreakRequest `json:"breaks" validate:"dive"`
+	Notes    *string        `json:"notes,omitempty" validate:"omitempty,max=500"`
+	Reason   string         `json:"reason" validate:"required,max=500"`
+}
+
+// BreakRequest represents a break of an adjusted time entry
+type BreakRequest struct {
+	StartedAt *time.Time `json:"started_at" validate:"required"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Paid      bool       `json:"paid"`
+}
@@ -0,0 +1,64 @@
+package shift
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/shift"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+)
+
+// saleStatuses maps the order events that reach shifts to what they make of
+// the sale
+var saleStatuses = map[string]shift.SaleStatus{
+	order.EventCreated:   shift.SaleCompleted,
+	order.EventCancelled: shift.SaleVoided,
+	order.EventRefunded:  shift.SaleRefunded,
+}
+
+// HandleOrderEvent records orders rung up on a register shift, and their
+// voids and refunds, from the message broker. Orders without a shift are
+// ignored. Recording is idempotent, so redeliveries are harmless. Malformed
+// messages are dropped.
+func (h *Handler) HandleOrderEvent(ctx context.Context, msg amqp.Delivery) error {
+	log := logger.FromContext(ctx)
+
+	var event messagequeue.Event
+	if err := json.Unmarshal(msg.Body, &event); err != nil {
+		log.Errorf("Dropping malformed order event: %v", err)
+		return nil
+	}
+	status, ok := saleStatuses[event.Type]
+	if !ok {
+		return nil
+	}
+	var data order.EventData
+	raw, _ := json.Marshal(event.Data)
+	if err := json.Unmarshal(raw, &data); err != nil || data.OrderID == uuid.Nil {
+		log.Errorf("Dropping %s event %s without an order", event.Type, event.ID)
+		return nil
+	}
+	if data.ShiftID == nil {
+		return nil
+	}
+
+	sale := &shift.Sale{
+		OrderID:  data.OrderID,
+		ShiftID:  *data.ShiftID,
+		Tender:   shift.Tender(data.Tender),
+		Amount:   data.TotalAmount,
+		Tax:      data.TaxAmount,
+		Currency: data.Currency,
+		Status:   status,
+	}
+	if err := h.shiftRepo.RecordSale(ctx, sale); err != nil {
+		return fmt.Errorf("failed to record sale of order %s on shift %s: %w", data.OrderID, data.ShiftID, err)
+	}
+	return nil
+}
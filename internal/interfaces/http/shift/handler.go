@@ -0,0 +1,468 @@
+package shift
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/shift"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	printout "github.com/onichange/pos-system/pkg/receipt"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// defaultCurrency is the currency of shifts opened without one
+const defaultCurrency = "USD"
+
+// maxDayShifts caps the shifts of a day reported by GetZReports
+const maxDayShifts = 500
+
+// Handler handles register shift HTTP requests
+type Handler struct {
+	shiftRepo shift.Repository
+}
+
+// NewHandler creates a new shift handler
+func NewHandler(shiftRepo shift.Repository) *Handler {
+	return &Handler{
+		shiftRepo: shiftRepo,
+	}
+}
+
+// OpenShift handles POST /shifts, opening a shift on a register for the
+// caller
+func (h *Handler) OpenShift(c *fiber.Ctx) error {
+	staffID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	var req OpenShiftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	currency := req.Currency
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	s := &shift.Shift{
+		ID:           uuid.New(),
+		StoreID:      req.StoreID,
+		RegisterID:   req.RegisterID,
+		StaffID:      staffID,
+		Currency:     currency,
+		OpeningFloat: req.OpeningFloat,
+		Notes:        req.Notes,
+	}
+	if err := h.shiftRepo.Open(c.UserContext(), s); err != nil {
+		return shiftError(c, err, "Failed to open shift")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(s)
+}
+
+// GetShifts handles GET /shifts?store_id=&register_id=&status=. Staff see
+// their own shifts; admins see everyone's.
+func (h *Handler) GetShifts(c *fiber.Ctx) error {
+	staffID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	filter := shift.Filter{
+		RegisterID: c.Query("register_id"),
+		Status:     shift.Status(c.Query("status")),
+	}
+	if storeIDStr := c.Query("store_id"); storeIDStr != "" {
+		storeID, err := uuid.Parse(storeIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid store ID",
+			})
+		}
+		filter.StoreID = &storeID
+	}
+	if !isAdmin(c) {
+		filter.StaffID = &staffID
+	}
+
+	// Parse pagination
+	limit := 20
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	shifts, err := h.shiftRepo.List(c.UserContext(), filter, limit, offset)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch shifts: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch shifts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   shifts,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetShift handles GET /shifts/:id
+func (h *Handler) GetShift(c *fiber.Ctx) error {
+	s, err := h.accessibleShift(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(s)
+}
+
+// GetShiftInternal handles GET /internal/v1/shifts/:id, for the order
+// service to check a shift is open before ringing an order up on it
+func (h *Handler) GetShiftInternal(c *fiber.Ctx) error {
+	shiftID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid shift ID",
+		})
+	}
+
+	s, err := h.shiftRepo.GetByID(c.UserContext(), shiftID)
+	if err != nil {
+		return shiftError(c, err, "Failed to fetch shift")
+	}
+	return c.JSON(s)
+}
+
+// CloseShift handles POST /shifts/:id/close, recording the cash counted in
+// the drawer and taking the shift's Z report
+func (h *Handler) CloseShift(c *fiber.Ctx) error {
+	s, err := h.accessibleShift(c)
+	if err != nil {
+		return err
+	}
+	staffID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	var req CloseShiftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	closed, err := h.shiftRepo.Close(c.UserContext(), s.ID, staffID, *req.CountedCash, req.Notes, time.Now())
+	if err != nil {
+		return shiftError(c, err, "Failed to close shift")
+	}
+
+	report, err := h.zReport(c.UserContext(), closed)
+	if err != nil {
+		// The shift is closed; its Z report is taken when next read
+		logger.FromContext(c.UserContext()).Errorf("Failed to take Z report of shift %s: %v", closed.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Shift closed, but its Z report failed",
+		})
+	}
+	metrics.ShiftsClosed.WithLabelValues(strings.ToLower(overShortLabel(*report.Cash.OverShort))).Inc()
+
+	return c.JSON(CloseShiftResponse{Shift: closed, Report: report})
+}
+
+// AddCashMovement handles POST /shifts/:id/cash-movements
+func (h *Handler) AddCashMovement(c *fiber.Ctx) error {
+	s, err := h.accessibleShift(c)
+	if err != nil {
+		return err
+	}
+	staffID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	var req CashMovementRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	m := &shift.CashMovement{
+		ID:      uuid.New(),
+		ShiftID: s.ID,
+		Type:    req.Type,
+		Amount:  req.Amount,
+		Reason:  req.Reason,
+		StaffID: staffID,
+	}
+	if err := h.shiftRepo.AddMovement(c.UserContext(), m); err != nil {
+		return shiftError(c, err, "Failed to record cash movement")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(m)
+}
+
+// GetCashMovements handles GET /shifts/:id/cash-movements
+func (h *Handler) GetCashMovements(c *fiber.Ctx) error {
+	s, err := h.accessibleShift(c)
+	if err != nil {
+		return err
+	}
+
+	movements, err := h.shiftRepo.GetMovements(c.UserContext(), s.ID)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch cash movements: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch cash movements",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": movements,
+	})
+}
+
+// GetReport handles GET /shifts/:id/report?format=json|pdf: the X report of
+// an open shift, or the Z report of a closed one
+func (h *Handler) GetReport(c *fiber.Ctx) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "pdf" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "format must be json or pdf",
+		})
+	}
+
+	s, err := h.accessibleShift(c)
+	if err != nil {
+		return err
+	}
+
+	var report *shift.Report
+	if s.IsOpen() {
+		report, err = h.build(c.UserContext(), shift.ReportX, s)
+	} else {
+		report, err = h.zReport(c.UserContext(), s)
+	}
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to build report of shift %s: %v", s.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to build shift report",
+		})
+	}
+
+	if format == "pdf" {
+		name := string(report.Kind) + "-report-" + s.RegisterID + "-" + report.OpenedAt.Format("20060102-1504")
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		c.Set(fiber.HeaderContentDisposition, `inline; filename="`+name+`.pdf"`)
+		return c.Send(printout.PDF(buildReport(report)))
+	}
+	return c.JSON(report)
+}
+
+// GetZReports handles GET /shifts/z-reports?date=&store_id=&staff_id=: the
+// Z reports of the shifts closed on a day, the current one by default, with
+// the cash expected and counted in total and per cashier. Days run in UTC.
+// Staff see their own shifts; admins see everyone's.
+func (h *Handler) GetZReports(c *fiber.Ctx) error {
+	staffID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	day := time.Now().UTC()
+	if value := c.Query("date"); value != "" {
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "date must be a YYYY-MM-DD date",
+			})
+		}
+		day = t
+	}
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	filter := shift.Filter{
+		Status:     shift.StatusClosed,
+		ClosedFrom: &from,
+		ClosedTo:   &to,
+	}
+	if storeIDStr := c.Query("store_id"); storeIDStr != "" {
+		storeID, err := uuid.Parse(storeIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid store ID",
+			})
+		}
+		filter.StoreID = &storeID
+	}
+	if staffIDStr := c.Query("staff_id"); staffIDStr != "" {
+		id, err := uuid.Parse(staffIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid staff ID",
+			})
+		}
+		filter.StaffID = &id
+	}
+	if !isAdmin(c) {
+		filter.StaffID = &staffID
+	}
+
+	ctx := c.UserContext()
+	shifts, err := h.shiftRepo.List(ctx, filter, maxDayShifts, 0)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch closed shifts: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch Z reports",
+		})
+	}
+
+	reports := make([]*shift.Report, 0, len(shifts))
+	for _, s := range shifts {
+		// Shifts whose report failed at close are reported now
+		report, err := h.zReport(ctx, s)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to take Z report of shift %s: %v", s.ID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch Z reports",
+			})
+		}
+		reports = append(reports, report)
+	}
+
+	return c.JSON(shift.BuildDayReport(from, filter.StoreID, reports))
+}
+
+// zReport returns a closed shift's Z report, taking and storing it on
+// first read
+func (h *Handler) zReport(ctx context.Context, s *shift.Shift) (*shift.Report, error) {
+	report, err := h.shiftRepo.GetReport(ctx, s.ID)
+	if err != nil || report != nil {
+		return report, err
+	}
+
+	report, err = h.build(ctx, shift.ReportZ, s)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.shiftRepo.SaveReport(ctx, s.ID, report); err != nil {
+		return nil, err
+	}
+
+	// Another request may have stored its report first; the stored one stands
+	return h.shiftRepo.GetReport(ctx, s.ID)
+}
+
+// build totals a shift's sales and cash movements as they stand
+func (h *Handler) build(ctx context.Context, kind shift.ReportKind, s *shift.Shift) (*shift.Report, error) {
+	sales, err := h.shiftRepo.GetSales(ctx, s.ID)
+	if err != nil {
+		return nil, err
+	}
+	movements, err := h.shiftRepo.GetMovements(ctx, s.ID)
+	if err != nil {
+		return nil, err
+	}
+	return shift.BuildReport(kind, s, sales, movements, time.Now().UTC()), nil
+}
+
+// accessibleShift returns the shift in the path if the caller works it or
+// is an admin
+func (h *Handler) accessibleShift(c *fiber.Ctx) (*shift.Shift, error) {
+	staffID, err := currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	shiftID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid shift ID")
+	}
+
+	s, err := h.shiftRepo.GetByID(c.UserContext(), shiftID)
+	if err != nil {
+		return nil, shiftError(c, err, "Failed to fetch shift")
+	}
+	if s.StaffID != staffID && !isAdmin(c) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+	return s, nil
+}
+
+// shiftError maps a failure to the error answered, with 404 for an unknown
+// shift and 409 for a closed one or a register already open
+func shiftError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, shift.ErrShiftNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Shift not found")
+	case errors.Is(err, shift.ErrShiftClosed), errors.Is(err, shift.ErrShiftOpen):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+	logger.FromContext(c.UserContext()).Errorf("%s: %v", message, err)
+	return fiber.NewError(fiber.StatusInternalServerError, message)
+}
+
+// currentUser returns the authenticated user's ID
+func currentUser(c *fiber.Ctx) (uuid.UUID, error) {
+	userIDStr, _ := c.Locals("user_id").(string)
+	if userIDStr == "" {
+		return uuid.Nil, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+	return userID, nil
+}
+
+// isAdmin reports whether the authenticated user is an admin
+func isAdmin(c *fiber.Ctx) bool {
+	roles, _ := c.Locals("roles").([]string)
+	for _, role := range roles {
+		if role == "admin" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package shift
+
+import (
+	"fmt"
+
+	"github.com/onichange/pos-system/internal/domain/shift"
+	printout "github.com/onichange/pos-system/pkg/receipt"
+)
+
+// tenderNames are the printed names of tenders
+var tenderNames = map[shift.Tender]string{
+	shift.TenderCash:          "Cash",
+	shift.TenderCard:          "Card",
+	shift.TenderDigitalWallet: "Digital wallet",
+	shift.TenderBankTransfer:  "Bank transfer",
+}
+
+// movementNames are the printed names of cash movements
+var movementNames = map[shift.MovementType]string{
+	shift.MovementPayIn:  "Pay ins",
+	shift.MovementPayOut: "Pay outs",
+	shift.MovementDrop:   "Drops",
+}
+
+// buildReport lays a report out as a receipt, to print on the register or
+// render as PDF. Tenders are its lines and the sales, tax and cash its totals.
+func buildReport(r *shift.Report) *printout.Receipt {
+	out := &printout.Receipt{
+		Header:   []string{string(r.Kind) + " REPORT"},
+		Currency: r.Currency,
+	}
+
+	out.Meta = []string{
+		"Register " + r.RegisterID,
+		"Store " + r.StoreID.String(),
+		"Staff " + r.StaffID.String(),
+		"Opened " + r.OpenedAt.Format("2006-01-02 15:04"),
+	}
+	if r.ClosedAt != nil {
+		out.Meta = append(out.Meta, "Closed "+r.ClosedAt.Format("2006-01-02 15:04"))
+	}
+	out.Meta = append(out.Meta, "Printed "+r.GeneratedAt.Format("2006-01-02 15:04"))
+
+	for _, t := range r.Tenders {
+		name := tenderNames[t.Tender]
+		if name == "" {
+			name = string(t.Tender)
+		}
+		out.Items = append(out.Items, printout.Item{
+			Name:     fmt.Sprintf("%s (%d)", name, t.Orders),
+			Quantity: 1,
+			Amount:   t.Amount,
+		})
+	}
+
+	out.Totals = []printout.Total{
+		{Label: fmt.Sprintf("Gross sales (%d)", r.Sales.Orders), Amount: r.Sales.Gross},
+		{Label: fmt.Sprintf("Voids (%d)", r.Sales.Voids), Amount: -r.Sales.VoidAmount},
+		{Label: fmt.Sprintf("Refunds (%d)", r.Sales.Refunds), Amount: -r.Sales.RefundAmount},
+		{Label: "NET SALES", Amount: r.Sales.Net, Grand: true},
+		{Label: "Average sale", Amount: r.Sales.AverageTicket},
+		{Label: "Tax", Amount: r.Tax.Amount},
+		{Label: "Net of tax", Amount: r.Tax.Taxable},
+		{Label: "Opening float", Amount: r.Cash.OpeningFloat},
+		{Label: "Cash sales", Amount: r.Cash.Sales},
+	}
+	for _, m := range r.Movements {
+		amount := m.Amount
+		if m.Type != shift.MovementPayIn {
+			amount = -amount
+		}
+		out.Totals = append(out.Totals, printout.Total{
+			Label:  fmt.Sprintf("%s (%d)", movementNames[m.Type], m.Count),
+			Amount: amount,
+		})
+	}
+	out.Totals = append(out.Totals, printout.Total{Label: "Expected cash", Amount: r.Cash.Expected})
+	if r.Cash.Counted != nil {
+		out.Totals = append(out.Totals,
+			printout.Total{Label: "Counted cash", Amount: *r.Cash.Counted},
+			printout.Total{Label: overShortLabel(*r.Cash.OverShort), Amount: *r.Cash.OverShort, Grand: true},
+		)
+	}
+
+	return out
+}
+
+// overShortLabel names a drawer's variance
+func overShortLabel(amount float64) string {
+	switch {
+	case amount > 0:
+		return "OVER"
+	case amount < 0:
+		return "SHORT"
+	}
+	return "BALANCED"
+}
@@ -0,0 +1,452 @@
+package shift
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/shift"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// EventPublisher publishes time clock events to the message broker
+type EventPublisher interface {
+	PublishEventContext(ctx context.Context, eventType, routingKey string, data map[string]interface{}) error
+}
+
+// TimeClockHandler handles staff time clock HTTP requests. Staff clock
+// themselves in and out; admins adjust entries, with every adjustment kept
+// for audit.
+type TimeClockHandler struct {
+	timeClockRepo shift.TimeClockRepository
+	shiftRepo     shift.Repository
+	events        EventPublisher // Nil when no broker is reachable
+}
+
+// NewTimeClockHandler creates a new time clock handler
+func NewTimeClockHandler(timeClockRepo shift.TimeClockRepository, shiftRepo shift.Repository, events EventPublisher) *TimeClockHandler {
+	return &TimeClockHandler{
+		timeClockRepo: timeClockRepo,
+		shiftRepo:     shiftRepo,
+		events:        events,
+	}
+}
+
+// ClockIn handles POST /timeclock/clock-in, clocking the caller in at a
+// store and, optionally, on one of its open register shifts
+func (h *TimeClockHandler) ClockIn(c *fiber.Ctx) error {
+	staffID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	var req ClockInRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	if req.ShiftID != nil {
+		s, err := h.shiftRepo.GetByID(c.UserContext(), *req.ShiftID)
+		if err != nil {
+			return shiftError(c, err, "Failed to fetch shift")
+		}
+		if !s.IsOpen() {
+			return fiber.NewError(fiber.StatusConflict, shift.ErrShiftClosed.Error())
+		}
+		if s.StoreID != req.StoreID {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Shift is on another store",
+			})
+		}
+	}
+
+	e := &shift.TimeEntry{
+		ID:      uuid.New(),
+		StoreID: req.StoreID,
+		StaffID: staffID,
+		ShiftID: req.ShiftID,
+		Notes:   req.Notes,
+	}
+	if err := h.timeClockRepo.ClockIn(c.UserContext(), e); err != nil {
+		return timeClockError(c, err, "Failed to clock in")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(e)
+}
+
+// ClockOut handles POST /timeclock/clock-out, ending a running break with it
+func (h *TimeClockHandler) ClockOut(c *fiber.Ctx) error {
+	var req ClockOutRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	e, err := h.changeOpenEntry(c, func(e *shift.TimeEntry, now time.Time) error {
+		if req.Notes != "" {
+			e.Notes = req.Notes
+		}
+		return e.Close(now)
+	})
+	if err != nil {
+		return err
+	}
+
+	h.publish(c.UserContext(), shift.EventTimeEntryClosed, e)
+	return c.JSON(e)
+}
+
+// StartBreak handles POST /timeclock/breaks/start
+func (h *TimeClockHandler) StartBreak(c *fiber.Ctx) error {
+	var req StartBreakRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	e, err := h.changeOpenEntry(c, func(e *shift.TimeEntry, now time.Time) error {
+		return e.StartBreak(req.Paid, now)
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(e)
+}
+
+// EndBreak handles POST /timeclock/breaks/end
+func (h *TimeClockHandler) EndBreak(c *fiber.Ctx) error {
+	e, err := h.changeOpenEntry(c, func(e *shift.TimeEntry, now time.Time) error {
+		return e.EndBreak(now)
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(e)
+}
+
+// GetCurrent handles GET /timeclock/current: the caller's open time entry,
+// with the time worked so far
+func (h *TimeClockHandler) GetCurrent(c *fiber.Ctx) error {
+	staffID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	e, err := h.timeClockRepo.GetOpenEntry(c.UserContext(), staffID)
+	if err != nil {
+		return timeClockError(c, err, "Failed to fetch time entry")
+	}
+
+	worked, breaks := e.Durations(time.Now().UTC())
+	return c.JSON(fiber.Map{
+		"entry":                e,
+		"worked_seconds":       int64(worked / time.Second),
+		"unpaid_break_seconds": int64(breaks / time.Second),
+	})
+}
+
+// GetEntries handles GET /timeclock/entries?store_id=&staff_id=&status=&from=&to=.
+// Staff see their own entries; admins see everyone's.
+func (h *TimeClockHandler) GetEntries(c *fiber.Ctx) error {
+	staffID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	filter := shift.EntryFilter{
+		Status: shift.EntryStatus(c.Query("status")),
+	}
+	if storeIDStr := c.Query("store_id"); storeIDStr != "" {
+		storeID, err := uuid.Parse(storeIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid store ID",
+			})
+		}
+		filter.StoreID = &storeID
+	}
+	if staffIDStr := c.Query("staff_id"); staffIDStr != "" && isAdmin(c) {
+		id, err := uuid.Parse(staffIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid staff ID",
+			})
+		}
+		filter.StaffID = &id
+	}
+	if !isAdmin(c) {
+		filter.StaffID = &staffID
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := parseTime(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "from must be an RFC 3339 time or a YYYY-MM-DD date",
+			})
+		}
+		filter.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := parseTime(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "to must be an RFC 3339 time or a YYYY-MM-DD date",
+			})
+		}
+		filter.To = &to
+	}
+
+	// Parse pagination
+	limit := 20
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	entries, err := h.timeClockRepo.ListEntries(c.UserContext(), filter, limit, offset)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch time entries: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch time entries",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   entries,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetEntry handles GET /timeclock/entries/:id
+func (h *TimeClockHandler) GetEntry(c *fiber.Ctx) error {
+	e, err := h.accessibleEntry(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(e)
+}
+
+// AdjustEntry handles PUT /timeclock/entries/:id, an admin correcting an
+// entry's times and breaks, such as clocking out a forgotten entry. The
+// reason is kept with the entry as it was before and after.
+func (h *TimeClockHandler) AdjustEntry(c *fiber.Ctx) error {
+	adminID, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+	if !isAdmin(c) {
+		return fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	entryID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid time entry ID",
+		})
+	}
+
+	var req AdjustTimeEntryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	before, err := h.timeClockRepo.GetEntry(c.UserContext(), entryID)
+	if err != nil {
+		return timeClockError(c, err, "Failed to fetch time entry")
+	}
+	if !before.IsOpen() && req.ClockOut == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "clock_out is required for a closed time entry",
+		})
+	}
+
+	now := time.Now().UTC()
+	after := *before
+	after.ClockIn = req.ClockIn.UTC()
+	after.ClockOut = nil
+	if req.ClockOut != nil {
+		clockOut := req.ClockOut.UTC()
+		after.ClockOut = &clockOut
+		after.Status = shift.EntryClosed
+	}
+	after.Breaks = make([]shift.Break, 0, len(req.Breaks))
+	for _, b := range req.Breaks {
+		adjusted := shift.Break{StartedAt: b.StartedAt.UTC(), Paid: b.Paid}
+		if b.EndedAt != nil {
+			endedAt := b.EndedAt.UTC()
+			adjusted.EndedAt = &endedAt
+		}
+		after.Breaks = append(after.Breaks, adjusted)
+	}
+	if req.Notes != nil {
+		after.Notes = *req.Notes
+	}
+	if after.ClockIn.After(now) || (after.ClockOut != nil && after.ClockOut.After(now)) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Times must not be in the future",
+		})
+	}
+	if err := after.Validate(); err != nil {
+		return timeClockError(c, err, "Failed to adjust time entry")
+	}
+
+	adj := &shift.TimeAdjustment{
+		ID:         uuid.New(),
+		AdjustedBy: adminID,
+		Reason:     req.Reason,
+		Before:     before,
+	}
+	if err := h.timeClockRepo.Adjust(c.UserContext(), &after, adj); err != nil {
+		return timeClockError(c, err, "Failed to adjust time entry")
+	}
+
+	h.publish(c.UserContext(), shift.EventTimeEntryAdjusted, &after)
+	return c.JSON(adj)
+}
+
+// GetAdjustments handles GET /timeclock/entries/:id/adjustments
+func (h *TimeClockHandler) GetAdjustments(c *fiber.Ctx) error {
+	e, err := h.accessibleEntry(c)
+	if err != nil {
+		return err
+	}
+
+	adjustments, err := h.timeClockRepo.GetAdjustments(c.UserContext(), e.ID)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch adjustments of time entry %s: %v", e.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch time entry adjustments",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": adjustments,
+	})
+}
+
+// changeOpenEntry applies change to the caller's open time entry and saves
+// it
+func (h *TimeClockHandler) changeOpenEntry(c *fiber.Ctx, change func(e *shift.TimeEntry, now time.Time) error) (*shift.TimeEntry, error) {
+	staffID, err := currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := h.timeClockRepo.GetOpenEntry(c.UserContext(), staffID)
+	if err != nil {
+		return nil, timeClockError(c, err, "Failed to fetch time entry")
+	}
+	if err := change(e, time.Now().UTC()); err != nil {
+		return nil, timeClockError(c, err, "Failed to update time entry")
+	}
+	if err := h.timeClockRepo.Update(c.UserContext(), e); err != nil {
+		return nil, timeClockError(c, err, "Failed to update time entry")
+	}
+	return e, nil
+}
+
+// accessibleEntry returns the time entry in the path if it is the caller's
+// or the caller is an admin
+func (h *TimeClockHandler) accessibleEntry(c *fiber.Ctx) (*shift.TimeEntry, error) {
+	staffID, err := currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	entryID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid time entry ID")
+	}
+
+	e, err := h.timeClockRepo.GetEntry(c.UserContext(), entryID)
+	if err != nil {
+		return nil, timeClockError(c, err, "Failed to fetch time entry")
+	}
+	if e.StaffID != staffID && !isAdmin(c) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+	return e, nil
+}
+
+// publish sends a time clock event with the entry as it stands. A failure
+// is logged, not returned: the entry is already saved.
+func (h *TimeClockHandler) publish(ctx context.Context, eventType string, e *shift.TimeEntry) {
+	if h.events == nil {
+		return
+	}
+	data := shift.NewTimeEntryEventData(e, time.Now().UTC())
+	if err := h.events.PublishEventContext(ctx, eventType, eventType, data.Map()); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to publish %s event for time entry %s: %v", eventType, e.ID, err)
+	}
+}
+
+// timeClockError maps a failure to the error answered, with 404 for an
+// unknown entry or a caller not clocked in, 409 for a clock or break state
+// that does not allow the change, and 400 for invalid times
+func timeClockError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, shift.ErrTimeEntryNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Time entry not found")
+	case errors.Is(err, shift.ErrNotClockedIn):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, shift.ErrClockedIn), errors.Is(err, shift.ErrOnBreak),
+		errors.Is(err, shift.ErrNotOnBreak), errors.Is(err, shift.ErrEntryChanged):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, shift.ErrInvalidTimes):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	logger.FromContext(c.UserContext()).Errorf("%s: %v", message, err)
+	return fiber.NewError(fiber.StatusInternalServerError, message)
+}
+
+// parseTime parses an RFC 3339 time or a YYYY-MM-DD date, in UTC
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,431 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/store"
+	"github.com/onichange/pos-system/pkg/audit/security"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/middleware"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+const (
+	// pairingAlphabet leaves out characters easily misread on a screen
+	pairingAlphabet   = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	pairingCodeLength = 8
+
+	// deviceKeyPrefix marks device API keys, and keyPrefixLength is how much
+	// of a key is stored in the clear to tell keys apart
+	deviceKeyPrefix = "dev_"
+	keyPrefixLength = 12
+)
+
+// DeviceHandler handles registering, pairing and deactivating the devices
+// of stores, and their heartbeats
+type DeviceHandler struct {
+	storeRepo  store.Repository
+	deviceRepo store.DeviceRepository
+	cfg        config.DevicesConfig
+}
+
+// NewDeviceHandler creates a new device handler
+func NewDeviceHandler(storeRepo store.Repository, deviceRepo store.DeviceRepository, cfg config.DevicesConfig) *DeviceHandler {
+	return &DeviceHandler{
+		storeRepo:  storeRepo,
+		deviceRepo: deviceRepo,
+		cfg:        cfg,
+	}
+}
+
+// ListDevices handles GET /stores/:id/devices?status=
+func (h *DeviceHandler) ListDevices(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+
+	status := store.DeviceStatus(c.Query("status"))
+	switch status {
+	case "", store.DevicePending, store.DeviceActive, store.DeviceDeactivated:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "status must be pending, active or deactivated",
+		})
+	}
+
+	devices, err := h.deviceRepo.ListByStore(c.UserContext(), storeID, status)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch devices of store %s: %v", storeID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch devices",
+		})
+	}
+
+	now := time.Now()
+	responses := make([]*DeviceResponse, len(devices))
+	for i, d := range devices {
+		responses[i] = ToDeviceResponse(d, now, h.cfg.OfflineAfter)
+	}
+
+	return c.JSON(fiber.Map{
+		"data": responses,
+	})
+}
+
+// GetDevice handles GET /stores/:id/devices/:deviceId
+func (h *DeviceHandler) GetDevice(c *fiber.Ctx) error {
+	storeID, deviceID, err := deviceParams(c)
+	if err != nil {
+		return err
+	}
+
+	d, err := h.deviceRepo.GetByID(c.UserContext(), storeID, deviceID)
+	if err != nil {
+		return h.deviceError(c, "fetch", err)
+	}
+
+	return c.JSON(ToDeviceResponse(d, time.Now(), h.cfg.OfflineAfter))
+}
+
+// RegisterDevice handles POST /stores/:id/devices. The response carries the
+// pairing code, shown once, which is entered on the device to pair it.
+func (h *DeviceHandler) RegisterDevice(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+
+	var req RegisterDeviceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	if _, err := h.storeRepo.GetByID(c.UserContext(), storeID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Store not found",
+		})
+	}
+
+	code, err := newPairingCode()
+	if err != nil {
+		return err
+	}
+	d := &store.Device{
+		ID:           uuid.New(),
+		StoreID:      storeID,
+		Name:         req.Name,
+		Type:         store.DeviceType(req.Type),
+		SerialNumber: req.SerialNumber,
+	}
+	if err := h.deviceRepo.Create(c.UserContext(), d, hashSecret(code), h.cfg.PairingCodeTTL); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to register device: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to register device",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(&PairingCodeResponse{
+		DeviceResponse: ToDeviceResponse(d, time.Now(), h.cfg.OfflineAfter),
+		PairingCode:    code,
+	})
+}
+
+// ResetPairing handles POST /stores/:id/devices/:deviceId/pairing-code. The
+// device's API key stops working and it is paired again with the new code,
+// e.g. after it was replaced, wiped or found again.
+func (h *DeviceHandler) ResetPairing(c *fiber.Ctx) error {
+	storeID, deviceID, err := deviceParams(c)
+	if err != nil {
+		return err
+	}
+
+	code, err := newPairingCode()
+	if err != nil {
+		return err
+	}
+	d, err := h.deviceRepo.ResetPairing(c.UserContext(), storeID, deviceID, hashSecret(code), h.cfg.PairingCodeTTL)
+	if err != nil {
+		return h.deviceError(c, "reset pairing of", err)
+	}
+
+	return c.JSON(&PairingCodeResponse{
+		DeviceResponse: ToDeviceResponse(d, time.Now(), h.cfg.OfflineAfter),
+		PairingCode:    code,
+	})
+}
+
+// DeactivateDevice handles POST /stores/:id/devices/:deviceId/deactivate,
+// locking out a stolen or retired device
+func (h *DeviceHandler) DeactivateDevice(c *fiber.Ctx) error {
+	storeID, deviceID, err := deviceParams(c)
+	if err != nil {
+		return err
+	}
+
+	var req DeactivateDeviceRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	d, err := h.deviceRepo.Deactivate(c.UserContext(), storeID, deviceID, req.Reason)
+	if err != nil {
+		return h.deviceError(c, "deactivate", err)
+	}
+
+	security.RecordRequest(c, security.Event{
+		Type:      security.EventDeviceDeactivated,
+		Outcome:   security.OutcomeSuccess,
+		SubjectID: d.ID.String(),
+		Details: map[string]string{
+			"store_id": d.StoreID.String(),
+			"reason":   d.DeactivationReason,
+		},
+	})
+
+	return c.JSON(ToDeviceResponse(d, time.Now(), h.cfg.OfflineAfter))
+}
+
+// PairDevice handles POST /devices/pair, called by a device with the code
+// it was registered with. The API key in the response is shown once; the
+// device sends it in X-Device-Key from then on.
+func (h *DeviceHandler) PairDevice(c *fiber.Ctx) error {
+	var req PairDeviceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	key, err := newDeviceKey()
+	if err != nil {
+		return err
+	}
+	code := normalizePairingCode(req.PairingCode)
+	d, err := h.deviceRepo.Pair(c.UserContext(), hashSecret(code), hashSecret(key), key[:keyPrefixLength], req.SerialNumber, req.AppVersion)
+	if errors.Is(err, store.ErrInvalidPairingCode) {
+		security.RecordRequest(c, security.Event{
+			Type:    security.EventDevicePaired,
+			Outcome: security.OutcomeFailure,
+		})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid or expired pairing code",
+		})
+	}
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to pair device: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to pair device",
+		})
+	}
+
+	security.RecordRequest(c, security.Event{
+		Type:      security.EventDevicePaired,
+		Outcome:   security.OutcomeSuccess,
+		SubjectID: d.ID.String(),
+		Details: map[string]string{
+			"store_id": d.StoreID.String(),
+		},
+	})
+
+	return c.JSON(&PairDeviceResponse{
+		Device:            ToDeviceResponse(d, time.Now(), h.cfg.OfflineAfter),
+		APIKey:            key,
+		HeartbeatInterval: int(h.cfg.HeartbeatInterval.Seconds()),
+	})
+}
+
+// Heartbeat handles POST /devices/heartbeat, sent by paired devices every
+// heartbeat interval with their API key. A deactivated device is answered
+// 403 with deactivated set, and should lock itself.
+func (h *DeviceHandler) Heartbeat(c *fiber.Ctx) error {
+	key := c.Get(middleware.DeviceKeyHeader)
+	if key == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Device key is required",
+		})
+	}
+
+	var req HeartbeatRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	d, err := h.deviceRepo.GetByKey(c.UserContext(), hashSecret(key))
+	if errors.Is(err, store.ErrDeviceNotFound) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unknown device key",
+		})
+	}
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to look up device key: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record heartbeat",
+		})
+	}
+	if d.Status == store.DeviceDeactivated {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":       "Device deactivated",
+			"deactivated": true,
+		})
+	}
+
+	if err := h.deviceRepo.Heartbeat(c.UserContext(), d.ID, req.AppVersion, c.IP()); err != nil {
+		if errors.Is(err, store.ErrDeviceNotFound) {
+			// Deactivated since it was looked up
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":       "Device deactivated",
+				"deactivated": true,
+			})
+		}
+		logger.FromContext(c.UserContext()).Errorf("Failed to record heartbeat of device %s: %v", d.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record heartbeat",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":                     d.Status,
+		"heartbeat_interval_seconds": int(h.cfg.HeartbeatInterval.Seconds()),
+	})
+}
+
+// VerifyDevice handles POST /internal/v1/devices/verify, with which the
+// gateway checks the API keys devices send. Answers with the device, active
+// or deactivated, or 404 for a key no device holds.
+func (h *DeviceHandler) VerifyDevice(c *fiber.Ctx) error {
+	var req VerifyDeviceRequest
+	if err := c.BodyParser(&req); err != nil || req.Key == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Device key is required",
+		})
+	}
+
+	d, err := h.deviceRepo.GetByKey(c.UserContext(), hashSecret(req.Key))
+	if errors.Is(err, store.ErrDeviceNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Unknown device key",
+		})
+	}
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to look up device key: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to verify device",
+		})
+	}
+
+	return c.JSON(d)
+}
+
+// deviceError answers for a device that could not be found or acted on
+func (h *DeviceHandler) deviceError(c *fiber.Ctx, action string, err error) error {
+	if errors.Is(err, store.ErrDeviceNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Device not found",
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("Failed to %s device: %v", action, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to " + action + " device",
+	})
+}
+
+// deviceParams parses the store and device IDs of a device route, failing
+// with a 400 error when either is invalid
+func deviceParams(c *fiber.Ctx) (storeID, deviceID uuid.UUID, err error) {
+	if storeID, err = uuid.Parse(c.Params("id")); err != nil {
+		return storeID, deviceID, fiber.NewError(fiber.StatusBadRequest, "Invalid store ID")
+	}
+	if deviceID, err = uuid.Parse(c.Params("deviceId")); err != nil {
+		return storeID, deviceID, fiber.NewError(fiber.StatusBadRequest, "Invalid device ID")
+	}
+	return storeID, deviceID, nil
+}
+
+// newPairingCode generates a one-time pairing code
+func newPairingCode() (string, error) {
+	b := make([]byte, pairingCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		// The alphabet's length divides 256, so every character is as likely
+		b[i] = pairingAlphabet[int(b[i])%len(pairingAlphabet)]
+	}
+	return string(b), nil
+}
+
+// normalizePairingCode accepts a code typed in lower case or grouped with
+// dashes or spaces
+func normalizePairingCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+}
+
+// newDeviceKey generates a device API key
+func newDeviceKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return deviceKeyPrefix + hex.EncodeToString(b), nil
+}
+
+// hashSecret hashes a pairing code or API key for storage. Keys are random
+// enough that an unsalted hash cannot be reversed; codes expire within
+// minutes and pair one device once.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
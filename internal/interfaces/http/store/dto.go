@@ -1,6 +1,8 @@
 package store
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 
 	"github.com/onichange/pos-system/internal/domain/store"
@@ -8,52 +10,55 @@ import (
 
 // CreateStoreRequest represents create store request
 type CreateStoreRequest struct {
-	Name       string  `json:"name" validate:"required"`
-	Code       string  `json:"code" validate:"required"`
-	Latitude   float64 `json:"latitude" validate:"required"`
-	Longitude  float64 `json:"longitude" validate:"required"`
-	Address    string  `json:"address" validate:"required"`
-	City       string  `json:"city" validate:"required"`
-	State      string  `json:"state" validate:"required"`
-	PostalCode string  `json:"postal_code" validate:"required"`
-	Country    string  `json:"country" validate:"required"`
-	Phone      string  `json:"phone,omitempty"`
-	Email      string  `json:"email,omitempty"`
+	Name       string     `json:"name" validate:"required"`
+	Code       string     `json:"code" validate:"required"`
+	Latitude   float64    `json:"latitude" validate:"required"`
+	Longitude  float64    `json:"longitude" validate:"required"`
+	Address    string     `json:"address" validate:"required"`
+	City       string     `json:"city" validate:"required"`
+	State      string     `json:"state" validate:"required"`
+	PostalCode string     `json:"postal_code" validate:"required"`
+	Country    string     `json:"country" validate:"required"`
+	Phone      string     `json:"phone,omitempty"`
+	Email      string     `json:"email,omitempty"`
+	ManagerID  *uuid.UUID `json:"manager_id,omitempty"`
 }
 
 // UpdateStoreRequest represents update store request
 type UpdateStoreRequest struct {
-	Name       string  `json:"name,omitempty"`
-	Code       string  `json:"code,omitempty"`
-	Latitude   float64 `json:"latitude,omitempty"`
-	Longitude  float64 `json:"longitude,omitempty"`
-	Address    string  `json:"address,omitempty"`
-	City       string  `json:"city,omitempty"`
-	State      string  `json:"state,omitempty"`
-	PostalCode string  `json:"postal_code,omitempty"`
-	Country    string  `json:"country,omitempty"`
-	Phone      string  `json:"phone,omitempty"`
-	Email      string  `json:"email,omitempty"`
-	Status     string  `json:"status,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Code       string     `json:"code,omitempty"`
+	Latitude   float64    `json:"latitude,omitempty"`
+	Longitude  float64    `json:"longitude,omitempty"`
+	Address    string     `json:"address,omitempty"`
+	City       string     `json:"city,omitempty"`
+	State      string     `json:"state,omitempty"`
+	PostalCode string     `json:"postal_code,omitempty"`
+	Country    string     `json:"country,omitempty"`
+	Phone      string     `json:"phone,omitempty"`
+	Email      string     `json:"email,omitempty"`
+	ManagerID  *uuid.UUID `json:"manager_id,omitempty"`
+	Status     string     `json:"status,omitempty"`
 }
 
 // StoreResponse represents store response
 type StoreResponse struct {
-	ID         uuid.UUID `json:"id"`
-	Name       string    `json:"name"`
-	Code       string    `json:"code"`
-	Latitude   float64   `json:"latitude"`
-	Longitude  float64   `json:"longitude"`
-	Address    string    `json:"address"`
-	City       string    `json:"city"`
-	State      string    `json:"state"`
-	PostalCode string    `json:"postal_code"`
-	Country    string    `json:"country"`
-	Phone      string    `json:"phone,omitempty"`
-	Email      string    `json:"email,omitempty"`
-	Status     string    `json:"status"`
-	CreatedAt  string    `json:"created_at"`
-	UpdatedAt  string    `json:"updated_at"`
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Code       string     `json:"code"`
+	Latitude   float64    `json:"latitude"`
+	Longitude  float64    `json:"longitude"`
+	Address    string     `json:"address"`
+	City       string     `json:"city"`
+	State      string     `json:"state"`
+	PostalCode string     `json:"postal_code"`
+	Country    string     `json:"country"`
+	Phone      string     `json:"phone,omitempty"`
+	Email      string     `json:"email,omitempty"`
+	ManagerID  *uuid.UUID `json:"manager_id,omitempty"`
+	Status     string     `json:"status"`
+	CreatedAt  string     `json:"created_at"`
+	UpdatedAt  string     `json:"updated_at"`
 }
 
 // ToResponse converts domain Store to StoreResponse
@@ -71,8 +76,65 @@ func ToResponse(s *store.Store) *StoreResponse {
 		Country:    s.Country,
 		Phone:      s.Phone,
 		Email:      s.Email,
+		ManagerID:  s.ManagerID,
 		Status:     string(s.Status),
 		CreatedAt:  s.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:  s.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
 }
+
+// RegisterDeviceRequest represents register device request
+type RegisterDeviceRequest struct {
+	Name         string `json:"name" validate:"required,max=100"`
+	Type         string `json:"type" validate:"required,oneof=terminal printer scanner"`
+	SerialNumber string `json:"serial_number,omitempty" validate:"max=100"`
+}
+
+// DeactivateDeviceRequest represents deactivate device request
+type DeactivateDeviceRequest struct {
+	Reason string `json:"reason,omitempty" validate:"max=500"` // e.g. stolen, retired
+}
+
+// PairDeviceRequest is sent by a device to pair with its code
+type PairDeviceRequest struct {
+	PairingCode  string `json:"pairing_code" validate:"required,max=20"`
+	SerialNumber string `json:"serial_number,omitempty" validate:"max=100"`
+	AppVersion   string `json:"app_version,omitempty" validate:"max=50"`
+}
+
+// HeartbeatRequest is sent by a paired device to check in
+type HeartbeatRequest struct {
+	AppVersion string `json:"app_version,omitempty" validate:"max=50"`
+}
+
+// VerifyDeviceRequest carries a device API key to verify
+type VerifyDeviceRequest struct {
+	Key string `json:"key"`
+}
+
+// DeviceResponse represents device response
+type DeviceResponse struct {
+	*store.Device
+	Online bool `json:"online"`
+}
+
+// PairingCodeResponse is a device with the pairing code it was just issued
+type PairingCodeResponse struct {
+	*DeviceResponse
+	PairingCode string `json:"pairing_code"`
+}
+
+// PairDeviceResponse is a newly paired device with its API key
+type PairDeviceResponse struct {
+	Device            *DeviceResponse `json:"device"`
+	APIKey            string          `json:"api_key"`
+	HeartbeatInterval int             `json:"heartbeat_interval_seconds"`
+}
+
+// ToDeviceResponse converts domain Device to DeviceResponse
+func ToDeviceResponse(d *store.Device, now time.Time, offlineAfter time.Duration) *DeviceResponse {
+	return &DeviceResponse{
+		Device: d,
+		Online: d.Online(now, offlineAfter),
+	}
+}
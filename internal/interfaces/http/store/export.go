@@ -0,0 +1,509 @@
+package store
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/store"
+	"github.com/onichange/pos-system/internal/domain/user"
+	"github.com/onichange/pos-system/pkg/audit/security"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/dataexport"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// exportListLimit caps how many of a store's exports are listed
+const exportListLimit = 50
+
+// exportFormatVersion is written to every export's manifest, and raised
+// when the layout of its files changes
+const exportFormatVersion = 1
+
+// ExportSource streams a store's records from the service owning them, as
+// exportclient does
+type ExportSource interface {
+	ExportStore(ctx context.Context, storeID uuid.UUID) (io.ReadCloser, error)
+}
+
+// CustomerDirectory looks up customer profiles, as userclient does
+type CustomerDirectory interface {
+	GetUser(ctx context.Context, id uuid.UUID) (*user.User, error)
+}
+
+// ExportSources are the services a store's data is gathered from
+type ExportSources struct {
+	Catalog   ExportSource
+	Inventory ExportSource
+	Orders    ExportSource
+	Customers CustomerDirectory
+}
+
+// ExportHandler handles requesting and downloading exports of a store's
+// data, and builds them in the background
+type ExportHandler struct {
+	storeRepo  store.Repository
+	exportRepo store.ExportRepository
+	sources    ExportSources
+	cfg        config.ExportsConfig
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(storeRepo store.Repository, exportRepo store.ExportRepository, sources ExportSources, cfg config.ExportsConfig) *ExportHandler {
+	return &ExportHandler{
+		storeRepo:  storeRepo,
+		exportRepo: exportRepo,
+		sources:    sources,
+		cfg:        cfg,
+	}
+}
+
+// CreateExport handles POST /stores/:id/exports. The export is built in the
+// background; its status tells when it can be downloaded.
+func (h *ExportHandler) CreateExport(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+
+	if _, err := h.storeRepo.GetByID(c.UserContext(), storeID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Store not found",
+		})
+	}
+
+	e := &store.Export{ID: uuid.New(), StoreID: storeID}
+	if err := h.exportRepo.Create(c.UserContext(), e); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to request export of store %s: %v", storeID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to request export",
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(e)
+}
+
+// ListExports handles GET /stores/:id/exports, newest first
+func (h *ExportHandler) ListExports(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+
+	exports, err := h.exportRepo.ListByStore(c.UserContext(), storeID, exportListLimit)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch exports of store %s: %v", storeID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch exports",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": exports,
+	})
+}
+
+// GetExport handles GET /stores/:id/exports/:exportId
+func (h *ExportHandler) GetExport(c *fiber.Ctx) error {
+	e, err := h.export(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(e)
+}
+
+// DownloadExport handles GET /stores/:id/exports/:exportId/download,
+// answering with the zipped export once it is completed. The ETag is the
+// archive's SHA-256 checksum.
+func (h *ExportHandler) DownloadExport(c *fiber.Ctx) error {
+	e, err := h.export(c)
+	if err != nil {
+		return err
+	}
+	if e.Status != store.ExportCompleted {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":  "Export is not completed",
+			"status": e.Status,
+		})
+	}
+
+	f, err := os.Open(h.path(e))
+	if errors.Is(err, os.ErrNotExist) {
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{
+			"error": "Export has expired",
+		})
+	}
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to open export %s: %v", e.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to download export",
+		})
+	}
+
+	security.RecordRequest(c, security.Event{
+		Type:      security.EventDataExport,
+		Outcome:   security.OutcomeSuccess,
+		SubjectID: e.ID.String(),
+		Details: map[string]string{
+			"dataset":  "store",
+			"store_id": e.StoreID.String(),
+		},
+	})
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="store-export-`+e.ID.String()+`.zip"`)
+	c.Set(fiber.HeaderETag, `"`+e.Checksum+`"`)
+	return c.SendStream(f, int(e.Size))
+}
+
+// RunExporter builds requested exports, one at a time, every interval until
+// ctx is cancelled, and deletes those past their expiry. An export whose
+// build runs past the timeout, as when its instance stopped, is built
+// again. An interval of zero disables it.
+func (h *ExportHandler) RunExporter(ctx context.Context, log *logger.Logger) {
+	defer apperrors.Recover(ctx, "store-exporter")
+
+	if h.cfg.Interval <= 0 {
+		return
+	}
+
+	pass := func() {
+		h.deleteExpired(ctx, log)
+
+		for ctx.Err() == nil {
+			e, err := h.exportRepo.Claim(ctx, h.cfg.Timeout)
+			if err != nil {
+				log.Errorf("Failed to claim store export: %v", err)
+				return
+			}
+			if e == nil {
+				return
+			}
+			h.runExport(ctx, e, log)
+		}
+	}
+
+	pass()
+
+	ticker := time.NewTicker(h.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pass()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runExport builds a claimed export in its tenant and records the outcome
+func (h *ExportHandler) runExport(ctx context.Context, e *store.Export, log *logger.Logger) {
+	ctx = tenant.NewContext(ctx, &tenant.Tenant{ID: e.TenantID, Source: tenant.SourceJob})
+
+	buildCtx, cancel := context.WithTimeout(ctx, h.cfg.Timeout)
+	err := h.build(buildCtx, e)
+	cancel()
+
+	if err == nil {
+		err = h.exportRepo.Complete(ctx, e, h.cfg.TTL)
+		if err == nil {
+			log.Infof("Built export %s of store %s", e.ID, e.StoreID)
+			return
+		}
+		log.Errorf("Failed to record export %s of store %s: %v", e.ID, e.StoreID, err)
+		return
+	}
+	if ctx.Err() != nil {
+		return // Shutting down; built again once the timeout passes
+	}
+
+	log.Errorf("Failed to build export %s of store %s: %v", e.ID, e.StoreID, err)
+	reason := "Failed to export the store"
+	var datasetErr *datasetError
+	if errors.As(err, &datasetErr) {
+		reason = "Failed to export " + datasetErr.dataset
+	}
+	if err := h.exportRepo.Fail(ctx, e, reason, h.cfg.TTL); err != nil {
+		log.Errorf("Failed to record export %s of store %s: %v", e.ID, e.StoreID, err)
+	}
+}
+
+// deleteExpired deletes the exports past their expiry, with their archives
+func (h *ExportHandler) deleteExpired(ctx context.Context, log *logger.Logger) {
+	expired, err := h.exportRepo.DeleteExpired(ctx)
+	if err != nil {
+		log.Errorf("Failed to delete expired store exports: %v", err)
+		return
+	}
+	for _, e := range expired {
+		if err := os.Remove(h.path(e)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warnf("Failed to delete archive of expired export %s: %v", e.ID, err)
+		}
+	}
+	if len(expired) > 0 {
+		log.Infof("Deleted %d expired store exports", len(expired))
+	}
+}
+
+// datasetError is a failure to export one dataset of a store
+type datasetError struct {
+	dataset string
+	err     error
+}
+
+func (e *datasetError) Error() string {
+	return e.dataset + ": " + e.err.Error()
+}
+
+func (e *datasetError) Unwrap() error {
+	return e.err
+}
+
+// exportManifest describes an export's files, and is written last
+type exportManifest struct {
+	FormatVersion int            `json:"format_version"`
+	ExportID      uuid.UUID      `json:"export_id"`
+	StoreID       uuid.UUID      `json:"store_id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Files         map[string]int `json:"files"` // Records per JSON Lines file
+}
+
+// exportedCustomer is a customer as written to an export
+type exportedCustomer struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name,omitempty"`
+	LastName  string    `json:"last_name,omitempty"`
+	Phone     string    `json:"phone,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// build writes a store's data to the export's archive, replacing it only
+// once complete, and fills in the export's records, size and checksum.
+// Each dataset is read by its service in one query, so is consistent in
+// itself; the datasets are read one after the other.
+func (h *ExportHandler) build(ctx context.Context, e *store.Export) error {
+	s, err := h.storeRepo.GetByID(ctx, e.StoreID)
+	if err != nil {
+		return &datasetError{dataset: "store", err: err}
+	}
+
+	path := h.path(e)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	partial := path + ".partial"
+	f, err := os.OpenFile(partial, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(partial) // Once renamed, there is nothing to remove
+	defer f.Close()
+
+	checksum := sha256.New()
+	zw := zip.NewWriter(io.MultiWriter(f, checksum))
+
+	records := map[string]int{}
+	if err := writeJSON(zw, "store.json", s); err != nil {
+		return err
+	}
+
+	customerIDs := map[uuid.UUID]bool{}
+	datasets := []struct {
+		name   string
+		source ExportSource
+		each   func(record []byte) error
+	}{
+		{name: "catalog", source: h.sources.Catalog},
+		{name: "inventory", source: h.sources.Inventory},
+		{name: "orders", source: h.sources.Orders, each: func(record []byte) error {
+			var o struct {
+				UserID uuid.UUID `json:"user_id"`
+			}
+			if err := json.Unmarshal(record, &o); err != nil {
+				return err
+			}
+			if o.UserID != uuid.Nil {
+				customerIDs[o.UserID] = true
+			}
+			return nil
+		}},
+	}
+	for _, d := range datasets {
+		n, err := copyDataset(ctx, zw, d.name+".jsonl", d.source, e.StoreID, d.each)
+		if err != nil {
+			return &datasetError{dataset: d.name, err: err}
+		}
+		records[d.name] = n
+	}
+
+	n, err := h.writeCustomers(ctx, zw, customerIDs)
+	if err != nil {
+		return &datasetError{dataset: "customers", err: err}
+	}
+	records["customers"] = n
+
+	manifest := exportManifest{
+		FormatVersion: exportFormatVersion,
+		ExportID:      e.ID,
+		StoreID:       e.StoreID,
+		CreatedAt:     time.Now().UTC(),
+		Files:         make(map[string]int, len(records)),
+	}
+	for name, n := range records {
+		manifest.Files[name+".jsonl"] = n
+	}
+	if err := writeJSON(zw, "manifest.json", manifest); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(partial, path); err != nil {
+		return err
+	}
+
+	e.Records = records
+	e.Size = info.Size()
+	e.Checksum = hex.EncodeToString(checksum.Sum(nil))
+	return nil
+}
+
+// writeCustomers writes the profiles of the customers who ordered at the
+// store, in ID order. Customers deleted since are left out.
+func (h *ExportHandler) writeCustomers(ctx context.Context, zw *zip.Writer, ids map[uuid.UUID]bool) (int, error) {
+	sorted := make([]uuid.UUID, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].String() < sorted[j].String() })
+
+	w, err := zw.Create("customers.jsonl")
+	if err != nil {
+		return 0, err
+	}
+	enc := json.NewEncoder(w)
+
+	count := 0
+	for _, id := range sorted {
+		u, err := h.sources.Customers.GetUser(ctx, id)
+		if errors.Is(err, user.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		err = enc.Encode(exportedCustomer{
+			ID:        u.ID,
+			Email:     u.Email,
+			FirstName: u.FirstName,
+			LastName:  u.LastName,
+			Phone:     u.Phone,
+			CreatedAt: u.CreatedAt,
+		})
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// path returns where an export's archive is written
+func (h *ExportHandler) path(e *store.Export) string {
+	return filepath.Join(h.cfg.Dir, e.TenantID, e.ID.String()+".zip")
+}
+
+// export fetches the export of an export route, failing with a 400 or 404
+// error when it cannot
+func (h *ExportHandler) export(c *fiber.Ctx) (*store.Export, error) {
+	storeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid store ID")
+	}
+	exportID, err := uuid.Parse(c.Params("exportId"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid export ID")
+	}
+
+	e, err := h.exportRepo.GetByID(c.UserContext(), storeID, exportID)
+	if errors.Is(err, store.ErrExportNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Export not found")
+	}
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch export %s: %v", exportID, err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch export")
+	}
+	return e, nil
+}
+
+// copyDataset copies the records a service streams for a store to a JSON
+// Lines file of the archive, calling each, when set, with every record.
+// A stream cut short fails the copy.
+func copyDataset(ctx context.Context, zw *zip.Writer, file string, source ExportSource, storeID uuid.UUID, each func(record []byte) error) (int, error) {
+	body, err := source.ExportStore(ctx, storeID)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	w, err := zw.Create(file)
+	if err != nil {
+		return 0, err
+	}
+	n, err := dataexport.Read(body, func(record []byte) error {
+		if each != nil {
+			if err := each(record); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(record); err != nil {
+			return err
+		}
+		_, err := w.Write([]byte{'\n'})
+		return err
+	})
+	if err != nil {
+		return n, fmt.Errorf("after %d records: %w", n, err)
+	}
+	return n, nil
+}
+
+// writeJSON writes v to a JSON file of the archive
+func writeJSON(zw *zip.Writer, file string, v any) error {
+	w, err := zw.Create(file)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/onichange/pos-system/internal/domain/store"
+	"github.com/onichange/pos-system/pkg/logger"
 	"github.com/onichange/pos-system/pkg/validator"
 )
 
@@ -37,8 +38,9 @@ func (h *Handler) GetStores(c *fiber.Ctx) error {
 		}
 	}
 
-	stores, err := h.storeRepo.GetAll(c.Context(), limit, offset)
+	stores, err := h.storeRepo.GetAll(c.UserContext(), limit, offset)
 	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to fetch stores: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch stores",
 		})
@@ -65,7 +67,7 @@ func (h *Handler) GetStoreByID(c *fiber.Ctx) error {
 		})
 	}
 
-	s, err := h.storeRepo.GetByID(c.Context(), storeID)
+	s, err := h.storeRepo.GetByID(c.UserContext(), storeID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Store not found",
@@ -104,10 +106,12 @@ func (h *Handler) CreateStore(c *fiber.Ctx) error {
 		Country:    req.Country,
 		Phone:      req.Phone,
 		Email:      req.Email,
+		ManagerID:  req.ManagerID,
 		Status:     store.StatusActive,
 	}
 
-	if err := h.storeRepo.Create(c.Context(), s); err != nil {
+	if err := h.storeRepo.Create(c.UserContext(), s); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to create store: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create store",
 		})
@@ -125,7 +129,7 @@ func (h *Handler) UpdateStore(c *fiber.Ctx) error {
 		})
 	}
 
-	s, err := h.storeRepo.GetByID(c.Context(), storeID)
+	s, err := h.storeRepo.GetByID(c.UserContext(), storeID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Store not found",
@@ -173,11 +177,15 @@ func (h *Handler) UpdateStore(c *fiber.Ctx) error {
 	if req.Email != "" {
 		s.Email = req.Email
 	}
+	if req.ManagerID != nil {
+		s.ManagerID = req.ManagerID
+	}
 	if req.Status != "" {
 		s.Status = store.StoreStatus(req.Status)
 	}
 
-	if err := h.storeRepo.Update(c.Context(), s); err != nil {
+	if err := h.storeRepo.Update(c.UserContext(), s); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to update store: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update store",
 		})
@@ -195,7 +203,8 @@ func (h *Handler) DeleteStore(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.storeRepo.Delete(c.Context(), storeID); err != nil {
+	if err := h.storeRepo.Delete(c.UserContext(), storeID); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to delete store: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete store",
 		})
@@ -231,8 +240,9 @@ func (h *Handler) SearchStores(c *fiber.Ctx) error {
 		})
 	}
 
-	stores, err := h.storeRepo.SearchByLocation(c.Context(), lat, lng, radius)
+	stores, err := h.storeRepo.SearchByLocation(c.UserContext(), lat, lng, radius)
 	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to search stores: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to search stores",
 		})
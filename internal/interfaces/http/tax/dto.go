@@ -0,0 +1,74 @@
+package tax
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/tax"
+)
+
+// CreateJurisdictionRequest represents create tax jurisdiction request. A
+// city jurisdiction needs its state.
+type CreateJurisdictionRequest struct {
+	Country       string             `json:"country" validate:"required,len=2"`
+	State         string             `json:"state,omitempty" validate:"max=8,required_with=City"`
+	City          string             `json:"city,omitempty" validate:"max=100"`
+	Name          string             `json:"name" validate:"required,max=255"`
+	Rate          float64            `json:"rate" validate:"gte=0,lt=1"`
+	CategoryRates map[string]float64 `json:"category_rates,omitempty" validate:"omitempty,dive,keys,max=32,endkeys,gte=0,lt=1"`
+	Active        *bool              `json:"active,omitempty"` // Defaults to true
+}
+
+// UpdateJurisdictionRequest represents update tax jurisdiction request. The
+// category rates given replace the jurisdiction's; its location is fixed.
+type UpdateJurisdictionRequest struct {
+	Name          string             `json:"name,omitempty" validate:"omitempty,max=255"`
+	Rate          *float64           `json:"rate,omitempty" validate:"omitempty,gte=0,lt=1"`
+	CategoryRates map[string]float64 `json:"category_rates,omitempty" validate:"omitempty,dive,keys,max=32,endkeys,gte=0,lt=1"`
+	Active        *bool              `json:"active,omitempty"`
+}
+
+// SaveCategoryRequest represents create or replace tax category request
+type SaveCategoryRequest struct {
+	Code        string `json:"code" validate:"required,max=32"`
+	Name        string `json:"name" validate:"required,max=255"`
+	Description string `json:"description,omitempty"`
+	Exempt      bool   `json:"exempt"`
+}
+
+// SaveAssignmentRequest represents assign tax category request
+type SaveAssignmentRequest struct {
+	Kind      string    `json:"kind" validate:"required,oneof=product catalog_category"`
+	SubjectID uuid.UUID `json:"subject_id" validate:"required"` // Catalog variant or catalog category ID
+	Category  string    `json:"category" validate:"required,max=32"`
+}
+
+// SaveStoreRateRequest represents set store tax rate request. Rates and
+// exemptions given replace the store's; those left out fall back to the
+// jurisdiction's.
+type SaveStoreRateRequest struct {
+	Rate             *float64           `json:"rate,omitempty" validate:"omitempty,gte=0,lt=1"`
+	CategoryRates    map[string]float64 `json:"category_rates,omitempty" validate:"omitempty,dive,keys,max=32,endkeys,gte=0,lt=1"`
+	ExemptCategories []string           `json:"exempt_categories,omitempty" validate:"omitempty,dive,max=32"`
+	Inclusive        bool               `json:"inclusive"` // The store's prices include the tax
+}
+
+// CreateHolidayRequest represents create tax holiday request
+type CreateHolidayRequest struct {
+	JurisdictionID uuid.UUID `json:"jurisdiction_id" validate:"required"`
+	Name           string    `json:"name" validate:"required,max=255"`
+	Category       string    `json:"category,omitempty" validate:"max=32"`
+	StartsOn       string    `json:"starts_on" validate:"required,datetime=2006-01-02"`
+	EndsOn         string    `json:"ends_on" validate:"required,datetime=2006-01-02"`
+	Rate           float64   `json:"rate" validate:"gte=0,lt=1"`
+	MaxUnitPrice   float64   `json:"max_unit_price,omitempty" validate:"gte=0"`
+}
+
+// QuoteRequest asks for the tax due on a sale. Date, for previewing tax
+// holidays, defaults to today.
+type QuoteRequest struct {
+	StoreID  uuid.UUID     `json:"store_id" validate:"required"`
+	Address  *tax.Location `json:"address,omitempty"`
+	Currency string        `json:"currency" validate:"required,len=3"`
+	Date     string        `json:"date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Lines    []tax.Line    `json:"lines" validate:"required,min=1,max=500"`
+}
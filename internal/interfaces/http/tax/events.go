@@ -0,0 +1,75 @@
+package tax
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/tax"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+)
+
+// HandleOrderEvent keeps the filing ledger from order events on the message
+// broker: created and updated orders record their tax by jurisdiction, and
+// cancelled and refunded ones reverse it. Recording is idempotent, so
+// redeliveries are harmless. Malformed messages are dropped.
+func (h *Handler) HandleOrderEvent(ctx context.Context, msg amqp.Delivery) error {
+	log := logger.FromContext(ctx)
+
+	var event messagequeue.Event
+	if err := json.Unmarshal(msg.Body, &event); err != nil {
+		log.Errorf("Dropping malformed order event: %v", err)
+		return nil
+	}
+	switch event.Type {
+	case order.EventCreated, order.EventUpdated, order.EventCancelled, order.EventRefunded:
+	default:
+		return nil
+	}
+	var data order.EventData
+	raw, _ := json.Marshal(event.Data)
+	if err := json.Unmarshal(raw, &data); err != nil || data.OrderID == uuid.Nil {
+		log.Errorf("Dropping %s event %s without an order", event.Type, event.ID)
+		return nil
+	}
+
+	at := event.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	if event.Type == order.EventCancelled || event.Type == order.EventRefunded {
+		if err := h.taxRepo.ReverseSale(ctx, data.OrderID, at); err != nil {
+			return fmt.Errorf("failed to reverse tax of order %s: %w", data.OrderID, err)
+		}
+		return nil
+	}
+
+	sale := &tax.Sale{
+		OrderID:    data.OrderID,
+		StoreID:    data.StoreID,
+		Currency:   data.Currency,
+		Taxes:      make([]tax.JurisdictionTax, len(data.Taxes)),
+		OccurredAt: at,
+	}
+	for i, t := range data.Taxes {
+		sale.Taxes[i] = tax.JurisdictionTax{
+			JurisdictionID: t.JurisdictionID,
+			Name:           t.Name,
+			Level:          tax.Level(t.Level),
+			Taxable:        t.Taxable,
+			Exempt:         t.Exempt,
+			Tax:            t.Tax,
+		}
+	}
+	if err := h.taxRepo.RecordSale(ctx, sale); err != nil {
+		return fmt.Errorf("failed to record tax of order %s: %w", data.OrderID, err)
+	}
+	return nil
+}
@@ -0,0 +1,493 @@
+package tax
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/store"
+	"github.com/onichange/pos-system/internal/domain/tax"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// dateLayout is the layout of dates in requests
+const dateLayout = "2006-01-02"
+
+// StoreLocator fetches stores from the store service, to tax sales at them
+type StoreLocator interface {
+	GetStore(ctx context.Context, id uuid.UUID) (*store.Store, error)
+}
+
+// Handler handles tax HTTP requests
+type Handler struct {
+	taxRepo tax.Repository
+	stores  StoreLocator
+}
+
+// NewHandler creates a new tax handler
+func NewHandler(taxRepo tax.Repository, stores StoreLocator) *Handler {
+	return &Handler{
+		taxRepo: taxRepo,
+		stores:  stores,
+	}
+}
+
+// GetJurisdictions handles GET /tax/jurisdictions?country=
+func (h *Handler) GetJurisdictions(c *fiber.Ctx) error {
+	limit, offset := pagination(c)
+	country := strings.ToUpper(c.Query("country"))
+
+	jurisdictions, err := h.taxRepo.ListJurisdictions(c.UserContext(), country, limit, offset)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch tax jurisdictions")
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   jurisdictions,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetJurisdiction handles GET /tax/jurisdictions/:id
+func (h *Handler) GetJurisdiction(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid jurisdiction ID",
+		})
+	}
+
+	j, err := h.taxRepo.GetJurisdiction(c.UserContext(), id)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch tax jurisdiction")
+	}
+
+	return c.JSON(j)
+}
+
+// CreateJurisdiction handles POST /tax/jurisdictions
+func (h *Handler) CreateJurisdiction(c *fiber.Ctx) error {
+	var req CreateJurisdictionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	loc := tax.Location{Country: req.Country, State: req.State, City: req.City}.Normalize()
+	j := &tax.Jurisdiction{
+		ID:            uuid.New(),
+		Country:       loc.Country,
+		State:         loc.State,
+		City:          loc.City,
+		Name:          req.Name,
+		Rate:          req.Rate,
+		CategoryRates: lowerKeys(req.CategoryRates),
+		Active:        req.Active == nil || *req.Active,
+	}
+
+	if err := h.taxRepo.CreateJurisdiction(c.UserContext(), j); err != nil {
+		return h.writeError(c, err, "Failed to create tax jurisdiction")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(j)
+}
+
+// UpdateJurisdiction handles PUT /tax/jurisdictions/:id
+func (h *Handler) UpdateJurisdiction(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid jurisdiction ID",
+		})
+	}
+
+	var req UpdateJurisdictionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	j, err := h.taxRepo.GetJurisdiction(c.UserContext(), id)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch tax jurisdiction")
+	}
+
+	if req.Name != "" {
+		j.Name = req.Name
+	}
+	if req.Rate != nil {
+		j.Rate = *req.Rate
+	}
+	if req.CategoryRates != nil {
+		j.CategoryRates = lowerKeys(req.CategoryRates)
+	}
+	if req.Active != nil {
+		j.Active = *req.Active
+	}
+
+	if err := h.taxRepo.UpdateJurisdiction(c.UserContext(), j); err != nil {
+		return h.writeError(c, err, "Failed to update tax jurisdiction")
+	}
+
+	return c.JSON(j)
+}
+
+// GetCategories handles GET /tax/categories
+func (h *Handler) GetCategories(c *fiber.Ctx) error {
+	categories, err := h.taxRepo.ListCategories(c.UserContext())
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch tax categories")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": categories,
+	})
+}
+
+// SaveCategory handles POST /tax/categories, creating or replacing the
+// category with the code given
+func (h *Handler) SaveCategory(c *fiber.Ctx) error {
+	var req SaveCategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	category := &tax.Category{
+		Code:        strings.ToLower(req.Code),
+		Name:        req.Name,
+		Description: req.Description,
+		Exempt:      req.Exempt,
+	}
+	if err := h.taxRepo.SaveCategory(c.UserContext(), category); err != nil {
+		return h.writeError(c, err, "Failed to save tax category")
+	}
+
+	return c.JSON(category)
+}
+
+// GetAssignments handles GET /tax/assignments?kind=
+func (h *Handler) GetAssignments(c *fiber.Ctx) error {
+	limit, offset := pagination(c)
+	kind := tax.AssignmentKind(c.Query("kind"))
+	if kind != "" && kind != tax.AssignProduct && kind != tax.AssignCatalogCategory {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "kind must be product or catalog_category",
+		})
+	}
+
+	assignments, err := h.taxRepo.ListAssignments(c.UserContext(), kind, limit, offset)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch tax category assignments")
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   assignments,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// SaveAssignment handles POST /tax/assignments, giving a catalog variant or
+// every product of a catalog category a tax category
+func (h *Handler) SaveAssignment(c *fiber.Ctx) error {
+	var req SaveAssignmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	a := &tax.Assignment{
+		SubjectID: req.SubjectID,
+		Kind:      tax.AssignmentKind(req.Kind),
+		Category:  strings.ToLower(req.Category),
+	}
+	if err := h.taxRepo.SaveAssignment(c.UserContext(), a); err != nil {
+		return h.writeError(c, err, "Failed to assign tax category")
+	}
+
+	return c.JSON(a)
+}
+
+// DeleteAssignment handles DELETE /tax/assignments/:kind/:subjectId
+func (h *Handler) DeleteAssignment(c *fiber.Ctx) error {
+	kind := tax.AssignmentKind(c.Params("kind"))
+	if kind != tax.AssignProduct && kind != tax.AssignCatalogCategory {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "kind must be product or catalog_category",
+		})
+	}
+	subjectID, err := uuid.Parse(c.Params("subjectId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid subject ID",
+		})
+	}
+
+	if err := h.taxRepo.DeleteAssignment(c.UserContext(), kind, subjectID); err != nil {
+		return h.writeError(c, err, "Failed to delete tax category assignment")
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// GetHolidays handles GET /tax/holidays?jurisdiction_id=
+func (h *Handler) GetHolidays(c *fiber.Ctx) error {
+	limit, offset := pagination(c)
+
+	var jurisdictionID *uuid.UUID
+	if value := c.Query("jurisdiction_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid jurisdiction ID",
+			})
+		}
+		jurisdictionID = &id
+	}
+
+	holidays, err := h.taxRepo.ListHolidays(c.UserContext(), jurisdictionID, limit, offset)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch tax holidays")
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   holidays,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// CreateHoliday handles POST /tax/holidays
+func (h *Handler) CreateHoliday(c *fiber.Ctx) error {
+	var req CreateHolidayRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	// Validation checked the layout
+	startsOn, _ := time.Parse(dateLayout, req.StartsOn)
+	endsOn, _ := time.Parse(dateLayout, req.EndsOn)
+	if endsOn.Before(startsOn) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ends_on must not be before starts_on",
+		})
+	}
+
+	holiday := &tax.Holiday{
+		ID:             uuid.New(),
+		JurisdictionID: req.JurisdictionID,
+		Name:           req.Name,
+		Category:       strings.ToLower(req.Category),
+		StartsOn:       startsOn,
+		EndsOn:         endsOn,
+		Rate:           req.Rate,
+		MaxUnitPrice:   req.MaxUnitPrice,
+	}
+	if err := h.taxRepo.CreateHoliday(c.UserContext(), holiday); err != nil {
+		return h.writeError(c, err, "Failed to create tax holiday")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(holiday)
+}
+
+// DeleteHoliday handles DELETE /tax/holidays/:id
+func (h *Handler) DeleteHoliday(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid holiday ID",
+		})
+	}
+
+	if err := h.taxRepo.DeleteHoliday(c.UserContext(), id); err != nil {
+		return h.writeError(c, err, "Failed to delete tax holiday")
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// Quote handles POST /tax/quote, for previewing the tax on a sale, and POST
+// /internal/v1/tax/quote, called by the order service as it prices orders
+func (h *Handler) Quote(c *fiber.Ctx) error {
+	var req QuoteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	day := time.Now().UTC()
+	if req.Date != "" {
+		// Validation checked the layout
+		day, _ = time.Parse(dateLayout, req.Date)
+	}
+
+	quote, err := h.quote(c.UserContext(), &req, day)
+	if err != nil {
+		metrics.TaxQuotes.WithLabelValues("failed").Inc()
+		return h.writeError(c, err, "Failed to quote tax")
+	}
+	metrics.TaxQuotes.WithLabelValues("quoted").Inc()
+
+	return c.JSON(quote)
+}
+
+// quote works out the tax due on req's lines on day, at its ship-to address
+// or, without one, at its store, as its store levies it
+func (h *Handler) quote(ctx context.Context, req *QuoteRequest, day time.Time) (*tax.Quote, error) {
+	var loc tax.Location
+	if req.Address != nil && req.Address.Country != "" {
+		loc = req.Address.Normalize()
+	} else {
+		s, err := h.stores.GetStore(ctx, req.StoreID)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, tax.ErrStoreNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		loc = tax.Location{Country: s.Country, State: s.State, City: s.City}.Normalize()
+	}
+
+	categories, err := h.taxRepo.ResolveCategories(ctx, req.Lines)
+	if err != nil {
+		return nil, err
+	}
+	jurisdictions, err := h.taxRepo.MatchJurisdictions(ctx, loc)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(jurisdictions))
+	for i, j := range jurisdictions {
+		ids[i] = j.ID
+	}
+	holidays, err := h.taxRepo.GetHolidays(ctx, ids, day)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := h.taxRepo.StoreRates(ctx, req.StoreID)
+	if err != nil {
+		return nil, err
+	}
+
+	return tax.Calculate(loc, req.Currency, req.Lines, categories, jurisdictions, rates, holidays, day), nil
+}
+
+// writeError answers a failed request, with 404 for unknown records and
+// stores, 409 for a duplicate jurisdiction and 400 for an unknown category
+func (h *Handler) writeError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, tax.ErrJurisdictionNotFound), errors.Is(err, tax.ErrHolidayNotFound),
+		errors.Is(err, tax.ErrAssignmentNotFound), errors.Is(err, tax.ErrStoreNotFound),
+		errors.Is(err, tax.ErrStoreRateNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": capitalize(err.Error()),
+		})
+	case errors.Is(err, tax.ErrJurisdictionExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A tax jurisdiction already covers this location",
+		})
+	case errors.Is(err, tax.ErrCategoryNotFound):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": capitalize(err.Error()),
+		})
+	}
+	logger.FromContext(c.UserContext()).Errorf("%s: %v", message, err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+// lowerKeys lower-cases the tax category codes keying rates
+func lowerKeys(rates map[string]float64) map[string]float64 {
+	if rates == nil {
+		return nil
+	}
+	lowered := make(map[string]float64, len(rates))
+	for code, rate := range rates {
+		lowered[strings.ToLower(code)] = rate
+	}
+	return lowered
+}
+
+// capitalize turns an error message into a response message
+func capitalize(message string) string {
+	if message == "" {
+		return message
+	}
+	return strings.ToUpper(message[:1]) + message[1:]
+}
+
+// pagination parses the limit and offset of a list request
+func pagination(c *fiber.Ctx) (int, int) {
+	limit := 50
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+	return limit, offset
+}
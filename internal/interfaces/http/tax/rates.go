@@ -0,0 +1,100 @@
+package tax
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/tax"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// GetStoreRates handles GET /tax/stores/:storeId/rates
+func (h *Handler) GetStoreRates(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("storeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+
+	rates, err := h.taxRepo.StoreRates(c.UserContext(), storeID)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch store tax rates")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": rates,
+	})
+}
+
+// SaveStoreRate handles PUT /tax/stores/:storeId/rates/:jurisdictionId,
+// setting how the store levies the jurisdiction's tax
+func (h *Handler) SaveStoreRate(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("storeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+	jurisdictionID, err := uuid.Parse(c.Params("jurisdictionId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid jurisdiction ID",
+		})
+	}
+
+	var req SaveStoreRateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	rate := &tax.StoreRate{
+		StoreID:        storeID,
+		JurisdictionID: jurisdictionID,
+		Rate:           req.Rate,
+		CategoryRates:  lowerKeys(req.CategoryRates),
+		Inclusive:      req.Inclusive,
+	}
+	for _, code := range req.ExemptCategories {
+		rate.ExemptCategories = append(rate.ExemptCategories, strings.ToLower(code))
+	}
+	if err := h.taxRepo.SaveStoreRate(c.UserContext(), rate); err != nil {
+		return h.writeError(c, err, "Failed to save store tax rate")
+	}
+
+	return c.JSON(rate)
+}
+
+// DeleteStoreRate handles DELETE /tax/stores/:storeId/rates/:jurisdictionId,
+// leaving the jurisdiction's own rates to apply at the store
+func (h *Handler) DeleteStoreRate(c *fiber.Ctx) error {
+	storeID, err := uuid.Parse(c.Params("storeId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid store ID",
+		})
+	}
+	jurisdictionID, err := uuid.Parse(c.Params("jurisdictionId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid jurisdiction ID",
+		})
+	}
+
+	if err := h.taxRepo.DeleteStoreRate(c.UserContext(), storeID, jurisdictionID); err != nil {
+		return h.writeError(c, err, "Failed to delete store tax rate")
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
@@ -0,0 +1,111 @@
+package tax
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/onichange/pos-system/internal/domain/tax"
+)
+
+// maxReportDays bounds the period of a filing report
+const maxReportDays = 366
+
+// reportColumns are the header of a CSV filing report
+var reportColumns = []string{
+	"jurisdiction_id", "name", "level", "country", "state", "city", "currency", "orders", "taxable", "exempt", "tax",
+}
+
+// GetReport handles GET /tax/reports?from=&to=&country=&state=&format=json|csv:
+// the tax collected for each jurisdiction over a period, for filing returns.
+// from and to are YYYY-MM-DD dates, both included.
+func (h *Handler) GetReport(c *fiber.Ctx) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "format must be json or csv",
+		})
+	}
+
+	from, err := time.Parse(dateLayout, c.Query("from"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "from must be a YYYY-MM-DD date",
+		})
+	}
+	to, err := time.Parse(dateLayout, c.Query("to"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "to must be a YYYY-MM-DD date",
+		})
+	}
+	if to.Before(from) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "to must not be before from",
+		})
+	}
+	if to.Sub(from) >= maxReportDays*24*time.Hour {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Reports cover at most %d days", maxReportDays),
+		})
+	}
+
+	filter := tax.ReportFilter{
+		From:    from,
+		To:      to,
+		Country: strings.ToUpper(c.Query("country")),
+		State:   strings.ToUpper(c.Query("state")),
+	}
+	rows, err := h.taxRepo.Report(c.UserContext(), filter)
+	if err != nil {
+		return h.writeError(c, err, "Failed to build tax report")
+	}
+
+	if format == "csv" {
+		return writeReportCSV(c, filter, rows)
+	}
+	return c.JSON(&tax.Report{
+		From:        filter.From,
+		To:          filter.To,
+		Country:     filter.Country,
+		State:       filter.State,
+		Rows:        rows,
+		GeneratedAt: time.Now().UTC(),
+	})
+}
+
+// writeReportCSV answers with rows as a CSV attachment
+func writeReportCSV(c *fiber.Ctx, filter tax.ReportFilter, rows []*tax.ReportRow) error {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.Write(reportColumns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := []string{
+			row.JurisdictionID.String(), row.Name, string(row.Level), row.Country, row.State, row.City, row.Currency,
+			strconv.Itoa(row.Orders), money(row.Taxable), money(row.Exempt), money(row.Tax),
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("tax-report-%s-%s.csv", filter.From.Format(dateLayout), filter.To.Format(dateLayout))
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	return c.SendString(b.String())
+}
+
+// money formats an amount with two decimals
+func money(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
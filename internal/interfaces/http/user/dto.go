@@ -28,6 +28,11 @@ type ChangePasswordRequest struct {
 	NewPassword     string `json:"new_password" validate:"required,min=8"`
 }
 
+// RefreshTokenRequest represents refresh token and logout request
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
 // LoginRequest represents login request
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
@@ -5,8 +5,10 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/onichange/pos-system/internal/domain/user"
+	"github.com/onichange/pos-system/pkg/audit/security"
 	"github.com/onichange/pos-system/pkg/auth"
 	"github.com/onichange/pos-system/pkg/encryption"
+	"github.com/onichange/pos-system/pkg/logger"
 	"github.com/onichange/pos-system/pkg/validator"
 )
 
@@ -14,13 +16,20 @@ import (
 type Handler struct {
 	userRepo   user.Repository
 	jwtManager *auth.JWTManager
+	passwords  *encryption.PasswordHasher
+
+	// Refresh tokens revoked on refresh, logout and password change; nil
+	// when Redis is unreachable
+	revocations *auth.RevocationList
 }
 
 // NewHandler creates a new user handler
-func NewHandler(userRepo user.Repository, jwtManager *auth.JWTManager) *Handler {
+func NewHandler(userRepo user.Repository, jwtManager *auth.JWTManager, passwords *encryption.PasswordHasher, revocations *auth.RevocationList) *Handler {
 	return &Handler{
-		userRepo:   userRepo,
-		jwtManager: jwtManager,
+		userRepo:    userRepo,
+		jwtManager:  jwtManager,
+		passwords:   passwords,
+		revocations: revocations,
 	}
 }
 
@@ -42,8 +51,9 @@ func (h *Handler) CreateUser(c *fiber.Ctx) error {
 	}
 
 	// Check if user exists
-	exists, err := h.userRepo.ExistsByEmail(c.Context(), req.Email)
+	exists, err := h.userRepo.ExistsByEmail(c.UserContext(), req.Email)
 	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to check user existence: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to check user existence",
 		})
@@ -55,8 +65,9 @@ func (h *Handler) CreateUser(c *fiber.Ctx) error {
 	}
 
 	// Hash password
-	passwordHash, err := encryption.HashPassword(req.Password)
+	passwordHash, err := h.passwords.Hash(req.Password)
 	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to hash password: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to hash password",
 		})
@@ -73,13 +84,14 @@ func (h *Handler) CreateUser(c *fiber.Ctx) error {
 		MFAEnabled:   false,
 	}
 
-	if err := h.userRepo.Create(c.Context(), u); err != nil {
+	if err := h.userRepo.Create(c.UserContext(), u); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to create user: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create user",
 		})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(toUserResponse(u))
+	return c.Status(fiber.StatusCreated).JSON(ToResponse(u))
 }
 
 // GetUserProfile handles GET /users/me
@@ -98,14 +110,14 @@ func (h *Handler) GetUserProfile(c *fiber.Ctx) error {
 		})
 	}
 
-	u, err := h.userRepo.GetByID(c.Context(), userID)
+	u, err := h.userRepo.GetByID(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "User not found",
 		})
 	}
 
-	return c.JSON(toUserResponse(u))
+	return c.JSON(ToResponse(u))
 }
 
 // UpdateUserProfile handles PUT /users/me
@@ -125,7 +137,7 @@ func (h *Handler) UpdateUserProfile(c *fiber.Ctx) error {
 	}
 
 	// Get existing user
-	u, err := h.userRepo.GetByID(c.Context(), userID)
+	u, err := h.userRepo.GetByID(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "User not found",
@@ -152,13 +164,14 @@ func (h *Handler) UpdateUserProfile(c *fiber.Ctx) error {
 	}
 
 	// Save user
-	if err := h.userRepo.Update(c.Context(), u); err != nil {
+	if err := h.userRepo.Update(c.UserContext(), u); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to update user: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update user",
 		})
 	}
 
-	return c.JSON(toUserResponse(u))
+	return c.JSON(ToResponse(u))
 }
 
 // Login handles POST /auth/login
@@ -179,8 +192,9 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 	}
 
 	// Get user by email
-	u, err := h.userRepo.GetByEmail(c.Context(), req.Email)
+	u, err := h.userRepo.GetByEmail(c.UserContext(), req.Email)
 	if err != nil {
+		recordLoginFailure(c, "", req.Email, "unknown_account")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid credentials",
 		})
@@ -188,36 +202,55 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 
 	// Check if account is locked
 	if u.IsLocked() {
+		recordLoginFailure(c, u.ID.String(), req.Email, "account_locked")
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Account is locked",
 		})
 	}
 
 	// Verify password
-	valid, err := encryption.VerifyPassword(req.Password, u.PasswordHash)
+	valid, needsRehash, err := h.passwords.Verify(req.Password, u.PasswordHash)
 	if err != nil || !valid {
 		u.IncrementFailedLogin()
-		h.userRepo.Update(c.Context(), u)
+		h.userRepo.Update(c.UserContext(), u)
+		recordLoginFailure(c, u.ID.String(), req.Email, "invalid_password")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid credentials",
 		})
 	}
 
+	// Upgrade bcrypt, legacy, or outdated hashes while the password is at hand
+	if needsRehash {
+		h.rehashPassword(c, u.ID, req.Password)
+	}
+
 	// Reset failed login attempts
 	u.ResetFailedLogin()
 	u.UpdateLastLogin()
-	h.userRepo.Update(c.Context(), u)
+	h.userRepo.Update(c.UserContext(), u)
 
-	// Generate tokens
-	tokenPair, err := h.jwtManager.GenerateTokenPair(u.ID.String(), u.Email, []string{"user"}, "")
+	// Generate tokens carrying the user's roles, bound to the user's tenant
+	roles := u.Roles
+	if len(roles) == 0 {
+		roles = []string{"user"}
+	}
+	tokenPair, err := h.jwtManager.GenerateTenantTokenPair(u.TenantID, u.ID.String(), u.Email, roles, "")
 	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to generate tokens: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate tokens",
 		})
 	}
 
+	security.RecordRequest(c, security.Event{
+		Type:      security.EventLogin,
+		Outcome:   security.OutcomeSuccess,
+		ActorID:   u.ID.String(),
+		SubjectID: u.ID.String(),
+	})
+
 	return c.JSON(LoginResponse{
-		User:         toUserResponse(u),
+		User:         ToResponse(u),
 		AccessToken:  tokenPair.AccessToken,
 		RefreshToken: tokenPair.RefreshToken,
 		ExpiresAt:    tokenPair.ExpiresAt,
@@ -233,18 +266,18 @@ func (h *Handler) GetUserByID(c *fiber.Ctx) error {
 		})
 	}
 
-	u, err := h.userRepo.GetByID(c.Context(), userID)
+	u, err := h.userRepo.GetByID(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "User not found",
 		})
 	}
 
-	return c.JSON(toUserResponse(u))
+	return c.JSON(ToResponse(u))
 }
 
-// toUserResponse converts domain User to UserResponse
-func toUserResponse(u *user.User) *UserResponse {
+// ToResponse converts domain User to UserResponse
+func ToResponse(u *user.User) *UserResponse {
 	return &UserResponse{
 		ID:          u.ID,
 		Email:       u.Email,
@@ -257,3 +290,29 @@ func toUserResponse(u *user.User) *UserResponse {
 		UpdatedAt:   u.UpdatedAt,
 	}
 }
+
+// recordLoginFailure writes a failed login to the security audit log. The
+// email is masked; userID is empty when no account matched.
+func recordLoginFailure(c *fiber.Ctx, userID, email, reason string) {
+	security.RecordRequest(c, security.Event{
+		Type:      security.EventLoginFailed,
+		Outcome:   security.OutcomeFailure,
+		SubjectID: userID,
+		Details: map[string]string{
+			"email":  logger.MaskSensitive(email),
+			"reason": reason,
+		},
+	})
+}
+
+// rehashPassword stores a new hash of a verified password. Failures are only
+// logged; the old hash keeps working.
+func (h *Handler) rehashPassword(c *fiber.Ctx, userID uuid.UUID, password string) {
+	passwordHash, err := h.passwords.Hash(password)
+	if err == nil {
+		err = h.userRepo.UpdatePassword(c.UserContext(), userID, passwordHash)
+	}
+	if err != nil {
+		logger.FromContext(c.UserContext()).Warnf("Failed to re-hash password of user %s: %v", userID, err)
+	}
+}
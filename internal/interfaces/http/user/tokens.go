@@ -0,0 +1,208 @@
+package user
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/pkg/audit/security"
+	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// RefreshToken handles POST /auth/refresh, trading a refresh token for a new
+// token pair. The refresh token is revoked, so each one is used once.
+func (h *Handler) RefreshToken(c *fiber.Ctx) error {
+	var req RefreshTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	claims, err := h.jwtManager.ValidateRefreshTokenContext(c.UserContext(), req.RefreshToken)
+	if err != nil {
+		if !errors.Is(err, auth.ErrTokenRevoked) {
+			logger.FromContext(c.UserContext()).Debugf("Refused refresh token: %v", err)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid refresh token",
+		})
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid refresh token",
+		})
+	}
+	u, err := h.userRepo.GetByID(c.UserContext(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid refresh token",
+		})
+	}
+	if u.IsLocked() {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Account is locked",
+		})
+	}
+
+	if h.revocations != nil {
+		if err := h.revocations.Revoke(c.UserContext(), claims.ID); err != nil {
+			logger.FromContext(c.UserContext()).Errorf("Failed to revoke refresh token of user %s: %v", u.ID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to refresh tokens",
+			})
+		}
+	}
+
+	// New tokens carry the user's current roles
+	roles := u.Roles
+	if len(roles) == 0 {
+		roles = []string{"user"}
+	}
+	tokenPair, err := h.jwtManager.GenerateTenantTokenPair(u.TenantID, u.ID.String(), u.Email, roles, claims.DeviceID)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to generate tokens: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate tokens",
+		})
+	}
+
+	return c.JSON(tokenPair)
+}
+
+// Logout handles POST /auth/logout, revoking the refresh token given. A
+// token already revoked is accepted, so retries are harmless.
+func (h *Handler) Logout(c *fiber.Ctx) error {
+	var req RefreshTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	if h.revocations == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Token revocation is unavailable",
+		})
+	}
+
+	claims, err := h.jwtManager.ValidateRefreshTokenContext(c.UserContext(), req.RefreshToken)
+	if errors.Is(err, auth.ErrTokenRevoked) {
+		return c.Status(fiber.StatusNoContent).Send(nil)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid refresh token",
+		})
+	}
+
+	if err := h.revocations.Revoke(c.UserContext(), claims.ID); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to revoke refresh token of user %s: %v", claims.UserID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to log out",
+		})
+	}
+
+	security.RecordRequest(c, security.Event{
+		Type:      security.EventLogout,
+		Outcome:   security.OutcomeSuccess,
+		ActorID:   claims.UserID,
+		SubjectID: claims.UserID,
+	})
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
+
+// ChangePassword handles PUT /users/me/password. Every refresh token issued
+// to the user so far is revoked, signing other devices out when their
+// access tokens expire.
+func (h *Handler) ChangePassword(c *fiber.Ctx) error {
+	userIDStr := c.Locals("user_id")
+	if userIDStr == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	u, err := h.userRepo.GetByID(c.UserContext(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	if valid, _, err := h.passwords.Verify(req.CurrentPassword, u.PasswordHash); err != nil || !valid {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid credentials",
+		})
+	}
+
+	passwordHash, err := h.passwords.Hash(req.NewPassword)
+	if err == nil {
+		err = h.userRepo.UpdatePassword(c.UserContext(), u.ID, passwordHash)
+	}
+	if err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to change password of user %s: %v", u.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to change password",
+		})
+	}
+
+	security.RecordRequest(c, security.Event{
+		Type:      security.EventPasswordChanged,
+		Outcome:   security.OutcomeSuccess,
+		ActorID:   u.ID.String(),
+		SubjectID: u.ID.String(),
+	})
+
+	// The password is changed either way; refresh tokens left unrevoked
+	// still expire on their own
+	if h.revocations == nil {
+		logger.FromContext(c.UserContext()).Warnf("Refresh tokens of user %s were not revoked: revocation is unavailable", u.ID)
+	} else if err := h.revocations.RevokeUser(c.UserContext(), u.ID.String(), time.Now()); err != nil {
+		logger.FromContext(c.UserContext()).Errorf("Failed to revoke refresh tokens of user %s: %v", u.ID, err)
+	}
+
+	return c.Status(fiber.StatusNoContent).Send(nil)
+}
@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/webhook"
+	"github.com/onichange/pos-system/pkg/encryption"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/tenant"
+	outbound "github.com/onichange/pos-system/pkg/webhook"
+)
+
+// maxResponseBody caps how much of an endpoint's response is recorded
+const maxResponseBody = 1024
+
+// pruneInterval is how often finished deliveries past retention are deleted
+const pruneInterval = time.Hour
+
+// RunDispatcher attempts due deliveries every dispatch interval until ctx is
+// cancelled. Each pass claims a batch across tenants, attempts it
+// concurrently and records every attempt in the delivery's tenant; a failed
+// delivery is retried after an exponential backoff until it runs out of
+// attempts.
+func (h *Handler) RunDispatcher(ctx context.Context, log *logger.Logger) {
+	defer apperrors.Recover(ctx, "webhook-dispatcher")
+
+	// Claimed deliveries are held off other instances for longer than an
+	// attempt can take
+	lease := h.cfg.Timeout + time.Minute
+	var lastPrune time.Time
+
+	pass := func() {
+		now := time.Now()
+		due, err := h.webhookRepo.ClaimDue(ctx, now, lease, h.cfg.BatchSize)
+		if err != nil {
+			log.Errorf("Failed to claim webhook deliveries: %v", err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, d := range due {
+			wg.Add(1)
+			go func(d *webhook.DueDelivery) {
+				defer wg.Done()
+				ctx := tenant.NewContext(ctx, &tenant.Tenant{ID: d.Subscription.TenantID, Source: tenant.SourceJob})
+				if err := h.attempt(ctx, d); err != nil {
+					log.Errorf("Failed to record webhook delivery %s: %v", d.Delivery.ID, err)
+				}
+			}(d)
+		}
+		wg.Wait()
+
+		if h.cfg.Retention > 0 && now.Sub(lastPrune) >= pruneInterval {
+			lastPrune = now
+			pruned, err := h.webhookRepo.PruneDeliveries(ctx, now.Add(-h.cfg.Retention))
+			if err != nil {
+				log.Errorf("Failed to prune webhook deliveries: %v", err)
+			} else if pruned > 0 {
+				log.Infof("Pruned %d webhook deliveries", pruned)
+			}
+		}
+	}
+
+	pass()
+
+	ticker := time.NewTicker(h.cfg.DispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pass()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// attempt posts a claimed delivery, signed with its subscription's secret,
+// and records the outcome
+func (h *Handler) attempt(ctx context.Context, due *webhook.DueDelivery) error {
+	d, s := due.Delivery, due.Subscription
+	event := outbound.Event{
+		ID:        d.EventID,
+		Type:      d.EventType,
+		CreatedAt: d.EventAt.UTC(),
+		Data:      d.Payload,
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	a := &webhook.Attempt{
+		ID:          uuid.New(),
+		DeliveryID:  d.ID,
+		Number:      d.Attempts + 1,
+		AttemptedAt: start.UTC(),
+	}
+
+	req, err := outbound.NewRequest(ctx, s.URL, event, body, encryption.SignPayload(body, start, s.Secret))
+	if err != nil {
+		a.Error = err.Error()
+	} else if resp, err := h.client.Do(req); err != nil {
+		a.Error = err.Error()
+	} else {
+		code := resp.StatusCode
+		a.ResponseCode = &code
+		excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+		resp.Body.Close()
+		a.ResponseBody = string(excerpt)
+		if !a.Succeeded() {
+			a.Error = fmt.Sprintf("endpoint returned %d", code)
+		}
+	}
+	a.DurationMS = time.Since(start).Milliseconds()
+
+	status := webhook.DeliveryPending
+	var next *time.Time
+	switch {
+	case a.Succeeded():
+		status = webhook.DeliverySucceeded
+	case a.Number >= h.cfg.MaxAttempts:
+		status = webhook.DeliveryFailed
+	default:
+		retryAt := start.Add(webhook.Backoff(a.Number, h.cfg.BackoffBase, h.cfg.BackoffMax)).UTC()
+		next = &retryAt
+	}
+
+	result := "success"
+	if !a.Succeeded() {
+		result = "failure"
+	}
+	metrics.WebhookDeliveries.WithLabelValues(d.EventType, result).Inc()
+
+	return h.webhookRepo.RecordAttempt(ctx, a, status, next)
+}
@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/onichange/pos-system/internal/domain/webhook"
+)
+
+// CreateSubscriptionRequest represents create webhook subscription request.
+// Partners subscribe for themselves; admins name the partner.
+type CreateSubscriptionRequest struct {
+	PartnerID   string   `json:"partner_id,omitempty" validate:"max=100"`
+	URL         string   `json:"url" validate:"required,url,max=2048"`
+	EventTypes  []string `json:"event_types" validate:"required,min=1,max=50,dive,required,max=100"`
+	Description string   `json:"description,omitempty" validate:"max=500"`
+}
+
+// UpdateSubscriptionRequest represents update webhook subscription request.
+// Fields left out are kept; event types given replace the current ones.
+type UpdateSubscriptionRequest struct {
+	URL         string   `json:"url,omitempty" validate:"omitempty,url,max=2048"`
+	EventTypes  []string `json:"event_types,omitempty" validate:"omitempty,max=50,dive,required,max=100"`
+	Description *string  `json:"description,omitempty" validate:"omitempty,max=500"`
+}
+
+// ReplayRequest represents replay failed deliveries request. Since defaults
+// to a day ago.
+type ReplayRequest struct {
+	Since *time.Time `json:"since,omitempty"`
+}
+
+// SubscriptionWithSecret is a created subscription along with the secret
+// its deliveries are signed with, which is not shown again
+type SubscriptionWithSecret struct {
+	*webhook.Subscription
+	Secret string `json:"secret"`
+}
+
+// DeliveryWithAttempts is a delivery along with its attempts, oldest first
+type DeliveryWithAttempts struct {
+	*webhook.Delivery
+	AttemptLog []*webhook.Attempt `json:"attempt_log"`
+}
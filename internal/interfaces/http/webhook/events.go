@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+
+	"github.com/onichange/pos-system/internal/domain/webhook"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+)
+
+// HandleEvent queues a delivery of an event from the message broker to
+// every subscription matching its type. Redeliveries are queued once.
+// Malformed messages are dropped.
+func (h *Handler) HandleEvent(ctx context.Context, msg amqp.Delivery) error {
+	var event messagequeue.Event
+	if err := json.Unmarshal(msg.Body, &event); err != nil || event.ID == "" || event.Type == "" {
+		logger.FromContext(ctx).Errorf("Dropping malformed event: %v", err)
+		return nil
+	}
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Dropping %s event %s with unencodable data: %v", event.Type, event.ID, err)
+		return nil
+	}
+	createdAt := event.Timestamp
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	if _, err := h.webhookRepo.Enqueue(ctx, &webhook.Event{
+		ID:        event.ID,
+		Type:      event.Type,
+		Data:      data,
+		CreatedAt: createdAt,
+	}, time.Now()); err != nil {
+		return fmt.Errorf("failed to queue webhooks for %s event %s: %w", event.Type, event.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,413 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/internal/domain/webhook"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// secretPrefix marks subscription secrets, so they are recognizable when
+// leaked
+const secretPrefix = "whsec_"
+
+// Handler handles webhook HTTP requests and delivers partner webhooks.
+// Routes serve admins, who manage every partner's subscriptions, and
+// partners, whose signed requests reach only their own.
+type Handler struct {
+	webhookRepo webhook.Repository
+	cfg         config.WebhooksConfig
+	client      *http.Client
+}
+
+// NewHandler creates a new webhook handler
+func NewHandler(webhookRepo webhook.Repository, cfg config.WebhooksConfig) *Handler {
+	return &Handler{
+		webhookRepo: webhookRepo,
+		cfg:         cfg,
+		client:      &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// GetSubscriptions handles GET /webhooks/subscriptions?partner_id=
+func (h *Handler) GetSubscriptions(c *fiber.Ctx) error {
+	limit, offset := pagination(c)
+	partnerID := c.Query("partner_id")
+	if partner := signedPartner(c); partner != "" {
+		partnerID = partner
+	}
+
+	subscriptions, err := h.webhookRepo.ListSubscriptions(c.UserContext(), partnerID, limit, offset)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch webhook subscriptions")
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   subscriptions,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetSubscription handles GET /webhooks/subscriptions/:id
+func (h *Handler) GetSubscription(c *fiber.Ctx) error {
+	s, err := h.subscription(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(s)
+}
+
+// CreateSubscription handles POST /webhooks/subscriptions. The response
+// carries the secret deliveries are signed with; it is not shown again.
+func (h *Handler) CreateSubscription(c *fiber.Ctx) error {
+	var req CreateSubscriptionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	partnerID := req.PartnerID
+	if partner := signedPartner(c); partner != "" {
+		partnerID = partner
+	}
+	if partnerID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "partner_id is required",
+		})
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return h.writeError(c, err, "Failed to create webhook subscription")
+	}
+
+	s := &webhook.Subscription{
+		ID:          uuid.New(),
+		PartnerID:   partnerID,
+		URL:         req.URL,
+		EventTypes:  normalizeEventTypes(req.EventTypes),
+		Description: req.Description,
+		Secret:      secret,
+		Status:      webhook.SubscriptionActive,
+	}
+	if err := h.webhookRepo.CreateSubscription(c.UserContext(), s); err != nil {
+		return h.writeError(c, err, "Failed to create webhook subscription")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(SubscriptionWithSecret{Subscription: s, Secret: secret})
+}
+
+// UpdateSubscription handles PUT /webhooks/subscriptions/:id
+func (h *Handler) UpdateSubscription(c *fiber.Ctx) error {
+	var req UpdateSubscriptionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	s, err := h.subscription(c)
+	if err != nil {
+		return err
+	}
+
+	if req.URL != "" {
+		s.URL = req.URL
+	}
+	if len(req.EventTypes) > 0 {
+		s.EventTypes = normalizeEventTypes(req.EventTypes)
+	}
+	if req.Description != nil {
+		s.Description = *req.Description
+	}
+
+	if err := h.webhookRepo.UpdateSubscription(c.UserContext(), s); err != nil {
+		return h.writeError(c, err, "Failed to update webhook subscription")
+	}
+
+	return c.JSON(s)
+}
+
+// DeleteSubscription handles DELETE /webhooks/subscriptions/:id, dropping
+// its deliveries too
+func (h *Handler) DeleteSubscription(c *fiber.Ctx) error {
+	s, err := h.subscription(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.webhookRepo.DeleteSubscription(c.UserContext(), s.ID); err != nil {
+		return h.writeError(c, err, "Failed to delete webhook subscription")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// PauseSubscription handles POST /webhooks/subscriptions/:id/pause. Events
+// keep being queued for the subscription and go out once it is resumed.
+func (h *Handler) PauseSubscription(c *fiber.Ctx) error {
+	return h.setStatus(c, webhook.SubscriptionPaused)
+}
+
+// ResumeSubscription handles POST /webhooks/subscriptions/:id/resume
+func (h *Handler) ResumeSubscription(c *fiber.Ctx) error {
+	return h.setStatus(c, webhook.SubscriptionActive)
+}
+
+// setStatus pauses or resumes the subscription in the path
+func (h *Handler) setStatus(c *fiber.Ctx, status webhook.SubscriptionStatus) error {
+	s, err := h.subscription(c)
+	if err != nil {
+		return err
+	}
+
+	s.Status = status
+	if err := h.webhookRepo.UpdateSubscription(c.UserContext(), s); err != nil {
+		return h.writeError(c, err, "Failed to update webhook subscription")
+	}
+
+	return c.JSON(s)
+}
+
+// ReplaySubscription handles POST /webhooks/subscriptions/:id/replay,
+// queueing the subscription's failed deliveries of events since since again
+func (h *Handler) ReplaySubscription(c *fiber.Ctx) error {
+	var req ReplayRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	s, err := h.subscription(c)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	since := now.Add(-24 * time.Hour)
+	if req.Since != nil {
+		since = *req.Since
+	}
+
+	replayed, err := h.webhookRepo.ReplayFailed(c.UserContext(), s.ID, since, now)
+	if err != nil {
+		return h.writeError(c, err, "Failed to replay webhook deliveries")
+	}
+
+	return c.JSON(fiber.Map{
+		"subscription_id": s.ID,
+		"since":           since,
+		"replayed":        replayed,
+	})
+}
+
+// GetDeliveries handles GET /webhooks/deliveries?subscription_id=&status=&event_type=
+func (h *Handler) GetDeliveries(c *fiber.Ctx) error {
+	limit, offset := pagination(c)
+
+	filter := webhook.DeliveryFilter{
+		PartnerID: signedPartner(c),
+		Status:    webhook.DeliveryStatus(c.Query("status")),
+		EventType: c.Query("event_type"),
+	}
+	if raw := c.Query("subscription_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid subscription ID",
+			})
+		}
+		filter.SubscriptionID = &id
+	}
+
+	deliveries, err := h.webhookRepo.ListDeliveries(c.UserContext(), filter, limit, offset)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch webhook deliveries")
+	}
+
+	return c.JSON(fiber.Map{
+		"data":   deliveries,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetDelivery handles GET /webhooks/deliveries/:id, with the response code
+// and error of every attempt
+func (h *Handler) GetDelivery(c *fiber.Ctx) error {
+	d, err := h.delivery(c)
+	if err != nil {
+		return err
+	}
+
+	attempts, err := h.webhookRepo.ListAttempts(c.UserContext(), d.ID)
+	if err != nil {
+		return h.writeError(c, err, "Failed to fetch webhook delivery attempts")
+	}
+
+	return c.JSON(DeliveryWithAttempts{Delivery: d, AttemptLog: attempts})
+}
+
+// ReplayDelivery handles POST /webhooks/deliveries/:id/replay, queueing a
+// succeeded or failed delivery again with fresh attempts
+func (h *Handler) ReplayDelivery(c *fiber.Ctx) error {
+	d, err := h.delivery(c)
+	if err != nil {
+		return err
+	}
+
+	d, err = h.webhookRepo.Replay(c.UserContext(), d.ID, time.Now().UTC())
+	if err != nil {
+		return h.writeError(c, err, "Failed to replay webhook delivery")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(d)
+}
+
+// subscription returns the subscription in the path, answering the request
+// when it is not found or belongs to another partner than the signed one
+func (h *Handler) subscription(c *fiber.Ctx) (*webhook.Subscription, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid subscription ID")
+	}
+
+	s, err := h.webhookRepo.GetSubscription(c.UserContext(), id)
+	if err == nil && !owns(c, s) {
+		err = webhook.ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, h.writeError(c, err, "Failed to fetch webhook subscription")
+	}
+	return s, nil
+}
+
+// delivery returns the delivery in the path, answering the request when it
+// is not found or belongs to another partner than the signed one
+func (h *Handler) delivery(c *fiber.Ctx) (*webhook.Delivery, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid delivery ID")
+	}
+
+	d, err := h.webhookRepo.GetDelivery(c.UserContext(), id)
+	if err != nil {
+		return nil, h.writeError(c, err, "Failed to fetch webhook delivery")
+	}
+	if signedPartner(c) != "" {
+		s, err := h.webhookRepo.GetSubscription(c.UserContext(), d.SubscriptionID)
+		if err == nil && !owns(c, s) {
+			err = webhook.ErrDeliveryNotFound
+		}
+		if err != nil {
+			return nil, h.writeError(c, err, "Failed to fetch webhook delivery")
+		}
+	}
+	return d, nil
+}
+
+// writeError turns err into a fiber error, logging unexpected ones
+func (h *Handler) writeError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, webhook.ErrSubscriptionNotFound), errors.Is(err, webhook.ErrDeliveryNotFound):
+		return fiber.NewError(fiber.StatusNotFound, capitalize(err.Error()))
+	case errors.Is(err, webhook.ErrDeliveryPending):
+		return fiber.NewError(fiber.StatusConflict, capitalize(err.Error()))
+	}
+	logger.FromContext(c.UserContext()).Errorf("%s: %v", message, err)
+	return fiber.NewError(fiber.StatusInternalServerError, message)
+}
+
+// signedPartner returns the partner whose signed request is served, or ""
+// for an admin
+func signedPartner(c *fiber.Ctx) string {
+	partnerID, _ := c.Locals("partner_id").(string)
+	return partnerID
+}
+
+// owns reports whether the caller may manage s: admins manage every
+// subscription and partners their own
+func owns(c *fiber.Ctx, s *webhook.Subscription) bool {
+	partnerID := signedPartner(c)
+	return partnerID == "" || partnerID == s.PartnerID
+}
+
+// newSecret generates a subscription secret
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// normalizeEventTypes lower-cases and de-duplicates subscribed event types
+func normalizeEventTypes(types []string) []string {
+	seen := make(map[string]bool, len(types))
+	normalized := make([]string, 0, len(types))
+	for _, t := range types {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		normalized = append(normalized, t)
+	}
+	return normalized
+}
+
+// capitalize turns an error message into a response message
+func capitalize(message string) string {
+	if message == "" {
+		return message
+	}
+	return strings.ToUpper(message[:1]) + message[1:]
+}
+
+// pagination parses the limit and offset of a list request
+func pagination(c *fiber.Ctx) (int, int) {
+	limit := 50
+	offset := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+	return limit, offset
+}
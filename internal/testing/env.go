@@ -0,0 +1,329 @@
+// Package testing runs services end to end for tests: it starts Postgres,
+// Redis and RabbitMQ in containers, applies every service's migrations, and
+// boots services in-process on random ports, with helpers to call their
+// APIs and watch the events they publish.
+package testing
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	stdtesting "testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/modules/redis"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/onichange/pos-system/migrations"
+	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/messaging"
+)
+
+// Container images, matching deployments/docker/docker-compose.yml
+const (
+	postgresImage = "postgres:15-alpine"
+	redisImage    = "redis:7-alpine"
+	rabbitMQImage = "rabbitmq:3-management-alpine"
+)
+
+// startupTimeout bounds how long each container may take to start
+const startupTimeout = 2 * time.Minute
+
+// Test credentials; the containers are only reachable from the host
+const (
+	dbName             = "onichange"
+	dbUser             = "postgres"
+	dbPassword         = "postgres"
+	accessTokenSecret  = "e2e-access-secret-0123456789abcdef"
+	refreshTokenSecret = "e2e-refresh-secret-0123456789abcdef"
+)
+
+// Env is a set of backing services shared by the services a test boots. All
+// services use the one database, as in docker-compose.
+type Env struct {
+	DB          *pgxpool.Pool
+	RedisHost   string
+	RedisPort   string
+	RabbitMQURL string
+
+	root     string // Repository root, holding deployments
+	dbHost   string
+	dbPort   string
+	log      *logger.Logger
+	jwt      *auth.JWTManager
+	mu       sync.Mutex
+	services map[string]*Service
+}
+
+// Start starts the backing services and applies every migration. It skips
+// the test or benchmark in -short mode and where Docker is not available.
+// Everything is torn down when it finishes.
+func Start(t stdtesting.TB) *Env {
+	t.Helper()
+	if stdtesting.Short() {
+		t.Skip("Skipping end-to-end test")
+	}
+	skipWithoutDocker(t)
+
+	root, err := repositoryRoot()
+	if err != nil {
+		t.Fatalf("Failed to find repository root: %v", err)
+	}
+
+	ctx := context.Background()
+	env := &Env{
+		root:     root,
+		log:      logger.New("test"),
+		services: make(map[string]*Service),
+	}
+
+	pg, err := postgres.Run(ctx, postgresImage,
+		postgres.WithDatabase(dbName),
+		postgres.WithUsername(dbUser),
+		postgres.WithPassword(dbPassword),
+		postgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, pg)
+	if err != nil {
+		t.Fatalf("Failed to start Postgres: %v", err)
+	}
+	env.dbHost, env.dbPort = endpoint(t, pg, "5432/tcp")
+
+	rd, err := redis.Run(ctx, redisImage)
+	testcontainers.CleanupContainer(t, rd)
+	if err != nil {
+		t.Fatalf("Failed to start Redis: %v", err)
+	}
+	env.RedisHost, env.RedisPort = endpoint(t, rd, "6379/tcp")
+
+	mq, err := testcontainers.Run(ctx, rabbitMQImage,
+		testcontainers.WithExposedPorts("5672/tcp"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("Server startup complete").WithStartupTimeout(startupTimeout),
+			wait.ForListeningPort("5672/tcp").WithStartupTimeout(startupTimeout),
+		),
+	)
+	testcontainers.CleanupContainer(t, mq)
+	if err != nil {
+		t.Fatalf("Failed to start RabbitMQ: %v", err)
+	}
+	env.RabbitMQURL = "amqp://guest:guest@" + net.JoinHostPort(endpoint(t, mq, "5672/tcp")) + "/"
+
+	dsn, err := pg.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to get Postgres connection string: %v", err)
+	}
+	env.DB, err = pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("Failed to connect to Postgres: %v", err)
+	}
+	t.Cleanup(env.DB.Close)
+
+	env.migrate(t)
+
+	// Declare the exchanges and queues the services publish to and consume.
+	// Kafka topics are left out; no broker runs.
+	cfg := env.Config(t, "api-gateway")
+	topology, err := messaging.LoadTopology(cfg.Messaging.TopologyFile)
+	if err != nil {
+		t.Fatalf("Failed to load messaging topology: %v", err)
+	}
+	client, err := messaging.NewRabbitMQClient(env.RabbitMQURL, env.log)
+	if err != nil {
+		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
+	}
+	_, err = client.ApplyTopology(topology)
+	client.Close()
+	if err != nil {
+		t.Fatalf("Failed to apply messaging topology: %v", err)
+	}
+
+	env.jwt = auth.NewJWTManager(accessTokenSecret, refreshTokenSecret,
+		cfg.JWT.AccessTokenExpiry, cfg.JWT.RefreshTokenExpiry, cfg.JWT.Issuer)
+	return env
+}
+
+// skipWithoutDocker skips t where Docker does not answer, as
+// testcontainers.SkipIfProviderIsNotHealthy does for tests but not benchmarks
+func skipWithoutDocker(t stdtesting.TB) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Skipf("Docker is not available: %v", r)
+		}
+	}()
+
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err == nil {
+		err = provider.Health(context.Background())
+	}
+	if err != nil {
+		t.Skipf("Docker is not available: %v", err)
+	}
+}
+
+// migrate applies every directory of migrations to the database
+func (e *Env) migrate(t stdtesting.TB) {
+	t.Helper()
+	ctx := context.Background()
+
+	entries, err := fs.ReadDir(migrations.FS, ".")
+	if err != nil {
+		t.Fatalf("Failed to list migrations: %v", err)
+	}
+	for _, entry := range entries {
+		loaded, err := database.LoadMigrations(migrations.FS, entry.Name())
+		if err != nil {
+			t.Fatalf("Failed to load %s migrations: %v", entry.Name(), err)
+		}
+		if _, err := database.NewMigrator(e.DB, entry.Name()).Up(ctx, loaded); err != nil {
+			t.Fatalf("Failed to apply %s migrations: %v", entry.Name(), err)
+		}
+	}
+}
+
+// Config returns a service's configuration pointing at the environment: its
+// database, Redis and RabbitMQ, a random port, and the services started so
+// far. Services started later are not known to it.
+func (e *Env) Config(t stdtesting.TB, service string) *config.Config {
+	t.Helper()
+
+	cfg, err := config.LoadService(service)
+	if err != nil {
+		t.Fatalf("Failed to load %s config: %v", service, err)
+	}
+
+	cfg.Server.Host = "127.0.0.1"
+	cfg.Server.Port = "0"
+
+	cfg.Database.Host = e.dbHost
+	cfg.Database.Port = e.dbPort
+	cfg.Database.User = dbUser
+	cfg.Database.Password = dbPassword
+	cfg.Database.DBName = dbName
+	cfg.Database.SSLMode = "disable"
+
+	cfg.Redis.Host = e.RedisHost
+	cfg.Redis.Port = e.RedisPort
+	cfg.Redis.Password = ""
+
+	cfg.Messaging.RabbitMQURL = e.RabbitMQURL
+	cfg.Messaging.KafkaBrokers = nil
+	cfg.Messaging.TopologyFile = filepath.Join(e.root, "deployments", "messaging", "topology.yaml")
+
+	cfg.JWT.AccessTokenSecret = accessTokenSecret
+	cfg.JWT.RefreshTokenSecret = refreshTokenSecret
+	cfg.JWT.PreviousAccessTokenSecrets = nil
+	cfg.JWT.PreviousRefreshTokenSecrets = nil
+
+	// Services of the environment call each other in plaintext
+	cfg.GRPC.Insecure = true
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for name, svc := range e.services {
+		if url := serviceURL(&cfg.Services, name); url != nil {
+			*url = svc.URL
+		}
+		if target := serviceGRPCTarget(&cfg.Services, name); target != nil && svc.GRPCTarget != "" {
+			*target = svc.GRPCTarget
+		}
+	}
+	return cfg
+}
+
+// Token returns an access token for a user with the given roles
+func (e *Env) Token(t *stdtesting.T, userID uuid.UUID, roles ...string) string {
+	t.Helper()
+	if len(roles) == 0 {
+		roles = []string{"user"}
+	}
+	pair, err := e.jwt.GenerateTokenPair(userID.String(), userID.String()+"@example.com", roles, "")
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+	return pair.AccessToken
+}
+
+// serviceURL returns the field holding a service's URL
+func serviceURL(s *config.ServicesConfig, service string) *string {
+	urls := map[string]*string{
+		"order-service":        &s.OrderServiceURL,
+		"user-service":         &s.UserServiceURL,
+		"store-service":        &s.StoreServiceURL,
+		"payment-service":      &s.PaymentServiceURL,
+		"inventory-service":    &s.InventoryServiceURL,
+		"notification-service": &s.NotificationServiceURL,
+		"catalog-service":      &s.CatalogServiceURL,
+		"loyalty-service":      &s.LoyaltyServiceURL,
+		"promotion-service":    &s.PromotionServiceURL,
+		"analytics-service":    &s.AnalyticsServiceURL,
+		"receipt-service":      &s.ReceiptServiceURL,
+		"shift-service":        &s.ShiftServiceURL,
+		"tax-service":          &s.TaxServiceURL,
+		"procurement-service":  &s.ProcurementServiceURL,
+		"webhook-service":      &s.WebhookServiceURL,
+		"search-service":       &s.SearchServiceURL,
+		"sync-service":         &s.SyncServiceURL,
+	}
+	return urls[service]
+}
+
+// serviceGRPCTarget returns the field holding a service's gRPC target, for
+// the services serving a gRPC API
+func serviceGRPCTarget(s *config.ServicesConfig, service string) *string {
+	targets := map[string]*string{
+		"order-service":     &s.OrderGRPCTarget,
+		"payment-service":   &s.PaymentGRPCTarget,
+		"user-service":      &s.UserGRPCTarget,
+		"inventory-service": &s.InventoryGRPCTarget,
+		"catalog-service":   &s.CatalogGRPCTarget,
+	}
+	return targets[service]
+}
+
+// endpoint returns the host and mapped port of a container port, e.g. "5432/tcp"
+func endpoint(t stdtesting.TB, c testcontainers.Container, port string) (string, string) {
+	t.Helper()
+
+	addr, err := c.PortEndpoint(context.Background(), nat.Port(port), "")
+	if err != nil {
+		t.Fatalf("Failed to get endpoint of port %s: %v", port, err)
+	}
+	host, mapped, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("Failed to parse endpoint %s: %v", addr, err)
+	}
+	return host, mapped
+}
+
+// repositoryRoot finds the directory holding go.mod, from the test's
+// working directory up
+func repositoryRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no go.mod above the working directory")
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,110 @@
+package testing
+
+import (
+	"encoding/json"
+	"sync"
+	stdtesting "testing"
+	"time"
+
+	"github.com/streadway/amqp"
+
+	"github.com/onichange/pos-system/pkg/messagequeue"
+)
+
+// eventsExchange is the topic exchange services publish domain events to
+const eventsExchange = "events"
+
+// Events records the domain events published with matching routing keys
+type Events struct {
+	mu     sync.Mutex
+	events []messagequeue.Event
+	added  chan struct{}
+}
+
+// Subscribe records the events published from now on whose routing keys
+// match any of the patterns, e.g. "order.*" or "#"
+func (e *Env) Subscribe(t *stdtesting.T, patterns ...string) *Events {
+	t.Helper()
+
+	conn, err := amqp.Dial(e.RabbitMQURL)
+	if err != nil {
+		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	ch, err := conn.Channel()
+	if err != nil {
+		t.Fatalf("Failed to open channel: %v", err)
+	}
+
+	// A server-named queue that goes away with the connection
+	q, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		t.Fatalf("Failed to declare queue: %v", err)
+	}
+	for _, pattern := range patterns {
+		if err := ch.QueueBind(q.Name, pattern, eventsExchange, false, nil); err != nil {
+			t.Fatalf("Failed to bind %s: %v", pattern, err)
+		}
+	}
+	msgs, err := ch.Consume(q.Name, "", true, true, false, false, nil)
+	if err != nil {
+		t.Fatalf("Failed to consume events: %v", err)
+	}
+
+	events := &Events{added: make(chan struct{}, 1)}
+	go func() {
+		for msg := range msgs {
+			var event messagequeue.Event
+			if err := json.Unmarshal(msg.Body, &event); err != nil {
+				continue
+			}
+			events.mu.Lock()
+			events.events = append(events.events, event)
+			events.mu.Unlock()
+
+			select {
+			case events.added <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return events
+}
+
+// All returns the events recorded so far
+func (ev *Events) All() []messagequeue.Event {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	return append([]messagequeue.Event(nil), ev.events...)
+}
+
+// Wait returns the first recorded event of the type that match accepts,
+// waiting up to timeout for it to be published. A nil match accepts any
+// event of the type.
+func (ev *Events) Wait(t *stdtesting.T, eventType string, timeout time.Duration, match func(messagequeue.Event) bool) messagequeue.Event {
+	t.Helper()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		for _, event := range ev.All() {
+			if event.Type == eventType && (match == nil || match(event)) {
+				return event
+			}
+		}
+		select {
+		case <-ev.added:
+		case <-deadline.C:
+			t.Fatalf("No %s event within %s; got %d other events", eventType, timeout, len(ev.All()))
+		}
+	}
+}
+
+// Publish publishes a domain event as a service would
+func (e *Env) Publish(t *stdtesting.T, eventType, routingKey string, data map[string]interface{}) {
+	t.Helper()
+	if err := e.broker(t).PublishEvent(eventType, routingKey, data); err != nil {
+		t.Fatalf("Failed to publish %s: %v", eventType, err)
+	}
+}
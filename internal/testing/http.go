@@ -0,0 +1,81 @@
+package testing
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	stdtesting "testing"
+	"time"
+)
+
+// requestTimeout bounds each request a test sends
+const requestTimeout = 10 * time.Second
+
+// Response is a service's answer to a request
+type Response struct {
+	Status int
+	Body   []byte
+}
+
+// Decode decodes the response's JSON body into v
+func (r *Response) Decode(t *stdtesting.T, v any) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("Failed to decode response %s: %v", r.Body, err)
+	}
+}
+
+// Request sends body, when not nil, as JSON to the service, authenticated
+// with token when it is not empty
+func (s *Service) Request(t *stdtesting.T, method, path string, body any, token string) *Response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Failed to encode request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s %s failed: %v", s.Name, method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read %s response: %v", s.Name, err)
+	}
+	return &Response{Status: resp.StatusCode, Body: data}
+}
+
+// Expect sends a request like Request, failing the test unless it is
+// answered with status. The body, when out is not nil, is decoded into out.
+func (s *Service) Expect(t *stdtesting.T, status int, method, path string, body any, token string, out any) *Response {
+	t.Helper()
+
+	resp := s.Request(t, method, path, body, token)
+	if resp.Status != status {
+		t.Fatalf("%s %s %s returned %d, want %d: %s", s.Name, method, path, resp.Status, status, resp.Body)
+	}
+	if out != nil {
+		resp.Decode(t, out)
+	}
+	return resp
+}
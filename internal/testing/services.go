@@ -0,0 +1,530 @@
+package testing
+
+import (
+	"context"
+	"net"
+	stdtesting "testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+
+	"github.com/onichange/pos-system/internal/infrastructure/catalogclient"
+	"github.com/onichange/pos-system/internal/infrastructure/exportclient"
+	"github.com/onichange/pos-system/internal/infrastructure/inventoryclient"
+	"github.com/onichange/pos-system/internal/infrastructure/orderclient"
+	"github.com/onichange/pos-system/internal/infrastructure/outbox"
+	"github.com/onichange/pos-system/internal/infrastructure/paymentclient"
+	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	"github.com/onichange/pos-system/internal/infrastructure/storeclient"
+	"github.com/onichange/pos-system/internal/infrastructure/unitofwork"
+	cataloggrpc "github.com/onichange/pos-system/internal/interfaces/grpc/catalog"
+	inventorygrpc "github.com/onichange/pos-system/internal/interfaces/grpc/inventory"
+	ordergrpc "github.com/onichange/pos-system/internal/interfaces/grpc/order"
+	paymentgrpc "github.com/onichange/pos-system/internal/interfaces/grpc/payment"
+	"github.com/onichange/pos-system/internal/interfaces/http/catalog"
+	"github.com/onichange/pos-system/internal/interfaces/http/inventory"
+	"github.com/onichange/pos-system/internal/interfaces/http/offline"
+	"github.com/onichange/pos-system/internal/interfaces/http/order"
+	"github.com/onichange/pos-system/internal/interfaces/http/payment"
+	"github.com/onichange/pos-system/internal/interfaces/http/procurement"
+	storehttp "github.com/onichange/pos-system/internal/interfaces/http/store"
+	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/database"
+	appgrpc "github.com/onichange/pos-system/pkg/grpc"
+	"github.com/onichange/pos-system/pkg/i18n"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+	"github.com/onichange/pos-system/pkg/middleware"
+	"github.com/onichange/pos-system/pkg/payments/provider"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/saga"
+	"github.com/onichange/pos-system/pkg/tenant"
+	catalogpb "github.com/onichange/pos-system/proto/catalog"
+	inventorypb "github.com/onichange/pos-system/proto/inventory"
+	orderpb "github.com/onichange/pos-system/proto/order"
+	paymentpb "github.com/onichange/pos-system/proto/payment"
+)
+
+// shutdownTimeout bounds how long a service may take to stop
+const shutdownTimeout = 10 * time.Second
+
+// Service is a service running in the test process
+type Service struct {
+	Name       string
+	URL        string // e.g. http://127.0.0.1:41234
+	GRPCTarget string // e.g. 127.0.0.1:41235; empty when it serves no gRPC API
+
+	tokens *auth.JWTManager // Signs the service tokens of calls to the gRPC API
+}
+
+// Serve runs app as the named service on a random port until the test
+// finishes. Configs made afterwards point the service's URL at it.
+func (e *Env) Serve(t *stdtesting.T, name string, app *fiber.App) *Service {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen for %s: %v", name, err)
+	}
+	go app.Listener(listener)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		app.ShutdownWithContext(ctx)
+	})
+
+	svc := &Service{Name: name, URL: "http://" + listener.Addr().String()}
+	e.mu.Lock()
+	e.services[name] = svc
+	e.mu.Unlock()
+	return svc
+}
+
+// ServeGRPC serves a service's internal gRPC API on a random port until the
+// test finishes, with the interceptors of cmd services. Configs made
+// afterwards point the service's gRPC target at it.
+func (e *Env) ServeGRPC(t *stdtesting.T, svc *Service, register func(*grpc.Server)) {
+	t.Helper()
+
+	cfg := e.Config(t, svc.Name)
+	tokens := jwtManager(cfg)
+	server, err := appgrpc.NewServer("127.0.0.1:0", cfg.GRPC, cfg.Tenant, tokens, e.log)
+	if err != nil {
+		t.Fatalf("Failed to create gRPC server for %s: %v", svc.Name, err)
+	}
+	register(server.GetServer())
+	go server.Start()
+	t.Cleanup(server.Stop)
+
+	e.mu.Lock()
+	svc.GRPCTarget = server.Addr()
+	svc.tokens = tokens
+	e.mu.Unlock()
+}
+
+// Dial connects to a service's gRPC API as other services do, until the
+// test finishes
+func (s *Service) Dial(t *stdtesting.T) *grpc.ClientConn {
+	t.Helper()
+	if s.GRPCTarget == "" {
+		t.Fatalf("%s serves no gRPC API", s.Name)
+	}
+	conn, err := appgrpc.Dial(s.GRPCTarget, config.GRPCConfig{Timeout: 10 * time.Second, MaxAttempts: 1, Insecure: true}, s.tokens)
+	if err != nil {
+		t.Fatalf("Failed to dial %s: %v", s.Name, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// Service returns a started service, or nil
+func (e *Env) Service(name string) *Service {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.services[name]
+}
+
+// newApp creates a service's Fiber app with the middleware its routes rely on
+func newApp() *fiber.App {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(middleware.RequestID())
+	app.Use(i18n.Middleware())
+	return app
+}
+
+// jwtManager returns the JWT manager services of the environment verify
+// tokens with
+func jwtManager(cfg *config.Config) *auth.JWTManager {
+	return auth.NewJWTManager(
+		cfg.JWT.AccessTokenSecret,
+		cfg.JWT.RefreshTokenSecret,
+		cfg.JWT.AccessTokenExpiry,
+		cfg.JWT.RefreshTokenExpiry,
+		cfg.JWT.Issuer,
+	)
+}
+
+// broker connects to the environment's RabbitMQ until the test finishes
+func (e *Env) broker(t *stdtesting.T) *messagequeue.RabbitMQ {
+	t.Helper()
+	broker, err := messagequeue.NewRabbitMQ(e.RabbitMQURL, e.log)
+	if err != nil {
+		t.Fatalf("Failed to connect to RabbitMQ: %v", err)
+	}
+	t.Cleanup(func() { broker.Close() })
+	return broker
+}
+
+// outbox returns a unit of work whose transactions record the events of a
+// service in the outbox table, relaying them to the environment's RabbitMQ
+// until the test finishes
+func (e *Env) outbox(t *stdtesting.T, cfg *config.Config) *unitofwork.UnitOfWork {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		outbox.NewRelay(e.DB, outbox.DialRabbitMQ(e.RabbitMQURL, e.log), cfg.Outbox).Run(ctx, e.log)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return unitofwork.New(e.DB, nil)
+}
+
+// StartCatalog starts catalog-service with the routes of
+// cmd/catalog-service and its gRPC API
+func (e *Env) StartCatalog(t *stdtesting.T) *Service {
+	t.Helper()
+
+	catalogRepo := repository.NewCatalogRepository(e.DB)
+	catalogHandler := catalog.NewHandler(catalogRepo, e.broker(t))
+
+	app := newApp()
+	api := app.Group("/api/v1")
+	api.Get("/categories", catalogHandler.GetCategories)
+	api.Post("/categories", catalogHandler.CreateCategory)
+	api.Get("/products", catalogHandler.GetProducts)
+	api.Get("/products/:id", catalogHandler.GetProductByID)
+	api.Post("/products", catalogHandler.CreateProduct)
+	api.Post("/products/:id/variants", catalogHandler.CreateVariant)
+	api.Get("/variants/lookup", catalogHandler.LookupVariant)
+	api.Get("/stores/:storeId/prices", catalogHandler.GetPriceList)
+	api.Put("/stores/:storeId/prices/:variantId", catalogHandler.SetPrice)
+	api.Delete("/stores/:storeId/prices/:variantId", catalogHandler.DeletePrice)
+
+	internal := app.Group("/internal/v1", tenant.Middleware(e.Config(t, "catalog-service").Tenant))
+	internal.Get("/variants/lookup", catalogHandler.LookupVariant)
+	internal.Get("/stores/:storeId/export", catalogHandler.ExportStore)
+
+	svc := e.Serve(t, "catalog-service", app)
+	e.ServeGRPC(t, svc, func(s *grpc.Server) {
+		catalogpb.RegisterPricingServiceServer(s, cataloggrpc.NewServer(catalogRepo))
+	})
+	return svc
+}
+
+// StartInventory starts inventory-service with the routes of
+// cmd/inventory-service and its gRPC API, publishing its events to RabbitMQ
+// through the outbox and sweeping expired reservations in the background
+func (e *Env) StartInventory(t *stdtesting.T) *Service {
+	t.Helper()
+	cfg := e.Config(t, "inventory-service")
+
+	uow := e.outbox(t, cfg)
+	inTx := func(ctx context.Context, fn func(tx *inventory.Tx) error) error {
+		return uow.Do(ctx, func(tx *unitofwork.Tx) error {
+			return fn(&inventory.Tx{Inventory: tx.Inventory(), Reservations: tx.Reservations(), Counts: tx.Counts(), Events: tx.Events()})
+		})
+	}
+	inventoryRepo := repository.NewInventoryRepository(e.DB)
+	inventoryHandler := inventory.NewHandler(inventoryRepo, repository.NewStockReservationRepository(e.DB, e.DB),
+		cfg.Inventory.ReservationTTL, inTx)
+	transferHandler := inventory.NewTransferHandler(repository.NewStockTransferRepository(e.DB, e.DB))
+	countHandler := inventory.NewCountHandler(repository.NewStockCountRepository(e.DB, e.DB), inventoryHandler)
+	importHandler := inventory.NewImportHandler(repository.NewStockImportRepository(e.DB, database.NewQueryExecutor(e.DB)))
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		inventoryHandler.RunSweeper(ctx, cfg.Inventory.SweepInterval, e.log)
+	}()
+	t.Cleanup(func() {
+		stop()
+		<-done
+	})
+
+	app := newApp()
+	api := app.Group("/api/v1")
+	api.Get("/inventory/transfers", transferHandler.ListTransfers)
+	api.Post("/inventory/transfers", transferHandler.CreateTransfer)
+	api.Get("/inventory/transfers/:id", transferHandler.GetTransfer)
+	api.Post("/inventory/transfers/:id/dispatch", transferHandler.DispatchTransfer)
+	api.Post("/inventory/transfers/:id/receive", transferHandler.ReceiveTransfer)
+	api.Post("/inventory/transfers/:id/cancel", transferHandler.CancelTransfer)
+	api.Get("/inventory/counts", countHandler.ListCounts)
+	api.Post("/inventory/counts", countHandler.CreateCount)
+	api.Get("/inventory/counts/:id", countHandler.GetCount)
+	api.Post("/inventory/counts/:id/lines", countHandler.RecordCounts)
+	api.Get("/inventory/counts/:id/variances", countHandler.GetVariances)
+	api.Post("/inventory/counts/:id/reconcile", countHandler.ReconcileCount)
+	api.Post("/inventory/counts/:id/cancel", countHandler.CancelCount)
+	api.Get("/inventory/export", inventoryHandler.Export)
+	api.Post("/inventory/import", importHandler.Import)
+	api.Get("/inventory/:id", inventoryHandler.GetInventory)
+	api.Get("/inventory/product/:product_id", inventoryHandler.GetInventoryByProduct)
+	api.Get("/inventory/store/:store_id", inventoryHandler.GetInventoryByStore)
+	api.Post("/inventory", inventoryHandler.CreateInventory)
+	api.Post("/inventory/:id/adjust", inventoryHandler.AdjustInventory)
+	api.Post("/inventory/reserve", inventoryHandler.ReserveStock)
+	api.Post("/inventory/reserve-batch", inventoryHandler.ReserveBatch)
+	api.Post("/inventory/release", inventoryHandler.ReleaseStock)
+	api.Get("/inventory/reservations/:id", inventoryHandler.GetReservation)
+	api.Post("/inventory/reservations/:id/commit", inventoryHandler.CommitReservation)
+	api.Post("/inventory/reservations/:id/release", inventoryHandler.ReleaseReservation)
+
+	internal := app.Group("/internal/v1", tenant.Middleware(cfg.Tenant))
+	internal.Get("/stores/:storeId/export", inventoryHandler.ExportStore)
+	internal.Get("/inventory/low-stock", inventoryHandler.ListLowStock)
+	internal.Post("/inventory/reservations", inventoryHandler.ReserveStock)
+	internal.Post("/inventory/reservations/batch", inventoryHandler.ReserveBatch)
+	internal.Post("/inventory/reservations/:id/commit", inventoryHandler.CommitReservation)
+	internal.Post("/inventory/reservations/:id/release", inventoryHandler.ReleaseReservation)
+
+	svc := e.Serve(t, "inventory-service", app)
+	e.ServeGRPC(t, svc, func(s *grpc.Server) {
+		inventorypb.RegisterInventoryServiceServer(s, inventorygrpc.NewServer(inventoryHandler, inventoryRepo))
+	})
+	return svc
+}
+
+// StartPayment starts payment-service with the payment routes of
+// cmd/payment-service, its internal API and its gRPC API, charging through
+// the simulated provider and publishing its events to RabbitMQ through the
+// outbox
+func (e *Env) StartPayment(t *stdtesting.T) *Service {
+	t.Helper()
+	cfg := e.Config(t, "payment-service")
+
+	uow := e.outbox(t, cfg)
+	inTx := func(ctx context.Context, fn func(tx *payment.Tx) error) error {
+		return uow.Do(ctx, func(tx *unitofwork.Tx) error {
+			return fn(&payment.Tx{Payments: tx.Payments(), Events: tx.Events()})
+		})
+	}
+	paymentRepo := repository.NewPaymentRepository(e.DB)
+	paymentHandler := payment.NewHandler(paymentRepo, provider.NewSimulated(),
+		performance.NewBulkhead(10), nil, inTx)
+
+	app := newApp()
+	protected := app.Group("/api/v1", middleware.JWTAuth(jwtManager(cfg)), tenant.Middleware(cfg.Tenant))
+	protected.Get("/payments", paymentHandler.GetUserPayments)
+	protected.Get("/payments/export", middleware.RequireRole("admin"), paymentHandler.Export)
+	protected.Get("/payments/:id", paymentHandler.GetPayment)
+	protected.Get("/payments/order/:order_id", paymentHandler.GetPaymentsByOrder)
+	protected.Post("/payments", paymentHandler.ProcessPayment)
+
+	internal := app.Group("/internal/v1", middleware.ServiceAuth(jwtManager(cfg)), tenant.Middleware(cfg.Tenant))
+	internal.Post("/payments", paymentHandler.Charge)
+	internal.Get("/payments/:id", paymentHandler.GetPaymentInternal)
+	internal.Post("/payments/:id/refund", paymentHandler.RefundPayment)
+
+	svc := e.Serve(t, "payment-service", app)
+	e.ServeGRPC(t, svc, func(s *grpc.Server) {
+		paymentpb.RegisterPaymentServiceServer(s, paymentgrpc.NewServer(paymentRepo))
+	})
+	return svc
+}
+
+// StartOrder starts order-service with the routes of cmd/order-service and
+// its gRPC API, publishing its events to RabbitMQ through the outbox. Items
+// are priced by catalog-service when it was started first; promotions,
+// taxes, loyalty and shifts are left out. Orders are checked out when
+// inventory-service and payment-service were started first. Orders are kept
+// in the store its orders config selects, and carts in the environment's
+// Redis.
+func (e *Env) StartOrder(t *stdtesting.T) *Service {
+	t.Helper()
+	cfg := e.Config(t, "order-service")
+
+	var prices order.PriceResolver
+	if e.Service("catalog-service") != nil {
+		conn, err := appgrpc.Dial(cfg.Services.CatalogGRPCTarget, cfg.GRPC, jwtManager(cfg))
+		if err != nil {
+			t.Fatalf("Failed to dial catalog-service: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		prices = catalogclient.NewClient(conn)
+	}
+
+	jobs := performance.NewNamedWorkerPool("order-jobs", cfg.Workers)
+	jobs.Start()
+	t.Cleanup(jobs.Stop)
+
+	uow := e.outbox(t, cfg)
+	var orderRepo repository.OrderStore = repository.NewOrderRepository(e.DB, nil)
+	if cfg.Orders.Store == "events" {
+		orderRepo = repository.NewEventSourcedOrderRepository(e.DB, e.DB, nil, cfg.Orders.SnapshotEvery)
+		uow.WithEventSourcedOrders(cfg.Orders.SnapshotEvery)
+	}
+	inTx := func(ctx context.Context, fn func(tx *order.Tx) error) error {
+		return uow.Do(ctx, func(tx *unitofwork.Tx) error {
+			return fn(&order.Tx{Orders: tx.Orders(), Events: tx.Events()})
+		})
+	}
+
+	orderHandler := order.NewHandler(orderRepo, prices, nil, nil, nil, nil, inTx, nil, jobs)
+
+	if e.Service("inventory-service") != nil && e.Service("payment-service") != nil {
+		orchestrator := saga.NewOrchestrator(saga.NewPostgresStore(e.DB), cfg.Saga)
+		stock := inventoryclient.NewReservationClient(cfg.Services.InventoryServiceURL, cfg.Proxy)
+		payments := paymentclient.NewClient(cfg.Services.PaymentServiceURL, cfg.Proxy, jwtManager(cfg))
+		if err := orderHandler.EnableCheckout(orchestrator, stock, payments, cfg.Orders); err != nil {
+			t.Fatalf("Failed to register checkout saga: %v", err)
+		}
+	}
+
+	carts := redis.NewClient(&redis.Options{Addr: net.JoinHostPort(e.RedisHost, e.RedisPort)})
+	t.Cleanup(func() { carts.Close() })
+	orderHandler.EnableCarts(repository.NewCartRepository(carts, cfg.Orders.CartTTL))
+
+	app := newApp()
+	protected := app.Group("/api/v1", middleware.JWTAuth(jwtManager(cfg)), tenant.Middleware(cfg.Tenant))
+	protected.Get("/orders", orderHandler.GetOrders)
+	protected.Get("/orders/export", middleware.RequireRole("admin"), orderHandler.Export) // Accounting exports of every order
+	protected.Get("/orders/:id", orderHandler.GetOrderByID)
+	protected.Post("/orders", orderHandler.CreateOrder)
+	protected.Put("/orders/:id", orderHandler.UpdateOrder)
+	protected.Delete("/orders/:id", orderHandler.DeleteOrder)
+	protected.Patch("/orders/:id/status", middleware.RequireRole("admin"), orderHandler.UpdateOrderStatus)
+	protected.Put("/orders/:id/status", middleware.RequireRole("admin"), orderHandler.UpdateOrderStatus)
+	protected.Get("/orders/:id/status/history", middleware.RequireRole("admin"), orderHandler.GetOrderStatusHistory)
+	protected.Get("/orders/:id/history", middleware.RequireRole("admin"), orderHandler.GetOrderHistory)
+	protected.Post("/orders/:id/checkout", orderHandler.CheckoutOrder)
+	protected.Post("/carts", orderHandler.CreateCart)
+	protected.Get("/carts/:id", orderHandler.GetCart)
+	protected.Delete("/carts/:id", orderHandler.DeleteCart)
+	protected.Post("/carts/:id/items", orderHandler.AddCartItem)
+	protected.Put("/carts/:id/items/:productId", orderHandler.UpdateCartItem)
+	protected.Delete("/carts/:id/items/:productId", orderHandler.RemoveCartItem)
+	protected.Put("/carts/:id/discount", orderHandler.ApplyCartDiscount)
+	protected.Delete("/carts/:id/discount", orderHandler.RemoveCartDiscount)
+	protected.Post("/carts/:id/checkout", orderHandler.CheckoutCart)
+
+	internal := app.Group("/internal/v1", tenant.Middleware(cfg.Tenant))
+	internal.Get("/orders/:id", orderHandler.GetOrderInternal)
+	internal.Post("/orders/offline", orderHandler.ImportOfflineOrder)
+
+	svc := e.Serve(t, "order-service", app)
+	e.ServeGRPC(t, svc, func(s *grpc.Server) {
+		orderpb.RegisterOrderServiceServer(s, ordergrpc.NewServer(orderRepo))
+	})
+	return svc
+}
+
+// StartStore starts store-service with the store and export routes of
+// cmd/store-service, building exports in the background. Exports gather
+// data from the catalog, inventory and order services, which should be
+// started first, and look up customers in customers.
+func (e *Env) StartStore(t *stdtesting.T, customers storehttp.CustomerDirectory) *Service {
+	t.Helper()
+	cfg := e.Config(t, "store-service")
+
+	storeRepo := repository.NewStoreRepository(e.DB)
+	storeHandler := storehttp.NewHandler(storeRepo)
+	exportHandler := storehttp.NewExportHandler(storeRepo, repository.NewExportRepository(e.DB), storehttp.ExportSources{
+		Catalog:   exportclient.NewClient("catalog-service", cfg.Services.CatalogServiceURL, cfg.Proxy),
+		Inventory: exportclient.NewClient("inventory-service", cfg.Services.InventoryServiceURL, cfg.Proxy),
+		Orders:    exportclient.NewClient("order-service", cfg.Services.OrderServiceURL, cfg.Proxy),
+		Customers: customers,
+	}, cfg.Exports)
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		exportHandler.RunExporter(ctx, e.log)
+	}()
+	t.Cleanup(func() {
+		stop()
+		<-done
+	})
+
+	app := newApp()
+	api := app.Group("/api/v1", tenant.Middleware(cfg.Tenant))
+	api.Get("/stores/:id", storeHandler.GetStoreByID)
+	api.Post("/stores", storeHandler.CreateStore)
+	api.Get("/stores/:id/exports", exportHandler.ListExports)
+	api.Post("/stores/:id/exports", exportHandler.CreateExport)
+	api.Get("/stores/:id/exports/:exportId", exportHandler.GetExport)
+	api.Get("/stores/:id/exports/:exportId/download", exportHandler.DownloadExport)
+
+	internal := app.Group("/internal/v1", tenant.Middleware(cfg.Tenant))
+	internal.Get("/stores/:id", storeHandler.GetStoreByID)
+
+	return e.Serve(t, "store-service", app)
+}
+
+// StartProcurement starts procurement-service with the supplier and
+// purchase order routes of cmd/procurement-service, reordering low stock in
+// the background and requesting notifications through the outbox. Stock is
+// reordered from inventory-service and delivered to stores of
+// store-service, which should be started first.
+func (e *Env) StartProcurement(t *stdtesting.T) *Service {
+	t.Helper()
+	cfg := e.Config(t, "procurement-service")
+
+	uow := e.outbox(t, cfg)
+	inTx := func(ctx context.Context, fn func(tx *procurement.Tx) error) error {
+		return uow.Do(ctx, func(tx *unitofwork.Tx) error {
+			return fn(&procurement.Tx{Procurement: tx.Procurement(), Events: tx.Events()})
+		})
+	}
+	procurementRepo := repository.NewProcurementRepository(e.DB)
+	stores := storeclient.NewClient(cfg.Services.StoreServiceURL, cfg.Proxy)
+	procurementHandler := procurement.NewHandler(procurementRepo, stores, nil)
+	reorderer := procurement.NewReorderer(
+		procurementRepo,
+		inventoryclient.NewLowStockClient(cfg.Services.InventoryServiceURL, cfg.Proxy),
+		stores,
+		inTx,
+	)
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reorderer.Run(ctx, cfg.Procurement.ReorderInterval, e.log)
+	}()
+	t.Cleanup(func() {
+		stop()
+		<-done
+	})
+
+	app := newApp()
+	protected := app.Group("/api/v1", middleware.JWTAuth(jwtManager(cfg)), tenant.Middleware(cfg.Tenant), middleware.RequireRole("admin"))
+	protected.Get("/suppliers", procurementHandler.GetSuppliers)
+	protected.Get("/suppliers/:id", procurementHandler.GetSupplier)
+	protected.Post("/suppliers", procurementHandler.CreateSupplier)
+	protected.Get("/suppliers/:id/products", procurementHandler.GetSupplierProducts)
+	protected.Put("/suppliers/:id/products/:productId", procurementHandler.SetSupplierProduct)
+	protected.Delete("/suppliers/:id/products/:productId", procurementHandler.DeleteSupplierProduct)
+	protected.Get("/purchase-orders", procurementHandler.GetOrders)
+	protected.Get("/purchase-orders/:id", procurementHandler.GetOrder)
+	protected.Post("/purchase-orders", procurementHandler.CreateOrder)
+	protected.Post("/purchase-orders/:id/submit", procurementHandler.SubmitOrder)
+	protected.Post("/purchase-orders/:id/cancel", procurementHandler.CancelOrder)
+
+	return e.Serve(t, "procurement-service", app)
+}
+
+// StartSync starts sync-service with the routes of cmd/sync-service,
+// recording the catalog and inventory events of its queues in the change
+// feeds. Transactions are uploaded to order-service, which must be started
+// first; tombstones are not pruned.
+func (e *Env) StartSync(t *stdtesting.T) *Service {
+	t.Helper()
+	cfg := e.Config(t, "sync-service")
+
+	syncHandler := offline.NewHandler(repository.NewSyncRepository(e.DB),
+		orderclient.NewClient(cfg.Services.OrderServiceURL, cfg.Proxy), cfg.Sync)
+
+	broker := e.broker(t)
+	broker.UseDefaultTenant(cfg.Tenant.Default)
+	for _, queue := range cfg.Service.Queues {
+		if err := broker.ConsumeContext(queue, cfg.ServiceName, syncHandler.HandleEvent); err != nil {
+			t.Fatalf("Failed to consume %s: %v", queue, err)
+		}
+	}
+
+	app := newApp()
+	terminals := app.Group("/api/v1/sync/stores/:storeId", middleware.JWTAuth(jwtManager(cfg)), tenant.Middleware(cfg.Tenant))
+	terminals.Get("/changes", syncHandler.GetChanges)
+	terminals.Post("/transactions", syncHandler.PushTransactions)
+
+	return e.Serve(t, "sync-service", app)
+}
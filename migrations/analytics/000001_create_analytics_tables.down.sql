@@ -0,0 +1,6 @@
+-- Rollback analytics rollup tables
+DROP TABLE IF EXISTS analytics_stock_rollups;
+DROP TABLE IF EXISTS analytics_payment_rollups;
+DROP TABLE IF EXISTS analytics_product_rollups;
+DROP TABLE IF EXISTS analytics_sales_rollups;
+DROP TABLE IF EXISTS analytics_applied_events;
@@ -0,0 +1,68 @@
+-- Create analytics rollup tables. Each rollup keeps hourly and daily buckets
+-- (granularity 'hour' or 'day'), in UTC, that events add to as they arrive.
+
+-- Events already added to the rollups, so redeliveries are not counted twice
+CREATE TABLE analytics_applied_events (
+    event_id VARCHAR(128) PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE analytics_sales_rollups (
+    granularity VARCHAR(4) NOT NULL,
+    bucket TIMESTAMP NOT NULL,
+    store_id UUID NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    orders_placed BIGINT NOT NULL DEFAULT 0,
+    orders_completed BIGINT NOT NULL DEFAULT 0,
+    orders_cancelled BIGINT NOT NULL DEFAULT 0,
+    orders_refunded BIGINT NOT NULL DEFAULT 0,
+    gross_sales DECIMAL(15,2) NOT NULL DEFAULT 0,
+    revenue DECIMAL(15,2) NOT NULL DEFAULT 0,
+    refunds DECIMAL(15,2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (granularity, bucket, store_id, currency),
+    CONSTRAINT chk_analytics_sales_rollups_granularity CHECK (granularity IN ('hour', 'day'))
+);
+
+CREATE TABLE analytics_product_rollups (
+    granularity VARCHAR(4) NOT NULL,
+    bucket TIMESTAMP NOT NULL,
+    store_id UUID NOT NULL,
+    product_id VARCHAR(64) NOT NULL, -- Catalog variant ID
+    currency VARCHAR(3) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    units_sold BIGINT NOT NULL DEFAULT 0,
+    revenue DECIMAL(15,2) NOT NULL DEFAULT 0,
+    units_refunded BIGINT NOT NULL DEFAULT 0,
+    refunds DECIMAL(15,2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (granularity, bucket, store_id, product_id, currency),
+    CONSTRAINT chk_analytics_product_rollups_granularity CHECK (granularity IN ('hour', 'day'))
+);
+
+CREATE TABLE analytics_payment_rollups (
+    granularity VARCHAR(4) NOT NULL,
+    bucket TIMESTAMP NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    method VARCHAR(30) NOT NULL,
+    payments_started BIGINT NOT NULL DEFAULT 0,
+    payments_completed BIGINT NOT NULL DEFAULT 0,
+    amount_completed DECIMAL(15,2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (granularity, bucket, currency, method),
+    CONSTRAINT chk_analytics_payment_rollups_granularity CHECK (granularity IN ('hour', 'day'))
+);
+
+CREATE TABLE analytics_stock_rollups (
+    granularity VARCHAR(4) NOT NULL,
+    bucket TIMESTAMP NOT NULL,
+    store_id UUID NOT NULL, -- Nil UUID for stock held outside stores
+    product_id UUID NOT NULL,
+    units_reserved BIGINT NOT NULL DEFAULT 0,
+    units_released BIGINT NOT NULL DEFAULT 0,
+    low_stock_events BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (granularity, bucket, store_id, product_id),
+    CONSTRAINT chk_analytics_stock_rollups_granularity CHECK (granularity IN ('hour', 'day'))
+);
+
+-- Indexes for performance
+CREATE INDEX idx_analytics_applied_events_applied_at ON analytics_applied_events(applied_at);
+CREATE INDEX idx_analytics_sales_rollups_store ON analytics_sales_rollups(store_id, granularity, bucket);
+CREATE INDEX idx_analytics_product_rollups_store ON analytics_product_rollups(store_id, granularity, bucket);
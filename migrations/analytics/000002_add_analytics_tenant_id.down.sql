@@ -0,0 +1,14 @@
+-- Rollback analytics tenant scoping
+ALTER TABLE analytics_stock_rollups DROP CONSTRAINT analytics_stock_rollups_pkey,
+    ADD PRIMARY KEY (granularity, bucket, store_id, product_id);
+ALTER TABLE analytics_payment_rollups DROP CONSTRAINT analytics_payment_rollups_pkey,
+    ADD PRIMARY KEY (granularity, bucket, currency, method);
+ALTER TABLE analytics_product_rollups DROP CONSTRAINT analytics_product_rollups_pkey,
+    ADD PRIMARY KEY (granularity, bucket, store_id, product_id, currency);
+ALTER TABLE analytics_sales_rollups DROP CONSTRAINT analytics_sales_rollups_pkey,
+    ADD PRIMARY KEY (granularity, bucket, store_id, currency);
+
+ALTER TABLE analytics_stock_rollups DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE analytics_payment_rollups DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE analytics_product_rollups DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE analytics_sales_rollups DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,16 @@
+-- Keep analytics rollups per tenant; existing rows belong to the default
+-- tenant. Applied events stay global since event IDs are unique across
+-- tenants.
+ALTER TABLE analytics_sales_rollups ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE analytics_product_rollups ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE analytics_payment_rollups ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE analytics_stock_rollups ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+ALTER TABLE analytics_sales_rollups DROP CONSTRAINT analytics_sales_rollups_pkey,
+    ADD PRIMARY KEY (tenant_id, granularity, bucket, store_id, currency);
+ALTER TABLE analytics_product_rollups DROP CONSTRAINT analytics_product_rollups_pkey,
+    ADD PRIMARY KEY (tenant_id, granularity, bucket, store_id, product_id, currency);
+ALTER TABLE analytics_payment_rollups DROP CONSTRAINT analytics_payment_rollups_pkey,
+    ADD PRIMARY KEY (tenant_id, granularity, bucket, currency, method);
+ALTER TABLE analytics_stock_rollups DROP CONSTRAINT analytics_stock_rollups_pkey,
+    ADD PRIMARY KEY (tenant_id, granularity, bucket, store_id, product_id);
@@ -0,0 +1,2 @@
+-- Rollback analytics time entries
+DROP TABLE IF EXISTS analytics_time_entries;
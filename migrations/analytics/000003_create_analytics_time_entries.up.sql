@@ -0,0 +1,18 @@
+-- Keep the latest version of each staff time entry from the time clock's
+-- events, for weekly timesheets. Versions make late or redelivered events
+-- harmless without recording them as applied.
+CREATE TABLE analytics_time_entries (
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    entry_id UUID NOT NULL,
+    version INTEGER NOT NULL,
+    store_id UUID NOT NULL,
+    staff_id UUID NOT NULL,
+    closed BOOLEAN NOT NULL DEFAULT FALSE,
+    clock_in TIMESTAMP NOT NULL,
+    clock_out TIMESTAMP,
+    worked_seconds BIGINT NOT NULL DEFAULT 0, -- Includes paid breaks
+    unpaid_break_seconds BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, entry_id)
+);
+
+CREATE INDEX idx_analytics_time_entries_clock_in ON analytics_time_entries(tenant_id, clock_in);
@@ -0,0 +1,50 @@
+-- Create append-only audit log table
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- Who acted (empty for unauthenticated requests)
+    actor_id VARCHAR(100) NOT NULL DEFAULT '',
+    actor_roles TEXT[] NOT NULL DEFAULT '{}',
+    -- What was done
+    method VARCHAR(10) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    path TEXT NOT NULL,
+    resource_ids JSONB NOT NULL DEFAULT '{}',
+    -- Top-level fields in the request body; values are never stored
+    changes TEXT[] NOT NULL DEFAULT '{}',
+    -- Where from
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    -- Outcome
+    status INTEGER NOT NULL,
+    result VARCHAR(20) NOT NULL CHECK (result IN ('success', 'denied', 'failure')),
+    duration_ms BIGINT NOT NULL DEFAULT 0
+);
+
+-- Create indexes for the query API
+CREATE INDEX idx_audit_log_occurred_at ON audit_log(occurred_at);
+CREATE INDEX idx_audit_log_actor_id ON audit_log(actor_id, occurred_at);
+CREATE INDEX idx_audit_log_route ON audit_log(route, occurred_at);
+
+-- Entries can never be changed, and can only be deleted by the retention job,
+-- which sets audit.allow_purge for its transaction
+CREATE OR REPLACE FUNCTION audit_log_append_only()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' AND current_setting('audit.allow_purge', true) = 'on' THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW
+    EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,9 @@
+-- Rollback product catalog tables
+DROP TRIGGER IF EXISTS update_store_prices_updated_at ON store_prices;
+DROP TRIGGER IF EXISTS update_product_variants_updated_at ON product_variants;
+DROP TRIGGER IF EXISTS update_products_updated_at ON products;
+DROP TRIGGER IF EXISTS update_categories_updated_at ON categories;
+DROP TABLE IF EXISTS store_prices;
+DROP TABLE IF EXISTS product_variants;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,68 @@
+-- Create product catalog tables
+CREATE TABLE categories (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    parent_id UUID REFERENCES categories(id),
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP
+);
+
+CREATE TABLE products (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    category_id UUID REFERENCES categories(id),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    brand VARCHAR(255),
+    status VARCHAR(20) NOT NULL DEFAULT 'draft',
+    images JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP,
+    CONSTRAINT chk_products_status CHECK (status IN ('draft', 'active', 'archived'))
+);
+
+CREATE TABLE product_variants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(id),
+    sku VARCHAR(64) NOT NULL,
+    barcode VARCHAR(64),
+    name VARCHAR(255),
+    attributes JSONB NOT NULL DEFAULT '{}',
+    base_price DECIMAL(12,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP,
+    CONSTRAINT chk_product_variants_base_price CHECK (base_price >= 0)
+);
+
+-- Per-store price lists; variants without an entry sell at their base price
+CREATE TABLE store_prices (
+    store_id UUID NOT NULL,
+    variant_id UUID NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+    price DECIMAL(12,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (store_id, variant_id),
+    CONSTRAINT chk_store_prices_price CHECK (price >= 0)
+);
+
+-- Indexes for performance
+CREATE INDEX idx_categories_parent_id ON categories(parent_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_products_category_id ON products(category_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_products_created_at ON products(created_at DESC) WHERE deleted_at IS NULL;
+CREATE INDEX idx_product_variants_product_id ON product_variants(product_id) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_product_variants_sku ON product_variants(sku) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_product_variants_barcode ON product_variants(barcode) WHERE deleted_at IS NULL AND barcode IS NOT NULL;
+
+-- Update timestamp triggers
+CREATE TRIGGER update_categories_updated_at BEFORE UPDATE ON categories
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_products_updated_at BEFORE UPDATE ON products
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_product_variants_updated_at BEFORE UPDATE ON product_variants
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_store_prices_updated_at BEFORE UPDATE ON store_prices
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
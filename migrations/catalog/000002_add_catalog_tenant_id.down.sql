@@ -0,0 +1,17 @@
+-- Rollback catalog tenant scoping. Fails while a slug, SKU or barcode is used
+-- in several tenants.
+DROP INDEX IF EXISTS idx_store_prices_tenant_store_id;
+DROP INDEX IF EXISTS idx_products_tenant_created_at;
+
+DROP INDEX IF EXISTS idx_product_variants_tenant_barcode;
+DROP INDEX IF EXISTS idx_product_variants_tenant_sku;
+CREATE UNIQUE INDEX idx_product_variants_sku ON product_variants(sku) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_product_variants_barcode ON product_variants(barcode) WHERE deleted_at IS NULL AND barcode IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_categories_tenant_slug;
+ALTER TABLE categories ADD CONSTRAINT categories_slug_key UNIQUE (slug);
+
+ALTER TABLE store_prices DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE product_variants DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE products DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE categories DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,17 @@
+-- Scope the catalog to a tenant; existing rows belong to the default tenant.
+-- Category slugs, SKUs and barcodes are unique within a tenant.
+ALTER TABLE categories ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE products ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE product_variants ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE store_prices ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_slug_key;
+CREATE UNIQUE INDEX idx_categories_tenant_slug ON categories(tenant_id, slug);
+
+DROP INDEX IF EXISTS idx_product_variants_sku;
+DROP INDEX IF EXISTS idx_product_variants_barcode;
+CREATE UNIQUE INDEX idx_product_variants_tenant_sku ON product_variants(tenant_id, sku) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_product_variants_tenant_barcode ON product_variants(tenant_id, barcode) WHERE deleted_at IS NULL AND barcode IS NOT NULL;
+
+CREATE INDEX idx_products_tenant_created_at ON products(tenant_id, created_at DESC) WHERE deleted_at IS NULL;
+CREATE INDEX idx_store_prices_tenant_store_id ON store_prices(tenant_id, store_id);
@@ -0,0 +1,2 @@
+-- Rollback feature flags table migration
+DROP TABLE IF EXISTS feature_flags;
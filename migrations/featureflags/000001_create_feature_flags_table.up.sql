@@ -0,0 +1,14 @@
+-- Create feature flags table
+CREATE TABLE feature_flags (
+    key VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Percentage of users/stores in the rollout (0-100)
+    rollout INTEGER NOT NULL DEFAULT 0 CHECK (rollout BETWEEN 0 AND 100),
+    -- Explicitly targeted user and store IDs
+    users TEXT[] NOT NULL DEFAULT '{}',
+    stores TEXT[] NOT NULL DEFAULT '{}',
+    -- Timestamps
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
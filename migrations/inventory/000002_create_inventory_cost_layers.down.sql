@@ -0,0 +1,2 @@
+-- Rollback inventory cost layers
+DROP TABLE IF EXISTS inventory_cost_layers;
@@ -0,0 +1,17 @@
+-- Stock received from suppliers, one layer per receipt line at its unit cost
+CREATE TABLE inventory_cost_layers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    inventory_id UUID NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL,
+    unit_cost DECIMAL(15,4) NOT NULL,
+    source_type VARCHAR(50) NOT NULL,
+    source_id UUID NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_inventory_cost_layers_quantity CHECK (quantity > 0),
+    CONSTRAINT chk_inventory_cost_layers_unit_cost CHECK (unit_cost >= 0)
+);
+
+-- A receipt line is posted to stock once
+CREATE UNIQUE INDEX idx_inventory_cost_layers_source ON inventory_cost_layers(source_type, source_id);
+
+CREATE INDEX idx_inventory_cost_layers_inventory ON inventory_cost_layers(inventory_id, received_at);
@@ -0,0 +1,14 @@
+-- Rollback inventory tenant scoping. Fails while a product or receipt line
+-- is stocked in several tenants.
+DROP INDEX IF EXISTS idx_inventory_cost_layers_tenant_source;
+CREATE UNIQUE INDEX idx_inventory_cost_layers_source ON inventory_cost_layers(source_type, source_id);
+
+DROP INDEX IF EXISTS idx_inventory_tenant_store_id;
+DROP INDEX IF EXISTS idx_inventory_tenant_product_global;
+DROP INDEX IF EXISTS idx_inventory_tenant_product_store;
+CREATE UNIQUE INDEX idx_inventory_product_store ON inventory(product_id, store_id) WHERE store_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_inventory_product_global ON inventory(product_id) WHERE store_id IS NULL;
+
+ALTER TABLE inventory_cost_layers DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE inventory DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,14 @@
+-- Scope inventory to a tenant; existing rows belong to the default tenant.
+ALTER TABLE inventory ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE stock_movements ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE inventory_cost_layers ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+DROP INDEX IF EXISTS idx_inventory_product_store;
+DROP INDEX IF EXISTS idx_inventory_product_global;
+CREATE UNIQUE INDEX idx_inventory_tenant_product_store ON inventory(tenant_id, product_id, store_id) WHERE store_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_inventory_tenant_product_global ON inventory(tenant_id, product_id) WHERE store_id IS NULL;
+CREATE INDEX idx_inventory_tenant_store_id ON inventory(tenant_id, store_id);
+
+-- A receipt line is posted to stock once per tenant
+DROP INDEX IF EXISTS idx_inventory_cost_layers_source;
+CREATE UNIQUE INDEX idx_inventory_cost_layers_tenant_source ON inventory_cost_layers(tenant_id, source_type, source_id);
@@ -0,0 +1,10 @@
+-- Create the archive of stock movements, moved out of the live table once
+-- past their retention (archive.tables.stock_movements). Partitions are
+-- created per month of created_at as rows are archived. Columns added to
+-- stock_movements must be added here too.
+CREATE SCHEMA IF NOT EXISTS archive;
+
+CREATE TABLE archive.stock_movements (LIKE stock_movements INCLUDING DEFAULTS) PARTITION BY RANGE (created_at);
+
+CREATE INDEX idx_archive_stock_movements_tenant_created_at ON archive.stock_movements(tenant_id, created_at);
+CREATE INDEX idx_archive_stock_movements_inventory_id ON archive.stock_movements(inventory_id);
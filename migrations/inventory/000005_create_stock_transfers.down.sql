@@ -0,0 +1,3 @@
+-- Rollback stock transfers
+DROP TABLE IF EXISTS stock_transfer_items;
+DROP TABLE IF EXISTS stock_transfers;
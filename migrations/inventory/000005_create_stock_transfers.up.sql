@@ -0,0 +1,29 @@
+-- Stock moved from one store to another: requested, in transit, then received
+CREATE TABLE stock_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    source_store_id UUID NOT NULL,
+    destination_store_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'requested', -- requested, in_transit, received, cancelled
+    note TEXT,
+    requested_by UUID,
+    dispatched_by UUID,
+    received_by UUID,
+    dispatched_at TIMESTAMP,
+    received_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_stock_transfers_stores CHECK (source_store_id <> destination_store_id)
+);
+
+CREATE INDEX idx_stock_transfers_source ON stock_transfers(tenant_id, source_store_id, created_at DESC);
+CREATE INDEX idx_stock_transfers_destination ON stock_transfers(tenant_id, destination_store_id, created_at DESC);
+
+CREATE TABLE stock_transfer_items (
+    transfer_id UUID NOT NULL REFERENCES stock_transfers(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL,
+    quantity INTEGER NOT NULL,
+    PRIMARY KEY (transfer_id, product_id),
+    CONSTRAINT chk_stock_transfer_items_quantity CHECK (quantity > 0)
+);
@@ -0,0 +1,2 @@
+-- Rollback stock reservations
+DROP TABLE IF EXISTS stock_reservations;
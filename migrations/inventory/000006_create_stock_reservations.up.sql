@@ -0,0 +1,18 @@
+-- Stock held for orders: held until it expires, unless committed or released
+CREATE TABLE stock_reservations (
+    id UUID PRIMARY KEY,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    inventory_id UUID NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'held', -- held, committed, released, expired
+    reference_id UUID, -- order_id
+    reference_type VARCHAR(50),
+    expires_at TIMESTAMP NOT NULL,
+    committed_at TIMESTAMP,
+    closed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_stock_reservations_quantity CHECK (quantity > 0)
+);
+
+CREATE INDEX idx_stock_reservations_expiry ON stock_reservations(expires_at) WHERE status = 'held';
+CREATE INDEX idx_stock_reservations_reference ON stock_reservations(tenant_id, reference_type, reference_id) WHERE reference_id IS NOT NULL;
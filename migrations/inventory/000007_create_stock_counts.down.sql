@@ -0,0 +1,3 @@
+-- Rollback stock counts
+DROP TABLE IF EXISTS stock_count_lines;
+DROP TABLE IF EXISTS stock_counts;
@@ -0,0 +1,30 @@
+-- Stock takes of a store: products are counted while open, and stock on hand
+-- is corrected to the counts when reconciled
+CREATE TABLE stock_counts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    store_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- open, reconciled, cancelled
+    note TEXT,
+    opened_by UUID,
+    closed_by UUID,
+    closed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- A store is counted by one count at a time
+CREATE UNIQUE INDEX idx_stock_counts_open ON stock_counts(tenant_id, store_id) WHERE status = 'open';
+CREATE INDEX idx_stock_counts_store ON stock_counts(tenant_id, store_id, created_at DESC);
+
+CREATE TABLE stock_count_lines (
+    count_id UUID NOT NULL REFERENCES stock_counts(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL,
+    inventory_id UUID NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    counted_quantity INTEGER NOT NULL,
+    system_quantity INTEGER, -- Stock on hand the count replaced; set when reconciled
+    counted_by UUID,
+    counted_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (count_id, product_id),
+    CONSTRAINT chk_stock_count_lines_quantity CHECK (counted_quantity >= 0)
+);
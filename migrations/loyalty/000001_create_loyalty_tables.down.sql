@@ -0,0 +1,4 @@
+-- Rollback loyalty tables
+DROP TRIGGER IF EXISTS update_loyalty_accounts_updated_at ON loyalty_accounts;
+DROP TABLE IF EXISTS loyalty_transactions;
+DROP TABLE IF EXISTS loyalty_accounts;
@@ -0,0 +1,37 @@
+-- Create loyalty accounts and the points ledger
+CREATE TABLE loyalty_accounts (
+    user_id UUID PRIMARY KEY,
+    balance BIGINT NOT NULL DEFAULT 0,
+    lifetime_points BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_loyalty_accounts_balance CHECK (balance >= 0)
+);
+
+-- Credits (earn, release) keep their unspent points in remaining; redemptions
+-- consume the credits that expire first, and the sweeper expires the rest
+CREATE TABLE loyalty_transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    points BIGINT NOT NULL,
+    remaining BIGINT NOT NULL DEFAULT 0,
+    order_id UUID,
+    amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    currency VARCHAR(3),
+    description TEXT,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_loyalty_transactions_type CHECK (type IN ('earn', 'redeem', 'release', 'reverse', 'expire')),
+    CONSTRAINT chk_loyalty_transactions_remaining CHECK (remaining >= 0)
+);
+
+-- Indexes for performance. One transaction of each type per order makes
+-- redelivered order events and retried redemptions no-ops.
+CREATE INDEX idx_loyalty_transactions_user_id ON loyalty_transactions(user_id, created_at DESC);
+CREATE INDEX idx_loyalty_transactions_expires_at ON loyalty_transactions(expires_at) WHERE remaining > 0;
+CREATE UNIQUE INDEX idx_loyalty_transactions_order_type ON loyalty_transactions(order_id, type) WHERE order_id IS NOT NULL;
+
+-- Update timestamp trigger
+CREATE TRIGGER update_loyalty_accounts_updated_at BEFORE UPDATE ON loyalty_accounts
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
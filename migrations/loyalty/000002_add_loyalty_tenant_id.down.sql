@@ -0,0 +1,8 @@
+-- Rollback loyalty tenant scoping
+DROP INDEX IF EXISTS idx_loyalty_transactions_tenant_order_type;
+DROP INDEX IF EXISTS idx_loyalty_transactions_tenant_user_id;
+CREATE INDEX idx_loyalty_transactions_user_id ON loyalty_transactions(user_id, created_at DESC);
+CREATE UNIQUE INDEX idx_loyalty_transactions_order_type ON loyalty_transactions(order_id, type) WHERE order_id IS NOT NULL;
+
+ALTER TABLE loyalty_transactions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE loyalty_accounts DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,9 @@
+-- Scope loyalty accounts and the points ledger to a tenant; existing rows
+-- belong to the default tenant.
+ALTER TABLE loyalty_accounts ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE loyalty_transactions ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+DROP INDEX IF EXISTS idx_loyalty_transactions_user_id;
+DROP INDEX IF EXISTS idx_loyalty_transactions_order_type;
+CREATE INDEX idx_loyalty_transactions_tenant_user_id ON loyalty_transactions(tenant_id, user_id, created_at DESC);
+CREATE UNIQUE INDEX idx_loyalty_transactions_tenant_order_type ON loyalty_transactions(tenant_id, order_id, type) WHERE order_id IS NOT NULL;
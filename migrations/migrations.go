@@ -0,0 +1,168 @@
+// Package migrations embeds the schema migrations of every service, a
+// directory each, so that a service binary can migrate its own database:
+// with its migrate command, or on start when database.migrate_on_start is
+// set. omnictl migrates every service's database from the same directories.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/onichange/pos-system/pkg/database"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// FS holds the migrations directories
+//
+//go:embed */*.sql
+var FS embed.FS
+
+// Subcommands are the subcommands of the migrate command
+var Subcommands = []string{"up", "down", "status", "force"}
+
+// owners names the services whose databases a migrations directory belongs
+// to, where that is not <directory>-service
+var owners = map[string][]string{
+	"audit":        {"api-gateway"},
+	"featureflags": {"api-gateway"},
+	"outbox":       {"order-service", "payment-service", "inventory-service", "procurement-service"},
+	"saga":         {"order-service"},
+	"tenant":       {"api-gateway"},
+}
+
+// Owners returns the services owning a migrations directory
+func Owners(dir string) []string {
+	if services, ok := owners[dir]; ok {
+		return services
+	}
+	return []string{dir + "-service"}
+}
+
+// Dirs returns the migrations directories applied to a service's database,
+// in order
+func Dirs(service string) []string {
+	entries, _ := fs.ReadDir(FS, ".") // Embedded, so readable
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() && slices.Contains(Owners(entry.Name()), service) {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	return dirs
+}
+
+// Up applies the pending migrations of every directory a service owns to
+// its database, logging each applied
+func Up(ctx context.Context, pool *pgxpool.Pool, service string, log *logger.Logger) error {
+	for _, dir := range Dirs(service) {
+		migrations, err := database.LoadMigrations(FS, dir)
+		if err != nil {
+			return err
+		}
+		applied, err := database.NewMigrator(pool, dir).Up(ctx, migrations)
+		for _, m := range applied {
+			log.Infof("Applied migration %s/%06d_%s", dir, m.Version, m.Description)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to migrate %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// Run runs a service's migrate command on its database:
+//
+//	<service> migrate up|down|status|force [flags] [directory...]
+//
+// Without directories it acts on every one the service owns.
+func Run(ctx context.Context, pool *pgxpool.Pool, service string, args []string) error {
+	if len(args) == 0 || !slices.Contains(Subcommands, args[0]) {
+		return fmt.Errorf("usage: %s migrate up|down|status|force [flags] [directory...]", service)
+	}
+	sub := args[0]
+
+	flags := flag.NewFlagSet(service+" migrate "+sub, flag.ExitOnError)
+	steps := flags.Int("steps", 1, "Migrations to roll back (down)")
+	version := flags.Int64("version", -1, "Last version to record as applied (force); 0 records none")
+	flags.Parse(args[1:])
+
+	owned := Dirs(service)
+	dirs := flags.Args()
+	for _, dir := range dirs {
+		if !slices.Contains(owned, dir) {
+			return fmt.Errorf("%s has no %s migrations; it has %v", service, dir, owned)
+		}
+	}
+	switch sub {
+	case "down", "force":
+		if len(dirs) != 1 {
+			return fmt.Errorf("migrate %s takes exactly one directory", sub)
+		}
+	}
+	if sub == "force" && *version < 0 {
+		return errors.New("migrate force needs -version")
+	}
+	if len(dirs) == 0 {
+		dirs = owned
+	}
+
+	for _, dir := range dirs {
+		migrations, err := database.LoadMigrations(FS, dir)
+		if err != nil {
+			return err
+		}
+		if err := Exec(ctx, sub, database.NewMigrator(pool, dir), dir, migrations, *steps, *version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Exec runs a migrate subcommand with the migrations of one directory,
+// printing what it did, each line starting with label
+func Exec(ctx context.Context, sub string, migrator *database.Migrator, label string, migrations []database.Migration, steps int, version int64) error {
+	switch sub {
+	case "up":
+		applied, err := migrator.Up(ctx, migrations)
+		for _, m := range applied {
+			fmt.Printf("%s: applied %06d_%s\n", label, m.Version, m.Description)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Printf("%s: up to date\n", label)
+		}
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, migrations, steps)
+		for _, m := range reverted {
+			fmt.Printf("%s: rolled back %06d_%s\n", label, m.Version, m.Description)
+		}
+		return err
+	case "status":
+		applied, err := migrator.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			state := "pending"
+			if at, ok := applied[m.Version]; ok {
+				state = "applied " + at.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%s: %06d_%s  %s\n", label, m.Version, m.Description, state)
+		}
+	case "force":
+		if err := migrator.Force(ctx, migrations, version); err != nil {
+			return err
+		}
+		fmt.Printf("%s: recorded as migrated to version %d\n", label, version)
+	default:
+		return fmt.Errorf("unknown migrate subcommand %q; want one of %v", sub, Subcommands)
+	}
+	return nil
+}
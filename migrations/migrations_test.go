@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/database"
+)
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	entries, err := fs.ReadDir(FS, ".")
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+
+	for _, entry := range entries {
+		migrations, err := database.LoadMigrations(FS, entry.Name())
+		require.NoError(t, err, entry.Name())
+		assert.NotEmpty(t, migrations, entry.Name())
+	}
+}
+
+func TestDirsOfService(t *testing.T) {
+	assert.Equal(t, []string{"order", "outbox", "saga"}, Dirs("order-service"))
+	assert.Equal(t, []string{"inventory", "outbox"}, Dirs("inventory-service"))
+	assert.Equal(t, []string{"audit", "featureflags", "tenant"}, Dirs("api-gateway"))
+	assert.Empty(t, Dirs("search-service"))
+}
+
+func TestRunChecksArguments(t *testing.T) {
+	ctx := context.Background()
+
+	assert.ErrorContains(t, Run(ctx, nil, "order-service", nil), "usage")
+	assert.ErrorContains(t, Run(ctx, nil, "order-service", []string{"sideways"}), "usage")
+	assert.ErrorContains(t, Run(ctx, nil, "order-service", []string{"up", "user"}), "no user migrations")
+	assert.ErrorContains(t, Run(ctx, nil, "order-service", []string{"down"}), "exactly one directory")
+	assert.ErrorContains(t, Run(ctx, nil, "order-service", []string{"force", "order"}), "needs -version")
+}
@@ -0,0 +1,5 @@
+-- Rollback notifications tenant scoping
+DROP INDEX IF EXISTS idx_notifications_tenant_user_created;
+
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE notifications DROP COLUMN IF EXISTS tenant_id;
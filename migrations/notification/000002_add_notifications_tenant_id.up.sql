@@ -0,0 +1,6 @@
+-- Scope notifications to a tenant; existing rows belong to the default
+-- tenant.
+ALTER TABLE notifications ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE notification_preferences ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+CREATE INDEX idx_notifications_tenant_user_created ON notifications(tenant_id, user_id, created_at DESC);
@@ -0,0 +1,3 @@
+-- Archived rows are dropped with the table; restore them first with
+-- omnictl archive restore. The archive schema is kept for other services.
+DROP TABLE IF EXISTS archive.notifications;
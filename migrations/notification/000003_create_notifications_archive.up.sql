@@ -0,0 +1,9 @@
+-- Create the archive of notifications, moved out of the live table once
+-- past their retention (archive.tables.notifications). Partitions are
+-- created per month of created_at as rows are archived. Columns added to
+-- notifications must be added here too.
+CREATE SCHEMA IF NOT EXISTS archive;
+
+CREATE TABLE archive.notifications (LIKE notifications INCLUDING DEFAULTS) PARTITION BY RANGE (created_at);
+
+CREATE INDEX idx_archive_notifications_tenant_created_at ON archive.notifications(tenant_id, created_at);
@@ -0,0 +1,23 @@
+-- Create the deliveries of notifications on channels sent outside the app,
+-- such as email. A delivery is attempted until sent or out of attempts, and
+-- goes with its notification when that is deleted or archived.
+CREATE TABLE notification_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    notification_id UUID NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    recipient VARCHAR(255) NOT NULL DEFAULT '', -- Where the last attempt went
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    last_attempt_at TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMP,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_notification_deliveries_status CHECK (status IN ('pending', 'sent', 'failed'))
+);
+
+-- A notification is delivered once per channel
+CREATE UNIQUE INDEX idx_notification_deliveries_channel ON notification_deliveries(notification_id, channel);
+CREATE INDEX idx_notification_deliveries_due ON notification_deliveries(channel, next_attempt_at) WHERE status = 'pending';
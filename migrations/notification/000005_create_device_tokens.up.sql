@@ -0,0 +1,17 @@
+-- Create the device tokens mobile apps register to receive notifications on
+-- the push channel. A token belongs to whoever registered it last.
+CREATE TABLE device_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    provider VARCHAR(20) NOT NULL,
+    token TEXT NOT NULL,
+    device_name VARCHAR(255) NOT NULL DEFAULT '',
+    app_version VARCHAR(50) NOT NULL DEFAULT '',
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_device_tokens_provider CHECK (provider IN ('fcm', 'apns'))
+);
+
+CREATE UNIQUE INDEX idx_device_tokens_token ON device_tokens(tenant_id, provider, token);
+CREATE INDEX idx_device_tokens_user ON device_tokens(tenant_id, user_id, updated_at DESC);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_notification_deliveries_sent;
+
+ALTER TABLE notification_deliveries
+    DROP COLUMN IF EXISTS delivered_at,
+    DROP COLUMN IF EXISTS receipt,
+    DROP COLUMN IF EXISTS provider_message_id;
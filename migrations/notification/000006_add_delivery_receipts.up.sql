@@ -0,0 +1,9 @@
+-- Record the receipts providers such as Twilio report back on sent
+-- deliveries, matched by the provider's ID of the message
+ALTER TABLE notification_deliveries
+    ADD COLUMN provider_message_id VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN receipt VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN delivered_at TIMESTAMP;
+
+-- Sends to a user are counted for per-user rate limits
+CREATE INDEX idx_notification_deliveries_sent ON notification_deliveries(channel, sent_at) WHERE sent_at IS NOT NULL;
@@ -0,0 +1,4 @@
+-- Rollback orders tenant scoping
+DROP INDEX IF EXISTS idx_orders_tenant_store_id;
+DROP INDEX IF EXISTS idx_orders_tenant_user_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,5 @@
+-- Scope orders to a tenant; existing rows belong to the default tenant
+ALTER TABLE orders ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+CREATE INDEX idx_orders_tenant_user_id ON orders(tenant_id, user_id);
+CREATE INDEX idx_orders_tenant_store_id ON orders(tenant_id, store_id);
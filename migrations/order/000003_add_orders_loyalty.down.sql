@@ -0,0 +1,3 @@
+-- Rollback order loyalty redemption
+ALTER TABLE orders DROP COLUMN IF EXISTS loyalty_discount;
+ALTER TABLE orders DROP COLUMN IF EXISTS loyalty_points;
@@ -0,0 +1,3 @@
+-- Record loyalty points redeemed against an order and the discount they gave
+ALTER TABLE orders ADD COLUMN loyalty_points BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN loyalty_discount DECIMAL(15,2) NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Rollback order promotions
+ALTER TABLE orders DROP COLUMN IF EXISTS promotions;
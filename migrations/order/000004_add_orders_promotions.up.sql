@@ -0,0 +1,2 @@
+-- Record the promotions that discounted an order; the discounts themselves are on its items
+ALTER TABLE orders ADD COLUMN promotions JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,4 @@
+-- Rollback order shifts
+DROP INDEX IF EXISTS idx_orders_shift_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS tender;
+ALTER TABLE orders DROP COLUMN IF EXISTS shift_id;
@@ -0,0 +1,5 @@
+-- Link orders rung up at a register to its shift, with the tender they were paid by
+ALTER TABLE orders ADD COLUMN shift_id UUID;
+ALTER TABLE orders ADD COLUMN tender VARCHAR(32) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_orders_shift_id ON orders(shift_id) WHERE shift_id IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Rollback order sales tax
+ALTER TABLE orders DROP COLUMN IF EXISTS taxes;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_amount;
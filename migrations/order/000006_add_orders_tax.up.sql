@@ -0,0 +1,3 @@
+-- Add the sales tax quoted by the tax service, in total and by jurisdiction
+ALTER TABLE orders ADD COLUMN tax_amount DECIMAL(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN taxes JSONB NOT NULL DEFAULT '[]';
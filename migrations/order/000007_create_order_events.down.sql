@@ -0,0 +1,5 @@
+-- Rollback the event-sourced order store
+DROP TABLE IF EXISTS order_snapshots;
+DROP TRIGGER IF EXISTS order_events_append_only ON order_events;
+DROP TABLE IF EXISTS order_events;
+DROP FUNCTION IF EXISTS order_events_append_only();
@@ -0,0 +1,52 @@
+-- Create the history of the event-sourced order store (orders.store: events).
+-- Each change of an order is appended to order_events; the orders table is
+-- their projection, kept current in the same transaction.
+CREATE TABLE order_events (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL,
+    tenant_id VARCHAR(63) NOT NULL,
+    version INTEGER NOT NULL CHECK (version >= 1),
+    type VARCHAR(30) NOT NULL CHECK (type IN ('created', 'updated', 'status_changed', 'deleted')),
+    -- The order without its addresses (created, updated) or the new status
+    data JSONB NOT NULL,
+    -- Addresses with their PII fields encrypted, like those of orders
+    shipping_address JSONB,
+    billing_address JSONB,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (order_id, version)
+);
+
+CREATE INDEX idx_order_events_tenant_occurred_at ON order_events(tenant_id, occurred_at);
+
+-- Events are never changed or removed, except that re-encryption under a
+-- rotated key may rewrite the sealed address fields
+CREATE OR REPLACE FUNCTION order_events_append_only()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND (NEW.id, NEW.order_id, NEW.tenant_id, NEW.version, NEW.type, NEW.data, NEW.occurred_at)
+            IS NOT DISTINCT FROM (OLD.id, OLD.order_id, OLD.tenant_id, OLD.version, OLD.type, OLD.data, OLD.occurred_at) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'order_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER order_events_append_only
+    BEFORE UPDATE OR DELETE ON order_events
+    FOR EACH ROW
+    EXECUTE FUNCTION order_events_append_only();
+
+-- The state of each order as of a version, so it is rebuilt from the
+-- snapshot and the events after it rather than from its whole history
+CREATE TABLE order_snapshots (
+    id UUID PRIMARY KEY, -- The order's ID
+    tenant_id VARCHAR(63) NOT NULL,
+    version INTEGER NOT NULL,
+    data JSONB NOT NULL,
+    shipping_address JSONB,
+    billing_address JSONB,
+    -- When the event of version occurred
+    as_of TIMESTAMPTZ NOT NULL,
+    taken_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+-- Archived rows are dropped with their tables; restore them first with
+-- omnictl archive restore. The archive schema is kept for other services.
+DROP TABLE IF EXISTS archive.order_audit_log;
+DROP TABLE IF EXISTS archive.orders;
@@ -0,0 +1,14 @@
+-- Create the archive of orders in a final status, moved out of the live
+-- tables once past their retention (archive.tables.orders). Partitions of
+-- archive.orders are created per month of updated_at as rows are archived.
+-- Columns added to orders or order_audit_log must be added here too.
+CREATE SCHEMA IF NOT EXISTS archive;
+
+CREATE TABLE archive.orders (LIKE orders INCLUDING DEFAULTS) PARTITION BY RANGE (updated_at);
+
+CREATE INDEX idx_archive_orders_id ON archive.orders(id);
+CREATE INDEX idx_archive_orders_tenant_updated_at ON archive.orders(tenant_id, updated_at);
+
+CREATE TABLE archive.order_audit_log (LIKE order_audit_log INCLUDING DEFAULTS);
+
+CREATE INDEX idx_archive_order_audit_log_order_id ON archive.order_audit_log(order_id);
@@ -0,0 +1,3 @@
+-- Rollback the order status history
+DROP TABLE IF EXISTS archive.order_status_history;
+DROP TABLE IF EXISTS order_status_history;
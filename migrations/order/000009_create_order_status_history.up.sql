@@ -0,0 +1,20 @@
+-- Record every status transition of an order (PATCH /orders/:id/status),
+-- written in the same statement as the order's new status
+CREATE TABLE order_status_history (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    tenant_id VARCHAR(63) NOT NULL,
+    from_status VARCHAR(50) NOT NULL,
+    to_status VARCHAR(50) NOT NULL,
+    -- The user who moved the order, NULL when the system did
+    changed_by UUID,
+    reason TEXT NOT NULL DEFAULT '',
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_status_history_order_changed_at ON order_status_history(order_id, changed_at);
+
+-- Archived with their orders, like order_audit_log
+CREATE TABLE archive.order_status_history (LIKE order_status_history INCLUDING DEFAULTS);
+
+CREATE INDEX idx_archive_order_status_history_order_id ON archive.order_status_history(order_id);
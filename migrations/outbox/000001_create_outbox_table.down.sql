@@ -0,0 +1,2 @@
+-- Rollback outbox table migration
+DROP TABLE IF EXISTS outbox;
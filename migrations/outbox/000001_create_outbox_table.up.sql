@@ -0,0 +1,19 @@
+-- Create outbox table of events waiting to be relayed to the message broker
+CREATE TABLE outbox (
+    id UUID PRIMARY KEY,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT '',
+    exchange VARCHAR(255) NOT NULL,
+    routing_key VARCHAR(255) NOT NULL,
+    -- The message as published, and its trace, request ID and tenant headers
+    body JSONB NOT NULL,
+    headers JSONB NOT NULL DEFAULT '{}',
+    -- Times the broker refused it, and why it last did
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    available_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX idx_outbox_created_at ON outbox(created_at, id);
+CREATE INDEX idx_outbox_tenant_id ON outbox(tenant_id, created_at);
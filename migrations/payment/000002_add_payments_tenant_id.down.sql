@@ -0,0 +1,4 @@
+-- Rollback payments tenant scoping
+DROP INDEX IF EXISTS idx_payments_tenant_user_id;
+DROP INDEX IF EXISTS idx_payments_tenant_order_id;
+ALTER TABLE payments DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,5 @@
+-- Scope payments to a tenant; existing rows belong to the default tenant
+ALTER TABLE payments ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+CREATE INDEX idx_payments_tenant_order_id ON payments(tenant_id, order_id);
+CREATE INDEX idx_payments_tenant_user_id ON payments(tenant_id, user_id);
@@ -0,0 +1,3 @@
+-- Rollback provider events
+DROP INDEX IF EXISTS idx_payments_unsettled;
+DROP TABLE IF EXISTS payment_provider_events;
@@ -0,0 +1,14 @@
+-- Provider webhook events already applied, so a redelivery changes nothing
+CREATE TABLE payment_provider_events (
+    provider VARCHAR(50) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    payment_id UUID NOT NULL,
+    tenant_id VARCHAR(63) NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (provider, event_id)
+);
+
+CREATE INDEX idx_payment_provider_events_received_at ON payment_provider_events(received_at);
+
+-- The reconciler looks for payments left unsettled
+CREATE INDEX idx_payments_unsettled ON payments(updated_at) WHERE status IN ('pending', 'processing');
@@ -0,0 +1,2 @@
+-- Rollback payments export index
+DROP INDEX IF EXISTS idx_payments_tenant_created_at;
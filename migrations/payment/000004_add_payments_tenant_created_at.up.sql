@@ -0,0 +1,2 @@
+-- Exports read a tenant's payments by the period they were created in
+CREATE INDEX idx_payments_tenant_created_at ON payments(tenant_id, created_at);
@@ -0,0 +1,82 @@
+-- Create suppliers, purchase orders and goods receipts
+CREATE TABLE suppliers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(32) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    contact_name VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    phone VARCHAR(50) NOT NULL DEFAULT '',
+    address TEXT NOT NULL DEFAULT '',
+    currency VARCHAR(3) NOT NULL,
+    payment_terms VARCHAR(100) NOT NULL DEFAULT '',
+    lead_time_days INTEGER NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_suppliers_lead_time CHECK (lead_time_days >= 0)
+);
+
+CREATE UNIQUE INDEX idx_suppliers_code ON suppliers(UPPER(code));
+
+CREATE TABLE purchase_orders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    number BIGSERIAL NOT NULL UNIQUE,
+    supplier_id UUID NOT NULL REFERENCES suppliers(id) ON DELETE RESTRICT,
+    store_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft',
+    currency VARCHAR(3) NOT NULL,
+    total DECIMAL(15,2) NOT NULL DEFAULT 0,
+    expected_on DATE,
+    notes TEXT NOT NULL DEFAULT '',
+    created_by UUID,
+    submitted_at TIMESTAMP,
+    received_at TIMESTAMP,
+    closed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_purchase_orders_status CHECK (status IN ('draft', 'submitted', 'partially_received', 'received', 'closed', 'cancelled'))
+);
+
+CREATE TABLE purchase_order_lines (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    purchase_order_id UUID NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    product_id UUID NOT NULL,
+    supplier_sku VARCHAR(100) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    quantity INTEGER NOT NULL,
+    unit_cost DECIMAL(15,4) NOT NULL,
+    received_quantity INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT chk_purchase_order_lines_quantity CHECK (quantity > 0 AND unit_cost >= 0),
+    -- Goods beyond the quantity ordered are refused rather than received
+    CONSTRAINT chk_purchase_order_lines_received CHECK (received_quantity >= 0 AND received_quantity <= quantity)
+);
+
+CREATE TABLE goods_receipts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    purchase_order_id UUID NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    store_id UUID NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    received_by UUID,
+    received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    posted_at TIMESTAMP -- Set once inventory took the stock
+);
+
+CREATE TABLE goods_receipt_lines (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    receipt_id UUID NOT NULL REFERENCES goods_receipts(id) ON DELETE CASCADE,
+    line_id UUID NOT NULL REFERENCES purchase_order_lines(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL,
+    quantity INTEGER NOT NULL,
+    unit_cost DECIMAL(15,4) NOT NULL,
+    CONSTRAINT chk_goods_receipt_lines_quantity CHECK (quantity > 0 AND unit_cost >= 0)
+);
+
+-- Indexes for performance
+CREATE INDEX idx_purchase_orders_supplier ON purchase_orders(supplier_id, submitted_at);
+CREATE INDEX idx_purchase_orders_store ON purchase_orders(store_id, created_at DESC);
+CREATE INDEX idx_purchase_order_lines_order ON purchase_order_lines(purchase_order_id, position);
+CREATE INDEX idx_goods_receipts_order ON goods_receipts(purchase_order_id, received_at);
+CREATE INDEX idx_goods_receipts_unposted ON goods_receipts(received_at) WHERE posted_at IS NULL;
+CREATE INDEX idx_goods_receipt_lines_receipt ON goods_receipt_lines(receipt_id);
@@ -0,0 +1,12 @@
+-- Rollback procurement tenant scoping. Fails while a supplier code is used in
+-- several tenants.
+DROP INDEX IF EXISTS idx_purchase_orders_tenant_created_at;
+DROP INDEX IF EXISTS idx_purchase_orders_tenant_store;
+CREATE INDEX idx_purchase_orders_store ON purchase_orders(store_id, created_at DESC);
+
+DROP INDEX IF EXISTS idx_suppliers_tenant_code;
+CREATE UNIQUE INDEX idx_suppliers_code ON suppliers(UPPER(code));
+
+ALTER TABLE goods_receipts DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE purchase_orders DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE suppliers DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,13 @@
+-- Scope suppliers, purchase orders and goods receipts to a tenant; existing
+-- rows belong to the default tenant. Order and receipt lines follow their
+-- order or receipt. Supplier codes are unique within a tenant.
+ALTER TABLE suppliers ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE purchase_orders ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE goods_receipts ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+DROP INDEX IF EXISTS idx_suppliers_code;
+CREATE UNIQUE INDEX idx_suppliers_tenant_code ON suppliers(tenant_id, UPPER(code));
+
+DROP INDEX IF EXISTS idx_purchase_orders_store;
+CREATE INDEX idx_purchase_orders_tenant_store ON purchase_orders(tenant_id, store_id, created_at DESC);
+CREATE INDEX idx_purchase_orders_tenant_created_at ON purchase_orders(tenant_id, created_at DESC);
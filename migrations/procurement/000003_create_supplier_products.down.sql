@@ -0,0 +1,3 @@
+-- Rollback supplier products and reorder runs
+DROP TABLE IF EXISTS reorder_runs;
+DROP TABLE IF EXISTS supplier_products;
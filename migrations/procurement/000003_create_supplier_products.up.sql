@@ -0,0 +1,23 @@
+-- Products each supplier sells and at what cost, for reordering low stock
+-- from the cheapest active supplier. They go with their supplier.
+CREATE TABLE supplier_products (
+    supplier_id UUID NOT NULL REFERENCES suppliers(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL,
+    supplier_sku VARCHAR(100) NOT NULL DEFAULT '',
+    unit_cost DECIMAL(15,4) NOT NULL,
+    min_order_quantity INTEGER NOT NULL DEFAULT 1,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (supplier_id, product_id),
+    CONSTRAINT chk_supplier_products_cost CHECK (unit_cost >= 0 AND min_order_quantity > 0)
+);
+
+CREATE INDEX idx_supplier_products_tenant_product ON supplier_products(tenant_id, product_id, unit_cost);
+
+-- When each tenant's low stock was last reordered, so that one instance of
+-- the service reorders it per interval
+CREATE TABLE reorder_runs (
+    tenant_id VARCHAR(63) PRIMARY KEY,
+    ran_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,3 @@
+-- Rollback promotions table
+DROP TRIGGER IF EXISTS update_promotions_updated_at ON promotions;
+DROP TABLE IF EXISTS promotions;
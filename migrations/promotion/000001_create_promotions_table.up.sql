@@ -0,0 +1,29 @@
+-- Create promotions table
+CREATE TABLE promotions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    type VARCHAR(30) NOT NULL,
+    rule JSONB NOT NULL DEFAULT '{}',
+    priority INTEGER NOT NULL DEFAULT 0,
+    exclusive BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    store_ids UUID[] NOT NULL DEFAULT '{}', -- Empty runs in every store
+    currency VARCHAR(3),
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP,
+    CONSTRAINT chk_promotions_type CHECK (type IN ('bogo', 'basket_threshold', 'category_percent', 'happy_hour')),
+    CONSTRAINT chk_promotions_schedule CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at)
+);
+
+-- Indexes for performance
+CREATE INDEX idx_promotions_running ON promotions(priority DESC, created_at)
+    WHERE deleted_at IS NULL AND active;
+CREATE INDEX idx_promotions_store_ids ON promotions USING GIN (store_ids) WHERE deleted_at IS NULL;
+
+-- Update timestamp trigger
+CREATE TRIGGER update_promotions_updated_at BEFORE UPDATE ON promotions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,6 @@
+-- Rollback promotions tenant scoping
+DROP INDEX IF EXISTS idx_promotions_tenant_running;
+CREATE INDEX idx_promotions_running ON promotions(priority DESC, created_at)
+    WHERE deleted_at IS NULL AND active;
+
+ALTER TABLE promotions DROP COLUMN IF EXISTS tenant_id;
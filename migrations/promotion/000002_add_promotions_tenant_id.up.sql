@@ -0,0 +1,6 @@
+-- Scope promotions to a tenant; existing rows belong to the default tenant.
+ALTER TABLE promotions ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+DROP INDEX IF EXISTS idx_promotions_running;
+CREATE INDEX idx_promotions_tenant_running ON promotions(tenant_id, priority DESC, created_at)
+    WHERE deleted_at IS NULL AND active;
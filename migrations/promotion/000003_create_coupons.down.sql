@@ -0,0 +1,9 @@
+-- Rollback coupons
+DROP TABLE IF EXISTS coupon_redemptions;
+DROP TRIGGER IF EXISTS update_coupons_updated_at ON coupons;
+DROP TABLE IF EXISTS coupons;
+
+ALTER TABLE promotions DROP CONSTRAINT chk_promotions_type;
+ALTER TABLE promotions ADD CONSTRAINT chk_promotions_type
+    CHECK (type IN ('bogo', 'basket_threshold', 'category_percent', 'happy_hour'));
+ALTER TABLE promotions DROP COLUMN IF EXISTS coupon_only;
@@ -0,0 +1,41 @@
+-- Coupon codes unlocking promotions, and the orders that redeemed them
+ALTER TABLE promotions ADD COLUMN coupon_only BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE promotions DROP CONSTRAINT chk_promotions_type;
+ALTER TABLE promotions ADD CONSTRAINT chk_promotions_type
+    CHECK (type IN ('bogo', 'basket_threshold', 'category_percent', 'happy_hour', 'percent_off', 'amount_off'));
+
+CREATE TABLE coupons (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    code VARCHAR(64) NOT NULL, -- Upper case
+    promotion_id UUID NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+    max_uses INTEGER NOT NULL DEFAULT 0, -- 0 is unlimited
+    max_uses_per_user INTEGER NOT NULL DEFAULT 0,
+    uses INTEGER NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP,
+    CONSTRAINT chk_coupons_limits CHECK (max_uses >= 0 AND max_uses_per_user >= 0 AND uses >= 0)
+);
+
+-- One redemption of a coupon per order makes retried redemptions no-ops
+CREATE TABLE coupon_redemptions (
+    coupon_id UUID NOT NULL REFERENCES coupons(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    user_id UUID NOT NULL,
+    redeemed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (coupon_id, order_id)
+);
+
+-- Indexes for performance
+CREATE UNIQUE INDEX idx_coupons_tenant_code ON coupons(tenant_id, code) WHERE deleted_at IS NULL;
+CREATE INDEX idx_coupons_promotion_id ON coupons(promotion_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_coupon_redemptions_order_id ON coupon_redemptions(tenant_id, order_id);
+CREATE INDEX idx_coupon_redemptions_user_id ON coupon_redemptions(coupon_id, user_id);
+
+-- Update timestamp trigger
+CREATE TRIGGER update_coupons_updated_at BEFORE UPDATE ON coupons
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
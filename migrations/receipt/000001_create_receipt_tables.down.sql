@@ -0,0 +1,3 @@
+-- Rollback receipt templates and print jobs tables
+DROP TABLE IF EXISTS print_jobs;
+DROP TABLE IF EXISTS receipt_templates;
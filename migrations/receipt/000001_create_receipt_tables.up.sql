@@ -0,0 +1,35 @@
+-- Create receipt templates and print jobs tables
+CREATE TABLE receipt_templates (
+    store_id UUID PRIMARY KEY,
+    header TEXT[] NOT NULL DEFAULT '{}',
+    footer TEXT[] NOT NULL DEFAULT '{}',
+    width INTEGER NOT NULL DEFAULT 48,
+    show_barcode BOOLEAN NOT NULL DEFAULT TRUE,
+    open_drawer BOOLEAN NOT NULL DEFAULT FALSE,
+    drawer_pin INTEGER NOT NULL DEFAULT 2,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_receipt_templates_width CHECK (width BETWEEN 24 AND 64),
+    CONSTRAINT chk_receipt_templates_drawer_pin CHECK (drawer_pin IN (2, 5))
+);
+
+CREATE TABLE print_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    store_id UUID NOT NULL,
+    printer_id VARCHAR(64) NOT NULL DEFAULT '', -- Empty lets any of the store's agents print it
+    order_id UUID,
+    kind VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    payload BYTEA NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    claimed_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    CONSTRAINT chk_print_jobs_kind CHECK (kind IN ('receipt', 'drawer')),
+    CONSTRAINT chk_print_jobs_status CHECK (status IN ('queued', 'printing', 'printed', 'failed'))
+);
+
+-- Indexes for performance
+CREATE INDEX idx_print_jobs_pending ON print_jobs(store_id, created_at)
+    WHERE status IN ('queued', 'printing');
+CREATE INDEX idx_print_jobs_store ON print_jobs(store_id, created_at DESC);
@@ -0,0 +1,9 @@
+-- Rollback receipt tenant scoping
+DROP INDEX IF EXISTS idx_print_jobs_tenant_store;
+DROP INDEX IF EXISTS idx_print_jobs_tenant_pending;
+CREATE INDEX idx_print_jobs_pending ON print_jobs(store_id, created_at)
+    WHERE status IN ('queued', 'printing');
+CREATE INDEX idx_print_jobs_store ON print_jobs(store_id, created_at DESC);
+
+ALTER TABLE print_jobs DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE receipt_templates DROP COLUMN IF EXISTS tenant_id;
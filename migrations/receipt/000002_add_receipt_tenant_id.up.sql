@@ -0,0 +1,10 @@
+-- Scope receipt templates and print jobs to a tenant; existing rows belong
+-- to the default tenant.
+ALTER TABLE receipt_templates ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE print_jobs ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+DROP INDEX IF EXISTS idx_print_jobs_pending;
+DROP INDEX IF EXISTS idx_print_jobs_store;
+CREATE INDEX idx_print_jobs_tenant_pending ON print_jobs(tenant_id, store_id, created_at)
+    WHERE status IN ('queued', 'printing');
+CREATE INDEX idx_print_jobs_tenant_store ON print_jobs(tenant_id, store_id, created_at DESC);
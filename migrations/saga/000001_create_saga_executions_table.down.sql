@@ -0,0 +1,2 @@
+-- Rollback saga executions table migration
+DROP TABLE IF EXISTS saga_executions;
@@ -0,0 +1,25 @@
+-- Create saga executions table
+CREATE TABLE saga_executions (
+    id UUID PRIMARY KEY,
+    saga VARCHAR(100) NOT NULL,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL CHECK (status IN ('running', 'compensating', 'completed', 'compensated', 'failed')),
+    -- Steps taken while running, steps left to compensate while compensating
+    step INTEGER NOT NULL DEFAULT 0 CHECK (step >= 0),
+    data JSONB NOT NULL DEFAULT '{}',
+    failed_step VARCHAR(100) NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    deadline_at TIMESTAMPTZ,
+    -- The instance running the saga, until lease_until
+    owner UUID NOT NULL,
+    lease_until TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX idx_saga_executions_lease ON saga_executions(lease_until)
+    WHERE status IN ('running', 'compensating');
+CREATE INDEX idx_saga_executions_tenant_id ON saga_executions(tenant_id, created_at);
+CREATE INDEX idx_saga_executions_failed ON saga_executions(saga, updated_at)
+    WHERE status = 'failed';
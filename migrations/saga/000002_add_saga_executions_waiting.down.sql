@@ -0,0 +1,2 @@
+-- Rollback waiting saga executions
+ALTER TABLE saga_executions DROP COLUMN IF EXISTS waiting;
@@ -0,0 +1,4 @@
+-- Mark executions whose step awaits its outcome (saga.Await). A waiting
+-- execution's lease runs until its step is retried; waking it claims it
+-- before then.
+ALTER TABLE saga_executions ADD COLUMN waiting BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+-- Rollback register shifts, cash movements and shift sales tables
+DROP TABLE IF EXISTS shift_sales;
+DROP TABLE IF EXISTS cash_movements;
+DROP TABLE IF EXISTS shifts;
@@ -0,0 +1,50 @@
+-- Create register shifts, cash movements and shift sales tables
+CREATE TABLE shifts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    store_id UUID NOT NULL,
+    register_id VARCHAR(64) NOT NULL,
+    staff_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    currency VARCHAR(3) NOT NULL,
+    opening_float DECIMAL(15,2) NOT NULL DEFAULT 0,
+    counted_cash DECIMAL(15,2),
+    notes TEXT NOT NULL DEFAULT '',
+    z_report JSONB, -- Taken at close and never changed
+    opened_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    closed_at TIMESTAMP,
+    closed_by UUID,
+    CONSTRAINT chk_shifts_status CHECK (status IN ('open', 'closed')),
+    CONSTRAINT chk_shifts_opening_float CHECK (opening_float >= 0)
+);
+
+CREATE TABLE cash_movements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    shift_id UUID NOT NULL REFERENCES shifts(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    staff_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_cash_movements_type CHECK (type IN ('pay_in', 'pay_out', 'drop')),
+    CONSTRAINT chk_cash_movements_amount CHECK (amount > 0)
+);
+
+-- One row per order rung up on a shift, kept from the order's events
+CREATE TABLE shift_sales (
+    order_id UUID PRIMARY KEY,
+    shift_id UUID NOT NULL REFERENCES shifts(id) ON DELETE CASCADE,
+    tender VARCHAR(32) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'completed',
+    recorded_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_shift_sales_status CHECK (status IN ('completed', 'voided', 'refunded'))
+);
+
+-- A register has at most one open shift
+CREATE UNIQUE INDEX idx_shifts_open_register ON shifts(store_id, register_id) WHERE status = 'open';
+
+-- Indexes for performance
+CREATE INDEX idx_shifts_store ON shifts(store_id, opened_at DESC);
+CREATE INDEX idx_cash_movements_shift ON cash_movements(shift_id, created_at);
+CREATE INDEX idx_shift_sales_shift ON shift_sales(shift_id);
@@ -0,0 +1,2 @@
+-- Rollback shift sales tax
+ALTER TABLE shift_sales DROP COLUMN IF EXISTS tax;
@@ -0,0 +1,2 @@
+-- Record the tax charged on each sale for X and Z reports
+ALTER TABLE shift_sales ADD COLUMN tax DECIMAL(15,2) NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+-- Rollback shifts tenant scoping
+DROP INDEX IF EXISTS idx_shifts_tenant_store;
+DROP INDEX IF EXISTS idx_shifts_tenant_open_register;
+CREATE UNIQUE INDEX idx_shifts_open_register ON shifts(store_id, register_id) WHERE status = 'open';
+CREATE INDEX idx_shifts_store ON shifts(store_id, opened_at DESC);
+
+ALTER TABLE shift_sales DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE cash_movements DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE shifts DROP COLUMN IF EXISTS tenant_id;
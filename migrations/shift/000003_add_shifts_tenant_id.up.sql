@@ -0,0 +1,11 @@
+-- Scope shifts, cash movements and shift sales to a tenant; existing rows
+-- belong to the default tenant.
+ALTER TABLE shifts ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE cash_movements ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE shift_sales ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+-- A register has at most one open shift within a tenant
+DROP INDEX IF EXISTS idx_shifts_open_register;
+DROP INDEX IF EXISTS idx_shifts_store;
+CREATE UNIQUE INDEX idx_shifts_tenant_open_register ON shifts(tenant_id, store_id, register_id) WHERE status = 'open';
+CREATE INDEX idx_shifts_tenant_store ON shifts(tenant_id, store_id, opened_at DESC);
@@ -0,0 +1,3 @@
+-- Rollback time clock tables
+DROP TABLE IF EXISTS time_entry_adjustments;
+DROP TABLE IF EXISTS time_entries;
@@ -0,0 +1,37 @@
+-- Create the staff time clock: entries from clock-in to clock-out, with
+-- their breaks, and the audit trail of managers' adjustments
+CREATE TABLE time_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    store_id UUID NOT NULL,
+    staff_id UUID NOT NULL,
+    shift_id UUID REFERENCES shifts(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    clock_in TIMESTAMP NOT NULL,
+    clock_out TIMESTAMP,
+    breaks JSONB NOT NULL DEFAULT '[]',
+    notes TEXT NOT NULL DEFAULT '',
+    version INTEGER NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_time_entries_status CHECK (status IN ('open', 'closed')),
+    CONSTRAINT chk_time_entries_clock_out CHECK (clock_out IS NULL OR clock_out >= clock_in)
+);
+
+CREATE TABLE time_entry_adjustments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    entry_id UUID NOT NULL REFERENCES time_entries(id) ON DELETE CASCADE,
+    adjusted_by UUID NOT NULL,
+    reason TEXT NOT NULL,
+    before JSONB NOT NULL,
+    after JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- A staff member is clocked in at most once within a tenant
+CREATE UNIQUE INDEX idx_time_entries_tenant_open_staff ON time_entries(tenant_id, staff_id) WHERE status = 'open';
+
+-- Indexes for performance
+CREATE INDEX idx_time_entries_tenant_clock_in ON time_entries(tenant_id, clock_in DESC);
+CREATE INDEX idx_time_entries_tenant_staff ON time_entries(tenant_id, staff_id, clock_in DESC);
+CREATE INDEX idx_time_entry_adjustments_entry ON time_entry_adjustments(entry_id, created_at);
@@ -0,0 +1,6 @@
+-- Rollback stores tenant scoping. Fails while a code is used in several
+-- tenants.
+DROP INDEX IF EXISTS idx_stores_tenant_created_at;
+DROP INDEX IF EXISTS idx_stores_tenant_code;
+ALTER TABLE stores ADD CONSTRAINT stores_code_key UNIQUE (code);
+ALTER TABLE stores DROP COLUMN IF EXISTS tenant_id;
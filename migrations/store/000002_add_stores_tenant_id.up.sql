@@ -0,0 +1,7 @@
+-- Scope stores to a tenant; existing rows belong to the default tenant.
+-- Store codes are unique within a tenant.
+ALTER TABLE stores ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+ALTER TABLE stores DROP CONSTRAINT IF EXISTS stores_code_key;
+CREATE UNIQUE INDEX idx_stores_tenant_code ON stores(tenant_id, code);
+CREATE INDEX idx_stores_tenant_created_at ON stores(tenant_id, created_at) WHERE deleted_at IS NULL;
@@ -0,0 +1,35 @@
+-- Create the terminals, printers and scanners paired to stores. Only
+-- SHA-256 hashes of pairing codes and API keys are stored.
+CREATE TABLE devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    store_id UUID NOT NULL REFERENCES stores(id),
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    serial_number VARCHAR(100) NOT NULL DEFAULT '',
+    -- Pairing, while pending
+    pairing_code_hash VARCHAR(64),
+    pairing_expires_at TIMESTAMP,
+    -- API key, once paired; kept on deactivation so the device is told it is locked out
+    key_hash VARCHAR(64),
+    key_prefix VARCHAR(16) NOT NULL DEFAULT '',
+    -- Heartbeats
+    app_version VARCHAR(50) NOT NULL DEFAULT '',
+    last_ip VARCHAR(45) NOT NULL DEFAULT '',
+    last_seen_at TIMESTAMP,
+    paired_at TIMESTAMP,
+    deactivated_at TIMESTAMP,
+    deactivation_reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_devices_type CHECK (type IN ('terminal', 'printer', 'scanner')),
+    CONSTRAINT chk_devices_status CHECK (status IN ('pending', 'active', 'deactivated'))
+);
+
+CREATE INDEX idx_devices_tenant_store ON devices(tenant_id, store_id, created_at);
+CREATE UNIQUE INDEX idx_devices_tenant_pairing_code ON devices(tenant_id, pairing_code_hash) WHERE pairing_code_hash IS NOT NULL;
+CREATE UNIQUE INDEX idx_devices_key_hash ON devices(key_hash) WHERE key_hash IS NOT NULL;
+
+CREATE TRIGGER update_devices_updated_at BEFORE UPDATE ON devices
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
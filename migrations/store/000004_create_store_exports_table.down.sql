@@ -0,0 +1,2 @@
+-- Rollback store exports table
+DROP TABLE IF EXISTS store_exports;
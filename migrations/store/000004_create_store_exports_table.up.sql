@@ -0,0 +1,21 @@
+-- Create the store data exports built in the background. The archives are
+-- files named after the tenant and export ID; only their metadata is here.
+CREATE TABLE store_exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    store_id UUID NOT NULL REFERENCES stores(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    records JSONB NOT NULL DEFAULT '{}',
+    size BIGINT NOT NULL DEFAULT 0,
+    checksum VARCHAR(64) NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    CONSTRAINT chk_store_exports_status CHECK (status IN ('pending', 'running', 'completed', 'failed'))
+);
+
+CREATE INDEX idx_store_exports_tenant_store ON store_exports(tenant_id, store_id, created_at DESC);
+CREATE INDEX idx_store_exports_unfinished ON store_exports(created_at) WHERE status IN ('pending', 'running');
+CREATE INDEX idx_store_exports_expires ON store_exports(expires_at) WHERE expires_at IS NOT NULL;
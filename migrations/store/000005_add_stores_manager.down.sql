@@ -0,0 +1,2 @@
+-- Rollback stores manager
+ALTER TABLE stores DROP COLUMN IF EXISTS manager_id;
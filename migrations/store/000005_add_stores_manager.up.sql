@@ -0,0 +1,3 @@
+-- The manager of a store is told of the purchase orders raised for it
+-- automatically when its stock runs low
+ALTER TABLE stores ADD COLUMN manager_id UUID;
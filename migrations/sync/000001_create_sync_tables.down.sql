@@ -0,0 +1,3 @@
+-- Rollback the sync change feeds
+DROP TABLE IF EXISTS sync_changes;
+DROP TABLE IF EXISTS sync_feeds;
@@ -0,0 +1,32 @@
+-- Create the change feeds terminals sync from. A tenant's feed numbers its
+-- changes in commit order; sync_changes keeps only the latest change of each
+-- entity, so a terminal that was offline downloads each entity once.
+
+-- Each tenant's last sequence number, and the highest sequence number of the
+-- tombstones pruned from its feed. Terminals that last synced below the
+-- horizon have missed a deletion and must download a full snapshot.
+CREATE TABLE sync_feeds (
+    tenant_id VARCHAR(63) PRIMARY KEY,
+    last_seq BIGINT NOT NULL DEFAULT 0,
+    horizon BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE sync_changes (
+    tenant_id VARCHAR(63) NOT NULL,
+    entity VARCHAR(16) NOT NULL,
+    entity_id UUID NOT NULL,
+    -- The store whose feed the change belongs to; the nil UUID for entities
+    -- every store shares, such as products
+    store_key UUID NOT NULL,
+    seq BIGINT NOT NULL,
+    op VARCHAR(8) NOT NULL,
+    data JSONB,
+    version BIGINT NOT NULL, -- Time of the event, in microseconds
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, entity, entity_id, store_key),
+    CONSTRAINT chk_sync_changes_entity CHECK (entity IN ('product', 'price', 'stock')),
+    CONSTRAINT chk_sync_changes_op CHECK (op IN ('upsert', 'delete'))
+);
+
+CREATE INDEX idx_sync_changes_feed ON sync_changes(tenant_id, seq);
+CREATE INDEX idx_sync_changes_tombstones ON sync_changes(changed_at) WHERE op = 'delete';
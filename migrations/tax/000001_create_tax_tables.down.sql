@@ -0,0 +1,6 @@
+-- Rollback tax jurisdictions, categories, holidays and the filing ledger
+DROP TABLE IF EXISTS tax_ledger;
+DROP TABLE IF EXISTS tax_holidays;
+DROP TABLE IF EXISTS tax_assignments;
+DROP TABLE IF EXISTS tax_categories;
+DROP TABLE IF EXISTS tax_jurisdictions;
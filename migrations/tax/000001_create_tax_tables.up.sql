@@ -0,0 +1,74 @@
+-- Create tax jurisdictions, categories, holidays and the filing ledger
+CREATE TABLE tax_jurisdictions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    country VARCHAR(2) NOT NULL,
+    state VARCHAR(8) NOT NULL DEFAULT '',
+    city VARCHAR(100) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL,
+    rate DECIMAL(7,6) NOT NULL DEFAULT 0,
+    category_rates JSONB NOT NULL DEFAULT '{}', -- Rates by tax category, replacing the standard one
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_tax_jurisdictions_rate CHECK (rate >= 0 AND rate < 1),
+    CONSTRAINT chk_tax_jurisdictions_city CHECK (city = '' OR state <> '')
+);
+
+CREATE TABLE tax_categories (
+    code VARCHAR(32) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    exempt BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Tax categories of catalog variants and of whole catalog categories
+CREATE TABLE tax_assignments (
+    subject_id UUID NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    category VARCHAR(32) NOT NULL REFERENCES tax_categories(code) ON DELETE CASCADE,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (kind, subject_id),
+    CONSTRAINT chk_tax_assignments_kind CHECK (kind IN ('product', 'catalog_category'))
+);
+
+CREATE TABLE tax_holidays (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    jurisdiction_id UUID NOT NULL REFERENCES tax_jurisdictions(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    category VARCHAR(32) NOT NULL DEFAULT '', -- Empty covers every category
+    starts_on DATE NOT NULL,
+    ends_on DATE NOT NULL,
+    rate DECIMAL(7,6) NOT NULL DEFAULT 0,
+    max_unit_price DECIMAL(15,2) NOT NULL DEFAULT 0, -- 0 covers items of any price
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_tax_holidays_dates CHECK (ends_on >= starts_on),
+    CONSTRAINT chk_tax_holidays_rate CHECK (rate >= 0 AND rate < 1)
+);
+
+-- Tax collected on each order by jurisdiction, kept from the order's events.
+-- A reversal takes a cancelled or refunded sale off the period it happened in.
+CREATE TABLE tax_ledger (
+    order_id UUID NOT NULL,
+    jurisdiction_id UUID NOT NULL REFERENCES tax_jurisdictions(id),
+    kind VARCHAR(20) NOT NULL,
+    store_id UUID NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    taxable DECIMAL(15,2) NOT NULL DEFAULT 0,
+    exempt DECIMAL(15,2) NOT NULL DEFAULT 0,
+    tax DECIMAL(15,2) NOT NULL DEFAULT 0,
+    occurred_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (order_id, jurisdiction_id, kind),
+    CONSTRAINT chk_tax_ledger_kind CHECK (kind IN ('sale', 'reversal'))
+);
+
+-- A location has at most one jurisdiction at each level
+CREATE UNIQUE INDEX idx_tax_jurisdictions_location ON tax_jurisdictions(country, state, LOWER(city));
+
+-- Indexes for performance
+CREATE INDEX idx_tax_holidays_jurisdiction ON tax_holidays(jurisdiction_id, starts_on);
+CREATE INDEX idx_tax_ledger_occurred_at ON tax_ledger(occurred_at);
+
+INSERT INTO tax_categories (code, name, description, exempt) VALUES
+    ('standard', 'Standard', 'Products with no other tax category', FALSE),
+    ('exempt', 'Exempt', 'Products exempt from sales tax everywhere', TRUE);
@@ -0,0 +1,7 @@
+-- Rollback tax tenant scoping
+DROP INDEX IF EXISTS idx_tax_assignments_tenant_updated_at;
+DROP INDEX IF EXISTS idx_tax_ledger_tenant_occurred_at;
+CREATE INDEX idx_tax_ledger_occurred_at ON tax_ledger(occurred_at);
+
+ALTER TABLE tax_ledger DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE tax_assignments DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,8 @@
+-- Scope tax assignments and the ledger to a tenant; existing rows belong to
+-- the default tenant. Jurisdictions, holidays and categories stay shared.
+ALTER TABLE tax_assignments ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE tax_ledger ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+DROP INDEX IF EXISTS idx_tax_ledger_occurred_at;
+CREATE INDEX idx_tax_ledger_tenant_occurred_at ON tax_ledger(tenant_id, occurred_at);
+CREATE INDEX idx_tax_assignments_tenant_updated_at ON tax_assignments(tenant_id, updated_at DESC);
@@ -0,0 +1,2 @@
+-- Rollback store tax rates
+DROP TABLE IF EXISTS tax_rates;
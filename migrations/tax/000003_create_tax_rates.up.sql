@@ -0,0 +1,15 @@
+-- How stores levy a jurisdiction's tax when it differs from the
+-- jurisdiction's own rates: at other rates, exempting more categories, or
+-- included in the store's prices
+CREATE TABLE tax_rates (
+    store_id UUID NOT NULL,
+    jurisdiction_id UUID NOT NULL REFERENCES tax_jurisdictions(id) ON DELETE CASCADE,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    rate DECIMAL(7,6), -- Replaces the standard rate; NULL keeps the jurisdiction's
+    category_rates JSONB NOT NULL DEFAULT '{}',
+    exempt_categories TEXT[] NOT NULL DEFAULT '{}',
+    inclusive BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, store_id, jurisdiction_id),
+    CONSTRAINT chk_tax_rates_rate CHECK (rate IS NULL OR (rate >= 0 AND rate < 1))
+);
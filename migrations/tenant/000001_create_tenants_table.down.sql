@@ -0,0 +1,2 @@
+-- Rollback tenants table migration
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,15 @@
+-- Create the tenant registry
+CREATE TABLE tenants (
+    id VARCHAR(63) PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'suspended')),
+    -- Requests per minute for each client of the tenant; 0 uses the gateway-wide limit
+    rate_limit_requests_per_minute INTEGER NOT NULL DEFAULT 0 CHECK (rate_limit_requests_per_minute >= 0),
+    settings JSONB NOT NULL DEFAULT '{}',
+    -- Timestamps
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Rows written before tenants existed belong to the default tenant
+INSERT INTO tenants (id, name) VALUES ('default', 'Default');
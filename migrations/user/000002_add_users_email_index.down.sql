@@ -0,0 +1,5 @@
+-- Rollback the users email blind index. Columns stay TEXT, as encrypted values
+-- would not fit the original sizes.
+DROP INDEX IF EXISTS idx_users_email_index_missing;
+DROP INDEX IF EXISTS idx_users_email_index;
+ALTER TABLE users DROP COLUMN IF EXISTS email_index;
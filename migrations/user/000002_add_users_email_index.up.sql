@@ -0,0 +1,9 @@
+-- Encrypted email and phone: ciphertexts outgrow the original column sizes,
+-- and emails are looked up through a blind index (HMAC of the normalized
+-- address) filled on write and by user-service -backfill-blind-index
+ALTER TABLE users ALTER COLUMN email TYPE TEXT;
+ALTER TABLE users ALTER COLUMN phone TYPE TEXT;
+ALTER TABLE users ADD COLUMN email_index VARCHAR(64);
+
+CREATE UNIQUE INDEX idx_users_email_index ON users(email_index) WHERE deleted_at IS NULL;
+CREATE INDEX idx_users_email_index_missing ON users(id) WHERE email_index IS NULL;
@@ -0,0 +1,2 @@
+-- Rollback users roles
+ALTER TABLE users DROP COLUMN IF EXISTS roles;
@@ -0,0 +1,3 @@
+-- Roles carried in the tokens issued at login; admins are made with
+-- omnictl user create-admin
+ALTER TABLE users ADD COLUMN roles TEXT[] NOT NULL DEFAULT '{user}';
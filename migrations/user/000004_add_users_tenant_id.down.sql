@@ -0,0 +1,8 @@
+-- Rollback users tenant scoping. Fails while an email is used in several
+-- tenants.
+DROP INDEX IF EXISTS idx_users_tenant_id;
+DROP INDEX IF EXISTS idx_users_tenant_email;
+DROP INDEX IF EXISTS idx_users_tenant_email_index;
+CREATE UNIQUE INDEX idx_users_email_index ON users(email_index) WHERE deleted_at IS NULL;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
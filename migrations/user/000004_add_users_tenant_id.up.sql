@@ -0,0 +1,10 @@
+-- Scope users to a tenant; existing rows belong to the default tenant.
+-- Emails are unique within a tenant, so one address can sign up with
+-- several brands.
+ALTER TABLE users ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+DROP INDEX IF EXISTS idx_users_email_index;
+CREATE UNIQUE INDEX idx_users_tenant_email_index ON users(tenant_id, email_index) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_users_tenant_email ON users(tenant_id, email) WHERE email_index IS NULL AND deleted_at IS NULL;
+CREATE INDEX idx_users_tenant_id ON users(tenant_id, id) WHERE deleted_at IS NULL;
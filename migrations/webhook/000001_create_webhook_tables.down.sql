@@ -0,0 +1,4 @@
+-- Rollback partner webhook subscriptions, deliveries and attempts
+DROP TABLE IF EXISTS webhook_delivery_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
@@ -0,0 +1,8 @@
+-- Rollback webhook tenant scoping
+DROP INDEX IF EXISTS idx_webhook_deliveries_tenant_created;
+DROP INDEX IF EXISTS idx_webhook_subscriptions_tenant_partner;
+CREATE INDEX idx_webhook_subscriptions_partner ON webhook_subscriptions(partner_id, created_at);
+CREATE INDEX idx_webhook_deliveries_created ON webhook_deliveries(created_at DESC);
+
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,9 @@
+-- Scope webhook subscriptions and deliveries to a tenant; existing rows
+-- belong to the default tenant. Attempts follow their delivery.
+ALTER TABLE webhook_subscriptions ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE webhook_deliveries ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+DROP INDEX IF EXISTS idx_webhook_subscriptions_partner;
+DROP INDEX IF EXISTS idx_webhook_deliveries_created;
+CREATE INDEX idx_webhook_subscriptions_tenant_partner ON webhook_subscriptions(tenant_id, partner_id, created_at);
+CREATE INDEX idx_webhook_deliveries_tenant_created ON webhook_deliveries(tenant_id, created_at DESC);
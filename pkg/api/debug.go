@@ -0,0 +1,25 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/middleware"
+)
+
+// SetupDebugConfig exposes the effective configuration, with secrets masked, to admins
+func SetupDebugConfig(app *fiber.App, jwtManager *auth.JWTManager, current func() *config.Config) {
+	app.Get("/debug/config",
+		middleware.JWTAuth(jwtManager),
+		middleware.RequireRole("admin"),
+		func(c *fiber.Ctx) error {
+			body, err := current().EffectiveYAML()
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "failed to render configuration")
+			}
+			c.Set("Content-Type", "application/x-yaml")
+			return c.Send(body)
+		},
+	)
+}
@@ -0,0 +1,242 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package analytics is a client of the Analytics operations of the API.
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Analytics requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Analytics operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// GetRevenue sends GET /analytics/revenue: revenue trend
+func (c *Client) GetRevenue(ctx context.Context, params *GetRevenueParams) (*GetRevenueResponse, error) {
+	var out GetRevenueResponse
+	if err := c.client.Do(ctx, "GET", "/analytics/revenue", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetRevenueParams are the query parameters of GetRevenue
+type GetRevenueParams struct {
+	Granularity *string
+	From        *string
+	To          *string
+	StoreID     *uuid.UUID
+	Currency    *string
+}
+
+func (p *GetRevenueParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Granularity != nil {
+		q.Set("granularity", *p.Granularity)
+	}
+	if p.From != nil {
+		q.Set("from", *p.From)
+	}
+	if p.To != nil {
+		q.Set("to", *p.To)
+	}
+	if p.StoreID != nil {
+		q.Set("store_id", (*p.StoreID).String())
+	}
+	if p.Currency != nil {
+		q.Set("currency", *p.Currency)
+	}
+	return q
+}
+
+// GetTopProducts sends GET /analytics/top-products: top products
+func (c *Client) GetTopProducts(ctx context.Context, params *GetTopProductsParams) (*GetTopProductsResponse, error) {
+	var out GetTopProductsResponse
+	if err := c.client.Do(ctx, "GET", "/analytics/top-products", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTopProductsParams are the query parameters of GetTopProducts
+type GetTopProductsParams struct {
+	By       *string
+	From     *string
+	To       *string
+	StoreID  *uuid.UUID
+	Currency *string
+	Limit    *int
+}
+
+func (p *GetTopProductsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.By != nil {
+		q.Set("by", *p.By)
+	}
+	if p.From != nil {
+		q.Set("from", *p.From)
+	}
+	if p.To != nil {
+		q.Set("to", *p.To)
+	}
+	if p.StoreID != nil {
+		q.Set("store_id", (*p.StoreID).String())
+	}
+	if p.Currency != nil {
+		q.Set("currency", *p.Currency)
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	return q
+}
+
+// GetConversion sends GET /analytics/conversion: conversion
+func (c *Client) GetConversion(ctx context.Context, params *GetConversionParams) (*GetConversionResponse, error) {
+	var out GetConversionResponse
+	if err := c.client.Do(ctx, "GET", "/analytics/conversion", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetConversionParams are the query parameters of GetConversion
+type GetConversionParams struct {
+	From     *string
+	To       *string
+	StoreID  *uuid.UUID
+	Currency *string
+}
+
+func (p *GetConversionParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.From != nil {
+		q.Set("from", *p.From)
+	}
+	if p.To != nil {
+		q.Set("to", *p.To)
+	}
+	if p.StoreID != nil {
+		q.Set("store_id", (*p.StoreID).String())
+	}
+	if p.Currency != nil {
+		q.Set("currency", *p.Currency)
+	}
+	return q
+}
+
+// GetStockActivity sends GET /analytics/stock: stock activity
+func (c *Client) GetStockActivity(ctx context.Context, params *GetStockActivityParams) (*GetStockActivityResponse, error) {
+	var out GetStockActivityResponse
+	if err := c.client.Do(ctx, "GET", "/analytics/stock", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetStockActivityParams are the query parameters of GetStockActivity
+type GetStockActivityParams struct {
+	From    *string
+	To      *string
+	StoreID *uuid.UUID
+	Limit   *int
+}
+
+func (p *GetStockActivityParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.From != nil {
+		q.Set("from", *p.From)
+	}
+	if p.To != nil {
+		q.Set("to", *p.To)
+	}
+	if p.StoreID != nil {
+		q.Set("store_id", (*p.StoreID).String())
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	return q
+}
+
+// GetTimesheets sends GET /analytics/timesheets: weekly timesheets
+func (c *Client) GetTimesheets(ctx context.Context, params *GetTimesheetsParams) (*GetTimesheetsResponse, error) {
+	var out GetTimesheetsResponse
+	if err := c.client.Do(ctx, "GET", "/analytics/timesheets", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTimesheetsParams are the query parameters of GetTimesheets
+type GetTimesheetsParams struct {
+	Week    *string
+	StoreID *uuid.UUID
+	Format  *string
+}
+
+func (p *GetTimesheetsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Week != nil {
+		q.Set("week", *p.Week)
+	}
+	if p.StoreID != nil {
+		q.Set("store_id", (*p.StoreID).String())
+	}
+	if p.Format != nil {
+		q.Set("format", *p.Format)
+	}
+	return q
+}
+
+// GetRevenueResponse is generated from #/paths/~1analytics~1revenue/get/responses/200
+type GetRevenueResponse struct {
+	Data []apiclient.SalesPoint `json:"data,omitempty"`
+}
+
+// GetTopProductsResponse is generated from #/paths/~1analytics~1top-products/get/responses/200
+type GetTopProductsResponse struct {
+	Data []apiclient.ProductSales `json:"data,omitempty"`
+}
+
+// GetConversionResponse is generated from #/paths/~1analytics~1conversion/get/responses/200
+type GetConversionResponse struct {
+	Data apiclient.Conversion `json:"data,omitempty"`
+}
+
+// GetStockActivityResponse is generated from #/paths/~1analytics~1stock/get/responses/200
+type GetStockActivityResponse struct {
+	Data []apiclient.StockActivity `json:"data,omitempty"`
+}
+
+// GetTimesheetsResponse is generated from #/paths/~1analytics~1timesheets/get/responses/200
+type GetTimesheetsResponse struct {
+	Data      []apiclient.Timesheet `json:"data,omitempty"`
+	WeekStart string                `json:"week_start,omitempty"`
+}
@@ -0,0 +1,79 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package authentication is a client of the Authentication operations of the API.
+package authentication
+
+import (
+	"context"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Authentication requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Authentication operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// Login sends POST /auth/login: user login
+func (c *Client) Login(ctx context.Context, body *LoginRequest) (*LoginResponse, error) {
+	var out LoginResponse
+	if err := c.client.Do(ctx, "POST", "/auth/login", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RefreshToken sends POST /auth/refresh: refresh access token
+func (c *Client) RefreshToken(ctx context.Context, body *RefreshTokenRequest) (*RefreshTokenResponse, error) {
+	var out RefreshTokenResponse
+	if err := c.client.Do(ctx, "POST", "/auth/refresh", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Logout sends POST /auth/logout: user logout
+func (c *Client) Logout(ctx context.Context, body *LogoutRequest) error {
+	return c.client.Do(ctx, "POST", "/auth/logout", nil, body, nil)
+}
+
+// LoginResponse is generated from #/paths/~1auth~1login/post/responses/200
+type LoginResponse struct {
+	User         apiclient.User `json:"user,omitempty"`
+	AccessToken  string         `json:"access_token,omitempty"`
+	RefreshToken string         `json:"refresh_token,omitempty"`
+	// When the access token expires
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// LoginRequest is generated from #/paths/~1auth~1login/post/requestBody
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	// MFA code if MFA is enabled
+	MFACode *string `json:"mfa_code,omitempty"`
+}
+
+// RefreshTokenResponse is generated from #/paths/~1auth~1refresh/post/responses/200
+type RefreshTokenResponse struct {
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// When the access token expires
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// RefreshTokenRequest is generated from #/paths/~1auth~1refresh/post/requestBody
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// LogoutRequest is generated from #/paths/~1auth~1logout/post/requestBody
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -0,0 +1,152 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package catalog is a client of the Catalog operations of the API.
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Catalog requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Catalog operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// ListCategories sends GET /categories: list categories
+func (c *Client) ListCategories(ctx context.Context) (*ListCategoriesResponse, error) {
+	var out ListCategoriesResponse
+	if err := c.client.Do(ctx, "GET", "/categories", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateCategory sends POST /categories: create category
+func (c *Client) CreateCategory(ctx context.Context, body *CreateCategoryRequest) (*apiclient.Category, error) {
+	var out apiclient.Category
+	if err := c.client.Do(ctx, "POST", "/categories", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListProducts sends GET /products: list products
+func (c *Client) ListProducts(ctx context.Context, params *ListProductsParams) (*ListProductsResponse, error) {
+	var out ListProductsResponse
+	if err := c.client.Do(ctx, "GET", "/products", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListProductsParams are the query parameters of ListProducts
+type ListProductsParams struct {
+	CategoryID *uuid.UUID
+	Limit      *int
+	Offset     *int
+}
+
+func (p *ListProductsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.CategoryID != nil {
+		q.Set("category_id", (*p.CategoryID).String())
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// CreateProduct sends POST /products: create product
+func (c *Client) CreateProduct(ctx context.Context, body *apiclient.CreateProductRequest) (*apiclient.Product, error) {
+	var out apiclient.Product
+	if err := c.client.Do(ctx, "POST", "/products", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetProduct sends GET /products/{id}: get product by ID
+func (c *Client) GetProduct(ctx context.Context, id uuid.UUID) (*apiclient.Product, error) {
+	var out apiclient.Product
+	if err := c.client.Do(ctx, "GET", "/products/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// LookupVariant sends GET /variants/lookup: look up a variant
+func (c *Client) LookupVariant(ctx context.Context, params *LookupVariantParams) (*apiclient.Variant, error) {
+	var out apiclient.Variant
+	if err := c.client.Do(ctx, "GET", "/variants/lookup", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// LookupVariantParams are the query parameters of LookupVariant
+type LookupVariantParams struct {
+	SKU     *string
+	Barcode *string
+}
+
+func (p *LookupVariantParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.SKU != nil {
+		q.Set("sku", *p.SKU)
+	}
+	if p.Barcode != nil {
+		q.Set("barcode", *p.Barcode)
+	}
+	return q
+}
+
+// GetPriceList sends GET /stores/{storeId}/prices: get a store's price list
+func (c *Client) GetPriceList(ctx context.Context, storeID uuid.UUID) (*GetPriceListResponse, error) {
+	var out GetPriceListResponse
+	if err := c.client.Do(ctx, "GET", "/stores/"+url.PathEscape(storeID.String())+"/prices", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListCategoriesResponse is generated from #/paths/~1categories/get/responses/200
+type ListCategoriesResponse struct {
+	Data []apiclient.Category `json:"data,omitempty"`
+}
+
+// CreateCategoryRequest is generated from #/paths/~1categories/post/requestBody
+type CreateCategoryRequest struct {
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+	Name     string     `json:"name"`
+	Slug     string     `json:"slug"`
+}
+
+// ListProductsResponse is generated from #/paths/~1products/get/responses/200
+type ListProductsResponse struct {
+	Data []apiclient.Product `json:"data,omitempty"`
+}
+
+// GetPriceListResponse is generated from #/paths/~1stores~1{storeId}~1prices/get/responses/200
+type GetPriceListResponse struct {
+	Data []apiclient.Price `json:"data,omitempty"`
+}
@@ -0,0 +1,88 @@
+package apiclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/middleware"
+)
+
+func TestClientDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token-1", r.Header.Get("Authorization"))
+		assert.Equal(t, "/api/v1/orders", r.URL.Path)
+		assert.Equal(t, "5", r.URL.Query().Get("limit"))
+		w.Write([]byte(`{"limit":5}`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL+"/api/v1", WithToken("token-1"))
+	var out struct {
+		Limit int `json:"limit"`
+	}
+	require.NoError(t, client.Do(context.Background(), http.MethodGet, "/orders", url.Values{"limit": {"5"}}, nil, &out))
+	assert.Equal(t, 5, out.Limit)
+}
+
+func TestClientDoReturnsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"Order not found"}`))
+	}))
+	defer server.Close()
+
+	err := New(server.URL).Do(context.Background(), http.MethodGet, "/orders/1", nil, nil, nil)
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.Status)
+	assert.Equal(t, "Order not found", apiErr.Message)
+	assert.Equal(t, "GET /orders/1 returned 404: Order not found", err.Error())
+}
+
+func TestClientSendsRawBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/csv", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "product_id\n1\n", string(body))
+		w.Write([]byte(`{"rows":1}`))
+	}))
+	defer server.Close()
+
+	var out struct {
+		Rows int `json:"rows"`
+	}
+	body := &RawBody{ContentType: "text/csv", Data: []byte("product_id\n1\n")}
+	require.NoError(t, New(server.URL).Do(context.Background(), http.MethodPost, "/inventory/import", nil, body, &out))
+	assert.Equal(t, 1, out.Rows)
+}
+
+func TestClientSignsPartnerRequests(t *testing.T) {
+	app := fiber.New()
+	app.Post("/partner/webhooks/subscriptions",
+		middleware.VerifySignature(middleware.StaticSecrets(map[string]string{"partner-1": "s3cret"}), 5*time.Minute, nil),
+		func(c *fiber.Ctx) error { return c.JSON(fiber.Map{"partner": c.Locals("partner_id")}) })
+	server := httptest.NewServer(adaptor.FiberApp(app))
+	defer server.Close()
+
+	body := map[string]string{"url": "https://partner.example.com/hooks"}
+	var out map[string]string
+	signed := New(server.URL, WithPartnerSignature("partner-1", "s3cret"))
+	require.NoError(t, signed.Do(context.Background(), http.MethodPost, "/partner/webhooks/subscriptions", nil, body, &out))
+	assert.Equal(t, "partner-1", out["partner"])
+
+	wrong := New(server.URL, WithPartnerSignature("partner-1", "other"))
+	err := wrong.Do(context.Background(), http.MethodPost, "/partner/webhooks/subscriptions", nil, body, nil)
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.Status)
+}
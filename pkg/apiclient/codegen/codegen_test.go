@@ -0,0 +1,125 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGeneratedFilesAreUpToDate fails when pkg/api/openapi.yaml changed
+// without go generate ./pkg/apiclient being run
+func TestGeneratedFilesAreUpToDate(t *testing.T) {
+	spec, err := Load("../../api/openapi.yaml")
+	require.NoError(t, err)
+	files, err := Generate(spec, "pkg/api/openapi.yaml")
+	require.NoError(t, err)
+
+	for _, file := range files {
+		current, err := os.ReadFile(filepath.Join("..", filepath.FromSlash(file.Path)))
+		require.NoError(t, err, "%s is missing; run go generate ./pkg/apiclient", file.Path)
+		assert.Equal(t, string(file.Data), string(current), "%s is stale; run go generate ./pkg/apiclient", file.Path)
+	}
+}
+
+func TestCamel(t *testing.T) {
+	for name, want := range map[string]string{
+		"store_id":          "StoreID",
+		"listOrders":        "ListOrders",
+		"getPartnerWebhook": "GetPartnerWebhook",
+		"subjectId":         "SubjectID",
+		"digital_wallet":    "DigitalWallet",
+		"api_key_url":       "APIKeyURL",
+		"80mm":              "80mm",
+	} {
+		assert.Equal(t, want, Camel(name), name)
+	}
+	assert.Equal(t, "storeID", lowerCamel("storeId"))
+	assert.Equal(t, "typeParam", lowerCamel("type"))
+}
+
+func TestSingular(t *testing.T) {
+	assert.Equal(t, "OrderCategory", singular("OrderCategories"))
+	assert.Equal(t, "OrderAddress", singular("OrderAddresses"))
+	assert.Equal(t, "OrderTax", singular("OrderTaxes"))
+	assert.Equal(t, "OrderItem", singular("OrderItems"))
+	assert.Equal(t, "OrderDataItem", singular("OrderData"))
+}
+
+func TestGenerateTypes(t *testing.T) {
+	spec, err := Parse([]byte(`
+paths:
+  /widgets:
+    post:
+      operationId: createWidget
+      tags: [Widgets]
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Widget'
+      responses:
+        '201':
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Widget'
+components:
+  schemas:
+    Widget:
+      type: object
+      required: [name]
+      properties:
+        id:
+          type: string
+          format: uuid
+        name:
+          type: string
+        size:
+          type: string
+          enum: [small, large]
+`))
+	require.NoError(t, err)
+
+	files, err := Generate(spec, "widgets.yaml")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	types := string(files[0].Data)
+	assert.Contains(t, types, "ID   *uuid.UUID  `json:\"id,omitempty\"`")
+	assert.Contains(t, types, "Name string      `json:\"name\"`")
+	assert.Contains(t, types, "WidgetSizeSmall WidgetSize = \"small\"")
+
+	assert.Equal(t, "widgets/client.gen.go", files[1].Path)
+	assert.Contains(t, string(files[1].Data),
+		"func (c *Client) CreateWidget(ctx context.Context, body *apiclient.Widget) (*apiclient.Widget, error)")
+}
+
+func TestGenerateRawRequestBodies(t *testing.T) {
+	spec, err := Parse([]byte(`
+paths:
+  /widgets/import:
+    post:
+      operationId: importWidgets
+      tags: [Widgets]
+      requestBody:
+        required: true
+        content:
+          text/csv:
+            schema:
+              type: string
+      responses:
+        '200':
+          description: Imported
+`))
+	require.NoError(t, err)
+
+	files, err := Generate(spec, "widgets.yaml")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Contains(t, string(files[1].Data),
+		"func (c *Client) ImportWidgets(ctx context.Context, body *apiclient.RawBody) error")
+}
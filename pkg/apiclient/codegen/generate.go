@@ -0,0 +1,379 @@
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"path"
+	"strings"
+)
+
+// ImportPath is the import path of the package generated code is written into
+const ImportPath = "github.com/onichange/pos-system/pkg/apiclient"
+
+// TypesFile is the generated file of the component types
+const TypesFile = "types.gen.go"
+
+// ClientFile is the generated file of each service client package
+const ClientFile = "client.gen.go"
+
+// File is a generated Go source file
+type File struct {
+	Path string // Relative to the apiclient directory
+	Data []byte
+}
+
+// imports are the packages generated code may use, keyed by the selector
+// that shows it does
+var imports = []struct{ selector, path string }{
+	{"context.Context", "context"},
+	{"fmt.Sprint", "fmt"},
+	{"url.PathEscape", "net/url"},
+	{"url.Values", "net/url"},
+	{"time.Time", "time"},
+	{"uuid.UUID", "github.com/google/uuid"},
+	{"apiclient.", ImportPath},
+}
+
+// Generate generates the component types of the spec and a client package
+// per operation tag. source names the spec in the generated files' header.
+func Generate(spec *Spec, source string) ([]File, error) {
+	inputs := inputSchemas(spec)
+
+	shared := newTypes(spec, inputs, "")
+	for _, name := range spec.Components.Schemas.Keys {
+		if err := shared.component(name); err != nil {
+			return nil, err
+		}
+	}
+	typesFile, err := render(source, "", "apiclient", shared.source())
+	if err != nil {
+		return nil, err
+	}
+	files := []File{{Path: TypesFile, Data: typesFile}}
+
+	// Group the operations by their first tag, in the order tags appear
+	var tags []string
+	byTag := make(map[string][]*operation)
+	for _, p := range spec.Paths.Keys {
+		item := spec.Paths.Values[p]
+		for _, op := range item.Operations() {
+			if op.Operation.OperationID == "" || len(op.Operation.Tags) == 0 {
+				return nil, fmt.Errorf("%s %s: an operationId and a tag are required", op.Method, p)
+			}
+			tag := op.Operation.Tags[0]
+			if _, ok := byTag[tag]; !ok {
+				tags = append(tags, tag)
+			}
+			byTag[tag] = append(byTag[tag], &operation{
+				Method:     op.Method,
+				Path:       p,
+				Operation:  op.Operation,
+				Parameters: append(append([]*Parameter(nil), item.Parameters...), op.Operation.Parameters...),
+			})
+		}
+	}
+
+	for _, tag := range tags {
+		pkg := packageName(tag)
+		local := newTypes(spec, inputs, "apiclient.")
+		var b strings.Builder
+		fmt.Fprintf(&b, "// Client sends the %s requests of the API\n", tag)
+		b.WriteString("type Client struct {\n\tclient *apiclient.Client\n}\n\n")
+		fmt.Fprintf(&b, "// New creates a client of the %s operations sending requests through client\n", tag)
+		b.WriteString("func New(client *apiclient.Client) *Client {\n\treturn &Client{client: client}\n}\n")
+		for _, op := range byTag[tag] {
+			if err := op.write(&b, local); err != nil {
+				return nil, err
+			}
+		}
+		if decls := local.source(); decls != "" {
+			b.WriteString("\n" + decls)
+		}
+
+		doc := fmt.Sprintf("// Package %s is a client of the %s operations of the API.\n", pkg, tag)
+		data, err := render(source, doc, pkg, b.String())
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Path: path.Join(pkg, ClientFile), Data: data})
+	}
+	return files, nil
+}
+
+// render formats a generated file, importing the packages its body uses
+func render(source, doc, pkg, body string) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by apigen from %s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&b, "%spackage %s\n\n", doc, pkg)
+
+	var used []string
+	for _, imp := range imports {
+		if imp.path == ImportPath && pkg == "apiclient" {
+			continue
+		}
+		if strings.Contains(body, imp.selector) {
+			used = append(used, imp.path)
+		}
+	}
+	used = dedupe(used)
+	if len(used) > 0 {
+		// Standard library packages first, as goimports groups them
+		b.WriteString("import (\n")
+		for i, imp := range used {
+			if i > 0 && !isStd(imp) && isStd(used[i-1]) {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "\t%q\n", imp)
+		}
+		b.WriteString(")\n\n")
+	}
+	b.WriteString(body)
+
+	data, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format package %s: %w", pkg, err)
+	}
+	return data, nil
+}
+
+// operation is one operation of a client package
+type operation struct {
+	Method     string
+	Path       string
+	Operation  *Operation
+	Parameters []*Parameter // Of the path and the operation
+}
+
+// result is how a method returns an operation's response
+type result struct {
+	typ string // "" when the response has no body
+	raw bool   // The body is returned as is
+}
+
+// response returns the result of the operation's first 2xx response, or
+// false when it has none, as for WebSocket upgrades
+func (op *operation) response(t *types, name string) (result, bool, error) {
+	for _, status := range op.Operation.Responses.Keys {
+		if !strings.HasPrefix(status, "2") {
+			continue
+		}
+		content := op.Operation.Responses.Values[status].Content
+		if media, ok := content.Values["application/json"]; ok {
+			typ, err := t.goType(media.Schema, name+"Response", fmt.Sprintf("%s/responses/%s", op.at(), status), false)
+			return result{typ: typ}, true, err
+		}
+		return result{raw: len(content.Keys) > 0}, true, nil
+	}
+	return result{}, false, nil
+}
+
+// at locates the operation in the spec
+func (op *operation) at() string {
+	return fmt.Sprintf("#/paths/%s/%s", strings.ReplaceAll(op.Path, "/", "~1"), strings.ToLower(op.Method))
+}
+
+// write writes the method, and the type of its query parameters, sending
+// the operation
+func (op *operation) write(b *strings.Builder, t *types) error {
+	name := Camel(op.Operation.OperationID)
+	res, ok, err := op.response(t, name)
+	if err != nil || !ok {
+		return err
+	}
+
+	args := []string{"ctx context.Context"}
+	pathExpr, pathArgs, err := op.pathExpr()
+	if err != nil {
+		return err
+	}
+	args = append(args, pathArgs...)
+
+	var query []*Parameter
+	for _, param := range op.Parameters {
+		if param.In == "query" {
+			query = append(query, param)
+		}
+	}
+	queryExpr := "nil"
+	if len(query) > 0 {
+		args = append(args, fmt.Sprintf("params *%sParams", name))
+		queryExpr = "params.values()"
+	}
+
+	bodyExpr := "nil"
+	optionalBody := false
+	if body := op.Operation.RequestBody; body != nil {
+		if media, ok := body.Content.Values["application/json"]; ok {
+			typ, err := t.goType(media.Schema, name+"Request", op.at()+"/requestBody", true)
+			if err != nil {
+				return err
+			}
+			args = append(args, "body "+pointer(typ))
+			optionalBody = !body.Required && strings.HasPrefix(pointer(typ), "*")
+		} else {
+			// Other media types, such as uploaded files, are sent as is
+			args = append(args, "body *"+t.qualifier+"RawBody")
+		}
+		bodyExpr = "body"
+	}
+
+	b.WriteString("\n")
+	fmt.Fprintf(b, "// %s sends %s %s", name, op.Method, op.Path)
+	if summary := strings.TrimSpace(op.Operation.Summary); summary != "" {
+		fmt.Fprintf(b, ": %s", sentenceCase(strings.TrimSuffix(summary, ".")))
+	}
+	b.WriteString("\n")
+
+	returns := "error"
+	switch {
+	case res.raw:
+		returns = "([]byte, error)"
+	case res.typ != "":
+		returns = fmt.Sprintf("(%s, error)", pointer(res.typ))
+	}
+	fmt.Fprintf(b, "func (c *Client) %s(%s) %s {\n", name, strings.Join(args, ", "), returns)
+
+	if optionalBody {
+		b.WriteString("\tvar in any\n\tif body != nil {\n\t\tin = body\n\t}\n")
+		bodyExpr = "in"
+	}
+	call := fmt.Sprintf("ctx, %q, %s, %s, %s", op.Method, pathExpr, queryExpr, bodyExpr)
+	switch {
+	case res.raw:
+		fmt.Fprintf(b, "\treturn c.client.DoRaw(%s)\n", call)
+	case res.typ == "":
+		fmt.Fprintf(b, "\treturn c.client.Do(%s, nil)\n", call)
+	case strings.HasPrefix(pointer(res.typ), "*"):
+		fmt.Fprintf(b, "\tvar out %s\n", res.typ)
+		fmt.Fprintf(b, "\tif err := c.client.Do(%s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", call)
+		b.WriteString("\treturn &out, nil\n")
+	default:
+		fmt.Fprintf(b, "\tvar out %s\n", res.typ)
+		fmt.Fprintf(b, "\tif err := c.client.Do(%s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", call)
+		b.WriteString("\treturn out, nil\n")
+	}
+	b.WriteString("}\n")
+
+	if len(query) > 0 {
+		writeParams(b, name, query)
+	}
+	return nil
+}
+
+// pathExpr returns the expression building the operation's path and the
+// method arguments of its parameters
+func (op *operation) pathExpr() (string, []string, error) {
+	var parts, args []string
+	rest := op.Path
+	for {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(rest, "}")
+		if end < start {
+			return "", nil, fmt.Errorf("%s: malformed path", op.at())
+		}
+		if start > 0 {
+			parts = append(parts, fmt.Sprintf("%q", rest[:start]))
+		}
+
+		paramName := rest[start+1 : end]
+		var param *Parameter
+		for _, p := range op.Parameters {
+			if p.In == "path" && p.Name == paramName {
+				param = p
+			}
+		}
+		if param == nil {
+			return "", nil, fmt.Errorf("%s: path parameter %s is not declared", op.at(), paramName)
+		}
+		arg := lowerCamel(paramName)
+		typ := paramType(param.Schema)
+		args = append(args, arg+" "+typ)
+		parts = append(parts, "url.PathEscape("+stringExpr(arg, typ)+")")
+		rest = rest[end+1:]
+	}
+	if rest != "" {
+		parts = append(parts, fmt.Sprintf("%q", rest))
+	}
+	return strings.Join(parts, " + "), args, nil
+}
+
+// writeParams writes the type of a method's query parameters
+func writeParams(b *strings.Builder, name string, query []*Parameter) {
+	fmt.Fprintf(b, "\n// %sParams are the query parameters of %s\n", name, name)
+	fmt.Fprintf(b, "type %sParams struct {\n", name)
+	for _, param := range query {
+		typ := paramType(param.Schema)
+		if !param.Required {
+			typ = "*" + typ
+		}
+		fmt.Fprintf(b, "\t%s %s\n", Camel(param.Name), typ)
+	}
+	b.WriteString("}\n\n")
+
+	fmt.Fprintf(b, "func (p *%sParams) values() url.Values {\n", name)
+	b.WriteString("\tq := url.Values{}\n\tif p == nil {\n\t\treturn q\n\t}\n")
+	for _, param := range query {
+		field := "p." + Camel(param.Name)
+		typ := paramType(param.Schema)
+		if param.Required {
+			fmt.Fprintf(b, "\tq.Set(%q, %s)\n", param.Name, stringExpr(field, typ))
+			continue
+		}
+		fmt.Fprintf(b, "\tif %s != nil {\n\t\tq.Set(%q, %s)\n\t}\n", field, param.Name, stringExpr("*"+field, typ))
+	}
+	b.WriteString("\treturn q\n}\n")
+}
+
+// paramType returns the Go type of a path or query parameter
+func paramType(s *Schema) string {
+	if s == nil {
+		return "string"
+	}
+	switch s.Type {
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	}
+	if s.Format == "uuid" {
+		return "uuid.UUID"
+	}
+	return "string"
+}
+
+// stringExpr returns the expression formatting a parameter value of type typ
+func stringExpr(expr, typ string) string {
+	switch typ {
+	case "string":
+		return expr
+	case "uuid.UUID":
+		if strings.HasPrefix(expr, "*") {
+			return "(" + expr + ").String()"
+		}
+		return expr + ".String()"
+	}
+	return "fmt.Sprint(" + expr + ")"
+}
+
+// isStd reports whether an import path is of the standard library
+func isStd(path string) bool {
+	return !strings.Contains(strings.SplitN(path, "/", 2)[0], ".")
+}
+
+// dedupe drops repeated import paths
+func dedupe(paths []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,131 @@
+package codegen
+
+import (
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// initialisms are the words Go names spell in capitals
+var initialisms = map[string]string{
+	"api":  "API",
+	"csv":  "CSV",
+	"http": "HTTP",
+	"id":   "ID",
+	"ids":  "IDs",
+	"ip":   "IP",
+	"json": "JSON",
+	"mfa":  "MFA",
+	"pdf":  "PDF",
+	"sku":  "SKU",
+	"uri":  "URI",
+	"url":  "URL",
+	"uuid": "UUID",
+}
+
+// words splits a snake_case, kebab-case or camelCase name into its words
+func words(name string) []string {
+	var result []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			result = append(result, string(word))
+			word = word[:0]
+		}
+	}
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+		}
+		word = append(word, r)
+	}
+	flush()
+	return result
+}
+
+// Camel returns the exported Go name of a name from the spec, e.g. StoreID
+// for store_id or ListOrders for listOrders
+func Camel(name string) string {
+	var b strings.Builder
+	for _, word := range words(name) {
+		if initialism, ok := initialisms[strings.ToLower(word)]; ok {
+			b.WriteString(initialism)
+			continue
+		}
+		runes := []rune(word)
+		b.WriteRune(unicode.ToUpper(runes[0]))
+		b.WriteString(string(runes[1:]))
+	}
+	return b.String()
+}
+
+// lowerCamel returns the unexported Go name of a name from the spec, e.g.
+// storeID for storeId
+func lowerCamel(name string) string {
+	parts := words(name)
+	if len(parts) == 0 {
+		return name
+	}
+	result := strings.ToLower(parts[0]) + Camel(strings.Join(parts[1:], "_"))
+	if token.IsKeyword(result) || reserved[result] {
+		result += "Param"
+	}
+	return result
+}
+
+// reserved are the names generated methods use for their own variables
+var reserved = map[string]bool{
+	"body":   true,
+	"c":      true,
+	"ctx":    true,
+	"err":    true,
+	"in":     true,
+	"out":    true,
+	"params": true,
+	"path":   true,
+}
+
+// singular returns the name of an element of a list named name
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "sses"), strings.HasSuffix(name, "xes"),
+		strings.HasSuffix(name, "ches"), strings.HasSuffix(name, "shes"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "ss"):
+		return name + "Item"
+	case strings.HasSuffix(name, "s"):
+		return strings.TrimSuffix(name, "s")
+	}
+	return name + "Item"
+}
+
+// sentenceCase lowercases the first word of a summary unless it is an
+// acronym, e.g. "Get order by ID" becomes "get order by ID"
+func sentenceCase(summary string) string {
+	runes := []rune(summary)
+	if len(runes) > 1 && unicode.IsUpper(runes[0]) && unicode.IsUpper(runes[1]) {
+		return summary
+	}
+	if len(runes) > 0 {
+		runes[0] = unicode.ToLower(runes[0])
+	}
+	return string(runes)
+}
+
+// packageName returns the name of the client package of a tag
+func packageName(tag string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(tag) {
+		if r >= 'a' && r <= 'z' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
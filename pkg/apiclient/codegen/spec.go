@@ -0,0 +1,208 @@
+// Package codegen generates the apiclient types and service clients from
+// the OpenAPI document of the gateway's API.
+package codegen
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Ordered is a YAML mapping decoded in document order
+type Ordered[T any] struct {
+	Keys   []string
+	Values map[string]T
+}
+
+// UnmarshalYAML decodes a mapping, keeping its key order
+func (o *Ordered[T]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping", node.Line)
+	}
+	o.Values = make(map[string]T, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var value T
+		if err := node.Content[i+1].Decode(&value); err != nil {
+			return err
+		}
+		key := node.Content[i].Value
+		o.Keys = append(o.Keys, key)
+		o.Values[key] = value
+	}
+	return nil
+}
+
+// Spec is the part of an OpenAPI 3 document clients are generated from
+type Spec struct {
+	Paths      Ordered[*PathItem] `yaml:"paths"`
+	Components struct {
+		Schemas Ordered[*Schema] `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+// PathItem holds the operations on a path
+type PathItem struct {
+	Parameters []*Parameter `yaml:"parameters"`
+	Get        *Operation   `yaml:"get"`
+	Post       *Operation   `yaml:"post"`
+	Put        *Operation   `yaml:"put"`
+	Patch      *Operation   `yaml:"patch"`
+	Delete     *Operation   `yaml:"delete"`
+}
+
+// MethodOperation is an operation with its HTTP method
+type MethodOperation struct {
+	Method    string
+	Operation *Operation
+}
+
+// Operations returns the path's operations, in a fixed method order
+func (p *PathItem) Operations() []MethodOperation {
+	var ops []MethodOperation
+	for _, op := range []MethodOperation{
+		{"GET", p.Get}, {"POST", p.Post}, {"PUT", p.Put}, {"PATCH", p.Patch}, {"DELETE", p.Delete},
+	} {
+		if op.Operation != nil {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// Operation is one method on a path
+type Operation struct {
+	OperationID string             `yaml:"operationId"`
+	Summary     string             `yaml:"summary"`
+	Tags        []string           `yaml:"tags"`
+	Parameters  []*Parameter       `yaml:"parameters"`
+	RequestBody *RequestBody       `yaml:"requestBody"`
+	Responses   Ordered[*Response] `yaml:"responses"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name     string  `yaml:"name"`
+	In       string  `yaml:"in"`
+	Required bool    `yaml:"required"`
+	Schema   *Schema `yaml:"schema"`
+}
+
+// RequestBody is an operation's body
+type RequestBody struct {
+	Required bool                `yaml:"required"`
+	Content  Ordered[*MediaType] `yaml:"content"`
+}
+
+// Response is an operation's answer with one status
+type Response struct {
+	Content Ordered[*MediaType] `yaml:"content"`
+}
+
+// MediaType is the schema of a body in one content type
+type MediaType struct {
+	Schema *Schema `yaml:"schema"`
+}
+
+// Schema describes a JSON value
+type Schema struct {
+	Ref                  string           `yaml:"$ref"`
+	Type                 string           `yaml:"type"`
+	Format               string           `yaml:"format"`
+	Description          string           `yaml:"description"`
+	Enum                 []string         `yaml:"enum"`
+	Nullable             bool             `yaml:"nullable"`
+	Required             []string         `yaml:"required"`
+	Properties           Ordered[*Schema] `yaml:"properties"`
+	Items                *Schema          `yaml:"items"`
+	AllOf                []*Schema        `yaml:"allOf"`
+	AdditionalProperties *Schema          `yaml:"-"`
+}
+
+// UnmarshalYAML decodes a schema, whose additionalProperties may be a
+// schema or a boolean
+func (s *Schema) UnmarshalYAML(node *yaml.Node) error {
+	type plain Schema
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != "additionalProperties" {
+			continue
+		}
+		value := node.Content[i+1]
+		if value.Kind == yaml.MappingNode {
+			s.AdditionalProperties = new(Schema)
+			return value.Decode(s.AdditionalProperties)
+		}
+		if value.Value == "true" {
+			s.AdditionalProperties = &Schema{}
+		}
+	}
+	return nil
+}
+
+// RefName returns the name of the component a $ref points at
+func (s *Schema) RefName() string {
+	return strings.TrimPrefix(s.Ref, "#/components/schemas/")
+}
+
+// IsRequired reports whether a property must be present
+func (s *Schema) IsRequired(property string) bool {
+	for _, name := range s.Required {
+		if name == property {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads an OpenAPI document
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// Parse decodes an OpenAPI document
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// Schema returns a component schema, or nil
+func (s *Spec) Schema(name string) *Schema {
+	return s.Components.Schemas.Values[name]
+}
+
+// Resolve follows a $ref to its component schema
+func (s *Spec) Resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = s.Schema(schema.RefName())
+	}
+	return schema
+}
+
+// Properties returns the names of a schema's properties, including those of
+// the schemas it is composed of with allOf
+func (s *Spec) Properties(schema *Schema) []string {
+	schema = s.Resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	var names []string
+	for _, part := range schema.AllOf {
+		names = append(names, s.Properties(part)...)
+	}
+	return append(names, schema.Properties.Keys...)
+}
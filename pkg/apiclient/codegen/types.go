@@ -0,0 +1,249 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// types declares the Go types of one generated package
+type types struct {
+	spec *Spec
+	// inputs are the component schemas requests are made of, whose optional
+	// fields are pointers so that zero values can be sent
+	inputs map[string]bool
+	// qualifier prefixes the names of component types, e.g. "apiclient."
+	qualifier string
+	decls     []string
+	declared  map[string]bool
+}
+
+func newTypes(spec *Spec, inputs map[string]bool, qualifier string) *types {
+	return &types{spec: spec, inputs: inputs, qualifier: qualifier, declared: make(map[string]bool)}
+}
+
+// source returns the declarations in the order they were made
+func (t *types) source() string {
+	return strings.Join(t.decls, "\n")
+}
+
+// reserve claims a type name, returning the slot its declaration goes into.
+// Declarations of nested types made while building it follow it.
+func (t *types) reserve(name string) (int, error) {
+	if t.declared[name] {
+		return 0, fmt.Errorf("type %s is declared twice", name)
+	}
+	t.declared[name] = true
+	t.decls = append(t.decls, "")
+	return len(t.decls) - 1, nil
+}
+
+// component declares a component schema
+func (t *types) component(name string) error {
+	schema := t.spec.Schema(name)
+	typ, err := t.goType(schema, name, "#/components/schemas/"+name, t.inputs[name])
+	if err != nil || typ == name {
+		return err
+	}
+	slot, err := t.reserve(name)
+	if err != nil {
+		return err
+	}
+	t.decls[slot] = fmt.Sprintf("// %s is generated from #/components/schemas/%s\ntype %s %s\n", name, name, name, typ)
+	return nil
+}
+
+// goType returns the Go type of a schema, declaring a type named name when
+// the schema needs one. at locates the schema in the spec.
+func (t *types) goType(s *Schema, name, at string, input bool) (string, error) {
+	switch {
+	case s == nil:
+		return "any", nil
+	case s.Ref != "":
+		if t.spec.Schema(s.RefName()) == nil {
+			return "", fmt.Errorf("%s: unknown schema %s", at, s.Ref)
+		}
+		return t.qualifier + s.RefName(), nil
+	case len(s.AllOf) > 0 || len(s.Properties.Keys) > 0:
+		return name, t.declareStruct(s, name, at, input)
+	}
+
+	switch s.Type {
+	case "array":
+		item, err := t.goType(s.Items, singular(name), at+"/items", input)
+		return "[]" + item, err
+	case "object":
+		if s.AdditionalProperties != nil {
+			value, err := t.goType(s.AdditionalProperties, name+"Value", at+"/additionalProperties", input)
+			return "map[string]" + value, err
+		}
+		return "map[string]any", nil
+	case "string":
+		switch s.Format {
+		case "uuid":
+			return "uuid.UUID", nil
+		case "date-time":
+			return "time.Time", nil
+		case "byte", "binary":
+			return "[]byte", nil
+		}
+		if len(s.Enum) > 0 {
+			return name, t.declareEnum(s, name, at)
+		}
+		return "string", nil
+	case "integer":
+		if s.Format == "int64" {
+			return "int64", nil
+		}
+		return "int", nil
+	case "number":
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	}
+	return "any", nil
+}
+
+// declareStruct declares an object schema, embedding the component schemas
+// it is composed of with allOf
+func (t *types) declareStruct(s *Schema, name, at string, input bool) error {
+	slot, err := t.reserve(name)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	writeDoc(&b, name, at, s.Description)
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	parts := append([]*Schema(nil), s.AllOf...)
+	for i, part := range s.AllOf {
+		if part.Ref == "" {
+			continue
+		}
+		if t.spec.Schema(part.RefName()) == nil {
+			return fmt.Errorf("%s/allOf/%d: unknown schema %s", at, i, part.Ref)
+		}
+		fmt.Fprintf(&b, "\t%s%s\n", t.qualifier, part.RefName())
+	}
+	parts = append(parts, s)
+	for i, part := range parts {
+		if part.Ref != "" {
+			continue
+		}
+		partAt := at
+		if part != s {
+			partAt = fmt.Sprintf("%s/allOf/%d", at, i)
+		}
+		for _, property := range part.Properties.Keys {
+			if err := t.field(&b, part, property, name, partAt, input); err != nil {
+				return err
+			}
+		}
+	}
+	b.WriteString("}\n")
+
+	t.decls[slot] = b.String()
+	return nil
+}
+
+// field writes the field of a struct holding one of the schema's properties
+func (t *types) field(b *strings.Builder, s *Schema, property, parent, at string, input bool) error {
+	schema := s.Properties.Values[property]
+	name := Camel(property)
+	typ, err := t.goType(schema, parent+name, at+"/properties/"+property, input)
+	if err != nil {
+		return err
+	}
+
+	required := s.IsRequired(property)
+	if (schema != nil && schema.Nullable) || (input && !required) {
+		typ = pointer(typ)
+	}
+	tag := property
+	if !required {
+		tag += ",omitempty"
+	}
+
+	if schema != nil && schema.Description != "" {
+		for _, line := range strings.Split(strings.TrimSpace(schema.Description), "\n") {
+			fmt.Fprintf(b, "\t// %s\n", strings.TrimSpace(line))
+		}
+	}
+	fmt.Fprintf(b, "\t%s %s `json:\"%s\"`\n", name, typ, tag)
+	return nil
+}
+
+// declareEnum declares a string enum with a constant per value
+func (t *types) declareEnum(s *Schema, name, at string) error {
+	slot, err := t.reserve(name)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	writeDoc(&b, name, at, s.Description)
+	fmt.Fprintf(&b, "type %s string\n\n// Values of %s\nconst (\n", name, name)
+	for _, value := range s.Enum {
+		fmt.Fprintf(&b, "\t%s%s %s = %q\n", name, Camel(value), name, value)
+	}
+	b.WriteString(")\n")
+
+	t.decls[slot] = b.String()
+	return nil
+}
+
+// writeDoc writes the doc comment of a declared type
+func writeDoc(b *strings.Builder, name, at, description string) {
+	fmt.Fprintf(b, "// %s is generated from %s", name, at)
+	if description = strings.TrimSpace(description); description != "" {
+		b.WriteString(":\n//")
+		for _, line := range strings.Split(description, "\n") {
+			fmt.Fprintf(b, " %s", strings.TrimSpace(line))
+		}
+	}
+	b.WriteString("\n")
+}
+
+// pointer returns a pointer to typ, or typ when it can already be left out
+func pointer(typ string) string {
+	if strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || strings.HasPrefix(typ, "*") || typ == "any" {
+		return typ
+	}
+	return "*" + typ
+}
+
+// inputSchemas returns the component schemas reachable from request bodies
+func inputSchemas(spec *Spec) map[string]bool {
+	inputs := make(map[string]bool)
+	var walk func(s *Schema)
+	walk = func(s *Schema) {
+		if s == nil {
+			return
+		}
+		if s.Ref != "" {
+			if name := s.RefName(); !inputs[name] {
+				inputs[name] = true
+				walk(spec.Schema(name))
+			}
+			return
+		}
+		for _, property := range s.Properties.Keys {
+			walk(s.Properties.Values[property])
+		}
+		for _, part := range s.AllOf {
+			walk(part)
+		}
+		walk(s.Items)
+		walk(s.AdditionalProperties)
+	}
+
+	for _, path := range spec.Paths.Keys {
+		for _, op := range spec.Paths.Values[path].Operations() {
+			if body := op.Operation.RequestBody; body != nil {
+				for _, contentType := range body.Content.Keys {
+					walk(body.Content.Values[contentType].Schema)
+				}
+			}
+		}
+	}
+	return inputs
+}
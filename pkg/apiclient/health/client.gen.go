@@ -0,0 +1,35 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package health is a client of the Health operations of the API.
+package health
+
+import (
+	"context"
+
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Health requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Health operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// HealthCheck sends GET /health: health check
+func (c *Client) HealthCheck(ctx context.Context) (*HealthCheckResponse, error) {
+	var out HealthCheckResponse
+	if err := c.client.Do(ctx, "GET", "/health", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// HealthCheckResponse is generated from #/paths/~1health/get/responses/200
+type HealthCheckResponse struct {
+	Status    string `json:"status,omitempty"`
+	Timestamp int    `json:"timestamp,omitempty"`
+}
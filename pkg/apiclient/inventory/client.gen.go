@@ -0,0 +1,362 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package inventory is a client of the Inventory operations of the API.
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Inventory requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Inventory operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// ListInventory sends GET /inventory: list inventory items
+func (c *Client) ListInventory(ctx context.Context, params *ListInventoryParams) (*ListInventoryResponse, error) {
+	var out ListInventoryResponse
+	if err := c.client.Do(ctx, "GET", "/inventory", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListInventoryParams are the query parameters of ListInventory
+type ListInventoryParams struct {
+	Limit  *int
+	Offset *int
+}
+
+func (p *ListInventoryParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// ExportInventory sends GET /inventory/export: export a store's stock as CSV
+func (c *Client) ExportInventory(ctx context.Context, params *ExportInventoryParams) ([]byte, error) {
+	return c.client.DoRaw(ctx, "GET", "/inventory/export", params.values(), nil)
+}
+
+// ExportInventoryParams are the query parameters of ExportInventory
+type ExportInventoryParams struct {
+	StoreID uuid.UUID
+	Format  *string
+	Columns *string
+	Gzip    *bool
+}
+
+func (p *ExportInventoryParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	q.Set("store_id", p.StoreID.String())
+	if p.Format != nil {
+		q.Set("format", *p.Format)
+	}
+	if p.Columns != nil {
+		q.Set("columns", *p.Columns)
+	}
+	if p.Gzip != nil {
+		q.Set("gzip", fmt.Sprint(*p.Gzip))
+	}
+	return q
+}
+
+// ImportInventory sends POST /inventory/import: import stock records from CSV
+func (c *Client) ImportInventory(ctx context.Context, params *ImportInventoryParams, body *apiclient.RawBody) (*apiclient.StockImportReport, error) {
+	var out apiclient.StockImportReport
+	if err := c.client.Do(ctx, "POST", "/inventory/import", params.values(), body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ImportInventoryParams are the query parameters of ImportInventory
+type ImportInventoryParams struct {
+	DryRun *bool
+}
+
+func (p *ImportInventoryParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.DryRun != nil {
+		q.Set("dry_run", fmt.Sprint(*p.DryRun))
+	}
+	return q
+}
+
+// GetInventory sends GET /inventory/{id}: get inventory by ID
+func (c *Client) GetInventory(ctx context.Context, id uuid.UUID) (*apiclient.Inventory, error) {
+	var out apiclient.Inventory
+	if err := c.client.Do(ctx, "GET", "/inventory/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateInventory sends PUT /inventory/{id}: update inventory
+func (c *Client) UpdateInventory(ctx context.Context, id uuid.UUID, body *UpdateInventoryRequest) (*apiclient.Inventory, error) {
+	var out apiclient.Inventory
+	if err := c.client.Do(ctx, "PUT", "/inventory/"+url.PathEscape(id.String()), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetInventoryCostLayers sends GET /inventory/{id}/cost-layers: inventory cost layers
+func (c *Client) GetInventoryCostLayers(ctx context.Context, id uuid.UUID) (*apiclient.InventoryValuation, error) {
+	var out apiclient.InventoryValuation
+	if err := c.client.Do(ctx, "GET", "/inventory/"+url.PathEscape(id.String())+"/cost-layers", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListStockTransfers sends GET /inventory/transfers: list stock transfers
+func (c *Client) ListStockTransfers(ctx context.Context, params *ListStockTransfersParams) (*ListStockTransfersResponse, error) {
+	var out ListStockTransfersResponse
+	if err := c.client.Do(ctx, "GET", "/inventory/transfers", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListStockTransfersParams are the query parameters of ListStockTransfers
+type ListStockTransfersParams struct {
+	StoreID *uuid.UUID
+	Status  *string
+	Limit   *int
+	Offset  *int
+}
+
+func (p *ListStockTransfersParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.StoreID != nil {
+		q.Set("store_id", (*p.StoreID).String())
+	}
+	if p.Status != nil {
+		q.Set("status", *p.Status)
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// CreateStockTransfer sends POST /inventory/transfers: request stock transfer
+func (c *Client) CreateStockTransfer(ctx context.Context, body *apiclient.CreateStockTransferRequest) (*apiclient.StockTransfer, error) {
+	var out apiclient.StockTransfer
+	if err := c.client.Do(ctx, "POST", "/inventory/transfers", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetStockTransfer sends GET /inventory/transfers/{id}: get stock transfer
+func (c *Client) GetStockTransfer(ctx context.Context, id uuid.UUID) (*apiclient.StockTransfer, error) {
+	var out apiclient.StockTransfer
+	if err := c.client.Do(ctx, "GET", "/inventory/transfers/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DispatchStockTransfer sends POST /inventory/transfers/{id}/dispatch: dispatch stock transfer
+func (c *Client) DispatchStockTransfer(ctx context.Context, id uuid.UUID) (*apiclient.StockTransfer, error) {
+	var out apiclient.StockTransfer
+	if err := c.client.Do(ctx, "POST", "/inventory/transfers/"+url.PathEscape(id.String())+"/dispatch", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReceiveStockTransfer sends POST /inventory/transfers/{id}/receive: receive stock transfer
+func (c *Client) ReceiveStockTransfer(ctx context.Context, id uuid.UUID) (*apiclient.StockTransfer, error) {
+	var out apiclient.StockTransfer
+	if err := c.client.Do(ctx, "POST", "/inventory/transfers/"+url.PathEscape(id.String())+"/receive", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CancelStockTransfer sends POST /inventory/transfers/{id}/cancel: cancel stock transfer
+func (c *Client) CancelStockTransfer(ctx context.Context, id uuid.UUID) (*apiclient.StockTransfer, error) {
+	var out apiclient.StockTransfer
+	if err := c.client.Do(ctx, "POST", "/inventory/transfers/"+url.PathEscape(id.String())+"/cancel", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListStockCounts sends GET /inventory/counts: list stock counts
+func (c *Client) ListStockCounts(ctx context.Context, params *ListStockCountsParams) (*ListStockCountsResponse, error) {
+	var out ListStockCountsResponse
+	if err := c.client.Do(ctx, "GET", "/inventory/counts", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListStockCountsParams are the query parameters of ListStockCounts
+type ListStockCountsParams struct {
+	StoreID *uuid.UUID
+	Status  *string
+	Limit   *int
+	Offset  *int
+}
+
+func (p *ListStockCountsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.StoreID != nil {
+		q.Set("store_id", (*p.StoreID).String())
+	}
+	if p.Status != nil {
+		q.Set("status", *p.Status)
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// CreateStockCount sends POST /inventory/counts: open stock count
+func (c *Client) CreateStockCount(ctx context.Context, body *apiclient.CreateStockCountRequest) (*apiclient.StockCount, error) {
+	var out apiclient.StockCount
+	if err := c.client.Do(ctx, "POST", "/inventory/counts", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetStockCount sends GET /inventory/counts/{id}: get stock count
+func (c *Client) GetStockCount(ctx context.Context, id uuid.UUID) (*apiclient.StockCount, error) {
+	var out apiclient.StockCount
+	if err := c.client.Do(ctx, "GET", "/inventory/counts/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RecordStockCounts sends POST /inventory/counts/{id}/lines: record counted quantities
+func (c *Client) RecordStockCounts(ctx context.Context, id uuid.UUID, body *apiclient.RecordStockCountsRequest) (*RecordStockCountsResponse, error) {
+	var out RecordStockCountsResponse
+	if err := c.client.Do(ctx, "POST", "/inventory/counts/"+url.PathEscape(id.String())+"/lines", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetStockCountVariances sends GET /inventory/counts/{id}/variances: stock count variance report
+func (c *Client) GetStockCountVariances(ctx context.Context, id uuid.UUID, params *GetStockCountVariancesParams) (*apiclient.StockCountVarianceReport, error) {
+	var out apiclient.StockCountVarianceReport
+	if err := c.client.Do(ctx, "GET", "/inventory/counts/"+url.PathEscape(id.String())+"/variances", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetStockCountVariancesParams are the query parameters of GetStockCountVariances
+type GetStockCountVariancesParams struct {
+	Format *string
+}
+
+func (p *GetStockCountVariancesParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Format != nil {
+		q.Set("format", *p.Format)
+	}
+	return q
+}
+
+// ReconcileStockCount sends POST /inventory/counts/{id}/reconcile: reconcile stock count
+func (c *Client) ReconcileStockCount(ctx context.Context, id uuid.UUID) (*apiclient.StockCountReconciliation, error) {
+	var out apiclient.StockCountReconciliation
+	if err := c.client.Do(ctx, "POST", "/inventory/counts/"+url.PathEscape(id.String())+"/reconcile", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CancelStockCount sends POST /inventory/counts/{id}/cancel: cancel stock count
+func (c *Client) CancelStockCount(ctx context.Context, id uuid.UUID) (*apiclient.StockCount, error) {
+	var out apiclient.StockCount
+	if err := c.client.Do(ctx, "POST", "/inventory/counts/"+url.PathEscape(id.String())+"/cancel", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AdjustInventory sends POST /inventory/{id}/adjust: adjust stock on hand
+func (c *Client) AdjustInventory(ctx context.Context, id uuid.UUID, body *apiclient.AdjustInventoryRequest) (*apiclient.InventoryAdjustment, error) {
+	var out apiclient.InventoryAdjustment
+	if err := c.client.Do(ctx, "POST", "/inventory/"+url.PathEscape(id.String())+"/adjust", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListInventoryResponse is generated from #/paths/~1inventory/get/responses/200
+type ListInventoryResponse struct {
+	Data []apiclient.Inventory `json:"data,omitempty"`
+}
+
+// UpdateInventoryRequest is generated from #/paths/~1inventory~1{id}/put/requestBody
+type UpdateInventoryRequest struct {
+	Quantity        *int     `json:"quantity,omitempty"`
+	ReorderPoint    *int     `json:"reorder_point,omitempty"`
+	ReorderQuantity *int     `json:"reorder_quantity,omitempty"`
+	CostPrice       *float64 `json:"cost_price,omitempty"`
+	SellingPrice    *float64 `json:"selling_price,omitempty"`
+}
+
+// ListStockTransfersResponse is generated from #/paths/~1inventory~1transfers/get/responses/200
+type ListStockTransfersResponse struct {
+	Data []apiclient.StockTransfer `json:"data,omitempty"`
+}
+
+// ListStockCountsResponse is generated from #/paths/~1inventory~1counts/get/responses/200
+type ListStockCountsResponse struct {
+	Data []apiclient.StockCount `json:"data,omitempty"`
+}
+
+// RecordStockCountsResponse is generated from #/paths/~1inventory~1counts~1{id}~1lines/post/responses/200
+type RecordStockCountsResponse struct {
+	Data []apiclient.StockCountLine `json:"data,omitempty"`
+}
@@ -0,0 +1,74 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package loyalty is a client of the Loyalty operations of the API.
+package loyalty
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Loyalty requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Loyalty operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// GetLoyaltyProgram sends GET /loyalty/program: get the loyalty program
+func (c *Client) GetLoyaltyProgram(ctx context.Context) (*apiclient.LoyaltyProgram, error) {
+	var out apiclient.LoyaltyProgram
+	if err := c.client.Do(ctx, "GET", "/loyalty/program", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLoyaltyAccount sends GET /loyalty/account: get my loyalty account
+func (c *Client) GetLoyaltyAccount(ctx context.Context) (*apiclient.LoyaltyAccount, error) {
+	var out apiclient.LoyaltyAccount
+	if err := c.client.Do(ctx, "GET", "/loyalty/account", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListLoyaltyTransactions sends GET /loyalty/transactions: get my points history
+func (c *Client) ListLoyaltyTransactions(ctx context.Context, params *ListLoyaltyTransactionsParams) (*ListLoyaltyTransactionsResponse, error) {
+	var out ListLoyaltyTransactionsResponse
+	if err := c.client.Do(ctx, "GET", "/loyalty/transactions", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListLoyaltyTransactionsParams are the query parameters of ListLoyaltyTransactions
+type ListLoyaltyTransactionsParams struct {
+	Limit  *int
+	Offset *int
+}
+
+func (p *ListLoyaltyTransactionsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// ListLoyaltyTransactionsResponse is generated from #/paths/~1loyalty~1transactions/get/responses/200
+type ListLoyaltyTransactionsResponse struct {
+	Data []apiclient.LoyaltyTransaction `json:"data,omitempty"`
+}
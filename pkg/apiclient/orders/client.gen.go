@@ -0,0 +1,254 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package orders is a client of the Orders operations of the API.
+package orders
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Orders requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Orders operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// ListOrders sends GET /orders: list orders
+func (c *Client) ListOrders(ctx context.Context, params *ListOrdersParams) (*ListOrdersResponse, error) {
+	var out ListOrdersResponse
+	if err := c.client.Do(ctx, "GET", "/orders", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListOrdersParams are the query parameters of ListOrders
+type ListOrdersParams struct {
+	Limit  *int
+	Offset *int
+}
+
+func (p *ListOrdersParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// CreateOrder sends POST /orders: create order
+func (c *Client) CreateOrder(ctx context.Context, body *apiclient.CreateOrderRequest) (*apiclient.Order, error) {
+	var out apiclient.Order
+	if err := c.client.Do(ctx, "POST", "/orders", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ExportOrders sends GET /orders/export: export orders as CSV
+func (c *Client) ExportOrders(ctx context.Context, params *ExportOrdersParams) ([]byte, error) {
+	return c.client.DoRaw(ctx, "GET", "/orders/export", params.values(), nil)
+}
+
+// ExportOrdersParams are the query parameters of ExportOrders
+type ExportOrdersParams struct {
+	From    string
+	To      string
+	StoreID *uuid.UUID
+	Format  *string
+	Columns *string
+	Gzip    *bool
+}
+
+func (p *ExportOrdersParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	q.Set("from", p.From)
+	q.Set("to", p.To)
+	if p.StoreID != nil {
+		q.Set("store_id", (*p.StoreID).String())
+	}
+	if p.Format != nil {
+		q.Set("format", *p.Format)
+	}
+	if p.Columns != nil {
+		q.Set("columns", *p.Columns)
+	}
+	if p.Gzip != nil {
+		q.Set("gzip", fmt.Sprint(*p.Gzip))
+	}
+	return q
+}
+
+// GetOrder sends GET /orders/{id}: get order by ID
+func (c *Client) GetOrder(ctx context.Context, id uuid.UUID) (*apiclient.Order, error) {
+	var out apiclient.Order
+	if err := c.client.Do(ctx, "GET", "/orders/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateOrder sends PUT /orders/{id}: update order
+func (c *Client) UpdateOrder(ctx context.Context, id uuid.UUID, body *UpdateOrderRequest) (*apiclient.Order, error) {
+	var out apiclient.Order
+	if err := c.client.Do(ctx, "PUT", "/orders/"+url.PathEscape(id.String()), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteOrder sends DELETE /orders/{id}: delete order
+func (c *Client) DeleteOrder(ctx context.Context, id uuid.UUID) error {
+	return c.client.Do(ctx, "DELETE", "/orders/"+url.PathEscape(id.String()), nil, nil, nil)
+}
+
+// UpdateOrderStatus sends PUT /orders/{id}/status: update order status
+func (c *Client) UpdateOrderStatus(ctx context.Context, id uuid.UUID, body *UpdateOrderStatusRequest) (*apiclient.Order, error) {
+	var out apiclient.Order
+	if err := c.client.Do(ctx, "PUT", "/orders/"+url.PathEscape(id.String())+"/status", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// TransitionOrderStatus sends PATCH /orders/{id}/status: transition order status
+func (c *Client) TransitionOrderStatus(ctx context.Context, id uuid.UUID, body *TransitionOrderStatusRequest) (*apiclient.Order, error) {
+	var out apiclient.Order
+	if err := c.client.Do(ctx, "PATCH", "/orders/"+url.PathEscape(id.String())+"/status", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOrderStatusHistory sends GET /orders/{id}/status/history: get order status history
+func (c *Client) GetOrderStatusHistory(ctx context.Context, id uuid.UUID) (*apiclient.OrderStatusHistory, error) {
+	var out apiclient.OrderStatusHistory
+	if err := c.client.Do(ctx, "GET", "/orders/"+url.PathEscape(id.String())+"/status/history", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CheckoutOrder sends POST /orders/{id}/checkout: check out an order
+func (c *Client) CheckoutOrder(ctx context.Context, id uuid.UUID, body *CheckoutOrderRequest) (*apiclient.Checkout, error) {
+	var out apiclient.Checkout
+	if err := c.client.Do(ctx, "POST", "/orders/"+url.PathEscape(id.String())+"/checkout", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOrderHistory sends GET /orders/{id}/history: get order history
+func (c *Client) GetOrderHistory(ctx context.Context, id uuid.UUID, params *GetOrderHistoryParams) (*apiclient.OrderHistory, error) {
+	var out apiclient.OrderHistory
+	if err := c.client.Do(ctx, "GET", "/orders/"+url.PathEscape(id.String())+"/history", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOrderHistoryParams are the query parameters of GetOrderHistory
+type GetOrderHistoryParams struct {
+	AsOf *string
+}
+
+func (p *GetOrderHistoryParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.AsOf != nil {
+		q.Set("as_of", *p.AsOf)
+	}
+	return q
+}
+
+// ListOrdersResponse is generated from #/paths/~1orders/get/responses/200
+type ListOrdersResponse struct {
+	Data   []apiclient.Order `json:"data,omitempty"`
+	Limit  int               `json:"limit,omitempty"`
+	Offset int               `json:"offset,omitempty"`
+}
+
+// UpdateOrderRequest is generated from #/paths/~1orders~1{id}/put/requestBody
+type UpdateOrderRequest struct {
+	Items           []apiclient.OrderItem `json:"items,omitempty"`
+	ShippingAddress *apiclient.Address    `json:"shipping_address,omitempty"`
+	BillingAddress  *apiclient.Address    `json:"billing_address,omitempty"`
+	Notes           *string               `json:"notes,omitempty"`
+}
+
+// UpdateOrderStatusRequest is generated from #/paths/~1orders~1{id}~1status/put/requestBody
+type UpdateOrderStatusRequest struct {
+	Status UpdateOrderStatusRequestStatus `json:"status"`
+	// Why the order moved, kept in its status history
+	Reason *string `json:"reason,omitempty"`
+}
+
+// UpdateOrderStatusRequestStatus is generated from #/paths/~1orders~1{id}~1status/put/requestBody/properties/status
+type UpdateOrderStatusRequestStatus string
+
+// Values of UpdateOrderStatusRequestStatus
+const (
+	UpdateOrderStatusRequestStatusConfirmed  UpdateOrderStatusRequestStatus = "confirmed"
+	UpdateOrderStatusRequestStatusProcessing UpdateOrderStatusRequestStatus = "processing"
+	UpdateOrderStatusRequestStatusShipped    UpdateOrderStatusRequestStatus = "shipped"
+	UpdateOrderStatusRequestStatusDelivered  UpdateOrderStatusRequestStatus = "delivered"
+	UpdateOrderStatusRequestStatusCancelled  UpdateOrderStatusRequestStatus = "cancelled"
+	UpdateOrderStatusRequestStatusRefunded   UpdateOrderStatusRequestStatus = "refunded"
+)
+
+// TransitionOrderStatusRequest is generated from #/paths/~1orders~1{id}~1status/patch/requestBody
+type TransitionOrderStatusRequest struct {
+	Status TransitionOrderStatusRequestStatus `json:"status"`
+	// Why the order moved, kept in its status history
+	Reason *string `json:"reason,omitempty"`
+}
+
+// TransitionOrderStatusRequestStatus is generated from #/paths/~1orders~1{id}~1status/patch/requestBody/properties/status
+type TransitionOrderStatusRequestStatus string
+
+// Values of TransitionOrderStatusRequestStatus
+const (
+	TransitionOrderStatusRequestStatusConfirmed  TransitionOrderStatusRequestStatus = "confirmed"
+	TransitionOrderStatusRequestStatusProcessing TransitionOrderStatusRequestStatus = "processing"
+	TransitionOrderStatusRequestStatusShipped    TransitionOrderStatusRequestStatus = "shipped"
+	TransitionOrderStatusRequestStatusDelivered  TransitionOrderStatusRequestStatus = "delivered"
+	TransitionOrderStatusRequestStatusCancelled  TransitionOrderStatusRequestStatus = "cancelled"
+	TransitionOrderStatusRequestStatusRefunded   TransitionOrderStatusRequestStatus = "refunded"
+)
+
+// CheckoutOrderRequest is generated from #/paths/~1orders~1{id}~1checkout/post/requestBody
+type CheckoutOrderRequest struct {
+	PaymentMethodToken string                                `json:"payment_method_token"`
+	PaymentMethodType  CheckoutOrderRequestPaymentMethodType `json:"payment_method_type"`
+	ThreeDSecure       *bool                                 `json:"three_d_secure,omitempty"`
+}
+
+// CheckoutOrderRequestPaymentMethodType is generated from #/paths/~1orders~1{id}~1checkout/post/requestBody/properties/payment_method_type
+type CheckoutOrderRequestPaymentMethodType string
+
+// Values of CheckoutOrderRequestPaymentMethodType
+const (
+	CheckoutOrderRequestPaymentMethodTypeCard          CheckoutOrderRequestPaymentMethodType = "card"
+	CheckoutOrderRequestPaymentMethodTypeBankTransfer  CheckoutOrderRequestPaymentMethodType = "bank_transfer"
+	CheckoutOrderRequestPaymentMethodTypeDigitalWallet CheckoutOrderRequestPaymentMethodType = "digital_wallet"
+)
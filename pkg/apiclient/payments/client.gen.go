@@ -0,0 +1,79 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package payments is a client of the Payments operations of the API.
+package payments
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Payments requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Payments operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// ProcessPayment sends POST /payments: process payment
+func (c *Client) ProcessPayment(ctx context.Context, body *apiclient.ProcessPaymentRequest) (*apiclient.Payment, error) {
+	var out apiclient.Payment
+	if err := c.client.Do(ctx, "POST", "/payments", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// HandlePaymentWebhook sends POST /payments/webhook: payment provider webhook
+func (c *Client) HandlePaymentWebhook(ctx context.Context, body map[string]any) error {
+	return c.client.Do(ctx, "POST", "/payments/webhook", nil, body, nil)
+}
+
+// ExportPayments sends GET /payments/export: export payments as CSV
+func (c *Client) ExportPayments(ctx context.Context, params *ExportPaymentsParams) ([]byte, error) {
+	return c.client.DoRaw(ctx, "GET", "/payments/export", params.values(), nil)
+}
+
+// ExportPaymentsParams are the query parameters of ExportPayments
+type ExportPaymentsParams struct {
+	From    string
+	To      string
+	Format  *string
+	Columns *string
+	Gzip    *bool
+}
+
+func (p *ExportPaymentsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	q.Set("from", p.From)
+	q.Set("to", p.To)
+	if p.Format != nil {
+		q.Set("format", *p.Format)
+	}
+	if p.Columns != nil {
+		q.Set("columns", *p.Columns)
+	}
+	if p.Gzip != nil {
+		q.Set("gzip", fmt.Sprint(*p.Gzip))
+	}
+	return q
+}
+
+// GetPayment sends GET /payments/{id}: get payment by ID
+func (c *Client) GetPayment(ctx context.Context, id uuid.UUID) (*apiclient.Payment, error) {
+	var out apiclient.Payment
+	if err := c.client.Do(ctx, "GET", "/payments/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
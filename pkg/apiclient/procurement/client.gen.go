@@ -0,0 +1,282 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package procurement is a client of the Procurement operations of the API.
+package procurement
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Procurement requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Procurement operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// ListSuppliers sends GET /suppliers: list suppliers
+func (c *Client) ListSuppliers(ctx context.Context, params *ListSuppliersParams) (*ListSuppliersResponse, error) {
+	var out ListSuppliersResponse
+	if err := c.client.Do(ctx, "GET", "/suppliers", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSuppliersParams are the query parameters of ListSuppliers
+type ListSuppliersParams struct {
+	Active *bool
+	Limit  *int
+	Offset *int
+}
+
+func (p *ListSuppliersParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Active != nil {
+		q.Set("active", fmt.Sprint(*p.Active))
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// CreateSupplier sends POST /suppliers: create supplier
+func (c *Client) CreateSupplier(ctx context.Context, body *apiclient.SupplierRequest) (*apiclient.Supplier, error) {
+	var out apiclient.Supplier
+	if err := c.client.Do(ctx, "POST", "/suppliers", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSupplier sends GET /suppliers/{id}: get supplier
+func (c *Client) GetSupplier(ctx context.Context, id uuid.UUID) (*apiclient.Supplier, error) {
+	var out apiclient.Supplier
+	if err := c.client.Do(ctx, "GET", "/suppliers/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateSupplier sends PUT /suppliers/{id}: update supplier
+func (c *Client) UpdateSupplier(ctx context.Context, id uuid.UUID, body *apiclient.SupplierRequest) (*apiclient.Supplier, error) {
+	var out apiclient.Supplier
+	if err := c.client.Do(ctx, "PUT", "/suppliers/"+url.PathEscape(id.String()), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteSupplier sends DELETE /suppliers/{id}: delete supplier
+func (c *Client) DeleteSupplier(ctx context.Context, id uuid.UUID) error {
+	return c.client.Do(ctx, "DELETE", "/suppliers/"+url.PathEscape(id.String()), nil, nil, nil)
+}
+
+// GetSupplierPerformance sends GET /suppliers/{id}/performance: supplier performance
+func (c *Client) GetSupplierPerformance(ctx context.Context, id uuid.UUID, params *GetSupplierPerformanceParams) (*apiclient.SupplierPerformance, error) {
+	var out apiclient.SupplierPerformance
+	if err := c.client.Do(ctx, "GET", "/suppliers/"+url.PathEscape(id.String())+"/performance", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSupplierPerformanceParams are the query parameters of GetSupplierPerformance
+type GetSupplierPerformanceParams struct {
+	From *string
+	To   *string
+}
+
+func (p *GetSupplierPerformanceParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.From != nil {
+		q.Set("from", *p.From)
+	}
+	if p.To != nil {
+		q.Set("to", *p.To)
+	}
+	return q
+}
+
+// ListSupplierProducts sends GET /suppliers/{id}/products: list supplier products
+func (c *Client) ListSupplierProducts(ctx context.Context, id uuid.UUID) (*ListSupplierProductsResponse, error) {
+	var out ListSupplierProductsResponse
+	if err := c.client.Do(ctx, "GET", "/suppliers/"+url.PathEscape(id.String())+"/products", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetSupplierProduct sends PUT /suppliers/{id}/products/{productId}: set supplier product
+func (c *Client) SetSupplierProduct(ctx context.Context, id uuid.UUID, productID uuid.UUID, body *apiclient.SupplierProductRequest) (*apiclient.SupplierProduct, error) {
+	var out apiclient.SupplierProduct
+	if err := c.client.Do(ctx, "PUT", "/suppliers/"+url.PathEscape(id.String())+"/products/"+url.PathEscape(productID.String()), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteSupplierProduct sends DELETE /suppliers/{id}/products/{productId}: delete supplier product
+func (c *Client) DeleteSupplierProduct(ctx context.Context, id uuid.UUID, productID uuid.UUID) error {
+	return c.client.Do(ctx, "DELETE", "/suppliers/"+url.PathEscape(id.String())+"/products/"+url.PathEscape(productID.String()), nil, nil, nil)
+}
+
+// ListPurchaseOrders sends GET /purchase-orders: list purchase orders
+func (c *Client) ListPurchaseOrders(ctx context.Context, params *ListPurchaseOrdersParams) (*ListPurchaseOrdersResponse, error) {
+	var out ListPurchaseOrdersResponse
+	if err := c.client.Do(ctx, "GET", "/purchase-orders", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPurchaseOrdersParams are the query parameters of ListPurchaseOrders
+type ListPurchaseOrdersParams struct {
+	SupplierID *uuid.UUID
+	StoreID    *uuid.UUID
+	Status     *string
+	Limit      *int
+	Offset     *int
+}
+
+func (p *ListPurchaseOrdersParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.SupplierID != nil {
+		q.Set("supplier_id", (*p.SupplierID).String())
+	}
+	if p.StoreID != nil {
+		q.Set("store_id", (*p.StoreID).String())
+	}
+	if p.Status != nil {
+		q.Set("status", *p.Status)
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// CreatePurchaseOrder sends POST /purchase-orders: create purchase order
+func (c *Client) CreatePurchaseOrder(ctx context.Context, body *apiclient.PurchaseOrderRequest) (*apiclient.PurchaseOrder, error) {
+	var out apiclient.PurchaseOrder
+	if err := c.client.Do(ctx, "POST", "/purchase-orders", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPurchaseOrder sends GET /purchase-orders/{id}: get purchase order
+func (c *Client) GetPurchaseOrder(ctx context.Context, id uuid.UUID) (*apiclient.PurchaseOrder, error) {
+	var out apiclient.PurchaseOrder
+	if err := c.client.Do(ctx, "GET", "/purchase-orders/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdatePurchaseOrder sends PUT /purchase-orders/{id}: update draft purchase order
+func (c *Client) UpdatePurchaseOrder(ctx context.Context, id uuid.UUID, body *apiclient.PurchaseOrderRequest) (*apiclient.PurchaseOrder, error) {
+	var out apiclient.PurchaseOrder
+	if err := c.client.Do(ctx, "PUT", "/purchase-orders/"+url.PathEscape(id.String()), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SubmitPurchaseOrder sends POST /purchase-orders/{id}/submit: submit purchase order
+func (c *Client) SubmitPurchaseOrder(ctx context.Context, id uuid.UUID, body *SubmitPurchaseOrderRequest) (*apiclient.PurchaseOrder, error) {
+	var in any
+	if body != nil {
+		in = body
+	}
+	var out apiclient.PurchaseOrder
+	if err := c.client.Do(ctx, "POST", "/purchase-orders/"+url.PathEscape(id.String())+"/submit", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CancelPurchaseOrder sends POST /purchase-orders/{id}/cancel: cancel purchase order
+func (c *Client) CancelPurchaseOrder(ctx context.Context, id uuid.UUID) (*apiclient.PurchaseOrder, error) {
+	var out apiclient.PurchaseOrder
+	if err := c.client.Do(ctx, "POST", "/purchase-orders/"+url.PathEscape(id.String())+"/cancel", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ClosePurchaseOrder sends POST /purchase-orders/{id}/close: close purchase order short
+func (c *Client) ClosePurchaseOrder(ctx context.Context, id uuid.UUID) (*apiclient.PurchaseOrder, error) {
+	var out apiclient.PurchaseOrder
+	if err := c.client.Do(ctx, "POST", "/purchase-orders/"+url.PathEscape(id.String())+"/close", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListGoodsReceipts sends GET /purchase-orders/{id}/receipts: list goods receipts
+func (c *Client) ListGoodsReceipts(ctx context.Context, id uuid.UUID) (*ListGoodsReceiptsResponse, error) {
+	var out ListGoodsReceiptsResponse
+	if err := c.client.Do(ctx, "GET", "/purchase-orders/"+url.PathEscape(id.String())+"/receipts", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReceiveGoods sends POST /purchase-orders/{id}/receipts: receive goods
+func (c *Client) ReceiveGoods(ctx context.Context, id uuid.UUID, body *apiclient.GoodsReceiptRequest) (*apiclient.GoodsReceipt, error) {
+	var out apiclient.GoodsReceipt
+	if err := c.client.Do(ctx, "POST", "/purchase-orders/"+url.PathEscape(id.String())+"/receipts", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSuppliersResponse is generated from #/paths/~1suppliers/get/responses/200
+type ListSuppliersResponse struct {
+	Data []apiclient.Supplier `json:"data,omitempty"`
+}
+
+// ListSupplierProductsResponse is generated from #/paths/~1suppliers~1{id}~1products/get/responses/200
+type ListSupplierProductsResponse struct {
+	Data []apiclient.SupplierProduct `json:"data,omitempty"`
+}
+
+// ListPurchaseOrdersResponse is generated from #/paths/~1purchase-orders/get/responses/200
+type ListPurchaseOrdersResponse struct {
+	Data []apiclient.PurchaseOrder `json:"data,omitempty"`
+}
+
+// SubmitPurchaseOrderRequest is generated from #/paths/~1purchase-orders~1{id}~1submit/post/requestBody
+type SubmitPurchaseOrderRequest struct {
+	ExpectedOn *string `json:"expected_on,omitempty"`
+}
+
+// ListGoodsReceiptsResponse is generated from #/paths/~1purchase-orders~1{id}~1receipts/get/responses/200
+type ListGoodsReceiptsResponse struct {
+	Data []apiclient.GoodsReceipt `json:"data,omitempty"`
+}
@@ -0,0 +1,188 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package promotions is a client of the Promotions operations of the API.
+package promotions
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Promotions requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Promotions operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// ListPromotions sends GET /promotions: list promotions
+func (c *Client) ListPromotions(ctx context.Context, params *ListPromotionsParams) (*ListPromotionsResponse, error) {
+	var out ListPromotionsResponse
+	if err := c.client.Do(ctx, "GET", "/promotions", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPromotionsParams are the query parameters of ListPromotions
+type ListPromotionsParams struct {
+	Active *bool
+	Limit  *int
+	Offset *int
+}
+
+func (p *ListPromotionsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Active != nil {
+		q.Set("active", fmt.Sprint(*p.Active))
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// CreatePromotion sends POST /promotions: create promotion
+func (c *Client) CreatePromotion(ctx context.Context, body *apiclient.PromotionRequest) (*apiclient.Promotion, error) {
+	var out apiclient.Promotion
+	if err := c.client.Do(ctx, "POST", "/promotions", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPromotion sends GET /promotions/{id}: get promotion
+func (c *Client) GetPromotion(ctx context.Context, id uuid.UUID) (*apiclient.Promotion, error) {
+	var out apiclient.Promotion
+	if err := c.client.Do(ctx, "GET", "/promotions/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdatePromotion sends PUT /promotions/{id}: update promotion
+func (c *Client) UpdatePromotion(ctx context.Context, id uuid.UUID, body *apiclient.PromotionRequest) (*apiclient.Promotion, error) {
+	var out apiclient.Promotion
+	if err := c.client.Do(ctx, "PUT", "/promotions/"+url.PathEscape(id.String()), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeletePromotion sends DELETE /promotions/{id}: delete promotion
+func (c *Client) DeletePromotion(ctx context.Context, id uuid.UUID) error {
+	return c.client.Do(ctx, "DELETE", "/promotions/"+url.PathEscape(id.String()), nil, nil, nil)
+}
+
+// ListCoupons sends GET /promotions/{id}/coupons: list a promotion's coupons
+func (c *Client) ListCoupons(ctx context.Context, id uuid.UUID, params *ListCouponsParams) (*ListCouponsResponse, error) {
+	var out ListCouponsResponse
+	if err := c.client.Do(ctx, "GET", "/promotions/"+url.PathEscape(id.String())+"/coupons", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListCouponsParams are the query parameters of ListCoupons
+type ListCouponsParams struct {
+	Limit  *int
+	Offset *int
+}
+
+func (p *ListCouponsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// CreateCoupon sends POST /promotions/{id}/coupons: create coupon
+func (c *Client) CreateCoupon(ctx context.Context, id uuid.UUID, body *apiclient.CouponRequest) (*apiclient.Coupon, error) {
+	var out apiclient.Coupon
+	if err := c.client.Do(ctx, "POST", "/promotions/"+url.PathEscape(id.String())+"/coupons", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateCoupon sends PUT /promotions/{id}/coupons/{couponId}: update coupon
+func (c *Client) UpdateCoupon(ctx context.Context, id uuid.UUID, couponID uuid.UUID, body *UpdateCouponRequest) (*apiclient.Coupon, error) {
+	var out apiclient.Coupon
+	if err := c.client.Do(ctx, "PUT", "/promotions/"+url.PathEscape(id.String())+"/coupons/"+url.PathEscape(couponID.String()), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteCoupon sends DELETE /promotions/{id}/coupons/{couponId}: delete coupon
+func (c *Client) DeleteCoupon(ctx context.Context, id uuid.UUID, couponID uuid.UUID) error {
+	return c.client.Do(ctx, "DELETE", "/promotions/"+url.PathEscape(id.String())+"/coupons/"+url.PathEscape(couponID.String()), nil, nil, nil)
+}
+
+// ValidateCoupon sends POST /coupons/validate: validate coupon
+func (c *Client) ValidateCoupon(ctx context.Context, body *ValidateCouponRequest) (*apiclient.CouponValidation, error) {
+	var out apiclient.CouponValidation
+	if err := c.client.Do(ctx, "POST", "/coupons/validate", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPromotionsResponse is generated from #/paths/~1promotions/get/responses/200
+type ListPromotionsResponse struct {
+	Data []apiclient.Promotion `json:"data,omitempty"`
+}
+
+// ListCouponsResponse is generated from #/paths/~1promotions~1{id}~1coupons/get/responses/200
+type ListCouponsResponse struct {
+	Data   []apiclient.Coupon `json:"data,omitempty"`
+	Limit  int                `json:"limit,omitempty"`
+	Offset int                `json:"offset,omitempty"`
+}
+
+// UpdateCouponRequest is generated from #/paths/~1promotions~1{id}~1coupons~1{couponId}/put/requestBody
+type UpdateCouponRequest struct {
+	MaxUses        *int       `json:"max_uses,omitempty"`
+	MaxUsesPerUser *int       `json:"max_uses_per_user,omitempty"`
+	Active         *bool      `json:"active,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+// ValidateCouponRequest is generated from #/paths/~1coupons~1validate/post/requestBody
+type ValidateCouponRequest struct {
+	Code    string    `json:"code"`
+	StoreID uuid.UUID `json:"store_id"`
+	// Currency of the lines; required with them
+	Currency *string                     `json:"currency,omitempty"`
+	Lines    []ValidateCouponRequestLine `json:"lines,omitempty"`
+}
+
+// ValidateCouponRequestLine is generated from #/paths/~1coupons~1validate/post/requestBody/properties/lines/items
+type ValidateCouponRequestLine struct {
+	// Catalog variant ID
+	ProductID  *string    `json:"product_id,omitempty"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	Quantity   *int       `json:"quantity,omitempty"`
+	UnitPrice  *float64   `json:"unit_price,omitempty"`
+}
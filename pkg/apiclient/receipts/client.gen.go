@@ -0,0 +1,193 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package receipts is a client of the Receipts operations of the API.
+package receipts
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Receipts requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Receipts operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// PrintReceipt sends POST /receipts: print a receipt
+func (c *Client) PrintReceipt(ctx context.Context, body *apiclient.PrintReceiptRequest) (*apiclient.PrintJob, error) {
+	var out apiclient.PrintJob
+	if err := c.client.Do(ctx, "POST", "/receipts", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RenderReceipt sends GET /receipts/{orderId}: render a receipt
+func (c *Client) RenderReceipt(ctx context.Context, orderID uuid.UUID, params *RenderReceiptParams) ([]byte, error) {
+	return c.client.DoRaw(ctx, "GET", "/receipts/"+url.PathEscape(orderID.String()), params.values(), nil)
+}
+
+// RenderReceiptParams are the query parameters of RenderReceipt
+type RenderReceiptParams struct {
+	Format *string
+}
+
+func (p *RenderReceiptParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Format != nil {
+		q.Set("format", *p.Format)
+	}
+	return q
+}
+
+// GetReceiptTemplate sends GET /stores/{storeId}/receipt-template: get a store's receipt template
+func (c *Client) GetReceiptTemplate(ctx context.Context, storeID uuid.UUID) (*apiclient.ReceiptTemplate, error) {
+	var out apiclient.ReceiptTemplate
+	if err := c.client.Do(ctx, "GET", "/stores/"+url.PathEscape(storeID.String())+"/receipt-template", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateReceiptTemplate sends PUT /stores/{storeId}/receipt-template: update a store's receipt template
+func (c *Client) UpdateReceiptTemplate(ctx context.Context, storeID uuid.UUID, body *apiclient.UpdateReceiptTemplateRequest) (*apiclient.ReceiptTemplate, error) {
+	var out apiclient.ReceiptTemplate
+	if err := c.client.Do(ctx, "PUT", "/stores/"+url.PathEscape(storeID.String())+"/receipt-template", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPrintJobs sends GET /print-jobs: list print jobs
+func (c *Client) ListPrintJobs(ctx context.Context, params *ListPrintJobsParams) (*ListPrintJobsResponse, error) {
+	var out ListPrintJobsResponse
+	if err := c.client.Do(ctx, "GET", "/print-jobs", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPrintJobsParams are the query parameters of ListPrintJobs
+type ListPrintJobsParams struct {
+	StoreID uuid.UUID
+	Status  *string
+	Limit   *int
+	Offset  *int
+}
+
+func (p *ListPrintJobsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	q.Set("store_id", p.StoreID.String())
+	if p.Status != nil {
+		q.Set("status", *p.Status)
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// GetPrintJob sends GET /print-jobs/{id}: get a print job
+func (c *Client) GetPrintJob(ctx context.Context, id uuid.UUID) (*apiclient.PrintJob, error) {
+	var out apiclient.PrintJob
+	if err := c.client.Do(ctx, "GET", "/print-jobs/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// OpenCashDrawer sends POST /print-jobs/drawer: open a cash drawer
+func (c *Client) OpenCashDrawer(ctx context.Context, body *OpenCashDrawerRequest) (*apiclient.PrintJob, error) {
+	var out apiclient.PrintJob
+	if err := c.client.Do(ctx, "POST", "/print-jobs/drawer", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ClaimPrintJobs sends GET /print-agent/jobs: claim print jobs
+func (c *Client) ClaimPrintJobs(ctx context.Context, params *ClaimPrintJobsParams) (*ClaimPrintJobsResponse, error) {
+	var out ClaimPrintJobsResponse
+	if err := c.client.Do(ctx, "GET", "/print-agent/jobs", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ClaimPrintJobsParams are the query parameters of ClaimPrintJobs
+type ClaimPrintJobsParams struct {
+	PrinterID *string
+	Limit     *int
+}
+
+func (p *ClaimPrintJobsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.PrinterID != nil {
+		q.Set("printer_id", *p.PrinterID)
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	return q
+}
+
+// AckPrintJob sends POST /print-agent/jobs/{id}/ack: acknowledge a print job
+func (c *Client) AckPrintJob(ctx context.Context, id uuid.UUID, body *AckPrintJobRequest) (*apiclient.PrintJob, error) {
+	var out apiclient.PrintJob
+	if err := c.client.Do(ctx, "POST", "/print-agent/jobs/"+url.PathEscape(id.String())+"/ack", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPrintJobsResponse is generated from #/paths/~1print-jobs/get/responses/200
+type ListPrintJobsResponse struct {
+	Data []apiclient.PrintJob `json:"data,omitempty"`
+}
+
+// OpenCashDrawerRequest is generated from #/paths/~1print-jobs~1drawer/post/requestBody
+type OpenCashDrawerRequest struct {
+	StoreID   uuid.UUID `json:"store_id"`
+	PrinterID *string   `json:"printer_id,omitempty"`
+}
+
+// ClaimPrintJobsResponse is generated from #/paths/~1print-agent~1jobs/get/responses/200
+type ClaimPrintJobsResponse struct {
+	Data []apiclient.PrintJob `json:"data,omitempty"`
+}
+
+// AckPrintJobRequest is generated from #/paths/~1print-agent~1jobs~1{id}~1ack/post/requestBody
+type AckPrintJobRequest struct {
+	Status AckPrintJobRequestStatus `json:"status"`
+	Error  *string                  `json:"error,omitempty"`
+}
+
+// AckPrintJobRequestStatus is generated from #/paths/~1print-agent~1jobs~1{id}~1ack/post/requestBody/properties/status
+type AckPrintJobRequestStatus string
+
+// Values of AckPrintJobRequestStatus
+const (
+	AckPrintJobRequestStatusPrinted AckPrintJobRequestStatus = "printed"
+	AckPrintJobRequestStatusFailed  AckPrintJobRequestStatus = "failed"
+)
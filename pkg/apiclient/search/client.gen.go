@@ -0,0 +1,141 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package search is a client of the Search operations of the API.
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Search requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Search operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// SearchOrders sends GET /search/orders: search orders
+func (c *Client) SearchOrders(ctx context.Context, params *SearchOrdersParams) (*SearchOrdersResponse, error) {
+	var out SearchOrdersResponse
+	if err := c.client.Do(ctx, "GET", "/search/orders", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SearchOrdersParams are the query parameters of SearchOrders
+type SearchOrdersParams struct {
+	Q        *string
+	Status   *string
+	StoreID  *uuid.UUID
+	From     *string
+	To       *string
+	Interval *string
+	Limit    *int
+	Offset   *int
+}
+
+func (p *SearchOrdersParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Q != nil {
+		q.Set("q", *p.Q)
+	}
+	if p.Status != nil {
+		q.Set("status", *p.Status)
+	}
+	if p.StoreID != nil {
+		q.Set("store_id", (*p.StoreID).String())
+	}
+	if p.From != nil {
+		q.Set("from", *p.From)
+	}
+	if p.To != nil {
+		q.Set("to", *p.To)
+	}
+	if p.Interval != nil {
+		q.Set("interval", *p.Interval)
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// SearchProducts sends GET /search/products: search products
+func (c *Client) SearchProducts(ctx context.Context, params *SearchProductsParams) (*SearchProductsResponse, error) {
+	var out SearchProductsResponse
+	if err := c.client.Do(ctx, "GET", "/search/products", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SearchProductsParams are the query parameters of SearchProducts
+type SearchProductsParams struct {
+	Q          *string
+	Status     *string
+	Brand      *string
+	CategoryID *uuid.UUID
+	Limit      *int
+	Offset     *int
+}
+
+func (p *SearchProductsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Q != nil {
+		q.Set("q", *p.Q)
+	}
+	if p.Status != nil {
+		q.Set("status", *p.Status)
+	}
+	if p.Brand != nil {
+		q.Set("brand", *p.Brand)
+	}
+	if p.CategoryID != nil {
+		q.Set("category_id", (*p.CategoryID).String())
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// SearchOrdersResponse is generated from #/paths/~1search~1orders/get/responses/200
+type SearchOrdersResponse struct {
+	Data  []apiclient.OrderSearchHit `json:"data,omitempty"`
+	Total int                        `json:"total,omitempty"`
+	// Counts by status, store_id and created_at
+	Facets map[string][]apiclient.SearchBucket `json:"facets,omitempty"`
+	Limit  int                                 `json:"limit,omitempty"`
+	Offset int                                 `json:"offset,omitempty"`
+}
+
+// SearchProductsResponse is generated from #/paths/~1search~1products/get/responses/200
+type SearchProductsResponse struct {
+	Data  []apiclient.ProductSearchHit `json:"data,omitempty"`
+	Total int                          `json:"total,omitempty"`
+	// Counts by status, brand and category_id
+	Facets map[string][]apiclient.SearchBucket `json:"facets,omitempty"`
+	Limit  int                                 `json:"limit,omitempty"`
+	Offset int                                 `json:"offset,omitempty"`
+}
@@ -0,0 +1,384 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package shifts is a client of the Shifts operations of the API.
+package shifts
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Shifts requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Shifts operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// ListShifts sends GET /shifts: list shifts
+func (c *Client) ListShifts(ctx context.Context, params *ListShiftsParams) (*ListShiftsResponse, error) {
+	var out ListShiftsResponse
+	if err := c.client.Do(ctx, "GET", "/shifts", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListShiftsParams are the query parameters of ListShifts
+type ListShiftsParams struct {
+	StoreID    *uuid.UUID
+	RegisterID *string
+	Status     *string
+	Limit      *int
+	Offset     *int
+}
+
+func (p *ListShiftsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.StoreID != nil {
+		q.Set("store_id", (*p.StoreID).String())
+	}
+	if p.RegisterID != nil {
+		q.Set("register_id", *p.RegisterID)
+	}
+	if p.Status != nil {
+		q.Set("status", *p.Status)
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// OpenShift sends POST /shifts: open a shift
+func (c *Client) OpenShift(ctx context.Context, body *apiclient.OpenShiftRequest) (*apiclient.Shift, error) {
+	var out apiclient.Shift
+	if err := c.client.Do(ctx, "POST", "/shifts", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetShiftZReports sends GET /shifts/z-reports: end-of-day Z reports
+func (c *Client) GetShiftZReports(ctx context.Context, params *GetShiftZReportsParams) (*apiclient.ShiftDayReport, error) {
+	var out apiclient.ShiftDayReport
+	if err := c.client.Do(ctx, "GET", "/shifts/z-reports", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetShiftZReportsParams are the query parameters of GetShiftZReports
+type GetShiftZReportsParams struct {
+	Date    *string
+	StoreID *uuid.UUID
+	StaffID *uuid.UUID
+}
+
+func (p *GetShiftZReportsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Date != nil {
+		q.Set("date", *p.Date)
+	}
+	if p.StoreID != nil {
+		q.Set("store_id", (*p.StoreID).String())
+	}
+	if p.StaffID != nil {
+		q.Set("staff_id", (*p.StaffID).String())
+	}
+	return q
+}
+
+// GetShift sends GET /shifts/{id}: get a shift
+func (c *Client) GetShift(ctx context.Context, id uuid.UUID) (*apiclient.Shift, error) {
+	var out apiclient.Shift
+	if err := c.client.Do(ctx, "GET", "/shifts/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CloseShift sends POST /shifts/{id}/close: close a shift
+func (c *Client) CloseShift(ctx context.Context, id uuid.UUID, body *CloseShiftRequest) (*CloseShiftResponse, error) {
+	var out CloseShiftResponse
+	if err := c.client.Do(ctx, "POST", "/shifts/"+url.PathEscape(id.String())+"/close", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListCashMovements sends GET /shifts/{id}/cash-movements: list cash movements
+func (c *Client) ListCashMovements(ctx context.Context, id uuid.UUID) (*ListCashMovementsResponse, error) {
+	var out ListCashMovementsResponse
+	if err := c.client.Do(ctx, "GET", "/shifts/"+url.PathEscape(id.String())+"/cash-movements", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RecordCashMovement sends POST /shifts/{id}/cash-movements: record a cash movement
+func (c *Client) RecordCashMovement(ctx context.Context, id uuid.UUID, body *RecordCashMovementRequest) (*apiclient.CashMovement, error) {
+	var out apiclient.CashMovement
+	if err := c.client.Do(ctx, "POST", "/shifts/"+url.PathEscape(id.String())+"/cash-movements", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetShiftReport sends GET /shifts/{id}/report: get a shift report
+func (c *Client) GetShiftReport(ctx context.Context, id uuid.UUID, params *GetShiftReportParams) (*apiclient.ShiftReport, error) {
+	var out apiclient.ShiftReport
+	if err := c.client.Do(ctx, "GET", "/shifts/"+url.PathEscape(id.String())+"/report", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetShiftReportParams are the query parameters of GetShiftReport
+type GetShiftReportParams struct {
+	Format *string
+}
+
+func (p *GetShiftReportParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Format != nil {
+		q.Set("format", *p.Format)
+	}
+	return q
+}
+
+// ClockIn sends POST /timeclock/clock-in: clock in
+func (c *Client) ClockIn(ctx context.Context, body *ClockInRequest) (*apiclient.TimeEntry, error) {
+	var out apiclient.TimeEntry
+	if err := c.client.Do(ctx, "POST", "/timeclock/clock-in", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ClockOut sends POST /timeclock/clock-out: clock out
+func (c *Client) ClockOut(ctx context.Context, body *ClockOutRequest) (*apiclient.TimeEntry, error) {
+	var in any
+	if body != nil {
+		in = body
+	}
+	var out apiclient.TimeEntry
+	if err := c.client.Do(ctx, "POST", "/timeclock/clock-out", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StartBreak sends POST /timeclock/breaks/start: start a break
+func (c *Client) StartBreak(ctx context.Context, body *StartBreakRequest) (*apiclient.TimeEntry, error) {
+	var in any
+	if body != nil {
+		in = body
+	}
+	var out apiclient.TimeEntry
+	if err := c.client.Do(ctx, "POST", "/timeclock/breaks/start", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EndBreak sends POST /timeclock/breaks/end: end a break
+func (c *Client) EndBreak(ctx context.Context) (*apiclient.TimeEntry, error) {
+	var out apiclient.TimeEntry
+	if err := c.client.Do(ctx, "POST", "/timeclock/breaks/end", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCurrentTimeEntry sends GET /timeclock/current: get the current time entry
+func (c *Client) GetCurrentTimeEntry(ctx context.Context) (*GetCurrentTimeEntryResponse, error) {
+	var out GetCurrentTimeEntryResponse
+	if err := c.client.Do(ctx, "GET", "/timeclock/current", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListTimeEntries sends GET /timeclock/entries: list time entries
+func (c *Client) ListTimeEntries(ctx context.Context, params *ListTimeEntriesParams) (*ListTimeEntriesResponse, error) {
+	var out ListTimeEntriesResponse
+	if err := c.client.Do(ctx, "GET", "/timeclock/entries", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListTimeEntriesParams are the query parameters of ListTimeEntries
+type ListTimeEntriesParams struct {
+	StoreID *uuid.UUID
+	StaffID *uuid.UUID
+	Status  *string
+	From    *string
+	To      *string
+	Limit   *int
+	Offset  *int
+}
+
+func (p *ListTimeEntriesParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.StoreID != nil {
+		q.Set("store_id", (*p.StoreID).String())
+	}
+	if p.StaffID != nil {
+		q.Set("staff_id", (*p.StaffID).String())
+	}
+	if p.Status != nil {
+		q.Set("status", *p.Status)
+	}
+	if p.From != nil {
+		q.Set("from", *p.From)
+	}
+	if p.To != nil {
+		q.Set("to", *p.To)
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// GetTimeEntry sends GET /timeclock/entries/{id}: get a time entry
+func (c *Client) GetTimeEntry(ctx context.Context, id uuid.UUID) (*apiclient.TimeEntry, error) {
+	var out apiclient.TimeEntry
+	if err := c.client.Do(ctx, "GET", "/timeclock/entries/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AdjustTimeEntry sends PUT /timeclock/entries/{id}: adjust a time entry
+func (c *Client) AdjustTimeEntry(ctx context.Context, id uuid.UUID, body *AdjustTimeEntryRequest) (*apiclient.TimeAdjustment, error) {
+	var out apiclient.TimeAdjustment
+	if err := c.client.Do(ctx, "PUT", "/timeclock/entries/"+url.PathEscape(id.String()), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListTimeEntryAdjustments sends GET /timeclock/entries/{id}/adjustments: list time entry adjustments
+func (c *Client) ListTimeEntryAdjustments(ctx context.Context, id uuid.UUID) (*ListTimeEntryAdjustmentsResponse, error) {
+	var out ListTimeEntryAdjustmentsResponse
+	if err := c.client.Do(ctx, "GET", "/timeclock/entries/"+url.PathEscape(id.String())+"/adjustments", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListShiftsResponse is generated from #/paths/~1shifts/get/responses/200
+type ListShiftsResponse struct {
+	Data   []apiclient.Shift `json:"data,omitempty"`
+	Limit  int               `json:"limit,omitempty"`
+	Offset int               `json:"offset,omitempty"`
+}
+
+// CloseShiftResponse is generated from #/paths/~1shifts~1{id}~1close/post/responses/200
+type CloseShiftResponse struct {
+	Shift  apiclient.Shift       `json:"shift,omitempty"`
+	Report apiclient.ShiftReport `json:"report,omitempty"`
+}
+
+// CloseShiftRequest is generated from #/paths/~1shifts~1{id}~1close/post/requestBody
+type CloseShiftRequest struct {
+	CountedCash float64 `json:"counted_cash"`
+	Notes       *string `json:"notes,omitempty"`
+}
+
+// ListCashMovementsResponse is generated from #/paths/~1shifts~1{id}~1cash-movements/get/responses/200
+type ListCashMovementsResponse struct {
+	Data []apiclient.CashMovement `json:"data,omitempty"`
+}
+
+// RecordCashMovementRequest is generated from #/paths/~1shifts~1{id}~1cash-movements/post/requestBody
+type RecordCashMovementRequest struct {
+	Type   RecordCashMovementRequestType `json:"type"`
+	Amount float64                       `json:"amount"`
+	Reason *string                       `json:"reason,omitempty"`
+}
+
+// RecordCashMovementRequestType is generated from #/paths/~1shifts~1{id}~1cash-movements/post/requestBody/properties/type
+type RecordCashMovementRequestType string
+
+// Values of RecordCashMovementRequestType
+const (
+	RecordCashMovementRequestTypePayIn  RecordCashMovementRequestType = "pay_in"
+	RecordCashMovementRequestTypePayOut RecordCashMovementRequestType = "pay_out"
+	RecordCashMovementRequestTypeDrop   RecordCashMovementRequestType = "drop"
+)
+
+// ClockInRequest is generated from #/paths/~1timeclock~1clock-in/post/requestBody
+type ClockInRequest struct {
+	StoreID uuid.UUID  `json:"store_id"`
+	ShiftID *uuid.UUID `json:"shift_id,omitempty"`
+	Notes   *string    `json:"notes,omitempty"`
+}
+
+// ClockOutRequest is generated from #/paths/~1timeclock~1clock-out/post/requestBody
+type ClockOutRequest struct {
+	Notes *string `json:"notes,omitempty"`
+}
+
+// StartBreakRequest is generated from #/paths/~1timeclock~1breaks~1start/post/requestBody
+type StartBreakRequest struct {
+	Paid *bool `json:"paid,omitempty"`
+}
+
+// GetCurrentTimeEntryResponse is generated from #/paths/~1timeclock~1current/get/responses/200
+type GetCurrentTimeEntryResponse struct {
+	Entry              apiclient.TimeEntry `json:"entry,omitempty"`
+	WorkedSeconds      int                 `json:"worked_seconds,omitempty"`
+	UnpaidBreakSeconds int                 `json:"unpaid_break_seconds,omitempty"`
+}
+
+// ListTimeEntriesResponse is generated from #/paths/~1timeclock~1entries/get/responses/200
+type ListTimeEntriesResponse struct {
+	Data   []apiclient.TimeEntry `json:"data,omitempty"`
+	Limit  int                   `json:"limit,omitempty"`
+	Offset int                   `json:"offset,omitempty"`
+}
+
+// AdjustTimeEntryRequest is generated from #/paths/~1timeclock~1entries~1{id}/put/requestBody
+type AdjustTimeEntryRequest struct {
+	ClockIn time.Time `json:"clock_in"`
+	// Required for a closed entry; clocks an open one out
+	ClockOut *time.Time            `json:"clock_out,omitempty"`
+	Breaks   []apiclient.TimeBreak `json:"breaks,omitempty"`
+	Notes    *string               `json:"notes,omitempty"`
+	Reason   string                `json:"reason"`
+}
+
+// ListTimeEntryAdjustmentsResponse is generated from #/paths/~1timeclock~1entries~1{id}~1adjustments/get/responses/200
+type ListTimeEntryAdjustmentsResponse struct {
+	Data []apiclient.TimeAdjustment `json:"data,omitempty"`
+}
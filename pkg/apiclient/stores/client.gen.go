@@ -0,0 +1,262 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package stores is a client of the Stores operations of the API.
+package stores
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Stores requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Stores operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// ListStores sends GET /stores: list stores
+func (c *Client) ListStores(ctx context.Context, params *ListStoresParams) (*ListStoresResponse, error) {
+	var out ListStoresResponse
+	if err := c.client.Do(ctx, "GET", "/stores", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListStoresParams are the query parameters of ListStores
+type ListStoresParams struct {
+	Limit  *int
+	Offset *int
+}
+
+func (p *ListStoresParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// CreateStore sends POST /stores: create store
+func (c *Client) CreateStore(ctx context.Context, body *apiclient.CreateStoreRequest) (*apiclient.Store, error) {
+	var out apiclient.Store
+	if err := c.client.Do(ctx, "POST", "/stores", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetStore sends GET /stores/{id}: get store by ID
+func (c *Client) GetStore(ctx context.Context, id uuid.UUID) (*apiclient.Store, error) {
+	var out apiclient.Store
+	if err := c.client.Do(ctx, "GET", "/stores/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SearchStores sends GET /stores/search: search stores by location
+func (c *Client) SearchStores(ctx context.Context, params *SearchStoresParams) (*SearchStoresResponse, error) {
+	var out SearchStoresResponse
+	if err := c.client.Do(ctx, "GET", "/stores/search", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SearchStoresParams are the query parameters of SearchStores
+type SearchStoresParams struct {
+	Lat    float64
+	Lng    float64
+	Radius *float64
+}
+
+func (p *SearchStoresParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	q.Set("lat", fmt.Sprint(p.Lat))
+	q.Set("lng", fmt.Sprint(p.Lng))
+	if p.Radius != nil {
+		q.Set("radius", fmt.Sprint(*p.Radius))
+	}
+	return q
+}
+
+// ListDevices sends GET /stores/{id}/devices: list a store's devices
+func (c *Client) ListDevices(ctx context.Context, id uuid.UUID, params *ListDevicesParams) (*ListDevicesResponse, error) {
+	var out ListDevicesResponse
+	if err := c.client.Do(ctx, "GET", "/stores/"+url.PathEscape(id.String())+"/devices", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListDevicesParams are the query parameters of ListDevices
+type ListDevicesParams struct {
+	Status *string
+}
+
+func (p *ListDevicesParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Status != nil {
+		q.Set("status", *p.Status)
+	}
+	return q
+}
+
+// RegisterDevice sends POST /stores/{id}/devices: register a device
+func (c *Client) RegisterDevice(ctx context.Context, id uuid.UUID, body *apiclient.RegisterDeviceRequest) (*apiclient.DevicePairingCode, error) {
+	var out apiclient.DevicePairingCode
+	if err := c.client.Do(ctx, "POST", "/stores/"+url.PathEscape(id.String())+"/devices", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDevice sends GET /stores/{id}/devices/{deviceId}: get a device
+func (c *Client) GetDevice(ctx context.Context, id uuid.UUID, deviceID uuid.UUID) (*apiclient.Device, error) {
+	var out apiclient.Device
+	if err := c.client.Do(ctx, "GET", "/stores/"+url.PathEscape(id.String())+"/devices/"+url.PathEscape(deviceID.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ResetDevicePairing sends POST /stores/{id}/devices/{deviceId}/pairing-code: issue a new pairing code
+func (c *Client) ResetDevicePairing(ctx context.Context, id uuid.UUID, deviceID uuid.UUID) (*apiclient.DevicePairingCode, error) {
+	var out apiclient.DevicePairingCode
+	if err := c.client.Do(ctx, "POST", "/stores/"+url.PathEscape(id.String())+"/devices/"+url.PathEscape(deviceID.String())+"/pairing-code", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeactivateDevice sends POST /stores/{id}/devices/{deviceId}/deactivate: deactivate a device
+func (c *Client) DeactivateDevice(ctx context.Context, id uuid.UUID, deviceID uuid.UUID, body *DeactivateDeviceRequest) (*apiclient.Device, error) {
+	var in any
+	if body != nil {
+		in = body
+	}
+	var out apiclient.Device
+	if err := c.client.Do(ctx, "POST", "/stores/"+url.PathEscape(id.String())+"/devices/"+url.PathEscape(deviceID.String())+"/deactivate", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListStoreExports sends GET /stores/{id}/exports: list a store's exports
+func (c *Client) ListStoreExports(ctx context.Context, id uuid.UUID) (*ListStoreExportsResponse, error) {
+	var out ListStoreExportsResponse
+	if err := c.client.Do(ctx, "GET", "/stores/"+url.PathEscape(id.String())+"/exports", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateStoreExport sends POST /stores/{id}/exports: request an export of a store's data
+func (c *Client) CreateStoreExport(ctx context.Context, id uuid.UUID) (*apiclient.StoreExport, error) {
+	var out apiclient.StoreExport
+	if err := c.client.Do(ctx, "POST", "/stores/"+url.PathEscape(id.String())+"/exports", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetStoreExport sends GET /stores/{id}/exports/{exportId}: get a store export
+func (c *Client) GetStoreExport(ctx context.Context, id uuid.UUID, exportID uuid.UUID) (*apiclient.StoreExport, error) {
+	var out apiclient.StoreExport
+	if err := c.client.Do(ctx, "GET", "/stores/"+url.PathEscape(id.String())+"/exports/"+url.PathEscape(exportID.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DownloadStoreExport sends GET /stores/{id}/exports/{exportId}/download: download a store export
+func (c *Client) DownloadStoreExport(ctx context.Context, id uuid.UUID, exportID uuid.UUID) ([]byte, error) {
+	return c.client.DoRaw(ctx, "GET", "/stores/"+url.PathEscape(id.String())+"/exports/"+url.PathEscape(exportID.String())+"/download", nil, nil)
+}
+
+// PairDevice sends POST /devices/pair: pair a device
+func (c *Client) PairDevice(ctx context.Context, body *apiclient.PairDeviceRequest) (*apiclient.DevicePairing, error) {
+	var out apiclient.DevicePairing
+	if err := c.client.Do(ctx, "POST", "/devices/pair", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SendDeviceHeartbeat sends POST /devices/heartbeat: send a device heartbeat
+func (c *Client) SendDeviceHeartbeat(ctx context.Context, body *SendDeviceHeartbeatRequest) (*SendDeviceHeartbeatResponse, error) {
+	var in any
+	if body != nil {
+		in = body
+	}
+	var out SendDeviceHeartbeatResponse
+	if err := c.client.Do(ctx, "POST", "/devices/heartbeat", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListStoresResponse is generated from #/paths/~1stores/get/responses/200
+type ListStoresResponse struct {
+	Data []apiclient.Store `json:"data,omitempty"`
+}
+
+// SearchStoresResponse is generated from #/paths/~1stores~1search/get/responses/200
+type SearchStoresResponse struct {
+	Data []apiclient.Store `json:"data,omitempty"`
+}
+
+// ListDevicesResponse is generated from #/paths/~1stores~1{id}~1devices/get/responses/200
+type ListDevicesResponse struct {
+	Data []apiclient.Device `json:"data,omitempty"`
+}
+
+// DeactivateDeviceRequest is generated from #/paths/~1stores~1{id}~1devices~1{deviceId}~1deactivate/post/requestBody
+type DeactivateDeviceRequest struct {
+	Reason *string `json:"reason,omitempty"`
+}
+
+// ListStoreExportsResponse is generated from #/paths/~1stores~1{id}~1exports/get/responses/200
+type ListStoreExportsResponse struct {
+	Data []apiclient.StoreExport `json:"data,omitempty"`
+}
+
+// SendDeviceHeartbeatResponse is generated from #/paths/~1devices~1heartbeat/post/responses/200
+type SendDeviceHeartbeatResponse struct {
+	Status                   SendDeviceHeartbeatResponseStatus `json:"status,omitempty"`
+	HeartbeatIntervalSeconds int                               `json:"heartbeat_interval_seconds,omitempty"`
+}
+
+// SendDeviceHeartbeatResponseStatus is generated from #/paths/~1devices~1heartbeat/post/responses/200/properties/status
+type SendDeviceHeartbeatResponseStatus string
+
+// Values of SendDeviceHeartbeatResponseStatus
+const (
+	SendDeviceHeartbeatResponseStatusActive SendDeviceHeartbeatResponseStatus = "active"
+)
+
+// SendDeviceHeartbeatRequest is generated from #/paths/~1devices~1heartbeat/post/requestBody
+type SendDeviceHeartbeatRequest struct {
+	AppVersion *string `json:"app_version,omitempty"`
+}
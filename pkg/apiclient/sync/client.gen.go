@@ -0,0 +1,66 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package sync is a client of the Sync operations of the API.
+package sync
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Sync requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Sync operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// GetSyncChanges sends GET /sync/stores/{storeId}/changes: pull a store's changes
+func (c *Client) GetSyncChanges(ctx context.Context, storeID uuid.UUID, params *GetSyncChangesParams) (*apiclient.SyncChangePage, error) {
+	var out apiclient.SyncChangePage
+	if err := c.client.Do(ctx, "GET", "/sync/stores/"+url.PathEscape(storeID.String())+"/changes", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSyncChangesParams are the query parameters of GetSyncChanges
+type GetSyncChangesParams struct {
+	Since *int
+	Limit *int
+}
+
+func (p *GetSyncChangesParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Since != nil {
+		q.Set("since", fmt.Sprint(*p.Since))
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	return q
+}
+
+// PushSyncTransactions sends POST /sync/stores/{storeId}/transactions: upload sales rung up offline
+func (c *Client) PushSyncTransactions(ctx context.Context, storeID uuid.UUID, body *apiclient.SyncPushRequest) (*PushSyncTransactionsResponse, error) {
+	var out PushSyncTransactionsResponse
+	if err := c.client.Do(ctx, "POST", "/sync/stores/"+url.PathEscape(storeID.String())+"/transactions", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PushSyncTransactionsResponse is generated from #/paths/~1sync~1stores~1{storeId}~1transactions/post/responses/200
+type PushSyncTransactionsResponse struct {
+	Data []apiclient.SyncTransactionResult `json:"data,omitempty"`
+}
@@ -0,0 +1,297 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package tax is a client of the Tax operations of the API.
+package tax
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Tax requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Tax operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// ListTaxJurisdictions sends GET /tax/jurisdictions: list tax jurisdictions
+func (c *Client) ListTaxJurisdictions(ctx context.Context, params *ListTaxJurisdictionsParams) (*ListTaxJurisdictionsResponse, error) {
+	var out ListTaxJurisdictionsResponse
+	if err := c.client.Do(ctx, "GET", "/tax/jurisdictions", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListTaxJurisdictionsParams are the query parameters of ListTaxJurisdictions
+type ListTaxJurisdictionsParams struct {
+	Country *string
+	Limit   *int
+	Offset  *int
+}
+
+func (p *ListTaxJurisdictionsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Country != nil {
+		q.Set("country", *p.Country)
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// CreateTaxJurisdiction sends POST /tax/jurisdictions: create tax jurisdiction
+func (c *Client) CreateTaxJurisdiction(ctx context.Context, body *apiclient.TaxJurisdictionRequest) (*apiclient.TaxJurisdiction, error) {
+	var out apiclient.TaxJurisdiction
+	if err := c.client.Do(ctx, "POST", "/tax/jurisdictions", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTaxJurisdiction sends GET /tax/jurisdictions/{id}: get tax jurisdiction
+func (c *Client) GetTaxJurisdiction(ctx context.Context, id uuid.UUID) (*apiclient.TaxJurisdiction, error) {
+	var out apiclient.TaxJurisdiction
+	if err := c.client.Do(ctx, "GET", "/tax/jurisdictions/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateTaxJurisdiction sends PUT /tax/jurisdictions/{id}: update tax jurisdiction
+func (c *Client) UpdateTaxJurisdiction(ctx context.Context, id uuid.UUID, body *apiclient.TaxJurisdictionRequest) (*apiclient.TaxJurisdiction, error) {
+	var out apiclient.TaxJurisdiction
+	if err := c.client.Do(ctx, "PUT", "/tax/jurisdictions/"+url.PathEscape(id.String()), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListTaxCategories sends GET /tax/categories: list tax categories
+func (c *Client) ListTaxCategories(ctx context.Context) (*ListTaxCategoriesResponse, error) {
+	var out ListTaxCategoriesResponse
+	if err := c.client.Do(ctx, "GET", "/tax/categories", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutTaxCategory sends POST /tax/categories: create or replace tax category
+func (c *Client) PutTaxCategory(ctx context.Context, body *apiclient.TaxCategory) (*apiclient.TaxCategory, error) {
+	var out apiclient.TaxCategory
+	if err := c.client.Do(ctx, "POST", "/tax/categories", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListTaxAssignments sends GET /tax/assignments: list tax category assignments
+func (c *Client) ListTaxAssignments(ctx context.Context, params *ListTaxAssignmentsParams) (*ListTaxAssignmentsResponse, error) {
+	var out ListTaxAssignmentsResponse
+	if err := c.client.Do(ctx, "GET", "/tax/assignments", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListTaxAssignmentsParams are the query parameters of ListTaxAssignments
+type ListTaxAssignmentsParams struct {
+	Kind   *string
+	Limit  *int
+	Offset *int
+}
+
+func (p *ListTaxAssignmentsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Kind != nil {
+		q.Set("kind", *p.Kind)
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// AssignTaxCategory sends POST /tax/assignments: assign a tax category
+func (c *Client) AssignTaxCategory(ctx context.Context, body *apiclient.TaxAssignment) (*apiclient.TaxAssignment, error) {
+	var out apiclient.TaxAssignment
+	if err := c.client.Do(ctx, "POST", "/tax/assignments", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteTaxAssignment sends DELETE /tax/assignments/{kind}/{subjectId}: remove a tax category assignment
+func (c *Client) DeleteTaxAssignment(ctx context.Context, kind string, subjectID uuid.UUID) error {
+	return c.client.Do(ctx, "DELETE", "/tax/assignments/"+url.PathEscape(kind)+"/"+url.PathEscape(subjectID.String()), nil, nil, nil)
+}
+
+// ListTaxHolidays sends GET /tax/holidays: list tax holidays
+func (c *Client) ListTaxHolidays(ctx context.Context, params *ListTaxHolidaysParams) (*ListTaxHolidaysResponse, error) {
+	var out ListTaxHolidaysResponse
+	if err := c.client.Do(ctx, "GET", "/tax/holidays", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListTaxHolidaysParams are the query parameters of ListTaxHolidays
+type ListTaxHolidaysParams struct {
+	JurisdictionID *uuid.UUID
+	Limit          *int
+	Offset         *int
+}
+
+func (p *ListTaxHolidaysParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.JurisdictionID != nil {
+		q.Set("jurisdiction_id", (*p.JurisdictionID).String())
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// CreateTaxHoliday sends POST /tax/holidays: create tax holiday
+func (c *Client) CreateTaxHoliday(ctx context.Context, body *apiclient.TaxHoliday) (*apiclient.TaxHoliday, error) {
+	var out apiclient.TaxHoliday
+	if err := c.client.Do(ctx, "POST", "/tax/holidays", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteTaxHoliday sends DELETE /tax/holidays/{id}: delete tax holiday
+func (c *Client) DeleteTaxHoliday(ctx context.Context, id uuid.UUID) error {
+	return c.client.Do(ctx, "DELETE", "/tax/holidays/"+url.PathEscape(id.String()), nil, nil, nil)
+}
+
+// ListTaxStoreRates sends GET /tax/stores/{storeId}/rates: list a store's tax rates
+func (c *Client) ListTaxStoreRates(ctx context.Context, storeID uuid.UUID) (*ListTaxStoreRatesResponse, error) {
+	var out ListTaxStoreRatesResponse
+	if err := c.client.Do(ctx, "GET", "/tax/stores/"+url.PathEscape(storeID.String())+"/rates", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SaveTaxStoreRate sends PUT /tax/stores/{storeId}/rates/{jurisdictionId}: set a store's tax rate
+func (c *Client) SaveTaxStoreRate(ctx context.Context, storeID uuid.UUID, jurisdictionID uuid.UUID, body *SaveTaxStoreRateRequest) (*apiclient.TaxStoreRate, error) {
+	var out apiclient.TaxStoreRate
+	if err := c.client.Do(ctx, "PUT", "/tax/stores/"+url.PathEscape(storeID.String())+"/rates/"+url.PathEscape(jurisdictionID.String()), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteTaxStoreRate sends DELETE /tax/stores/{storeId}/rates/{jurisdictionId}: remove a store's tax rate
+func (c *Client) DeleteTaxStoreRate(ctx context.Context, storeID uuid.UUID, jurisdictionID uuid.UUID) error {
+	return c.client.Do(ctx, "DELETE", "/tax/stores/"+url.PathEscape(storeID.String())+"/rates/"+url.PathEscape(jurisdictionID.String()), nil, nil, nil)
+}
+
+// QuoteTax sends POST /tax/quote: quote tax
+func (c *Client) QuoteTax(ctx context.Context, body *apiclient.TaxQuoteRequest) (*apiclient.TaxQuote, error) {
+	var out apiclient.TaxQuote
+	if err := c.client.Do(ctx, "POST", "/tax/quote", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTaxReport sends GET /tax/reports: tax filing report
+func (c *Client) GetTaxReport(ctx context.Context, params *GetTaxReportParams) (*apiclient.TaxReport, error) {
+	var out apiclient.TaxReport
+	if err := c.client.Do(ctx, "GET", "/tax/reports", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTaxReportParams are the query parameters of GetTaxReport
+type GetTaxReportParams struct {
+	From    string
+	To      string
+	Country *string
+	State   *string
+	Format  *string
+}
+
+func (p *GetTaxReportParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	q.Set("from", p.From)
+	q.Set("to", p.To)
+	if p.Country != nil {
+		q.Set("country", *p.Country)
+	}
+	if p.State != nil {
+		q.Set("state", *p.State)
+	}
+	if p.Format != nil {
+		q.Set("format", *p.Format)
+	}
+	return q
+}
+
+// ListTaxJurisdictionsResponse is generated from #/paths/~1tax~1jurisdictions/get/responses/200
+type ListTaxJurisdictionsResponse struct {
+	Data []apiclient.TaxJurisdiction `json:"data,omitempty"`
+}
+
+// ListTaxCategoriesResponse is generated from #/paths/~1tax~1categories/get/responses/200
+type ListTaxCategoriesResponse struct {
+	Data []apiclient.TaxCategory `json:"data,omitempty"`
+}
+
+// ListTaxAssignmentsResponse is generated from #/paths/~1tax~1assignments/get/responses/200
+type ListTaxAssignmentsResponse struct {
+	Data []apiclient.TaxAssignment `json:"data,omitempty"`
+}
+
+// ListTaxHolidaysResponse is generated from #/paths/~1tax~1holidays/get/responses/200
+type ListTaxHolidaysResponse struct {
+	Data []apiclient.TaxHoliday `json:"data,omitempty"`
+}
+
+// ListTaxStoreRatesResponse is generated from #/paths/~1tax~1stores~1{storeId}~1rates/get/responses/200
+type ListTaxStoreRatesResponse struct {
+	Data []apiclient.TaxStoreRate `json:"data,omitempty"`
+}
+
+// SaveTaxStoreRateRequest is generated from #/paths/~1tax~1stores~1{storeId}~1rates~1{jurisdictionId}/put/requestBody
+type SaveTaxStoreRateRequest struct {
+	Rate             *float64           `json:"rate,omitempty"`
+	CategoryRates    map[string]float64 `json:"category_rates,omitempty"`
+	ExemptCategories []string           `json:"exempt_categories,omitempty"`
+	Inclusive        *bool              `json:"inclusive,omitempty"`
+}
@@ -0,0 +1,1968 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+package apiclient
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User is generated from #/components/schemas/User
+type User struct {
+	ID          uuid.UUID `json:"id,omitempty"`
+	Email       string    `json:"email,omitempty"`
+	FirstName   string    `json:"first_name,omitempty"`
+	LastName    string    `json:"last_name,omitempty"`
+	Phone       string    `json:"phone,omitempty"`
+	MFAEnabled  bool      `json:"mfa_enabled,omitempty"`
+	LastLoginAt time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+// Order is generated from #/components/schemas/Order
+type Order struct {
+	ID      uuid.UUID   `json:"id,omitempty"`
+	UserID  uuid.UUID   `json:"user_id,omitempty"`
+	StoreID uuid.UUID   `json:"store_id,omitempty"`
+	Status  OrderStatus `json:"status,omitempty"`
+	// Items less the loyalty discount, plus tax not included in their prices
+	TotalAmount float64 `json:"total_amount,omitempty"`
+	TaxAmount   float64 `json:"tax_amount,omitempty"`
+	// Part of tax_amount already included in the item prices
+	TaxIncluded float64 `json:"tax_included,omitempty"`
+	// total_amount formatted for the language negotiated from Accept-Language
+	FormattedTotal string `json:"formatted_total,omitempty"`
+	// tax_amount formatted for the language negotiated from Accept-Language
+	FormattedTax string `json:"formatted_tax,omitempty"`
+	// Tax by jurisdiction, as quoted by the tax service
+	Taxes    []JurisdictionTax `json:"taxes,omitempty"`
+	Currency string            `json:"currency,omitempty"`
+	// Loyalty points redeemed against the order
+	LoyaltyPoints int `json:"loyalty_points,omitempty"`
+	// Discount the redeemed points gave, already taken off total_amount
+	LoyaltyDiscount float64 `json:"loyalty_discount,omitempty"`
+	// Promotions that discounted the order; their discounts are on the items
+	Promotions      []OrderPromotion `json:"promotions,omitempty"`
+	ShiftID         uuid.UUID        `json:"shift_id,omitempty"`
+	Tender          OrderTender      `json:"tender,omitempty"`
+	Items           []OrderItem      `json:"items,omitempty"`
+	ShippingAddress Address          `json:"shipping_address,omitempty"`
+	BillingAddress  Address          `json:"billing_address,omitempty"`
+	Notes           string           `json:"notes,omitempty"`
+	CreatedAt       time.Time        `json:"created_at,omitempty"`
+	UpdatedAt       time.Time        `json:"updated_at,omitempty"`
+	CompletedAt     time.Time        `json:"completed_at,omitempty"`
+	CancelledAt     time.Time        `json:"cancelled_at,omitempty"`
+}
+
+// OrderStatus is generated from #/components/schemas/Order/properties/status
+type OrderStatus string
+
+// Values of OrderStatus
+const (
+	OrderStatusPending    OrderStatus = "pending"
+	OrderStatusConfirmed  OrderStatus = "confirmed"
+	OrderStatusProcessing OrderStatus = "processing"
+	OrderStatusShipped    OrderStatus = "shipped"
+	OrderStatusDelivered  OrderStatus = "delivered"
+	OrderStatusCancelled  OrderStatus = "cancelled"
+	OrderStatusRefunded   OrderStatus = "refunded"
+)
+
+// OrderPromotion is generated from #/components/schemas/Order/properties/promotions/items
+type OrderPromotion struct {
+	PromotionID uuid.UUID `json:"promotion_id,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	Amount      float64   `json:"amount,omitempty"`
+	// Coupon code that unlocked the promotion
+	Coupon string `json:"coupon,omitempty"`
+}
+
+// OrderTender is generated from #/components/schemas/Order/properties/tender
+type OrderTender string
+
+// Values of OrderTender
+const (
+	OrderTenderCash          OrderTender = "cash"
+	OrderTenderCard          OrderTender = "card"
+	OrderTenderDigitalWallet OrderTender = "digital_wallet"
+	OrderTenderBankTransfer  OrderTender = "bank_transfer"
+)
+
+// OrderChange is generated from #/components/schemas/OrderChange
+type OrderChange struct {
+	// Position of the change in the order's history, from 1
+	Version int             `json:"version,omitempty"`
+	Type    OrderChangeType `json:"type,omitempty"`
+	// The order as created or updated
+	Order Order `json:"order,omitempty"`
+	// The new status of a status change
+	Status     string    `json:"status,omitempty"`
+	OccurredAt time.Time `json:"occurred_at,omitempty"`
+}
+
+// OrderChangeType is generated from #/components/schemas/OrderChange/properties/type
+type OrderChangeType string
+
+// Values of OrderChangeType
+const (
+	OrderChangeTypeCreated       OrderChangeType = "created"
+	OrderChangeTypeUpdated       OrderChangeType = "updated"
+	OrderChangeTypeStatusChanged OrderChangeType = "status_changed"
+	OrderChangeTypeDeleted       OrderChangeType = "deleted"
+)
+
+// OrderHistory is generated from #/components/schemas/OrderHistory
+type OrderHistory struct {
+	Order   Order         `json:"order,omitempty"`
+	Changes []OrderChange `json:"changes,omitempty"`
+}
+
+// Checkout is generated from #/components/schemas/Checkout
+type Checkout struct {
+	// Also the ID of the checkout's payment
+	CheckoutID uuid.UUID      `json:"checkout_id,omitempty"`
+	OrderID    uuid.UUID      `json:"order_id,omitempty"`
+	Status     CheckoutStatus `json:"status,omitempty"`
+	Order      Order          `json:"order,omitempty"`
+}
+
+// CheckoutStatus is generated from #/components/schemas/Checkout/properties/status
+type CheckoutStatus string
+
+// Values of CheckoutStatus
+const (
+	CheckoutStatusCompleted       CheckoutStatus = "completed"
+	CheckoutStatusAwaitingPayment CheckoutStatus = "awaiting_payment"
+)
+
+// OrderStatusHistory is generated from #/components/schemas/OrderStatusHistory
+type OrderStatusHistory struct {
+	Transitions []OrderStatusTransition `json:"transitions,omitempty"`
+}
+
+// OrderStatusTransition is generated from #/components/schemas/OrderStatusTransition
+type OrderStatusTransition struct {
+	From OrderStatusTransitionFrom `json:"from,omitempty"`
+	To   OrderStatusTransitionTo   `json:"to,omitempty"`
+	// The user who moved the order, if any
+	ChangedBy uuid.UUID `json:"changed_by,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	ChangedAt time.Time `json:"changed_at,omitempty"`
+}
+
+// OrderStatusTransitionFrom is generated from #/components/schemas/OrderStatusTransition/properties/from
+type OrderStatusTransitionFrom string
+
+// Values of OrderStatusTransitionFrom
+const (
+	OrderStatusTransitionFromPending    OrderStatusTransitionFrom = "pending"
+	OrderStatusTransitionFromConfirmed  OrderStatusTransitionFrom = "confirmed"
+	OrderStatusTransitionFromProcessing OrderStatusTransitionFrom = "processing"
+	OrderStatusTransitionFromShipped    OrderStatusTransitionFrom = "shipped"
+	OrderStatusTransitionFromDelivered  OrderStatusTransitionFrom = "delivered"
+	OrderStatusTransitionFromCancelled  OrderStatusTransitionFrom = "cancelled"
+	OrderStatusTransitionFromRefunded   OrderStatusTransitionFrom = "refunded"
+)
+
+// OrderStatusTransitionTo is generated from #/components/schemas/OrderStatusTransition/properties/to
+type OrderStatusTransitionTo string
+
+// Values of OrderStatusTransitionTo
+const (
+	OrderStatusTransitionToPending    OrderStatusTransitionTo = "pending"
+	OrderStatusTransitionToConfirmed  OrderStatusTransitionTo = "confirmed"
+	OrderStatusTransitionToProcessing OrderStatusTransitionTo = "processing"
+	OrderStatusTransitionToShipped    OrderStatusTransitionTo = "shipped"
+	OrderStatusTransitionToDelivered  OrderStatusTransitionTo = "delivered"
+	OrderStatusTransitionToCancelled  OrderStatusTransitionTo = "cancelled"
+	OrderStatusTransitionToRefunded   OrderStatusTransitionTo = "refunded"
+)
+
+// OrderItem is generated from #/components/schemas/OrderItem
+type OrderItem struct {
+	// Catalog variant ID
+	ProductID *uuid.UUID `json:"product_id,omitempty"`
+	Name      *string    `json:"name,omitempty"`
+	Quantity  *int       `json:"quantity,omitempty"`
+	UnitPrice *float64   `json:"unit_price,omitempty"`
+	Subtotal  *float64   `json:"subtotal,omitempty"`
+	// Promotion discount on the item
+	Discount   *float64   `json:"discount,omitempty"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	// Sales tax on the subtotal
+	Tax *float64 `json:"tax,omitempty"`
+}
+
+// Address is generated from #/components/schemas/Address
+type Address struct {
+	Street     *string `json:"street,omitempty"`
+	City       *string `json:"city,omitempty"`
+	State      *string `json:"state,omitempty"`
+	PostalCode *string `json:"postal_code,omitempty"`
+	Country    *string `json:"country,omitempty"`
+}
+
+// CreateOrderRequest is generated from #/components/schemas/CreateOrderRequest
+type CreateOrderRequest struct {
+	StoreID         uuid.UUID                `json:"store_id"`
+	Items           []CreateOrderRequestItem `json:"items"`
+	ShippingAddress *Address                 `json:"shipping_address,omitempty"`
+	BillingAddress  *Address                 `json:"billing_address,omitempty"`
+	Notes           *string                  `json:"notes,omitempty"`
+	// Loyalty points to pay part of the order with
+	RedeemPoints *int `json:"redeem_points,omitempty"`
+	// Open register shift the order is rung up on; must be in the order's store and currency
+	ShiftID *uuid.UUID `json:"shift_id,omitempty"`
+	// How the order was paid; required with shift_id
+	Tender *CreateOrderRequestTender `json:"tender,omitempty"`
+	// Coupons unlocking promotions; one that cannot be used fails the
+	// order with 400 and the reason
+	CouponCodes []string `json:"coupon_codes,omitempty"`
+}
+
+// CreateOrderRequestItem is generated from #/components/schemas/CreateOrderRequest/properties/items/items
+type CreateOrderRequestItem struct {
+	// Catalog variant ID; the name and price come from the store's price list
+	ProductID uuid.UUID `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+}
+
+// CreateOrderRequestTender is generated from #/components/schemas/CreateOrderRequest/properties/tender:
+// How the order was paid; required with shift_id
+type CreateOrderRequestTender string
+
+// Values of CreateOrderRequestTender
+const (
+	CreateOrderRequestTenderCash          CreateOrderRequestTender = "cash"
+	CreateOrderRequestTenderCard          CreateOrderRequestTender = "card"
+	CreateOrderRequestTenderDigitalWallet CreateOrderRequestTender = "digital_wallet"
+	CreateOrderRequestTenderBankTransfer  CreateOrderRequestTender = "bank_transfer"
+)
+
+// Store is generated from #/components/schemas/Store
+type Store struct {
+	ID         uuid.UUID `json:"id,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Code       string    `json:"code,omitempty"`
+	Latitude   float64   `json:"latitude,omitempty"`
+	Longitude  float64   `json:"longitude,omitempty"`
+	Address    string    `json:"address,omitempty"`
+	City       string    `json:"city,omitempty"`
+	State      string    `json:"state,omitempty"`
+	PostalCode string    `json:"postal_code,omitempty"`
+	Country    string    `json:"country,omitempty"`
+	Phone      string    `json:"phone,omitempty"`
+	Email      string    `json:"email,omitempty"`
+	// User told of the purchase orders raised automatically when the store runs low on stock
+	ManagerID uuid.UUID   `json:"manager_id,omitempty"`
+	Status    StoreStatus `json:"status,omitempty"`
+	CreatedAt time.Time   `json:"created_at,omitempty"`
+	UpdatedAt time.Time   `json:"updated_at,omitempty"`
+}
+
+// StoreStatus is generated from #/components/schemas/Store/properties/status
+type StoreStatus string
+
+// Values of StoreStatus
+const (
+	StoreStatusActive   StoreStatus = "active"
+	StoreStatusInactive StoreStatus = "inactive"
+)
+
+// CreateStoreRequest is generated from #/components/schemas/CreateStoreRequest
+type CreateStoreRequest struct {
+	Name       string     `json:"name"`
+	Code       string     `json:"code"`
+	Latitude   float64    `json:"latitude"`
+	Longitude  float64    `json:"longitude"`
+	Address    *string    `json:"address,omitempty"`
+	City       *string    `json:"city,omitempty"`
+	State      *string    `json:"state,omitempty"`
+	PostalCode *string    `json:"postal_code,omitempty"`
+	Country    *string    `json:"country,omitempty"`
+	Phone      *string    `json:"phone,omitempty"`
+	Email      *string    `json:"email,omitempty"`
+	ManagerID  *uuid.UUID `json:"manager_id,omitempty"`
+}
+
+// Device is generated from #/components/schemas/Device
+type Device struct {
+	ID           uuid.UUID    `json:"id,omitempty"`
+	StoreID      uuid.UUID    `json:"store_id,omitempty"`
+	Name         string       `json:"name,omitempty"`
+	Type         DeviceType   `json:"type,omitempty"`
+	Status       DeviceStatus `json:"status,omitempty"`
+	SerialNumber string       `json:"serial_number,omitempty"`
+	// When the pairing code expires, while pending
+	PairingExpiresAt time.Time `json:"pairing_expires_at,omitempty"`
+	// Start of the device's API key, to tell keys apart
+	KeyPrefix  string `json:"key_prefix,omitempty"`
+	AppVersion string `json:"app_version,omitempty"`
+	LastIP     string `json:"last_ip,omitempty"`
+	// Time of the last heartbeat
+	LastSeenAt time.Time `json:"last_seen_at,omitempty"`
+	// Whether an active device sent a heartbeat recently
+	Online             bool      `json:"online,omitempty"`
+	PairedAt           time.Time `json:"paired_at,omitempty"`
+	DeactivatedAt      time.Time `json:"deactivated_at,omitempty"`
+	DeactivationReason string    `json:"deactivation_reason,omitempty"`
+	CreatedAt          time.Time `json:"created_at,omitempty"`
+	UpdatedAt          time.Time `json:"updated_at,omitempty"`
+}
+
+// DeviceType is generated from #/components/schemas/Device/properties/type
+type DeviceType string
+
+// Values of DeviceType
+const (
+	DeviceTypeTerminal DeviceType = "terminal"
+	DeviceTypePrinter  DeviceType = "printer"
+	DeviceTypeScanner  DeviceType = "scanner"
+)
+
+// DeviceStatus is generated from #/components/schemas/Device/properties/status
+type DeviceStatus string
+
+// Values of DeviceStatus
+const (
+	DeviceStatusPending     DeviceStatus = "pending"
+	DeviceStatusActive      DeviceStatus = "active"
+	DeviceStatusDeactivated DeviceStatus = "deactivated"
+)
+
+// DevicePairingCode is generated from #/components/schemas/DevicePairingCode
+type DevicePairingCode struct {
+	Device
+	// Entered on the device to pair it; shown only once
+	PairingCode string `json:"pairing_code,omitempty"`
+}
+
+// RegisterDeviceRequest is generated from #/components/schemas/RegisterDeviceRequest
+type RegisterDeviceRequest struct {
+	Name         string                    `json:"name"`
+	Type         RegisterDeviceRequestType `json:"type"`
+	SerialNumber *string                   `json:"serial_number,omitempty"`
+}
+
+// RegisterDeviceRequestType is generated from #/components/schemas/RegisterDeviceRequest/properties/type
+type RegisterDeviceRequestType string
+
+// Values of RegisterDeviceRequestType
+const (
+	RegisterDeviceRequestTypeTerminal RegisterDeviceRequestType = "terminal"
+	RegisterDeviceRequestTypePrinter  RegisterDeviceRequestType = "printer"
+	RegisterDeviceRequestTypeScanner  RegisterDeviceRequestType = "scanner"
+)
+
+// PairDeviceRequest is generated from #/components/schemas/PairDeviceRequest
+type PairDeviceRequest struct {
+	// Case and dashes are ignored
+	PairingCode  string  `json:"pairing_code"`
+	SerialNumber *string `json:"serial_number,omitempty"`
+	AppVersion   *string `json:"app_version,omitempty"`
+}
+
+// DevicePairing is generated from #/components/schemas/DevicePairing
+type DevicePairing struct {
+	Device Device `json:"device,omitempty"`
+	// Sent in X-Device-Key; shown only once
+	APIKey                   string `json:"api_key,omitempty"`
+	HeartbeatIntervalSeconds int    `json:"heartbeat_interval_seconds,omitempty"`
+}
+
+// StoreExport is generated from #/components/schemas/StoreExport
+type StoreExport struct {
+	ID      uuid.UUID         `json:"id,omitempty"`
+	StoreID uuid.UUID         `json:"store_id,omitempty"`
+	Status  StoreExportStatus `json:"status,omitempty"`
+	// Records per dataset, once completed
+	Records map[string]int `json:"records,omitempty"`
+	// Size of the archive in bytes
+	Size int64 `json:"size,omitempty"`
+	// Hex SHA-256 of the archive
+	Checksum string `json:"checksum,omitempty"`
+	// Why the export failed
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	StartedAt   time.Time `json:"started_at,omitempty"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+	// When the export is deleted
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// StoreExportStatus is generated from #/components/schemas/StoreExport/properties/status
+type StoreExportStatus string
+
+// Values of StoreExportStatus
+const (
+	StoreExportStatusPending   StoreExportStatus = "pending"
+	StoreExportStatusRunning   StoreExportStatus = "running"
+	StoreExportStatusCompleted StoreExportStatus = "completed"
+	StoreExportStatusFailed    StoreExportStatus = "failed"
+)
+
+// Payment is generated from #/components/schemas/Payment
+type Payment struct {
+	ID      uuid.UUID `json:"id,omitempty"`
+	OrderID uuid.UUID `json:"order_id,omitempty"`
+	UserID  uuid.UUID `json:"user_id,omitempty"`
+	Amount  float64   `json:"amount,omitempty"`
+	// amount formatted for the language negotiated from Accept-Language
+	FormattedAmount   string                   `json:"formatted_amount,omitempty"`
+	Currency          string                   `json:"currency,omitempty"`
+	Status            PaymentStatus            `json:"status,omitempty"`
+	PaymentMethodType PaymentPaymentMethodType `json:"payment_method_type,omitempty"`
+	// Payment provider that processed the payment
+	Provider              string `json:"provider,omitempty"`
+	ProviderTransactionID string `json:"provider_transaction_id,omitempty"`
+	ThreeDSecureEnabled   bool   `json:"three_d_secure_enabled,omitempty"`
+	// action_required while the customer must authenticate
+	ThreeDSecureStatus string    `json:"three_d_secure_status,omitempty"`
+	CreatedAt          time.Time `json:"created_at,omitempty"`
+	UpdatedAt          time.Time `json:"updated_at,omitempty"`
+	ProcessedAt        time.Time `json:"processed_at,omitempty"`
+	CompletedAt        time.Time `json:"completed_at,omitempty"`
+}
+
+// PaymentStatus is generated from #/components/schemas/Payment/properties/status
+type PaymentStatus string
+
+// Values of PaymentStatus
+const (
+	PaymentStatusPending    PaymentStatus = "pending"
+	PaymentStatusProcessing PaymentStatus = "processing"
+	PaymentStatusCompleted  PaymentStatus = "completed"
+	PaymentStatusFailed     PaymentStatus = "failed"
+	PaymentStatusRefunded   PaymentStatus = "refunded"
+)
+
+// PaymentPaymentMethodType is generated from #/components/schemas/Payment/properties/payment_method_type
+type PaymentPaymentMethodType string
+
+// Values of PaymentPaymentMethodType
+const (
+	PaymentPaymentMethodTypeCard          PaymentPaymentMethodType = "card"
+	PaymentPaymentMethodTypeCash          PaymentPaymentMethodType = "cash"
+	PaymentPaymentMethodTypeDigitalWallet PaymentPaymentMethodType = "digital_wallet"
+)
+
+// ProcessPaymentRequest is generated from #/components/schemas/ProcessPaymentRequest
+type ProcessPaymentRequest struct {
+	OrderID uuid.UUID `json:"order_id"`
+	// Tokenized payment method (PCI-DSS compliant)
+	PaymentMethodToken string  `json:"payment_method_token"`
+	Amount             float64 `json:"amount"`
+	// ISO 4217 code, USD when omitted
+	Currency          *string                                `json:"currency,omitempty"`
+	PaymentMethodType ProcessPaymentRequestPaymentMethodType `json:"payment_method_type"`
+	ThreeDSecure      *bool                                  `json:"three_d_secure,omitempty"`
+}
+
+// ProcessPaymentRequestPaymentMethodType is generated from #/components/schemas/ProcessPaymentRequest/properties/payment_method_type
+type ProcessPaymentRequestPaymentMethodType string
+
+// Values of ProcessPaymentRequestPaymentMethodType
+const (
+	ProcessPaymentRequestPaymentMethodTypeCard          ProcessPaymentRequestPaymentMethodType = "card"
+	ProcessPaymentRequestPaymentMethodTypeCash          ProcessPaymentRequestPaymentMethodType = "cash"
+	ProcessPaymentRequestPaymentMethodTypeDigitalWallet ProcessPaymentRequestPaymentMethodType = "digital_wallet"
+)
+
+// Inventory is generated from #/components/schemas/Inventory
+type Inventory struct {
+	ID               uuid.UUID `json:"id,omitempty"`
+	ProductID        uuid.UUID `json:"product_id,omitempty"`
+	StoreID          uuid.UUID `json:"store_id,omitempty"`
+	Quantity         int       `json:"quantity,omitempty"`
+	ReservedQuantity int       `json:"reserved_quantity,omitempty"`
+	// Quantity less reserved_quantity
+	AvailableQuantity int `json:"available_quantity,omitempty"`
+	// Available quantity at or below which stock is low
+	ReorderPoint    int     `json:"reorder_point,omitempty"`
+	ReorderQuantity int     `json:"reorder_quantity,omitempty"`
+	CostPrice       float64 `json:"cost_price,omitempty"`
+	SellingPrice    float64 `json:"selling_price,omitempty"`
+	// Optimistic locking version
+	Version   int       `json:"version,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// Category is generated from #/components/schemas/Category
+type Category struct {
+	ID        uuid.UUID `json:"id,omitempty"`
+	ParentID  uuid.UUID `json:"parent_id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Slug      string    `json:"slug,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// Product is generated from #/components/schemas/Product
+type Product struct {
+	ID          uuid.UUID     `json:"id,omitempty"`
+	CategoryID  uuid.UUID     `json:"category_id,omitempty"`
+	Name        string        `json:"name,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Brand       string        `json:"brand,omitempty"`
+	Status      ProductStatus `json:"status,omitempty"`
+	Images      []Image       `json:"images,omitempty"`
+	Variants    []Variant     `json:"variants,omitempty"`
+	CreatedAt   time.Time     `json:"created_at,omitempty"`
+	UpdatedAt   time.Time     `json:"updated_at,omitempty"`
+}
+
+// ProductStatus is generated from #/components/schemas/Product/properties/status
+type ProductStatus string
+
+// Values of ProductStatus
+const (
+	ProductStatusDraft    ProductStatus = "draft"
+	ProductStatusActive   ProductStatus = "active"
+	ProductStatusArchived ProductStatus = "archived"
+)
+
+// Image is generated from #/components/schemas/Image
+type Image struct {
+	URL      *string `json:"url,omitempty"`
+	AltText  *string `json:"alt_text,omitempty"`
+	Position *int    `json:"position,omitempty"`
+}
+
+// Variant is generated from #/components/schemas/Variant
+type Variant struct {
+	ID         uuid.UUID         `json:"id,omitempty"`
+	ProductID  uuid.UUID         `json:"product_id,omitempty"`
+	SKU        string            `json:"sku,omitempty"`
+	Barcode    string            `json:"barcode,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	BasePrice  float64           `json:"base_price,omitempty"`
+	Currency   string            `json:"currency,omitempty"`
+	CreatedAt  time.Time         `json:"created_at,omitempty"`
+	UpdatedAt  time.Time         `json:"updated_at,omitempty"`
+}
+
+// CreateProductRequest is generated from #/components/schemas/CreateProductRequest
+type CreateProductRequest struct {
+	CategoryID  *uuid.UUID                    `json:"category_id,omitempty"`
+	Name        string                        `json:"name"`
+	Description *string                       `json:"description,omitempty"`
+	Brand       *string                       `json:"brand,omitempty"`
+	Status      *CreateProductRequestStatus   `json:"status,omitempty"`
+	Images      []Image                       `json:"images,omitempty"`
+	Variants    []CreateProductRequestVariant `json:"variants"`
+}
+
+// CreateProductRequestStatus is generated from #/components/schemas/CreateProductRequest/properties/status
+type CreateProductRequestStatus string
+
+// Values of CreateProductRequestStatus
+const (
+	CreateProductRequestStatusDraft    CreateProductRequestStatus = "draft"
+	CreateProductRequestStatusActive   CreateProductRequestStatus = "active"
+	CreateProductRequestStatusArchived CreateProductRequestStatus = "archived"
+)
+
+// CreateProductRequestVariant is generated from #/components/schemas/CreateProductRequest/properties/variants/items
+type CreateProductRequestVariant struct {
+	SKU        string            `json:"sku"`
+	Barcode    *string           `json:"barcode,omitempty"`
+	Name       *string           `json:"name,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	BasePrice  float64           `json:"base_price"`
+	Currency   *string           `json:"currency,omitempty"`
+}
+
+// Price is generated from #/components/schemas/Price
+type Price struct {
+	StoreID   uuid.UUID `json:"store_id,omitempty"`
+	VariantID uuid.UUID `json:"variant_id,omitempty"`
+	Price     float64   `json:"price,omitempty"`
+	Currency  string    `json:"currency,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// LoyaltyProgram is generated from #/components/schemas/LoyaltyProgram
+type LoyaltyProgram struct {
+	// Points earned per currency unit of a completed order
+	PointsPerUnit float64 `json:"points_per_unit,omitempty"`
+	// Discount one redeemed point gives
+	PointValue    float64 `json:"point_value,omitempty"`
+	MinRedemption int     `json:"min_redemption,omitempty"`
+	// Largest share of an order payable with points
+	MaxRedemptionShare float64 `json:"max_redemption_share,omitempty"`
+	// Omitted when points never expire
+	PointsLifetimeDays int                  `json:"points_lifetime_days,omitempty"`
+	Tiers              []LoyaltyProgramTier `json:"tiers,omitempty"`
+}
+
+// LoyaltyProgramTier is generated from #/components/schemas/LoyaltyProgram/properties/tiers/items
+type LoyaltyProgramTier struct {
+	Name string `json:"name,omitempty"`
+	// Lifetime points that reach the tier
+	MinPoints  int     `json:"min_points,omitempty"`
+	Multiplier float64 `json:"multiplier,omitempty"`
+}
+
+// LoyaltyAccount is generated from #/components/schemas/LoyaltyAccount
+type LoyaltyAccount struct {
+	UserID           uuid.UUID `json:"user_id,omitempty"`
+	Balance          int       `json:"balance,omitempty"`
+	LifetimePoints   int       `json:"lifetime_points,omitempty"`
+	Tier             string    `json:"tier,omitempty"`
+	NextTier         string    `json:"next_tier,omitempty"`
+	PointsToNextTier int       `json:"points_to_next_tier,omitempty"`
+	// Points expiring within the program's notice period
+	ExpiringPoints int       `json:"expiring_points,omitempty"`
+	NextExpiryAt   time.Time `json:"next_expiry_at,omitempty"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at,omitempty"`
+}
+
+// LoyaltyTransaction is generated from #/components/schemas/LoyaltyTransaction
+type LoyaltyTransaction struct {
+	ID     uuid.UUID              `json:"id,omitempty"`
+	Type   LoyaltyTransactionType `json:"type,omitempty"`
+	UserID uuid.UUID              `json:"user_id,omitempty"`
+	// Positive for credits, negative for debits
+	Points      int       `json:"points,omitempty"`
+	OrderID     uuid.UUID `json:"order_id,omitempty"`
+	Amount      float64   `json:"amount,omitempty"`
+	Currency    string    `json:"currency,omitempty"`
+	Description string    `json:"description,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+}
+
+// LoyaltyTransactionType is generated from #/components/schemas/LoyaltyTransaction/properties/type
+type LoyaltyTransactionType string
+
+// Values of LoyaltyTransactionType
+const (
+	LoyaltyTransactionTypeEarn    LoyaltyTransactionType = "earn"
+	LoyaltyTransactionTypeRedeem  LoyaltyTransactionType = "redeem"
+	LoyaltyTransactionTypeRelease LoyaltyTransactionType = "release"
+	LoyaltyTransactionTypeReverse LoyaltyTransactionType = "reverse"
+	LoyaltyTransactionTypeExpire  LoyaltyTransactionType = "expire"
+)
+
+// PromotionRule is generated from #/components/schemas/PromotionRule:
+// Which fields apply depends on the promotion type
+type PromotionRule struct {
+	// Catalog variant IDs in scope; with category_ids empty, every item is
+	ProductIDs  []string    `json:"product_ids,omitempty"`
+	CategoryIDs []uuid.UUID `json:"category_ids,omitempty"`
+	// Percent off the discounted units (bogo; 100 makes them free), the
+	// items in scope (category_percent, happy_hour, percent_off) or the
+	// basket (basket_threshold)
+	Percent *float64 `json:"percent,omitempty"`
+	// bogo units bought before get_quantity units are discounted
+	BuyQuantity *int `json:"buy_quantity,omitempty"`
+	GetQuantity *int `json:"get_quantity,omitempty"`
+	// basket_threshold (and optionally amount_off) subtotal in scope to reach
+	MinSubtotal *float64 `json:"min_subtotal,omitempty"`
+	// amount_off amount, or basket_threshold amount off when no percent is given
+	Amount *float64 `json:"amount,omitempty"`
+	// happy_hour window start, HH:MM in timezone
+	StartTime *string `json:"start_time,omitempty"`
+	// happy_hour window end; before start_time runs past midnight
+	EndTime *string `json:"end_time,omitempty"`
+	// happy_hour days, 0 (Sunday) to 6; empty is every day
+	Days     []int   `json:"days,omitempty"`
+	Timezone *string `json:"timezone,omitempty"`
+}
+
+// PromotionRequest is generated from #/components/schemas/PromotionRequest
+type PromotionRequest struct {
+	Name        string               `json:"name"`
+	Description *string              `json:"description,omitempty"`
+	Type        PromotionRequestType `json:"type"`
+	Rule        *PromotionRule       `json:"rule,omitempty"`
+	Priority    *int                 `json:"priority,omitempty"`
+	Exclusive   *bool                `json:"exclusive,omitempty"`
+	Active      *bool                `json:"active,omitempty"`
+	// Applies only to orders presenting one of its coupons
+	CouponOnly *bool `json:"coupon_only,omitempty"`
+	// Stores the campaign runs in; empty is every store
+	StoreIDs []uuid.UUID `json:"store_ids,omitempty"`
+	// Currency of the rule's amounts; required by basket_threshold and amount_off
+	Currency *string    `json:"currency,omitempty"`
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+// PromotionRequestType is generated from #/components/schemas/PromotionRequest/properties/type
+type PromotionRequestType string
+
+// Values of PromotionRequestType
+const (
+	PromotionRequestTypeBogo            PromotionRequestType = "bogo"
+	PromotionRequestTypeBasketThreshold PromotionRequestType = "basket_threshold"
+	PromotionRequestTypeCategoryPercent PromotionRequestType = "category_percent"
+	PromotionRequestTypeHappyHour       PromotionRequestType = "happy_hour"
+	PromotionRequestTypePercentOff      PromotionRequestType = "percent_off"
+	PromotionRequestTypeAmountOff       PromotionRequestType = "amount_off"
+)
+
+// Promotion is generated from #/components/schemas/Promotion
+type Promotion struct {
+	PromotionRequest
+	ID        uuid.UUID `json:"id,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// CouponRequest is generated from #/components/schemas/CouponRequest
+type CouponRequest struct {
+	// Stored in upper case and matched regardless of case
+	Code string `json:"code"`
+	// Orders that can use the coupon; 0 is unlimited
+	MaxUses *int `json:"max_uses,omitempty"`
+	// Orders each customer can use it on; 0 is unlimited
+	MaxUsesPerUser *int       `json:"max_uses_per_user,omitempty"`
+	Active         *bool      `json:"active,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+// Coupon is generated from #/components/schemas/Coupon
+type Coupon struct {
+	CouponRequest
+	ID          uuid.UUID `json:"id,omitempty"`
+	PromotionID uuid.UUID `json:"promotion_id,omitempty"`
+	// Orders using the coupon, leaving out cancelled and refunded ones
+	Uses      int       `json:"uses,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// CouponValidation is generated from #/components/schemas/CouponValidation
+type CouponValidation struct {
+	Valid bool `json:"valid,omitempty"`
+	// Why the coupon cannot be used
+	Reason    string    `json:"reason,omitempty"`
+	Coupon    Coupon    `json:"coupon,omitempty"`
+	Promotion Promotion `json:"promotion,omitempty"`
+	// What the coupon and the promotions running take off the lines given
+	Discount CouponValidationDiscount `json:"discount,omitempty"`
+}
+
+// CouponValidationDiscount is generated from #/components/schemas/CouponValidation/properties/discount:
+// What the coupon and the promotions running take off the lines given
+type CouponValidationDiscount struct {
+	LineDiscounts []float64                             `json:"line_discounts,omitempty"`
+	Applied       []CouponValidationDiscountAppliedItem `json:"applied,omitempty"`
+	Total         float64                               `json:"total,omitempty"`
+}
+
+// CouponValidationDiscountAppliedItem is generated from #/components/schemas/CouponValidation/properties/discount/properties/applied/items
+type CouponValidationDiscountAppliedItem struct {
+	PromotionID uuid.UUID `json:"promotion_id,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	Type        string    `json:"type,omitempty"`
+	Amount      float64   `json:"amount,omitempty"`
+	Coupon      string    `json:"coupon,omitempty"`
+}
+
+// SalesPoint is generated from #/components/schemas/SalesPoint
+type SalesPoint struct {
+	Bucket          time.Time `json:"bucket,omitempty"`
+	Currency        string    `json:"currency,omitempty"`
+	OrdersPlaced    int       `json:"orders_placed,omitempty"`
+	OrdersCompleted int       `json:"orders_completed,omitempty"`
+	OrdersCancelled int       `json:"orders_cancelled,omitempty"`
+	OrdersRefunded  int       `json:"orders_refunded,omitempty"`
+	// Value of orders placed
+	GrossSales float64 `json:"gross_sales,omitempty"`
+	// Value of orders completed
+	Revenue float64 `json:"revenue,omitempty"`
+	Refunds float64 `json:"refunds,omitempty"`
+	// Revenue less refunds
+	NetRevenue float64 `json:"net_revenue,omitempty"`
+}
+
+// ProductSales is generated from #/components/schemas/ProductSales
+type ProductSales struct {
+	// Catalog variant ID
+	ProductID string `json:"product_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Currency  string `json:"currency,omitempty"`
+	UnitsSold int    `json:"units_sold,omitempty"`
+	// After item discounts, before order-level ones
+	Revenue       float64 `json:"revenue,omitempty"`
+	UnitsRefunded int     `json:"units_refunded,omitempty"`
+	Refunds       float64 `json:"refunds,omitempty"`
+}
+
+// Conversion is generated from #/components/schemas/Conversion
+type Conversion struct {
+	OrdersPlaced       int     `json:"orders_placed,omitempty"`
+	OrdersCompleted    int     `json:"orders_completed,omitempty"`
+	OrdersCancelled    int     `json:"orders_cancelled,omitempty"`
+	CompletionRate     float64 `json:"completion_rate,omitempty"`
+	CancellationRate   float64 `json:"cancellation_rate,omitempty"`
+	PaymentsStarted    int     `json:"payments_started,omitempty"`
+	PaymentsCompleted  int     `json:"payments_completed,omitempty"`
+	PaymentSuccessRate float64 `json:"payment_success_rate,omitempty"`
+}
+
+// StockActivity is generated from #/components/schemas/StockActivity
+type StockActivity struct {
+	ProductID      uuid.UUID `json:"product_id,omitempty"`
+	StoreID        uuid.UUID `json:"store_id,omitempty"`
+	UnitsReserved  int       `json:"units_reserved,omitempty"`
+	UnitsReleased  int       `json:"units_released,omitempty"`
+	LowStockEvents int       `json:"low_stock_events,omitempty"`
+}
+
+// Timesheet is generated from #/components/schemas/Timesheet
+type Timesheet struct {
+	StaffID          uuid.UUID      `json:"staff_id,omitempty"`
+	WeekStart        string         `json:"week_start,omitempty"`
+	Entries          int            `json:"entries,omitempty"`
+	WorkedHours      float64        `json:"worked_hours,omitempty"`
+	RegularHours     float64        `json:"regular_hours,omitempty"`
+	OvertimeHours    float64        `json:"overtime_hours,omitempty"`
+	UnpaidBreakHours float64        `json:"unpaid_break_hours,omitempty"`
+	Days             []TimesheetDay `json:"days,omitempty"`
+}
+
+// TimesheetDay is generated from #/components/schemas/TimesheetDay
+type TimesheetDay struct {
+	Date             string  `json:"date,omitempty"`
+	Entries          int     `json:"entries,omitempty"`
+	WorkedHours      float64 `json:"worked_hours,omitempty"`
+	UnpaidBreakHours float64 `json:"unpaid_break_hours,omitempty"`
+}
+
+// OrderSearchHit is generated from #/components/schemas/OrderSearchHit
+type OrderSearchHit struct {
+	ID            uuid.UUID `json:"id,omitempty"`
+	TenantID      string    `json:"tenant_id,omitempty"`
+	StoreID       uuid.UUID `json:"store_id,omitempty"`
+	UserID        uuid.UUID `json:"user_id,omitempty"`
+	CustomerName  string    `json:"customer_name,omitempty"`
+	CustomerEmail string    `json:"customer_email,omitempty"`
+	Status        string    `json:"status,omitempty"`
+	TotalAmount   float64   `json:"total_amount,omitempty"`
+	Currency      string    `json:"currency,omitempty"`
+	ItemNames     []string  `json:"item_names,omitempty"`
+	// Catalog variant IDs
+	ProductIDs []string  `json:"product_ids,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+	// Time of the last event indexed
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// ProductSearchHit is generated from #/components/schemas/ProductSearchHit
+type ProductSearchHit struct {
+	ID           uuid.UUID `json:"id,omitempty"`
+	TenantID     string    `json:"tenant_id,omitempty"`
+	CategoryID   uuid.UUID `json:"category_id,omitempty"`
+	Name         string    `json:"name,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	Brand        string    `json:"brand,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	Skus         []string  `json:"skus,omitempty"`
+	Barcodes     []string  `json:"barcodes,omitempty"`
+	VariantNames []string  `json:"variant_names,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at,omitempty"`
+}
+
+// SearchBucket is generated from #/components/schemas/SearchBucket
+type SearchBucket struct {
+	// Facet value; dates are RFC 3339
+	Key   string `json:"key,omitempty"`
+	Count int    `json:"count,omitempty"`
+}
+
+// SyncChange is generated from #/components/schemas/SyncChange
+type SyncChange struct {
+	Seq    int64            `json:"seq,omitempty"`
+	Entity SyncChangeEntity `json:"entity,omitempty"`
+	// Product ID, or the variant ID of a price or stock change
+	EntityID uuid.UUID `json:"entity_id,omitempty"`
+	// Absent for products, which every store shares
+	StoreID uuid.UUID    `json:"store_id,omitempty"`
+	Op      SyncChangeOp `json:"op,omitempty"`
+	// The entity's state; a product with its variants and base prices, a price with its currency, or the available stock. Absent for tombstones; a deleted price reverts to the base price.
+	Data      map[string]any `json:"data,omitempty"`
+	ChangedAt time.Time      `json:"changed_at,omitempty"`
+}
+
+// SyncChangeEntity is generated from #/components/schemas/SyncChange/properties/entity
+type SyncChangeEntity string
+
+// Values of SyncChangeEntity
+const (
+	SyncChangeEntityProduct SyncChangeEntity = "product"
+	SyncChangeEntityPrice   SyncChangeEntity = "price"
+	SyncChangeEntityStock   SyncChangeEntity = "stock"
+)
+
+// SyncChangeOp is generated from #/components/schemas/SyncChange/properties/op
+type SyncChangeOp string
+
+// Values of SyncChangeOp
+const (
+	SyncChangeOpUpsert SyncChangeOp = "upsert"
+	SyncChangeOpDelete SyncChangeOp = "delete"
+)
+
+// SyncChangePage is generated from #/components/schemas/SyncChangePage
+type SyncChangePage struct {
+	Changes []SyncChange `json:"changes,omitempty"`
+	// Pass as since on the next pull
+	Cursor  int64 `json:"cursor,omitempty"`
+	HasMore bool  `json:"has_more,omitempty"`
+}
+
+// SyncPushRequest is generated from #/components/schemas/SyncPushRequest
+type SyncPushRequest struct {
+	TerminalID string `json:"terminal_id"`
+	// For transactions that name none. client_wins keeps the prices charged and items no longer sold; server_wins reprices from the catalog and rejects items no longer sold; reject rejects any difference. Defaults to the service's configured policy.
+	ConflictPolicy *SyncPushRequestConflictPolicy `json:"conflict_policy,omitempty"`
+	Transactions   []OfflineTransaction           `json:"transactions"`
+}
+
+// SyncPushRequestConflictPolicy is generated from #/components/schemas/SyncPushRequest/properties/conflict_policy:
+// For transactions that name none. client_wins keeps the prices charged and items no longer sold; server_wins reprices from the catalog and rejects items no longer sold; reject rejects any difference. Defaults to the service's configured policy.
+type SyncPushRequestConflictPolicy string
+
+// Values of SyncPushRequestConflictPolicy
+const (
+	SyncPushRequestConflictPolicyClientWins SyncPushRequestConflictPolicy = "client_wins"
+	SyncPushRequestConflictPolicyServerWins SyncPushRequestConflictPolicy = "server_wins"
+	SyncPushRequestConflictPolicyReject     SyncPushRequestConflictPolicy = "reject"
+)
+
+// OfflineTransaction is generated from #/components/schemas/OfflineTransaction
+type OfflineTransaction struct {
+	// Generated by the terminal; also the ID of the order it creates
+	ID uuid.UUID `json:"id"`
+	// With the names and unit prices charged
+	Items    []OrderItem `json:"items"`
+	Currency *string     `json:"currency,omitempty"`
+	ShiftID  *uuid.UUID  `json:"shift_id,omitempty"`
+	// Required with shift_id
+	Tender *OfflineTransactionTender `json:"tender,omitempty"`
+	Notes  *string                   `json:"notes,omitempty"`
+	// Overrides the request's policy
+	ConflictPolicy *OfflineTransactionConflictPolicy `json:"conflict_policy,omitempty"`
+	// When it was rung up
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OfflineTransactionTender is generated from #/components/schemas/OfflineTransaction/properties/tender:
+// Required with shift_id
+type OfflineTransactionTender string
+
+// Values of OfflineTransactionTender
+const (
+	OfflineTransactionTenderCash          OfflineTransactionTender = "cash"
+	OfflineTransactionTenderCard          OfflineTransactionTender = "card"
+	OfflineTransactionTenderDigitalWallet OfflineTransactionTender = "digital_wallet"
+	OfflineTransactionTenderBankTransfer  OfflineTransactionTender = "bank_transfer"
+)
+
+// OfflineTransactionConflictPolicy is generated from #/components/schemas/OfflineTransaction/properties/conflict_policy:
+// Overrides the request's policy
+type OfflineTransactionConflictPolicy string
+
+// Values of OfflineTransactionConflictPolicy
+const (
+	OfflineTransactionConflictPolicyClientWins OfflineTransactionConflictPolicy = "client_wins"
+	OfflineTransactionConflictPolicyServerWins OfflineTransactionConflictPolicy = "server_wins"
+	OfflineTransactionConflictPolicyReject     OfflineTransactionConflictPolicy = "reject"
+)
+
+// SyncTransactionResult is generated from #/components/schemas/SyncTransactionResult
+type SyncTransactionResult struct {
+	ID          uuid.UUID                   `json:"id,omitempty"`
+	Status      SyncTransactionResultStatus `json:"status,omitempty"`
+	TotalAmount float64                     `json:"total_amount,omitempty"`
+	Conflicts   []SyncConflict              `json:"conflicts,omitempty"`
+	Error       string                      `json:"error,omitempty"`
+}
+
+// SyncTransactionResultStatus is generated from #/components/schemas/SyncTransactionResult/properties/status
+type SyncTransactionResultStatus string
+
+// Values of SyncTransactionResultStatus
+const (
+	SyncTransactionResultStatusAccepted  SyncTransactionResultStatus = "accepted"
+	SyncTransactionResultStatusDuplicate SyncTransactionResultStatus = "duplicate"
+	SyncTransactionResultStatusRejected  SyncTransactionResultStatus = "rejected"
+	SyncTransactionResultStatusFailed    SyncTransactionResultStatus = "failed"
+)
+
+// SyncConflict is generated from #/components/schemas/SyncConflict
+type SyncConflict struct {
+	ProductID   uuid.UUID              `json:"product_id,omitempty"`
+	Kind        SyncConflictKind       `json:"kind,omitempty"`
+	ClientPrice float64                `json:"client_price,omitempty"`
+	ServerPrice float64                `json:"server_price,omitempty"`
+	Resolution  SyncConflictResolution `json:"resolution,omitempty"`
+}
+
+// SyncConflictKind is generated from #/components/schemas/SyncConflict/properties/kind
+type SyncConflictKind string
+
+// Values of SyncConflictKind
+const (
+	SyncConflictKindPriceChanged SyncConflictKind = "price_changed"
+	SyncConflictKindUnavailable  SyncConflictKind = "unavailable"
+)
+
+// SyncConflictResolution is generated from #/components/schemas/SyncConflict/properties/resolution
+type SyncConflictResolution string
+
+// Values of SyncConflictResolution
+const (
+	SyncConflictResolutionKeptClient SyncConflictResolution = "kept_client"
+	SyncConflictResolutionUsedServer SyncConflictResolution = "used_server"
+	SyncConflictResolutionRejected   SyncConflictResolution = "rejected"
+)
+
+// ReceiptTemplate is generated from #/components/schemas/ReceiptTemplate
+type ReceiptTemplate struct {
+	StoreID uuid.UUID `json:"store_id,omitempty"`
+	Header  []string  `json:"header,omitempty"`
+	Footer  []string  `json:"footer,omitempty"`
+	// Characters per line; 32 for 58mm paper, 42 or 48 for 80mm
+	Width       int       `json:"width,omitempty"`
+	ShowBarcode bool      `json:"show_barcode,omitempty"`
+	OpenDrawer  bool      `json:"open_drawer,omitempty"`
+	DrawerPin   int       `json:"drawer_pin,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdateReceiptTemplateRequest is generated from #/components/schemas/UpdateReceiptTemplateRequest
+type UpdateReceiptTemplateRequest struct {
+	Header      []string `json:"header,omitempty"`
+	Footer      []string `json:"footer,omitempty"`
+	Width       *int     `json:"width,omitempty"`
+	ShowBarcode *bool    `json:"show_barcode,omitempty"`
+	OpenDrawer  *bool    `json:"open_drawer,omitempty"`
+	DrawerPin   *int     `json:"drawer_pin,omitempty"`
+}
+
+// PrintReceiptRequest is generated from #/components/schemas/PrintReceiptRequest
+type PrintReceiptRequest struct {
+	OrderID uuid.UUID `json:"order_id"`
+	// Empty prints on any of the store's printers
+	PrinterID *string `json:"printer_id,omitempty"`
+	Copies    *int    `json:"copies,omitempty"`
+	// Overrides the store's template; only the first copy opens the drawer
+	OpenDrawer *bool `json:"open_drawer,omitempty"`
+}
+
+// PrintJob is generated from #/components/schemas/PrintJob
+type PrintJob struct {
+	ID        uuid.UUID      `json:"id,omitempty"`
+	StoreID   uuid.UUID      `json:"store_id,omitempty"`
+	PrinterID string         `json:"printer_id,omitempty"`
+	OrderID   uuid.UUID      `json:"order_id,omitempty"`
+	Kind      PrintJobKind   `json:"kind,omitempty"`
+	Status    PrintJobStatus `json:"status,omitempty"`
+	// ESC/POS bytes, only returned to print agents
+	Payload     []byte    `json:"payload,omitempty"`
+	Attempts    int       `json:"attempts,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	ClaimedAt   time.Time `json:"claimed_at,omitempty"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+}
+
+// PrintJobKind is generated from #/components/schemas/PrintJob/properties/kind
+type PrintJobKind string
+
+// Values of PrintJobKind
+const (
+	PrintJobKindReceipt PrintJobKind = "receipt"
+	PrintJobKindDrawer  PrintJobKind = "drawer"
+)
+
+// PrintJobStatus is generated from #/components/schemas/PrintJob/properties/status
+type PrintJobStatus string
+
+// Values of PrintJobStatus
+const (
+	PrintJobStatusQueued   PrintJobStatus = "queued"
+	PrintJobStatusPrinting PrintJobStatus = "printing"
+	PrintJobStatusPrinted  PrintJobStatus = "printed"
+	PrintJobStatusFailed   PrintJobStatus = "failed"
+)
+
+// OpenShiftRequest is generated from #/components/schemas/OpenShiftRequest
+type OpenShiftRequest struct {
+	StoreID    uuid.UUID `json:"store_id"`
+	RegisterID string    `json:"register_id"`
+	// Defaults to USD
+	Currency     *string  `json:"currency,omitempty"`
+	OpeningFloat *float64 `json:"opening_float,omitempty"`
+	Notes        *string  `json:"notes,omitempty"`
+}
+
+// Shift is generated from #/components/schemas/Shift
+type Shift struct {
+	ID           uuid.UUID   `json:"id,omitempty"`
+	StoreID      uuid.UUID   `json:"store_id,omitempty"`
+	RegisterID   string      `json:"register_id,omitempty"`
+	StaffID      uuid.UUID   `json:"staff_id,omitempty"`
+	Status       ShiftStatus `json:"status,omitempty"`
+	Currency     string      `json:"currency,omitempty"`
+	OpeningFloat float64     `json:"opening_float,omitempty"`
+	CountedCash  float64     `json:"counted_cash,omitempty"`
+	Notes        string      `json:"notes,omitempty"`
+	OpenedAt     time.Time   `json:"opened_at,omitempty"`
+	ClosedAt     time.Time   `json:"closed_at,omitempty"`
+	ClosedBy     uuid.UUID   `json:"closed_by,omitempty"`
+}
+
+// ShiftStatus is generated from #/components/schemas/Shift/properties/status
+type ShiftStatus string
+
+// Values of ShiftStatus
+const (
+	ShiftStatusOpen   ShiftStatus = "open"
+	ShiftStatusClosed ShiftStatus = "closed"
+)
+
+// CashMovement is generated from #/components/schemas/CashMovement
+type CashMovement struct {
+	ID        uuid.UUID        `json:"id,omitempty"`
+	ShiftID   uuid.UUID        `json:"shift_id,omitempty"`
+	Type      CashMovementType `json:"type,omitempty"`
+	Amount    float64          `json:"amount,omitempty"`
+	Reason    string           `json:"reason,omitempty"`
+	StaffID   uuid.UUID        `json:"staff_id,omitempty"`
+	CreatedAt time.Time        `json:"created_at,omitempty"`
+}
+
+// CashMovementType is generated from #/components/schemas/CashMovement/properties/type
+type CashMovementType string
+
+// Values of CashMovementType
+const (
+	CashMovementTypePayIn  CashMovementType = "pay_in"
+	CashMovementTypePayOut CashMovementType = "pay_out"
+	CashMovementTypeDrop   CashMovementType = "drop"
+)
+
+// TimeEntry is generated from #/components/schemas/TimeEntry
+type TimeEntry struct {
+	ID        uuid.UUID       `json:"id,omitempty"`
+	StoreID   uuid.UUID       `json:"store_id,omitempty"`
+	StaffID   uuid.UUID       `json:"staff_id,omitempty"`
+	ShiftID   uuid.UUID       `json:"shift_id,omitempty"`
+	Status    TimeEntryStatus `json:"status,omitempty"`
+	ClockIn   time.Time       `json:"clock_in,omitempty"`
+	ClockOut  time.Time       `json:"clock_out,omitempty"`
+	Breaks    []TimeBreak     `json:"breaks,omitempty"`
+	Notes     string          `json:"notes,omitempty"`
+	Version   int             `json:"version,omitempty"`
+	UpdatedAt time.Time       `json:"updated_at,omitempty"`
+}
+
+// TimeEntryStatus is generated from #/components/schemas/TimeEntry/properties/status
+type TimeEntryStatus string
+
+// Values of TimeEntryStatus
+const (
+	TimeEntryStatusOpen   TimeEntryStatus = "open"
+	TimeEntryStatusClosed TimeEntryStatus = "closed"
+)
+
+// TimeBreak is generated from #/components/schemas/TimeBreak
+type TimeBreak struct {
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Paid      *bool      `json:"paid,omitempty"`
+}
+
+// TimeAdjustment is generated from #/components/schemas/TimeAdjustment
+type TimeAdjustment struct {
+	ID         uuid.UUID `json:"id,omitempty"`
+	EntryID    uuid.UUID `json:"entry_id,omitempty"`
+	AdjustedBy uuid.UUID `json:"adjusted_by,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Before     TimeEntry `json:"before,omitempty"`
+	After      TimeEntry `json:"after,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+}
+
+// ShiftDayReport is generated from #/components/schemas/ShiftDayReport
+type ShiftDayReport struct {
+	Date     string                  `json:"date,omitempty"`
+	StoreID  uuid.UUID               `json:"store_id,omitempty"`
+	Totals   []ShiftDayReportTotal   `json:"totals,omitempty"`
+	Cashiers []ShiftDayReportCashier `json:"cashiers,omitempty"`
+	Reports  []ShiftReport           `json:"reports,omitempty"`
+}
+
+// ShiftDayReportTotal is generated from #/components/schemas/ShiftDayReport/properties/totals/items
+type ShiftDayReportTotal struct {
+	Currency string  `json:"currency,omitempty"`
+	Shifts   int     `json:"shifts,omitempty"`
+	NetSales float64 `json:"net_sales,omitempty"`
+	// Cash expected in the drawers at close
+	Expected float64 `json:"expected,omitempty"`
+	Counted  float64 `json:"counted,omitempty"`
+	// Counted less expected
+	OverShort float64 `json:"over_short,omitempty"`
+}
+
+// ShiftDayReportCashier is generated from #/components/schemas/ShiftDayReport/properties/cashiers/items
+type ShiftDayReportCashier struct {
+	StaffID   uuid.UUID `json:"staff_id,omitempty"`
+	Currency  string    `json:"currency,omitempty"`
+	Shifts    int       `json:"shifts,omitempty"`
+	Expected  float64   `json:"expected,omitempty"`
+	Counted   float64   `json:"counted,omitempty"`
+	OverShort float64   `json:"over_short,omitempty"`
+}
+
+// ShiftReport is generated from #/components/schemas/ShiftReport
+type ShiftReport struct {
+	Kind        ShiftReportKind  `json:"kind,omitempty"`
+	ShiftID     uuid.UUID        `json:"shift_id,omitempty"`
+	StoreID     uuid.UUID        `json:"store_id,omitempty"`
+	RegisterID  string           `json:"register_id,omitempty"`
+	StaffID     uuid.UUID        `json:"staff_id,omitempty"`
+	Currency    string           `json:"currency,omitempty"`
+	OpenedAt    time.Time        `json:"opened_at,omitempty"`
+	ClosedAt    time.Time        `json:"closed_at,omitempty"`
+	GeneratedAt time.Time        `json:"generated_at,omitempty"`
+	Sales       ShiftReportSales `json:"sales,omitempty"`
+	// Net sales by tender
+	Tenders []ShiftReportTender `json:"tenders,omitempty"`
+	// Tax charged on the sales kept
+	Tax       ShiftReportTax        `json:"tax,omitempty"`
+	Cash      ShiftReportCash       `json:"cash,omitempty"`
+	Movements []ShiftReportMovement `json:"movements,omitempty"`
+}
+
+// ShiftReportKind is generated from #/components/schemas/ShiftReport/properties/kind
+type ShiftReportKind string
+
+// Values of ShiftReportKind
+const (
+	ShiftReportKindX ShiftReportKind = "X"
+	ShiftReportKindZ ShiftReportKind = "Z"
+)
+
+// ShiftReportSales is generated from #/components/schemas/ShiftReport/properties/sales
+type ShiftReportSales struct {
+	Orders        int     `json:"orders,omitempty"`
+	Gross         float64 `json:"gross,omitempty"`
+	Voids         int     `json:"voids,omitempty"`
+	VoidAmount    float64 `json:"void_amount,omitempty"`
+	Refunds       int     `json:"refunds,omitempty"`
+	RefundAmount  float64 `json:"refund_amount,omitempty"`
+	Net           float64 `json:"net,omitempty"`
+	AverageTicket float64 `json:"average_ticket,omitempty"`
+}
+
+// ShiftReportTender is generated from #/components/schemas/ShiftReport/properties/tenders/items
+type ShiftReportTender struct {
+	Tender string  `json:"tender,omitempty"`
+	Orders int     `json:"orders,omitempty"`
+	Amount float64 `json:"amount,omitempty"`
+}
+
+// ShiftReportTax is generated from #/components/schemas/ShiftReport/properties/tax:
+// Tax charged on the sales kept
+type ShiftReportTax struct {
+	Taxable float64 `json:"taxable,omitempty"`
+	Amount  float64 `json:"amount,omitempty"`
+}
+
+// ShiftReportCash is generated from #/components/schemas/ShiftReport/properties/cash
+type ShiftReportCash struct {
+	OpeningFloat float64 `json:"opening_float,omitempty"`
+	Sales        float64 `json:"sales,omitempty"`
+	PayIns       float64 `json:"pay_ins,omitempty"`
+	PayOuts      float64 `json:"pay_outs,omitempty"`
+	Drops        float64 `json:"drops,omitempty"`
+	Expected     float64 `json:"expected,omitempty"`
+	Counted      float64 `json:"counted,omitempty"`
+	// Counted less expected cash; negative when short. Only on Z reports.
+	OverShort float64 `json:"over_short,omitempty"`
+}
+
+// ShiftReportMovement is generated from #/components/schemas/ShiftReport/properties/movements/items
+type ShiftReportMovement struct {
+	Type   string  `json:"type,omitempty"`
+	Count  int     `json:"count,omitempty"`
+	Amount float64 `json:"amount,omitempty"`
+}
+
+// TaxJurisdictionRequest is generated from #/components/schemas/TaxJurisdictionRequest:
+// Country, state and city are set on create only
+type TaxJurisdictionRequest struct {
+	Country *string `json:"country,omitempty"`
+	// Required for a city jurisdiction
+	State *string `json:"state,omitempty"`
+	City  *string `json:"city,omitempty"`
+	Name  *string `json:"name,omitempty"`
+	// Standard rate; 0.0725 is 7.25%
+	Rate *float64 `json:"rate,omitempty"`
+	// Rates by tax category code, replacing the standard rate
+	CategoryRates map[string]float64 `json:"category_rates,omitempty"`
+	Active        *bool              `json:"active,omitempty"`
+}
+
+// TaxJurisdiction is generated from #/components/schemas/TaxJurisdiction
+type TaxJurisdiction struct {
+	ID            uuid.UUID          `json:"id,omitempty"`
+	Country       string             `json:"country,omitempty"`
+	State         string             `json:"state,omitempty"`
+	City          string             `json:"city,omitempty"`
+	Name          string             `json:"name,omitempty"`
+	Rate          float64            `json:"rate,omitempty"`
+	CategoryRates map[string]float64 `json:"category_rates,omitempty"`
+	Active        bool               `json:"active,omitempty"`
+	CreatedAt     time.Time          `json:"created_at,omitempty"`
+	UpdatedAt     time.Time          `json:"updated_at,omitempty"`
+}
+
+// TaxCategory is generated from #/components/schemas/TaxCategory
+type TaxCategory struct {
+	Code        string     `json:"code"`
+	Name        string     `json:"name"`
+	Description *string    `json:"description,omitempty"`
+	Exempt      *bool      `json:"exempt,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+// TaxAssignment is generated from #/components/schemas/TaxAssignment
+type TaxAssignment struct {
+	Kind TaxAssignmentKind `json:"kind"`
+	// Catalog variant or catalog category ID
+	SubjectID uuid.UUID `json:"subject_id"`
+	// Tax category code
+	Category  string     `json:"category"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// TaxAssignmentKind is generated from #/components/schemas/TaxAssignment/properties/kind
+type TaxAssignmentKind string
+
+// Values of TaxAssignmentKind
+const (
+	TaxAssignmentKindProduct         TaxAssignmentKind = "product"
+	TaxAssignmentKindCatalogCategory TaxAssignmentKind = "catalog_category"
+)
+
+// TaxHoliday is generated from #/components/schemas/TaxHoliday
+type TaxHoliday struct {
+	ID             *uuid.UUID `json:"id,omitempty"`
+	JurisdictionID uuid.UUID  `json:"jurisdiction_id"`
+	Name           string     `json:"name"`
+	// Tax category covered; empty covers all of them
+	Category *string  `json:"category,omitempty"`
+	StartsOn string   `json:"starts_on"`
+	EndsOn   string   `json:"ends_on"`
+	Rate     *float64 `json:"rate,omitempty"`
+	// Only items priced at most this are covered; 0 covers any price
+	MaxUnitPrice *float64   `json:"max_unit_price,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+}
+
+// TaxQuoteRequest is generated from #/components/schemas/TaxQuoteRequest
+type TaxQuoteRequest struct {
+	StoreID uuid.UUID `json:"store_id"`
+	// Where the sale is shipped to; defaults to the store's location
+	Address  *TaxQuoteRequestAddress `json:"address,omitempty"`
+	Currency string                  `json:"currency"`
+	// Day to quote for, to preview tax holidays; defaults to today
+	Date  *string               `json:"date,omitempty"`
+	Lines []TaxQuoteRequestLine `json:"lines"`
+}
+
+// TaxQuoteRequestAddress is generated from #/components/schemas/TaxQuoteRequest/properties/address:
+// Where the sale is shipped to; defaults to the store's location
+type TaxQuoteRequestAddress struct {
+	Country *string `json:"country,omitempty"`
+	State   *string `json:"state,omitempty"`
+	City    *string `json:"city,omitempty"`
+}
+
+// TaxQuoteRequestLine is generated from #/components/schemas/TaxQuoteRequest/properties/lines/items
+type TaxQuoteRequestLine struct {
+	// Catalog variant ID
+	ProductID *string `json:"product_id,omitempty"`
+	// Catalog category ID
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	Quantity   *int       `json:"quantity,omitempty"`
+	// Line amount after discounts, with any tax the store's prices include
+	Amount *float64 `json:"amount,omitempty"`
+}
+
+// TaxStoreRate is generated from #/components/schemas/TaxStoreRate
+type TaxStoreRate struct {
+	StoreID        uuid.UUID `json:"store_id,omitempty"`
+	JurisdictionID uuid.UUID `json:"jurisdiction_id,omitempty"`
+	// Replaces the jurisdiction's standard rate at the store
+	Rate float64 `json:"rate,omitempty"`
+	// Replace the jurisdiction's rates of these tax categories
+	CategoryRates map[string]float64 `json:"category_rates,omitempty"`
+	// Tax categories the store does not levy the tax on
+	ExemptCategories []string `json:"exempt_categories,omitempty"`
+	// The store's prices include the tax
+	Inclusive bool      `json:"inclusive,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// JurisdictionTax is generated from #/components/schemas/JurisdictionTax
+type JurisdictionTax struct {
+	JurisdictionID uuid.UUID            `json:"jurisdiction_id,omitempty"`
+	Name           string               `json:"name,omitempty"`
+	Level          JurisdictionTaxLevel `json:"level,omitempty"`
+	Taxable        float64              `json:"taxable,omitempty"`
+	// Sales exempted by tax category or tax holiday
+	Exempt float64 `json:"exempt,omitempty"`
+	Tax    float64 `json:"tax,omitempty"`
+	// The tax is included in the store's prices rather than added to them
+	Inclusive bool `json:"inclusive,omitempty"`
+}
+
+// JurisdictionTaxLevel is generated from #/components/schemas/JurisdictionTax/properties/level
+type JurisdictionTaxLevel string
+
+// Values of JurisdictionTaxLevel
+const (
+	JurisdictionTaxLevelCountry JurisdictionTaxLevel = "country"
+	JurisdictionTaxLevelState   JurisdictionTaxLevel = "state"
+	JurisdictionTaxLevelCity    JurisdictionTaxLevel = "city"
+)
+
+// TaxQuote is generated from #/components/schemas/TaxQuote
+type TaxQuote struct {
+	Location TaxQuoteLocation `json:"location,omitempty"`
+	Date     time.Time        `json:"date,omitempty"`
+	Currency string           `json:"currency,omitempty"`
+	// Tax on each line, in request order
+	Lines         []TaxQuoteLine    `json:"lines,omitempty"`
+	Jurisdictions []JurisdictionTax `json:"jurisdictions,omitempty"`
+	TotalTax      float64           `json:"total_tax,omitempty"`
+	// Part of total_tax already in the line amounts
+	IncludedTax float64 `json:"included_tax,omitempty"`
+}
+
+// TaxQuoteLocation is generated from #/components/schemas/TaxQuote/properties/location
+type TaxQuoteLocation struct {
+	Country string `json:"country,omitempty"`
+	State   string `json:"state,omitempty"`
+	City    string `json:"city,omitempty"`
+}
+
+// TaxQuoteLine is generated from #/components/schemas/TaxQuote/properties/lines/items
+type TaxQuoteLine struct {
+	Category string  `json:"category,omitempty"`
+	Rate     float64 `json:"rate,omitempty"`
+	Tax      float64 `json:"tax,omitempty"`
+	// Part of the tax already in the line amount
+	Included float64 `json:"included,omitempty"`
+}
+
+// TaxReport is generated from #/components/schemas/TaxReport
+type TaxReport struct {
+	From        time.Time      `json:"from,omitempty"`
+	To          time.Time      `json:"to,omitempty"`
+	Country     string         `json:"country,omitempty"`
+	State       string         `json:"state,omitempty"`
+	Rows        []TaxReportRow `json:"rows,omitempty"`
+	GeneratedAt time.Time      `json:"generated_at,omitempty"`
+}
+
+// TaxReportRow is generated from #/components/schemas/TaxReport/properties/rows/items
+type TaxReportRow struct {
+	JurisdictionID uuid.UUID         `json:"jurisdiction_id,omitempty"`
+	Name           string            `json:"name,omitempty"`
+	Level          TaxReportRowLevel `json:"level,omitempty"`
+	Country        string            `json:"country,omitempty"`
+	State          string            `json:"state,omitempty"`
+	City           string            `json:"city,omitempty"`
+	Currency       string            `json:"currency,omitempty"`
+	Orders         int               `json:"orders,omitempty"`
+	Taxable        float64           `json:"taxable,omitempty"`
+	Exempt         float64           `json:"exempt,omitempty"`
+	Tax            float64           `json:"tax,omitempty"`
+}
+
+// TaxReportRowLevel is generated from #/components/schemas/TaxReport/properties/rows/items/properties/level
+type TaxReportRowLevel string
+
+// Values of TaxReportRowLevel
+const (
+	TaxReportRowLevelCountry TaxReportRowLevel = "country"
+	TaxReportRowLevelState   TaxReportRowLevel = "state"
+	TaxReportRowLevelCity    TaxReportRowLevel = "city"
+)
+
+// InventoryValuation is generated from #/components/schemas/InventoryValuation
+type InventoryValuation struct {
+	Quantity int                       `json:"quantity,omitempty"`
+	Value    float64                   `json:"value,omitempty"`
+	Layers   []InventoryValuationLayer `json:"layers,omitempty"`
+	// Units on hand beyond every layer
+	Uncosted int `json:"uncosted,omitempty"`
+}
+
+// InventoryValuationLayer is generated from #/components/schemas/InventoryValuation/properties/layers/items
+type InventoryValuationLayer struct {
+	ID          uuid.UUID `json:"id,omitempty"`
+	InventoryID uuid.UUID `json:"inventory_id,omitempty"`
+	Quantity    int       `json:"quantity,omitempty"`
+	// Units still on hand, first in, first out
+	Remaining  int       `json:"remaining,omitempty"`
+	UnitCost   float64   `json:"unit_cost,omitempty"`
+	SourceType string    `json:"source_type,omitempty"`
+	SourceID   uuid.UUID `json:"source_id,omitempty"`
+	ReceivedAt time.Time `json:"received_at,omitempty"`
+}
+
+// AdjustInventoryRequest is generated from #/components/schemas/AdjustInventoryRequest
+type AdjustInventoryRequest struct {
+	// Units added; negative to remove stock
+	QuantityChange int                          `json:"quantity_change"`
+	Reason         AdjustInventoryRequestReason `json:"reason"`
+	Note           *string                      `json:"note,omitempty"`
+	// Version of the inventory the change was worked out from
+	Version int `json:"version"`
+}
+
+// AdjustInventoryRequestReason is generated from #/components/schemas/AdjustInventoryRequest/properties/reason
+type AdjustInventoryRequestReason string
+
+// Values of AdjustInventoryRequestReason
+const (
+	AdjustInventoryRequestReasonDamage          AdjustInventoryRequestReason = "damage"
+	AdjustInventoryRequestReasonTheft           AdjustInventoryRequestReason = "theft"
+	AdjustInventoryRequestReasonCountCorrection AdjustInventoryRequestReason = "count_correction"
+)
+
+// StockMovement is generated from #/components/schemas/StockMovement
+type StockMovement struct {
+	ID               uuid.UUID `json:"id,omitempty"`
+	InventoryID      uuid.UUID `json:"inventory_id,omitempty"`
+	MovementType     string    `json:"movement_type,omitempty"`
+	Quantity         int       `json:"quantity,omitempty"`
+	PreviousQuantity int       `json:"previous_quantity,omitempty"`
+	NewQuantity      int       `json:"new_quantity,omitempty"`
+	Reason           string    `json:"reason,omitempty"`
+	ReferenceID      uuid.UUID `json:"reference_id,omitempty"`
+	ReferenceType    string    `json:"reference_type,omitempty"`
+	UserID           uuid.UUID `json:"user_id,omitempty"`
+	CreatedAt        time.Time `json:"created_at,omitempty"`
+}
+
+// InventoryAdjustment is generated from #/components/schemas/InventoryAdjustment
+type InventoryAdjustment struct {
+	Inventory Inventory     `json:"inventory,omitempty"`
+	Movement  StockMovement `json:"movement,omitempty"`
+}
+
+// CreateStockTransferRequest is generated from #/components/schemas/CreateStockTransferRequest
+type CreateStockTransferRequest struct {
+	SourceStoreID      uuid.UUID                        `json:"source_store_id"`
+	DestinationStoreID uuid.UUID                        `json:"destination_store_id"`
+	Items              []CreateStockTransferRequestItem `json:"items"`
+	Note               *string                          `json:"note,omitempty"`
+}
+
+// CreateStockTransferRequestItem is generated from #/components/schemas/CreateStockTransferRequest/properties/items/items
+type CreateStockTransferRequestItem struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+}
+
+// StockTransfer is generated from #/components/schemas/StockTransfer
+type StockTransfer struct {
+	ID                 uuid.UUID           `json:"id,omitempty"`
+	SourceStoreID      uuid.UUID           `json:"source_store_id,omitempty"`
+	DestinationStoreID uuid.UUID           `json:"destination_store_id,omitempty"`
+	Status             StockTransferStatus `json:"status,omitempty"`
+	Items              []StockTransferItem `json:"items,omitempty"`
+	Note               string              `json:"note,omitempty"`
+	RequestedBy        uuid.UUID           `json:"requested_by,omitempty"`
+	DispatchedBy       uuid.UUID           `json:"dispatched_by,omitempty"`
+	ReceivedBy         uuid.UUID           `json:"received_by,omitempty"`
+	DispatchedAt       time.Time           `json:"dispatched_at,omitempty"`
+	ReceivedAt         time.Time           `json:"received_at,omitempty"`
+	CancelledAt        time.Time           `json:"cancelled_at,omitempty"`
+	CreatedAt          time.Time           `json:"created_at,omitempty"`
+	UpdatedAt          time.Time           `json:"updated_at,omitempty"`
+}
+
+// StockTransferStatus is generated from #/components/schemas/StockTransfer/properties/status
+type StockTransferStatus string
+
+// Values of StockTransferStatus
+const (
+	StockTransferStatusRequested StockTransferStatus = "requested"
+	StockTransferStatusInTransit StockTransferStatus = "in_transit"
+	StockTransferStatusReceived  StockTransferStatus = "received"
+	StockTransferStatusCancelled StockTransferStatus = "cancelled"
+)
+
+// StockTransferItem is generated from #/components/schemas/StockTransfer/properties/items/items
+type StockTransferItem struct {
+	ProductID uuid.UUID `json:"product_id,omitempty"`
+	Quantity  int       `json:"quantity,omitempty"`
+}
+
+// CreateStockCountRequest is generated from #/components/schemas/CreateStockCountRequest
+type CreateStockCountRequest struct {
+	StoreID uuid.UUID `json:"store_id"`
+	Note    *string   `json:"note,omitempty"`
+}
+
+// RecordStockCountsRequest is generated from #/components/schemas/RecordStockCountsRequest
+type RecordStockCountsRequest struct {
+	Items []RecordStockCountsRequestItem `json:"items"`
+}
+
+// RecordStockCountsRequestItem is generated from #/components/schemas/RecordStockCountsRequest/properties/items/items
+type RecordStockCountsRequestItem struct {
+	ProductID       uuid.UUID `json:"product_id"`
+	CountedQuantity int       `json:"counted_quantity"`
+}
+
+// StockCount is generated from #/components/schemas/StockCount
+type StockCount struct {
+	ID       uuid.UUID        `json:"id,omitempty"`
+	StoreID  uuid.UUID        `json:"store_id,omitempty"`
+	Status   StockCountStatus `json:"status,omitempty"`
+	Note     string           `json:"note,omitempty"`
+	OpenedBy uuid.UUID        `json:"opened_by,omitempty"`
+	ClosedBy uuid.UUID        `json:"closed_by,omitempty"`
+	// When reconciled or cancelled
+	ClosedAt  time.Time `json:"closed_at,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// StockCountStatus is generated from #/components/schemas/StockCount/properties/status
+type StockCountStatus string
+
+// Values of StockCountStatus
+const (
+	StockCountStatusOpen       StockCountStatus = "open"
+	StockCountStatusReconciled StockCountStatus = "reconciled"
+	StockCountStatusCancelled  StockCountStatus = "cancelled"
+)
+
+// StockCountLine is generated from #/components/schemas/StockCountLine
+type StockCountLine struct {
+	ProductID       uuid.UUID `json:"product_id,omitempty"`
+	InventoryID     uuid.UUID `json:"inventory_id,omitempty"`
+	CountedQuantity int       `json:"counted_quantity,omitempty"`
+	// Stock on hand the count is measured against
+	SystemQuantity int `json:"system_quantity,omitempty"`
+	// Counted less on record; negative when stock is missing
+	Variance int `json:"variance,omitempty"`
+	// Variance at the inventory's cost price
+	VarianceValue float64   `json:"variance_value,omitempty"`
+	CountedBy     uuid.UUID `json:"counted_by,omitempty"`
+	CountedAt     time.Time `json:"counted_at,omitempty"`
+}
+
+// StockCountVarianceReport is generated from #/components/schemas/StockCountVarianceReport
+type StockCountVarianceReport struct {
+	Count   StockCount                      `json:"count,omitempty"`
+	Lines   []StockCountLine                `json:"lines,omitempty"`
+	Summary StockCountVarianceReportSummary `json:"summary,omitempty"`
+}
+
+// StockCountVarianceReportSummary is generated from #/components/schemas/StockCountVarianceReport/properties/summary
+type StockCountVarianceReportSummary struct {
+	LinesCounted int `json:"lines_counted,omitempty"`
+	// Products counted differently than on record
+	LinesVarying  int     `json:"lines_varying,omitempty"`
+	UnitsOver     int     `json:"units_over,omitempty"`
+	UnitsShort    int     `json:"units_short,omitempty"`
+	VarianceValue float64 `json:"variance_value,omitempty"`
+}
+
+// StockCountReconciliation is generated from #/components/schemas/StockCountReconciliation
+type StockCountReconciliation struct {
+	Count       StockCount                           `json:"count,omitempty"`
+	Adjustments []StockCountReconciliationAdjustment `json:"adjustments,omitempty"`
+}
+
+// StockCountReconciliationAdjustment is generated from #/components/schemas/StockCountReconciliation/properties/adjustments/items
+type StockCountReconciliationAdjustment struct {
+	Inventory      Inventory `json:"inventory,omitempty"`
+	QuantityChange int       `json:"quantity_change,omitempty"`
+}
+
+// StockImportReport is generated from #/components/schemas/StockImportReport
+type StockImportReport struct {
+	DryRun   bool                     `json:"dry_run,omitempty"`
+	Rows     int                      `json:"rows,omitempty"`
+	Imported int                      `json:"imported,omitempty"`
+	Errors   []StockImportReportError `json:"errors,omitempty"`
+}
+
+// StockImportReportError is generated from #/components/schemas/StockImportReport/properties/errors/items
+type StockImportReportError struct {
+	// Line of the file, the header being line 1
+	Line   int    `json:"line,omitempty"`
+	Column string `json:"column,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SupplierRequest is generated from #/components/schemas/SupplierRequest
+type SupplierRequest struct {
+	Code         string  `json:"code"`
+	Name         string  `json:"name"`
+	ContactName  *string `json:"contact_name,omitempty"`
+	Email        *string `json:"email,omitempty"`
+	Phone        *string `json:"phone,omitempty"`
+	Address      *string `json:"address,omitempty"`
+	Currency     string  `json:"currency"`
+	PaymentTerms *string `json:"payment_terms,omitempty"`
+	LeadTimeDays *int    `json:"lead_time_days,omitempty"`
+	Active       *bool   `json:"active,omitempty"`
+	Notes        *string `json:"notes,omitempty"`
+}
+
+// Supplier is generated from #/components/schemas/Supplier
+type Supplier struct {
+	ID           uuid.UUID `json:"id,omitempty"`
+	Code         string    `json:"code,omitempty"`
+	Name         string    `json:"name,omitempty"`
+	ContactName  string    `json:"contact_name,omitempty"`
+	Email        string    `json:"email,omitempty"`
+	Phone        string    `json:"phone,omitempty"`
+	Address      string    `json:"address,omitempty"`
+	Currency     string    `json:"currency,omitempty"`
+	PaymentTerms string    `json:"payment_terms,omitempty"`
+	LeadTimeDays int       `json:"lead_time_days,omitempty"`
+	Active       bool      `json:"active,omitempty"`
+	Notes        string    `json:"notes,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at,omitempty"`
+}
+
+// SupplierProductRequest is generated from #/components/schemas/SupplierProductRequest
+type SupplierProductRequest struct {
+	SupplierSKU *string `json:"supplier_sku,omitempty"`
+	UnitCost    float64 `json:"unit_cost"`
+	// Fewest units the supplier takes an order for; defaults to 1
+	MinOrderQuantity *int `json:"min_order_quantity,omitempty"`
+}
+
+// SupplierProduct is generated from #/components/schemas/SupplierProduct
+type SupplierProduct struct {
+	SupplierID       uuid.UUID `json:"supplier_id,omitempty"`
+	ProductID        uuid.UUID `json:"product_id,omitempty"`
+	SupplierSKU      string    `json:"supplier_sku,omitempty"`
+	UnitCost         float64   `json:"unit_cost,omitempty"`
+	MinOrderQuantity int       `json:"min_order_quantity,omitempty"`
+	CreatedAt        time.Time `json:"created_at,omitempty"`
+	UpdatedAt        time.Time `json:"updated_at,omitempty"`
+}
+
+// SupplierPerformance is generated from #/components/schemas/SupplierPerformance
+type SupplierPerformance struct {
+	SupplierID       uuid.UUID `json:"supplier_id,omitempty"`
+	From             time.Time `json:"from,omitempty"`
+	To               time.Time `json:"to,omitempty"`
+	Orders           int       `json:"orders,omitempty"`
+	OpenOrders       int       `json:"open_orders,omitempty"`
+	OverdueOrders    int       `json:"overdue_orders,omitempty"`
+	OrderedQuantity  int       `json:"ordered_quantity,omitempty"`
+	ReceivedQuantity int       `json:"received_quantity,omitempty"`
+	// Share of the quantity ordered on settled orders that arrived
+	FillRate   *float64 `json:"fill_rate,omitempty"`
+	OnTimeRate *float64 `json:"on_time_rate,omitempty"`
+	// Days from submission to the last goods receipt of settled orders
+	AverageLeadTimeDays *float64 `json:"average_lead_time_days,omitempty"`
+	QuotedLeadTimeDays  int      `json:"quoted_lead_time_days,omitempty"`
+}
+
+// PurchaseOrderRequest is generated from #/components/schemas/PurchaseOrderRequest
+type PurchaseOrderRequest struct {
+	// Required on create
+	SupplierID *uuid.UUID `json:"supplier_id,omitempty"`
+	// Required on create
+	StoreID    *uuid.UUID                 `json:"store_id,omitempty"`
+	Currency   *string                    `json:"currency,omitempty"`
+	ExpectedOn *string                    `json:"expected_on,omitempty"`
+	Notes      *string                    `json:"notes,omitempty"`
+	Lines      []PurchaseOrderRequestLine `json:"lines"`
+}
+
+// PurchaseOrderRequestLine is generated from #/components/schemas/PurchaseOrderRequest/properties/lines/items
+type PurchaseOrderRequestLine struct {
+	ProductID   uuid.UUID `json:"product_id"`
+	SupplierSKU *string   `json:"supplier_sku,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Quantity    int       `json:"quantity"`
+	UnitCost    *float64  `json:"unit_cost,omitempty"`
+}
+
+// PurchaseOrder is generated from #/components/schemas/PurchaseOrder
+type PurchaseOrder struct {
+	ID          uuid.UUID           `json:"id,omitempty"`
+	Number      int64               `json:"number,omitempty"`
+	SupplierID  uuid.UUID           `json:"supplier_id,omitempty"`
+	StoreID     uuid.UUID           `json:"store_id,omitempty"`
+	Status      PurchaseOrderStatus `json:"status,omitempty"`
+	Currency    string              `json:"currency,omitempty"`
+	Lines       []PurchaseOrderLine `json:"lines,omitempty"`
+	Total       float64             `json:"total,omitempty"`
+	ExpectedOn  time.Time           `json:"expected_on,omitempty"`
+	Notes       string              `json:"notes,omitempty"`
+	CreatedBy   uuid.UUID           `json:"created_by,omitempty"`
+	SubmittedAt time.Time           `json:"submitted_at,omitempty"`
+	ReceivedAt  time.Time           `json:"received_at,omitempty"`
+	ClosedAt    time.Time           `json:"closed_at,omitempty"`
+	CreatedAt   time.Time           `json:"created_at,omitempty"`
+	UpdatedAt   time.Time           `json:"updated_at,omitempty"`
+}
+
+// PurchaseOrderStatus is generated from #/components/schemas/PurchaseOrder/properties/status
+type PurchaseOrderStatus string
+
+// Values of PurchaseOrderStatus
+const (
+	PurchaseOrderStatusDraft             PurchaseOrderStatus = "draft"
+	PurchaseOrderStatusSubmitted         PurchaseOrderStatus = "submitted"
+	PurchaseOrderStatusPartiallyReceived PurchaseOrderStatus = "partially_received"
+	PurchaseOrderStatusReceived          PurchaseOrderStatus = "received"
+	PurchaseOrderStatusClosed            PurchaseOrderStatus = "closed"
+	PurchaseOrderStatusCancelled         PurchaseOrderStatus = "cancelled"
+)
+
+// PurchaseOrderLine is generated from #/components/schemas/PurchaseOrder/properties/lines/items
+type PurchaseOrderLine struct {
+	ID               uuid.UUID `json:"id,omitempty"`
+	ProductID        uuid.UUID `json:"product_id,omitempty"`
+	SupplierSKU      string    `json:"supplier_sku,omitempty"`
+	Description      string    `json:"description,omitempty"`
+	Quantity         int       `json:"quantity,omitempty"`
+	UnitCost         float64   `json:"unit_cost,omitempty"`
+	ReceivedQuantity int       `json:"received_quantity,omitempty"`
+}
+
+// GoodsReceiptRequest is generated from #/components/schemas/GoodsReceiptRequest
+type GoodsReceiptRequest struct {
+	Lines      []GoodsReceiptRequestLine `json:"lines"`
+	Note       *string                   `json:"note,omitempty"`
+	ReceivedAt *time.Time                `json:"received_at,omitempty"`
+}
+
+// GoodsReceiptRequestLine is generated from #/components/schemas/GoodsReceiptRequest/properties/lines/items
+type GoodsReceiptRequestLine struct {
+	LineID   uuid.UUID `json:"line_id"`
+	Quantity int       `json:"quantity"`
+	// Defaults to the order line's
+	UnitCost *float64 `json:"unit_cost,omitempty"`
+}
+
+// GoodsReceipt is generated from #/components/schemas/GoodsReceipt
+type GoodsReceipt struct {
+	ID              uuid.UUID          `json:"id,omitempty"`
+	TenantID        string             `json:"tenant_id,omitempty"`
+	PurchaseOrderID uuid.UUID          `json:"purchase_order_id,omitempty"`
+	StoreID         uuid.UUID          `json:"store_id,omitempty"`
+	Lines           []GoodsReceiptLine `json:"lines,omitempty"`
+	Note            string             `json:"note,omitempty"`
+	ReceivedBy      uuid.UUID          `json:"received_by,omitempty"`
+	ReceivedAt      time.Time          `json:"received_at,omitempty"`
+	// When inventory took the stock; absent until then
+	PostedAt time.Time `json:"posted_at,omitempty"`
+}
+
+// GoodsReceiptLine is generated from #/components/schemas/GoodsReceipt/properties/lines/items
+type GoodsReceiptLine struct {
+	ID        uuid.UUID `json:"id,omitempty"`
+	LineID    uuid.UUID `json:"line_id,omitempty"`
+	ProductID uuid.UUID `json:"product_id,omitempty"`
+	Quantity  int       `json:"quantity,omitempty"`
+	UnitCost  float64   `json:"unit_cost,omitempty"`
+}
+
+// WebhookSubscriptionRequest is generated from #/components/schemas/WebhookSubscriptionRequest
+type WebhookSubscriptionRequest struct {
+	// Required of admins creating a subscription; ignored for partners
+	PartnerID   *string  `json:"partner_id,omitempty"`
+	URL         string   `json:"url"`
+	EventTypes  []string `json:"event_types"`
+	Description *string  `json:"description,omitempty"`
+}
+
+// WebhookSubscription is generated from #/components/schemas/WebhookSubscription
+type WebhookSubscription struct {
+	ID          uuid.UUID                 `json:"id,omitempty"`
+	TenantID    string                    `json:"tenant_id,omitempty"`
+	PartnerID   string                    `json:"partner_id,omitempty"`
+	URL         string                    `json:"url,omitempty"`
+	EventTypes  []string                  `json:"event_types,omitempty"`
+	Description string                    `json:"description,omitempty"`
+	Status      WebhookSubscriptionStatus `json:"status,omitempty"`
+	CreatedAt   time.Time                 `json:"created_at,omitempty"`
+	UpdatedAt   time.Time                 `json:"updated_at,omitempty"`
+}
+
+// WebhookSubscriptionStatus is generated from #/components/schemas/WebhookSubscription/properties/status
+type WebhookSubscriptionStatus string
+
+// Values of WebhookSubscriptionStatus
+const (
+	WebhookSubscriptionStatusActive WebhookSubscriptionStatus = "active"
+	WebhookSubscriptionStatusPaused WebhookSubscriptionStatus = "paused"
+)
+
+// WebhookDelivery is generated from #/components/schemas/WebhookDelivery
+type WebhookDelivery struct {
+	ID             uuid.UUID `json:"id,omitempty"`
+	SubscriptionID uuid.UUID `json:"subscription_id,omitempty"`
+	EventID        string    `json:"event_id,omitempty"`
+	EventType      string    `json:"event_type,omitempty"`
+	// The event's data; left out of lists
+	Payload map[string]any        `json:"payload,omitempty"`
+	Status  WebhookDeliveryStatus `json:"status,omitempty"`
+	// Attempts since the delivery was queued or last replayed
+	Attempts      int       `json:"attempts,omitempty"`
+	NextAttemptAt time.Time `json:"next_attempt_at,omitempty"`
+	LastAttemptAt time.Time `json:"last_attempt_at,omitempty"`
+	// Of the last attempt
+	ResponseCode int       `json:"response_code,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+	EventAt      time.Time `json:"event_at,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+	DeliveredAt  time.Time `json:"delivered_at,omitempty"`
+}
+
+// WebhookDeliveryStatus is generated from #/components/schemas/WebhookDelivery/properties/status
+type WebhookDeliveryStatus string
+
+// Values of WebhookDeliveryStatus
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookAttempt is generated from #/components/schemas/WebhookAttempt
+type WebhookAttempt struct {
+	ID         uuid.UUID `json:"id,omitempty"`
+	DeliveryID uuid.UUID `json:"delivery_id,omitempty"`
+	Number     int       `json:"number,omitempty"`
+	// Absent when no response arrived
+	ResponseCode int `json:"response_code,omitempty"`
+	// The first kilobyte of the response
+	ResponseBody string    `json:"response_body,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	DurationMs   int       `json:"duration_ms,omitempty"`
+	AttemptedAt  time.Time `json:"attempted_at,omitempty"`
+}
@@ -0,0 +1,56 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package users is a client of the Users operations of the API.
+package users
+
+import (
+	"context"
+
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Users requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Users operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// GetCurrentUser sends GET /users/me: get current user profile
+func (c *Client) GetCurrentUser(ctx context.Context) (*apiclient.User, error) {
+	var out apiclient.User
+	if err := c.client.Do(ctx, "GET", "/users/me", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateCurrentUser sends PUT /users/me: update user profile
+func (c *Client) UpdateCurrentUser(ctx context.Context, body *UpdateCurrentUserRequest) (*apiclient.User, error) {
+	var out apiclient.User
+	if err := c.client.Do(ctx, "PUT", "/users/me", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ChangePassword sends PUT /users/me/password: change password
+func (c *Client) ChangePassword(ctx context.Context, body *ChangePasswordRequest) error {
+	return c.client.Do(ctx, "PUT", "/users/me/password", nil, body, nil)
+}
+
+// UpdateCurrentUserRequest is generated from #/paths/~1users~1me/put/requestBody
+type UpdateCurrentUserRequest struct {
+	FirstName *string `json:"first_name,omitempty"`
+	LastName  *string `json:"last_name,omitempty"`
+	Phone     *string `json:"phone,omitempty"`
+}
+
+// ChangePasswordRequest is generated from #/paths/~1users~1me~1password/put/requestBody
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
@@ -0,0 +1,402 @@
+// Code generated by apigen from pkg/api/openapi.yaml. DO NOT EDIT.
+
+// Package webhooks is a client of the Webhooks operations of the API.
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/onichange/pos-system/pkg/apiclient"
+)
+
+// Client sends the Webhooks requests of the API
+type Client struct {
+	client *apiclient.Client
+}
+
+// New creates a client of the Webhooks operations sending requests through client
+func New(client *apiclient.Client) *Client {
+	return &Client{client: client}
+}
+
+// ListWebhookSubscriptions sends GET /webhooks/subscriptions: list webhook subscriptions
+func (c *Client) ListWebhookSubscriptions(ctx context.Context, params *ListWebhookSubscriptionsParams) (*ListWebhookSubscriptionsResponse, error) {
+	var out ListWebhookSubscriptionsResponse
+	if err := c.client.Do(ctx, "GET", "/webhooks/subscriptions", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListWebhookSubscriptionsParams are the query parameters of ListWebhookSubscriptions
+type ListWebhookSubscriptionsParams struct {
+	PartnerID *string
+	Limit     *int
+	Offset    *int
+}
+
+func (p *ListWebhookSubscriptionsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.PartnerID != nil {
+		q.Set("partner_id", *p.PartnerID)
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// CreateWebhookSubscription sends POST /webhooks/subscriptions: create webhook subscription
+func (c *Client) CreateWebhookSubscription(ctx context.Context, body *apiclient.WebhookSubscriptionRequest) (*CreateWebhookSubscriptionResponse, error) {
+	var out CreateWebhookSubscriptionResponse
+	if err := c.client.Do(ctx, "POST", "/webhooks/subscriptions", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetWebhookSubscription sends GET /webhooks/subscriptions/{id}: get webhook subscription
+func (c *Client) GetWebhookSubscription(ctx context.Context, id uuid.UUID) (*apiclient.WebhookSubscription, error) {
+	var out apiclient.WebhookSubscription
+	if err := c.client.Do(ctx, "GET", "/webhooks/subscriptions/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateWebhookSubscription sends PUT /webhooks/subscriptions/{id}: update webhook subscription
+func (c *Client) UpdateWebhookSubscription(ctx context.Context, id uuid.UUID, body *apiclient.WebhookSubscriptionRequest) (*apiclient.WebhookSubscription, error) {
+	var out apiclient.WebhookSubscription
+	if err := c.client.Do(ctx, "PUT", "/webhooks/subscriptions/"+url.PathEscape(id.String()), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteWebhookSubscription sends DELETE /webhooks/subscriptions/{id}: delete webhook subscription
+func (c *Client) DeleteWebhookSubscription(ctx context.Context, id uuid.UUID) error {
+	return c.client.Do(ctx, "DELETE", "/webhooks/subscriptions/"+url.PathEscape(id.String()), nil, nil, nil)
+}
+
+// PauseWebhookSubscription sends POST /webhooks/subscriptions/{id}/pause: pause webhook subscription
+func (c *Client) PauseWebhookSubscription(ctx context.Context, id uuid.UUID) (*apiclient.WebhookSubscription, error) {
+	var out apiclient.WebhookSubscription
+	if err := c.client.Do(ctx, "POST", "/webhooks/subscriptions/"+url.PathEscape(id.String())+"/pause", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ResumeWebhookSubscription sends POST /webhooks/subscriptions/{id}/resume: resume webhook subscription
+func (c *Client) ResumeWebhookSubscription(ctx context.Context, id uuid.UUID) (*apiclient.WebhookSubscription, error) {
+	var out apiclient.WebhookSubscription
+	if err := c.client.Do(ctx, "POST", "/webhooks/subscriptions/"+url.PathEscape(id.String())+"/resume", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReplayWebhookSubscription sends POST /webhooks/subscriptions/{id}/replay: replay failed webhook deliveries
+func (c *Client) ReplayWebhookSubscription(ctx context.Context, id uuid.UUID, body *ReplayWebhookSubscriptionRequest) (*ReplayWebhookSubscriptionResponse, error) {
+	var in any
+	if body != nil {
+		in = body
+	}
+	var out ReplayWebhookSubscriptionResponse
+	if err := c.client.Do(ctx, "POST", "/webhooks/subscriptions/"+url.PathEscape(id.String())+"/replay", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListWebhookDeliveries sends GET /webhooks/deliveries: list webhook deliveries
+func (c *Client) ListWebhookDeliveries(ctx context.Context, params *ListWebhookDeliveriesParams) (*ListWebhookDeliveriesResponse, error) {
+	var out ListWebhookDeliveriesResponse
+	if err := c.client.Do(ctx, "GET", "/webhooks/deliveries", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListWebhookDeliveriesParams are the query parameters of ListWebhookDeliveries
+type ListWebhookDeliveriesParams struct {
+	SubscriptionID *uuid.UUID
+	Status         *string
+	EventType      *string
+	Limit          *int
+	Offset         *int
+}
+
+func (p *ListWebhookDeliveriesParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.SubscriptionID != nil {
+		q.Set("subscription_id", (*p.SubscriptionID).String())
+	}
+	if p.Status != nil {
+		q.Set("status", *p.Status)
+	}
+	if p.EventType != nil {
+		q.Set("event_type", *p.EventType)
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// GetWebhookDelivery sends GET /webhooks/deliveries/{id}: get webhook delivery
+func (c *Client) GetWebhookDelivery(ctx context.Context, id uuid.UUID) (*GetWebhookDeliveryResponse, error) {
+	var out GetWebhookDeliveryResponse
+	if err := c.client.Do(ctx, "GET", "/webhooks/deliveries/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReplayWebhookDelivery sends POST /webhooks/deliveries/{id}/replay: replay webhook delivery
+func (c *Client) ReplayWebhookDelivery(ctx context.Context, id uuid.UUID) (*apiclient.WebhookDelivery, error) {
+	var out apiclient.WebhookDelivery
+	if err := c.client.Do(ctx, "POST", "/webhooks/deliveries/"+url.PathEscape(id.String())+"/replay", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPartnerWebhookSubscriptions sends GET /partner/webhooks/subscriptions: list webhook subscriptions
+func (c *Client) ListPartnerWebhookSubscriptions(ctx context.Context, params *ListPartnerWebhookSubscriptionsParams) (*ListPartnerWebhookSubscriptionsResponse, error) {
+	var out ListPartnerWebhookSubscriptionsResponse
+	if err := c.client.Do(ctx, "GET", "/partner/webhooks/subscriptions", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPartnerWebhookSubscriptionsParams are the query parameters of ListPartnerWebhookSubscriptions
+type ListPartnerWebhookSubscriptionsParams struct {
+	Limit  *int
+	Offset *int
+}
+
+func (p *ListPartnerWebhookSubscriptionsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// CreatePartnerWebhookSubscription sends POST /partner/webhooks/subscriptions: create webhook subscription
+func (c *Client) CreatePartnerWebhookSubscription(ctx context.Context, body *apiclient.WebhookSubscriptionRequest) (*CreatePartnerWebhookSubscriptionResponse, error) {
+	var out CreatePartnerWebhookSubscriptionResponse
+	if err := c.client.Do(ctx, "POST", "/partner/webhooks/subscriptions", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPartnerWebhookSubscription sends GET /partner/webhooks/subscriptions/{id}: get webhook subscription
+func (c *Client) GetPartnerWebhookSubscription(ctx context.Context, id uuid.UUID) (*apiclient.WebhookSubscription, error) {
+	var out apiclient.WebhookSubscription
+	if err := c.client.Do(ctx, "GET", "/partner/webhooks/subscriptions/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdatePartnerWebhookSubscription sends PUT /partner/webhooks/subscriptions/{id}: update webhook subscription
+func (c *Client) UpdatePartnerWebhookSubscription(ctx context.Context, id uuid.UUID, body *apiclient.WebhookSubscriptionRequest) (*apiclient.WebhookSubscription, error) {
+	var out apiclient.WebhookSubscription
+	if err := c.client.Do(ctx, "PUT", "/partner/webhooks/subscriptions/"+url.PathEscape(id.String()), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeletePartnerWebhookSubscription sends DELETE /partner/webhooks/subscriptions/{id}: delete webhook subscription
+func (c *Client) DeletePartnerWebhookSubscription(ctx context.Context, id uuid.UUID) error {
+	return c.client.Do(ctx, "DELETE", "/partner/webhooks/subscriptions/"+url.PathEscape(id.String()), nil, nil, nil)
+}
+
+// PausePartnerWebhookSubscription sends POST /partner/webhooks/subscriptions/{id}/pause: pause webhook subscription
+func (c *Client) PausePartnerWebhookSubscription(ctx context.Context, id uuid.UUID) (*apiclient.WebhookSubscription, error) {
+	var out apiclient.WebhookSubscription
+	if err := c.client.Do(ctx, "POST", "/partner/webhooks/subscriptions/"+url.PathEscape(id.String())+"/pause", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ResumePartnerWebhookSubscription sends POST /partner/webhooks/subscriptions/{id}/resume: resume webhook subscription
+func (c *Client) ResumePartnerWebhookSubscription(ctx context.Context, id uuid.UUID) (*apiclient.WebhookSubscription, error) {
+	var out apiclient.WebhookSubscription
+	if err := c.client.Do(ctx, "POST", "/partner/webhooks/subscriptions/"+url.PathEscape(id.String())+"/resume", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReplayPartnerWebhookSubscription sends POST /partner/webhooks/subscriptions/{id}/replay: replay failed webhook deliveries
+func (c *Client) ReplayPartnerWebhookSubscription(ctx context.Context, id uuid.UUID, body *ReplayPartnerWebhookSubscriptionRequest) (*ReplayPartnerWebhookSubscriptionResponse, error) {
+	var in any
+	if body != nil {
+		in = body
+	}
+	var out ReplayPartnerWebhookSubscriptionResponse
+	if err := c.client.Do(ctx, "POST", "/partner/webhooks/subscriptions/"+url.PathEscape(id.String())+"/replay", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPartnerWebhookDeliveries sends GET /partner/webhooks/deliveries: list webhook deliveries
+func (c *Client) ListPartnerWebhookDeliveries(ctx context.Context, params *ListPartnerWebhookDeliveriesParams) (*ListPartnerWebhookDeliveriesResponse, error) {
+	var out ListPartnerWebhookDeliveriesResponse
+	if err := c.client.Do(ctx, "GET", "/partner/webhooks/deliveries", params.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPartnerWebhookDeliveriesParams are the query parameters of ListPartnerWebhookDeliveries
+type ListPartnerWebhookDeliveriesParams struct {
+	SubscriptionID *uuid.UUID
+	Status         *string
+	EventType      *string
+	Limit          *int
+	Offset         *int
+}
+
+func (p *ListPartnerWebhookDeliveriesParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.SubscriptionID != nil {
+		q.Set("subscription_id", (*p.SubscriptionID).String())
+	}
+	if p.Status != nil {
+		q.Set("status", *p.Status)
+	}
+	if p.EventType != nil {
+		q.Set("event_type", *p.EventType)
+	}
+	if p.Limit != nil {
+		q.Set("limit", fmt.Sprint(*p.Limit))
+	}
+	if p.Offset != nil {
+		q.Set("offset", fmt.Sprint(*p.Offset))
+	}
+	return q
+}
+
+// GetPartnerWebhookDelivery sends GET /partner/webhooks/deliveries/{id}: get webhook delivery
+func (c *Client) GetPartnerWebhookDelivery(ctx context.Context, id uuid.UUID) (*GetPartnerWebhookDeliveryResponse, error) {
+	var out GetPartnerWebhookDeliveryResponse
+	if err := c.client.Do(ctx, "GET", "/partner/webhooks/deliveries/"+url.PathEscape(id.String()), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReplayPartnerWebhookDelivery sends POST /partner/webhooks/deliveries/{id}/replay: replay webhook delivery
+func (c *Client) ReplayPartnerWebhookDelivery(ctx context.Context, id uuid.UUID) (*apiclient.WebhookDelivery, error) {
+	var out apiclient.WebhookDelivery
+	if err := c.client.Do(ctx, "POST", "/partner/webhooks/deliveries/"+url.PathEscape(id.String())+"/replay", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListWebhookSubscriptionsResponse is generated from #/paths/~1webhooks~1subscriptions/get/responses/200
+type ListWebhookSubscriptionsResponse struct {
+	Data []apiclient.WebhookSubscription `json:"data,omitempty"`
+}
+
+// CreateWebhookSubscriptionResponse is generated from #/paths/~1webhooks~1subscriptions/post/responses/201
+type CreateWebhookSubscriptionResponse struct {
+	apiclient.WebhookSubscription
+	// Signs deliveries in the Webhook-Signature header
+	Secret string `json:"secret,omitempty"`
+}
+
+// ReplayWebhookSubscriptionResponse is generated from #/paths/~1webhooks~1subscriptions~1{id}~1replay/post/responses/200
+type ReplayWebhookSubscriptionResponse struct {
+	SubscriptionID uuid.UUID `json:"subscription_id,omitempty"`
+	Since          time.Time `json:"since,omitempty"`
+	Replayed       int       `json:"replayed,omitempty"`
+}
+
+// ReplayWebhookSubscriptionRequest is generated from #/paths/~1webhooks~1subscriptions~1{id}~1replay/post/requestBody
+type ReplayWebhookSubscriptionRequest struct {
+	// Defaults to a day ago
+	Since *time.Time `json:"since,omitempty"`
+}
+
+// ListWebhookDeliveriesResponse is generated from #/paths/~1webhooks~1deliveries/get/responses/200
+type ListWebhookDeliveriesResponse struct {
+	Data []apiclient.WebhookDelivery `json:"data,omitempty"`
+}
+
+// GetWebhookDeliveryResponse is generated from #/paths/~1webhooks~1deliveries~1{id}/get/responses/200
+type GetWebhookDeliveryResponse struct {
+	apiclient.WebhookDelivery
+	AttemptLog []apiclient.WebhookAttempt `json:"attempt_log,omitempty"`
+}
+
+// ListPartnerWebhookSubscriptionsResponse is generated from #/paths/~1partner~1webhooks~1subscriptions/get/responses/200
+type ListPartnerWebhookSubscriptionsResponse struct {
+	Data []apiclient.WebhookSubscription `json:"data,omitempty"`
+}
+
+// CreatePartnerWebhookSubscriptionResponse is generated from #/paths/~1partner~1webhooks~1subscriptions/post/responses/201
+type CreatePartnerWebhookSubscriptionResponse struct {
+	apiclient.WebhookSubscription
+	// Signs deliveries in the Webhook-Signature header
+	Secret string `json:"secret,omitempty"`
+}
+
+// ReplayPartnerWebhookSubscriptionResponse is generated from #/paths/~1partner~1webhooks~1subscriptions~1{id}~1replay/post/responses/200
+type ReplayPartnerWebhookSubscriptionResponse struct {
+	SubscriptionID uuid.UUID `json:"subscription_id,omitempty"`
+	Since          time.Time `json:"since,omitempty"`
+	Replayed       int       `json:"replayed,omitempty"`
+}
+
+// ReplayPartnerWebhookSubscriptionRequest is generated from #/paths/~1partner~1webhooks~1subscriptions~1{id}~1replay/post/requestBody
+type ReplayPartnerWebhookSubscriptionRequest struct {
+	// Defaults to a day ago
+	Since *time.Time `json:"since,omitempty"`
+}
+
+// ListPartnerWebhookDeliveriesResponse is generated from #/paths/~1partner~1webhooks~1deliveries/get/responses/200
+type ListPartnerWebhookDeliveriesResponse struct {
+	Data []apiclient.WebhookDelivery `json:"data,omitempty"`
+}
+
+// GetPartnerWebhookDeliveryResponse is generated from #/paths/~1partner~1webhooks~1deliveries~1{id}/get/responses/200
+type GetPartnerWebhookDeliveryResponse struct {
+	apiclient.WebhookDelivery
+	AttemptLog []apiclient.WebhookAttempt `json:"attempt_log,omitempty"`
+}
@@ -0,0 +1,86 @@
+// Package archive moves rows past their retention out of the live tables
+// into cold storage: a table of the same columns in the archive schema,
+// partitioned by month, so old months can be detached, dumped or dropped
+// without touching live data. Archived rows stay queryable, and can be
+// restored to their live table by time range.
+//
+// Each service archives the tables of its own database. The archive tables
+// are created by the service's migrations with the columns of the live
+// table; a migration adding a column to the live table must add it to the
+// archive table too, or archiving the table fails until it does.
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Errors of archiving and restoring
+var (
+	ErrUnknownTable   = errors.New("table is not archived")
+	ErrNoPolicy       = errors.New("no archive policy for table")
+	ErrColumnMismatch = errors.New("archive table columns differ from the live table")
+)
+
+// Schema holds the archive tables
+const Schema = "archive"
+
+// Table is a live table whose old rows are archived. Its rows are keyed by
+// a UUID id column and belong to the tenant in their tenant_id column.
+type Table struct {
+	Name string // In the public schema; archived to archive.<Name>
+	// TimeColumn is compared with the retention cutoff, and partitions the
+	// archive table by month
+	TimeColumn string
+	// Where limits the rows archived further, such as to orders in a final
+	// status; empty archives every row past the cutoff
+	Where string
+	// Dependents reference the table's rows, and are archived and restored
+	// with the rows they reference
+	Dependents []Dependent
+}
+
+// Dependent is a table whose rows reference rows of an archived table
+type Dependent struct {
+	Name       string
+	ForeignKey string // Column holding the ID of the row referenced
+}
+
+// Month is how many rows of a table are archived for one month
+type Month struct {
+	Month time.Time `json:"month"`
+	Rows  int64     `json:"rows"`
+}
+
+// live returns the quoted name of a live table
+func live(name string) string {
+	return pgx.Identifier{"public", name}.Sanitize()
+}
+
+// archived returns the quoted name of an archive table
+func archived(name string) string {
+	return pgx.Identifier{Schema, name}.Sanitize()
+}
+
+// partition returns the quoted name of the partition of an archive table
+// holding a month, given as YYYY-MM, and the bounds of the month
+func partition(name, month string) (string, string, string, error) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return "", "", "", err
+	}
+	end := start.AddDate(0, 1, 0)
+	return pgx.Identifier{Schema, fmt.Sprintf("%s_p%s", name, start.Format("200601"))}.Sanitize(),
+		start.Format("2006-01-02"), end.Format("2006-01-02"), nil
+}
+
+// where returns the table's extra condition, or one always true
+func (t Table) where() string {
+	if t.Where == "" {
+		return "TRUE"
+	}
+	return t.Where
+}
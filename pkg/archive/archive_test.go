@@ -0,0 +1,52 @@
+package archive
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+func TestPartition(t *testing.T) {
+	name, from, to, err := partition("orders", "2024-12")
+	require.NoError(t, err)
+	assert.Equal(t, `"archive"."orders_p202412"`, name)
+	assert.Equal(t, "2024-12-01", from)
+	assert.Equal(t, "2025-01-01", to, "a month ends where the next begins")
+
+	_, _, _, err = partition("orders", "December")
+	assert.Error(t, err)
+}
+
+func TestNames(t *testing.T) {
+	assert.Equal(t, `"public"."stock_movements"`, live("stock_movements"))
+	assert.Equal(t, `"archive"."stock_movements"`, archived("stock_movements"))
+	assert.Equal(t, `"archive"."odd""name"`, archived(`odd"name`))
+
+	assert.Equal(t, "TRUE", Table{Name: "notifications"}.where())
+	assert.Equal(t, "status = 'done'", Table{Name: "jobs", Where: "status = 'done'"}.where())
+}
+
+func TestArchiveNeedsTableAndPolicy(t *testing.T) {
+	cfg := config.ArchiveConfig{
+		Interval:  time.Hour,
+		BatchSize: 100,
+		Tables:    map[string]config.ArchivePolicy{"orders": {Retention: time.Hour}},
+	}
+	// Neither call reaches the database
+	a := NewArchiver(nil, cfg, Table{Name: "notifications", TimeColumn: "created_at"}, Table{Name: "audit", TimeColumn: "created_at"})
+	assert.Equal(t, []string{"audit", "notifications"}, a.Tables())
+
+	_, err := a.Archive(context.Background(), "orders")
+	assert.ErrorIs(t, err, ErrUnknownTable)
+
+	_, err = a.Archive(context.Background(), "notifications")
+	assert.ErrorIs(t, err, ErrNoPolicy)
+
+	_, err = a.Restore(context.Background(), "orders", time.Time{}, time.Now(), "")
+	assert.ErrorIs(t, err, ErrUnknownTable)
+}
@@ -0,0 +1,343 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/database"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// DB is the database holding the live and archive tables, as *pgxpool.Pool
+type DB interface {
+	database.Querier
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Archiver archives and restores the tables of one service's database
+type Archiver struct {
+	db     DB
+	cfg    config.ArchiveConfig
+	tables map[string]Table
+}
+
+// NewArchiver creates an archiver for tables, archiving those with a policy
+// in cfg
+func NewArchiver(db DB, cfg config.ArchiveConfig, tables ...Table) *Archiver {
+	a := &Archiver{db: db, cfg: cfg, tables: make(map[string]Table, len(tables))}
+	for _, t := range tables {
+		a.tables[t.Name] = t
+	}
+	return a
+}
+
+// Tables returns the names of the tables the archiver knows, sorted
+func (a *Archiver) Tables() []string {
+	names := make([]string, 0, len(a.tables))
+	for name := range a.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// table returns a known table
+func (a *Archiver) table(name string) (Table, error) {
+	t, ok := a.tables[name]
+	if !ok {
+		return Table{}, fmt.Errorf("%w: %s", ErrUnknownTable, name)
+	}
+	return t, nil
+}
+
+// Archive moves the rows of a table past its retention to the archive, a
+// batch per transaction, and returns how many it moved, not counting
+// dependents
+func (a *Archiver) Archive(ctx context.Context, name string) (int64, error) {
+	t, err := a.table(name)
+	if err != nil {
+		return 0, err
+	}
+	policy, ok := a.cfg.Tables[name]
+	if !ok || policy.Retention <= 0 {
+		return 0, fmt.Errorf("%w: %s", ErrNoPolicy, name)
+	}
+
+	var total int64
+	for ctx.Err() == nil {
+		moved, err := a.archiveBatch(ctx, t, policy.Retention)
+		total += moved
+		if err != nil || moved < int64(a.cfg.BatchSize) {
+			return total, err
+		}
+	}
+	return total, ctx.Err()
+}
+
+// archiveBatch moves up to a batch of rows past the cutoff, creating the
+// partitions of the months they fall in
+func (a *Archiver) archiveBatch(ctx context.Context, t Table, retention time.Duration) (int64, error) {
+	var moved int64
+	err := a.inTx(ctx, t, func(tx pgx.Tx) error {
+		query := fmt.Sprintf(`
+			SELECT id, to_char(%[2]s, 'YYYY-MM') FROM %[1]s
+			WHERE %[2]s < NOW() - make_interval(secs => $1) AND (%[3]s)
+			ORDER BY %[2]s
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		`, live(t.Name), pgx.Identifier{t.TimeColumn}.Sanitize(), t.where())
+
+		rows, err := tx.Query(ctx, query, retention.Seconds(), a.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		var ids []uuid.UUID
+		months := map[string]bool{}
+		for rows.Next() {
+			var id uuid.UUID
+			var month string
+			if err := rows.Scan(&id, &month); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+			months[month] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil || len(ids) == 0 {
+			return err
+		}
+
+		for month := range months {
+			name, from, to, err := partition(t.Name, month)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, fmt.Sprintf(
+				`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')`,
+				name, archived(t.Name), from, to,
+			))
+			if err != nil {
+				return err
+			}
+		}
+
+		// Dependents go first, as removing the rows they reference may cascade to them
+		for _, d := range t.Dependents {
+			if _, err := move(ctx, tx, d.Name, live(d.Name), archived(d.Name), d.ForeignKey, ids); err != nil {
+				return err
+			}
+		}
+		moved, err = move(ctx, tx, t.Name, live(t.Name), archived(t.Name), "id", ids)
+		return err
+	})
+	return moved, err
+}
+
+// Restore moves the archived rows of a table whose time column falls in
+// [from, to) back to the live table, with their dependents, and returns
+// how many it restored. A tenant ID restores only that tenant's rows.
+func (a *Archiver) Restore(ctx context.Context, name string, from, to time.Time, tenantID string) (int64, error) {
+	t, err := a.table(name)
+	if err != nil {
+		return 0, err
+	}
+
+	var restored int64
+	err = a.inTx(ctx, t, func(tx pgx.Tx) error {
+		columns, err := columnList(ctx, tx, t.Name)
+		if err != nil {
+			return err
+		}
+
+		query := fmt.Sprintf(`
+			WITH restored AS (
+				DELETE FROM %[1]s
+				WHERE %[3]s >= $1 AND %[3]s < $2 AND ($3 = '' OR tenant_id = $3)
+				RETURNING %[4]s
+			)
+			INSERT INTO %[2]s (%[4]s) SELECT %[4]s FROM restored
+			RETURNING id
+		`, archived(t.Name), live(t.Name), pgx.Identifier{t.TimeColumn}.Sanitize(), columns)
+
+		rows, err := tx.Query(ctx, query, from, to, tenantID)
+		if err != nil {
+			return err
+		}
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		restored = int64(len(ids))
+
+		// Dependents go last, as they may reference the restored rows
+		for _, d := range t.Dependents {
+			if _, err := move(ctx, tx, d.Name, archived(d.Name), live(d.Name), d.ForeignKey, ids); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return restored, err
+}
+
+// Status returns how many rows of a table are archived per month, oldest
+// first
+func (a *Archiver) Status(ctx context.Context, name string) ([]Month, error) {
+	t, err := a.table(name)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(
+		`SELECT date_trunc('month', %s), COUNT(*) FROM %s GROUP BY 1 ORDER BY 1`,
+		pgx.Identifier{t.TimeColumn}.Sanitize(), archived(t.Name),
+	)
+	rows, err := a.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	months := []Month{}
+	for rows.Next() {
+		var m Month
+		if err := rows.Scan(&m.Month, &m.Rows); err != nil {
+			return nil, err
+		}
+		months = append(months, m)
+	}
+	return months, rows.Err()
+}
+
+// Run archives every table with a policy every cfg.Interval until ctx is
+// cancelled. An interval of zero disables it.
+func (a *Archiver) Run(ctx context.Context, log *logger.Logger) {
+	defer apperrors.Recover(ctx, "archive")
+
+	if a.cfg.Interval <= 0 {
+		return
+	}
+
+	pass := func() {
+		for _, name := range a.Tables() {
+			if _, ok := a.cfg.Tables[name]; !ok {
+				continue
+			}
+			moved, err := a.Archive(ctx, name)
+			if err != nil && ctx.Err() == nil {
+				log.Errorf("Failed to archive %s: %v", name, err)
+			}
+			if moved > 0 {
+				log.Infof("Archived %d %s rows", moved, name)
+			}
+		}
+	}
+
+	pass()
+
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pass()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// inTx runs fn in a transaction holding the table's archive lock, so
+// instances archiving or restoring a table take turns
+func (a *Archiver) inTx(ctx context.Context, t Table, fn func(tx pgx.Tx) error) error {
+	tx, err := a.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('archive.' || $1))`, t.Name); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// move moves the rows of a table whose column holds one of ids from one
+// schema's copy of the table to the other's
+func move(ctx context.Context, tx pgx.Tx, name, from, to, column string, ids []uuid.UUID) (int64, error) {
+	columns, err := columnList(ctx, tx, name)
+	if err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf(`
+		WITH moved AS (DELETE FROM %[1]s WHERE %[3]s = ANY($1) RETURNING %[4]s)
+		INSERT INTO %[2]s (%[4]s) SELECT %[4]s FROM moved
+	`, from, to, pgx.Identifier{column}.Sanitize(), columns)
+
+	tag, err := tx.Exec(ctx, query, ids)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// columnList returns the quoted columns of a table, checking its live and
+// archive tables have the same
+func columnList(ctx context.Context, q database.Querier, name string) (string, error) {
+	rows, err := q.Query(ctx, `
+		SELECT table_schema, column_name FROM information_schema.columns
+		WHERE table_name = $1 AND table_schema IN ('public', $2)
+		ORDER BY ordinal_position
+	`, name, Schema)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var liveColumns []string
+	archivedColumns := map[string]bool{}
+	for rows.Next() {
+		var schema, column string
+		if err := rows.Scan(&schema, &column); err != nil {
+			return "", err
+		}
+		if schema == Schema {
+			archivedColumns[column] = true
+		} else {
+			liveColumns = append(liveColumns, column)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	quoted := make([]string, len(liveColumns))
+	for i, column := range liveColumns {
+		if !archivedColumns[column] {
+			return "", fmt.Errorf("%w: %s.%s lacks %s", ErrColumnMismatch, Schema, name, column)
+		}
+		delete(archivedColumns, column)
+		quoted[i] = pgx.Identifier{column}.Sanitize()
+	}
+	for column := range archivedColumns {
+		return "", fmt.Errorf("%w: %s lacks %s", ErrColumnMismatch, name, column)
+	}
+	if len(quoted) == 0 {
+		return "", fmt.Errorf("%w: %s has no columns", ErrColumnMismatch, name)
+	}
+	return strings.Join(quoted, ", "), nil
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when an audit entry does not exist
+var ErrNotFound = errors.New("audit entry not found")
+
+// Result values recorded for an audited request
+const (
+	ResultSuccess = "success"
+	ResultDenied  = "denied"
+	ResultFailure = "failure"
+)
+
+// Entry is a single audited action
+type Entry struct {
+	ID          int64             `json:"id"`
+	OccurredAt  time.Time         `json:"occurred_at"`
+	ActorID     string            `json:"actor_id,omitempty"`
+	ActorRoles  []string          `json:"actor_roles,omitempty"`
+	Method      string            `json:"method"`
+	Route       string            `json:"route"` // Route template, e.g. /api/v1/orders/:id
+	Path        string            `json:"path"`
+	ResourceIDs map[string]string `json:"resource_ids,omitempty"`
+	Changes     []string          `json:"changes,omitempty"` // Top-level fields in the request body; values are never stored
+	IP          string            `json:"ip"`
+	UserAgent   string            `json:"user_agent,omitempty"`
+	RequestID   string            `json:"request_id,omitempty"`
+	Status      int               `json:"status"`
+	Result      string            `json:"result"`
+	DurationMs  int64             `json:"duration_ms"`
+}
+
+// Filter narrows an audit query; zero values match everything
+type Filter struct {
+	ActorID    string
+	Route      string
+	ResourceID string
+	Result     string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// resultFor classifies a response status
+func resultFor(status int) string {
+	switch {
+	case status == 401 || status == 403:
+		return ResultDenied
+	case status >= 400:
+		return ResultFailure
+	default:
+		return ResultSuccess
+	}
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// memoryStore collects appended entries
+type memoryStore struct {
+	mu      sync.Mutex
+	entries []*Entry
+}
+
+func (s *memoryStore) Append(_ context.Context, entries []*Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+func (s *memoryStore) Get(context.Context, int64) (*Entry, error) { return nil, ErrNotFound }
+
+func (s *memoryStore) Query(context.Context, Filter) ([]*Entry, error) { return s.entries, nil }
+
+func (s *memoryStore) Purge(context.Context, time.Time) (int64, error) { return 0, nil }
+
+func TestMiddlewareRecordsMutatingRequests(t *testing.T) {
+	store := &memoryStore{}
+	recorder := NewRecorder(store, 10, logger.New("test"))
+
+	app := fiber.New()
+	app.Use(Middleware(recorder))
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", "user-1")
+		c.Locals("roles", []string{"cashier"})
+		return c.Next()
+	})
+	app.Get("/orders/:id", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	app.Put("/orders/:id", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	app.Delete("/orders/:id", func(c *fiber.Ctx) error { return fiber.ErrForbidden })
+
+	req := httptest.NewRequest(fiber.MethodPut, "/orders/42", strings.NewReader(`{"status":"paid","card_number":"4111"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	_, err := app.Test(req)
+	require.NoError(t, err)
+
+	_, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/orders/42", nil))
+	require.NoError(t, err)
+
+	_, err = app.Test(httptest.NewRequest(fiber.MethodDelete, "/orders/42", nil))
+	require.NoError(t, err)
+
+	// Cancelling Run drains the buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	recorder.Run(ctx)
+
+	require.Len(t, store.entries, 2)
+
+	update := store.entries[0]
+	assert.Equal(t, "user-1", update.ActorID)
+	assert.Equal(t, []string{"cashier"}, update.ActorRoles)
+	assert.Equal(t, "/orders/:id", update.Route)
+	assert.Equal(t, map[string]string{"id": "42"}, update.ResourceIDs)
+	assert.Equal(t, []string{"card_number", "status"}, update.Changes)
+	assert.Equal(t, ResultSuccess, update.Result)
+
+	deleted := store.entries[1]
+	assert.Equal(t, fiber.StatusForbidden, deleted.Status)
+	assert.Equal(t, ResultDenied, deleted.Result)
+}
+
+func TestChangedFieldsIgnoresNonObjectBodies(t *testing.T) {
+	assert.Nil(t, changedFields(nil))
+	assert.Nil(t, changedFields([]byte(`[1, 2]`)))
+	assert.Nil(t, changedFields([]byte(`not json`)))
+}
+
+func TestResultFor(t *testing.T) {
+	assert.Equal(t, ResultSuccess, resultFor(fiber.StatusCreated))
+	assert.Equal(t, ResultDenied, resultFor(fiber.StatusUnauthorized))
+	assert.Equal(t, ResultFailure, resultFor(fiber.StatusConflict))
+	assert.Equal(t, ResultFailure, resultFor(fiber.StatusBadGateway))
+}
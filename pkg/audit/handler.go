@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/onichange/pos-system/pkg/audit/security"
+)
+
+// Handler serves the read-only audit query API
+type Handler struct {
+	store Store
+}
+
+// NewHandler creates an audit query handler
+func NewHandler(store Store) *Handler {
+	return &Handler{store: store}
+}
+
+// RegisterRoutes mounts the query endpoints on router
+func (h *Handler) RegisterRoutes(router fiber.Router) {
+	router.Get("/", h.ListEntries)
+	router.Get("/:id", h.GetEntry)
+}
+
+// ListEntries handles GET /audit?actor_id=&route=&resource_id=&result=&from=&to=&limit=&offset=
+// from and to are RFC 3339 timestamps.
+func (h *Handler) ListEntries(c *fiber.Ctx) error {
+	filter := Filter{
+		ActorID:    c.Query("actor_id"),
+		Route:      c.Query("route"),
+		ResourceID: c.Query("resource_id"),
+		Result:     c.Query("result"),
+		Limit:      c.QueryInt("limit", defaultQueryLimit),
+		Offset:     c.QueryInt("offset", 0),
+	}
+
+	var err error
+	if filter.From, err = parseTime(c.Query("from")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid from timestamp",
+		})
+	}
+	if filter.To, err = parseTime(c.Query("to")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid to timestamp",
+		})
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	entries, err := h.store.Query(c.UserContext(), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch audit entries",
+		})
+	}
+
+	// Reading the audit trail is itself a security-relevant export
+	security.RecordRequest(c, security.Event{
+		Type:    security.EventDataExport,
+		Outcome: security.OutcomeSuccess,
+		Details: map[string]string{
+			"dataset": "audit_log",
+			"query":   c.Request().URI().QueryArgs().String(),
+			"count":   strconv.Itoa(len(entries)),
+		},
+	})
+
+	return c.JSON(fiber.Map{
+		"data":   entries,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// GetEntry handles GET /audit/:id
+func (h *Handler) GetEntry(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid audit entry ID",
+		})
+	}
+
+	entry, err := h.store.Get(c.UserContext(), int64(id))
+	if errors.Is(err, ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Audit entry not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch audit entry",
+		})
+	}
+
+	return c.JSON(entry)
+}
+
+// parseTime parses an optional RFC 3339 timestamp
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+
+	"github.com/onichange/pos-system/pkg/middleware"
+)
+
+// Middleware records every mutating request (POST, PUT, PATCH, DELETE) once
+// the response is known. Mount it before authentication so denied requests
+// are audited too; the actor is read after the handler chain has run.
+func Middleware(recorder *Recorder) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !isMutating(c.Method()) {
+			return c.Next()
+		}
+
+		start := time.Now()
+		// Entries are written after the request completes, so request strings are copied
+		changes := changedFields(c.Body())
+		path := utils.CopyString(c.Path())
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+
+		entry := &Entry{
+			OccurredAt:  start.UTC(),
+			Method:      utils.CopyString(c.Method()),
+			Route:       c.Route().Path,
+			Path:        path,
+			ResourceIDs: routeParams(c),
+			Changes:     changes,
+			IP:          c.IP(),
+			UserAgent:   utils.CopyString(c.Get(fiber.HeaderUserAgent)),
+			RequestID:   middleware.GetRequestID(c),
+			Status:      status,
+			Result:      resultFor(status),
+			DurationMs:  time.Since(start).Milliseconds(),
+		}
+		if userID, ok := c.Locals("user_id").(string); ok {
+			entry.ActorID = userID
+		}
+		if roles, ok := c.Locals("roles").([]string); ok {
+			entry.ActorRoles = roles
+		}
+
+		recorder.Record(entry)
+		return err
+	}
+}
+
+// isMutating reports whether a method changes state
+func isMutating(method string) bool {
+	switch method {
+	case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// changedFields summarizes a JSON body as its sorted top-level field names.
+// Values are left out so credentials and card data never reach the audit log.
+func changedFields(body []byte) []string {
+	var fields map[string]json.RawMessage
+	if len(body) == 0 || json.Unmarshal(body, &fields) != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// routeParams returns the resource IDs captured by the matched route
+func routeParams(c *fiber.Ctx) map[string]string {
+	params := c.Route().Params
+	if len(params) == 0 {
+		return nil
+	}
+
+	ids := make(map[string]string, len(params))
+	for _, name := range params {
+		if value := c.Params(name); value != "" {
+			ids[name] = utils.CopyString(value)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+const (
+	// batchSize is the most entries written in one round trip
+	batchSize = 100
+
+	// flushInterval bounds how long an entry waits in the buffer
+	flushInterval = time.Second
+)
+
+// Recorder buffers entries and writes them to the store in batches, so
+// auditing adds no database round trip to the request path
+type Recorder struct {
+	store   Store
+	entries chan *Entry
+	logger  *logger.Logger
+}
+
+// NewRecorder creates a recorder holding up to bufferSize unwritten entries
+func NewRecorder(store Store, bufferSize int, log *logger.Logger) *Recorder {
+	return &Recorder{
+		store:   store,
+		entries: make(chan *Entry, bufferSize),
+		logger:  log,
+	}
+}
+
+// Record queues an entry. When the buffer is full the entry is logged and
+// dropped rather than blocking the request.
+func (r *Recorder) Record(entry *Entry) {
+	select {
+	case r.entries <- entry:
+	default:
+		r.logger.Errorf("Audit buffer full, dropping entry: %s %s by %q (status %d)",
+			entry.Method, entry.Path, entry.ActorID, entry.Status)
+	}
+}
+
+// Run writes queued entries until ctx is cancelled, then drains the buffer
+func (r *Recorder) Run(ctx context.Context) {
+	defer apperrors.Recover(ctx, "audit-recorder")
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Entry, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		// Writes outlive ctx so the final drain is not cancelled
+		writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if err := r.store.Append(writeCtx, batch); err != nil {
+			r.logger.Errorf("Failed to write %d audit entries: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case entry := <-r.entries:
+			batch = append(batch, entry)
+			if len(batch) >= batchSize {
+				flush()
+			}
+
+		case <-ticker.C:
+			flush()
+
+		case <-ctx.Done():
+			for {
+				select {
+				case entry := <-r.entries:
+					batch = append(batch, entry)
+					if len(batch) >= batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// RunRetention purges entries older than retention every interval until ctx
+// is cancelled. A retention of zero keeps entries forever.
+func RunRetention(ctx context.Context, store Store, retention, interval time.Duration, log *logger.Logger) {
+	defer apperrors.Recover(ctx, "audit-retention")
+
+	if retention <= 0 {
+		return
+	}
+
+	purge := func() {
+		purged, err := store.Purge(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Errorf("Failed to purge audit entries: %v", err)
+			return
+		}
+		if purged > 0 {
+			log.Infof("Purged %d audit entries older than %s", purged, retention)
+		}
+	}
+
+	purge()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			purge()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
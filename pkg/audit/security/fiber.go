@@ -0,0 +1,33 @@
+package security
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// RecordRequest records an event about the current request. The actor,
+// tenant, client address, user agent, and route are filled in when not set.
+func RecordRequest(c *fiber.Ctx, event Event) {
+	if event.ActorID == "" {
+		event.ActorID, _ = c.Locals("user_id").(string)
+	}
+	if event.TenantID == "" {
+		event.TenantID, _ = c.Locals("tenant_id").(string)
+	}
+	if event.IP == "" {
+		event.IP = c.IP()
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = c.Get(fiber.HeaderUserAgent)
+	}
+
+	details := make(map[string]string, len(event.Details)+2)
+	for key, value := range event.Details {
+		details[key] = value
+	}
+	details["method"] = c.Method()
+	details["route"] = c.Route().Path
+	event.Details = details
+
+	// Written before the handler returns, so request strings need no copying
+	Record(c.UserContext(), event)
+}
@@ -0,0 +1,113 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// writeEvents writes n login events and returns the log's lines
+func writeEvents(t *testing.T, l *Log, buf *bytes.Buffer, n int) []string {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		require.NoError(t, l.Write(context.Background(), Event{
+			Type:    EventLogin,
+			Outcome: OutcomeSuccess,
+			ActorID: "user-1",
+		}))
+	}
+	return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
+
+func TestWriteChainsEvents(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, "user-service", nil)
+
+	ctx := logger.ContextWithRequestID(context.Background(), "req-1")
+	require.NoError(t, l.Write(ctx, Event{Type: EventLogin, Outcome: OutcomeSuccess}))
+	lines := writeEvents(t, l, &buf, 2)
+
+	assert.Contains(t, lines[0], `"request_id":"req-1"`)
+	assert.Contains(t, lines[0], `"service":"user-service"`)
+
+	verified, err := Verify(strings.NewReader(buf.String()), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, verified)
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	var buf bytes.Buffer
+	lines := writeEvents(t, New(&buf, "user-service", nil), &buf, 3)
+
+	edited := append([]string{}, lines...)
+	edited[1] = strings.Replace(edited[1], `"actor_id":"user-1"`, `"actor_id":"user-2"`, 1)
+
+	tests := map[string][]string{
+		"edited":    edited,
+		"removed":   {lines[0], lines[2]},
+		"reordered": {lines[1], lines[0], lines[2]},
+		"truncated": {lines[1], lines[2]},
+	}
+	for name, tampered := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Verify(strings.NewReader(strings.Join(tampered, "\n")), nil)
+			assert.ErrorIs(t, err, ErrTampered)
+		})
+	}
+}
+
+func TestVerifyWithChainKey(t *testing.T) {
+	var buf bytes.Buffer
+	writeEvents(t, New(&buf, "user-service", []byte("chain-key")), &buf, 2)
+
+	_, err := Verify(strings.NewReader(buf.String()), []byte("chain-key"))
+	assert.NoError(t, err)
+
+	_, err = Verify(strings.NewReader(buf.String()), nil)
+	assert.ErrorIs(t, err, ErrTampered)
+}
+
+func TestVerifyInterleavedChains(t *testing.T) {
+	var first, second, combined bytes.Buffer
+	a := writeEvents(t, New(&first, "api-gateway", nil), &first, 2)
+	b := writeEvents(t, New(&second, "api-gateway", nil), &second, 2)
+
+	combined.WriteString(strings.Join([]string{a[0], b[0], b[1], a[1]}, "\n"))
+
+	verified, err := Verify(&combined, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, verified)
+}
+
+func TestFileSinkResumesChain(t *testing.T) {
+	cfg := config.SecurityAuditConfig{Sink: SinkFile, Path: filepath.Join(t.TempDir(), "audit", "security.log")}
+
+	for i := 0; i < 2; i++ {
+		l, err := Open("user-service", cfg)
+		require.NoError(t, err)
+		require.NoError(t, l.Write(context.Background(), Event{Type: EventLogin, Outcome: OutcomeSuccess}))
+		require.NoError(t, l.Close())
+	}
+
+	data, err := os.ReadFile(cfg.Path)
+	require.NoError(t, err)
+	verified, err := Verify(bytes.NewReader(data), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, verified)
+	assert.Contains(t, string(data), `"seq":2`)
+}
+
+func TestRecordWithoutInit(t *testing.T) {
+	require.NoError(t, Init("user-service", config.SecurityAuditConfig{Sink: SinkNone}))
+	Record(context.Background(), Event{Type: EventLogin, Outcome: OutcomeSuccess})
+	assert.NoError(t, Close())
+}
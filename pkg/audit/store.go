@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Store persists audit entries. Entries are never updated; they are only
+// appended and, once past retention, purged.
+type Store interface {
+	Append(ctx context.Context, entries []*Entry) error
+	Get(ctx context.Context, id int64) (*Entry, error)
+	Query(ctx context.Context, filter Filter) ([]*Entry, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+const (
+	// defaultQueryLimit is used when a filter does not set a limit
+	defaultQueryLimit = 100
+
+	// maxQueryLimit caps a single page of results
+	maxQueryLimit = 1000
+)
+
+// PostgresStore keeps entries in the append-only audit_log table
+type PostgresStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresStore creates a Postgres-backed audit store
+func NewPostgresStore(db *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const entryColumns = `id, occurred_at, actor_id, actor_roles, method, route, path, resource_ids, changes,
+		ip, user_agent, request_id, status, result, duration_ms`
+
+// Append writes a batch of entries
+func (s *PostgresStore) Append(ctx context.Context, entries []*Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	rows := make([][]interface{}, 0, len(entries))
+	for _, e := range entries {
+		rows = append(rows, []interface{}{
+			e.OccurredAt, e.ActorID, nonNil(e.ActorRoles), e.Method, e.Route, e.Path, resourceIDs(e.ResourceIDs),
+			nonNil(e.Changes), e.IP, e.UserAgent, e.RequestID, e.Status, e.Result, e.DurationMs,
+		})
+	}
+
+	_, err := s.db.CopyFrom(ctx,
+		pgx.Identifier{"audit_log"},
+		[]string{"occurred_at", "actor_id", "actor_roles", "method", "route", "path", "resource_ids", "changes",
+			"ip", "user_agent", "request_id", "status", "result", "duration_ms"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to append audit entries: %w", err)
+	}
+	return nil
+}
+
+// Get returns an entry by ID
+func (s *PostgresStore) Get(ctx context.Context, id int64) (*Entry, error) {
+	query := `SELECT ` + entryColumns + ` FROM audit_log WHERE id = $1`
+
+	entry, err := scanEntry(s.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return entry, err
+}
+
+// Query returns entries matching filter, newest first
+func (s *PostgresStore) Query(ctx context.Context, filter Filter) ([]*Entry, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ActorID != "" {
+		where("actor_id = $%d", filter.ActorID)
+	}
+	if filter.Route != "" {
+		where("route = $%d", filter.Route)
+	}
+	if filter.ResourceID != "" {
+		where("EXISTS (SELECT 1 FROM jsonb_each_text(resource_ids) r WHERE r.value = $%d)", filter.ResourceID)
+	}
+	if filter.Result != "" {
+		where("result = $%d", filter.Result)
+	}
+	if !filter.From.IsZero() {
+		where("occurred_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("occurred_at < $%d", filter.To)
+	}
+
+	query := `SELECT ` + entryColumns + ` FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+	if limit > maxQueryLimit {
+		limit = maxQueryLimit
+	}
+	args = append(args, limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY occurred_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*Entry
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// Purge deletes entries older than before. The table rejects deletes unless
+// audit.allow_purge is set for the transaction, so only retention can remove rows.
+func (s *PostgresStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SET LOCAL audit.allow_purge = 'on'`); err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx, `DELETE FROM audit_log WHERE occurred_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// scanEntry reads one audit_log row
+func scanEntry(row pgx.Row) (*Entry, error) {
+	var entry Entry
+	err := row.Scan(
+		&entry.ID, &entry.OccurredAt, &entry.ActorID, &entry.ActorRoles, &entry.Method, &entry.Route, &entry.Path,
+		&entry.ResourceIDs, &entry.Changes, &entry.IP, &entry.UserAgent, &entry.RequestID, &entry.Status,
+		&entry.Result, &entry.DurationMs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// nonNil stores empty arrays rather than NULL
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// resourceIDs stores an empty object rather than NULL
+func resourceIDs(ids map[string]string) map[string]string {
+	if ids == nil {
+		return map[string]string{}
+	}
+	return ids
+}
@@ -1,19 +1,26 @@
 package auth
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+// RoleService is the role of the tokens services call each other's internal
+// APIs with
+const RoleService = "service"
+
 // JWTClaims represents JWT claims
 type JWTClaims struct {
 	UserID   string   `json:"user_id"`
 	Email    string   `json:"email"`
 	Roles    []string `json:"roles"`
 	DeviceID string   `json:"device_id"`
+	TenantID string   `json:"tenant_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -31,6 +38,12 @@ type JWTManager struct {
 	accessExpiry  time.Duration
 	refreshExpiry time.Duration
 	issuer        string
+
+	// Secrets rotated out, still accepted until the tokens they signed expire
+	previousAccessSecrets  [][]byte
+	previousRefreshSecrets [][]byte
+
+	revocations *RevocationList // Nil accepts every valid refresh token
 }
 
 // NewJWTManager creates a new JWT manager
@@ -44,8 +57,29 @@ func NewJWTManager(accessSecret, refreshSecret string, accessExpiry, refreshExpi
 	}
 }
 
+// WithPreviousSecrets makes m also accept tokens signed with secrets that
+// were rotated out, so a rotation does not sign everyone out. Tokens are
+// always signed with the current secrets.
+func (m *JWTManager) WithPreviousSecrets(accessSecrets, refreshSecrets []string) *JWTManager {
+	m.previousAccessSecrets = secretBytes(accessSecrets)
+	m.previousRefreshSecrets = secretBytes(refreshSecrets)
+	return m
+}
+
+// WithRevocationList makes m deny refresh tokens revoked in l, such as on
+// logout or a password change
+func (m *JWTManager) WithRevocationList(l *RevocationList) *JWTManager {
+	m.revocations = l
+	return m
+}
+
 // GenerateTokenPair generates both access and refresh tokens
 func (m *JWTManager) GenerateTokenPair(userID, email string, roles []string, deviceID string) (*TokenPair, error) {
+	return m.GenerateTenantTokenPair("", userID, email, roles, deviceID)
+}
+
+// GenerateTenantTokenPair generates both tokens with a tenant_id claim, binding them to a tenant
+func (m *JWTManager) GenerateTenantTokenPair(tenantID, userID, email string, roles []string, deviceID string) (*TokenPair, error) {
 	now := time.Now()
 	accessExpiresAt := now.Add(m.accessExpiry)
 	refreshExpiresAt := now.Add(m.refreshExpiry)
@@ -56,6 +90,7 @@ func (m *JWTManager) GenerateTokenPair(userID, email string, roles []string, dev
 		Email:    email,
 		Roles:    roles,
 		DeviceID: deviceID,
+		TenantID: tenantID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(accessExpiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -77,6 +112,7 @@ func (m *JWTManager) GenerateTokenPair(userID, email string, roles []string, dev
 		Email:    email,
 		Roles:    roles,
 		DeviceID: deviceID,
+		TenantID: tenantID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(refreshExpiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -99,14 +135,66 @@ func (m *JWTManager) GenerateTokenPair(userID, email string, roles []string, dev
 	}, nil
 }
 
+// GenerateServiceToken generates an access token for a call between
+// services on behalf of a tenant, as verified by middleware.ServiceAuth. An
+// empty tenantID binds it to no tenant.
+func (m *JWTManager) GenerateServiceToken(tenantID string) (string, error) {
+	now := time.Now()
+	claims := &JWTClaims{
+		UserID:   RoleService,
+		Roles:    []string{RoleService},
+		TenantID: tenantID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.accessExpiry)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    m.issuer,
+			ID:        uuid.New().String(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.accessSecret)
+}
+
 // ValidateAccessToken validates an access token
 func (m *JWTManager) ValidateAccessToken(tokenString string) (*JWTClaims, error) {
-	return m.validateToken(tokenString, m.accessSecret)
+	return m.validateWithSecrets(tokenString, m.accessSecret, m.previousAccessSecrets)
 }
 
 // ValidateRefreshToken validates a refresh token
 func (m *JWTManager) ValidateRefreshToken(tokenString string) (*JWTClaims, error) {
-	return m.validateToken(tokenString, m.refreshSecret)
+	return m.ValidateRefreshTokenContext(context.Background(), tokenString)
+}
+
+// ValidateRefreshTokenContext validates a refresh token and, with a
+// revocation list, checks it was not revoked, failing with ErrTokenRevoked
+// if it was. When the list cannot be read the token is refused.
+func (m *JWTManager) ValidateRefreshTokenContext(ctx context.Context, tokenString string) (*JWTClaims, error) {
+	claims, err := m.validateWithSecrets(tokenString, m.refreshSecret, m.previousRefreshSecrets)
+	if err != nil || m.revocations == nil {
+		return claims, err
+	}
+
+	revoked, err := m.revocations.IsRevoked(ctx, claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// validateWithSecrets validates a JWT token against the current secret, then
+// against previous ones when its signature does not match
+func (m *JWTManager) validateWithSecrets(tokenString string, secret []byte, previous [][]byte) (*JWTClaims, error) {
+	claims, err := m.validateToken(tokenString, secret)
+	for _, p := range previous {
+		if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			break
+		}
+		claims, err = m.validateToken(tokenString, p)
+	}
+	return claims, err
 }
 
 // validateToken validates a JWT token
@@ -128,3 +216,14 @@ func (m *JWTManager) validateToken(tokenString string, secret []byte) (*JWTClaim
 
 	return nil, errors.New("invalid token")
 }
+
+// secretBytes converts secrets to keys, skipping empty ones
+func secretBytes(secrets []string) [][]byte {
+	keys := make([][]byte, 0, len(secrets))
+	for _, s := range secrets {
+		if s != "" {
+			keys = append(keys, []byte(s))
+		}
+	}
+	return keys
+}
@@ -89,3 +89,39 @@ func TestJWTManager_ValidateRefreshToken(t *testing.T) {
 	assert.Equal(t, userID, claims.UserID)
 }
 
+
+func TestJWTManager_AcceptsPreviousSecrets(t *testing.T) {
+	old := NewJWTManager(
+		"old-access-secret-key-minimum-32-characters-long",
+		"old-refresh-secret-key-minimum-32-characters-long",
+		15*time.Minute,
+		7*24*time.Hour,
+		"test-issuer",
+	)
+	tokenPair, err := old.GenerateTokenPair("user-123", "test@example.com", []string{"user"}, "")
+	require.NoError(t, err)
+
+	rotated := NewJWTManager(
+		"new-access-secret-key-minimum-32-characters-long",
+		"new-refresh-secret-key-minimum-32-characters-long",
+		15*time.Minute,
+		7*24*time.Hour,
+		"test-issuer",
+	)
+	_, err = rotated.ValidateAccessToken(tokenPair.AccessToken)
+	assert.Error(t, err)
+
+	rotated.WithPreviousSecrets(
+		[]string{"old-access-secret-key-minimum-32-characters-long"},
+		[]string{"old-refresh-secret-key-minimum-32-characters-long"},
+	)
+	claims, err := rotated.ValidateAccessToken(tokenPair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "user-123", claims.UserID)
+	_, err = rotated.ValidateRefreshToken(tokenPair.RefreshToken)
+	require.NoError(t, err)
+
+	// A previous access secret does not validate refresh tokens
+	_, err = rotated.ValidateRefreshToken(tokenPair.AccessToken)
+	assert.Error(t, err)
+}
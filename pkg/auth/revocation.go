@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/cache"
+)
+
+var (
+	// ErrTokenRevoked is returned for a refresh token that was revoked
+	// before it expired
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrRevocationDisabled is returned when revoking without a revocation
+	// list, such as when Redis is unreachable
+	ErrRevocationDisabled = errors.New("token revocation is not configured")
+)
+
+// RevocationList denies refresh tokens before they expire: one token by its
+// ID (jti) on logout, or every token of a user issued up to a time on a
+// password change. Entries live in the cache, normally Redis, so every
+// instance sees them, and expire when the tokens they deny would have.
+type RevocationList struct {
+	cache cache.Cache
+	ttl   time.Duration // The refresh token lifetime
+}
+
+// NewRevocationList creates a revocation list for refresh tokens that live
+// for ttl
+func NewRevocationList(cache cache.Cache, ttl time.Duration) *RevocationList {
+	return &RevocationList{
+		cache: cache,
+		ttl:   ttl,
+	}
+}
+
+// Revoke denies the token with the given ID
+func (l *RevocationList) Revoke(ctx context.Context, jti string) error {
+	if jti == "" {
+		return errors.New("token has no ID")
+	}
+	return l.cache.Set(ctx, revokedTokenKey(jti), "1", l.ttl)
+}
+
+// RevokeUser denies every token of a user issued at or before at. Tokens
+// carry their issue time in seconds, so ones issued within the same second
+// are denied too.
+func (l *RevocationList) RevokeUser(ctx context.Context, userID string, at time.Time) error {
+	if userID == "" {
+		return errors.New("token has no user")
+	}
+	return l.cache.Set(ctx, revokedUserKey(userID), strconv.FormatInt(at.Unix(), 10), l.ttl)
+}
+
+// IsRevoked reports whether a token's claims were revoked, by token ID or
+// for its user
+func (l *RevocationList) IsRevoked(ctx context.Context, claims *JWTClaims) (bool, error) {
+	if claims.ID != "" {
+		revoked, err := l.cache.Exists(ctx, revokedTokenKey(claims.ID))
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	if claims.UserID == "" {
+		return false, nil
+	}
+	key := revokedUserKey(claims.UserID)
+	exists, err := l.cache.Exists(ctx, key)
+	if err != nil || !exists {
+		return false, err
+	}
+	value, err := l.cache.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	revokedAt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid revocation time %q for user %s", value, claims.UserID)
+	}
+	if claims.IssuedAt == nil {
+		return true, nil
+	}
+	return claims.IssuedAt.Unix() <= revokedAt, nil
+}
+
+// revokedTokenKey is the cache key denying a token
+func revokedTokenKey(jti string) string {
+	return fmt.Sprintf("token:revoked:%s", jti)
+}
+
+// revokedUserKey is the cache key holding the time up to which a user's
+// tokens are denied
+func revokedUserKey(userID string) string {
+	return fmt.Sprintf("token:revoked-user:%s", userID)
+}
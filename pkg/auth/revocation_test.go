@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryCache is a cache.Cache kept in a map, ignoring TTLs
+type memoryCache struct {
+	mu     sync.Mutex
+	values map[string]string
+	err    error // Returned by every call when set
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{values: map[string]string{}}
+}
+
+func (c *memoryCache) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return "", c.err
+	}
+	value, ok := c.values[key]
+	if !ok {
+		return "", errors.New("cache miss")
+	}
+	return value, nil
+}
+
+func (c *memoryCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.values[key] = value.(string)
+	return nil
+}
+
+func (c *memoryCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, key)
+	return c.err
+}
+
+func (c *memoryCache) Exists(ctx context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.values[key]
+	return ok, c.err
+}
+
+func newRevokingManager(t *testing.T) (*JWTManager, *SessionManager, *memoryCache) {
+	t.Helper()
+	store := newMemoryCache()
+	revocations := NewRevocationList(store, 7*24*time.Hour)
+	manager := NewJWTManager(
+		"test-access-secret-key-minimum-32-characters-long",
+		"test-refresh-secret-key-minimum-32-characters-long",
+		15*time.Minute,
+		7*24*time.Hour,
+		"test-issuer",
+	).WithRevocationList(revocations)
+	sessions := NewSessionManager(store, 5, 7*24*time.Hour).WithRevocationList(revocations)
+	return manager, sessions, store
+}
+
+func TestJWTManager_ValidateRefreshToken_RevokedByID(t *testing.T) {
+	manager, sessions, _ := newRevokingManager(t)
+	ctx := context.Background()
+
+	first, err := manager.GenerateTokenPair("user-123", "test@example.com", []string{"user"}, "")
+	require.NoError(t, err)
+	second, err := manager.GenerateTokenPair("user-123", "test@example.com", []string{"user"}, "")
+	require.NoError(t, err)
+
+	claims, err := manager.ValidateRefreshTokenContext(ctx, first.RefreshToken)
+	require.NoError(t, err)
+	require.NoError(t, sessions.RevokeToken(ctx, claims.ID))
+
+	_, err = manager.ValidateRefreshTokenContext(ctx, first.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = manager.ValidateRefreshToken(first.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	// Other tokens of the user stay valid
+	_, err = manager.ValidateRefreshTokenContext(ctx, second.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestJWTManager_ValidateRefreshToken_RevokedForUser(t *testing.T) {
+	manager, sessions, _ := newRevokingManager(t)
+	ctx := context.Background()
+
+	tokens, err := manager.GenerateTokenPair("user-123", "test@example.com", []string{"user"}, "")
+	require.NoError(t, err)
+	other, err := manager.GenerateTokenPair("user-456", "other@example.com", []string{"user"}, "")
+	require.NoError(t, err)
+
+	require.NoError(t, sessions.RevokeUserTokens(ctx, "user-123"))
+
+	_, err = manager.ValidateRefreshTokenContext(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = manager.ValidateRefreshTokenContext(ctx, other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestRevocationList_IssuedAfterRevocation(t *testing.T) {
+	revocations := NewRevocationList(newMemoryCache(), time.Hour)
+	ctx := context.Background()
+
+	revokedAt := time.Now().Add(-time.Minute)
+	require.NoError(t, revocations.RevokeUser(ctx, "user-123", revokedAt))
+
+	manager := NewJWTManager(
+		"test-access-secret-key-minimum-32-characters-long",
+		"test-refresh-secret-key-minimum-32-characters-long",
+		15*time.Minute,
+		time.Hour,
+		"test-issuer",
+	)
+	tokens, err := manager.GenerateTokenPair("user-123", "test@example.com", []string{"user"}, "")
+	require.NoError(t, err)
+	claims, err := manager.ValidateRefreshToken(tokens.RefreshToken)
+	require.NoError(t, err)
+
+	revoked, err := revocations.IsRevoked(ctx, claims)
+	require.NoError(t, err)
+	assert.False(t, revoked, "tokens issued after the revocation stay valid")
+}
+
+func TestJWTManager_ValidateRefreshToken_RevocationUnreadable(t *testing.T) {
+	manager, _, store := newRevokingManager(t)
+
+	tokens, err := manager.GenerateTokenPair("user-123", "test@example.com", []string{"user"}, "")
+	require.NoError(t, err)
+
+	store.err = errors.New("connection refused")
+	_, err = manager.ValidateRefreshTokenContext(context.Background(), tokens.RefreshToken)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrTokenRevoked)
+}
+
+func TestSessionManager_RevokeToken_Disabled(t *testing.T) {
+	sessions := NewSessionManager(newMemoryCache(), 5, time.Hour)
+
+	err := sessions.RevokeToken(context.Background(), "token-id")
+	assert.ErrorIs(t, err, ErrRevocationDisabled)
+}
@@ -30,6 +30,7 @@ type SessionManager struct {
 	sessionDuration time.Duration
 	mu              sync.RWMutex
 	sessions        map[string][]string // userID -> sessionIDs
+	revocations     *RevocationList     // Nil when refresh tokens cannot be revoked
 }
 
 // NewSessionManager creates a new session manager
@@ -42,6 +43,13 @@ func NewSessionManager(cache cache.Cache, maxSessions int, sessionDuration time.
 	}
 }
 
+// WithRevocationList makes sm revoke refresh tokens in l, which the
+// JWTManager validating them should share
+func (sm *SessionManager) WithRevocationList(l *RevocationList) *SessionManager {
+	sm.revocations = l
+	return sm
+}
+
 // CreateSession creates a new session
 func (sm *SessionManager) CreateSession(ctx context.Context, userID, deviceID, ipAddress, userAgent string) (*Session, error) {
 	sessionID := uuid.New().String()
@@ -120,6 +128,27 @@ func (sm *SessionManager) InvalidateUserSessions(ctx context.Context, userID str
 	return nil
 }
 
+// RevokeToken revokes the refresh token with the given ID (jti), such as on
+// logout
+func (sm *SessionManager) RevokeToken(ctx context.Context, jti string) error {
+	if sm.revocations == nil {
+		return ErrRevocationDisabled
+	}
+	return sm.revocations.Revoke(ctx, jti)
+}
+
+// RevokeUserTokens revokes every refresh token issued to a user so far,
+// such as on a password change, and invalidates the user's sessions
+func (sm *SessionManager) RevokeUserTokens(ctx context.Context, userID string) error {
+	if sm.revocations == nil {
+		return ErrRevocationDisabled
+	}
+	if err := sm.revocations.RevokeUser(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+	return sm.InvalidateUserSessions(ctx, userID)
+}
+
 // DeviceFingerprint generates a device fingerprint
 func DeviceFingerprint(userAgent, ipAddress string) string {
 	// Simple fingerprint - in production, use more sophisticated method
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/onichange/pos-system/pkg/performance"
+)
+
+// BulkheadHook runs Redis commands and pipelines through a bulkhead, so a
+// slow Redis fails calls fast instead of holding every request
+type BulkheadHook struct {
+	Bulkhead *performance.Bulkhead
+}
+
+// DialHook leaves dialing alone; the pool bounds connections
+func (BulkheadHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook runs a command once a slot is free
+func (h BulkheadHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := h.Bulkhead.Do(ctx, func() error {
+			return next(ctx, cmd)
+		})
+		if err != nil && cmd.Err() == nil {
+			cmd.SetErr(err)
+		}
+		return err
+	}
+}
+
+// ProcessPipelineHook runs a pipeline once a slot is free; it takes one slot
+func (h BulkheadHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := h.Bulkhead.Do(ctx, func() error {
+			return next(ctx, cmds)
+		})
+		if err != nil {
+			for _, cmd := range cmds {
+				if cmd.Err() == nil {
+					cmd.SetErr(err)
+				}
+			}
+		}
+		return err
+	}
+}
+
+// UseBulkhead limits concurrent commands to bulkhead's size. A nil bulkhead
+// leaves the client unlimited.
+func (r *RedisCache) UseBulkhead(bulkhead *performance.Bulkhead) {
+	if bulkhead != nil {
+		r.client.AddHook(BulkheadHook{Bulkhead: bulkhead})
+	}
+}
+
+var _ redis.Hook = BulkheadHook{}
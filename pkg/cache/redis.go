@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
+
+	"github.com/onichange/pos-system/pkg/tracing"
 )
 
 // RedisCache implements cache interface using Redis
@@ -22,6 +24,8 @@ func NewRedisCache(host, port, password string, db int, poolSize, minIdleConns i
 		MinIdleConns: minIdleConns,
 	})
 
+	client.AddHook(tracing.RedisHook{})
+
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -48,6 +52,37 @@ func (r *RedisCache) Delete(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
 }
 
+// DeletePattern deletes the keys matching a glob pattern (e.g. "session:*"),
+// scanning in batches rather than blocking Redis with KEYS, and returns how
+// many were deleted
+func (r *RedisCache) DeletePattern(ctx context.Context, pattern string) (int64, error) {
+	var deleted int64
+	iter := r.client.Scan(ctx, 0, pattern, 500).Iterator()
+	batch := make([]string, 0, 500)
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == cap(batch) {
+			n, err := r.client.Unlink(ctx, batch...).Result()
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	if len(batch) > 0 {
+		n, err := r.client.Unlink(ctx, batch...).Result()
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
 // Exists checks if a key exists
 func (r *RedisCache) Exists(ctx context.Context, key string) (bool, error) {
 	count, err := r.client.Exists(ctx, key).Result()
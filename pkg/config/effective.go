@@ -0,0 +1,96 @@
+package config
+
+import (
+	"net/url"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maskedValue replaces secrets in printed configuration
+const maskedValue = "********"
+
+// Masked returns a copy of the configuration with secrets replaced, safe to print or serve
+func (c *Config) Masked() *Config {
+	masked := *c
+
+	masked.Database.Password = mask(c.Database.Password)
+	masked.Database.CredentialsFunc = nil
+	masked.Redis.Password = mask(c.Redis.Password)
+	masked.Search.Password = mask(c.Search.Password)
+	masked.JWT.AccessTokenSecret = mask(c.JWT.AccessTokenSecret)
+	masked.JWT.RefreshTokenSecret = mask(c.JWT.RefreshTokenSecret)
+	masked.JWT.PreviousAccessTokenSecrets = maskAll(c.JWT.PreviousAccessTokenSecrets)
+	masked.JWT.PreviousRefreshTokenSecrets = maskAll(c.JWT.PreviousRefreshTokenSecrets)
+	masked.Messaging.RabbitMQURL = maskURL(c.Messaging.RabbitMQURL)
+	masked.Remote.Token = mask(c.Remote.Token)
+	masked.Proxy.Discovery.ConsulToken = mask(c.Proxy.Discovery.ConsulToken)
+	masked.Secrets.VaultToken = mask(c.Secrets.VaultToken)
+	masked.Secrets.AWSSecretAccessKey = mask(c.Secrets.AWSSecretAccessKey)
+	masked.Secrets.AWSSessionToken = mask(c.Secrets.AWSSessionToken)
+	masked.Encryption.LocalKey = mask(c.Encryption.LocalKey)
+	masked.Encryption.GCPAccessToken = mask(c.Encryption.GCPAccessToken)
+	masked.Encryption.BlindIndexKey = mask(c.Encryption.BlindIndexKey)
+	masked.Encryption.PreviousKeys = nil
+	for _, key := range c.Encryption.PreviousKeys {
+		key.LocalKey = mask(key.LocalKey)
+		masked.Encryption.PreviousKeys = append(masked.Encryption.PreviousKeys, key)
+	}
+	masked.ErrorReporting.DSN = mask(c.ErrorReporting.DSN) // The DSN's user part is the project key
+	masked.Audit.Security.ChainKey = mask(c.Audit.Security.ChainKey)
+
+	masked.Signature.Partners = maskValues(c.Signature.Partners)
+	masked.Webhooks.Secrets = maskAll(c.Webhooks.Secrets)
+	masked.Email.Password = mask(c.Email.Password)
+	masked.SMS.Twilio.AuthToken = mask(c.SMS.Twilio.AuthToken)
+	masked.Payments.Stripe.SecretKey = mask(c.Payments.Stripe.SecretKey)
+	masked.Payments.Stripe.WebhookSecrets = maskAll(c.Payments.Stripe.WebhookSecrets)
+	masked.Tracing.Headers = maskValues(c.Tracing.Headers)
+
+	return &masked
+}
+
+// EffectiveYAML renders the masked configuration as YAML, in config file format
+func (c *Config) EffectiveYAML() ([]byte, error) {
+	return yaml.Marshal(c.Masked())
+}
+
+// mask hides a non-empty secret, keeping empty values visible so missing secrets stand out
+func mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	return maskedValue
+}
+
+// maskAll returns a copy of a list with every item masked
+func maskAll(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	masked := make([]string, len(values))
+	for i, value := range values {
+		masked[i] = mask(value)
+	}
+	return masked
+}
+
+// maskValues returns a copy of a map with every value masked
+func maskValues(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	masked := make(map[string]string, len(values))
+	for key, value := range values {
+		masked[key] = mask(value)
+	}
+	return masked
+}
+
+// maskURL hides the password part of a URL's user info
+func maskURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return mask(raw)
+	}
+	return u.Redacted()
+}
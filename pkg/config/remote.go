@@ -0,0 +1,272 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// remoteWaitTime bounds a Consul blocking query
+	remoteWaitTime = 55 * time.Second
+
+	// remotePollInterval is how often etcd is polled for changes
+	remotePollInterval = 5 * time.Second
+)
+
+// RemoteSource reads configuration keys from a key/value store. Watch returns the
+// keys under prefix once their index differs from the given one; an index of 0
+// returns immediately.
+type RemoteSource interface {
+	Watch(ctx context.Context, prefix string, index uint64) (map[string]string, uint64, error)
+}
+
+// NewRemoteSource creates the configured remote source, or nil if none is configured
+func NewRemoteSource(cfg RemoteConfig) RemoteSource {
+	client := &http.Client{Timeout: remoteWaitTime + 15*time.Second}
+	address := strings.TrimRight(cfg.Address, "/")
+
+	switch cfg.Backend {
+	case "consul":
+		return &ConsulSource{address: address, token: cfg.Token, client: client}
+	case "etcd":
+		return &EtcdSource{address: address, token: cfg.Token, client: client}
+	default:
+		return nil
+	}
+}
+
+// remotePrefixes returns the key prefixes for a service, most specific last
+func remotePrefixes(cfg RemoteConfig, service string) []string {
+	base := strings.Trim(cfg.Prefix, "/")
+	return []string{base + "/global/", base + "/" + service + "/"}
+}
+
+// applyRemote fetches the remote keys for a service and merges them into config
+func applyRemote(ctx context.Context, config *Config, service string) error {
+	source := NewRemoteSource(config.Remote)
+	if source == nil {
+		return nil
+	}
+
+	for _, prefix := range remotePrefixes(config.Remote, service) {
+		values, _, err := source.Watch(ctx, prefix, 0)
+		if err != nil {
+			return fmt.Errorf("failed to read remote config %s: %w", prefix, err)
+		}
+		if err := mergeRemote(config, prefix, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeRemote applies keys such as "<prefix>security/rate_limit_burst" = "20".
+// Values are YAML scalars or flow collections, e.g. "15s" or "[a, b]".
+func mergeRemote(config *Config, prefix string, values map[string]string) error {
+	tree := map[string]interface{}{}
+	for key, raw := range values {
+		path := strings.Split(strings.Trim(strings.TrimPrefix(key, prefix), "/"), "/")
+		// The remote backend settings themselves stay local
+		if path[0] == "" || path[0] == "remote" {
+			continue
+		}
+
+		var value interface{}
+		if err := yaml.Unmarshal([]byte(raw), &value); err != nil {
+			return fmt.Errorf("invalid remote config value for %s: %w", key, err)
+		}
+
+		node := tree
+		for _, segment := range path[:len(path)-1] {
+			child, ok := node[segment].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				node[segment] = child
+			}
+			node = child
+		}
+		node[path[len(path)-1]] = value
+	}
+
+	if len(tree) == 0 {
+		return nil
+	}
+
+	data, err := yaml.Marshal(tree)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil {
+		return fmt.Errorf("failed to apply remote config %s: %w", prefix, err)
+	}
+	return nil
+}
+
+// ConsulSource reads keys from the Consul KV store using blocking queries
+type ConsulSource struct {
+	address string
+	token   string
+	client  *http.Client
+}
+
+// Watch returns the keys under prefix, blocking until the Consul index moves past index
+func (c *ConsulSource) Watch(ctx context.Context, prefix string, index uint64) (map[string]string, uint64, error) {
+	url := fmt.Sprintf("%s/v1/kv/%s?recurse=true", c.address, prefix)
+	if index > 0 {
+		url += fmt.Sprintf("&index=%d&wait=%s", index, remoteWaitTime)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, index, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, index, fmt.Errorf("consul request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	newIndex, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	// A missing prefix is an empty configuration
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string]string{}, newIndex, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, index, fmt.Errorf("consul returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var entries []struct {
+		Key   string `json:"Key"`
+		Value []byte `json:"Value"` // base64 in JSON, decoded by encoding/json
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, index, fmt.Errorf("failed to decode consul response: %w", err)
+	}
+
+	values := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		// Folder keys end with a slash and carry no value
+		if !strings.HasSuffix(entry.Key, "/") {
+			values[entry.Key] = string(entry.Value)
+		}
+	}
+	return values, newIndex, nil
+}
+
+// EtcdSource reads keys from etcd through its v3 JSON gateway. etcd watches are
+// streaming, so changes are detected by polling and comparing a content hash.
+type EtcdSource struct {
+	address string
+	token   string
+	client  *http.Client
+}
+
+// Watch returns the keys under prefix once their content hash differs from index
+func (e *EtcdSource) Watch(ctx context.Context, prefix string, index uint64) (map[string]string, uint64, error) {
+	for {
+		values, err := e.rangePrefix(ctx, prefix)
+		if err != nil {
+			return nil, index, err
+		}
+
+		hash := hashValues(values)
+		if index == 0 || hash != index {
+			return values, hash, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, index, ctx.Err()
+		case <-time.After(remotePollInterval):
+		}
+	}
+}
+
+// rangePrefix reads all keys with the given prefix
+func (e *EtcdSource) rangePrefix(ctx context.Context, prefix string) (map[string]string, error) {
+	// range_end is the prefix with its last byte incremented
+	end := []byte(prefix)
+	end[len(end)-1]++
+
+	body, err := json.Marshal(map[string]string{
+		"key":       base64.StdEncoding.EncodeToString([]byte(prefix)),
+		"range_end": base64.StdEncoding.EncodeToString(end),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.address+"/v3/kv/range", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.token != "" {
+		req.Header.Set("Authorization", e.token)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("etcd request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("etcd returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var result struct {
+		KVs []struct {
+			Key   []byte `json:"key"`
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode etcd response: %w", err)
+	}
+
+	values := make(map[string]string, len(result.KVs))
+	for _, kv := range result.KVs {
+		values[string(kv.Key)] = string(kv.Value)
+	}
+	return values, nil
+}
+
+// hashValues returns a stable, non-zero hash of a key/value set
+func hashValues(values map[string]string) uint64 {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h := fnv.New64a()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s=%s\x00", key, values[key])
+	}
+	if sum := h.Sum64(); sum != 0 {
+		return sum
+	}
+	return 1
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = newValidator()
+
+// newValidator creates a validator that reports fields by their config file path
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		name := strings.SplitN(fld.Tag.Get("yaml"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	_ = v.RegisterValidation("url_list", isURLList)
+	return v
+}
+
+// isURLList accepts a comma-separated list of absolute http(s) URLs
+func isURLList(fl validator.FieldLevel) bool {
+	urls := SplitURLs(fl.Field().String())
+	if len(urls) == 0 {
+		return false
+	}
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidationErrors lists every problem found in a configuration
+type ValidationErrors []string
+
+func (e ValidationErrors) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e, "\n  - ")
+}
+
+// Validate checks the configuration against its struct tags and reports all
+// problems at once. Secrets from an external backend are checked once resolved.
+func (c *Config) Validate() error {
+	var problems ValidationErrors
+
+	if err := validate.Struct(c); err != nil {
+		var fieldErrors validator.ValidationErrors
+		if !errors.As(err, &fieldErrors) {
+			return err
+		}
+		for _, fe := range fieldErrors {
+			problems = append(problems, describeFieldError(fe))
+		}
+	}
+
+	if c.Secrets.Provider == "env" {
+		if err := c.ValidateSecrets(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// describeFieldError turns a validator error into a human-readable message
+func describeFieldError(fe validator.FieldError) string {
+	// Namespace is "Config.server.port"; drop the root type name
+	path := fe.Namespace()
+	if i := strings.Index(path, "."); i >= 0 {
+		path = path[i+1:]
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", path)
+	case "required_if":
+		return fmt.Sprintf("%s is required when %s", path, fe.Param())
+	case "numeric":
+		return fmt.Sprintf("%s must be numeric, got %q", path, fe.Value())
+	case "url":
+		return fmt.Sprintf("%s must be a valid URL, got %q", path, fe.Value())
+	case "url_list":
+		return fmt.Sprintf("%s must be one or more comma-separated http(s) URLs, got %q", path, fe.Value())
+	case "startswith":
+		return fmt.Sprintf("%s must start with %q, got %q", path, fe.Param(), fe.Value())
+	case "hostname_port":
+		return fmt.Sprintf("%s must be host:port, got %q", path, fe.Value())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s], got %q", path, fe.Param(), fe.Value())
+	case "min", "gt":
+		return fmt.Sprintf("%s must be greater than %s, got %v", path, minBound(fe), fe.Value())
+	case "ltefield":
+		return fmt.Sprintf("%s must not exceed %s", path, fe.Param())
+	case "gtefield":
+		return fmt.Sprintf("%s must be at least %s", path, fe.Param())
+	case "gtfield":
+		return fmt.Sprintf("%s must be greater than %s", path, fe.Param())
+	default:
+		return fmt.Sprintf("%s failed %s validation", path, fe.Tag())
+	}
+}
+
+// minBound formats the lower bound of a min/gt rule
+func minBound(fe validator.FieldError) string {
+	if fe.Tag() == "min" {
+		return "or equal to " + fe.Param()
+	}
+	return fe.Param()
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ChangeFunc is called with the previous and the new configuration after a reload
+type ChangeFunc func(old, new *Config)
+
+// Watcher reloads configuration when config files change or on SIGHUP and
+// publishes immutable snapshots to subscribers. Snapshots must not be modified.
+type Watcher struct {
+	current     atomic.Pointer[Config]
+	mu          sync.Mutex
+	subscribers []ChangeFunc
+	fingerprint string
+	onError     func(error)
+}
+
+// NewWatcher creates a watcher seeded with the configuration loaded at startup
+func NewWatcher(initial *Config) *Watcher {
+	w := &Watcher{onError: func(error) {}}
+	w.current.Store(initial)
+	w.fingerprint = filesFingerprint(initial.Server.Environment)
+	return w
+}
+
+// Current returns the latest configuration snapshot
+func (w *Watcher) Current() *Config {
+	return w.current.Load()
+}
+
+// Subscribe registers a callback invoked after every effective configuration change
+func (w *Watcher) Subscribe(fn ChangeFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// OnError sets the function receiving reload errors (e.g. a logger)
+func (w *Watcher) OnError(fn func(error)) {
+	w.onError = fn
+}
+
+// Reload loads configuration again and notifies subscribers if it changed.
+// An invalid configuration is rejected and the current snapshot is kept.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	old := w.current.Load()
+	var next *Config
+	var err error
+	if old.ServiceName != "" {
+		next, err = LoadService(old.ServiceName)
+	} else {
+		next, err = Load()
+	}
+	if err != nil {
+		return fmt.Errorf("config reload rejected: %w", err)
+	}
+	carrySecrets(old, next)
+	w.fingerprint = filesFingerprint(next.Server.Environment)
+
+	if equalConfig(old, next) {
+		return nil
+	}
+
+	w.current.Store(next)
+	for _, fn := range w.subscribers {
+		fn(old, next)
+	}
+	return nil
+}
+
+// Run polls config files every ConfigReloadInterval, watches the remote backend,
+// and reloads on SIGHUP until ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	w.watchRemote(ctx)
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var tick <-chan time.Time
+	if interval := w.Current().Server.ConfigReloadInterval; interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			if err := w.Reload(); err != nil {
+				w.onError(err)
+			}
+		case <-tick:
+			w.mu.Lock()
+			changed := filesFingerprint(w.current.Load().Server.Environment) != w.fingerprint
+			w.mu.Unlock()
+			if changed {
+				if err := w.Reload(); err != nil {
+					w.onError(err)
+				}
+			}
+		}
+	}
+}
+
+// carrySecrets keeps values resolved from a secrets backend, which Load does not fetch
+func carrySecrets(old, next *Config) {
+	next.Database.CredentialsFunc = old.Database.CredentialsFunc
+	if next.Secrets.Provider != "env" {
+		next.JWT.AccessTokenSecret = old.JWT.AccessTokenSecret
+		next.JWT.RefreshTokenSecret = old.JWT.RefreshTokenSecret
+		next.Database.User = old.Database.User
+		next.Database.Password = old.Database.Password
+	}
+}
+
+// equalConfig compares two configurations, ignoring function fields
+func equalConfig(a, b *Config) bool {
+	left, right := *a, *b
+	left.Database.CredentialsFunc = nil
+	right.Database.CredentialsFunc = nil
+	return reflect.DeepEqual(left, right)
+}
+
+// filesFingerprint summarizes the presence and modification time of config files
+func filesFingerprint(environment string) string {
+	var b strings.Builder
+	for _, path := range configFileCandidates(environment) {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&b, "%s@%d/%d;", path, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return b.String()
+}
+
+// watchRemote starts one watch per remote prefix, reloading whenever keys change
+func (w *Watcher) watchRemote(ctx context.Context) {
+	cfg := w.Current()
+	source := NewRemoteSource(cfg.Remote)
+	if source == nil || cfg.ServiceName == "" {
+		return
+	}
+
+	for _, prefix := range remotePrefixes(cfg.Remote, cfg.ServiceName) {
+		go func() {
+			var index uint64
+			for ctx.Err() == nil {
+				_, next, err := source.Watch(ctx, prefix, index)
+				if err != nil {
+					if ctx.Err() == nil {
+						w.onError(err)
+						// Back off before retrying an unreachable backend
+						select {
+						case <-ctx.Done():
+						case <-time.After(remotePollInterval):
+						}
+					}
+					continue
+				}
+
+				// The first response only establishes the index
+				if index != 0 && next != index {
+					if err := w.Reload(); err != nil {
+						w.onError(err)
+					}
+				}
+				index = next
+			}
+		}()
+	}
+}
@@ -0,0 +1,240 @@
+// Package csvexport streams records as a CSV attachment, writing each row to
+// the response as it is produced so an export never holds more than a row in
+// memory. Callers pick the columns, whether the file is laid out for Excel,
+// and whether it is sent gzipped, from the request's query.
+//
+// A CSV file has no trailer to tell a partial export from a complete one, so
+// a stream that fails part way drops the connection instead of ending the
+// response: clients see a broken download rather than a short file.
+package csvexport
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// ContentType is the media type of a CSV export
+const ContentType = "text/csv; charset=utf-8"
+
+// Formats an export may be requested in
+const (
+	FormatCSV   = "csv"   // RFC 4180 with LF line endings
+	FormatExcel = "excel" // CSV with a byte order mark and CRLF line endings, which Excel opens as UTF-8
+)
+
+// DateLayout is the layout of the dates bounding an export's period
+const DateLayout = "2006-01-02"
+
+// Errors of reading an export request
+var (
+	ErrFormat = errors.New("format must be csv or excel")
+	ErrColumn = errors.New("unknown column")
+	ErrPeriod = errors.New("from and to must be YYYY-MM-DD dates, to not before from")
+)
+
+// byteOrderMark tells Excel a CSV file is UTF-8
+const byteOrderMark = "\ufeff"
+
+// Column is a column of an export: its header and how a record fills it
+type Column[T any] struct {
+	Name  string
+	Value func(T) string
+}
+
+// Export is a requested CSV export of records of type T
+type Export[T any] struct {
+	Columns []Column[T]
+	Excel   bool // Laid out for Excel
+	Gzip    bool // Sent as a gzipped file
+}
+
+// FromQuery reads an export of records of type T from the request's query:
+// format=csv|excel, columns as a comma separated subset of all in the order
+// wanted, all of them when absent, and gzip=true to send the file gzipped
+func FromQuery[T any](c *fiber.Ctx, all []Column[T]) (*Export[T], error) {
+	e := &Export[T]{Gzip: c.QueryBool("gzip")}
+
+	switch c.Query("format", FormatCSV) {
+	case FormatCSV:
+	case FormatExcel:
+		e.Excel = true
+	default:
+		return nil, ErrFormat
+	}
+
+	columns, err := Select(all, c.Query("columns"))
+	if err != nil {
+		return nil, err
+	}
+	e.Columns = columns
+	return e, nil
+}
+
+// Period reads the period of an export from the request's from and to
+// query dates, both included, as the start of from up to the end of to in UTC
+func Period(c *fiber.Ctx) (from, to time.Time, err error) {
+	from, err = time.Parse(DateLayout, c.Query("from"))
+	if err != nil {
+		return from, to, ErrPeriod
+	}
+	to, err = time.Parse(DateLayout, c.Query("to"))
+	if err != nil || to.Before(from) {
+		return from, to, ErrPeriod
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// Select picks the columns named in a comma separated list out of all, in
+// the order listed. An empty list selects every column.
+func Select[T any](all []Column[T], names string) ([]Column[T], error) {
+	if strings.TrimSpace(names) == "" {
+		return all, nil
+	}
+
+	byName := make(map[string]Column[T], len(all))
+	for _, col := range all {
+		byName[col.Name] = col
+	}
+
+	var selected []Column[T]
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		col, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrColumn, name)
+		}
+		seen[name] = true
+		selected = append(selected, col)
+	}
+	return selected, nil
+}
+
+// Stream sends the records produce emits as a CSV attachment named after
+// name, one row per record as it is emitted. produce runs after the handler
+// returns, with the request's user context; its error drops the connection.
+func (e *Export[T]) Stream(c *fiber.Ctx, name string, produce func(ctx context.Context, emit func(T) error) error) error {
+	ctx := c.UserContext()
+	log := logger.FromContext(ctx)
+
+	filename := name + ".csv"
+	if e.Gzip {
+		// A gzipped file, not a gzip content encoding: clients save it as is
+		filename += ".gz"
+		c.Set(fiber.HeaderContentType, "application/gzip")
+	} else {
+		c.Set(fiber.HeaderContentType, ContentType)
+	}
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+
+	// The server's write timeout bounds a whole response; an export is
+	// bounded by the client staying connected instead
+	conn := c.Context().Conn()
+	c.Context().SetBodyStreamWriter(func(bw *bufio.Writer) {
+		_ = conn.SetWriteDeadline(time.Time{})
+
+		count, err := e.write(ctx, bw, produce)
+		if err != nil {
+			log.Errorf("CSV export failed after %d rows: %v", count, err)
+			_ = conn.Close()
+			return
+		}
+		_ = bw.Flush()
+	})
+	return nil
+}
+
+// write writes the header and a row per record produce emits to out, and
+// returns how many rows it wrote
+func (e *Export[T]) write(ctx context.Context, out io.Writer, produce func(ctx context.Context, emit func(T) error) error) (int, error) {
+	var zw *gzip.Writer
+	if e.Gzip {
+		zw = gzip.NewWriter(out)
+		out = zw
+	}
+	if e.Excel {
+		if _, err := io.WriteString(out, byteOrderMark); err != nil {
+			return 0, err
+		}
+	}
+
+	w := csv.NewWriter(out)
+	w.UseCRLF = e.Excel
+
+	record := make([]string, len(e.Columns))
+	for i, col := range e.Columns {
+		record[i] = col.Name
+	}
+	if err := w.Write(record); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err := produce(ctx, func(v T) error {
+		for i, col := range e.Columns {
+			record[i] = col.Value(v)
+			if e.Excel {
+				record[i] = defuseFormula(record[i])
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return count, err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// defuseFormula keeps Excel from evaluating a cell as a formula, quoting
+// text that starts like one. Numbers, negative ones included, are left be.
+func defuseFormula(cell string) string {
+	if cell == "" || !strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return cell
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		return cell
+	}
+	return "'" + cell
+}
+
+// Time formats an optional time for a cell: RFC 3339 in UTC, or empty
+func Time(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Money formats an amount for a cell with two decimals
+func Money(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
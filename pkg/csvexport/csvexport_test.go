@@ -0,0 +1,166 @@
+package csvexport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type row struct {
+	ID   string
+	Note string
+}
+
+var columns = []Column[row]{
+	{Name: "id", Value: func(r row) string { return r.ID }},
+	{Name: "note", Value: func(r row) string { return r.Note }},
+}
+
+var rows = []row{{ID: "a", Note: "plain"}, {ID: "b", Note: "=SUM(A1)"}, {ID: "c", Note: "-5.5"}}
+
+func produceRows(ctx context.Context, emit func(row) error) error {
+	for _, r := range rows {
+		if err := emit(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func serve(t *testing.T, query string) (*response, error) {
+	app := fiber.New()
+	app.Get("/export", func(c *fiber.Ctx) error {
+		export, err := FromQuery(c, columns)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		return export.Stream(c, "rows", produceRows)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/export"+query, nil), -1)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return &response{
+		status:      resp.StatusCode,
+		contentType: resp.Header.Get(fiber.HeaderContentType),
+		disposition: resp.Header.Get(fiber.HeaderContentDisposition),
+		body:        body,
+	}, err
+}
+
+type response struct {
+	status      int
+	contentType string
+	disposition string
+	body        []byte
+}
+
+func TestStreamWritesAllColumns(t *testing.T) {
+	resp, err := serve(t, "")
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.status)
+	assert.Equal(t, ContentType, resp.contentType)
+	assert.Equal(t, `attachment; filename="rows.csv"`, resp.disposition)
+	assert.Equal(t, "id,note\na,plain\nb,=SUM(A1)\nc,-5.5\n", string(resp.body))
+}
+
+func TestStreamSelectsColumns(t *testing.T) {
+	resp, err := serve(t, "?columns=note,id")
+	require.NoError(t, err)
+	assert.Equal(t, "note,id\nplain,a\n=SUM(A1),b\n-5.5,c\n", string(resp.body))
+
+	resp, err = serve(t, "?columns=id,total")
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.status)
+	assert.Contains(t, string(resp.body), "total")
+}
+
+func TestStreamForExcel(t *testing.T) {
+	resp, err := serve(t, "?format=excel&columns=note")
+	require.NoError(t, err)
+	assert.Equal(t, "\ufeffnote\r\nplain\r\n'=SUM(A1)\r\n-5.5\r\n", string(resp.body))
+
+	resp, err = serve(t, "?format=xlsx")
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.status)
+}
+
+func TestStreamGzipped(t *testing.T) {
+	resp, err := serve(t, "?gzip=true&columns=id")
+	require.NoError(t, err)
+	assert.Equal(t, "application/gzip", resp.contentType)
+	assert.Equal(t, `attachment; filename="rows.csv.gz"`, resp.disposition)
+
+	zr, err := gzip.NewReader(bytes.NewReader(resp.body))
+	require.NoError(t, err)
+	body, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, "id\na\nb\nc\n", string(body))
+}
+
+func TestWriteStopsOnFailure(t *testing.T) {
+	export := &Export[row]{Columns: columns, Gzip: true}
+	failure := errors.New("query failed")
+
+	var out bytes.Buffer
+	n, err := export.write(context.Background(), &out, func(ctx context.Context, emit func(row) error) error {
+		if err := emit(rows[0]); err != nil {
+			return err
+		}
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, 1, n)
+
+	// The gzip stream is left without its footer, so it does not read as whole
+	zr, err := gzip.NewReader(&out)
+	if err == nil {
+		_, err = io.ReadAll(zr)
+	}
+	assert.Error(t, err)
+}
+
+func TestDefuseFormula(t *testing.T) {
+	assert.Equal(t, "'=1+1", defuseFormula("=1+1"))
+	assert.Equal(t, "'@cmd", defuseFormula("@cmd"))
+	assert.Equal(t, "'-x", defuseFormula("-x"))
+	assert.Equal(t, "-12.50", defuseFormula("-12.50"))
+	assert.Equal(t, "plain", defuseFormula("plain"))
+	assert.Equal(t, "", defuseFormula(""))
+}
+
+func TestPeriod(t *testing.T) {
+	period := func(query string) (string, error) {
+		app := fiber.New()
+		var got string
+		var perr error
+		app.Get("/", func(c *fiber.Ctx) error {
+			from, to, err := Period(c)
+			got, perr = from.Format(time.RFC3339)+" "+to.Format(time.RFC3339), err
+			return nil
+		})
+		_, err := app.Test(httptest.NewRequest("GET", "/"+query, nil))
+		require.NoError(t, err)
+		return got, perr
+	}
+
+	got, err := period("?from=2024-03-01&to=2024-03-31")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-01T00:00:00Z 2024-04-01T00:00:00Z", got)
+
+	_, err = period("?from=2024-03-01")
+	assert.ErrorIs(t, err, ErrPeriod)
+	_, err = period("?from=2024-03-02&to=2024-03-01")
+	assert.ErrorIs(t, err, ErrPeriod)
+}
@@ -0,0 +1,21 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ExecBatch sends the statements of batch on q in one round trip and reads
+// each result, returning the first failure. Outside a transaction the batch
+// runs as one implicit transaction, so a failure leaves none of it applied.
+func ExecBatch(ctx context.Context, q Querier, batch *pgx.Batch) error {
+	results := q.SendBatch(ctx, batch)
+	for i := 0; i < batch.Len(); i++ {
+		if _, err := results.Exec(); err != nil {
+			results.Close()
+			return err
+		}
+	}
+	return results.Close()
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// batchQuerier answers batches with results
+type batchQuerier struct {
+	fakeQuerier
+	results *fakeBatchResults
+}
+
+func (q *batchQuerier) SendBatch(context.Context, *pgx.Batch) pgx.BatchResults {
+	return q.results
+}
+
+func TestExecBatchReadsEveryResult(t *testing.T) {
+	batch := &pgx.Batch{}
+	for i := 0; i < 3; i++ {
+		batch.Queue("INSERT INTO t (n) VALUES ($1)", i)
+	}
+
+	q := &batchQuerier{results: &fakeBatchResults{}}
+	assert.NoError(t, ExecBatch(context.Background(), q, batch))
+	assert.Equal(t, 3, q.results.execs)
+	assert.True(t, q.results.closed)
+
+	failed := errors.New("duplicate key")
+	q = &batchQuerier{results: &fakeBatchResults{failAt: 2, err: failed}}
+	assert.ErrorIs(t, ExecBatch(context.Background(), q, batch), failed)
+	assert.Equal(t, 2, q.results.execs, "stops at the first failure")
+	assert.True(t, q.results.closed)
+}
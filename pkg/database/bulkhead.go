@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/onichange/pos-system/pkg/performance"
+)
+
+// Querier is the part of a connection pool that repositories use. Both
+// *pgxpool.Pool and the result of WithBulkhead implement it.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// WithBulkhead runs queries on q through bulkhead, which holds a slot until a
+// query's rows or row have been read. A nil bulkhead returns q unchanged.
+func WithBulkhead(q Querier, bulkhead *performance.Bulkhead) Querier {
+	if bulkhead == nil {
+		return q
+	}
+	return &bulkheadQuerier{q: q, bulkhead: bulkhead}
+}
+
+// bulkheadQuerier limits concurrent queries
+type bulkheadQuerier struct {
+	q        Querier
+	bulkhead *performance.Bulkhead
+}
+
+// Exec runs a statement once a slot is free
+func (b *bulkheadQuerier) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	release, err := b.bulkhead.Acquire(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer release()
+	return b.q.Exec(ctx, sql, args...)
+}
+
+// Query runs a query once a slot is free; the slot is freed when the rows are
+// closed or read to the end
+func (b *bulkheadQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	release, err := b.bulkhead.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := b.q.Query(ctx, sql, args...)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &bulkheadRows{Rows: rows, release: sync.OnceFunc(release)}, nil
+}
+
+// QueryRow runs a single-row query once a slot is free; the slot is freed
+// when the row is scanned
+func (b *bulkheadQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	release, err := b.bulkhead.Acquire(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return &bulkheadRow{row: b.q.QueryRow(ctx, sql, args...), release: release}
+}
+
+// SendBatch sends a batch once a slot is free; the slot is freed when the
+// results are closed
+func (b *bulkheadQuerier) SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults {
+	release, err := b.bulkhead.Acquire(ctx)
+	if err != nil {
+		return errBatchResults{err: err}
+	}
+	return &bulkheadBatchResults{BatchResults: b.q.SendBatch(ctx, batch), release: sync.OnceFunc(release)}
+}
+
+// bulkheadRows frees its slot once the rows are done
+type bulkheadRows struct {
+	pgx.Rows
+	release func()
+}
+
+// Next advances to the next row, freeing the slot after the last
+func (r *bulkheadRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.release()
+	return false
+}
+
+// Close closes the rows and frees the slot
+func (r *bulkheadRows) Close() {
+	r.Rows.Close()
+	r.release()
+}
+
+// bulkheadRow frees its slot once scanned
+type bulkheadRow struct {
+	row     pgx.Row
+	release func()
+}
+
+// Scan reads the row and frees the slot
+func (r *bulkheadRow) Scan(dest ...any) error {
+	defer r.release()
+	return r.row.Scan(dest...)
+}
+
+// bulkheadBatchResults frees its slot once closed
+type bulkheadBatchResults struct {
+	pgx.BatchResults
+	release func()
+}
+
+// Close reads the remaining results and frees the slot
+func (r *bulkheadBatchResults) Close() error {
+	defer r.release()
+	return r.BatchResults.Close()
+}
+
+// errRow is a row that could not be queried
+type errRow struct {
+	err error
+}
+
+// Scan returns the query error
+func (r errRow) Scan(...any) error {
+	return r.err
+}
+
+// errBatchResults is a batch that could not be sent
+type errBatchResults struct {
+	err error
+}
+
+// Exec returns the send error
+func (r errBatchResults) Exec() (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, r.err
+}
+
+// Query returns the send error
+func (r errBatchResults) Query() (pgx.Rows, error) {
+	return nil, r.err
+}
+
+// QueryRow returns a row failing with the send error
+func (r errBatchResults) QueryRow() pgx.Row {
+	return errRow{err: r.err}
+}
+
+// Close returns the send error
+func (r errBatchResults) Close() error {
+	return r.err
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/performance"
+)
+
+// fakeQuerier returns rows with a fixed number of results
+type fakeQuerier struct{}
+
+func (fakeQuerier) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (fakeQuerier) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return &fakeRows{remaining: 2}, nil
+}
+
+func (fakeQuerier) QueryRow(context.Context, string, ...any) pgx.Row {
+	return &fakeRows{remaining: 1}
+}
+
+func (fakeQuerier) SendBatch(context.Context, *pgx.Batch) pgx.BatchResults {
+	return &fakeBatchResults{}
+}
+
+// fakeBatchResults fails its Exec number failAt with err, when set
+type fakeBatchResults struct {
+	pgx.BatchResults
+	execs  int
+	failAt int
+	err    error
+	closed bool
+}
+
+func (r *fakeBatchResults) Exec() (pgconn.CommandTag, error) {
+	r.execs++
+	if r.execs == r.failAt {
+		return pgconn.CommandTag{}, r.err
+	}
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (r *fakeBatchResults) Close() error {
+	r.closed = true
+	return nil
+}
+
+type fakeRows struct {
+	pgx.Rows
+	remaining int
+}
+
+func (r *fakeRows) Next() bool {
+	r.remaining--
+	return r.remaining >= 0
+}
+
+func (r *fakeRows) Close() {}
+
+func (r *fakeRows) Scan(...any) error { return nil }
+
+func TestWithBulkheadHoldsSlotUntilRowsAreRead(t *testing.T) {
+	bulkhead := performance.NewDependencyBulkhead("test", config.BulkheadConfig{MaxConcurrent: 1})
+	q := WithBulkhead(fakeQuerier{}, bulkhead)
+	ctx := context.Background()
+
+	rows, err := q.Query(ctx, "SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, bulkhead.CurrentConcurrency())
+
+	_, err = q.Exec(ctx, "UPDATE t SET x = 1")
+	assert.ErrorIs(t, err, performance.ErrBulkheadFull)
+	assert.ErrorIs(t, q.QueryRow(ctx, "SELECT 1").Scan(), performance.ErrBulkheadFull)
+
+	for rows.Next() {
+	}
+	rows.Close()
+	assert.Equal(t, 0, bulkhead.CurrentConcurrency(), "freed once, after the last row")
+
+	require.NoError(t, q.QueryRow(ctx, "SELECT 1").Scan())
+	assert.Equal(t, 0, bulkhead.CurrentConcurrency())
+
+	results := q.SendBatch(ctx, &pgx.Batch{})
+	assert.Equal(t, 1, bulkhead.CurrentConcurrency())
+	_, err = q.SendBatch(ctx, &pgx.Batch{}).Exec()
+	assert.ErrorIs(t, err, performance.ErrBulkheadFull)
+	require.NoError(t, results.Close())
+	assert.Equal(t, 0, bulkhead.CurrentConcurrency(), "freed once the results are closed")
+
+	assert.Equal(t, fakeQuerier{}, WithBulkhead(fakeQuerier{}, nil))
+}
@@ -0,0 +1,267 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationFile matches {version}_{description}.{up|down}.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change of a migrations directory
+type Migration struct {
+	Version     int64
+	Description string
+	Up          string // SQL applying the change
+	Down        string // SQL rolling it back; empty when there is none
+}
+
+// LoadMigrations reads the migrations of dir in fsys, ordered by version.
+// Every version needs an up file; down files are optional.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		m := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", entry.Name(), err)
+		}
+		sql, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Description: m[2]}
+			byVersion[version] = migration
+		} else if migration.Description != m[2] {
+			return nil, fmt.Errorf("migration %d of %s has two descriptions: %s and %s", version, dir, migration.Description, m[2])
+		}
+		if m[3] == "up" {
+			migration.Up = string(sql)
+		} else {
+			migration.Down = string(sql)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s of %s has no up file", migration.Version, migration.Description, dir)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Pending returns the migrations not in applied, in order
+func Pending(migrations []Migration, applied map[int64]time.Time) []Migration {
+	var pending []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// Migrator applies the migrations of one source, a migrations directory, to
+// a database, recording each applied version in schema_versions. Several
+// sources can share a database. Each migration runs in a transaction holding
+// a per-source advisory lock, so concurrent runs apply it once.
+type Migrator struct {
+	pool   *pgxpool.Pool
+	source string
+}
+
+// NewMigrator creates a migrator for source
+func NewMigrator(pool *pgxpool.Pool, source string) *Migrator {
+	return &Migrator{pool: pool, source: source}
+}
+
+// ensureTable creates the schema_versions table
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_versions (
+			source VARCHAR(100) NOT NULL,
+			version BIGINT NOT NULL,
+			description TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (source, version)
+		)
+	`)
+	return err
+}
+
+// Applied returns the versions of the source applied, with when
+func (m *Migrator) Applied(ctx context.Context) (map[int64]time.Time, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.pool.Query(ctx, `SELECT version, applied_at FROM schema_versions WHERE source = $1`, m.source)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// Up applies the pending migrations in order and returns those applied. It
+// stops at the first that fails, which is rolled back.
+func (m *Migrator) Up(ctx context.Context, migrations []Migration) ([]Migration, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range Pending(migrations, applied) {
+		ran, err := m.inLock(ctx, migration, true, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, migration.Up); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx,
+				`INSERT INTO schema_versions (source, version, description) VALUES ($1, $2, $3)`,
+				m.source, migration.Version, migration.Description,
+			)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s of %s failed: %w", migration.Version, migration.Description, m.source, err)
+		}
+		if ran {
+			done = append(done, migration)
+		}
+	}
+	return done, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first, and
+// returns those rolled back
+func (m *Migrator) Down(ctx context.Context, migrations []Migration, steps int) ([]Migration, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return done, fmt.Errorf("migration %d_%s of %s has no down file", migration.Version, migration.Description, m.source)
+		}
+		ran, err := m.inLock(ctx, migration, false, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, migration.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx,
+				`DELETE FROM schema_versions WHERE source = $1 AND version = $2`,
+				m.source, migration.Version,
+			)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("rollback of migration %d_%s of %s failed: %w", migration.Version, migration.Description, m.source, err)
+		}
+		if ran {
+			done = append(done, migration)
+		}
+	}
+	return done, nil
+}
+
+// Force records the migrations up to version as applied and the later ones
+// as not, without running them; for databases migrated by other means
+func (m *Migrator) Force(ctx context.Context, migrations []Migration, version int64) error {
+	if err := m.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	tx, err := m.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_versions WHERE source = $1`, m.source); err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		if migration.Version > version {
+			break
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO schema_versions (source, version, description) VALUES ($1, $2, $3)`,
+			m.source, migration.Version, migration.Description,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// inLock runs fn in a transaction holding the source's advisory lock, once
+// the migration is confirmed still to be applied (up) or rolled back. It
+// reports whether fn ran; another run may have got there first.
+func (m *Migrator) inLock(ctx context.Context, migration Migration, up bool, fn func(tx pgx.Tx) error) (bool, error) {
+	tx, err := m.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('schema_versions:' || $1))`, m.source); err != nil {
+		return false, err
+	}
+
+	var applied bool
+	if err := tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM schema_versions WHERE source = $1 AND version = $2)`,
+		m.source, migration.Version,
+	).Scan(&applied); err != nil {
+		return false, err
+	}
+	if applied == up {
+		return false, nil
+	}
+
+	if err := fn(tx); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrationsOrdersByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"order/000002_add_orders_tenant_id.up.sql":   {Data: []byte("ALTER TABLE orders ADD COLUMN tenant_id UUID;")},
+		"order/000002_add_orders_tenant_id.down.sql": {Data: []byte("ALTER TABLE orders DROP COLUMN tenant_id;")},
+		"order/000010_add_orders_tax.up.sql":         {Data: []byte("ALTER TABLE orders ADD COLUMN tax NUMERIC;")},
+		"order/000001_create_orders_table.up.sql":    {Data: []byte("CREATE TABLE orders (id UUID);")},
+		"order/README.md":                            {Data: []byte("not a migration")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "order")
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "create_orders_table", migrations[0].Description)
+	assert.Empty(t, migrations[0].Down)
+	assert.Equal(t, int64(2), migrations[1].Version)
+	assert.Equal(t, "ALTER TABLE orders DROP COLUMN tenant_id;", migrations[1].Down)
+	assert.Equal(t, int64(10), migrations[2].Version)
+}
+
+func TestLoadMigrationsRejectsMissingUp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"user/000001_create_users_table.up.sql": {Data: []byte("CREATE TABLE users (id UUID);")},
+		"user/000002_add_users_roles.down.sql":  {Data: []byte("ALTER TABLE users DROP COLUMN roles;")},
+	}
+
+	_, err := LoadMigrations(fsys, "user")
+	assert.ErrorContains(t, err, "no up file")
+}
+
+func TestPendingSkipsApplied(t *testing.T) {
+	migrations := []Migration{{Version: 1}, {Version: 2}, {Version: 3}}
+	applied := map[int64]time.Time{1: time.Now(), 3: time.Now()}
+
+	pending := Pending(migrations, applied)
+	require.Len(t, pending, 1)
+	assert.Equal(t, int64(2), pending[0].Version)
+}
@@ -5,20 +5,59 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/onichange/pos-system/pkg/config"
 	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/tracing"
 )
 
-// PostgresDB wraps pgxpool.Pool with health check
+// PostgresDB wraps pgxpool.Pool with health check, and the pools of the
+// configured read replicas
 type PostgresDB struct {
 	Pool   *pgxpool.Pool
 	logger *logger.Logger
+
+	replicas    *replicaSet // Nil without replicas
+	stopReplica context.CancelFunc
 }
 
-// NewPostgresDB creates a new PostgreSQL connection pool
+// NewPostgresDB creates a new PostgreSQL connection pool, and one per read
+// replica. The primary must be reachable; replicas that are not are left
+// out of reads until their health checks pass.
 func NewPostgresDB(cfg config.DatabaseConfig, log *logger.Logger) (*PostgresDB, error) {
+	pool, err := newPool(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Test connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	log.Infof("Connected to PostgreSQL database: %s", cfg.DBName)
+
+	db := &PostgresDB{
+		Pool:   pool,
+		logger: log,
+	}
+	if len(cfg.Replicas) > 0 {
+		if err := db.connectReplicas(cfg, log); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// newPool creates a connection pool for cfg, without connecting
+func newPool(cfg config.DatabaseConfig) (*pgxpool.Pool, error) {
 	dsn := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode,
@@ -35,6 +74,19 @@ func NewPostgresDB(cfg config.DatabaseConfig, log *logger.Logger) (*PostgresDB,
 	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
 	poolConfig.MaxConnIdleTime = cfg.ConnMaxIdleTime
 
+	// Rotating credentials are read for each new connection; existing connections
+	// keep their credentials until MaxConnLifetime recycles them
+	if cfg.CredentialsFunc != nil {
+		credentials := cfg.CredentialsFunc
+		poolConfig.BeforeConnect = func(ctx context.Context, connConfig *pgx.ConnConfig) error {
+			connConfig.User, connConfig.Password = credentials()
+			return nil
+		}
+	}
+
+	// Every query, batch, and copy gets a span under the caller's trace
+	poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
+
 	// Health check configuration
 	poolConfig.HealthCheckPeriod = 1 * time.Minute
 
@@ -42,22 +94,7 @@ func NewPostgresDB(cfg config.DatabaseConfig, log *logger.Logger) (*PostgresDB,
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
-
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := pool.Ping(ctx); err != nil {
-		pool.Close()
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
-
-	log.Infof("Connected to PostgreSQL database: %s", cfg.DBName)
-
-	return &PostgresDB{
-		Pool:   pool,
-		logger: log,
-	}, nil
+	return pool, nil
 }
 
 // HealthCheck checks database connection health
@@ -65,8 +102,12 @@ func (db *PostgresDB) HealthCheck(ctx context.Context) error {
 	return db.Pool.Ping(ctx)
 }
 
-// Close closes the database connection pool
+// Close closes the database connection pool, and those of the replicas
 func (db *PostgresDB) Close() {
+	if db.replicas != nil {
+		db.stopReplica()
+		db.replicas.close()
+	}
 	db.Pool.Close()
 	db.logger.Info("PostgreSQL connection pool closed")
 }
@@ -2,7 +2,10 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -32,49 +35,107 @@ func (qe *QueryExecutor) ExecuteWithTimeout(ctx context.Context, timeout time.Du
 	return qe.pool.Query(ctx, query, args...)
 }
 
-// BatchInsert performs batch insert operation
+// maxQueryParams is the most parameters Postgres accepts in one statement
+const maxQueryParams = 65535
+
+// BatchInsert performs batch insert operation. Table and column names are
+// quoted, so they are never read as SQL. Batches with more values than one
+// statement takes are split, and inserted in one transaction.
 func (qe *QueryExecutor) BatchInsert(ctx context.Context, table string, columns []string, values [][]interface{}) error {
-	if len(values) == 0 {
+	stmts, err := batchInsertStatements(table, columns, values)
+	if err != nil {
+		return err
+	}
+	switch len(stmts) {
+	case 0:
 		return nil
+	case 1:
+		_, err := qe.pool.Exec(ctx, stmts[0].sql, stmts[0].args...)
+		return err
+	}
+
+	tx, err := qe.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(ctx, stmt.sql, stmt.args...); err != nil {
+			return err
+		}
 	}
+	return tx.Commit(ctx)
+}
 
-	// Build batch insert query
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", table, formatColumns(columns))
-	
-	// Build VALUES clause
-	valuePlaceholders := make([]string, len(values))
-	allArgs := make([]interface{}, 0, len(values)*len(columns))
-	argIndex := 1
+// insertStatement is one statement of a batch insert
+type insertStatement struct {
+	sql  string
+	args []interface{}
+}
 
+// batchInsertStatements builds the statements inserting values into table,
+// as many rows to a statement as its parameters allow
+func batchInsertStatements(table string, columns []string, values [][]interface{}) ([]insertStatement, error) {
+	if table == "" {
+		return nil, errors.New("batch insert: no table")
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("batch insert: no columns")
+	}
+	for _, column := range columns {
+		if column == "" {
+			return nil, errors.New("batch insert: empty column name")
+		}
+	}
 	for i, row := range values {
-		placeholders := make([]string, len(columns))
-		for j := range columns {
-			placeholders[j] = fmt.Sprintf("$%d", argIndex)
-			allArgs = append(allArgs, row[j])
-			argIndex++
+		if len(row) != len(columns) {
+			return nil, fmt.Errorf("batch insert: row %d has %d values for %d columns", i, len(row), len(columns))
 		}
-		valuePlaceholders[i] = fmt.Sprintf("(%s)", fmt.Sprintf("%s", placeholders))
 	}
 
-	query += fmt.Sprintf("%s", valuePlaceholders[0])
-	for i := 1; i < len(valuePlaceholders); i++ {
-		query += ", " + valuePlaceholders[i]
+	prefix := "INSERT INTO " + quoteTable(table) + " (" + formatColumns(columns) + ") VALUES "
+	rowsPerStmt := maxQueryParams / len(columns)
+
+	var stmts []insertStatement
+	for start := 0; start < len(values); start += rowsPerStmt {
+		rows := values[start:min(start+rowsPerStmt, len(values))]
+
+		var sql strings.Builder
+		sql.WriteString(prefix)
+		args := make([]interface{}, 0, len(rows)*len(columns))
+		for i, row := range rows {
+			if i > 0 {
+				sql.WriteString(", ")
+			}
+			sql.WriteByte('(')
+			for j, value := range row {
+				if j > 0 {
+					sql.WriteString(", ")
+				}
+				args = append(args, value)
+				sql.WriteByte('$')
+				sql.WriteString(strconv.Itoa(len(args)))
+			}
+			sql.WriteByte(')')
+		}
+		stmts = append(stmts, insertStatement{sql: sql.String(), args: args})
 	}
+	return stmts, nil
+}
 
-	_, err := qe.pool.Exec(ctx, query, allArgs...)
-	return err
+// quoteTable quotes a table name for SQL, keeping a schema prefix
+func quoteTable(table string) string {
+	return pgx.Identifier(strings.Split(table, ".")).Sanitize()
 }
 
-// formatColumns formats column names for SQL
+// formatColumns formats column names for SQL, quoting each
 func formatColumns(columns []string) string {
-	if len(columns) == 0 {
-		return ""
-	}
-	result := columns[0]
-	for i := 1; i < len(columns); i++ {
-		result += ", " + columns[i]
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = pgx.Identifier{column}.Sanitize()
 	}
-	return result
+	return strings.Join(quoted, ", ")
 }
 
 // BatchUpdate performs batch update operation
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchRows builds n rows of width values, numbered in order
+func batchRows(n, width int) [][]interface{} {
+	rows := make([][]interface{}, n)
+	for i := range rows {
+		rows[i] = make([]interface{}, width)
+		for j := range rows[i] {
+			rows[i][j] = i*width + j
+		}
+	}
+	return rows
+}
+
+func TestBatchInsertStatementsOneRow(t *testing.T) {
+	stmts, err := batchInsertStatements("inventory", []string{"id", "name"}, [][]interface{}{{1, "a"}})
+	require.NoError(t, err)
+	require.Len(t, stmts, 1)
+	assert.Equal(t, `INSERT INTO "inventory" ("id", "name") VALUES ($1, $2)`, stmts[0].sql)
+	assert.Equal(t, []interface{}{1, "a"}, stmts[0].args)
+}
+
+func TestBatchInsertStatementsHundredRows(t *testing.T) {
+	stmts, err := batchInsertStatements("inventory", []string{"id", "name"}, batchRows(100, 2))
+	require.NoError(t, err)
+	require.Len(t, stmts, 1)
+	assert.True(t, strings.HasPrefix(stmts[0].sql, `INSERT INTO "inventory" ("id", "name") VALUES ($1, $2), ($3, $4)`))
+	assert.True(t, strings.HasSuffix(stmts[0].sql, "($199, $200)"))
+	assert.Len(t, stmts[0].args, 200)
+	assert.Equal(t, 199, stmts[0].args[199])
+}
+
+func TestBatchInsertStatementsSplitsLargeBatches(t *testing.T) {
+	columns := make([]string, 11)
+	for i := range columns {
+		columns[i] = fmt.Sprintf("c%d", i)
+	}
+	stmts, err := batchInsertStatements("inventory", columns, batchRows(10000, 11))
+	require.NoError(t, err)
+	require.Len(t, stmts, 2)
+
+	next := 0
+	for _, stmt := range stmts {
+		assert.LessOrEqual(t, len(stmt.args), maxQueryParams)
+		assert.Contains(t, stmt.sql, "VALUES ($1, $2,")
+		assert.True(t, strings.HasSuffix(stmt.sql, fmt.Sprintf("$%d)", len(stmt.args))))
+		// Rows keep their order across statements
+		for _, arg := range stmt.args {
+			require.Equal(t, next, arg)
+			next++
+		}
+	}
+	assert.Equal(t, 110000, next)
+}
+
+func TestBatchInsertStatementsQuoteIdentifiers(t *testing.T) {
+	stmts, err := batchInsertStatements(`orders; DROP TABLE users; --`, []string{`id"); DROP TABLE users; --`}, [][]interface{}{{1}})
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "orders; DROP TABLE users; --" ("id""); DROP TABLE users; --") VALUES ($1)`, stmts[0].sql)
+
+	stmts, err = batchInsertStatements("archive.orders", []string{"id"}, [][]interface{}{{1}})
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "archive"."orders" ("id") VALUES ($1)`, stmts[0].sql)
+}
+
+func TestBatchInsertStatementsRejectInvalidInput(t *testing.T) {
+	stmts, err := batchInsertStatements("inventory", []string{"id"}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, stmts)
+
+	_, err = batchInsertStatements("inventory", []string{"id", "name"}, [][]interface{}{{1, "a"}, {2}})
+	assert.ErrorContains(t, err, "row 1 has 1 values for 2 columns")
+
+	_, err = batchInsertStatements("inventory", nil, [][]interface{}{{1}})
+	assert.Error(t, err)
+
+	_, err = batchInsertStatements("", []string{"id"}, [][]interface{}{{1}})
+	assert.Error(t, err)
+
+	_, err = batchInsertStatements("inventory", []string{""}, [][]interface{}{{1}})
+	assert.Error(t, err)
+}
@@ -3,83 +3,206 @@ package database
 import (
 	"context"
 	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/onichange/pos-system/pkg/config"
 	"github.com/onichange/pos-system/pkg/logger"
 )
 
-// ReplicaPool manages read replicas with load balancing
-type ReplicaPool struct {
-	primary  *pgxpool.Pool
-	replicas []*pgxpool.Pool
-	current  int
-	logger   *logger.Logger
+// replicaPingTimeout bounds each health check of a replica
+const replicaPingTimeout = 2 * time.Second
+
+// replicaConn is the connection pool of a replica; *pgxpool.Pool implements it
+type replicaConn interface {
+	Querier
+	Ping(ctx context.Context) error
+	Close()
 }
 
-// NewReplicaPool creates a new replica pool
-func NewReplicaPool(primaryConfig config.DatabaseConfig, replicaConfigs []config.DatabaseConfig, log *logger.Logger) (*ReplicaPool, error) {
-	// Create primary connection
-	primary, err := NewPostgresDB(primaryConfig, log)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create primary connection: %w", err)
-	}
+// replica is a read replica and the outcome of its last health check
+type replica struct {
+	addr    string
+	conn    replicaConn
+	healthy atomic.Bool
+}
 
-	// Create replica connections
-	replicas := make([]*pgxpool.Pool, 0, len(replicaConfigs))
-	for _, cfg := range replicaConfigs {
-		replica, err := NewPostgresDB(cfg, log)
+// replicaSet spreads reads over the healthy replicas in turn
+type replicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+// connectReplicas creates a pool for each of cfg.Replicas, reached with the
+// primary's credentials and database name, checks them, and keeps checking
+// them every cfg.ReplicaCheckInterval until db is closed
+func (db *PostgresDB) connectReplicas(cfg config.DatabaseConfig, log *logger.Logger) error {
+	set := &replicaSet{}
+	for _, addr := range cfg.Replicas {
+		replicaCfg := cfg
+		var err error
+		replicaCfg.Host, replicaCfg.Port, err = net.SplitHostPort(addr)
 		if err != nil {
-			log.Warnf("Failed to create replica connection: %v", err)
-			continue
+			set.close()
+			return fmt.Errorf("invalid replica address %q: %w", addr, err)
 		}
-		replicas = append(replicas, replica.Pool)
+		pool, err := newPool(replicaCfg)
+		if err != nil {
+			set.close()
+			return fmt.Errorf("replica %s: %w", addr, err)
+		}
+		set.replicas = append(set.replicas, &replica{addr: addr, conn: pool})
 	}
 
-	return &ReplicaPool{
-		primary:  primary.Pool,
-		replicas: replicas,
-		logger:   log,
-	}, nil
+	ctx, stop := context.WithCancel(context.Background())
+	set.check(ctx, log)
+	go set.watch(ctx, cfg.ReplicaCheckInterval, log)
+
+	db.replicas = set
+	db.stopReplica = stop
+	log.Infof("Reading from %d PostgreSQL replicas", len(set.replicas))
+	return nil
 }
 
-// GetPrimary returns the primary database connection
-func (rp *ReplicaPool) GetPrimary() *pgxpool.Pool {
-	return rp.primary
+// pick returns the next healthy replica, or nil when none is
+func (s *replicaSet) pick() *replica {
+	n := uint64(len(s.replicas))
+	start := s.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if r := s.replicas[(start+i)%n]; r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
 }
 
-// GetReplica returns a read replica (round-robin)
-func (rp *ReplicaPool) GetReplica() *pgxpool.Pool {
-	if len(rp.replicas) == 0 {
-		return rp.primary // Fallback to primary if no replicas
+// check pings every replica, taking those that fail out of reads until a
+// later check passes
+func (s *replicaSet) check(ctx context.Context, log *logger.Logger) {
+	for _, r := range s.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+		err := r.conn.Ping(pingCtx)
+		cancel()
+
+		healthy := err == nil
+		if r.healthy.Swap(healthy) == healthy {
+			continue
+		}
+		if healthy {
+			log.Infof("Replica %s is healthy; reading from it", r.addr)
+		} else {
+			log.Warnf("Replica %s is unhealthy; reading elsewhere: %v", r.addr, err)
+		}
 	}
+}
 
-	rp.current = (rp.current + 1) % len(rp.replicas)
-	return rp.replicas[rp.current]
+// watch checks the replicas every interval until ctx is done
+func (s *replicaSet) watch(ctx context.Context, interval time.Duration, log *logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check(ctx, log)
+		}
+	}
 }
 
-// HealthCheck checks health of all connections
-func (rp *ReplicaPool) HealthCheck(ctx context.Context) error {
-	// Check primary
-	if err := rp.primary.Ping(ctx); err != nil {
-		return fmt.Errorf("primary connection unhealthy: %w", err)
+// close closes the replicas' pools
+func (s *replicaSet) close() {
+	for _, r := range s.replicas {
+		r.conn.Close()
 	}
+}
 
-	// Check replicas
-	for i, replica := range rp.replicas {
-		if err := replica.Ping(ctx); err != nil {
-			rp.logger.Warnf("Replica %d is unhealthy: %v", i, err)
+// HealthyReplicas returns how many replicas passed their last health check
+func (db *PostgresDB) HealthyReplicas() int {
+	if db.replicas == nil {
+		return 0
+	}
+	healthy := 0
+	for _, r := range db.replicas.replicas {
+		if r.healthy.Load() {
+			healthy++
 		}
 	}
+	return healthy
+}
 
-	return nil
+// Routed returns a Querier that sends reads, SELECT statements without side
+// effects, to the healthy replicas in turn, and everything else to the
+// primary, as it does reads while no replica is healthy. Replicas lag the
+// primary, so repositories opt in where reads may be a moment stale, such
+// as listings; without replicas it is the primary pool.
+func (db *PostgresDB) Routed() Querier {
+	if db.replicas == nil {
+		return db.Pool
+	}
+	return &routedQuerier{primary: db.Pool, replicas: db.replicas}
+}
+
+// routedQuerier sends reads to replicas
+type routedQuerier struct {
+	primary  Querier
+	replicas *replicaSet
+}
+
+// Exec runs a statement on the primary
+func (q *routedQuerier) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return q.primary.Exec(ctx, sql, args...)
+}
+
+// Query runs a read on a replica, and anything else on the primary
+func (q *routedQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return q.route(sql).Query(ctx, sql, args...)
+}
+
+// QueryRow runs a read on a replica, and anything else on the primary
+func (q *routedQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return q.route(sql).QueryRow(ctx, sql, args...)
 }
 
-// Close closes all connections
-func (rp *ReplicaPool) Close() {
-	rp.primary.Close()
-	for _, replica := range rp.replicas {
-		replica.Close()
+// SendBatch sends a batch to the primary
+func (q *routedQuerier) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return q.primary.SendBatch(ctx, b)
+}
+
+// route returns where to run sql
+func (q *routedQuerier) route(sql string) Querier {
+	if !isRead(sql) {
+		return q.primary
+	}
+	if r := q.replicas.pick(); r != nil {
+		return r.conn
+	}
+	return q.primary
+}
+
+// writeMarkers are what makes a SELECT more than a read: row locks, and
+// functions changing state
+var writeMarkers = []string{
+	" for update", " for no key update", " for share", " for key share",
+	"nextval(", "setval(", "set_config(", "pg_advisory",
+}
+
+// isRead reports whether sql is a SELECT that neither locks rows nor
+// changes state, and so can run on a replica
+func isRead(sql string) bool {
+	normalized := strings.ToLower(strings.Join(strings.Fields(sql), " "))
+	if !strings.HasPrefix(normalized, "select ") {
+		return false
+	}
+	for _, marker := range writeMarkers {
+		if strings.Contains(normalized, marker) {
+			return false
+		}
 	}
+	return true
 }
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// recordingConn records which connection each statement ran on
+type recordingConn struct {
+	fakeQuerier
+	name    string
+	ran     *[]string
+	pingErr error
+}
+
+func (c *recordingConn) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	*c.ran = append(*c.ran, c.name)
+	return c.fakeQuerier.Exec(ctx, sql, args...)
+}
+
+func (c *recordingConn) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	*c.ran = append(*c.ran, c.name)
+	return c.fakeQuerier.Query(ctx, sql, args...)
+}
+
+func (c *recordingConn) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	*c.ran = append(*c.ran, c.name)
+	return c.fakeQuerier.QueryRow(ctx, sql, args...)
+}
+
+func (c *recordingConn) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	*c.ran = append(*c.ran, c.name)
+	return c.fakeQuerier.SendBatch(ctx, b)
+}
+
+func (c *recordingConn) Ping(context.Context) error { return c.pingErr }
+
+func (c *recordingConn) Close() {}
+
+// newRouted returns a routed querier over a primary and healthy replicas
+// named r1, r2..., recording where statements run
+func newRouted(replicas int) (*routedQuerier, []*recordingConn, *[]string) {
+	ran := &[]string{}
+	set := &replicaSet{}
+	var conns []*recordingConn
+	for i := 0; i < replicas; i++ {
+		conn := &recordingConn{name: "r" + string(rune('1'+i)), ran: ran}
+		r := &replica{addr: conn.name, conn: conn}
+		r.healthy.Store(true)
+		set.replicas = append(set.replicas, r)
+		conns = append(conns, conn)
+	}
+	return &routedQuerier{primary: &recordingConn{name: "primary", ran: ran}, replicas: set}, conns, ran
+}
+
+func TestIsRead(t *testing.T) {
+	for sql, read := range map[string]bool{
+		"SELECT id FROM stores WHERE tenant_id = $1":                         true,
+		"\n\t\tselect count(*)\n\t\tFROM orders":                             true,
+		"SELECT * FROM inventory WHERE id = $1 FOR UPDATE":                   false,
+		"SELECT * FROM inventory WHERE id = $1\n\t\tFOR\n\t\tNO KEY UPDATE":  false,
+		"SELECT * FROM shifts FOR SHARE SKIP LOCKED":                         false,
+		"SELECT nextval('receipt_numbers')":                                  false,
+		"SELECT pg_advisory_xact_lock($1)":                                   false,
+		"INSERT INTO stores (id) VALUES ($1) RETURNING id":                   false,
+		"UPDATE stores SET name = $1 RETURNING id":                           false,
+		"WITH moved AS (DELETE FROM orders RETURNING *) SELECT * FROM moved": false,
+	} {
+		assert.Equal(t, read, isRead(sql), sql)
+	}
+}
+
+func TestRoutedQuerierSendsReadsToReplicas(t *testing.T) {
+	q, _, ran := newRouted(2)
+	ctx := context.Background()
+
+	q.Query(ctx, "SELECT * FROM stores")
+	q.QueryRow(ctx, "SELECT * FROM stores WHERE id = $1").Scan()
+	q.Query(ctx, "SELECT * FROM stores")
+	q.Exec(ctx, "UPDATE stores SET name = $1")
+	q.QueryRow(ctx, "INSERT INTO stores (id) VALUES ($1) RETURNING id").Scan()
+	q.Query(ctx, "SELECT * FROM inventory FOR UPDATE")
+	q.SendBatch(ctx, &pgx.Batch{})
+
+	assert.Equal(t, []string{"r2", "r1", "r2", "primary", "primary", "primary", "primary"}, *ran)
+}
+
+func TestRoutedQuerierSkipsUnhealthyReplicas(t *testing.T) {
+	q, conns, ran := newRouted(2)
+	ctx := context.Background()
+	log := logger.New("test")
+
+	conns[0].pingErr = errors.New("connection refused")
+	q.replicas.check(ctx, log)
+	q.Query(ctx, "SELECT 1")
+	q.Query(ctx, "SELECT 1")
+	assert.Equal(t, []string{"r2", "r2"}, *ran)
+
+	// Reads fall back to the primary while no replica is healthy
+	conns[1].pingErr = errors.New("connection refused")
+	q.replicas.check(ctx, log)
+	*ran = nil
+	q.Query(ctx, "SELECT 1")
+	assert.Equal(t, []string{"primary"}, *ran)
+
+	// A replica passing its check takes reads again
+	conns[0].pingErr = nil
+	q.replicas.check(ctx, log)
+	*ran = nil
+	q.Query(ctx, "SELECT 1")
+	assert.Equal(t, []string{"r1"}, *ran)
+}
+
+func TestRoutedWithoutReplicasIsThePrimary(t *testing.T) {
+	db := &PostgresDB{}
+	assert.Equal(t, Querier(db.Pool), db.Routed())
+	assert.Zero(t, db.HealthyReplicas())
+}
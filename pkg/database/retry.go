@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/onichange/pos-system/pkg/performance"
+)
+
+// SQLSTATE codes of failures that rolled the statement back and may succeed
+// when run again
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// WithRetry retries queries on q that fail transiently, as policy allows.
+// Only failures that leave no side effect are retried: the statement never
+// reached the server, or the server rolled it back for a serialization
+// failure or deadlock. Rows are not retried once returned, nor are batches.
+func WithRetry(q Querier, policy performance.RetryPolicy) Querier {
+	policy.Retryable = IsTransient
+	return &retryQuerier{q: q, policy: policy}
+}
+
+// IsTransient reports whether err is a database failure worth retrying
+func IsTransient(err error) bool {
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+	}
+	return false
+}
+
+// retryQuerier retries transient query failures
+type retryQuerier struct {
+	q      Querier
+	policy performance.RetryPolicy
+}
+
+// Exec runs a statement, retrying transient failures
+func (r *retryQuerier) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := performance.Retry(ctx, r.policy, func(int) error {
+		var err error
+		tag, err = r.q.Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
+}
+
+// Query runs a query, retrying transient failures to start it
+func (r *retryQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := performance.Retry(ctx, r.policy, func(int) error {
+		var err error
+		rows, err = r.q.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow runs a single-row query when the row is scanned, retrying
+// transient failures
+func (r *retryQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return &retryRow{ctx: ctx, r: r, sql: sql, args: args}
+}
+
+// SendBatch sends a batch without retrying it; its failures are only known
+// as its results are read
+func (r *retryQuerier) SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults {
+	return r.q.SendBatch(ctx, batch)
+}
+
+// retryRow defers its query to Scan, where pgx reports query errors
+type retryRow struct {
+	ctx  context.Context
+	r    *retryQuerier
+	sql  string
+	args []any
+}
+
+// Scan runs the query and reads the row
+func (row *retryRow) Scan(dest ...any) error {
+	return performance.Retry(row.ctx, row.r.policy, func(int) error {
+		return row.r.q.QueryRow(row.ctx, row.sql, row.args...).Scan(dest...)
+	})
+}
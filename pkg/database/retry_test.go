@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/onichange/pos-system/pkg/performance"
+)
+
+// failingQuerier fails its first calls with err
+type failingQuerier struct {
+	fakeQuerier
+	err      error
+	failures int
+	calls    int
+}
+
+func (q *failingQuerier) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	q.calls++
+	if q.calls <= q.failures {
+		return pgconn.CommandTag{}, q.err
+	}
+	return q.fakeQuerier.Exec(ctx, sql, args...)
+}
+
+func (q *failingQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	q.calls++
+	if q.calls <= q.failures {
+		return errRow{err: q.err}
+	}
+	return q.fakeQuerier.QueryRow(ctx, sql, args...)
+}
+
+func TestWithRetryRetriesTransientFailures(t *testing.T) {
+	policy := performance.RetryPolicy{MaxAttempts: 3}
+	ctx := context.Background()
+
+	deadlock := &failingQuerier{err: &pgconn.PgError{Code: deadlockDetected}, failures: 2}
+	_, err := WithRetry(deadlock, policy).Exec(ctx, "UPDATE t SET x = 1")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, deadlock.calls)
+
+	serialization := &failingQuerier{err: &pgconn.PgError{Code: serializationFailure}, failures: 1}
+	assert.NoError(t, WithRetry(serialization, policy).QueryRow(ctx, "SELECT 1").Scan())
+	assert.Equal(t, 2, serialization.calls)
+
+	// Other failures may have taken effect, so they are returned at once
+	violation := &failingQuerier{err: &pgconn.PgError{Code: "23505"}, failures: 1}
+	_, err = WithRetry(violation, policy).Exec(ctx, "INSERT INTO t VALUES (1)")
+	assert.Error(t, err)
+	assert.Equal(t, 1, violation.calls)
+
+	missing := &failingQuerier{err: pgx.ErrNoRows, failures: 1}
+	assert.ErrorIs(t, WithRetry(missing, policy).QueryRow(ctx, "SELECT 1").Scan(), pgx.ErrNoRows)
+	assert.Equal(t, 1, missing.calls)
+
+	assert.False(t, IsTransient(errors.New("connection reset")))
+}
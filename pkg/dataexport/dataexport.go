@@ -0,0 +1,113 @@
+// Package dataexport streams a store's records from the service owning them
+// as JSON Lines: one JSON value per line, ending in a trailer line that
+// counts them. A stream cut short, by the sender failing or the connection
+// dropping, lacks a correct trailer, so readers can tell a partial export
+// from a complete one.
+package dataexport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// ContentType is the media type of an export stream
+const ContentType = "application/x-ndjson"
+
+// maxRecordSize is the longest line a stream may hold
+const maxRecordSize = 16 << 20
+
+// Errors of reading a stream
+var (
+	ErrIncomplete = errors.New("export stream ended before its trailer")
+	ErrFailed     = errors.New("export stream failed")
+)
+
+// trailer ends a stream: the number of records sent, or why sending stopped
+type trailer struct {
+	End   *int   `json:"@end,omitempty"`
+	Error string `json:"@error,omitempty"`
+}
+
+// Stream sends the records produce emits as the response body, as they are
+// emitted. produce runs after the handler returns, with the request's user
+// context, and its error ends the stream with a failure trailer.
+func Stream(c *fiber.Ctx, produce func(ctx context.Context, emit func(v any) error) error) error {
+	ctx := c.UserContext()
+	log := logger.FromContext(ctx)
+
+	c.Set(fiber.HeaderContentType, ContentType)
+
+	// The server's write timeout bounds a whole response; an export is
+	// bounded by the client staying connected instead
+	conn := c.Context().Conn()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		_ = conn.SetWriteDeadline(time.Time{})
+		enc := json.NewEncoder(w)
+
+		count := 0
+		err := produce(ctx, func(v any) error {
+			if err := enc.Encode(v); err != nil {
+				return err
+			}
+			count++
+			return nil
+		})
+
+		end := trailer{End: &count}
+		if err != nil {
+			log.Errorf("Export stream failed after %d records: %v", count, err)
+			end = trailer{Error: "export failed"}
+		}
+		if enc.Encode(end) == nil {
+			_ = w.Flush()
+		}
+	})
+	return nil
+}
+
+// Read calls fn with each record of a stream, and returns how many there
+// were once the trailer confirms the stream is complete
+func Read(r io.Reader, fn func(record []byte) error) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxRecordSize)
+
+	count := 0
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		if bytes.HasPrefix(line, []byte(`{"@`)) {
+			var end trailer
+			if err := json.Unmarshal(line, &end); err == nil && (end.End != nil || end.Error != "") {
+				if end.Error != "" {
+					return count, fmt.Errorf("%w: %s", ErrFailed, end.Error)
+				}
+				if *end.End != count {
+					return count, fmt.Errorf("%w: %d of %d records", ErrIncomplete, count, *end.End)
+				}
+				return count, nil
+			}
+		}
+
+		if err := fn(line); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+	return count, ErrIncomplete
+}
@@ -0,0 +1,86 @@
+package dataexport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type record struct {
+	ID string `json:"id"`
+}
+
+func serve(t *testing.T, produce func(ctx context.Context, emit func(v any) error) error) string {
+	app := fiber.New()
+	app.Get("/export", func(c *fiber.Ctx) error {
+		return Stream(c, produce)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/export", nil), -1)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, ContentType, resp.Header.Get(fiber.HeaderContentType))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestStreamEndsWithCount(t *testing.T) {
+	body := serve(t, func(ctx context.Context, emit func(v any) error) error {
+		for _, id := range []string{"a", "b", "c"} {
+			if err := emit(record{ID: id}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	var ids []string
+	n, err := Read(strings.NewReader(body), func(line []byte) error {
+		var r record
+		require.NoError(t, json.Unmarshal(line, &r))
+		ids = append(ids, r.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []string{"a", "b", "c"}, ids)
+}
+
+func TestStreamReportsFailure(t *testing.T) {
+	body := serve(t, func(ctx context.Context, emit func(v any) error) error {
+		if err := emit(record{ID: "a"}); err != nil {
+			return err
+		}
+		return errors.New("database went away")
+	})
+	assert.NotContains(t, body, "database went away")
+
+	n, err := Read(strings.NewReader(body), func([]byte) error { return nil })
+	assert.ErrorIs(t, err, ErrFailed)
+	assert.Equal(t, 1, n)
+}
+
+func TestReadDetectsTruncation(t *testing.T) {
+	_, err := Read(strings.NewReader("{\"id\":\"a\"}\n{\"id\":\"b\"}\n"), func([]byte) error { return nil })
+	assert.ErrorIs(t, err, ErrIncomplete)
+
+	_, err = Read(strings.NewReader("{\"id\":\"a\"}\n{\"@end\":2}\n"), func([]byte) error { return nil })
+	assert.ErrorIs(t, err, ErrIncomplete)
+}
+
+func TestReadStopsOnCallbackError(t *testing.T) {
+	stop := errors.New("stop")
+	n, err := Read(strings.NewReader("{\"id\":\"a\"}\n{\"@end\":1}\n"), func([]byte) error { return stop })
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 0, n)
+}
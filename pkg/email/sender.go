@@ -0,0 +1,216 @@
+// Package email sends HTML email over SMTP, to a mail server of one's own or
+// to the SMTP interface of Amazon SES, rendered from templates.
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// ErrRejected is matched by errors of mail the server refused for good, such
+// as an unknown recipient; sending it again fails the same way
+var ErrRejected = errors.New("email rejected")
+
+// implicitTLSPort is the SMTP submission port spoken over TLS from the start
+const implicitTLSPort = 465
+
+// Message is an email to one recipient
+type Message struct {
+	ID      string // Unique per message; becomes the Message-ID
+	To      string // Address
+	ToName  string
+	Subject string
+	HTML    string
+}
+
+// Sender sends email
+type Sender interface {
+	// Send sends m. A failure matching ErrRejected is permanent; any other
+	// may pass when tried again.
+	Send(ctx context.Context, m *Message) error
+}
+
+// SMTP sends email through an SMTP server, authenticating with PLAIN when
+// credentials are set
+type SMTP struct {
+	host     string
+	port     int
+	from     mail.Address
+	username string
+	password string
+	timeout  time.Duration
+	tls      *tls.Config
+	now      func() time.Time
+}
+
+// NewSender creates the sender cfg describes. It returns nil when the
+// provider is none.
+func NewSender(cfg config.EmailConfig) (Sender, error) {
+	switch cfg.Provider {
+	case "", "none":
+		return nil, nil
+	case "smtp":
+		return NewSMTP(cfg), nil
+	case "ses":
+		cfg.Host = "email-smtp." + cfg.Region + ".amazonaws.com"
+		return NewSMTP(cfg), nil
+	}
+	return nil, fmt.Errorf("unknown email provider %q", cfg.Provider)
+}
+
+// NewSMTP creates a sender through the SMTP server in cfg
+func NewSMTP(cfg config.EmailConfig) *SMTP {
+	return &SMTP{
+		host:     cfg.Host,
+		port:     cfg.Port,
+		from:     mail.Address{Name: cfg.FromName, Address: cfg.From},
+		username: cfg.Username,
+		password: cfg.Password,
+		timeout:  cfg.Timeout,
+		tls:      &tls.Config{ServerName: cfg.Host, MinVersion: tls.VersionTLS12},
+		now:      time.Now,
+	}
+}
+
+// Send sends m in one SMTP session, which ctx and the configured timeout
+// bound
+func (s *SMTP) Send(ctx context.Context, m *Message) error {
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return fmt.Errorf("%w: invalid recipient %q", ErrRejected, m.To)
+	}
+	to.Name = m.ToName
+	body, err := s.compose(m, to)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	var conn net.Conn
+	if s.port == implicitTLSPort {
+		conn, err = (&tls.Dialer{Config: s.tls}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp: %w", rejected(err))
+	}
+	defer client.Close()
+
+	if err := s.deliver(client, to.Address, body); err != nil {
+		return fmt.Errorf("smtp: %w", rejected(err))
+	}
+	return nil
+}
+
+// deliver runs the SMTP transaction sending body to rcpt
+func (s *SMTP) deliver(client *smtp.Client, rcpt string, body []byte) error {
+	if s.port != implicitTLSPort {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(s.tls); err != nil {
+				return err
+			}
+		}
+	}
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(s.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(rcpt); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// compose renders m as a MIME message with a quoted-printable HTML body
+func (s *SMTP) compose(m *Message, to *mail.Address) ([]byte, error) {
+	var b bytes.Buffer
+	header := func(key, value string) {
+		b.WriteString(key + ": " + value + "\r\n")
+	}
+	header("From", s.from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", oneLine(m.Subject)))
+	header("Date", s.now().Format(time.RFC1123Z))
+	if m.ID != "" {
+		header("Message-ID", "<"+oneLine(m.ID)+"@"+domain(s.from.Address)+">")
+	}
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/html; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&b)
+	if _, err := w.Write([]byte(m.HTML)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// rejected marks a permanent SMTP reply, in the 5xx range, with ErrRejected
+func rejected(err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return fmt.Errorf("%w: %w", ErrRejected, err)
+	}
+	return err
+}
+
+// oneLine keeps a header value on one line, so it cannot add headers
+func oneLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// domain returns the domain of an address
+func domain(address string) string {
+	if i := strings.LastIndexByte(address, '@'); i >= 0 {
+		return address[i+1:]
+	}
+	return "localhost"
+}
@@ -0,0 +1,158 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// fakeSMTP is an SMTP server accepting one session, answering RCPT with
+// rcptReply
+type fakeSMTP struct {
+	listener  net.Listener
+	rcptReply string
+	from      string
+	rcpt      string
+	data      chan string
+}
+
+func newFakeSMTP(t *testing.T, rcptReply string) *fakeSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	s := &fakeSMTP{listener: listener, rcptReply: rcptReply, data: make(chan string, 1)}
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTP) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+
+	tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			tp.PrintfLine("250 localhost")
+		case "MAIL":
+			s.from = arg
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			s.rcpt = arg
+			tp.PrintfLine("%s", s.rcptReply)
+		case "DATA":
+			tp.PrintfLine("354 Go ahead")
+			data, _ := io.ReadAll(tp.DotReader())
+			s.data <- string(data)
+			tp.PrintfLine("250 Queued")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("502 Unknown command")
+		}
+	}
+}
+
+func (s *fakeSMTP) sender() *SMTP {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return NewSMTP(config.EmailConfig{
+		Host:     addr.IP.String(),
+		Port:     addr.Port,
+		From:     "receipts@shop.example",
+		FromName: "Shop",
+		Timeout:  5 * time.Second,
+	})
+}
+
+func TestSMTPSendsMessage(t *testing.T) {
+	server := newFakeSMTP(t, "250 OK")
+
+	err := server.sender().Send(context.Background(), &Message{
+		ID:      "n-1",
+		To:      "ana@example.com",
+		ToName:  "Ana",
+		Subject: "Your order\r\nBcc: someone@example.com",
+		HTML:    "<p>Thanks for your order of 4 × coffee</p>",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "FROM:<receipts@shop.example>", server.from)
+	assert.Equal(t, "TO:<ana@example.com>", server.rcpt)
+
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(<-server.data)))
+	require.NoError(t, err)
+	assert.Equal(t, `"Shop" <receipts@shop.example>`, msg.Header.Get("From"))
+	assert.Equal(t, `"Ana" <ana@example.com>`, msg.Header.Get("To"))
+	assert.Equal(t, "Your order Bcc: someone@example.com", msg.Header.Get("Subject"))
+	assert.Empty(t, msg.Header.Get("Bcc"))
+	assert.Equal(t, "<n-1@shop.example>", msg.Header.Get("Message-ID"))
+	assert.Equal(t, `text/html; charset="utf-8"`, msg.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	require.NoError(t, err)
+	assert.Equal(t, "<p>Thanks for your order of 4 × coffee</p>", strings.TrimSpace(string(body)))
+}
+
+func TestSMTPRejections(t *testing.T) {
+	// A permanent refusal is not worth retrying
+	err := newFakeSMTP(t, "550 No such user").sender().Send(context.Background(), &Message{To: "gone@example.com"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrRejected))
+
+	// A temporary one is
+	err = newFakeSMTP(t, "451 Try again later").sender().Send(context.Background(), &Message{To: "busy@example.com"})
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrRejected))
+
+	// So is a server that cannot be reached
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	unreachable := NewSMTP(config.EmailConfig{Host: "127.0.0.1", Port: port, From: "a@b.example", Timeout: time.Second})
+	err = unreachable.Send(context.Background(), &Message{To: "ana@example.com"})
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrRejected))
+
+	// An address that does not parse never will
+	err = unreachable.Send(context.Background(), &Message{To: "not an address"})
+	assert.True(t, errors.Is(err, ErrRejected))
+}
+
+func TestNewSender(t *testing.T) {
+	sender, err := NewSender(config.EmailConfig{Provider: "none"})
+	require.NoError(t, err)
+	assert.Nil(t, sender)
+
+	sender, err = NewSender(config.EmailConfig{Provider: "ses", Region: "eu-west-1", Port: 587})
+	require.NoError(t, err)
+	ses := sender.(*SMTP)
+	assert.Equal(t, "email-smtp.eu-west-1.amazonaws.com", ses.host)
+	assert.Equal(t, 587, ses.port)
+
+	_, err = NewSender(config.EmailConfig{Provider: "pigeon"})
+	assert.Error(t, err)
+}
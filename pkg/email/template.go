@@ -0,0 +1,113 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*.tmpl
+var builtin embed.FS
+
+// DefaultTemplate is rendered for names without a template of their own
+const DefaultTemplate = "default"
+
+// Template file suffixes; the name is what precedes them
+const (
+	subjectSuffix = ".subject.tmpl"
+	htmlSuffix    = ".html.tmpl"
+)
+
+// TemplateData is what templates render
+type TemplateData struct {
+	Title         string
+	Message       string
+	Type          string
+	Priority      string
+	RecipientName string
+	Data          map[string]interface{} // The notification's data
+}
+
+// Templates renders the subject and HTML body of emails by name. A name's
+// subject comes from <name>.subject.tmpl, a text/template, and its body from
+// <name>.html.tmpl, an html/template escaping what it renders.
+type Templates struct {
+	subjects *texttemplate.Template
+	bodies   *htmltemplate.Template
+}
+
+// LoadTemplates loads the built-in templates, then those in dir, which
+// replace built-in ones of the same name. An empty dir loads only the
+// built-in templates.
+func LoadTemplates(dir string) (*Templates, error) {
+	t := &Templates{
+		subjects: texttemplate.New(""),
+		bodies:   htmltemplate.New(""),
+	}
+	if err := t.load(builtin, "templates"); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := t.load(os.DirFS(dir), "."); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// load parses the templates in dir of fsys
+func (t *Templates) load(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read email templates: %w", err)
+	}
+	for _, entry := range entries {
+		file := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, file))
+		if err != nil {
+			return fmt.Errorf("failed to read email template %s: %w", file, err)
+		}
+		if name, ok := strings.CutSuffix(file, subjectSuffix); ok {
+			_, err = t.subjects.New(name).Parse(string(content))
+		} else if name, ok := strings.CutSuffix(file, htmlSuffix); ok {
+			_, err = t.bodies.New(name).Parse(string(content))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse email template %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// Render renders the subject and HTML body of template name, each falling
+// back to DefaultTemplate when name has none
+func (t *Templates) Render(name string, data *TemplateData) (subject, html string, err error) {
+	subjectTemplate := t.subjects.Lookup(name)
+	if subjectTemplate == nil {
+		subjectTemplate = t.subjects.Lookup(DefaultTemplate)
+	}
+	var b bytes.Buffer
+	if err := subjectTemplate.Execute(&b, data); err != nil {
+		return "", "", fmt.Errorf("failed to render email subject: %w", err)
+	}
+	subject = oneLine(b.String())
+
+	bodyTemplate := t.bodies.Lookup(name)
+	if bodyTemplate == nil {
+		bodyTemplate = t.bodies.Lookup(DefaultTemplate)
+	}
+	b.Reset()
+	if err := bodyTemplate.Execute(&b, data); err != nil {
+		return "", "", fmt.Errorf("failed to render email body: %w", err)
+	}
+	return subject, b.String(), nil
+}
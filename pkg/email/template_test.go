@@ -0,0 +1,55 @@
+package email
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplatesRenderDefault(t *testing.T) {
+	templates, err := LoadTemplates("")
+	require.NoError(t, err)
+
+	subject, html, err := templates.Render("order", &TemplateData{
+		Title:         "Order shipped",
+		Message:       "Your order is on its way <soon>",
+		RecipientName: "Ana",
+		Data:          map[string]interface{}{"order_number": "ORD-1", "template": "order"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Order shipped", subject)
+	assert.Contains(t, html, "Hi Ana,")
+	assert.Contains(t, html, "Your order is on its way &lt;soon&gt;")
+	assert.Contains(t, html, "ORD-1")
+	assert.NotContains(t, html, ">template<")
+}
+
+func TestTemplatesFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "low_stock.subject.tmpl"),
+		[]byte("Low stock: {{.Data.sku}}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "low_stock.html.tmpl"),
+		[]byte("<p>{{.Data.sku}} has {{.Data.quantity}} left</p>"), 0o644))
+
+	templates, err := LoadTemplates(dir)
+	require.NoError(t, err)
+
+	data := &TemplateData{Title: "Stock", Data: map[string]interface{}{"sku": "<b>CF-1</b>", "quantity": 2}}
+	subject, html, err := templates.Render("low_stock", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Low stock: <b>CF-1</b>", subject)
+	assert.Equal(t, "<p>&lt;b&gt;CF-1&lt;/b&gt; has 2 left</p>", html)
+
+	// Other names still render the built-in template
+	subject, _, err = templates.Render("payment", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Stock", subject)
+
+	// A template that does not parse fails loading
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.html.tmpl"), []byte("{{.Title"), 0o644))
+	_, err = LoadTemplates(dir)
+	assert.Error(t, err)
+}
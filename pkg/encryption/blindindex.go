@@ -0,0 +1,68 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// minBlindIndexKeyLen is the shortest key accepted for blind indexes
+const minBlindIndexKeyLen = 32
+
+// BlindIndex computes keyed hashes of values stored encrypted, so a column
+// encrypted with random nonces can still be looked up by equality: the
+// repository stores Compute of each value beside its ciphertext and queries
+// by Compute of the value searched for.
+//
+// Equal values have equal indexes, so an index reveals which rows share a
+// value; keep it to columns that are looked up, and use a key of its own
+// rather than the master key. A nil BlindIndex computes empty indexes.
+type BlindIndex struct {
+	key []byte
+}
+
+// NewBlindIndex creates a blind index keyed by key. It returns nil for an
+// empty key.
+func NewBlindIndex(key []byte) (*BlindIndex, error) {
+	if len(key) == 0 {
+		return nil, nil
+	}
+	if len(key) < minBlindIndexKeyLen {
+		return nil, fmt.Errorf("blind index key must be at least %d bytes", minBlindIndexKeyLen)
+	}
+	return &BlindIndex{key: key}, nil
+}
+
+// ParseBlindIndexKey creates a blind index from a base64 key, as configured.
+// It returns nil for an empty key.
+func ParseBlindIndexKey(encoded string) (*BlindIndex, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blind index key: %w", err)
+	}
+	return NewBlindIndex(key)
+}
+
+// Compute returns the hex HMAC-SHA256 of value for the column named by
+// domain, e.g. "users.email". The domain keeps equal values in different
+// columns from having equal indexes. Values should be normalized first, as
+// with NormalizeEmail.
+func (b *BlindIndex) Compute(domain, value string) string {
+	if b == nil {
+		return ""
+	}
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(domain))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NormalizeEmail returns the form of an email address that is indexed, so
+// lookups ignore case and surrounding space
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
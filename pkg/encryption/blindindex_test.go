@@ -0,0 +1,44 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlindIndexIsDeterministicPerKeyAndDomain(t *testing.T) {
+	index, err := NewBlindIndex(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	other, err := NewBlindIndex(bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+
+	email := NormalizeEmail("  Ada@Example.COM ")
+	assert.Equal(t, "ada@example.com", email)
+
+	computed := index.Compute("users.email", email)
+	assert.Len(t, computed, 64)
+	assert.NotContains(t, computed, "ada")
+	assert.Equal(t, computed, index.Compute("users.email", NormalizeEmail("ada@example.com")))
+	assert.NotEqual(t, computed, index.Compute("users.phone", email))
+	assert.NotEqual(t, computed, other.Compute("users.email", email))
+}
+
+func TestParseBlindIndexKey(t *testing.T) {
+	index, err := ParseBlindIndexKey("")
+	require.NoError(t, err)
+	assert.Nil(t, index)
+	assert.Empty(t, index.Compute("users.email", "ada@example.com"))
+
+	_, err = ParseBlindIndexKey(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+
+	_, err = ParseBlindIndexKey("not base64!")
+	assert.Error(t, err)
+
+	index, err = ParseBlindIndexKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+	require.NoError(t, err)
+	assert.NotEmpty(t, index.Compute("users.email", "ada@example.com"))
+}
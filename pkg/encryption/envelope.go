@@ -0,0 +1,279 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+var (
+	ErrInvalidCiphertext = errors.New("invalid envelope ciphertext")
+	ErrUnknownKey        = errors.New("ciphertext was encrypted under an unknown master key")
+)
+
+// Envelope format version and algorithms, recorded in each ciphertext header
+const (
+	envelopeVersion = 1
+
+	AlgorithmAES256GCM byte = 1
+)
+
+// dataKeySize is the size of each object's AES-256 data key
+const dataKeySize = 32
+
+// KeyWrapper protects data keys with a master key that never leaves a key
+// management service, such as AWS KMS, GCP KMS, or Vault Transit
+type KeyWrapper interface {
+	// KeyID identifies the master key; it is recorded in every ciphertext
+	KeyID() string
+	WrapKey(ctx context.Context, dataKey []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// Envelope encrypts each object under a fresh data key, stored alongside the
+// ciphertext wrapped by the master key. Only the small data key travels to
+// the key management service; the payload is encrypted locally.
+//
+// A ciphertext is laid out as:
+//
+//	version (1) | algorithm (1) | key version (4) | key ID length (2) | key ID | wrapped key length (2) | wrapped key | nonce | sealed payload
+//
+// The header up to the nonce is authenticated along with the payload, so it
+// cannot be altered to point at another key.
+type Envelope struct {
+	keys *Keyring
+}
+
+// NewEnvelope creates an envelope encrypting under wrapper's master key,
+// as version 1 of a single-key keyring
+func NewEnvelope(wrapper KeyWrapper) *Envelope {
+	return NewKeyringEnvelope(NewKeyring(1, wrapper))
+}
+
+// NewKeyringEnvelope creates an envelope encrypting under the keyring's
+// current key and decrypting under any of its versions. It returns nil for a
+// nil keyring; the string methods of a nil Envelope store values as is.
+func NewKeyringEnvelope(keys *Keyring) *Envelope {
+	if keys == nil {
+		return nil
+	}
+	return &Envelope{keys: keys}
+}
+
+// Keyring returns the keys the envelope encrypts under
+func (e *Envelope) Keyring() *Keyring {
+	return e.keys
+}
+
+// Encrypt seals plaintext under a new data key. aad, which may be nil, must
+// be passed again to Decrypt; use it to bind a ciphertext to its owner, such
+// as a row ID.
+func (e *Envelope) Encrypt(ctx context.Context, plaintext, aad []byte) ([]byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+	version, wrapper := e.keys.Current()
+	wrapped, err := wrapper.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	header, err := envelopeHeader(AlgorithmAES256GCM, version, wrapper.KeyID(), wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return sealAESGCM(dataKey, header, plaintext, slices.Concat(header, aad))
+}
+
+// Decrypt opens a ciphertext produced by Encrypt under any key version still
+// in the keyring
+func (e *Envelope) Decrypt(ctx context.Context, ciphertext, aad []byte) ([]byte, error) {
+	h, err := parseEnvelopeHeader(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	wrapper, ok := e.keys.Version(h.keyVersion)
+	if !ok || h.keyID != wrapper.KeyID() {
+		return nil, fmt.Errorf("%w: %s version %d", ErrUnknownKey, h.keyID, h.keyVersion)
+	}
+
+	dataKey, err := wrapper.UnwrapKey(ctx, h.wrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return openAESGCM(dataKey, ciphertext[h.length:], slices.Concat(ciphertext[:h.length], aad))
+}
+
+// Reencrypt encrypts ciphertext again under the current key version. It
+// reports false and returns ciphertext as is when it already uses that
+// version.
+func (e *Envelope) Reencrypt(ctx context.Context, ciphertext, aad []byte) ([]byte, bool, error) {
+	version, err := KeyVersionOf(ciphertext)
+	if err != nil {
+		return nil, false, err
+	}
+	if current, _ := e.keys.Current(); version == current {
+		return ciphertext, false, nil
+	}
+
+	plaintext, err := e.Decrypt(ctx, ciphertext, aad)
+	if err != nil {
+		return nil, false, err
+	}
+	reencrypted, err := e.Encrypt(ctx, plaintext, aad)
+	if err != nil {
+		return nil, false, err
+	}
+	return reencrypted, true, nil
+}
+
+// KeyIDOf returns the master key a ciphertext was encrypted under
+func KeyIDOf(ciphertext []byte) (string, error) {
+	h, err := parseEnvelopeHeader(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return h.keyID, nil
+}
+
+// KeyVersionOf returns the keyring version a ciphertext was encrypted under
+func KeyVersionOf(ciphertext []byte) (uint32, error) {
+	h, err := parseEnvelopeHeader(ciphertext)
+	if err != nil {
+		return 0, err
+	}
+	return h.keyVersion, nil
+}
+
+// header is a parsed ciphertext header
+type header struct {
+	algorithm  byte
+	keyVersion uint32
+	keyID      string
+	wrappedKey []byte
+	length     int // Bytes before the nonce
+}
+
+// envelopeHeader encodes a ciphertext header
+func envelopeHeader(algorithm byte, keyVersion uint32, keyID string, wrapped []byte) ([]byte, error) {
+	if len(keyID) > 0xFFFF || len(wrapped) > 0xFFFF {
+		return nil, errors.New("key ID or wrapped key too long")
+	}
+	h := make([]byte, 0, 10+len(keyID)+len(wrapped))
+	h = append(h, envelopeVersion, algorithm)
+	h = binary.BigEndian.AppendUint32(h, keyVersion)
+	h = binary.BigEndian.AppendUint16(h, uint16(len(keyID)))
+	h = append(h, keyID...)
+	h = binary.BigEndian.AppendUint16(h, uint16(len(wrapped)))
+	h = append(h, wrapped...)
+	return h, nil
+}
+
+// parseEnvelopeHeader decodes the header at the start of a ciphertext
+func parseEnvelopeHeader(ciphertext []byte) (header, error) {
+	if len(ciphertext) < 6 || ciphertext[0] != envelopeVersion {
+		return header{}, ErrInvalidCiphertext
+	}
+	h := header{algorithm: ciphertext[1], keyVersion: binary.BigEndian.Uint32(ciphertext[2:])}
+	if h.algorithm != AlgorithmAES256GCM {
+		return header{}, fmt.Errorf("%w: unsupported algorithm %d", ErrInvalidCiphertext, h.algorithm)
+	}
+
+	offset := 6
+	field := func() ([]byte, bool) {
+		if len(ciphertext) < offset+2 {
+			return nil, false
+		}
+		n := int(binary.BigEndian.Uint16(ciphertext[offset:]))
+		offset += 2
+		if len(ciphertext) < offset+n {
+			return nil, false
+		}
+		value := ciphertext[offset : offset+n]
+		offset += n
+		return value, true
+	}
+
+	keyID, ok := field()
+	if !ok {
+		return header{}, ErrInvalidCiphertext
+	}
+	wrapped, ok := field()
+	if !ok {
+		return header{}, ErrInvalidCiphertext
+	}
+	h.keyID, h.wrappedKey, h.length = string(keyID), wrapped, offset
+	return h, nil
+}
+
+// sealAESGCM appends nonce and sealed plaintext to dst
+func sealAESGCM(key, dst, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(append(dst, nonce...), nonce, plaintext, aad), nil
+}
+
+// openAESGCM opens a nonce-prefixed sealed payload
+func openAESGCM(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	nonce, sealed := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, aad)
+}
+
+// newGCM creates an AES-GCM cipher for key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// LocalKeyWrapper wraps data keys with a master key held in process memory.
+// It suits development and tests; production keys belong in a KMS.
+type LocalKeyWrapper struct {
+	keyID     string
+	masterKey []byte
+}
+
+// NewLocalKeyWrapper creates a wrapper for a 32-byte master key
+func NewLocalKeyWrapper(keyID string, masterKey []byte) (*LocalKeyWrapper, error) {
+	if len(masterKey) != dataKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", dataKeySize, len(masterKey))
+	}
+	return &LocalKeyWrapper{keyID: keyID, masterKey: masterKey}, nil
+}
+
+// KeyID returns the master key's name
+func (w *LocalKeyWrapper) KeyID() string {
+	return w.keyID
+}
+
+// WrapKey encrypts dataKey under the master key
+func (w *LocalKeyWrapper) WrapKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	return sealAESGCM(w.masterKey, nil, dataKey, []byte(w.keyID))
+}
+
+// UnwrapKey decrypts a wrapped data key
+func (w *LocalKeyWrapper) UnwrapKey(_ context.Context, wrapped []byte) ([]byte, error) {
+	return openAESGCM(w.masterKey, wrapped, []byte(w.keyID))
+}
@@ -0,0 +1,65 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testWrapper(t *testing.T, keyID string) *LocalKeyWrapper {
+	t.Helper()
+	w, err := NewLocalKeyWrapper(keyID, bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+	return w
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	e := NewEnvelope(testWrapper(t, "master-1"))
+
+	ciphertext, err := e.Encrypt(ctx, []byte("4111 1111 1111 1111"), []byte("user:42"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), "4111")
+
+	keyID, err := KeyIDOf(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "master-1", keyID)
+
+	plaintext, err := e.Decrypt(ctx, ciphertext, []byte("user:42"))
+	require.NoError(t, err)
+	assert.Equal(t, "4111 1111 1111 1111", string(plaintext))
+
+	// Each object gets its own data key
+	again, err := e.Encrypt(ctx, []byte("4111 1111 1111 1111"), []byte("user:42"))
+	require.NoError(t, err)
+	assert.NotEqual(t, ciphertext, again)
+}
+
+func TestEnvelopeRejectsTampering(t *testing.T) {
+	ctx := context.Background()
+	e := NewEnvelope(testWrapper(t, "master-1"))
+	ciphertext, err := e.Encrypt(ctx, []byte("secret"), []byte("user:42"))
+	require.NoError(t, err)
+
+	_, err = e.Decrypt(ctx, ciphertext, []byte("user:43"))
+	assert.Error(t, err, "bound to its associated data")
+
+	flipped := bytes.Clone(ciphertext)
+	flipped[len(flipped)-1] ^= 1
+	_, err = e.Decrypt(ctx, flipped, []byte("user:42"))
+	assert.Error(t, err)
+
+	_, err = e.Decrypt(ctx, ciphertext[:5], nil)
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	_, err = NewEnvelope(testWrapper(t, "master-2")).Decrypt(ctx, ciphertext, []byte("user:42"))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestNewLocalKeyWrapperChecksKeySize(t *testing.T) {
+	_, err := NewLocalKeyWrapper("short", []byte("too short"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,154 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// FieldTag is the struct tag marking string fields for field-level
+// encryption, as in:
+//
+//	Phone string `json:"phone" encrypt:"pii"`
+const FieldTag = "encrypt"
+
+// fieldClassPII is the only class of encrypted field so far
+const fieldClassPII = "pii"
+
+// EncryptFields encrypts, in place, the string fields of the struct v points
+// to that are tagged encrypt:"pii", including those of nested structs and
+// struct pointers. aad binds the values to their row, as for EncryptString.
+//
+// Nested struct pointers are replaced by encrypted copies, so a shallow copy
+// of a domain object can be encrypted for storage without changing the
+// original.
+func (e *Envelope) EncryptFields(ctx context.Context, v any, aad []byte) error {
+	return walkFields(v, func(field reflect.Value) error {
+		if field.String() == "" || IsEncryptedString(field.String()) {
+			return nil
+		}
+		sealed, err := e.EncryptString(ctx, field.String(), aad)
+		if err != nil {
+			return err
+		}
+		field.SetString(sealed)
+		return nil
+	})
+}
+
+// DecryptFields decrypts, in place, the fields EncryptFields encrypted.
+// Fields stored before they were encrypted are left as is.
+func (e *Envelope) DecryptFields(ctx context.Context, v any, aad []byte) error {
+	return walkFields(v, func(field reflect.Value) error {
+		plaintext, err := e.DecryptString(ctx, field.String(), aad)
+		if err != nil {
+			return err
+		}
+		field.SetString(plaintext)
+		return nil
+	})
+}
+
+// ReencryptFields encrypts the fields of v again under the current key
+// version, reporting whether any changed
+func (e *Envelope) ReencryptFields(ctx context.Context, v any, aad []byte) (bool, error) {
+	if e == nil {
+		return false, nil
+	}
+	changed := false
+	err := walkFields(v, func(field reflect.Value) error {
+		if field.String() == "" {
+			return nil
+		}
+		sealed, ok, err := e.ReencryptString(ctx, field.String(), aad)
+		if err != nil {
+			return err
+		}
+		if ok {
+			field.SetString(sealed)
+			changed = true
+		}
+		return nil
+	})
+	return changed, err
+}
+
+// walkFields calls fn with each tagged string field of the struct v points to
+func walkFields(v any, fn func(field reflect.Value) error) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("encrypted fields need a non-nil struct pointer")
+	}
+	return walkStruct(rv.Elem(), fn)
+}
+
+// walkStruct visits the tagged fields of an addressable struct
+func walkStruct(rv reflect.Value, fn func(field reflect.Value) error) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		field := rv.Field(i)
+
+		if class, ok := sf.Tag.Lookup(FieldTag); ok {
+			if class != fieldClassPII || field.Kind() != reflect.String {
+				return fmt.Errorf("field %s.%s: only string fields can be tagged %s:%q", rt.Name(), sf.Name, FieldTag, fieldClassPII)
+			}
+			if err := fn(field); err != nil {
+				return fmt.Errorf("field %s.%s: %w", rt.Name(), sf.Name, err)
+			}
+			continue
+		}
+
+		switch {
+		case field.Kind() == reflect.Struct && hasTaggedFields(field.Type()):
+			if err := walkStruct(field, fn); err != nil {
+				return err
+			}
+		case field.Kind() == reflect.Pointer && !field.IsNil() && hasTaggedFields(field.Type().Elem()):
+			// Work on a copy so the struct the pointer is shared with keeps its values
+			copied := reflect.New(field.Elem().Type())
+			copied.Elem().Set(field.Elem())
+			if err := walkStruct(copied.Elem(), fn); err != nil {
+				return err
+			}
+			field.Set(copied)
+		}
+	}
+	return nil
+}
+
+// taggedTypes caches whether struct types have tagged fields
+var taggedTypes sync.Map // reflect.Type -> bool
+
+// hasTaggedFields reports whether struct type t has tagged fields, directly
+// or in nested structs
+func hasTaggedFields(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	if tagged, ok := taggedTypes.Load(t); ok {
+		return tagged.(bool)
+	}
+	taggedTypes.Store(t, false) // Ends the recursion of self-referencing types
+
+	tagged := false
+	for i := 0; i < t.NumField() && !tagged; i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		_, ok := sf.Tag.Lookup(FieldTag)
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		tagged = ok || hasTaggedFields(ft)
+	}
+	taggedTypes.Store(t, tagged)
+	return tagged
+}
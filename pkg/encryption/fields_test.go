@@ -0,0 +1,87 @@
+package encryption
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAddress struct {
+	Street  string `encrypt:"pii"`
+	Country string
+}
+
+type testCustomer struct {
+	Name     string
+	Phone    string `encrypt:"pii"`
+	Shipping *testAddress
+	Billing  testAddress
+}
+
+func TestEncryptFields(t *testing.T) {
+	ctx := context.Background()
+	e := NewEnvelope(testWrapper(t, "master-1"))
+
+	address := &testAddress{Street: "12 Main St", Country: "VN"}
+	customer := testCustomer{
+		Name:     "An",
+		Phone:    "+84 912 345 678",
+		Shipping: address,
+		Billing:  testAddress{Street: "1 Side Rd", Country: "VN"},
+	}
+
+	sealed := customer
+	require.NoError(t, e.EncryptFields(ctx, &sealed, []byte("c1")))
+	assert.True(t, IsEncryptedString(sealed.Phone))
+	assert.True(t, IsEncryptedString(sealed.Shipping.Street))
+	assert.True(t, IsEncryptedString(sealed.Billing.Street))
+	assert.Equal(t, "An", sealed.Name)
+	assert.Equal(t, "VN", sealed.Shipping.Country)
+	assert.Equal(t, "12 Main St", address.Street, "shared structs are copied, not encrypted in place")
+
+	require.NoError(t, e.DecryptFields(ctx, &sealed, []byte("c1")))
+	assert.Equal(t, customer.Phone, sealed.Phone)
+	assert.Equal(t, *customer.Shipping, *sealed.Shipping)
+	assert.Equal(t, customer.Billing, sealed.Billing)
+
+	require.NoError(t, e.EncryptFields(ctx, &sealed, []byte("c1")))
+	assert.Error(t, e.DecryptFields(ctx, &sealed, []byte("c2")), "bound to the row")
+}
+
+func TestReencryptFields(t *testing.T) {
+	ctx := context.Background()
+	keys := NewKeyring(1, testWrapper(t, "master-1"))
+	e := NewKeyringEnvelope(keys)
+
+	customer := testCustomer{Phone: "+84 912 345 678"}
+	require.NoError(t, e.EncryptFields(ctx, &customer, nil))
+
+	changed, err := e.ReencryptFields(ctx, &customer, nil)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	newKey, err := NewLocalKeyWrapper("master-2", make([]byte, 32))
+	require.NoError(t, err)
+	require.NoError(t, keys.Rotate(2, newKey))
+
+	changed, err = e.ReencryptFields(ctx, &customer, nil)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	require.NoError(t, keys.Retire(1))
+	require.NoError(t, e.DecryptFields(ctx, &customer, nil))
+	assert.Equal(t, "+84 912 345 678", customer.Phone)
+}
+
+func TestEncryptFieldsRejectsInvalidTargets(t *testing.T) {
+	e := NewEnvelope(testWrapper(t, "master-1"))
+
+	assert.Error(t, e.EncryptFields(context.Background(), testCustomer{}, nil), "needs a pointer")
+
+	type badTag struct {
+		Age int `encrypt:"pii"`
+	}
+	assert.Error(t, e.EncryptFields(context.Background(), &badTag{Age: 30}, nil))
+}
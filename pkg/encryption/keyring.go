@@ -0,0 +1,75 @@
+package encryption
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Keyring holds the numbered versions of a master key. New ciphertexts are
+// encrypted under the current version, and every version still in the ring
+// can decrypt, so a key is rotated online: rotate to the new version,
+// re-encrypt stored data, then retire the old one. It is safe for concurrent
+// use.
+type Keyring struct {
+	mu       sync.RWMutex
+	current  uint32
+	versions map[uint32]KeyWrapper
+}
+
+// NewKeyring creates a keyring whose current key is wrapper at version
+func NewKeyring(version uint32, wrapper KeyWrapper) *Keyring {
+	return &Keyring{
+		current:  version,
+		versions: map[uint32]KeyWrapper{version: wrapper},
+	}
+}
+
+// Add adds an older version, used only to decrypt
+func (k *Keyring) Add(version uint32, wrapper KeyWrapper) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.versions[version]; ok {
+		return fmt.Errorf("key version %d already exists", version)
+	}
+	k.versions[version] = wrapper
+	return nil
+}
+
+// Rotate makes wrapper the current key at version, which must be newer than
+// the current one. The previous version stays available to decrypt.
+func (k *Keyring) Rotate(version uint32, wrapper KeyWrapper) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if version <= k.current {
+		return fmt.Errorf("key version %d is not newer than current version %d", version, k.current)
+	}
+	k.versions[version] = wrapper
+	k.current = version
+	return nil
+}
+
+// Retire removes an old version once no ciphertext uses it
+func (k *Keyring) Retire(version uint32) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if version == k.current {
+		return fmt.Errorf("cannot retire current key version %d", version)
+	}
+	delete(k.versions, version)
+	return nil
+}
+
+// Current returns the version new ciphertexts are encrypted under
+func (k *Keyring) Current() (uint32, KeyWrapper) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current, k.versions[k.current]
+}
+
+// Version returns the key at version, if it is in the ring
+func (k *Keyring) Version(version uint32) (KeyWrapper, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	wrapper, ok := k.versions[version]
+	return wrapper, ok
+}
@@ -0,0 +1,48 @@
+package encryption
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	ctx := context.Background()
+	keys := NewKeyring(1, testWrapper(t, "master-1"))
+	e := NewKeyringEnvelope(keys)
+
+	old, err := e.Encrypt(ctx, []byte("JBSWY3DPEHPK3PXP"), nil)
+	require.NoError(t, err)
+
+	newKey, err := NewLocalKeyWrapper("master-2", make([]byte, 32))
+	require.NoError(t, err)
+	require.NoError(t, keys.Rotate(2, newKey))
+	assert.Error(t, keys.Rotate(2, newKey), "versions only move forward")
+
+	// Old ciphertexts still decrypt, new ones use the new version
+	plaintext, err := e.Decrypt(ctx, old, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", string(plaintext))
+
+	current, err := e.Encrypt(ctx, plaintext, nil)
+	require.NoError(t, err)
+	version, err := KeyVersionOf(current)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), version)
+
+	reencrypted, changed, err := e.Reencrypt(ctx, old, nil)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	_, changed, err = e.Reencrypt(ctx, reencrypted, nil)
+	require.NoError(t, err)
+	assert.False(t, changed, "already under the current version")
+
+	assert.Error(t, keys.Retire(2), "the current version cannot be retired")
+	require.NoError(t, keys.Retire(1))
+	_, err = e.Decrypt(ctx, old, nil)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	_, err = e.Decrypt(ctx, reencrypted, nil)
+	assert.NoError(t, err)
+}
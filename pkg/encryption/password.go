@@ -0,0 +1,160 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+var ErrUnsupportedHash = errors.New("unsupported password hash format")
+
+// Argon2Params are the Argon2id parameters of a password hash
+type Argon2Params struct {
+	Time      uint32
+	MemoryKiB uint32
+	Threads   uint8
+}
+
+// DefaultArgon2Params are used by HashPassword, and were used for hashes
+// stored before hashes recorded their parameters
+var DefaultArgon2Params = Argon2Params{Time: 2, MemoryKiB: 64 * 1024, Threads: 4}
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// PasswordHasher hashes passwords with Argon2id, encoded in PHC string format:
+//
+//	$argon2id$v=19$m=<memory KiB>,t=<time>,p=<threads>$<salt>$<hash>
+//
+// It verifies hashes made with any parameters, hashes in the format stored
+// before PHC (base64 of salt and hash, with DefaultArgon2Params), and bcrypt
+// hashes of migrated accounts.
+type PasswordHasher struct {
+	params Argon2Params
+}
+
+// NewPasswordHasher creates a hasher making hashes with cfg's parameters
+func NewPasswordHasher(cfg config.PasswordHashingConfig) *PasswordHasher {
+	return &PasswordHasher{params: Argon2Params{Time: cfg.Time, MemoryKiB: cfg.MemoryKiB, Threads: cfg.Threads}}
+}
+
+// defaultHasher backs HashPassword and VerifyPassword
+var defaultHasher = &PasswordHasher{params: DefaultArgon2Params}
+
+// Hash hashes a password
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := h.params
+	hash := argon2.IDKey([]byte(password), salt, p.Time, p.MemoryKiB, p.Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.MemoryKiB, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
+}
+
+// Verify checks a password against a hash. needsRehash is set for a matching
+// password whose hash is bcrypt, legacy, or made with other parameters; the
+// caller should then store Hash(password) in its place.
+func (h *PasswordHasher) Verify(password, encoded string) (ok, needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		params, salt, hash, err := parseArgon2id(encoded)
+		if err != nil {
+			return false, false, err
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.Time, params.MemoryKiB, params.Threads, uint32(len(hash)))
+		ok = subtle.ConstantTimeCompare(computed, hash) == 1
+		return ok, ok && params != h.params, nil
+
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		return err == nil, err == nil, err
+
+	case strings.HasPrefix(encoded, "$"):
+		return false, false, ErrUnsupportedHash
+
+	default:
+		ok, err := verifyLegacyArgon2(password, encoded)
+		return ok, ok, err
+	}
+}
+
+// parseArgon2id decodes a PHC-format Argon2id hash
+func parseArgon2id(encoded string) (Argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+	var p Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.MemoryKiB, &p.Time, &p.Threads); err != nil {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(hash) == 0 {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+	return p, salt, hash, nil
+}
+
+// verifyLegacyArgon2 checks a hash stored before PHC encoding: base64 of a
+// 16-byte salt followed by the hash, made with DefaultArgon2Params
+func verifyLegacyArgon2(password, encoded string) (bool, error) {
+	decoded, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	if len(decoded) < argon2SaltLen {
+		return false, errors.New("invalid hash format")
+	}
+
+	salt := decoded[:argon2SaltLen]
+	expectedHash := decoded[argon2SaltLen:]
+
+	p := DefaultArgon2Params
+	computedHash := argon2.IDKey([]byte(password), salt, p.Time, p.MemoryKiB, p.Threads, argon2KeyLen)
+
+	// Time-constant comparison
+	return subtle.ConstantTimeCompare(computedHash, expectedHash) == 1, nil
+}
+
+// HashPassword hashes a password using Argon2id with DefaultArgon2Params
+func HashPassword(password string) (string, error) {
+	return defaultHasher.Hash(password)
+}
+
+// VerifyPassword verifies a password against a hash in any format
+// PasswordHasher accepts
+func VerifyPassword(password, hash string) (bool, error) {
+	ok, _, err := defaultHasher.Verify(password, hash)
+	return ok, err
+}
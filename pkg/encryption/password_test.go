@@ -0,0 +1,87 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// testHasher uses cheap parameters to keep tests fast
+func testHasher() *PasswordHasher {
+	return NewPasswordHasher(config.PasswordHashingConfig{Time: 1, MemoryKiB: 8 * 1024, Threads: 1})
+}
+
+func TestPasswordHasherUsesPHCFormat(t *testing.T) {
+	h := testHasher()
+	hash, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=8192,t=1,p=1$"), hash)
+
+	ok, needsRehash, err := h.Verify("correct horse", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+
+	ok, _, err = h.Verify("battery staple", hash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestPasswordHasherRehashesOnParameterChange(t *testing.T) {
+	hash, err := testHasher().Hash("correct horse")
+	require.NoError(t, err)
+
+	stronger := NewPasswordHasher(config.PasswordHashingConfig{Time: 2, MemoryKiB: 8 * 1024, Threads: 1})
+	ok, needsRehash, err := stronger.Verify("correct horse", hash)
+	require.NoError(t, err)
+	assert.True(t, ok, "hashes record their own parameters")
+	assert.True(t, needsRehash)
+
+	_, needsRehash, err = stronger.Verify("wrong", hash)
+	require.NoError(t, err)
+	assert.False(t, needsRehash, "only verified passwords are re-hashed")
+}
+
+func TestPasswordHasherVerifiesBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	ok, needsRehash, err := testHasher().Verify("correct horse", string(legacy))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, needsRehash)
+
+	ok, needsRehash, err = testHasher().Verify("wrong", string(legacy))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, needsRehash)
+}
+
+func TestPasswordHasherVerifiesPrePHCHashes(t *testing.T) {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	require.NoError(t, err)
+	p := DefaultArgon2Params
+	hash := argon2.IDKey([]byte("correct horse"), salt, p.Time, p.MemoryKiB, p.Threads, 32)
+	legacy := base64.RawStdEncoding.EncodeToString(append(salt, hash...))
+
+	ok, needsRehash, err := testHasher().Verify("correct horse", legacy)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, needsRehash)
+}
+
+func TestPasswordHasherRejectsUnknownFormats(t *testing.T) {
+	for _, hash := range []string{"$scrypt$ln=15$abc$def", "$argon2id$v=19$m=8192$abc", "$argon2id$v=16$m=8192,t=1,p=1$c2FsdA$aGFzaA"} {
+		_, _, err := testHasher().Verify("password", hash)
+		assert.ErrorIs(t, err, ErrUnsupportedHash, hash)
+	}
+}
@@ -0,0 +1,211 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+)
+
+// sealedPrefix marks a text column value produced by EncryptString
+const sealedPrefix = "enc:"
+
+// EncryptString encrypts s for a text column
+func (e *Envelope) EncryptString(ctx context.Context, s string, aad []byte) (string, error) {
+	if e == nil {
+		return s, nil
+	}
+	ciphertext, err := e.Encrypt(ctx, []byte(s), aad)
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptString decrypts a value produced by EncryptString. Values stored
+// before the column was encrypted are returned as is.
+func (e *Envelope) DecryptString(ctx context.Context, s string, aad []byte) (string, error) {
+	ciphertext, ok, err := decodeSealed(s)
+	if err != nil || !ok {
+		return s, err
+	}
+	if e == nil {
+		return "", ErrUnknownKey
+	}
+	plaintext, err := e.Decrypt(ctx, ciphertext, aad)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// ReencryptString encrypts a text column value under the current key version,
+// encrypting plaintext left from before the column was encrypted. It reports
+// whether the value changed.
+func (e *Envelope) ReencryptString(ctx context.Context, s string, aad []byte) (string, bool, error) {
+	ciphertext, ok, err := decodeSealed(s)
+	if err != nil {
+		return "", false, err
+	}
+	if !ok {
+		sealed, err := e.EncryptString(ctx, s, aad)
+		return sealed, err == nil, err
+	}
+
+	reencrypted, changed, err := e.Reencrypt(ctx, ciphertext, aad)
+	if err != nil || !changed {
+		return s, false, err
+	}
+	return sealedPrefix + base64.StdEncoding.EncodeToString(reencrypted), true, nil
+}
+
+// IsEncryptedString reports whether s was produced by EncryptString
+func IsEncryptedString(s string) bool {
+	return strings.HasPrefix(s, sealedPrefix)
+}
+
+// decodeSealed returns the ciphertext of an EncryptString value, or false for
+// plaintext
+func decodeSealed(s string) ([]byte, bool, error) {
+	if !IsEncryptedString(s) {
+		return nil, false, nil
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(s[len(sealedPrefix):])
+	if err != nil {
+		return nil, false, ErrInvalidCiphertext
+	}
+	return ciphertext, true, nil
+}
+
+// SealedValue is a stored value encrypted with EncryptString
+type SealedValue struct {
+	ID    string
+	Value string
+	AAD   []byte // As passed to EncryptString
+}
+
+// SealedStore is a column of values encrypted with EncryptString, such as
+// MFA secrets, that can be re-encrypted in place
+type SealedStore interface {
+	// Name labels the store in logs and metrics
+	Name() string
+	// ScanSealed returns up to limit non-empty values with IDs after after,
+	// in ID order; after is empty for the first page
+	ScanSealed(ctx context.Context, after string, limit int) ([]SealedValue, error)
+	// SwapSealed replaces the value of id if it still equals old, reporting
+	// whether it did
+	SwapSealed(ctx context.Context, id, old, new string) (bool, error)
+}
+
+// ReencryptResult counts the values one pass over a store visited
+type ReencryptResult struct {
+	Scanned     int
+	Reencrypted int
+	Conflicts   int // Changed by a write during the pass; already current
+	Failed      int // Could not be decrypted, such as under a retired key
+}
+
+// ReencryptStore moves every value in store to the current key version,
+// batchSize values at a time. Values that fail to decrypt are counted and
+// skipped so one bad row does not stall the rotation.
+func (e *Envelope) ReencryptStore(ctx context.Context, store SealedStore, batchSize int) (ReencryptResult, error) {
+	var result ReencryptResult
+	if e == nil {
+		return result, nil
+	}
+	after := ""
+	for {
+		values, err := store.ScanSealed(ctx, after, batchSize)
+		if err != nil {
+			return result, err
+		}
+
+		for _, v := range values {
+			result.Scanned++
+			outcome, err := e.reencryptValue(ctx, store, v)
+			if err != nil && ctx.Err() != nil {
+				return result, err
+			}
+			switch outcome {
+			case "reencrypted":
+				result.Reencrypted++
+			case "conflict":
+				result.Conflicts++
+			case "failed":
+				result.Failed++
+				logger.FromContext(ctx).Warnf("Failed to re-encrypt %s %s: %v", store.Name(), v.ID, err)
+			}
+			if outcome != "" {
+				metrics.EncryptionReencrypted.WithLabelValues(store.Name(), outcome).Inc()
+			}
+		}
+
+		if len(values) < batchSize {
+			return result, nil
+		}
+		after = values[len(values)-1].ID
+	}
+}
+
+// reencryptValue re-encrypts one value, returning the outcome label, or an
+// empty one when the value was already current
+func (e *Envelope) reencryptValue(ctx context.Context, store SealedStore, v SealedValue) (string, error) {
+	reencrypted, changed, err := e.ReencryptString(ctx, v.Value, v.AAD)
+	if err != nil {
+		return "failed", err
+	}
+	if !changed {
+		return "", nil
+	}
+
+	swapped, err := store.SwapSealed(ctx, v.ID, v.Value, reencrypted)
+	if err != nil {
+		return "failed", err
+	}
+	if !swapped {
+		return "conflict", nil
+	}
+	return "reencrypted", nil
+}
+
+// RunReencryption re-encrypts the stores every interval until ctx is
+// cancelled, so data moves to a rotated key without downtime. An interval
+// of zero or a nil envelope disables it.
+func RunReencryption(ctx context.Context, envelope *Envelope, stores []SealedStore, batchSize int, interval time.Duration, log *logger.Logger) {
+	defer apperrors.Recover(ctx, "reencryption")
+
+	if interval <= 0 || envelope == nil {
+		return
+	}
+
+	pass := func() {
+		for _, store := range stores {
+			result, err := envelope.ReencryptStore(ctx, store, batchSize)
+			if err != nil {
+				log.Errorf("Failed to re-encrypt %s: %v", store.Name(), err)
+				continue
+			}
+			if result.Reencrypted > 0 || result.Failed > 0 {
+				log.Infof("Re-encrypted %d of %d %s values (%d failed)", result.Reencrypted, result.Scanned, store.Name(), result.Failed)
+			}
+		}
+	}
+
+	pass()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pass()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
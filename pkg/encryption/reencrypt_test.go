@@ -0,0 +1,112 @@
+package encryption
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is a SealedStore over a map
+type memoryStore struct {
+	values map[string]string
+	// beforeSwap runs before each swap, to simulate concurrent writes
+	beforeSwap func(id string)
+}
+
+func (s *memoryStore) Name() string { return "test" }
+
+func (s *memoryStore) ScanSealed(_ context.Context, after string, limit int) ([]SealedValue, error) {
+	ids := make([]string, 0, len(s.values))
+	for id := range s.values {
+		if id > after {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	var page []SealedValue
+	for _, id := range ids[:min(limit, len(ids))] {
+		page = append(page, SealedValue{ID: id, Value: s.values[id], AAD: []byte(id)})
+	}
+	return page, nil
+}
+
+func (s *memoryStore) SwapSealed(_ context.Context, id, old, new string) (bool, error) {
+	if s.beforeSwap != nil {
+		s.beforeSwap(id)
+	}
+	if s.values[id] != old {
+		return false, nil
+	}
+	s.values[id] = new
+	return true, nil
+}
+
+func TestEncryptStringPassesLegacyPlaintextThrough(t *testing.T) {
+	ctx := context.Background()
+	e := NewEnvelope(testWrapper(t, "master-1"))
+
+	sealed, err := e.EncryptString(ctx, "JBSWY3DPEHPK3PXP", []byte("u1"))
+	require.NoError(t, err)
+	assert.True(t, IsEncryptedString(sealed))
+
+	plaintext, err := e.DecryptString(ctx, sealed, []byte("u1"))
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+
+	plaintext, err = e.DecryptString(ctx, "JBSWY3DPEHPK3PXP", []byte("u1"))
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+
+	var disabled *Envelope
+	stored, err := disabled.EncryptString(ctx, "JBSWY3DPEHPK3PXP", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", stored)
+	_, err = disabled.DecryptString(ctx, sealed, []byte("u1"))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestReencryptStoreMovesValuesToCurrentKey(t *testing.T) {
+	ctx := context.Background()
+	keys := NewKeyring(1, testWrapper(t, "master-1"))
+	e := NewKeyringEnvelope(keys)
+
+	store := &memoryStore{values: map[string]string{"u1": "legacy-plaintext"}}
+	for _, id := range []string{"u2", "u3", "u4"} {
+		sealed, err := e.EncryptString(ctx, "secret-"+id, []byte(id))
+		require.NoError(t, err)
+		store.values[id] = sealed
+	}
+	store.values["u5"] = "enc:AQ==" // Corrupt
+
+	newKey, err := NewLocalKeyWrapper("master-2", make([]byte, 32))
+	require.NoError(t, err)
+	require.NoError(t, keys.Rotate(2, newKey))
+
+	// u3 is rewritten by the application while the pass runs
+	store.beforeSwap = func(id string) {
+		if id == "u3" {
+			store.values["u3"], _ = e.EncryptString(ctx, "changed", []byte("u3"))
+		}
+	}
+
+	result, err := e.ReencryptStore(ctx, store, 2)
+	require.NoError(t, err)
+	assert.Equal(t, ReencryptResult{Scanned: 5, Reencrypted: 3, Conflicts: 1, Failed: 1}, result)
+
+	require.NoError(t, keys.Retire(1))
+	for id, want := range map[string]string{"u1": "legacy-plaintext", "u2": "secret-u2", "u3": "changed", "u4": "secret-u4"} {
+		assert.True(t, IsEncryptedString(store.values[id]), id)
+		got, err := e.DecryptString(ctx, store.values[id], []byte(id))
+		require.NoError(t, err, id)
+		assert.Equal(t, want, got)
+	}
+
+	// A second pass has nothing left to do
+	store.beforeSwap = nil
+	result, err = e.ReencryptStore(ctx, store, 2)
+	require.NoError(t, err)
+	assert.Equal(t, ReencryptResult{Scanned: 5, Failed: 1}, result)
+}
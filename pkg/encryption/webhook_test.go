@@ -0,0 +1,76 @@
+package encryption
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerifyPayload(t *testing.T) {
+	payload := []byte(`{"type":"order.created"}`)
+	now := time.Now()
+
+	header := SignPayload(payload, now, "whsec_new")
+	assert.True(t, strings.HasPrefix(header, "t="))
+	assert.Contains(t, header, ",v1=")
+
+	signedAt, err := VerifyPayload(payload, header, []string{"whsec_new"}, 5*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, now.Unix(), signedAt.Unix())
+
+	_, err = VerifyPayload([]byte(`{"type":"order.cancelled"}`), header, []string{"whsec_new"}, 5*time.Minute)
+	assert.ErrorIs(t, err, ErrSignatureMismatch)
+	_, err = VerifyPayload(payload, header, []string{"whsec_other"}, 5*time.Minute)
+	assert.ErrorIs(t, err, ErrSignatureMismatch)
+}
+
+func TestVerifyPayloadRejectsReplays(t *testing.T) {
+	payload := []byte("body")
+	header := SignPayload(payload, time.Now().Add(-10*time.Minute), "secret")
+
+	_, err := VerifyPayload(payload, header, []string{"secret"}, 5*time.Minute)
+	assert.ErrorIs(t, err, ErrSignatureExpired)
+
+	_, err = VerifyPayload(payload, header, []string{"secret"}, 0)
+	assert.NoError(t, err, "a zero tolerance skips the window")
+}
+
+func TestPayloadSecretRotation(t *testing.T) {
+	payload := []byte("body")
+
+	// The sender signs with both secrets while receivers move over
+	header := SignPayload(payload, time.Now(), "old", "new")
+	assert.Equal(t, 2, strings.Count(header, "v1="))
+	for _, accepted := range [][]string{{"old"}, {"new"}, {"retired", "new"}} {
+		_, err := VerifyPayload(payload, header, accepted, time.Minute)
+		assert.NoError(t, err, accepted)
+	}
+
+	// A receiver accepting both verifies a sender that already moved on
+	_, err := VerifyPayload(payload, SignPayload(payload, time.Now(), "new"), []string{"old", "new"}, time.Minute)
+	assert.NoError(t, err)
+}
+
+func TestVerifyPayloadRejectsMalformedHeaders(t *testing.T) {
+	for _, header := range []string{"", "t=123", "v1=abcd", "t=abc,v1=abcd", "t=123,v1=zz", "garbage"} {
+		_, err := VerifyPayload([]byte("body"), header, []string{"secret"}, 0)
+		assert.ErrorIs(t, err, ErrSignatureMalformed, header)
+	}
+}
+
+func TestVerifyPayloadMACIgnoresSpelling(t *testing.T) {
+	payload := []byte("body")
+	header := SignPayload(payload, time.Now(), "secret")
+	_, mac, err := VerifyPayloadMAC(payload, header, []string{"secret"}, time.Minute)
+	require.NoError(t, err)
+
+	respelled := strings.ToUpper(strings.Replace(header, ",", " , ", 1))
+	respelled = strings.Replace(respelled, "T=", "t=", 1)
+	respelled = strings.Replace(respelled, "V1=", "v1=", 1)
+	_, again, err := VerifyPayloadMAC(payload, respelled, []string{"secret"}, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, mac, again)
+}
@@ -0,0 +1,62 @@
+package errors
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// httpComponent tags reports raised while serving a request
+const httpComponent = "http"
+
+// PanicHandler reports a panic recovered by Fiber's recover middleware. Use it
+// as the StackTraceHandler with EnableStackTrace set; the recover middleware
+// still turns the panic into a 500 response.
+func PanicHandler(c *fiber.Ctx, recovered interface{}) {
+	CapturePanic(c.UserContext(), recovered, requestInfo(c))
+}
+
+// Middleware reports requests that fail with a 5xx status, whether the handler
+// returned an error or wrote the response itself. Client errors are not reported.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		if !Enabled() {
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// The error handler has not written the response yet
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+		if status < fiber.StatusInternalServerError {
+			return err
+		}
+
+		info := requestInfo(c)
+		if err != nil {
+			Capture(c.UserContext(), err, info)
+		} else {
+			CaptureMessage(c.UserContext(), fmt.Sprintf("%s %s responded %d", info.Method, info.Route, status), info)
+		}
+		return err
+	}
+}
+
+// requestInfo describes the request being served
+func requestInfo(c *fiber.Ctx) Info {
+	info := Info{
+		Component: httpComponent,
+		Method:    utils.CopyString(c.Method()), // Reports are sent after the request buffer is reused
+		Route:     c.Route().Path,
+	}
+	if userID, ok := c.Locals("user_id").(string); ok {
+		info.UserID = userID
+	}
+	return info
+}
@@ -0,0 +1,165 @@
+// Package errors reports panics and server errors to Sentry or a compatible
+// backend (GlitchTip, self-hosted Sentry). Reporting is disabled until Init is
+// called with a DSN; until then every function here is a no-op.
+package errors
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// flushTimeout bounds how long a crashing goroutine waits for its report to be sent
+const flushTimeout = 2 * time.Second
+
+// enabled is set once a client with a DSN has been installed
+var enabled atomic.Bool
+
+// Info describes where an error happened. Request ID, tenant, and trace ID are
+// also read from the context passed alongside it.
+type Info struct {
+	Component string // Consumer, worker, or "http"
+	Method    string
+	Route     string
+	UserID    string
+}
+
+// Init configures reporting for a service. Without a DSN reporting stays
+// disabled and Init returns nil.
+func Init(serviceName, environment string, cfg config.ErrorReportingConfig) error {
+	if cfg.DSN == "" {
+		return nil
+	}
+	return initClient(sentry.ClientOptions{
+		Dsn:         cfg.DSN,
+		Environment: environment,
+		Release:     cfg.Release,
+		ServerName:  serviceName,
+		SampleRate:  cfg.SampleRate,
+	})
+}
+
+// initClient installs a client with the given options
+func initClient(options sentry.ClientOptions) error {
+	if err := sentry.Init(options); err != nil {
+		return fmt.Errorf("failed to initialize error reporting: %w", err)
+	}
+	enabled.Store(true)
+	return nil
+}
+
+// Enabled reports whether errors are being shipped
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Flush waits up to timeout for queued reports to be sent; call it before exiting
+func Flush(timeout time.Duration) bool {
+	if !Enabled() {
+		return true
+	}
+	return sentry.Flush(timeout)
+}
+
+// Capture reports an error
+func Capture(ctx context.Context, err error, info Info) {
+	if !Enabled() || err == nil {
+		return
+	}
+	withScope(ctx, info, func(hub *sentry.Hub) {
+		hub.CaptureException(err)
+	})
+}
+
+// CaptureMessage reports a failure that has no error value, such as a handler
+// writing a 5xx response itself
+func CaptureMessage(ctx context.Context, message string, info Info) {
+	if !Enabled() {
+		return
+	}
+	withScope(ctx, info, func(hub *sentry.Hub) {
+		hub.CaptureMessage(message)
+	})
+}
+
+// CapturePanic reports a recovered panic value. Call it from the deferred
+// function that recovered, so the stack trace points at the panic.
+func CapturePanic(ctx context.Context, recovered interface{}, info Info) {
+	if !Enabled() || recovered == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	withScope(ctx, info, func(hub *sentry.Hub) {
+		hub.Scope().SetLevel(sentry.LevelFatal)
+		hub.RecoverWithContext(ctx, recovered)
+	})
+}
+
+// Guard runs fn, turning a panic into an error after reporting it. Message
+// consumers and worker tasks use it so one bad message or task is retried or
+// dropped instead of crashing the process.
+func Guard(ctx context.Context, component string, fn func() error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			CapturePanic(ctx, recovered, Info{Component: component})
+			err = fmt.Errorf("%s panicked: %v", component, recovered)
+		}
+	}()
+	return fn()
+}
+
+// Recover reports a panic in a background goroutine and re-panics once the
+// report is sent, so the process still crashes as it would without reporting.
+// Use it as the first deferred call: defer errors.Recover(ctx, "audit-recorder").
+func Recover(ctx context.Context, component string) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	CapturePanic(ctx, recovered, Info{Component: component})
+	Flush(flushTimeout)
+	panic(recovered)
+}
+
+// withScope captures on a hub whose scope describes ctx and info
+func withScope(ctx context.Context, info Info, capture func(hub *sentry.Hub)) {
+	hub := sentry.CurrentHub().Clone()
+	scope := hub.Scope()
+
+	if info.Component != "" {
+		scope.SetTag("component", info.Component)
+	}
+	if info.Method != "" {
+		scope.SetTag("method", info.Method)
+	}
+	if info.Route != "" {
+		scope.SetTag("route", info.Route)
+	}
+	if info.UserID != "" {
+		scope.SetUser(sentry.User{ID: info.UserID})
+	}
+
+	if ctx != nil {
+		if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+			scope.SetTag("request_id", requestID)
+		}
+		if tenantID := tenant.IDFromContext(ctx); tenantID != "" {
+			scope.SetTag("tenant_id", tenantID)
+		}
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+			scope.SetTag("trace_id", spanContext.TraceID().String())
+		}
+	}
+
+	capture(hub)
+}
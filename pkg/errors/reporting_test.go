@@ -0,0 +1,99 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// mockReporting installs a client that keeps events in memory
+func mockReporting(t *testing.T) *sentry.MockTransport {
+	transport := &sentry.MockTransport{}
+	require.NoError(t, initClient(sentry.ClientOptions{
+		Dsn:       "https://public@sentry.example.com/1",
+		Transport: transport,
+	}))
+	t.Cleanup(func() { enabled.Store(false) })
+	return transport
+}
+
+func TestDisabledWithoutDSN(t *testing.T) {
+	require.NoError(t, Init("order-service", "test", config.ErrorReportingConfig{SampleRate: 1}))
+	assert.False(t, Enabled())
+	assert.True(t, Flush(time.Millisecond))
+}
+
+func TestGuardReportsPanic(t *testing.T) {
+	transport := mockReporting(t)
+	ctx := logger.ContextWithRequestID(context.Background(), "req-1")
+
+	err := Guard(ctx, "kafka:orders", func() error {
+		panic("boom")
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+
+	events := transport.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, sentry.LevelFatal, events[0].Level)
+	assert.Equal(t, "kafka:orders", events[0].Tags["component"])
+	assert.Equal(t, "req-1", events[0].Tags["request_id"])
+}
+
+func TestGuardPassesErrorsThrough(t *testing.T) {
+	transport := mockReporting(t)
+
+	want := fmt.Errorf("temporary failure")
+	assert.Equal(t, want, Guard(context.Background(), "worker", func() error { return want }))
+	assert.Empty(t, transport.Events())
+}
+
+func TestMiddlewareReportsServerErrors(t *testing.T) {
+	transport := mockReporting(t)
+
+	app := fiber.New()
+	app.Use(recover.New(recover.Config{EnableStackTrace: true, StackTraceHandler: PanicHandler}))
+	app.Use(Middleware())
+	app.Get("/orders/:id", func(c *fiber.Ctx) error {
+		c.Locals("user_id", "user-7")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get order"})
+	})
+	app.Get("/missing", func(c *fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusNotFound, "not found")
+	})
+	app.Get("/panic", func(c *fiber.Ctx) error {
+		panic("handler bug")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/missing", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	assert.Empty(t, transport.Events(), "client errors are not reported")
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/orders/42", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/panic", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+
+	events := transport.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "/orders/:id", events[0].Tags["route"])
+	assert.Equal(t, "GET", events[0].Tags["method"])
+	assert.Equal(t, "user-7", events[0].User.ID)
+	assert.Equal(t, "/panic", events[1].Tags["route"])
+	assert.Equal(t, sentry.LevelFatal, events[1].Level)
+}
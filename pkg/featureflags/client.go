@@ -0,0 +1,126 @@
+package featureflags
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// Client evaluates flags from a store, caching them locally for a short TTL so
+// evaluation on the request path does not hit the store every time
+type Client struct {
+	store  Store
+	ttl    time.Duration
+	logger *logger.Logger
+
+	mu        sync.RWMutex
+	flags     map[string]*Flag
+	refreshed time.Time
+}
+
+// NewClient creates a flag client
+func NewClient(store Store, ttl time.Duration, log *logger.Logger) *Client {
+	return &Client{
+		store:  store,
+		ttl:    ttl,
+		logger: log,
+		flags:  make(map[string]*Flag),
+	}
+}
+
+// IsEnabled evaluates a flag; unknown flags and store errors evaluate to false
+func (c *Client) IsEnabled(ctx context.Context, key string, ec EvalContext) bool {
+	flags, err := c.snapshot(ctx)
+	if err != nil {
+		c.logger.Warnf("Feature flag %s evaluated as off: %v", key, err)
+		return false
+	}
+
+	flag, ok := flags[key]
+	if !ok {
+		return false
+	}
+	return flag.Evaluate(ec)
+}
+
+// Enabled evaluates a flag for the user and store of the current request
+func (c *Client) Enabled(ctx *fiber.Ctx, key string) bool {
+	return c.IsEnabled(ctx.UserContext(), key, EvalContextFromFiber(ctx))
+}
+
+// Require returns a middleware that hides a route (404) while its flag is off
+func (c *Client) Require(key string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if !c.Enabled(ctx, key) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Not found",
+			})
+		}
+		return ctx.Next()
+	}
+}
+
+// Invalidate drops the local cache so the next evaluation reads the store
+func (c *Client) Invalidate() {
+	c.mu.Lock()
+	c.refreshed = time.Time{}
+	c.mu.Unlock()
+}
+
+// snapshot returns the cached flags, refreshing them when the TTL has expired.
+// A failed refresh keeps serving the previous snapshot.
+func (c *Client) snapshot(ctx context.Context) (map[string]*Flag, error) {
+	c.mu.RLock()
+	flags, fresh := c.flags, time.Since(c.refreshed) < c.ttl
+	c.mu.RUnlock()
+	if fresh {
+		return flags, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another request may have refreshed while we waited for the lock
+	if time.Since(c.refreshed) < c.ttl {
+		return c.flags, nil
+	}
+
+	list, err := c.store.List(ctx)
+	if err != nil {
+		if len(c.flags) > 0 {
+			c.logger.Warnf("Failed to refresh feature flags, using cached values: %v", err)
+			// Wait a full TTL before trying the store again
+			c.refreshed = time.Now()
+			return c.flags, nil
+		}
+		return nil, err
+	}
+
+	next := make(map[string]*Flag, len(list))
+	for _, flag := range list {
+		next[flag.Key] = flag
+	}
+	c.flags = next
+	c.refreshed = time.Now()
+	return next, nil
+}
+
+// EvalContextFromFiber builds an evaluation context from the authenticated user and
+// the store the request is for (X-Store-ID header or store_id local)
+func EvalContextFromFiber(ctx *fiber.Ctx) EvalContext {
+	var ec EvalContext
+	if userID, ok := ctx.Locals("user_id").(string); ok {
+		ec.UserID = userID
+	}
+	if storeID, ok := ctx.Locals("store_id").(string); ok {
+		ec.StoreID = storeID
+	}
+	if ec.StoreID == "" {
+		ec.StoreID = ctx.Get("X-Store-ID")
+	}
+	return ec
+}
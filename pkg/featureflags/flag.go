@@ -0,0 +1,69 @@
+package featureflags
+
+import (
+	"errors"
+	"hash/fnv"
+	"slices"
+	"time"
+)
+
+// ErrNotFound is returned when a flag does not exist
+var ErrNotFound = errors.New("feature flag not found")
+
+// Flag is a feature toggle with optional targeting and percentage rollout
+type Flag struct {
+	Key         string    `json:"key" validate:"required,max=100"`
+	Description string    `json:"description"`
+	Enabled     bool      `json:"enabled"`
+	Rollout     int       `json:"rollout" validate:"min=0,max=100"` // Percentage of users/stores that get the flag
+	Users       []string  `json:"users,omitempty"`                  // Always enabled for these user IDs
+	Stores      []string  `json:"stores,omitempty"`                 // Always enabled for these store IDs
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// EvalContext identifies who a flag is evaluated for
+type EvalContext struct {
+	UserID  string
+	StoreID string
+}
+
+// Evaluate reports whether the flag is on for the given context. A disabled flag is
+// always off; targeted users and stores are always on; everyone else is bucketed
+// deterministically so a user keeps the same result as the rollout grows.
+func (f *Flag) Evaluate(ec EvalContext) bool {
+	if !f.Enabled {
+		return false
+	}
+
+	if ec.UserID != "" && slices.Contains(f.Users, ec.UserID) {
+		return true
+	}
+	if ec.StoreID != "" && slices.Contains(f.Stores, ec.StoreID) {
+		return true
+	}
+
+	if f.Rollout >= 100 {
+		return true
+	}
+	if f.Rollout <= 0 {
+		return false
+	}
+
+	// Bucket by user, falling back to store for unauthenticated terminals
+	unit := ec.UserID
+	if unit == "" {
+		unit = ec.StoreID
+	}
+	if unit == "" {
+		return false
+	}
+
+	return bucket(f.Key, unit) < f.Rollout
+}
+
+// bucket maps a flag/unit pair to a stable value in [0, 100)
+func bucket(key, unit string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key + ":" + unit))
+	return int(h.Sum32() % 100)
+}
@@ -0,0 +1,103 @@
+package featureflags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+func TestFlagEvaluate(t *testing.T) {
+	flag := &Flag{Key: "new-checkout", Enabled: true, Users: []string{"u-1"}, Stores: []string{"s-1"}}
+
+	assert.True(t, flag.Evaluate(EvalContext{UserID: "u-1"}))
+	assert.True(t, flag.Evaluate(EvalContext{UserID: "u-2", StoreID: "s-1"}))
+	assert.False(t, flag.Evaluate(EvalContext{UserID: "u-2"}))
+
+	flag.Rollout = 100
+	assert.True(t, flag.Evaluate(EvalContext{UserID: "u-2"}))
+
+	flag.Enabled = false
+	assert.False(t, flag.Evaluate(EvalContext{UserID: "u-1"}))
+}
+
+func TestFlagRolloutIsStickyAndProportional(t *testing.T) {
+	flag := &Flag{Key: "inventory-v2", Enabled: true, Rollout: 30}
+
+	enabled := 0
+	for i := 0; i < 10000; i++ {
+		ec := EvalContext{UserID: fmt.Sprintf("user-%d", i)}
+		result := flag.Evaluate(ec)
+		assert.Equal(t, result, flag.Evaluate(ec))
+		if result {
+			enabled++
+		}
+	}
+	assert.InDelta(t, 3000, enabled, 300)
+
+	// Growing the rollout never turns a flag off for someone who had it
+	grown := &Flag{Key: "inventory-v2", Enabled: true, Rollout: 60}
+	for i := 0; i < 1000; i++ {
+		ec := EvalContext{UserID: fmt.Sprintf("user-%d", i)}
+		if flag.Evaluate(ec) {
+			assert.True(t, grown.Evaluate(ec))
+		}
+	}
+}
+
+type memoryStore struct {
+	flags map[string]*Flag
+	err   error
+	lists int
+}
+
+func (m *memoryStore) Get(ctx context.Context, key string) (*Flag, error) {
+	if flag, ok := m.flags[key]; ok {
+		return flag, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memoryStore) List(ctx context.Context) ([]*Flag, error) {
+	m.lists++
+	if m.err != nil {
+		return nil, m.err
+	}
+	var flags []*Flag
+	for _, flag := range m.flags {
+		flags = append(flags, flag)
+	}
+	return flags, nil
+}
+
+func (m *memoryStore) Save(ctx context.Context, flag *Flag) error {
+	m.flags[flag.Key] = flag
+	return nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, key string) error {
+	delete(m.flags, key)
+	return nil
+}
+
+func TestClientCachesAndFallsBack(t *testing.T) {
+	store := &memoryStore{flags: map[string]*Flag{
+		"receipts": {Key: "receipts", Enabled: true, Rollout: 100},
+	}}
+	client := NewClient(store, time.Minute, logger.New("test"))
+	ctx := context.Background()
+
+	assert.True(t, client.IsEnabled(ctx, "receipts", EvalContext{UserID: "u"}))
+	assert.False(t, client.IsEnabled(ctx, "unknown", EvalContext{UserID: "u"}))
+	assert.Equal(t, 1, store.lists)
+
+	// A failing store keeps serving the last snapshot
+	store.err = errors.New("redis down")
+	client.Invalidate()
+	assert.True(t, client.IsEnabled(ctx, "receipts", EvalContext{UserID: "u"}))
+}
@@ -0,0 +1,145 @@
+package featureflags
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// UpsertFlagRequest is the body of PUT /feature-flags/:key
+type UpsertFlagRequest struct {
+	Description string   `json:"description" validate:"max=500"`
+	Enabled     bool     `json:"enabled"`
+	Rollout     int      `json:"rollout" validate:"min=0,max=100"`
+	Users       []string `json:"users"`
+	Stores      []string `json:"stores"`
+}
+
+// Handler serves the feature flag admin API
+type Handler struct {
+	store  Store
+	client *Client
+}
+
+// NewHandler creates an admin handler; client may be nil
+func NewHandler(store Store, client *Client) *Handler {
+	return &Handler{
+		store:  store,
+		client: client,
+	}
+}
+
+// RegisterRoutes mounts the admin endpoints on router
+func (h *Handler) RegisterRoutes(router fiber.Router) {
+	router.Get("/", h.ListFlags)
+	router.Get("/:key", h.GetFlag)
+	router.Put("/:key", h.UpsertFlag)
+	router.Delete("/:key", h.DeleteFlag)
+	router.Get("/:key/evaluate", h.EvaluateFlag)
+}
+
+// ListFlags handles GET /feature-flags
+func (h *Handler) ListFlags(c *fiber.Ctx) error {
+	flags, err := h.store.List(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch feature flags",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": flags,
+	})
+}
+
+// GetFlag handles GET /feature-flags/:key
+func (h *Handler) GetFlag(c *fiber.Ctx) error {
+	flag, err := h.store.Get(c.UserContext(), c.Params("key"))
+	if err != nil {
+		return h.storeError(c, err)
+	}
+
+	return c.JSON(flag)
+}
+
+// UpsertFlag handles PUT /feature-flags/:key
+func (h *Handler) UpsertFlag(c *fiber.Ctx) error {
+	var req UpsertFlagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	flag := &Flag{
+		Key:         c.Params("key"),
+		Description: req.Description,
+		Enabled:     req.Enabled,
+		Rollout:     req.Rollout,
+		Users:       req.Users,
+		Stores:      req.Stores,
+	}
+
+	if validationErrors := validator.ValidateStruct(flag); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	if err := h.store.Save(c.UserContext(), flag); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save feature flag",
+		})
+	}
+	h.invalidate()
+
+	return c.JSON(flag)
+}
+
+// DeleteFlag handles DELETE /feature-flags/:key
+func (h *Handler) DeleteFlag(c *fiber.Ctx) error {
+	if err := h.store.Delete(c.UserContext(), c.Params("key")); err != nil {
+		return h.storeError(c, err)
+	}
+	h.invalidate()
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// EvaluateFlag handles GET /feature-flags/:key/evaluate?user_id=&store_id=
+func (h *Handler) EvaluateFlag(c *fiber.Ctx) error {
+	flag, err := h.store.Get(c.UserContext(), c.Params("key"))
+	if err != nil {
+		return h.storeError(c, err)
+	}
+
+	ec := EvalContext{UserID: c.Query("user_id"), StoreID: c.Query("store_id")}
+	return c.JSON(fiber.Map{
+		"key":      flag.Key,
+		"user_id":  ec.UserID,
+		"store_id": ec.StoreID,
+		"enabled":  flag.Evaluate(ec),
+	})
+}
+
+// invalidate makes local changes visible immediately on this instance
+func (h *Handler) invalidate() {
+	if h.client != nil {
+		h.client.Invalidate()
+	}
+}
+
+// storeError maps store errors to responses
+func (h *Handler) storeError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Feature flag not found",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to fetch feature flag",
+	})
+}
@@ -0,0 +1,190 @@
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// Store persists feature flags
+type Store interface {
+	Get(ctx context.Context, key string) (*Flag, error)
+	List(ctx context.Context) ([]*Flag, error)
+	Save(ctx context.Context, flag *Flag) error
+	Delete(ctx context.Context, key string) error
+}
+
+// redisFlagsKey is the Redis hash holding all flags as JSON
+const redisFlagsKey = "featureflags"
+
+// RedisStore keeps flags in a Redis hash
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a Redis-backed flag store
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Get returns a flag by key
+func (s *RedisStore) Get(ctx context.Context, key string) (*Flag, error) {
+	data, err := s.client.HGet(ctx, redisFlagsKey, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var flag Flag
+	if err := json.Unmarshal(data, &flag); err != nil {
+		return nil, fmt.Errorf("failed to decode flag %s: %w", key, err)
+	}
+	return &flag, nil
+}
+
+// List returns all flags
+func (s *RedisStore) List(ctx context.Context) ([]*Flag, error) {
+	values, err := s.client.HGetAll(ctx, redisFlagsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make([]*Flag, 0, len(values))
+	for key, data := range values {
+		var flag Flag
+		if err := json.Unmarshal([]byte(data), &flag); err != nil {
+			return nil, fmt.Errorf("failed to decode flag %s: %w", key, err)
+		}
+		flags = append(flags, &flag)
+	}
+	return flags, nil
+}
+
+// Save creates or replaces a flag
+func (s *RedisStore) Save(ctx context.Context, flag *Flag) error {
+	flag.UpdatedAt = time.Now()
+	data, err := json.Marshal(flag)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, redisFlagsKey, flag.Key, data).Err()
+}
+
+// Delete removes a flag
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	removed, err := s.client.HDel(ctx, redisFlagsKey, key).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// PostgresStore keeps flags in the feature_flags table
+type PostgresStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresStore creates a Postgres-backed flag store
+func NewPostgresStore(db *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Get returns a flag by key
+func (s *PostgresStore) Get(ctx context.Context, key string) (*Flag, error) {
+	query := `
+		SELECT key, description, enabled, rollout, users, stores, updated_at
+		FROM feature_flags
+		WHERE key = $1
+	`
+
+	var flag Flag
+	err := s.db.QueryRow(ctx, query, key).Scan(
+		&flag.Key, &flag.Description, &flag.Enabled, &flag.Rollout, &flag.Users, &flag.Stores, &flag.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// List returns all flags
+func (s *PostgresStore) List(ctx context.Context) ([]*Flag, error) {
+	query := `
+		SELECT key, description, enabled, rollout, users, stores, updated_at
+		FROM feature_flags
+		ORDER BY key
+	`
+
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []*Flag
+	for rows.Next() {
+		var flag Flag
+		if err := rows.Scan(
+			&flag.Key, &flag.Description, &flag.Enabled, &flag.Rollout, &flag.Users, &flag.Stores, &flag.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		flags = append(flags, &flag)
+	}
+	return flags, rows.Err()
+}
+
+// Save creates or replaces a flag
+func (s *PostgresStore) Save(ctx context.Context, flag *Flag) error {
+	query := `
+		INSERT INTO feature_flags (key, description, enabled, rollout, users, stores, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (key) DO UPDATE SET
+			description = EXCLUDED.description,
+			enabled = EXCLUDED.enabled,
+			rollout = EXCLUDED.rollout,
+			users = EXCLUDED.users,
+			stores = EXCLUDED.stores,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	flag.UpdatedAt = time.Now()
+	_, err := s.db.Exec(ctx, query,
+		flag.Key, flag.Description, flag.Enabled, flag.Rollout, nonNil(flag.Users), nonNil(flag.Stores), flag.UpdatedAt,
+	)
+	return err
+}
+
+// Delete removes a flag
+func (s *PostgresStore) Delete(ctx context.Context, key string) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM feature_flags WHERE key = $1`, key)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// nonNil stores empty arrays rather than NULL
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
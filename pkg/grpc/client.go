@@ -0,0 +1,162 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// Dial creates a connection to the service at target, balanced round robin
+// over the addresses it resolves to. Calls without a deadline get
+// cfg.Timeout, calls failing with UNAVAILABLE are retried with backoff, and
+// each carries a service token of tokens for the tenant of its context, and
+// the request ID and trace of its context. The connection is made on the
+// first call.
+func Dial(target string, cfg config.GRPCConfig, tokens *auth.JWTManager) (*grpc.ClientConn, error) {
+	creds, err := clientCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+	serviceConfig, err := json.Marshal(newServiceConfig(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpc.NewClient(target,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(string(serviceConfig)),
+		grpc.WithChainUnaryInterceptor(deadlineInterceptor(cfg.Timeout), propagationInterceptor(tokens)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client of %s: %w", target, err)
+	}
+	return conn, nil
+}
+
+// serviceConfig is the gRPC service config of clients, see
+// https://github.com/grpc/grpc/blob/master/doc/service_config.md
+type serviceConfig struct {
+	LoadBalancingConfig []map[string]struct{} `json:"loadBalancingConfig"`
+	MethodConfig        []methodConfig        `json:"methodConfig,omitempty"`
+}
+
+type methodConfig struct {
+	Name        []struct{}   `json:"name"` // One empty name matches every method
+	RetryPolicy *retryPolicy `json:"retryPolicy,omitempty"`
+}
+
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+// newServiceConfig balances round robin and retries calls failing with
+// UNAVAILABLE, the status of calls that could not reach a server. Other
+// failures are left to the caller, as the server may have acted on them.
+func newServiceConfig(cfg config.GRPCConfig) serviceConfig {
+	sc := serviceConfig{LoadBalancingConfig: []map[string]struct{}{{"round_robin": {}}}}
+	if cfg.MaxAttempts > 1 {
+		sc.MethodConfig = []methodConfig{{
+			Name: []struct{}{{}},
+			RetryPolicy: &retryPolicy{
+				MaxAttempts:          cfg.MaxAttempts,
+				InitialBackoff:       seconds(cfg.InitialBackoff),
+				MaxBackoff:           seconds(cfg.MaxBackoff),
+				BackoffMultiplier:    2,
+				RetryableStatusCodes: []string{"UNAVAILABLE"},
+			},
+		}}
+	}
+	return sc
+}
+
+// seconds formats a duration as the service config expects, e.g. "0.1s"
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%gs", d.Seconds())
+}
+
+// deadlineInterceptor gives calls without a deadline one of timeout, shared
+// by all their attempts
+func deadlineInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// propagationInterceptor traces calls and sends a service token for the
+// tenant of their context, and their request ID and trace, as metadata
+func propagationInterceptor(tokens *auth.JWTManager) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := otel.Tracer("grpc").Start(ctx, method, trace.WithSpanKind(trace.SpanKindClient))
+		defer span.End()
+
+		md, _ := metadata.FromOutgoingContext(ctx)
+		md = md.Copy()
+		otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+		token, err := tokens.GenerateServiceToken(tenant.IDFromContext(ctx))
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to sign service token: %v", err)
+		}
+		md.Set(authorizationHeader, "Bearer "+token)
+		if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+			md.Set(logger.RequestIDHeader, requestID)
+		}
+
+		err = invoker(metadata.NewOutgoingContext(ctx, md), method, req, reply, cc, opts...)
+		code := status.Code(err)
+		span.SetAttributes(attribute.String("rpc.grpc.status_code", code.String()))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, code.String())
+		}
+		return err
+	}
+}
+
+// metadataCarrier lets the trace propagator read and write gRPC metadata
+type metadataCarrier metadata.MD
+
+// Get returns the first value of a key
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Set replaces the values of a key
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys lists the keys present
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,81 @@
+package grpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// errNoCertificate is returned for a config with neither a certificate nor
+// plaintext explicitly allowed
+var errNoCertificate = errors.New("gRPC certificate is required unless grpc.insecure is set")
+
+// serverCredentials returns the transport credentials of servers: mutual TLS
+// requiring client certificates signed by the CA when cfg has a certificate,
+// and plaintext only when cfg allows it
+func serverCredentials(cfg config.GRPCConfig) (credentials.TransportCredentials, error) {
+	if cfg.CertFile == "" {
+		return plaintext(cfg)
+	}
+	cert, pool, err := loadTLS(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
+
+// clientCredentials returns the transport credentials of clients: mutual TLS
+// presenting the certificate and trusting only servers signed by the CA when
+// cfg has a certificate, and plaintext only when cfg allows it
+func clientCredentials(cfg config.GRPCConfig) (credentials.TransportCredentials, error) {
+	if cfg.CertFile == "" {
+		return plaintext(cfg)
+	}
+	cert, pool, err := loadTLS(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   cfg.ServerName,
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
+
+// plaintext returns credentials without TLS if cfg is explicitly insecure
+func plaintext(cfg config.GRPCConfig) (credentials.TransportCredentials, error) {
+	if !cfg.Insecure {
+		return nil, errNoCertificate
+	}
+	return insecure.NewCredentials(), nil
+}
+
+// loadTLS reads the certificate, its key, and the CA of cfg
+func loadTLS(cfg config.GRPCConfig) (tls.Certificate, *x509.CertPool, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("failed to load gRPC certificate: %w", err)
+	}
+	ca, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("failed to read gRPC CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return tls.Certificate{}, nil, fmt.Errorf("no certificates in gRPC CA file %s", cfg.CAFile)
+	}
+	return cert, pool, nil
+}
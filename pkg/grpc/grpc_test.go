@@ -0,0 +1,287 @@
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/tenant"
+	catalogpb "github.com/onichange/pos-system/proto/catalog"
+)
+
+// pricing records the context of each call, failing the first failures calls
+// with UNAVAILABLE
+type pricing struct {
+	catalogpb.UnimplementedPricingServiceServer
+	failures int32
+	calls    atomic.Int32
+	last     atomic.Pointer[context.Context]
+}
+
+func (p *pricing) ResolvePrices(ctx context.Context, _ *catalogpb.ResolvePricesRequest) (*catalogpb.ResolvePricesResponse, error) {
+	p.last.Store(&ctx)
+	if p.calls.Add(1) <= p.failures {
+		return nil, status.Error(codes.Unavailable, "unavailable")
+	}
+	return &catalogpb.ResolvePricesResponse{}, nil
+}
+
+// tokens signs and verifies the service tokens of calls
+var tokens = auth.NewJWTManager("access-secret", "refresh-secret", time.Minute, time.Hour, "test")
+
+func testConfig() config.GRPCConfig {
+	return config.GRPCConfig{
+		Timeout:        time.Second,
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     50 * time.Millisecond,
+		Insecure:       true,
+	}
+}
+
+// serve starts a server of p on a random port until the test finishes
+func serve(t *testing.T, cfg config.GRPCConfig, tenants config.TenantConfig, p *pricing) string {
+	t.Helper()
+	server, err := NewServer("127.0.0.1:0", cfg, tenants, tokens, logger.New("test"))
+	require.NoError(t, err)
+	catalogpb.RegisterPricingServiceServer(server.GetServer(), p)
+	go server.Start()
+	t.Cleanup(server.Stop)
+	return server.Addr()
+}
+
+// call resolves prices through a client of target
+func call(t *testing.T, ctx context.Context, target string, cfg config.GRPCConfig) error {
+	t.Helper()
+	return callWith(t, ctx, target, cfg, tokens)
+}
+
+// callWith resolves prices through a client of target signing its service
+// tokens with signer
+func callWith(t *testing.T, ctx context.Context, target string, cfg config.GRPCConfig, signer *auth.JWTManager) error {
+	t.Helper()
+	conn, err := Dial(target, cfg, signer)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = catalogpb.NewPricingServiceClient(conn).ResolvePrices(ctx, &catalogpb.ResolvePricesRequest{})
+	return err
+}
+
+func TestPropagatesTenantAndRequestID(t *testing.T) {
+	p := &pricing{}
+	target := serve(t, testConfig(), config.TenantConfig{}, p)
+
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "acme", Source: tenant.SourceHeader})
+	ctx = logger.ContextWithRequestID(ctx, "req-123")
+	require.NoError(t, call(t, ctx, target, testConfig()))
+
+	got := *p.last.Load()
+	assert.Equal(t, "acme", tenant.IDFromContext(got))
+	assert.Equal(t, "req-123", logger.RequestIDFromContext(got))
+}
+
+func TestServerResolvesTenant(t *testing.T) {
+	p := &pricing{}
+	target := serve(t, testConfig(), config.TenantConfig{Default: "default"}, p)
+
+	// Without a tenant, the default applies and a request ID is generated
+	require.NoError(t, call(t, context.Background(), target, testConfig()))
+	got := *p.last.Load()
+	assert.Equal(t, "default", tenant.IDFromContext(got))
+	assert.NotEmpty(t, logger.RequestIDFromContext(got))
+
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "not a tenant!", Source: tenant.SourceHeader})
+	assert.Equal(t, codes.InvalidArgument, status.Code(call(t, ctx, target, testConfig())))
+
+	// Metadata cannot name another tenant than the service token
+	ctx = tenant.NewContext(context.Background(), &tenant.Tenant{ID: "acme", Source: tenant.SourceHeader})
+	require.NoError(t, call(t, metadata.AppendToOutgoingContext(ctx, tenant.Header, "acme"), target, testConfig()))
+	assert.Equal(t, "acme", tenant.IDFromContext(*p.last.Load()))
+	assert.Equal(t, codes.PermissionDenied, status.Code(call(t, metadata.AppendToOutgoingContext(ctx, tenant.Header, "other"), target, testConfig())))
+	assert.Equal(t, codes.PermissionDenied, status.Code(call(t, metadata.AppendToOutgoingContext(context.Background(), tenant.Header, "other"), target, testConfig())))
+
+	required := serve(t, testConfig(), config.TenantConfig{Required: true}, &pricing{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(call(t, context.Background(), required, testConfig())))
+}
+
+func TestServerRequiresServiceToken(t *testing.T) {
+	p := &pricing{}
+	target := serve(t, testConfig(), config.TenantConfig{Default: "default"}, p)
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	pricingClient := catalogpb.NewPricingServiceClient(conn)
+
+	_, err = pricingClient.ResolvePrices(context.Background(), &catalogpb.ResolvePricesRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Tokens of users are not service tokens
+	pair, err := tokens.GenerateTenantTokenPair("acme", "user-1", "user@example.com", []string{"admin"}, "")
+	require.NoError(t, err)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authorizationHeader, "Bearer "+pair.AccessToken)
+	_, err = pricingClient.ResolvePrices(ctx, &catalogpb.ResolvePricesRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Nor are tokens signed with another secret
+	forger := auth.NewJWTManager("other-secret", "refresh-secret", time.Minute, time.Hour, "test")
+	assert.Equal(t, codes.Unauthenticated, status.Code(callWith(t, context.Background(), target, testConfig(), forger)))
+	assert.Zero(t, p.calls.Load())
+
+	// Health checks need no token
+	_, err = grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.NoError(t, err)
+}
+
+func TestDefaultDeadline(t *testing.T) {
+	p := &pricing{}
+	target := serve(t, testConfig(), config.TenantConfig{}, p)
+
+	require.NoError(t, call(t, context.Background(), target, testConfig()))
+	deadline, ok := (*p.last.Load()).Deadline()
+	require.True(t, ok, "calls without a deadline get the configured timeout")
+	assert.WithinDuration(t, time.Now().Add(time.Second), deadline, time.Second)
+
+	// A caller's deadline is kept
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	require.NoError(t, call(t, ctx, target, testConfig()))
+	deadline, _ = (*p.last.Load()).Deadline()
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second)
+}
+
+func TestRetriesUnavailable(t *testing.T) {
+	p := &pricing{failures: 2}
+	target := serve(t, testConfig(), config.TenantConfig{}, p)
+
+	require.NoError(t, call(t, context.Background(), target, testConfig()))
+	assert.Equal(t, int32(3), p.calls.Load())
+
+	// Attempts stop at MaxAttempts
+	p = &pricing{failures: 5}
+	target = serve(t, testConfig(), config.TenantConfig{}, p)
+	assert.Equal(t, codes.Unavailable, status.Code(call(t, context.Background(), target, testConfig())))
+	assert.Equal(t, int32(3), p.calls.Load())
+}
+
+func TestNewServiceConfig(t *testing.T) {
+	sc := newServiceConfig(testConfig())
+	require.Len(t, sc.MethodConfig, 1)
+	assert.Equal(t, "0.01s", sc.MethodConfig[0].RetryPolicy.InitialBackoff)
+	assert.Equal(t, []string{"UNAVAILABLE"}, sc.MethodConfig[0].RetryPolicy.RetryableStatusCodes)
+
+	// One attempt disables retries
+	cfg := testConfig()
+	cfg.MaxAttempts = 1
+	assert.Empty(t, newServiceConfig(cfg).MethodConfig)
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig()
+	cfg.CertFile, cfg.KeyFile, cfg.CAFile = issue(t, dir, "trusted")
+	target := serve(t, cfg, config.TenantConfig{}, &pricing{})
+
+	require.NoError(t, call(t, context.Background(), target, cfg))
+
+	// Plaintext clients and clients of another CA are refused
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Error(t, call(t, ctx, target, testConfig()))
+
+	other := testConfig()
+	other.CertFile, other.KeyFile, other.CAFile = issue(t, dir, "other")
+	assert.Error(t, call(t, ctx, target, other))
+}
+
+func TestCredentialsRequireFiles(t *testing.T) {
+	cfg := testConfig()
+	cfg.CertFile = filepath.Join(t.TempDir(), "missing.pem")
+	_, err := NewServer("127.0.0.1:0", cfg, config.TenantConfig{}, tokens, logger.New("test"))
+	assert.Error(t, err)
+	_, err = Dial("127.0.0.1:1", cfg, tokens)
+	assert.Error(t, err)
+}
+
+func TestCredentialsRequireCertificateUnlessInsecure(t *testing.T) {
+	cfg := testConfig()
+	cfg.Insecure = false
+	_, err := NewServer("127.0.0.1:0", cfg, config.TenantConfig{}, tokens, logger.New("test"))
+	assert.ErrorIs(t, err, errNoCertificate)
+	_, err = Dial("127.0.0.1:1", cfg, tokens)
+	assert.ErrorIs(t, err, errNoCertificate)
+}
+
+// issue writes a CA and a certificate it signs for 127.0.0.1, usable by
+// servers and clients, returning the certificate, key and CA files
+func issue(t *testing.T, dir, name string) (string, string, string) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name + " CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+"-key.pem")
+	caFile := filepath.Join(dir, name+"-ca.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	writePEM(t, caFile, "CERTIFICATE", caDER)
+	return certFile, keyFile, caFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
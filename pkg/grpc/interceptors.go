@@ -0,0 +1,131 @@
+package grpc
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/config"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+const (
+	// maxRequestIDLength bounds caller-supplied request IDs, as over HTTP
+	maxRequestIDLength = 128
+
+	// authorizationHeader carries the service token of calls
+	authorizationHeader = "authorization"
+
+	// healthService prefixes the methods of the health service, which
+	// callers such as probes use without a token
+	healthService = "/grpc.health.v1.Health/"
+)
+
+// recoverInterceptor answers a panicking call with INTERNAL after reporting
+// the panic, so one bad call does not crash the process
+func recoverInterceptor(log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				apperrors.CapturePanic(ctx, recovered, apperrors.Info{Component: "grpc", Method: info.FullMethod})
+				log.WithContext(ctx).Errorf("gRPC call %s panicked: %v", info.FullMethod, recovered)
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// contextInterceptor traces calls and stores the caller's request ID and
+// tenant in their context, as the HTTP middleware does for requests. Callers
+// must present a service token, whose tenant is the one of the call; the
+// default of tenants applies to tokens bound to none. X-Tenant-ID metadata
+// naming another tenant than the token is refused. Health checks need no
+// token.
+func contextInterceptor(tenants config.TenantConfig, tokens *auth.JWTManager) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		carrier := metadataCarrier(md)
+
+		ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+		ctx, span := otel.Tracer("grpc").Start(ctx, info.FullMethod, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		requestID := carrier.Get(logger.RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+		ctx = logger.ContextWithRequestID(ctx, requestID)
+
+		if !strings.HasPrefix(info.FullMethod, healthService) {
+			claims, err := verifyServiceToken(carrier, tokens)
+			if err != nil {
+				return nil, err
+			}
+			if requested := carrier.Get(tenant.Header); requested != "" && requested != claims.TenantID {
+				return nil, status.Error(codes.PermissionDenied, "tenant does not match the service token")
+			}
+
+			tenantID, source := claims.TenantID, tenant.SourceJWT
+			if tenantID == "" {
+				tenantID, source = tenants.Default, tenant.SourceDefault
+			}
+			switch {
+			case tenantID != "" && !tenant.ValidID(tenantID):
+				return nil, status.Error(codes.InvalidArgument, "invalid tenant ID")
+			case tenantID != "":
+				ctx = tenant.NewContext(ctx, &tenant.Tenant{ID: tenantID, Source: source})
+			case tenants.Required:
+				return nil, status.Error(codes.InvalidArgument, "tenant could not be resolved")
+			}
+		}
+
+		resp, err := handler(ctx, req)
+		code := status.Code(err)
+		span.SetAttributes(attribute.String("rpc.grpc.status_code", code.String()))
+		if serverFault(code) {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, code.String())
+		}
+		return resp, err
+	}
+}
+
+// verifyServiceToken returns the claims of the service token in the
+// authorization metadata, as middleware.ServiceAuth does over HTTP
+func verifyServiceToken(carrier metadataCarrier, tokens *auth.JWTManager) (*auth.JWTClaims, error) {
+	token, ok := strings.CutPrefix(carrier.Get(authorizationHeader), "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "service token is required")
+	}
+	claims, err := tokens.ValidateAccessToken(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+	if !slices.Contains(claims.Roles, auth.RoleService) {
+		return nil, status.Error(codes.PermissionDenied, "service token is required")
+	}
+	return claims, nil
+}
+
+// serverFault reports whether a status blames the server rather than the call
+func serverFault(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
@@ -9,6 +9,8 @@ import (
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
+	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/config"
 	"github.com/onichange/pos-system/pkg/logger"
 )
 
@@ -17,16 +19,25 @@ type Server struct {
 	server   *grpc.Server
 	health   *health.Server
 	logger   *logger.Logger
-	port     string
 	listener net.Listener
 }
 
-// NewServer creates a new gRPC server
-func NewServer(port string, log *logger.Logger) (*Server, error) {
+// NewServer creates a gRPC server listening on addr, e.g. ":9085". Calls
+// must carry a service token verified by tokens, naming their tenant, and
+// carry the caller's request ID and trace; with a certificate in cfg, only
+// clients presenting a certificate signed by its CA are accepted.
+func NewServer(addr string, cfg config.GRPCConfig, tenants config.TenantConfig, tokens *auth.JWTManager, log *logger.Logger) (*Server, error) {
+	creds, err := serverCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create gRPC server with options
 	opts := []grpc.ServerOption{
+		grpc.Creds(creds),
 		grpc.MaxRecvMsgSize(10 * 1024 * 1024), // 10MB
 		grpc.MaxSendMsgSize(10 * 1024 * 1024), // 10MB
+		grpc.ChainUnaryInterceptor(recoverInterceptor(log), contextInterceptor(tenants, tokens)),
 	}
 
 	server := grpc.NewServer(opts...)
@@ -40,16 +51,15 @@ func NewServer(port string, log *logger.Logger) (*Server, error) {
 	reflection.Register(server)
 
 	// Create listener
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to listen on port %s: %w", port, err)
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
 	}
 
 	return &Server{
 		server:   server,
 		health:   healthServer,
 		logger:   log,
-		port:     port,
 		listener: listener,
 	}, nil
 }
@@ -59,9 +69,15 @@ func (s *Server) GetServer() *grpc.Server {
 	return s.server
 }
 
+// Addr returns the address the server listens on, with the actual port when
+// it was created with port 0
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
 // Start starts the gRPC server
 func (s *Server) Start() error {
-	s.logger.Infof("gRPC server starting on port %s", s.port)
+	s.logger.Infof("gRPC server listening on %s", s.Addr())
 	return s.server.Serve(s.listener)
 }
 
@@ -0,0 +1,83 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// catalogFiles holds a catalog per locale other than DefaultLocale, whose
+// messages are the English texts the code is written in
+//
+//go:embed locales/*.json
+var catalogFiles embed.FS
+
+// catalog is the translations of one locale
+type catalog struct {
+	// Messages translates error messages, keyed by their English text
+	Messages map[string]string `json:"messages"`
+	// Validation holds a message template per validator tag, in which
+	// {field} and {param} are replaced by the field name and tag parameter
+	Validation map[string]string `json:"validation"`
+}
+
+// catalogs are the loaded catalogs by locale
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]catalog {
+	loaded := map[string]catalog{}
+	for _, locale := range Supported[1:] {
+		data, err := catalogFiles.ReadFile("locales/" + locale + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("i18n: missing catalog for %s: %v", locale, err))
+		}
+
+		var c catalog
+		if err := json.Unmarshal(data, &c); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog for %s: %v", locale, err))
+		}
+		loaded[locale] = c
+	}
+	return loaded
+}
+
+// englishValidation are the validator messages of DefaultLocale, matching
+// those pkg/validator produces
+var englishValidation = map[string]string{
+	"required": "{field} is required",
+	"email":    "{field} must be a valid email address",
+	"min":      "{field} must be at least {param} characters",
+	"max":      "{field} must be at most {param} characters",
+	"len":      "{field} must be exactly {param} characters",
+	"numeric":  "{field} must be numeric",
+	"alphanum": "{field} must contain only alphanumeric characters",
+	"url":      "{field} must be a valid URL",
+	"uuid":     "{field} must be a valid UUID",
+	"":         "{field} is invalid",
+}
+
+// Translate returns message in locale. Messages without a translation,
+// including those naming request-specific values, are returned unchanged.
+func Translate(locale, message string) string {
+	if translated, ok := catalogs[locale].Messages[message]; ok {
+		return translated
+	}
+	return message
+}
+
+// TranslateValidation returns the message for a field failing a validator
+// tag in locale, falling back to English for untranslated tags
+func TranslateValidation(locale, field, tag, param string) string {
+	template, ok := catalogs[locale].Validation[tag]
+	if !ok {
+		template, ok = englishValidation[tag]
+	}
+	if !ok {
+		if template, ok = catalogs[locale].Validation[""]; !ok {
+			template = englishValidation[""]
+		}
+	}
+
+	return strings.NewReplacer("{field}", field, "{param}", param).Replace(template)
+}
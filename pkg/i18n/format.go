@@ -0,0 +1,74 @@
+package i18n
+
+import (
+	"math"
+	"strings"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// moneyLayout is where a locale puts the currency symbol
+type moneyLayout struct {
+	symbolAfter bool   // 12,50 € rather than €12.50
+	separator   string // Between the symbol and the amount, non-breaking
+}
+
+// moneyLayouts are the layouts of the supported locales
+var moneyLayouts = map[string]moneyLayout{
+	"en": {symbolAfter: false, separator: ""},
+	"es": {symbolAfter: true, separator: "\u00a0"},
+	"fr": {symbolAfter: true, separator: "\u00a0"},
+	"de": {symbolAfter: true, separator: "\u00a0"},
+	"vi": {symbolAfter: true, separator: "\u00a0"},
+	"pt": {symbolAfter: false, separator: "\u00a0"},
+}
+
+// printer returns the number printer of a supported locale
+func printer(locale string) *message.Printer {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		tag = language.MustParse(DefaultLocale)
+	}
+	return message.NewPrinter(tag)
+}
+
+// FormatNumber formats v with the grouping and decimal separators of locale
+// and scale decimals
+func FormatNumber(locale string, v float64, scale int) string {
+	return printer(locale).Sprint(number.Decimal(v, number.Scale(scale)))
+}
+
+// FormatMoney formats an amount of an ISO 4217 currency for display in
+// locale, e.g. $1,234.50 in en and 1.234,50 € in de. Amounts are rounded to
+// the currency's minor unit, so VND has no decimals. An unknown currency
+// code is shown as is in place of a symbol.
+func FormatMoney(locale string, amount float64, currencyCode string) string {
+	layout, ok := moneyLayouts[locale]
+	if !ok {
+		locale = DefaultLocale
+		layout = moneyLayouts[DefaultLocale]
+	}
+	p := printer(locale)
+
+	symbol := strings.ToUpper(currencyCode)
+	scale := 2
+	if unit, err := currency.ParseISO(currencyCode); err == nil {
+		symbol = p.Sprint(currency.Symbol(unit))
+		scale, _ = currency.Standard.Rounding(unit)
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = math.Abs(amount)
+	}
+	formatted := p.Sprint(number.Decimal(amount, number.Scale(scale)))
+
+	if layout.symbolAfter {
+		return sign + formatted + layout.separator + symbol
+	}
+	return sign + symbol + layout.separator + formatted
+}
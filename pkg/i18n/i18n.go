@@ -0,0 +1,63 @@
+// Package i18n localizes API responses for the language a client asks for in
+// Accept-Language: error and validation messages are translated from
+// per-locale catalogs, and numbers and money are formatted with the locale's
+// separators and currency placement.
+package i18n
+
+import (
+	"context"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is used when a client accepts none of the supported locales
+const DefaultLocale = "en"
+
+// Supported lists the locales with a message catalog, DefaultLocale first
+var Supported = []string{"en", "es", "fr", "de", "vi", "pt"}
+
+// matcher picks the closest supported locale, e.g. es for es-MX
+var matcher = language.NewMatcher(supportedTags())
+
+func supportedTags() []language.Tag {
+	tags := make([]language.Tag, len(Supported))
+	for i, locale := range Supported {
+		tags[i] = language.MustParse(locale)
+	}
+	return tags
+}
+
+// Negotiate returns the supported locale that best matches an Accept-Language
+// header, or DefaultLocale if none does
+func Negotiate(acceptLanguage string) string {
+	if acceptLanguage == "" {
+		return DefaultLocale
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return Supported[index]
+}
+
+// contextKey is the context key for the locale
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying locale
+func NewContext(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, contextKey{}, locale)
+}
+
+// FromContext returns the locale stored in ctx, or DefaultLocale if there is none
+func FromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(contextKey{}).(string); ok && locale != "" {
+		return locale
+	}
+	return DefaultLocale
+}
@@ -0,0 +1,123 @@
+package i18n
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]string{
+		"":                        "en",
+		"de":                      "de",
+		"es-MX":                   "es",
+		"pt-BR, en;q=0.5":         "pt",
+		"ja, vi;q=0.8, fr;q=0.5":  "vi",
+		"ja":                      "en",
+		"not a language header!!": "en",
+	}
+	for header, want := range tests {
+		assert.Equal(t, want, Negotiate(header), header)
+	}
+}
+
+func TestCatalogsTranslateTheSameMessages(t *testing.T) {
+	for _, locale := range Supported[1:] {
+		assert.Len(t, catalogs[locale].Messages, len(catalogs["es"].Messages), locale)
+		for tag := range englishValidation {
+			assert.Contains(t, catalogs[locale].Validation, tag, locale)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	assert.Equal(t, "Bestellung nicht gefunden", Translate("de", "Order not found"))
+	assert.Equal(t, "Order not found", Translate("en", "Order not found"))
+	assert.Equal(t, "Something else", Translate("de", "Something else"))
+
+	assert.Equal(t, "password doit contenir au moins 8 caractères", TranslateValidation("fr", "password", "min", "8"))
+	assert.Equal(t, "sku must be at least 8 characters", TranslateValidation("en", "sku", "min", "8"))
+	assert.Equal(t, "status no es válido", TranslateValidation("es", "status", "oneof", "open closed"))
+}
+
+func TestFormatMoney(t *testing.T) {
+	assert.Equal(t, "$1,234.50", FormatMoney("en", 1234.5, "USD"))
+	assert.Equal(t, "-$3.00", FormatMoney("en", -3, "USD"))
+	assert.Equal(t, "1.234,50\u00a0€", FormatMoney("de", 1234.5, "EUR"))
+	assert.Equal(t, "R$\u00a01.234,50", FormatMoney("pt", 1234.5, "BRL"))
+	assert.Equal(t, "1.234.568\u00a0₫", FormatMoney("vi", 1234567.8, "VND"))
+	assert.Equal(t, "XYZ12.00", FormatMoney("en", 12, "XYZ"))
+	assert.Equal(t, "$5.00", FormatMoney("ja", 5, "USD"), "unsupported locales use the default")
+
+	assert.Equal(t, "1.234,5", FormatNumber("es", 1234.5, 1))
+}
+
+func TestMiddleware(t *testing.T) {
+	type request struct {
+		Name string `json:"name" validate:"required"`
+		Code string `json:"code" validate:"min=3"`
+	}
+
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return c.Status(err.(*fiber.Error).Code).JSON(fiber.Map{"error": err.(*fiber.Error).Message})
+		},
+	})
+	app.Use(Middleware())
+	app.Get("/ok", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"error": "Order not found", "locale": FromContext(c.UserContext())})
+	})
+	app.Get("/missing", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Order not found", "order_id": 42})
+	})
+	app.Get("/invalid", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validator.ValidateStruct(request{Code: "ab"}),
+		})
+	})
+	app.Get("/denied", func(c *fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusForbidden, "Access denied")
+	})
+
+	get := func(path, language string) (map[string]interface{}, string) {
+		req := httptest.NewRequest(fiber.MethodGet, path, nil)
+		req.Header.Set(fiber.HeaderAcceptLanguage, language)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		raw, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(raw, &body))
+		return body, resp.Header.Get(fiber.HeaderContentLanguage)
+	}
+
+	body, language := get("/ok", "de-AT")
+	assert.Equal(t, "de", language)
+	assert.Equal(t, "de", body["locale"])
+	assert.Equal(t, "Order not found", body["error"], "successful responses are left alone")
+
+	body, _ = get("/missing", "de")
+	assert.Equal(t, "Bestellung nicht gefunden", body["error"])
+	assert.Equal(t, float64(42), body["order_id"])
+
+	body, _ = get("/missing", "en")
+	assert.Equal(t, "Order not found", body["error"])
+
+	body, _ = get("/invalid", "vi")
+	assert.Equal(t, "Xác thực dữ liệu thất bại", body["error"])
+	details := body["details"].([]interface{})
+	require.Len(t, details, 2)
+	assert.Equal(t, "name là bắt buộc", details[0].(map[string]interface{})["message"])
+	assert.Equal(t, "code phải có ít nhất 3 ký tự", details[1].(map[string]interface{})["message"])
+
+	body, _ = get("/denied", "pt")
+	assert.Equal(t, "Acesso negado", body["error"])
+}
@@ -0,0 +1,118 @@
+package i18n
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// localsKey is the fiber Locals key for the negotiated locale
+const localsKey = "locale"
+
+// Locale returns the locale negotiated for a request by Middleware, or
+// DefaultLocale outside it
+func Locale(c *fiber.Ctx) string {
+	if locale, ok := c.Locals(localsKey).(string); ok {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// Middleware negotiates the request's locale from Accept-Language, stores it
+// in the request context, and translates error responses into it: the
+// "error" message of JSON responses with an error status and of returned
+// *fiber.Error values, and the messages of validation errors listed under
+// "details" or "errors", which are rendered again from their field, tag and
+// param. Mount it inside compress so it sees uncompressed bodies.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		locale := Negotiate(c.Get(fiber.HeaderAcceptLanguage))
+		c.Locals(localsKey, locale)
+		c.SetUserContext(NewContext(c.UserContext(), locale))
+		c.Set(fiber.HeaderContentLanguage, locale)
+		c.Vary(fiber.HeaderAcceptLanguage)
+
+		err := c.Next()
+		if locale == DefaultLocale {
+			return err
+		}
+
+		// The error handler writes the response after every middleware returns
+		var fe *fiber.Error
+		if errors.As(err, &fe) {
+			return fiber.NewError(fe.Code, Translate(locale, fe.Message))
+		}
+		if err == nil {
+			translateResponse(c, locale)
+		}
+		return err
+	}
+}
+
+// translateResponse translates the body of a JSON error response in place
+func translateResponse(c *fiber.Ctx, locale string) {
+	res := c.Response()
+	if res.StatusCode() < fiber.StatusBadRequest || res.IsBodyStream() ||
+		len(res.Header.Peek(fiber.HeaderContentEncoding)) > 0 ||
+		!strings.HasPrefix(string(res.Header.ContentType()), fiber.MIMEApplicationJSON) {
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(res.Body()))
+	decoder.UseNumber() // Keeps other fields exactly as written
+	var body map[string]interface{}
+	if err := decoder.Decode(&body); err != nil {
+		return
+	}
+
+	changed := false
+	if message, ok := body["error"].(string); ok {
+		if translated := Translate(locale, message); translated != message {
+			body["error"] = translated
+			changed = true
+		}
+	}
+	for _, key := range []string{"details", "errors"} {
+		if translateValidationErrors(body[key], locale) {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	translated, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+	res.SetBodyRaw(translated)
+}
+
+// translateValidationErrors re-renders the messages of a list of
+// validator.ValidationError, reporting whether any changed
+func translateValidationErrors(v interface{}, locale string) bool {
+	list, ok := v.([]interface{})
+	if !ok {
+		return false
+	}
+
+	changed := false
+	for _, item := range list {
+		fieldError, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		field, hasField := fieldError["field"].(string)
+		tag, hasTag := fieldError["tag"].(string)
+		if !hasField || !hasTag {
+			continue
+		}
+		param, _ := fieldError["param"].(string)
+		fieldError["message"] = TranslateValidation(locale, field, tag, param)
+		changed = true
+	}
+	return changed
+}
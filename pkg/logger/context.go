@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader is the header carrying the request ID across services
+const RequestIDHeader = "X-Request-ID"
+
+// contextKey is the type of context keys defined in this package
+type contextKey string
+
+const (
+	requestIDKey contextKey = "request_id"
+	userIDKey    contextKey = "user_id"
+)
+
+// ContextWithRequestID returns a context carrying the request ID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or ""
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// ContextWithUserID returns a context carrying the authenticated user's ID
+func ContextWithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserIDFromContext returns the user ID stored in ctx, or ""
+func UserIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	userID, _ := ctx.Value(userIDKey).(string)
+	return userID
+}
+
+// FromContext returns the service logger created by New, tagged with the
+// request, user, trace, and span IDs found in ctx. Handlers and repositories
+// use it so their lines can be matched to the trace of the request.
+func FromContext(ctx context.Context) *Logger {
+	return (&Logger{logger: log.Logger}).WithContext(ctx)
+}
+
+// WithContext returns a logger that tags every line with the request, user,
+// trace, and span IDs found in ctx
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	if ctx == nil {
+		return l
+	}
+
+	fields := l.logger.With()
+	tagged := false
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		fields = fields.Str("request_id", requestID)
+		tagged = true
+	}
+	if userID := UserIDFromContext(ctx); userID != "" {
+		fields = fields.Str("user_id", userID)
+		tagged = true
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields = fields.
+			Str("trace_id", spanContext.TraceID().String()).
+			Str("span_id", spanContext.SpanID().String())
+		tagged = true
+	}
+
+	if !tagged {
+		return l
+	}
+	return &Logger{logger: fields.Logger()}
+}
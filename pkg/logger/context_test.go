@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithContextAddsCorrelationFields(t *testing.T) {
+	var buf bytes.Buffer
+	l := &Logger{logger: zerolog.New(&buf)}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	ctx = ContextWithRequestID(ctx, "req-1")
+	ctx = ContextWithUserID(ctx, "user-1")
+
+	l.WithContext(ctx).Info("hello")
+
+	var line map[string]string
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "req-1", line["request_id"])
+	assert.Equal(t, "user-1", line["user_id"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", line["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", line["span_id"])
+}
+
+func TestWithContextWithoutFields(t *testing.T) {
+	l := &Logger{logger: zerolog.Nop()}
+
+	assert.Same(t, l, l.WithContext(context.Background()))
+}
+
+func TestFromContextUsesServiceLogger(t *testing.T) {
+	var buf bytes.Buffer
+	previous := log.Logger
+	log.Logger = zerolog.New(&buf)
+	t.Cleanup(func() { log.Logger = previous })
+
+	FromContext(ContextWithRequestID(context.Background(), "req-2")).Info("hello")
+
+	assert.Contains(t, buf.String(), `"request_id":"req-2"`)
+}
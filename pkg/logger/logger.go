@@ -86,6 +86,12 @@ func (l *Logger) Fatalf(format string, v ...interface{}) {
 	l.logger.Fatal().Msgf(format, v...)
 }
 
+// DebugEnabled reports whether debug messages are written, so callers can skip
+// building expensive debug output
+func (l *Logger) DebugEnabled() bool {
+	return l.logger.GetLevel() <= zerolog.DebugLevel && zerolog.GlobalLevel() <= zerolog.DebugLevel
+}
+
 // WithRequestID adds request ID to logger context
 func (l *Logger) WithRequestID(requestID string) *Logger {
 	return &Logger{
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// redactedValue replaces the value of a redacted field
+const redactedValue = "[REDACTED]"
+
+// DefaultRedactFields are removed entirely from logged payloads
+var DefaultRedactFields = []string{
+	"password", "new_password", "old_password", "current_password",
+	"token", "access_token", "refresh_token", "id_token", "authorization",
+	"secret", "client_secret", "api_key",
+	"card_number", "pan", "cvv", "cvc", "expiry", "pin", "ssn",
+}
+
+// DefaultMaskFields are partially shown with MaskSensitive
+var DefaultMaskFields = []string{"email", "phone", "phone_number"}
+
+// Redactor scrubs sensitive fields from JSON payloads before they are logged.
+// Field names are matched case-insensitively at any depth.
+type Redactor struct {
+	redact map[string]bool
+	mask   map[string]bool
+}
+
+// NewRedactor creates a redactor; fields in redact are replaced, fields in mask
+// are partially masked
+func NewRedactor(redact, mask []string) *Redactor {
+	return &Redactor{
+		redact: fieldSet(redact),
+		mask:   fieldSet(mask),
+	}
+}
+
+// RedactJSON returns body with sensitive fields scrubbed. Bodies that are not
+// valid JSON cannot be scrubbed reliably, so ok is false and nothing should be logged.
+func (r *Redactor) RedactJSON(body []byte) (redacted []byte, ok bool) {
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, false
+	}
+
+	redacted, err := json.Marshal(r.scrub(payload))
+	if err != nil {
+		return nil, false
+	}
+	return redacted, true
+}
+
+// scrub walks a decoded JSON value
+func (r *Redactor) scrub(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			name := strings.ToLower(key)
+			switch {
+			case r.redact[name]:
+				v[key] = redactedValue
+			case r.mask[name]:
+				if s, ok := field.(string); ok {
+					v[key] = MaskSensitive(s)
+				} else {
+					v[key] = redactedValue
+				}
+			default:
+				v[key] = r.scrub(field)
+			}
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = r.scrub(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// fieldSet lowercases field names into a lookup set
+func fieldSet(fields []string) map[string]bool {
+	set := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		set[strings.ToLower(strings.TrimSpace(field))] = true
+	}
+	return set
+}
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactJSON(t *testing.T) {
+	redactor := NewRedactor(DefaultRedactFields, DefaultMaskFields)
+
+	body := []byte(`{
+		"Password": "hunter2",
+		"email": "jane.doe@example.com",
+		"payment": {"card_number": "4111111111111111", "amount": 12.5},
+		"items": [{"name": "coffee", "token": "abc"}],
+		"phone": 5551234
+	}`)
+
+	redacted, ok := redactor.RedactJSON(body)
+	require.True(t, ok)
+	assert.JSONEq(t, `{
+		"Password": "[REDACTED]",
+		"email": "jan***com",
+		"payment": {"card_number": "[REDACTED]", "amount": 12.5},
+		"items": [{"name": "coffee", "token": "[REDACTED]"}],
+		"phone": "[REDACTED]"
+	}`, string(redacted))
+}
+
+func TestRedactJSONRejectsInvalidJSON(t *testing.T) {
+	redactor := NewRedactor(DefaultRedactFields, nil)
+
+	_, ok := redactor.RedactJSON([]byte(`password=hunter2`))
+	assert.False(t, ok)
+}
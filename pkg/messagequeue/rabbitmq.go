@@ -1,22 +1,37 @@
 package messagequeue
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/streadway/amqp"
 
+	apperrors "github.com/onichange/pos-system/pkg/errors"
 	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/tenant"
+	"github.com/onichange/pos-system/pkg/tracing"
 )
 
 // RabbitMQ represents a RabbitMQ connection
 type RabbitMQ struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	logger  *logger.Logger
+	conn          *amqp.Connection
+	channel       *amqp.Channel
+	logger        *logger.Logger
+	defaultTenant string
+
+	// Set in confirm mode, where publishing waits for the broker's answer
+	confirms  chan amqp.Confirmation
+	publishMu sync.Mutex
 }
 
+// ErrNotConfirmed is returned in confirm mode for a message the broker
+// refused, or did not answer for before its channel closed
+var ErrNotConfirmed = errors.New("message not confirmed by the broker")
+
 // NewRabbitMQ creates a new RabbitMQ connection
 func NewRabbitMQ(url string, log *logger.Logger) (*RabbitMQ, error) {
 	conn, err := amqp.Dial(url)
@@ -37,6 +52,24 @@ func NewRabbitMQ(url string, log *logger.Logger) (*RabbitMQ, error) {
 	}, nil
 }
 
+// UseConfirms puts the channel in confirm mode: publishing then returns once
+// the broker has taken the message, or with ErrNotConfirmed when it did not,
+// so a message published without error is not lost. Publishes wait for each
+// other in this mode.
+func (r *RabbitMQ) UseConfirms() error {
+	if err := r.channel.Confirm(false); err != nil {
+		return fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	r.confirms = r.channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	return nil
+}
+
+// UseDefaultTenant handles consumed messages without a tenant header, such as
+// those published before tenancy, as belonging to tenantID
+func (r *RabbitMQ) UseDefaultTenant(tenantID string) {
+	r.defaultTenant = tenantID
+}
+
 // DeclareExchange declares an exchange
 func (r *RabbitMQ) DeclareExchange(name, kind string) error {
 	return r.channel.ExchangeDeclare(
@@ -78,27 +111,144 @@ func (r *RabbitMQ) DeclareDeadLetterQueue(name string) (amqp.Queue, error) {
 
 // Publish publishes a message to an exchange
 func (r *RabbitMQ) Publish(exchange, routingKey string, message interface{}) error {
+	return r.PublishContext(context.Background(), exchange, routingKey, message)
+}
+
+// PublishContext publishes a message to an exchange, propagating the trace context in headers
+func (r *RabbitMQ) PublishContext(ctx context.Context, exchange, routingKey string, message interface{}) error {
 	body, err := json.Marshal(message)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	return r.channel.Publish(
+	ctx, span := tracing.StartProducerSpan(ctx, "rabbitmq", exchange)
+	defer span.End()
+
+	headers := amqp.Table{}
+	tracing.InjectAMQP(ctx, headers)
+
+	if r.confirms != nil {
+		r.publishMu.Lock()
+		defer r.publishMu.Unlock()
+	}
+	err = r.channel.Publish(
 		exchange,   // exchange
 		routingKey, // routing key
 		false,      // mandatory
 		false,      // immediate
 		amqp.Publishing{
+			Headers:      headers,
 			ContentType:  "application/json",
 			DeliveryMode: amqp.Persistent, // Make message persistent
 			Timestamp:    time.Now(),
 			Body:         body,
 		},
 	)
+	if err == nil && r.confirms != nil {
+		err = r.awaitConfirm(ctx)
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+// awaitConfirm waits for the broker to confirm the message just published
+func (r *RabbitMQ) awaitConfirm(ctx context.Context) error {
+	select {
+	case confirm, ok := <-r.confirms:
+		if !ok || !confirm.Ack {
+			return ErrNotConfirmed
+		}
+		return nil
+	case <-ctx.Done():
+		// The confirmation may still arrive and be taken for the next
+		// message's, so the channel is not used again
+		r.channel.Close()
+		return ctx.Err()
+	}
 }
 
 // Consume consumes messages from a queue
 func (r *RabbitMQ) Consume(queue, consumer string, handler func(amqp.Delivery) error) error {
+	return r.ConsumeContext(queue, consumer, func(_ context.Context, msg amqp.Delivery) error {
+		return handler(msg)
+	})
+}
+
+// ConsumeContext consumes messages from a queue, passing each handler a context
+// that continues the publisher's trace
+func (r *RabbitMQ) ConsumeContext(queue, consumer string, handler func(context.Context, amqp.Delivery) error) error {
+	return r.consume(queue, consumer, handler, func(_ context.Context, msg amqp.Delivery, _ error) {
+		// Nack and requeue
+		msg.Nack(false, true)
+	})
+}
+
+// AttemptsHeader counts the failed attempts at a message consumed with
+// ConsumeWithRetry
+const AttemptsHeader = "x-attempts"
+
+// ConsumeWithRetry consumes messages from a queue like ConsumeContext, but
+// hands a message that failed to the queue's retry queue, <queue>.retry,
+// which returns it after its delay. After maxAttempts attempts the message is
+// rejected to the queue's dead letter queue instead. Both queues come from
+// the messaging topology (retry_delay and dead_letter).
+func (r *RabbitMQ) ConsumeWithRetry(queue, consumer string, maxAttempts int, handler func(context.Context, amqp.Delivery) error) error {
+	return r.consume(queue, consumer, handler, func(ctx context.Context, msg amqp.Delivery, err error) {
+		attempts := attemptsOf(msg) + 1
+		if attempts >= maxAttempts {
+			r.logger.WithContext(ctx).Warnf("Dead-lettering message from %s after %d attempts", queue, attempts)
+			msg.Nack(false, false)
+			return
+		}
+
+		headers := amqp.Table{}
+		for key, value := range msg.Headers {
+			headers[key] = value
+		}
+		headers[AttemptsHeader] = int32(attempts)
+
+		if r.confirms != nil {
+			r.publishMu.Lock()
+			defer r.publishMu.Unlock()
+		}
+		err = r.channel.Publish("", queue+".retry", false, false, amqp.Publishing{
+			Headers:       headers,
+			ContentType:   msg.ContentType,
+			DeliveryMode:  amqp.Persistent,
+			CorrelationId: msg.CorrelationId,
+			MessageId:     msg.MessageId,
+			Timestamp:     msg.Timestamp,
+			Type:          msg.Type,
+			Body:          msg.Body,
+		})
+		if err == nil && r.confirms != nil {
+			err = r.awaitConfirm(ctx)
+		}
+		if err != nil {
+			r.logger.WithContext(ctx).Errorf("Failed to schedule retry of message from %s: %v", queue, err)
+			msg.Nack(false, true)
+			return
+		}
+		msg.Ack(false)
+	})
+}
+
+// attemptsOf returns how many attempts at msg failed before
+func attemptsOf(msg amqp.Delivery) int {
+	switch n := msg.Headers[AttemptsHeader].(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	}
+	return 0
+}
+
+// consume runs handler on each message of a queue, acknowledging those it
+// handles and passing the others to failed
+func (r *RabbitMQ) consume(queue, consumer string, handler func(context.Context, amqp.Delivery) error, failed func(context.Context, amqp.Delivery, error)) error {
 	msgs, err := r.channel.Consume(
 		queue,    // queue
 		consumer, // consumer
@@ -114,14 +264,25 @@ func (r *RabbitMQ) Consume(queue, consumer string, handler func(amqp.Delivery) e
 
 	go func() {
 		for msg := range msgs {
-			if err := handler(msg); err != nil {
-				r.logger.Errorf("Error processing message: %v", err)
-				// Nack and requeue
-				msg.Nack(false, true)
+			ctx := tracing.ExtractAMQP(context.Background(), msg.Headers)
+			if _, ok := tenant.FromContext(ctx); !ok && r.defaultTenant != "" {
+				ctx = tenant.NewContext(ctx, &tenant.Tenant{ID: r.defaultTenant, Source: tenant.SourceDefault})
+			}
+			ctx, span := tracing.StartConsumerSpan(ctx, "rabbitmq", queue)
+
+			// A panicking handler nacks its message instead of stopping the consumer
+			err := apperrors.Guard(ctx, "rabbitmq:"+queue, func() error {
+				return handler(ctx, msg)
+			})
+			if err != nil {
+				span.RecordError(err)
+				r.logger.WithContext(ctx).Errorf("Error processing message: %v", err)
+				failed(ctx, msg, err)
 			} else {
 				// Ack message
 				msg.Ack(false)
 			}
+			span.End()
 		}
 	}()
 
@@ -150,6 +311,11 @@ type Event struct {
 
 // PublishEvent publishes a domain event
 func (r *RabbitMQ) PublishEvent(eventType, routingKey string, data map[string]interface{}) error {
+	return r.PublishEventContext(context.Background(), eventType, routingKey, data)
+}
+
+// PublishEventContext publishes a domain event as part of the trace in ctx
+func (r *RabbitMQ) PublishEventContext(ctx context.Context, eventType, routingKey string, data map[string]interface{}) error {
 	event := Event{
 		ID:        fmt.Sprintf("evt_%d", time.Now().UnixNano()),
 		Type:      eventType,
@@ -158,5 +324,5 @@ func (r *RabbitMQ) PublishEvent(eventType, routingKey string, data map[string]in
 		Data:      data,
 	}
 
-	return r.Publish("events", routingKey, event)
+	return r.PublishContext(ctx, "events", routingKey, event)
 }
@@ -0,0 +1,97 @@
+package messaging
+
+import (
+	"fmt"
+
+	"github.com/streadway/amqp"
+)
+
+// DeadLetters describes the dead letter queue of a queue
+type DeadLetters struct {
+	Queue     string // The queue messages were rejected from
+	DLQ       string
+	Messages  int
+	Consumers int
+}
+
+// DeadLetterQueues lists the dead letter queues the topology declares, with
+// how many messages wait in each. Queues missing from the broker are left out.
+func (r *RabbitMQClient) DeadLetterQueues(t *Topology) ([]DeadLetters, error) {
+	var queues []DeadLetters
+	for _, q := range t.Queues {
+		if !q.DeadLetter {
+			continue
+		}
+		dlq := deadLetterQueueName(q.Name)
+		state, err := r.channel.QueueInspect(dlq)
+		if err != nil {
+			// The server closes the channel on a missing queue
+			if err := r.reopenChannel(); err != nil {
+				return queues, err
+			}
+			continue
+		}
+		queues = append(queues, DeadLetters{
+			Queue:     q.Name,
+			DLQ:       dlq,
+			Messages:  state.Messages,
+			Consumers: state.Consumers,
+		})
+	}
+	return queues, nil
+}
+
+// RedriveDeadLetters moves up to limit messages (all, when limit is not
+// positive) from queue's dead letter queue back onto queue, through the
+// default exchange, with their headers and properties. Only the messages
+// waiting when it starts are moved, so those rejected again are not looped
+// over. A message is acknowledged once republished; on failure it stays in
+// the dead letter queue.
+func (r *RabbitMQClient) RedriveDeadLetters(queue string, limit int) (int, error) {
+	dlq := deadLetterQueueName(queue)
+	state, err := r.channel.QueueInspect(dlq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect %s: %w", dlq, err)
+	}
+
+	count := state.Messages
+	if limit > 0 && limit < count {
+		count = limit
+	}
+
+	moved := 0
+	for moved < count {
+		msg, ok, err := r.channel.Get(dlq, false)
+		if err != nil {
+			return moved, fmt.Errorf("failed to read %s: %w", dlq, err)
+		}
+		if !ok {
+			break
+		}
+
+		err = r.channel.Publish("", queue, false, false, amqp.Publishing{
+			Headers:         msg.Headers,
+			ContentType:     msg.ContentType,
+			ContentEncoding: msg.ContentEncoding,
+			DeliveryMode:    msg.DeliveryMode,
+			Priority:        msg.Priority,
+			CorrelationId:   msg.CorrelationId,
+			MessageId:       msg.MessageId,
+			Timestamp:       msg.Timestamp,
+			Type:            msg.Type,
+			AppId:           msg.AppId,
+			Body:            msg.Body,
+		})
+		if err != nil {
+			msg.Nack(false, true)
+			return moved, fmt.Errorf("failed to republish to %s: %w", queue, err)
+		}
+		if err := msg.Ack(false); err != nil {
+			return moved, fmt.Errorf("failed to acknowledge %s: %w", dlq, err)
+		}
+		moved++
+	}
+
+	r.logger.Infof("Redrove %d messages from %s to %s", moved, dlq, queue)
+	return moved, nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/IBM/sarama"
 
 	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/tracing"
 )
 
 // KafkaProducer wraps Kafka producer
@@ -17,8 +18,10 @@ type KafkaProducer struct {
 
 // KafkaConsumer wraps Kafka consumer
 type KafkaConsumer struct {
-	consumer sarama.ConsumerGroup
-	logger   *logger.Logger
+	consumer    sarama.ConsumerGroup
+	groupID     string
+	deadLetters deadLetterSender // Set by WithDeadLetters
+	logger      *logger.Logger
 }
 
 // NewKafkaProducer creates a new Kafka producer
@@ -44,14 +47,27 @@ func NewKafkaProducer(brokers []string, log *logger.Logger) (*KafkaProducer, err
 
 // Publish publishes a message to a topic
 func (k *KafkaProducer) Publish(topic string, key []byte, value []byte) error {
+	return k.PublishContext(context.Background(), topic, key, value)
+}
+
+// PublishContext publishes a message to a topic, propagating the trace context in record headers
+func (k *KafkaProducer) PublishContext(ctx context.Context, topic string, key []byte, value []byte) error {
+	ctx, span := tracing.StartProducerSpan(ctx, "kafka", topic)
+	defer span.End()
+
+	headers := make([]sarama.RecordHeader, 0, 2)
+	tracing.InjectKafka(ctx, &headers)
+
 	msg := &sarama.ProducerMessage{
-		Topic: topic,
-		Key:   sarama.ByteEncoder(key),
-		Value: sarama.ByteEncoder(value),
+		Topic:   topic,
+		Key:     sarama.ByteEncoder(key),
+		Value:   sarama.ByteEncoder(value),
+		Headers: headers,
 	}
 
 	partition, offset, err := k.producer.SendMessage(msg)
 	if err != nil {
+		span.RecordError(err)
 		return fmt.Errorf("failed to send message: %w", err)
 	}
 
@@ -79,6 +95,7 @@ func NewKafkaConsumer(brokers []string, groupID string, log *logger.Logger) (*Ka
 
 	return &KafkaConsumer{
 		consumer: consumer,
+		groupID:  groupID,
 		logger:   log,
 	}, nil
 }
@@ -0,0 +1,203 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/tracing"
+)
+
+const (
+	// handlerRetries is how many times a failing message is retried before it
+	// is dead-lettered or redelivered
+	handlerRetries = 3
+
+	// handlerRetryBackoff is the upper bound of the random delay before the
+	// first handler retry, doubling with each retry
+	handlerRetryBackoff = 200 * time.Millisecond
+)
+
+// handlerRetry is how a failing message is retried. It draws on no retry
+// budget: a message that runs out of retries is dead-lettered, or else
+// consumed again once the group rejoins.
+var handlerRetry = performance.RetryPolicy{
+	Name:        "kafka",
+	MaxAttempts: handlerRetries,
+	BaseDelay:   handlerRetryBackoff,
+}
+
+// MessageHandler processes a single Kafka message
+type MessageHandler func(ctx context.Context, msg *sarama.ConsumerMessage) error
+
+// deadLetterSender publishes dead-lettered messages; sarama.SyncProducer implements it
+type deadLetterSender interface {
+	SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error)
+}
+
+// WithDeadLetters makes ConsumeFunc publish a message whose handler runs out
+// of retries to the <topic>.dlq topic through producer, and move past it once
+// published. Without it, such a message stops consumption of the group until
+// it rejoins and consumes it again.
+func (k *KafkaConsumer) WithDeadLetters(producer *KafkaProducer) *KafkaConsumer {
+	k.deadLetters = producer.producer
+	return k
+}
+
+// ConsumeFunc consumes a topic with a plain handler function until ctx is cancelled.
+// Offsets are marked only after the handler succeeds, or its message is dead-lettered,
+// and are committed when partitions are revoked, so neither a failing handler nor a
+// rebalance loses a message. A message that can be neither handled nor dead-lettered
+// ends the group session unmarked, to be consumed again once the group rejoins.
+func (k *KafkaConsumer) ConsumeFunc(ctx context.Context, topic string, handler MessageHandler) error {
+	groupHandler := &funcGroupHandler{
+		groupID:     k.groupID,
+		handler:     handler,
+		deadLetters: k.deadLetters,
+		logger:      k.logger,
+	}
+
+	for {
+		// Consume returns on every rebalance and must be called again to rejoin
+		if err := k.consumer.Consume(ctx, []string{topic}, groupHandler); err != nil {
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				return nil
+			}
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// funcGroupHandler adapts a MessageHandler to sarama.ConsumerGroupHandler
+type funcGroupHandler struct {
+	groupID     string
+	handler     MessageHandler
+	deadLetters deadLetterSender // Nil redelivers messages instead
+	logger      *logger.Logger
+}
+
+// Setup is run at the beginning of a new session, before ConsumeClaim
+func (h *funcGroupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	h.logger.Infof("Kafka consumer group %s joined (generation %d)", h.groupID, session.GenerationID())
+	return nil
+}
+
+// Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited
+func (h *funcGroupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	// Flush marked offsets before partitions are handed to another member
+	session.Commit()
+	h.logger.Infof("Kafka consumer group %s released partitions (generation %d)", h.groupID, session.GenerationID())
+	return nil
+}
+
+// ConsumeClaim processes messages of a single partition sequentially
+func (h *funcGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	// In-flight messages finish even when the session is being torn down
+	handlerCtx := context.WithoutCancel(session.Context())
+	partition := strconv.Itoa(int(claim.Partition()))
+
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+
+			status := "success"
+			if err := h.handle(handlerCtx, msg); err != nil {
+				if dlqErr := h.deadLetter(handlerCtx, msg, err); dlqErr != nil {
+					// Offsets are cumulative, so marking a later message would skip this one:
+					// end the session instead, and consume it again once the group rejoins
+					metrics.KafkaMessagesConsumed.WithLabelValues(h.groupID, msg.Topic, "failed").Inc()
+					h.logger.Errorf("Stopping at Kafka message %s/%d@%d after %d attempts: %v",
+						msg.Topic, msg.Partition, msg.Offset, handlerRetries, dlqErr)
+					return fmt.Errorf("kafka message %s/%d@%d not handled: %w", msg.Topic, msg.Partition, msg.Offset, dlqErr)
+				}
+				status = "dead_lettered"
+				h.logger.Errorf("Dead-lettered Kafka message %s/%d@%d after %d attempts: %v",
+					msg.Topic, msg.Partition, msg.Offset, handlerRetries, err)
+			}
+
+			session.MarkMessage(msg, "")
+			metrics.KafkaMessagesConsumed.WithLabelValues(h.groupID, msg.Topic, status).Inc()
+			metrics.KafkaConsumerLag.WithLabelValues(h.groupID, msg.Topic, partition).
+				Set(float64(claim.HighWaterMarkOffset() - msg.Offset - 1))
+
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+// handle runs the handler with a bounded number of retries
+func (h *funcGroupHandler) handle(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	// Continue the producer's trace so async hops show up in the same trace
+	ctx = tracing.ExtractKafka(ctx, msg.Headers)
+	ctx, span := tracing.StartConsumerSpan(ctx, "kafka", msg.Topic)
+	defer span.End()
+
+	err := performance.Retry(ctx, handlerRetry, func(attempt int) error {
+		// A panicking handler counts as a failed attempt
+		err := apperrors.Guard(ctx, "kafka:"+msg.Topic, func() error {
+			return h.handler(ctx, msg)
+		})
+		if err != nil {
+			h.logger.WithContext(ctx).Warnf("Kafka handler failed for %s/%d@%d (attempt %d): %v",
+				msg.Topic, msg.Partition, msg.Offset, attempt+1, err)
+		}
+		return err
+	})
+	if err == nil {
+		return nil
+	}
+	span.RecordError(err)
+
+	// The message is dead-lettered or held up from here on, so make sure
+	// someone hears about it
+	apperrors.Capture(ctx, err, apperrors.Info{Component: "kafka:" + msg.Topic})
+	return err
+}
+
+// deadLetter publishes a message whose handler failed with cause to its
+// topic's dead letter topic, with the cause and where it was consumed from
+// in its headers. It returns cause when there is no dead letter topic.
+func (h *funcGroupHandler) deadLetter(ctx context.Context, msg *sarama.ConsumerMessage, cause error) error {
+	if h.deadLetters == nil {
+		return cause
+	}
+
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+3)
+	for _, header := range msg.Headers {
+		headers = append(headers, *header)
+	}
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte("x-error"), Value: []byte(cause.Error())},
+		sarama.RecordHeader{Key: []byte("x-original-partition"), Value: []byte(strconv.Itoa(int(msg.Partition)))},
+		sarama.RecordHeader{Key: []byte("x-original-offset"), Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+	)
+
+	dlq := deadLetterQueueName(msg.Topic)
+	if _, _, err := h.deadLetters.SendMessage(&sarama.ProducerMessage{
+		Topic:   dlq,
+		Key:     sarama.ByteEncoder(msg.Key),
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: headers,
+	}); err != nil {
+		h.logger.WithContext(ctx).Errorf("Failed to dead-letter Kafka message %s/%d@%d to %s: %v",
+			msg.Topic, msg.Partition, msg.Offset, dlq, err)
+		return fmt.Errorf("%w; dead-lettering failed: %v", cause, err)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+const testTopic = "orders"
+
+// fakeSession is a group session over one partition, recording marked offsets
+type fakeSession struct {
+	ctx    context.Context
+	mu     sync.Mutex
+	marked int64 // The next offset to consume, once committed
+}
+
+func (s *fakeSession) Claims() map[string][]int32               { return map[string][]int32{testTopic: {0}} }
+func (s *fakeSession) MemberID() string                         { return "member" }
+func (s *fakeSession) GenerationID() int32                      { return 1 }
+func (s *fakeSession) MarkOffset(string, int32, int64, string)  {}
+func (s *fakeSession) ResetOffset(string, int32, int64, string) {}
+func (s *fakeSession) Commit()                                  {}
+func (s *fakeSession) Context() context.Context                 { return s.ctx }
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marked = msg.Offset + 1
+}
+
+// fakeClaim is a partition claim delivering messages
+type fakeClaim struct {
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Topic() string                            { return testTopic }
+func (c *fakeClaim) Partition() int32                         { return 0 }
+func (c *fakeClaim) InitialOffset() int64                     { return 0 }
+func (c *fakeClaim) HighWaterMarkOffset() int64               { return int64(cap(c.messages)) }
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+// fakeGroup is a consumer group of one member over one partition holding
+// count messages. Each Consume is a session delivering the messages from the
+// committed offset; once every message is committed, it cancels the consumer.
+type fakeGroup struct {
+	sarama.ConsumerGroup
+	count     int64
+	committed int64
+	sessions  int
+	cancel    context.CancelFunc
+}
+
+func (g *fakeGroup) Consume(ctx context.Context, _ []string, handler sarama.ConsumerGroupHandler) error {
+	g.sessions++
+	sessionCtx, endSession := context.WithCancel(ctx)
+	defer endSession()
+
+	session := &fakeSession{ctx: sessionCtx, marked: g.committed}
+	claim := &fakeClaim{messages: make(chan *sarama.ConsumerMessage, g.count)}
+	for offset := g.committed; offset < g.count; offset++ {
+		claim.messages <- &sarama.ConsumerMessage{Topic: testTopic, Offset: offset, Value: []byte("order")}
+	}
+	close(claim.messages)
+
+	if err := handler.Setup(session); err != nil {
+		return err
+	}
+	handler.ConsumeClaim(session, claim)
+	handler.Cleanup(session)
+	g.committed = session.marked
+
+	if g.committed == g.count {
+		g.cancel()
+	}
+	return nil
+}
+
+// fakeDeadLetters records dead-lettered messages, failing with err
+type fakeDeadLetters struct {
+	sent []*sarama.ProducerMessage
+	err  error
+}
+
+func (d *fakeDeadLetters) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	if d.err != nil {
+		return 0, 0, d.err
+	}
+	d.sent = append(d.sent, msg)
+	return 0, int64(len(d.sent) - 1), nil
+}
+
+// consume runs ConsumeFunc over count messages until all are committed,
+// returning the group and the offsets handled, in order
+func consume(t *testing.T, count int64, deadLetters deadLetterSender, handler MessageHandler) (*fakeGroup, []int64) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	group := &fakeGroup{count: count, cancel: cancel}
+	consumer := &KafkaConsumer{consumer: group, groupID: "test", deadLetters: deadLetters, logger: logger.New("test")}
+
+	var handled []int64
+	require.NoError(t, consumer.ConsumeFunc(ctx, testTopic, func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		handled = append(handled, msg.Offset)
+		return handler(ctx, msg)
+	}))
+	require.Equal(t, count, group.committed, "consumer timed out before committing every message")
+	return group, handled
+}
+
+// failing returns a handler failing the message at offset the first n times
+func failing(offset int64, n int) MessageHandler {
+	failures := 0
+	return func(_ context.Context, msg *sarama.ConsumerMessage) error {
+		if msg.Offset == offset && failures < n {
+			failures++
+			return errors.New("database unavailable")
+		}
+		return nil
+	}
+}
+
+func TestConsumeFuncMarksHandledMessages(t *testing.T) {
+	group, handled := consume(t, 3, nil, failing(-1, 0))
+	assert.Equal(t, []int64{0, 1, 2}, handled)
+	assert.Equal(t, 1, group.sessions)
+}
+
+func TestConsumeFuncRetriesFailingMessages(t *testing.T) {
+	group, handled := consume(t, 3, nil, failing(1, handlerRetries-1))
+	assert.Equal(t, []int64{0, 1, 1, 1, 2}, handled)
+	assert.Equal(t, 1, group.sessions)
+}
+
+func TestConsumeFuncRedeliversUnhandledMessages(t *testing.T) {
+	// The message runs out of retries in the first session, which ends with
+	// it unmarked, so the next one consumes it again
+	group, handled := consume(t, 3, nil, failing(1, handlerRetries))
+	assert.Equal(t, []int64{0, 1, 1, 1, 1, 2}, handled)
+	assert.Equal(t, 2, group.sessions)
+}
+
+func TestConsumeFuncDeadLettersUnhandledMessages(t *testing.T) {
+	deadLetters := &fakeDeadLetters{}
+	group, handled := consume(t, 3, deadLetters, failing(1, handlerRetries))
+	assert.Equal(t, []int64{0, 1, 1, 1, 2}, handled)
+	assert.Equal(t, 1, group.sessions)
+
+	require.Len(t, deadLetters.sent, 1)
+	sent := deadLetters.sent[0]
+	assert.Equal(t, "orders.dlq", sent.Topic)
+	headers := map[string]string{}
+	for _, h := range sent.Headers {
+		headers[string(h.Key)] = string(h.Value)
+	}
+	assert.Equal(t, "database unavailable", headers["x-error"])
+	assert.Equal(t, "1", headers["x-original-offset"])
+}
+
+func TestConsumeFuncRedeliversWhenDeadLetteringFails(t *testing.T) {
+	deadLetters := &fakeDeadLetters{err: errors.New("broker unavailable")}
+	group, handled := consume(t, 2, deadLetters, failing(0, handlerRetries))
+	assert.Equal(t, []int64{0, 0, 0, 0, 1}, handled)
+	assert.Equal(t, 2, group.sessions)
+	assert.Empty(t, deadLetters.sent)
+}
+
+func TestConsumeClaimFinishesInFlightMessageOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &fakeSession{ctx: ctx}
+	claim := &fakeClaim{messages: make(chan *sarama.ConsumerMessage, 1)}
+	claim.messages <- &sarama.ConsumerMessage{Topic: testTopic, Offset: 0}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := &funcGroupHandler{groupID: "test", logger: logger.New("test"),
+		handler: func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+			close(started)
+			<-release
+			return ctx.Err() // The handler's context outlives the session
+		},
+	}
+
+	done := make(chan error)
+	go func() { done <- handler.ConsumeClaim(session, claim) }()
+
+	// The session ends while the first message is being handled
+	<-started
+	cancel()
+	close(release)
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ConsumeClaim did not return after the session ended")
+	}
+	assert.Equal(t, int64(1), session.marked, "the in-flight message is handled and marked")
+}
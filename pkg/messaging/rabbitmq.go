@@ -1,12 +1,14 @@
 package messaging
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/streadway/amqp"
 
 	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/tracing"
 )
 
 // RabbitMQClient wraps RabbitMQ connection and channel
@@ -76,18 +78,39 @@ func (r *RabbitMQClient) BindQueue(queue, key, exchange string) error {
 
 // Publish publishes a message to an exchange
 func (r *RabbitMQClient) Publish(exchange, key string, body []byte) error {
-	return r.channel.Publish(
+	return r.PublishContext(context.Background(), exchange, key, body)
+}
+
+// PublishContext publishes a message to an exchange, propagating the trace context in headers
+func (r *RabbitMQClient) PublishContext(ctx context.Context, exchange, key string, body []byte) error {
+	ctx, span := tracing.StartProducerSpan(ctx, "rabbitmq", exchange)
+	defer span.End()
+
+	headers := amqp.Table{}
+	tracing.InjectAMQP(ctx, headers)
+
+	err := r.channel.Publish(
 		exchange, // exchange
 		key,      // routing key
 		false,    // mandatory
 		false,    // immediate
 		amqp.Publishing{
+			Headers:      headers,
 			ContentType:  "application/json",
 			Body:         body,
 			DeliveryMode: amqp.Persistent, // Make message persistent
 			Timestamp:    time.Now(),
 		},
 	)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+// DeliveryContext returns a context carrying the trace context and request ID of a delivery
+func DeliveryContext(ctx context.Context, d amqp.Delivery) context.Context {
+	return tracing.ExtractAMQP(ctx, d.Headers)
 }
 
 // Consume consumes messages from a queue
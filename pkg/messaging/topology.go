@@ -0,0 +1,449 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/streadway/amqp"
+	"gopkg.in/yaml.v3"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+const (
+	// DeadLetterExchange is the exchange that receives rejected messages
+	DeadLetterExchange = "dlx"
+
+	// deadLetterTTL is how long dead-lettered messages are kept
+	deadLetterTTL = 7 * 24 * time.Hour
+)
+
+// Topology describes the broker objects a service expects to exist
+type Topology struct {
+	Exchanges   []ExchangeSpec `yaml:"exchanges"`
+	Queues      []QueueSpec    `yaml:"queues"`
+	Bindings    []BindingSpec  `yaml:"bindings"`
+	KafkaTopics []TopicSpec    `yaml:"kafka_topics"`
+}
+
+// ExchangeSpec describes a RabbitMQ exchange
+type ExchangeSpec struct {
+	Name    string `yaml:"name"`
+	Kind    string `yaml:"kind"` // direct, topic, fanout, headers
+	Durable *bool  `yaml:"durable"`
+}
+
+// QueueSpec describes a RabbitMQ queue and its optional dead letter queue
+type QueueSpec struct {
+	Name       string        `yaml:"name"`
+	Durable    *bool         `yaml:"durable"`
+	MessageTTL time.Duration `yaml:"message_ttl"`
+	MaxLength  int           `yaml:"max_length"`
+	DeadLetter bool          `yaml:"dead_letter"` // Declares <name>.dlq bound to the dlx exchange
+	RetryDelay time.Duration `yaml:"retry_delay"` // Declares <name>.retry, returning messages put there to the queue after this delay
+}
+
+// BindingSpec binds a queue to an exchange
+type BindingSpec struct {
+	Queue      string `yaml:"queue"`
+	Exchange   string `yaml:"exchange"`
+	RoutingKey string `yaml:"routing_key"`
+}
+
+// TopicSpec describes a Kafka topic
+type TopicSpec struct {
+	Name              string        `yaml:"name"`
+	Partitions        int32         `yaml:"partitions"`
+	ReplicationFactor int16         `yaml:"replication_factor"`
+	Retention         time.Duration `yaml:"retention"`
+}
+
+// Drift describes a difference between the declared and the live topology
+type Drift struct {
+	Kind   string // exchange, queue, binding, topic
+	Name   string
+	Detail string
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("%s %s: %s", d.Kind, d.Name, d.Detail)
+}
+
+// LoadTopology reads a topology file (YAML or JSON)
+func LoadTopology(path string) (*Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read topology file: %w", err)
+	}
+
+	var topology Topology
+	if err := yaml.Unmarshal(data, &topology); err != nil {
+		return nil, fmt.Errorf("failed to parse topology file: %w", err)
+	}
+
+	if err := topology.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &topology, nil
+}
+
+// Validate checks the topology for missing or dangling references
+func (t *Topology) Validate() error {
+	exchanges := make(map[string]bool)
+	for _, e := range t.Exchanges {
+		if e.Name == "" || e.Kind == "" {
+			return errors.New("exchange name and kind are required")
+		}
+		exchanges[e.Name] = true
+	}
+
+	queues := make(map[string]bool)
+	for _, q := range t.Queues {
+		if q.Name == "" {
+			return errors.New("queue name is required")
+		}
+		if _, err := queueArguments(q); err != nil {
+			return err
+		}
+		if q.RetryDelay > 0 {
+			if _, err := retryQueueArguments(q); err != nil {
+				return err
+			}
+		}
+		queues[q.Name] = true
+	}
+
+	for _, b := range t.Bindings {
+		if !queues[b.Queue] {
+			return fmt.Errorf("binding references undeclared queue %q", b.Queue)
+		}
+		if !exchanges[b.Exchange] {
+			return fmt.Errorf("binding references undeclared exchange %q", b.Exchange)
+		}
+	}
+
+	for _, topic := range t.KafkaTopics {
+		if topic.Name == "" {
+			return errors.New("kafka topic name is required")
+		}
+		if topic.Partitions <= 0 {
+			return fmt.Errorf("kafka topic %q must have at least one partition", topic.Name)
+		}
+	}
+
+	return nil
+}
+
+// deadLetterQueueName returns the dead letter queue name for a queue
+func deadLetterQueueName(queue string) string {
+	return queue + ".dlq"
+}
+
+// retryQueueName returns the retry queue name for a queue
+func retryQueueName(queue string) string {
+	return queue + ".retry"
+}
+
+// retryQueueArguments builds the x-arguments for a queue's retry queue,
+// which dead-letters each message back to the queue through the default
+// exchange once it has waited the retry delay
+func retryQueueArguments(q QueueSpec) (amqp.Table, error) {
+	delay, err := milliseconds(q.Name, "retry_delay", q.RetryDelay)
+	if err != nil {
+		return nil, err
+	}
+	return amqp.Table{
+		"x-message-ttl":             delay,
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": q.Name,
+	}, nil
+}
+
+// queueArguments builds the x-arguments for a queue spec
+func queueArguments(q QueueSpec) (amqp.Table, error) {
+	args := amqp.Table{}
+	if q.MessageTTL > 0 {
+		ttl, err := milliseconds(q.Name, "message_ttl", q.MessageTTL)
+		if err != nil {
+			return nil, err
+		}
+		args["x-message-ttl"] = ttl
+	}
+	if q.MaxLength > 0 {
+		if q.MaxLength > math.MaxInt32 {
+			return nil, fmt.Errorf("queue %q max_length %d exceeds %d", q.Name, q.MaxLength, math.MaxInt32)
+		}
+		args["x-max-length"] = int32(q.MaxLength)
+	}
+	if q.DeadLetter {
+		args["x-dead-letter-exchange"] = DeadLetterExchange
+		args["x-dead-letter-routing-key"] = deadLetterQueueName(q.Name)
+	}
+	return args, nil
+}
+
+// milliseconds converts a duration setting of a queue to the 32-bit
+// milliseconds RabbitMQ expects, refusing ones over about 24.8 days rather
+// than letting them wrap
+func milliseconds(queue, setting string, d time.Duration) (int32, error) {
+	ms := d.Milliseconds()
+	if ms > math.MaxInt32 {
+		return 0, fmt.Errorf("queue %q %s %s exceeds %s", queue, setting, d, time.Duration(math.MaxInt32)*time.Millisecond)
+	}
+	return int32(ms), nil
+}
+
+// isDurable defaults durability to true
+func isDurable(durable *bool) bool {
+	return durable == nil || *durable
+}
+
+// ApplyTopology declares the RabbitMQ part of a topology. Objects that already
+// exist with different settings are reported as drift instead of failing startup.
+func (r *RabbitMQClient) ApplyTopology(t *Topology) ([]Drift, error) {
+	var drifts []Drift
+
+	// declare runs a declaration, turning precondition failures into drift
+	declare := func(kind, name string, fn func() error) error {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		var amqpErr *amqp.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp.PreconditionFailed {
+			drifts = append(drifts, Drift{Kind: kind, Name: name, Detail: amqpErr.Reason})
+			// The server closes the channel on a failed declaration
+			return r.reopenChannel()
+		}
+		return fmt.Errorf("failed to declare %s %s: %w", kind, name, err)
+	}
+
+	needsDLX := false
+	for _, q := range t.Queues {
+		if q.DeadLetter {
+			needsDLX = true
+			break
+		}
+	}
+	if needsDLX {
+		if err := declare("exchange", DeadLetterExchange, func() error {
+			return r.DeclareExchange(DeadLetterExchange, "direct")
+		}); err != nil {
+			return drifts, err
+		}
+	}
+
+	for _, e := range t.Exchanges {
+		if err := declare("exchange", e.Name, func() error {
+			return r.channel.ExchangeDeclare(e.Name, e.Kind, isDurable(e.Durable), false, false, false, nil)
+		}); err != nil {
+			return drifts, err
+		}
+	}
+
+	for _, q := range t.Queues {
+		if q.DeadLetter {
+			dlq := deadLetterQueueName(q.Name)
+			if err := declare("queue", dlq, func() error {
+				_, err := r.channel.QueueDeclare(dlq, true, false, false, false, amqp.Table{
+					"x-message-ttl": int32(deadLetterTTL / time.Millisecond),
+				})
+				return err
+			}); err != nil {
+				return drifts, err
+			}
+			if err := declare("binding", dlq, func() error {
+				return r.BindQueue(dlq, dlq, DeadLetterExchange)
+			}); err != nil {
+				return drifts, err
+			}
+		}
+
+		if q.RetryDelay > 0 {
+			retry := retryQueueName(q.Name)
+			args, err := retryQueueArguments(q)
+			if err != nil {
+				return drifts, err
+			}
+			if err := declare("queue", retry, func() error {
+				_, err := r.channel.QueueDeclare(retry, true, false, false, false, args)
+				return err
+			}); err != nil {
+				return drifts, err
+			}
+		}
+
+		args, err := queueArguments(q)
+		if err != nil {
+			return drifts, err
+		}
+		if err := declare("queue", q.Name, func() error {
+			_, err := r.channel.QueueDeclare(q.Name, isDurable(q.Durable), false, false, false, args)
+			return err
+		}); err != nil {
+			return drifts, err
+		}
+	}
+
+	for _, b := range t.Bindings {
+		name := fmt.Sprintf("%s->%s(%s)", b.Exchange, b.Queue, b.RoutingKey)
+		if err := declare("binding", name, func() error {
+			return r.BindQueue(b.Queue, b.RoutingKey, b.Exchange)
+		}); err != nil {
+			return drifts, err
+		}
+	}
+
+	for _, d := range drifts {
+		r.logger.Warnf("Messaging topology drift: %s", d)
+	}
+	r.logger.Infof("Applied RabbitMQ topology: %d exchanges, %d queues, %d bindings",
+		len(t.Exchanges), len(t.Queues), len(t.Bindings))
+
+	return drifts, nil
+}
+
+// reopenChannel replaces a channel closed by the server
+func (r *RabbitMQClient) reopenChannel() error {
+	channel, err := r.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to reopen channel: %w", err)
+	}
+	r.channel = channel
+	return nil
+}
+
+// ApplyKafkaTopics creates missing Kafka topics and reports drift on existing ones.
+// Partition counts are grown to match the declaration; retention is only reported.
+func ApplyKafkaTopics(brokers []string, topics []TopicSpec, log *logger.Logger) ([]Drift, error) {
+	if len(topics) == 0 {
+		return nil, nil
+	}
+
+	admin, err := sarama.NewClusterAdmin(brokers, sarama.NewConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka admin: %w", err)
+	}
+	defer admin.Close()
+
+	existing, err := admin.ListTopics()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Kafka topics: %w", err)
+	}
+
+	var drifts []Drift
+	for _, spec := range topics {
+		live, ok := existing[spec.Name]
+		if !ok {
+			if err := admin.CreateTopic(spec.Name, topicDetail(spec), false); err != nil {
+				return drifts, fmt.Errorf("failed to create topic %s: %w", spec.Name, err)
+			}
+			log.Infof("Created Kafka topic %s (%d partitions)", spec.Name, spec.Partitions)
+			continue
+		}
+
+		drifts = append(drifts, topicDrift(spec, live)...)
+
+		if live.NumPartitions < spec.Partitions {
+			if err := admin.CreatePartitions(spec.Name, spec.Partitions, nil, false); err != nil {
+				return drifts, fmt.Errorf("failed to grow partitions for %s: %w", spec.Name, err)
+			}
+			log.Infof("Grew Kafka topic %s to %d partitions", spec.Name, spec.Partitions)
+		}
+	}
+
+	for _, d := range drifts {
+		log.Warnf("Messaging topology drift: %s", d)
+	}
+
+	return drifts, nil
+}
+
+// topicDetail converts a topic spec into a sarama topic detail
+func topicDetail(spec TopicSpec) *sarama.TopicDetail {
+	replication := spec.ReplicationFactor
+	if replication <= 0 {
+		replication = 1
+	}
+
+	detail := &sarama.TopicDetail{
+		NumPartitions:     spec.Partitions,
+		ReplicationFactor: replication,
+		ConfigEntries:     map[string]*string{},
+	}
+	if spec.Retention > 0 {
+		retention := strconv.FormatInt(spec.Retention.Milliseconds(), 10)
+		detail.ConfigEntries["retention.ms"] = &retention
+	}
+	return detail
+}
+
+// topicDrift compares a topic spec with the live topic
+func topicDrift(spec TopicSpec, live sarama.TopicDetail) []Drift {
+	var drifts []Drift
+
+	if live.NumPartitions != spec.Partitions {
+		drifts = append(drifts, Drift{
+			Kind:   "topic",
+			Name:   spec.Name,
+			Detail: fmt.Sprintf("partitions: declared %d, live %d", spec.Partitions, live.NumPartitions),
+		})
+	}
+
+	if spec.Retention > 0 {
+		want := strconv.FormatInt(spec.Retention.Milliseconds(), 10)
+		if got := live.ConfigEntries["retention.ms"]; got == nil || *got != want {
+			current := "default"
+			if got != nil {
+				current = *got
+			}
+			drifts = append(drifts, Drift{
+				Kind:   "topic",
+				Name:   spec.Name,
+				Detail: fmt.Sprintf("retention.ms: declared %s, live %s", want, current),
+			})
+		}
+	}
+
+	return drifts
+}
+
+// SetupTopology loads the configured topology file and applies it to RabbitMQ and Kafka.
+// It is a no-op when no topology file is configured.
+func SetupTopology(cfg config.MessagingConfig, log *logger.Logger) ([]Drift, error) {
+	if cfg.TopologyFile == "" {
+		return nil, nil
+	}
+
+	topology, err := LoadTopology(cfg.TopologyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	if len(topology.Exchanges) > 0 || len(topology.Queues) > 0 {
+		client, err := NewRabbitMQClient(cfg.RabbitMQURL, log)
+		if err != nil {
+			return nil, err
+		}
+		defer client.Close()
+
+		rabbitDrifts, err := client.ApplyTopology(topology)
+		drifts = append(drifts, rabbitDrifts...)
+		if err != nil {
+			return drifts, err
+		}
+	}
+
+	kafkaDrifts, err := ApplyKafkaTopics(cfg.KafkaBrokers, topology.KafkaTopics, log)
+	drifts = append(drifts, kafkaDrifts...)
+	return drifts, err
+}
@@ -0,0 +1,100 @@
+package messaging
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTopology(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "topology.yaml")
+	content := `
+exchanges:
+  - name: events
+    kind: topic
+queues:
+  - name: order.events
+    dead_letter: true
+    message_ttl: 1h
+bindings:
+  - queue: order.events
+    exchange: events
+    routing_key: order.*
+kafka_topics:
+  - name: orders
+    partitions: 3
+    retention: 24h
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	topology, err := LoadTopology(path)
+	require.NoError(t, err)
+	assert.Len(t, topology.Exchanges, 1)
+	assert.Equal(t, time.Hour, topology.Queues[0].MessageTTL)
+	assert.Equal(t, 24*time.Hour, topology.KafkaTopics[0].Retention)
+}
+
+func TestTopologyValidate_DanglingBinding(t *testing.T) {
+	topology := &Topology{
+		Queues:   []QueueSpec{{Name: "order.events"}},
+		Bindings: []BindingSpec{{Queue: "order.events", Exchange: "missing"}},
+	}
+	assert.Error(t, topology.Validate())
+}
+
+func TestQueueArguments_DeadLetter(t *testing.T) {
+	args, err := queueArguments(QueueSpec{Name: "order.events", DeadLetter: true, MessageTTL: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, DeadLetterExchange, args["x-dead-letter-exchange"])
+	assert.Equal(t, "order.events.dlq", args["x-dead-letter-routing-key"])
+	assert.Equal(t, int32(60000), args["x-message-ttl"])
+}
+
+func TestQueueArguments_LargeTTL(t *testing.T) {
+	// The largest TTL RabbitMQ takes, about 24.8 days
+	largest := time.Duration(math.MaxInt32) * time.Millisecond
+	args, err := queueArguments(QueueSpec{Name: "order.events", MessageTTL: largest})
+	require.NoError(t, err)
+	assert.Equal(t, int32(math.MaxInt32), args["x-message-ttl"])
+
+	// Longer ones are refused instead of wrapping to a negative TTL
+	_, err = queueArguments(QueueSpec{Name: "order.events", MessageTTL: 30 * 24 * time.Hour})
+	assert.Error(t, err)
+	_, err = queueArguments(QueueSpec{Name: "order.events", MaxLength: math.MaxInt32 + 1})
+	assert.Error(t, err)
+	_, err = retryQueueArguments(QueueSpec{Name: "order.events", RetryDelay: largest + time.Millisecond})
+	assert.Error(t, err)
+
+	// And fail validation, so services refuse the topology file at startup
+	topology := &Topology{Queues: []QueueSpec{{Name: "order.events", MessageTTL: 30 * 24 * time.Hour}}}
+	assert.Error(t, topology.Validate())
+	topology = &Topology{Queues: []QueueSpec{{Name: "order.events", RetryDelay: 30 * 24 * time.Hour}}}
+	assert.Error(t, topology.Validate())
+}
+
+func TestRetryQueueArguments(t *testing.T) {
+	q := QueueSpec{Name: "notification.requests", RetryDelay: 30 * time.Second}
+	args, err := retryQueueArguments(q)
+	require.NoError(t, err)
+	assert.Equal(t, "notification.requests.retry", retryQueueName(q.Name))
+	assert.Equal(t, int32(30000), args["x-message-ttl"])
+	assert.Equal(t, "", args["x-dead-letter-exchange"])
+	assert.Equal(t, "notification.requests", args["x-dead-letter-routing-key"])
+}
+
+func TestTopicDrift(t *testing.T) {
+	retention := "3600000"
+	live := sarama.TopicDetail{
+		NumPartitions: 3,
+		ConfigEntries: map[string]*string{"retention.ms": &retention},
+	}
+
+	assert.Empty(t, topicDrift(TopicSpec{Name: "orders", Partitions: 3, Retention: time.Hour}, live))
+	assert.Len(t, topicDrift(TopicSpec{Name: "orders", Partitions: 6, Retention: 2 * time.Hour}, live), 2)
+}
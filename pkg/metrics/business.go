@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Reasons a stock reservation is refused
+const (
+	ConflictInsufficientStock = "insufficient_stock"
+	ConflictVersion           = "version_conflict"
+)
+
+// noStore labels inventory and orders that are not tied to a store
+const noStore = "none"
+
+// Business metrics describe what the services do for stores and customers,
+// as opposed to how their HTTP servers behave
+var (
+	OrdersCreated = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "orders_created_total",
+			Help: "Total number of orders created",
+		},
+		[]string{"store_id"},
+	)
+
+	OrderValue = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "orders_value_total",
+			Help: "Total value of orders created, in the order currency",
+		},
+		[]string{"store_id", "currency"},
+	)
+
+	PaymentsProcessed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "payments_processed_total",
+			Help: "Total number of payments that reached a final status",
+		},
+		[]string{"provider", "status"},
+	)
+
+	Refunds = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "refunds_total",
+			Help: "Total number of refunds issued",
+		},
+		[]string{"provider"},
+	)
+
+	RefundAmount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "refunds_amount_total",
+			Help: "Total amount refunded, in the payment currency",
+		},
+		[]string{"provider", "currency"},
+	)
+
+	ReservationConflicts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "inventory_reservation_conflicts_total",
+			Help: "Total number of stock reservations refused",
+		},
+		[]string{"reason"},
+	)
+
+	LowStockEvents = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "inventory_low_stock_events_total",
+			Help: "Total number of times available stock fell to the reorder point",
+		},
+		[]string{"store_id"},
+	)
+
+	StockOuts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "inventory_stock_outs_total",
+			Help: "Total number of times available stock ran out",
+		},
+		[]string{"store_id"},
+	)
+)
+
+// RecordOrderCreated counts a new order and its value
+func RecordOrderCreated(storeID, currency string, amount float64) {
+	store := storeLabel(storeID)
+	OrdersCreated.WithLabelValues(store).Inc()
+	OrderValue.WithLabelValues(store, currency).Add(amount)
+}
+
+// RecordPayment counts a payment that reached status with provider
+func RecordPayment(provider, status string) {
+	PaymentsProcessed.WithLabelValues(provider, status).Inc()
+}
+
+// RecordRefund counts a refund and the amount returned to the customer
+func RecordRefund(provider, currency string, amount float64) {
+	Refunds.WithLabelValues(provider).Inc()
+	RefundAmount.WithLabelValues(provider, currency).Add(amount)
+}
+
+// RecordReservationConflict counts a refused reservation
+func RecordReservationConflict(reason string) {
+	ReservationConflicts.WithLabelValues(reason).Inc()
+}
+
+// RecordStockChange counts low-stock and stock-out events when available
+// stock crosses the reorder point or zero. Levels that stay below a threshold
+// are not counted again, so each event is one item needing attention.
+func RecordStockChange(storeID string, previous, current, reorderPoint int) {
+	store := storeLabel(storeID)
+	if previous > reorderPoint && current <= reorderPoint {
+		LowStockEvents.WithLabelValues(store).Inc()
+	}
+	if previous > 0 && current <= 0 {
+		StockOuts.WithLabelValues(store).Inc()
+	}
+}
+
+// storeLabel returns the store label for an optional store ID
+func storeLabel(storeID string) string {
+	if storeID == "" {
+		return noStore
+	}
+	return storeID
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordOrderCreated(t *testing.T) {
+	RecordOrderCreated("store-orders", "USD", 12.5)
+	RecordOrderCreated("store-orders", "USD", 7.5)
+	RecordOrderCreated("", "USD", 1)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(OrdersCreated.WithLabelValues("store-orders")))
+	assert.Equal(t, 20.0, testutil.ToFloat64(OrderValue.WithLabelValues("store-orders", "USD")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(OrdersCreated.WithLabelValues(noStore)))
+}
+
+func TestRecordStockChange(t *testing.T) {
+	const store = "store-stock"
+	lowStock := func() float64 { return testutil.ToFloat64(LowStockEvents.WithLabelValues(store)) }
+	stockOuts := func() float64 { return testutil.ToFloat64(StockOuts.WithLabelValues(store)) }
+
+	// Above the reorder point
+	RecordStockChange(store, 20, 15, 10)
+	assert.Equal(t, 0.0, lowStock())
+
+	// Crossing the reorder point
+	RecordStockChange(store, 15, 10, 10)
+	assert.Equal(t, 1.0, lowStock())
+	assert.Equal(t, 0.0, stockOuts())
+
+	// Already low: not counted again
+	RecordStockChange(store, 10, 5, 10)
+	assert.Equal(t, 1.0, lowStock())
+
+	// Running out
+	RecordStockChange(store, 5, 0, 10)
+	assert.Equal(t, 1.0, lowStock())
+	assert.Equal(t, 1.0, stockOuts())
+
+	// Straight from plenty to nothing counts both
+	RecordStockChange(store, 50, 0, 10)
+	assert.Equal(t, 2.0, lowStock())
+	assert.Equal(t, 2.0, stockOuts())
+}
@@ -5,30 +5,42 @@ import (
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// Handler serves the default registry. Scrapers that ask for OpenMetrics also
+// receive exemplars linking samples to traces.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})
+}
+
 // FiberMetricsHandler creates a Fiber handler for Prometheus metrics
 func FiberMetricsHandler() fiber.Handler {
+	handler := Handler()
+
 	return func(c *fiber.Ctx) error {
 		// Create a buffer to capture metrics output
 		var buf bytes.Buffer
-		
+
 		// Create a response writer that writes to buffer
 		w := &bufferResponseWriter{
 			buffer: &buf,
 			header: make(http.Header),
 		}
-		
-		// Create a simple HTTP request
+
+		// Pass the scraper's Accept header through so the exposition format is negotiated
 		req, _ := http.NewRequest("GET", "/metrics", nil)
-		
+		req.Header.Set(fiber.HeaderAccept, c.Get(fiber.HeaderAccept))
+
 		// Serve metrics to buffer
-		promhttp.Handler().ServeHTTP(w, req)
-		
+		handler.ServeHTTP(w, req)
+
 		// Set content type
-		c.Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
-		
+		c.Set("Content-Type", w.header.Get("Content-Type"))
+
 		// Write metrics to Fiber response
 		return c.Send(buf.Bytes())
 	}
@@ -51,4 +63,3 @@ func (w *bufferResponseWriter) Write(b []byte) (int, error) {
 func (w *bufferResponseWriter) WriteHeader(statusCode int) {
 	w.status = statusCode
 }
-
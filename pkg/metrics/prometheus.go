@@ -6,23 +6,7 @@ import (
 )
 
 var (
-	// HTTP metrics
-	HTTPRequestsTotal = promauto.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "http_requests_total",
-			Help: "Total number of HTTP requests",
-		},
-		[]string{"method", "endpoint", "status"},
-	)
-
-	HTTPRequestDuration = promauto.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "http_request_duration_seconds",
-			Help:    "HTTP request duration in seconds",
-			Buckets: prometheus.DefBuckets,
-		},
-		[]string{"method", "endpoint"},
-	)
+	// HTTP server metrics are registered per service by NewRED
 
 	// Database metrics
 	DatabaseConnections = promauto.NewGaugeVec(
@@ -59,34 +43,266 @@ var (
 		[]string{"cache_type"},
 	)
 
-	// Business metrics
-	OrdersCreated = promauto.NewCounter(
+	// Messaging metrics
+	KafkaConsumerLag = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_consumer_lag",
+			Help: "Number of messages the consumer group is behind the partition high water mark",
+		},
+		[]string{"group", "topic", "partition"},
+	)
+
+	KafkaMessagesConsumed = promauto.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "orders_created_total",
-			Help: "Total number of orders created",
+			Name: "kafka_messages_consumed_total",
+			Help: "Total number of Kafka messages handled by consumers",
+		},
+		[]string{"group", "topic", "status"},
+	)
+
+	// Rate limiting metrics
+	RateLimitBackendUp = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ratelimit_backend_up",
+			Help: "Whether the shared Redis rate limit backend is reachable (1) or the fallback policy is in effect (0)",
 		},
 	)
 
-	PaymentsProcessed = promauto.NewCounterVec(
+	RateLimitFallbackRequests = promauto.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "payments_processed_total",
-			Help: "Total number of payments processed",
+			Name: "ratelimit_fallback_requests_total",
+			Help: "Total number of requests handled by the rate limit fallback policy",
 		},
-		[]string{"status"},
+		[]string{"policy", "result"},
+	)
+
+	// Bulkhead metrics
+	BulkheadInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "bulkhead_in_flight",
+			Help: "Number of calls running inside a dependency's bulkhead",
+		},
+		[]string{"dependency"},
+	)
+
+	BulkheadQueued = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "bulkhead_queued",
+			Help: "Number of calls waiting for a slot in a dependency's bulkhead",
+		},
+		[]string{"dependency"},
+	)
+
+	BulkheadRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bulkhead_rejected_total",
+			Help: "Total number of calls a dependency's bulkhead turned away, by reason",
+		},
+		[]string{"dependency", "reason"},
+	)
+
+	OutboundRateLimited = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbound_rate_limited_total",
+			Help: "Total number of outbound calls held back by a dependency's rate limit, by whether they waited or were rejected",
+		},
+		[]string{"dependency", "result"},
+	)
+
+	Retries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "retries_total",
+			Help: "Total number of retries of failed calls, and of retries refused by the retry budget",
+		},
+		[]string{"caller", "result"},
+	)
+
+	AdaptiveConcurrencyLimit = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "adaptive_concurrency_limit",
+			Help: "Current adaptive limit on in-flight requests to a dependency",
+		},
+		[]string{"dependency"},
+	)
+
+	AdaptiveConcurrencyRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "adaptive_concurrency_rejected_total",
+			Help: "Total number of requests rejected by a dependency's adaptive concurrency limit",
+		},
+		[]string{"dependency"},
+	)
+
+	// Circuit breaker metrics
+	CircuitBreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "circuit_breaker_state",
+			Help: "Circuit breaker state (0 = closed, 1 = open, 2 = half-open)",
+		},
+		[]string{"name"},
+	)
+
+	CircuitBreakerTransitions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "circuit_breaker_transitions_total",
+			Help: "Total number of circuit breaker state changes by the state entered",
+		},
+		[]string{"name", "to"},
+	)
+
+	CircuitBreakerRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "circuit_breaker_rejected_total",
+			Help: "Total number of calls rejected by an open or probing circuit breaker",
+		},
+		[]string{"name"},
+	)
+
+	// Encryption metrics
+	EncryptionReencrypted = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "encryption_reencrypted_total",
+			Help: "Total number of stored values visited by key rotation re-encryption",
+		},
+		[]string{"store", "result"},
+	)
+
+	// Webhook metrics
+	WebhookDeliveries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_deliveries_total",
+			Help: "Total number of outbound webhook deliveries",
+		},
+		[]string{"event", "result"},
+	)
+
+	// Notification metrics
+	NotificationDeliveries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "notification_deliveries_total",
+			Help: "Total number of notification delivery attempts",
+		},
+		[]string{"channel", "result"},
+	)
+
+	// Loyalty metrics
+	LoyaltyPoints = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "loyalty_points_total",
+			Help: "Total number of loyalty points moved, by transaction type",
+		},
+		[]string{"type"},
+	)
+
+	// Promotion metrics
+	PromotionsApplied = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "promotions_applied_total",
+			Help: "Total number of promotions applied to orders being priced, by rule type",
+		},
+		[]string{"type"},
+	)
+
+	// Analytics metrics
+	AnalyticsEvents = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "analytics_events_total",
+			Help: "Total number of events consumed into the analytics rollups, by event type and result",
+		},
+		[]string{"event", "result"},
+	)
+
+	// Receipt metrics
+	PrintJobs = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "receipt_print_jobs_total",
+			Help: "Total number of print jobs by kind and status (queued when created, printed or failed when acknowledged)",
+		},
+		[]string{"kind", "status"},
+	)
+
+	// Shift metrics
+	ShiftsClosed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "shifts_closed_total",
+			Help: "Total number of register shifts closed, by whether the drawer counted over, short or balanced",
+		},
+		[]string{"result"},
+	)
+
+	// Tax metrics
+	TaxQuotes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tax_quotes_total",
+			Help: "Total number of sales tax quotes, by whether they were quoted or failed",
+		},
+		[]string{"result"},
 	)
-)
 
-// RecordHTTPRequest records an HTTP request metric
-func RecordHTTPRequest(method, endpoint string, statusCode int, duration float64) {
-	status := "success"
-	if statusCode >= 400 {
-		status = "error"
-	}
-	if statusCode >= 500 {
-		status = "server_error"
-	}
+	// Worker pool metrics
+	WorkerPoolTasks = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_pool_tasks_total",
+			Help: "Total number of background tasks by outcome: success, failed, timeout, panicked, rejected, or dropped",
+		},
+		[]string{"pool", "result"},
+	)
+
+	WorkerPoolTaskDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "worker_pool_task_duration_seconds",
+			Help:    "Background task duration in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"pool"},
+	)
 
-	HTTPRequestsTotal.WithLabelValues(method, endpoint, status).Inc()
-	HTTPRequestDuration.WithLabelValues(method, endpoint).Observe(duration)
-}
+	WorkerPoolQueued = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "worker_pool_queued",
+			Help: "Number of background tasks waiting for a worker",
+		},
+		[]string{"pool"},
+	)
+
+	WorkerPoolBusy = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "worker_pool_busy",
+			Help: "Number of workers running a background task",
+		},
+		[]string{"pool"},
+	)
 
+	// Gateway proxy metrics
+	UpstreamHealthy = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "proxy_upstream_healthy",
+			Help: "Whether an upstream instance is receiving traffic (1) or ejected after failures (0)",
+		},
+		[]string{"service", "target"},
+	)
+
+	UpstreamEjections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "proxy_upstream_ejections_total",
+			Help: "Total number of times an upstream instance was taken out of rotation, by reason",
+		},
+		[]string{"service", "target", "reason"},
+	)
+
+	UpstreamDiscoveryFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "proxy_upstream_discovery_failures_total",
+			Help: "Total number of failed or empty lookups of a service's instances, which keep the instances already known",
+		},
+		[]string{"service"},
+	)
+
+	ProxyHedgedRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "proxy_hedged_requests_total",
+			Help: "Total number of requests sent to a second instance after the hedge delay, by which copy answered first",
+		},
+		[]string{"service", "winner"},
+	)
+)
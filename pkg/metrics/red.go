@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultDurationBuckets suit API latencies from cache hits to slow payment calls
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// RED records request rate, errors, and duration for a service's HTTP server.
+// Errors are requests with a 5xx status, so rate and errors share one counter.
+type RED struct {
+	service   string
+	exemplars bool
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+}
+
+// NewRED registers the RED metrics with the default registry. With exemplars
+// enabled, observations made within a sampled trace link to its trace ID.
+func NewRED(service string, buckets []float64, exemplars bool) *RED {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+	// Histograms need strictly increasing bounds
+	buckets = slices.Compact(slices.Sorted(slices.Values(buckets)))
+
+	return &RED{
+		service:   service,
+		exemplars: exemplars,
+		requests: register(prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_server_requests_total",
+				Help: "Total number of HTTP requests handled, by route and status code",
+			},
+			[]string{"service", "method", "route", "status"},
+		)),
+		duration: register(prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_server_request_duration_seconds",
+				Help:    "HTTP request duration in seconds, by route and status class",
+				Buckets: buckets,
+			},
+			[]string{"service", "method", "route", "status_class"},
+		)),
+		inFlight: register(prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "http_server_requests_in_flight",
+				Help: "Number of HTTP requests currently being handled",
+			},
+			[]string{"service"},
+		)),
+	}
+}
+
+// Start marks a request as in flight; call the returned function when it completes
+func (r *RED) Start() func() {
+	gauge := r.inFlight.WithLabelValues(r.service)
+	gauge.Inc()
+	return gauge.Dec
+}
+
+// Observe records a completed request
+func (r *RED) Observe(ctx context.Context, method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	counter := r.requests.WithLabelValues(r.service, method, route, code)
+	histogram := r.duration.WithLabelValues(r.service, method, route, statusClass(status))
+
+	if exemplar := r.exemplar(ctx); exemplar != nil {
+		counter.(prometheus.ExemplarAdder).AddWithExemplar(1, exemplar)
+		histogram.(prometheus.ExemplarObserver).ObserveWithExemplar(duration.Seconds(), exemplar)
+		return
+	}
+
+	counter.Inc()
+	histogram.Observe(duration.Seconds())
+}
+
+// exemplar returns the trace labels for ctx, or nil outside a sampled trace
+func (r *RED) exemplar(ctx context.Context) prometheus.Labels {
+	if !r.exemplars || ctx == nil {
+		return nil
+	}
+
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() || !spanContext.IsSampled() {
+		return nil
+	}
+	return prometheus.Labels{"trace_id": spanContext.TraceID().String()}
+}
+
+// statusClass groups status codes (2xx, 4xx, ...) to bound histogram cardinality
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// register adds a collector to the default registry, reusing an identical
+// collector that is already registered
+func register[C prometheus.Collector](collector C) C {
+	if err := prometheus.Register(collector); err != nil {
+		var already prometheus.AlreadyRegisteredError
+		if errors.As(err, &already) {
+			if existing, ok := already.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return collector
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestStatusClass(t *testing.T) {
+	assert.Equal(t, "2xx", statusClass(200))
+	assert.Equal(t, "4xx", statusClass(404))
+	assert.Equal(t, "5xx", statusClass(503))
+	assert.Equal(t, "unknown", statusClass(0))
+	assert.Equal(t, "unknown", statusClass(600))
+}
+
+func TestNewREDReusesRegisteredCollectors(t *testing.T) {
+	first := NewRED("red-test", []float64{1, 0.5, 0.5}, false)
+	second := NewRED("red-test", nil, false)
+
+	assert.Same(t, first.requests, second.requests)
+	assert.Same(t, first.duration, second.duration)
+}
+
+func TestREDObserve(t *testing.T) {
+	red := NewRED("red-observe", nil, true)
+
+	done := red.Start()
+	assert.Equal(t, 1.0, testutil.ToFloat64(red.inFlight.WithLabelValues("red-observe")))
+	done()
+	assert.Equal(t, 0.0, testutil.ToFloat64(red.inFlight.WithLabelValues("red-observe")))
+
+	red.Observe(context.Background(), "GET", "/orders/:id", 200, 10*time.Millisecond)
+	red.Observe(context.Background(), "GET", "/orders/:id", 500, 10*time.Millisecond)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(red.requests.WithLabelValues("red-observe", "GET", "/orders/:id", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(red.requests.WithLabelValues("red-observe", "GET", "/orders/:id", "500")))
+}
+
+func TestREDExemplar(t *testing.T) {
+	red := NewRED("red-exemplar", nil, true)
+
+	assert.Nil(t, red.exemplar(context.Background()))
+
+	traceID := trace.TraceID{1, 2, 3}
+	sampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	assert.Equal(t, prometheus.Labels{"trace_id": traceID.String()}, red.exemplar(sampled))
+
+	unsampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  trace.SpanID{1},
+	}))
+	assert.Nil(t, red.exemplar(unsampled))
+
+	red.exemplars = false
+	assert.Nil(t, red.exemplar(sampled))
+}
@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/onichange/pos-system/pkg/audit/security"
 	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/logger"
 )
 
 // JWTAuth creates a JWT authentication middleware
@@ -36,24 +39,78 @@ func JWTAuth(jwtManager *auth.JWTManager) fiber.Handler {
 			})
 		}
 
-		// Set user information in context
-		c.Locals("user_id", claims.UserID)
-		c.Locals("email", claims.Email)
-		c.Locals("roles", claims.Roles)
-		c.Locals("device_id", claims.DeviceID)
+		authenticate(c, claims)
+		return c.Next()
+	}
+}
 
-		// Set user ID in header for downstream services
-		c.Set("X-User-ID", claims.UserID)
+// OptionalJWTAuth authenticates requests carrying a valid bearer token, as
+// JWTAuth does, and lets the others through unauthenticated. It lets
+// middleware running before JWTAuth, such as rate limiting, tell users and
+// their tenants apart without trusting anything the client claims.
+func OptionalJWTAuth(jwtManager *auth.JWTManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer ")
+		if !ok {
+			return c.Next()
+		}
+		if claims, err := jwtManager.ValidateAccessToken(token); err == nil {
+			authenticate(c, claims)
+		}
+		return c.Next()
+	}
+}
+
+// ServiceAuth authenticates calls to a service's internal API, which carry a
+// token from auth.JWTManager.GenerateServiceToken. Requests without a valid
+// token are rejected with 401, and tokens of users with 403.
+func ServiceAuth(jwtManager *auth.JWTManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer ")
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Service token is required",
+			})
+		}
+		claims, err := jwtManager.ValidateAccessToken(token)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid or expired token",
+			})
+		}
 
+		authenticate(c, claims)
+		if !slices.Contains(claims.Roles, auth.RoleService) {
+			recordPermissionDenied(c, []string{auth.RoleService})
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Insufficient permissions",
+			})
+		}
 		return c.Next()
 	}
 }
 
+// authenticate sets the user information of verified claims in the context
+func authenticate(c *fiber.Ctx, claims *auth.JWTClaims) {
+	c.Locals("user_id", claims.UserID)
+	c.Locals("email", claims.Email)
+	c.Locals("roles", claims.Roles)
+	c.Locals("device_id", claims.DeviceID)
+	if claims.TenantID != "" {
+		c.Locals("tenant_id", claims.TenantID)
+	}
+	c.SetUserContext(logger.ContextWithUserID(c.UserContext(), claims.UserID))
+
+	// Set user ID in header for downstream services
+	c.Set("X-User-ID", claims.UserID)
+}
+
 // RequireRole creates a middleware that requires specific roles
 func RequireRole(requiredRoles ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		roles, ok := c.Locals("roles").([]string)
 		if !ok {
+			recordPermissionDenied(c, requiredRoles)
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "Access denied",
 			})
@@ -68,9 +125,21 @@ func RequireRole(requiredRoles ...string) fiber.Handler {
 			}
 		}
 
+		recordPermissionDenied(c, requiredRoles)
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Insufficient permissions",
 		})
 	}
 }
 
+// recordPermissionDenied writes a role check failure to the security audit log
+func recordPermissionDenied(c *fiber.Ctx, requiredRoles []string) {
+	security.RecordRequest(c, security.Event{
+		Type:    security.EventPermissionDenied,
+		Outcome: security.OutcomeDenied,
+		Details: map[string]string{
+			"required_roles": strings.Join(requiredRoles, ","),
+			"path":           c.Path(),
+		},
+	})
+}
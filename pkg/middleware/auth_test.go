@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/auth"
+)
+
+func TestServiceAuth(t *testing.T) {
+	jwtManager := auth.NewJWTManager("access", "refresh", time.Hour, time.Hour, "test")
+	app := fiber.New()
+	app.Post("/internal/v1/payments", ServiceAuth(jwtManager), func(c *fiber.Ctx) error {
+		tenantID, _ := c.Locals("tenant_id").(string)
+		return c.SendString(tenantID)
+	})
+
+	send := func(authorization string) (int, string) {
+		req := httptest.NewRequest(fiber.MethodPost, "/internal/v1/payments", nil)
+		if authorization != "" {
+			req.Header.Set(fiber.HeaderAuthorization, authorization)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	serviceToken, err := jwtManager.GenerateServiceToken("acme")
+	require.NoError(t, err)
+	status, body := send("Bearer " + serviceToken)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "acme", body)
+
+	// Unauthenticated calls, and tokens signed with another secret
+	status, _ = send("")
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	status, _ = send(serviceToken)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	forged, err := auth.NewJWTManager("other", "refresh", time.Hour, time.Hour, "test").GenerateServiceToken("acme")
+	require.NoError(t, err)
+	status, _ = send("Bearer " + forged)
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+
+	// Users, even admins, are not services
+	pair, err := jwtManager.GenerateTenantTokenPair("acme", "user-1", "user@example.com", []string{"admin"}, "")
+	require.NoError(t, err)
+	status, _ = send("Bearer " + pair.AccessToken)
+	assert.Equal(t, fiber.StatusForbidden, status)
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// BodyLogger logs request and response bodies at debug level for troubleshooting
+// integrations. Sensitive JSON fields are redacted or masked before writing;
+// bodies that are not JSON are summarized rather than logged. It does nothing
+// unless cfg.BodyLogging is set and the logger is at debug level.
+func BodyLogger(log *logger.Logger, cfg config.LoggingConfig) fiber.Handler {
+	redactor := logger.NewRedactor(
+		append(append([]string{}, logger.DefaultRedactFields...), cfg.RedactFields...),
+		append(append([]string{}, logger.DefaultMaskFields...), cfg.MaskFields...),
+	)
+
+	return func(c *fiber.Ctx) error {
+		if !cfg.BodyLogging || !log.DebugEnabled() {
+			return c.Next()
+		}
+
+		requestBody := loggableBody(redactor, c.Body(), string(c.Request().Header.ContentType()), cfg.MaxBodySize)
+
+		err := c.Next()
+
+		// Reading a streamed body here would wait for the whole stream
+		responseBody := "[streamed]"
+		if !c.Response().IsBodyStream() {
+			responseBody = loggableBody(redactor, c.Response().Body(), string(c.Response().Header.ContentType()), cfg.MaxBodySize)
+		}
+
+		RequestLogger(c, log).
+			WithField("method", c.Method()).
+			WithField("path", c.Path()).
+			WithField("status", c.Response().StatusCode()).
+			WithField("request_body", requestBody).
+			WithField("response_body", responseBody).
+			Debug("HTTP body")
+
+		return err
+	}
+}
+
+// loggableBody returns the redacted body, or a summary when it cannot be redacted
+func loggableBody(redactor *logger.Redactor, body []byte, contentType string, maxSize int) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	if !strings.Contains(contentType, "json") {
+		return fmt.Sprintf("[%d bytes of %s omitted]", len(body), contentTypeOrUnknown(contentType))
+	}
+
+	redacted, ok := redactor.RedactJSON(body)
+	if !ok {
+		return fmt.Sprintf("[%d bytes of invalid JSON omitted]", len(body))
+	}
+
+	if len(redacted) > maxSize {
+		return string(redacted[:maxSize]) + "...(truncated)"
+	}
+	return string(redacted)
+}
+
+// contentTypeOrUnknown names a possibly empty content type
+func contentTypeOrUnknown(contentType string) string {
+	if contentType == "" {
+		return "unknown content"
+	}
+	return contentType
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/onichange/pos-system/pkg/audit/security"
+	"github.com/onichange/pos-system/pkg/cache"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// DeviceKeyHeader carries the API key of a paired in-store device
+const DeviceKeyHeader = "X-Device-Key"
+
+// Outcomes of checking a device key
+var (
+	ErrUnknownDevice     = errors.New("unknown device key")
+	ErrDeviceDeactivated = errors.New("device deactivated")
+)
+
+// DeviceVerifier returns the ID of the active device holding key, or fails
+// with ErrUnknownDevice or ErrDeviceDeactivated
+type DeviceVerifier func(ctx context.Context, key string) (deviceID string, err error)
+
+// Cached outcomes of a device key check, besides "active:<device ID>"
+const (
+	deviceCacheActive      = "active:"
+	deviceCacheUnknown     = "unknown"
+	deviceCacheDeactivated = "deactivated"
+)
+
+// DeviceAuth locks out deactivated devices: requests carrying a device key
+// are refused unless verify finds the device active, and the device's ID is
+// stored in Locals("device_id"). Requests without a key pass through. The
+// outcome is cached in c for ttl, keyed by a hash of the key, so a device is
+// locked out at most ttl after it is deactivated. The key is not passed on
+// to the services behind.
+func DeviceAuth(verify DeviceVerifier, c cache.Cache, ttl time.Duration) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		key := ctx.Get(DeviceKeyHeader)
+		if key == "" {
+			return ctx.Next()
+		}
+
+		sum := sha256.Sum256([]byte(key))
+		cacheKey := "device:" + tenant.IDFromContext(ctx.UserContext()) + ":" + hex.EncodeToString(sum[:])
+
+		outcome, err := c.Get(ctx.UserContext(), cacheKey)
+		if err != nil {
+			deviceID, err := verify(ctx.UserContext(), key)
+			switch {
+			case err == nil:
+				outcome = deviceCacheActive + deviceID
+			case errors.Is(err, ErrUnknownDevice):
+				outcome = deviceCacheUnknown
+			case errors.Is(err, ErrDeviceDeactivated):
+				outcome = deviceCacheDeactivated
+			default:
+				logger.FromContext(ctx.UserContext()).Errorf("Failed to verify device key: %v", err)
+				return ctx.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+					"error": "Device verification unavailable",
+				})
+			}
+			if ttl > 0 {
+				_ = c.Set(ctx.UserContext(), cacheKey, outcome, ttl)
+			}
+		}
+
+		deviceID, active := strings.CutPrefix(outcome, deviceCacheActive)
+		if !active {
+			security.RecordRequest(ctx, security.Event{
+				Type:    security.EventDeviceRejected,
+				Outcome: security.OutcomeDenied,
+				Details: map[string]string{
+					"reason": outcome,
+				},
+			})
+			if outcome == deviceCacheDeactivated {
+				return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error":       "Device deactivated",
+					"deactivated": true,
+				})
+			}
+			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Unknown device key",
+			})
+		}
+
+		ctx.Locals("device_id", deviceID)
+		ctx.Request().Header.Del(DeviceKeyHeader)
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/cache"
+)
+
+// mapCache is a cache.Cache that never expires entries
+type mapCache map[string]string
+
+func (m mapCache) Get(_ context.Context, key string) (string, error) {
+	value, ok := m[key]
+	if !ok {
+		return "", cache.ErrCacheMiss
+	}
+	return value, nil
+}
+
+func (m mapCache) Set(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	m[key] = value.(string)
+	return nil
+}
+
+func (m mapCache) Delete(_ context.Context, key string) error {
+	delete(m, key)
+	return nil
+}
+
+func (m mapCache) Exists(_ context.Context, key string) (bool, error) {
+	_, ok := m[key]
+	return ok, nil
+}
+
+func TestDeviceAuth(t *testing.T) {
+	devices := map[string]error{"dev_active": nil, "dev_stolen": ErrDeviceDeactivated}
+	calls := 0
+	verify := func(_ context.Context, key string) (string, error) {
+		calls++
+		if key == "dev_down" {
+			return "", errors.New("store-service unavailable")
+		}
+		err, ok := devices[key]
+		if !ok {
+			return "", ErrUnknownDevice
+		}
+		return "device-" + key, err
+	}
+
+	app := fiber.New()
+	app.Get("/orders", DeviceAuth(verify, mapCache{}, time.Minute), func(c *fiber.Ctx) error {
+		deviceID, _ := c.Locals("device_id").(string)
+		assert.Empty(t, c.Get(DeviceKeyHeader), "the key is not passed on")
+		return c.SendString(deviceID)
+	})
+
+	send := func(key string) int {
+		req := httptest.NewRequest(fiber.MethodGet, "/orders", nil)
+		if key != "" {
+			req.Header.Set(DeviceKeyHeader, key)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusOK, send(""))
+	assert.Equal(t, 0, calls)
+
+	assert.Equal(t, fiber.StatusOK, send("dev_active"))
+	assert.Equal(t, fiber.StatusForbidden, send("dev_stolen"))
+	assert.Equal(t, fiber.StatusUnauthorized, send("dev_other"))
+	assert.Equal(t, fiber.StatusServiceUnavailable, send("dev_down"))
+	assert.Equal(t, 4, calls)
+
+	// Outcomes are cached, so deactivation takes effect once they expire
+	devices["dev_active"] = ErrDeviceDeactivated
+	assert.Equal(t, fiber.StatusOK, send("dev_active"))
+	assert.Equal(t, fiber.StatusForbidden, send("dev_stolen"))
+	assert.Equal(t, 4, calls)
+
+	// Failed checks are not cached
+	assert.Equal(t, fiber.StatusServiceUnavailable, send("dev_down"))
+	assert.Equal(t, 5, calls)
+}
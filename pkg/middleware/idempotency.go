@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// IdempotencyKeyHeader is the header clients use to make unsafe requests retryable
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyConfig configures the idempotency middleware
+type IdempotencyConfig struct {
+	TTL      time.Duration // How long completed responses are replayed
+	LockTTL  time.Duration // How long an in-progress request holds the key
+	Required bool          // Reject requests without an Idempotency-Key
+}
+
+// DefaultIdempotencyConfig returns the default idempotency settings
+func DefaultIdempotencyConfig() IdempotencyConfig {
+	return IdempotencyConfig{
+		TTL:     24 * time.Hour,
+		LockTTL: 30 * time.Second,
+	}
+}
+
+// idempotencyRecord is what is stored per key in Redis
+type idempotencyRecord struct {
+	Completed   bool   `json:"completed"`
+	BodyHash    string `json:"body_hash"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Idempotency replays the stored response when a request is retried with the same
+// Idempotency-Key, route, and user. A retry with a different body is rejected, and a
+// retry while the first attempt is still running gets 409. Server errors are not
+// stored, so those requests can be retried. Redis failures fail open.
+func Idempotency(client *redis.Client, cfg IdempotencyConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		idempotencyKey := c.Get(IdempotencyKeyHeader)
+		if idempotencyKey == "" {
+			if cfg.Required {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Idempotency-Key header is required",
+				})
+			}
+			return c.Next()
+		}
+		if client == nil || len(idempotencyKey) > 255 {
+			return c.Next()
+		}
+
+		ctx := c.UserContext()
+		key := idempotencyRedisKey(c, idempotencyKey)
+		bodyHash := hashBytes(c.Body())
+
+		// Claim the key; the first request wins and runs the handler
+		lock, _ := json.Marshal(idempotencyRecord{BodyHash: bodyHash})
+		acquired, err := client.SetNX(ctx, key, lock, cfg.LockTTL).Result()
+		if err != nil {
+			return c.Next()
+		}
+
+		if !acquired {
+			data, err := client.Get(ctx, key).Bytes()
+			if errors.Is(err, redis.Nil) {
+				// The lock expired between the two calls; treat as a fresh attempt
+				return c.Next()
+			}
+			if err != nil {
+				return c.Next()
+			}
+
+			var record idempotencyRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				return c.Next()
+			}
+
+			if record.BodyHash != bodyHash {
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+					"error": "Idempotency-Key was already used with a different request body",
+				})
+			}
+			if !record.Completed {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"error": "A request with this Idempotency-Key is still being processed",
+				})
+			}
+
+			c.Set("Idempotent-Replayed", "true")
+			if record.ContentType != "" {
+				c.Set(fiber.HeaderContentType, record.ContentType)
+			}
+			return c.Status(record.Status).Send(record.Body)
+		}
+
+		if err := c.Next(); err != nil {
+			client.Del(ctx, key)
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			client.Del(ctx, key)
+			return nil
+		}
+
+		record, _ := json.Marshal(idempotencyRecord{
+			Completed:   true,
+			BodyHash:    bodyHash,
+			Status:      status,
+			ContentType: string(c.Response().Header.ContentType()),
+			Body:        c.Response().Body(),
+		})
+		client.Set(ctx, key, record, cfg.TTL)
+
+		return nil
+	}
+}
+
+// idempotencyRedisKey scopes a client key to the user and route
+func idempotencyRedisKey(c *fiber.Ctx, idempotencyKey string) string {
+	userID, _ := c.Locals("user_id").(string)
+	if userID == "" {
+		userID = c.Get("X-User-ID")
+	}
+	return "idempotency:" + hashBytes([]byte(userID+"|"+c.Method()+"|"+c.Route().Path+"|"+idempotencyKey))
+}
+
+// hashBytes returns the hex SHA-256 of data
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRedis returns a client of an in-memory Redis living as long as the test
+func newRedis(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client, server
+}
+
+// newIdempotentApp serves POST /orders behind the idempotency middleware with
+// handler, counting the times it ran
+func newIdempotentApp(client *redis.Client, handler fiber.Handler) (*fiber.App, *int) {
+	runs := 0
+	app := fiber.New()
+	app.Use(Idempotency(client, DefaultIdempotencyConfig()))
+	app.Post("/orders", func(c *fiber.Ctx) error {
+		runs++
+		return handler(c)
+	})
+	return app, &runs
+}
+
+// postOrder sends POST /orders with the Idempotency-Key key and body, returning
+// the response and its body
+func postOrder(t *testing.T, app *fiber.App, key, body string) (*http.Response, string) {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodPost, "/orders", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Set(IdempotencyKeyHeader, key)
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(data)
+}
+
+func TestIdempotencyReplaysStoredResponse(t *testing.T) {
+	client, _ := newRedis(t)
+	app, runs := newIdempotentApp(client, func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": "order-1"})
+	})
+
+	first, firstBody := postOrder(t, app, "key-1", `{"total":10}`)
+	assert.Equal(t, fiber.StatusCreated, first.StatusCode)
+	assert.Empty(t, first.Header.Get("Idempotent-Replayed"))
+
+	retry, retryBody := postOrder(t, app, "key-1", `{"total":10}`)
+	assert.Equal(t, fiber.StatusCreated, retry.StatusCode)
+	assert.Equal(t, firstBody, retryBody)
+	assert.Equal(t, fiber.MIMEApplicationJSON, retry.Header.Get(fiber.HeaderContentType))
+	assert.Equal(t, "true", retry.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, 1, *runs)
+
+	// Another key runs the handler again
+	other, _ := postOrder(t, app, "key-2", `{"total":10}`)
+	assert.Empty(t, other.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, 2, *runs)
+}
+
+func TestIdempotencyRejectsDifferentBody(t *testing.T) {
+	client, _ := newRedis(t)
+	app, runs := newIdempotentApp(client, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	postOrder(t, app, "key-1", `{"total":10}`)
+	resp, _ := postOrder(t, app, "key-1", `{"total":99}`)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, 1, *runs)
+}
+
+func TestIdempotencyRejectsConcurrentRequest(t *testing.T) {
+	client, _ := newRedis(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	app, runs := newIdempotentApp(client, func(c *fiber.Ctx) error {
+		close(started)
+		<-release
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	done := make(chan *http.Response)
+	go func() {
+		req := httptest.NewRequest(fiber.MethodPost, "/orders", strings.NewReader(`{"total":10}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		resp, _ := app.Test(req, -1)
+		done <- resp
+	}()
+
+	// The first request holds the key while its handler runs
+	<-started
+	resp, _ := postOrder(t, app, "key-1", `{"total":10}`)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+
+	close(release)
+	select {
+	case first := <-done:
+		require.NotNil(t, first)
+		assert.Equal(t, fiber.StatusCreated, first.StatusCode)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the first request did not finish")
+	}
+	assert.Equal(t, 1, *runs)
+}
+
+func TestIdempotencyDoesNotStoreServerErrors(t *testing.T) {
+	client, server := newRedis(t)
+	status := fiber.StatusServiceUnavailable
+	app, runs := newIdempotentApp(client, func(c *fiber.Ctx) error {
+		return c.SendStatus(status)
+	})
+
+	resp, _ := postOrder(t, app, "key-1", `{"total":10}`)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+	assert.Empty(t, server.Keys(), "the key is released")
+
+	// The retry runs the handler again, and its success is stored
+	status = fiber.StatusCreated
+	resp, _ = postOrder(t, app, "key-1", `{"total":10}`)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, 2, *runs)
+
+	resp, _ = postOrder(t, app, "key-1", `{"total":10}`)
+	assert.Equal(t, "true", resp.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, 2, *runs)
+}
@@ -4,31 +4,46 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+
 	"github.com/onichange/pos-system/pkg/metrics"
 )
 
-// PrometheusMetrics creates a middleware to record Prometheus metrics
-func PrometheusMetrics() fiber.Handler {
+// unmatchedRoute labels requests that matched no route, keeping raw paths
+// (and their unbounded cardinality) out of the metrics
+const unmatchedRoute = "unmatched"
+
+// PrometheusMetrics creates a middleware recording RED metrics (rate, errors,
+// duration) and in-flight requests per route
+func PrometheusMetrics(red *metrics.RED) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		start := time.Now()
+		done := red.Start()
+		defer done()
 
 		// Process request
 		err := c.Next()
 
-		// Calculate duration
-		duration := time.Since(start).Seconds()
-
-		// Record metrics
-		method := c.Method()
-		endpoint := c.Route().Path
-		if endpoint == "" {
-			endpoint = c.Path()
+		status := c.Response().StatusCode()
+		if err != nil {
+			// The error handler has not written the response yet
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
 		}
-		statusCode := c.Response().StatusCode()
 
-		metrics.RecordHTTPRequest(method, endpoint, statusCode, duration)
+		red.Observe(c.UserContext(), c.Method(), routeLabel(c), status, time.Since(start))
 
 		return err
 	}
 }
 
+// routeLabel returns the matched route template. When nothing matched, the
+// last route seen is a catch-all middleware mounted at "/".
+func routeLabel(c *fiber.Ctx) string {
+	route := c.Route().Path
+	if route == "" || (route == "/" && c.Path() != "/") {
+		return unmatchedRoute
+	}
+	return route
+}
@@ -3,69 +3,279 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/redis/go-redis/v9"
+
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// Rate limit fallback policies, applied while Redis is unavailable
+const (
+	// RateLimitFallbackLocal limits each instance with an in-memory sliding window
+	RateLimitFallbackLocal = "local"
+
+	// RateLimitFallbackOpen lets every request through
+	RateLimitFallbackOpen = "open"
 )
 
-// RateLimiter implements token bucket algorithm with Redis
+// redisRetryInterval is how long the limiter uses the fallback after a Redis error
+// before trying Redis again, so an outage does not add a timeout to every request
+const redisRetryInterval = 5 * time.Second
+
+// slidingWindowScript atomically trims a client's request log to the window,
+// records the request if the limit allows it, and reports what is left.
+//
+// KEYS[1] log key; ARGV: now (ms), window (ms), limit, unique member.
+// Returns {allowed, remaining, reset (ms)} where reset is when the oldest
+// request in the window expires and a slot frees up.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local reset = now + window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window
+end
+
+return {allowed, limit - count, reset}
+`)
+
+// RateLimitResult is the outcome of a rate limit check
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int64
+	Remaining int64
+	Reset     time.Time // When the next slot frees up
+}
+
+// RetryAfter returns how long a rejected client should wait
+func (r RateLimitResult) RetryAfter() time.Duration {
+	wait := time.Until(r.Reset)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// TenantLimitFunc returns the requests per window allowed to each client of a
+// tenant, or 0 to apply the limiter's own limit. ok is false for tenants that
+// do not exist, whose clients are limited as if they named no tenant.
+type TenantLimitFunc func(ctx context.Context, tenantID string) (limit int, ok bool)
+
+// RateLimiter implements a sliding-window log limiter in Redis. Unlike a fixed
+// window, a client can never burst to twice the limit across a window boundary.
+// While Redis is unavailable the fallback policy applies; requests never fail
+// because the limiter's backend is down.
 type RateLimiter struct {
-	client *redis.Client
-	limit  int
-	window time.Duration
+	client       *redis.Client
+	limit        atomic.Int64
+	window       time.Duration
+	tenantLimits atomic.Pointer[TenantLimitFunc]
+
+	fallbackOpen atomic.Bool
+	local        *localRateLimiter
+	downUntil    atomic.Int64 // Unix nanoseconds until Redis is retried
+	logger       atomic.Pointer[logger.Logger]
 }
 
-// NewRateLimiter creates a new rate limiter
+// NewRateLimiter creates a new rate limiter that falls back to per-instance
+// limiting while Redis is unavailable
 func NewRateLimiter(client *redis.Client, limit int, window time.Duration) *RateLimiter {
-	return &RateLimiter{
+	rl := &RateLimiter{
 		client: client,
-		limit:  limit,
 		window: window,
+		local:  newLocalRateLimiter(),
+	}
+	rl.limit.Store(int64(limit))
+	metrics.RateLimitBackendUp.Set(1)
+	return rl
+}
+
+// SetFallback sets the policy used while Redis is unavailable (RateLimitFallbackLocal
+// or RateLimitFallbackOpen). Redis outages and recoveries are logged to log.
+func (rl *RateLimiter) SetFallback(policy string, log *logger.Logger) {
+	rl.fallbackOpen.Store(policy == RateLimitFallbackOpen)
+	rl.logger.Store(log)
+}
+
+// SetLimit changes the number of requests allowed per window at runtime
+func (rl *RateLimiter) SetLimit(limit int) {
+	rl.limit.Store(int64(limit))
+}
+
+// SetTenantLimits sets the per-tenant limits applied to requests of a tenant
+func (rl *RateLimiter) SetTenantLimits(limits TenantLimitFunc) {
+	rl.tenantLimits.Store(&limits)
+}
+
+// Allow records a request for identifier and reports whether it is within the limit
+func (rl *RateLimiter) Allow(ctx context.Context, identifier string) (RateLimitResult, error) {
+	return rl.allow(ctx, identifier, rl.limit.Load())
+}
+
+// allow records a request for identifier and reports whether it is within limit
+func (rl *RateLimiter) allow(ctx context.Context, identifier string, limit int64) (RateLimitResult, error) {
+	now := time.Now()
+	// Requests in the same millisecond must not overwrite each other in the log
+	member := strconv.FormatInt(now.UnixNano(), 36) + "-" + strconv.FormatUint(rand.Uint64(), 36)
+
+	values, err := slidingWindowScript.Run(ctx, rl.client,
+		[]string{fmt.Sprintf("ratelimit:%s", identifier)},
+		now.UnixMilli(), rl.window.Milliseconds(), limit, member,
+	).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
 	}
+	if len(values) != 3 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	return RateLimitResult{
+		Allowed:   values[0] == 1,
+		Limit:     limit,
+		Remaining: max(values[1], 0),
+		Reset:     time.UnixMilli(values[2]),
+	}, nil
 }
 
-// RateLimitMiddleware returns a Fiber middleware for rate limiting
+// RateLimitMiddleware returns a Fiber middleware for rate limiting. Every response
+// carries X-RateLimit-Limit, X-RateLimit-Remaining, and X-RateLimit-Reset (Unix
+// seconds); rejected requests also get Retry-After (seconds). Authenticated
+// users are counted by user ID, and the others by IP address. Users of a
+// tenant are counted apart from other tenants' and get the tenant's limit.
+// Users and tenants are only taken from a verified token, so mount it after
+// JWTAuth or OptionalJWTAuth; headers naming them are ignored, as a client
+// could rotate them to dodge its limit.
 func (rl *RateLimiter) RateLimitMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// Get client identifier (IP address or user ID)
 		identifier := c.IP()
-		if userID := c.Get("X-User-ID"); userID != "" {
+		if userID, _ := c.Locals("user_id").(string); userID != "" {
 			identifier = userID
 		}
 
-		key := fmt.Sprintf("ratelimit:%s", identifier)
-		ctx := context.Background()
-
-		// Use Redis sliding window log algorithm
-		now := time.Now().Unix()
-		windowStart := now - int64(rl.window.Seconds())
-
-		// Remove old entries
-		rl.client.ZRemRangeByScore(ctx, key, "0", fmt.Sprintf("%d", windowStart))
+		limit := rl.limit.Load()
+		if tenantID := requestTenant(c); tenantID != "" {
+			tenantLimit, known := 0, true
+			if limits := rl.tenantLimits.Load(); limits != nil {
+				tenantLimit, known = (*limits)(c.UserContext(), tenantID)
+			}
+			if known {
+				identifier = "tenant:" + tenantID + ":" + identifier
+				if tenantLimit > 0 {
+					limit = int64(tenantLimit)
+				}
+			}
+		}
 
-		// Count current requests
-		count, err := rl.client.ZCard(ctx, key).Result()
-		if err != nil {
-			// If Redis fails, allow request (fail open)
+		result, ok := rl.check(c.UserContext(), identifier, limit)
+		if !ok {
 			return c.Next()
 		}
 
-		if count >= int64(rl.limit) {
+		c.Set("X-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+		c.Set("X-RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+		c.Set("X-RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(float64(result.Reset.UnixMilli())/1000)), 10))
+
+		if !result.Allowed {
+			retryAfter := int64(math.Ceil(result.RetryAfter().Seconds()))
+			c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(retryAfter, 10))
 			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-				"error": "rate limit exceeded",
-				"retry_after": rl.window.Seconds(),
+				"error":       "rate limit exceeded",
+				"retry_after": retryAfter,
 			})
 		}
 
-		// Add current request
-		rl.client.ZAdd(ctx, key, redis.Z{
-			Score:  float64(now),
-			Member: fmt.Sprintf("%d", now),
-		})
-		rl.client.Expire(ctx, key, rl.window)
-
 		return c.Next()
 	}
 }
 
+// check applies the limit through Redis, or through the fallback policy while
+// Redis is unavailable. ok is false when the request is let through unlimited.
+func (rl *RateLimiter) check(ctx context.Context, identifier string, limit int64) (RateLimitResult, bool) {
+	if time.Now().UnixNano() >= rl.downUntil.Load() {
+		result, err := rl.allow(ctx, identifier, limit)
+		if err == nil {
+			if rl.downUntil.Swap(0) != 0 {
+				metrics.RateLimitBackendUp.Set(1)
+				rl.logf("Rate limit backend recovered; shared limits restored")
+			}
+			return result, true
+		}
+
+		// A request cancelled by its client says nothing about Redis
+		if ctx.Err() != nil {
+			return RateLimitResult{}, false
+		}
+
+		if rl.downUntil.Swap(time.Now().Add(redisRetryInterval).UnixNano()) == 0 {
+			metrics.RateLimitBackendUp.Set(0)
+			rl.logf("Rate limit backend unavailable, using %s fallback: %v", rl.fallbackPolicy(), err)
+		}
+	}
+
+	if rl.fallbackOpen.Load() {
+		metrics.RateLimitFallbackRequests.WithLabelValues(RateLimitFallbackOpen, "allowed").Inc()
+		return RateLimitResult{}, false
+	}
+
+	result := rl.local.allow(identifier, limit, rl.window)
+	outcome := "allowed"
+	if !result.Allowed {
+		outcome = "limited"
+	}
+	metrics.RateLimitFallbackRequests.WithLabelValues(RateLimitFallbackLocal, outcome).Inc()
+	return result, true
+}
+
+// requestTenant returns the tenant of the request's verified token: the one
+// tenant.Middleware resolved from its claim, or the claim itself. Tenants
+// resolved from a header or host are not the client's to vouch for.
+func requestTenant(c *fiber.Ctx) string {
+	if t, ok := tenant.FromContext(c.UserContext()); ok {
+		if t.Source == tenant.SourceJWT {
+			return t.ID
+		}
+		return ""
+	}
+	if tenantID, _ := c.Locals("tenant_id").(string); tenant.ValidID(tenantID) {
+		return tenantID
+	}
+	return ""
+}
+
+// fallbackPolicy names the active fallback policy
+func (rl *RateLimiter) fallbackPolicy() string {
+	if rl.fallbackOpen.Load() {
+		return RateLimitFallbackOpen
+	}
+	return RateLimitFallbackLocal
+}
+
+// logf logs backend transitions when a logger is configured
+func (rl *RateLimiter) logf(format string, v ...interface{}) {
+	if log := rl.logger.Load(); log != nil {
+		log.Warnf(format, v...)
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// localWindow holds one client's counts for the current and previous window
+type localWindow struct {
+	start    time.Time
+	current  int64
+	previous int64
+}
+
+// localRateLimiter is an in-memory sliding-window counter used while Redis is
+// unavailable. Limits are per instance, so a cluster of N gateways admits up to
+// N times the configured limit, which is preferable to not limiting at all.
+type localRateLimiter struct {
+	mu          sync.Mutex
+	windows     map[string]*localWindow
+	lastCleanup time.Time
+}
+
+// newLocalRateLimiter creates an empty in-memory limiter
+func newLocalRateLimiter() *localRateLimiter {
+	return &localRateLimiter{
+		windows:     make(map[string]*localWindow),
+		lastCleanup: time.Now(),
+	}
+}
+
+// allow records a request for identifier. The previous window's count is
+// weighted by how much of it still overlaps the sliding window, which
+// approximates a sliding log in constant memory per client.
+func (l *localRateLimiter) allow(identifier string, limit int64, window time.Duration) RateLimitResult {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cleanup(now, window)
+
+	w, ok := l.windows[identifier]
+	if !ok {
+		w = &localWindow{start: now.Truncate(window)}
+		// The identifier may point into a reused request buffer
+		l.windows[strings.Clone(identifier)] = w
+	}
+
+	// Roll the window forward
+	if elapsed := now.Sub(w.start); elapsed >= window {
+		if elapsed >= 2*window {
+			w.previous = 0
+		} else {
+			w.previous = w.current
+		}
+		w.current = 0
+		w.start = now.Truncate(window)
+	}
+
+	overlap := 1 - float64(now.Sub(w.start))/float64(window)
+	estimated := int64(float64(w.previous)*overlap) + w.current
+
+	result := RateLimitResult{
+		Limit: limit,
+		Reset: w.start.Add(window),
+	}
+	if estimated < limit {
+		w.current++
+		estimated++
+		result.Allowed = true
+	}
+	result.Remaining = max(limit-estimated, 0)
+	return result
+}
+
+// cleanup drops clients idle for two windows; called with mu held
+func (l *localRateLimiter) cleanup(now time.Time, window time.Duration) {
+	if now.Sub(l.lastCleanup) < window {
+		return
+	}
+	l.lastCleanup = now
+
+	for identifier, w := range l.windows {
+		if now.Sub(w.start) >= 2*window {
+			delete(l.windows, identifier)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/auth"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// unreachableRedis returns a client whose connections are refused immediately
+func unreachableRedis() *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		DialTimeout: 100 * time.Millisecond,
+		MaxRetries:  -1,
+	})
+}
+
+func newRateLimitedApp(rl *RateLimiter) *fiber.App {
+	app := fiber.New()
+	app.Use(rl.RateLimitMiddleware())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	return app
+}
+
+func TestRateLimiterLocalFallback(t *testing.T) {
+	rl := NewRateLimiter(unreachableRedis(), 2, time.Minute)
+	rl.SetFallback(RateLimitFallbackLocal, nil)
+	app := newRateLimitedApp(rl)
+
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("X-RateLimit-Limit"))
+	}
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "0", resp.Header.Get("X-RateLimit-Remaining"))
+	assert.NotEmpty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+}
+
+func TestRateLimiterOpenFallback(t *testing.T) {
+	rl := NewRateLimiter(unreachableRedis(), 1, time.Minute)
+	rl.SetFallback(RateLimitFallbackOpen, nil)
+	app := newRateLimitedApp(rl)
+
+	for i := 0; i < 3; i++ {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("X-RateLimit-Limit"))
+	}
+}
+
+// newTenantRateLimitedApp limits requests as the gateway does, after reading
+// the user and tenant of bearer tokens issued by the returned manager. acme
+// allows 3 requests, globex the default, and no other tenant exists.
+func newTenantRateLimitedApp(rl *RateLimiter) (*fiber.App, *auth.JWTManager) {
+	rl.SetTenantLimits(func(_ context.Context, tenantID string) (int, bool) {
+		switch tenantID {
+		case "acme":
+			return 3, true
+		case "globex":
+			return 0, true
+		}
+		return 0, false
+	})
+	jwtManager := auth.NewJWTManager("access", "refresh", time.Hour, time.Hour, "test")
+	app := fiber.New()
+	app.Use(OptionalJWTAuth(jwtManager), rl.RateLimitMiddleware())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	return app, jwtManager
+}
+
+// rateLimitedRequest sends GET / with the given headers
+func rateLimitedRequest(t *testing.T, app *fiber.App, headers map[string]string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestRateLimiterTenantLimits(t *testing.T) {
+	rl := NewRateLimiter(unreachableRedis(), 1, time.Minute)
+	rl.SetFallback(RateLimitFallbackLocal, nil)
+	app, jwtManager := newTenantRateLimitedApp(rl)
+
+	request := func(tenantID string) *http.Response {
+		pair, err := jwtManager.GenerateTenantTokenPair(tenantID, "user-1", "user@example.com", nil, "")
+		require.NoError(t, err)
+		return rateLimitedRequest(t, app, map[string]string{fiber.HeaderAuthorization: "Bearer " + pair.AccessToken})
+	}
+
+	// The tenant's own limit applies to its users
+	for i := 0; i < 3; i++ {
+		resp := request("acme")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "3", resp.Header.Get("X-RateLimit-Limit"))
+	}
+	assert.Equal(t, fiber.StatusTooManyRequests, request("acme").StatusCode)
+
+	// The same user is counted apart in other tenants, at the default limit
+	resp := request("globex")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("X-RateLimit-Limit"))
+	assert.Equal(t, fiber.StatusTooManyRequests, request("globex").StatusCode)
+
+	// Tokens of tenants that do not exist share the user's own bucket
+	assert.Equal(t, fiber.StatusOK, request("initech").StatusCode)
+	assert.Equal(t, fiber.StatusTooManyRequests, request("umbrella").StatusCode)
+}
+
+func TestRateLimiterIgnoresSpoofedHeaders(t *testing.T) {
+	rl := NewRateLimiter(unreachableRedis(), 1, time.Minute)
+	rl.SetFallback(RateLimitFallbackLocal, nil)
+	app, jwtManager := newTenantRateLimitedApp(rl)
+
+	resp := rateLimitedRequest(t, app, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("X-RateLimit-Limit"))
+
+	// Naming a tenant with a larger limit, another tenant, or another user
+	// neither raises the limit nor starts a fresh count
+	for _, headers := range []map[string]string{
+		{tenant.Header: "acme"},
+		{tenant.Header: "globex"},
+		{"X-User-ID": "someone-else"},
+		{fiber.HeaderAuthorization: "Bearer forged"},
+	} {
+		resp := rateLimitedRequest(t, app, headers)
+		assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode, headers)
+		assert.Equal(t, "1", resp.Header.Get("X-RateLimit-Limit"), headers)
+	}
+
+	// Nor does a header next to a valid token of another tenant
+	pair, err := jwtManager.GenerateTenantTokenPair("globex", "user-1", "user@example.com", nil, "")
+	require.NoError(t, err)
+	headers := map[string]string{fiber.HeaderAuthorization: "Bearer " + pair.AccessToken, tenant.Header: "acme"}
+	assert.Equal(t, fiber.StatusOK, rateLimitedRequest(t, app, headers).StatusCode)
+	resp = rateLimitedRequest(t, app, headers)
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("X-RateLimit-Limit"))
+}
+
+func TestRateLimiterTrustsOnlyTenantsResolvedFromTokens(t *testing.T) {
+	rl := NewRateLimiter(unreachableRedis(), 1, time.Minute)
+	rl.SetFallback(RateLimitFallbackLocal, nil)
+	rl.SetTenantLimits(func(context.Context, string) (int, bool) { return 3, true })
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		source := c.Get("X-Test-Source")
+		c.SetUserContext(tenant.NewContext(c.UserContext(), &tenant.Tenant{ID: "acme", Source: source}))
+		return c.Next()
+	}, rl.RateLimitMiddleware())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	limit := func(source string) string {
+		return rateLimitedRequest(t, app, map[string]string{"X-Test-Source": source}).Header.Get("X-RateLimit-Limit")
+	}
+	assert.Equal(t, "3", limit(tenant.SourceJWT))
+	assert.Equal(t, "1", limit(tenant.SourceHeader))
+	assert.Equal(t, "1", limit(tenant.SourceHost))
+	assert.Equal(t, "1", limit(tenant.SourceDefault))
+}
+
+func TestLocalRateLimiterSlidesAcrossWindows(t *testing.T) {
+	l := newLocalRateLimiter()
+	window := time.Minute
+
+	assert.True(t, l.allow("client", 1, window).Allowed)
+	assert.False(t, l.allow("client", 1, window).Allowed)
+	assert.True(t, l.allow("other", 1, window).Allowed)
+
+	// Two windows later nothing from the old windows counts
+	w := l.windows["client"]
+	w.start = w.start.Add(-2 * window)
+	assert.True(t, l.allow("client", 1, window).Allowed)
+	assert.Zero(t, w.previous)
+}
+
+func TestSlidingWindowScriptLimitsAcrossWindowEdge(t *testing.T) {
+	client, _ := newRedis(t)
+	ctx := context.Background()
+
+	// run records a request at now (ms) in a window of 1s allowing 2 requests
+	run := func(now int64) []int64 {
+		values, err := slidingWindowScript.Run(ctx, client, []string{"ratelimit:client"},
+			now, 1000, 2, strconv.FormatInt(now, 10)).Int64Slice()
+		require.NoError(t, err)
+		return values
+	}
+
+	// {allowed, remaining, reset}
+	assert.Equal(t, []int64{1, 1, 1000}, run(0))
+	assert.Equal(t, []int64{1, 0, 1000}, run(500))
+	assert.Equal(t, []int64{0, 0, 1000}, run(999))
+
+	// Past the edge only the first request has left the window, so one slot
+	// frees up rather than a fresh window's worth
+	assert.Equal(t, []int64{1, 0, 1500}, run(1000))
+	assert.Equal(t, []int64{0, 0, 1500}, run(1200))
+	assert.Equal(t, []int64{1, 0, 2000}, run(1500))
+}
+
+func TestRateLimiterSetsHeadersFromRedis(t *testing.T) {
+	client, server := newRedis(t)
+	rl := NewRateLimiter(client, 2, time.Minute)
+	app := newRateLimitedApp(rl)
+
+	request := func() *http.Response {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		require.NoError(t, err)
+		return resp
+	}
+	resetIn := func(resp *http.Response) time.Duration {
+		reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		require.NoError(t, err)
+		return time.Until(time.Unix(reset, 0))
+	}
+
+	start := time.Now()
+	for _, remaining := range []string{"1", "0"} {
+		resp := request()
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("X-RateLimit-Limit"))
+		assert.Equal(t, remaining, resp.Header.Get("X-RateLimit-Remaining"))
+		assert.Empty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+		assert.InDelta(t, time.Minute.Seconds(), resetIn(resp).Seconds(), 2)
+	}
+
+	resp := request()
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "0", resp.Header.Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "60", resp.Header.Get(fiber.HeaderRetryAfter))
+
+	// Once the oldest request is 45s old, its slot frees up in 15s
+	key := server.Keys()[0]
+	members, err := server.ZMembers(key) // By score, oldest first
+	require.NoError(t, err)
+	_, err = server.ZAdd(key, float64(start.Add(-45*time.Second).UnixMilli()), members[0])
+	require.NoError(t, err)
+
+	resp = request()
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "15", resp.Header.Get(fiber.HeaderRetryAfter))
+	assert.InDelta(t, 15, resetIn(resp).Seconds(), 2)
+}
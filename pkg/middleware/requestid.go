@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// maxRequestIDLength bounds client-supplied request IDs
+const maxRequestIDLength = 128
+
+// RequestID assigns each request an ID, reusing a valid incoming X-Request-ID so the
+// same ID follows a request through the gateway, services, and message consumers.
+// The ID is echoed in the response header and stored in the request's user context.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Copied because the ID outlives the request in logs, events, and audit entries
+		requestID := utils.CopyString(c.Get(logger.RequestIDHeader))
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+
+		c.Locals("request_id", requestID)
+		c.Set(logger.RequestIDHeader, requestID)
+		c.SetUserContext(logger.ContextWithRequestID(c.UserContext(), requestID))
+
+		return c.Next()
+	}
+}
+
+// GetRequestID returns the ID assigned by RequestID, or "" if the middleware did not run
+func GetRequestID(c *fiber.Ctx) string {
+	requestID, _ := c.Locals("request_id").(string)
+	return requestID
+}
+
+// RequestLogger returns a logger tagged with the request ID
+func RequestLogger(c *fiber.Ctx, log *logger.Logger) *logger.Logger {
+	return log.WithContext(c.UserContext())
+}
@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/i18n"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// cachedResponseEntry is what is stored per cached response in Redis
+type cachedResponseEntry struct {
+	ContentType string `json:"content_type,omitempty"`
+	ETag        string `json:"etag"`
+	Body        []byte `json:"body"`
+}
+
+// ResponseCache answers GET requests on the configured routes from Redis.
+// Responses are cached per tenant, path, query, locale, and roles, and per
+// user unless the route is shared. A successful mutation under a route, or
+// under one of the paths that invalidate it, drops the tenant's cached
+// responses of that route. Redis failures fail open.
+type ResponseCache struct {
+	client *redis.Client
+	routes []config.ResponseCacheRoute // Longest path first
+}
+
+// NewResponseCache creates a response cache for the routes in cfg
+func NewResponseCache(client *redis.Client, cfg config.ResponseCacheConfig) *ResponseCache {
+	routes := slices.Clone(cfg.Routes)
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].Path) > len(routes[j].Path)
+	})
+	return &ResponseCache{client: client, routes: routes}
+}
+
+// Middleware returns the handler serving and invalidating cached responses.
+// It must run after authentication and tenant resolution, which its keys
+// depend on. Every response on a cached route gets an ETag, and a request
+// whose If-None-Match matches it is answered 304 Not Modified.
+func (rc *ResponseCache) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet:
+			if route := rc.route(c.Path()); route != nil {
+				return rc.serve(c, route)
+			}
+			return c.Next()
+		case fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if c.Response().StatusCode() < fiber.StatusBadRequest {
+			rc.invalidate(c)
+		}
+		return nil
+	}
+}
+
+// route returns the cached route a path falls under, or nil
+func (rc *ResponseCache) route(path string) *config.ResponseCacheRoute {
+	for i := range rc.routes {
+		route := &rc.routes[i]
+		if !underPath(path, route.Path) {
+			continue
+		}
+		for _, except := range route.Except {
+			if underPath(path, except) {
+				return nil
+			}
+		}
+		return route
+	}
+	return nil
+}
+
+// serve answers from the cache, or runs the handler and caches a 200 answer
+func (rc *ResponseCache) serve(c *fiber.Ctx, route *config.ResponseCacheRoute) error {
+	ctx := c.UserContext()
+	bypass := strings.Contains(c.Get(fiber.HeaderCacheControl), "no-cache")
+
+	generation, err := rc.generation(c, route)
+	if err != nil {
+		return c.Next()
+	}
+	key := rc.key(c, route, generation)
+
+	if !bypass {
+		data, err := rc.client.Get(ctx, key).Bytes()
+		if err == nil {
+			var entry cachedResponseEntry
+			if err := json.Unmarshal(data, &entry); err == nil {
+				metrics.CacheHits.WithLabelValues("response").Inc()
+				c.Set("X-Cache", "HIT")
+				return sendCached(c, &entry)
+			}
+		} else if !errors.Is(err, redis.Nil) {
+			return c.Next()
+		}
+	}
+	metrics.CacheMisses.WithLabelValues("response").Inc()
+
+	if err := c.Next(); err != nil {
+		return err
+	}
+	if c.Response().StatusCode() != fiber.StatusOK ||
+		strings.Contains(string(c.Response().Header.Peek(fiber.HeaderCacheControl)), "no-store") {
+		return nil
+	}
+
+	entry := cachedResponseEntry{
+		ContentType: string(c.Response().Header.ContentType()),
+		ETag:        responseETag(c.Response().Body()),
+		Body:        c.Response().Body(),
+	}
+	if data, err := json.Marshal(entry); err == nil {
+		rc.client.Set(ctx, key, data, route.TTL)
+	}
+	c.Set("X-Cache", "MISS")
+	c.Set(fiber.HeaderETag, entry.ETag)
+	if c.Get(fiber.HeaderIfNoneMatch) == entry.ETag {
+		notModified(c)
+	}
+	return nil
+}
+
+// sendCached answers with entry, or 304 when the client holds its ETag
+func sendCached(c *fiber.Ctx, entry *cachedResponseEntry) error {
+	c.Set(fiber.HeaderETag, entry.ETag)
+	if c.Get(fiber.HeaderIfNoneMatch) == entry.ETag {
+		notModified(c)
+		return nil
+	}
+	if entry.ContentType != "" {
+		c.Set(fiber.HeaderContentType, entry.ContentType)
+	}
+	return c.Status(fiber.StatusOK).Send(entry.Body)
+}
+
+// notModified turns the response into a bodiless 304
+func notModified(c *fiber.Ctx) {
+	c.Response().ResetBody()
+	c.Status(fiber.StatusNotModified)
+}
+
+// invalidate drops the tenant's cached responses of every route the mutated
+// path falls under or invalidates
+func (rc *ResponseCache) invalidate(c *fiber.Ctx) {
+	path := c.Path()
+	for i := range rc.routes {
+		route := &rc.routes[i]
+		affected := underPath(path, route.Path)
+		for _, prefix := range route.InvalidatedBy {
+			affected = affected || underPath(path, prefix)
+		}
+		if affected {
+			rc.client.Incr(c.UserContext(), generationKey(c, route))
+		}
+	}
+}
+
+// generation returns the route's current generation in the caller's tenant.
+// Invalidating a route starts a new generation, leaving the responses cached
+// under older ones to expire unread.
+func (rc *ResponseCache) generation(c *fiber.Ctx, route *config.ResponseCacheRoute) (int64, error) {
+	generation, err := rc.client.Get(c.UserContext(), generationKey(c, route)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return generation, err
+}
+
+// generationKey is the Redis key of a route's generation in the caller's tenant
+func generationKey(c *fiber.Ctx, route *config.ResponseCacheRoute) string {
+	return "response-cache:generation:" + tenant.IDFromContext(c.UserContext()) + ":" + route.Path
+}
+
+// key is the Redis key of the response to the request in generation
+func (rc *ResponseCache) key(c *fiber.Ctx, route *config.ResponseCacheRoute, generation int64) string {
+	query, _ := url.ParseQuery(string(c.Request().URI().QueryString()))
+	roles, _ := c.Locals("roles").([]string)
+	roles = slices.Sorted(slices.Values(roles))
+
+	parts := []string{c.Path(), query.Encode(), i18n.Locale(c), strings.Join(roles, ",")}
+	if !route.Shared {
+		userID, _ := c.Locals("user_id").(string)
+		parts = append(parts, userID)
+	}
+	return "response-cache:" + tenant.IDFromContext(c.UserContext()) + ":" + route.Path + ":" +
+		strconv.FormatInt(generation, 10) + ":" + hashBytes([]byte(strings.Join(parts, "|")))
+}
+
+// responseETag is the strong ETag of a response body
+func responseETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// underPath reports whether path is prefix or lies below it, a * segment of
+// prefix matching any one segment of path
+func underPath(path, prefix string) bool {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, want := range strings.Split(strings.Trim(prefix, "/"), "/") {
+		if want == "" {
+			continue // The root
+		}
+		if i >= len(segments) || (want != "*" && want != segments[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+func testResponseCacheConfig() config.ResponseCacheConfig {
+	return config.ResponseCacheConfig{
+		Enabled: true,
+		Routes: []config.ResponseCacheRoute{
+			{Path: "/api/v1/stores", TTL: time.Minute, Shared: true, Except: []string{"/api/v1/stores/*/exports"}},
+			{Path: "/api/v1/stores/*/prices", TTL: time.Second},
+			{Path: "/api/v1/inventory", TTL: time.Second, InvalidatedBy: []string{"/api/v1/orders"}},
+		},
+	}
+}
+
+func TestResponseCacheRoutes(t *testing.T) {
+	rc := NewResponseCache(unreachableRedis(), testResponseCacheConfig())
+
+	route := func(path string) string {
+		if r := rc.route(path); r != nil {
+			return r.Path
+		}
+		return ""
+	}
+	assert.Equal(t, "/api/v1/stores", route("/api/v1/stores"))
+	assert.Equal(t, "/api/v1/stores", route("/api/v1/stores/42"))
+	assert.Equal(t, "/api/v1/stores/*/prices", route("/api/v1/stores/42/prices"), "the longest path wins")
+	assert.Equal(t, "", route("/api/v1/stores/42/exports/7"))
+	assert.Equal(t, "", route("/api/v1/stores-archive"))
+	assert.Equal(t, "/api/v1/inventory", route("/api/v1/inventory/9"))
+	assert.Equal(t, "", route("/api/v1/orders"))
+}
+
+func TestResponseCacheFailsOpen(t *testing.T) {
+	calls := 0
+	app := fiber.New()
+	app.Use(NewResponseCache(unreachableRedis(), testResponseCacheConfig()).Middleware())
+	app.Get("/api/v1/stores", func(c *fiber.Ctx) error {
+		calls++
+		return c.JSON(fiber.Map{"data": []string{}})
+	})
+	app.Post("/api/v1/stores", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/stores", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("X-Cache"))
+	}
+	assert.Equal(t, 2, calls, "every request reaches the handler without Redis")
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/stores", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+}
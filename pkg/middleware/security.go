@@ -33,12 +33,18 @@ func RequestSizeLimit(maxSize int64) fiber.Handler {
 
 // CORSMiddleware handles CORS with strict whitelist
 func CORSMiddleware(allowedOrigins []string) fiber.Handler {
+	return DynamicCORSMiddleware(func() []string { return allowedOrigins })
+}
+
+// DynamicCORSMiddleware handles CORS with allowed origins read on every request,
+// so they can change at runtime (e.g. on config reload)
+func DynamicCORSMiddleware(allowedOrigins func() []string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		origin := c.Get("Origin")
-		
+
 		// Check if origin is allowed
 		allowed := false
-		for _, allowedOrigin := range allowedOrigins {
+		for _, allowedOrigin := range allowedOrigins() {
 			if allowedOrigin == "*" || allowedOrigin == origin {
 				allowed = true
 				break
@@ -50,6 +56,7 @@ func CORSMiddleware(allowedOrigins []string) fiber.Handler {
 			c.Set("Access-Control-Allow-Credentials", "true")
 			c.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
 			c.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+			c.Set("Access-Control-Expose-Headers", "X-Request-ID, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After")
 			c.Set("Access-Control-Max-Age", "3600")
 		}
 
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/encryption"
+)
+
+func TestVerifySignature(t *testing.T) {
+	app := fiber.New()
+	app.Post("/webhook", VerifySignature(StaticSecrets(map[string]string{"kiosk-1": "s3cret"}), 5*time.Minute, nil),
+		func(c *fiber.Ctx) error { return c.SendString(c.Locals("partner_id").(string)) })
+
+	body := []byte(`{"event":"sale"}`)
+	send := func(partnerID, secret string, signedAt time.Time, sentBody []byte) int {
+		timestamp := signedAt.Unix()
+		req := httptest.NewRequest(fiber.MethodPost, "/webhook", bytes.NewReader(sentBody))
+		req.Header.Set(SignaturePartnerHeader, partnerID)
+		req.Header.Set(SignatureTimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	now := time.Now()
+	assert.Equal(t, fiber.StatusOK, send("kiosk-1", "s3cret", now, body))
+	assert.Equal(t, fiber.StatusUnauthorized, send("kiosk-1", "wrong", now, body))
+	assert.Equal(t, fiber.StatusUnauthorized, send("kiosk-2", "s3cret", now, body))
+	assert.Equal(t, fiber.StatusUnauthorized, send("kiosk-1", "s3cret", now, []byte(`{"event":"refund"}`)))
+	assert.Equal(t, fiber.StatusUnauthorized, send("kiosk-1", "s3cret", now.Add(-10*time.Minute), body))
+}
+
+func TestVerifySignatureAcceptsPayloadSignatures(t *testing.T) {
+	app := fiber.New()
+	app.Post("/webhook", VerifySignature(StaticSecrets(map[string]string{"partner-1": "old new"}), 5*time.Minute, nil),
+		func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	body := []byte(`{"event":"sale"}`)
+	send := func(signature string) int {
+		req := httptest.NewRequest(fiber.MethodPost, "/webhook", bytes.NewReader(body))
+		req.Header.Set(SignaturePartnerHeader, "partner-1")
+		req.Header.Set(SignatureHeader, signature)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	// Either secret is accepted while the partner rotates
+	assert.Equal(t, fiber.StatusOK, send(encryption.SignPayload(body, time.Now(), "old")))
+	assert.Equal(t, fiber.StatusOK, send(encryption.SignPayload(body, time.Now(), "new")))
+	assert.Equal(t, fiber.StatusUnauthorized, send(encryption.SignPayload(body, time.Now(), "other")))
+	assert.Equal(t, fiber.StatusUnauthorized, send(encryption.SignPayload(body, time.Now().Add(-time.Hour), "new")))
+}
+
+func TestNormalizeSignature(t *testing.T) {
+	signature := Sign("secret", 1700000000, []byte("body"))
+	assert.Equal(t, signature, normalizeSignature(signature[len(signaturePrefix):]))
+	assert.Equal(t, signature, normalizeSignature(" "+signature+" "))
+}
+
+func TestVerifySignatureRejectsRespelledReplays(t *testing.T) {
+	client, _ := newRedis(t)
+	app := fiber.New()
+	app.Post("/webhook", VerifySignature(StaticSecrets(map[string]string{"partner-1": "s3cret"}), 5*time.Minute, client),
+		func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	body := []byte(`{"event":"sale"}`)
+	send := func(req *http.Request) (int, string) {
+		req.Header.Set(SignaturePartnerHeader, "partner-1")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(data)
+	}
+	signed := func(signature string) *http.Request {
+		req := httptest.NewRequest(fiber.MethodPost, "/webhook", bytes.NewReader(body))
+		req.Header.Set(SignatureHeader, signature)
+		return req
+	}
+
+	header := encryption.SignPayload(body, time.Now(), "s3cret")
+	timestamp, signature, _ := strings.Cut(header, ",v1=")
+	status, _ := send(signed(header))
+	assert.Equal(t, fiber.StatusOK, status)
+
+	// Every spelling of the signature verifies, and is the same request
+	sha256Form := signed("SHA256=" + strings.ToUpper(signature))
+	sha256Form.Header.Set(SignatureTimestampHeader, strings.TrimPrefix(timestamp, "t="))
+	for name, replay := range map[string]*http.Request{
+		"same header":        signed(header),
+		"upper case":         signed(timestamp + ",v1=" + strings.ToUpper(signature)),
+		"padded":             signed(" " + timestamp + " , v1=" + signature + " "),
+		"other scheme":       signed(timestamp + ",v0=abcd,v1=" + signature),
+		"repeated signature": signed(timestamp + ",v1=" + signature + ",v1=" + signature),
+		"sha256 form":        sha256Form,
+	} {
+		status, body := send(replay)
+		assert.Equal(t, fiber.StatusUnauthorized, status, name)
+		assert.Contains(t, body, "already been processed", name)
+	}
+}
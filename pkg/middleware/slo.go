@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/onichange/pos-system/pkg/slo"
+)
+
+// SLOTracking counts completed requests against the service's objectives
+func SLOTracking(tracker *slo.Tracker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// The error handler has not written the response yet
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+
+		tracker.Observe(routeLabel(c), status, time.Since(start))
+		return err
+	}
+}
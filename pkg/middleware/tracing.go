@@ -2,58 +2,86 @@ package middleware
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// TracingMiddleware creates OpenTelemetry tracing middleware
+// TracingMiddleware creates OpenTelemetry tracing middleware. The span is set on
+// c.UserContext(), so repository, cache, and proxy spans started from it nest
+// under the request.
 func TracingMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Extract trace context from headers
-		ctx := otel.GetTextMapPropagator().Extract(c.Context(), propagation.HeaderCarrier(c.GetReqHeaders()))
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), propagation.HeaderCarrier(c.GetReqHeaders()))
 
-		// Start span
-		tracer := otel.Tracer("api-gateway")
-		ctx, span := tracer.Start(ctx, c.Route().Path)
+		// Start span; it is renamed to the matched route once routing is done
+		tracer := otel.Tracer("http")
+		ctx, span := tracer.Start(ctx, c.Method(), trace.WithSpanKind(trace.SpanKindServer))
 		defer span.End()
 
 		// Set span attributes
 		span.SetAttributes(
 			attribute.String("http.method", c.Method()),
 			attribute.String("http.path", c.Path()),
-			attribute.String("http.route", c.Route().Path),
 		)
 
 		// Store span in context
+		c.SetUserContext(ctx)
 		c.Locals("span", span)
 		c.Locals("trace_id", span.SpanContext().TraceID().String())
 
-		// Inject trace context into response headers
-		headers := make(propagation.HeaderCarrier)
-		c.Response().Header.VisitAll(func(key, value []byte) {
-			headers[string(key)] = []string{string(value)}
-		})
-		otel.GetTextMapPropagator().Inject(ctx, headers)
-		// Set headers back
-		for key, values := range headers {
-			for _, value := range values {
-				c.Response().Header.Set(key, value)
-			}
-		}
+		// Return the trace context so clients can report the trace they were part of
+		otel.GetTextMapPropagator().Inject(ctx, responseHeaderCarrier{c})
 
 		// Process request
 		err := c.Next()
 
-		// Set status code
-		span.SetAttributes(attribute.Int("http.status_code", c.Response().StatusCode()))
+		route := routeLabel(c)
+		span.SetName(c.Method() + " " + route)
+		span.SetAttributes(attribute.String("http.route", route))
 
+		// Set status code
+		status := c.Response().StatusCode()
 		if err != nil {
 			span.RecordError(err)
-			span.SetStatus(codes.Error, err.Error())
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, utils.StatusMessage(status))
 		}
 
 		return err
 	}
 }
+
+// responseHeaderCarrier writes propagated fields to the response headers
+type responseHeaderCarrier struct {
+	c *fiber.Ctx
+}
+
+// Get returns a response header
+func (r responseHeaderCarrier) Get(key string) string {
+	return string(r.c.Response().Header.Peek(key))
+}
+
+// Set sets a response header
+func (r responseHeaderCarrier) Set(key, value string) {
+	r.c.Set(key, value)
+}
+
+// Keys lists the response headers
+func (r responseHeaderCarrier) Keys() []string {
+	var keys []string
+	r.c.Response().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
@@ -0,0 +1,170 @@
+// Package provider charges payment methods through a payment service
+// provider. Payment methods arrive tokenized by the provider's client SDK,
+// so card data never reaches the services (PCI-DSS).
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrDeclined is matched by a DeclineError
+	ErrDeclined = errors.New("payment declined")
+	// ErrUnavailable is returned when the provider could not be reached or
+	// failed on its side. Whether the call took effect is unknown; retry it
+	// with the same idempotency key.
+	ErrUnavailable = errors.New("payment provider unavailable")
+	// ErrInvalidSignature is returned for a webhook whose signature does not
+	// verify
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrWebhooksUnsupported is returned by providers that send no webhooks
+	ErrWebhooksUnsupported = errors.New("provider sends no webhooks")
+)
+
+// ChargeStatus is where a charge stands at the provider
+type ChargeStatus string
+
+const (
+	StatusSucceeded      ChargeStatus = "succeeded"       // The funds were captured
+	StatusAuthorized     ChargeStatus = "authorized"      // The funds are held, waiting for Capture
+	StatusRequiresAction ChargeStatus = "requires_action" // The customer must authenticate (3D Secure)
+	StatusProcessing     ChargeStatus = "processing"      // The provider settles it later and sends a webhook
+	StatusFailed         ChargeStatus = "failed"
+	StatusCanceled       ChargeStatus = "canceled"
+)
+
+// EventType is the kind of a webhook event
+type EventType string
+
+const (
+	EventSucceeded  EventType = "succeeded"  // A charge was captured
+	EventAuthorized EventType = "authorized" // A charge's funds are held
+	EventFailed     EventType = "failed"     // A charge failed, such as after 3D Secure
+	EventCanceled   EventType = "canceled"   // An authorized charge was released
+	EventOther      EventType = "other"      // Anything else the provider sends
+)
+
+// Provider charges, captures and refunds payments. Calls taking an
+// idempotency key may be retried with it without acting twice.
+type Provider interface {
+	// Name identifies the provider, as recorded on payments
+	Name() string
+	// Charge charges a payment method. A declined payment method fails
+	// with a DeclineError.
+	Charge(ctx context.Context, req ChargeRequest) (*Charge, error)
+	// GetCharge reports where a charge stands
+	GetCharge(ctx context.Context, chargeID string) (*Charge, error)
+	// Capture captures an authorized charge; an amount of 0 captures all of it
+	Capture(ctx context.Context, chargeID string, amount int64, idempotencyKey string) (*Charge, error)
+	// Refund returns some or all of a captured charge
+	Refund(ctx context.Context, req RefundRequest) (*Refund, error)
+	// ParseWebhook verifies a webhook's signature and parses its event
+	ParseWebhook(payload []byte, header http.Header) (*Event, error)
+}
+
+// ChargeRequest describes a charge. Amounts are in the currency's minor
+// unit, such as cents; see MinorUnits.
+type ChargeRequest struct {
+	Amount         int64
+	Currency       string
+	PaymentMethod  string // Provider token of the payment method
+	Capture        bool   // False only authorizes, leaving Capture to take the funds
+	ThreeDSecure   bool   // Ask for 3D Secure even when the provider would skip it
+	Description    string
+	Metadata       map[string]string // Sent back with webhook events of the charge
+	IdempotencyKey string
+}
+
+// Charge is a charge as the provider reports it
+type Charge struct {
+	ID       string
+	Status   ChargeStatus
+	Amount   int64
+	Currency string
+}
+
+// RefundRequest describes a refund; an amount of 0 refunds what is left of
+// the charge
+type RefundRequest struct {
+	ChargeID       string
+	Amount         int64
+	Reason         string
+	IdempotencyKey string
+}
+
+// Refund is a refund as the provider reports it
+type Refund struct {
+	ID     string
+	Status string // succeeded, pending or failed
+	Amount int64
+}
+
+// Event is a verified webhook event about a charge
+type Event struct {
+	ID        string
+	Type      EventType
+	RawType   string // The provider's own event type
+	ChargeID  string
+	Status    ChargeStatus
+	Amount    int64
+	Metadata  map[string]string // As given in the ChargeRequest
+	CreatedAt time.Time
+}
+
+// DeclineError is returned when the provider declines a payment method
+type DeclineError struct {
+	ChargeID string // Set when the provider recorded the failed charge
+	Code     string // Such as card_declined or insufficient_funds
+	Message  string
+}
+
+func (e *DeclineError) Error() string {
+	return fmt.Sprintf("payment declined: %s (%s)", e.Message, e.Code)
+}
+
+// Is matches ErrDeclined
+func (e *DeclineError) Is(target error) bool {
+	return target == ErrDeclined
+}
+
+// APIError is returned when the provider refuses a request, such as for an
+// unknown payment method. Retrying it fails the same way.
+type APIError struct {
+	Status  int
+	Type    string
+	Code    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("payment provider: %d %s: %s", e.Status, e.Type, e.Message)
+}
+
+// zeroDecimalCurrencies have no minor unit (ISO 4217 exponent 0)
+var zeroDecimalCurrencies = map[string]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "JPY": true, "KMF": true, "KRW": true, "MGA": true,
+	"PYG": true, "RWF": true, "UGX": true, "VND": true, "VUV": true, "XAF": true, "XOF": true, "XPF": true,
+}
+
+// MinorUnits converts an amount in a currency's major unit to its minor
+// unit, rounding to the nearest one: 12.34 USD is 1234
+func MinorUnits(amount float64, currency string) int64 {
+	if zeroDecimalCurrencies[strings.ToUpper(currency)] {
+		return int64(math.Round(amount))
+	}
+	return int64(math.Round(amount * 100))
+}
+
+// MajorUnits converts an amount in a currency's minor unit to its major unit
+func MajorUnits(amount int64, currency string) float64 {
+	if zeroDecimalCurrencies[strings.ToUpper(currency)] {
+		return float64(amount)
+	}
+	return float64(amount) / 100
+}
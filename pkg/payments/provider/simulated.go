@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Simulated approves every charge at once without moving money, for
+// development and tests. Like a real provider it answers a retried call
+// with the result of the first one. It sends no webhooks.
+type Simulated struct {
+	mu      sync.Mutex
+	charges map[string]*Charge // By ID
+	replies map[string]any     // Results by idempotency key
+}
+
+// NewSimulated creates a simulated provider
+func NewSimulated() *Simulated {
+	return &Simulated{
+		charges: map[string]*Charge{},
+		replies: map[string]any{},
+	}
+}
+
+// Name returns "simulated"
+func (s *Simulated) Name() string {
+	return "simulated"
+}
+
+// Charge approves the charge, capturing it when asked to
+func (s *Simulated) Charge(ctx context.Context, req ChargeRequest) (*Charge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.replies[req.IdempotencyKey].(*Charge); ok {
+		return c, nil
+	}
+	c := &Charge{ID: "sim_" + uuid.NewString(), Status: StatusAuthorized, Amount: req.Amount, Currency: strings.ToUpper(req.Currency)}
+	if req.Capture {
+		c.Status = StatusSucceeded
+	}
+	s.charges[c.ID] = c
+	s.remember(req.IdempotencyKey, c)
+	return c, nil
+}
+
+// GetCharge reports a charge made since the provider was created
+func (s *Simulated) GetCharge(ctx context.Context, chargeID string) (*Charge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.charges[chargeID]
+	if !ok {
+		return nil, &APIError{Status: http.StatusNotFound, Type: "invalid_request_error", Code: "resource_missing", Message: "no such charge"}
+	}
+	charge := *c
+	return &charge, nil
+}
+
+// Capture captures an authorized charge
+func (s *Simulated) Capture(ctx context.Context, chargeID string, amount int64, idempotencyKey string) (*Charge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.replies[idempotencyKey].(*Charge); ok {
+		return c, nil
+	}
+	c, ok := s.charges[chargeID]
+	if !ok || c.Status != StatusAuthorized {
+		return nil, &APIError{Status: http.StatusBadRequest, Type: "invalid_request_error", Message: "charge cannot be captured"}
+	}
+	if amount > 0 && amount < c.Amount {
+		c.Amount = amount
+	}
+	c.Status = StatusSucceeded
+	s.remember(idempotencyKey, c)
+	return c, nil
+}
+
+// Refund refunds a captured charge
+func (s *Simulated) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.replies[req.IdempotencyKey].(*Refund); ok {
+		return r, nil
+	}
+	c, ok := s.charges[req.ChargeID]
+	if !ok || c.Status != StatusSucceeded {
+		return nil, &APIError{Status: http.StatusBadRequest, Type: "invalid_request_error", Message: "charge cannot be refunded"}
+	}
+	amount := req.Amount
+	if amount <= 0 {
+		amount = c.Amount
+	}
+	r := &Refund{ID: "sim_re_" + uuid.NewString(), Status: "succeeded", Amount: amount}
+	s.remember(req.IdempotencyKey, r)
+	return r, nil
+}
+
+// ParseWebhook fails with ErrWebhooksUnsupported
+func (s *Simulated) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	return nil, ErrWebhooksUnsupported
+}
+
+// remember keeps a result for calls retried with the same idempotency key
+func (s *Simulated) remember(idempotencyKey string, result any) {
+	if idempotencyKey != "" {
+		s.replies[idempotencyKey] = result
+	}
+}
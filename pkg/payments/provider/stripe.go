@@ -0,0 +1,297 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/encryption"
+)
+
+// StripeSignatureHeader carries the signature of a Stripe webhook, in the
+// t=<unix seconds>,v1=<hex HMAC-SHA256> form encryption.VerifyPayload reads
+const StripeSignatureHeader = "Stripe-Signature"
+
+// stripeAPIVersion pins the shape of the objects Stripe returns
+const stripeAPIVersion = "2024-06-20"
+
+// Stripe charges through Stripe PaymentIntents
+type Stripe struct {
+	baseURL          string
+	secretKey        string
+	webhookSecrets   []string
+	webhookTolerance time.Duration
+	client           *http.Client
+}
+
+// NewStripe creates a Stripe provider with the credentials in cfg
+func NewStripe(cfg config.StripeConfig) *Stripe {
+	return &Stripe{
+		baseURL:          strings.TrimRight(cfg.APIURL, "/"),
+		secretKey:        cfg.SecretKey,
+		webhookSecrets:   cfg.WebhookSecrets,
+		webhookTolerance: cfg.WebhookTolerance,
+		client:           &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Name returns "stripe"
+func (s *Stripe) Name() string {
+	return "stripe"
+}
+
+// stripeIntent is the part of a PaymentIntent the provider reads
+type stripeIntent struct {
+	ID       string            `json:"id"`
+	Status   string            `json:"status"`
+	Amount   int64             `json:"amount"`
+	Currency string            `json:"currency"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// stripeErrorBody is the body of a Stripe error response
+type stripeErrorBody struct {
+	Error struct {
+		Type          string `json:"type"`
+		Code          string `json:"code"`
+		DeclineCode   string `json:"decline_code"`
+		Message       string `json:"message"`
+		PaymentIntent *struct {
+			ID string `json:"id"`
+		} `json:"payment_intent"`
+	} `json:"error"`
+}
+
+// Charge creates and confirms a PaymentIntent
+func (s *Stripe) Charge(ctx context.Context, req ChargeRequest) (*Charge, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(req.Amount, 10))
+	form.Set("currency", strings.ToLower(req.Currency))
+	form.Set("payment_method", req.PaymentMethod)
+	form.Set("confirm", "true")
+	// Confirming server-side has no page to return to, so payment methods
+	// that redirect the customer are left out
+	form.Set("automatic_payment_methods[enabled]", "true")
+	form.Set("automatic_payment_methods[allow_redirects]", "never")
+	if req.Capture {
+		form.Set("capture_method", "automatic")
+	} else {
+		form.Set("capture_method", "manual")
+	}
+	if req.ThreeDSecure {
+		form.Set("payment_method_options[card][request_three_d_secure]", "any")
+	}
+	if req.Description != "" {
+		form.Set("description", req.Description)
+	}
+	for key, value := range req.Metadata {
+		form.Set("metadata["+key+"]", value)
+	}
+
+	var intent stripeIntent
+	if err := s.post(ctx, "/v1/payment_intents", form, req.IdempotencyKey, &intent); err != nil {
+		return nil, err
+	}
+	return intent.charge(), nil
+}
+
+// GetCharge retrieves a PaymentIntent
+func (s *Stripe) GetCharge(ctx context.Context, chargeID string) (*Charge, error) {
+	var intent stripeIntent
+	if err := s.do(ctx, http.MethodGet, "/v1/payment_intents/"+url.PathEscape(chargeID), nil, "", &intent); err != nil {
+		return nil, err
+	}
+	return intent.charge(), nil
+}
+
+// Capture captures an authorized PaymentIntent
+func (s *Stripe) Capture(ctx context.Context, chargeID string, amount int64, idempotencyKey string) (*Charge, error) {
+	form := url.Values{}
+	if amount > 0 {
+		form.Set("amount_to_capture", strconv.FormatInt(amount, 10))
+	}
+
+	var intent stripeIntent
+	if err := s.post(ctx, "/v1/payment_intents/"+url.PathEscape(chargeID)+"/capture", form, idempotencyKey, &intent); err != nil {
+		return nil, err
+	}
+	return intent.charge(), nil
+}
+
+// Refund refunds a captured PaymentIntent
+func (s *Stripe) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	form := url.Values{}
+	form.Set("payment_intent", req.ChargeID)
+	if req.Amount > 0 {
+		form.Set("amount", strconv.FormatInt(req.Amount, 10))
+	}
+	// Stripe's own reason takes a few fixed values; ours is free text
+	if req.Reason != "" {
+		form.Set("metadata[reason]", req.Reason)
+	}
+
+	var refund struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+		Amount int64  `json:"amount"`
+	}
+	if err := s.post(ctx, "/v1/refunds", form, req.IdempotencyKey, &refund); err != nil {
+		return nil, err
+	}
+	if refund.Status == "requires_action" {
+		refund.Status = "pending"
+	}
+	return &Refund{ID: refund.ID, Status: refund.Status, Amount: refund.Amount}, nil
+}
+
+// ParseWebhook verifies the Stripe-Signature of a webhook against the
+// configured webhook secrets and parses its event. Events about anything
+// but PaymentIntents have type EventOther.
+func (s *Stripe) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	if _, err := encryption.VerifyPayload(payload, header.Get(StripeSignatureHeader), s.webhookSecrets, s.webhookTolerance); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	var body struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Created int64  `json:"created"`
+		Data    struct {
+			Object json.RawMessage `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, fmt.Errorf("invalid stripe event: %w", err)
+	}
+
+	event := &Event{
+		ID:        body.ID,
+		Type:      EventOther,
+		RawType:   body.Type,
+		CreatedAt: time.Unix(body.Created, 0).UTC(),
+	}
+	kind, ok := strings.CutPrefix(body.Type, "payment_intent.")
+	if !ok {
+		return event, nil
+	}
+	switch kind {
+	case "succeeded":
+		event.Type = EventSucceeded
+	case "amount_capturable_updated":
+		event.Type = EventAuthorized
+	case "payment_failed":
+		event.Type = EventFailed
+	case "canceled":
+		event.Type = EventCanceled
+	}
+
+	var intent stripeIntent
+	if err := json.Unmarshal(body.Data.Object, &intent); err != nil {
+		return nil, fmt.Errorf("invalid stripe payment intent: %w", err)
+	}
+	charge := intent.charge()
+	event.ChargeID = charge.ID
+	event.Status = charge.Status
+	event.Amount = charge.Amount
+	event.Metadata = intent.Metadata
+	return event, nil
+}
+
+// post sends a form-encoded request to the Stripe API and decodes the
+// response into out
+func (s *Stripe) post(ctx context.Context, path string, form url.Values, idempotencyKey string, out any) error {
+	return s.do(ctx, http.MethodPost, path, form, idempotencyKey, out)
+}
+
+// do sends a request to the Stripe API, with form as its body when set, and
+// decodes the response into out
+func (s *Stripe) do(ctx context.Context, method, path string, form url.Values, idempotencyKey string, out any) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.secretKey)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Stripe-Version", stripeAPIVersion)
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	// 409 is a request with the same idempotency key still in flight
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusConflict {
+		return fmt.Errorf("%w: stripe answered %d", ErrUnavailable, resp.StatusCode)
+	}
+	if resp.StatusCode >= 400 {
+		return stripeError(resp.StatusCode, respBody)
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// stripeError converts a Stripe error response: card errors are declines,
+// everything else an APIError
+func stripeError(status int, body []byte) error {
+	var e stripeErrorBody
+	if err := json.Unmarshal(body, &e); err != nil {
+		return &APIError{Status: status, Message: string(body)}
+	}
+
+	if e.Error.Type == "card_error" {
+		decline := &DeclineError{Code: e.Error.Code, Message: e.Error.Message}
+		if e.Error.DeclineCode != "" {
+			decline.Code = e.Error.DeclineCode
+		}
+		if e.Error.PaymentIntent != nil {
+			decline.ChargeID = e.Error.PaymentIntent.ID
+		}
+		return decline
+	}
+	return &APIError{Status: status, Type: e.Error.Type, Code: e.Error.Code, Message: e.Error.Message}
+}
+
+// charge converts a PaymentIntent to a Charge
+func (i *stripeIntent) charge() *Charge {
+	c := &Charge{ID: i.ID, Amount: i.Amount, Currency: strings.ToUpper(i.Currency)}
+	switch i.Status {
+	case "succeeded":
+		c.Status = StatusSucceeded
+	case "requires_capture":
+		c.Status = StatusAuthorized
+	case "requires_action", "requires_confirmation":
+		c.Status = StatusRequiresAction
+	case "processing":
+		c.Status = StatusProcessing
+	case "canceled":
+		c.Status = StatusCanceled
+	default: // requires_payment_method: the last attempt failed
+		c.Status = StatusFailed
+	}
+	return c
+}
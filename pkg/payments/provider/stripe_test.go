@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/encryption"
+)
+
+func newTestStripe(t *testing.T, handler http.HandlerFunc) *Stripe {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewStripe(config.StripeConfig{
+		APIURL:           server.URL,
+		SecretKey:        "sk_test_123",
+		WebhookSecrets:   []string{"whsec_old", "whsec_new"},
+		WebhookTolerance: 5 * time.Minute,
+		Timeout:          time.Second,
+	})
+}
+
+func TestStripeChargeSendsIdempotencyKey(t *testing.T) {
+	stripe := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/payment_intents", r.URL.Path)
+		assert.Equal(t, "Bearer sk_test_123", r.Header.Get("Authorization"))
+		assert.Equal(t, "payment-1", r.Header.Get("Idempotency-Key"))
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "1234", r.PostForm.Get("amount"))
+		assert.Equal(t, "usd", r.PostForm.Get("currency"))
+		assert.Equal(t, "pm_card_visa", r.PostForm.Get("payment_method"))
+		assert.Equal(t, "true", r.PostForm.Get("confirm"))
+		assert.Equal(t, "automatic", r.PostForm.Get("capture_method"))
+		assert.Equal(t, "any", r.PostForm.Get("payment_method_options[card][request_three_d_secure]"))
+		assert.Equal(t, "order-1", r.PostForm.Get("metadata[order_id]"))
+		w.Write([]byte(`{"id":"pi_1","status":"succeeded","amount":1234,"currency":"usd"}`))
+	})
+
+	charge, err := stripe.Charge(context.Background(), ChargeRequest{
+		Amount:         MinorUnits(12.34, "USD"),
+		Currency:       "USD",
+		PaymentMethod:  "pm_card_visa",
+		Capture:        true,
+		ThreeDSecure:   true,
+		Metadata:       map[string]string{"order_id": "order-1"},
+		IdempotencyKey: "payment-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &Charge{ID: "pi_1", Status: StatusSucceeded, Amount: 1234, Currency: "USD"}, charge)
+}
+
+func TestStripeChargeDeclined(t *testing.T) {
+	stripe := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusPaymentRequired)
+		w.Write([]byte(`{"error":{"type":"card_error","code":"card_declined","decline_code":"insufficient_funds",` +
+			`"message":"Your card has insufficient funds.","payment_intent":{"id":"pi_2"}}}`))
+	})
+
+	_, err := stripe.Charge(context.Background(), ChargeRequest{Amount: 500, Currency: "USD", PaymentMethod: "pm_card_visa"})
+	require.ErrorIs(t, err, ErrDeclined)
+	var decline *DeclineError
+	require.ErrorAs(t, err, &decline)
+	assert.Equal(t, "pi_2", decline.ChargeID)
+	assert.Equal(t, "insufficient_funds", decline.Code)
+}
+
+func TestStripeErrors(t *testing.T) {
+	status := http.StatusBadRequest
+	stripe := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"error":{"type":"invalid_request_error","code":"resource_missing","message":"No such PaymentMethod"}}`))
+	})
+	req := ChargeRequest{Amount: 500, Currency: "USD", PaymentMethod: "pm_missing"}
+
+	_, err := stripe.Charge(context.Background(), req)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "resource_missing", apiErr.Code)
+	assert.NotErrorIs(t, err, ErrUnavailable)
+
+	for _, status = range []int{http.StatusConflict, http.StatusTooManyRequests, http.StatusBadGateway} {
+		_, err = stripe.Charge(context.Background(), req)
+		assert.ErrorIs(t, err, ErrUnavailable, "status %d", status)
+	}
+}
+
+func TestStripeCaptureAndRefund(t *testing.T) {
+	stripe := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		switch r.URL.Path {
+		case "/v1/payment_intents/pi_1":
+			assert.Equal(t, http.MethodGet, r.Method)
+			w.Write([]byte(`{"id":"pi_1","status":"requires_capture","amount":1000,"currency":"usd"}`))
+		case "/v1/payment_intents/pi_1/capture":
+			assert.Equal(t, "800", r.PostForm.Get("amount_to_capture"))
+			assert.Equal(t, "capture-1", r.Header.Get("Idempotency-Key"))
+			w.Write([]byte(`{"id":"pi_1","status":"succeeded","amount":1000,"currency":"usd"}`))
+		case "/v1/refunds":
+			assert.Equal(t, "pi_1", r.PostForm.Get("payment_intent"))
+			assert.Equal(t, "300", r.PostForm.Get("amount"))
+			assert.Equal(t, "damaged item", r.PostForm.Get("metadata[reason]"))
+			w.Write([]byte(`{"id":"re_1","status":"succeeded","amount":300}`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+	ctx := context.Background()
+
+	charge, err := stripe.GetCharge(ctx, "pi_1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusAuthorized, charge.Status)
+
+	charge, err = stripe.Capture(ctx, "pi_1", 800, "capture-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, charge.Status)
+
+	refund, err := stripe.Refund(ctx, RefundRequest{ChargeID: "pi_1", Amount: 300, Reason: "damaged item", IdempotencyKey: "refund-1"})
+	require.NoError(t, err)
+	assert.Equal(t, &Refund{ID: "re_1", Status: "succeeded", Amount: 300}, refund)
+}
+
+func TestStripeParseWebhook(t *testing.T) {
+	stripe := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {})
+	payload := []byte(`{"id":"evt_1","type":"payment_intent.payment_failed","created":1700000000,` +
+		`"data":{"object":{"id":"pi_1","status":"requires_payment_method","amount":1234,"currency":"usd","metadata":{"payment_id":"p-1"}}}}`)
+
+	header := http.Header{}
+	header.Set(StripeSignatureHeader, encryption.SignPayload(payload, time.Now(), "whsec_new"))
+	event, err := stripe.ParseWebhook(payload, header)
+	require.NoError(t, err)
+	assert.Equal(t, EventFailed, event.Type)
+	assert.Equal(t, "pi_1", event.ChargeID)
+	assert.Equal(t, StatusFailed, event.Status)
+	assert.Equal(t, "p-1", event.Metadata["payment_id"])
+
+	header.Set(StripeSignatureHeader, encryption.SignPayload(payload, time.Now(), "whsec_other"))
+	_, err = stripe.ParseWebhook(payload, header)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	header.Set(StripeSignatureHeader, encryption.SignPayload(payload, time.Now().Add(-time.Hour), "whsec_new"))
+	_, err = stripe.ParseWebhook(payload, header)
+	assert.ErrorIs(t, err, ErrInvalidSignature, "old signatures are replays")
+}
+
+func TestMinorUnits(t *testing.T) {
+	assert.Equal(t, int64(1999), MinorUnits(19.99, "USD"))
+	assert.Equal(t, int64(1500), MinorUnits(1500, "jpy"))
+	assert.Equal(t, 19.99, MajorUnits(1999, "USD"))
+	assert.Equal(t, 1500.0, MajorUnits(1500, "JPY"))
+}
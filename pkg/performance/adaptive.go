@@ -0,0 +1,171 @@
+package performance
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/metrics"
+)
+
+var ErrConcurrencyLimit = errors.New("concurrency limit reached")
+
+// LimitOutcome is how a call under an adaptive limit ended
+type LimitOutcome int
+
+const (
+	// OutcomeSuccess is a call the dependency served; its latency is sampled
+	OutcomeSuccess LimitOutcome = iota
+	// OutcomeDropped is a call the dependency failed to serve, such as a
+	// timeout or an unavailable response
+	OutcomeDropped
+	// OutcomeIgnored is a call that says nothing about the dependency, such
+	// as one the caller cancelled
+	OutcomeIgnored
+)
+
+// Weights of each latency sample in the short and long term averages
+const (
+	shortLatencyWeight = 0.1
+	longLatencyWeight  = 0.01
+)
+
+// gradientSmoothing is how far each slow sample moves the limit towards the
+// limit scaled by the gradient
+const gradientSmoothing = 0.2
+
+// AdaptiveLimiter limits in-flight calls to a dependency to a limit that
+// follows its health, so a dependency that slows down is sent less work
+// before it fails outright. Successful calls grow the limit by one while it
+// is in use (additive increase). When the short term average latency rises
+// above Tolerance times the long term one, the limit shrinks towards their
+// ratio (the gradient), and each dropped call cuts it by Backoff
+// (multiplicative decrease). It is safe for concurrent use, and a nil
+// AdaptiveLimiter admits every call.
+type AdaptiveLimiter struct {
+	name      string // Dependency label on metrics; empty exports none
+	minLimit  float64
+	maxLimit  float64
+	tolerance float64
+	backoff   float64
+	now       func() time.Time
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	shortRTT time.Duration
+	longRTT  time.Duration
+	samples  int
+}
+
+// NewAdaptiveLimiter creates the limiter described by cfg. It returns nil when
+// cfg disables adaptive limiting.
+func NewAdaptiveLimiter(name string, cfg config.AdaptiveConcurrencyConfig) *AdaptiveLimiter {
+	if !cfg.Enabled {
+		return nil
+	}
+	l := &AdaptiveLimiter{
+		name:      name,
+		minLimit:  float64(max(cfg.MinLimit, 1)),
+		maxLimit:  float64(max(cfg.MaxLimit, cfg.MinLimit, 1)),
+		tolerance: max(cfg.Tolerance, 1),
+		backoff:   cfg.Backoff,
+		now:       time.Now,
+	}
+	l.limit = min(max(float64(cfg.InitialLimit), l.minLimit), l.maxLimit)
+	l.export()
+	return l
+}
+
+// Limit returns the current limit
+func (l *AdaptiveLimiter) Limit() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// InFlight returns the calls currently admitted
+func (l *AdaptiveLimiter) InFlight() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// Acquire admits a call, or returns ErrConcurrencyLimit when the limit is
+// reached. Call done exactly once with the call's outcome.
+func (l *AdaptiveLimiter) Acquire() (done func(LimitOutcome), err error) {
+	if l == nil {
+		return func(LimitOutcome) {}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if float64(l.inFlight) >= l.limit {
+		if l.name != "" {
+			metrics.AdaptiveConcurrencyRejected.WithLabelValues(l.name).Inc()
+		}
+		return nil, ErrConcurrencyLimit
+	}
+	l.inFlight++
+
+	start := l.now()
+	return func(outcome LimitOutcome) {
+		l.release(outcome, l.now().Sub(start))
+	}, nil
+}
+
+// release ends a call and adjusts the limit to its outcome
+func (l *AdaptiveLimiter) release(outcome LimitOutcome, latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Whether the call needed most of the limit, measured before it ends
+	saturated := float64(l.inFlight)*2 >= l.limit
+	l.inFlight--
+
+	switch outcome {
+	case OutcomeIgnored:
+		return
+	case OutcomeDropped:
+		l.setLimit(l.limit * l.backoff)
+		return
+	}
+
+	if l.samples == 0 {
+		l.shortRTT, l.longRTT = latency, latency
+	} else {
+		l.shortRTT += time.Duration(shortLatencyWeight * float64(latency-l.shortRTT))
+		l.longRTT += time.Duration(longLatencyWeight * float64(latency-l.longRTT))
+	}
+	l.samples++
+
+	if l.shortRTT > 0 {
+		if gradient := l.tolerance * float64(l.longRTT) / float64(l.shortRTT); gradient < 1 {
+			l.setLimit(l.limit * (1 - gradientSmoothing + gradientSmoothing*max(gradient, 0.5)))
+			return
+		}
+	}
+	if saturated {
+		l.setLimit(l.limit + 1)
+	}
+}
+
+// setLimit moves the limit within its bounds. Callers hold mu.
+func (l *AdaptiveLimiter) setLimit(limit float64) {
+	l.limit = min(max(limit, l.minLimit), l.maxLimit)
+	l.export()
+}
+
+// export publishes the limit. Callers hold mu or own l.
+func (l *AdaptiveLimiter) export() {
+	if l.name != "" {
+		metrics.AdaptiveConcurrencyLimit.WithLabelValues(l.name).Set(l.limit)
+	}
+}
@@ -0,0 +1,106 @@
+package performance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+func testAdaptiveConfig() config.AdaptiveConcurrencyConfig {
+	return config.AdaptiveConcurrencyConfig{
+		Enabled:      true,
+		InitialLimit: 4,
+		MinLimit:     2,
+		MaxLimit:     10,
+		Tolerance:    2,
+		Backoff:      0.5,
+	}
+}
+
+// call runs one call taking latency through l
+func call(t *testing.T, l *AdaptiveLimiter, now *time.Time, latency time.Duration, outcome LimitOutcome) {
+	t.Helper()
+	done, err := l.Acquire()
+	require.NoError(t, err)
+	*now = now.Add(latency)
+	done(outcome)
+}
+
+func TestAdaptiveLimiterRejectsAtLimit(t *testing.T) {
+	l := NewAdaptiveLimiter("", testAdaptiveConfig())
+
+	var held []func(LimitOutcome)
+	for i := 0; i < 4; i++ {
+		done, err := l.Acquire()
+		require.NoError(t, err)
+		held = append(held, done)
+	}
+	_, err := l.Acquire()
+	assert.ErrorIs(t, err, ErrConcurrencyLimit)
+
+	for _, done := range held {
+		done(OutcomeIgnored)
+	}
+	assert.Equal(t, 0, l.InFlight())
+	assert.Equal(t, 4, l.Limit(), "ignored calls leave the limit alone")
+}
+
+func TestAdaptiveLimiterGrowsWhileSaturated(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewAdaptiveLimiter("", testAdaptiveConfig())
+	l.now = func() time.Time { return now }
+
+	// A lone call does not use half the limit, so the limit stays put
+	call(t, l, &now, 10*time.Millisecond, OutcomeSuccess)
+	assert.Equal(t, 4, l.Limit())
+
+	// Calls running alongside half the limit grow it up to the maximum
+	for i := 0; i < 20; i++ {
+		var held []func(LimitOutcome)
+		for len(held) < l.Limit()/2 {
+			done, err := l.Acquire()
+			require.NoError(t, err)
+			held = append(held, done)
+		}
+		call(t, l, &now, 10*time.Millisecond, OutcomeSuccess)
+		for _, done := range held {
+			done(OutcomeIgnored)
+		}
+	}
+	assert.Equal(t, 10, l.Limit())
+}
+
+func TestAdaptiveLimiterShrinks(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewAdaptiveLimiter("", testAdaptiveConfig())
+	l.now = func() time.Time { return now }
+	for i := 0; i < 50; i++ {
+		call(t, l, &now, 10*time.Millisecond, OutcomeSuccess)
+	}
+	require.Equal(t, 4, l.Limit())
+
+	// Latency well past the tolerance pulls the limit down to the minimum
+	for i := 0; i < 50; i++ {
+		call(t, l, &now, 200*time.Millisecond, OutcomeSuccess)
+	}
+	assert.Equal(t, 2, l.Limit())
+
+	// Failures cut it by the backoff
+	l.setLimit(8)
+	call(t, l, &now, time.Millisecond, OutcomeDropped)
+	assert.Equal(t, 4, l.Limit())
+}
+
+func TestAdaptiveLimiterDisabled(t *testing.T) {
+	assert.Nil(t, NewAdaptiveLimiter("test", config.AdaptiveConcurrencyConfig{}))
+
+	var l *AdaptiveLimiter
+	done, err := l.Acquire()
+	require.NoError(t, err)
+	done(OutcomeSuccess)
+	assert.Zero(t, l.Limit())
+}
@@ -3,22 +3,44 @@ package performance
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/metrics"
 )
 
 var (
 	ErrBulkheadFull = errors.New("bulkhead is full")
+	// ErrBulkheadTimeout also matches ErrBulkheadFull
+	ErrBulkheadTimeout = fmt.Errorf("%w: timed out waiting for a slot", ErrBulkheadFull)
+)
+
+// Dependencies with their own bulkhead
+const (
+	BulkheadDatabase = "database"
+	BulkheadRedis    = "redis"
+	BulkheadProvider = "provider"
 )
 
-// Bulkhead implements the bulkhead pattern for resource isolation
+// Bulkhead implements the bulkhead pattern for resource isolation. At most
+// maxConcurrency calls run at once; further calls wait in a queue of maxQueue
+// for up to maxWait and are rejected beyond that. A nil Bulkhead runs every
+// call directly.
 type Bulkhead struct {
+	name           string // Dependency label on metrics; empty exports none
 	maxConcurrency int
+	maxQueue       int
+	maxWait        time.Duration
 	semaphore      chan struct{}
 	mu             sync.RWMutex
 	current        int
+	queued         int
 }
 
-// NewBulkhead creates a new bulkhead
+// NewBulkhead creates a new bulkhead that rejects calls once maxConcurrency
+// are running
 func NewBulkhead(maxConcurrency int) *Bulkhead {
 	return &Bulkhead{
 		maxConcurrency: maxConcurrency,
@@ -26,27 +48,145 @@ func NewBulkhead(maxConcurrency int) *Bulkhead {
 	}
 }
 
-// Execute executes a function with bulkhead protection
+// NewDependencyBulkhead creates the bulkhead for a dependency, exporting its
+// state as bulkhead_* metrics. It returns nil when cfg disables the bulkhead.
+func NewDependencyBulkhead(name string, cfg config.BulkheadConfig) *Bulkhead {
+	if cfg.MaxConcurrent <= 0 {
+		return nil
+	}
+	return &Bulkhead{
+		name:           name,
+		maxConcurrency: cfg.MaxConcurrent,
+		maxQueue:       cfg.MaxQueue,
+		maxWait:        cfg.MaxWait,
+		semaphore:      make(chan struct{}, cfg.MaxConcurrent),
+	}
+}
+
+// Bulkheads holds a service's bulkhead per dependency
+type Bulkheads struct {
+	Database *Bulkhead
+	Redis    *Bulkhead
+	Provider *Bulkhead
+}
+
+// NewBulkheads creates the bulkheads described by cfg
+func NewBulkheads(cfg config.BulkheadsConfig) *Bulkheads {
+	return &Bulkheads{
+		Database: NewDependencyBulkhead(BulkheadDatabase, cfg.Database),
+		Redis:    NewDependencyBulkhead(BulkheadRedis, cfg.Redis),
+		Provider: NewDependencyBulkhead(BulkheadProvider, cfg.Provider),
+	}
+}
+
+// Do runs fn once a slot is free. It returns ErrBulkheadFull without running
+// fn when the queue is full, ErrBulkheadTimeout after waiting maxWait, or the
+// context's error if it ends first.
+func (b *Bulkhead) Do(ctx context.Context, fn func() error) error {
+	release, err := b.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn()
+}
+
+// Execute executes a function with bulkhead protection; it is Do
 func (b *Bulkhead) Execute(ctx context.Context, fn func() error) error {
+	return b.Do(ctx, fn)
+}
+
+// Acquire takes a slot for work that outlives a single call, such as reading
+// query rows. Call release exactly once when done.
+func (b *Bulkhead) Acquire(ctx context.Context) (release func(), err error) {
+	if b == nil {
+		return func() {}, nil
+	}
+
 	// Try to acquire semaphore
 	select {
 	case b.semaphore <- struct{}{}:
-		b.mu.Lock()
-		b.current++
-		b.mu.Unlock()
-
-		defer func() {
-			<-b.semaphore
-			b.mu.Lock()
-			b.current--
-			b.mu.Unlock()
-		}()
-
-		return fn()
+		b.started()
+		return b.release, nil
 	case <-ctx.Done():
-		return ctx.Err()
+		return nil, ctx.Err()
 	default:
-		return ErrBulkheadFull
+	}
+
+	if !b.enqueue() {
+		b.reject("full")
+		return nil, ErrBulkheadFull
+	}
+	defer b.dequeue()
+
+	var timeout <-chan time.Time
+	if b.maxWait > 0 {
+		timer := time.NewTimer(b.maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case b.semaphore <- struct{}{}:
+		b.started()
+		return b.release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timeout:
+		b.reject("timeout")
+		return nil, ErrBulkheadTimeout
+	}
+}
+
+// started counts a call taking a slot
+func (b *Bulkhead) started() {
+	b.mu.Lock()
+	b.current++
+	b.mu.Unlock()
+	if b.name != "" {
+		metrics.BulkheadInFlight.WithLabelValues(b.name).Inc()
+	}
+}
+
+// release frees a slot
+func (b *Bulkhead) release() {
+	<-b.semaphore
+	b.mu.Lock()
+	b.current--
+	b.mu.Unlock()
+	if b.name != "" {
+		metrics.BulkheadInFlight.WithLabelValues(b.name).Dec()
+	}
+}
+
+// enqueue takes a place in the queue, reporting false when it is full
+func (b *Bulkhead) enqueue() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.queued >= b.maxQueue {
+		return false
+	}
+	b.queued++
+	if b.name != "" {
+		metrics.BulkheadQueued.WithLabelValues(b.name).Inc()
+	}
+	return true
+}
+
+// dequeue gives up a place in the queue
+func (b *Bulkhead) dequeue() {
+	b.mu.Lock()
+	b.queued--
+	b.mu.Unlock()
+	if b.name != "" {
+		metrics.BulkheadQueued.WithLabelValues(b.name).Dec()
+	}
+}
+
+// reject counts a call turned away
+func (b *Bulkhead) reject(reason string) {
+	if b.name != "" {
+		metrics.BulkheadRejected.WithLabelValues(b.name, reason).Inc()
 	}
 }
 
@@ -57,8 +197,14 @@ func (b *Bulkhead) CurrentConcurrency() int {
 	return b.current
 }
 
+// Queued returns the number of calls waiting for a slot
+func (b *Bulkhead) Queued() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.queued
+}
+
 // MaxConcurrency returns max concurrency
 func (b *Bulkhead) MaxConcurrency() int {
 	return b.maxConcurrency
 }
-
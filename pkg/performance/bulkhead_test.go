@@ -0,0 +1,83 @@
+package performance
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+func TestBulkheadQueuesThenRejects(t *testing.T) {
+	b := NewDependencyBulkhead("test", config.BulkheadConfig{MaxConcurrent: 1, MaxQueue: 1, MaxWait: time.Second})
+
+	hold := make(chan struct{})
+	running := make(chan struct{})
+	go func() {
+		_ = b.Do(context.Background(), func() error {
+			close(running)
+			<-hold
+			return nil
+		})
+	}()
+	<-running
+
+	// The second call waits in the queue
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var queuedErr error
+	go func() {
+		defer wg.Done()
+		queuedErr = b.Do(context.Background(), func() error { return nil })
+	}()
+	require.Eventually(t, func() bool { return b.Queued() == 1 }, time.Second, time.Millisecond)
+
+	// The third finds the queue full
+	err := b.Do(context.Background(), func() error { return nil })
+	assert.ErrorIs(t, err, ErrBulkheadFull)
+
+	close(hold)
+	wg.Wait()
+	assert.NoError(t, queuedErr)
+	assert.Equal(t, 0, b.CurrentConcurrency())
+	assert.Equal(t, 0, b.Queued())
+}
+
+func TestBulkheadWaitLimits(t *testing.T) {
+	b := NewDependencyBulkhead("test", config.BulkheadConfig{MaxConcurrent: 1, MaxQueue: 5, MaxWait: 20 * time.Millisecond})
+	release, err := b.Acquire(context.Background())
+	require.NoError(t, err)
+	defer release()
+
+	err = b.Do(context.Background(), func() error { return nil })
+	assert.ErrorIs(t, err, ErrBulkheadTimeout)
+	assert.ErrorIs(t, err, ErrBulkheadFull)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	b.maxWait = 0
+	err = b.Do(ctx, func() error { return nil })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestBulkheadPassesErrorsThrough(t *testing.T) {
+	b := NewBulkhead(1)
+	failure := errors.New("provider declined")
+	assert.Equal(t, failure, b.Do(context.Background(), func() error { return failure }))
+	assert.Equal(t, 0, b.CurrentConcurrency())
+
+	// A disabled bulkhead runs every call
+	var disabled *Bulkhead
+	assert.Nil(t, NewDependencyBulkhead("test", config.BulkheadConfig{}))
+	ran := false
+	require.NoError(t, disabled.Do(context.Background(), func() error {
+		ran = true
+		return nil
+	}))
+	assert.True(t, ran)
+}
@@ -2,12 +2,17 @@ package performance
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"time"
+
+	"github.com/onichange/pos-system/pkg/metrics"
 )
 
 var (
 	ErrCircuitOpen = errors.New("circuit breaker is open")
+	// ErrTooManyProbes also matches ErrCircuitOpen
+	ErrTooManyProbes = fmt.Errorf("%w: half-open probe limit reached", ErrCircuitOpen)
 )
 
 // CircuitBreakerState represents the state of a circuit breaker
@@ -19,24 +24,82 @@ const (
 	StateHalfOpen
 )
 
+// String returns the state's name, as used in metric labels
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// circuitWindowBuckets is how many buckets a rolling failure window is split into
+const circuitWindowBuckets = 10
+
+// CircuitBreakerSettings tunes a circuit breaker
+type CircuitBreakerSettings struct {
+	MaxFailures       int           // Consecutive failures that open the circuit, when FailureRatio is 0
+	ResetTimeout      time.Duration // How long the circuit stays open before probing
+	HalfOpenMaxProbes int           // Calls let through at once while half-open; at least 1
+	SuccessThreshold  int           // Consecutive probe successes that close the circuit; at least 1
+
+	// With a FailureRatio, the circuit opens once that share of the calls in
+	// the last Window failed, counting only windows of MinRequests or more
+	FailureRatio float64
+	Window       time.Duration
+	MinRequests  int
+}
+
 // CircuitBreaker implements the circuit breaker pattern
 type CircuitBreaker struct {
-	maxFailures   int
-	resetTimeout  time.Duration
+	name          string // Label on metrics; empty exports none
+	settings      CircuitBreakerSettings
 	state         CircuitBreakerState
+	generation    uint64 // Bumped on every state change so late results are ignored
 	failureCount  int
+	successCount  int // Consecutive successes while half-open
+	probes        int // Calls in flight while half-open
+	window        [circuitWindowBuckets]circuitBucket
+	bucket        int64 // Index of the newest window bucket
 	lastFailTime  time.Time
+	now           func() time.Time
 	mu            sync.RWMutex
 	onStateChange func(from, to CircuitBreakerState)
 }
 
+// circuitBucket counts the calls of one slice of the rolling window
+type circuitBucket struct {
+	requests int
+	failures int
+}
+
 // NewCircuitBreaker creates a new circuit breaker
 func NewCircuitBreaker(maxFailures int, resetTimeout time.Duration) *CircuitBreaker {
-	return &CircuitBreaker{
-		maxFailures:  maxFailures,
-		resetTimeout: resetTimeout,
-		state:        StateClosed,
+	return NewNamedCircuitBreaker("", CircuitBreakerSettings{
+		MaxFailures:  maxFailures,
+		ResetTimeout: resetTimeout,
+	})
+}
+
+// NewNamedCircuitBreaker creates a circuit breaker with the given settings,
+// exporting its state as circuit_breaker_* metrics
+func NewNamedCircuitBreaker(name string, settings CircuitBreakerSettings) *CircuitBreaker {
+	settings.HalfOpenMaxProbes = max(settings.HalfOpenMaxProbes, 1)
+	settings.SuccessThreshold = max(settings.SuccessThreshold, 1)
+	cb := &CircuitBreaker{
+		name:     name,
+		settings: settings,
+		state:    StateClosed,
+		now:      time.Now,
+	}
+	if name != "" {
+		metrics.CircuitBreakerState.WithLabelValues(name).Set(float64(StateClosed))
 	}
+	return cb
 }
 
 // OnStateChange sets a callback for state changes
@@ -46,47 +109,136 @@ func (cb *CircuitBreaker) OnStateChange(fn func(from, to CircuitBreakerState)) {
 	cb.onStateChange = fn
 }
 
-// Call executes a function with circuit breaker protection
+// Call executes a function with circuit breaker protection. While the circuit
+// is open it returns ErrCircuitOpen without calling fn; while half-open, calls
+// beyond the probe limit get ErrTooManyProbes.
 func (cb *CircuitBreaker) Call(fn func() error) error {
+	generation, err := cb.before()
+	if err != nil {
+		if cb.name != "" {
+			metrics.CircuitBreakerRejected.WithLabelValues(cb.name).Inc()
+		}
+		return err
+	}
+
+	// Execute function
+	err = fn()
+
+	cb.after(generation, err == nil)
+	return err
+}
+
+// Allow admits a call made outside Call, such as one whose outcome is only
+// known in parts, and returns the function reporting how it ended. It rejects
+// calls as Call does. An ignored outcome frees a probe without counting. A
+// nil breaker admits every call.
+func (cb *CircuitBreaker) Allow() (done func(LimitOutcome), err error) {
+	if cb == nil {
+		return func(LimitOutcome) {}, nil
+	}
+
+	generation, err := cb.before()
+	if err != nil {
+		if cb.name != "" {
+			metrics.CircuitBreakerRejected.WithLabelValues(cb.name).Inc()
+		}
+		return nil, err
+	}
+	return func(outcome LimitOutcome) {
+		if outcome == OutcomeIgnored {
+			cb.release(generation)
+			return
+		}
+		cb.after(generation, outcome == OutcomeSuccess)
+	}, nil
+}
+
+// RetryAfter returns how long the circuit stays open before it lets a probe
+// through, or 0 when it is not open
+func (cb *CircuitBreaker) RetryAfter() time.Duration {
+	if cb == nil {
+		return 0
+	}
+
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	if cb.state != StateOpen {
+		return 0
+	}
+	return max(cb.settings.ResetTimeout-cb.now().Sub(cb.lastFailTime), 0)
+}
+
+// before admits a call and returns the generation it ran in
+func (cb *CircuitBreaker) before() (uint64, error) {
 	cb.mu.Lock()
+	defer cb.mu.Unlock()
 
 	// Check if circuit is open
 	if cb.state == StateOpen {
-		if time.Since(cb.lastFailTime) >= cb.resetTimeout {
-			// Try to transition to half-open
-			cb.setState(StateHalfOpen)
-		} else {
-			cb.mu.Unlock()
-			return ErrCircuitOpen
+		if cb.now().Sub(cb.lastFailTime) < cb.settings.ResetTimeout {
+			return 0, ErrCircuitOpen
 		}
+		// Try to transition to half-open
+		cb.setState(StateHalfOpen)
 	}
 
-	cb.mu.Unlock()
-
-	// Execute function
-	err := fn()
+	if cb.state == StateHalfOpen {
+		if cb.probes >= cb.settings.HalfOpenMaxProbes {
+			return 0, ErrTooManyProbes
+		}
+		cb.probes++
+	}
+	return cb.generation, nil
+}
 
+// after records the result of a call admitted in generation. Results of calls
+// that started before the last state change are ignored.
+func (cb *CircuitBreaker) after(generation uint64, success bool) {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
-	if err != nil {
+	if generation != cb.generation {
+		return
+	}
+	if cb.state == StateHalfOpen {
+		cb.probes--
+	}
+	if success {
+		cb.recordSuccess()
+	} else {
 		cb.recordFailure()
-		return err
 	}
+}
 
-	cb.recordSuccess()
-	return nil
+// release frees the probe of a call admitted in generation without recording
+// a result
+func (cb *CircuitBreaker) release(generation uint64) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if generation == cb.generation && cb.state == StateHalfOpen {
+		cb.probes--
+	}
 }
 
 // recordFailure records a failure
 func (cb *CircuitBreaker) recordFailure() {
 	cb.failureCount++
-	cb.lastFailTime = time.Now()
+	cb.lastFailTime = cb.now()
 
-	if cb.state == StateHalfOpen {
+	switch {
+	case cb.state == StateHalfOpen:
 		// Half-open failed, go back to open
 		cb.setState(StateOpen)
-	} else if cb.failureCount >= cb.maxFailures {
+	case cb.settings.FailureRatio > 0:
+		bucket := cb.currentBucket()
+		bucket.requests++
+		bucket.failures++
+		if cb.windowTripped() {
+			cb.setState(StateOpen)
+		}
+	case cb.failureCount >= cb.settings.MaxFailures:
 		// Too many failures, open circuit
 		cb.setState(StateOpen)
 	}
@@ -94,14 +246,44 @@ func (cb *CircuitBreaker) recordFailure() {
 
 // recordSuccess records a success
 func (cb *CircuitBreaker) recordSuccess() {
-	if cb.state == StateHalfOpen {
-		// Half-open succeeded, close circuit
-		cb.setState(StateClosed)
-		cb.failureCount = 0
-	} else {
-		// Reset failure count on success
-		cb.failureCount = 0
+	// Reset failure count on success
+	cb.failureCount = 0
+
+	switch {
+	case cb.state == StateHalfOpen:
+		cb.successCount++
+		if cb.successCount >= cb.settings.SuccessThreshold {
+			// Enough probes succeeded, close circuit
+			cb.setState(StateClosed)
+		}
+	case cb.settings.FailureRatio > 0:
+		cb.currentBucket().requests++
+	}
+}
+
+// currentBucket returns the window bucket of now, clearing the buckets that
+// fell out of the window
+func (cb *CircuitBreaker) currentBucket() *circuitBucket {
+	width := max(cb.settings.Window/circuitWindowBuckets, time.Millisecond)
+	index := cb.now().UnixNano() / int64(width)
+	if gap := index - cb.bucket; gap > 0 {
+		for i := int64(1); i <= min(gap, circuitWindowBuckets); i++ {
+			cb.window[(cb.bucket+i)%circuitWindowBuckets] = circuitBucket{}
+		}
+		cb.bucket = index
+	}
+	return &cb.window[cb.bucket%circuitWindowBuckets]
+}
+
+// windowTripped reports whether the failures in the window reach the ratio
+func (cb *CircuitBreaker) windowTripped() bool {
+	requests, failures := 0, 0
+	for _, b := range cb.window {
+		requests += b.requests
+		failures += b.failures
 	}
+	return requests >= cb.settings.MinRequests &&
+		float64(failures) >= cb.settings.FailureRatio*float64(requests)
 }
 
 // setState sets the circuit breaker state
@@ -109,6 +291,16 @@ func (cb *CircuitBreaker) setState(newState CircuitBreakerState) {
 	if cb.state != newState {
 		oldState := cb.state
 		cb.state = newState
+		cb.generation++
+		cb.failureCount = 0
+		cb.successCount = 0
+		cb.probes = 0
+		cb.window = [circuitWindowBuckets]circuitBucket{}
+
+		if cb.name != "" {
+			metrics.CircuitBreakerState.WithLabelValues(cb.name).Set(float64(newState))
+			metrics.CircuitBreakerTransitions.WithLabelValues(cb.name, newState.String()).Inc()
+		}
 		if cb.onStateChange != nil {
 			cb.onStateChange(oldState, newState)
 		}
@@ -121,4 +313,3 @@ func (cb *CircuitBreaker) State() CircuitBreakerState {
 	defer cb.mu.RUnlock()
 	return cb.state
 }
-
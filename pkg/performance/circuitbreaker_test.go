@@ -0,0 +1,160 @@
+package performance
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errUpstream = errors.New("upstream failed")
+
+func fail() error    { return errUpstream }
+func succeed() error { return nil }
+
+// withBreakerClock makes cb read the time from *now
+func withBreakerClock(cb *CircuitBreaker, now *time.Time) *CircuitBreaker {
+	cb.now = func() time.Time { return *now }
+	return cb
+}
+
+func TestCircuitBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	now := time.Unix(0, 0)
+	cb := withBreakerClock(NewCircuitBreaker(3, time.Second), &now)
+
+	var transitions []CircuitBreakerState
+	cb.OnStateChange(func(_, to CircuitBreakerState) { transitions = append(transitions, to) })
+
+	_ = cb.Call(fail)
+	_ = cb.Call(fail)
+	require.NoError(t, cb.Call(succeed), "a success resets the count")
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, errUpstream, cb.Call(fail))
+	}
+	assert.Equal(t, StateOpen, cb.State())
+	assert.ErrorIs(t, cb.Call(succeed), ErrCircuitOpen)
+
+	// After the reset timeout one probe closes it again
+	now = now.Add(time.Second)
+	require.NoError(t, cb.Call(succeed))
+	assert.Equal(t, StateClosed, cb.State())
+	assert.Equal(t, []CircuitBreakerState{StateOpen, StateHalfOpen, StateClosed}, transitions)
+}
+
+func TestCircuitBreakerHalfOpenProbes(t *testing.T) {
+	now := time.Unix(0, 0)
+	cb := withBreakerClock(NewNamedCircuitBreaker("test", CircuitBreakerSettings{
+		MaxFailures:       1,
+		ResetTimeout:      time.Second,
+		HalfOpenMaxProbes: 2,
+		SuccessThreshold:  3,
+	}), &now)
+
+	_ = cb.Call(fail)
+	now = now.Add(time.Second)
+
+	// Two probes run at once; a third is turned away
+	hold := make(chan struct{})
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		started := make(chan struct{})
+		go func() {
+			results <- cb.Call(func() error {
+				close(started)
+				<-hold
+				return nil
+			})
+		}()
+		<-started
+	}
+	err := cb.Call(succeed)
+	assert.ErrorIs(t, err, ErrTooManyProbes)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	close(hold)
+	require.NoError(t, <-results)
+	require.NoError(t, <-results)
+	assert.Equal(t, StateHalfOpen, cb.State(), "two of three successes")
+
+	require.NoError(t, cb.Call(succeed))
+	assert.Equal(t, StateClosed, cb.State())
+
+	// A failed probe reopens the circuit
+	_ = cb.Call(fail)
+	now = now.Add(time.Second)
+	require.NoError(t, cb.Call(succeed))
+	_ = cb.Call(fail)
+	assert.Equal(t, StateOpen, cb.State())
+}
+
+func TestCircuitBreakerFailureRatio(t *testing.T) {
+	now := time.Unix(0, 0)
+	cb := withBreakerClock(NewNamedCircuitBreaker("", CircuitBreakerSettings{
+		ResetTimeout: time.Second,
+		FailureRatio: 0.5,
+		Window:       10 * time.Second,
+		MinRequests:  10,
+	}), &now)
+
+	// Alternating calls never fail three times in a row, but half of them fail
+	for i := 0; i < 8; i++ {
+		if i%2 == 0 {
+			_ = cb.Call(fail)
+		} else {
+			_ = cb.Call(succeed)
+		}
+	}
+	assert.Equal(t, StateClosed, cb.State(), "too few requests to judge")
+	_ = cb.Call(succeed)
+	_ = cb.Call(fail)
+	assert.Equal(t, StateOpen, cb.State())
+
+	// Failures age out of the window
+	now = now.Add(time.Second)
+	require.NoError(t, cb.Call(succeed))
+	for i := 0; i < 9; i++ {
+		_ = cb.Call(fail)
+	}
+	now = now.Add(11 * time.Second)
+	for i := 0; i < 9; i++ {
+		require.NoError(t, cb.Call(succeed))
+	}
+	_ = cb.Call(fail)
+	assert.Equal(t, StateClosed, cb.State())
+}
+
+func TestCircuitBreakerAllow(t *testing.T) {
+	now := time.Unix(0, 0)
+	cb := withBreakerClock(NewCircuitBreaker(2, 10*time.Second), &now)
+
+	// Ignored outcomes count neither way
+	for _, outcome := range []LimitOutcome{OutcomeDropped, OutcomeIgnored, OutcomeDropped} {
+		done, err := cb.Allow()
+		require.NoError(t, err)
+		done(outcome)
+	}
+	assert.Equal(t, StateOpen, cb.State())
+
+	now = now.Add(4 * time.Second)
+	_, err := cb.Allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 6*time.Second, cb.RetryAfter())
+
+	// An ignored probe frees its slot for the next
+	now = now.Add(6 * time.Second)
+	done, err := cb.Allow()
+	require.NoError(t, err)
+	assert.Zero(t, cb.RetryAfter())
+	done(OutcomeIgnored)
+	done, err = cb.Allow()
+	require.NoError(t, err)
+	done(OutcomeSuccess)
+	assert.Equal(t, StateClosed, cb.State())
+
+	var disabled *CircuitBreaker
+	done, err = disabled.Allow()
+	require.NoError(t, err)
+	done(OutcomeDropped)
+}
@@ -0,0 +1,205 @@
+package performance
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/metrics"
+)
+
+// retryBudgetWindow is how far back a retry budget counts calls
+const retryBudgetWindow = 10 * time.Second
+
+// RetryPolicy is how Retry repeats a failing call
+type RetryPolicy struct {
+	Name        string        // Caller label on metrics; empty exports none
+	MaxAttempts int           // Including the first; 1 or less never retries
+	BaseDelay   time.Duration // Upper bound of the delay before the first retry, doubling with each retry
+	MaxDelay    time.Duration // Caps the doubling; 0 leaves it uncapped
+	Retryable   func(error) bool
+	Budget      *RetryBudget // Shared limit on retries; nil allows every retry
+	OnRetry     func(attempt int, err error)
+}
+
+// NewRetryPolicy creates the policy described by cfg, drawing on budget
+func NewRetryPolicy(name string, cfg config.RetryConfig, budget *RetryBudget) RetryPolicy {
+	return RetryPolicy{
+		Name:        name,
+		MaxAttempts: cfg.MaxAttempts,
+		BaseDelay:   cfg.BaseDelay,
+		MaxDelay:    cfg.MaxDelay,
+		Budget:      budget,
+	}
+}
+
+// Retry calls fn until it succeeds, up to policy.MaxAttempts times, and
+// returns the last error. attempt counts from 0. Errors are retried unless
+// marked Permanent, rejected by policy.Retryable, caused by ctx ending, or
+// the budget is spent. Each retry waits a random delay up to the policy's
+// exponential backoff ("full jitter"), so callers failing together do not
+// retry together.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(attempt int) error) error {
+	policy.Budget.request()
+
+	for attempt := 0; ; attempt++ {
+		err := fn(attempt)
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if attempt+1 >= policy.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+		if policy.Retryable != nil && !policy.Retryable(err) {
+			return err
+		}
+		if !policy.Budget.withdraw() {
+			policy.record("budget_exhausted")
+			return err
+		}
+
+		policy.record("retried")
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt+1, err)
+		}
+		if !Sleep(ctx, policy.Delay(attempt+1)) {
+			return err
+		}
+	}
+}
+
+// Delay returns a random wait before retry n, counting from 1, between zero
+// and BaseDelay doubled n-1 times, capped at MaxDelay
+func (p RetryPolicy) Delay(n int) time.Duration {
+	ceiling := p.BaseDelay << min(n-1, 30)
+	if ceiling < p.BaseDelay || (p.MaxDelay > 0 && ceiling > p.MaxDelay) {
+		ceiling = max(p.MaxDelay, p.BaseDelay)
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling + 1)
+}
+
+// record counts a retry decision
+func (p RetryPolicy) record(result string) {
+	if p.Name != "" {
+		metrics.Retries.WithLabelValues(p.Name, result).Inc()
+	}
+}
+
+// Sleep waits for d, returning false if ctx ends first
+func Sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// permanentError marks an error Retry must not retry
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying; Retry returns err itself
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// RetryBudget limits retries across every caller sharing it to a ratio of
+// their first attempts over the last ten seconds, plus a floor so a quiet
+// service can still retry. When a dependency fails outright, retries then add
+// a bounded share of load instead of multiplying it. It is safe for
+// concurrent use, and a nil RetryBudget allows every retry.
+type RetryBudget struct {
+	ratio  float64
+	floor  int
+	now    func() time.Time
+	mu     sync.Mutex
+	slots  [10]budgetSlot // One per second of the window
+	second int64          // Unix second of the newest slot
+}
+
+// budgetSlot counts the calls of one second
+type budgetSlot struct {
+	requests int
+	retries  int
+}
+
+// NewRetryBudget creates the budget described by cfg. It returns nil when cfg
+// disables the budget.
+func NewRetryBudget(cfg config.RetryConfig) *RetryBudget {
+	if cfg.BudgetRatio <= 0 {
+		return nil
+	}
+	return &RetryBudget{
+		ratio: cfg.BudgetRatio,
+		floor: cfg.BudgetMinPerSecond * int(retryBudgetWindow/time.Second),
+		now:   time.Now,
+	}
+}
+
+// request counts a first attempt
+func (b *RetryBudget) request() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.current().requests++
+}
+
+// withdraw takes a retry from the budget if one is left
+func (b *RetryBudget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	slot := b.current()
+	requests, retries := 0, 0
+	for _, s := range b.slots {
+		requests += s.requests
+		retries += s.retries
+	}
+	if retries >= b.floor && float64(retries) >= b.ratio*float64(requests) {
+		return false
+	}
+	slot.retries++
+	return true
+}
+
+// current returns the slot of this second, clearing the slots that fell out
+// of the window. Callers hold mu.
+func (b *RetryBudget) current() *budgetSlot {
+	second := b.now().Unix()
+	if gap := second - b.second; gap > 0 {
+		for i := int64(1); i <= min(gap, int64(len(b.slots))); i++ {
+			b.slots[(b.second+i)%int64(len(b.slots))] = budgetSlot{}
+		}
+		b.second = second
+	}
+	return &b.slots[b.second%int64(len(b.slots))]
+}
@@ -0,0 +1,120 @@
+package performance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+var errFlaky = errors.New("flaky")
+
+func TestRetryStopsAtMaxAttempts(t *testing.T) {
+	var attempts []int
+	err := Retry(context.Background(), RetryPolicy{MaxAttempts: 3}, func(attempt int) error {
+		attempts = append(attempts, attempt)
+		return errFlaky
+	})
+	assert.Equal(t, errFlaky, err)
+	assert.Equal(t, []int{0, 1, 2}, attempts)
+
+	calls := 0
+	require.NoError(t, Retry(context.Background(), RetryPolicy{MaxAttempts: 3}, func(int) error {
+		calls++
+		if calls < 2 {
+			return errFlaky
+		}
+		return nil
+	}))
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetryClassifiesErrors(t *testing.T) {
+	fatal := errors.New("card declined")
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		Retryable:   func(err error) bool { return errors.Is(err, errFlaky) },
+	}
+
+	calls := 0
+	err := Retry(context.Background(), policy, func(int) error {
+		calls++
+		return fatal
+	})
+	assert.Equal(t, fatal, err)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	err = Retry(context.Background(), policy, func(int) error {
+		calls++
+		return Permanent(errFlaky)
+	})
+	assert.Equal(t, errFlaky, err, "Permanent is unwrapped")
+	assert.Equal(t, 1, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	err = Retry(ctx, RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour}, func(int) error {
+		calls++
+		cancel()
+		return errFlaky
+	})
+	assert.Equal(t, errFlaky, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryDelayIsCappedFullJitter(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		assert.LessOrEqual(t, policy.Delay(1), 10*time.Millisecond)
+		assert.LessOrEqual(t, policy.Delay(3), 40*time.Millisecond)
+		assert.LessOrEqual(t, policy.Delay(10), 50*time.Millisecond)
+		assert.GreaterOrEqual(t, policy.Delay(10), time.Duration(0))
+	}
+	assert.LessOrEqual(t, RetryPolicy{BaseDelay: time.Second}.Delay(100), time.Second<<30)
+	assert.Zero(t, RetryPolicy{}.Delay(1))
+}
+
+func TestRetryBudget(t *testing.T) {
+	now := time.Unix(1000, 0)
+	budget := NewRetryBudget(config.RetryConfig{BudgetRatio: 0.1, BudgetMinPerSecond: 1})
+	budget.now = func() time.Time { return now }
+
+	// The floor allows 10 retries in the window without traffic
+	for i := 0; i < 10; i++ {
+		require.True(t, budget.withdraw(), "retry %d", i)
+	}
+	assert.False(t, budget.withdraw())
+
+	// 199 first attempts earn 20 retries in all, rounding up
+	for i := 0; i < 199; i++ {
+		budget.request()
+	}
+	for i := 0; i < 10; i++ {
+		require.True(t, budget.withdraw())
+	}
+	assert.False(t, budget.withdraw())
+
+	// Retry's own first attempt makes 200, which earns no more, so it returns
+	// the last error
+	calls := 0
+	err := Retry(context.Background(), RetryPolicy{MaxAttempts: 5, Budget: budget}, func(int) error {
+		calls++
+		return errFlaky
+	})
+	assert.Equal(t, errFlaky, err)
+	assert.Equal(t, 1, calls)
+
+	// Calls leave the window after ten seconds
+	now = now.Add(10 * time.Second)
+	assert.True(t, budget.withdraw())
+
+	assert.Nil(t, NewRetryBudget(config.RetryConfig{}))
+	var disabled *RetryBudget
+	assert.True(t, disabled.withdraw())
+}
@@ -0,0 +1,176 @@
+package performance
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/metrics"
+)
+
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// Token bucket modes
+const (
+	LimitWait   = "wait"   // Wait for a token
+	LimitReject = "reject" // Fail at once without a token
+)
+
+// Dependencies with their own outbound rate limit
+const (
+	LimitProvider = "provider"
+	LimitSMS      = "sms"
+)
+
+// TokenBucket limits calls to a steady rate, allowing bursts of up to burst
+// calls after a quiet spell. Unlike the HTTP rate limiter it keeps its state
+// in the process, so each replica gets its own budget. A nil TokenBucket
+// allows every call.
+type TokenBucket struct {
+	name    string // Dependency label on metrics; empty exports none
+	rate    float64
+	burst   float64
+	mode    string
+	maxWait time.Duration
+	now     func() time.Time
+
+	mu     sync.Mutex
+	tokens float64 // Negative while callers wait for reserved tokens
+	last   time.Time
+}
+
+// NewTokenBucket creates a token bucket of rate calls per second that rejects
+// calls beyond it. The bucket starts full.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	burst = max(burst, 1)
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		mode:   LimitReject,
+		now:    time.Now,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// NewDependencyLimiter creates the token bucket for a dependency, exporting
+// held back calls as outbound_rate_limited_total. It returns nil when cfg
+// disables the limit.
+func NewDependencyLimiter(name string, cfg config.TokenBucketConfig) *TokenBucket {
+	if cfg.Rate <= 0 {
+		return nil
+	}
+	b := NewTokenBucket(cfg.Rate, cfg.Burst)
+	b.name = name
+	b.mode = cfg.Mode
+	b.maxWait = cfg.MaxWait
+	return b
+}
+
+// Allow takes a token if one is available, without waiting
+func (b *TokenBucket) Allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	if b.tokens < 1 {
+		b.record("rejected")
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Wait takes a token, waiting for one if needed. It returns ErrRateLimited at
+// once when the wait would exceed maxWait or the context's deadline, or the
+// context's error if it ends while waiting.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	delay, err := b.reserve(ctx)
+	if err != nil || delay <= 0 {
+		return err
+	}
+	b.record("delayed")
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+// Do runs fn once a token is available, waiting or rejecting according to the
+// bucket's mode
+func (b *TokenBucket) Do(ctx context.Context, fn func() error) error {
+	if b != nil && b.mode == LimitReject {
+		if !b.Allow() {
+			return ErrRateLimited
+		}
+		return fn()
+	}
+
+	if err := b.Wait(ctx); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// reserve takes a token ahead of time and returns how long until it is due
+func (b *TokenBucket) reserve(ctx context.Context) (time.Duration, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, nil
+	}
+
+	delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if b.maxWait > 0 && delay > b.maxWait {
+		b.record("rejected")
+		return 0, ErrRateLimited
+	}
+	if deadline, ok := ctx.Deadline(); ok && b.now().Add(delay).After(deadline) {
+		b.record("rejected")
+		return 0, ErrRateLimited
+	}
+	b.tokens--
+	return delay, nil
+}
+
+// cancel returns a token reserved by a caller that gave up waiting
+func (b *TokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+1, b.burst)
+}
+
+// refill adds the tokens earned since the last call. Callers hold mu.
+func (b *TokenBucket) refill() {
+	now := b.now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*b.rate, b.burst)
+	}
+	b.last = now
+}
+
+// record counts a call held back by the bucket
+func (b *TokenBucket) record(result string) {
+	if b.name != "" {
+		metrics.OutboundRateLimited.WithLabelValues(b.name, result).Inc()
+	}
+}
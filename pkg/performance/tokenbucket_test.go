@@ -0,0 +1,111 @@
+package performance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// withClock makes b read the time from *now
+func withClock(b *TokenBucket, now *time.Time) *TokenBucket {
+	b.now = func() time.Time { return *now }
+	b.last = *now
+	return b
+}
+
+func TestTokenBucketBurstAndRefill(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := withClock(NewTokenBucket(10, 3), &now)
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, b.Allow(), "call %d is within the burst", i)
+	}
+	assert.False(t, b.Allow())
+
+	// One token every 100ms
+	now = now.Add(100 * time.Millisecond)
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+
+	// A long quiet spell refills no more than the burst
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.True(t, b.Allow())
+	}
+	assert.False(t, b.Allow())
+}
+
+func TestTokenBucketRejectMode(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := withClock(NewDependencyLimiter("test", config.TokenBucketConfig{Rate: 1, Burst: 1, Mode: LimitReject}), &now)
+
+	calls := 0
+	call := func() error {
+		calls++
+		return nil
+	}
+	require.NoError(t, b.Do(context.Background(), call))
+	assert.ErrorIs(t, b.Do(context.Background(), call), ErrRateLimited)
+	assert.Equal(t, 1, calls)
+}
+
+func TestTokenBucketWaitMode(t *testing.T) {
+	b := NewDependencyLimiter("test", config.TokenBucketConfig{Rate: 50, Burst: 1, Mode: LimitWait, MaxWait: time.Second})
+
+	// The second call waits about 20ms for its token
+	require.NoError(t, b.Do(context.Background(), func() error { return nil }))
+	start := time.Now()
+	require.NoError(t, b.Do(context.Background(), func() error { return nil }))
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+}
+
+func TestTokenBucketWaitLimits(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := withClock(NewDependencyLimiter("test", config.TokenBucketConfig{Rate: 1, Burst: 1, Mode: LimitWait, MaxWait: 500 * time.Millisecond}), &now)
+	require.True(t, b.Allow())
+
+	// The next token is a second away, beyond maxWait
+	assert.ErrorIs(t, b.Wait(context.Background()), ErrRateLimited)
+
+	// Beyond the context's deadline too
+	b.maxWait = 0
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(100*time.Millisecond))
+	defer cancel()
+	assert.ErrorIs(t, b.Wait(ctx), ErrRateLimited)
+
+	// Rejected calls reserve nothing, so the token is due on time
+	now = now.Add(time.Second)
+	assert.True(t, b.Allow())
+}
+
+func TestTokenBucketCancelledWaitReturnsToken(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := withClock(NewTokenBucket(1, 1), &now)
+	require.True(t, b.Allow())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, b.Wait(ctx), context.Canceled)
+
+	now = now.Add(time.Second)
+	assert.True(t, b.Allow())
+}
+
+func TestTokenBucketDisabled(t *testing.T) {
+	assert.Nil(t, NewDependencyLimiter("test", config.TokenBucketConfig{Mode: LimitWait}))
+
+	var b *TokenBucket
+	assert.True(t, b.Allow())
+	assert.NoError(t, b.Wait(context.Background()))
+	ran := false
+	require.NoError(t, b.Do(context.Background(), func() error {
+		ran = true
+		return nil
+	}))
+	assert.True(t, ran)
+}
@@ -2,16 +2,46 @@ package performance
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/onichange/pos-system/pkg/config"
+	apperrors "github.com/onichange/pos-system/pkg/errors"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+)
+
+var (
+	ErrWorkerPoolFull   = errors.New("worker pool queue is full")
+	ErrWorkerPoolClosed = errors.New("worker pool is shut down")
 )
 
-// WorkerPool manages a pool of workers for concurrent task processing
+// Task is a unit of background work. Its context ends when the task times out
+// or the pool stops waiting for it to drain.
+type Task func(ctx context.Context) error
+
+// job is a queued task with the context it was submitted under
+type job struct {
+	ctx  context.Context
+	task Task
+}
+
+// WorkerPool runs background tasks on a fixed number of goroutines, so work
+// started by requests stays bounded and is drained on shutdown. A panicking
+// task is reported and the worker carries on.
 type WorkerPool struct {
+	name      string // Pool label on metrics and panic reports; empty exports no metrics
 	workers   int
-	taskQueue chan func()
+	timeout   time.Duration // Per task; 0 leaves tasks unbounded
+	taskQueue chan job
 	wg        sync.WaitGroup
-	ctx       context.Context
+	ctx       context.Context // Ended when draining is cut short
 	cancel    context.CancelFunc
+	mu        sync.RWMutex
+	closed    bool
 }
 
 // NewWorkerPool creates a new worker pool
@@ -19,12 +49,21 @@ func NewWorkerPool(workers int, queueSize int) *WorkerPool {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &WorkerPool{
 		workers:   workers,
-		taskQueue: make(chan func(), queueSize),
+		taskQueue: make(chan job, queueSize),
 		ctx:       ctx,
 		cancel:    cancel,
 	}
 }
 
+// NewNamedWorkerPool creates the worker pool described by cfg, exporting its
+// state as worker_pool_* metrics
+func NewNamedWorkerPool(name string, cfg config.WorkerPoolConfig) *WorkerPool {
+	wp := NewWorkerPool(cfg.Workers, cfg.QueueSize)
+	wp.name = name
+	wp.timeout = cfg.TaskTimeout
+	return wp
+}
+
 // Start starts the worker pool
 func (wp *WorkerPool) Start() {
 	for i := 0; i < wp.workers; i++ {
@@ -33,36 +72,153 @@ func (wp *WorkerPool) Start() {
 	}
 }
 
-// worker is a single worker goroutine
+// worker is a single worker goroutine; it runs tasks until the queue is
+// closed and drained
 func (wp *WorkerPool) worker(id int) {
 	defer wp.wg.Done()
-	for {
-		select {
-		case task := <-wp.taskQueue:
-			if task != nil {
-				task()
-			}
-		case <-wp.ctx.Done():
-			return
+	for j := range wp.taskQueue {
+		wp.gauge(metrics.WorkerPoolQueued, -1)
+		if wp.ctx.Err() != nil {
+			wp.record("dropped", 0)
+			continue
 		}
+		wp.run(j)
+	}
+}
+
+// run runs one task under the pool's timeout
+func (wp *WorkerPool) run(j job) {
+	wp.gauge(metrics.WorkerPoolBusy, 1)
+	defer wp.gauge(metrics.WorkerPoolBusy, -1)
+
+	// The task keeps the values of the submitting context, such as its
+	// logger and trace, but not its cancellation
+	ctx, cancel := context.WithCancel(context.WithoutCancel(j.ctx))
+	defer cancel()
+	stop := context.AfterFunc(wp.ctx, cancel)
+	defer stop()
+	if wp.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, wp.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	returned := false
+	err := apperrors.Guard(ctx, wp.component(), func() error {
+		err := j.task(ctx)
+		returned = true
+		return err
+	})
+
+	result := "success"
+	switch {
+	case !returned:
+		result = "panicked"
+	case errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil:
+		result = "timeout"
+	case err != nil:
+		result = "failed"
+	}
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Background task in %s failed: %v", wp.component(), err)
 	}
+	wp.record(result, time.Since(start))
 }
 
-// Submit submits a task to the worker pool
+// Go queues task without waiting. It returns ErrWorkerPoolFull when the queue
+// is full and ErrWorkerPoolClosed once the pool is shutting down. The task
+// runs with ctx's values, but outlives its cancellation.
+func (wp *WorkerPool) Go(ctx context.Context, task Task) error {
+	wp.mu.RLock()
+	defer wp.mu.RUnlock()
+	if wp.closed {
+		return ErrWorkerPoolClosed
+	}
+
+	select {
+	case wp.taskQueue <- job{ctx: ctx, task: task}:
+		wp.gauge(metrics.WorkerPoolQueued, 1)
+		return nil
+	default:
+		wp.record("rejected", 0)
+		return ErrWorkerPoolFull
+	}
+}
+
+// Submit submits a task to the worker pool, waiting for room in the queue
 func (wp *WorkerPool) Submit(task func()) error {
+	wp.mu.RLock()
+	defer wp.mu.RUnlock()
+	if wp.closed {
+		return ErrWorkerPoolClosed
+	}
+
 	select {
-	case wp.taskQueue <- task:
+	case wp.taskQueue <- job{ctx: wp.ctx, task: func(context.Context) error { task(); return nil }}:
+		wp.gauge(metrics.WorkerPoolQueued, 1)
 		return nil
 	case <-wp.ctx.Done():
 		return wp.ctx.Err()
 	}
 }
 
-// Stop stops the worker pool gracefully
+// Shutdown stops accepting tasks and waits for queued and running tasks to
+// finish. If ctx ends first, running tasks are cancelled, the rest of the
+// queue is dropped, and ctx's error is returned once the workers exit.
+func (wp *WorkerPool) Shutdown(ctx context.Context) error {
+	wp.mu.Lock()
+	if !wp.closed {
+		wp.closed = true
+		close(wp.taskQueue)
+	}
+	wp.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		wp.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		wp.cancel()
+		return nil
+	case <-ctx.Done():
+		wp.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Stop stops the worker pool gracefully, draining every queued task
 func (wp *WorkerPool) Stop() {
-	close(wp.taskQueue)
-	wp.cancel()
-	wp.wg.Wait()
+	_ = wp.Shutdown(context.Background())
+}
+
+// component names the pool in panic reports and logs
+func (wp *WorkerPool) component() string {
+	if wp.name == "" {
+		return "worker-pool"
+	}
+	return "worker-pool:" + wp.name
+}
+
+// record counts a finished or refused task
+func (wp *WorkerPool) record(result string, duration time.Duration) {
+	if wp.name == "" {
+		return
+	}
+	metrics.WorkerPoolTasks.WithLabelValues(wp.name, result).Inc()
+	if duration > 0 {
+		metrics.WorkerPoolTaskDuration.WithLabelValues(wp.name).Observe(duration.Seconds())
+	}
+}
+
+// gauge moves one of the pool's gauges by delta
+func (wp *WorkerPool) gauge(g *prometheus.GaugeVec, delta float64) {
+	if wp.name != "" {
+		g.WithLabelValues(wp.name).Add(delta)
+	}
 }
 
 // FanOut distributes tasks to multiple workers
@@ -0,0 +1,103 @@
+package performance
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+func TestWorkerPoolDrainsOnShutdown(t *testing.T) {
+	wp := NewNamedWorkerPool("test", config.WorkerPoolConfig{Workers: 2, QueueSize: 10})
+	wp.Start()
+
+	var done atomic.Int32
+	for i := 0; i < 10; i++ {
+		require.NoError(t, wp.Go(context.Background(), func(context.Context) error {
+			time.Sleep(time.Millisecond)
+			done.Add(1)
+			return nil
+		}))
+	}
+
+	require.NoError(t, wp.Shutdown(context.Background()))
+	assert.Equal(t, int32(10), done.Load(), "queued tasks finish before Shutdown returns")
+	assert.ErrorIs(t, wp.Go(context.Background(), func(context.Context) error { return nil }), ErrWorkerPoolClosed)
+	assert.ErrorIs(t, wp.Submit(func() {}), ErrWorkerPoolClosed)
+}
+
+func TestWorkerPoolIsolatesTasks(t *testing.T) {
+	wp := NewNamedWorkerPool("test", config.WorkerPoolConfig{Workers: 1, QueueSize: 10, TaskTimeout: 10 * time.Millisecond})
+	wp.Start()
+
+	// A panic and a timeout do not stop the worker
+	require.NoError(t, wp.Go(context.Background(), func(context.Context) error { panic("boom") }))
+	var timedOut atomic.Bool
+	require.NoError(t, wp.Go(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		timedOut.Store(errors.Is(ctx.Err(), context.DeadlineExceeded))
+		return ctx.Err()
+	}))
+
+	// Tasks outlive the request that started them but keep its values
+	type key struct{}
+	reqCtx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "request"))
+	cancel()
+	var value atomic.Value
+	require.NoError(t, wp.Go(reqCtx, func(ctx context.Context) error {
+		value.Store(ctx.Value(key{}))
+		return ctx.Err()
+	}))
+
+	require.NoError(t, wp.Shutdown(context.Background()))
+	assert.True(t, timedOut.Load())
+	assert.Equal(t, "request", value.Load())
+}
+
+func TestWorkerPoolRejectsWhenFull(t *testing.T) {
+	wp := NewWorkerPool(1, 1)
+	wp.Start()
+
+	hold := make(chan struct{})
+	running := make(chan struct{})
+	require.NoError(t, wp.Go(context.Background(), func(context.Context) error {
+		close(running)
+		<-hold
+		return nil
+	}))
+	<-running
+
+	require.NoError(t, wp.Go(context.Background(), func(context.Context) error { return nil }))
+	assert.ErrorIs(t, wp.Go(context.Background(), func(context.Context) error { return nil }), ErrWorkerPoolFull)
+
+	close(hold)
+	require.NoError(t, wp.Shutdown(context.Background()))
+}
+
+func TestWorkerPoolShutdownCancelsStragglers(t *testing.T) {
+	wp := NewWorkerPool(1, 10)
+	wp.Start()
+
+	var cancelled, ran atomic.Bool
+	require.NoError(t, wp.Go(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		cancelled.Store(true)
+		return ctx.Err()
+	}))
+	require.NoError(t, wp.Go(context.Background(), func(context.Context) error {
+		ran.Store(true)
+		return nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, wp.Shutdown(ctx), context.DeadlineExceeded)
+	assert.True(t, cancelled.Load())
+	assert.False(t, ran.Load(), "tasks still queued are dropped")
+}
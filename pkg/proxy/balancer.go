@@ -0,0 +1,406 @@
+package proxy
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/metrics"
+)
+
+// Load balancing policies
+const (
+	RoundRobin       = "round-robin"
+	LeastConnections = "least-connections"
+)
+
+// Reasons an instance is ejected
+const (
+	EjectErrors      = "errors"
+	EjectLatency     = "latency"
+	EjectHealthCheck = "health_check"
+)
+
+// latencyWeight is the weight of each new sample in an instance's average latency
+const latencyWeight = 0.1
+
+// minSlowStartWeight is the share of its traffic an instance gets on return
+const minSlowStartWeight = 0.1
+
+// Target is one upstream instance
+type Target struct {
+	URL string
+
+	inFlight atomic.Int64
+
+	mu           sync.Mutex
+	failures     int           // Consecutive failed requests
+	ejectedUntil time.Time     // Out of rotation before this; kept afterwards to time the slow start
+	ejections    int           // Recent ejections, each lengthening the next
+	healthy      bool          // State last exported as proxy_upstream_healthy
+	latency      time.Duration // Moving average since the instance last returned
+	samples      int
+}
+
+// InFlight returns the number of requests currently sent to the target
+func (t *Target) InFlight() int64 {
+	return t.inFlight.Load()
+}
+
+// Latency returns the moving average latency and the requests it covers
+func (t *Target) Latency() (time.Duration, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.latency, t.samples
+}
+
+// Balancer picks the upstream instance for each request and detects outliers.
+// An instance failing FailureThreshold requests in a row, or averaging
+// LatencyFactor times the median latency of its peers, is ejected for
+// EjectionTime times its number of recent ejections, up to MaxEjectionTime.
+// No more than MaxEjectionPercent of the instances are ejected this way.
+// A returning instance's share of traffic ramps up over SlowStart. When every
+// instance is ejected, all of them are tried rather than failing the request
+// outright. It is safe for concurrent use.
+type Balancer struct {
+	service       string
+	policy        string
+	threshold     int
+	ejection      time.Duration
+	maxEjection   time.Duration
+	maxPercent    int
+	slowStart     time.Duration
+	latencyFactor float64
+	latencyMin    int
+	next          atomic.Uint64
+	now           func() time.Time
+	random        func() float64
+
+	mu      sync.RWMutex
+	targets []*Target
+}
+
+// NewBalancer creates a balancer over the given instance URLs
+func NewBalancer(service string, urls []string, cfg config.ProxyConfig) *Balancer {
+	b := &Balancer{
+		service:       service,
+		policy:        cfg.LoadBalancing,
+		threshold:     cfg.FailureThreshold,
+		ejection:      cfg.EjectionTime,
+		maxEjection:   max(cfg.MaxEjectionTime, cfg.EjectionTime),
+		maxPercent:    cfg.MaxEjectionPercent,
+		slowStart:     cfg.SlowStart,
+		latencyFactor: cfg.LatencyFactor,
+		latencyMin:    cfg.LatencyMinRequests,
+		now:           time.Now,
+		random:        rand.Float64,
+	}
+	b.SetTargets(urls)
+	return b
+}
+
+// SetTargets replaces the instance list, e.g. after service discovery.
+// Instances already known keep their health and in-flight counts.
+func (b *Balancer) SetTargets(urls []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	known := make(map[string]*Target, len(b.targets))
+	for _, t := range b.targets {
+		known[t.URL] = t
+	}
+
+	targets := make([]*Target, 0, len(urls))
+	for _, u := range urls {
+		t, ok := known[u]
+		if !ok {
+			t = &Target{URL: u, healthy: true}
+			metrics.UpstreamHealthy.WithLabelValues(b.service, u).Set(1)
+		}
+		delete(known, u)
+		targets = append(targets, t)
+	}
+	for u := range known {
+		metrics.UpstreamHealthy.DeleteLabelValues(b.service, u)
+	}
+	b.targets = targets
+}
+
+// Targets returns the current instances
+func (b *Balancer) Targets() []*Target {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]*Target(nil), b.targets...)
+}
+
+// Pick returns the instance for the next request, or nil without instances
+func (b *Balancer) Pick() *Target {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	now := b.now()
+	candidates := make([]*Target, 0, len(b.targets))
+	for _, t := range b.targets {
+		if !t.ejected(now) {
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = b.targets
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	if b.slowStart > 0 {
+		candidates = b.ramp(candidates, now)
+	}
+
+	start := int(b.next.Add(1) % uint64(len(candidates)))
+	if b.policy != LeastConnections {
+		return candidates[start]
+	}
+
+	// Scan from the round-robin position so ties are spread over the instances
+	best := candidates[start]
+	for i := 1; i < len(candidates); i++ {
+		t := candidates[(start+i)%len(candidates)]
+		if t.InFlight() < best.InFlight() {
+			best = t
+		}
+	}
+	return best
+}
+
+// ramp passes over instances in slow start with a chance that falls as their
+// window runs out, so they take a growing share of traffic
+func (b *Balancer) ramp(candidates []*Target, now time.Time) []*Target {
+	kept := make([]*Target, 0, len(candidates))
+	for _, t := range candidates {
+		if weight := t.weight(now, b.slowStart); weight >= 1 || b.random() < weight {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		return candidates
+	}
+	return kept
+}
+
+// weight is the share of its traffic t takes at now, given a slow start window
+func (t *Target) weight(now time.Time, window time.Duration) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.ejectedUntil.IsZero() || now.Before(t.ejectedUntil) {
+		return 1
+	}
+	elapsed := now.Sub(t.ejectedUntil)
+	if elapsed >= window {
+		return 1
+	}
+	return max(minSlowStartWeight, float64(elapsed)/float64(window))
+}
+
+// PickOther returns an instance other than t for a second copy of a request,
+// or nil when t is the only one in rotation
+func (b *Balancer) PickOther(t *Target) *Target {
+	for range b.Targets() {
+		if other := b.Pick(); other != t && other != nil {
+			return other
+		}
+	}
+	return nil
+}
+
+// Acquire counts a request sent to t; call the returned function when it completes
+func (b *Balancer) Acquire(t *Target) func() {
+	t.inFlight.Add(1)
+	return func() { t.inFlight.Add(-1) }
+}
+
+// Report records the outcome of a request to t that took latency. Transport
+// errors and 502, 503, and 504 responses count as failures; any other
+// response shows the instance is serving.
+func (b *Balancer) Report(t *Target, err error, status int, latency time.Duration) {
+	if err != nil || isUpstreamFailure(status) {
+		b.fail(t)
+		return
+	}
+	b.succeed(t, latency)
+}
+
+// fail counts a failure and ejects t once the threshold is reached
+func (b *Balancer) fail(t *Target) {
+	t.mu.Lock()
+	t.failures++
+	reached := t.failures >= b.threshold && !b.now().Before(t.ejectedUntil)
+	t.mu.Unlock()
+
+	if reached {
+		b.eject(t, EjectErrors)
+	}
+}
+
+// succeed resets the failure count and adds latency to the average. A request
+// finishing while t is ejected does not end the ejection.
+func (b *Balancer) succeed(t *Target, latency time.Duration) {
+	now := b.now()
+
+	t.mu.Lock()
+	t.failures = 0
+	if latency > 0 {
+		if t.samples == 0 {
+			t.latency = latency
+		} else {
+			t.latency += time.Duration(latencyWeight * float64(latency-t.latency))
+		}
+		t.samples++
+	}
+	judge := b.latencyFactor > 0 && t.samples >= b.latencyMin
+	if !t.healthy && !now.Before(t.ejectedUntil) {
+		t.healthy = true
+		metrics.UpstreamHealthy.WithLabelValues(b.service, t.URL).Set(1)
+	}
+	t.mu.Unlock()
+
+	if judge && b.slow(t) {
+		b.eject(t, EjectLatency)
+	}
+}
+
+// slow reports whether t averages more than latencyFactor times the median of
+// the other instances in rotation with enough requests to judge
+func (b *Balancer) slow(t *Target) bool {
+	now := b.now()
+	var peers []time.Duration
+	for _, other := range b.Targets() {
+		if other == t || other.ejected(now) {
+			continue
+		}
+		if latency, samples := other.Latency(); samples >= b.latencyMin {
+			peers = append(peers, latency)
+		}
+	}
+	if len(peers) == 0 {
+		return false
+	}
+
+	slices.Sort(peers)
+	median := peers[len(peers)/2]
+	if len(peers)%2 == 0 {
+		median = (peers[len(peers)/2-1] + median) / 2
+	}
+	latency, _ := t.Latency()
+	return float64(latency) > b.latencyFactor*float64(median)
+}
+
+// eject takes t out of rotation. Ejections for errors and latency are skipped
+// once MaxEjectionPercent of the instances are out; failed health checks
+// always eject.
+func (b *Balancer) eject(t *Target, reason string) {
+	if reason != EjectHealthCheck && !b.canEject(t) {
+		return
+	}
+
+	now := b.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if now.Before(t.ejectedUntil) {
+		return
+	}
+	// Forget earlier ejections once the instance has stayed in rotation a while
+	if !t.ejectedUntil.IsZero() && now.Sub(t.ejectedUntil) > b.maxEjection {
+		t.ejections = 0
+	}
+	t.ejections++
+	t.ejectedUntil = now.Add(min(b.ejection*time.Duration(t.ejections), b.maxEjection))
+	t.failures = 0
+	t.latency, t.samples = 0, 0
+	t.healthy = false
+
+	metrics.UpstreamHealthy.WithLabelValues(b.service, t.URL).Set(0)
+	metrics.UpstreamEjections.WithLabelValues(b.service, t.URL, reason).Inc()
+}
+
+// canEject reports whether ejecting t keeps within MaxEjectionPercent. One
+// instance may always be ejected.
+func (b *Balancer) canEject(t *Target) bool {
+	targets := b.Targets()
+	now := b.now()
+
+	ejected := 0
+	for _, other := range targets {
+		if other != t && other.ejected(now) {
+			ejected++
+		}
+	}
+	return ejected < max(1, len(targets)*b.maxPercent/100)
+}
+
+// restore returns t to rotation after a passed health check; its slow start
+// begins now
+func (b *Balancer) restore(t *Target) {
+	now := b.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.failures = 0
+	if now.Before(t.ejectedUntil) {
+		t.ejectedUntil = now
+	}
+	if !t.healthy {
+		t.healthy = true
+		metrics.UpstreamHealthy.WithLabelValues(b.service, t.URL).Set(1)
+	}
+}
+
+// ejected reports whether t is out of rotation at now
+func (t *Target) ejected(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return now.Before(t.ejectedUntil)
+}
+
+// CheckHealth requests path on every instance. Instances answering 2xx are
+// returned to rotation; the rest are ejected.
+func (b *Balancer) CheckHealth(ctx context.Context, client *http.Client, path string, timeout time.Duration) {
+	for _, t := range b.Targets() {
+		if b.probe(ctx, client, t.URL+path, timeout) {
+			b.restore(t)
+		} else {
+			b.eject(t, EjectHealthCheck)
+		}
+	}
+}
+
+// probe reports whether a GET of url answers 2xx within timeout
+func (b *Balancer) probe(ctx context.Context, client *http.Client, url string, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+// isUpstreamFailure reports whether status means the instance could not serve
+func isUpstreamFailure(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+func testProxyConfig(policy string) config.ProxyConfig {
+	return config.ProxyConfig{
+		LoadBalancing:      policy,
+		FailureThreshold:   2,
+		EjectionTime:       time.Minute,
+		HealthCheckPath:    "/health",
+		HealthCheckTimeout: time.Second,
+		Timeout:            time.Second,
+		ConnectTimeout:     time.Second,
+	}
+}
+
+func TestBalancerRoundRobin(t *testing.T) {
+	b := NewBalancer("test", []string{"http://a", "http://b", "http://c"}, testProxyConfig(RoundRobin))
+
+	counts := map[string]int{}
+	for i := 0; i < 9; i++ {
+		counts[b.Pick().URL]++
+	}
+	assert.Equal(t, map[string]int{"http://a": 3, "http://b": 3, "http://c": 3}, counts)
+}
+
+func TestBalancerLeastConnections(t *testing.T) {
+	b := NewBalancer("test", []string{"http://a", "http://b"}, testProxyConfig(LeastConnections))
+	targets := b.Targets()
+
+	release := b.Acquire(targets[0])
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "http://b", b.Pick().URL)
+	}
+	release()
+	assert.Equal(t, int64(0), targets[0].InFlight())
+}
+
+func TestBalancerEjectsAndRecovers(t *testing.T) {
+	now := time.Now()
+	b := NewBalancer("test", []string{"http://a", "http://b"}, testProxyConfig(RoundRobin))
+	b.now = func() time.Time { return now }
+	a := b.Targets()[0]
+
+	// One failure stays under the threshold; a success resets the count
+	b.Report(a, errors.New("connection refused"), 0, 0)
+	b.Report(a, nil, http.StatusNotFound, time.Millisecond)
+	b.Report(a, nil, http.StatusBadGateway, time.Millisecond)
+	assert.False(t, a.ejected(now))
+
+	b.Report(a, nil, http.StatusServiceUnavailable, time.Millisecond)
+	require.True(t, a.ejected(now))
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "http://b", b.Pick().URL)
+	}
+
+	now = now.Add(time.Minute)
+	assert.False(t, a.ejected(now))
+}
+
+func TestBalancerFailsOpen(t *testing.T) {
+	b := NewBalancer("test", []string{"http://a", "http://b"}, testProxyConfig(RoundRobin))
+	for _, target := range b.Targets() {
+		b.eject(target, EjectHealthCheck)
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		seen[b.Pick().URL] = true
+	}
+	assert.Len(t, seen, 2)
+	assert.Nil(t, NewBalancer("test", nil, testProxyConfig(RoundRobin)).Pick())
+}
+
+func TestBalancerSetTargetsKeepsState(t *testing.T) {
+	b := NewBalancer("test", []string{"http://a", "http://b"}, testProxyConfig(RoundRobin))
+	a := b.Targets()[0]
+	b.eject(a, EjectHealthCheck)
+
+	b.SetTargets([]string{"http://a", "http://c"})
+	targets := b.Targets()
+	require.Len(t, targets, 2)
+	assert.Same(t, a, targets[0])
+	assert.Equal(t, "http://c", targets[1].URL)
+}
+
+func TestBalancerCheckHealth(t *testing.T) {
+	var healthy atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer upstream.Close()
+
+	b := NewBalancer("test", []string{upstream.URL}, testProxyConfig(RoundRobin))
+	target := b.Targets()[0]
+
+	b.CheckHealth(context.Background(), upstream.Client(), "/health", time.Second)
+	assert.True(t, target.ejected(time.Now()))
+
+	healthy.Store(true)
+	b.CheckHealth(context.Background(), upstream.Client(), "/health", time.Second)
+	assert.False(t, target.ejected(time.Now()))
+}
+
+func TestServiceProxyBalancesInstances(t *testing.T) {
+	var hitsA, hitsB atomic.Int32
+	a := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hitsA.Add(1) }))
+	defer a.Close()
+	b := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hitsB.Add(1) }))
+	defer b.Close()
+
+	p := NewServiceProxy("order-service", a.URL+","+b.URL, testProxyConfig(RoundRobin))
+	defer p.Close()
+
+	app := fiber.New()
+	app.Get("/orders", p.Proxy)
+	for i := 0; i < 4; i++ {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/orders", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Equal(t, int32(2), hitsA.Load())
+	assert.Equal(t, int32(2), hitsB.Load())
+}
+
+func TestBalancerEjectsSlowOutlier(t *testing.T) {
+	cfg := testProxyConfig(RoundRobin)
+	cfg.LatencyFactor = 3
+	cfg.LatencyMinRequests = 5
+	cfg.MaxEjectionPercent = 50
+	b := NewBalancer("test", []string{"http://a", "http://b", "http://c"}, cfg)
+	targets := b.Targets()
+
+	for i := 0; i < 5; i++ {
+		b.Report(targets[0], nil, http.StatusOK, 10*time.Millisecond)
+		b.Report(targets[1], nil, http.StatusOK, 12*time.Millisecond)
+		b.Report(targets[2], nil, http.StatusOK, 25*time.Millisecond)
+	}
+	assert.False(t, targets[2].ejected(time.Now()), "within the factor of the median")
+
+	for i := 0; i < 30 && !targets[2].ejected(time.Now()); i++ {
+		b.Report(targets[2], nil, http.StatusOK, 200*time.Millisecond)
+	}
+	assert.True(t, targets[2].ejected(time.Now()))
+	_, samples := targets[2].Latency()
+	assert.Zero(t, samples, "the average restarts when the instance returns")
+}
+
+func TestBalancerEjectionBackoffAndCap(t *testing.T) {
+	now := time.Now()
+	cfg := testProxyConfig(RoundRobin)
+	cfg.FailureThreshold = 1
+	cfg.EjectionTime = time.Minute
+	cfg.MaxEjectionTime = 3 * time.Minute
+	cfg.MaxEjectionPercent = 50
+	b := NewBalancer("test", []string{"http://a", "http://b", "http://c", "http://d"}, cfg)
+	b.now = func() time.Time { return now }
+	targets := b.Targets()
+	a := targets[0]
+
+	// Each repeat ejection lasts longer, up to the maximum
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		b.Report(a, errors.New("reset"), 0, 0)
+		require.True(t, a.ejected(now))
+		assert.False(t, a.ejected(now.Add(want)))
+		assert.True(t, a.ejected(now.Add(want-time.Second)))
+		now = now.Add(want)
+	}
+
+	// Half of four instances may be ejected for errors; health checks are not capped
+	b.Report(targets[1], errors.New("reset"), 0, 0)
+	now = now.Add(-time.Second)
+	b.Report(targets[2], errors.New("reset"), 0, 0)
+	assert.False(t, targets[2].ejected(now))
+	b.eject(targets[3], EjectHealthCheck)
+	assert.True(t, targets[3].ejected(now))
+}
+
+func TestBalancerSlowStart(t *testing.T) {
+	now := time.Now()
+	cfg := testProxyConfig(RoundRobin)
+	cfg.SlowStart = 10 * time.Second
+	b := NewBalancer("test", []string{"http://a", "http://b"}, cfg)
+	b.now = func() time.Time { return now }
+	b.random = func() float64 { return 0.5 }
+	a := b.Targets()[0]
+
+	b.eject(a, EjectHealthCheck)
+	b.restore(a)
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "http://b", b.Pick().URL, "skipped early in the slow start")
+	}
+
+	now = now.Add(6 * time.Second)
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		seen[b.Pick().URL] = true
+	}
+	assert.True(t, seen["http://a"], "taking traffic past the midpoint")
+}
@@ -0,0 +1,204 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// Service discovery modes
+const (
+	DiscoveryStatic = "static"
+	DiscoveryDNS    = "dns"
+	DiscoveryConsul = "consul"
+)
+
+// errNoInstances is returned by a lookup that found no instances
+var errNoInstances = errors.New("no instances found")
+
+// Resolver finds the current instance URLs of a service
+type Resolver interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// NewResolver returns the resolver of a service URL list for cfg.Mode. Static
+// discovery uses the URLs as listed. DNS and Consul discovery treat each URL
+// as naming a service: its host is looked up, and every instance found is
+// reached with the URL's scheme and path.
+func NewResolver(baseURLs string, cfg config.DiscoveryConfig) Resolver {
+	urls := config.SplitURLs(baseURLs)
+	switch cfg.Mode {
+	case DiscoveryDNS:
+		return NewDNSResolver(urls)
+	case DiscoveryConsul:
+		return NewConsulResolver(urls, cfg.ConsulAddr, cfg.ConsulToken, cfg.ConsulDatacenter)
+	default:
+		return StaticResolver(urls)
+	}
+}
+
+// StaticResolver resolves to a fixed list of instance URLs
+type StaticResolver []string
+
+// Resolve returns the listed URLs
+func (r StaticResolver) Resolve(context.Context) ([]string, error) {
+	return append([]string(nil), r...), nil
+}
+
+// DNSResolver finds instances in DNS SRV records. Each service URL's host is
+// the full record name, such as http://_http._tcp.order-service.pos.internal.
+// Only records of the lowest priority are used, the others being backups;
+// weights are ignored since the balancer spreads requests evenly.
+type DNSResolver struct {
+	services []string
+	lookup   func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// NewDNSResolver creates a resolver of SRV records named by the service URLs
+func NewDNSResolver(services []string) *DNSResolver {
+	return &DNSResolver{services: services, lookup: net.DefaultResolver.LookupSRV}
+}
+
+// Resolve looks up every service URL's SRV records
+func (r *DNSResolver) Resolve(ctx context.Context) ([]string, error) {
+	var instances []string
+	for _, raw := range r.services {
+		service, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service URL %q: %w", raw, err)
+		}
+
+		_, records, err := r.lookup(ctx, "", "", service.Hostname())
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up %s: %w", service.Hostname(), err)
+		}
+		if len(records) == 0 {
+			continue
+		}
+
+		priority := records[0].Priority
+		for _, record := range records {
+			priority = min(priority, record.Priority)
+		}
+		for _, record := range records {
+			if record.Priority == priority {
+				instances = append(instances, instanceURL(service, strings.TrimSuffix(record.Target, "."), int(record.Port)))
+			}
+		}
+	}
+	if len(instances) == 0 {
+		return nil, errNoInstances
+	}
+	return instances, nil
+}
+
+// ConsulResolver finds the instances passing their health checks in a Consul
+// catalog. Each service URL's host is the registered service name, such as
+// http://order-service.
+type ConsulResolver struct {
+	services   []string
+	addr       string
+	token      string
+	datacenter string
+	client     *http.Client
+}
+
+// NewConsulResolver creates a resolver of the services registered with the
+// Consul agent at addr
+func NewConsulResolver(services []string, addr, token, datacenter string) *ConsulResolver {
+	return &ConsulResolver{
+		services:   services,
+		addr:       strings.TrimRight(addr, "/"),
+		token:      token,
+		datacenter: datacenter,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// consulEntry is one instance in a Consul health response
+type consulEntry struct {
+	Node struct {
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		Address string `json:"Address"`
+		Port    int    `json:"Port"`
+	} `json:"Service"`
+}
+
+// Resolve lists the healthy instances of every service URL
+func (r *ConsulResolver) Resolve(ctx context.Context) ([]string, error) {
+	var instances []string
+	for _, raw := range r.services {
+		service, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service URL %q: %w", raw, err)
+		}
+
+		entries, err := r.healthy(ctx, service.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			// Services registered without an address are reached at their node's
+			host := entry.Service.Address
+			if host == "" {
+				host = entry.Node.Address
+			}
+			instances = append(instances, instanceURL(service, host, entry.Service.Port))
+		}
+	}
+	if len(instances) == 0 {
+		return nil, errNoInstances
+	}
+	return instances, nil
+}
+
+// healthy requests the instances of name passing their health checks
+func (r *ConsulResolver) healthy(ctx context.Context, name string) ([]consulEntry, error) {
+	query := url.Values{"passing": {"true"}}
+	if r.datacenter != "" {
+		query.Set("dc", r.datacenter)
+	}
+	endpoint := r.addr + "/v1/health/service/" + url.PathEscape(name) + "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.token != "" {
+		req.Header.Set("X-Consul-Token", r.token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("consul request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("consul returned status %d for service %s", resp.StatusCode, name)
+	}
+
+	var entries []consulEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to decode consul response: %w", err)
+	}
+	return entries, nil
+}
+
+// instanceURL is the URL of an instance at host and port of the service
+func instanceURL(service *url.URL, host string, port int) string {
+	instance := *service
+	instance.Host = net.JoinHostPort(host, strconv.Itoa(port))
+	return strings.TrimRight(instance.String(), "/")
+}
@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+func TestDNSResolverUsesLowestPriority(t *testing.T) {
+	r := NewDNSResolver([]string{"http://_http._tcp.order.pos.internal"})
+	r.lookup = func(_ context.Context, _, _, name string) (string, []*net.SRV, error) {
+		require.Equal(t, "_http._tcp.order.pos.internal", name)
+		return "", []*net.SRV{
+			{Target: "order-1.pos.internal.", Port: 8081, Priority: 10},
+			{Target: "order-2.pos.internal.", Port: 8082, Priority: 10},
+			{Target: "backup.pos.internal.", Port: 8081, Priority: 20},
+		}, nil
+	}
+
+	urls, err := r.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://order-1.pos.internal:8081", "http://order-2.pos.internal:8082"}, urls)
+}
+
+func TestConsulResolverListsPassingInstances(t *testing.T) {
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/health/service/order-service", r.URL.Path)
+		assert.Equal(t, "true", r.URL.Query().Get("passing"))
+		assert.Equal(t, "dc2", r.URL.Query().Get("dc"))
+		assert.Equal(t, "secret", r.Header.Get("X-Consul-Token"))
+		w.Write([]byte(`[
+			{"Node": {"Address": "10.0.0.1"}, "Service": {"Address": "10.0.1.1", "Port": 8081}},
+			{"Node": {"Address": "10.0.0.2"}, "Service": {"Address": "", "Port": 8081}}
+		]`))
+	}))
+	defer consul.Close()
+
+	r := NewResolver("https://order-service/api", config.DiscoveryConfig{
+		Mode:             DiscoveryConsul,
+		ConsulAddr:       consul.URL,
+		ConsulToken:      "secret",
+		ConsulDatacenter: "dc2",
+	})
+	urls, err := r.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://10.0.1.1:8081/api", "https://10.0.0.2:8081/api"}, urls)
+}
+
+// fakeResolver answers lookups with whatever it was last given
+type fakeResolver struct {
+	urls []string
+	err  error
+}
+
+func (r *fakeResolver) Resolve(context.Context) ([]string, error) {
+	return r.urls, r.err
+}
+
+func TestServiceProxyReresolvesInstances(t *testing.T) {
+	p := NewServiceProxy("test", "http://a,http://b", testProxyConfig(RoundRobin))
+	defer p.Close()
+	require.Len(t, p.Balancer().Targets(), 2)
+	b := p.Balancer().Targets()[1]
+
+	// Instances still listed keep their state
+	resolver := &fakeResolver{urls: []string{"http://b", "http://c"}}
+	p.resolve(resolver, time.Second)
+	targets := p.Balancer().Targets()
+	require.Len(t, targets, 2)
+	assert.Same(t, b, targets[0])
+	assert.Equal(t, "http://c", targets[1].URL)
+
+	// A failed lookup keeps the instances already known
+	resolver.err = errors.New("lookup timed out")
+	p.resolve(resolver, time.Second)
+	assert.Equal(t, targets, p.Balancer().Targets())
+}
@@ -0,0 +1,172 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/middleware"
+	"github.com/onichange/pos-system/pkg/performance"
+)
+
+// Policy is how one request is sent upstream
+type Policy struct {
+	Timeout         time.Duration // Per attempt
+	ConnectTimeout  time.Duration
+	Retries         int
+	RetryOn         map[int]bool
+	RetryBackoff    time.Duration
+	RetryMaxBackoff time.Duration
+	HedgeAfter      time.Duration // 0 disables hedging
+}
+
+// routePolicy is the policy for requests under a path prefix
+type routePolicy struct {
+	path    string
+	methods map[string]bool // nil matches every method
+	policy  Policy
+}
+
+// policies resolves the policy of each request
+type policies struct {
+	base   Policy
+	routes []routePolicy // Longest path first
+}
+
+// newPolicies builds the proxy default and the route overrides from cfg
+func newPolicies(cfg config.ProxyConfig) *policies {
+	p := &policies{
+		base: Policy{
+			Timeout:         cfg.Timeout,
+			ConnectTimeout:  cfg.ConnectTimeout,
+			Retries:         cfg.Retries,
+			RetryOn:         statusSet(cfg.RetryOn),
+			RetryBackoff:    cfg.RetryBackoff,
+			RetryMaxBackoff: cfg.RetryMaxBackoff,
+			HedgeAfter:      cfg.HedgeAfter,
+		},
+	}
+
+	for _, route := range cfg.Routes {
+		policy := p.base
+		if route.Timeout > 0 {
+			policy.Timeout = route.Timeout
+		}
+		if route.ConnectTimeout > 0 {
+			policy.ConnectTimeout = route.ConnectTimeout
+		}
+		if route.Retries != nil {
+			policy.Retries = *route.Retries
+		}
+		if route.RetryOn != nil {
+			policy.RetryOn = statusSet(route.RetryOn)
+		}
+		if route.HedgeAfter > 0 {
+			policy.HedgeAfter = route.HedgeAfter
+		}
+
+		var methods map[string]bool
+		if len(route.Methods) > 0 {
+			methods = make(map[string]bool, len(route.Methods))
+			for _, method := range route.Methods {
+				methods[strings.ToUpper(method)] = true
+			}
+		}
+		p.routes = append(p.routes, routePolicy{path: route.Path, methods: methods, policy: policy})
+	}
+
+	sort.SliceStable(p.routes, func(i, j int) bool {
+		return len(p.routes[i].path) > len(p.routes[j].path)
+	})
+	return p
+}
+
+// forRequest returns the policy for a request
+func (p *policies) forRequest(method, path string) Policy {
+	for _, route := range p.routes {
+		if route.methods != nil && !route.methods[method] {
+			continue
+		}
+		if matchesPrefix(path, route.path) {
+			return route.policy
+		}
+	}
+	return p.base
+}
+
+// matchesPrefix reports whether path is prefix or lies below it, so
+// /api/v1/orders matches /api/v1/orders/42 but not /api/v1/orders-archive
+func matchesPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// retryable reports whether a request may be sent again without risking a
+// duplicate side effect
+func retryable(method string, header http.Header) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return header.Get(middleware.IdempotencyKeyHeader) != ""
+}
+
+// hedgeable reports whether a second copy of a request may be sent while the
+// first is still running. Only reads are hedged: the losing copy is cancelled,
+// but the upstream may already have acted on it.
+func hedgeable(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// retry returns how Retry repeats a request under p. A response with a
+// status in RetryOn fails its attempt with errRetryStatus.
+func (p Policy) retry(service string, budget *performance.RetryBudget) performance.RetryPolicy {
+	return performance.RetryPolicy{
+		Name:        "proxy:" + service,
+		MaxAttempts: p.Retries + 1,
+		BaseDelay:   p.RetryBackoff,
+		MaxDelay:    p.RetryMaxBackoff,
+		Budget:      budget,
+	}
+}
+
+// errRetryStatus fails an attempt answered with a status worth retrying; the
+// last such response is still passed on once retries run out
+var errRetryStatus = errors.New("upstream answered with a retryable status")
+
+// statusSet builds a lookup set of status codes
+func statusSet(codes []int) map[int]bool {
+	set := make(map[int]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return set
+}
+
+// connectTimeoutKey carries the dial timeout of a request to the transport
+type connectTimeoutKey struct{}
+
+// withConnectTimeout sets the dial timeout for requests made with ctx
+func withConnectTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, connectTimeoutKey{}, timeout)
+}
+
+// dialContext dials with the timeout set by withConnectTimeout, so one shared
+// transport serves routes with different connect timeouts
+func dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if timeout, ok := ctx.Value(connectTimeoutKey{}).(time.Duration); ok && timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
@@ -0,0 +1,248 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+func TestPoliciesForRequest(t *testing.T) {
+	none := 0
+	cfg := testProxyConfig(RoundRobin)
+	cfg.Timeout = 10 * time.Second
+	cfg.Retries = 1
+	cfg.RetryOn = []int{503}
+	cfg.Routes = []config.ProxyRoute{
+		{Path: "/api/v1/reports", Timeout: time.Minute},
+		{Path: "/api/v1/reports/daily", Methods: []string{"get"}, Retries: &none},
+		{Path: "/api/v1/payments", Methods: []string{"POST"}, Timeout: 30 * time.Second, Retries: &none},
+	}
+	p := newPolicies(cfg)
+
+	assert.Equal(t, 10*time.Second, p.forRequest("GET", "/api/v1/orders").Timeout)
+	assert.Equal(t, time.Minute, p.forRequest("GET", "/api/v1/reports/weekly").Timeout)
+
+	daily := p.forRequest("GET", "/api/v1/reports/daily")
+	assert.Equal(t, 10*time.Second, daily.Timeout, "overrides start from the proxy defaults")
+	assert.Equal(t, 0, daily.Retries)
+	assert.Equal(t, time.Minute, p.forRequest("DELETE", "/api/v1/reports/daily").Timeout)
+
+	assert.Equal(t, 30*time.Second, p.forRequest("POST", "/api/v1/payments").Timeout)
+	assert.Equal(t, 10*time.Second, p.forRequest("GET", "/api/v1/payments/42").Timeout)
+	assert.Equal(t, 10*time.Second, p.forRequest("POST", "/api/v1/payments-archive").Timeout)
+	assert.True(t, p.forRequest("GET", "/").RetryOn[503])
+}
+
+func TestProxyRetriesOnAnotherInstance(t *testing.T) {
+	var failing, healthy atomic.Int32
+	a := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failing.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer a.Close()
+	b := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		healthy.Add(1)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer b.Close()
+
+	cfg := testProxyConfig(RoundRobin)
+	cfg.Timeout = time.Second
+	cfg.Retries = 1
+	cfg.RetryOn = []int{503}
+	p := NewServiceProxy("order-service", a.URL+","+b.URL, cfg)
+	defer p.Close()
+
+	app := fiber.New()
+	app.Get("/orders", p.Proxy)
+
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/orders", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Equal(t, int32(2), healthy.Load())
+
+	// A POST without an Idempotency-Key is not sent twice
+	single := NewServiceProxy("order-service", a.URL, cfg)
+	defer single.Close()
+	app = fiber.New()
+	app.Post("/orders", single.Proxy)
+
+	failing.Store(0)
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("{}")))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), failing.Load())
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("{}"))
+	req.Header.Set("Idempotency-Key", "order-1")
+	_, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), failing.Load())
+}
+
+func TestProxyRouteTimeout(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/reports" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer upstream.Close()
+
+	cfg := testProxyConfig(RoundRobin)
+	cfg.Timeout = 50 * time.Millisecond
+	cfg.ConnectTimeout = time.Second
+	cfg.Routes = []config.ProxyRoute{{Path: "/reports", Timeout: time.Second}}
+	p := NewServiceProxy("report-service", upstream.URL, cfg)
+	defer p.Close()
+
+	app := fiber.New()
+	app.Get("/*", p.Proxy)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/reports", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	upstreamSlow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer upstreamSlow.Close()
+	slow := NewServiceProxy("order-service", upstreamSlow.URL, cfg)
+	defer slow.Close()
+
+	app = fiber.New()
+	app.Get("/*", slow.Proxy)
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/orders", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+}
+
+func TestProxyHedgesSlowInstance(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(500 * time.Millisecond):
+		}
+		_, _ = w.Write([]byte("slow"))
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("fast"))
+	}))
+	defer fast.Close()
+
+	cfg := testProxyConfig(RoundRobin)
+	cfg.FailureThreshold = 1
+	cfg.Routes = []config.ProxyRoute{{Path: "/stores", Methods: []string{"GET"}, HedgeAfter: 20 * time.Millisecond}}
+	p := NewServiceProxy("store-service", slow.URL+","+fast.URL, cfg)
+	defer p.Close()
+
+	app := fiber.New()
+	app.Get("/stores", p.Proxy)
+
+	// Whichever instance goes first, the fast one answers
+	for i := 0; i < 2; i++ {
+		start := time.Now()
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/stores", nil), -1)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "fast", string(body))
+		assert.Less(t, time.Since(start), 400*time.Millisecond)
+	}
+
+	// The cancelled copy is not counted against the slow instance
+	for _, target := range p.Balancer().Targets() {
+		assert.False(t, target.ejected(time.Now()), target.URL)
+	}
+	assert.False(t, hedgeable(http.MethodPost))
+}
+
+func TestProxyShedsLoadPastAdaptiveLimit(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+	}))
+	defer upstream.Close()
+
+	cfg := testProxyConfig(RoundRobin)
+	cfg.Retries = 1
+	cfg.AdaptiveConcurrency = config.AdaptiveConcurrencyConfig{
+		Enabled: true, InitialLimit: 1, MinLimit: 1, MaxLimit: 1, Tolerance: 2, Backoff: 0.9,
+	}
+	p := NewServiceProxy("order-service", upstream.URL, cfg)
+	defer p.Close()
+
+	app := fiber.New()
+	app.Get("/orders", p.Proxy)
+
+	first := make(chan int)
+	go func() {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/orders", nil), -1)
+		if err != nil {
+			first <- 0
+			return
+		}
+		first <- resp.StatusCode
+	}()
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	// The second request is turned away without reaching the service or retrying
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/orders", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-first)
+}
+
+func TestProxyCircuitBreakerFailsFast(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer upstream.Close()
+
+	cfg := testProxyConfig(RoundRobin)
+	cfg.FailureThreshold = 100 // Keep the only instance in rotation
+	cfg.CircuitBreaker = config.CircuitBreakerConfig{
+		Enabled:      true,
+		FailureRatio: 0.5,
+		Window:       time.Minute,
+		MinRequests:  3,
+		ResetTimeout: 30 * time.Second,
+	}
+	p := NewServiceProxy("order-service", upstream.URL, cfg)
+	defer p.Close()
+
+	app := fiber.New()
+	app.Get("/orders", p.Proxy)
+
+	for i := 0; i < 3; i++ {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/orders", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	}
+
+	// The circuit is open: the service is not called until the reset timeout
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/orders", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "30", resp.Header.Get(fiber.HeaderRetryAfter))
+	assert.Equal(t, int32(3), calls.Load())
+}
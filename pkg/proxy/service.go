@@ -2,40 +2,269 @@ package proxy
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
+	"math"
+	"net"
 	"net/http"
 	"net/url"
-	"strings"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/metrics"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/tenant"
 )
 
 // ServiceProxy handles proxying requests to microservices
 type ServiceProxy struct {
-	client  *http.Client
-	baseURL string
+	client   *http.Client
+	balancer *Balancer
+	policies *policies
+	service  string // Upstream name recorded as peer.service on spans
+	budget   *performance.RetryBudget
+	limiter  *performance.AdaptiveLimiter // Adapts in-flight requests to the service's health; nil leaves them unlimited
+	breaker  *performance.CircuitBreaker  // Fails requests fast while the service is failing; nil never does
+	stop     chan struct{}
+	wg       sync.WaitGroup // Background health checks and re-resolution
 }
 
-// NewServiceProxy creates a new service proxy
-func NewServiceProxy(baseURL string) *ServiceProxy {
-	return &ServiceProxy{
+// NewServiceProxy creates a proxy to service. baseURLs lists its instances,
+// separated by commas, or with DNS or Consul discovery the names to look
+// them up by; requests are spread over them by cfg.LoadBalancing. With a
+// health check interval the instances are also probed in the background, and
+// with a refresh interval discovered instances are looked up again, until
+// Close is called.
+func NewServiceProxy(service, baseURLs string, cfg config.ProxyConfig) *ServiceProxy {
+	p := &ServiceProxy{
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			// Timeouts are set per request from the route's policy
 			Transport: &http.Transport{
+				DialContext:         dialContext(&net.Dialer{KeepAlive: 30 * time.Second}),
 				MaxIdleConns:        100,
 				MaxIdleConnsPerHost: 10,
 				IdleConnTimeout:     90 * time.Second,
 			},
 		},
-		baseURL: baseURL,
+		balancer: NewBalancer(service, nil, cfg),
+		policies: newPolicies(cfg),
+		service:  service,
+		limiter:  performance.NewAdaptiveLimiter(service, cfg.AdaptiveConcurrency),
+		breaker:  newBreaker(service, cfg.CircuitBreaker),
+		stop:     make(chan struct{}),
+	}
+
+	resolver := NewResolver(baseURLs, cfg.Discovery)
+	p.resolve(resolver, cfg.Discovery.ResolveTimeout)
+	if _, static := resolver.(StaticResolver); !static && cfg.Discovery.RefreshInterval > 0 {
+		p.wg.Add(1)
+		go p.refresh(resolver, cfg.Discovery.RefreshInterval, cfg.Discovery.ResolveTimeout)
+	}
+
+	if cfg.HealthCheckInterval > 0 {
+		p.wg.Add(1)
+		go p.checkHealth(cfg.HealthCheckInterval, cfg.HealthCheckPath, cfg.HealthCheckTimeout)
+	}
+	return p
+}
+
+// newBreaker creates the circuit breaker of service, or nil when disabled
+func newBreaker(service string, cfg config.CircuitBreakerConfig) *performance.CircuitBreaker {
+	if !cfg.Enabled {
+		return nil
+	}
+	return performance.NewNamedCircuitBreaker("proxy:"+service, performance.CircuitBreakerSettings{
+		ResetTimeout:      cfg.ResetTimeout,
+		HalfOpenMaxProbes: cfg.HalfOpenMaxProbes,
+		SuccessThreshold:  cfg.SuccessThreshold,
+		FailureRatio:      cfg.FailureRatio,
+		Window:            cfg.Window,
+		MinRequests:       cfg.MinRequests,
+	})
+}
+
+// Balancer returns the balancer choosing the proxy's upstream instances
+func (p *ServiceProxy) Balancer() *Balancer {
+	return p.balancer
+}
+
+// UseRetryBudget limits the proxy's retries by budget, which other callers
+// in the process may share
+func (p *ServiceProxy) UseRetryBudget(budget *performance.RetryBudget) {
+	p.budget = budget
+}
+
+// Close stops background health checks and re-resolution
+func (p *ServiceProxy) Close() {
+	select {
+	case <-p.stop:
+	default:
+		close(p.stop)
+	}
+	p.wg.Wait()
+}
+
+// resolve looks the instances up and hands them to the balancer. A failed or
+// empty lookup keeps the instances already known rather than leaving none.
+func (p *ServiceProxy) resolve(resolver Resolver, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	urls, err := resolver.Resolve(ctx)
+	if err != nil {
+		metrics.UpstreamDiscoveryFailures.WithLabelValues(p.service).Inc()
+		logger.FromContext(ctx).Warnf("Failed to resolve %s instances: %v", p.service, err)
+		return
+	}
+	p.balancer.SetTargets(urls)
+}
+
+// refresh looks the instances up again every interval until Close is called
+func (p *ServiceProxy) refresh(resolver Resolver, interval, timeout time.Duration) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.resolve(resolver, timeout)
+		}
+	}
+}
+
+// checkHealth probes the instances every interval until Close is called
+func (p *ServiceProxy) checkHealth(interval time.Duration, path string, timeout time.Duration) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.balancer.CheckHealth(context.Background(), p.client, path, timeout)
+		}
 	}
 }
 
-// Proxy proxies the request to the target service
+// upstreamResponse is a response read in full from an instance
+type upstreamResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// outbound is the request sent upstream, copied out of the fiber context so
+// hedged attempts can run concurrently
+type outbound struct {
+	ctx    context.Context
+	method string
+	path   string
+	query  string
+	body   []byte
+	header http.Header
+}
+
+// skipHeaders are the request headers not copied upstream
+var skipHeaders = map[string]bool{
+	"Host":                true,
+	"Content-Length":      true,
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Connection":    true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+}
+
+// Proxy proxies the request to the target service. Failed attempts are retried
+// on another instance as the route's policy allows. While the service's
+// circuit is open requests fail fast with 503 and Retry-After. WebSocket
+// upgrades and server-sent event streams are passed through as they arrive.
 func (p *ServiceProxy) Proxy(c *fiber.Ctx) error {
-	// Build target URL
-	targetURL := p.baseURL + c.Path()
+	out := p.outbound(c)
+	policy := p.policies.forRequest(out.method, out.path)
+	if isWebSocketUpgrade(c) {
+		return p.proxyWebSocket(c, out, policy)
+	}
+	if acceptsEventStream(out.header) {
+		return p.proxyStream(c, out, policy)
+	}
+
+	retry := policy.retry(p.service, p.budget)
+	if !retryable(out.method, out.header) {
+		retry.MaxAttempts = 1
+	}
+
+	var resp *upstreamResponse
+	err := performance.Retry(out.ctx, retry, func(attempt int) error {
+		var err error
+		resp, err = p.send(out, policy, attempt)
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) && fiberErr.Code == fiber.StatusServiceUnavailable ||
+			errors.Is(err, performance.ErrCircuitOpen) {
+			return performance.Permanent(err) // No instances to retry on, or the service is overloaded or failing
+		}
+		if err == nil && policy.RetryOn[resp.status] {
+			return errRetryStatus
+		}
+		return err
+	})
+	if errors.Is(err, performance.ErrCircuitOpen) {
+		return p.circuitOpen(c)
+	}
+	if err != nil && !errors.Is(err, errRetryStatus) {
+		return err
+	}
+
+	// Copy response headers
+	for key, values := range resp.header {
+		for _, value := range values {
+			c.Set(key, value)
+		}
+	}
+
+	// Set status and body
+	c.Status(resp.status)
+	return c.Send(resp.body)
+}
+
+// circuitOpen answers a request turned away by the open circuit, telling the
+// client when the service will be tried again
+func (p *ServiceProxy) circuitOpen(c *fiber.Ctx) error {
+	seconds := max(int64(math.Ceil(p.breaker.RetryAfter().Seconds())), 1)
+	c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(seconds, 10))
+	return fiber.NewError(fiber.StatusServiceUnavailable, "Service unavailable, try again")
+}
+
+// outbound builds the request sent upstream
+func (p *ServiceProxy) outbound(c *fiber.Ctx) *outbound {
+	out := &outbound{
+		ctx:    c.UserContext(),
+		method: c.Method(),
+		path:   c.Path(),
+		body:   c.Body(),
+		header: make(http.Header),
+	}
 
 	// Add query parameters
 	queries := c.Queries()
@@ -44,57 +273,208 @@ func (p *ServiceProxy) Proxy(c *fiber.Ctx) error {
 		for key, value := range queries {
 			values.Set(key, value)
 		}
-		if queryString := values.Encode(); queryString != "" {
-			targetURL += "?" + queryString
+		out.query = values.Encode()
+	}
+
+	// Copy headers except Host, Content-Length, and hop-by-hop headers, which
+	// describe the client's connection rather than the request
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		name := http.CanonicalHeaderKey(string(key))
+		if !skipHeaders[name] {
+			out.header.Add(name, string(value))
 		}
+	})
+
+	// Forward user information
+	if userID := c.Locals("user_id"); userID != nil {
+		out.header.Set("X-User-ID", userID.(string))
 	}
 
-	// Create request
-	req, err := http.NewRequest(c.Method(), targetURL, bytes.NewReader(c.Body()))
-	if err != nil {
-		return fiber.NewError(fiber.StatusBadGateway, "Failed to create request")
+	// Forward the request ID, which may have been generated by this gateway
+	if requestID, ok := c.Locals("request_id").(string); ok {
+		out.header.Set(logger.RequestIDHeader, requestID)
 	}
 
-	// Copy headers (except Host and some Fiber-specific headers)
-	skipHeaders := map[string]bool{
-		"Host":           true,
-		"Connection":     true,
-		"Content-Length": true,
+	// Forward the resolved tenant so services scope their queries to it
+	if tenantID := tenant.IDFromContext(out.ctx); tenantID != "" {
+		out.header.Set(tenant.Header, tenantID)
 	}
 
-	c.Request().Header.VisitAll(func(key, value []byte) {
-		keyStr := strings.ToLower(string(key))
-		if !skipHeaders[keyStr] {
-			req.Header.Add(string(key), string(value))
+	return out
+}
+
+// send makes one attempt, hedged when the policy allows
+func (p *ServiceProxy) send(out *outbound, policy Policy, attempt int) (*upstreamResponse, error) {
+	target := p.balancer.Pick()
+	if target == nil {
+		return nil, fiber.NewError(fiber.StatusServiceUnavailable, "No upstream instances available")
+	}
+	if policy.HedgeAfter > 0 && hedgeable(out.method) {
+		return p.hedge(out, policy, target, attempt)
+	}
+	return p.attempt(out.ctx, target, out, policy, attempt, false)
+}
+
+// hedgeResult is the outcome of one copy of a hedged request
+type hedgeResult struct {
+	resp   *upstreamResponse
+	err    error
+	hedged bool
+}
+
+// hedge sends the request to target and, if no answer has arrived after the
+// hedge delay, a copy to another instance. The first usable answer wins and
+// the other copy is cancelled.
+func (p *ServiceProxy) hedge(out *outbound, policy Policy, target *Target, attempt int) (*upstreamResponse, error) {
+	ctx, cancel := context.WithCancel(out.ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, 2)
+	run := func(target *Target, hedged bool) {
+		resp, err := p.attempt(ctx, target, out, policy, attempt, hedged)
+		results <- hedgeResult{resp: resp, err: err, hedged: hedged}
+	}
+	go run(target, false)
+
+	timer := time.NewTimer(policy.HedgeAfter)
+	defer timer.Stop()
+
+	hedged := false
+	select {
+	case result := <-results:
+		return result.resp, result.err
+	case <-timer.C:
+		if other := p.balancer.PickOther(target); other != nil {
+			go run(other, true)
+			hedged = true
 		}
-	})
+	}
 
-	// Forward user information
-	if userID := c.Locals("user_id"); userID != nil {
-		req.Header.Set("X-User-ID", userID.(string))
+	// Take the first usable answer, or the second copy's if the first failed
+	result := <-results
+	if hedged && (result.err != nil || policy.RetryOn[result.resp.status]) {
+		result = <-results
+	}
+	if hedged {
+		winner := "primary"
+		if result.hedged {
+			winner = "hedge"
+		}
+		metrics.ProxyHedgedRequests.WithLabelValues(p.service, winner).Inc()
+	}
+	return result.resp, result.err
+}
+
+// attempt sends the request to one instance and reads the whole response
+// within the policy's timeout
+func (p *ServiceProxy) attempt(ctx context.Context, target *Target, out *outbound, policy Policy, attempt int, hedged bool) (*upstreamResponse, error) {
+	// Build target URL
+	targetURL := target.URL + out.path
+	if out.query != "" {
+		targetURL += "?" + out.query
+	}
+
+	// Fail fast while the service keeps failing
+	settle, err := p.breaker.Allow()
+	if err != nil {
+		return nil, err
+	}
+
+	// Shed the request when the service already has as much as it can handle
+	done, err := p.limiter.Acquire()
+	if err != nil {
+		settle(performance.OutcomeIgnored)
+		return nil, fiber.NewError(fiber.StatusServiceUnavailable, "Service overloaded, try again")
+	}
+	outcome := performance.OutcomeIgnored
+	defer func() {
+		done(outcome)
+		settle(outcome)
+	}()
+
+	ctx, span := otel.Tracer("proxy").Start(ctx, out.method+" "+p.service,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("peer.service", p.service),
+			attribute.String("http.method", out.method),
+			attribute.String("http.url", target.URL+out.path),
+			attribute.Int("http.resend_count", attempt),
+			attribute.Bool("proxy.hedged", hedged),
+		),
+	)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(withConnectTimeout(ctx, policy.ConnectTimeout), policy.Timeout)
+	defer cancel()
+
+	// Create request
+	req, err := http.NewRequestWithContext(ctx, out.method, targetURL, bytes.NewReader(out.body))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadGateway, "Failed to create request")
 	}
+	req.Header = out.header.Clone()
+
+	// Continue the trace in the upstream service
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	// Execute request
+	release := p.balancer.Acquire(target)
+	defer release()
+
+	start := time.Now()
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadGateway, "Failed to connect to service")
+		outcome = limitOutcome(err, 0)
+		return nil, p.failed(span, target, err, "Failed to connect to service")
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadGateway, "Failed to read response")
+		outcome = limitOutcome(err, 0)
+		return nil, p.failed(span, target, err, "Failed to read response")
 	}
+	p.balancer.Report(target, nil, resp.StatusCode, time.Since(start))
+	outcome = limitOutcome(nil, resp.StatusCode)
 
-	// Copy response headers
-	for key, values := range resp.Header {
-		for _, value := range values {
-			c.Set(key, value)
-		}
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, resp.Status)
 	}
 
-	// Set status and body
-	c.Status(resp.StatusCode)
-	return c.Send(body)
+	return &upstreamResponse{status: resp.StatusCode, header: resp.Header, body: body}, nil
+}
+
+// limitOutcome classifies an attempt for the adaptive concurrency limit and
+// the circuit breaker. An attempt cancelled by the caller says nothing about
+// the service.
+func limitOutcome(err error, status int) performance.LimitOutcome {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return performance.OutcomeIgnored
+	case err != nil || isUpstreamFailure(status):
+		return performance.OutcomeDropped
+	}
+	return performance.OutcomeSuccess
+}
+
+// failed records a failed attempt and returns the error for the client. An
+// attempt cancelled by the caller, such as the losing copy of a hedged request,
+// says nothing about the instance and is not held against it.
+func (p *ServiceProxy) failed(span trace.Span, target *Target, err error, message string) error {
+	span.RecordError(err)
+	switch {
+	case errors.Is(err, context.Canceled):
+		span.SetStatus(codes.Error, "request cancelled")
+		return fiber.NewError(fiber.StatusBadGateway, message)
+	case errors.Is(err, context.DeadlineExceeded):
+		p.balancer.Report(target, err, 0, 0)
+		span.SetStatus(codes.Error, "upstream timed out")
+		return fiber.NewError(fiber.StatusGatewayTimeout, "Service timed out")
+	default:
+		p.balancer.Report(target, err, 0, 0)
+		span.SetStatus(codes.Error, "upstream unreachable")
+		return fiber.NewError(fiber.StatusBadGateway, message)
+	}
 }
@@ -0,0 +1,302 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// streamBufferSize is the largest chunk relayed from an event stream at once
+const streamBufferSize = 4096
+
+// isWebSocketUpgrade reports whether the client asks to switch to WebSocket
+func isWebSocketUpgrade(c *fiber.Ctx) bool {
+	return strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket") &&
+		hasToken(c.Get(fiber.HeaderConnection), "upgrade")
+}
+
+// acceptsEventStream reports whether the client asks for server-sent events
+func acceptsEventStream(header http.Header) bool {
+	return strings.Contains(header.Get(fiber.HeaderAccept), "text/event-stream")
+}
+
+// hasToken reports whether a comma-separated header value contains token
+func hasToken(value, token string) bool {
+	for _, item := range strings.Split(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(item), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stream proxies the request like Proxy, but relays the response as it
+// arrives instead of after reading it whole, for downloads too large to
+// hold. Like event streams, they are neither retried nor hedged.
+func (p *ServiceProxy) Stream(c *fiber.Ctx) error {
+	out := p.outbound(c)
+	return p.proxyStream(c, out, p.policies.forRequest(out.method, out.path))
+}
+
+// proxyStream relays a response as it arrives, flushing each chunk to the
+// client, so server-sent events are not held back until the stream ends. The
+// policy's timeout covers only the wait for the response headers; streams are
+// neither retried nor hedged.
+func (p *ServiceProxy) proxyStream(c *fiber.Ctx, out *outbound, policy Policy) error {
+	target := p.balancer.Pick()
+	if target == nil {
+		return fiber.NewError(fiber.StatusServiceUnavailable, "No upstream instances available")
+	}
+
+	targetURL := target.URL + out.path
+	if out.query != "" {
+		targetURL += "?" + out.query
+	}
+
+	ctx, span := p.startSpan(out, target, "sse")
+	ctx, cancel := context.WithCancel(withConnectTimeout(ctx, policy.ConnectTimeout))
+
+	req, err := http.NewRequestWithContext(ctx, out.method, targetURL, bytes.NewReader(out.body))
+	if err != nil {
+		cancel()
+		span.End()
+		return fiber.NewError(fiber.StatusBadGateway, "Failed to create request")
+	}
+	req.Header = out.header.Clone()
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	release := p.balancer.Acquire(target)
+	done := func() {
+		release()
+		cancel()
+		span.End()
+	}
+
+	headerTimer := time.AfterFunc(policy.Timeout, cancel)
+	start := time.Now()
+	resp, err := p.client.Do(req)
+	timedOut := !headerTimer.Stop()
+	if err != nil {
+		defer done()
+		if timedOut {
+			p.balancer.Report(target, err, 0, 0)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "upstream timed out")
+			return fiber.NewError(fiber.StatusGatewayTimeout, "Service timed out")
+		}
+		return p.failed(span, target, err, "Failed to connect to service")
+	}
+	p.balancer.Report(target, nil, resp.StatusCode, time.Since(start))
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+
+	for key, values := range resp.Header {
+		for _, value := range values {
+			c.Set(key, value)
+		}
+	}
+	c.Status(resp.StatusCode)
+
+	// The server's write timeout bounds a whole response; a stream is bounded
+	// by the client staying connected instead
+	conn := c.Context().Conn()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer done()
+		defer resp.Body.Close()
+
+		_ = conn.SetWriteDeadline(time.Time{})
+		buf := make([]byte, streamBufferSize)
+		for {
+			n, err := resp.Body.Read(buf)
+			if n > 0 {
+				if _, werr := w.Write(buf[:n]); werr != nil {
+					return
+				}
+				if werr := w.Flush(); werr != nil {
+					return // Client went away
+				}
+			}
+			if err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					span.RecordError(err)
+				}
+				if err != io.EOF {
+					// Drop the client too, so a response the upstream cut
+					// short does not reach it looking complete
+					_ = conn.Close()
+				}
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// proxyWebSocket forwards a WebSocket handshake to an instance and, once it
+// switches protocols, copies frames both ways until either side closes. The
+// policy's timeouts cover only the dial and the handshake.
+func (p *ServiceProxy) proxyWebSocket(c *fiber.Ctx, out *outbound, policy Policy) error {
+	target := p.balancer.Pick()
+	if target == nil {
+		return fiber.NewError(fiber.StatusServiceUnavailable, "No upstream instances available")
+	}
+
+	u, err := url.Parse(target.URL + out.path)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, "Failed to create request")
+	}
+	u.RawQuery = out.query
+
+	ctx, span := p.startSpan(out, target, "websocket")
+	release := p.balancer.Acquire(target)
+	done := func() {
+		release()
+		span.End()
+	}
+
+	start := time.Now()
+	upstream, err := dialUpstream(ctx, u, policy.ConnectTimeout)
+	if err != nil {
+		defer done()
+		return p.failed(span, target, err, "Failed to connect to service")
+	}
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Host:       u.Host,
+		Header:     out.header.Clone(),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+	}
+	req.Header.Set(fiber.HeaderConnection, "Upgrade")
+	req.Header.Set(fiber.HeaderUpgrade, c.Get(fiber.HeaderUpgrade))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	// Bound the handshake, then clear the deadline for the session
+	_ = upstream.SetDeadline(time.Now().Add(policy.Timeout))
+	reader := bufio.NewReader(upstream)
+	if err = req.Write(upstream); err == nil {
+		var resp *http.Response
+		if resp, err = http.ReadResponse(reader, req); err == nil {
+			_ = upstream.SetDeadline(time.Time{})
+			return p.switchProtocols(c, span, target, upstream, reader, resp, time.Since(start), done)
+		}
+	}
+
+	defer done()
+	upstream.Close()
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		p.balancer.Report(target, err, 0, 0)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "upstream timed out")
+		return fiber.NewError(fiber.StatusGatewayTimeout, "Service timed out")
+	}
+	return p.failed(span, target, err, "Failed to connect to service")
+}
+
+// switchProtocols relays the upstream's handshake response. On 101 Switching
+// Protocols the client connection is hijacked and joined to upstream; any
+// other response is passed on as is.
+func (p *ServiceProxy) switchProtocols(c *fiber.Ctx, span trace.Span, target *Target, upstream net.Conn,
+	reader *bufio.Reader, resp *http.Response, latency time.Duration, done func()) error {
+	p.balancer.Report(target, nil, resp.StatusCode, latency)
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+
+	for key, values := range resp.Header {
+		for _, value := range values {
+			c.Set(key, value)
+		}
+	}
+	c.Status(resp.StatusCode)
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer done()
+		defer upstream.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, resp.Status)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadGateway, "Failed to read response")
+		}
+		return c.Send(body)
+	}
+
+	c.Response().Header.SetNoDefaultContentType(true)
+	c.Context().Hijack(func(client net.Conn) {
+		defer done()
+		pipe(client, upstream, reader)
+	})
+	return nil
+}
+
+// pipe copies between the client and upstream connections until either side
+// closes, then closes both. Bytes the handshake reader buffered past the
+// response are sent first.
+func pipe(client, upstream net.Conn, reader io.Reader) {
+	copied := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(upstream, client)
+		copied <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(client, reader)
+		copied <- struct{}{}
+	}()
+
+	<-copied
+	client.Close()
+	upstream.Close()
+	<-copied
+}
+
+// dialUpstream opens a connection to the instance serving u
+func dialUpstream(ctx context.Context, u *url.URL, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	host := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	if u.Scheme != "https" {
+		return dialer.DialContext(ctx, "tcp", host)
+	}
+	tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}}
+	return tlsDialer.DialContext(ctx, "tcp", host)
+}
+
+// startSpan starts the client span of a streaming request
+func (p *ServiceProxy) startSpan(out *outbound, target *Target, kind string) (context.Context, trace.Span) {
+	return otel.Tracer("proxy").Start(out.ctx, out.method+" "+p.service,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("peer.service", p.service),
+			attribute.String("http.method", out.method),
+			attribute.String("http.url", target.URL+out.path),
+			attribute.String("proxy.stream", kind),
+		),
+	)
+}
@@ -0,0 +1,157 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve runs app on a local port until the test ends
+func serve(t *testing.T, app *fiber.App) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(listener) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+	return listener.Addr().String()
+}
+
+func TestProxyStreamsServerSentEvents(t *testing.T) {
+	next := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+		<-next
+		_, _ = w.Write([]byte("data: second\n\n"))
+	}))
+	defer upstream.Close()
+
+	cfg := testProxyConfig(RoundRobin)
+	cfg.Timeout = 50 * time.Millisecond // Covers the headers, not the stream
+	p := NewServiceProxy("notification-service", upstream.URL, cfg)
+	defer p.Close()
+
+	app := fiber.New()
+	app.Get("/events", p.Proxy)
+	addr := serve(t, app)
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// The first event arrives while the upstream is still holding the stream open
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: first\n", line)
+
+	time.Sleep(100 * time.Millisecond)
+	close(next)
+	rest, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "\ndata: second\n\n", string(rest))
+}
+
+func TestProxyWebSocket(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("room") != "kitchen" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			kind, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(kind, append([]byte("echo: "), message...)); err != nil {
+				return
+			}
+		}
+	}))
+	defer upstream.Close()
+
+	p := NewServiceProxy("notification-service", upstream.URL, testProxyConfig(RoundRobin))
+	defer p.Close()
+
+	app := fiber.New()
+	app.Get("/ws", p.Proxy)
+	addr := serve(t, app)
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws://"+addr+"/ws?room=kitchen", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	for _, message := range []string{"order ready", "table 4"} {
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(message)))
+		_, reply, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, "echo: "+message, string(reply))
+	}
+
+	// A refused handshake is passed back to the client
+	_, resp, err = websocket.DefaultDialer.Dial("ws://"+addr+"/ws?room=lobby", nil)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestStreamRelaysDownloads(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		_, _ = w.Write([]byte("id\n1\n"))
+		if r.URL.Query().Get("fail") == "" {
+			return
+		}
+		// The upstream drops the connection part way through the body
+		w.(http.Flusher).Flush()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}))
+	defer upstream.Close()
+
+	p := NewServiceProxy("order-service", upstream.URL, testProxyConfig(RoundRobin))
+	defer p.Close()
+
+	app := fiber.New()
+	app.Get("/orders/export", p.Stream)
+	addr := serve(t, app)
+
+	resp, err := http.Get("http://" + addr + "/orders/export")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "id\n1\n", string(body))
+
+	// A download the upstream cut short does not end cleanly
+	resp, err = http.Get("http://" + addr + "/orders/export?fail=1")
+	if err == nil {
+		_, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	assert.Error(t, err)
+}
@@ -0,0 +1,156 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// apnsTokenLifetime is how long a provider token is used. APNs refuses
+// tokens older than an hour, and new ones more often than every 20 minutes.
+const apnsTokenLifetime = 50 * time.Minute
+
+// APNs sends through the Apple Push Notification service over HTTP/2,
+// authenticating with provider tokens signed by a .p8 signing key
+type APNs struct {
+	baseURL string
+	keyID   string
+	teamID  string
+	topic   string
+	key     *ecdsa.PrivateKey
+	client  *http.Client
+	now     func() time.Time
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNs creates an APNs provider with the signing key in cfg.KeyFile
+func NewAPNs(cfg config.APNsConfig, timeout time.Duration) (*APNs, error) {
+	data, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %w", err)
+	}
+
+	return &APNs{
+		baseURL: strings.TrimRight(cfg.APIURL, "/"),
+		keyID:   cfg.KeyID,
+		teamID:  cfg.TeamID,
+		topic:   cfg.Topic,
+		key:     key,
+		client:  &http.Client{Timeout: timeout},
+		now:     time.Now,
+	}, nil
+}
+
+// Name returns "apns"
+func (a *APNs) Name() string {
+	return ProviderAPNs
+}
+
+// Send sends m as an alert to the device token
+func (a *APNs) Send(ctx context.Context, token string, m *Message) error {
+	providerToken, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{}
+	for key, value := range m.Data {
+		payload[key] = value
+	}
+	payload["aps"] = map[string]interface{}{
+		"alert": map[string]string{"title": m.Title, "body": m.Body},
+		"sound": "default",
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "5")
+	if m.Urgent {
+		req.Header.Set("apns-priority", "10")
+	}
+	if m.ID != "" {
+		req.Header.Set("apns-collapse-id", m.ID)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("apns: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return nil
+	}
+
+	var failure struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&failure)
+
+	switch failure.Reason {
+	case "ExpiredProviderToken", "InvalidProviderToken":
+		// Sign a new provider token next time
+		a.mu.Lock()
+		a.token = ""
+		a.mu.Unlock()
+		return fmt.Errorf("apns returned %d: %s", resp.StatusCode, failure.Reason)
+	}
+	// 410 is a token no longer active for the topic
+	invalid := resp.StatusCode == http.StatusGone ||
+		failure.Reason == "BadDeviceToken" || failure.Reason == "DeviceTokenNotForTopic" || failure.Reason == "Unregistered"
+	return classify("apns", resp.StatusCode, failure.Reason, invalid)
+}
+
+// providerToken returns the provider token, signing a new one once the last
+// one is apnsTokenLifetime old
+func (a *APNs) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	if a.token != "" && now.Sub(a.issuedAt) < apnsTokenLifetime {
+		return a.token, nil
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": a.teamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = a.keyID
+	signed, err := token.SignedString(a.key)
+	if err != nil {
+		return "", fmt.Errorf("apns: failed to sign provider token: %w", err)
+	}
+	a.token = signed
+	a.issuedAt = now
+	return signed, nil
+}
@@ -0,0 +1,113 @@
+package push
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// newTestAPNs creates an APNs provider against url with a fresh signing key
+func newTestAPNs(t *testing.T, url string) (*APNs, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "AuthKey.p8")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	apns, err := NewAPNs(config.APNsConfig{
+		APIURL:  url,
+		KeyFile: path,
+		KeyID:   "KEY123",
+		TeamID:  "TEAM456",
+		Topic:   "com.example.pos",
+	}, time.Second)
+	require.NoError(t, err)
+	return apns, key
+}
+
+func TestAPNsSends(t *testing.T) {
+	var key *ecdsa.PrivateKey
+	var payload map[string]interface{}
+	var header http.Header
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, header = r.URL.Path, r.Header
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+	}))
+	defer server.Close()
+
+	apns, signingKey := newTestAPNs(t, server.URL)
+	key = signingKey
+
+	m := &Message{ID: "n-1", Title: "Low stock", Body: "Oat milk", Data: map[string]string{"sku": "OAT-1"}}
+	require.NoError(t, apns.Send(context.Background(), "abc123", m))
+
+	assert.Equal(t, "/3/device/abc123", path)
+	assert.Equal(t, "com.example.pos", header.Get("apns-topic"))
+	assert.Equal(t, "alert", header.Get("apns-push-type"))
+	assert.Equal(t, "5", header.Get("apns-priority"))
+	assert.Equal(t, "n-1", header.Get("apns-collapse-id"))
+	assert.Equal(t, "OAT-1", payload["sku"])
+	assert.Equal(t, map[string]interface{}{"title": "Low stock", "body": "Oat milk"}, payload["aps"].(map[string]interface{})["alert"])
+
+	// The provider token is signed by the team's key
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(strings.TrimPrefix(header.Get("Authorization"), "bearer "), claims,
+		func(*jwt.Token) (interface{}, error) { return &key.PublicKey, nil })
+	require.NoError(t, err)
+	assert.Equal(t, "KEY123", token.Header["kid"])
+	assert.Equal(t, "TEAM456", claims["iss"])
+}
+
+func TestAPNsFailures(t *testing.T) {
+	var status int
+	var reason string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"reason": reason})
+	}))
+	defer server.Close()
+	apns, _ := newTestAPNs(t, server.URL)
+
+	send := func(code int, why string) error {
+		status, reason = code, why
+		return apns.Send(context.Background(), "abc123", &Message{Title: "Hi"})
+	}
+
+	assert.True(t, errors.Is(send(http.StatusGone, "Unregistered"), ErrInvalidToken))
+	assert.True(t, errors.Is(send(http.StatusBadRequest, "BadDeviceToken"), ErrInvalidToken))
+
+	err := send(http.StatusBadRequest, "PayloadTooLarge")
+	assert.True(t, errors.Is(err, ErrRejected))
+	assert.False(t, errors.Is(err, ErrInvalidToken))
+
+	// An expired provider token is replaced and the send may pass next time
+	first, err := apns.providerToken()
+	require.NoError(t, err)
+	err = send(http.StatusForbidden, "ExpiredProviderToken")
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrRejected))
+	apns.now = func() time.Time { return time.Now().Add(time.Second) }
+	second, err := apns.providerToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
@@ -0,0 +1,218 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// fcmScope is the OAuth scope sending messages needs
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// tokenMargin is how long before it expires an access token is replaced
+const tokenMargin = time.Minute
+
+// FCM sends through the Firebase Cloud Messaging HTTP v1 API, authorized by
+// access tokens obtained with a service account key
+type FCM struct {
+	baseURL   string
+	projectID string
+	account   fcmServiceAccount
+	key       *rsa.PrivateKey
+	client    *http.Client
+	now       func() time.Time
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// fcmServiceAccount is the part of a service account key FCM reads
+type fcmServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// fcmError is the body of an FCM error response
+type fcmError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// NewFCM creates an FCM provider with the service account key in
+// cfg.CredentialsFile
+func NewFCM(cfg config.FCMConfig, timeout time.Duration) (*FCM, error) {
+	data, err := os.ReadFile(cfg.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+	var account fcmServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %w", err)
+	}
+	if account.ClientEmail == "" || account.TokenURI == "" {
+		return nil, fmt.Errorf("credentials are not a service account key")
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	return &FCM{
+		baseURL:   strings.TrimRight(cfg.APIURL, "/"),
+		projectID: cfg.ProjectID,
+		account:   account,
+		key:       key,
+		client:    &http.Client{Timeout: timeout},
+		now:       time.Now,
+	}, nil
+}
+
+// Name returns "fcm"
+func (f *FCM) Name() string {
+	return ProviderFCM
+}
+
+// Send sends m to the registration token
+func (f *FCM) Send(ctx context.Context, token string, m *Message) error {
+	accessToken, err := f.authorize(ctx)
+	if err != nil {
+		return err
+	}
+
+	android := map[string]interface{}{"priority": "NORMAL"}
+	apnsHeaders := map[string]string{"apns-priority": "5"}
+	if m.Urgent {
+		android["priority"] = "HIGH"
+		apnsHeaders["apns-priority"] = "10"
+	}
+	if m.ID != "" {
+		android["collapse_key"] = m.ID
+		apnsHeaders["apns-collapse-id"] = m.ID
+	}
+	message := map[string]interface{}{
+		"token":        token,
+		"notification": map[string]string{"title": m.Title, "body": m.Body},
+		"android":      android,
+		"apns":         map[string]interface{}{"headers": apnsHeaders},
+	}
+	if len(m.Data) > 0 {
+		message["data"] = m.Data
+	}
+	body, err := json.Marshal(map[string]interface{}{"message": message})
+	if err != nil {
+		return err
+	}
+
+	endpoint := f.baseURL + "/v1/projects/" + url.PathEscape(f.projectID) + "/messages:send"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fcm: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return nil
+	}
+
+	var failure fcmError
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&failure)
+	errorCode := failure.Error.Status
+	for _, detail := range failure.Error.Details {
+		if detail.ErrorCode != "" {
+			errorCode = detail.ErrorCode
+		}
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		// The access token was revoked or expired early; fetch another next time
+		f.mu.Lock()
+		f.accessToken = ""
+		f.mu.Unlock()
+		return fmt.Errorf("fcm returned %d: %s", resp.StatusCode, failure.Error.Message)
+	}
+	// UNREGISTERED tokens were dropped by the app or FCM; SENDER_ID_MISMATCH
+	// ones belong to another project
+	invalid := errorCode == "UNREGISTERED" || errorCode == "SENDER_ID_MISMATCH"
+	return classify("fcm", resp.StatusCode, strings.TrimSpace(errorCode+" "+failure.Error.Message), invalid)
+}
+
+// authorize returns an access token, obtaining a new one by a signed
+// assertion of the service account once the last one nears expiry
+func (f *FCM) authorize(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	if f.accessToken != "" && now.Before(f.expiresAt.Add(-tokenMargin)) {
+		return f.accessToken, nil
+	}
+
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(f.key)
+	if err != nil {
+		return "", fmt.Errorf("fcm: failed to sign token request: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fcm: failed to obtain access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("fcm: token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(excerpt)))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("fcm: failed to decode access token: %w", err)
+	}
+	f.accessToken = token.AccessToken
+	f.expiresAt = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
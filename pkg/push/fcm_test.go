@@ -0,0 +1,125 @@
+package push
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// newTestFCM creates an FCM provider against server, which answers both the
+// token endpoint and the send API
+func newTestFCM(t *testing.T, server *httptest.Server) (*FCM, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	credentials, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "pos@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    server.URL + "/token",
+	})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "fcm.json")
+	require.NoError(t, os.WriteFile(path, credentials, 0o600))
+
+	fcm, err := NewFCM(config.FCMConfig{APIURL: server.URL, ProjectID: "pos-app", CredentialsFile: path}, time.Second)
+	require.NoError(t, err)
+	return fcm, key
+}
+
+func TestFCMSends(t *testing.T) {
+	var tokenRequests atomic.Int32
+	var key *rsa.PrivateKey
+	var sent map[string]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests.Add(1)
+			require.NoError(t, r.ParseForm())
+			claims := jwt.MapClaims{}
+			_, err := jwt.ParseWithClaims(r.Form.Get("assertion"), claims, func(*jwt.Token) (interface{}, error) {
+				return &key.PublicKey, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, "pos@project.iam.gserviceaccount.com", claims["iss"])
+			assert.Equal(t, fcmScope, claims["scope"])
+			w.Write([]byte(`{"access_token":"ya29.token","expires_in":3600}`))
+		case "/v1/projects/pos-app/messages:send":
+			assert.Equal(t, "Bearer ya29.token", r.Header.Get("Authorization"))
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&sent))
+			w.Write([]byte(`{"name":"projects/pos-app/messages/1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	fcm, signingKey := newTestFCM(t, server)
+	key = signingKey
+
+	m := &Message{ID: "n-1", Title: "Order ready", Body: "Table 4", Data: map[string]string{"order_id": "o-1"}, Urgent: true}
+	require.NoError(t, fcm.Send(context.Background(), "device-1", m))
+	require.NoError(t, fcm.Send(context.Background(), "device-2", m))
+
+	// The access token is reused until it nears expiry
+	assert.Equal(t, int32(1), tokenRequests.Load())
+	assert.Equal(t, "device-2", sent["message"]["token"])
+	assert.Equal(t, map[string]interface{}{"title": "Order ready", "body": "Table 4"}, sent["message"]["notification"])
+	assert.Equal(t, map[string]interface{}{"order_id": "o-1"}, sent["message"]["data"])
+	assert.Equal(t, "HIGH", sent["message"]["android"].(map[string]interface{})["priority"])
+}
+
+func TestFCMFailures(t *testing.T) {
+	var status int
+	var answer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Write([]byte(`{"access_token":"ya29.token","expires_in":3600}`))
+			return
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(answer))
+	}))
+	defer server.Close()
+	fcm, _ := newTestFCM(t, server)
+
+	send := func(code int, body string) error {
+		status, answer = code, body
+		return fcm.Send(context.Background(), "device-1", &Message{Title: "Hi"})
+	}
+
+	// A token the app dropped is to be removed
+	err := send(http.StatusNotFound, `{"error":{"code":404,"message":"Requested entity was not found.","status":"NOT_FOUND",
+		"details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"UNREGISTERED"}]}}`)
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+
+	// A malformed message is refused for good, without blaming the token
+	err = send(http.StatusBadRequest, `{"error":{"code":400,"message":"Invalid JSON payload","status":"INVALID_ARGUMENT"}}`)
+	assert.True(t, errors.Is(err, ErrRejected))
+	assert.False(t, errors.Is(err, ErrInvalidToken))
+
+	// Throttling and outages pass
+	err = send(http.StatusServiceUnavailable, `{"error":{"code":503,"status":"UNAVAILABLE"}}`)
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrRejected))
+	assert.False(t, errors.Is(err, ErrInvalidToken))
+}
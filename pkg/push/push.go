@@ -0,0 +1,83 @@
+// Package push sends notifications to mobile apps through Firebase Cloud
+// Messaging and the Apple Push Notification service.
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// Names of the providers, as devices register with them
+const (
+	ProviderFCM  = "fcm"
+	ProviderAPNs = "apns"
+)
+
+var (
+	// ErrInvalidToken is matched by errors of sends to a device token the
+	// provider no longer knows, such as one of an uninstalled app; the token
+	// should be dropped
+	ErrInvalidToken = errors.New("device token is no longer valid")
+	// ErrRejected is matched by errors of messages the provider refused for
+	// good; sending them again fails the same way
+	ErrRejected = errors.New("push rejected")
+)
+
+// Message is a notification shown on a device
+type Message struct {
+	ID     string // Unique per notification; a device shows one message per ID
+	Title  string
+	Body   string
+	Data   map[string]string // Handed to the app with the message
+	Urgent bool              // Delivered at once, waking the device
+}
+
+// Provider sends messages to device tokens issued by one push service
+type Provider interface {
+	// Name returns the provider's name, such as ProviderFCM
+	Name() string
+	// Send sends m to the device holding token. A failure matching
+	// ErrInvalidToken or ErrRejected is permanent; any other may pass when
+	// tried again.
+	Send(ctx context.Context, token string, m *Message) error
+}
+
+// NewProviders creates the providers configured in cfg, by name. A provider
+// left unconfigured is missing.
+func NewProviders(cfg config.PushConfig) (map[string]Provider, error) {
+	providers := map[string]Provider{}
+	if cfg.FCM.ProjectID != "" {
+		fcm, err := NewFCM(cfg.FCM, cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("fcm: %w", err)
+		}
+		providers[ProviderFCM] = fcm
+	}
+	if cfg.APNs.KeyFile != "" {
+		apns, err := NewAPNs(cfg.APNs, cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("apns: %w", err)
+		}
+		providers[ProviderAPNs] = apns
+	}
+	return providers, nil
+}
+
+// classify maps a provider's answer to a failed send onto the errors callers
+// act on: ErrInvalidToken when the token is gone, ErrRejected for any other
+// refusal that retrying cannot change
+func classify(provider string, status int, reason string, invalidToken bool) error {
+	err := fmt.Errorf("%s returned %d: %s", provider, status, reason)
+	switch {
+	case invalidToken:
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	case status == 429 || status >= 500:
+		return err
+	case status >= 400:
+		return fmt.Errorf("%w: %w", ErrRejected, err)
+	}
+	return err
+}
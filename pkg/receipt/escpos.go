@@ -0,0 +1,88 @@
+package receipt
+
+import (
+	"bytes"
+	"strings"
+)
+
+// ESC/POS control bytes
+const (
+	esc = 0x1b
+	gs  = 0x1d
+	lf  = 0x0a
+)
+
+// Cash drawer pulse timing, in units of 2ms
+const (
+	drawerPulseOn  = 25  // 50ms
+	drawerPulseOff = 250 // 500ms
+)
+
+// ESCPOS renders r as an ESC/POS byte stream: the receipt, its barcode, a
+// partial cut and, when set, a cash drawer kick
+func ESCPOS(r *Receipt) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{esc, '@'}) // Initialize
+
+	for _, row := range r.Layout() {
+		buf.Write([]byte{esc, 'a', byte(row.Align)})
+		if row.Bold {
+			buf.Write([]byte{esc, 'E', 1})
+		}
+		if row.Large {
+			buf.Write([]byte{gs, '!', 0x11}) // Double width and height
+		}
+		buf.WriteString(row.Text)
+		buf.WriteByte(lf)
+		if row.Large {
+			buf.Write([]byte{gs, '!', 0})
+		}
+		if row.Bold {
+			buf.Write([]byte{esc, 'E', 0})
+		}
+	}
+
+	if r.Barcode != "" {
+		buf.WriteByte(lf)
+		buf.Write([]byte{esc, 'a', byte(AlignCenter)})
+		buf.Write(barcode(r.Barcode))
+	}
+
+	buf.Write([]byte{esc, 'a', byte(AlignLeft)})
+	buf.Write([]byte{gs, 'V', 'B', 3}) // Feed three lines and cut, leaving a tab
+	if r.OpenDrawer {
+		buf.Write(kick(r.DrawerPin))
+	}
+	return buf.Bytes()
+}
+
+// DrawerKick renders a job that only opens the cash drawer on pin
+func DrawerKick(pin DrawerPin) []byte {
+	return append([]byte{esc, '@'}, kick(pin)...)
+}
+
+// kick pulses the drawer connector pin, pin 2 unless pin 5 is asked for
+func kick(pin DrawerPin) []byte {
+	var m byte
+	if pin == DrawerPin5 {
+		m = 1
+	}
+	return []byte{esc, 'p', m, drawerPulseOn, drawerPulseOff}
+}
+
+// barcode renders data as a CODE128 barcode with its text printed below
+func barcode(data string) []byte {
+	// A literal '{' is sent as "{{", since '{' starts a CODE128 control
+	data = strings.ReplaceAll(sanitize(data), "{", "{{")
+	if len(data) > 253 {
+		data = data[:253]
+	}
+	out := []byte{
+		gs, 'h', 80, // Height in dots
+		gs, 'w', 2, // Module width
+		gs, 'H', 2, // Text below the bars
+		gs, 'k', 73, byte(len(data) + 2), '{', 'B', // CODE128, code set B
+	}
+	out = append(out, data...)
+	return append(out, lf)
+}
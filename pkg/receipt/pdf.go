@@ -0,0 +1,100 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// PDF page geometry, in points. Pages are as wide as an 80mm roll and as
+// long as the receipt.
+const (
+	pdfPageWidth   = 226.77 // 80mm
+	pdfMargin      = 12.0
+	pdfMaxFontSize = 9.0
+	pdfCharWidth   = 0.6 // Courier advance per point of font size
+)
+
+// PDF renders r as a single page PDF document in Courier, laid out as it
+// prints. The barcode is printed as its text.
+func PDF(r *Receipt) []byte {
+	rows := r.Layout()
+	if r.Barcode != "" {
+		rows = append(rows, Row{}, Row{Text: clip(r.Barcode, r.LineWidth()), Align: AlignCenter})
+	}
+
+	width := r.LineWidth()
+	fontSize := (pdfPageWidth - 2*pdfMargin) / (float64(width) * pdfCharWidth)
+	if fontSize > pdfMaxFontSize {
+		fontSize = pdfMaxFontSize
+	}
+	leading := fontSize * 1.25
+
+	height := 2 * pdfMargin
+	for _, row := range rows {
+		height += rowLeading(row, leading)
+	}
+
+	var content bytes.Buffer
+	y := height - pdfMargin
+	for _, row := range rows {
+		y -= rowLeading(row, leading)
+		if row.Text == "" {
+			continue
+		}
+		size, chars := fontSize, width
+		if row.Large {
+			size, chars = 2*fontSize, width/2
+		}
+		font := "F1"
+		if row.Bold {
+			font = "F2"
+		}
+		text := clip(row.Text, chars)
+		x := pdfMargin
+		switch row.Align {
+		case AlignCenter:
+			x += float64(chars-len(text)) / 2 * size * pdfCharWidth
+		case AlignRight:
+			x += float64(chars-len(text)) * size * pdfCharWidth
+		}
+		fmt.Fprintf(&content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y+leading-size, pdfEscape(text))
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Contents 4 0 R /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>", pdfPageWidth, height),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold >>",
+	}
+
+	var doc bytes.Buffer
+	doc.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = doc.Len()
+		fmt.Fprintf(&doc, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := doc.Len()
+	fmt.Fprintf(&doc, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&doc, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&doc, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return doc.Bytes()
+}
+
+// rowLeading returns the vertical space row takes
+func rowLeading(row Row, leading float64) float64 {
+	if row.Large {
+		return 2 * leading
+	}
+	return leading
+}
+
+// pdfEscape escapes the characters that end or escape a PDF string
+func pdfEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(text)
+}
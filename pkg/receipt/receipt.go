@@ -0,0 +1,228 @@
+// Package receipt lays out sales receipts and renders them for thermal
+// printers (ESC/POS) and as PDF documents.
+package receipt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultWidth is the characters per line of an 80mm printer's default font
+const DefaultWidth = 48
+
+// Receipts narrower or wider than this are clamped
+const (
+	minWidth = 24
+	maxWidth = 64
+)
+
+// DrawerPin selects the cash drawer connector a kick pulses
+type DrawerPin int
+
+const (
+	DrawerPin2 DrawerPin = 2
+	DrawerPin5 DrawerPin = 5
+)
+
+// Receipt is a sales receipt, independent of how it is printed
+type Receipt struct {
+	Width    int      // Characters per line; DefaultWidth when zero
+	Header   []string // Centered above the items; the first line is printed large
+	Meta     []string // Order details such as number, date and cashier
+	Items    []Item
+	Totals   []Total
+	Footer   []string // Centered below the totals
+	Barcode  string   // Printed as a CODE128 barcode when set, such as the order number
+	Currency string   // Shown on grand totals
+
+	OpenDrawer bool // Kick the cash drawer after printing
+	DrawerPin  DrawerPin
+}
+
+// Item is a receipt line
+type Item struct {
+	Name      string
+	Quantity  int
+	UnitPrice float64
+	Discount  float64
+	Amount    float64 // After the discount
+}
+
+// Total is a line of the totals block
+type Total struct {
+	Label  string
+	Amount float64
+	Grand  bool // Printed emphasized, with the currency
+}
+
+// Align is the alignment of a row
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// Row is a laid out line of a receipt. Large rows are printed at double
+// width, so they hold half as many characters.
+type Row struct {
+	Text  string
+	Align Align
+	Bold  bool
+	Large bool
+}
+
+// LineWidth returns the characters per line of r
+func (r *Receipt) LineWidth() int {
+	switch {
+	case r.Width == 0:
+		return DefaultWidth
+	case r.Width < minWidth:
+		return minWidth
+	case r.Width > maxWidth:
+		return maxWidth
+	}
+	return r.Width
+}
+
+// Layout lays r out in rows of at most its line width. Text outside
+// printable ASCII is replaced, since printers' default code pages differ.
+func (r *Receipt) Layout() []Row {
+	width := r.LineWidth()
+	var rows []Row
+
+	for i, line := range r.Header {
+		if i == 0 {
+			for _, text := range wrap(line, width/2) {
+				rows = append(rows, Row{Text: text, Align: AlignCenter, Bold: true, Large: true})
+			}
+			continue
+		}
+		for _, text := range wrap(line, width) {
+			rows = append(rows, Row{Text: text, Align: AlignCenter})
+		}
+	}
+	if len(r.Header) > 0 {
+		rows = append(rows, Row{})
+	}
+
+	for _, line := range r.Meta {
+		for _, text := range wrap(line, width) {
+			rows = append(rows, Row{Text: text})
+		}
+	}
+
+	rule := Row{Text: strings.Repeat("-", width)}
+	rows = append(rows, rule)
+	for _, item := range r.Items {
+		rows = append(rows, Row{Text: columns(item.Name, amount(item.Amount), width)})
+		if item.Quantity != 1 {
+			rows = append(rows, Row{Text: clip(fmt.Sprintf("  %d x %s", item.Quantity, amount(item.UnitPrice)), width)})
+		}
+		if item.Discount > 0 {
+			rows = append(rows, Row{Text: columns("  Discount", "-"+amount(item.Discount), width)})
+		}
+	}
+	rows = append(rows, rule)
+
+	for _, total := range r.Totals {
+		value := amount(total.Amount)
+		if total.Grand && r.Currency != "" {
+			value = r.Currency + " " + value
+		}
+		rows = append(rows, Row{Text: columns(total.Label, value, width), Bold: total.Grand})
+	}
+
+	if len(r.Footer) > 0 {
+		rows = append(rows, Row{})
+	}
+	for _, line := range r.Footer {
+		for _, text := range wrap(line, width) {
+			rows = append(rows, Row{Text: text, Align: AlignCenter})
+		}
+	}
+
+	return rows
+}
+
+// amount formats a money amount
+func amount(value float64) string {
+	return fmt.Sprintf("%.2f", value)
+}
+
+// columns puts left and right on one line of width characters, shortening
+// left to fit
+func columns(left, right string, width int) string {
+	left, right = sanitize(left), sanitize(right)
+	if len(right) >= width {
+		return clip(right, width)
+	}
+	room := width - len(right) - 1
+	left = clip(left, room)
+	return left + strings.Repeat(" ", width-len(left)-len(right)) + right
+}
+
+// wrap breaks text into lines of at most width characters at spaces,
+// splitting words longer than a line
+func wrap(text string, width int) []string {
+	text = sanitize(text)
+	if strings.TrimSpace(text) == "" {
+		return []string{""}
+	}
+
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		for len(word) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			lines = append(lines, word[:width])
+			word = word[width:]
+		}
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// clip shortens text to at most width characters
+func clip(text string, width int) string {
+	text = sanitize(text)
+	if width <= 0 {
+		return ""
+	}
+	if len(text) > width {
+		return text[:width]
+	}
+	return text
+}
+
+// sanitize replaces characters outside printable ASCII with '?'
+func sanitize(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for _, r := range text {
+		switch {
+		case r == '\t':
+			b.WriteByte(' ')
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
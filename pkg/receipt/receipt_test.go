@@ -0,0 +1,103 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sample() *Receipt {
+	return &Receipt{
+		Width:  32,
+		Header: []string{"Corner Store", "1 Main Street, Springfield"},
+		Meta:   []string{"Order 1234"},
+		Items: []Item{
+			{Name: "Coffee", Quantity: 2, UnitPrice: 3.5, Amount: 7},
+			{Name: "Croissant with a very long name indeed", Quantity: 1, UnitPrice: 2.25, Discount: 0.25, Amount: 2},
+		},
+		Totals: []Total{
+			{Label: "Subtotal", Amount: 9},
+			{Label: "TOTAL", Amount: 9, Grand: true},
+		},
+		Footer:   []string{"Thank you (come again)"},
+		Barcode:  "1234",
+		Currency: "USD",
+	}
+}
+
+func TestLayoutFitsLineWidth(t *testing.T) {
+	rows := sample().Layout()
+
+	for _, row := range rows {
+		limit := 32
+		if row.Large {
+			limit = 16
+		}
+		assert.LessOrEqual(t, len(row.Text), limit, row.Text)
+	}
+
+	texts := make([]string, len(rows))
+	for i, row := range rows {
+		texts[i] = row.Text
+	}
+	assert.Contains(t, texts, "Coffee                      7.00")
+	assert.Contains(t, texts, "  2 x 3.50")
+	assert.Contains(t, texts, "  Discount                 -0.25")
+	assert.Contains(t, texts, "TOTAL                   USD 9.00")
+
+	assert.Equal(t, Row{Text: "Corner Store", Align: AlignCenter, Bold: true, Large: true}, rows[0])
+}
+
+func TestLayoutWrapsAndSanitizes(t *testing.T) {
+	assert.Equal(t, []string{"one two", "three"}, wrap("one two three", 8))
+	assert.Equal(t, []string{"abcd", "efgh", "ij"}, wrap("abcdefghij", 4))
+	assert.Equal(t, "Caf? ?", sanitize("Café €"))
+	assert.Equal(t, minWidth, (&Receipt{Width: 10}).LineWidth())
+	assert.Equal(t, DefaultWidth, (&Receipt{}).LineWidth())
+}
+
+func TestESCPOS(t *testing.T) {
+	r := sample()
+	out := ESCPOS(r)
+
+	assert.True(t, bytes.HasPrefix(out, []byte{esc, '@'}))
+	assert.Contains(t, string(out), "Coffee                      7.00\n")
+	assert.Contains(t, string(out), string([]byte{gs, 'k', 73, 6, '{', 'B', '1', '2', '3', '4'}))
+	assert.True(t, bytes.HasSuffix(out, []byte{gs, 'V', 'B', 3}), "cut ends a receipt without a drawer kick")
+
+	r.OpenDrawer = true
+	r.DrawerPin = DrawerPin5
+	assert.True(t, bytes.HasSuffix(ESCPOS(r), []byte{gs, 'V', 'B', 3, esc, 'p', 1, drawerPulseOn, drawerPulseOff}))
+	assert.Equal(t, []byte{esc, '@', esc, 'p', 0, drawerPulseOn, drawerPulseOff}, DrawerKick(DrawerPin2))
+}
+
+func TestPDF(t *testing.T) {
+	doc := PDF(sample())
+	text := string(doc)
+
+	assert.True(t, strings.HasPrefix(text, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(text, "%%EOF\n"))
+	assert.Contains(t, text, `(Thank you \(come again\))`)
+	assert.Contains(t, text, "/BaseFont /Courier-Bold")
+
+	// Every xref entry points at its object
+	xref := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(text)
+	require.Len(t, xref, 2)
+	start, err := strconv.Atoi(xref[1])
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(text[start:], "xref\n0 7\n"))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(text[start:], -1)
+	require.Len(t, entries, 6)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(entry[1])
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(text[offset:], fmt.Sprintf("%d 0 obj\n", i+1)))
+	}
+}
@@ -0,0 +1,299 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// resumeBatch is how many lapsed executions a resume pass claims at most
+const resumeBatch = 50
+
+// Orchestrator starts sagas and resumes those interrupted elsewhere. Each
+// instance of a service running sagas has one, with every saga it may need
+// to resume registered before Run is called.
+type Orchestrator struct {
+	store Store
+	cfg   config.SagaConfig
+	owner uuid.UUID // Identifies this instance's leases
+	retry performance.RetryPolicy
+
+	mu          sync.RWMutex
+	definitions map[string]*Definition
+}
+
+// NewOrchestrator creates an orchestrator persisting executions in store
+func NewOrchestrator(store Store, cfg config.SagaConfig) *Orchestrator {
+	return &Orchestrator{
+		store: store,
+		cfg:   cfg,
+		owner: uuid.New(),
+		retry: performance.RetryPolicy{
+			Name:        "saga",
+			MaxAttempts: cfg.StepAttempts,
+			BaseDelay:   500 * time.Millisecond,
+			MaxDelay:    10 * time.Second,
+		},
+		definitions: map[string]*Definition{},
+	}
+}
+
+// Register adds a saga the orchestrator can start and resume
+func (o *Orchestrator) Register(def Definition) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.definitions[def.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateDef, def.Name)
+	}
+	o.definitions[def.Name] = &def
+	return nil
+}
+
+// definition returns a registered saga
+func (o *Orchestrator) definition(name string) (*Definition, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	def, ok := o.definitions[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSaga, name)
+	}
+	return def, nil
+}
+
+// Start runs a new execution of a saga for the tenant in ctx, with data
+// seeding the execution's data, and returns it once it has finished. When a
+// step fails, the execution is returned compensated or failed with the
+// step's error. Should ctx end first, the execution is left to be resumed.
+// An execution left waiting by a step is returned with ErrAwaiting.
+func (o *Orchestrator) Start(ctx context.Context, name string, data map[string]interface{}) (*Execution, error) {
+	def, err := o.definition(name)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Execution{
+		ID:       uuid.New(),
+		Saga:     name,
+		TenantID: tenant.IDFromContext(ctx),
+		Status:   StatusRunning,
+	}
+	for key, value := range data {
+		if err := e.Set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	if def.Timeout > 0 {
+		deadline := time.Now().Add(def.Timeout).UTC()
+		e.DeadlineAt = &deadline
+	}
+
+	if err := o.store.Create(ctx, e, o.owner, o.cfg.Lease); err != nil {
+		return nil, err
+	}
+	return e, o.run(ctx, def, e)
+}
+
+// Resume claims executions whose lease lapsed, such as those of a crashed
+// instance or waiting past their wait, and runs them to the end, returning
+// how many it resumed
+func (o *Orchestrator) Resume(ctx context.Context) (int, error) {
+	names := o.names()
+	if len(names) == 0 {
+		return 0, nil
+	}
+
+	claimed, err := o.store.ClaimLapsed(ctx, names, o.owner, o.cfg.Lease, resumeBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	log := logger.FromContext(ctx)
+	for _, e := range claimed {
+		def, err := o.definition(e.Saga)
+		if err != nil {
+			return 0, err
+		}
+
+		log.Infof("Resuming %s saga %s at step %d (%s)", e.Saga, e.ID, e.Step, e.Status)
+		ctx := tenant.NewContext(ctx, &tenant.Tenant{ID: e.TenantID, Source: tenant.SourceJob})
+		if err := o.run(ctx, def, e); err != nil && !e.Status.Finished() && !errors.Is(err, ErrAwaiting) {
+			log.Errorf("Failed to resume %s saga %s: %v", e.Saga, e.ID, err)
+		}
+	}
+	return len(claimed), nil
+}
+
+// Wake takes the step a waiting execution awaits again at once, such as when
+// the event reporting its outcome arrives, and runs the execution on from
+// there as Start does. It returns ErrNotFound when the execution is not
+// waiting, as when it moved on already.
+func (o *Orchestrator) Wake(ctx context.Context, id uuid.UUID) (*Execution, error) {
+	e, err := o.store.Wake(ctx, id, o.names(), o.owner, o.cfg.Lease)
+	if err != nil {
+		return nil, err
+	}
+	def, err := o.definition(e.Saga)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = tenant.NewContext(ctx, &tenant.Tenant{ID: e.TenantID, Source: tenant.SourceJob})
+	return e, o.run(ctx, def, e)
+}
+
+// names lists the registered sagas
+func (o *Orchestrator) names() []string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	names := make([]string, 0, len(o.definitions))
+	for name := range o.definitions {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Run resumes lapsed executions every cfg.ResumeInterval until ctx ends
+func (o *Orchestrator) Run(ctx context.Context) {
+	log := logger.FromContext(ctx)
+
+	resume := func() {
+		if _, err := o.Resume(ctx); err != nil {
+			log.Errorf("Failed to resume sagas: %v", err)
+		}
+	}
+
+	resume()
+
+	ticker := time.NewTicker(o.cfg.ResumeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			resume()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// run takes the remaining steps of an execution, then compensates them if
+// one failed, saving its progress after each. It returns the error of the
+// step that failed, or why the execution could not go on.
+func (o *Orchestrator) run(ctx context.Context, def *Definition, e *Execution) error {
+	log := logger.FromContext(ctx)
+	var failure error
+	if e.Error != "" {
+		failure = errors.New(e.Error)
+	}
+
+	for e.Status == StatusRunning && e.Step < len(def.Steps) {
+		step := def.Steps[e.Step]
+		e.Waiting = false
+
+		err := ErrDeadline
+		if e.DeadlineAt == nil || time.Now().Before(*e.DeadlineAt) {
+			err = o.attempt(ctx, step, step.Action, e)
+		}
+
+		// The step is taken again once woken, or once the lease for its wait lapses
+		var await *awaitError
+		if errors.As(err, &await) {
+			e.Waiting = true
+			if err := o.store.Save(ctx, e, o.owner, await.retryAfter); err != nil {
+				return err
+			}
+			return ErrAwaiting
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err() // Shutting down; resumed once the lease lapses
+			}
+
+			// The failed step may have taken effect, so it is compensated too
+			failure = fmt.Errorf("%s: %w", step.Name, err)
+			log.Warnf("Step %s of %s saga %s failed, compensating: %v", step.Name, e.Saga, e.ID, err)
+			e.Status = StatusCompensating
+			e.Step++
+			e.FailedStep = step.Name
+			e.Error = failure.Error()
+		} else {
+			e.Step++
+		}
+		if err := o.store.Save(ctx, e, o.owner, o.cfg.Lease); err != nil {
+			return err
+		}
+	}
+
+	if e.Status == StatusRunning {
+		e.Status = StatusCompleted
+		return o.store.Save(ctx, e, o.owner, o.cfg.Lease)
+	}
+
+	for e.Status == StatusCompensating {
+		if e.Step == 0 {
+			e.Status = StatusCompensated
+			if err := o.store.Save(ctx, e, o.owner, o.cfg.Lease); err != nil {
+				return err
+			}
+			break
+		}
+
+		step := def.Steps[e.Step-1]
+		if step.Compensate != nil {
+			if err := o.attempt(ctx, step, step.Compensate, e); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				log.Errorf("Compensating step %s of %s saga %s failed; it needs manual repair: %v", step.Name, e.Saga, e.ID, err)
+				e.Status = StatusFailed
+				e.Error = fmt.Sprintf("%s; compensating %s: %v", e.Error, step.Name, err)
+				if err := o.store.Save(ctx, e, o.owner, o.cfg.Lease); err != nil {
+					return err
+				}
+				break
+			}
+		}
+
+		e.Step--
+		if err := o.store.Save(ctx, e, o.owner, o.cfg.Lease); err != nil {
+			return err
+		}
+	}
+
+	return failure
+}
+
+// attempt calls fn for a step, retrying failures, with each attempt limited
+// to the step's timeout. Errors marked performance.Permanent, and Await, are
+// not retried.
+func (o *Orchestrator) attempt(ctx context.Context, step Step, fn func(context.Context, *Execution) error, e *Execution) error {
+	timeout := step.Timeout
+	if timeout <= 0 {
+		timeout = o.cfg.StepTimeout
+	}
+
+	return performance.Retry(ctx, o.retry, func(int) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		err := fn(ctx, e)
+		if errors.Is(err, ErrAwaiting) {
+			return performance.Permanent(err)
+		}
+		return err
+	})
+}
@@ -0,0 +1,141 @@
+// Package saga runs workflows spanning several services, such as returns,
+// stock transfers and tenant provisioning, as sagas: a sequence of steps each
+// paired with a compensation that undoes it. When a step fails, the steps
+// already taken are compensated in reverse order. Progress is persisted after
+// every step, so a saga interrupted by a crash is resumed where it stopped by
+// whichever instance finds its lease lapsed.
+//
+// A step may run more than once (after a timeout, a retry, or a crash before
+// its completion was recorded), and a failed step is compensated too, since
+// it may have taken effect before failing. Actions and compensations must
+// therefore be idempotent, and compensations must tolerate an action that
+// never happened.
+//
+// A step whose outcome arrives later, such as a payment the provider settles
+// after 3D Secure, returns Await. The execution then waits, holding no
+// instance, until Wake is called for it or its wait runs out, and the step
+// is taken again to find out the outcome.
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Errors of running and storing sagas
+var (
+	ErrNotFound     = errors.New("saga execution not found")
+	ErrUnknownSaga  = errors.New("unknown saga")
+	ErrLeaseLost    = errors.New("saga execution claimed by another instance")
+	ErrDeadline     = errors.New("saga deadline exceeded")
+	ErrDuplicateDef = errors.New("saga already registered")
+	// ErrAwaiting is returned for an execution left waiting by a step that
+	// returned Await
+	ErrAwaiting = errors.New("saga step awaiting its outcome")
+)
+
+// Await is returned by a step's Action that started something whose outcome
+// arrives later. The execution is left waiting, and the step is taken again
+// when Wake is called for it, or after retryAfter at the latest; it must
+// then look up the outcome rather than start over.
+func Await(retryAfter time.Duration) error {
+	return &awaitError{retryAfter: retryAfter}
+}
+
+// awaitError is returned by Await
+type awaitError struct {
+	retryAfter time.Duration
+}
+
+func (e *awaitError) Error() string {
+	return ErrAwaiting.Error()
+}
+
+// Is matches ErrAwaiting
+func (e *awaitError) Is(target error) bool {
+	return target == ErrAwaiting
+}
+
+// Status is where an execution is in its lifecycle
+type Status string
+
+const (
+	StatusRunning      Status = "running"      // Taking its steps
+	StatusCompensating Status = "compensating" // A step failed; undoing the steps taken
+	StatusCompleted    Status = "completed"    // Every step succeeded
+	StatusCompensated  Status = "compensated"  // A step failed and every step taken was undone
+	StatusFailed       Status = "failed"       // A compensation failed too; needs manual repair
+)
+
+// Finished reports whether an execution with status s will take no more steps
+func (s Status) Finished() bool {
+	return s == StatusCompleted || s == StatusCompensated || s == StatusFailed
+}
+
+// Step is one step of a saga
+type Step struct {
+	Name string
+	// Action takes the step. It can read what earlier steps recorded in the
+	// execution's data and record what later steps or its compensation need.
+	Action func(ctx context.Context, e *Execution) error
+	// Compensate undoes Action; nil when there is nothing to undo
+	Compensate func(ctx context.Context, e *Execution) error
+	// Timeout limits one attempt of Action or Compensate; 0 uses the
+	// orchestrator's step timeout
+	Timeout time.Duration
+}
+
+// Definition describes a saga
+type Definition struct {
+	Name  string
+	Steps []Step
+	// Timeout limits how long the saga may take its steps, counted from when
+	// it starts; a saga still running after it is compensated. 0 sets no limit.
+	Timeout time.Duration
+}
+
+// Execution is one run of a saga, as persisted between steps
+type Execution struct {
+	ID       uuid.UUID `json:"id"`
+	Saga     string    `json:"saga"`
+	TenantID string    `json:"tenant_id,omitempty"`
+	Status   Status    `json:"status"`
+	// Step counts the steps taken while running, and the steps left to
+	// compensate while compensating
+	Step int `json:"step"`
+	// Waiting is set while the step is waiting for its outcome; see Await
+	Waiting    bool                       `json:"waiting,omitempty"`
+	Data       map[string]json.RawMessage `json:"data"`
+	FailedStep string                     `json:"failed_step,omitempty"`
+	Error      string                     `json:"error,omitempty"` // Why the saga is compensating or failed
+	DeadlineAt *time.Time                 `json:"deadline_at,omitempty"`
+	CreatedAt  time.Time                  `json:"created_at"`
+	UpdatedAt  time.Time                  `json:"updated_at"`
+}
+
+// Set records a value for later steps and compensations under key
+func (e *Execution) Set(key string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if e.Data == nil {
+		e.Data = map[string]json.RawMessage{}
+	}
+	e.Data[key] = raw
+	return nil
+}
+
+// Get reads the value recorded under key into value, reporting whether
+// there is one
+func (e *Execution) Get(key string, value interface{}) (bool, error) {
+	raw, ok := e.Data[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, value)
+}
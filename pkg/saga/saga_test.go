@@ -0,0 +1,361 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/performance"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// memStore is a Store keeping executions in memory; leases lapse when
+// expire is called
+type memStore struct {
+	mu         sync.Mutex
+	executions map[uuid.UUID]Execution
+	owners     map[uuid.UUID]uuid.UUID
+	lapsed     map[uuid.UUID]bool
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		executions: map[uuid.UUID]Execution{},
+		owners:     map[uuid.UUID]uuid.UUID{},
+		lapsed:     map[uuid.UUID]bool{},
+	}
+}
+
+func (s *memStore) Create(_ context.Context, e *Execution, owner uuid.UUID, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.CreatedAt, e.UpdatedAt = time.Now(), time.Now()
+	s.executions[e.ID] = *e
+	s.owners[e.ID] = owner
+	return nil
+}
+
+func (s *memStore) Save(_ context.Context, e *Execution, owner uuid.UUID, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owners[e.ID] != owner {
+		return ErrLeaseLost
+	}
+	s.executions[e.ID] = *e
+	s.lapsed[e.ID] = false
+	return nil
+}
+
+func (s *memStore) Get(_ context.Context, id uuid.UUID) (*Execution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.executions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &e, nil
+}
+
+func (s *memStore) ClaimLapsed(_ context.Context, sagas []string, owner uuid.UUID, _ time.Duration, limit int) ([]*Execution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []*Execution
+	for id, e := range s.executions {
+		if len(claimed) == limit || e.Status.Finished() || !s.lapsed[id] || !contains(sagas, e.Saga) {
+			continue
+		}
+		s.owners[id] = owner
+		s.lapsed[id] = false
+		e.Waiting = false
+		s.executions[id] = e
+		claimed = append(claimed, &e)
+	}
+	return claimed, nil
+}
+
+func (s *memStore) Wake(_ context.Context, id uuid.UUID, sagas []string, owner uuid.UUID, _ time.Duration) (*Execution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.executions[id]
+	if !ok || !e.Waiting || e.Status != StatusRunning || !contains(sagas, e.Saga) {
+		return nil, ErrNotFound
+	}
+	s.owners[id] = owner
+	s.lapsed[id] = false
+	e.Waiting = false
+	s.executions[id] = e
+	return &e, nil
+}
+
+// expire lets the lease of an execution lapse, as if its instance crashed
+func (s *memStore) expire(id uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lapsed[id] = true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+var testConfig = config.SagaConfig{
+	StepTimeout:    time.Second,
+	StepAttempts:   1,
+	Lease:          time.Minute,
+	ResumeInterval: time.Minute,
+}
+
+// transfer is a stock transfer saga recording what each step and
+// compensation did in log. fail names a step whose action fails.
+func transfer(log *[]string, fail string) Definition {
+	step := func(name string) Step {
+		return Step{
+			Name: name,
+			Action: func(_ context.Context, e *Execution) error {
+				*log = append(*log, name)
+				if name == fail {
+					return errors.New(name + " unavailable")
+				}
+				return e.Set(name, "done")
+			},
+			Compensate: func(_ context.Context, e *Execution) error {
+				*log = append(*log, "undo "+name)
+				return nil
+			},
+		}
+	}
+	return Definition{
+		Name:  "stock_transfer",
+		Steps: []Step{step("reserve"), step("ship"), step("receive")},
+	}
+}
+
+func TestStartCompletes(t *testing.T) {
+	var log []string
+	store := newMemStore()
+	o := NewOrchestrator(store, testConfig)
+	require.NoError(t, o.Register(transfer(&log, "")))
+	assert.ErrorIs(t, o.Register(transfer(&log, "")), ErrDuplicateDef)
+
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "acme"})
+	e, err := o.Start(ctx, "stock_transfer", map[string]interface{}{"quantity": 4})
+	require.NoError(t, err)
+
+	assert.Equal(t, StatusCompleted, e.Status)
+	assert.Equal(t, "acme", e.TenantID)
+	assert.Equal(t, []string{"reserve", "ship", "receive"}, log)
+
+	var quantity int
+	found, err := e.Get("quantity", &quantity)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 4, quantity)
+
+	stored, err := store.Get(ctx, e.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, stored.Status)
+	assert.Equal(t, 3, stored.Step)
+
+	_, err = o.Start(ctx, "returns", nil)
+	assert.ErrorIs(t, err, ErrUnknownSaga)
+}
+
+func TestStepFailureCompensatesInReverse(t *testing.T) {
+	var log []string
+	o := NewOrchestrator(newMemStore(), testConfig)
+	require.NoError(t, o.Register(transfer(&log, "ship")))
+
+	e, err := o.Start(context.Background(), "stock_transfer", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ship unavailable")
+
+	assert.Equal(t, StatusCompensated, e.Status)
+	assert.Equal(t, "ship", e.FailedStep)
+	assert.Equal(t, 0, e.Step)
+	// The failed step is compensated too, in case it took effect
+	assert.Equal(t, []string{"reserve", "ship", "undo ship", "undo reserve"}, log)
+}
+
+func TestCompensationFailureFails(t *testing.T) {
+	var log []string
+	def := transfer(&log, "receive")
+	def.Steps[1].Compensate = func(context.Context, *Execution) error {
+		return errors.New("carrier unreachable")
+	}
+
+	o := NewOrchestrator(newMemStore(), testConfig)
+	require.NoError(t, o.Register(def))
+
+	e, err := o.Start(context.Background(), "stock_transfer", nil)
+	require.Error(t, err)
+	assert.Equal(t, StatusFailed, e.Status)
+	assert.Equal(t, 2, e.Step, "the step that could not be compensated is kept")
+	assert.Contains(t, e.Error, "compensating ship: carrier unreachable")
+	assert.Equal(t, []string{"reserve", "ship", "receive", "undo receive"}, log)
+}
+
+func TestStepTimeoutAndRetry(t *testing.T) {
+	attempts := 0
+	def := Definition{
+		Name: "provision_tenant",
+		Steps: []Step{{
+			Name:    "create_schema",
+			Timeout: 10 * time.Millisecond,
+			Action: func(ctx context.Context, e *Execution) error {
+				attempts++
+				if attempts == 1 {
+					<-ctx.Done() // Hangs until the attempt times out
+					return ctx.Err()
+				}
+				return nil
+			},
+		}},
+	}
+
+	cfg := testConfig
+	cfg.StepAttempts = 2
+	o := NewOrchestrator(newMemStore(), cfg)
+	o.retry.BaseDelay = 0
+	require.NoError(t, o.Register(def))
+
+	e, err := o.Start(context.Background(), "provision_tenant", nil)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, e.Status)
+	assert.Equal(t, 2, attempts)
+
+	// Permanent errors are not retried
+	attempts = 0
+	def.Name = "provision_tenant_permanent"
+	def.Steps[0].Action = func(context.Context, *Execution) error {
+		attempts++
+		return performance.Permanent(errors.New("tenant exists"))
+	}
+	require.NoError(t, o.Register(def))
+	e, err = o.Start(context.Background(), "provision_tenant_permanent", nil)
+	require.Error(t, err)
+	assert.Equal(t, StatusCompensated, e.Status)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestSagaDeadline(t *testing.T) {
+	var log []string
+	def := transfer(&log, "")
+	def.Timeout = time.Nanosecond
+
+	o := NewOrchestrator(newMemStore(), testConfig)
+	require.NoError(t, o.Register(def))
+
+	e, err := o.Start(context.Background(), "stock_transfer", nil)
+	assert.ErrorIs(t, err, ErrDeadline)
+	assert.Equal(t, StatusCompensated, e.Status)
+	assert.Equal(t, []string{"undo reserve"}, log)
+}
+
+func TestResumeAfterCrash(t *testing.T) {
+	var log []string
+	store := newMemStore()
+
+	// An instance crashes after its first step was recorded
+	crashed := NewOrchestrator(store, testConfig)
+	def := transfer(&log, "")
+	def.Steps[1].Action = func(ctx context.Context, _ *Execution) error {
+		log = append(log, "ship")
+		return context.Canceled
+	}
+	require.NoError(t, crashed.Register(def))
+
+	ctx, cancel := context.WithCancel(tenant.NewContext(context.Background(), &tenant.Tenant{ID: "acme"}))
+	cancel()
+	e, err := crashed.Start(ctx, "stock_transfer", nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, StatusRunning, e.Status)
+	assert.Equal(t, 1, e.Step)
+	assert.Equal(t, []string{"reserve", "ship"}, log)
+
+	// Nothing is resumed while the lease holds
+	resumer := NewOrchestrator(store, testConfig)
+	var tenants []string
+	resumed := transfer(&log, "")
+	resumed.Steps[1].Action = func(ctx context.Context, e *Execution) error {
+		tenants = append(tenants, tenant.IDFromContext(ctx))
+		log = append(log, "ship")
+		return nil
+	}
+	require.NoError(t, resumer.Register(resumed))
+	n, err := resumer.Resume(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// Once it lapses, another instance takes the remaining steps
+	store.expire(e.ID)
+	log = nil
+	n, err = resumer.Resume(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"ship", "receive"}, log, "the recorded step is not taken again")
+	assert.Equal(t, []string{"acme"}, tenants, "steps run for the execution's tenant")
+
+	stored, err := store.Get(context.Background(), e.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, stored.Status)
+
+	// The crashed instance lost its lease
+	assert.ErrorIs(t, store.Save(context.Background(), e, crashed.owner, time.Minute), ErrLeaseLost)
+}
+
+func TestAwaitAndWake(t *testing.T) {
+	var log []string
+	settled := false
+	def := transfer(&log, "")
+	def.Steps[1].Action = func(_ context.Context, e *Execution) error {
+		log = append(log, "ship")
+		if !settled {
+			return Await(time.Hour)
+		}
+		return nil
+	}
+
+	store := newMemStore()
+	o := NewOrchestrator(store, testConfig)
+	require.NoError(t, o.Register(def))
+
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "acme"})
+	e, err := o.Start(ctx, "stock_transfer", nil)
+	assert.ErrorIs(t, err, ErrAwaiting)
+	assert.Equal(t, StatusRunning, e.Status)
+	assert.Equal(t, 1, e.Step)
+	assert.Equal(t, []string{"reserve", "ship"}, log, "awaiting is not retried")
+
+	stored, err := store.Get(ctx, e.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.Waiting)
+
+	// Woken before its outcome, the step waits again
+	e, err = o.Wake(context.Background(), e.ID)
+	assert.ErrorIs(t, err, ErrAwaiting)
+	assert.True(t, e.Waiting)
+
+	// Once it has one, the execution runs on
+	settled = true
+	log = nil
+	e, err = o.Wake(context.Background(), e.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, e.Status)
+	assert.False(t, e.Waiting)
+	assert.Equal(t, []string{"ship", "receive"}, log)
+
+	_, err = o.Wake(context.Background(), e.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "only waiting executions are woken")
+}
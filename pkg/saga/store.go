@@ -0,0 +1,196 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/onichange/pos-system/pkg/database"
+)
+
+// Store persists executions. Each unfinished execution is leased to the
+// instance running it, identified by an owner ID; only the owner can save
+// it, until its lease lapses and another instance claims it.
+type Store interface {
+	// Create saves a new execution leased to owner
+	Create(ctx context.Context, e *Execution, owner uuid.UUID, lease time.Duration) error
+	// Save records an execution's progress and renews its lease, failing
+	// with ErrLeaseLost if owner no longer holds it
+	Save(ctx context.Context, e *Execution, owner uuid.UUID, lease time.Duration) error
+	// Get returns an execution by ID
+	Get(ctx context.Context, id uuid.UUID) (*Execution, error)
+	// ClaimLapsed leases to owner up to limit unfinished executions of the
+	// named sagas whose lease has lapsed, no longer waiting
+	ClaimLapsed(ctx context.Context, sagas []string, owner uuid.UUID, lease time.Duration, limit int) ([]*Execution, error)
+	// Wake leases a waiting execution of one of the named sagas to owner, no
+	// longer waiting, failing with ErrNotFound if it is not waiting
+	Wake(ctx context.Context, id uuid.UUID, sagas []string, owner uuid.UUID, lease time.Duration) (*Execution, error)
+}
+
+// executionColumns are the columns scanExecution reads
+const executionColumns = `
+	id, saga, tenant_id, status, step, waiting, data, failed_step, error, deadline_at, created_at, updated_at
+`
+
+// PostgresStore keeps executions in the saga_executions table of the
+// database of the service running them. Executions of every tenant are
+// kept together, so they can be resumed by a background job.
+type PostgresStore struct {
+	db database.Querier
+}
+
+// NewPostgresStore creates a Postgres-backed execution store
+func NewPostgresStore(db database.Querier) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Create saves a new execution leased to owner
+func (s *PostgresStore) Create(ctx context.Context, e *Execution, owner uuid.UUID, lease time.Duration) error {
+	data, err := json.Marshal(nonNil(e.Data))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO saga_executions (
+			id, saga, tenant_id, status, step, data, failed_step, error, deadline_at,
+			owner, lease_until
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW() + make_interval(secs => $11))
+		RETURNING created_at, updated_at
+	`
+
+	return s.db.QueryRow(ctx, query,
+		e.ID, e.Saga, e.TenantID, string(e.Status), e.Step, data, e.FailedStep, e.Error, e.DeadlineAt,
+		owner, lease.Seconds(),
+	).Scan(&e.CreatedAt, &e.UpdatedAt)
+}
+
+// Save records an execution's progress and renews its lease
+func (s *PostgresStore) Save(ctx context.Context, e *Execution, owner uuid.UUID, lease time.Duration) error {
+	data, err := json.Marshal(nonNil(e.Data))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE saga_executions SET
+			status = $1,
+			step = $2,
+			data = $3,
+			failed_step = $4,
+			error = $5,
+			lease_until = NOW() + make_interval(secs => $6),
+			waiting = $9,
+			updated_at = NOW()
+		WHERE id = $7 AND owner = $8
+		RETURNING updated_at
+	`
+
+	err = s.db.QueryRow(ctx, query,
+		string(e.Status), e.Step, data, e.FailedStep, e.Error, lease.Seconds(), e.ID, owner, e.Waiting,
+	).Scan(&e.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrLeaseLost
+	}
+	return err
+}
+
+// Get returns an execution by ID
+func (s *PostgresStore) Get(ctx context.Context, id uuid.UUID) (*Execution, error) {
+	query := `SELECT ` + executionColumns + ` FROM saga_executions WHERE id = $1`
+
+	e, err := scanExecution(s.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return e, err
+}
+
+// ClaimLapsed leases unfinished executions with a lapsed lease to owner.
+// Rows locked by a concurrent claim are skipped, so instances resuming
+// together claim different executions.
+func (s *PostgresStore) ClaimLapsed(ctx context.Context, sagas []string, owner uuid.UUID, lease time.Duration, limit int) ([]*Execution, error) {
+	query := `
+		UPDATE saga_executions SET
+			owner = $1,
+			lease_until = NOW() + make_interval(secs => $2),
+			waiting = FALSE,
+			updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM saga_executions
+			WHERE status IN ($3, $4) AND saga = ANY($5) AND lease_until < NOW()
+			ORDER BY lease_until
+			LIMIT $6
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + executionColumns
+
+	rows, err := s.db.Query(ctx, query,
+		owner, lease.Seconds(), string(StatusRunning), string(StatusCompensating), sagas, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var executions []*Execution
+	for rows.Next() {
+		e, err := scanExecution(rows)
+		if err != nil {
+			return nil, err
+		}
+		executions = append(executions, e)
+	}
+	return executions, rows.Err()
+}
+
+// Wake leases a waiting execution to owner. Only one of several instances
+// woken together claims it.
+func (s *PostgresStore) Wake(ctx context.Context, id uuid.UUID, sagas []string, owner uuid.UUID, lease time.Duration) (*Execution, error) {
+	query := `
+		UPDATE saga_executions SET
+			owner = $1,
+			lease_until = NOW() + make_interval(secs => $2),
+			waiting = FALSE,
+			updated_at = NOW()
+		WHERE id = $3 AND waiting AND status = $4 AND saga = ANY($5)
+		RETURNING ` + executionColumns
+
+	e, err := scanExecution(s.db.QueryRow(ctx, query, owner, lease.Seconds(), id, string(StatusRunning), sagas))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return e, err
+}
+
+// scanExecution scans executionColumns
+func scanExecution(row interface{ Scan(dest ...interface{}) error }) (*Execution, error) {
+	var e Execution
+	var status string
+	var data []byte
+
+	err := row.Scan(
+		&e.ID, &e.Saga, &e.TenantID, &status, &e.Step, &e.Waiting, &data, &e.FailedStep, &e.Error, &e.DeadlineAt,
+		&e.CreatedAt, &e.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	e.Status = Status(status)
+	if err := json.Unmarshal(data, &e.Data); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// nonNil stores a missing data map as an empty object
+func nonNil(data map[string]json.RawMessage) map[string]json.RawMessage {
+	if data == nil {
+		return map[string]json.RawMessage{}
+	}
+	return data
+}
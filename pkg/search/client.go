@@ -0,0 +1,196 @@
+// Package search is a small client of the OpenSearch REST API, covering the
+// index, delete and search calls the search service makes.
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// Client calls an OpenSearch cluster
+type Client struct {
+	baseURL  string
+	username string
+	password string
+	prefix   string
+	client   *http.Client
+}
+
+// Response is the part of a search response the client reads
+type Response struct {
+	Total        int64
+	Hits         []json.RawMessage // Source documents, in rank order
+	Aggregations map[string][]Bucket
+}
+
+// Bucket is one bucket of a terms or date histogram aggregation
+type Bucket struct {
+	Key   string `json:"key"` // Dates are RFC 3339
+	Count int64  `json:"count"`
+}
+
+// StatusError is returned when OpenSearch answers with an unexpected status
+type StatusError struct {
+	Method string
+	Path   string
+	Status int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("opensearch: %s %s: %d %s", e.Method, e.Path, e.Status, e.Body)
+}
+
+// NewClient creates a client of the cluster described by cfg
+func NewClient(cfg config.SearchConfig) *Client {
+	return &Client{
+		baseURL:  strings.TrimRight(cfg.URL, "/"),
+		username: cfg.Username,
+		password: cfg.Password,
+		prefix:   cfg.IndexPrefix,
+		client:   &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Index returns the full name of an index, with the configured prefix
+func (c *Client) Index(name string) string {
+	return c.prefix + name
+}
+
+// EnsureIndex creates an index with the given settings and mappings unless
+// it already exists. Mappings of an existing index are left as they are.
+func (c *Client) EnsureIndex(ctx context.Context, index string, body map[string]any) error {
+	err := c.do(ctx, http.MethodPut, "/"+url.PathEscape(index), body, nil)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Status == http.StatusBadRequest &&
+		strings.Contains(statusErr.Body, "resource_already_exists_exception") {
+		return nil
+	}
+	return err
+}
+
+// Put indexes a document under id. The version orders writes of the same
+// document, so an older one arriving late is ignored rather than
+// overwriting a newer one; equal versions overwrite.
+func (c *Client) Put(ctx context.Context, index, id string, version int64, doc any) error {
+	err := c.do(ctx, http.MethodPut, docPath(index, id, version), doc, nil)
+	if isStatus(err, http.StatusConflict) {
+		return nil
+	}
+	return err
+}
+
+// Delete removes a document, unless a newer version of it was indexed.
+// Documents that do not exist are ignored.
+func (c *Client) Delete(ctx context.Context, index, id string, version int64) error {
+	err := c.do(ctx, http.MethodDelete, docPath(index, id, version), nil, nil)
+	if isStatus(err, http.StatusConflict) || isStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Search runs a query against an index
+func (c *Client) Search(ctx context.Context, index string, query map[string]any) (*Response, error) {
+	var raw struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				Source json.RawMessage `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+		Aggregations map[string]struct {
+			Buckets []struct {
+				Key         any    `json:"key"`
+				KeyAsString string `json:"key_as_string"`
+				DocCount    int64  `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"aggregations"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_search", query, &raw); err != nil {
+		return nil, err
+	}
+
+	resp := &Response{
+		Total:        raw.Hits.Total.Value,
+		Hits:         make([]json.RawMessage, 0, len(raw.Hits.Hits)),
+		Aggregations: make(map[string][]Bucket, len(raw.Aggregations)),
+	}
+	for _, hit := range raw.Hits.Hits {
+		resp.Hits = append(resp.Hits, hit.Source)
+	}
+	for name, agg := range raw.Aggregations {
+		buckets := make([]Bucket, 0, len(agg.Buckets))
+		for _, b := range agg.Buckets {
+			key := b.KeyAsString
+			if key == "" {
+				key = fmt.Sprint(b.Key)
+			}
+			buckets = append(buckets, Bucket{Key: key, Count: b.DocCount})
+		}
+		resp.Aggregations[name] = buckets
+	}
+	return resp, nil
+}
+
+// do sends a JSON request and decodes the JSON response into out, if given
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("opensearch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &StatusError{Method: method, Path: req.URL.Path, Status: resp.StatusCode, Body: string(detail)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// docPath returns the path of a document with external versioning
+func docPath(index, id string, version int64) string {
+	return "/" + url.PathEscape(index) + "/_doc/" + url.PathEscape(id) +
+		"?version_type=external_gte&version=" + strconv.FormatInt(version, 10)
+}
+
+// isStatus reports whether err is a StatusError with the given status
+func isStatus(err error, status int) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.Status == status
+}
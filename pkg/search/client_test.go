@@ -0,0 +1,94 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(config.SearchConfig{URL: server.URL, Username: "indexer", Password: "secret", IndexPrefix: "test-", Timeout: time.Second})
+}
+
+func TestPutUsesExternalVersions(t *testing.T) {
+	var stale bool
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		assert.Equal(t, "indexer", user)
+		assert.Equal(t, "secret", password)
+		assert.Equal(t, "/test-orders/_doc/o-1", r.URL.Path)
+		assert.Equal(t, "external_gte", r.URL.Query().Get("version_type"))
+		if stale {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		assert.Equal(t, "42", r.URL.Query().Get("version"))
+		w.WriteHeader(http.StatusCreated)
+	})
+	ctx := context.Background()
+
+	require.NoError(t, client.Put(ctx, client.Index("orders"), "o-1", 42, map[string]string{"status": "pending"}))
+
+	stale = true
+	assert.NoError(t, client.Put(ctx, client.Index("orders"), "o-1", 41, map[string]string{"status": "draft"}), "older versions are ignored")
+}
+
+func TestDeleteIgnoresMissingDocuments(t *testing.T) {
+	status := http.StatusNotFound
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		w.WriteHeader(status)
+	})
+
+	assert.NoError(t, client.Delete(context.Background(), client.Index("products"), "p-1", 1))
+
+	status = http.StatusServiceUnavailable
+	err := client.Delete(context.Background(), client.Index("products"), "p-1", 1)
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.Status)
+}
+
+func TestEnsureIndexToleratesExistingIndex(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"type":"resource_already_exists_exception"}}`))
+	})
+
+	assert.NoError(t, client.EnsureIndex(context.Background(), client.Index("orders"), map[string]any{}))
+}
+
+func TestSearchReadsHitsAndBuckets(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/test-orders/_search", r.URL.Path)
+		var query map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&query))
+		assert.Contains(t, query, "query")
+
+		_, _ = w.Write([]byte(`{
+			"hits": {"total": {"value": 2}, "hits": [{"_source": {"id": "o-1"}}, {"_source": {"id": "o-2"}}]},
+			"aggregations": {
+				"status": {"buckets": [{"key": "pending", "doc_count": 2}]},
+				"created": {"buckets": [{"key": 1767225600000, "key_as_string": "2026-01-01T00:00:00.000Z", "doc_count": 2}]}
+			}
+		}`))
+	})
+
+	resp, err := client.Search(context.Background(), client.Index("orders"), map[string]any{"query": map[string]any{"match_all": map[string]any{}}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.Total)
+	require.Len(t, resp.Hits, 2)
+	assert.JSONEq(t, `{"id":"o-2"}`, string(resp.Hits[1]))
+	assert.Equal(t, []Bucket{{Key: "pending", Count: 2}}, resp.Aggregations["status"])
+	assert.Equal(t, []Bucket{{Key: "2026-01-01T00:00:00.000Z", Count: 2}}, resp.Aggregations["created"])
+}
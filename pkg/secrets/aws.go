@@ -0,0 +1,175 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// awsService is the SigV4 service name for Secrets Manager
+const awsService = "secretsmanager"
+
+// AWSProvider reads secrets from AWS Secrets Manager
+type AWSProvider struct {
+	service         string // SigV4 service name
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	endpoint        string
+	client          *http.Client
+}
+
+// NewAWSProvider creates a Secrets Manager provider for a region
+func NewAWSProvider(region, accessKeyID, secretAccessKey, sessionToken string) *AWSProvider {
+	return &AWSProvider{
+		service:         awsService,
+		region:          region,
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		sessionToken:    sessionToken,
+		endpoint:        fmt.Sprintf("https://%s.%s.amazonaws.com/", awsService, region),
+		client:          &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// GetSecret reads the current version of a secret. JSON object secrets are returned
+// key by key; plain string secrets are returned under the "value" key.
+func (a *AWSProvider) GetSecret(ctx context.Context, path string) (map[string]string, error) {
+	var result struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := a.do(ctx, "secretsmanager.GetSecretValue", map[string]string{"SecretId": path}, &result); err != nil {
+		return nil, err
+	}
+	return parseSecretString(result.SecretString), nil
+}
+
+// do calls an AWS JSON API action and decodes the response into out
+func (a *AWSProvider) do(ctx context.Context, target string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	a.sign(req, body, time.Now().UTC())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", a.service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&apiErr)
+		if strings.HasSuffix(apiErr.Type, "NotFoundException") {
+			return fmt.Errorf("%w: %s", ErrNotFound, apiErr.Message)
+		}
+		return fmt.Errorf("%s returned %d: %s %s", a.service, resp.StatusCode, apiErr.Type, apiErr.Message)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", a.service, err)
+	}
+	return nil
+}
+
+// parseSecretString decodes a JSON object secret, falling back to a single value
+func parseSecretString(secret string) map[string]string {
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &object); err != nil {
+		return map[string]string{"value": secret}
+	}
+
+	values := make(map[string]string, len(object))
+	for key, value := range object {
+		values[key] = fmt.Sprint(value)
+	}
+	return values
+}
+
+// sign adds AWS Signature Version 4 headers to a request
+func (a *AWSProvider) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if a.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.sessionToken)
+	}
+
+	// Canonical headers must be sorted by name
+	headers := [][2]string{
+		{"content-type", req.Header.Get("Content-Type")},
+		{"host", req.URL.Host},
+		{"x-amz-date", amzDate},
+	}
+	if a.sessionToken != "" {
+		headers = append(headers, [2]string{"x-amz-security-token", a.sessionToken})
+	}
+	headers = append(headers, [2]string{"x-amz-target", req.Header.Get("X-Amz-Target")})
+
+	var canonicalHeaders strings.Builder
+	names := make([]string, 0, len(headers))
+	for _, h := range headers {
+		canonicalHeaders.WriteString(h[0] + ":" + h[1] + "\n")
+		names = append(names, h[0])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"", // query string
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, a.region, a.service)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+a.secretAccessKey), date)
+	key = hmacSHA256(key, a.region)
+	key = hmacSHA256(key, a.service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		a.accessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,275 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/encryption"
+)
+
+// NewKeyring creates the keyring of the master key versions in config: the
+// current key, and previous keys kept to decrypt. Vault and AWS credentials
+// are shared with the secrets provider settings. It returns nil when the
+// local KMS has no key, leaving encryption disabled in development.
+func NewKeyring(cfg config.EncryptionConfig, secretsCfg config.SecretsConfig) (*encryption.Keyring, error) {
+	if (cfg.KMS == "" || cfg.KMS == "local") && cfg.LocalKey == "" {
+		return nil, nil
+	}
+	current, err := newKeyWrapper(cfg.KeyID, cfg.LocalKey, cfg, secretsCfg)
+	if err != nil {
+		return nil, err
+	}
+	keyring := encryption.NewKeyring(cfg.KeyVersion, current)
+	for _, previous := range cfg.PreviousKeys {
+		wrapper, err := newKeyWrapper(previous.KeyID, previous.LocalKey, cfg, secretsCfg)
+		if err != nil {
+			return nil, fmt.Errorf("key version %d: %w", previous.Version, err)
+		}
+		if err := keyring.Add(previous.Version, wrapper); err != nil {
+			return nil, err
+		}
+	}
+	return keyring, nil
+}
+
+// newKeyWrapper creates the wrapper for one master key held by cfg's KMS
+func newKeyWrapper(keyID, localKey string, cfg config.EncryptionConfig, secretsCfg config.SecretsConfig) (encryption.KeyWrapper, error) {
+	switch cfg.KMS {
+	case "", "local":
+		key, err := base64.StdEncoding.DecodeString(localKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid local encryption key: %w", err)
+		}
+		return encryption.NewLocalKeyWrapper(keyID, key)
+	case "vault":
+		return NewVaultTransit(NewVaultProvider(secretsCfg.VaultAddr, secretsCfg.VaultToken, secretsCfg.VaultMount), cfg.VaultTransitMount, keyID), nil
+	case "aws":
+		return NewAWSKMS(secretsCfg.AWSRegion, secretsCfg.AWSAccessKeyID, secretsCfg.AWSSecretAccessKey, secretsCfg.AWSSessionToken, keyID), nil
+	case "gcp":
+		return NewGCPKMS(keyID, cfg.GCPAccessToken), nil
+	default:
+		return nil, fmt.Errorf("unknown key management service %q", cfg.KMS)
+	}
+}
+
+// VaultTransit wraps data keys with a Vault Transit engine key
+type VaultTransit struct {
+	vault *VaultProvider
+	mount string
+	key   string
+}
+
+// NewVaultTransit creates a wrapper for a transit key
+func NewVaultTransit(vault *VaultProvider, mount, key string) *VaultTransit {
+	return &VaultTransit{vault: vault, mount: strings.Trim(mount, "/"), key: key}
+}
+
+// KeyID returns the transit key's path
+func (t *VaultTransit) KeyID() string {
+	return "vault:" + t.mount + "/" + t.key
+}
+
+// WrapKey encrypts dataKey with the transit key
+func (t *VaultTransit) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var response struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	request := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dataKey)}
+	if err := t.vault.do(ctx, http.MethodPost, fmt.Sprintf("/v1/%s/encrypt/%s", t.mount, t.key), request, &response); err != nil {
+		return nil, err
+	}
+	// Vault's ciphertext names the key version it used, e.g. vault:v3:...
+	return []byte(response.Data.Ciphertext), nil
+}
+
+// UnwrapKey decrypts a data key wrapped by WrapKey
+func (t *VaultTransit) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var response struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	request := map[string]string{"ciphertext": string(wrapped)}
+	if err := t.vault.do(ctx, http.MethodPost, fmt.Sprintf("/v1/%s/decrypt/%s", t.mount, t.key), request, &response); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(response.Data.Plaintext)
+}
+
+// AWSKMS wraps data keys with an AWS KMS key
+type AWSKMS struct {
+	aws   *AWSProvider
+	keyID string
+}
+
+// NewAWSKMS creates a wrapper for a KMS key ID, ARN, or alias
+func NewAWSKMS(region, accessKeyID, secretAccessKey, sessionToken, keyID string) *AWSKMS {
+	client := NewAWSProvider(region, accessKeyID, secretAccessKey, sessionToken)
+	client.service = "kms"
+	client.endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com/", region)
+	return &AWSKMS{aws: client, keyID: keyID}
+}
+
+// KeyID returns the KMS key
+func (k *AWSKMS) KeyID() string {
+	return "aws-kms:" + k.keyID
+}
+
+// WrapKey encrypts dataKey with the KMS key
+func (k *AWSKMS) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var response struct {
+		CiphertextBlob []byte `json:"CiphertextBlob"`
+	}
+	request := map[string]interface{}{"KeyId": k.keyID, "Plaintext": dataKey}
+	if err := k.aws.do(ctx, "TrentService.Encrypt", request, &response); err != nil {
+		return nil, err
+	}
+	return response.CiphertextBlob, nil
+}
+
+// UnwrapKey decrypts a data key wrapped by WrapKey
+func (k *AWSKMS) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var response struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	request := map[string]interface{}{"KeyId": k.keyID, "CiphertextBlob": wrapped}
+	if err := k.aws.do(ctx, "TrentService.Decrypt", request, &response); err != nil {
+		return nil, err
+	}
+	return response.Plaintext, nil
+}
+
+// gcpMetadataTokenURL serves access tokens for the instance's service account
+const gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// GCPKMS wraps data keys with a Cloud KMS key
+type GCPKMS struct {
+	name     string // projects/.../locations/.../keyRings/.../cryptoKeys/...
+	endpoint string
+	tokenURL string
+	client   *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time // Zero for a configured token, which never expires here
+}
+
+// NewGCPKMS creates a wrapper for a Cloud KMS key resource name. Without an
+// access token, tokens are fetched from the metadata server.
+func NewGCPKMS(name, accessToken string) *GCPKMS {
+	return &GCPKMS{
+		name:     name,
+		endpoint: "https://cloudkms.googleapis.com/v1/",
+		tokenURL: gcpMetadataTokenURL,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		token:    accessToken,
+	}
+}
+
+// KeyID returns the key's resource name
+func (g *GCPKMS) KeyID() string {
+	return "gcp-kms:" + g.name
+}
+
+// WrapKey encrypts dataKey with the Cloud KMS key
+func (g *GCPKMS) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var response struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	if err := g.do(ctx, "encrypt", map[string][]byte{"plaintext": dataKey}, &response); err != nil {
+		return nil, err
+	}
+	return response.Ciphertext, nil
+}
+
+// UnwrapKey decrypts a data key wrapped by WrapKey
+func (g *GCPKMS) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var response struct {
+		Plaintext []byte `json:"plaintext"`
+	}
+	if err := g.do(ctx, "decrypt", map[string][]byte{"ciphertext": wrapped}, &response); err != nil {
+		return nil, err
+	}
+	return response.Plaintext, nil
+}
+
+// do calls a Cloud KMS key method and decodes the response into out
+func (g *GCPKMS) do(ctx context.Context, method string, in, out interface{}) error {
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.endpoint+g.name+":"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cloud kms request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, g.name)
+	}
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("cloud kms returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// accessToken returns the configured token, or a metadata server token
+// refreshed a minute before it expires
+func (g *GCPKMS) accessToken(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && (g.expiresAt.IsZero() || time.Now().Before(g.expiresAt.Add(-time.Minute))) {
+		return g.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.tokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch gcp access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("gcp metadata server returned %d", resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode gcp access token: %w", err)
+	}
+	g.token = token.AccessToken
+	g.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return g.token, nil
+}
@@ -0,0 +1,149 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/encryption"
+)
+
+// reverse stands in for a KMS cipher
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
+
+func TestVaultTransitWrapsDataKeys(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-token", r.Header.Get("X-Vault-Token"))
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/pii":
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{
+				"ciphertext": "vault:v1:" + body["plaintext"],
+			}})
+		case "/v1/transit/decrypt/pii":
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{
+				"plaintext": strings.TrimPrefix(body["ciphertext"], "vault:v1:"),
+			}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	transit := NewVaultTransit(NewVaultProvider(vault.URL, "test-token", "secret"), "transit", "pii")
+	assert.Equal(t, "vault:transit/pii", transit.KeyID())
+
+	e := encryption.NewEnvelope(transit)
+	ciphertext, err := e.Encrypt(context.Background(), []byte("+84 912 345 678"), nil)
+	require.NoError(t, err)
+	plaintext, err := e.Decrypt(context.Background(), ciphertext, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "+84 912 345 678", string(plaintext))
+}
+
+func TestAWSKMSWrapsDataKeys(t *testing.T) {
+	kms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/kms/aws4_request")
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "alias/pii", body["KeyId"])
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			plaintext, _ := base64.StdEncoding.DecodeString(body["Plaintext"].(string))
+			_ = json.NewEncoder(w).Encode(map[string][]byte{"CiphertextBlob": reverse(plaintext)})
+		case "TrentService.Decrypt":
+			blob, _ := base64.StdEncoding.DecodeString(body["CiphertextBlob"].(string))
+			_ = json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": reverse(blob)})
+		}
+	}))
+	defer kms.Close()
+
+	wrapper := NewAWSKMS("eu-west-1", "AKID", "secret", "", "alias/pii")
+	wrapper.aws.endpoint = kms.URL + "/"
+
+	dataKey := []byte("0123456789abcdef0123456789abcdef")
+	wrapped, err := wrapper.WrapKey(context.Background(), dataKey)
+	require.NoError(t, err)
+	assert.NotEqual(t, dataKey, wrapped)
+	unwrapped, err := wrapper.UnwrapKey(context.Background(), wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, unwrapped)
+}
+
+func TestGCPKMSUsesMetadataToken(t *testing.T) {
+	tokens := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+			tokens++
+			_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "ya29.test", "expires_in": 3600})
+			return
+		}
+
+		assert.Equal(t, "Bearer ya29.test", r.Header.Get("Authorization"))
+		var body map[string][]byte
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":encrypt"):
+			_ = json.NewEncoder(w).Encode(map[string][]byte{"ciphertext": reverse(body["plaintext"])})
+		case strings.HasSuffix(r.URL.Path, ":decrypt"):
+			_ = json.NewEncoder(w).Encode(map[string][]byte{"plaintext": reverse(body["ciphertext"])})
+		}
+	}))
+	defer server.Close()
+
+	wrapper := NewGCPKMS("projects/p/locations/global/keyRings/r/cryptoKeys/pii", "")
+	wrapper.endpoint = server.URL + "/v1/"
+	wrapper.tokenURL = server.URL + "/token"
+
+	e := encryption.NewEnvelope(wrapper)
+	ciphertext, err := e.Encrypt(context.Background(), []byte("12 Main St"), nil)
+	require.NoError(t, err)
+	plaintext, err := e.Decrypt(context.Background(), ciphertext, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "12 Main St", string(plaintext))
+	assert.Equal(t, 1, tokens, "the token is reused until it nears expiry")
+}
+
+func TestNewKeyringLoadsPreviousKeys(t *testing.T) {
+	keyring, err := NewKeyring(config.EncryptionConfig{KMS: "local", KeyID: "dev"}, config.SecretsConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, keyring, "no local key leaves encryption disabled")
+
+	key := func(b byte) string { return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32)) }
+	keyring, err = NewKeyring(config.EncryptionConfig{
+		KMS:        "local",
+		KeyID:      "dev-2",
+		LocalKey:   key(2),
+		KeyVersion: 2,
+		PreviousKeys: []config.EncryptionKeyConfig{
+			{Version: 1, KeyID: "dev-1", LocalKey: key(1)},
+		},
+	}, config.SecretsConfig{})
+	require.NoError(t, err)
+
+	version, current := keyring.Current()
+	assert.Equal(t, uint32(2), version)
+	assert.Equal(t, "dev-2", current.KeyID())
+	previous, ok := keyring.Version(1)
+	require.True(t, ok)
+	assert.Equal(t, "dev-1", previous.KeyID())
+}
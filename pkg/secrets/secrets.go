@@ -0,0 +1,175 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// ErrNotFound is returned when a secret or key does not exist
+var ErrNotFound = errors.New("secret not found")
+
+// Provider fetches secrets from a secrets backend. A secret is a set of key/value pairs.
+type Provider interface {
+	GetSecret(ctx context.Context, path string) (map[string]string, error)
+}
+
+// NewProvider creates the provider selected in config, wrapped in a cache.
+// It returns nil for the "env" provider, where secrets come from environment variables.
+func NewProvider(cfg config.SecretsConfig) (Provider, error) {
+	var provider Provider
+	switch cfg.Provider {
+	case "", "env":
+		return nil, nil
+	case "vault":
+		provider = NewVaultProvider(cfg.VaultAddr, cfg.VaultToken, cfg.VaultMount)
+	case "aws":
+		provider = NewAWSProvider(cfg.AWSRegion, cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.AWSSessionToken)
+	default:
+		return nil, fmt.Errorf("unknown secrets provider %q", cfg.Provider)
+	}
+
+	return NewCachedProvider(provider, cfg.CacheTTL), nil
+}
+
+// cachedSecret is a secret with its expiry time
+type cachedSecret struct {
+	values    map[string]string
+	expiresAt time.Time
+}
+
+// CachedProvider lazily fetches secrets and caches them for a TTL
+type CachedProvider struct {
+	provider Provider
+	ttl      time.Duration
+	group    singleflight.Group
+	mu       sync.RWMutex
+	entries  map[string]cachedSecret
+}
+
+// NewCachedProvider wraps a provider with a TTL cache
+func NewCachedProvider(provider Provider, ttl time.Duration) *CachedProvider {
+	return &CachedProvider{
+		provider: provider,
+		ttl:      ttl,
+		entries:  make(map[string]cachedSecret),
+	}
+}
+
+// GetSecret returns a cached secret, fetching it on first use or after expiry
+func (c *CachedProvider) GetSecret(ctx context.Context, path string) (map[string]string, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[path]
+	c.mu.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.values, nil
+	}
+
+	// Concurrent misses for the same path share one backend call
+	result, err, _ := c.group.Do(path, func() (interface{}, error) {
+		values, err := c.provider.GetSecret(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		c.entries[path] = cachedSecret{values: values, expiresAt: time.Now().Add(c.ttl)}
+		c.mu.Unlock()
+		return values, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(map[string]string), nil
+}
+
+// Invalidate drops a cached secret so the next read fetches it again
+func (c *CachedProvider) Invalidate(path string) {
+	c.mu.Lock()
+	delete(c.entries, path)
+	c.mu.Unlock()
+}
+
+// GetValue returns a single key of a secret
+func GetValue(ctx context.Context, provider Provider, path, key string) (string, error) {
+	values, err := provider.GetSecret(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("%w: %s#%s", ErrNotFound, path, key)
+	}
+	return value, nil
+}
+
+// Resolve fills JWT secrets and database credentials in cfg from the configured
+// secrets backend. With a Vault database role, dynamic credentials are leased and
+// renewed in the background for the lifetime of the process.
+func Resolve(ctx context.Context, cfg *config.Config, log *logger.Logger) error {
+	provider, err := NewProvider(cfg.Secrets)
+	if err != nil {
+		return err
+	}
+	if provider == nil {
+		return nil
+	}
+
+	if path := cfg.Secrets.JWTSecretPath; path != "" {
+		if cfg.JWT.AccessTokenSecret, err = GetValue(ctx, provider, path, "access_secret"); err != nil {
+			return fmt.Errorf("failed to resolve JWT access secret: %w", err)
+		}
+		if cfg.JWT.RefreshTokenSecret, err = GetValue(ctx, provider, path, "refresh_secret"); err != nil {
+			return fmt.Errorf("failed to resolve JWT refresh secret: %w", err)
+		}
+		// Secrets rotated out are optional, comma-separated like their env variables
+		values, err := provider.GetSecret(ctx, path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve JWT secrets: %w", err)
+		}
+		if previous := values["previous_access_secrets"]; previous != "" {
+			cfg.JWT.PreviousAccessTokenSecrets = strings.Split(previous, ",")
+		}
+		if previous := values["previous_refresh_secrets"]; previous != "" {
+			cfg.JWT.PreviousRefreshTokenSecrets = strings.Split(previous, ",")
+		}
+	}
+
+	if path := cfg.Secrets.DBSecretPath; path != "" {
+		values, err := provider.GetSecret(ctx, path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve database credentials: %w", err)
+		}
+		if user := values["username"]; user != "" {
+			cfg.Database.User = user
+		}
+		if password := values["password"]; password != "" {
+			cfg.Database.Password = password
+		}
+	}
+
+	if cfg.Secrets.Provider == "vault" && cfg.Secrets.VaultDBRole != "" {
+		vault := NewVaultProvider(cfg.Secrets.VaultAddr, cfg.Secrets.VaultToken, cfg.Secrets.VaultMount)
+		lease, err := NewDatabaseLease(ctx, vault, cfg.Secrets.VaultDBRole, log)
+		if err != nil {
+			return err
+		}
+		go lease.Run(context.Background())
+
+		cfg.Database.User, cfg.Database.Password = lease.Credentials()
+		cfg.Database.CredentialsFunc = lease.Credentials
+	}
+
+	log.Infof("Resolved secrets from %s provider", cfg.Secrets.Provider)
+	return cfg.ValidateSecrets()
+}
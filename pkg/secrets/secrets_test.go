@@ -0,0 +1,103 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+func newVaultServer(t *testing.T, calls *int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/pos/jwt":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"data": map[string]interface{}{"access_secret": "a", "refresh_secret": "r"},
+				},
+			})
+		case "/v1/database/creds/pos":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"lease_id":       "database/creds/pos/1",
+				"lease_duration": 3600,
+				"renewable":      true,
+				"data":           map[string]string{"username": "v-pos-1", "password": "p1"},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestVaultProviderGetSecret(t *testing.T) {
+	var calls int32
+	server := newVaultServer(t, &calls)
+	defer server.Close()
+
+	vault := NewVaultProvider(server.URL, "test-token", "secret")
+
+	values, err := vault.GetSecret(context.Background(), "pos/jwt")
+	require.NoError(t, err)
+	assert.Equal(t, "a", values["access_secret"])
+
+	_, err = vault.GetSecret(context.Background(), "pos/missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCachedProvider(t *testing.T) {
+	var calls int32
+	server := newVaultServer(t, &calls)
+	defer server.Close()
+
+	cached := NewCachedProvider(NewVaultProvider(server.URL, "test-token", "secret"), time.Minute)
+
+	for i := 0; i < 3; i++ {
+		value, err := GetValue(context.Background(), cached, "pos/jwt", "refresh_secret")
+		require.NoError(t, err)
+		assert.Equal(t, "r", value)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	cached.Invalidate("pos/jwt")
+	_, err := cached.GetSecret(context.Background(), "pos/jwt")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	_, err = GetValue(context.Background(), cached, "pos/jwt", "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDatabaseLease(t *testing.T) {
+	var calls int32
+	server := newVaultServer(t, &calls)
+	defer server.Close()
+
+	vault := NewVaultProvider(server.URL, "test-token", "secret")
+	lease, err := NewDatabaseLease(context.Background(), vault, "pos", logger.New("test"))
+	require.NoError(t, err)
+
+	user, password := lease.Credentials()
+	assert.Equal(t, "v-pos-1", user)
+	assert.Equal(t, "p1", password)
+}
+
+func TestParseSecretString(t *testing.T) {
+	assert.Equal(t, map[string]string{"username": "pos", "port": "5432"},
+		parseSecretString(`{"username":"pos","port":5432}`))
+	assert.Equal(t, map[string]string{"value": "plain"}, parseSecretString("plain"))
+}
@@ -0,0 +1,216 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// VaultProvider reads secrets from a HashiCorp Vault KV v2 engine
+type VaultProvider struct {
+	addr   string
+	token  string
+	mount  string
+	client *http.Client
+}
+
+// NewVaultProvider creates a Vault provider
+func NewVaultProvider(addr, token, mount string) *VaultProvider {
+	return &VaultProvider{
+		addr:   strings.TrimRight(addr, "/"),
+		token:  token,
+		mount:  strings.Trim(mount, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// GetSecret reads the latest version of a KV v2 secret
+func (v *VaultProvider) GetSecret(ctx context.Context, path string) (map[string]string, error) {
+	var response struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodGet, fmt.Sprintf("/v1/%s/data/%s", v.mount, strings.Trim(path, "/")), nil, &response); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(response.Data.Data))
+	for key, value := range response.Data.Data {
+		values[key] = fmt.Sprint(value)
+	}
+	return values, nil
+}
+
+// vaultLease is a lease on dynamic credentials
+type vaultLease struct {
+	LeaseID       string            `json:"lease_id"`
+	LeaseDuration int               `json:"lease_duration"`
+	Renewable     bool              `json:"renewable"`
+	Data          map[string]string `json:"data"`
+}
+
+// databaseCredentials requests new credentials from the database secrets engine
+func (v *VaultProvider) databaseCredentials(ctx context.Context, role string) (*vaultLease, error) {
+	var lease vaultLease
+	if err := v.do(ctx, http.MethodGet, "/v1/database/creds/"+role, nil, &lease); err != nil {
+		return nil, fmt.Errorf("failed to get database credentials: %w", err)
+	}
+	if lease.Data["username"] == "" || lease.Data["password"] == "" {
+		return nil, fmt.Errorf("vault returned incomplete database credentials for role %s", role)
+	}
+	return &lease, nil
+}
+
+// renewLease extends a lease and returns the granted duration
+func (v *VaultProvider) renewLease(ctx context.Context, leaseID string, increment time.Duration) (time.Duration, error) {
+	request := map[string]interface{}{
+		"lease_id":  leaseID,
+		"increment": int(increment / time.Second),
+	}
+
+	var lease vaultLease
+	if err := v.do(ctx, http.MethodPut, "/v1/sys/leases/renew", request, &lease); err != nil {
+		return 0, fmt.Errorf("failed to renew lease: %w", err)
+	}
+	return time.Duration(lease.LeaseDuration) * time.Second, nil
+}
+
+// do performs a Vault API request and decodes the JSON response into out
+func (v *VaultProvider) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, v.addr+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("vault returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// DatabaseLease holds dynamic database credentials and keeps their lease alive
+type DatabaseLease struct {
+	vault *VaultProvider
+	role  string
+	log   *logger.Logger
+
+	mu       sync.RWMutex
+	username string
+	password string
+	leaseID  string
+	duration time.Duration
+	renew    bool
+}
+
+// NewDatabaseLease requests initial credentials for a Vault database role
+func NewDatabaseLease(ctx context.Context, vault *VaultProvider, role string, log *logger.Logger) (*DatabaseLease, error) {
+	lease := &DatabaseLease{vault: vault, role: role, log: log}
+	if err := lease.rotate(ctx); err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// Credentials returns the current username and password
+func (l *DatabaseLease) Credentials() (string, string) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.username, l.password
+}
+
+// Run renews the lease at two thirds of its duration until ctx is cancelled.
+// When the lease cannot be renewed (max TTL reached, revoked, not renewable)
+// new credentials are requested instead.
+func (l *DatabaseLease) Run(ctx context.Context) {
+	for {
+		l.mu.RLock()
+		leaseID, duration, renewable := l.leaseID, l.duration, l.renew
+		l.mu.RUnlock()
+
+		wait := duration * 2 / 3
+		if wait < time.Second {
+			wait = time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if renewable {
+			granted, err := l.vault.renewLease(ctx, leaseID, duration)
+			// A shorter grant means the max TTL is close; rotate before it expires
+			if err == nil && granted >= duration/2 {
+				l.mu.Lock()
+				l.duration = granted
+				l.mu.Unlock()
+				continue
+			}
+			if err != nil {
+				l.log.Warnf("Failed to renew database lease for role %s: %v", l.role, err)
+			}
+		}
+
+		if err := l.rotate(ctx); err != nil {
+			l.log.Errorf("Failed to rotate database credentials for role %s: %v", l.role, err)
+			// Retry soon rather than waiting out a full lease
+			l.mu.Lock()
+			l.duration = 15 * time.Second
+			l.renew = false
+			l.mu.Unlock()
+		}
+	}
+}
+
+// rotate requests new credentials and replaces the current lease
+func (l *DatabaseLease) rotate(ctx context.Context) error {
+	lease, err := l.vault.databaseCredentials(ctx, l.role)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.username = lease.Data["username"]
+	l.password = lease.Data["password"]
+	l.leaseID = lease.LeaseID
+	l.duration = time.Duration(lease.LeaseDuration) * time.Second
+	l.renew = lease.Renewable
+	l.mu.Unlock()
+
+	l.log.Infof("Obtained database credentials for role %s (lease %s)", l.role, lease.LeaseID)
+	return nil
+}
@@ -0,0 +1,15 @@
+package slo
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// Handler serves the objectives' current status, e.g. at GET /slo
+func (t *Tracker) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"service":    t.service,
+			"objectives": t.Status(),
+		})
+	}
+}
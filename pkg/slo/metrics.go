@@ -0,0 +1,63 @@
+package slo
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	requestsDesc = prometheus.NewDesc(
+		"slo_requests_total",
+		"Total number of requests counted against an objective",
+		[]string{"service", "slo"}, nil,
+	)
+	goodRequestsDesc = prometheus.NewDesc(
+		"slo_good_requests_total",
+		"Total number of requests that met an objective",
+		[]string{"service", "slo"}, nil,
+	)
+	targetDesc = prometheus.NewDesc(
+		"slo_objective_target",
+		"Fraction of requests an objective requires to be good",
+		[]string{"service", "slo"}, nil,
+	)
+	budgetDesc = prometheus.NewDesc(
+		"slo_error_budget_remaining",
+		"Fraction of the error budget left in the SLO window, as seen by this instance",
+		[]string{"service", "slo"}, nil,
+	)
+	burnRateDesc = prometheus.NewDesc(
+		"slo_burn_rate",
+		"Error budget burn rate over a window, as seen by this instance",
+		[]string{"service", "slo", "window"}, nil,
+	)
+)
+
+// Describe implements prometheus.Collector
+func (t *Tracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- requestsDesc
+	ch <- goodRequestsDesc
+	ch <- targetDesc
+	ch <- budgetDesc
+	ch <- burnRateDesc
+}
+
+// Collect implements prometheus.Collector. Budgets and burn rates are worked
+// out at scrape time from the rolling counts. The counters let Prometheus
+// compute the same figures across all instances of a service.
+func (t *Tracker) Collect(ch chan<- prometheus.Metric) {
+	for _, status := range t.Status() {
+		ch <- prometheus.MustNewConstMetric(targetDesc, prometheus.GaugeValue, status.Target, t.service, status.Name)
+		ch <- prometheus.MustNewConstMetric(budgetDesc, prometheus.GaugeValue, status.ErrorBudgetRemaining, t.service, status.Name)
+		for window, rate := range status.BurnRates {
+			ch <- prometheus.MustNewConstMetric(burnRateDesc, prometheus.GaugeValue, rate, t.service, status.Name, window)
+		}
+	}
+
+	for _, o := range t.objectives {
+		total, good := o.counts()
+		ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, float64(total), t.service, o.Name)
+		ch <- prometheus.MustNewConstMetric(goodRequestsDesc, prometheus.CounterValue, float64(good), t.service, o.Name)
+	}
+}
+
+var _ prometheus.Collector = (*Tracker)(nil)
@@ -0,0 +1,270 @@
+// Package slo tracks a service's availability and latency objectives. Request
+// outcomes are kept in per-minute buckets covering the SLO window, from which
+// the remaining error budget and burn rates over the multi-window alerting
+// windows of the Google SRE workbook are derived. The same figures are
+// exported as slo_* metrics and served at /slo.
+package slo
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// Objective types
+const (
+	TypeAvailability = "availability"
+	TypeLatency      = "latency"
+)
+
+// bucketWidth is the resolution of the rolling counts
+const bucketWidth = time.Minute
+
+// BurnWindows are the windows burn rates are reported over
+var BurnWindows = []time.Duration{
+	5 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	2 * time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+	72 * time.Hour,
+}
+
+// Alert is a multi-window burn-rate condition: it fires when the burn rate
+// exceeds Threshold over both the long and the short window. The short window
+// lets the alert clear soon after the problem stops.
+type Alert struct {
+	Severity  string
+	Long      time.Duration
+	Short     time.Duration
+	Threshold float64
+}
+
+// Alerts are the recommended conditions for a 30-day window: paging at 2% and
+// 5% of the budget spent in 1 and 6 hours, ticketing at 10% in 1 and 3 days
+var Alerts = []Alert{
+	{Severity: "page", Long: time.Hour, Short: 5 * time.Minute, Threshold: 14.4},
+	{Severity: "page", Long: 6 * time.Hour, Short: 30 * time.Minute, Threshold: 6},
+	{Severity: "ticket", Long: 24 * time.Hour, Short: 2 * time.Hour, Threshold: 3},
+	{Severity: "ticket", Long: 72 * time.Hour, Short: 6 * time.Hour, Threshold: 1},
+}
+
+// Tracker records request outcomes against a service's objectives. It is
+// safe for concurrent use.
+type Tracker struct {
+	service    string
+	window     time.Duration
+	exclude    map[string]bool
+	objectives []*objective
+	now        func() time.Time
+}
+
+// NewTracker creates a tracker for the objectives in cfg
+func NewTracker(service string, cfg config.SLOConfig) *Tracker {
+	t := &Tracker{
+		service: service,
+		window:  cfg.Window,
+		exclude: routeSet(cfg.ExcludeRoutes),
+		now:     time.Now,
+	}
+	for _, o := range cfg.Objectives {
+		t.objectives = append(t.objectives, newObjective(o, cfg.Window))
+	}
+	return t
+}
+
+// Observe records a completed request
+func (t *Tracker) Observe(route string, status int, duration time.Duration) {
+	if t.exclude[route] {
+		return
+	}
+
+	minute := t.now().Unix() / int64(bucketWidth/time.Second)
+	for _, o := range t.objectives {
+		if o.routes != nil && !o.routes[route] {
+			continue
+		}
+		o.add(minute, o.met(status, duration))
+	}
+}
+
+// Status describes an objective over its window
+type Status struct {
+	Name                 string             `json:"name"`
+	Type                 string             `json:"type"`
+	Target               float64            `json:"target"`
+	Threshold            string             `json:"threshold,omitempty"`
+	Window               string             `json:"window"`
+	Total                uint64             `json:"total"`
+	Good                 uint64             `json:"good"`
+	SLI                  float64            `json:"sli"`                    // Fraction of good requests; 1 without traffic
+	ErrorBudgetRemaining float64            `json:"error_budget_remaining"` // 1 untouched, 0 spent, below 0 overspent
+	BurnRates            map[string]float64 `json:"burn_rates"`             // By window; 1 spends the budget exactly over the SLO window
+	Alerts               []string           `json:"alerts,omitempty"`       // Alert conditions currently met
+}
+
+// Status reports every objective
+func (t *Tracker) Status() []Status {
+	minute := t.now().Unix() / int64(bucketWidth/time.Second)
+
+	statuses := make([]Status, 0, len(t.objectives))
+	for _, o := range t.objectives {
+		total, good := o.sum(minute, t.window)
+		status := Status{
+			Name:                 o.Name,
+			Type:                 o.Type,
+			Target:               o.Target,
+			Window:               FormatWindow(t.window),
+			Total:                total,
+			Good:                 good,
+			SLI:                  ratio(good, total),
+			ErrorBudgetRemaining: budgetRemaining(total, good, o.Target),
+			BurnRates:            make(map[string]float64, len(BurnWindows)),
+		}
+		if o.Type == TypeLatency {
+			status.Threshold = o.Threshold.String()
+		}
+
+		for _, window := range BurnWindows {
+			status.BurnRates[FormatWindow(window)] = o.burnRate(minute, window)
+		}
+		for _, alert := range Alerts {
+			if o.burnRate(minute, alert.Long) > alert.Threshold && o.burnRate(minute, alert.Short) > alert.Threshold {
+				status.Alerts = append(status.Alerts, fmt.Sprintf("%s: burn rate above %g over %s and %s",
+					alert.Severity, alert.Threshold, FormatWindow(alert.Long), FormatWindow(alert.Short)))
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// objective keeps the rolling counts of one objective
+type objective struct {
+	config.SLOObjective
+	routes map[string]bool // nil counts every route
+
+	mu      sync.Mutex
+	buckets []bucket // Ring indexed by minute
+	total   uint64   // Since start, for the exported counters
+	good    uint64
+}
+
+// bucket holds the counts of one minute
+type bucket struct {
+	minute int64
+	total  uint32
+	good   uint32
+}
+
+// newObjective creates the counts for an objective tracked over window
+func newObjective(cfg config.SLOObjective, window time.Duration) *objective {
+	o := &objective{
+		SLOObjective: cfg,
+		buckets:      make([]bucket, max(int(window/bucketWidth), 1)),
+	}
+	if len(cfg.Routes) > 0 {
+		o.routes = routeSet(cfg.Routes)
+	}
+	return o
+}
+
+// met reports whether a request meets the objective
+func (o *objective) met(status int, duration time.Duration) bool {
+	if o.Type == TypeLatency {
+		return duration <= o.Threshold
+	}
+	return status < 500
+}
+
+// add counts a request in the bucket for minute
+func (o *objective) add(minute int64, good bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	b := &o.buckets[minute%int64(len(o.buckets))]
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+	b.total++
+	o.total++
+	if good {
+		b.good++
+		o.good++
+	}
+}
+
+// sum totals the buckets of the window ending at minute
+func (o *objective) sum(minute int64, window time.Duration) (total, good uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	n := min(int64(window/bucketWidth), int64(len(o.buckets)))
+	for m := minute - n + 1; m <= minute; m++ {
+		if b := o.buckets[m%int64(len(o.buckets))]; b.minute == m {
+			total += uint64(b.total)
+			good += uint64(b.good)
+		}
+	}
+	return total, good
+}
+
+// counts returns the totals since start
+func (o *objective) counts() (total, good uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.total, o.good
+}
+
+// burnRate is the error rate over window relative to the rate the objective
+// allows. Windows without traffic burn nothing.
+func (o *objective) burnRate(minute int64, window time.Duration) float64 {
+	total, good := o.sum(minute, window)
+	if total == 0 {
+		return 0
+	}
+	return (1 - ratio(good, total)) / (1 - o.Target)
+}
+
+// budgetRemaining is the fraction of the error budget left
+func budgetRemaining(total, good uint64, target float64) float64 {
+	if total == 0 {
+		return 1
+	}
+	allowed := float64(total) * (1 - target)
+	return 1 - float64(total-good)/allowed
+}
+
+// ratio returns good/total, or 1 without traffic
+func ratio(good, total uint64) float64 {
+	if total == 0 {
+		return 1
+	}
+	return float64(good) / float64(total)
+}
+
+// FormatWindow renders a window as the shortest of days, hours, or minutes,
+// e.g. 30d, 6h, 5m
+func FormatWindow(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
+
+// routeSet builds a lookup set of route templates
+func routeSet(routes []string) map[string]bool {
+	set := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		set[route] = true
+	}
+	return set
+}
@@ -0,0 +1,117 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// newTestTracker returns a tracker with a 99% availability objective and a
+// 90% latency objective, and a function to move its clock
+func newTestTracker() (*Tracker, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewTracker("order-service", config.SLOConfig{
+		Window:        30 * 24 * time.Hour,
+		ExcludeRoutes: []string{"/health"},
+		Objectives: []config.SLOObjective{
+			{Name: "availability", Type: TypeAvailability, Target: 0.99},
+			{Name: "latency", Type: TypeLatency, Target: 0.9, Threshold: 100 * time.Millisecond, Routes: []string{"/orders"}},
+		},
+	})
+	tracker.now = func() time.Time { return now }
+	return tracker, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestFormatWindow(t *testing.T) {
+	assert.Equal(t, "5m", FormatWindow(5*time.Minute))
+	assert.Equal(t, "6h", FormatWindow(6*time.Hour))
+	assert.Equal(t, "3d", FormatWindow(72*time.Hour))
+	assert.Equal(t, "90m", FormatWindow(90*time.Minute))
+}
+
+func TestStatusWithoutTraffic(t *testing.T) {
+	tracker, _ := newTestTracker()
+
+	status := tracker.Status()[0]
+	assert.Equal(t, 1.0, status.SLI)
+	assert.Equal(t, 1.0, status.ErrorBudgetRemaining)
+	assert.Equal(t, 0.0, status.BurnRates["1h"])
+	assert.Empty(t, status.Alerts)
+}
+
+func TestObserveCountsObjectives(t *testing.T) {
+	tracker, _ := newTestTracker()
+
+	for i := 0; i < 98; i++ {
+		tracker.Observe("/orders", 200, 50*time.Millisecond)
+	}
+	tracker.Observe("/orders", 503, 50*time.Millisecond)
+	tracker.Observe("/orders/:id", 200, time.Second) // Not a latency route
+	tracker.Observe("/health", 500, time.Second)     // Excluded
+
+	availability, latency := tracker.Status()[0], tracker.Status()[1]
+
+	assert.Equal(t, uint64(100), availability.Total)
+	assert.Equal(t, uint64(99), availability.Good)
+	assert.InDelta(t, 0.0, availability.ErrorBudgetRemaining, 1e-9) // 1% errors spend a 1% budget
+	assert.InDelta(t, 1.0, availability.BurnRates["5m"], 1e-9)
+
+	assert.Equal(t, uint64(99), latency.Total)
+	assert.Equal(t, uint64(99), latency.Good)
+	assert.Equal(t, "100ms", latency.Threshold)
+}
+
+func TestBurnRateAlerts(t *testing.T) {
+	tracker, advance := newTestTracker()
+
+	// A steady 50% error rate for an hour burns a 1% budget 50 times too fast
+	for i := 0; i < 60; i++ {
+		tracker.Observe("/orders", 200, time.Millisecond)
+		tracker.Observe("/orders", 500, time.Millisecond)
+		advance(time.Minute)
+	}
+	advance(-time.Minute)
+
+	status := tracker.Status()[0]
+	assert.InDelta(t, 50.0, status.BurnRates["1h"], 1e-9)
+	assert.Contains(t, status.Alerts, "page: burn rate above 14.4 over 1h and 5m")
+
+	// Once errors stop the short window clears the page
+	advance(10 * time.Minute)
+	for i := 0; i < 100; i++ {
+		tracker.Observe("/orders", 200, time.Millisecond)
+	}
+	status = tracker.Status()[0]
+	assert.NotContains(t, status.Alerts, "page: burn rate above 14.4 over 1h and 5m")
+}
+
+func TestOldBucketsExpire(t *testing.T) {
+	tracker, advance := newTestTracker()
+
+	tracker.Observe("/orders", 500, time.Millisecond)
+	advance(2 * time.Hour)
+	tracker.Observe("/orders", 200, time.Millisecond)
+
+	status := tracker.Status()[0]
+	assert.Equal(t, 0.0, status.BurnRates["1h"])
+	assert.Equal(t, uint64(2), status.Total)
+}
+
+func TestCollect(t *testing.T) {
+	tracker, _ := newTestTracker()
+	tracker.Observe("/orders", 200, time.Millisecond)
+	tracker.Observe("/orders", 500, time.Millisecond)
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(tracker))
+
+	count, err := testutil.GatherAndCount(registry, "slo_requests_total", "slo_good_requests_total", "slo_burn_rate")
+	require.NoError(t, err)
+	assert.Equal(t, 2+2+2*len(BurnWindows), count)
+}
@@ -0,0 +1,79 @@
+// Package sms sends text messages through an SMS provider, Twilio being the
+// one implemented, and checks the delivery receipts it reports back.
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// ErrRejected is matched by errors of messages the provider refused for
+// good, such as to a number that cannot receive text messages; sending them
+// again fails the same way
+var ErrRejected = errors.New("sms rejected")
+
+// Message is a text message to one phone number
+type Message struct {
+	To   string // E.164 phone number
+	Body string
+	// StatusCallback is where the provider reports how far the message got;
+	// empty reports nothing
+	StatusCallback string
+}
+
+// Receipt statuses a provider reports for a message it could or could not
+// hand to the recipient's device. Statuses before either are not final.
+const (
+	ReceiptDelivered   = "delivered"
+	ReceiptUndelivered = "undelivered"
+	ReceiptFailed      = "failed"
+)
+
+// Receipt is a provider's report of how far a message got
+type Receipt struct {
+	MessageID string // As returned by Send
+	Status    string // Such as ReceiptDelivered
+	ErrorCode string // The provider's reason for a message not delivered
+}
+
+// Final reports whether r is the last receipt of its message
+func (r *Receipt) Final() bool {
+	return r.Status == ReceiptDelivered || r.Status == ReceiptUndelivered || r.Status == ReceiptFailed
+}
+
+// Provider sends text messages
+type Provider interface {
+	// Send sends m, returning the provider's ID of the message. A failure
+	// matching ErrRejected is permanent; any other may pass when tried again.
+	Send(ctx context.Context, m *Message) (string, error)
+}
+
+// ReceiptParser reads the receipts a provider posts to a message's status
+// callback, as Twilio does
+type ReceiptParser interface {
+	// ParseReceipt verifies that form was posted to callbackURL by the
+	// provider, and returns the receipt it reports
+	ParseReceipt(callbackURL string, form url.Values, signature string) (*Receipt, error)
+}
+
+// NewProvider creates the provider cfg describes. It returns nil when the
+// provider is none.
+func NewProvider(cfg config.SMSConfig) (Provider, error) {
+	switch cfg.Provider {
+	case "", "none":
+		return nil, nil
+	case "twilio":
+		if cfg.Twilio.AccountSID == "" || cfg.Twilio.AuthToken == "" {
+			return nil, fmt.Errorf("twilio needs an account SID and auth token")
+		}
+		if cfg.Twilio.From == "" && cfg.Twilio.MessagingServiceSID == "" {
+			return nil, fmt.Errorf("twilio needs a from number or messaging service SID")
+		}
+		return NewTwilio(cfg.Twilio, cfg.Timeout), nil
+	}
+	return nil, fmt.Errorf("unknown sms provider %q", cfg.Provider)
+}
@@ -0,0 +1,142 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// ErrInvalidSignature is returned for status callbacks not signed with the
+// account's auth token
+var ErrInvalidSignature = errors.New("invalid twilio signature")
+
+// SignatureHeader carries the signature of a Twilio request
+const SignatureHeader = "X-Twilio-Signature"
+
+// maxBodyLength is the longest body Twilio sends, split into segments
+const maxBodyLength = 1600
+
+// Twilio sends through the Twilio Programmable Messaging API
+type Twilio struct {
+	baseURL             string
+	accountSID          string
+	authToken           string
+	from                string
+	messagingServiceSID string
+	client              *http.Client
+}
+
+// twilioError is the body of a Twilio error response
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewTwilio creates a Twilio provider for the account in cfg
+func NewTwilio(cfg config.TwilioConfig, timeout time.Duration) *Twilio {
+	return &Twilio{
+		baseURL:             strings.TrimRight(cfg.APIURL, "/"),
+		accountSID:          cfg.AccountSID,
+		authToken:           cfg.AuthToken,
+		from:                cfg.From,
+		messagingServiceSID: cfg.MessagingServiceSID,
+		client:              &http.Client{Timeout: timeout},
+	}
+}
+
+// Send creates a message, returning its SID
+func (t *Twilio) Send(ctx context.Context, m *Message) (string, error) {
+	body := m.Body
+	if runes := []rune(body); len(runes) > maxBodyLength {
+		body = string(runes[:maxBodyLength])
+	}
+
+	form := url.Values{"To": {m.To}, "Body": {body}}
+	if t.messagingServiceSID != "" {
+		form.Set("MessagingServiceSid", t.messagingServiceSID)
+	} else {
+		form.Set("From", t.from)
+	}
+	if m.StatusCallback != "" {
+		form.Set("StatusCallback", m.StatusCallback)
+	}
+
+	endpoint := t.baseURL + "/2010-04-01/Accounts/" + url.PathEscape(t.accountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(t.accountSID, t.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("twilio: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var failure twilioError
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&failure)
+		err := fmt.Errorf("twilio returned %d: %d %s", resp.StatusCode, failure.Code, failure.Message)
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			// Such as 21211 for an invalid number or 21610 for one that
+			// opted out
+			return "", fmt.Errorf("%w: %w", ErrRejected, err)
+		}
+		return "", err
+	}
+
+	var created struct {
+		SID string `json:"sid"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("twilio: failed to decode message: %w", err)
+	}
+	return created.SID, nil
+}
+
+// ParseReceipt verifies the signature of a status callback Twilio posted
+// form to callbackURL with, the URL exactly as given to Send, and returns the
+// receipt it reports
+func (t *Twilio) ParseReceipt(callbackURL string, form url.Values, signature string) (*Receipt, error) {
+	if !hmac.Equal([]byte(signature), []byte(t.sign(callbackURL, form))) {
+		return nil, ErrInvalidSignature
+	}
+	return &Receipt{
+		MessageID: form.Get("MessageSid"),
+		Status:    form.Get("MessageStatus"),
+		ErrorCode: form.Get("ErrorCode"),
+	}, nil
+}
+
+// sign computes the signature Twilio sends a request with: an HMAC-SHA1 of
+// the URL followed by each form parameter's name and value, sorted by name
+func (t *Twilio) sign(callbackURL string, form url.Values) string {
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mac := hmac.New(sha1.New, []byte(t.authToken))
+	mac.Write([]byte(callbackURL))
+	for _, key := range keys {
+		for _, value := range form[key] {
+			mac.Write([]byte(key + value))
+		}
+	}
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,105 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// newTestTwilio creates a Twilio provider against url
+func newTestTwilio(url string) *Twilio {
+	return NewTwilio(config.TwilioConfig{
+		APIURL:     url,
+		AccountSID: "AC123",
+		AuthToken:  "secret",
+		From:       "+15005550006",
+	}, time.Second)
+}
+
+func TestTwilioSends(t *testing.T) {
+	var form url.Values
+	var path, user, password string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		path, form = r.URL.Path, r.PostForm
+		user, password, _ = r.BasicAuth()
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"sid": "SM1", "status": "queued"})
+	}))
+	defer server.Close()
+
+	sid, err := newTestTwilio(server.URL).Send(context.Background(), &Message{
+		To:             "+14155550100",
+		Body:           "Order ready",
+		StatusCallback: "https://pos.example.com/api/v1/sms/status?delivery_id=d-1",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "SM1", sid)
+	assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", path)
+	assert.Equal(t, "AC123", user)
+	assert.Equal(t, "secret", password)
+	assert.Equal(t, "+14155550100", form.Get("To"))
+	assert.Equal(t, "+15005550006", form.Get("From"))
+	assert.Equal(t, "Order ready", form.Get("Body"))
+	assert.Equal(t, "https://pos.example.com/api/v1/sms/status?delivery_id=d-1", form.Get("StatusCallback"))
+}
+
+func TestTwilioFailures(t *testing.T) {
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": 21211, "message": "Invalid 'To' Phone Number"})
+	}))
+	defer server.Close()
+	twilio := newTestTwilio(server.URL)
+
+	send := func(code int) error {
+		status = code
+		_, err := twilio.Send(context.Background(), &Message{To: "+1", Body: "Hi"})
+		return err
+	}
+
+	assert.True(t, errors.Is(send(http.StatusBadRequest), ErrRejected))
+	assert.False(t, errors.Is(send(http.StatusTooManyRequests), ErrRejected))
+	assert.False(t, errors.Is(send(http.StatusServiceUnavailable), ErrRejected))
+}
+
+func TestTwilioParsesReceipts(t *testing.T) {
+	twilio := newTestTwilio("https://api.twilio.com")
+	callback := "https://pos.example.com/api/v1/sms/status?delivery_id=d-1"
+	form := url.Values{
+		"MessageSid":    {"SM1"},
+		"MessageStatus": {"undelivered"},
+		"ErrorCode":     {"30003"},
+	}
+
+	// Parameters are signed sorted by name, each name followed by its value
+	mac := hmac.New(sha1.New, []byte("secret"))
+	mac.Write([]byte(callback + "ErrorCode30003MessageSidSM1MessageStatusundelivered"))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	receipt, err := twilio.ParseReceipt(callback, form, signature)
+	require.NoError(t, err)
+	assert.Equal(t, &Receipt{MessageID: "SM1", Status: ReceiptUndelivered, ErrorCode: "30003"}, receipt)
+	assert.True(t, receipt.Final())
+
+	_, err = twilio.ParseReceipt(callback+"&x=1", form, signature)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	form.Set("MessageStatus", "delivered")
+	_, err = twilio.ParseReceipt(callback, form, signature)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
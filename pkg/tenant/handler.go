@@ -0,0 +1,185 @@
+package tenant
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+
+	"github.com/onichange/pos-system/pkg/validator"
+)
+
+// ProvisionTenantRequest is the body of POST /tenants
+type ProvisionTenantRequest struct {
+	ID                         string            `json:"id" validate:"required"`
+	Name                       string            `json:"name" validate:"required,max=200"`
+	RateLimitRequestsPerMinute int               `json:"rate_limit_requests_per_minute" validate:"min=0"`
+	Settings                   map[string]string `json:"settings"`
+}
+
+// UpdateTenantRequest is the body of PUT /tenants/:id
+type UpdateTenantRequest struct {
+	Name                       string            `json:"name" validate:"required,max=200"`
+	Status                     string            `json:"status" validate:"oneof=active suspended"`
+	RateLimitRequestsPerMinute int               `json:"rate_limit_requests_per_minute" validate:"min=0"`
+	Settings                   map[string]string `json:"settings"`
+}
+
+// Handler serves the tenant onboarding API
+type Handler struct {
+	store    Store
+	registry *Registry
+}
+
+// NewHandler creates an onboarding handler; registry may be nil
+func NewHandler(store Store, registry *Registry) *Handler {
+	return &Handler{
+		store:    store,
+		registry: registry,
+	}
+}
+
+// RegisterRoutes mounts the admin endpoints on router
+func (h *Handler) RegisterRoutes(router fiber.Router) {
+	router.Get("/", h.ListTenants)
+	router.Get("/:id", h.GetTenant)
+	router.Post("/", h.ProvisionTenant)
+	router.Put("/:id", h.UpdateTenant)
+}
+
+// ListTenants handles GET /tenants
+func (h *Handler) ListTenants(c *fiber.Ctx) error {
+	records, err := h.store.List(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch tenants",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": records,
+	})
+}
+
+// GetTenant handles GET /tenants/:id
+func (h *Handler) GetTenant(c *fiber.Ctx) error {
+	record, err := h.store.Get(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return h.storeError(c, err)
+	}
+
+	return c.JSON(record)
+}
+
+// GetCurrentTenant handles GET /tenant, returning the caller's tenant
+func (h *Handler) GetCurrentTenant(c *fiber.Ctx) error {
+	id := IDFromContext(c.UserContext())
+	if id == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Tenant not found",
+		})
+	}
+
+	record, err := h.store.Get(c.UserContext(), id)
+	if err != nil {
+		return h.storeError(c, err)
+	}
+
+	return c.JSON(record)
+}
+
+// ProvisionTenant handles POST /tenants. The tenant starts active; its users,
+// stores and catalog are then created with its ID in X-Tenant-ID or in their
+// tokens.
+func (h *Handler) ProvisionTenant(c *fiber.Ctx) error {
+	var req ProvisionTenantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+	if !ValidID(req.ID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid tenant ID",
+		})
+	}
+
+	record := &Record{
+		ID:                         req.ID,
+		Name:                       req.Name,
+		Status:                     StatusActive,
+		RateLimitRequestsPerMinute: req.RateLimitRequestsPerMinute,
+		Settings:                   req.Settings,
+	}
+	if err := h.store.Create(c.UserContext(), record); err != nil {
+		if errors.Is(err, ErrExists) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Tenant already exists",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to provision tenant",
+		})
+	}
+	h.invalidate()
+
+	return c.Status(fiber.StatusCreated).JSON(record)
+}
+
+// UpdateTenant handles PUT /tenants/:id, which also suspends and reactivates
+// tenants
+func (h *Handler) UpdateTenant(c *fiber.Ctx) error {
+	var req UpdateTenantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if validationErrors := validator.ValidateStruct(&req); len(validationErrors) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": validationErrors,
+		})
+	}
+
+	record := &Record{
+		ID:                         utils.CopyString(c.Params("id")),
+		Name:                       req.Name,
+		Status:                     req.Status,
+		RateLimitRequestsPerMinute: req.RateLimitRequestsPerMinute,
+		Settings:                   req.Settings,
+	}
+	if err := h.store.Update(c.UserContext(), record); err != nil {
+		return h.storeError(c, err)
+	}
+	h.invalidate()
+
+	return c.JSON(record)
+}
+
+// invalidate makes local changes visible immediately on this instance
+func (h *Handler) invalidate() {
+	if h.registry != nil {
+		h.registry.Invalidate()
+	}
+}
+
+// storeError maps store errors to responses
+func (h *Handler) storeError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Tenant not found",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to fetch tenant",
+	})
+}
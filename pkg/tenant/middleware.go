@@ -0,0 +1,131 @@
+package tenant
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// Middleware resolves the request's tenant and stores it in the request context.
+// The tenant comes from, in order: the JWT tenant_id claim, the X-Tenant-ID header,
+// the subdomain of cfg.BaseDomain, and cfg.Default. A header or host that names a
+// different tenant than the token is rejected; a token without the claim is bound
+// to cfg.Default, so the header and host only choose the tenant of requests
+// without a user, such as service-to-service calls. Mount it after JWTAuth so the
+// claim is available.
+func Middleware(cfg config.TenantConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		t, err := resolve(c, cfg)
+		if err != nil {
+			return c.Status(err.Code).JSON(fiber.Map{
+				"error": err.Message,
+			})
+		}
+
+		if t == nil {
+			if cfg.Required {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Tenant could not be resolved",
+				})
+			}
+			return c.Next()
+		}
+
+		// Locals makes the tenant visible through c.Context() as well as c.UserContext()
+		c.Locals(contextKey{}, t)
+		c.Locals("tenant_id", t.ID)
+		c.SetUserContext(NewContext(c.UserContext(), t))
+
+		// Forwarded to downstream services by the gateway proxy
+		c.Request().Header.Set(Header, t.ID)
+
+		return c.Next()
+	}
+}
+
+// unbound binds a user's token to no tenant: it is never a valid ID, so a
+// header or host naming one is rejected
+const unbound = "-"
+
+// resolve finds the tenant for a request
+func resolve(c *fiber.Ctx, cfg config.TenantConfig) (*Tenant, *fiber.Error) {
+	claimed, _ := c.Locals("tenant_id").(string)
+
+	// A user's token is bound to its claim, or without one to the default
+	// tenant, which a header or host cannot override
+	bound := claimed
+	if userID, _ := c.Locals("user_id").(string); userID != "" && bound == "" {
+		bound = cfg.Default
+		if bound == "" {
+			bound = unbound
+		}
+	}
+
+	candidates := []Tenant{
+		{ID: claimed, Source: SourceJWT},
+		{ID: utils.CopyString(c.Get(Header)), Source: SourceHeader},
+		{ID: fromHost(utils.CopyString(c.Hostname()), cfg.BaseDomain), Source: SourceHost},
+	}
+
+	var resolved *Tenant
+	for i := range candidates {
+		candidate := candidates[i]
+		if candidate.ID == "" {
+			continue
+		}
+
+		if !ValidID(candidate.ID) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid tenant ID")
+		}
+
+		// A token is only valid for its own tenant
+		if bound != "" && candidate.ID != bound {
+			return nil, fiber.NewError(fiber.StatusForbidden, "Tenant does not match token")
+		}
+
+		if resolved == nil {
+			resolved = &candidate
+		}
+	}
+
+	if resolved == nil && cfg.Default != "" {
+		resolved = &Tenant{ID: cfg.Default, Source: SourceDefault}
+	}
+	return resolved, nil
+}
+
+// fromHost returns the subdomain of baseDomain in host, e.g. acme for
+// acme.pos.example.com with base domain pos.example.com
+func fromHost(host, baseDomain string) string {
+	if baseDomain == "" {
+		return ""
+	}
+
+	suffix := "." + strings.ToLower(baseDomain)
+	host = strings.ToLower(host)
+	if !strings.HasSuffix(host, suffix) {
+		return ""
+	}
+
+	subdomain := strings.TrimSuffix(host, suffix)
+	if strings.Contains(subdomain, ".") {
+		return ""
+	}
+	return subdomain
+}
+
+// RequireID returns a middleware that admits only requests of tenant id, such
+// as platform administration reserved to the operator's own tenant
+func RequireID(id string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if id == "" || IDFromContext(c.UserContext()) != id {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Insufficient permissions",
+			})
+		}
+		return c.Next()
+	}
+}
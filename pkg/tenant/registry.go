@@ -0,0 +1,166 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// Statuses of a provisioned tenant
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended" // Requests of the tenant are rejected
+)
+
+var (
+	// ErrNotFound is returned when a tenant has not been provisioned
+	ErrNotFound = errors.New("tenant not found")
+
+	// ErrExists is returned when provisioning a tenant ID already in use
+	ErrExists = errors.New("tenant already exists")
+)
+
+// Record is a provisioned tenant and its settings
+type Record struct {
+	ID     string `json:"id"`
+	Name   string `json:"name" validate:"required,max=200"`
+	Status string `json:"status" validate:"oneof=active suspended"`
+	// Requests per minute allowed to each client of the tenant; 0 uses the gateway-wide limit
+	RateLimitRequestsPerMinute int               `json:"rate_limit_requests_per_minute" validate:"min=0"`
+	Settings                   map[string]string `json:"settings"`
+	CreatedAt                  time.Time         `json:"created_at"`
+	UpdatedAt                  time.Time         `json:"updated_at"`
+}
+
+// Active reports whether requests of the tenant are served
+func (r *Record) Active() bool {
+	return r.Status == StatusActive
+}
+
+// Registry looks tenants up in a store, caching them locally for a short TTL
+// so checks on the request path do not hit the store every time
+type Registry struct {
+	store  Store
+	ttl    time.Duration
+	logger *logger.Logger
+
+	mu        sync.RWMutex
+	tenants   map[string]*Record
+	refreshed time.Time
+}
+
+// NewRegistry creates a tenant registry
+func NewRegistry(store Store, ttl time.Duration, log *logger.Logger) *Registry {
+	return &Registry{
+		store:   store,
+		ttl:     ttl,
+		logger:  log,
+		tenants: make(map[string]*Record),
+	}
+}
+
+// Lookup returns a provisioned tenant, or ErrNotFound
+func (r *Registry) Lookup(ctx context.Context, id string) (*Record, error) {
+	tenants, err := r.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	record, ok := tenants[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return record, nil
+}
+
+// RateLimit returns the requests per minute allowed to each client of a
+// tenant, or 0 when the gateway-wide limit applies. ok is false when the
+// tenant is not provisioned or cannot be looked up.
+func (r *Registry) RateLimit(ctx context.Context, id string) (limit int, ok bool) {
+	record, err := r.Lookup(ctx, id)
+	if err != nil {
+		return 0, false
+	}
+	return record.RateLimitRequestsPerMinute, true
+}
+
+// Require returns a middleware that rejects requests of tenants that are not
+// provisioned or are suspended. Mount it after Middleware; requests without a
+// tenant are left to Middleware's Required setting.
+func (r *Registry) Require() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := IDFromContext(c.UserContext())
+		if id == "" {
+			return c.Next()
+		}
+
+		record, err := r.Lookup(c.UserContext(), id)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Unknown tenant",
+			})
+		case err != nil:
+			r.logger.Errorf("Failed to look up tenant %s: %v", id, err)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Tenant registry unavailable",
+			})
+		case !record.Active():
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Tenant is suspended",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// Invalidate drops the local cache so the next lookup reads the store
+func (r *Registry) Invalidate() {
+	r.mu.Lock()
+	r.refreshed = time.Time{}
+	r.mu.Unlock()
+}
+
+// snapshot returns the cached tenants, refreshing them when the TTL has
+// expired. A failed refresh keeps serving the previous snapshot.
+func (r *Registry) snapshot(ctx context.Context) (map[string]*Record, error) {
+	r.mu.RLock()
+	tenants, fresh := r.tenants, time.Since(r.refreshed) < r.ttl
+	r.mu.RUnlock()
+	if fresh {
+		return tenants, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Another request may have refreshed while we waited for the lock
+	if time.Since(r.refreshed) < r.ttl {
+		return r.tenants, nil
+	}
+
+	list, err := r.store.List(ctx)
+	if err != nil {
+		if len(r.tenants) > 0 {
+			r.logger.Warnf("Failed to refresh tenants, using cached values: %v", err)
+			// Wait a full TTL before trying the store again
+			r.refreshed = time.Now()
+			return r.tenants, nil
+		}
+		return nil, err
+	}
+
+	next := make(map[string]*Record, len(list))
+	for _, record := range list {
+		next[record.ID] = record
+	}
+	r.tenants = next
+	r.refreshed = time.Now()
+	return next, nil
+}
@@ -0,0 +1,176 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// memoryStore keeps tenants in a map, counting List calls
+type memoryStore struct {
+	records map[string]*Record
+	lists   atomic.Int32
+	err     error
+}
+
+func newMemoryStore(records ...*Record) *memoryStore {
+	s := &memoryStore{records: make(map[string]*Record)}
+	for _, r := range records {
+		s.records[r.ID] = r
+	}
+	return s
+}
+
+func (s *memoryStore) Get(_ context.Context, id string) (*Record, error) {
+	if r, ok := s.records[id]; ok {
+		return r, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memoryStore) List(context.Context) ([]*Record, error) {
+	s.lists.Add(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	records := make([]*Record, 0, len(s.records))
+	for _, r := range s.records {
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+func (s *memoryStore) Create(_ context.Context, r *Record) error {
+	if _, ok := s.records[r.ID]; ok {
+		return ErrExists
+	}
+	s.records[r.ID] = r
+	return nil
+}
+
+func (s *memoryStore) Update(_ context.Context, r *Record) error {
+	if _, ok := s.records[r.ID]; !ok {
+		return ErrNotFound
+	}
+	s.records[r.ID] = r
+	return nil
+}
+
+func TestRegistryCachesAndInvalidates(t *testing.T) {
+	store := newMemoryStore(&Record{ID: "acme", Status: StatusActive, RateLimitRequestsPerMinute: 500})
+	registry := NewRegistry(store, time.Minute, logger.New("test"))
+	ctx := context.Background()
+
+	limit, ok := registry.RateLimit(ctx, "acme")
+	assert.Equal(t, 500, limit)
+	assert.True(t, ok)
+	limit, ok = registry.RateLimit(ctx, "globex")
+	assert.Equal(t, 0, limit, "unknown tenants use the gateway-wide limit")
+	assert.False(t, ok, "unknown tenants are not counted apart")
+	assert.Equal(t, int32(1), store.lists.Load())
+
+	store.records["globex"] = &Record{ID: "globex", Status: StatusActive}
+	_, err := registry.Lookup(ctx, "globex")
+	assert.ErrorIs(t, err, ErrNotFound, "cached until the TTL expires")
+
+	registry.Invalidate()
+	_, err = registry.Lookup(ctx, "globex")
+	assert.NoError(t, err)
+
+	// A failed refresh keeps the previous snapshot
+	store.err = errors.New("connection refused")
+	registry.Invalidate()
+	_, err = registry.Lookup(ctx, "acme")
+	assert.NoError(t, err)
+}
+
+func TestRegistryRequire(t *testing.T) {
+	store := newMemoryStore(
+		&Record{ID: "acme", Status: StatusActive},
+		&Record{ID: "initech", Status: StatusSuspended},
+	)
+	app := fiber.New()
+	app.Use(Middleware(config.TenantConfig{}))
+	app.Use(NewRegistry(store, time.Minute, logger.New("test")).Require())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	status, _ := get(t, app, "example.com", "acme")
+	assert.Equal(t, fiber.StatusOK, status)
+	status, body := get(t, app, "example.com", "initech")
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Contains(t, body, "suspended")
+	status, body = get(t, app, "example.com", "globex")
+	assert.Equal(t, fiber.StatusForbidden, status)
+	assert.Contains(t, body, "Unknown tenant")
+
+	// Requests without a tenant are left to Middleware
+	status, _ = get(t, app, "example.com", "")
+	assert.Equal(t, fiber.StatusOK, status)
+
+	// Without a snapshot to fall back on, store errors reject requests
+	failing := newMemoryStore()
+	failing.err = errors.New("connection refused")
+	app = fiber.New()
+	app.Use(Middleware(config.TenantConfig{}))
+	app.Use(NewRegistry(failing, time.Minute, logger.New("test")).Require())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	status, _ = get(t, app, "example.com", "acme")
+	assert.Equal(t, fiber.StatusServiceUnavailable, status)
+}
+
+func TestRequireID(t *testing.T) {
+	app := fiber.New()
+	app.Use(Middleware(config.TenantConfig{Default: "default"}))
+	app.Use(RequireID("default"))
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	status, _ := get(t, app, "example.com", "")
+	assert.Equal(t, fiber.StatusOK, status)
+	status, _ = get(t, app, "example.com", "acme")
+	assert.Equal(t, fiber.StatusForbidden, status)
+}
+
+func TestHandlerProvisionsTenants(t *testing.T) {
+	store := newMemoryStore()
+	app := fiber.New()
+	app.Use(Middleware(config.TenantConfig{}))
+	handler := NewHandler(store, nil)
+	handler.RegisterRoutes(app.Group("/tenants"))
+	app.Get("/tenant", handler.GetCurrentTenant)
+
+	send := func(method, path, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(Header, "acme")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusCreated, send(fiber.MethodPost, "/tenants", `{"id":"acme","name":"Acme","rate_limit_requests_per_minute":500}`))
+	require.Contains(t, store.records, "acme")
+	assert.Equal(t, StatusActive, store.records["acme"].Status)
+	assert.Equal(t, 500, store.records["acme"].RateLimitRequestsPerMinute)
+
+	assert.Equal(t, fiber.StatusConflict, send(fiber.MethodPost, "/tenants", `{"id":"acme","name":"Acme"}`))
+	assert.Equal(t, fiber.StatusBadRequest, send(fiber.MethodPost, "/tenants", `{"id":"Not Valid","name":"Acme"}`))
+	assert.Equal(t, fiber.StatusBadRequest, send(fiber.MethodPost, "/tenants", `{"id":"globex"}`))
+
+	assert.Equal(t, fiber.StatusOK, send(fiber.MethodPut, "/tenants/acme", `{"name":"Acme","status":"suspended"}`))
+	assert.Equal(t, StatusSuspended, store.records["acme"].Status)
+	assert.Equal(t, fiber.StatusBadRequest, send(fiber.MethodPut, "/tenants/acme", `{"name":"Acme","status":"closed"}`))
+	assert.Equal(t, fiber.StatusNotFound, send(fiber.MethodPut, "/tenants/globex", `{"name":"Globex","status":"active"}`))
+
+	assert.Equal(t, fiber.StatusOK, send(fiber.MethodGet, "/tenant", ""))
+}
@@ -0,0 +1,128 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Store persists provisioned tenants
+type Store interface {
+	Get(ctx context.Context, id string) (*Record, error)
+	List(ctx context.Context) ([]*Record, error)
+	Create(ctx context.Context, record *Record) error
+	Update(ctx context.Context, record *Record) error
+}
+
+// uniqueViolation is the Postgres error code of a unique index violation
+const uniqueViolation = "23505"
+
+// recordColumns are the columns scanned by scanRecord
+const recordColumns = `id, name, status, rate_limit_requests_per_minute, settings, created_at, updated_at`
+
+// PostgresStore keeps tenants in the tenants table
+type PostgresStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresStore creates a Postgres-backed tenant store
+func NewPostgresStore(db *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Get returns a tenant by ID
+func (s *PostgresStore) Get(ctx context.Context, id string) (*Record, error) {
+	query := `SELECT ` + recordColumns + ` FROM tenants WHERE id = $1`
+
+	record, err := scanRecord(s.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return record, err
+}
+
+// List returns all tenants
+func (s *PostgresStore) List(ctx context.Context) ([]*Record, error) {
+	query := `SELECT ` + recordColumns + ` FROM tenants ORDER BY id`
+
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*Record
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// Create provisions a tenant, failing with ErrExists when its ID is taken
+func (s *PostgresStore) Create(ctx context.Context, record *Record) error {
+	query := `
+		INSERT INTO tenants (id, name, status, rate_limit_requests_per_minute, settings, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+	`
+
+	now := time.Now()
+	_, err := s.db.Exec(ctx, query,
+		record.ID, record.Name, record.Status, record.RateLimitRequestsPerMinute, nonNil(record.Settings), now,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return ErrExists
+	}
+	if err != nil {
+		return err
+	}
+
+	record.CreatedAt, record.UpdatedAt = now, now
+	return nil
+}
+
+// Update replaces a tenant's name, status and settings
+func (s *PostgresStore) Update(ctx context.Context, record *Record) error {
+	query := `
+		UPDATE tenants
+		SET name = $2, status = $3, rate_limit_requests_per_minute = $4, settings = $5, updated_at = $6
+		WHERE id = $1
+		RETURNING created_at
+	`
+
+	record.UpdatedAt = time.Now()
+	err := s.db.QueryRow(ctx, query,
+		record.ID, record.Name, record.Status, record.RateLimitRequestsPerMinute, nonNil(record.Settings), record.UpdatedAt,
+	).Scan(&record.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// scanRecord scans a row of recordColumns
+func scanRecord(row pgx.Row) (*Record, error) {
+	var r Record
+	if err := row.Scan(
+		&r.ID, &r.Name, &r.Status, &r.RateLimitRequestsPerMinute, &r.Settings, &r.CreatedAt, &r.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// nonNil stores empty settings rather than NULL
+func nonNil(settings map[string]string) map[string]string {
+	if settings == nil {
+		return map[string]string{}
+	}
+	return settings
+}
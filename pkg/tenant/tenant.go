@@ -0,0 +1,74 @@
+// Package tenant resolves the tenant (brand or franchise) a request belongs to
+// and carries it through contexts, service calls, and events so repositories
+// can scope every query to it.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// Header carries the tenant ID between services
+const Header = "X-Tenant-ID"
+
+// Sources a tenant can be resolved from
+const (
+	SourceJWT     = "jwt"
+	SourceHeader  = "header"
+	SourceHost    = "host"
+	SourceDefault = "default"
+	SourceJob     = "job"     // Background jobs acting on rows of each tenant in turn
+	SourceWebhook = "webhook" // Webhooks of external providers, naming the tenant in what they report on
+)
+
+// ErrNoTenant is returned by repositories when a query has no tenant to scope it to
+var ErrNoTenant = errors.New("no tenant in context")
+
+// idPattern restricts tenant IDs to lowercase slugs usable as subdomains
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Tenant identifies the tenant a request or event belongs to
+type Tenant struct {
+	ID     string `json:"id"`
+	Source string `json:"source"` // Where the ID was resolved from
+}
+
+// contextKey is the context key for the tenant. Values stored with
+// fiber's Locals under this key are also visible through c.Context().
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying t
+func NewContext(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant stored in ctx
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(contextKey{}).(*Tenant)
+	return t, ok && t != nil
+}
+
+// IDFromContext returns the tenant ID stored in ctx, or "" if there is none
+func IDFromContext(ctx context.Context) string {
+	if t, ok := FromContext(ctx); ok {
+		return t.ID
+	}
+	return ""
+}
+
+// Scope returns the tenant ID a repository must filter by. Repositories call it
+// before every query so a missing tenant fails the query instead of reading
+// across tenants.
+func Scope(ctx context.Context) (string, error) {
+	id := IDFromContext(ctx)
+	if id == "" {
+		return "", ErrNoTenant
+	}
+	return id, nil
+}
+
+// ValidID reports whether id is a well-formed tenant ID
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
@@ -0,0 +1,122 @@
+package tenant
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+// newTestApp echoes the resolved tenant; claim simulates the JWT tenant_id claim
+func newTestApp(cfg config.TenantConfig, claim string) *fiber.App {
+	return newUserTestApp(cfg, "", claim)
+}
+
+// newUserTestApp is newTestApp for requests authenticated as userID
+func newUserTestApp(cfg config.TenantConfig, userID, claim string) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if userID != "" {
+			c.Locals("user_id", userID)
+		}
+		if claim != "" {
+			c.Locals("tenant_id", claim)
+		}
+		return c.Next()
+	})
+	app.Use(Middleware(cfg))
+	app.Get("/", func(c *fiber.Ctx) error {
+		t, ok := FromContext(c.Context())
+		if !ok {
+			return c.SendString("none")
+		}
+		return c.SendString(t.ID + "/" + t.Source)
+	})
+	return app
+}
+
+func get(t *testing.T, app *fiber.App, host, header string) (int, string) {
+	req := httptest.NewRequest(fiber.MethodGet, "http://"+host+"/", nil)
+	if header != "" {
+		req.Header.Set(Header, header)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestMiddlewareResolutionOrder(t *testing.T) {
+	cfg := config.TenantConfig{Default: "default", BaseDomain: "pos.example.com"}
+
+	_, body := get(t, newTestApp(cfg, "acme"), "localhost", "")
+	assert.Equal(t, "acme/jwt", body)
+
+	_, body = get(t, newTestApp(cfg, ""), "acme.pos.example.com", "acme")
+	assert.Equal(t, "acme/header", body)
+
+	_, body = get(t, newTestApp(cfg, ""), "acme.pos.example.com", "")
+	assert.Equal(t, "acme/host", body)
+
+	_, body = get(t, newTestApp(cfg, ""), "localhost", "")
+	assert.Equal(t, "default/default", body)
+}
+
+func TestMiddlewareRejectsMismatchedTenant(t *testing.T) {
+	cfg := config.TenantConfig{BaseDomain: "pos.example.com"}
+
+	status, _ := get(t, newTestApp(cfg, "acme"), "localhost", "globex")
+	assert.Equal(t, fiber.StatusForbidden, status)
+
+	status, _ = get(t, newTestApp(cfg, "acme"), "globex.pos.example.com", "")
+	assert.Equal(t, fiber.StatusForbidden, status)
+
+	status, _ = get(t, newTestApp(cfg, ""), "localhost", "Not A Tenant")
+	assert.Equal(t, fiber.StatusBadRequest, status)
+}
+
+func TestMiddlewareBindsTokensWithoutClaimToDefault(t *testing.T) {
+	cfg := config.TenantConfig{Default: "default", BaseDomain: "pos.example.com"}
+	app := newUserTestApp(cfg, "user-1", "")
+
+	status, _ := get(t, app, "localhost", "globex")
+	assert.Equal(t, fiber.StatusForbidden, status)
+
+	status, _ = get(t, app, "globex.pos.example.com", "")
+	assert.Equal(t, fiber.StatusForbidden, status)
+
+	_, body := get(t, app, "localhost", "default")
+	assert.Equal(t, "default/header", body)
+
+	_, body = get(t, app, "localhost", "")
+	assert.Equal(t, "default/default", body)
+
+	// Without a default tenant, such a token names none
+	status, _ = get(t, newUserTestApp(config.TenantConfig{}, "user-1", ""), "localhost", "globex")
+	assert.Equal(t, fiber.StatusForbidden, status)
+}
+
+func TestMiddlewareRequired(t *testing.T) {
+	status, _ := get(t, newTestApp(config.TenantConfig{Required: true}, ""), "localhost", "")
+	assert.Equal(t, fiber.StatusBadRequest, status)
+
+	status, body := get(t, newTestApp(config.TenantConfig{}, ""), "localhost", "")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "none", body)
+}
+
+func TestScope(t *testing.T) {
+	_, err := Scope(context.Background())
+	assert.ErrorIs(t, err, ErrNoTenant)
+
+	id, err := Scope(NewContext(context.Background(), &Tenant{ID: "acme"}))
+	require.NoError(t, err)
+	assert.Equal(t, "acme", id)
+}
@@ -0,0 +1,140 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/IBM/sarama"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// messagingTracer is the instrumentation name used for broker spans
+const messagingTracer = "messaging"
+
+// AMQPHeaderCarrier adapts AMQP message headers to a TextMapCarrier
+type AMQPHeaderCarrier amqp.Table
+
+// Get returns the value for a key
+func (c AMQPHeaderCarrier) Get(key string) string {
+	if value, ok := c[key].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// Set stores a key/value pair
+func (c AMQPHeaderCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+// Keys lists the keys stored in the carrier
+func (c AMQPHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// KafkaHeaderCarrier adapts Kafka record headers to a TextMapCarrier
+type KafkaHeaderCarrier struct {
+	Headers *[]sarama.RecordHeader
+}
+
+// Get returns the value for a key
+func (c KafkaHeaderCarrier) Get(key string) string {
+	for _, h := range *c.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set stores a key/value pair, replacing an existing header with the same key
+func (c KafkaHeaderCarrier) Set(key, value string) {
+	for i, h := range *c.Headers {
+		if string(h.Key) == key {
+			(*c.Headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.Headers = append(*c.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+// Keys lists the keys stored in the carrier
+func (c KafkaHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.Headers))
+	for _, h := range *c.Headers {
+		keys = append(keys, string(h.Key))
+	}
+	return keys
+}
+
+// messageContext carries the request ID and tenant through brokers alongside the trace context
+var messageContext = propagation.NewCompositeTextMapPropagator(RequestIDPropagator{}, TenantPropagator{})
+
+// InjectAMQP writes the trace context, request ID, and tenant from ctx into AMQP headers
+func InjectAMQP(ctx context.Context, headers amqp.Table) {
+	carrier := AMQPHeaderCarrier(headers)
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	messageContext.Inject(ctx, carrier)
+}
+
+// ExtractAMQP returns a context carrying the trace context, request ID, and tenant found in AMQP headers
+func ExtractAMQP(ctx context.Context, headers amqp.Table) context.Context {
+	if headers == nil {
+		return ctx
+	}
+	carrier := AMQPHeaderCarrier(headers)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	return messageContext.Extract(ctx, carrier)
+}
+
+// InjectKafka writes the trace context, request ID, and tenant from ctx into Kafka record headers
+func InjectKafka(ctx context.Context, headers *[]sarama.RecordHeader) {
+	carrier := KafkaHeaderCarrier{Headers: headers}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	messageContext.Inject(ctx, carrier)
+}
+
+// ExtractKafka returns a context carrying the trace context, request ID, and tenant found in consumed Kafka headers
+func ExtractKafka(ctx context.Context, headers []*sarama.RecordHeader) context.Context {
+	recordHeaders := make([]sarama.RecordHeader, 0, len(headers))
+	for _, h := range headers {
+		if h != nil {
+			recordHeaders = append(recordHeaders, *h)
+		}
+	}
+	carrier := KafkaHeaderCarrier{Headers: &recordHeaders}
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	return messageContext.Extract(ctx, carrier)
+}
+
+// StartProducerSpan starts a span for publishing a message
+func StartProducerSpan(ctx context.Context, system, destination string) (context.Context, trace.Span) {
+	return otel.Tracer(messagingTracer).Start(ctx, destination+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", system),
+			attribute.String("messaging.destination", destination),
+		),
+	)
+}
+
+// StartConsumerSpan starts a span for processing a consumed message
+func StartConsumerSpan(ctx context.Context, system, source string) (context.Context, trace.Span) {
+	return otel.Tracer(messagingTracer).Start(ctx, source+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", system),
+			attribute.String("messaging.source", source),
+		),
+	)
+}
+
+var _ propagation.TextMapCarrier = AMQPHeaderCarrier{}
+var _ propagation.TextMapCarrier = KafkaHeaderCarrier{}
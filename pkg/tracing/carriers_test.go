@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/onichange/pos-system/pkg/logger"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+func testSpanContext(t *testing.T) trace.SpanContext {
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	assert.NoError(t, err)
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+}
+
+func TestAMQPHeaderCarrier_RoundTrip(t *testing.T) {
+	propagator := propagation.TraceContext{}
+	sc := testSpanContext(t)
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	headers := amqp.Table{}
+	propagator.Inject(ctx, AMQPHeaderCarrier(headers))
+	assert.NotEmpty(t, headers["traceparent"])
+
+	extracted := trace.SpanContextFromContext(propagator.Extract(context.Background(), AMQPHeaderCarrier(headers)))
+	assert.Equal(t, sc.TraceID(), extracted.TraceID())
+}
+
+func TestKafkaHeaderCarrier_RoundTrip(t *testing.T) {
+	propagator := propagation.TraceContext{}
+	sc := testSpanContext(t)
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	var headers []sarama.RecordHeader
+	propagator.Inject(ctx, KafkaHeaderCarrier{Headers: &headers})
+	assert.Len(t, headers, 1)
+
+	extracted := trace.SpanContextFromContext(propagator.Extract(context.Background(), KafkaHeaderCarrier{Headers: &headers}))
+	assert.Equal(t, sc.TraceID(), extracted.TraceID())
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	ctx := logger.ContextWithRequestID(context.Background(), "req-123")
+
+	headers := amqp.Table{}
+	InjectAMQP(ctx, headers)
+	assert.Equal(t, "req-123", headers[logger.RequestIDHeader])
+	assert.Equal(t, "req-123", logger.RequestIDFromContext(ExtractAMQP(context.Background(), headers)))
+
+	var recordHeaders []sarama.RecordHeader
+	InjectKafka(ctx, &recordHeaders)
+	consumed := make([]*sarama.RecordHeader, len(recordHeaders))
+	for i := range recordHeaders {
+		consumed[i] = &recordHeaders[i]
+	}
+	assert.Equal(t, "req-123", logger.RequestIDFromContext(ExtractKafka(context.Background(), consumed)))
+}
+
+func TestTenantPropagation(t *testing.T) {
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "acme", Source: tenant.SourceJWT})
+
+	headers := amqp.Table{}
+	InjectAMQP(ctx, headers)
+	assert.Equal(t, "acme", headers[tenant.Header])
+	assert.Equal(t, "acme", tenant.IDFromContext(ExtractAMQP(context.Background(), headers)))
+
+	// Malformed tenant IDs are not trusted
+	headers[tenant.Header] = "../other"
+	assert.Empty(t, tenant.IDFromContext(ExtractAMQP(context.Background(), headers)))
+}
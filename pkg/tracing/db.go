@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// databaseTracer is the instrumentation name used for database spans
+const databaseTracer = "database"
+
+// QueryTracer starts a client span for every query, batch, and copy made on a
+// pgx connection. Statements are recorded with their placeholders; argument
+// values are never attached because they may hold personal data.
+type QueryTracer struct{}
+
+// TraceQueryStart starts a span for Query, QueryRow, and Exec calls
+func (QueryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := sqlOperation(data.SQL)
+	ctx, _ = startDBSpan(ctx, conn, operation,
+		attribute.String("db.operation", operation),
+		attribute.String("db.statement", data.SQL),
+	)
+	return ctx
+}
+
+// TraceQueryEnd ends the query span
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	endDBSpan(ctx, data.CommandTag.RowsAffected(), data.Err)
+}
+
+// TraceBatchStart starts a span covering a whole batch
+func (QueryTracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx, _ = startDBSpan(ctx, conn, "BATCH",
+		attribute.String("db.operation", "BATCH"),
+		attribute.Int("db.batch.size", data.Batch.Len()),
+	)
+	return ctx
+}
+
+// TraceBatchQuery records each statement of a batch as an event on its span
+func (QueryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent("query", trace.WithAttributes(
+		attribute.String("db.statement", data.SQL),
+		attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()),
+	))
+	if data.Err != nil {
+		span.RecordError(data.Err)
+	}
+}
+
+// TraceBatchEnd ends the batch span
+func (QueryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	endDBSpan(ctx, -1, data.Err)
+}
+
+// TraceCopyFromStart starts a span for a bulk copy
+func (QueryTracer) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	table := data.TableName.Sanitize()
+	ctx, _ = startDBSpan(ctx, conn, "COPY",
+		attribute.String("db.operation", "COPY"),
+		attribute.String("db.sql.table", table),
+		attribute.String("db.statement", "COPY "+table+" ("+strings.Join(data.ColumnNames, ", ")+") FROM STDIN"),
+	)
+	return ctx
+}
+
+// TraceCopyFromEnd ends the copy span
+func (QueryTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	endDBSpan(ctx, data.CommandTag.RowsAffected(), data.Err)
+}
+
+// startDBSpan starts a client span named after the operation and database
+func startDBSpan(ctx context.Context, conn *pgx.Conn, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	name := operation
+	attrs = append(attrs, attribute.String("db.system", "postgresql"))
+	if conn != nil {
+		if database := conn.Config().Database; database != "" {
+			name += " " + database
+			attrs = append(attrs, attribute.String("db.name", database))
+		}
+	}
+
+	return otel.Tracer(databaseTracer).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// endDBSpan records the outcome of a database call; rowsAffected below zero is omitted
+func endDBSpan(ctx context.Context, rowsAffected int64, err error) {
+	span := trace.SpanFromContext(ctx)
+	if rowsAffected >= 0 {
+		span.SetAttributes(attribute.Int64("db.rows_affected", rowsAffected))
+	}
+	// A missing row is an expected outcome, not a failure
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// sqlOperation returns the leading keyword of a statement, such as SELECT
+func sqlOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+	return strings.ToUpper(fields[0])
+}
+
+var (
+	_ pgx.QueryTracer    = QueryTracer{}
+	_ pgx.BatchTracer    = QueryTracer{}
+	_ pgx.CopyFromTracer = QueryTracer{}
+)
@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider that keeps finished spans in memory
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestSQLOperation(t *testing.T) {
+	assert.Equal(t, "SELECT", sqlOperation("\n\t\tselect id FROM orders"))
+	assert.Equal(t, "UPDATE", sqlOperation("UPDATE orders SET status = $2"))
+	assert.Equal(t, "QUERY", sqlOperation("  "))
+}
+
+func TestQueryTracer(t *testing.T) {
+	recorder := recordSpans(t)
+	tracer := QueryTracer{}
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{
+		SQL:  "SELECT email FROM users WHERE id = $1",
+		Args: []any{"secret-value"},
+	})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: pgx.ErrNoRows})
+
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "DELETE FROM users"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("permission denied")})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, "SELECT", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("db.statement", "SELECT email FROM users WHERE id = $1"))
+	for _, attr := range spans[0].Attributes() {
+		assert.NotEqual(t, "secret-value", attr.Value.Emit(), "arguments must not be recorded")
+	}
+	assert.Equal(t, codes.Unset, spans[0].Status().Code, "a missing row is not an error")
+
+	assert.Equal(t, "DELETE", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
@@ -5,74 +5,189 @@ import (
 	"fmt"
 
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/onichange/pos-system/pkg/config"
 	"github.com/onichange/pos-system/pkg/logger"
 )
 
+// Trace exporters
+const (
+	ExporterNone     = "none"      // Spans are not recorded; trace context is still propagated
+	ExporterOTLPGRPC = "otlp-grpc" // OTLP over gRPC, usually port 4317
+	ExporterOTLPHTTP = "otlp-http" // OTLP over HTTP/protobuf, usually port 4318
+)
+
+// Samplers
+const (
+	SamplerParentRatio = "parent-ratio" // Follow the caller's decision; sample new traces at the ratio
+	SamplerRatio       = "ratio"        // Sample at the ratio regardless of the caller
+	SamplerAlwaysOn    = "always-on"
+	SamplerAlwaysOff   = "always-off"
+)
+
 // TracerProvider wraps OpenTelemetry tracer provider
 type TracerProvider struct {
-	tp        *tracesdk.TracerProvider
-	tracer    trace.Tracer
-	logger    *logger.Logger
+	tp     *tracesdk.TracerProvider
+	tracer trace.Tracer
+	logger *logger.Logger
 }
 
-// NewTracerProvider creates a new OpenTelemetry tracer provider with Jaeger exporter
-func NewTracerProvider(serviceName, jaegerURL string, log *logger.Logger) (*TracerProvider, error) {
-	// Create Jaeger exporter
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(jaegerURL)))
+// NewTracerProvider creates the tracer provider described by cfg and installs it
+// as the global provider along with the W3C trace context and baggage propagators.
+// With the none exporter no spans are recorded, but incoming trace context is
+// still passed on to downstream calls.
+func NewTracerProvider(serviceName, environment string, cfg config.TracingConfig, log *logger.Logger) (*TracerProvider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if cfg.Exporter == ExporterNone {
+		log.Info("Tracing disabled; trace context is propagated only")
+		return &TracerProvider{tracer: otel.Tracer(serviceName), logger: log}, nil
+	}
+
+	exp, err := newExporter(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Jaeger exporter: %w", err)
+		return nil, err
 	}
 
-	// Create resource
-	res, err := resource.New(context.Background(),
-		resource.WithAttributes(
-			semconv.ServiceNameKey.String(serviceName),
-			semconv.ServiceVersionKey.String("1.0.0"),
-		),
-	)
+	res, err := newResource(serviceName, environment, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
 
-	// Create tracer provider with sampling (1% in production)
+	sampler, err := newSampler(cfg, environment)
+	if err != nil {
+		return nil, err
+	}
+
 	tp := tracesdk.NewTracerProvider(
 		tracesdk.WithBatcher(exp),
 		tracesdk.WithResource(res),
-		tracesdk.WithSampler(tracesdk.TraceIDRatioBased(0.01)), // 1% sampling
+		tracesdk.WithSampler(sampler),
 	)
-
-	// Set global tracer provider
 	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
-		propagation.TraceContext{},
-		propagation.Baggage{},
-	))
-
-	tracer := tp.Tracer(serviceName)
 
-	log.Infof("OpenTelemetry tracer initialized for service: %s", serviceName)
+	log.Infof("OpenTelemetry tracer initialized for service %s: exporter=%s endpoint=%s sampler=%s",
+		serviceName, cfg.Exporter, cfg.Endpoint, sampler.Description())
 
 	return &TracerProvider{
 		tp:     tp,
-		tracer: tracer,
+		tracer: tp.Tracer(serviceName),
 		logger: log,
 	}, nil
 }
 
+// newExporter creates the OTLP exporter selected by cfg. Options left unset here
+// can still be given through the standard OTEL_EXPORTER_OTLP_* variables.
+func newExporter(cfg config.TracingConfig) (tracesdk.SpanExporter, error) {
+	ctx := context.Background()
+
+	switch cfg.Exporter {
+	case ExporterOTLPGRPC:
+		var opts []otlptracegrpc.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
+		}
+		exp, err := otlptracegrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP gRPC exporter: %w", err)
+		}
+		return exp, nil
+
+	case ExporterOTLPHTTP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		exp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP HTTP exporter: %w", err)
+		}
+		return exp, nil
+
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+}
+
+// newResource describes the service on every exported span. Attributes from
+// OTEL_RESOURCE_ATTRIBUTES are included; configured attributes take precedence.
+func newResource(serviceName, environment string, cfg config.TracingConfig) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(serviceName),
+		semconv.DeploymentEnvironmentKey.String(environment),
+	}
+	if cfg.ServiceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersionKey.String(cfg.ServiceVersion))
+	}
+	for key, value := range cfg.ResourceAttributes {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+
+	return resource.New(context.Background(),
+		resource.WithFromEnv(),
+		resource.WithHost(),
+		resource.WithAttributes(attrs...),
+	)
+}
+
+// newSampler returns the configured sampler. Without one, development samples
+// every trace and other environments follow the parent at the configured ratio.
+func newSampler(cfg config.TracingConfig, environment string) (tracesdk.Sampler, error) {
+	name := cfg.Sampler
+	if name == "" {
+		name = SamplerParentRatio
+		if environment == "development" {
+			name = SamplerAlwaysOn
+		}
+	}
+
+	switch name {
+	case SamplerParentRatio:
+		return tracesdk.ParentBased(tracesdk.TraceIDRatioBased(cfg.SampleRatio)), nil
+	case SamplerRatio:
+		return tracesdk.TraceIDRatioBased(cfg.SampleRatio), nil
+	case SamplerAlwaysOn:
+		return tracesdk.AlwaysSample(), nil
+	case SamplerAlwaysOff:
+		return tracesdk.NeverSample(), nil
+	default:
+		return nil, fmt.Errorf("unknown trace sampler %q", name)
+	}
+}
+
 // GetTracer returns the tracer
 func (t *TracerProvider) GetTracer() trace.Tracer {
 	return t.tracer
 }
 
-// Shutdown shuts down the tracer provider
+// Shutdown flushes buffered spans and shuts down the tracer provider
 func (t *TracerProvider) Shutdown(ctx context.Context) error {
+	if t.tp == nil {
+		return nil
+	}
 	return t.tp.Shutdown(ctx)
 }
 
@@ -80,4 +195,3 @@ func (t *TracerProvider) Shutdown(ctx context.Context) error {
 func StartSpan(ctx context.Context, tracer trace.Tracer, name string) (context.Context, trace.Span) {
 	return tracer.Start(ctx, name)
 }
-
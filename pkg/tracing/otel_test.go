@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+func TestNewSampler(t *testing.T) {
+	cfg := config.TracingConfig{SampleRatio: 0.25}
+
+	sampler, err := newSampler(cfg, "development")
+	require.NoError(t, err)
+	assert.Equal(t, "AlwaysOnSampler", sampler.Description())
+
+	sampler, err = newSampler(cfg, "production")
+	require.NoError(t, err)
+	assert.Contains(t, sampler.Description(), "ParentBased{root:TraceIDRatioBased{0.25}")
+
+	cfg.Sampler = SamplerRatio
+	sampler, err = newSampler(cfg, "development")
+	require.NoError(t, err)
+	assert.Equal(t, "TraceIDRatioBased{0.25}", sampler.Description())
+
+	cfg.Sampler = "sometimes"
+	_, err = newSampler(cfg, "production")
+	assert.Error(t, err)
+}
+
+func TestNewResource(t *testing.T) {
+	res, err := newResource("order-service", "staging", config.TracingConfig{
+		ServiceVersion:     "2.3.1",
+		ResourceAttributes: map[string]string{"region": "eu-west-1"},
+	})
+	require.NoError(t, err)
+
+	attrs := res.Set()
+	for key, want := range map[attribute.Key]string{
+		"service.name":           "order-service",
+		"service.version":        "2.3.1",
+		"deployment.environment": "staging",
+		"region":                 "eu-west-1",
+	} {
+		value, ok := attrs.Value(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, want, value.AsString())
+	}
+}
+
+func TestNewTracerProviderNone(t *testing.T) {
+	tp, err := NewTracerProvider("order-service", "development", config.TracingConfig{Exporter: ExporterNone}, logger.New("test"))
+	require.NoError(t, err)
+	assert.NotNil(t, tp.GetTracer())
+	assert.NoError(t, tp.Shutdown(context.Background()))
+}
@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// redisTracer is the instrumentation name used for Redis spans
+const redisTracer = "redis"
+
+// RedisHook starts a client span for every Redis command and pipeline. Only
+// the command name and key are recorded; values may hold tokens or personal data.
+type RedisHook struct{}
+
+// DialHook traces new connections
+func (RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, span := otel.Tracer(redisTracer).Start(ctx, "redis dial",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("net.peer.name", addr),
+			),
+		)
+		defer span.End()
+
+		conn, err := next(ctx, network, addr)
+		recordRedisError(span, err)
+		return conn, err
+	}
+}
+
+// ProcessHook traces a single command
+func (RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := otel.Tracer(redisTracer).Start(ctx, strings.ToUpper(cmd.Name()),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation", cmd.Name()),
+				attribute.String("db.statement", redisStatement(cmd)),
+			),
+		)
+		defer span.End()
+
+		err := next(ctx, cmd)
+		recordRedisError(span, err)
+		return err
+	}
+}
+
+// ProcessPipelineHook traces a pipeline or transaction as one span
+func (RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		statements := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			statements = append(statements, redisStatement(cmd))
+		}
+
+		ctx, span := otel.Tracer(redisTracer).Start(ctx, "PIPELINE",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "redis"),
+				attribute.String("db.operation", "pipeline"),
+				attribute.String("db.statement", strings.Join(statements, "\n")),
+				attribute.Int("db.redis.pipeline_length", len(cmds)),
+			),
+		)
+		defer span.End()
+
+		err := next(ctx, cmds)
+		recordRedisError(span, err)
+		return err
+	}
+}
+
+// redisStatement describes a command by its name and key
+func redisStatement(cmd redis.Cmder) string {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return cmd.Name()
+	}
+	key, ok := args[1].(string)
+	if !ok {
+		return cmd.Name()
+	}
+	return cmd.Name() + " " + key
+}
+
+// recordRedisError marks a span failed; a missing key is an expected outcome
+func recordRedisError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+var _ redis.Hook = RedisHook{}
@@ -0,0 +1,14 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisStatement(t *testing.T) {
+	assert.Equal(t, "set user:1", redisStatement(redis.NewStatusCmd(context.Background(), "set", "user:1", "secret-value")))
+	assert.Equal(t, "ping", redisStatement(redis.NewStatusCmd(context.Background(), "ping")))
+}
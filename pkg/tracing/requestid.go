@@ -0,0 +1,36 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// RequestIDPropagator carries the request ID alongside the trace context. It is
+// applied by the message carrier helpers regardless of the global propagator, so
+// request IDs flow through brokers even when tracing is disabled.
+type RequestIDPropagator struct{}
+
+// Inject writes the request ID from ctx into the carrier
+func (RequestIDPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+		carrier.Set(logger.RequestIDHeader, requestID)
+	}
+}
+
+// Extract returns a context carrying the request ID found in the carrier
+func (RequestIDPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	if requestID := carrier.Get(logger.RequestIDHeader); requestID != "" {
+		return logger.ContextWithRequestID(ctx, requestID)
+	}
+	return ctx
+}
+
+// Fields returns the keys the propagator sets
+func (RequestIDPropagator) Fields() []string {
+	return []string{logger.RequestIDHeader}
+}
+
+var _ propagation.TextMapPropagator = RequestIDPropagator{}
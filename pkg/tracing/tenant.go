@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// TenantPropagator carries the tenant ID in message headers so consumers
+// handle events in the tenant that produced them
+type TenantPropagator struct{}
+
+// Inject writes the tenant ID from ctx into the carrier
+func (TenantPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	if tenantID := tenant.IDFromContext(ctx); tenantID != "" {
+		carrier.Set(tenant.Header, tenantID)
+	}
+}
+
+// Extract returns a context carrying the tenant found in the carrier
+func (TenantPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	if tenantID := carrier.Get(tenant.Header); tenant.ValidID(tenantID) {
+		return tenant.NewContext(ctx, &tenant.Tenant{ID: tenantID, Source: tenant.SourceHeader})
+	}
+	return ctx
+}
+
+// Fields returns the keys the propagator sets
+func (TenantPropagator) Fields() []string {
+	return []string{tenant.Header}
+}
+
+var _ propagation.TextMapPropagator = TenantPropagator{}
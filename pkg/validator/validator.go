@@ -34,6 +34,7 @@ func ValidateStruct(s interface{}) []ValidationError {
 			errors = append(errors, ValidationError{
 				Field:   err.Field(),
 				Tag:     err.Tag(),
+				Param:   err.Param(),
 				Value:   fmt.Sprintf("%v", err.Value()),
 				Message: getErrorMessage(err),
 			})
@@ -43,10 +44,12 @@ func ValidateStruct(s interface{}) []ValidationError {
 	return errors
 }
 
-// ValidationError represents a validation error
+// ValidationError represents a validation error. Field, Tag and Param let
+// i18n.Middleware render Message in the client's language.
 type ValidationError struct {
 	Field   string `json:"field"`
 	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
 	Value   string `json:"value"`
 	Message string `json:"message"`
 }
@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/encryption"
+	"github.com/onichange/pos-system/pkg/metrics"
+)
+
+// Headers of a delivery
+const (
+	SignatureHeader = "Webhook-Signature" // t=<unix seconds>,v1=<hex HMAC-SHA256>; see encryption.SignPayload
+	IDHeader        = "Webhook-ID"        // Event ID, for receivers to drop duplicates
+	EventHeader     = "Webhook-Event"     // Event type
+)
+
+// Event is the JSON body of a delivery
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Sender delivers signed events to the configured endpoints. A nil Sender
+// sends nothing.
+type Sender struct {
+	endpoints []string
+	secrets   []string
+	client    *http.Client
+	now       func() time.Time
+}
+
+// NewSender creates the sender described by cfg. It returns nil when no
+// endpoints are configured.
+func NewSender(cfg config.WebhooksConfig) *Sender {
+	if len(cfg.Endpoints) == 0 {
+		return nil
+	}
+	return &Sender{
+		endpoints: cfg.Endpoints,
+		secrets:   cfg.Secrets,
+		client:    &http.Client{Timeout: cfg.Timeout},
+		now:       time.Now,
+	}
+}
+
+// Send delivers an event of eventType carrying data to every endpoint. It
+// returns the failed deliveries joined; an endpoint answering outside 2xx
+// counts as failed.
+func (s *Sender) Send(ctx context.Context, eventType string, data any) error {
+	if s == nil {
+		return nil
+	}
+
+	event := Event{ID: uuid.NewString(), Type: eventType, CreatedAt: s.now().UTC(), Data: data}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	signature := encryption.SignPayload(body, s.now(), s.secrets...)
+
+	var errs []error
+	for _, endpoint := range s.endpoints {
+		err := s.deliver(ctx, endpoint, event, body, signature)
+		result := "success"
+		if err != nil {
+			result = "failure"
+			errs = append(errs, err)
+		}
+		metrics.WebhookDeliveries.WithLabelValues(eventType, result).Inc()
+	}
+	return errors.Join(errs...)
+}
+
+// deliver posts a signed event to one endpoint
+func (s *Sender) deliver(ctx context.Context, endpoint string, event Event, body []byte, signature string) error {
+	req, err := NewRequest(ctx, endpoint, event, body, signature)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s to %s failed: %w", event.Type, endpoint, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s to %s returned %d", event.Type, endpoint, resp.StatusCode)
+	}
+	return nil
+}
+
+// NewRequest builds the POST delivering event, marshalled as body and
+// signed as by encryption.SignPayload, to endpoint
+func NewRequest(ctx context.Context, endpoint string, event Event, body []byte, signature string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signature)
+	req.Header.Set(IDHeader, event.ID)
+	req.Header.Set(EventHeader, event.Type)
+	return req, nil
+}
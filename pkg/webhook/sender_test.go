@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/config"
+	"github.com/onichange/pos-system/pkg/encryption"
+)
+
+func TestSenderSignsDeliveries(t *testing.T) {
+	var received Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		_, err = encryption.VerifyPayload(body, r.Header.Get(SignatureHeader), []string{"whsec"}, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, "order.created", r.Header.Get(EventHeader))
+		require.NoError(t, json.Unmarshal(body, &received))
+		assert.Equal(t, received.ID, r.Header.Get(IDHeader))
+	}))
+	defer server.Close()
+
+	sender := NewSender(config.WebhooksConfig{Endpoints: []string{server.URL}, Secrets: []string{"whsec"}, Timeout: time.Second})
+	require.NoError(t, sender.Send(context.Background(), "order.created", map[string]string{"id": "o-1"}))
+	assert.Equal(t, "order.created", received.Type)
+	assert.Equal(t, map[string]any{"id": "o-1"}, received.Data)
+}
+
+func TestSenderReportsFailedEndpoints(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	sender := NewSender(config.WebhooksConfig{Endpoints: []string{ok.URL, failing.URL}, Timeout: time.Second})
+	err := sender.Send(context.Background(), "order.updated", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "returned 502")
+}
+
+func TestNilSenderSendsNothing(t *testing.T) {
+	sender := NewSender(config.WebhooksConfig{})
+	assert.Nil(t, sender)
+	assert.NoError(t, sender.Send(context.Background(), "order.created", nil))
+}
+
+func TestNewRequestCarriesEventHeaders(t *testing.T) {
+	event := Event{ID: "evt-1", Type: "payment.completed"}
+	req, err := NewRequest(context.Background(), "https://partner.example.com/hooks", event, []byte(`{}`), "t=1,v1=ab")
+	require.NoError(t, err)
+
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, "t=1,v1=ab", req.Header.Get(SignatureHeader))
+	assert.Equal(t, "evt-1", req.Header.Get(IDHeader))
+	assert.Equal(t, "payment.completed", req.Header.Get(EventHeader))
+}
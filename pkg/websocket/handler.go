@@ -1,36 +1,49 @@
 package websocket
 
 import (
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
+
 	"github.com/onichange/pos-system/pkg/logger"
 )
 
-// HandleWebSocket handles WebSocket upgrade requests
-// Note: For Fiber compatibility, use nhooyr.io/websocket instead of gorilla/websocket
-// This handler is a placeholder that shows the structure
+// HandleWebSocket upgrades requests authenticated by middleware.JWTAuth to
+// WebSocket connections registered with hub under the caller's user ID,
+// tenant and roles
 func HandleWebSocket(hub *Hub, log *logger.Logger) fiber.Handler {
+	upgrade := websocket.New(func(conn *websocket.Conn) {
+		userID, _ := conn.Locals("user_id").(string)
+		client := NewClient(hub, conn, userID, log)
+		client.TenantID, _ = conn.Locals("tenant_id").(string)
+		client.Roles, _ = conn.Locals("roles").([]string)
+		client.Serve()
+	})
+
 	return func(c *fiber.Ctx) error {
+		if !websocket.IsWebSocketUpgrade(c) {
+			return fiber.ErrUpgradeRequired
+		}
+
 		// Get user ID from context (set by JWT middleware)
 		userID, ok := c.Locals("user_id").(string)
-		if !ok {
+		if !ok || userID == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Unauthorized",
 			})
 		}
+		return upgrade(c)
+	}
+}
 
-		// TODO: Implement WebSocket upgrade using nhooyr.io/websocket
-		// Example:
-		// conn, err := websocket.Accept(c.Context(), c.Response(), c.Request(), nil)
-		// if err != nil {
-		//     log.Errorf("WebSocket upgrade failed: %v", err)
-		//     return err
-		// }
-
-		// For now, return not implemented
-		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
-			"error": "WebSocket support requires nhooyr.io/websocket for Fiber compatibility",
-			"user_id": userID,
-		})
+// QueryToken passes the access_token query parameter of a WebSocket upgrade
+// on as its Authorization header, for browsers that cannot set headers on
+// the handshake. Place it before middleware.JWTAuth.
+func QueryToken() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token := c.Query("access_token"); token != "" && c.Get(fiber.HeaderAuthorization) == "" && websocket.IsWebSocketUpgrade(c) {
+			c.Request().Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+		}
+		return c.Next()
 	}
 }
 
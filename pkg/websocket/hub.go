@@ -3,11 +3,12 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/gofiber/contrib/websocket"
 	"github.com/google/uuid"
-	"github.com/gorilla/websocket"
 	"github.com/redis/go-redis/v9"
 
 	"github.com/onichange/pos-system/pkg/logger"
@@ -25,18 +26,33 @@ const (
 
 	// Maximum message size allowed from peer
 	maxMessageSize = 512 * 1024 // 512KB
+
+	// userChannelPrefix prefixes the Redis channel of each user's messages
+	userChannelPrefix = "websocket:user:"
+
+	// channelPrefix prefixes the Redis channel of each tenant's channels
+	channelPrefix = "websocket:channel:"
+)
+
+// Message types the hub answers subscription requests with
+const (
+	MessageSubscribed   = "subscribed"
+	MessageUnsubscribed = "unsubscribed"
+	MessageError        = "error"
 )
 
 // Client represents a WebSocket client connection
 type Client struct {
-	ID     string
-	UserID string
-	Hub    *Hub
-	Conn   *websocket.Conn
-	Send   chan []byte
-	Logger *logger.Logger
-	ctx    context.Context
-	cancel context.CancelFunc
+	ID       string
+	UserID   string
+	TenantID string
+	Roles    []string
+	Hub      *Hub
+	Conn     *websocket.Conn
+	Send     chan []byte
+	Logger   *logger.Logger
+	ctx      context.Context
+	cancel   context.CancelFunc
 }
 
 // Hub maintains the set of active clients and broadcasts messages
@@ -44,6 +60,12 @@ type Hub struct {
 	// Registered clients
 	clients map[string]map[*Client]bool
 
+	// Clients subscribed to each channel, by tenant and channel name
+	channels map[string]map[*Client]bool
+
+	// Decides whether a client may subscribe to a channel; nil allows none
+	authorize func(c *Client, channel string) bool
+
 	// Inbound messages from the clients
 	broadcast chan []byte
 
@@ -53,7 +75,7 @@ type Hub struct {
 	// Unregister requests from clients
 	unregister chan *Client
 
-	// Redis client for pub/sub
+	// Redis client for pub/sub; nil keeps messages on this instance
 	redis *redis.Client
 
 	// Logger
@@ -67,15 +89,18 @@ type Hub struct {
 type Message struct {
 	Type      string      `json:"type"`
 	UserID    string      `json:"user_id,omitempty"`
+	TenantID  string      `json:"tenant_id,omitempty"`
 	Channel   string      `json:"channel,omitempty"`
 	Data      interface{} `json:"data"`
 	Timestamp int64       `json:"timestamp"`
 }
 
-// NewHub creates a new WebSocket hub
+// NewHub creates a new WebSocket hub. With a Redis client, messages
+// published through it reach the clients of every instance.
 func NewHub(redisClient *redis.Client, log *logger.Logger) *Hub {
 	hub := &Hub{
 		clients:    make(map[string]map[*Client]bool),
+		channels:   make(map[string]map[*Client]bool),
 		broadcast:  make(chan []byte, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
@@ -84,7 +109,9 @@ func NewHub(redisClient *redis.Client, log *logger.Logger) *Hub {
 	}
 
 	// Start Redis pub/sub listener
-	go hub.listenRedis()
+	if redisClient != nil {
+		go hub.listenRedis()
+	}
 
 	return hub
 }
@@ -107,6 +134,12 @@ func (h *Hub) Run() {
 			if clients, ok := h.clients[client.UserID]; ok {
 				if _, ok := clients[client]; ok {
 					delete(clients, client)
+					for key, subscribers := range h.channels {
+						delete(subscribers, client)
+						if len(subscribers) == 0 {
+							delete(h.channels, key)
+						}
+					}
 					close(client.Send)
 					if len(clients) == 0 {
 						delete(h.clients, client.UserID)
@@ -121,12 +154,7 @@ func (h *Hub) Run() {
 			// Broadcast to all clients
 			for _, clients := range h.clients {
 				for client := range clients {
-					select {
-					case client.Send <- message:
-					default:
-						close(client.Send)
-						delete(clients, client)
-					}
+					h.send(client, message)
 				}
 			}
 			h.mu.RUnlock()
@@ -141,16 +169,113 @@ func (h *Hub) BroadcastToUser(userID string, message []byte) {
 
 	if clients, ok := h.clients[userID]; ok {
 		for client := range clients {
-			select {
-			case client.Send <- message:
-			default:
-				close(client.Send)
-				delete(clients, client)
-			}
+			h.send(client, message)
 		}
 	}
 }
 
+// send queues message for client. A client too slow to take it is
+// unregistered, which closes its connection.
+func (h *Hub) send(client *Client, message []byte) {
+	select {
+	case client.Send <- message:
+	default:
+		go func() { h.unregister <- client }()
+	}
+}
+
+// PublishToUser sends msg to the clients of msg.UserID on every instance,
+// through Redis, or on this one without it
+func (h *Hub) PublishToUser(msg *Message) error {
+	if msg.Timestamp == 0 {
+		msg.Timestamp = time.Now().Unix()
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if h.redis == nil {
+		h.BroadcastToUser(msg.UserID, payload)
+		return nil
+	}
+	return h.redis.Publish(context.Background(), userChannelPrefix+msg.UserID, payload).Err()
+}
+
+// Authorize sets the check a client's subscription to a channel must pass.
+// Without one, subscriptions are refused.
+func (h *Hub) Authorize(fn func(c *Client, channel string) bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.authorize = fn
+}
+
+// channelKey keys a channel by tenant, so clients only ever receive the
+// messages of their own tenant's channels
+func channelKey(tenantID, channel string) string {
+	return tenantID + ":" + channel
+}
+
+// Subscribe adds client to the subscribers of channel, if allowed
+func (h *Hub) Subscribe(client *Client, channel string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.authorize == nil || !h.authorize(client, channel) {
+		return false
+	}
+	if _, ok := h.clients[client.UserID][client]; !ok {
+		return false // Already unregistered
+	}
+	key := channelKey(client.TenantID, channel)
+	if h.channels[key] == nil {
+		h.channels[key] = make(map[*Client]bool)
+	}
+	h.channels[key][client] = true
+	return true
+}
+
+// Unsubscribe removes client from the subscribers of channel
+func (h *Hub) Unsubscribe(client *Client, channel string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := channelKey(client.TenantID, channel)
+	if subscribers, ok := h.channels[key]; ok {
+		delete(subscribers, client)
+		if len(subscribers) == 0 {
+			delete(h.channels, key)
+		}
+	}
+}
+
+// broadcastToSubscribers sends a message to the local subscribers of a
+// tenant's channel
+func (h *Hub) broadcastToSubscribers(tenantID, channel string, message []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.channels[channelKey(tenantID, channel)] {
+		h.send(client, message)
+	}
+}
+
+// PublishToChannel sends msg to the subscribers of msg.Channel in
+// msg.TenantID on every instance, through Redis, or on this one without it
+func (h *Hub) PublishToChannel(msg *Message) error {
+	if msg.Timestamp == 0 {
+		msg.Timestamp = time.Now().Unix()
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if h.redis == nil {
+		h.broadcastToSubscribers(msg.TenantID, msg.Channel, payload)
+		return nil
+	}
+	return h.redis.Publish(context.Background(), channelPrefix+channelKey(msg.TenantID, msg.Channel), payload).Err()
+}
+
 // BroadcastToChannel publishes message to Redis channel for horizontal scaling
 func (h *Hub) BroadcastToChannel(channel string, message []byte) error {
 	return h.redis.Publish(context.Background(), channel, message).Err()
@@ -158,7 +283,7 @@ func (h *Hub) BroadcastToChannel(channel string, message []byte) error {
 
 // listenRedis listens to Redis pub/sub for horizontal scaling
 func (h *Hub) listenRedis() {
-	pubsub := h.redis.Subscribe(context.Background(), "websocket:*")
+	pubsub := h.redis.PSubscribe(context.Background(), "websocket:*")
 	defer pubsub.Close()
 
 	ch := pubsub.Channel()
@@ -170,8 +295,10 @@ func (h *Hub) listenRedis() {
 			continue
 		}
 
-		// Broadcast to user if specified
-		if wsMessage.UserID != "" {
+		// Broadcast to a channel's subscribers or a user if specified
+		if strings.HasPrefix(msg.Channel, channelPrefix) {
+			h.broadcastToSubscribers(wsMessage.TenantID, wsMessage.Channel, []byte(msg.Payload))
+		} else if wsMessage.UserID != "" {
 			h.BroadcastToUser(wsMessage.UserID, []byte(msg.Payload))
 		} else {
 			// Broadcast to all
@@ -211,13 +338,34 @@ func (c *Client) ReadPump() {
 		}
 
 		// Process message (e.g., subscribe to channel)
-		if msg.Type == "subscribe" && msg.Channel != "" {
-			// Subscribe to Redis channel
+		switch {
+		case msg.Type == "subscribe" && msg.Channel != "":
+			if !c.Hub.Subscribe(c, msg.Channel) {
+				c.reply(MessageError, msg.Channel, "subscription refused")
+				continue
+			}
 			c.Logger.Infof("Client %s subscribed to channel: %s", c.ID, msg.Channel)
+			c.reply(MessageSubscribed, msg.Channel, nil)
+		case msg.Type == "unsubscribe" && msg.Channel != "":
+			c.Hub.Unsubscribe(c, msg.Channel)
+			c.reply(MessageUnsubscribed, msg.Channel, nil)
 		}
 	}
 }
 
+// reply answers a request of the client
+func (c *Client) reply(messageType, channel string, data interface{}) {
+	payload, err := json.Marshal(&Message{Type: messageType, Channel: channel, Data: data, Timestamp: time.Now().Unix()})
+	if err != nil {
+		return
+	}
+	c.Hub.mu.RLock()
+	defer c.Hub.mu.RUnlock()
+	if _, ok := c.Hub.clients[c.UserID][c]; ok {
+		c.Hub.send(c, payload)
+	}
+}
+
 // WritePump pumps messages from the hub to the WebSocket connection
 func (c *Client) WritePump() {
 	ticker := time.NewTicker(pingPeriod)
@@ -282,6 +430,20 @@ func (c *Client) Start() {
 	go c.ReadPump()
 }
 
+// Serve registers the client with its hub and pumps messages until the
+// connection closes. Fiber's WebSocket handlers must not return before then:
+// the connection is reused once they do.
+func (c *Client) Serve() {
+	c.Hub.register <- c
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.WritePump()
+	}()
+	c.ReadPump()
+	<-done
+}
+
 // Close closes the client connection
 func (c *Client) Close() {
 	c.cancel()
@@ -0,0 +1,128 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	gorilla "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/logger"
+)
+
+// serve runs a hub behind GET /ws on a local port, with every caller
+// authenticated as the user and tenant in the X-User and X-Tenant headers
+func serve(t *testing.T, hub *Hub) string {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/ws", func(c *fiber.Ctx) error {
+		if user := c.Get("X-User"); user != "" {
+			c.Locals("user_id", user)
+		}
+		c.Locals("tenant_id", c.Get("X-Tenant"))
+		return c.Next()
+	}, HandleWebSocket(hub, logger.New("test")))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(listener) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+	return listener.Addr().String()
+}
+
+func dial(t *testing.T, addr, user string) *gorilla.Conn {
+	return dialTenant(t, addr, user, "")
+}
+
+func dialTenant(t *testing.T, addr, user, tenantID string) *gorilla.Conn {
+	t.Helper()
+	conn, _, err := gorilla.DefaultDialer.Dial("ws://"+addr+"/ws", map[string][]string{"X-User": {user}, "X-Tenant": {tenantID}})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestPublishToUserReachesOnlyThatUser(t *testing.T) {
+	hub := NewHub(nil, logger.New("test"))
+	go hub.Run()
+	addr := serve(t, hub)
+
+	alice := dial(t, addr, "alice")
+	bob := dial(t, addr, "bob")
+
+	// Registration happens on the hub's goroutine after the handshake
+	require.Eventually(t, func() bool {
+		hub.mu.RLock()
+		defer hub.mu.RUnlock()
+		return len(hub.clients) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, hub.PublishToUser(&Message{Type: "notification", UserID: "alice", Data: "hello"}))
+
+	_ = alice.SetReadDeadline(time.Now().Add(time.Second))
+	_, payload, err := alice.ReadMessage()
+	require.NoError(t, err)
+	var msg Message
+	require.NoError(t, json.Unmarshal(payload, &msg))
+	assert.Equal(t, "notification", msg.Type)
+	assert.Equal(t, "hello", msg.Data)
+	assert.NotZero(t, msg.Timestamp)
+
+	_ = bob.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, err = bob.ReadMessage()
+	assert.Error(t, err, "bob must not receive alice's message")
+}
+
+// readMessage reads the next message of conn within a second
+func readMessage(t *testing.T, conn *gorilla.Conn) Message {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, payload, err := conn.ReadMessage()
+	require.NoError(t, err)
+	var msg Message
+	require.NoError(t, json.Unmarshal(payload, &msg))
+	return msg
+}
+
+func TestPublishToChannelReachesSubscribersOfTheTenant(t *testing.T) {
+	hub := NewHub(nil, logger.New("test"))
+	hub.Authorize(func(c *Client, channel string) bool { return channel != "private" })
+	go hub.Run()
+	addr := serve(t, hub)
+
+	acme := dialTenant(t, addr, "alice", "acme")
+	globex := dialTenant(t, addr, "bob", "globex")
+	for _, conn := range []*gorilla.Conn{acme, globex} {
+		require.NoError(t, conn.WriteJSON(Message{Type: "subscribe", Channel: "store:1:orders"}))
+		reply := readMessage(t, conn)
+		assert.Equal(t, MessageSubscribed, reply.Type)
+		assert.Equal(t, "store:1:orders", reply.Channel)
+	}
+
+	require.NoError(t, acme.WriteJSON(Message{Type: "subscribe", Channel: "private"}))
+	assert.Equal(t, MessageError, readMessage(t, acme).Type)
+
+	require.NoError(t, hub.PublishToChannel(&Message{Type: "order", TenantID: "acme", Channel: "store:1:orders", Data: "new"}))
+	msg := readMessage(t, acme)
+	assert.Equal(t, "order", msg.Type)
+	assert.Equal(t, "new", msg.Data)
+
+	_ = globex.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, err := globex.ReadMessage()
+	assert.Error(t, err, "another tenant's subscribers must not receive the message")
+}
+
+func TestHandleWebSocketRequiresUser(t *testing.T) {
+	hub := NewHub(nil, logger.New("test"))
+	go hub.Run()
+	addr := serve(t, hub)
+
+	_, resp, err := gorilla.DefaultDialer.Dial("ws://"+addr+"/ws", nil)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,310 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: proto/catalog/catalog.proto
+
+package catalog
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ResolvePricesRequest is the request to resolve prices
+type ResolvePricesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StoreId       string                 `protobuf:"bytes,1,opt,name=store_id,json=storeId,proto3" json:"store_id,omitempty"`
+	VariantIds    []string               `protobuf:"bytes,2,rep,name=variant_ids,json=variantIds,proto3" json:"variant_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolvePricesRequest) Reset() {
+	*x = ResolvePricesRequest{}
+	mi := &file_proto_catalog_catalog_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolvePricesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolvePricesRequest) ProtoMessage() {}
+
+func (x *ResolvePricesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_catalog_catalog_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolvePricesRequest.ProtoReflect.Descriptor instead.
+func (*ResolvePricesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_catalog_catalog_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ResolvePricesRequest) GetStoreId() string {
+	if x != nil {
+		return x.StoreId
+	}
+	return ""
+}
+
+func (x *ResolvePricesRequest) GetVariantIds() []string {
+	if x != nil {
+		return x.VariantIds
+	}
+	return nil
+}
+
+// ResolvePricesResponse is the response from resolving prices
+type ResolvePricesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Variants      []*PricedVariant       `protobuf:"bytes,1,rep,name=variants,proto3" json:"variants,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolvePricesResponse) Reset() {
+	*x = ResolvePricesResponse{}
+	mi := &file_proto_catalog_catalog_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolvePricesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolvePricesResponse) ProtoMessage() {}
+
+func (x *ResolvePricesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_catalog_catalog_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolvePricesResponse.ProtoReflect.Descriptor instead.
+func (*ResolvePricesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_catalog_catalog_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ResolvePricesResponse) GetVariants() []*PricedVariant {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+// PricedVariant is a variant with the price a store charges for it
+type PricedVariant struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VariantId     string                 `protobuf:"bytes,1,opt,name=variant_id,json=variantId,proto3" json:"variant_id,omitempty"`
+	ProductId     string                 `protobuf:"bytes,2,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	CategoryId    string                 `protobuf:"bytes,3,opt,name=category_id,json=categoryId,proto3" json:"category_id,omitempty"` // Empty when the product has no category
+	Sku           string                 `protobuf:"bytes,4,opt,name=sku,proto3" json:"sku,omitempty"`
+	Name          string                 `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"` // Product name, with the variant name when it has one
+	Status        string                 `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	Price         float64                `protobuf:"fixed64,7,opt,name=price,proto3" json:"price,omitempty"`
+	Currency      string                 `protobuf:"bytes,8,opt,name=currency,proto3" json:"currency,omitempty"`
+	StorePrice    bool                   `protobuf:"varint,9,opt,name=store_price,json=storePrice,proto3" json:"store_price,omitempty"` // Whether the price comes from the store's price list
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PricedVariant) Reset() {
+	*x = PricedVariant{}
+	mi := &file_proto_catalog_catalog_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PricedVariant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PricedVariant) ProtoMessage() {}
+
+func (x *PricedVariant) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_catalog_catalog_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PricedVariant.ProtoReflect.Descriptor instead.
+func (*PricedVariant) Descriptor() ([]byte, []int) {
+	return file_proto_catalog_catalog_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PricedVariant) GetVariantId() string {
+	if x != nil {
+		return x.VariantId
+	}
+	return ""
+}
+
+func (x *PricedVariant) GetProductId() string {
+	if x != nil {
+		return x.ProductId
+	}
+	return ""
+}
+
+func (x *PricedVariant) GetCategoryId() string {
+	if x != nil {
+		return x.CategoryId
+	}
+	return ""
+}
+
+func (x *PricedVariant) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+func (x *PricedVariant) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PricedVariant) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *PricedVariant) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *PricedVariant) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *PricedVariant) GetStorePrice() bool {
+	if x != nil {
+		return x.StorePrice
+	}
+	return false
+}
+
+var File_proto_catalog_catalog_proto protoreflect.FileDescriptor
+
+const file_proto_catalog_catalog_proto_rawDesc = "" +
+	"\n" +
+	"\x1bproto/catalog/catalog.proto\x12\acatalog\"R\n" +
+	"\x14ResolvePricesRequest\x12\x19\n" +
+	"\bstore_id\x18\x01 \x01(\tR\astoreId\x12\x1f\n" +
+	"\vvariant_ids\x18\x02 \x03(\tR\n" +
+	"variantIds\"K\n" +
+	"\x15ResolvePricesResponse\x122\n" +
+	"\bvariants\x18\x01 \x03(\v2\x16.catalog.PricedVariantR\bvariants\"\xff\x01\n" +
+	"\rPricedVariant\x12\x1d\n" +
+	"\n" +
+	"variant_id\x18\x01 \x01(\tR\tvariantId\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x02 \x01(\tR\tproductId\x12\x1f\n" +
+	"\vcategory_id\x18\x03 \x01(\tR\n" +
+	"categoryId\x12\x10\n" +
+	"\x03sku\x18\x04 \x01(\tR\x03sku\x12\x12\n" +
+	"\x04name\x18\x05 \x01(\tR\x04name\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\x12\x14\n" +
+	"\x05price\x18\a \x01(\x01R\x05price\x12\x1a\n" +
+	"\bcurrency\x18\b \x01(\tR\bcurrency\x12\x1f\n" +
+	"\vstore_price\x18\t \x01(\bR\n" +
+	"storePrice2`\n" +
+	"\x0ePricingService\x12N\n" +
+	"\rResolvePrices\x12\x1d.catalog.ResolvePricesRequest\x1a\x1e.catalog.ResolvePricesResponseB/Z-github.com/onichange/pos-system/proto/catalogb\x06proto3"
+
+var (
+	file_proto_catalog_catalog_proto_rawDescOnce sync.Once
+	file_proto_catalog_catalog_proto_rawDescData []byte
+)
+
+func file_proto_catalog_catalog_proto_rawDescGZIP() []byte {
+	file_proto_catalog_catalog_proto_rawDescOnce.Do(func() {
+		file_proto_catalog_catalog_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_catalog_catalog_proto_rawDesc), len(file_proto_catalog_catalog_proto_rawDesc)))
+	})
+	return file_proto_catalog_catalog_proto_rawDescData
+}
+
+var file_proto_catalog_catalog_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_proto_catalog_catalog_proto_goTypes = []any{
+	(*ResolvePricesRequest)(nil),  // 0: catalog.ResolvePricesRequest
+	(*ResolvePricesResponse)(nil), // 1: catalog.ResolvePricesResponse
+	(*PricedVariant)(nil),         // 2: catalog.PricedVariant
+}
+var file_proto_catalog_catalog_proto_depIdxs = []int32{
+	2, // 0: catalog.ResolvePricesResponse.variants:type_name -> catalog.PricedVariant
+	0, // 1: catalog.PricingService.ResolvePrices:input_type -> catalog.ResolvePricesRequest
+	1, // 2: catalog.PricingService.ResolvePrices:output_type -> catalog.ResolvePricesResponse
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_proto_catalog_catalog_proto_init() }
+func file_proto_catalog_catalog_proto_init() {
+	if File_proto_catalog_catalog_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_catalog_catalog_proto_rawDesc), len(file_proto_catalog_catalog_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_catalog_catalog_proto_goTypes,
+		DependencyIndexes: file_proto_catalog_catalog_proto_depIdxs,
+		MessageInfos:      file_proto_catalog_catalog_proto_msgTypes,
+	}.Build()
+	File_proto_catalog_catalog_proto = out.File
+	file_proto_catalog_catalog_proto_goTypes = nil
+	file_proto_catalog_catalog_proto_depIdxs = nil
+}
@@ -0,0 +1,181 @@
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/pkg/apiclient"
+	"github.com/onichange/pos-system/pkg/apiclient/analytics"
+	"github.com/onichange/pos-system/pkg/apiclient/authentication"
+	"github.com/onichange/pos-system/pkg/apiclient/catalog"
+	"github.com/onichange/pos-system/pkg/apiclient/codegen"
+	"github.com/onichange/pos-system/pkg/apiclient/health"
+	"github.com/onichange/pos-system/pkg/apiclient/inventory"
+	"github.com/onichange/pos-system/pkg/apiclient/loyalty"
+	"github.com/onichange/pos-system/pkg/apiclient/orders"
+	"github.com/onichange/pos-system/pkg/apiclient/payments"
+	"github.com/onichange/pos-system/pkg/apiclient/procurement"
+	"github.com/onichange/pos-system/pkg/apiclient/promotions"
+	"github.com/onichange/pos-system/pkg/apiclient/receipts"
+	"github.com/onichange/pos-system/pkg/apiclient/search"
+	"github.com/onichange/pos-system/pkg/apiclient/shifts"
+	"github.com/onichange/pos-system/pkg/apiclient/stores"
+	syncapi "github.com/onichange/pos-system/pkg/apiclient/sync"
+	"github.com/onichange/pos-system/pkg/apiclient/tax"
+	"github.com/onichange/pos-system/pkg/apiclient/users"
+	"github.com/onichange/pos-system/pkg/apiclient/webhooks"
+)
+
+// recorded is a request a generated client sent
+type recorded struct {
+	method string
+	path   string
+	query  map[string][]string
+	body   []byte
+}
+
+// TestConsumerContracts calls every method of the generated clients and
+// checks that each sends the request its operation in the spec describes
+func TestConsumerContracts(t *testing.T) {
+	spec, err := codegen.Load(specPath)
+	require.NoError(t, err)
+
+	var last recorded
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		last = recorded{method: r.Method, path: strings.TrimPrefix(r.URL.Path, "/api/v1"), query: r.URL.Query(), body: body}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	api := apiclient.New(server.URL+"/api/v1", apiclient.WithHTTPClient(server.Client()))
+	clients := []any{
+		analytics.New(api), authentication.New(api), catalog.New(api), health.New(api),
+		inventory.New(api), loyalty.New(api), orders.New(api), payments.New(api),
+		procurement.New(api), promotions.New(api), receipts.New(api), search.New(api),
+		shifts.New(api), stores.New(api), syncapi.New(api), tax.New(api), users.New(api),
+		webhooks.New(api),
+	}
+
+	called := make(map[string]bool)
+	for _, client := range clients {
+		value := reflect.ValueOf(client)
+		for i := 0; i < value.NumMethod(); i++ {
+			name := value.Type().Method(i).Name
+			method := value.Method(i)
+			t.Run(name, func(t *testing.T) {
+				op, path := operationOf(t, spec, name)
+				called[op.Operation.OperationID] = true
+
+				last = recorded{}
+				results := method.Call(zeroArgs(method.Type()))
+				if err, _ := results[len(results)-1].Interface().(error); err != nil {
+					t.Fatalf("%s failed: %v", name, err)
+				}
+
+				assert.Equal(t, op.Method, last.method)
+				assert.Regexp(t, pathPattern(path), last.path)
+				for param := range last.query {
+					assert.True(t, declaresQuery(spec, path, op.Operation, param), "%s sends undeclared query parameter %s", name, param)
+				}
+				checkBody(t, spec, op.Operation, last.body)
+			})
+		}
+	}
+
+	// Every operation but WebSocket upgrades has a method
+	for _, p := range spec.Paths.Keys {
+		for _, op := range spec.Paths.Values[p].Operations() {
+			if _, ok := op.Operation.Responses.Values["101"]; ok {
+				continue
+			}
+			assert.True(t, called[op.Operation.OperationID], "no client method sends %s", op.Operation.OperationID)
+		}
+	}
+}
+
+// operationOf returns the operation a generated method sends, and its path
+func operationOf(t *testing.T, spec *codegen.Spec, method string) (codegen.MethodOperation, string) {
+	t.Helper()
+	for _, p := range spec.Paths.Keys {
+		for _, op := range spec.Paths.Values[p].Operations() {
+			if codegen.Camel(op.Operation.OperationID) == method {
+				return op, p
+			}
+		}
+	}
+	t.Fatalf("no operation for method %s", method)
+	return codegen.MethodOperation{}, ""
+}
+
+// zeroArgs returns a context and zero values for the other arguments of a
+// method, with structs given as pointers to their zero value
+func zeroArgs(typ reflect.Type) []reflect.Value {
+	args := []reflect.Value{reflect.ValueOf(context.Background())}
+	for i := 1; i < typ.NumIn(); i++ {
+		in := typ.In(i)
+		if in.Kind() == reflect.Ptr && in.Elem().Kind() == reflect.Struct {
+			args = append(args, reflect.New(in.Elem()))
+			continue
+		}
+		args = append(args, reflect.Zero(in))
+	}
+	return args
+}
+
+// pathPattern matches the paths of a path template
+func pathPattern(template string) string {
+	pattern := regexp.MustCompile(`\\\{[^}]+\\\}`).ReplaceAllString(regexp.QuoteMeta(template), `[^/]*`)
+	return "^" + pattern + "$"
+}
+
+// declaresQuery reports whether an operation, or its path, declares a query
+// parameter
+func declaresQuery(spec *codegen.Spec, path string, op *codegen.Operation, name string) bool {
+	for _, param := range append(append([]*codegen.Parameter(nil), spec.Paths.Values[path].Parameters...), op.Parameters...) {
+		if param.In == "query" && param.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBody checks that a JSON request body has the properties the
+// operation's body schema requires, and no others
+func checkBody(t *testing.T, spec *codegen.Spec, op *codegen.Operation, body []byte) {
+	t.Helper()
+	if op.RequestBody == nil {
+		assert.Empty(t, body, "sends a body the operation does not take")
+		return
+	}
+	media, ok := op.RequestBody.Content.Values["application/json"]
+	if !ok {
+		return // Sent as is, such as an uploaded file
+	}
+	if len(body) == 0 {
+		assert.False(t, op.RequestBody.Required, "sends no body where one is required")
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &fields))
+	schema := spec.Resolve(media.Schema)
+	documented := spec.Properties(schema)
+	for field := range fields {
+		assert.Contains(t, documented, field, "sends undocumented property %s", field)
+	}
+	for _, required := range schema.Required {
+		assert.Contains(t, fields, required, "leaves out required property %s", required)
+	}
+}
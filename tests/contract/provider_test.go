@@ -0,0 +1,339 @@
+package contract
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/analytics"
+	"github.com/onichange/pos-system/internal/domain/catalog"
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/domain/loyalty"
+	"github.com/onichange/pos-system/internal/domain/offline"
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/procurement"
+	"github.com/onichange/pos-system/internal/domain/promotion"
+	"github.com/onichange/pos-system/internal/domain/receipt"
+	"github.com/onichange/pos-system/internal/domain/search"
+	"github.com/onichange/pos-system/internal/domain/shift"
+	"github.com/onichange/pos-system/internal/domain/store"
+	"github.com/onichange/pos-system/internal/domain/tax"
+	"github.com/onichange/pos-system/internal/domain/webhook"
+	cataloghttp "github.com/onichange/pos-system/internal/interfaces/http/catalog"
+	inventoryhttp "github.com/onichange/pos-system/internal/interfaces/http/inventory"
+	loyaltyhttp "github.com/onichange/pos-system/internal/interfaces/http/loyalty"
+	offlinehttp "github.com/onichange/pos-system/internal/interfaces/http/offline"
+	orderhttp "github.com/onichange/pos-system/internal/interfaces/http/order"
+	paymenthttp "github.com/onichange/pos-system/internal/interfaces/http/payment"
+	procurementhttp "github.com/onichange/pos-system/internal/interfaces/http/procurement"
+	promotionhttp "github.com/onichange/pos-system/internal/interfaces/http/promotion"
+	receipthttp "github.com/onichange/pos-system/internal/interfaces/http/receipt"
+	shifthttp "github.com/onichange/pos-system/internal/interfaces/http/shift"
+	storehttp "github.com/onichange/pos-system/internal/interfaces/http/store"
+	taxhttp "github.com/onichange/pos-system/internal/interfaces/http/tax"
+	userhttp "github.com/onichange/pos-system/internal/interfaces/http/user"
+	webhookhttp "github.com/onichange/pos-system/internal/interfaces/http/webhook"
+	"github.com/onichange/pos-system/pkg/apiclient/codegen"
+	"github.com/onichange/pos-system/pkg/auth"
+)
+
+// specPath is the OpenAPI document the gateway serves and the clients are
+// generated from
+const specPath = "../../pkg/api/openapi.yaml"
+
+// providerContracts pair each schema of the spec with the type its service
+// decodes or encodes it as
+var providerContracts = []struct {
+	// A component, or operationId:request or operationId:status, followed
+	// by .property to select a property and [] to select an array's items
+	schema string
+	value  any
+}{
+	{"User", userhttp.UserResponse{}},
+	{"login:request", userhttp.LoginRequest{}},
+	{"login:200", userhttp.LoginResponse{}},
+	{"refreshToken:request", userhttp.RefreshTokenRequest{}},
+	{"refreshToken:200", auth.TokenPair{}},
+	{"logout:request", userhttp.RefreshTokenRequest{}},
+	{"changePassword:request", userhttp.ChangePasswordRequest{}},
+	{"updateCurrentUser:request", userhttp.UpdateUserRequest{}},
+
+	{"Order", orderhttp.OrderResponse{}},
+	{"CreateOrderRequest", orderhttp.CreateOrderRequest{}},
+	{"updateOrder:request", orderhttp.UpdateOrderRequest{}},
+	{"updateOrderStatus:request", orderhttp.UpdateOrderStatusRequest{}},
+	{"transitionOrderStatus:request", orderhttp.UpdateOrderStatusRequest{}},
+	{"OrderStatusHistory", orderhttp.StatusHistoryResponse{}},
+	{"checkoutOrder:request", orderhttp.CheckoutRequest{}},
+	{"Checkout", orderhttp.CheckoutResponse{}},
+	{"OrderStatusTransition", orderhttp.StatusTransitionResponse{}},
+	{"OrderHistory", orderhttp.OrderHistoryResponse{}},
+	{"OrderChange", orderhttp.OrderChangeResponse{}},
+	{"OrderItem", order.OrderItem{}},
+	{"Order.promotions[]", order.AppliedPromotion{}},
+	{"Address", order.Address{}},
+
+	{"Store", storehttp.StoreResponse{}},
+	{"CreateStoreRequest", storehttp.CreateStoreRequest{}},
+	{"Device", storehttp.DeviceResponse{}},
+	{"DevicePairingCode", storehttp.PairingCodeResponse{}},
+	{"RegisterDeviceRequest", storehttp.RegisterDeviceRequest{}},
+	{"deactivateDevice:request", storehttp.DeactivateDeviceRequest{}},
+	{"PairDeviceRequest", storehttp.PairDeviceRequest{}},
+	{"DevicePairing", storehttp.PairDeviceResponse{}},
+	{"sendDeviceHeartbeat:request", storehttp.HeartbeatRequest{}},
+	{"StoreExport", store.Export{}},
+
+	{"Payment", paymenthttp.PaymentResponse{}},
+	{"ProcessPaymentRequest", paymenthttp.ProcessPaymentRequest{}},
+
+	{"Inventory", inventoryhttp.InventoryResponse{}},
+	{"updateInventory:request", inventoryhttp.UpdateInventoryRequest{}},
+	{"InventoryValuation", inventory.Valuation{}},
+	{"AdjustInventoryRequest", inventoryhttp.AdjustStockRequest{}},
+	{"InventoryAdjustment", inventoryhttp.AdjustStockResponse{}},
+	{"StockMovement", inventory.StockMovement{}},
+	{"CreateStockTransferRequest", inventoryhttp.CreateTransferRequest{}},
+	{"CreateStockTransferRequest.items[]", inventoryhttp.TransferItemRequest{}},
+	{"StockTransfer", inventory.Transfer{}},
+	{"StockTransfer.items[]", inventory.TransferItem{}},
+	{"CreateStockCountRequest", inventoryhttp.CreateCountRequest{}},
+	{"RecordStockCountsRequest", inventoryhttp.RecordCountsRequest{}},
+	{"RecordStockCountsRequest.items[]", inventoryhttp.CountItemRequest{}},
+	{"StockCount", inventory.Count{}},
+	{"StockCountLine", inventory.CountLine{}},
+	{"StockCountVarianceReport", inventoryhttp.VarianceReport{}},
+	{"StockCountVarianceReport.summary", inventoryhttp.VarianceSummary{}},
+	{"StockCountReconciliation", inventoryhttp.CountReconciliationResponse{}},
+	{"StockCountReconciliation.adjustments[]", inventoryhttp.CountAdjustmentResponse{}},
+	{"StockImportReport", inventoryhttp.ImportReport{}},
+	{"StockImportReport.errors[]", inventoryhttp.ImportRowError{}},
+
+	{"Category", catalog.Category{}},
+	{"createCategory:request", cataloghttp.CreateCategoryRequest{}},
+	{"Product", catalog.Product{}},
+	{"Variant", catalog.Variant{}},
+	{"CreateProductRequest", cataloghttp.CreateProductRequest{}},
+	{"CreateProductRequest.variants[]", cataloghttp.VariantRequest{}},
+	{"Image", catalog.Image{}},
+	{"Price", catalog.Price{}},
+
+	{"LoyaltyProgram", loyaltyhttp.ProgramResponse{}},
+	{"LoyaltyProgram.tiers[]", loyalty.Tier{}},
+	{"LoyaltyAccount", loyalty.Account{}},
+	{"LoyaltyTransaction", loyalty.Transaction{}},
+
+	{"PromotionRule", promotion.Rule{}},
+	{"PromotionRequest", promotionhttp.CreatePromotionRequest{}},
+	{"Promotion", promotion.Promotion{}},
+	{"CouponRequest", promotionhttp.CreateCouponRequest{}},
+	{"Coupon", promotion.Coupon{}},
+	{"updateCoupon:request", promotionhttp.UpdateCouponRequest{}},
+	{"validateCoupon:request", promotionhttp.ValidateCouponRequest{}},
+	{"CouponValidation", promotionhttp.CouponValidation{}},
+
+	{"SalesPoint", analytics.SalesPoint{}},
+	{"ProductSales", analytics.ProductSales{}},
+	{"Conversion", analytics.Conversion{}},
+	{"StockActivity", analytics.StockActivity{}},
+	{"Timesheet", analytics.Timesheet{}},
+	{"TimesheetDay", analytics.TimesheetDay{}},
+
+	{"OrderSearchHit", search.OrderDocument{}},
+	{"ProductSearchHit", search.ProductDocument{}},
+	{"SearchBucket", search.Bucket{}},
+
+	{"SyncChange", offline.Change{}},
+	{"SyncChangePage", offline.Page{}},
+	{"SyncPushRequest", offlinehttp.PushRequest{}},
+	{"OfflineTransaction", offlinehttp.Transaction{}},
+	{"SyncTransactionResult", offlinehttp.TransactionResult{}},
+	{"SyncConflict", order.Conflict{}},
+
+	{"ReceiptTemplate", receipt.Template{}},
+	{"UpdateReceiptTemplateRequest", receipthttp.UpdateTemplateRequest{}},
+	{"PrintReceiptRequest", receipthttp.PrintReceiptRequest{}},
+	{"PrintJob", receipt.PrintJob{}},
+	{"openCashDrawer:request", receipthttp.OpenDrawerRequest{}},
+	{"ackPrintJob:request", receipthttp.AckRequest{}},
+
+	{"OpenShiftRequest", shifthttp.OpenShiftRequest{}},
+	{"Shift", shift.Shift{}},
+	{"closeShift:request", shifthttp.CloseShiftRequest{}},
+	{"closeShift:200", shifthttp.CloseShiftResponse{}},
+	{"recordCashMovement:request", shifthttp.CashMovementRequest{}},
+	{"CashMovement", shift.CashMovement{}},
+	{"ShiftReport", shift.Report{}},
+	{"ShiftDayReport", shift.DayReport{}},
+	{"ShiftDayReport.totals[]", shift.DayTotal{}},
+	{"ShiftDayReport.cashiers[]", shift.CashierTotal{}},
+	{"clockIn:request", shifthttp.ClockInRequest{}},
+	{"clockOut:request", shifthttp.ClockOutRequest{}},
+	{"startBreak:request", shifthttp.StartBreakRequest{}},
+	{"adjustTimeEntry:request", shifthttp.AdjustTimeEntryRequest{}},
+	{"TimeEntry", shift.TimeEntry{}},
+	{"TimeBreak", shift.Break{}},
+	{"TimeAdjustment", shift.TimeAdjustment{}},
+
+	{"TaxJurisdictionRequest", taxhttp.CreateJurisdictionRequest{}},
+	{"TaxJurisdiction", tax.Jurisdiction{}},
+	{"TaxCategory", tax.Category{}},
+	{"TaxAssignment", tax.Assignment{}},
+	{"TaxHoliday", tax.Holiday{}},
+	{"TaxStoreRate", tax.StoreRate{}},
+	{"saveTaxStoreRate:request", taxhttp.SaveStoreRateRequest{}},
+	{"TaxQuoteRequest", taxhttp.QuoteRequest{}},
+	{"JurisdictionTax", tax.JurisdictionTax{}},
+	{"TaxQuote", tax.Quote{}},
+	{"TaxReport", tax.Report{}},
+
+	{"SupplierRequest", procurementhttp.CreateSupplierRequest{}},
+	{"Supplier", procurement.Supplier{}},
+	{"SupplierPerformance", procurement.Performance{}},
+	{"SupplierProductRequest", procurementhttp.SetSupplierProductRequest{}},
+	{"SupplierProduct", procurement.SupplierProduct{}},
+	{"PurchaseOrderRequest", procurementhttp.CreateOrderRequest{}},
+	{"PurchaseOrder", procurement.PurchaseOrder{}},
+	{"submitPurchaseOrder:request", procurementhttp.SubmitOrderRequest{}},
+	{"GoodsReceiptRequest", procurementhttp.CreateReceiptRequest{}},
+	{"GoodsReceipt", procurement.GoodsReceipt{}},
+
+	{"WebhookSubscriptionRequest", webhookhttp.CreateSubscriptionRequest{}},
+	{"WebhookSubscription", webhook.Subscription{}},
+	{"createWebhookSubscription:201", webhookhttp.SubscriptionWithSecret{}},
+	{"replayWebhookSubscription:request", webhookhttp.ReplayRequest{}},
+	{"WebhookDelivery", webhook.Delivery{}},
+	{"getWebhookDelivery:200", webhookhttp.DeliveryWithAttempts{}},
+	{"WebhookAttempt", webhook.Attempt{}},
+}
+
+// TestProviderContracts checks that the services read and write the fields
+// the spec documents, and no others, so that the generated clients match
+// what is on the wire
+func TestProviderContracts(t *testing.T) {
+	spec, err := codegen.Load(specPath)
+	require.NoError(t, err)
+
+	for _, contract := range providerContracts {
+		t.Run(contract.schema, func(t *testing.T) {
+			documented := spec.Properties(lookup(t, spec, contract.schema))
+			fields := jsonFields(reflect.TypeOf(contract.value))
+			assert.Empty(t, missing(documented, fields), "%s documents properties %T lacks", contract.schema, contract.value)
+			assert.Empty(t, missing(fields, documented), "%T has fields %s does not document", contract.value, contract.schema)
+		})
+	}
+}
+
+// TestProviderContractsCoverComponents keeps providerContracts in step with
+// the spec's components
+func TestProviderContractsCoverComponents(t *testing.T) {
+	spec, err := codegen.Load(specPath)
+	require.NoError(t, err)
+
+	covered := make(map[string]bool)
+	for _, contract := range providerContracts {
+		covered[contract.schema] = true
+	}
+	for _, name := range spec.Components.Schemas.Keys {
+		assert.True(t, covered[name], "schema %s has no provider contract", name)
+	}
+}
+
+// lookup finds a schema by the path of a provider contract
+func lookup(t *testing.T, spec *codegen.Spec, name string) *codegen.Schema {
+	t.Helper()
+
+	steps := strings.Split(name, ".")
+	schema := lookupRoot(t, spec, strings.TrimSuffix(steps[0], "[]"))
+	for i, step := range steps {
+		if i > 0 {
+			schema = spec.Resolve(schema)
+			property := strings.TrimSuffix(step, "[]")
+			require.Contains(t, schema.Properties.Values, property, "no property %s in %s", property, name)
+			schema = schema.Properties.Values[property]
+		}
+		if strings.HasSuffix(step, "[]") {
+			schema = spec.Resolve(schema)
+			require.NotNil(t, schema.Items, "%s is not an array in %s", step, name)
+			schema = schema.Items
+		}
+	}
+	return schema
+}
+
+// lookupRoot finds a schema by component name, or by operationId and
+// "request" or a response status
+func lookupRoot(t *testing.T, spec *codegen.Spec, name string) *codegen.Schema {
+	t.Helper()
+
+	opID, part, ok := strings.Cut(name, ":")
+	if !ok {
+		schema := spec.Schema(name)
+		require.NotNil(t, schema, "no schema %s", name)
+		return schema
+	}
+
+	for _, p := range spec.Paths.Keys {
+		for _, op := range spec.Paths.Values[p].Operations() {
+			if op.Operation.OperationID != opID {
+				continue
+			}
+			if part == "request" {
+				require.NotNil(t, op.Operation.RequestBody, "%s has no request body", opID)
+				return op.Operation.RequestBody.Content.Values["application/json"].Schema
+			}
+			response := op.Operation.Responses.Values[part]
+			require.NotNil(t, response, "%s has no %s response", opID, part)
+			return response.Content.Values["application/json"].Schema
+		}
+	}
+	t.Fatalf("no operation %s", opID)
+	return nil
+}
+
+// jsonFields returns the names encoding/json gives the fields of a struct,
+// including those of embedded structs
+func jsonFields(typ reflect.Type) []string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			names = append(names, jsonFields(field.Type)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// missing returns the names of want not in have
+func missing(want, have []string) []string {
+	present := make(map[string]bool, len(have))
+	for _, name := range have {
+		present[name] = true
+	}
+	var result []string
+	for _, name := range want {
+		if !present[name] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+package e2e
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/apiclient"
+	inventoryclient "github.com/onichange/pos-system/pkg/apiclient/inventory"
+	"github.com/onichange/pos-system/pkg/apiclient/orders"
+)
+
+func TestAccountingExport(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := context.Background()
+
+	orderService := env.StartOrder(t)
+	inventoryService := env.StartInventory(t)
+	admin := orders.New(apiclient.New(orderService.URL+"/api/v1",
+		apiclient.WithToken(env.Token(t, uuid.New(), "admin"))))
+
+	storeID := uuid.New()
+	var created []uuid.UUID
+	for i := 0; i < 3; i++ {
+		o, err := admin.CreateOrder(ctx, &apiclient.CreateOrderRequest{
+			StoreID: storeID,
+			Items:   []apiclient.CreateOrderRequestItem{{ProductID: uuid.New(), Quantity: 1}},
+		})
+		require.NoError(t, err)
+		created = append(created, o.ID)
+	}
+
+	readCSV := func(body []byte) [][]string {
+		t.Helper()
+		records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+		require.NoError(t, err)
+		return records
+	}
+
+	// The store's orders of the period, oldest first, in the columns asked for
+	today := time.Now().UTC()
+	from, to := today.AddDate(0, 0, -1).Format("2006-01-02"), today.AddDate(0, 0, 1).Format("2006-01-02")
+	columns := "id,status,store_id"
+	body, err := admin.ExportOrders(ctx, &orders.ExportOrdersParams{From: from, To: to, StoreID: &storeID, Columns: &columns})
+	require.NoError(t, err)
+	records := readCSV(body)
+	require.Equal(t, []string{"id", "status", "store_id"}, records[0])
+	require.Len(t, records, 4)
+	for i, id := range created {
+		require.Equal(t, []string{id.String(), "pending", storeID.String()}, records[i+1])
+	}
+
+	// Gzipped for Excel
+	format, gzipped := "excel", true
+	body, err = admin.ExportOrders(ctx, &orders.ExportOrdersParams{From: from, To: to, StoreID: &storeID, Format: &format, Gzip: &gzipped})
+	require.NoError(t, err)
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	require.NoError(t, err)
+	unzipped, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(unzipped, []byte("\ufeffid,created_at")))
+	require.Len(t, readCSV(bytes.TrimPrefix(unzipped, []byte("\ufeff"))), 4)
+
+	// A period before the orders has none of them
+	body, err = admin.ExportOrders(ctx, &orders.ExportOrdersParams{From: "2020-01-01", To: "2020-01-31", StoreID: &storeID})
+	require.NoError(t, err)
+	require.Len(t, readCSV(body), 1)
+
+	unknown := "id,card_number"
+	_, err = admin.ExportOrders(ctx, &orders.ExportOrdersParams{From: from, To: to, Columns: &unknown})
+	var apiErr *apiclient.Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusBadRequest, apiErr.Status)
+
+	// Exports are for admins
+	customer := orders.New(apiclient.New(orderService.URL+"/api/v1",
+		apiclient.WithToken(env.Token(t, uuid.New()))))
+	_, err = customer.ExportOrders(ctx, &orders.ExportOrdersParams{From: from, To: to})
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusForbidden, apiErr.Status)
+
+	// Stock is valued at cost
+	productID := uuid.New()
+	_, err = env.DB.Exec(ctx, `
+		INSERT INTO inventory (product_id, store_id, quantity, reserved_quantity, cost_price, tenant_id)
+		VALUES ($1, $2, 4, 1, 2.50, 'default')
+	`, productID, storeID)
+	require.NoError(t, err)
+
+	stock := inventoryclient.New(apiclient.New(inventoryService.URL + "/api/v1"))
+	columns = "product_id,available_quantity,stock_value"
+	body, err = stock.ExportInventory(ctx, &inventoryclient.ExportInventoryParams{StoreID: storeID, Columns: &columns})
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"product_id", "available_quantity", "stock_value"},
+		{productID.String(), "3", "10.00"},
+	}, readCSV(body))
+}
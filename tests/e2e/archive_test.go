@@ -0,0 +1,83 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/archive"
+	"github.com/onichange/pos-system/pkg/config"
+)
+
+func TestArchiveAndRestore(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := context.Background()
+
+	// Two delivered orders, one last changed two years ago with an audit
+	// entry, and a pending order as old
+	oldID, recentID, pendingID := uuid.New(), uuid.New(), uuid.New()
+	old := time.Date(2023, time.March, 14, 9, 30, 0, 0, time.UTC)
+	for _, o := range []struct {
+		id        uuid.UUID
+		status    string
+		updatedAt time.Time
+	}{
+		{oldID, "delivered", old},
+		{recentID, "delivered", time.Now().UTC()},
+		{pendingID, "pending", old},
+	} {
+		_, err := env.DB.Exec(ctx, `
+			INSERT INTO orders (id, user_id, store_id, status, total_amount, items, tenant_id, created_at, updated_at)
+			VALUES ($1, $2, $2, $3, 9.50, '[]', 'acme', $4, $4)
+		`, o.id, uuid.New(), o.status, o.updatedAt)
+		require.NoError(t, err)
+	}
+	_, err := env.DB.Exec(ctx,
+		`INSERT INTO order_audit_log (order_id, action, new_status) VALUES ($1, 'status_changed', 'delivered')`, oldID)
+	require.NoError(t, err)
+
+	cfg := config.ArchiveConfig{
+		BatchSize: 1,
+		Tables:    map[string]config.ArchivePolicy{"orders": {Retention: 365 * 24 * time.Hour}},
+	}
+	archiver := archive.NewArchiver(env.DB, cfg, repository.NewOrderRepository(env.DB, nil).ArchiveTables()...)
+
+	// Only the old delivered order moves, with its audit log
+	moved, err := archiver.Archive(ctx, "orders")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, moved)
+
+	count := func(query string, args ...interface{}) int {
+		var n int
+		require.NoError(t, env.DB.QueryRow(ctx, query, args...).Scan(&n))
+		return n
+	}
+	require.Equal(t, 0, count(`SELECT COUNT(*) FROM orders WHERE id = $1`, oldID))
+	require.Equal(t, 2, count(`SELECT COUNT(*) FROM orders WHERE id = ANY($1)`, []uuid.UUID{recentID, pendingID}))
+	require.Equal(t, 1, count(`SELECT COUNT(*) FROM archive.orders_p202303 WHERE id = $1`, oldID))
+	require.Equal(t, 1, count(`SELECT COUNT(*) FROM archive.order_audit_log WHERE order_id = $1`, oldID))
+
+	months, err := archiver.Status(ctx, "orders")
+	require.NoError(t, err)
+	require.Len(t, months, 1)
+	require.Equal(t, time.March, months[0].Month.Month())
+	require.EqualValues(t, 1, months[0].Rows)
+
+	// Another tenant's restore leaves the order archived
+	from, to := time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)
+	restored, err := archiver.Restore(ctx, "orders", from, to, "globex")
+	require.NoError(t, err)
+	require.Zero(t, restored)
+
+	restored, err = archiver.Restore(ctx, "orders", from, to, "acme")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, restored)
+	require.Equal(t, 1, count(`SELECT COUNT(*) FROM orders WHERE id = $1 AND status = 'delivered'`, oldID))
+	require.Equal(t, 1, count(`SELECT COUNT(*) FROM order_audit_log WHERE order_id = $1`, oldID))
+	require.Equal(t, 0, count(`SELECT COUNT(*) FROM archive.orders`))
+}
@@ -0,0 +1,66 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	orderhttp "github.com/onichange/pos-system/internal/interfaces/http/order"
+	e2e "github.com/onichange/pos-system/internal/testing"
+)
+
+func TestCartCheckout(t *testing.T) {
+	env := e2e.Start(t)
+	catalogService := env.StartCatalog(t)
+	inventoryService := env.StartInventory(t)
+	orderService := env.StartOrder(t) // Prices cart items through catalogService
+
+	storeID := uuid.New()
+	token := env.Token(t, uuid.New())
+
+	// A product priced at 4.50 in the store
+	variantID := stockProduct(t, catalogService, inventoryService, storeID)
+	item := "/items/" + variantID.String()
+
+	// The cart is priced server-side as it changes
+	var cart orderhttp.CartResponse
+	orderService.Expect(t, http.StatusCreated, http.MethodPost, "/api/v1/carts", map[string]interface{}{
+		"store_id": storeID,
+		"items":    []map[string]interface{}{{"product_id": variantID, "quantity": 2}},
+	}, token, &cart)
+	require.InDelta(t, 9.00, cart.TotalAmount, 0.001)
+	path := "/api/v1/carts/" + cart.ID.String()
+
+	orderService.Expect(t, http.StatusOK, http.MethodPost, path+"/items",
+		map[string]interface{}{"product_id": variantID, "quantity": 1}, token, &cart)
+	require.Len(t, cart.Items, 1)
+	require.Equal(t, 3, cart.Items[0].Quantity)
+	require.InDelta(t, 13.50, cart.TotalAmount, 0.001)
+
+	// A line discount, then 10% off what is left
+	orderService.Expect(t, http.StatusOK, http.MethodPut, path+item,
+		map[string]interface{}{"quantity": 3, "discount": 0.50}, token, &cart)
+	orderService.Expect(t, http.StatusOK, http.MethodPut, path+"/discount",
+		map[string]interface{}{"type": "percent", "value": 10}, token, &cart)
+	require.InDelta(t, 11.70, cart.TotalAmount, 0.001)
+
+	// Carts belong to whoever started them
+	orderService.Expect(t, http.StatusForbidden, http.MethodGet, path, nil, env.Token(t, uuid.New()), nil)
+
+	// Checking out creates the order as priced and ends the cart
+	var created orderhttp.OrderResponse
+	orderService.Expect(t, http.StatusCreated, http.MethodPost, path+"/checkout",
+		map[string]interface{}{}, token, &created)
+	require.Equal(t, string(order.StatusPending), created.Status)
+	require.Len(t, created.Items, 1)
+	require.InDelta(t, 1.80, created.Items[0].Discount, 0.001)
+	require.InDelta(t, 11.70, created.TotalAmount, 0.001)
+
+	orderService.Expect(t, http.StatusOK, http.MethodGet, "/api/v1/orders/"+created.ID.String(), nil, token, nil)
+	orderService.Expect(t, http.StatusNotFound, http.MethodGet, path, nil, token, nil)
+	orderService.Expect(t, http.StatusNotFound, http.MethodPost, path+"/checkout",
+		map[string]interface{}{}, token, nil)
+}
@@ -0,0 +1,71 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/catalog"
+	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+func TestCatalogResolvePrices(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := tenantContext()
+	catalogRepo := repository.NewCatalogRepository(env.DB)
+
+	product := &catalog.Product{
+		ID:     uuid.New(),
+		Name:   "Drip Coffee",
+		Status: catalog.StatusActive,
+		Variants: []*catalog.Variant{
+			{ID: uuid.New(), SKU: "E2E-DRIP-S-" + uuid.NewString()[:8], Name: "Small", BasePrice: 2.5, Currency: "USD"},
+			{ID: uuid.New(), SKU: "E2E-DRIP-L-" + uuid.NewString()[:8], Name: "Large", BasePrice: 3.5, Currency: "USD"},
+		},
+	}
+	require.NoError(t, catalogRepo.CreateProduct(ctx, product))
+	small, large := product.Variants[0].ID, product.Variants[1].ID
+
+	store, otherStore := uuid.New(), uuid.New()
+	require.NoError(t, catalogRepo.SetPrice(ctx, &catalog.Price{StoreID: store, VariantID: small, Price: 2.25, Currency: "EUR"}))
+	require.NoError(t, catalogRepo.SetPrice(ctx, &catalog.Price{StoreID: otherStore, VariantID: large, Price: 9, Currency: "USD"}))
+
+	resolve := func(ctx context.Context, storeID uuid.UUID) map[uuid.UUID]*catalog.PricedVariant {
+		priced, err := catalogRepo.ResolvePrices(ctx, storeID, []uuid.UUID{small, large, uuid.New()})
+		require.NoError(t, err)
+		byID := make(map[uuid.UUID]*catalog.PricedVariant)
+		for _, pv := range priced {
+			byID[pv.VariantID] = pv
+		}
+		return byID
+	}
+
+	// The store's price, in its currency, takes precedence over the base
+	// price; variants it does not price fall back to theirs, whatever other
+	// stores charge. Unknown variants are left out.
+	priced := resolve(ctx, store)
+	require.Len(t, priced, 2)
+	assert.Equal(t, 2.25, priced[small].Price)
+	assert.Equal(t, "EUR", priced[small].Currency)
+	assert.True(t, priced[small].StorePrice)
+	assert.Equal(t, "Drip Coffee - Small", priced[small].Name)
+	assert.Equal(t, 3.5, priced[large].Price)
+	assert.Equal(t, "USD", priced[large].Currency)
+	assert.False(t, priced[large].StorePrice)
+
+	// Once the store's price is removed, the base price applies again
+	require.NoError(t, catalogRepo.DeletePrice(ctx, store, small))
+	priced = resolve(ctx, store)
+	assert.Equal(t, 2.5, priced[small].Price)
+	assert.Equal(t, "USD", priced[small].Currency)
+	assert.False(t, priced[small].StorePrice)
+
+	// Other tenants see none of the catalog
+	other := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "other", Source: tenant.SourceDefault})
+	assert.Empty(t, resolve(other, store))
+}
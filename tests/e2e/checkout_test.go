@@ -0,0 +1,168 @@
+package e2e
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/catalog"
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/internal/infrastructure/inventoryclient"
+	inventoryhttp "github.com/onichange/pos-system/internal/interfaces/http/inventory"
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/apiclient"
+	"github.com/onichange/pos-system/pkg/apiclient/orders"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+)
+
+func TestCheckout(t *testing.T) {
+	env := e2e.Start(t)
+	catalogService := env.StartCatalog(t)
+	inventoryService := env.StartInventory(t)
+	orderService := env.StartOrder(t) // Prices items through catalogService
+
+	events := env.Subscribe(t, "order.*", "inventory.*")
+	storeID := uuid.New()
+	userID := uuid.New()
+	token := env.Token(t, userID)
+
+	// A product priced at 4.50 in the store, with 10 in stock
+	variantID := stockProduct(t, catalogService, inventoryService, storeID)
+
+	// The order is priced from the store's price list, sent through the
+	// generated client as callers of the API would
+	orderClient := orders.New(apiclient.New(orderService.URL+"/api/v1", apiclient.WithToken(token)))
+	created, err := orderClient.CreateOrder(context.Background(), &apiclient.CreateOrderRequest{
+		StoreID: storeID,
+		Items:   []apiclient.CreateOrderRequestItem{{ProductID: variantID, Quantity: 2}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, apiclient.OrderStatusPending, created.Status)
+	require.Len(t, created.Items, 1)
+	require.InDelta(t, 4.50, created.Items[0].UnitPrice, 0.001)
+	require.InDelta(t, 9.00, created.TotalAmount, 0.001)
+
+	event := events.Wait(t, order.EventCreated, 10*time.Second, func(e messagequeue.Event) bool {
+		return e.Data["order_id"] == created.ID.String()
+	})
+	require.Equal(t, userID.String(), event.Data["user_id"])
+
+	// The stock is reserved for the order
+	inventoryService.Expect(t, http.StatusOK, http.MethodPost, "/api/v1/inventory/reserve", map[string]interface{}{
+		"product_id": variantID,
+		"store_id":   storeID,
+		"quantity":   2,
+	}, token, nil)
+	events.Wait(t, inventory.EventReserved, 10*time.Second, nil)
+
+	var stock inventoryhttp.InventoryResponse
+	inventoryService.Expect(t, http.StatusOK, http.MethodGet,
+		"/api/v1/inventory/product/"+variantID.String()+"?store_id="+storeID.String(), nil, token, &stock)
+	require.Equal(t, 10, stock.Quantity)
+	require.Equal(t, 2, stock.ReservedQuantity)
+}
+
+func TestCheckoutSaga(t *testing.T) {
+	env := e2e.Start(t)
+	catalogService := env.StartCatalog(t)
+	inventoryService := env.StartInventory(t)
+	env.StartPayment(t)
+	orderService := env.StartOrder(t) // Checks out through inventoryService and the payment service
+
+	ctx := context.Background()
+	events := env.Subscribe(t, "order.*", "payment.*")
+	storeID := uuid.New()
+	userID := uuid.New()
+	token := env.Token(t, userID)
+	variantID := stockProduct(t, catalogService, inventoryService, storeID)
+
+	orderClient := orders.New(apiclient.New(orderService.URL+"/api/v1", apiclient.WithToken(token)))
+	card := &orders.CheckoutOrderRequest{
+		PaymentMethodToken: "pm_card_visa",
+		PaymentMethodType:  orders.CheckoutOrderRequestPaymentMethodTypeCard,
+	}
+
+	// Checking out reserves the stock, charges the total and confirms the order
+	created, err := orderClient.CreateOrder(ctx, &apiclient.CreateOrderRequest{
+		StoreID: storeID,
+		Items:   []apiclient.CreateOrderRequestItem{{ProductID: variantID, Quantity: 2}},
+	})
+	require.NoError(t, err)
+
+	checkout, err := orderClient.CheckoutOrder(ctx, created.ID, card)
+	require.NoError(t, err)
+	require.Equal(t, apiclient.CheckoutStatusCompleted, checkout.Status)
+	require.Equal(t, apiclient.OrderStatusConfirmed, checkout.Order.Status)
+
+	paid := events.Wait(t, payment.EventCompleted, 10*time.Second, func(e messagequeue.Event) bool {
+		return e.Data["payment_id"] == checkout.CheckoutID.String()
+	})
+	require.Equal(t, created.ID.String(), paid.Data["order_id"])
+	require.InDelta(t, 9.00, paid.Data["amount"], 0.001)
+
+	var stock inventoryhttp.InventoryResponse
+	inventoryService.Expect(t, http.StatusOK, http.MethodGet,
+		"/api/v1/inventory/product/"+variantID.String()+"?store_id="+storeID.String(), nil, token, &stock)
+	require.Equal(t, 2, stock.ReservedQuantity)
+
+	// An order beyond the stock is cancelled, with nothing charged or left reserved
+	tooMany, err := orderClient.CreateOrder(ctx, &apiclient.CreateOrderRequest{
+		StoreID: storeID,
+		Items:   []apiclient.CreateOrderRequestItem{{ProductID: variantID, Quantity: 20}},
+	})
+	require.NoError(t, err)
+
+	_, err = orderClient.CheckoutOrder(ctx, tooMany.ID, card)
+	var apiErr *apiclient.Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusConflict, apiErr.Status)
+
+	events.Wait(t, order.EventCancelled, 10*time.Second, func(e messagequeue.Event) bool {
+		return e.Data["order_id"] == tooMany.ID.String()
+	})
+	cancelled, err := orderClient.GetOrder(ctx, tooMany.ID)
+	require.NoError(t, err)
+	require.Equal(t, apiclient.OrderStatusCancelled, cancelled.Status)
+
+	inventoryService.Expect(t, http.StatusOK, http.MethodGet,
+		"/api/v1/inventory/product/"+variantID.String()+"?store_id="+storeID.String(), nil, token, &stock)
+	require.Equal(t, 2, stock.ReservedQuantity)
+}
+
+// stockProduct creates a product priced at 4.50 in a store, with 10 in stock
+// there, and returns its variant's ID
+func stockProduct(t *testing.T, catalogService, inventoryService *e2e.Service, storeID uuid.UUID) uuid.UUID {
+	t.Helper()
+
+	var product catalog.Product
+	catalogService.Expect(t, http.StatusCreated, http.MethodPost, "/api/v1/products", map[string]interface{}{
+		"name":   "Oat Latte",
+		"status": "active",
+		"variants": []map[string]interface{}{
+			{"sku": "E2E-OAT-LATTE", "base_price": 5.00, "currency": "USD"},
+		},
+	}, "", &product)
+	require.Len(t, product.Variants, 1)
+	variantID := product.Variants[0].ID
+
+	catalogService.Expect(t, http.StatusOK, http.MethodPut,
+		"/api/v1/stores/"+storeID.String()+"/prices/"+variantID.String(),
+		map[string]interface{}{"price": 4.50, "currency": "USD"}, "", nil)
+
+	receiving := inventoryclient.NewClient(inventoryService.Dial(t))
+	require.NoError(t, receiving.ReceiveStock(context.Background(), &inventory.StockReceipt{
+		ProductID:  variantID,
+		StoreID:    storeID,
+		Quantity:   10,
+		UnitCost:   1.75,
+		SourceType: "e2e",
+		SourceID:   uuid.New(),
+	}))
+	return variantID
+}
@@ -0,0 +1,135 @@
+package e2e
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/promotion"
+	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// newCoupon creates a percent off promotion and a coupon for it in the
+// tenant of ctx
+func newCoupon(t *testing.T, ctx context.Context, env *e2e.Env, maxUses, maxUsesPerUser int) (*repository.CouponRepository, *promotion.Coupon) {
+	t.Helper()
+	p := &promotion.Promotion{
+		ID:         uuid.New(),
+		Name:       "Ten off",
+		Type:       promotion.TypePercentOff,
+		Rule:       promotion.Rule{Percent: 10},
+		Active:     true,
+		CouponOnly: true,
+	}
+	require.NoError(t, repository.NewPromotionRepository(env.DB).Create(ctx, p))
+
+	couponRepo := repository.NewCouponRepository(env.DB, env.DB)
+	c := &promotion.Coupon{
+		ID:             uuid.New(),
+		Code:           "TEN-" + uuid.NewString()[:8],
+		PromotionID:    p.ID,
+		MaxUses:        maxUses,
+		MaxUsesPerUser: maxUsesPerUser,
+		Active:         true,
+	}
+	require.NoError(t, couponRepo.CreateCoupon(ctx, c))
+	return couponRepo, c
+}
+
+// redeemConcurrently redeems the coupon at once for an order of each of
+// users, and returns how many succeeded
+func redeemConcurrently(t *testing.T, ctx context.Context, couponRepo *repository.CouponRepository, couponID uuid.UUID, users ...uuid.UUID) int {
+	t.Helper()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		redeemed int
+	)
+	for _, userID := range users {
+		wg.Add(1)
+		go func(userID uuid.UUID) {
+			defer wg.Done()
+			err := couponRepo.Redeem(ctx, &promotion.CouponRedemption{CouponID: couponID, OrderID: uuid.New(), UserID: userID})
+			if err != nil {
+				assert.ErrorIs(t, err, promotion.ErrCouponExhausted)
+				return
+			}
+			mu.Lock()
+			redeemed++
+			mu.Unlock()
+		}(userID)
+	}
+	wg.Wait()
+	return redeemed
+}
+
+// repeat returns n copies of id
+func repeat(id uuid.UUID, n int) []uuid.UUID {
+	ids := make([]uuid.UUID, n)
+	for i := range ids {
+		ids[i] = id
+	}
+	return ids
+}
+
+func TestCouponRedemptionsConcurrentlyKeepPerUserLimit(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := tenantContext()
+	couponRepo, c := newCoupon(t, ctx, env, 0, 2)
+
+	userID := uuid.New()
+	assert.Equal(t, 2, redeemConcurrently(t, ctx, couponRepo, c.ID, repeat(userID, 10)...))
+
+	count, err := couponRepo.UserRedemptions(ctx, c.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// Other users have their own allowance
+	assert.Equal(t, 2, redeemConcurrently(t, ctx, couponRepo, c.ID, repeat(uuid.New(), 10)...))
+}
+
+func TestCouponRedemptionsConcurrentlyKeepTotalLimit(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := tenantContext()
+	couponRepo, c := newCoupon(t, ctx, env, 3, 0)
+
+	users := make([]uuid.UUID, 10)
+	for i := range users {
+		users[i] = uuid.New()
+	}
+	assert.Equal(t, 3, redeemConcurrently(t, ctx, couponRepo, c.ID, users...))
+
+	stored, err := couponRepo.GetCoupon(ctx, c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stored.Uses)
+}
+
+func TestCouponRedemptionsOfOtherTenantsDoNotCount(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := tenantContext()
+	couponRepo, c := newCoupon(t, ctx, env, 0, 1)
+	userID := uuid.New()
+
+	// A redemption of the same coupon and user recorded under another tenant
+	// does not use up the allowance of this one
+	_, err := env.DB.Exec(ctx, `
+		INSERT INTO coupon_redemptions (coupon_id, order_id, user_id, tenant_id)
+		VALUES ($1, $2, $3, 'other')
+	`, c.ID, uuid.New(), userID)
+	require.NoError(t, err)
+
+	require.NoError(t, couponRepo.Redeem(ctx, &promotion.CouponRedemption{CouponID: c.ID, OrderID: uuid.New(), UserID: userID}))
+	assert.ErrorIs(t, couponRepo.Redeem(ctx, &promotion.CouponRedemption{CouponID: c.ID, OrderID: uuid.New(), UserID: userID}),
+		promotion.ErrCouponExhausted)
+
+	// Nor can the other tenant redeem this tenant's coupon
+	other := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "other", Source: tenant.SourceDefault})
+	assert.ErrorIs(t, couponRepo.Redeem(other, &promotion.CouponRedemption{CouponID: c.ID, OrderID: uuid.New(), UserID: uuid.New()}),
+		promotion.ErrCouponExhausted)
+}
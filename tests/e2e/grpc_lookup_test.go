@@ -0,0 +1,63 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/infrastructure/orderclient"
+	orderhttp "github.com/onichange/pos-system/internal/interfaces/http/order"
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/apiclient"
+	"github.com/onichange/pos-system/pkg/apiclient/orders"
+	"github.com/onichange/pos-system/pkg/i18n"
+)
+
+func TestOrderLookupOverGRPC(t *testing.T) {
+	env := e2e.Start(t)
+	catalogService := env.StartCatalog(t)
+	inventoryService := env.StartInventory(t)
+	orderService := env.StartOrder(t)
+
+	ctx := context.Background()
+	storeID := uuid.New()
+	userID := uuid.New()
+	token := env.Token(t, userID)
+	variantID := stockProduct(t, catalogService, inventoryService, storeID)
+
+	orderClient := orders.New(apiclient.New(orderService.URL+"/api/v1", apiclient.WithToken(token)))
+	created, err := orderClient.CreateOrder(ctx, &apiclient.CreateOrderRequest{
+		StoreID: storeID,
+		Items:   []apiclient.CreateOrderRequestItem{{ProductID: variantID, Quantity: 2}},
+	})
+	require.NoError(t, err)
+
+	lookups := orderclient.NewGRPCClient(orderService.Dial(t))
+
+	// The gateway renders the order found over gRPC as the service renders it
+	// over HTTP
+	found, err := lookups.GetOrder(ctx, created.ID)
+	require.NoError(t, err)
+	overHTTP := orderService.Expect(t, http.StatusOK, http.MethodGet,
+		"/api/v1/orders/"+created.ID.String(), nil, token, nil)
+	overGRPC, err := json.Marshal(orderhttp.ToResponse(found).Localize(i18n.DefaultLocale))
+	require.NoError(t, err)
+	require.JSONEq(t, string(overHTTP.Body), string(overGRPC))
+
+	listed, err := lookups.ListOrders(ctx, userID, 20, 0)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	require.Equal(t, created.ID, listed[0].ID)
+
+	listed, err = lookups.ListOrders(ctx, uuid.New(), 20, 0)
+	require.NoError(t, err)
+	require.Empty(t, listed)
+
+	_, err = lookups.GetOrder(ctx, uuid.New())
+	require.ErrorIs(t, err, order.ErrOrderNotFound)
+}
@@ -0,0 +1,95 @@
+package e2e
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/apiclient"
+	inventoryclient "github.com/onichange/pos-system/pkg/apiclient/inventory"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+)
+
+func TestInventoryAdjustment(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := context.Background()
+	events := env.Subscribe(t, "inventory.*")
+
+	inventoryService := env.StartInventory(t)
+	client := inventoryclient.New(apiclient.New(inventoryService.URL + "/api/v1"))
+
+	// Ten on hand, three of them reserved
+	var inventoryID uuid.UUID
+	err := env.DB.QueryRow(ctx, `
+		INSERT INTO inventory (product_id, store_id, quantity, reserved_quantity, reorder_point, tenant_id)
+		VALUES ($1, $2, 10, 3, 5, 'default')
+		RETURNING id
+	`, uuid.New(), uuid.New()).Scan(&inventoryID)
+	require.NoError(t, err)
+	current, err := client.GetInventory(ctx, inventoryID)
+	require.NoError(t, err)
+
+	note := "Dropped pallet"
+	adjusted, err := client.AdjustInventory(ctx, inventoryID, &apiclient.AdjustInventoryRequest{
+		QuantityChange: -4,
+		Reason:         apiclient.AdjustInventoryRequestReasonDamage,
+		Note:           &note,
+		Version:        current.Version,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 6, adjusted.Inventory.Quantity)
+	require.Equal(t, 3, adjusted.Inventory.AvailableQuantity)
+	require.Equal(t, current.Version+1, adjusted.Inventory.Version)
+	require.Equal(t, "adjustment", adjusted.Movement.MovementType)
+	require.Equal(t, 10, adjusted.Movement.PreviousQuantity)
+	require.Equal(t, 6, adjusted.Movement.NewQuantity)
+	require.Equal(t, "damage: Dropped pallet", adjusted.Movement.Reason)
+
+	var recorded int
+	require.NoError(t, env.DB.QueryRow(ctx,
+		`SELECT count(*) FROM stock_movements WHERE inventory_id = $1 AND movement_type = 'adjustment'`,
+		inventoryID).Scan(&recorded))
+	require.Equal(t, 1, recorded)
+
+	events.Wait(t, inventory.EventAdjusted, 10*time.Second, func(e messagequeue.Event) bool {
+		return e.Data["reason"] == "damage" && e.Data["quantity"] == float64(-4)
+	})
+	// The adjustment took available stock to the reorder point
+	events.Wait(t, inventory.EventLowStock, 10*time.Second, func(e messagequeue.Event) bool {
+		return e.Data["available"] == float64(3)
+	})
+
+	// A change worked out from an older version is refused
+	_, err = client.AdjustInventory(ctx, inventoryID, &apiclient.AdjustInventoryRequest{
+		QuantityChange: 1,
+		Reason:         apiclient.AdjustInventoryRequestReasonCountCorrection,
+		Version:        current.Version,
+	})
+	var apiErr *apiclient.Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusConflict, apiErr.Status)
+
+	// Stock cannot drop below what is reserved
+	_, err = client.AdjustInventory(ctx, inventoryID, &apiclient.AdjustInventoryRequest{
+		QuantityChange: -4,
+		Reason:         apiclient.AdjustInventoryRequestReasonTheft,
+		Version:        adjusted.Inventory.Version,
+	})
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusBadRequest, apiErr.Status)
+
+	// And every adjustment needs a known reason
+	_, err = client.AdjustInventory(ctx, inventoryID, &apiclient.AdjustInventoryRequest{
+		QuantityChange: 1,
+		Reason:         "found",
+		Version:        adjusted.Inventory.Version,
+	})
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusBadRequest, apiErr.Status)
+}
@@ -0,0 +1,164 @@
+package e2e
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/loyalty"
+	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// earnPoints credits points to a member for a new order
+func earnPoints(t *testing.T, ctx context.Context, loyaltyRepo *repository.LoyaltyRepository, userID uuid.UUID, points int64) (bool, error) {
+	t.Helper()
+	orderID := uuid.New()
+	return loyaltyRepo.Earn(ctx, &loyalty.Transaction{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Points:    points,
+		OrderID:   &orderID,
+		Amount:    float64(points),
+		Currency:  "USD",
+		CreatedAt: time.Now(),
+	})
+}
+
+func TestLoyaltyRedemptionBeyondBalance(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := tenantContext()
+	loyaltyRepo := repository.NewLoyaltyRepository(env.DB, env.DB)
+
+	userID := uuid.New()
+	orderID := uuid.New()
+	credited, err := loyaltyRepo.Earn(ctx, &loyalty.Transaction{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Points:    300,
+		OrderID:   &orderID,
+		Amount:    300,
+		Currency:  "USD",
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	require.True(t, credited)
+
+	redeem := func(points int64) error {
+		return loyaltyRepo.Redeem(ctx, &loyalty.Redemption{
+			OrderID:  uuid.New(),
+			UserID:   userID,
+			Points:   points,
+			Amount:   float64(points) / 100,
+			Currency: "USD",
+		})
+	}
+	balance := func() int64 {
+		account, err := loyaltyRepo.GetAccount(ctx, userID, time.Now())
+		require.NoError(t, err)
+		return account.Balance
+	}
+
+	// A redemption beyond the balance debits nothing
+	assert.ErrorIs(t, redeem(301), loyalty.ErrInsufficientPoints)
+	assert.Equal(t, int64(300), balance())
+
+	// The balance can be spent in full, and then nothing more
+	require.NoError(t, redeem(200))
+	assert.ErrorIs(t, redeem(101), loyalty.ErrInsufficientPoints)
+	require.NoError(t, redeem(100))
+	assert.Zero(t, balance())
+	assert.ErrorIs(t, redeem(1), loyalty.ErrInsufficientPoints)
+
+	// Members who never earned have nothing to redeem
+	assert.ErrorIs(t, loyaltyRepo.Redeem(ctx, &loyalty.Redemption{
+		OrderID: uuid.New(), UserID: uuid.New(), Points: 1, Amount: 0.01, Currency: "USD",
+	}), loyalty.ErrInsufficientPoints)
+}
+
+func TestLoyaltyRedemptionsConcurrentlyKeepBalance(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := tenantContext()
+	loyaltyRepo := repository.NewLoyaltyRepository(env.DB, env.DB)
+
+	// Two credits, so redemptions spend across them
+	userID := uuid.New()
+	for _, points := range []int64{300, 200} {
+		credited, err := earnPoints(t, ctx, loyaltyRepo, userID, points)
+		require.NoError(t, err)
+		require.True(t, credited)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		redeemed int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := loyaltyRepo.Redeem(ctx, &loyalty.Redemption{
+				OrderID: uuid.New(), UserID: userID, Points: 150, Amount: 1.5, Currency: "USD",
+			})
+			if err != nil {
+				assert.ErrorIs(t, err, loyalty.ErrInsufficientPoints)
+				return
+			}
+			mu.Lock()
+			redeemed++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// 500 points pay for three redemptions of 150, and each credit is spent
+	// once
+	assert.Equal(t, 3, redeemed)
+	account, err := loyaltyRepo.GetAccount(ctx, userID, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), account.Balance)
+
+	var remaining int64
+	err = env.DB.QueryRow(ctx,
+		`SELECT SUM(remaining) FROM loyalty_transactions WHERE user_id = $1 AND type = 'earn'`, userID,
+	).Scan(&remaining)
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), remaining)
+}
+
+func TestLoyaltyAccountsOfOtherTenants(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := tenantContext()
+	other := tenant.NewContext(context.Background(), &tenant.Tenant{ID: "other", Source: tenant.SourceDefault})
+	loyaltyRepo := repository.NewLoyaltyRepository(env.DB, env.DB)
+
+	userID := uuid.New()
+	credited, err := earnPoints(t, ctx, loyaltyRepo, userID, 300)
+	require.NoError(t, err)
+	require.True(t, credited)
+
+	// Earning in another tenant fails and leaves nothing in either ledger
+	credited, err = earnPoints(t, other, loyaltyRepo, userID, 100)
+	assert.ErrorIs(t, err, loyalty.ErrOtherTenant)
+	assert.False(t, credited)
+	ledger, err := loyaltyRepo.GetTransactions(other, userID, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, ledger)
+
+	// Nor can the other tenant spend the account's points
+	assert.ErrorIs(t, loyaltyRepo.Redeem(other, &loyalty.Redemption{
+		OrderID: uuid.New(), UserID: userID, Points: 100, Amount: 1, Currency: "USD",
+	}), loyalty.ErrInsufficientPoints)
+
+	account, err := loyaltyRepo.GetAccount(ctx, userID, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(300), account.Balance)
+	assert.Equal(t, int64(300), account.LifetimePoints)
+}
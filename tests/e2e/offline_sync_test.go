@@ -0,0 +1,109 @@
+package e2e
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/catalog"
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/apiclient"
+	syncapi "github.com/onichange/pos-system/pkg/apiclient/sync"
+)
+
+func TestOfflineSync(t *testing.T) {
+	env := e2e.Start(t)
+	catalogService := env.StartCatalog(t)
+	env.StartOrder(t) // Reprices uploads through catalogService
+	syncService := env.StartSync(t)
+
+	storeID := uuid.New()
+	cashierID := uuid.New()
+	ctx := context.Background()
+	client := syncapi.New(apiclient.New(syncService.URL+"/api/v1",
+		apiclient.WithToken(env.Token(t, cashierID, "staff"))))
+
+	// A product at 5.00, priced at 4.50 in the store
+	var product catalog.Product
+	catalogService.Expect(t, http.StatusCreated, http.MethodPost, "/api/v1/products", map[string]interface{}{
+		"name":   "Flat White",
+		"status": "active",
+		"variants": []map[string]interface{}{
+			{"sku": "E2E-FLAT-WHITE", "base_price": 5.00, "currency": "USD"},
+		},
+	}, "", &product)
+	require.Len(t, product.Variants, 1)
+	variantID := product.Variants[0].ID
+	catalogService.Expect(t, http.StatusOK, http.MethodPut,
+		"/api/v1/stores/"+storeID.String()+"/prices/"+variantID.String(),
+		map[string]interface{}{"price": 4.50, "currency": "USD"}, "", nil)
+
+	// A terminal's first pull is a snapshot of the product and the store price
+	var snapshot *apiclient.SyncChangePage
+	require.Eventually(t, func() bool {
+		page, err := client.GetSyncChanges(ctx, storeID, nil)
+		require.NoError(t, err)
+		snapshot = page
+		return len(page.Changes) == 2
+	}, 10*time.Second, 100*time.Millisecond)
+	require.Equal(t, apiclient.SyncChangeEntityProduct, snapshot.Changes[0].Entity)
+	require.Equal(t, product.ID, snapshot.Changes[0].EntityID)
+	require.Equal(t, apiclient.SyncChangeEntityPrice, snapshot.Changes[1].Entity)
+	require.Equal(t, variantID, snapshot.Changes[1].EntityID)
+	require.InDelta(t, 4.50, snapshot.Changes[1].Data["price"], 0.001)
+	require.False(t, snapshot.HasMore)
+
+	// Offline, the terminal charged the base price it had before the store
+	// price reached it
+	sale := apiclient.OfflineTransaction{
+		ID:        uuid.New(),
+		Items:     []apiclient.OrderItem{{ProductID: &variantID, Quantity: apiclient.Ptr(2), UnitPrice: apiclient.Ptr(5.00)}},
+		CreatedAt: time.Now().Add(-time.Hour).UTC(),
+	}
+	reject := apiclient.SyncPushRequestConflictPolicyReject
+	pushed, err := client.PushSyncTransactions(ctx, storeID, &apiclient.SyncPushRequest{
+		TerminalID:     "till-1",
+		ConflictPolicy: &reject,
+		Transactions:   []apiclient.OfflineTransaction{sale},
+	})
+	require.NoError(t, err)
+	require.Len(t, pushed.Data, 1)
+	require.Equal(t, apiclient.SyncTransactionResultStatusRejected, pushed.Data[0].Status)
+	require.Len(t, pushed.Data[0].Conflicts, 1)
+	require.Equal(t, apiclient.SyncConflictKindPriceChanged, pushed.Data[0].Conflicts[0].Kind)
+	require.InDelta(t, 4.50, pushed.Data[0].Conflicts[0].ServerPrice, 0.001)
+
+	// Letting the catalog win reprices the sale; uploading it again is a no-op
+	serverWins := apiclient.SyncPushRequestConflictPolicyServerWins
+	for _, status := range []apiclient.SyncTransactionResultStatus{
+		apiclient.SyncTransactionResultStatusAccepted,
+		apiclient.SyncTransactionResultStatusDuplicate,
+	} {
+		pushed, err = client.PushSyncTransactions(ctx, storeID, &apiclient.SyncPushRequest{
+			TerminalID:     "till-1",
+			ConflictPolicy: &serverWins,
+			Transactions:   []apiclient.OfflineTransaction{sale},
+		})
+		require.NoError(t, err)
+		require.Len(t, pushed.Data, 1)
+		require.Equal(t, status, pushed.Data[0].Status)
+		require.Equal(t, sale.ID, pushed.Data[0].ID)
+		require.InDelta(t, 9.00, pushed.Data[0].TotalAmount, 0.001)
+	}
+
+	// Removing the store price reaches the terminal as a tombstone
+	catalogService.Expect(t, http.StatusNoContent, http.MethodDelete,
+		"/api/v1/stores/"+storeID.String()+"/prices/"+variantID.String(), nil, "", nil)
+	since := int(snapshot.Cursor)
+	require.Eventually(t, func() bool {
+		page, err := client.GetSyncChanges(ctx, storeID, &syncapi.GetSyncChangesParams{Since: &since})
+		require.NoError(t, err)
+		return len(page.Changes) == 1 &&
+			page.Changes[0].Entity == apiclient.SyncChangeEntityPrice &&
+			page.Changes[0].Op == apiclient.SyncChangeOpDelete
+	}, 10*time.Second, 100*time.Millisecond)
+}
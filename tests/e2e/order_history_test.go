@@ -0,0 +1,70 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/apiclient"
+	"github.com/onichange/pos-system/pkg/apiclient/orders"
+)
+
+func TestOrderHistory(t *testing.T) {
+	t.Setenv("ORDER_STORE", "events")
+	t.Setenv("ORDER_SNAPSHOT_EVERY", "2")
+
+	env := e2e.Start(t)
+	orderService := env.StartOrder(t)
+
+	ctx := context.Background()
+	admin := orders.New(apiclient.New(orderService.URL+"/api/v1",
+		apiclient.WithToken(env.Token(t, uuid.New(), "admin"))))
+
+	created, err := admin.CreateOrder(ctx, &apiclient.CreateOrderRequest{
+		StoreID: uuid.New(),
+		Items:   []apiclient.CreateOrderRequestItem{{ProductID: uuid.New(), Quantity: 1}},
+	})
+	require.NoError(t, err)
+
+	// Time passes between changes, so as_of can fall between them
+	time.Sleep(50 * time.Millisecond)
+	beforeConfirming := time.Now().UTC()
+	time.Sleep(50 * time.Millisecond)
+
+	for _, status := range []orders.UpdateOrderStatusRequestStatus{
+		orders.UpdateOrderStatusRequestStatusConfirmed,
+		orders.UpdateOrderStatusRequestStatusProcessing,
+	} {
+		_, err := admin.UpdateOrderStatus(ctx, created.ID, &orders.UpdateOrderStatusRequest{Status: status})
+		require.NoError(t, err)
+	}
+
+	// Every change is recorded, in order, past the snapshot taken at version 2
+	history, err := admin.GetOrderHistory(ctx, created.ID, nil)
+	require.NoError(t, err)
+	require.Equal(t, apiclient.OrderStatusProcessing, history.Order.Status)
+	require.Len(t, history.Changes, 3)
+	require.Equal(t, apiclient.OrderChangeTypeCreated, history.Changes[0].Type)
+	require.Equal(t, created.ID, history.Changes[0].Order.ID)
+	for i, change := range history.Changes {
+		require.Equal(t, i+1, change.Version)
+	}
+	require.Equal(t, "confirmed", history.Changes[1].Status)
+	require.Equal(t, "processing", history.Changes[2].Status)
+
+	// As of before it was confirmed, the order was pending
+	asOf := beforeConfirming.Format(time.RFC3339Nano)
+	past, err := admin.GetOrderHistory(ctx, created.ID, &orders.GetOrderHistoryParams{AsOf: &asOf})
+	require.NoError(t, err)
+	require.Equal(t, apiclient.OrderStatusPending, past.Order.Status)
+	require.Len(t, past.Changes, 1)
+
+	// Before it was created, there was no order
+	asOf = created.CreatedAt.Add(-time.Hour).Format(time.RFC3339)
+	_, err = admin.GetOrderHistory(ctx, created.ID, &orders.GetOrderHistoryParams{AsOf: &asOf})
+	require.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package e2e
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/apiclient"
+	"github.com/onichange/pos-system/pkg/apiclient/orders"
+)
+
+func TestOrderStatusTransitions(t *testing.T) {
+	env := e2e.Start(t)
+	orderService := env.StartOrder(t)
+
+	ctx := context.Background()
+	adminID := uuid.New()
+	admin := orders.New(apiclient.New(orderService.URL+"/api/v1",
+		apiclient.WithToken(env.Token(t, adminID, "admin"))))
+
+	created, err := admin.CreateOrder(ctx, &apiclient.CreateOrderRequest{
+		StoreID: uuid.New(),
+		Items:   []apiclient.CreateOrderRequestItem{{ProductID: uuid.New(), Quantity: 1}},
+	})
+	require.NoError(t, err)
+
+	reason := "paid at the counter"
+	updated, err := admin.TransitionOrderStatus(ctx, created.ID, &orders.TransitionOrderStatusRequest{
+		Status: orders.TransitionOrderStatusRequestStatusConfirmed,
+		Reason: &reason,
+	})
+	require.NoError(t, err)
+	require.Equal(t, apiclient.OrderStatusConfirmed, updated.Status)
+
+	// A confirmed order must be shipped before it is delivered
+	_, err = admin.TransitionOrderStatus(ctx, created.ID, &orders.TransitionOrderStatusRequest{
+		Status: orders.TransitionOrderStatusRequestStatusDelivered,
+	})
+	var apiErr *apiclient.Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusConflict, apiErr.Status)
+
+	_, err = admin.TransitionOrderStatus(ctx, created.ID, &orders.TransitionOrderStatusRequest{
+		Status: orders.TransitionOrderStatusRequestStatusShipped,
+	})
+	require.NoError(t, err)
+
+	// Only the moves made are recorded, with who made them
+	history, err := admin.GetOrderStatusHistory(ctx, created.ID)
+	require.NoError(t, err)
+	require.Len(t, history.Transitions, 2)
+	first := history.Transitions[0]
+	require.Equal(t, apiclient.OrderStatusTransitionFromPending, first.From)
+	require.Equal(t, apiclient.OrderStatusTransitionToConfirmed, first.To)
+	require.Equal(t, adminID, first.ChangedBy)
+	require.Equal(t, reason, first.Reason)
+	require.Equal(t, apiclient.OrderStatusTransitionToShipped, history.Transitions[1].To)
+}
@@ -0,0 +1,38 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	e2e "github.com/onichange/pos-system/internal/testing"
+)
+
+func TestPaymentInternalAPIRequiresServiceToken(t *testing.T) {
+	env := e2e.Start(t)
+	paymentService := env.StartPayment(t)
+
+	charge := map[string]any{
+		"id":                   uuid.New(),
+		"order_id":             uuid.New(),
+		"user_id":              uuid.New(),
+		"payment_method_token": "pm_card_visa",
+		"payment_method_type":  "card",
+		"amount":               10,
+		"currency":             "USD",
+	}
+	paymentID := uuid.NewString()
+
+	// Without a token, and with a user's
+	for _, token := range []string{"", env.Token(t, uuid.New())} {
+		status := http.StatusUnauthorized
+		if token != "" {
+			status = http.StatusForbidden
+		}
+		paymentService.Expect(t, status, http.MethodPost, "/internal/v1/payments", charge, token, nil)
+		paymentService.Expect(t, status, http.MethodGet, "/internal/v1/payments/"+paymentID, nil, token, nil)
+		paymentService.Expect(t, status, http.MethodPost, "/internal/v1/payments/"+paymentID+"/refund",
+			map[string]any{"reason": "test"}, token, nil)
+	}
+}
@@ -0,0 +1,113 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/notification"
+	"github.com/onichange/pos-system/internal/domain/procurement"
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/apiclient"
+	procurementclient "github.com/onichange/pos-system/pkg/apiclient/procurement"
+	"github.com/onichange/pos-system/pkg/apiclient/stores"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+)
+
+func TestReorderLowStock(t *testing.T) {
+	t.Setenv("PROCUREMENT_REORDER_INTERVAL", "200ms")
+
+	env := e2e.Start(t)
+	ctx := context.Background()
+	events := env.Subscribe(t, "notification.*")
+
+	env.StartInventory(t)
+	storeService := env.StartStore(t, customers{})
+	procurementService := env.StartProcurement(t)
+
+	managerID := uuid.New()
+	address, city, state, postalCode, country := "1 Quay St", "Auckland", "AUK", "1010", "NZ"
+	s, err := stores.New(apiclient.New(storeService.URL+"/api/v1")).CreateStore(ctx, &apiclient.CreateStoreRequest{
+		Name: "Harbour", Code: "HRB-1", Latitude: -36.84, Longitude: 174.76,
+		Address: &address, City: &city, State: &state, PostalCode: &postalCode, Country: &country,
+		ManagerID: &managerID,
+	})
+	require.NoError(t, err)
+
+	// Two products at their reorder point, one no supplier sells and one
+	// well stocked
+	restocked, minimum, unsold, stocked := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	_, err = env.DB.Exec(ctx, `
+		INSERT INTO inventory (product_id, store_id, quantity, reorder_point, reorder_quantity, tenant_id)
+		VALUES ($1, $5, 2, 5, 20, 'default'), ($2, $5, 0, 3, 0, 'default'),
+			($3, $5, 1, 5, 10, 'default'), ($4, $5, 50, 5, 10, 'default')
+	`, restocked, minimum, unsold, stocked, s.ID)
+	require.NoError(t, err)
+
+	client := procurementclient.New(apiclient.New(procurementService.URL+"/api/v1",
+		apiclient.WithToken(env.Token(t, uuid.New(), "admin"))))
+
+	// The cheaper supplier is inactive, so the dearer one is ordered from
+	inactive := false
+	cheap, err := client.CreateSupplier(ctx, &apiclient.SupplierRequest{Code: "CHEAP", Name: "Cheap Co", Currency: "NZD", Active: &inactive})
+	require.NoError(t, err)
+	acme, err := client.CreateSupplier(ctx, &apiclient.SupplierRequest{Code: "ACME", Name: "Acme", Currency: "NZD"})
+	require.NoError(t, err)
+
+	sku, six := "AC-1", 6
+	_, err = client.SetSupplierProduct(ctx, cheap.ID, restocked, &apiclient.SupplierProductRequest{UnitCost: 1})
+	require.NoError(t, err)
+	_, err = client.SetSupplierProduct(ctx, acme.ID, restocked, &apiclient.SupplierProductRequest{SupplierSKU: &sku, UnitCost: 1.5})
+	require.NoError(t, err)
+	_, err = client.SetSupplierProduct(ctx, acme.ID, minimum, &apiclient.SupplierProductRequest{UnitCost: 2, MinOrderQuantity: &six})
+	require.NoError(t, err)
+	_, err = client.SetSupplierProduct(ctx, acme.ID, stocked, &apiclient.SupplierProductRequest{UnitCost: 3})
+	require.NoError(t, err)
+
+	ordersOf := func() []apiclient.PurchaseOrder {
+		t.Helper()
+		list, err := client.ListPurchaseOrders(ctx, &procurementclient.ListPurchaseOrdersParams{StoreID: &s.ID})
+		require.NoError(t, err)
+		return list.Data
+	}
+
+	var raised []apiclient.PurchaseOrder
+	require.Eventually(t, func() bool {
+		raised = ordersOf()
+		return len(raised) > 0
+	}, 10*time.Second, 100*time.Millisecond)
+	require.Len(t, raised, 1)
+
+	o := raised[0]
+	require.Equal(t, apiclient.PurchaseOrderStatusDraft, o.Status)
+	require.Equal(t, acme.ID, o.SupplierID)
+	require.Equal(t, procurement.ReorderNote, o.Notes)
+	require.Equal(t, 42.0, o.Total)
+
+	quantities := map[uuid.UUID]int{}
+	for _, l := range o.Lines {
+		quantities[l.ProductID] = l.Quantity
+	}
+	// The reorder quantity, and enough to lift stock above the reorder
+	// point raised to the supplier's minimum
+	require.Equal(t, map[uuid.UUID]int{restocked: 20, minimum: 6}, quantities)
+
+	events.Wait(t, notification.EventRequested, 10*time.Second, func(e messagequeue.Event) bool {
+		data, _ := e.Data["data"].(map[string]interface{})
+		return e.Data["user_id"] == managerID.String() && data["purchase_order_id"] == o.ID.String()
+	})
+
+	// Stock on order is not reordered
+	time.Sleep(time.Second)
+	require.Len(t, ordersOf(), 1)
+
+	// Until the order is cancelled
+	_, err = client.CancelPurchaseOrder(ctx, o.ID)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(ordersOf()) == 2
+	}, 10*time.Second, 100*time.Millisecond)
+}
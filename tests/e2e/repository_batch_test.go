@@ -0,0 +1,163 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/tenant"
+)
+
+// batchSizes are the batch sizes benchmarked
+var batchSizes = []int{10, 100, 1000}
+
+func tenantContext() context.Context {
+	return tenant.NewContext(context.Background(), &tenant.Tenant{ID: "default", Source: tenant.SourceDefault})
+}
+
+// newOrders returns n pending orders with a few items each
+func newOrders(n int) []*order.Order {
+	orders := make([]*order.Order, n)
+	for i := range orders {
+		orders[i] = &order.Order{
+			ID:       uuid.New(),
+			UserID:   uuid.New(),
+			StoreID:  uuid.New(),
+			Status:   order.StatusPending,
+			Currency: "USD",
+			Items: []order.OrderItem{
+				{ProductID: uuid.NewString(), Name: "Coffee", Quantity: 2, UnitPrice: 3.5, Subtotal: 7},
+				{ProductID: uuid.NewString(), Name: "Bagel", Quantity: 1, UnitPrice: 2.25, Subtotal: 2.25},
+			},
+			TotalAmount: 9.25,
+		}
+	}
+	return orders
+}
+
+// newStock creates a stock record, returning it with n movements of it
+func newStock(t testing.TB, ctx context.Context, repo *repository.InventoryRepository, n int) (*inventory.Inventory, []*inventory.StockMovement) {
+	t.Helper()
+	inv := &inventory.Inventory{ID: uuid.New(), ProductID: uuid.New(), Quantity: n, AvailableQuantity: n}
+	require.NoError(t, repo.Create(ctx, inv))
+
+	movements := make([]*inventory.StockMovement, n)
+	for i := range movements {
+		movements[i] = &inventory.StockMovement{
+			ID:               uuid.New(),
+			InventoryID:      inv.ID,
+			MovementType:     inventory.MovementIn,
+			Quantity:         1,
+			PreviousQuantity: i,
+			NewQuantity:      i + 1,
+			Reason:           "received",
+		}
+	}
+	return inv, movements
+}
+
+func TestRepositoryBatches(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := tenantContext()
+
+	count := func(query string, args ...interface{}) int {
+		var n int
+		require.NoError(t, env.DB.QueryRow(ctx, query, args...).Scan(&n))
+		return n
+	}
+
+	for name, orderRepo := range map[string]repository.OrderStore{
+		"projection":    repository.NewOrderRepository(env.DB, nil),
+		"event sourced": repository.NewEventSourcedOrderRepository(env.DB, env.DB, nil, 10),
+	} {
+		t.Run(name, func(t *testing.T) {
+			orders := newOrders(3)
+			require.NoError(t, orderRepo.CreateBatch(ctx, orders))
+			for _, o := range orders {
+				require.False(t, o.CreatedAt.IsZero())
+				stored, err := orderRepo.GetByID(ctx, o.ID)
+				require.NoError(t, err)
+				require.Equal(t, o.Items, stored.Items)
+			}
+
+			// One order already created fails the batch, creating none of it
+			again := append(newOrders(1), orders[0])
+			require.ErrorIs(t, orderRepo.CreateBatch(ctx, again), order.ErrOrderExists)
+			_, err := orderRepo.GetByID(ctx, again[0].ID)
+			require.ErrorIs(t, err, order.ErrOrderNotFound)
+		})
+	}
+
+	inventoryRepo := repository.NewInventoryRepository(env.DB)
+	inv, movements := newStock(t, ctx, inventoryRepo, 3)
+	require.NoError(t, inventoryRepo.RecordMovements(ctx, movements))
+	require.Equal(t, 3, count(`SELECT COUNT(*) FROM stock_movements WHERE inventory_id = $1`, inv.ID))
+
+	// A movement of missing stock fails the batch, recording none of it
+	_, more := newStock(t, ctx, inventoryRepo, 2)
+	more[1].InventoryID = uuid.New()
+	require.Error(t, inventoryRepo.RecordMovements(ctx, more))
+	require.Zero(t, count(`SELECT COUNT(*) FROM stock_movements WHERE id = $1`, more[0].ID))
+}
+
+// BenchmarkCreateOrders compares creating orders one statement at a time
+// with creating them in one batch
+func BenchmarkCreateOrders(b *testing.B) {
+	env := e2e.Start(b)
+	ctx := tenantContext()
+	repo := repository.NewOrderRepository(env.DB, nil)
+
+	for _, n := range batchSizes {
+		b.Run(fmt.Sprintf("loop/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, o := range newOrders(n) {
+					require.NoError(b, repo.Create(ctx, o))
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("batch/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				require.NoError(b, repo.CreateBatch(ctx, newOrders(n)))
+			}
+		})
+	}
+}
+
+// BenchmarkRecordMovements compares recording stock movements one statement
+// at a time with recording them in one batch
+func BenchmarkRecordMovements(b *testing.B) {
+	env := e2e.Start(b)
+	ctx := tenantContext()
+	repo := repository.NewInventoryRepository(env.DB)
+
+	for _, n := range batchSizes {
+		_, movements := newStock(b, ctx, repo, n)
+		renew := func() {
+			for _, m := range movements {
+				m.ID = uuid.New()
+			}
+		}
+
+		b.Run(fmt.Sprintf("loop/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				renew()
+				for _, m := range movements {
+					require.NoError(b, repo.RecordMovement(ctx, m))
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("batch/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				renew()
+				require.NoError(b, repo.RecordMovements(ctx, movements))
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package e2e
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/inventory"
+	"github.com/onichange/pos-system/internal/infrastructure/inventoryclient"
+	inventoryhttp "github.com/onichange/pos-system/internal/interfaces/http/inventory"
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/messagequeue"
+)
+
+func TestReservationExpiry(t *testing.T) {
+	t.Setenv("INVENTORY_RESERVATION_TTL", "1s")
+	t.Setenv("INVENTORY_SWEEP_INTERVAL", "100ms")
+
+	env := e2e.Start(t)
+	inventoryService := env.StartInventory(t)
+	events := env.Subscribe(t, "inventory.*")
+	token := env.Token(t, uuid.New())
+
+	productID, storeID := uuid.New(), uuid.New()
+	require.NoError(t, inventoryclient.NewClient(inventoryService.Dial(t)).ReceiveStock(context.Background(), &inventory.StockReceipt{
+		ProductID:  productID,
+		StoreID:    storeID,
+		Quantity:   10,
+		UnitCost:   1,
+		SourceType: "e2e",
+		SourceID:   uuid.New(),
+	}))
+
+	reserved := func() int {
+		t.Helper()
+		var stock inventoryhttp.InventoryResponse
+		inventoryService.Expect(t, http.StatusOK, http.MethodGet,
+			"/api/v1/inventory/product/"+productID.String()+"?store_id="+storeID.String(), nil, token, &stock)
+		return stock.ReservedQuantity
+	}
+	reserve := func(quantity int) inventoryhttp.ReserveStockResponse {
+		t.Helper()
+		var res inventoryhttp.ReserveStockResponse
+		inventoryService.Expect(t, http.StatusOK, http.MethodPost, "/api/v1/inventory/reserve", map[string]interface{}{
+			"product_id": productID,
+			"store_id":   storeID,
+			"quantity":   quantity,
+		}, token, &res)
+		require.Equal(t, inventory.ReservationHeld, res.Status)
+		return res
+	}
+
+	// A reservation left alone is released once it expires
+	abandoned := reserve(3)
+	require.Equal(t, 3, reserved())
+
+	released := events.Wait(t, inventory.EventReleased, 10*time.Second, func(e messagequeue.Event) bool {
+		return e.Data["reservation_id"] == abandoned.ReservationID.String()
+	})
+	require.Equal(t, inventory.ReasonReservationExpired, released.Data["reason"])
+	require.Equal(t, 0, reserved())
+
+	var res inventory.Reservation
+	inventoryService.Expect(t, http.StatusOK, http.MethodGet, "/api/v1/inventory/reservations/"+abandoned.ReservationID.String(), nil, token, &res)
+	require.Equal(t, inventory.ReservationExpired, res.Status)
+	inventoryService.Expect(t, http.StatusConflict, http.MethodPost, "/api/v1/inventory/reservations/"+abandoned.ReservationID.String()+"/commit", nil, token, nil)
+
+	// A committed one is kept past its expiry, until released
+	committed := reserve(2)
+	inventoryService.Expect(t, http.StatusOK, http.MethodPost, "/api/v1/inventory/reservations/"+committed.ReservationID.String()+"/commit", nil, token, &res)
+	require.Equal(t, inventory.ReservationCommitted, res.Status)
+
+	time.Sleep(1500 * time.Millisecond)
+	require.Equal(t, 2, reserved())
+
+	inventoryService.Expect(t, http.StatusOK, http.MethodPost, "/api/v1/inventory/reservations/"+committed.ReservationID.String()+"/release", nil, token, &res)
+	require.Equal(t, inventory.ReservationReleased, res.Status)
+	require.Equal(t, 0, reserved())
+	inventoryService.Expect(t, http.StatusConflict, http.MethodPost, "/api/v1/inventory/reservations/"+committed.ReservationID.String()+"/release", nil, token, nil)
+
+	// Reserving under an ID again reserves nothing more
+	id := uuid.New()
+	for range 2 {
+		inventoryService.Expect(t, http.StatusOK, http.MethodPost, "/api/v1/inventory/reserve", map[string]interface{}{
+			"reservation_id": id,
+			"product_id":     productID,
+			"store_id":       storeID,
+			"quantity":       4,
+		}, token, nil)
+	}
+	require.Equal(t, 4, reserved())
+}
+
+func TestReserveBatch(t *testing.T) {
+	env := e2e.Start(t)
+	inventoryService := env.StartInventory(t)
+	token := env.Token(t, uuid.New())
+	receiving := inventoryclient.NewClient(inventoryService.Dial(t))
+
+	storeID := uuid.New()
+	plenty, scarce := uuid.New(), uuid.New()
+	for productID, quantity := range map[uuid.UUID]int{plenty: 10, scarce: 3} {
+		require.NoError(t, receiving.ReceiveStock(context.Background(), &inventory.StockReceipt{
+			ProductID:  productID,
+			StoreID:    storeID,
+			Quantity:   quantity,
+			UnitCost:   1,
+			SourceType: "e2e",
+			SourceID:   uuid.New(),
+		}))
+	}
+
+	reserved := func(productID uuid.UUID) int {
+		t.Helper()
+		var stock inventoryhttp.InventoryResponse
+		inventoryService.Expect(t, http.StatusOK, http.MethodGet,
+			"/api/v1/inventory/product/"+productID.String()+"?store_id="+storeID.String(), nil, token, &stock)
+		return stock.ReservedQuantity
+	}
+	item := func(id, productID uuid.UUID, quantity int) map[string]interface{} {
+		return map[string]interface{}{"reservation_id": id, "product_id": productID, "store_id": storeID, "quantity": quantity}
+	}
+
+	// A shortage of one item reserves none, naming the items short
+	var failed struct {
+		Items []inventoryhttp.ReserveBatchItemError `json:"items"`
+	}
+	inventoryService.Expect(t, http.StatusConflict, http.MethodPost, "/api/v1/inventory/reserve-batch", map[string]interface{}{
+		"items": []map[string]interface{}{
+			item(uuid.New(), plenty, 4), item(uuid.New(), scarce, 2), item(uuid.New(), scarce, 2), item(uuid.New(), uuid.New(), 1),
+		},
+	}, token, &failed)
+	require.Len(t, failed.Items, 2)
+	require.Equal(t, 2, failed.Items[0].Index)
+	require.Equal(t, "insufficient_stock", failed.Items[0].Code)
+	require.Equal(t, 3, failed.Items[1].Index)
+	require.Equal(t, "inventory_not_found", failed.Items[1].Code)
+	require.Equal(t, 0, reserved(plenty))
+	require.Equal(t, 0, reserved(scarce))
+
+	// Otherwise every item is reserved, once however often the batch is sent
+	batch := map[string]interface{}{
+		"items":          []map[string]interface{}{item(uuid.New(), plenty, 4), item(uuid.New(), scarce, 3)},
+		"reference_id":   uuid.New(),
+		"reference_type": "order",
+	}
+	for range 2 {
+		var resp inventoryhttp.ReserveBatchResponse
+		inventoryService.Expect(t, http.StatusOK, http.MethodPost, "/api/v1/inventory/reserve-batch", batch, token, &resp)
+		require.Len(t, resp.Reservations, 2)
+		for _, res := range resp.Reservations {
+			require.Equal(t, inventory.ReservationHeld, res.Status)
+		}
+	}
+	require.Equal(t, 4, reserved(plenty))
+	require.Equal(t, 3, reserved(scarce))
+}
@@ -0,0 +1,127 @@
+package e2e
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/apiclient"
+	inventoryclient "github.com/onichange/pos-system/pkg/apiclient/inventory"
+)
+
+func TestStockCount(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := context.Background()
+
+	inventoryService := env.StartInventory(t)
+	client := inventoryclient.New(apiclient.New(inventoryService.URL + "/api/v1"))
+
+	// Two of the second product are held for an order
+	storeID := uuid.New()
+	short, over, uncounted := uuid.New(), uuid.New(), uuid.New()
+	_, err := env.DB.Exec(ctx, `
+		INSERT INTO inventory (product_id, store_id, quantity, reserved_quantity, cost_price, tenant_id)
+		VALUES ($1, $4, 10, 0, 2.50, 'default'), ($2, $4, 5, 2, 8.00, 'default'), ($3, $4, 3, 0, 1.00, 'default')
+	`, short, over, uncounted, storeID)
+	require.NoError(t, err)
+
+	quantity := func(productID uuid.UUID) int {
+		t.Helper()
+		var q int
+		require.NoError(t, env.DB.QueryRow(ctx,
+			`SELECT quantity FROM inventory WHERE product_id = $1 AND store_id = $2`, productID, storeID).Scan(&q))
+		return q
+	}
+	record := func(countID uuid.UUID, items ...apiclient.RecordStockCountsRequestItem) error {
+		_, err := client.RecordStockCounts(ctx, countID, &apiclient.RecordStockCountsRequest{Items: items})
+		return err
+	}
+	requireStatus := func(err error, status int) {
+		t.Helper()
+		var apiErr *apiclient.Error
+		require.ErrorAs(t, err, &apiErr)
+		require.Equal(t, status, apiErr.Status)
+	}
+
+	count, err := client.CreateStockCount(ctx, &apiclient.CreateStockCountRequest{StoreID: storeID})
+	require.NoError(t, err)
+	require.Equal(t, apiclient.StockCountStatusOpen, count.Status)
+
+	// A store is counted by one count at a time
+	_, err = client.CreateStockCount(ctx, &apiclient.CreateStockCountRequest{StoreID: storeID})
+	requireStatus(err, http.StatusConflict)
+
+	// Only products the store stocks are counted
+	requireStatus(record(count.ID, apiclient.RecordStockCountsRequestItem{ProductID: uuid.New(), CountedQuantity: 1}), http.StatusNotFound)
+
+	require.NoError(t, record(count.ID,
+		apiclient.RecordStockCountsRequestItem{ProductID: short, CountedQuantity: 8},
+		apiclient.RecordStockCountsRequestItem{ProductID: over, CountedQuantity: 1},
+	))
+
+	// Counting below what is reserved is not reconciled, and changes nothing
+	_, err = client.ReconcileStockCount(ctx, count.ID)
+	requireStatus(err, http.StatusConflict)
+	require.Equal(t, 5, quantity(over))
+
+	// A recount replaces the earlier count
+	require.NoError(t, record(count.ID, apiclient.RecordStockCountsRequestItem{ProductID: over, CountedQuantity: 6}))
+
+	report, err := client.GetStockCountVariances(ctx, count.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, report.Lines, 2)
+	require.Equal(t, 2, report.Summary.LinesVarying)
+	require.Equal(t, 1, report.Summary.UnitsOver)
+	require.Equal(t, 2, report.Summary.UnitsShort)
+	require.InDelta(t, 3.0, report.Summary.VarianceValue, 0.001) // 8.00 found less 5.00 missing
+
+	resp, err := http.Get(inventoryService.URL + "/api/v1/inventory/counts/" + count.ID.String() + "/variances?format=csv")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, "variance", records[0][4])
+
+	// Reconciling sets the counted products' stock to their counts
+	reconciled, err := client.ReconcileStockCount(ctx, count.ID)
+	require.NoError(t, err)
+	require.Equal(t, apiclient.StockCountStatusReconciled, reconciled.Count.Status)
+	require.Len(t, reconciled.Adjustments, 2)
+	require.Equal(t, 8, quantity(short))
+	require.Equal(t, 6, quantity(over))
+	require.Equal(t, 3, quantity(uncounted))
+
+	var movements int
+	require.NoError(t, env.DB.QueryRow(ctx, `
+		SELECT count(*) FROM stock_movements
+		WHERE reference_type = 'stock_count' AND reference_id = $1 AND reason = 'count_correction'
+	`, count.ID).Scan(&movements))
+	require.Equal(t, 2, movements)
+
+	// The report keeps the stock the counts replaced
+	report, err = client.GetStockCountVariances(ctx, count.ID, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Summary.LinesVarying)
+
+	_, err = client.ReconcileStockCount(ctx, count.ID)
+	requireStatus(err, http.StatusConflict)
+	requireStatus(record(count.ID, apiclient.RecordStockCountsRequestItem{ProductID: short, CountedQuantity: 1}), http.StatusConflict)
+
+	// Once closed, the store can be counted again
+	next, err := client.CreateStockCount(ctx, &apiclient.CreateStockCountRequest{StoreID: storeID})
+	require.NoError(t, err)
+	cancelled, err := client.CancelStockCount(ctx, next.ID)
+	require.NoError(t, err)
+	require.Equal(t, apiclient.StockCountStatusCancelled, cancelled.Status)
+
+	list, err := client.ListStockCounts(ctx, &inventoryclient.ListStockCountsParams{StoreID: &storeID})
+	require.NoError(t, err)
+	require.Len(t, list.Data, 2)
+}
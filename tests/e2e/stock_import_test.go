@@ -0,0 +1,102 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/apiclient"
+	inventoryclient "github.com/onichange/pos-system/pkg/apiclient/inventory"
+)
+
+func TestStockImport(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := context.Background()
+
+	inventoryService := env.StartInventory(t)
+	client := inventoryclient.New(apiclient.New(inventoryService.URL + "/api/v1"))
+
+	storeID := uuid.New()
+	stocked := uuid.New()
+	_, err := env.DB.Exec(ctx, `
+		INSERT INTO inventory (product_id, store_id, quantity, tenant_id) VALUES ($1, $2, 1, 'default')
+	`, stocked, storeID)
+	require.NoError(t, err)
+
+	csvBody := func(lines ...string) *apiclient.RawBody {
+		return &apiclient.RawBody{ContentType: "text/csv", Data: []byte(strings.Join(lines, "\n") + "\n")}
+	}
+	count := func() int {
+		t.Helper()
+		var n int
+		require.NoError(t, env.DB.QueryRow(ctx, `SELECT count(*) FROM inventory WHERE store_id = $1`, storeID).Scan(&n))
+		return n
+	}
+
+	// Every invalid row is reported, and nothing is imported
+	product := uuid.New()
+	_, err = client.ImportInventory(ctx, nil, csvBody(
+		"product_id,store_id,quantity,cost_price",
+		fmt.Sprintf("%s,%s,5,2.50", product, storeID),
+		fmt.Sprintf("%s,%s,-1,2.50", uuid.New(), storeID),
+		fmt.Sprintf("%s,%s,3,", product, storeID),
+		fmt.Sprintf("%s,%s,3,", stocked, storeID),
+		"not-a-uuid,,1,",
+	))
+	var apiErr *apiclient.Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusUnprocessableEntity, apiErr.Status)
+	require.Contains(t, string(apiErr.Body), `"line":3,"column":"quantity"`)
+	require.Contains(t, string(apiErr.Body), `"line":4,"column":"product_id"`)
+	require.Contains(t, string(apiErr.Body), `"line":5,"column":"product_id"`)
+	require.Contains(t, string(apiErr.Body), `"line":6,"column":"product_id"`)
+	require.Equal(t, 1, count())
+
+	// Unknown columns are refused outright
+	_, err = client.ImportInventory(ctx, nil, csvBody("product_id,colour", uuid.New().String()+",red"))
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusBadRequest, apiErr.Status)
+
+	lines := []string{"product_id,store_id,quantity,reorder_point,cost_price"}
+	for i := 0; i < 2000; i++ {
+		lines = append(lines, fmt.Sprintf("%s,%s,%d,2,1.25", uuid.New(), storeID, i%10))
+	}
+
+	// A dry run validates without importing
+	report, err := client.ImportInventory(ctx, &inventoryclient.ImportInventoryParams{DryRun: apiclient.Ptr(true)}, csvBody(lines...))
+	require.NoError(t, err)
+	require.True(t, report.DryRun)
+	require.Equal(t, 2000, report.Rows)
+	require.Zero(t, report.Imported)
+	require.Equal(t, 1, count())
+
+	report, err = client.ImportInventory(ctx, nil, csvBody(lines...))
+	require.NoError(t, err)
+	require.Equal(t, 2000, report.Imported)
+	require.Equal(t, 2001, count())
+
+	// Importing the same products again imports none of them
+	_, err = client.ImportInventory(ctx, nil, csvBody(lines...))
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusUnprocessableEntity, apiErr.Status)
+
+	// Files are also accepted as a form upload
+	var form bytes.Buffer
+	w := multipart.NewWriter(&form)
+	part, err := w.CreateFormFile("file", "stock.csv")
+	require.NoError(t, err)
+	fmt.Fprintf(part, "product_id,store_id\n%s,%s\n", uuid.New(), storeID)
+	require.NoError(t, w.Close())
+	report, err = client.ImportInventory(ctx, nil, &apiclient.RawBody{ContentType: w.FormDataContentType(), Data: form.Bytes()})
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Imported)
+	require.Equal(t, 2002, count())
+}
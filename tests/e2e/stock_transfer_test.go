@@ -0,0 +1,112 @@
+package e2e
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/apiclient"
+	inventoryclient "github.com/onichange/pos-system/pkg/apiclient/inventory"
+)
+
+func TestStockTransfer(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := context.Background()
+
+	inventoryService := env.StartInventory(t)
+	client := inventoryclient.New(apiclient.New(inventoryService.URL + "/api/v1"))
+
+	// The source has both products; the destination stocks only the first
+	source, destination := uuid.New(), uuid.New()
+	stocked, unstocked := uuid.New(), uuid.New()
+	_, err := env.DB.Exec(ctx, `
+		INSERT INTO inventory (product_id, store_id, quantity, cost_price, tenant_id)
+		VALUES ($1, $3, 10, 2.50, 'default'), ($2, $3, 4, 8.00, 'default'), ($1, $4, 1, 2.50, 'default')
+	`, stocked, unstocked, source, destination)
+	require.NoError(t, err)
+
+	stock := func(productID, storeID uuid.UUID) (quantity, reserved int) {
+		t.Helper()
+		require.NoError(t, env.DB.QueryRow(ctx,
+			`SELECT quantity, reserved_quantity FROM inventory WHERE product_id = $1 AND store_id = $2`,
+			productID, storeID).Scan(&quantity, &reserved))
+		return quantity, reserved
+	}
+
+	transfer, err := client.CreateStockTransfer(ctx, &apiclient.CreateStockTransferRequest{
+		SourceStoreID:      source,
+		DestinationStoreID: destination,
+		Items: []apiclient.CreateStockTransferRequestItem{
+			{ProductID: stocked, Quantity: 3},
+			{ProductID: unstocked, Quantity: 4},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, apiclient.StockTransferStatusRequested, transfer.Status)
+	require.Len(t, transfer.Items, 2)
+
+	// Receiving comes after dispatch
+	_, err = client.ReceiveStockTransfer(ctx, transfer.ID)
+	var apiErr *apiclient.Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusConflict, apiErr.Status)
+
+	// Dispatching holds the stock at the source
+	dispatched, err := client.DispatchStockTransfer(ctx, transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, apiclient.StockTransferStatusInTransit, dispatched.Status)
+	quantity, reserved := stock(stocked, source)
+	require.Equal(t, 10, quantity)
+	require.Equal(t, 3, reserved)
+
+	// Receiving moves it to the destination, creating stock it had none of
+	received, err := client.ReceiveStockTransfer(ctx, transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, apiclient.StockTransferStatusReceived, received.Status)
+
+	quantity, reserved = stock(stocked, source)
+	require.Equal(t, 7, quantity)
+	require.Equal(t, 0, reserved)
+	quantity, _ = stock(unstocked, source)
+	require.Equal(t, 0, quantity)
+	quantity, _ = stock(stocked, destination)
+	require.Equal(t, 4, quantity)
+	quantity, _ = stock(unstocked, destination)
+	require.Equal(t, 4, quantity)
+
+	var out, in int
+	require.NoError(t, env.DB.QueryRow(ctx, `
+		SELECT count(*) FILTER (WHERE movement_type = 'out'), count(*) FILTER (WHERE movement_type = 'in')
+		FROM stock_movements WHERE reference_type = 'stock_transfer' AND reference_id = $1
+	`, transfer.ID).Scan(&out, &in))
+	require.Equal(t, 2, out)
+	require.Equal(t, 2, in)
+
+	// A transfer of more than is available is not dispatched, and holds nothing
+	tooMany, err := client.CreateStockTransfer(ctx, &apiclient.CreateStockTransferRequest{
+		SourceStoreID:      source,
+		DestinationStoreID: destination,
+		Items: []apiclient.CreateStockTransferRequestItem{
+			{ProductID: stocked, Quantity: 2},
+			{ProductID: unstocked, Quantity: 1},
+		},
+	})
+	require.NoError(t, err)
+	_, err = client.DispatchStockTransfer(ctx, tooMany.ID)
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusConflict, apiErr.Status)
+	_, reserved = stock(stocked, source)
+	require.Equal(t, 0, reserved)
+
+	cancelled, err := client.CancelStockTransfer(ctx, tooMany.ID)
+	require.NoError(t, err)
+	require.Equal(t, apiclient.StockTransferStatusCancelled, cancelled.Status)
+
+	list, err := client.ListStockTransfers(ctx, &inventoryclient.ListStockTransfersParams{StoreID: &destination})
+	require.NoError(t, err)
+	require.Len(t, list.Data, 2)
+}
@@ -0,0 +1,169 @@
+package e2e
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/user"
+	e2e "github.com/onichange/pos-system/internal/testing"
+	"github.com/onichange/pos-system/pkg/apiclient"
+	"github.com/onichange/pos-system/pkg/apiclient/orders"
+	"github.com/onichange/pos-system/pkg/apiclient/stores"
+)
+
+// customers is a customer directory holding some users
+type customers map[uuid.UUID]*user.User
+
+func (c customers) GetUser(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	if u, ok := c[id]; ok {
+		return u, nil
+	}
+	return nil, user.ErrNotFound
+}
+
+func TestStoreExport(t *testing.T) {
+	t.Setenv("EXPORTS_DIR", t.TempDir())
+	t.Setenv("EXPORTS_INTERVAL", "100ms")
+
+	env := e2e.Start(t)
+	ctx := context.Background()
+
+	// The customer who ordered, and one since deleted
+	customerID, deletedID := uuid.New(), uuid.New()
+	directory := customers{customerID: {ID: customerID, Email: "ada@example.com", FirstName: "Ada"}}
+
+	// Orders are left unpriced, as they are taken before the catalog starts
+	orderService := env.StartOrder(t)
+	env.StartCatalog(t)
+	env.StartInventory(t)
+	storeService := env.StartStore(t, directory)
+
+	admin := stores.New(apiclient.New(storeService.URL + "/api/v1"))
+	address, city, state, postalCode, country := "1 Quay St", "Auckland", "AUK", "1010", "NZ"
+	s, err := admin.CreateStore(ctx, &apiclient.CreateStoreRequest{
+		Name: "Harbour", Code: "HRB-1", Latitude: -36.84, Longitude: 174.76,
+		Address: &address, City: &city, State: &state, PostalCode: &postalCode, Country: &country,
+	})
+	require.NoError(t, err)
+
+	_, err = env.DB.Exec(ctx, `
+		INSERT INTO inventory (product_id, store_id, quantity, tenant_id)
+		VALUES ($1, $3, 5, 'default'), ($2, $3, 7, 'default'), ($2, $4, 9, 'default')
+	`, uuid.New(), uuid.New(), s.ID, uuid.New())
+	require.NoError(t, err)
+
+	for _, userID := range []uuid.UUID{customerID, customerID, deletedID} {
+		buyer := orders.New(apiclient.New(orderService.URL+"/api/v1",
+			apiclient.WithToken(env.Token(t, userID))))
+		_, err := buyer.CreateOrder(ctx, &apiclient.CreateOrderRequest{
+			StoreID: s.ID,
+			Items:   []apiclient.CreateOrderRequestItem{{ProductID: uuid.New(), Quantity: 1}},
+		})
+		require.NoError(t, err)
+	}
+
+	requested, err := admin.CreateStoreExport(ctx, s.ID)
+	require.NoError(t, err)
+	require.Equal(t, apiclient.StoreExportStatusPending, requested.Status)
+
+	// It is built in the background
+	var export *apiclient.StoreExport
+	require.Eventually(t, func() bool {
+		export, err = admin.GetStoreExport(ctx, s.ID, requested.ID)
+		require.NoError(t, err)
+		return export.Status == apiclient.StoreExportStatusCompleted || export.Status == apiclient.StoreExportStatusFailed
+	}, 30*time.Second, 100*time.Millisecond)
+	require.Equal(t, apiclient.StoreExportStatusCompleted, export.Status, export.Error)
+	require.Equal(t, map[string]int{"catalog": 0, "inventory": 2, "orders": 3, "customers": 1}, export.Records)
+
+	archive, err := admin.DownloadStoreExport(ctx, s.ID, export.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, export.Size, len(archive))
+	checksum := sha256.Sum256(archive)
+	require.Equal(t, export.Checksum, hex.EncodeToString(checksum[:]))
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(r)
+		require.NoError(t, err)
+		r.Close()
+		files[f.Name] = buf.Bytes()
+	}
+	require.ElementsMatch(t, []string{
+		"store.json", "catalog.jsonl", "inventory.jsonl", "orders.jsonl", "customers.jsonl", "manifest.json",
+	}, keys(files))
+
+	var exported struct {
+		ID   uuid.UUID `json:"id"`
+		Code string    `json:"code"`
+	}
+	require.NoError(t, json.Unmarshal(files["store.json"], &exported))
+	require.Equal(t, s.ID, exported.ID)
+	require.Equal(t, "HRB-1", exported.Code)
+
+	// Only the store's stock is exported
+	for _, line := range lines(t, files["inventory.jsonl"]) {
+		var inv struct {
+			StoreID uuid.UUID `json:"store_id"`
+		}
+		require.NoError(t, json.Unmarshal(line, &inv))
+		require.Equal(t, s.ID, inv.StoreID)
+	}
+	require.Len(t, lines(t, files["orders.jsonl"]), 3)
+
+	customerLines := lines(t, files["customers.jsonl"])
+	require.Len(t, customerLines, 1)
+	require.Contains(t, string(customerLines[0]), "ada@example.com")
+
+	var manifest struct {
+		ExportID uuid.UUID      `json:"export_id"`
+		Files    map[string]int `json:"files"`
+	}
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	require.Equal(t, export.ID, manifest.ExportID)
+	require.Equal(t, 3, manifest.Files["orders.jsonl"])
+
+	listed, err := admin.ListStoreExports(ctx, s.ID)
+	require.NoError(t, err)
+	require.Len(t, listed.Data, 1)
+
+	// An export of another store is not found
+	_, err = admin.GetStoreExport(ctx, uuid.New(), export.ID)
+	require.Error(t, err)
+}
+
+// keys returns the names of files
+func keys(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	return names
+}
+
+// lines splits a JSON Lines file
+func lines(t *testing.T, file []byte) [][]byte {
+	t.Helper()
+	var out [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(file))
+	for scanner.Scan() {
+		out = append(out, append([]byte(nil), scanner.Bytes()...))
+	}
+	require.NoError(t, scanner.Err())
+	return out
+}
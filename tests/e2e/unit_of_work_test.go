@@ -0,0 +1,87 @@
+package e2e
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onichange/pos-system/internal/domain/order"
+	"github.com/onichange/pos-system/internal/domain/payment"
+	"github.com/onichange/pos-system/internal/infrastructure/repository"
+	"github.com/onichange/pos-system/internal/infrastructure/unitofwork"
+	e2e "github.com/onichange/pos-system/internal/testing"
+)
+
+func TestUnitOfWork(t *testing.T) {
+	env := e2e.Start(t)
+	ctx := tenantContext()
+
+	count := func(query string, args ...interface{}) int {
+		var n int
+		require.NoError(t, env.DB.QueryRow(ctx, query, args...).Scan(&n))
+		return n
+	}
+
+	for name, uow := range map[string]*unitofwork.UnitOfWork{
+		"projection":    unitofwork.New(env.DB, nil),
+		"event sourced": unitofwork.New(env.DB, nil).WithEventSourcedOrders(10),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// createOrder creates an order with its payment intent and
+			// created event, failing with fail once they are written
+			createOrder := func(fail error) *order.Order {
+				o := newOrders(1)[0]
+				err := uow.Do(ctx, func(tx *unitofwork.Tx) error {
+					if err := tx.Orders().Create(ctx, o); err != nil {
+						return err
+					}
+					if err := tx.Payments().Create(ctx, &payment.Payment{
+						ID:                 uuid.New(),
+						OrderID:            o.ID,
+						UserID:             o.UserID,
+						PaymentMethodToken: "tok_visa",
+						PaymentMethodType:  payment.MethodCard,
+						Amount:             o.TotalAmount,
+						Currency:           o.Currency,
+						Status:             payment.StatusPending,
+					}); err != nil {
+						return err
+					}
+					if err := tx.Events().PublishEventContext(ctx, "order.created", "order.created", map[string]interface{}{
+						"order_id": o.ID.String(),
+					}); err != nil {
+						return err
+					}
+					return fail
+				})
+				require.ErrorIs(t, err, fail)
+				return o
+			}
+			orders := repository.NewOrderRepository(env.DB, nil)
+
+			t.Run("commits together", func(t *testing.T) {
+				outbox := count(`SELECT count(*) FROM outbox`)
+				o := createOrder(nil)
+
+				_, err := orders.GetByID(ctx, o.ID)
+				require.NoError(t, err)
+				assert.Equal(t, 1, count(`SELECT count(*) FROM payments WHERE order_id = $1`, o.ID))
+				assert.Equal(t, outbox+1, count(`SELECT count(*) FROM outbox`))
+			})
+
+			t.Run("rolls back together", func(t *testing.T) {
+				outbox := count(`SELECT count(*) FROM outbox`)
+				failed := errors.New("payment provider unavailable")
+				o := createOrder(failed)
+
+				_, err := orders.GetByID(ctx, o.ID)
+				assert.ErrorIs(t, err, order.ErrOrderNotFound)
+				assert.Zero(t, count(`SELECT count(*) FROM payments WHERE order_id = $1`, o.ID))
+				assert.Equal(t, outbox, count(`SELECT count(*) FROM outbox`))
+			})
+		})
+	}
+}